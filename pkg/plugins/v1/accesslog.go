@@ -0,0 +1,67 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/accesslog"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/sampling"
+)
+
+// WithAccessLog makes Serve write an access log entry through l for every HandleRequest and
+// HandleResponse call, recording the request, the plugin's verdict, and the handler latency.
+// Entries are subject to the sampler configured with WithSampler (feature sampling.FeatureAccessLog).
+func WithAccessLog(l *accesslog.Logger) ServeOption {
+	return func(o *serveOptions) error {
+		if l == nil {
+			return fmt.Errorf("access logger cannot be nil")
+		}
+		o.subscribers = append(o.subscribers, pendingSubscription{
+			handler: func(ctx context.Context, ev Event) {
+				if ev.Request == nil && ev.Response == nil {
+					return
+				}
+				if !o.sample(sampling.FeatureAccessLog, ev) {
+					return
+				}
+				if err := l.Log(accessLogEntry(ctx, ev)); err != nil {
+					o.logger.Printf("access log: %v", err)
+				}
+			},
+			kinds: []EventKind{EventRequest},
+		})
+		return nil
+	}
+}
+
+// accessLogEntry builds the access log entry for a HandleRequest or HandleResponse event.
+func accessLogEntry(ctx context.Context, ev Event) accesslog.Entry {
+	e := accesslog.Entry{
+		Time:          ev.Time,
+		RPC:           ev.Method,
+		Verdict:       ev.Verdict(),
+		Duration:      ev.Duration,
+		CorrelationID: CorrelationID(ctx, ev.Request),
+	}
+
+	if req := ev.Request; req != nil {
+		e.Method = req.GetMethod()
+		e.Path = req.GetPath()
+		e.RemoteAddr = req.GetRemoteAddr()
+		e.Tool = mcp.ToolName(req.GetBody())
+		e.BodyBytes = len(req.GetBody())
+	} else if resp := ev.Response; resp != nil {
+		e.StatusCode = int(resp.GetStatusCode())
+		e.BodyBytes = len(resp.GetBody())
+	}
+
+	if ev.Result != nil && ev.Result.GetStatusCode() != 0 {
+		e.StatusCode = int(ev.Result.GetStatusCode())
+	}
+	if ev.Err != nil {
+		e.Error = ev.Err.Error()
+	}
+
+	return e
+}
@@ -0,0 +1,233 @@
+// Package accesslog formats and writes one record per plugin call handled by the SDK.
+//
+// Enable it with mcpdpluginsv1.WithAccessLog:
+//
+//	l, err := accesslog.New(os.Stdout, accesslog.JSON())
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	err = mcpdpluginsv1.Serve(&MyPlugin{}, mcpdpluginsv1.WithAccessLog(l))
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/recordenc"
+)
+
+// Entry describes a single handled HandleRequest or HandleResponse call.
+type Entry struct {
+	Time          time.Time     `json:"time"`
+	RPC           string        `json:"rpc"`
+	Method        string        `json:"method,omitempty"`
+	Path          string        `json:"path,omitempty"`
+	RemoteAddr    string        `json:"remoteAddr,omitempty"`
+	Tool          string        `json:"tool,omitempty"`
+	CorrelationID string        `json:"correlationId,omitempty"`
+	Verdict       string        `json:"verdict"`
+	StatusCode    int           `json:"statusCode,omitempty"`
+	BodyBytes     int           `json:"bodyBytes"`
+	Duration      time.Duration `json:"-"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// Format renders an entry as a single line, without the trailing newline.
+type Format interface {
+	Format(e Entry) ([]byte, error)
+}
+
+// FormatFunc adapts a function to the Format interface.
+type FormatFunc func(e Entry) ([]byte, error)
+
+// Format calls f(e).
+func (f FormatFunc) Format(e Entry) ([]byte, error) {
+	return f(e)
+}
+
+// clfTimeLayout is the timestamp layout of the Common Log Format.
+const clfTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// Common returns the Common Log Format extended with verdict, duration and correlation ID:
+//
+//	127.0.0.1 - - [10/Oct/2025:13:55:36 +0000] "POST /mcp" 403 52 verdict=short_circuit duration=1.2ms id=abc
+func Common() Format {
+	return FormatFunc(func(e Entry) ([]byte, error) {
+		var b bytes.Buffer
+		b.WriteString(dash(e.RemoteAddr))
+		b.WriteString(" - - [")
+		b.WriteString(e.Time.Format(clfTimeLayout))
+		b.WriteString("] \"")
+		if e.Method != "" || e.Path != "" {
+			b.WriteString(dash(e.Method))
+			b.WriteByte(' ')
+			b.WriteString(dash(e.Path))
+		} else {
+			b.WriteString(e.RPC)
+		}
+		b.WriteString("\" ")
+		if e.StatusCode > 0 {
+			b.WriteString(strconv.Itoa(e.StatusCode))
+		} else {
+			b.WriteByte('-')
+		}
+		b.WriteByte(' ')
+		b.WriteString(strconv.Itoa(e.BodyBytes))
+		fmt.Fprintf(&b, " verdict=%s duration=%s", e.Verdict, e.Duration)
+		if e.Tool != "" {
+			fmt.Fprintf(&b, " tool=%s", e.Tool)
+		}
+		if e.CorrelationID != "" {
+			fmt.Fprintf(&b, " id=%s", e.CorrelationID)
+		}
+		if e.Error != "" {
+			fmt.Fprintf(&b, " error=%q", e.Error)
+		}
+
+		return b.Bytes(), nil
+	})
+}
+
+// JSON renders each entry as a JSON object. The duration is reported in milliseconds as durationMs.
+func JSON() Format {
+	return FormatFunc(func(e Entry) ([]byte, error) {
+		return json.Marshal(struct {
+			Entry
+			DurationMs float64 `json:"durationMs"`
+		}{Entry: e, DurationMs: float64(e.Duration) / float64(time.Millisecond)})
+	})
+}
+
+// RecordType is the type of the records accesslog entries convert to.
+const RecordType = "call.handled"
+
+// Record returns e as a recordenc.Record of type RecordType, for encoders: a warning when the
+// call was short-circuited and an error when it failed.
+func (e Entry) Record() recordenc.Record {
+	sev := recordenc.SeverityInfo
+	switch {
+	case e.Error != "" || e.Verdict == metrics.VerdictError:
+		sev = recordenc.SeverityError
+	case e.Verdict == metrics.VerdictShortCircuit:
+		sev = recordenc.SeverityWarning
+	}
+
+	attrs := map[string]string{
+		"rpc":         e.RPC,
+		"verdict":     e.Verdict,
+		"body_bytes":  strconv.Itoa(e.BodyBytes),
+		"duration_ms": strconv.FormatFloat(float64(e.Duration)/float64(time.Millisecond), 'f', -1, 64),
+	}
+	for k, v := range map[string]string{
+		"method":         e.Method,
+		"path":           e.Path,
+		"remote_addr":    e.RemoteAddr,
+		"tool":           e.Tool,
+		"correlation_id": e.CorrelationID,
+		"error":          e.Error,
+	} {
+		if v != "" {
+			attrs[k] = v
+		}
+	}
+	if e.StatusCode > 0 {
+		attrs["status_code"] = strconv.Itoa(e.StatusCode)
+	}
+
+	return recordenc.Record{
+		Type:       RecordType,
+		Time:       e.Time,
+		Severity:   sev,
+		Message:    e.RPC + " " + e.Verdict,
+		Attributes: attrs,
+	}
+}
+
+// Encoded renders each entry as its Record encoded by enc, which must be a text encoder such as
+// recordenc.JSON, recordenc.CEF or recordenc.OTLPLogs, so SIEMs can ingest the access log as an
+// audit trail in the format they expect.
+func Encoded(enc recordenc.Encoder) Format {
+	return FormatFunc(func(e Entry) ([]byte, error) {
+		if !recordenc.Text(enc) {
+			return nil, fmt.Errorf("%s is not a line-oriented encoding", enc.ContentType())
+		}
+		line, err := enc.Encode([]recordenc.Record{e.Record()})
+		if err != nil {
+			return nil, err
+		}
+		if bytes.ContainsAny(line, "\r\n") {
+			return nil, fmt.Errorf("encoded entry spans several lines")
+		}
+		return line, nil
+	})
+}
+
+// Template renders each entry with a text/template over Entry, e.g.
+// "{{.Time.Format \"15:04:05\"}} {{.Path}} {{.Verdict}} {{.Duration}}".
+func Template(text string) (Format, error) {
+	tmpl, err := template.New("accesslog").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid access log template: %w", err)
+	}
+
+	return FormatFunc(func(e Entry) ([]byte, error) {
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, e); err != nil {
+			return nil, err
+		}
+		return bytes.TrimRight(b.Bytes(), "\n"), nil
+	}), nil
+}
+
+// Logger writes formatted entries to an io.Writer, one per line. It is safe for concurrent use.
+type Logger struct {
+	format Format
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// New returns a Logger writing entries rendered by f to w.
+func New(w io.Writer, f Format) (*Logger, error) {
+	if w == nil {
+		return nil, fmt.Errorf("writer cannot be nil")
+	}
+	if f == nil {
+		return nil, fmt.Errorf("format cannot be nil")
+	}
+
+	return &Logger{w: w, format: f}, nil
+}
+
+// Log formats and writes e.
+func (l *Logger) Log(e Entry) error {
+	line, err := l.format.Format(e)
+	if err != nil {
+		return fmt.Errorf("failed to format access log entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write access log entry: %w", err)
+	}
+
+	return nil
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
@@ -0,0 +1,432 @@
+package accesslog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/accesslog"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/recordenc"
+)
+
+var entryTime = time.Date(2025, 10, 10, 13, 55, 36, 0, time.UTC)
+
+// requestEntry is a short-circuited HandleRequest call with every field set.
+func requestEntry() accesslog.Entry {
+	return accesslog.Entry{
+		Time:          entryTime,
+		RPC:           "HandleRequest",
+		Method:        "POST",
+		Path:          "/mcp",
+		RemoteAddr:    "127.0.0.1",
+		Tool:          "search",
+		CorrelationID: "abc",
+		Verdict:       "short_circuit",
+		StatusCode:    403,
+		BodyBytes:     52,
+		Duration:      1200 * time.Microsecond,
+	}
+}
+
+func TestCommon(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry func() accesslog.Entry
+		want  string
+	}{
+		{
+			name:  "request",
+			entry: requestEntry,
+			want: `127.0.0.1 - - [10/Oct/2025:13:55:36 +0000] "POST /mcp" 403 52 ` +
+				`verdict=short_circuit duration=1.2ms tool=search id=abc`,
+		},
+		{
+			name: "response without request details",
+			entry: func() accesslog.Entry {
+				return accesslog.Entry{
+					Time:     entryTime,
+					RPC:      "HandleResponse",
+					Verdict:  "error",
+					Duration: time.Millisecond,
+					Error:    `upstream "down"`,
+				}
+			},
+			want: `- - - [10/Oct/2025:13:55:36 +0000] "HandleResponse" - 0 verdict=error duration=1ms ` +
+				`error="upstream \"down\""`,
+		},
+		{
+			name: "missing method",
+			entry: func() accesslog.Entry {
+				return accesslog.Entry{Time: entryTime, RPC: "HandleRequest", Path: "/mcp", Verdict: "continue"}
+			},
+			want: `- - - [10/Oct/2025:13:55:36 +0000] "- /mcp" - 0 verdict=continue duration=0s`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := accesslog.Common().Format(tt.entry())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Common() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	got, err := accesslog.JSON().Format(requestEntry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(got, &fields); err != nil {
+		t.Fatalf("JSON() produced invalid JSON %s: %v", got, err)
+	}
+	want := map[string]any{
+		"time":          "2025-10-10T13:55:36Z",
+		"rpc":           "HandleRequest",
+		"method":        "POST",
+		"path":          "/mcp",
+		"remoteAddr":    "127.0.0.1",
+		"tool":          "search",
+		"correlationId": "abc",
+		"verdict":       "short_circuit",
+		"statusCode":    float64(403),
+		"bodyBytes":     float64(52),
+		"durationMs":    1.2,
+	}
+	if len(fields) != len(want) {
+		t.Errorf("JSON() fields = %v, want %v", fields, want)
+	}
+	for k, v := range want {
+		if fields[k] != v {
+			t.Errorf("JSON() %s = %v, want %v", k, fields[k], v)
+		}
+	}
+}
+
+func TestJSONOmitsEmptyFields(t *testing.T) {
+	got, err := accesslog.JSON().Format(accesslog.Entry{Time: entryTime, RPC: "HandleResponse", Verdict: "continue"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"time":"2025-10-10T13:55:36Z","rpc":"HandleResponse","verdict":"continue","bodyBytes":0,"durationMs":0}`
+	if string(got) != want {
+		t.Errorf("JSON() = %s, want %s", got, want)
+	}
+}
+
+func TestEntryRecord(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry accesslog.Entry
+		want  recordenc.Record
+	}{
+		{
+			name:  "short-circuited call",
+			entry: requestEntry(),
+			want: recordenc.Record{
+				Type:     accesslog.RecordType,
+				Time:     entryTime,
+				Severity: recordenc.SeverityWarning,
+				Message:  "HandleRequest short_circuit",
+				Attributes: map[string]string{
+					"rpc":            "HandleRequest",
+					"verdict":        "short_circuit",
+					"method":         "POST",
+					"path":           "/mcp",
+					"remote_addr":    "127.0.0.1",
+					"tool":           "search",
+					"correlation_id": "abc",
+					"status_code":    "403",
+					"body_bytes":     "52",
+					"duration_ms":    "1.2",
+				},
+			},
+		},
+		{
+			name:  "continued call omits empty fields",
+			entry: accesslog.Entry{Time: entryTime, RPC: "HandleResponse", Verdict: "continue"},
+			want: recordenc.Record{
+				Type:     accesslog.RecordType,
+				Time:     entryTime,
+				Severity: recordenc.SeverityInfo,
+				Message:  "HandleResponse continue",
+				Attributes: map[string]string{
+					"rpc":         "HandleResponse",
+					"verdict":     "continue",
+					"body_bytes":  "0",
+					"duration_ms": "0",
+				},
+			},
+		},
+		{
+			name:  "error verdict",
+			entry: accesslog.Entry{Time: entryTime, RPC: "HandleRequest", Verdict: "error", Duration: time.Second},
+			want: recordenc.Record{
+				Type:     accesslog.RecordType,
+				Time:     entryTime,
+				Severity: recordenc.SeverityError,
+				Message:  "HandleRequest error",
+				Attributes: map[string]string{
+					"rpc":         "HandleRequest",
+					"verdict":     "error",
+					"body_bytes":  "0",
+					"duration_ms": "1000",
+				},
+			},
+		},
+		{
+			name:  "error message",
+			entry: accesslog.Entry{Time: entryTime, RPC: "HandleResponse", Verdict: "continue", Error: "boom"},
+			want: recordenc.Record{
+				Type:     accesslog.RecordType,
+				Time:     entryTime,
+				Severity: recordenc.SeverityError,
+				Message:  "HandleResponse continue",
+				Attributes: map[string]string{
+					"rpc":         "HandleResponse",
+					"verdict":     "continue",
+					"error":       "boom",
+					"body_bytes":  "0",
+					"duration_ms": "0",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.entry.Record(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Record = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// staticEncoder is a text encoder returning out and err for every batch.
+type staticEncoder struct {
+	out []byte
+	err error
+}
+
+func (staticEncoder) ContentType() string { return recordenc.ContentTypeJSONLines }
+
+func (e staticEncoder) Encode([]recordenc.Record) ([]byte, error) { return e.out, e.err }
+
+func TestEncoded(t *testing.T) {
+	tests := []struct {
+		name    string
+		enc     recordenc.Encoder
+		wantErr string
+	}{
+		{name: "JSON", enc: recordenc.JSON()},
+		{name: "CEF", enc: recordenc.CEF("Acme", "guard", "1.0")},
+		{name: "OTLP", enc: recordenc.OTLPLogs(map[string]string{"service.name": "guard"})},
+		{
+			name:    "protobuf",
+			enc:     recordenc.Protobuf(),
+			wantErr: "application/x-protobuf is not a line-oriented encoding",
+		},
+		{name: "encoding error", enc: staticEncoder{err: errors.New("unencodable")}, wantErr: "unencodable"},
+		{name: "several lines", enc: staticEncoder{out: []byte("a\nb")}, wantErr: "encoded entry spans several lines"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := requestEntry()
+			entry.Error = "line1\nline2" // Encoders must escape it to keep the entry on one line.
+
+			got, err := accesslog.Encoded(tt.enc).Format(entry)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Format error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := tt.enc.Encode([]recordenc.Record{entry.Record()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Format = %s, want %s", got, want)
+			}
+			if bytes.ContainsAny(got, "\r\n") {
+				t.Errorf("Format = %q, want a single line", got)
+			}
+		})
+	}
+}
+
+func TestEncodedLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := accesslog.New(&buf, accesslog.Encoded(recordenc.CEF("", "", "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := l.Log(requestEntry()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %q, want two lines", buf.String())
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "CEF:0|Mozilla.ai|mcpd-plugin|0|call.handled|HandleRequest short_circuit|6|") {
+			t.Errorf("logged line %q, want a CEF warning", line)
+		}
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr string // Error from Template or Format; empty for none.
+	}{
+		{
+			name: "fields",
+			text: `{{.Time.Format "15:04:05"}} {{.Path}} {{.Verdict}} {{.Duration}}`,
+			want: "13:55:36 /mcp short_circuit 1.2ms",
+		},
+		{name: "trailing newlines trimmed", text: "{{.Tool}}\n\n", want: "search"},
+		{name: "invalid template", text: "{{.Path", wantErr: "invalid access log template"},
+		{name: "unknown field", text: "{{.Missing}}", wantErr: "Missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := accesslog.Template(tt.text)
+			var got []byte
+			if err == nil {
+				got, err = f.Format(requestEntry())
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Template(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	if _, err := accesslog.New(nil, accesslog.JSON()); err == nil {
+		t.Error("New accepted a nil writer")
+	}
+	if _, err := accesslog.New(&bytes.Buffer{}, nil); err == nil {
+		t.Error("New accepted a nil format")
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestLoggerLog(t *testing.T) {
+	errFormat := errors.New("bad entry")
+	tests := []struct {
+		name    string
+		format  accesslog.Format
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "one line per entry",
+			format: accesslog.FormatFunc(func(e accesslog.Entry) ([]byte, error) { return []byte(e.RPC), nil }),
+			want:   "HandleRequest\n",
+		},
+		{
+			name:    "format error",
+			format:  accesslog.FormatFunc(func(accesslog.Entry) ([]byte, error) { return nil, errFormat }),
+			wantErr: "failed to format access log entry: bad entry",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			l, err := accesslog.New(&buf, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = l.Log(requestEntry())
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Log error = %v, want %q", err, tt.wantErr)
+				}
+				if !errors.Is(err, errFormat) {
+					t.Errorf("Log error %v does not wrap the format error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("wrote %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggerWriteError(t *testing.T) {
+	l, err := accesslog.New(failingWriter{}, accesslog.JSON())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Log(requestEntry()); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Log error = %v, want the write error", err)
+	}
+}
+
+func TestLoggerConcurrent(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := accesslog.New(&buf, accesslog.Common())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 50
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Log(requestEntry()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	want, _ := accesslog.Common().Format(requestEntry())
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != n {
+		t.Fatalf("wrote %d lines, want %d", len(lines), n)
+	}
+	for _, line := range lines {
+		if line != string(want) {
+			t.Fatalf("interleaved line %q", line)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/accesslog"
+)
+
+// entryLog is an accesslog.Format collecting the entries it is given.
+type entryLog struct {
+	entries []accesslog.Entry
+	err     error
+}
+
+func (l *entryLog) Format(e accesslog.Entry) ([]byte, error) {
+	l.entries = append(l.entries, e)
+	return nil, l.err
+}
+
+func TestWithAccessLog(t *testing.T) {
+	at := time.Date(2025, 10, 10, 13, 55, 36, 0, time.UTC)
+	toolCall := []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search"}}`)
+	tests := []struct {
+		name string
+		ev   Event
+		want *accesslog.Entry // Nil for no entry.
+	}{
+		{
+			name: "request",
+			ev: Event{
+				Method: "HandleRequest",
+				Request: &HTTPRequest{
+					Method:     "POST",
+					Path:       "/mcp",
+					RemoteAddr: "10.0.0.1",
+					Body:       toolCall,
+					Headers:    map[string]string{"X-Request-Id": "req-1"},
+				},
+				Result: &HTTPResponse{Continue: true},
+			},
+			want: &accesslog.Entry{
+				RPC:           "HandleRequest",
+				Method:        "POST",
+				Path:          "/mcp",
+				RemoteAddr:    "10.0.0.1",
+				Tool:          "search",
+				CorrelationID: "req-1",
+				Verdict:       "continue",
+				BodyBytes:     len(toolCall),
+			},
+		},
+		{
+			name: "short-circuited request",
+			ev: Event{
+				Method:  "HandleRequest",
+				Request: &HTTPRequest{Method: "GET", Path: "/"},
+				Result:  &HTTPResponse{StatusCode: 403},
+			},
+			want: &accesslog.Entry{
+				RPC:        "HandleRequest",
+				Method:     "GET",
+				Path:       "/",
+				Verdict:    "short_circuit",
+				StatusCode: 403,
+			},
+		},
+		{
+			name: "response",
+			ev: Event{
+				Method:   "HandleResponse",
+				Response: &HTTPResponse{StatusCode: 200, Body: []byte("hello")},
+				Result:   &HTTPResponse{Continue: true},
+			},
+			want: &accesslog.Entry{RPC: "HandleResponse", Verdict: "continue", StatusCode: 200, BodyBytes: 5},
+		},
+		{
+			name: "rewritten response status",
+			ev: Event{
+				Method:   "HandleResponse",
+				Response: &HTTPResponse{StatusCode: 200},
+				Result:   &HTTPResponse{Continue: true, StatusCode: 502},
+			},
+			want: &accesslog.Entry{RPC: "HandleResponse", Verdict: "continue", StatusCode: 502},
+		},
+		{
+			name: "error",
+			ev: Event{
+				Method:   "HandleResponse",
+				Response: &HTTPResponse{StatusCode: 200},
+				Err:      errors.New("boom"),
+			},
+			want: &accesslog.Entry{RPC: "HandleResponse", Verdict: "error", StatusCode: 200, Error: "boom"},
+		},
+		{
+			name: "other RPCs",
+			ev:   Event{Method: "Configure"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &entryLog{}
+			l, err := accesslog.New(&bytes.Buffer{}, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			o, err := newServeOptions(WithAccessLog(l))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.ev.Kind = EventRequest
+			tt.ev.Time = at
+			tt.ev.Duration = time.Millisecond
+			o.bus.Publish(context.Background(), tt.ev)
+
+			if tt.want == nil {
+				if len(f.entries) != 0 {
+					t.Errorf("logged %+v, want nothing", f.entries)
+				}
+				return
+			}
+			if len(f.entries) != 1 {
+				t.Fatalf("logged %d entries, want 1", len(f.entries))
+			}
+			want := *tt.want
+			want.Time, want.Duration = at, time.Millisecond
+			if f.entries[0] != want {
+				t.Errorf("logged\n%+v\nwant\n%+v", f.entries[0], want)
+			}
+		})
+	}
+}
+
+func TestWithAccessLogErrors(t *testing.T) {
+	if _, err := newServeOptions(WithAccessLog(nil)); err == nil {
+		t.Error("WithAccessLog accepted a nil logger")
+	}
+
+	// Failures to write the access log are reported through the SDK logger.
+	var buf bytes.Buffer
+	l, err := accesslog.New(&bytes.Buffer{}, &entryLog{err: errors.New("bad entry")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := newServeOptions(WithLogger(log.New(&buf, "", 0)), WithAccessLog(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.bus.Publish(context.Background(), Event{Kind: EventRequest, Method: "HandleRequest", Request: &HTTPRequest{}})
+	if got := buf.String(); !strings.Contains(got, "access log: failed to format access log entry: bad entry") {
+		t.Errorf("logged %q, want the access log failure", got)
+	}
+}
@@ -0,0 +1,342 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// AdminServiceName is the gRPC service served on the admin socket by WithAdmin. Its messages are
+// protobuf well-known types, so any gRPC client (including grpcurl with the method descriptions
+// below) can call it without generated code:
+//
+//	GetConfig(google.protobuf.Empty) returns (google.protobuf.Struct)
+//	SetDebug(google.protobuf.BoolValue) returns (google.protobuf.BoolValue)
+//	FlushCaches(google.protobuf.Empty) returns (google.protobuf.Empty)
+//	CheckHealth(google.protobuf.Empty) returns (google.protobuf.Struct)
+//	GetStats(google.protobuf.Empty) returns (google.protobuf.Struct)
+//	GetComponents(google.protobuf.Empty) returns (google.protobuf.Struct)
+const AdminServiceName = "mozilla.mcpd.plugins.v1.Admin"
+
+// Full method names of the admin service methods.
+const (
+	adminGetConfigMethod   = "/" + AdminServiceName + "/GetConfig"
+	adminSetDebugMethod    = "/" + AdminServiceName + "/SetDebug"
+	adminFlushCachesMethod = "/" + AdminServiceName + "/FlushCaches"
+	adminCheckHealthMethod = "/" + AdminServiceName + "/CheckHealth"
+	adminGetStatsMethod    = "/" + AdminServiceName + "/GetStats"
+	adminComponentsMethod  = "/" + AdminServiceName + "/GetComponents"
+)
+
+// Redacted replaces secret custom_config values in the admin service's GetConfig.
+const Redacted = "[REDACTED]"
+
+// CacheFlusher is implemented by plugins holding caches that an operator can flush at runtime
+// through the admin service's FlushCaches.
+type CacheFlusher interface {
+	FlushCaches(ctx context.Context) error
+}
+
+// StatsReporter is implemented by plugins exposing runtime statistics, such as per-tool success
+// rates, through the admin service's GetStats. The map must hold values structpb.NewValue accepts.
+type StatsReporter interface {
+	AdminStats(ctx context.Context) (map[string]any, error)
+}
+
+// WithAdmin serves the admin service, AdminServiceName, on a separate listener so operators can
+// inspect a running plugin without restarting it: GetConfig returns the configuration last applied
+// and its ConfigDigest, with secret-looking custom_config values redacted (see RedactConfigValue);
+// SetDebug toggles debug mode, in which the SDK logs every handler call and plugins implementing
+// DebugToggler are notified; FlushCaches calls the plugin's CacheFlusher; CheckHealth samples
+// WithResourceGuard limits afresh and calls the plugin's CheckHealth; GetStats returns the
+// plugin's StatsReporter statistics; and GetComponents returns a CycloneDX-style component list
+// of the binary, from its Go build information, for supply-chain inventories of deployed plugins.
+//
+// network is "unix" or "tcp". The admin socket grants control over the plugin, so keep it off
+// networks mcpd's clients can reach. Operators can also set it with the --admin-address flag,
+// which uses a unix socket.
+func WithAdmin(network, address string) ServeOption {
+	return func(o *serveOptions) error {
+		if network != "unix" && network != "tcp" {
+			return fmt.Errorf("admin network must be unix or tcp, got %q", network)
+		}
+		if address == "" {
+			return fmt.Errorf("admin address cannot be empty")
+		}
+		o.admin = &adminListener{network: network, address: address}
+		return nil
+	}
+}
+
+// adminListener is where the admin service listens.
+type adminListener struct {
+	network string
+	address string
+}
+
+// secretKeyParts are the custom_config key segments marking a secret value.
+var secretKeyParts = map[string]struct{}{
+	"password": {}, "passwd": {}, "secret": {}, "token": {}, "credential": {}, "credentials": {},
+	"apikey": {}, "auth": {}, "authorization": {}, "private": {},
+}
+
+// RedactConfigValue returns value, or Redacted when the custom_config key looks like it holds a
+// secret: one of its "_", "-" or "." separated segments is password, secret, token, credential(s),
+// apikey, auth, authorization or private, or its last segment is key (as in api_key).
+func RedactConfigValue(key, value string) string {
+	parts := strings.FieldsFunc(strings.ToLower(key), func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	})
+	for i, p := range parts {
+		if _, ok := secretKeyParts[p]; ok || (p == "key" && i == len(parts)-1) {
+			return Redacted
+		}
+	}
+
+	return value
+}
+
+// adminServer implements the admin service for the plugin served by Serve.
+type adminServer struct {
+	o    *serveOptions
+	impl PluginServer
+}
+
+// serve starts the admin service and returns the function stopping it, to be called when the
+// plugin server stops.
+func (a *adminServer) serve(l *adminListener) (func(), error) {
+	lis, err := net.Listen(l.network, l.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for admin on %s %s: %w", l.network, l.address, err)
+	}
+
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(recoveryInterceptor(a.o)))
+	s.RegisterService(&adminServiceDesc, a)
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			a.o.logger.Printf("admin server stopped: %v", err)
+		}
+	}()
+	a.o.logger.Printf("Admin server listening on %s %s", l.network, l.address)
+
+	return func() {
+		s.Stop()
+		if l.network == "unix" {
+			_ = os.Remove(l.address)
+		}
+	}, nil
+}
+
+// GetConfig returns the configuration last applied, redacted, with its digest.
+func (a *adminServer) GetConfig(context.Context, *emptypb.Empty) (*structpb.Struct, error) {
+	cfg := a.o.configDigest.config.Load()
+	if cfg == nil {
+		return nil, status.Error(codes.FailedPrecondition, "no configuration applied yet")
+	}
+
+	custom := make(map[string]any, len(cfg.GetCustomConfig()))
+	for k, v := range cfg.GetCustomConfig() {
+		custom[k] = RedactConfigValue(k, v)
+	}
+	out := map[string]any{
+		"digest":        a.o.configDigest.current(),
+		"custom_config": custom,
+	}
+	if t := cfg.GetTelemetry(); t != nil {
+		out["telemetry"] = map[string]any{
+			"otlp_endpoint": t.GetOtlpEndpoint(),
+			"service_name":  t.GetServiceName(),
+			"environment":   t.GetEnvironment(),
+			"sample_ratio":  t.GetSampleRatio(),
+		}
+	}
+
+	return structpb.NewStruct(out)
+}
+
+// SetDebug switches debug mode and returns the previous mode.
+func (a *adminServer) SetDebug(_ context.Context, in *wrapperspb.BoolValue) (*wrapperspb.BoolValue, error) {
+	return wrapperspb.Bool(a.o.setDebug(a.impl, in.GetValue())), nil
+}
+
+// FlushCaches flushes the plugin's caches.
+func (a *adminServer) FlushCaches(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	f, ok := a.impl.(CacheFlusher)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "plugin has no caches to flush")
+	}
+	if err := f.FlushCaches(ctx); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to flush caches: %v", err)
+	}
+	a.o.logger.Println("caches flushed through the admin service")
+
+	return &emptypb.Empty{}, nil
+}
+
+// CheckHealth re-samples resources and reports them with the plugin's own health check.
+func (a *adminServer) CheckHealth(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	out := map[string]any{"healthy": true}
+	if g := a.o.resources; g != nil {
+		g.sample(a.o)
+		out["resources"] = g.current().String()
+	}
+	u := CurrentResourceUsage()
+	out["memory"], out["goroutines"] = float64(u.Memory), float64(u.Goroutines)
+
+	if _, err := a.impl.CheckHealth(ctx, &emptypb.Empty{}); err != nil {
+		out["healthy"], out["error"] = false, err.Error()
+	} else if a.o.resources != nil && a.o.resources.current() == ResourceExhausted {
+		out["healthy"] = false
+	}
+
+	return structpb.NewStruct(out)
+}
+
+// GetStats returns the plugin's statistics.
+func (a *adminServer) GetStats(ctx context.Context, _ *emptypb.Empty) (*structpb.Struct, error) {
+	r, ok := a.impl.(StatsReporter)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "plugin reports no statistics")
+	}
+	stats, err := r.AdminStats(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to collect statistics: %v", err)
+	}
+	out, err := structpb.NewStruct(stats)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "invalid statistics: %v", err)
+	}
+
+	return out, nil
+}
+
+// GetComponents returns the binary's component list.
+func (a *adminServer) GetComponents(context.Context, *emptypb.Empty) (*structpb.Struct, error) {
+	bom, err := componentList(a.impl)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to list components: %v", err)
+	}
+	out, err := structpb.NewStruct(bom)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "invalid component list: %v", err)
+	}
+
+	return out, nil
+}
+
+// adminServiceDesc describes the admin service to grpc.Server.RegisterService.
+var adminServiceDesc = grpc.ServiceDesc{
+	ServiceName: AdminServiceName,
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "GetConfig", Handler: adminHandler(adminGetConfigMethod, (*adminServer).GetConfig)},
+		{MethodName: "SetDebug", Handler: adminHandler(adminSetDebugMethod, (*adminServer).SetDebug)},
+		{MethodName: "FlushCaches", Handler: adminHandler(adminFlushCachesMethod, (*adminServer).FlushCaches)},
+		{MethodName: "CheckHealth", Handler: adminHandler(adminCheckHealthMethod, (*adminServer).CheckHealth)},
+		{MethodName: "GetStats", Handler: adminHandler(adminGetStatsMethod, (*adminServer).GetStats)},
+		{
+			MethodName: "GetComponents",
+			Handler:    adminHandler(adminComponentsMethod, (*adminServer).GetComponents),
+		},
+	},
+	Metadata: "admin.go",
+}
+
+// adminHandler adapts an admin method to a grpc.MethodDesc handler.
+func adminHandler[In, Out any, PIn interface{ *In }](
+	fullMethod string,
+	method func(*adminServer, context.Context, PIn) (Out, error),
+) func(any, context.Context, func(any) error, grpc.UnaryServerInterceptor) (any, error) {
+	return func(
+		srv any,
+		ctx context.Context,
+		dec func(any) error,
+		interceptor grpc.UnaryServerInterceptor,
+	) (any, error) {
+		in := PIn(new(In))
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return method(srv.(*adminServer), ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+		return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+			return method(srv.(*adminServer), ctx, req.(PIn))
+		})
+	}
+}
+
+// AdminClient calls the admin service of a plugin served WithAdmin.
+type AdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewAdminClient returns an AdminClient using cc, a connection to the plugin's admin socket.
+func NewAdminClient(cc grpc.ClientConnInterface) *AdminClient {
+	return &AdminClient{cc: cc}
+}
+
+// GetConfig returns the plugin's applied configuration, redacted, and its digest.
+func (c *AdminClient) GetConfig(ctx context.Context) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := c.cc.Invoke(ctx, adminGetConfigMethod, &emptypb.Empty{}, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// SetDebug switches the plugin's debug mode and returns the previous mode.
+func (c *AdminClient) SetDebug(ctx context.Context, enabled bool) (bool, error) {
+	out := new(wrapperspb.BoolValue)
+	if err := c.cc.Invoke(ctx, adminSetDebugMethod, wrapperspb.Bool(enabled), out); err != nil {
+		return false, err
+	}
+
+	return out.GetValue(), nil
+}
+
+// FlushCaches flushes the plugin's caches.
+func (c *AdminClient) FlushCaches(ctx context.Context) error {
+	return c.cc.Invoke(ctx, adminFlushCachesMethod, &emptypb.Empty{}, new(emptypb.Empty))
+}
+
+// CheckHealth forces a health re-check and returns its report.
+func (c *AdminClient) CheckHealth(ctx context.Context) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := c.cc.Invoke(ctx, adminCheckHealthMethod, &emptypb.Empty{}, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// GetStats returns the plugin's runtime statistics.
+func (c *AdminClient) GetStats(ctx context.Context) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := c.cc.Invoke(ctx, adminGetStatsMethod, &emptypb.Empty{}, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// GetComponents returns the CycloneDX-style component list of the plugin binary.
+func (c *AdminClient) GetComponents(ctx context.Context) (*structpb.Struct, error) {
+	out := new(structpb.Struct)
+	if err := c.cc.Invoke(ctx, adminComponentsMethod, &emptypb.Empty{}, out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
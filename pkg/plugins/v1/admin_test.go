@@ -0,0 +1,548 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestWithAdmin(t *testing.T) {
+	tests := []struct {
+		name    string
+		network string
+		address string
+		wantErr string
+	}{
+		{name: "unix", network: "unix", address: "/tmp/admin.sock"},
+		{name: "tcp", network: "tcp", address: "127.0.0.1:0"},
+		{name: "udp", network: "udp", address: "127.0.0.1:0", wantErr: `admin network must be unix or tcp, got "udp"`},
+		{name: "empty address", network: "unix", wantErr: "admin address cannot be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(WithAdmin(tt.network, tt.address))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("WithAdmin error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.admin.network != tt.network || o.admin.address != tt.address {
+				t.Errorf("admin listener = %+v, want %s %s", o.admin, tt.network, tt.address)
+			}
+		})
+	}
+}
+
+func TestRedactConfigValue(t *testing.T) {
+	tests := []struct {
+		key        string
+		wantRedact bool
+	}{
+		{key: "password", wantRedact: true},
+		{key: "DB_PASSWORD", wantRedact: true},
+		{key: "upstream.auth.header", wantRedact: true},
+		{key: "client-secret", wantRedact: true},
+		{key: "apikey", wantRedact: true},
+		{key: "api_key", wantRedact: true},
+		{key: "key", wantRedact: true},
+		{key: "github_token", wantRedact: true},
+		{key: "private_pem", wantRedact: true},
+		{key: "aws.credentials", wantRedact: true},
+		{key: "key_prefix"},
+		{key: "monkey"},
+		{key: "tokens_per_minute"},
+		{key: "author"},
+		{key: "mode"},
+		{key: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			want := "value"
+			if tt.wantRedact {
+				want = Redacted
+			}
+			if got := RedactConfigValue(tt.key, "value"); got != want {
+				t.Errorf("RedactConfigValue(%q) = %q, want %q", tt.key, got, want)
+			}
+		})
+	}
+}
+
+// adminPlugin implements the optional admin interfaces, failing each when its error is set.
+type adminPlugin struct {
+	BasePlugin
+
+	mu        sync.Mutex
+	healthErr error
+	flushErr  error
+	stats     map[string]any
+	statsErr  error
+	flushes   int
+	toggles   []bool
+}
+
+func (p *adminPlugin) CheckHealth(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.healthErr != nil {
+		return nil, p.healthErr
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (p *adminPlugin) FlushCaches(context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.flushErr != nil {
+		return p.flushErr
+	}
+	p.flushes++
+
+	return nil
+}
+
+func (p *adminPlugin) AdminStats(context.Context) (map[string]any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.stats, p.statsErr
+}
+
+func (p *adminPlugin) SetDebug(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.toggles = append(p.toggles, enabled)
+}
+
+func (p *adminPlugin) set(fn func(p *adminPlugin)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fn(p)
+}
+
+// serveAdmin serves the admin service for impl on a unix socket and returns a client for it.
+func serveAdmin(t *testing.T, o *serveOptions, impl PluginServer) (*AdminClient, string) {
+	t.Helper()
+
+	address := filepath.Join(t.TempDir(), "admin.sock")
+	stop, err := (&adminServer{o: o, impl: impl}).serve(&adminListener{network: "unix", address: address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(stop)
+	conn, err := grpc.NewClient("unix://"+address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return NewAdminClient(conn), address
+}
+
+func TestAdminGetConfig(t *testing.T) {
+	o, err := newServeOptions(WithLogger(discardLogger()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := serveAdmin(t, o, &adminPlugin{})
+
+	if _, err := c.GetConfig(context.Background()); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("GetConfig before Configure error = %v, want FailedPrecondition", err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  *PluginConfig
+		want map[string]any
+	}{
+		{
+			name: "custom config redacted",
+			cfg:  &PluginConfig{CustomConfig: map[string]string{"mode": "strict", "api_key": "sk-1"}},
+			want: map[string]any{"custom_config": map[string]any{"mode": "strict", "api_key": Redacted}},
+		},
+		{
+			name: "telemetry",
+			cfg: &PluginConfig{Telemetry: &TelemetryConfig{
+				OtlpEndpoint: "http://localhost:4318",
+				ServiceName:  "svc",
+				Environment:  "dev",
+				SampleRatio:  0.5,
+			}},
+			want: map[string]any{
+				"custom_config": map[string]any{},
+				"telemetry": map[string]any{
+					"otlp_endpoint": "http://localhost:4318",
+					"service_name":  "svc",
+					"environment":   "dev",
+					"sample_ratio":  0.5,
+				},
+			},
+		},
+	}
+	intercept := configDigestInterceptor(o)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &grpc.UnaryServerInfo{FullMethod: Plugin_Configure_FullMethodName}
+			if _, err := intercept(context.Background(), tt.cfg, info, func(context.Context, any) (any, error) {
+				return &emptypb.Empty{}, nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := c.GetConfig(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := got.AsMap()
+			if out["digest"] != ConfigDigest(tt.cfg) {
+				t.Errorf("digest = %v, want %s", out["digest"], ConfigDigest(tt.cfg))
+			}
+			delete(out, "digest")
+			if !equalJSONValues(out, tt.want) {
+				t.Errorf("GetConfig = %v, want %v", out, tt.want)
+			}
+		})
+	}
+}
+
+// equalJSONValues reports whether two structpb-compatible values are equal.
+func equalJSONValues(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		m, ok := b.(map[string]any)
+		if !ok || len(a) != len(m) {
+			return false
+		}
+		for k, v := range a {
+			if !equalJSONValues(v, m[k]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func TestAdminSetDebug(t *testing.T) {
+	var logs syncBuffer
+	o, err := newServeOptions(WithLogger(log.New(&logs, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &adminPlugin{}
+	c, _ := serveAdmin(t, o, p)
+
+	steps := []struct {
+		enable   bool
+		wantPrev bool
+		wantLog  string
+	}{
+		{enable: true, wantLog: "debug mode enabled"},
+		{enable: true, wantPrev: true},
+		{enable: false, wantPrev: true, wantLog: "debug mode disabled"},
+		{enable: false},
+	}
+	for i, s := range steps {
+		before := len(logs.String())
+		prev, err := c.SetDebug(context.Background(), s.enable)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prev != s.wantPrev {
+			t.Errorf("step %d: previous = %t, want %t", i, prev, s.wantPrev)
+		}
+		if got := strings.TrimSpace(logs.String()[before:]); got != s.wantLog {
+			t.Errorf("step %d: logged %q, want %q", i, got, s.wantLog)
+		}
+	}
+
+	// The plugin is only told about changes.
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if want := []bool{true, false}; !slices.Equal(p.toggles, want) {
+		t.Errorf("plugin notified %v, want %v", p.toggles, want)
+	}
+}
+
+func TestAdminFlushCaches(t *testing.T) {
+	tests := []struct {
+		name     string
+		impl     PluginServer
+		flushErr error
+		wantCode codes.Code
+	}{
+		{name: "flushed", impl: &adminPlugin{}, wantCode: codes.OK},
+		{name: "flush error", impl: &adminPlugin{}, flushErr: errors.New("busy"), wantCode: codes.Internal},
+		{name: "no caches", impl: &BasePlugin{}, wantCode: codes.Unimplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs syncBuffer
+			o, err := newServeOptions(WithLogger(log.New(&logs, "", 0)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p, ok := tt.impl.(*adminPlugin); ok {
+				p.set(func(p *adminPlugin) { p.flushErr = tt.flushErr })
+			}
+			c, _ := serveAdmin(t, o, tt.impl)
+
+			err = c.FlushCaches(context.Background())
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("FlushCaches error = %v, want %s", err, tt.wantCode)
+			}
+			if tt.flushErr != nil && !strings.Contains(err.Error(), "failed to flush caches: busy") {
+				t.Errorf("FlushCaches error = %v, want the plugin's error", err)
+			}
+			flushed := strings.Contains(logs.String(), "caches flushed through the admin service")
+			if flushed != (tt.wantCode == codes.OK) {
+				t.Errorf("logged %q", logs.String())
+			}
+		})
+	}
+}
+
+func TestAdminCheckHealth(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []ServeOption
+		healthErr     error
+		wantHealthy   bool
+		wantError     string
+		wantResources string
+	}{
+		{name: "healthy", wantHealthy: true},
+		{name: "plugin unhealthy", healthErr: errors.New("upstream down"), wantError: "upstream down"},
+		{
+			name:          "resources ok",
+			opts:          []ServeOption{WithResourceGuard(ResourceLimits{HardGoroutines: math.MaxInt32})},
+			wantHealthy:   true,
+			wantResources: "ok",
+		},
+		{
+			name:          "resources degraded",
+			opts:          []ServeOption{WithResourceGuard(ResourceLimits{SoftGoroutines: 1})},
+			wantHealthy:   true,
+			wantResources: "degraded",
+		},
+		{
+			name:          "resources exhausted",
+			opts:          []ServeOption{WithResourceGuard(ResourceLimits{HardGoroutines: 1})},
+			wantResources: "exhausted",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(append(tt.opts, WithLogger(discardLogger()))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, _ := serveAdmin(t, o, &adminPlugin{healthErr: tt.healthErr})
+
+			got, err := c.CheckHealth(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := got.AsMap()
+			if out["healthy"] != tt.wantHealthy {
+				t.Errorf("healthy = %v, want %t", out["healthy"], tt.wantHealthy)
+			}
+			if e, _ := out["error"].(string); e != tt.wantError {
+				t.Errorf("error = %q, want %q", e, tt.wantError)
+			}
+			if r, _ := out["resources"].(string); r != tt.wantResources {
+				t.Errorf("resources = %q, want %q", r, tt.wantResources)
+			}
+			if g, _ := out["goroutines"].(float64); g < 1 {
+				t.Errorf("goroutines = %v, want a count", out["goroutines"])
+			}
+			if _, ok := out["memory"].(float64); !ok {
+				t.Errorf("memory = %v, want a number", out["memory"])
+			}
+		})
+	}
+}
+
+func TestAdminGetStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		impl     PluginServer
+		stats    map[string]any
+		statsErr error
+		wantCode codes.Code
+		wantErr  string
+	}{
+		{
+			name:     "stats",
+			impl:     &adminPlugin{},
+			stats:    map[string]any{"calls": 3.0, "tools": map[string]any{"search": 0.5}},
+			wantCode: codes.OK,
+		},
+		{name: "no stats", impl: &BasePlugin{}, wantCode: codes.Unimplemented},
+		{
+			name:     "collection error",
+			impl:     &adminPlugin{},
+			statsErr: errors.New("locked"),
+			wantCode: codes.Internal,
+			wantErr:  "failed to collect statistics: locked",
+		},
+		{
+			name:     "invalid value",
+			impl:     &adminPlugin{},
+			stats:    map[string]any{"ch": make(chan int)},
+			wantCode: codes.Internal,
+			wantErr:  "invalid statistics",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(WithLogger(discardLogger()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p, ok := tt.impl.(*adminPlugin); ok {
+				p.set(func(p *adminPlugin) { p.stats, p.statsErr = tt.stats, tt.statsErr })
+			}
+			c, _ := serveAdmin(t, o, tt.impl)
+
+			got, err := c.GetStats(context.Background())
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("GetStats error = %v, want %s", err, tt.wantCode)
+			}
+			if tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("GetStats error = %v, want %q", err, tt.wantErr)
+			}
+			if tt.wantCode == codes.OK && !equalJSONValues(got.AsMap(), tt.stats) {
+				t.Errorf("GetStats = %v, want %v", got.AsMap(), tt.stats)
+			}
+		})
+	}
+}
+
+func TestAdminGetComponents(t *testing.T) {
+	o, err := newServeOptions(WithLogger(discardLogger()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := serveAdmin(t, o, &BasePlugin{})
+
+	got, err := c.GetComponents(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := got.AsMap(); out["bomFormat"] != "CycloneDX" || out["components"] == nil {
+		t.Errorf("GetComponents = %v, want a CycloneDX component list", out)
+	}
+}
+
+func TestAdminServe(t *testing.T) {
+	var logs bytes.Buffer
+	o, err := newServeOptions(WithLogger(log.New(&logs, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &adminServer{o: o, impl: &BasePlugin{}}
+
+	address := filepath.Join(t.TempDir(), "admin.sock")
+	stop, err := a.serve(&adminListener{network: "unix", address: address})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "Admin server listening on unix "+address) {
+		t.Errorf("logged %q, want the admin address", logs.String())
+	}
+
+	// A second server cannot take the same socket.
+	if _, err := a.serve(&adminListener{network: "unix", address: address}); err == nil ||
+		!strings.Contains(err.Error(), "failed to listen for admin on unix") {
+		t.Errorf("serve error = %v, want a listen failure", err)
+	}
+
+	// Stopping removes the socket.
+	stop()
+	if _, err := os.Stat(address); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("socket after stop: %v, want it removed", err)
+	}
+}
+
+func TestDebugLog(t *testing.T) {
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search"}}`
+	req := &HTTPRequest{Method: "POST", Path: "/mcp", Body: []byte(body)}
+	tests := []struct {
+		name    string
+		debug   bool
+		ev      Event
+		wantLog string // Empty when nothing should be logged.
+	}{
+		{
+			name: "off",
+			ev:   Event{Kind: EventRequest, Method: Plugin_HandleRequest_FullMethodName, Request: req},
+		},
+		{
+			name:    "request",
+			debug:   true,
+			ev:      Event{Kind: EventRequest, Method: Plugin_HandleRequest_FullMethodName, Request: req},
+			wantLog: `http_method=POST path="/mcp" tool="search"`,
+		},
+		{
+			name:  "response",
+			debug: true,
+			ev: Event{
+				Kind:     EventRequest,
+				Method:   Plugin_HandleResponse_FullMethodName,
+				Response: &HTTPResponse{StatusCode: 502},
+			},
+			wantLog: "status=502",
+		},
+		{
+			name:    "other method",
+			debug:   true,
+			ev:      Event{Kind: EventRequest, Method: Plugin_CheckHealth_FullMethodName, Err: errors.New("down")},
+			wantLog: "debug " + Plugin_CheckHealth_FullMethodName + ": duration=0s err=down",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			o, err := newServeOptions(WithLogger(log.New(&logs, "", 0)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			o.debug.Store(tt.debug)
+
+			o.bus.Publish(context.Background(), tt.ev)
+			if tt.wantLog == "" {
+				if logs.Len() > 0 {
+					t.Errorf("logged %q with debug mode off", logs.String())
+				}
+				return
+			}
+			if !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("logged %q, want %q", logs.String(), tt.wantLog)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// APIVersionMetadataKey is the gRPC metadata key carrying the plugin API version. mcpd sends its
+// own version in request metadata, and Serve returns ProtoVersion in the response header of
+// GetMetadata so either side can detect skew.
+const APIVersionMetadataKey = "mcpd-plugin-api-version"
+
+// versionSkewInterceptor compares the plugin API version advertised by mcpd with ProtoVersion and
+// logs a warning once per distinct mcpd version when they differ in major or minor version.
+// Without it, fields added by a newer mcpd would be silently dropped by the generated code.
+func versionSkewInterceptor(o *serveOptions) grpc.UnaryServerInterceptor {
+	var seen sync.Map
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if info.FullMethod == Plugin_GetMetadata_FullMethodName {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(APIVersionMetadataKey, ProtoVersion))
+		}
+
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get(APIVersionMetadataKey); len(v) > 0 && v[0] != "" {
+				if _, logged := seen.LoadOrStore(v[0], struct{}{}); !logged {
+					if msg := versionSkew(ProtoVersion, v[0]); msg != "" {
+						o.logger.Print(msg)
+					}
+				}
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// versionSkew describes the mismatch between the SDK's plugin API version and mcpd's, or returns
+// an empty string when they are compatible (patch releases never change the wire format).
+func versionSkew(sdk, host string) string {
+	prefix := fmt.Sprintf(
+		"plugin API version skew: SDK built for plugin API %s, mcpd speaks %s",
+		shortVersion(sdk), shortVersion(host),
+	)
+
+	sdkMajor, sdkMinor, ok1 := parseAPIVersion(sdk)
+	hostMajor, hostMinor, ok2 := parseAPIVersion(host)
+	switch {
+	case !ok1 || !ok2:
+		return prefix + "; unable to compare versions, check that the SDK matches your mcpd release"
+	case sdkMajor != hostMajor:
+		return prefix + "; major versions are incompatible, rebuild the plugin with an SDK for " + shortVersion(host)
+	case hostMinor > sdkMinor:
+		return prefix + "; fields added after " + shortVersion(sdk) +
+			" are dropped, upgrade github.com/mozilla-ai/mcpd-plugins-sdk-go to use them"
+	case hostMinor < sdkMinor:
+		return prefix + "; features added after " + shortVersion(host) + " are not used by this mcpd, upgrade mcpd"
+	default:
+		return ""
+	}
+}
+
+// parseAPIVersion extracts the major and minor numbers from a version such as "v1.2.3" or "1.2".
+func parseAPIVersion(v string) (int, int, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(v, "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return major, minor, true
+}
+
+// shortVersion formats a version as "vMAJOR.MINOR", for log messages.
+func shortVersion(v string) string {
+	if major, minor, ok := parseAPIVersion(v); ok {
+		return fmt.Sprintf("v%d.%d", major, minor)
+	}
+
+	return v
+}
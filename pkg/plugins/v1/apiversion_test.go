@@ -0,0 +1,108 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestVersionSkew(t *testing.T) {
+	tests := []struct {
+		name      string
+		sdk, host string
+		want      string // Substring of the warning; empty when compatible.
+	}{
+		{name: "same version", sdk: "v1.2.0", host: "v1.2.0"},
+		{name: "patch difference", sdk: "v1.2.0", host: "1.2.7"},
+		{name: "major and minor only", sdk: "v1.2.3", host: "v1.2"},
+		{
+			name: "newer mcpd",
+			sdk:  "v1.2.0",
+			host: "v1.4.1",
+			want: "SDK built for plugin API v1.2, mcpd speaks v1.4; fields added after v1.2 are dropped, " +
+				"upgrade github.com/mozilla-ai/mcpd-plugins-sdk-go",
+		},
+		{
+			name: "older mcpd",
+			sdk:  "v1.4.0",
+			host: "v1.2.0",
+			want: "mcpd speaks v1.2; features added after v1.2 are not used by this mcpd, upgrade mcpd",
+		},
+		{
+			name: "major mismatch",
+			sdk:  "v1.2.0",
+			host: "v2.0.0",
+			want: "major versions are incompatible, rebuild the plugin with an SDK for v2.0",
+		},
+		{
+			name: "unparseable host",
+			sdk:  "v1.2.0",
+			host: "latest",
+			want: "SDK built for plugin API v1.2, mcpd speaks latest; unable to compare versions",
+		},
+		{name: "missing minor", sdk: "v1.2.0", host: "v1", want: "unable to compare versions"},
+		{name: "non-numeric minor", sdk: "v1.x", host: "v1.2.0", want: "plugin API v1.x, mcpd speaks v1.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := versionSkew(tt.sdk, tt.host)
+			if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+				t.Errorf("versionSkew(%q, %q) = %q, want %q", tt.sdk, tt.host, got, tt.want)
+			}
+			if got != "" && !strings.HasPrefix(got, "plugin API version skew: ") {
+				t.Errorf("warning %q lacks the skew prefix", got)
+			}
+		})
+	}
+}
+
+func TestVersionSkewInterceptor(t *testing.T) {
+	var buf bytes.Buffer
+	o, err := newServeOptions(WithLogger(log.New(&buf, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	intercept := versionSkewInterceptor(o)
+	handle := func(version, method string) *headerStream {
+		t.Helper()
+		stream := &headerStream{}
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+		if version != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(APIVersionMetadataKey, version))
+		}
+		_, err := intercept(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+			func(context.Context, any) (any, error) { return nil, nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stream
+	}
+
+	s := handle("", Plugin_GetMetadata_FullMethodName)
+	if got := s.header.Get(APIVersionMetadataKey); !slices.Equal(got, []string{ProtoVersion}) {
+		t.Errorf("GetMetadata header = %v, want %s", s.header, ProtoVersion)
+	}
+	if s := handle("", Plugin_HandleRequest_FullMethodName); s.header.Len() != 0 {
+		t.Errorf("HandleRequest header = %v, want none", s.header)
+	}
+
+	handle(ProtoVersion, Plugin_HandleRequest_FullMethodName)
+	if buf.Len() != 0 {
+		t.Errorf("logged %q for a matching version", buf.String())
+	}
+
+	// Each distinct mismatching version is reported once.
+	handle("v9.0.0", Plugin_HandleRequest_FullMethodName)
+	handle("v9.0.0", Plugin_HandleResponse_FullMethodName)
+	handle("v9.1.0", Plugin_HandleRequest_FullMethodName)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "mcpd speaks v9.0") || !strings.Contains(lines[1], "v9.1") {
+		t.Errorf("logged %q, want one warning per mcpd version", lines)
+	}
+}
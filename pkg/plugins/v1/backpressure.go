@@ -0,0 +1,233 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// RetryAfterMetadataKey is the gRPC response trailer carrying, in whole milliseconds, how long mcpd
+// should wait before sending the plugin more traffic after a throttled call. The same delay is
+// attached to the status as an errdetails.RetryInfo detail.
+const RetryAfterMetadataKey = "mcpd-retry-after-ms"
+
+// Reasons a call was throttled, recorded as the metrics.LabelReason label of metrics.Throttled.
+const (
+	ThrottleInFlight    = "in_flight"
+	ThrottleLatency     = "latency"
+	ThrottleResources   = "resources"
+	ThrottleConcurrency = "concurrency"
+	ThrottleQueue       = "queue"
+)
+
+// Throttle returns the error a handler returns to ask mcpd to shed load to the plugin: a
+// codes.ResourceExhausted status carrying retryAfter as RetryInfo, with retryAfter also set in
+// the RetryAfterMetadataKey trailer of the call in ctx.
+//
+// Usage:
+//
+//	if upstreamSaturated() {
+//	    return nil, mcpdpluginsv1.Throttle(ctx, 2*time.Second, "upstream saturated")
+//	}
+func Throttle(ctx context.Context, retryAfter time.Duration, msg string) error {
+	if retryAfter < 0 {
+		retryAfter = 0
+	}
+	ms := strconv.FormatInt(retryAfter.Milliseconds(), 10)
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(RetryAfterMetadataKey, ms))
+
+	st := status.New(codes.ResourceExhausted, msg)
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+		return detailed.Err()
+	}
+
+	return st.Err()
+}
+
+// RetryAfter returns the delay carried by a throttled call's error, as produced by Throttle.
+// It reports false for errors that are not codes.ResourceExhausted or carry no delay.
+func RetryAfter(err error) (time.Duration, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.ResourceExhausted {
+		return 0, false
+	}
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok && ri.GetRetryDelay() != nil {
+			return ri.GetRetryDelay().AsDuration(), true
+		}
+	}
+
+	return 0, false
+}
+
+// BackpressureConfig configures WithBackpressure. A zero MaxInFlight or LatencySLO disables that
+// check.
+type BackpressureConfig struct {
+	// MaxInFlight is the number of concurrent HandleRequest and HandleResponse calls above which
+	// new calls are throttled.
+	MaxInFlight int
+
+	// LatencySLO is the handler latency calls are expected to stay within. When fewer than
+	// LatencyTarget of the calls completed in the last Window met it, new calls are throttled
+	// for the following Window.
+	LatencySLO time.Duration
+
+	// LatencyTarget is the fraction of calls that must meet LatencySLO (default 0.95).
+	LatencyTarget float64
+
+	// Window is the period over which latencies are evaluated (default 10s).
+	Window time.Duration
+
+	// MinSamples is the number of calls a Window needs before LatencySLO is evaluated (default 20).
+	MinSamples int
+
+	// RetryAfter is the delay suggested to mcpd in throttled responses (default 1s).
+	RetryAfter time.Duration
+}
+
+// WithBackpressure makes Serve throttle HandleRequest and HandleResponse calls with Throttle
+// while the plugin is overloaded, either by too many calls in flight or by latencies breaching
+// cfg.LatencySLO, so mcpd can shed load gracefully instead of waiting on a saturated plugin.
+// Throttled calls are counted as metrics.Throttled when WithMetrics is configured. Calls
+// rejected by WithResourceGuard carry the same signal.
+func WithBackpressure(cfg BackpressureConfig) ServeOption {
+	return func(o *serveOptions) error {
+		if cfg.MaxInFlight == 0 && cfg.LatencySLO == 0 {
+			return fmt.Errorf("backpressure needs MaxInFlight or LatencySLO")
+		}
+		if cfg.MaxInFlight < 0 || cfg.LatencySLO < 0 || cfg.Window < 0 ||
+			cfg.MinSamples < 0 || cfg.RetryAfter < 0 {
+			return fmt.Errorf("backpressure settings cannot be negative")
+		}
+		if cfg.LatencyTarget < 0 || cfg.LatencyTarget > 1 {
+			return fmt.Errorf("latency target must be between 0 and 1, got %v", cfg.LatencyTarget)
+		}
+		if cfg.LatencyTarget == 0 {
+			cfg.LatencyTarget = 0.95
+		}
+		if cfg.Window == 0 {
+			cfg.Window = 10 * time.Second
+		}
+		if cfg.MinSamples == 0 {
+			cfg.MinSamples = 20
+		}
+		if cfg.RetryAfter == 0 {
+			cfg.RetryAfter = time.Second
+		}
+		o.backpressure = &backpressure{cfg: cfg}
+		return nil
+	}
+}
+
+// backpressure tracks in-flight calls and windowed latencies.
+type backpressure struct {
+	cfg      BackpressureConfig
+	inFlight atomic.Int64
+
+	mu       sync.Mutex
+	start    time.Time
+	total    int
+	slow     int
+	breached bool
+}
+
+// interceptor throttles handler calls while the plugin is overloaded.
+func (b *backpressure) interceptor(o *serveOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !isHandlerMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		n := b.inFlight.Add(1)
+		defer b.inFlight.Add(-1)
+		if b.cfg.MaxInFlight > 0 && n > int64(b.cfg.MaxInFlight) {
+			return nil, o.throttle(ctx, info, b.cfg.RetryAfter, ThrottleInFlight)
+		}
+		if b.cfg.LatencySLO > 0 && b.overSLO(time.Now()) {
+			return nil, o.throttle(ctx, info, b.cfg.RetryAfter, ThrottleLatency)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		if b.cfg.LatencySLO > 0 {
+			b.observe(start, time.Since(start))
+		}
+
+		return resp, err
+	}
+}
+
+// overSLO reports whether the last complete window breached the latency SLO.
+func (b *backpressure) overSLO(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rotate(now)
+	return b.breached
+}
+
+// observe records the latency of a call that started at start.
+func (b *backpressure) observe(start time.Time, d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rotate(start.Add(d))
+	b.total++
+	if d > b.cfg.LatencySLO {
+		b.slow++
+	}
+}
+
+// rotate closes the current window once it has lasted cfg.Window, deciding whether the window
+// that follows is throttled. A throttled window admits no calls, so it closes without samples and
+// the one after it admits traffic again. After an idle gap longer than a window, the last
+// complete window was empty and nothing is throttled.
+func (b *backpressure) rotate(now time.Time) {
+	if b.start.IsZero() {
+		b.start = now
+		return
+	}
+	elapsed := now.Sub(b.start)
+	if elapsed < b.cfg.Window {
+		return
+	}
+
+	b.breached = elapsed < 2*b.cfg.Window && b.total >= b.cfg.MinSamples &&
+		float64(b.total-b.slow) < b.cfg.LatencyTarget*float64(b.total)
+	b.start, b.total, b.slow = now, 0, 0
+}
+
+// throttle records a throttled call and returns its Throttle error.
+func (o *serveOptions) throttle(
+	ctx context.Context,
+	info *grpc.UnaryServerInfo,
+	retryAfter time.Duration,
+	reason string,
+) error {
+	if r := o.metricsRecorder(); r != nil {
+		r.Count(metrics.Throttled, 1,
+			metrics.L(metrics.LabelMethod, path.Base(info.FullMethod)),
+			metrics.L(metrics.LabelReason, reason),
+		)
+	}
+
+	return Throttle(ctx, retryAfter, "plugin overloaded: "+reason)
+}
+
+// isHandlerMethod reports whether fullMethod is HandleRequest or HandleResponse.
+func isHandlerMethod(fullMethod string) bool {
+	return fullMethod == Plugin_HandleRequest_FullMethodName || fullMethod == Plugin_HandleResponse_FullMethodName
+}
@@ -0,0 +1,349 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+func TestThrottle(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter time.Duration
+		wantMS     string
+		want       time.Duration
+	}{
+		{name: "seconds", retryAfter: 2 * time.Second, wantMS: "2000", want: 2 * time.Second},
+		{name: "sub-millisecond", retryAfter: 1500 * time.Microsecond, wantMS: "1", want: 1500 * time.Microsecond},
+		{name: "negative", retryAfter: -time.Second, wantMS: "0", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &headerStream{}
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+
+			err := Throttle(ctx, tt.retryAfter, "upstream saturated")
+			st := status.Convert(err)
+			if st.Code() != codes.ResourceExhausted || st.Message() != "upstream saturated" {
+				t.Errorf("status = %s %q, want ResourceExhausted", st.Code(), st.Message())
+			}
+			if got := stream.trailer.Get(RetryAfterMetadataKey); !slices.Equal(got, []string{tt.wantMS}) {
+				t.Errorf("%s trailer = %q, want %s", RetryAfterMetadataKey, got, tt.wantMS)
+			}
+			if d, ok := RetryAfter(err); !ok || d != tt.want {
+				t.Errorf("RetryAfter = %s, %t; want %s", d, ok, tt.want)
+			}
+		})
+	}
+
+	// Outside a gRPC call there is no trailer to set, but the status still carries the delay.
+	if d, ok := RetryAfter(Throttle(context.Background(), time.Second, "busy")); !ok || d != time.Second {
+		t.Errorf("RetryAfter without a stream = %s, %t", d, ok)
+	}
+}
+
+func TestRetryAfterNotThrottled(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "nil", err: nil},
+		{name: "plain error", err: errors.New("boom")},
+		{name: "other code", err: status.Error(codes.Unavailable, "down")},
+		{name: "no retry info", err: status.Error(codes.ResourceExhausted, "quota")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if d, ok := RetryAfter(tt.err); ok {
+				t.Errorf("RetryAfter = %s, true; want false", d)
+			}
+		})
+	}
+}
+
+func TestWithBackpressure(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     BackpressureConfig
+		want    BackpressureConfig
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			cfg:  BackpressureConfig{LatencySLO: 50 * time.Millisecond},
+			want: BackpressureConfig{
+				LatencySLO:    50 * time.Millisecond,
+				LatencyTarget: 0.95,
+				Window:        10 * time.Second,
+				MinSamples:    20,
+				RetryAfter:    time.Second,
+			},
+		},
+		{
+			name: "explicit",
+			cfg: BackpressureConfig{
+				MaxInFlight:   4,
+				LatencyTarget: 1,
+				Window:        time.Second,
+				MinSamples:    1,
+				RetryAfter:    time.Hour,
+			},
+			want: BackpressureConfig{
+				MaxInFlight:   4,
+				LatencyTarget: 1,
+				Window:        time.Second,
+				MinSamples:    1,
+				RetryAfter:    time.Hour,
+			},
+		},
+		{
+			name:    "no check",
+			cfg:     BackpressureConfig{RetryAfter: time.Second},
+			wantErr: "needs MaxInFlight or LatencySLO",
+		},
+		{name: "negative in flight", cfg: BackpressureConfig{MaxInFlight: -1}, wantErr: "cannot be negative"},
+		{
+			name:    "negative window",
+			cfg:     BackpressureConfig{MaxInFlight: 1, Window: -time.Second},
+			wantErr: "cannot be negative",
+		},
+		{
+			name:    "negative retry",
+			cfg:     BackpressureConfig{MaxInFlight: 1, RetryAfter: -time.Second},
+			wantErr: "cannot be negative",
+		},
+		{
+			name:    "target above one",
+			cfg:     BackpressureConfig{LatencySLO: time.Second, LatencyTarget: 1.5},
+			wantErr: "latency target must be between 0 and 1, got 1.5",
+		},
+		{
+			name:    "negative target",
+			cfg:     BackpressureConfig{LatencySLO: time.Second, LatencyTarget: -0.1},
+			wantErr: "latency target must be between 0 and 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(WithBackpressure(tt.cfg))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("newServeOptions error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := o.backpressure.cfg; got != tt.want {
+				t.Errorf("config = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackpressureWindows(t *testing.T) {
+	cfg := BackpressureConfig{
+		LatencySLO:    10 * time.Millisecond,
+		LatencyTarget: 0.5,
+		Window:        time.Second,
+		MinSamples:    4,
+	}
+	t0 := time.Unix(1_700_000_000, 0)
+	const fast, slow = time.Millisecond, 100 * time.Millisecond
+
+	tests := []struct {
+		name      string
+		latencies []time.Duration // Of calls starting 10ms apart from the window start.
+		lastAt    time.Duration   // When set, the start of the last call.
+		check     []time.Duration // When overSLO is checked; all but the last must report a breach.
+		want      bool
+	}{
+		{
+			name:      "window still open",
+			latencies: []time.Duration{slow, slow, slow, slow},
+			check:     []time.Duration{500 * time.Millisecond},
+		},
+		{
+			name:      "breached window",
+			latencies: []time.Duration{slow, slow, slow, fast},
+			check:     []time.Duration{1100 * time.Millisecond},
+			want:      true,
+		},
+		{
+			name:      "target met",
+			latencies: []time.Duration{slow, slow, fast, fast},
+			check:     []time.Duration{1100 * time.Millisecond},
+		},
+		{
+			name:      "too few samples",
+			latencies: []time.Duration{slow, slow, slow},
+			check:     []time.Duration{1100 * time.Millisecond},
+		},
+		{
+			name:      "throttled window admits traffic after it",
+			latencies: []time.Duration{slow, slow, slow, slow},
+			check:     []time.Duration{1100 * time.Millisecond, 2200 * time.Millisecond},
+		},
+		{
+			name:      "completion time decides the window",
+			latencies: []time.Duration{slow, slow, slow, slow},
+			lastAt:    950 * time.Millisecond,
+			check:     []time.Duration{1100 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &backpressure{cfg: cfg}
+			b.rotate(t0)
+			for i, d := range tt.latencies {
+				start := time.Duration(i) * 10 * time.Millisecond
+				if i == len(tt.latencies)-1 && tt.lastAt > 0 {
+					start = tt.lastAt
+				}
+				b.observe(t0.Add(start), d)
+			}
+			last := len(tt.check) - 1
+			for _, at := range tt.check[:last] {
+				if !b.overSLO(t0.Add(at)) {
+					t.Fatalf("window before %s not throttled", at)
+				}
+			}
+			if got := b.overSLO(t0.Add(tt.check[last])); got != tt.want {
+				t.Errorf("overSLO = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackpressureIdleGap(t *testing.T) {
+	b := &backpressure{cfg: BackpressureConfig{
+		LatencySLO:    time.Millisecond,
+		LatencyTarget: 1,
+		Window:        time.Second,
+		MinSamples:    1,
+	}}
+	t0 := time.Unix(1_700_000_000, 0)
+	b.rotate(t0)
+	b.observe(t0, time.Second/2)
+
+	// The slow window ended long ago; the last complete window was empty.
+	if b.overSLO(t0.Add(time.Hour)) {
+		t.Error("throttled after an idle gap")
+	}
+}
+
+// blockingHandler is a handler whose calls wait for release.
+type blockingHandler struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingHandler) handle(context.Context, any) (any, error) {
+	h.started <- struct{}{}
+	<-h.release
+	return &HTTPResponse{Continue: true}, nil
+}
+
+func TestBackpressureInFlight(t *testing.T) {
+	r := &recorderLog{}
+	cfg := BackpressureConfig{MaxInFlight: 2, RetryAfter: 3 * time.Second}
+	o, err := newServeOptions(WithBackpressure(cfg), WithMetrics(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	intercept := o.backpressure.interceptor(o)
+	info := &grpc.UnaryServerInfo{FullMethod: Plugin_HandleRequest_FullMethodName}
+	h := &blockingHandler{started: make(chan struct{}), release: make(chan struct{})}
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := intercept(context.Background(), &HTTPRequest{}, info, h.handle); err != nil {
+				t.Errorf("admitted call failed: %v", err)
+			}
+		}()
+		<-h.started
+	}
+
+	_, err = intercept(context.Background(), &HTTPRequest{}, info, h.handle)
+	if d, ok := RetryAfter(err); !ok || d != 3*time.Second {
+		t.Errorf("third call error = %v, want a 3s throttle", err)
+	}
+	want := []string{"count throttled 1 method=HandleRequest reason=" + ThrottleInFlight}
+	if got := r.named(metrics.Throttled); !slices.Equal(got, want) {
+		t.Errorf("recorded %q, want %q", got, want)
+	}
+
+	// Other RPCs are neither limited nor counted.
+	configured := false
+	configure := &grpc.UnaryServerInfo{FullMethod: Plugin_Configure_FullMethodName}
+	_, err = intercept(context.Background(), &PluginConfig{}, configure, func(context.Context, any) (any, error) {
+		configured = true
+		return nil, nil
+	})
+	if err != nil || !configured {
+		t.Errorf("Configure = %v, called %t; want it passed through", err, configured)
+	}
+
+	close(h.release)
+	wg.Wait()
+	if n := o.backpressure.inFlight.Load(); n != 0 {
+		t.Errorf("in flight = %d after the calls returned, want 0", n)
+	}
+	go func() { <-h.started }()
+	if _, err := intercept(context.Background(), &HTTPRequest{}, info, h.handle); err != nil {
+		t.Errorf("call after the others returned: %v", err)
+	}
+}
+
+func TestBackpressureLatency(t *testing.T) {
+	r := &recorderLog{}
+	o, err := newServeOptions(WithBackpressure(BackpressureConfig{
+		LatencySLO: time.Millisecond,
+		Window:     time.Hour,
+		MinSamples: 1,
+	}), WithMetrics(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := o.backpressure
+	intercept := b.interceptor(o)
+	info := &grpc.UnaryServerInfo{FullMethod: Plugin_HandleResponse_FullMethodName}
+	slow := func(context.Context, any) (any, error) {
+		time.Sleep(5 * time.Millisecond)
+		return &HTTPResponse{Continue: true}, nil
+	}
+
+	if _, err := intercept(context.Background(), &HTTPResponse{}, info, slow); err != nil {
+		t.Fatal(err)
+	}
+	b.mu.Lock()
+	if b.total != 1 || b.slow != 1 {
+		t.Errorf("window has %d calls, %d slow; want 1 slow call", b.total, b.slow)
+	}
+	// End the window.
+	b.start = b.start.Add(-time.Hour)
+	b.mu.Unlock()
+
+	_, err = intercept(context.Background(), &HTTPResponse{}, info, slow)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("call after a breached window = %v, want throttled", err)
+	}
+	want := []string{"count throttled 1 method=HandleResponse reason=" + ThrottleLatency}
+	if got := r.named(metrics.Throttled); !slices.Equal(got, want) {
+		t.Errorf("recorded %q, want %q", got, want)
+	}
+}
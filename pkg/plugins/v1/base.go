@@ -19,6 +19,13 @@ import (
 //   - HandleRequest: passes through unchanged (continue=true)
 //   - HandleResponse: passes through unchanged (continue=true)
 //
+// The handlers accept nil messages, and messages with nil header maps or empty fields, passing
+// them through as empty: Serve never delivers nil messages to a plugin (see the nil handling
+// documented there), but plugins calling the defaults directly may.
+//
+// RPCs added to the plugin API after a plugin was built are answered by the embedded
+// UnimplementedPluginServer with codes.Unimplemented.
+//
 // Usage:
 //
 //	import (
@@ -62,7 +69,8 @@ func (b *BasePlugin) GetMetadata(ctx context.Context, _ *emptypb.Empty) (*Metada
 	return &Metadata{}, nil
 }
 
-// GetCapabilities returns no flows by default. Plugins should override this.
+// GetCapabilities returns no flows by default. Plugins should override this, typically
+// returning NewCapabilities with the flows they handle.
 func (b *BasePlugin) GetCapabilities(ctx context.Context, _ *emptypb.Empty) (*Capabilities, error) {
 	return &Capabilities{}, nil
 }
@@ -77,22 +85,22 @@ func (b *BasePlugin) CheckReady(ctx context.Context, _ *emptypb.Empty) (*emptypb
 	return &emptypb.Empty{}, nil
 }
 
-// HandleRequest passes through the request unchanged with continue=true.
+// HandleRequest passes through the request unchanged with continue=true, also for a nil req.
 func (b *BasePlugin) HandleRequest(ctx context.Context, req *HTTPRequest) (*HTTPResponse, error) {
 	return &HTTPResponse{
 		Continue:   true,
 		StatusCode: 0,
-		Headers:    req.Headers,
-		Body:       req.Body,
+		Headers:    req.GetHeaders(),
+		Body:       req.GetBody(),
 	}, nil
 }
 
-// HandleResponse passes through the response unchanged with continue=true.
+// HandleResponse passes through the response unchanged with continue=true, also for a nil resp.
 func (b *BasePlugin) HandleResponse(ctx context.Context, resp *HTTPResponse) (*HTTPResponse, error) {
 	return &HTTPResponse{
 		Continue:   true,
-		StatusCode: resp.StatusCode,
-		Headers:    resp.Headers,
-		Body:       resp.Body,
+		StatusCode: resp.GetStatusCode(),
+		Headers:    resp.GetHeaders(),
+		Body:       resp.GetBody(),
 	}, nil
 }
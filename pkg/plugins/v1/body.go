@@ -0,0 +1,103 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+)
+
+// BodyRequiredMetadataKey is the gRPC response trailer set to "true" on a call that failed because
+// the handler needed the body mcpd omitted, so mcpd can resend the call with its body.
+const BodyRequiredMetadataKey = "mcpd-body-required"
+
+// BodyNotRequestedReason is the errdetails.ErrorInfo reason of the status reporting such a call.
+const BodyNotRequestedReason = "BODY_NOT_REQUESTED"
+
+// ErrBodyNotRequested is returned by Body when mcpd omitted the body of the call because the
+// plugin is served WithHeadersOnly.
+var ErrBodyNotRequested = errors.New("body not requested")
+
+// WithHeadersOnly declares that the plugin only inspects headers, so mcpd can skip sending request
+// and response bodies to it. Serve advertises features.HeadersOnly and, on calls where mcpd agreed
+// to it, reports ErrBodyNotRequested returned by a handler to mcpd as a codes.FailedPrecondition
+// status with BodyNotRequestedReason and the BodyRequiredMetadataKey trailer, rather than as a
+// plugin failure.
+//
+// Handlers that may still need the body read it with Body. A reply whose Body is left empty keeps
+// the original body, so handlers can rewrite headers as usual.
+func WithHeadersOnly() ServeOption {
+	return func(o *serveOptions) error {
+		o.headersOnly = true
+		return nil
+	}
+}
+
+// BodyOmitted reports whether mcpd omitted the bodies of the current call, because the plugin is
+// served WithHeadersOnly or is a FieldSubscriber without FieldBody.
+func BodyOmitted(ctx context.Context) bool {
+	if features.Enabled(ctx, features.HeadersOnly) {
+		return true
+	}
+	fields, ok := SubscribedFields(ctx)
+
+	return ok && !fields.Has(FieldBody)
+}
+
+// Body returns the body of m, an HTTPRequest or HTTPResponse of the current call, or
+// ErrBodyNotRequested when mcpd omitted it. Returning that error from the handler makes mcpd
+// resend the call with its body.
+//
+// Usage:
+//
+//	body, err := mcpdpluginsv1.Body(ctx, req)
+//	if err != nil {
+//	    return nil, err
+//	}
+func Body(ctx context.Context, m interface{ GetBody() []byte }) ([]byte, error) {
+	if BodyOmitted(ctx) {
+		return nil, ErrBodyNotRequested
+	}
+
+	return m.GetBody(), nil
+}
+
+// features returns the optional features the plugin adds to SupportedFeatures, given the fields
+// it subscribes to.
+func (o *serveOptions) features(fields FieldSet) []features.Feature {
+	var fs []features.Feature
+	if o.headersOnly {
+		fs = append(fs, features.HeadersOnly)
+	}
+	if fields != nil {
+		fs = append(fs, features.SelectiveFields)
+	}
+
+	return fs
+}
+
+// bodyInterceptor reports handler calls that needed an omitted body to mcpd.
+func bodyInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err == nil || !isHandlerMethod(info.FullMethod) || !BodyOmitted(ctx) ||
+			!errors.Is(err, ErrBodyNotRequested) {
+			return resp, err
+		}
+
+		_ = grpc.SetTrailer(ctx, metadata.Pairs(BodyRequiredMetadataKey, "true"))
+		st := status.New(codes.FailedPrecondition, err.Error())
+		detail := &errdetails.ErrorInfo{Reason: BodyNotRequestedReason, Domain: "mcpd"}
+		if detailed, derr := st.WithDetails(detail); derr == nil {
+			return nil, detailed.Err()
+		}
+
+		return nil, st.Err()
+	}
+}
@@ -0,0 +1,187 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+)
+
+// bodyContext returns a call context with the features and selected fields of a call.
+func bodyContext(t *testing.T, fs features.Set, fields ...RequestField) context.Context {
+	t.Helper()
+
+	ctx := features.NewContext(context.Background(), fs)
+	if fields != nil {
+		s, err := NewFieldSet(fields...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx = context.WithValue(ctx, fieldsContextKey{}, s)
+	}
+
+	return ctx
+}
+
+func TestBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		features features.Set
+		fields   []RequestField
+		omitted  bool
+	}{
+		{name: "full call"},
+		{name: "other features", features: features.NewSet(features.BatchRPC)},
+		{name: "headers only", features: features.NewSet(features.HeadersOnly), omitted: true},
+		{
+			name:    "fields without the body",
+			fields:  []RequestField{FieldMethod, HeaderField("Authorization")},
+			omitted: true,
+		},
+		{name: "fields with the body", fields: []RequestField{FieldMethod, FieldBody}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := bodyContext(t, tt.features, tt.fields...)
+			if got := BodyOmitted(ctx); got != tt.omitted {
+				t.Errorf("BodyOmitted = %t, want %t", got, tt.omitted)
+			}
+
+			for _, m := range []interface{ GetBody() []byte }{
+				&HTTPRequest{Body: []byte("req")},
+				&HTTPResponse{Body: []byte("resp")},
+			} {
+				body, err := Body(ctx, m)
+				if tt.omitted {
+					if !errors.Is(err, ErrBodyNotRequested) || body != nil {
+						t.Errorf("Body = %q, %v; want ErrBodyNotRequested", body, err)
+					}
+					continue
+				}
+				if err != nil || string(body) != string(m.GetBody()) {
+					t.Errorf("Body = %q, %v; want %q", body, err, m.GetBody())
+				}
+			}
+		})
+	}
+}
+
+func TestServeOptionsFeatures(t *testing.T) {
+	fields, err := NewFieldSet(FieldMethod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		opts   []ServeOption
+		fields FieldSet
+		want   []features.Feature
+	}{
+		{name: "none"},
+		{name: "headers only", opts: []ServeOption{WithHeadersOnly()}, want: []features.Feature{features.HeadersOnly}},
+		{name: "field subscriber", fields: fields, want: []features.Feature{features.SelectiveFields}},
+		{
+			name:   "both",
+			opts:   []ServeOption{WithHeadersOnly()},
+			fields: fields,
+			want:   []features.Feature{features.HeadersOnly, features.SelectiveFields},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := o.features(tt.fields); !slices.Equal(got, tt.want) {
+				t.Errorf("features = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBodyInterceptor(t *testing.T) {
+	omitted := features.NewSet(features.HeadersOnly)
+	tests := []struct {
+		name        string
+		features    features.Set
+		method      string
+		err         error
+		wantCode    codes.Code
+		wantTrailer bool
+	}{
+		{name: "success", features: omitted, method: Plugin_HandleRequest_FullMethodName},
+		{
+			name:        "body needed",
+			features:    omitted,
+			method:      Plugin_HandleRequest_FullMethodName,
+			err:         ErrBodyNotRequested,
+			wantCode:    codes.FailedPrecondition,
+			wantTrailer: true,
+		},
+		{
+			name:        "wrapped on a response",
+			features:    omitted,
+			method:      Plugin_HandleResponse_FullMethodName,
+			err:         fmt.Errorf("read body: %w", ErrBodyNotRequested),
+			wantCode:    codes.FailedPrecondition,
+			wantTrailer: true,
+		},
+		{
+			name:     "other error",
+			features: omitted,
+			method:   Plugin_HandleRequest_FullMethodName,
+			err:      status.Error(codes.PermissionDenied, "no"),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "body was sent",
+			method:   Plugin_HandleRequest_FullMethodName,
+			err:      ErrBodyNotRequested,
+			wantCode: codes.Unknown,
+		},
+		{
+			name:     "not a handler",
+			features: omitted,
+			method:   Plugin_Configure_FullMethodName,
+			err:      ErrBodyNotRequested,
+			wantCode: codes.Unknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &headerStream{}
+			ctx := grpc.NewContextWithServerTransportStream(bodyContext(t, tt.features), stream)
+			_, err := bodyInterceptor()(ctx, &HTTPRequest{}, &grpc.UnaryServerInfo{FullMethod: tt.method},
+				func(context.Context, any) (any, error) { return &HTTPResponse{Continue: true}, tt.err })
+
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("code = %s, want %s", got, tt.wantCode)
+			}
+			trailer := stream.trailer.Get(BodyRequiredMetadataKey)
+			if tt.wantTrailer != slices.Equal(trailer, []string{"true"}) {
+				t.Errorf("%s trailer = %q, want set %t", BodyRequiredMetadataKey, trailer, tt.wantTrailer)
+			}
+			if !tt.wantTrailer {
+				return
+			}
+			var info *errdetails.ErrorInfo
+			for _, d := range status.Convert(err).Details() {
+				if ei, ok := d.(*errdetails.ErrorInfo); ok {
+					info = ei
+				}
+			}
+			if info.GetReason() != BodyNotRequestedReason || info.GetDomain() != "mcpd" {
+				t.Errorf("ErrorInfo = %v, want reason %s", info, BodyNotRequestedReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+)
+
+// Backend stores cached entries. Implementations must be safe for concurrent use.
+type Backend interface {
+	// Get returns the value stored under key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set stores value under key, expiring after ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryBackend is a Backend kept in process memory, evicting the least recently used entries
+// beyond its capacity.
+type MemoryBackend struct {
+	max   int
+	clock clock.Clock
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryBackend returns a MemoryBackend holding up to maxEntries entries (unbounded when zero).
+func NewMemoryBackend(maxEntries int) *MemoryBackend {
+	return &MemoryBackend{
+		max:     maxEntries,
+		clock:   clock.Real(),
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// Get implements Backend.
+func (b *MemoryBackend) Get(_ context.Context, key string) ([]byte, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	el, ok := b.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*memoryEntry)
+	if !b.clock.Now().Before(e.expires) {
+		b.order.Remove(el)
+		delete(b.entries, key)
+		return nil, false, nil
+	}
+	b.order.MoveToFront(el)
+
+	return e.value, true, nil
+}
+
+// Set implements Backend.
+func (b *MemoryBackend) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e := &memoryEntry{key: key, value: value, expires: b.clock.Now().Add(ttl)}
+	if el, ok := b.entries[key]; ok {
+		el.Value = e
+		b.order.MoveToFront(el)
+		return nil
+	}
+	b.entries[key] = b.order.PushFront(e)
+	for b.max > 0 && b.order.Len() > b.max {
+		oldest := b.order.Back()
+		b.order.Remove(oldest)
+		delete(b.entries, oldest.Value.(*memoryEntry).key)
+	}
+
+	return nil
+}
+
+// Delete implements Backend.
+func (b *MemoryBackend) Delete(_ context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if el, ok := b.entries[key]; ok {
+		b.order.Remove(el)
+		delete(b.entries, key)
+	}
+
+	return nil
+}
+
+// Len returns the number of entries held, including expired ones not yet evicted.
+func (b *MemoryBackend) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.order.Len()
+}
+
+// tiered is a read-through Backend over a local and a shared backend.
+type tiered struct {
+	local    Backend
+	shared   Backend
+	localTTL time.Duration
+}
+
+// ReadThrough returns a Backend that serves reads from local when possible and otherwise from
+// shared, copying hits into local for at most localTTL so hot entries avoid a network round trip.
+// Writes and deletes go to both. Entries may therefore outlive a delete on other instances by up
+// to localTTL; keep it short when that matters.
+func ReadThrough(local, shared Backend, localTTL time.Duration) Backend {
+	return &tiered{local: local, shared: shared, localTTL: localTTL}
+}
+
+func (t *tiered) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if v, ok, err := t.local.Get(ctx, key); err == nil && ok {
+		return v, true, nil
+	}
+
+	v, ok, err := t.shared.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	_ = t.local.Set(ctx, key, v, t.localTTL)
+
+	return v, true, nil
+}
+
+func (t *tiered) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_ = t.local.Set(ctx, key, value, min(ttl, t.localTTL))
+	return t.shared.Set(ctx, key, value, ttl)
+}
+
+func (t *tiered) Delete(ctx context.Context, key string) error {
+	_ = t.local.Delete(ctx, key)
+	return t.shared.Delete(ctx, key)
+}
+
+// Close closes the shared backend if it holds connections.
+func (t *tiered) Close() error {
+	if c, ok := t.shared.(interface{ Close() error }); ok {
+		return c.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,343 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/cache"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/memcache/memcachetest"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/resp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/resp/resptest"
+)
+
+// redisStrings is a Redis answering GET, SET key value PX ms and DEL from memory, ignoring expiry.
+type redisStrings struct {
+	mu     sync.Mutex
+	values map[string]string
+	ttls   map[string]string // PX argument of the last SET.
+}
+
+func newRedisStrings(t *testing.T) (*redisStrings, *resptest.Server) {
+	t.Helper()
+
+	r := &redisStrings{values: map[string]string{}, ttls: map[string]string{}}
+
+	return r, resptest.NewServer(t, r.handle)
+}
+
+func (r *redisStrings) handle(args []string) any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case args[0] == "GET" && len(args) == 2:
+		v, ok := r.values[args[1]]
+		if !ok {
+			return nil
+		}
+		return v
+	case args[0] == "SET" && len(args) == 5 && args[3] == "PX":
+		r.values[args[1]], r.ttls[args[1]] = args[2], args[4]
+		return resptest.Status("OK")
+	case args[0] == "DEL" && len(args) == 2:
+		_, ok := r.values[args[1]]
+		delete(r.values, args[1])
+		if ok {
+			return int64(1)
+		}
+		return int64(0)
+	}
+	return resp.Error("ERR unexpected command")
+}
+
+func (r *redisStrings) has(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.values[key]
+
+	return ok
+}
+
+func (r *redisStrings) ttl(key string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.ttls[key]
+}
+
+// backendTest exercises the Backend contract on b.
+func backendTest(t *testing.T, b cache.Backend) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, ok, err := b.Get(ctx, "missing"); ok || err != nil {
+		t.Errorf("Get of a missing key = %t, %v", ok, err)
+	}
+	if err := b.Set(ctx, "k", []byte("v1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Set(ctx, "k", []byte("v2"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, err := b.Get(ctx, "k"); !ok || err != nil || string(v) != "v2" {
+		t.Errorf("Get = %q, %t, %v; want the latest value", v, ok, err)
+	}
+	if err := b.Delete(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := b.Get(ctx, "k"); ok {
+		t.Error("Get found a deleted key")
+	}
+	if err := b.Delete(ctx, "k"); err != nil {
+		t.Errorf("Delete of a missing key = %v", err)
+	}
+}
+
+func TestMemoryBackend(t *testing.T) {
+	backendTest(t, cache.NewMemoryBackend(0))
+}
+
+func TestMemoryBackendEviction(t *testing.T) {
+	b := cache.NewMemoryBackend(2)
+	ctx := context.Background()
+
+	_ = b.Set(ctx, "a", []byte("a"), time.Minute)
+	_ = b.Set(ctx, "b", []byte("b"), time.Minute)
+	_, _, _ = b.Get(ctx, "a") // a is now the most recently used.
+	_ = b.Set(ctx, "c", []byte("c"), time.Minute)
+
+	if b.Len() != 2 {
+		t.Errorf("Len = %d, want 2", b.Len())
+	}
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok, _ := b.Get(ctx, key); ok != want {
+			t.Errorf("%s cached = %t, want %t", key, ok, want)
+		}
+	}
+}
+
+func TestMemoryBackendExpiry(t *testing.T) {
+	b := cache.NewMemoryBackend(0)
+	ctx := context.Background()
+
+	_ = b.Set(ctx, "k", []byte("v"), 0)
+	if _, ok, _ := b.Get(ctx, "k"); ok {
+		t.Error("Get returned an expired entry")
+	}
+	if b.Len() != 0 {
+		t.Errorf("Len = %d after reading an expired entry, want 0", b.Len())
+	}
+}
+
+// failingBackend is a Backend whose every call fails.
+type failingBackend struct{}
+
+var errBackend = errors.New("backend unavailable")
+
+func (failingBackend) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errBackend
+}
+
+func (failingBackend) Set(context.Context, string, []byte, time.Duration) error { return errBackend }
+
+func (failingBackend) Delete(context.Context, string) error { return errBackend }
+
+func TestReadThrough(t *testing.T) {
+	ctx := context.Background()
+	local, shared := cache.NewMemoryBackend(0), cache.NewMemoryBackend(0)
+	backendTest(t, cache.ReadThrough(local, shared, time.Minute))
+
+	b := cache.ReadThrough(local, shared, time.Minute)
+	_ = shared.Set(ctx, "k", []byte("shared"), time.Hour)
+	if v, ok, err := b.Get(ctx, "k"); !ok || err != nil || string(v) != "shared" {
+		t.Fatalf("Get = %q, %t, %v; want the shared entry", v, ok, err)
+	}
+	// The hit was copied into the local tier, which now answers on its own.
+	_ = shared.Delete(ctx, "k")
+	if v, ok, _ := b.Get(ctx, "k"); !ok || string(v) != "shared" {
+		t.Errorf("Get = %q, %t; want the local copy", v, ok)
+	}
+
+	// Writes go to both tiers.
+	_ = b.Set(ctx, "w", []byte("v"), time.Hour)
+	for name, tier := range map[string]cache.Backend{"local": local, "shared": shared} {
+		if _, ok, _ := tier.Get(ctx, "w"); !ok {
+			t.Errorf("%s tier missing the written entry", name)
+		}
+	}
+}
+
+func TestReadThroughFailures(t *testing.T) {
+	ctx := context.Background()
+
+	// Shared failures are returned; local ones are ignored.
+	b := cache.ReadThrough(cache.NewMemoryBackend(0), failingBackend{}, time.Minute)
+	if _, _, err := b.Get(ctx, "k"); !errors.Is(err, errBackend) {
+		t.Errorf("Get error = %v, want the shared error", err)
+	}
+	if err := b.Set(ctx, "k", []byte("v"), time.Minute); !errors.Is(err, errBackend) {
+		t.Errorf("Set error = %v, want the shared error", err)
+	}
+	if err := b.Delete(ctx, "k"); !errors.Is(err, errBackend) {
+		t.Errorf("Delete error = %v, want the shared error", err)
+	}
+
+	shared := cache.NewMemoryBackend(0)
+	b = cache.ReadThrough(failingBackend{}, shared, time.Minute)
+	_ = shared.Set(ctx, "k", []byte("v"), time.Minute)
+	if v, ok, err := b.Get(ctx, "k"); !ok || err != nil || string(v) != "v" {
+		t.Errorf("Get = %q, %t, %v; want the shared entry", v, ok, err)
+	}
+	if err := b.Set(ctx, "k", []byte("v"), time.Minute); err != nil {
+		t.Errorf("Set error = %v with only the local tier failing", err)
+	}
+}
+
+func TestRedisBackend(t *testing.T) {
+	redis, srv := newRedisStrings(t)
+	b, err := cache.NewRedisBackend(cache.RedisConfig{Addr: srv.Addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = b.Close() }()
+
+	backendTest(t, b)
+	if got := redis.ttl("k"); got != "60000" {
+		t.Errorf("SET PX %s, want the TTL in milliseconds", got)
+	}
+	_ = b.Set(context.Background(), "short", []byte("v"), time.Microsecond)
+	if got := redis.ttl("short"); got != "1" {
+		t.Errorf("SET PX %s for a sub-millisecond TTL, want 1", got)
+	}
+	binary := string([]byte{0, 1, '\r', '\n', 0xff})
+	_ = b.Set(context.Background(), "bin", []byte(binary), time.Minute)
+	if v, ok, err := b.Get(context.Background(), "bin"); !ok || err != nil || string(v) != binary {
+		t.Errorf("Get = %q, %t, %v; want the binary value", v, ok, err)
+	}
+
+	if _, err := cache.NewRedisBackend(cache.RedisConfig{}); err == nil {
+		t.Error("NewRedisBackend accepted an empty address")
+	}
+}
+
+func TestRedisBackendErrors(t *testing.T) {
+	srv := resptest.NewServer(t, func([]string) any { return resp.Error("READONLY replica") })
+	b, err := cache.NewRedisBackend(cache.RedisConfig{Addr: srv.Addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = b.Close() }()
+	ctx := context.Background()
+
+	if _, _, err := b.Get(ctx, "k"); err == nil || !strings.Contains(err.Error(), "READONLY") {
+		t.Errorf("Get error = %v", err)
+	}
+	if err := b.Set(ctx, "k", nil, time.Minute); err == nil {
+		t.Error("Set succeeded on an error reply")
+	}
+	if err := b.Delete(ctx, "k"); err == nil {
+		t.Error("Delete succeeded on an error reply")
+	}
+}
+
+func TestMemcachedBackend(t *testing.T) {
+	srv := memcachetest.NewServer(t)
+	b, err := cache.NewMemcachedBackend(cache.MemcachedConfig{Addrs: []string{srv.Addr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = b.Close() }()
+
+	backendTest(t, b)
+	_ = b.Set(context.Background(), "k", []byte("v"), 90*time.Second)
+	if it, _ := srv.Item("k"); it.Exptime != 90 {
+		t.Errorf("exptime %d, want 90", it.Exptime)
+	}
+
+	srv.Intercept(func(string) string { return "SERVER_ERROR object too large for cache\r\n" })
+	if err := b.Set(context.Background(), "big", []byte("v"), time.Minute); err == nil {
+		t.Error("Set succeeded on a server error")
+	}
+	if _, _, err := b.Get(context.Background(), "k"); err == nil {
+		t.Error("Get succeeded on a server error")
+	}
+
+	if _, err := cache.NewMemcachedBackend(cache.MemcachedConfig{}); err == nil {
+		t.Error("NewMemcachedBackend accepted no addresses")
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	redis, redisSrv := newRedisStrings(t)
+	memcached := memcachetest.NewServer(t)
+
+	tests := []struct {
+		name    string
+		cfg     cache.Config
+		shared  func(key string) bool // Reports whether key reached the shared backend.
+		tiered  bool
+		wantErr string
+	}{
+		{name: "default", cfg: cache.Config{}},
+		{name: "memory", cfg: cache.Config{Backend: "Memory"}},
+		{
+			name:   "redis",
+			cfg:    cache.Config{Backend: "redis", RedisAddr: redisSrv.Addr},
+			shared: redis.has,
+		},
+		{
+			name:   "redis with a local tier",
+			cfg:    cache.Config{Backend: "REDIS", RedisAddr: redisSrv.Addr, LocalTTL: time.Minute},
+			shared: redis.has,
+			tiered: true,
+		},
+		{
+			name:   "memcached",
+			cfg:    cache.Config{Backend: "memcached", MemcachedAddrs: []string{memcached.Addr}},
+			shared: func(k string) bool { _, ok := memcached.Item(k); return ok },
+		},
+		{name: "redis without an address", cfg: cache.Config{Backend: "redis"}, wantErr: "redis address is required"},
+		{name: "memcached without addresses", cfg: cache.Config{Backend: "memcached"}, wantErr: "memcached address"},
+		{name: "unknown", cfg: cache.Config{Backend: "disk"}, wantErr: `unknown cache backend "disk"`},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := cache.NewBackend(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NewBackend error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			key := "key-" + strconv.Itoa(i)
+			if err := b.Set(context.Background(), key, []byte("v"), time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			if tt.shared == nil {
+				if _, ok := b.(*cache.MemoryBackend); !ok {
+					t.Errorf("backend %T, want a MemoryBackend", b)
+				}
+				return
+			}
+			if !tt.shared(key) {
+				t.Error("entry did not reach the shared backend")
+			}
+			_, memory := b.(*cache.MemoryBackend)
+			_, redisOnly := b.(*cache.RedisBackend)
+			_, memcachedOnly := b.(*cache.MemcachedBackend)
+			if tiered := !memory && !redisOnly && !memcachedOnly; tiered != tt.tiered {
+				t.Errorf("backend %T, want tiered %t", b, tt.tiered)
+			}
+		})
+	}
+}
@@ -0,0 +1,473 @@
+// Package cache caches MCP responses so repeated calls such as tools/list are answered from the
+// request flow without reaching the upstream.
+//
+// Entries live in a Backend: MemoryBackend for a single instance, or RedisBackend and
+// MemcachedBackend to share cached responses between plugin instances and keep them across
+// restarts. ReadThrough puts a short-lived MemoryBackend in front of a shared backend so hot
+// entries avoid a network round trip.
+//
+// The cache is decoded from custom_config, so a caching plugin needs no code beyond serving Plugin:
+//
+//	// custom_config:
+//	//   backend:    redis
+//	//   redis_addr: redis:6379
+//	//   ttl:        5m
+//	if err := mcpdpluginsv1.Serve(cache.NewPlugin()); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+// HandleResponse does not see the originating request, so the request flow remembers the cache
+// key of each miss under its correlation ID (see mcpdpluginsv1.CorrelationID). Responses to
+// requests without a correlation ID are not cached.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/pending"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// pendingTTL bounds how long a miss's cache key is remembered while waiting for its response.
+const pendingTTL = time.Minute
+
+// StatusHeader is the response header reporting whether a response was served from the cache
+// ("HIT"), fetched from the upstream and stored ("MISS"), or shared from a concurrent identical
+// request ("COALESCED").
+const StatusHeader = "X-Cache"
+
+// Backend names accepted by Config.Backend.
+const (
+	BackendMemory    = "memory"
+	BackendRedis     = "redis"
+	BackendMemcached = "memcached"
+)
+
+// Config is the response cache, decodable from custom_config with mcpdpluginsv1.DecodeConfig.
+type Config struct {
+	// TTL is how long responses are cached.
+	TTL time.Duration `config:"ttl" default:"5m"`
+
+	// Methods lists the MCP methods whose responses are cached. tools/call is only safe to add
+	// for tools without side effects.
+	Methods []string `config:"methods" default:"tools/list,resources/list,resources/read,prompts/list"`
+
+	// Vary lists request headers whose values are part of the cache key, so callers with
+	// different credentials do not share entries. The upstream, tenant, method and params are
+	// always part of the key.
+	Vary []string `config:"vary" default:"Authorization"`
+
+	// KeyPrefix prefixes every key written to the backend.
+	KeyPrefix string `config:"key_prefix" default:"mcpd:cache:"`
+
+	// Backend is where entries are stored: memory, redis or memcached.
+	Backend string `config:"backend" default:"memory"`
+
+	// MaxEntries bounds the in-memory entries, for the memory backend and the local tier in front
+	// of shared backends.
+	MaxEntries int `config:"max_entries" default:"10000"`
+
+	// LocalTTL is how long entries read from a shared backend are kept in memory. Zero disables
+	// the local tier.
+	LocalTTL time.Duration `config:"local_ttl" default:"10s"`
+
+	// RedisAddr, RedisUsername, RedisPassword and RedisDB configure the redis backend.
+	RedisAddr     string `config:"redis_addr"`
+	RedisUsername string `config:"redis_username"`
+	RedisPassword string `config:"redis_password"`
+	RedisDB       int    `config:"redis_db"`
+
+	// MemcachedAddrs lists the servers of the memcached backend.
+	MemcachedAddrs []string `config:"memcached_addrs"`
+
+	// Coalesce makes identical requests wait for the response to a concurrent miss, up to
+	// CoalesceTimeout, instead of each reaching the upstream. Coalescing is per plugin instance.
+	Coalesce        bool          `config:"coalesce" default:"false"`
+	CoalesceTimeout time.Duration `config:"coalesce_timeout" default:"5s"`
+}
+
+// DefaultConfig returns the Config with every default applied.
+func DefaultConfig() Config {
+	var cfg Config
+	if _, err := config.Decode(nil, &cfg); err != nil {
+		panic(fmt.Sprintf("cache: invalid defaults: %v", err))
+	}
+
+	return cfg
+}
+
+// NewBackend returns the Backend selected by cfg, wrapped with ReadThrough for shared backends
+// when LocalTTL is set.
+func NewBackend(cfg Config) (Backend, error) {
+	var (
+		shared Backend
+		err    error
+	)
+	switch strings.ToLower(cfg.Backend) {
+	case "", BackendMemory:
+		return NewMemoryBackend(cfg.MaxEntries), nil
+	case BackendRedis:
+		shared, err = NewRedisBackend(RedisConfig{
+			Addr:     cfg.RedisAddr,
+			Username: cfg.RedisUsername,
+			Password: cfg.RedisPassword,
+			DB:       cfg.RedisDB,
+		})
+	case BackendMemcached:
+		shared, err = NewMemcachedBackend(MemcachedConfig{Addrs: cfg.MemcachedAddrs})
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", cfg.Backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cfg.LocalTTL <= 0 {
+		return shared, nil
+	}
+
+	return ReadThrough(NewMemoryBackend(cfg.MaxEntries), shared, cfg.LocalTTL), nil
+}
+
+// Cache serves cached responses from the request flow and stores responses from the response
+// flow. It is safe for concurrent use.
+type Cache struct {
+	backend Backend
+	ttl     time.Duration
+	methods map[string]struct{}
+	vary    []string
+	prefix  string
+	clock   clock.Clock
+	flights *Coalescer
+	pending *pending.Map[string]
+}
+
+// entry is the cached form of a response. Only the result is kept; the JSON-RPC id is replaced
+// with the id of each request served from it.
+type entry struct {
+	Result json.RawMessage `json:"result"`
+}
+
+// Option configures a Cache.
+type Option func(*Cache) error
+
+// WithClock sets the clock entries expire by (defaults to clock.Real()), which also applies to
+// the coalescer and to the MemoryBackend New builds from the Config.
+func WithClock(c clock.Clock) Option {
+	return func(cache *Cache) error {
+		if c == nil {
+			return fmt.Errorf("clock cannot be nil")
+		}
+		cache.clock = c
+		return nil
+	}
+}
+
+// New returns a Cache for cfg storing entries in backend. A nil backend is built from cfg with
+// NewBackend.
+func New(cfg Config, backend Backend, opts ...Option) (*Cache, error) {
+	if cfg.TTL <= 0 {
+		return nil, fmt.Errorf("ttl must be positive")
+	}
+	if cfg.Coalesce && cfg.CoalesceTimeout <= 0 {
+		return nil, fmt.Errorf("coalesce_timeout must be positive")
+	}
+
+	c := &Cache{
+		backend: backend,
+		ttl:     cfg.TTL,
+		methods: map[string]struct{}{},
+		vary:    cfg.Vary,
+		prefix:  cfg.KeyPrefix,
+		clock:   clock.Real(),
+		pending: pending.New[string](pendingTTL),
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.backend == nil {
+		b, err := NewBackend(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if mb, ok := b.(*MemoryBackend); ok {
+			mb.clock = c.clock
+		}
+		c.backend = b
+	}
+	for _, m := range cfg.Methods {
+		if m != "" {
+			c.methods[m] = struct{}{}
+		}
+	}
+	if cfg.Coalesce {
+		c.flights = NewCoalescer(cfg.CoalesceTimeout)
+		c.flights.clock = c.clock
+	}
+
+	return c, nil
+}
+
+// Key returns the cache key of req, and false when req is not a cacheable MCP request.
+func (c *Cache) Key(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) (string, bool) {
+	if !strings.EqualFold(req.GetMethod(), http.MethodPost) {
+		return "", false
+	}
+	m, err := mcp.ParseOne(req.GetBody())
+	if err != nil || !m.IsRequest() {
+		return "", false
+	}
+	if _, ok := c.methods[m.Method]; !ok {
+		return "", false
+	}
+	params, err := canonicalParams(m.Params)
+	if err != nil {
+		return "", false
+	}
+
+	h := sha256.New()
+	for _, part := range []string{mcpdpluginsv1.Upstream(ctx), mcpdpluginsv1.Tenant(ctx), m.Method, params} {
+		_, _ = io.WriteString(h, part)
+		_, _ = h.Write([]byte{0})
+	}
+	for _, name := range c.vary {
+		_, _ = io.WriteString(h, mcpdpluginsv1.GetHeader(req.GetHeaders(), name))
+		_, _ = h.Write([]byte{0})
+	}
+
+	return c.prefix + hex.EncodeToString(h.Sum(nil)), true
+}
+
+// HandleRequest short-circuits cacheable requests with the cached response when there is one.
+// Other requests continue unchanged, and the keys of misses are remembered for HandleResponse.
+// With coalescing, a miss identical to one in flight first waits for that response. Backend errors
+// are treated as misses.
+func (c *Cache) HandleRequest(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) *mcpdpluginsv1.HTTPResponse {
+	key, ok := c.Key(ctx, req)
+	if !ok {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+
+	if raw, found, err := c.backend.Get(ctx, key); err == nil && found {
+		var e entry
+		if json.Unmarshal(raw, &e) == nil {
+			if resp, ok := c.serve(req, e.Result, "HIT"); ok {
+				return resp
+			}
+		}
+	}
+
+	id := mcpdpluginsv1.CorrelationID(ctx, req)
+	if id == "" {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+	if c.flights != nil {
+		if f, leader := c.flights.Join(key); !leader {
+			if result, ok := f.Wait(ctx); ok {
+				if resp, ok := c.serve(req, result, "COALESCED"); ok {
+					return resp
+				}
+			}
+		}
+	}
+	c.pending.Put(id, key, c.clock.Now())
+
+	return &mcpdpluginsv1.HTTPResponse{Continue: true}
+}
+
+// serve returns the response answering req with result, reported as cacheStatus in StatusHeader.
+func (c *Cache) serve(
+	req *mcpdpluginsv1.HTTPRequest,
+	result json.RawMessage,
+	cacheStatus string,
+) (*mcpdpluginsv1.HTTPResponse, bool) {
+	m, err := mcp.ParseOne(req.GetBody())
+	if err != nil {
+		return nil, false
+	}
+	body, _ := json.Marshal(mcp.Message{JSONRPC: mcp.JSONRPCVersion, ID: m.ID, Result: result})
+
+	return &mcpdpluginsv1.HTTPResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json", StatusHeader: cacheStatus},
+		Body:       body,
+	}, true
+}
+
+// HandleResponse stores successful JSON responses to remembered misses and returns resp,
+// continuing the chain. Error responses and tool results flagged isError are not cached.
+func (c *Cache) HandleResponse(ctx context.Context, resp *mcpdpluginsv1.HTTPResponse) *mcpdpluginsv1.HTTPResponse {
+	out := &mcpdpluginsv1.HTTPResponse{
+		Continue:   true,
+		StatusCode: resp.GetStatusCode(),
+		Headers:    resp.GetHeaders(),
+		Body:       resp.GetBody(),
+	}
+
+	key, _ := c.pending.Take(mcpdpluginsv1.CorrelationID(ctx, nil), c.clock.Now())
+	if key == "" {
+		return out
+	}
+	var result json.RawMessage
+	if c.flights != nil {
+		defer func() { c.flights.Complete(key, result) }()
+	}
+	if resp.GetStatusCode() != http.StatusOK {
+		return out
+	}
+	if ct := mcpdpluginsv1.GetHeader(resp.GetHeaders(), "Content-Type"); ct != "" &&
+		!strings.HasPrefix(strings.ToLower(ct), "application/json") {
+		return out
+	}
+	m, err := mcp.ParseOne(resp.GetBody())
+	if err != nil || m.Error != nil || len(m.Result) == 0 || isToolError(m.Result) {
+		return out
+	}
+	result = m.Result
+
+	raw, _ := json.Marshal(entry{Result: m.Result})
+	if err := c.backend.Set(ctx, key, raw, c.ttl); err != nil {
+		return out
+	}
+
+	headers := make(map[string]string, len(resp.GetHeaders())+1)
+	for k, v := range resp.GetHeaders() {
+		headers[k] = v
+	}
+	headers[StatusHeader] = "MISS"
+	out.Headers = headers
+
+	return out
+}
+
+// Close closes the backend if it holds connections.
+func (c *Cache) Close() error {
+	if cl, ok := c.backend.(io.Closer); ok {
+		return cl.Close()
+	}
+
+	return nil
+}
+
+// canonicalParams re-encodes params with sorted keys and without _meta, which carries per-call
+// values such as progress tokens. Absent and empty params encode the same.
+func canonicalParams(params json.RawMessage) (string, error) {
+	if len(params) == 0 {
+		return "", nil
+	}
+	var v any
+	if err := json.Unmarshal(params, &v); err != nil {
+		return "", err
+	}
+	if obj, ok := v.(map[string]any); ok {
+		delete(obj, "_meta")
+		if len(obj) == 0 {
+			return "", nil
+		}
+	}
+	b, err := json.Marshal(v)
+
+	return string(b), err
+}
+
+func isToolError(result json.RawMessage) bool {
+	var r struct {
+		IsError bool `json:"isError"`
+	}
+
+	return json.Unmarshal(result, &r) == nil && r.IsError
+}
+
+// Plugin is a request- and response-flow plugin running the Cache decoded from its custom_config.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	opts  []Option
+	cache atomic.Pointer[Cache]
+}
+
+// NewPlugin returns a Plugin caching in memory with the default Config until Configure is called.
+// Every Cache it builds is configured with opts.
+func NewPlugin(opts ...Option) *Plugin {
+	p := &Plugin{opts: opts}
+	c, err := New(DefaultConfig(), nil, opts...)
+	if err != nil {
+		panic(fmt.Sprintf("cache: invalid defaults: %v", err))
+	}
+	p.cache.Store(c)
+
+	return p
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "response-cache",
+		Version:     pluginVersion,
+		Description: "Caches MCP responses in memory, Redis or memcached.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest, mcpdpluginsv1.FlowResponse), nil
+}
+
+// Configure decodes the cache from cfg's custom_config, replacing (and closing) the previous one.
+func (p *Plugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	var c Config
+	if err := mcpdpluginsv1.DecodeConfig(ctx, cfg, &c); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	cache, err := New(c, nil, p.opts...)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if old := p.cache.Swap(cache); old != nil {
+		_ = old.Close()
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// Stop closes the cache's backend connections.
+func (p *Plugin) Stop(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	_ = p.cache.Load().Close()
+	return &emptypb.Empty{}, nil
+}
+
+// HandleRequest serves cached responses.
+func (p *Plugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.cache.Load().HandleRequest(ctx, req), nil
+}
+
+// HandleResponse stores responses.
+func (p *Plugin) HandleResponse(
+	ctx context.Context,
+	resp *mcpdpluginsv1.HTTPResponse,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.cache.Load().HandleResponse(ctx, resp), nil
+}
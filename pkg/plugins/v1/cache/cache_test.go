@@ -0,0 +1,366 @@
+package cache_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/cache"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/plugintest"
+)
+
+// withID returns a context carrying the correlation ID id, as mcpd sends it.
+func withID(id string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", id))
+}
+
+func post(body string, headers ...string) *mcpdpluginsv1.HTTPRequest {
+	h := map[string]string{}
+	for i := 0; i+1 < len(headers); i += 2 {
+		h[headers[i]] = headers[i+1]
+	}
+
+	return &mcpdpluginsv1.HTTPRequest{Method: http.MethodPost, Headers: h, Body: []byte(body)}
+}
+
+func ok(body string) *mcpdpluginsv1.HTTPResponse {
+	return &mcpdpluginsv1.HTTPResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       []byte(body),
+	}
+}
+
+func newCache(t *testing.T, cfg cache.Config, opts ...cache.Option) *cache.Cache {
+	t.Helper()
+
+	c, err := cache.New(cfg, nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestNewErrors(t *testing.T) {
+	valid := cache.DefaultConfig()
+	tests := []struct {
+		name string
+		cfg  func(*cache.Config)
+		opts []cache.Option
+		want string
+	}{
+		{name: "zero ttl", cfg: func(c *cache.Config) { c.TTL = 0 }, want: "ttl must be positive"},
+		{
+			name: "coalesce without a timeout",
+			cfg:  func(c *cache.Config) { c.Coalesce, c.CoalesceTimeout = true, 0 },
+			want: "coalesce_timeout must be positive",
+		},
+		{name: "unknown backend", cfg: func(c *cache.Config) { c.Backend = "disk" }, want: "unknown cache backend"},
+		{name: "nil clock", opts: []cache.Option{cache.WithClock(nil)}, want: "clock cannot be nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			if _, err := cache.New(cfg, nil, tt.opts...); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	c := newCache(t, cache.DefaultConfig())
+	base, ok := c.Key(context.Background(), post(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	if !ok || !strings.HasPrefix(base, "mcpd:cache:") {
+		t.Fatalf("Key = %q, %t; want a prefixed key", base, ok)
+	}
+	upstream := mcpdpluginsv1.ContextWithUpstream(context.Background(), "github")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		req  *mcpdpluginsv1.HTTPRequest
+		ok   bool
+		same bool // Whether the key equals the one of a plain tools/list.
+	}{
+		{name: "other id", req: post(`{"jsonrpc":"2.0","id":"x","method":"tools/list"}`), ok: true, same: true},
+		{
+			name: "empty params",
+			req:  post(`{"jsonrpc":"2.0","id":1,"method":"tools/list","params":{}}`),
+			ok:   true, same: true,
+		},
+		{
+			name: "only _meta",
+			req:  post(`{"jsonrpc":"2.0","id":1,"method":"tools/list","params":{"_meta":{"progressToken":1}}}`),
+			ok:   true, same: true,
+		},
+		{
+			name: "params",
+			req:  post(`{"jsonrpc":"2.0","id":1,"method":"tools/list","params":{"cursor":"c"}}`),
+			ok:   true,
+		},
+		{
+			name: "vary header",
+			req:  post(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, "authorization", "Bearer a"),
+			ok:   true,
+		},
+		{
+			name: "other header",
+			req:  post(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, "User-Agent", "test"),
+			ok:   true, same: true,
+		},
+		{name: "upstream", ctx: upstream, req: post(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`), ok: true},
+		{
+			name: "tenant",
+			ctx:  mcpdpluginsv1.ContextWithTenant(context.Background(), "acme"),
+			req:  post(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`),
+			ok:   true,
+		},
+		{
+			name: "GET",
+			req: &mcpdpluginsv1.HTTPRequest{
+				Method: http.MethodGet,
+				Body:   []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`),
+			},
+		},
+		{name: "uncached method", req: post(`{"jsonrpc":"2.0","id":1,"method":"tools/call"}`)},
+		{name: "notification", req: post(`{"jsonrpc":"2.0","method":"tools/list"}`)},
+		{name: "batch", req: post(`[{"jsonrpc":"2.0","id":1,"method":"tools/list"}]`)},
+		{name: "not JSON-RPC", req: post(`{"id":1,"method":"tools/list"}`)},
+		{name: "invalid params", req: post(`{"jsonrpc":"2.0","id":1,"method":"tools/list","params":{"a":}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			key, ok := c.Key(ctx, tt.req)
+			if ok != tt.ok {
+				t.Fatalf("Key cacheable = %t, want %t", ok, tt.ok)
+			}
+			if ok && (key == base) != tt.same {
+				t.Errorf("key equal to the plain one = %t, want %t", key == base, tt.same)
+			}
+		})
+	}
+
+	// Params are compared by value, not by encoding.
+	read := func(params string) string {
+		body := `{"jsonrpc":"2.0","id":1,"method":"resources/read","params":` + params + `}`
+		key, _ := c.Key(context.Background(), post(body))
+		return key
+	}
+	a, b := read(`{"a":1,"b":2}`), read(`{ "b":2, "a":1 }`)
+	if a != b {
+		t.Error("reordered params changed the key")
+	}
+}
+
+func TestHitAndMiss(t *testing.T) {
+	clk := plugintest.NewFakeClock(time.Time{})
+	cfg := cache.DefaultConfig()
+	cfg.TTL = time.Minute
+	c := newCache(t, cfg, cache.WithClock(clk))
+
+	req := func(id string) *mcpdpluginsv1.HTTPRequest {
+		return post(`{"jsonrpc":"2.0","id":` + id + `,"method":"tools/list"}`)
+	}
+
+	if r := c.HandleRequest(withID("a"), req("1")); !r.GetContinue() {
+		t.Fatalf("first request not forwarded: %v", r)
+	}
+	resp := c.HandleResponse(withID("a"), ok(`{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}`))
+	if !resp.GetContinue() || resp.GetHeaders()[cache.StatusHeader] != "MISS" {
+		t.Errorf("response %v, want it continued and marked MISS", resp)
+	}
+	if got := string(resp.GetBody()); got != `{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}` {
+		t.Errorf("response body %s, want it unchanged", got)
+	}
+
+	hit := c.HandleRequest(withID("b"), req(`"two"`))
+	if hit.GetContinue() || hit.GetStatusCode() != http.StatusOK || hit.GetHeaders()[cache.StatusHeader] != "HIT" {
+		t.Fatalf("second request %v, want a cached 200", hit)
+	}
+	if got := string(hit.GetBody()); got != `{"jsonrpc":"2.0","id":"two","result":{"tools":[]}}` {
+		t.Errorf("cached body %s, want the request's id", got)
+	}
+	if ct := hit.GetHeaders()["Content-Type"]; ct != "application/json" {
+		t.Errorf("cached Content-Type %q", ct)
+	}
+
+	// The response to a request served from the cache is not stored again.
+	r := c.HandleResponse(withID("b"), ok(`{"jsonrpc":"2.0","id":"two","result":{}}`))
+	if got := r.GetHeaders()[cache.StatusHeader]; got != "" {
+		t.Errorf("response to a hit marked %q", got)
+	}
+
+	clk.Advance(time.Minute)
+	if r := c.HandleRequest(withID("c"), req("3")); !r.GetContinue() {
+		t.Errorf("request after the TTL served from the cache: %v", r)
+	}
+}
+
+func TestUncachedResponses(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string // Correlation ID of the response; empty uses the request's.
+		resp *mcpdpluginsv1.HTTPResponse
+	}{
+		{name: "error status", resp: &mcpdpluginsv1.HTTPResponse{StatusCode: 500, Body: []byte(`{}`)}},
+		{
+			name: "non-JSON content type",
+			resp: &mcpdpluginsv1.HTTPResponse{
+				StatusCode: 200,
+				Headers:    map[string]string{"Content-Type": "text/event-stream"},
+				Body:       []byte(`{"jsonrpc":"2.0","id":1,"result":{}}`),
+			},
+		},
+		{name: "JSON-RPC error", resp: ok(`{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"nope"}}`)},
+		{name: "tool error", resp: ok(`{"jsonrpc":"2.0","id":1,"result":{"isError":true,"content":[]}}`)},
+		{name: "no result", resp: ok(`{"jsonrpc":"2.0","id":1}`)},
+		{name: "invalid body", resp: ok(`not json`)},
+		{name: "unknown request", id: "other", resp: ok(`{"jsonrpc":"2.0","id":1,"result":{}}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCache(t, cache.DefaultConfig())
+			req := post(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+			c.HandleRequest(withID("a"), req)
+
+			id := tt.id
+			if id == "" {
+				id = "a"
+			}
+			out := c.HandleResponse(withID(id), tt.resp)
+			if !out.GetContinue() || out.GetHeaders()[cache.StatusHeader] != "" {
+				t.Errorf("response %v, want it continued unmarked", out)
+			}
+			if r := c.HandleRequest(withID("b"), req); !r.GetContinue() {
+				t.Errorf("response was cached: %v", r)
+			}
+		})
+	}
+}
+
+func TestContentTypeParameters(t *testing.T) {
+	c := newCache(t, cache.DefaultConfig())
+	req := post(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	c.HandleRequest(withID("a"), req)
+
+	resp := ok(`{"jsonrpc":"2.0","id":1,"result":{}}`)
+	resp.Headers["Content-Type"] = "Application/JSON; charset=utf-8"
+	if out := c.HandleResponse(withID("a"), resp); out.GetHeaders()[cache.StatusHeader] != "MISS" {
+		t.Errorf("response with a charset not cached: %v", out)
+	}
+	if resp.Headers[cache.StatusHeader] != "" {
+		t.Error("HandleResponse modified the response's headers")
+	}
+}
+
+func TestNoCorrelationID(t *testing.T) {
+	c := newCache(t, cache.DefaultConfig())
+	req := post(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+
+	c.HandleRequest(context.Background(), req)
+	out := c.HandleResponse(context.Background(), ok(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+	if out.GetHeaders()[cache.StatusHeader] != "" {
+		t.Errorf("response without a correlation ID cached: %v", out)
+	}
+}
+
+func TestBackendFailures(t *testing.T) {
+	c, err := cache.New(cache.DefaultConfig(), failingBackend{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := post(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+
+	if r := c.HandleRequest(withID("a"), req); !r.GetContinue() {
+		t.Errorf("request not forwarded when the backend fails: %v", r)
+	}
+	if out := c.HandleResponse(withID("a"), ok(`{"jsonrpc":"2.0","id":1,"result":{}}`)); !out.GetContinue() ||
+		out.GetHeaders()[cache.StatusHeader] != "" {
+		t.Errorf("response %v, want it continued unmarked when the store fails", out)
+	}
+
+	// A corrupt entry is treated as a miss.
+	b := cache.NewMemoryBackend(0)
+	c, err = cache.New(cache.DefaultConfig(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := c.Key(context.Background(), req)
+	_ = b.Set(context.Background(), key, []byte("corrupt"), time.Minute)
+	if r := c.HandleRequest(withID("a"), req); !r.GetContinue() {
+		t.Errorf("corrupt entry served: %v", r)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	p := cache.NewPlugin()
+	ctx := context.Background()
+
+	md, err := p.GetMetadata(ctx, &emptypb.Empty{})
+	if err != nil || md.GetName() != "response-cache" {
+		t.Errorf("GetMetadata = %v, %v", md, err)
+	}
+	caps, err := p.GetCapabilities(ctx, &emptypb.Empty{})
+	if err != nil || !caps.HasFlow(mcpdpluginsv1.FlowRequest) || !caps.HasFlow(mcpdpluginsv1.FlowResponse) {
+		t.Errorf("GetCapabilities = %v, %v; want both flows", caps, err)
+	}
+
+	req := post(`{"jsonrpc":"2.0","id":1,"method":"prompts/list"}`)
+	roundTrip := func(id string) *mcpdpluginsv1.HTTPResponse {
+		t.Helper()
+		r, err := p.HandleRequest(withID(id), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.GetContinue() {
+			if _, err := p.HandleResponse(withID(id), ok(`{"jsonrpc":"2.0","id":1,"result":{}}`)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return r
+	}
+	roundTrip("a")
+	if r := roundTrip("b"); r.GetHeaders()[cache.StatusHeader] != "HIT" {
+		t.Errorf("default plugin did not cache: %v", r)
+	}
+
+	for _, cfg := range []map[string]string{{"ttl": "soon"}, {"backend": "disk"}, {"ttl": "0s"}} {
+		_, err := p.Configure(ctx, &mcpdpluginsv1.PluginConfig{CustomConfig: cfg})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Configure(%v) error = %v, want InvalidArgument", cfg, err)
+		}
+	}
+	if r := roundTrip("c"); r.GetHeaders()[cache.StatusHeader] != "HIT" {
+		t.Error("failed Configure replaced the cache")
+	}
+
+	// A new configuration starts from an empty cache.
+	cfg := &mcpdpluginsv1.PluginConfig{CustomConfig: map[string]string{"methods": "tools/list"}}
+	if _, err := p.Configure(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if r := roundTrip("d"); !r.GetContinue() {
+		t.Errorf("prompts/list served after it was removed from methods: %v", r)
+	}
+
+	if _, err := p.Stop(ctx, &emptypb.Empty{}); err != nil {
+		t.Errorf("Stop = %v", err)
+	}
+}
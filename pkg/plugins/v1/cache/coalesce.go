@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/pending"
+)
+
+// Coalescer coalesces identical concurrent calls: the first caller for a key leads the call to the
+// upstream while the others wait for its result instead of repeating it. Requests and responses
+// are separate plugin calls, so the leader completes its flight from the response flow. It is safe
+// for concurrent use.
+type Coalescer struct {
+	timeout time.Duration
+	clock   clock.Clock
+
+	// mu makes joining a flight atomic.
+	mu      sync.Mutex
+	flights *pending.Map[*Flight]
+}
+
+// Flight is an in-flight call shared by the callers of a key.
+type Flight struct {
+	done     chan struct{}
+	deadline time.Time
+	clock    clock.Clock
+	result   []byte
+}
+
+// NewCoalescer returns a Coalescer whose followers wait up to timeout for the leader. A flight
+// whose leader has not completed within timeout is abandoned, and the next caller leads anew.
+func NewCoalescer(timeout time.Duration) *Coalescer {
+	return &Coalescer{timeout: timeout, clock: clock.Real(), flights: pending.New[*Flight](timeout)}
+}
+
+// Join returns the flight of key and whether the caller leads it. The leader must eventually call
+// Complete; followers call Wait.
+func (c *Coalescer) Join(key string) (*Flight, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	if f, ok := c.flights.Get(key, now); ok {
+		return f, false
+	}
+	f := &Flight{done: make(chan struct{}), deadline: now.Add(c.timeout), clock: c.clock}
+	c.flights.Put(key, f, now)
+
+	return f, true
+}
+
+// Complete ends the flight of key, handing result to its followers. A nil result releases them
+// without one, for calls that failed or must not be shared.
+func (c *Coalescer) Complete(key string, result []byte) {
+	// The followers of an expired flight have stopped waiting for it.
+	f, ok := c.flights.Take(key, c.clock.Now())
+
+	if ok {
+		f.result = result
+		close(f.done)
+	}
+}
+
+// Wait blocks until the leader completes the flight, the flight's timeout elapses or ctx ends,
+// and returns the leader's result and whether there is one.
+func (f *Flight) Wait(ctx context.Context) ([]byte, bool) {
+	timer := f.clock.NewTimer(clock.Until(f.clock, f.deadline))
+	defer timer.Stop()
+
+	select {
+	case <-f.done:
+		return f.result, f.result != nil
+	case <-timer.C():
+		return nil, false
+	case <-ctx.Done():
+		return nil, false
+	}
+}
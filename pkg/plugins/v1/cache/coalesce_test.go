@@ -0,0 +1,172 @@
+package cache_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/cache"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/plugintest"
+)
+
+func TestCoalescerJoin(t *testing.T) {
+	c := cache.NewCoalescer(time.Minute)
+
+	lead, leader := c.Join("k")
+	if !leader {
+		t.Fatal("first caller does not lead")
+	}
+	follow, leader := c.Join("k")
+	if leader || follow != lead {
+		t.Errorf("second caller leads %t, want it to follow the same flight", leader)
+	}
+	if _, leader := c.Join("other"); !leader {
+		t.Error("caller of another key does not lead")
+	}
+}
+
+func TestCoalescerComplete(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   []byte
+		wantOK   bool
+		wantBody string
+	}{
+		{name: "result shared", result: []byte(`{"tools":[]}`), wantOK: true, wantBody: `{"tools":[]}`},
+		{name: "nil result releases followers", result: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cache.NewCoalescer(time.Minute)
+			c.Join("k")
+
+			const followers = 4
+			var wg sync.WaitGroup
+			results := make([]string, followers)
+			oks := make([]bool, followers)
+			for i := range followers {
+				f, leader := c.Join("k")
+				if leader {
+					t.Fatal("follower leads")
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					res, ok := f.Wait(context.Background())
+					results[i], oks[i] = string(res), ok
+				}()
+			}
+			c.Complete("k", tt.result)
+			wg.Wait()
+
+			for i := range followers {
+				if oks[i] != tt.wantOK || results[i] != tt.wantBody {
+					t.Errorf("follower %d got %q, %t, want %q, %t", i, results[i], oks[i], tt.wantBody, tt.wantOK)
+				}
+			}
+
+			// The flight is over: the next caller leads, and completing it again does nothing.
+			if _, leader := c.Join("k"); !leader {
+				t.Error("caller after Complete does not lead")
+			}
+			c.Complete("unknown", []byte("x"))
+		})
+	}
+}
+
+func TestCoalescerWaitAbandoned(t *testing.T) {
+	c := cache.NewCoalescer(20 * time.Millisecond)
+	c.Join("k")
+	f, _ := c.Join("k")
+
+	start := time.Now()
+	if res, ok := f.Wait(context.Background()); ok || res != nil {
+		t.Errorf("Wait = %q, %t, want no result after the timeout", res, ok)
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("Wait returned after %s, want about the 20ms timeout", elapsed)
+	}
+
+	// The next caller leads a new flight in place of the abandoned one.
+	if _, leader := c.Join("k"); !leader {
+		t.Error("caller after the timeout does not lead")
+	}
+}
+
+func TestCoalescerWaitContext(t *testing.T) {
+	c := cache.NewCoalescer(time.Minute)
+	c.Join("k")
+	f, _ := c.Join("k")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, ok := f.Wait(ctx); ok {
+		t.Error("Wait returned a result for a canceled context")
+	}
+}
+
+func TestCacheCoalescing(t *testing.T) {
+	cfg := cache.DefaultConfig()
+	cfg.Coalesce, cfg.CoalesceTimeout = true, 5*time.Second
+	tests := []struct {
+		name       string
+		response   *mcpdpluginsv1.HTTPResponse // Nil when the leader never completes.
+		wantServed bool
+	}{
+		{
+			name:       "follower served the leader's response",
+			response:   ok(`{"jsonrpc":"2.0","id":1,"result":{"tools":["a"]}}`),
+			wantServed: true,
+		},
+		{
+			name:     "follower forwarded after an error response",
+			response: &mcpdpluginsv1.HTTPResponse{StatusCode: http.StatusBadGateway},
+		},
+		{name: "follower forwarded after the timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := plugintest.NewFakeClock(time.Time{})
+			c := newCache(t, cfg, cache.WithClock(clk))
+			req := func(id string) *mcpdpluginsv1.HTTPRequest {
+				return post(`{"jsonrpc":"2.0","id":` + id + `,"method":"tools/list"}`)
+			}
+
+			if r := c.HandleRequest(withID("leader"), req("1")); !r.GetContinue() {
+				t.Fatalf("leader not forwarded: %v", r)
+			}
+			followed := make(chan *mcpdpluginsv1.HTTPResponse)
+			go func() { followed <- c.HandleRequest(withID("follower"), req(`"f"`)) }()
+
+			// Release the follower once it waits for the leader.
+			clk.WaitForTimers(1)
+			if tt.response != nil {
+				c.HandleResponse(withID("leader"), tt.response)
+			} else {
+				clk.Advance(cfg.CoalesceTimeout)
+			}
+
+			var got *mcpdpluginsv1.HTTPResponse
+			select {
+			case got = <-followed:
+			case <-time.After(5 * time.Second):
+				t.Fatal("follower still waiting")
+			}
+			if !tt.wantServed {
+				if !got.GetContinue() {
+					t.Errorf("follower %v, want it forwarded", got)
+				}
+				return
+			}
+			if got.GetContinue() || got.GetHeaders()[cache.StatusHeader] != "COALESCED" {
+				t.Fatalf("follower %v, want the coalesced response", got)
+			}
+			if body := string(got.GetBody()); body != `{"jsonrpc":"2.0","id":"f","result":{"tools":["a"]}}` {
+				t.Errorf("coalesced body %s, want the leader's result with the follower's id", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/memcache"
+)
+
+// MemcachedConfig configures the connections of a MemcachedBackend.
+type MemcachedConfig = memcache.Config
+
+// MemcachedBackend is a Backend stored in memcached, shared by every plugin instance using the
+// same servers. Memcached rejects values over its item size limit (1MiB by default); such entries
+// are simply not cached.
+type MemcachedBackend struct {
+	client *memcache.Client
+}
+
+// NewMemcachedBackend returns a MemcachedBackend for cfg. Connections are opened on first use.
+func NewMemcachedBackend(cfg MemcachedConfig) (*MemcachedBackend, error) {
+	c, err := memcache.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemcachedBackend{client: c}, nil
+}
+
+// Get implements Backend.
+func (b *MemcachedBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	v, err := b.client.Get(ctx, key)
+	if errors.Is(err, memcache.ErrNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return v, true, nil
+}
+
+// Set implements Backend.
+func (b *MemcachedBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return b.client.Set(ctx, key, value, ttl)
+}
+
+// Delete implements Backend.
+func (b *MemcachedBackend) Delete(ctx context.Context, key string) error {
+	return b.client.Delete(ctx, key)
+}
+
+// Close closes the backend's connections.
+func (b *MemcachedBackend) Close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/resp"
+)
+
+// RedisConfig configures the connection of a RedisBackend.
+type RedisConfig = resp.Config
+
+// RedisBackend is a Backend stored in Redis, shared by every plugin instance using the same server
+// and persisted across restarts according to the server's configuration.
+type RedisBackend struct {
+	client *resp.Client
+}
+
+// NewRedisBackend returns a RedisBackend for cfg. Connections are opened on first use.
+func NewRedisBackend(cfg RedisConfig) (*RedisBackend, error) {
+	c, err := resp.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisBackend{client: c}, nil
+}
+
+// Get implements Backend.
+func (b *RedisBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := b.client.Do(ctx, "GET", key)
+	if errors.Is(err, resp.ErrNil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	s, _ := reply.(string)
+
+	return []byte(s), true, nil
+}
+
+// Set implements Backend.
+func (b *RedisBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ms := max(1, ttl.Milliseconds())
+	_, err := b.client.Do(ctx, "SET", key, string(value), "PX", strconv.FormatInt(ms, 10))
+
+	return err
+}
+
+// Delete implements Backend.
+func (b *RedisBackend) Delete(ctx context.Context, key string) error {
+	_, err := b.client.Do(ctx, "DEL", key)
+	return err
+}
+
+// Close closes the backend's connections.
+func (b *RedisBackend) Close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,223 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// candidateTimeout bounds a single candidate evaluation.
+const candidateTimeout = 5 * time.Second
+
+// CandidateOption configures WithCandidate.
+type CandidateOption func(*candidateOptions) error
+
+type candidateOptions struct {
+	maxInFlight int
+	onCompare   func(ctx context.Context, c Comparison)
+}
+
+// Comparison is the outcome of evaluating the same call with the current and candidate handlers.
+type Comparison struct {
+	// Method is the short RPC name (e.g. "HandleRequest").
+	Method string
+
+	// Request and Response are the inputs of the evaluated call; only one is set.
+	Request  *HTTPRequest
+	Response *HTTPResponse
+
+	// Current and Candidate are the verdicts (metrics.Verdict*) of each handler.
+	Current   string
+	Candidate string
+
+	// CurrentStatus and CandidateStatus are the status codes of short-circuit verdicts.
+	CurrentStatus   int32
+	CandidateStatus int32
+
+	// CandidateErr is the error returned by the candidate, if any.
+	CandidateErr error
+}
+
+// Diverged reports whether the two handlers reached different verdicts, or short-circuited with
+// different status codes.
+func (c Comparison) Diverged() bool {
+	return c.Current != c.Candidate || c.CurrentStatus != c.CandidateStatus
+}
+
+// WithCandidateMaxInFlight bounds concurrent candidate evaluations (default 64). Calls arriving
+// while the limit is reached are not evaluated and are counted as metrics.CandidateSkipped.
+func WithCandidateMaxInFlight(n int) CandidateOption {
+	return func(o *candidateOptions) error {
+		if n < 1 {
+			return fmt.Errorf("candidate max in flight must be at least 1")
+		}
+		o.maxInFlight = n
+		return nil
+	}
+}
+
+// WithComparisonHandler calls h with every comparison, e.g. to record divergent calls for later
+// analysis. h runs on the evaluation goroutine.
+func WithComparisonHandler(h func(ctx context.Context, c Comparison)) CandidateOption {
+	return func(o *candidateOptions) error {
+		if h == nil {
+			return fmt.Errorf("comparison handler cannot be nil")
+		}
+		o.onCompare = h
+		return nil
+	}
+}
+
+// WithCandidate evaluates candidate on the same HandleRequest and HandleResponse traffic as the
+// served plugin, for example when migrating from regex rules to CEL or OPA policies. Only the
+// served plugin's results are returned to mcpd; the candidate runs in the background on a copy
+// of each input and its verdict is compared with the current one.
+//
+// Every comparison is counted as metrics.CandidateComparisons, labelled by both verdicts, and
+// divergences are also counted as metrics.CandidateDivergences and logged. Configure calls that
+// succeed for the served plugin are forwarded to the candidate.
+func WithCandidate(candidate PluginServer, opts ...CandidateOption) ServeOption {
+	return func(o *serveOptions) error {
+		if candidate == nil {
+			return fmt.Errorf("candidate cannot be nil")
+		}
+		co := &candidateOptions{maxInFlight: 64}
+		for _, opt := range opts {
+			if err := opt(co); err != nil {
+				return err
+			}
+		}
+		o.candidate = &candidateEvaluator{
+			o:        o,
+			impl:     candidate,
+			opts:     co,
+			inFlight: make(chan struct{}, co.maxInFlight),
+		}
+		return nil
+	}
+}
+
+type candidateEvaluator struct {
+	o        *serveOptions
+	impl     PluginServer
+	opts     *candidateOptions
+	inFlight chan struct{}
+}
+
+func (e *candidateEvaluator) interceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var in proto.Message
+		switch r := req.(type) {
+		case *HTTPRequest, *HTTPResponse:
+			// Copy the input before the current handler can mutate it.
+			in = proto.Clone(r.(proto.Message))
+		case *PluginConfig:
+			resp, err := handler(ctx, req)
+			if err == nil {
+				if _, cerr := e.impl.Configure(ctx, proto.Clone(r).(*PluginConfig)); cerr != nil {
+					e.o.logger.Printf("candidate: Configure failed: %v", cerr)
+				}
+			}
+			return resp, err
+		default:
+			return handler(ctx, req)
+		}
+
+		resp, err := handler(ctx, req)
+
+		select {
+		case e.inFlight <- struct{}{}:
+		default:
+			if r := e.o.metricsRecorder(); r != nil {
+				r.Count(metrics.CandidateSkipped, 1, metrics.L(metrics.LabelMethod, path.Base(info.FullMethod)))
+			}
+			return resp, err
+		}
+
+		current := Event{Err: err}
+		if out, ok := resp.(*HTTPResponse); ok && err == nil {
+			current.Result = out
+		}
+		go func() {
+			defer func() { <-e.inFlight }()
+			e.evaluate(context.WithoutCancel(ctx), path.Base(info.FullMethod), in, current)
+		}()
+
+		return resp, err
+	}
+}
+
+// evaluate runs the candidate on in and compares its verdict with current.
+func (e *candidateEvaluator) evaluate(ctx context.Context, method string, in proto.Message, current Event) {
+	ctx, cancel := context.WithTimeout(ctx, candidateTimeout)
+	defer cancel()
+
+	c := Comparison{Method: method}
+	candidate := Event{}
+	func() {
+		defer func() {
+			if p := recover(); p != nil {
+				candidate.Err = fmt.Errorf("candidate panicked: %v", p)
+			}
+		}()
+		switch r := in.(type) {
+		case *HTTPRequest:
+			c.Request, current.Request, candidate.Request = r, r, r
+			candidate.Result, candidate.Err = e.impl.HandleRequest(ctx, CloneRequest(r))
+		case *HTTPResponse:
+			c.Response, current.Response, candidate.Response = r, r, r
+			candidate.Result, candidate.Err = e.impl.HandleResponse(ctx, CloneResponse(r))
+		}
+	}()
+	if candidate.Err != nil {
+		candidate.Result = nil
+	}
+
+	c.Current, c.Candidate = current.Verdict(), candidate.Verdict()
+	c.CandidateErr = candidate.Err
+	if c.Current == metrics.VerdictShortCircuit {
+		c.CurrentStatus = current.Result.GetStatusCode()
+	}
+	if c.Candidate == metrics.VerdictShortCircuit {
+		c.CandidateStatus = candidate.Result.GetStatusCode()
+	}
+
+	if r := e.o.metricsRecorder(); r != nil {
+		labels := []metrics.Label{
+			metrics.L(metrics.LabelMethod, method),
+			metrics.L(metrics.LabelVerdict, c.Current),
+			metrics.L(metrics.LabelCandidateVerdict, c.Candidate),
+		}
+		r.Count(metrics.CandidateComparisons, 1, labels...)
+		if c.Diverged() {
+			r.Count(metrics.CandidateDivergences, 1, labels...)
+		}
+	}
+	if c.Diverged() {
+		e.logDivergence(ctx, c)
+	}
+	if e.opts.onCompare != nil {
+		e.opts.onCompare(ctx, c)
+	}
+}
+
+func (e *candidateEvaluator) logDivergence(ctx context.Context, c Comparison) {
+	detail := fmt.Sprintf("correlation_id=%q", CorrelationID(ctx, c.Request))
+	if c.Request != nil {
+		detail = fmt.Sprintf("http_method=%s path=%q tool=%q %s",
+			c.Request.GetMethod(), c.Request.GetPath(), mcp.ToolName(c.Request.GetBody()), detail)
+	}
+	if c.CandidateErr != nil {
+		detail += fmt.Sprintf(" candidate_error=%q", c.CandidateErr.Error())
+	}
+
+	e.o.logger.Printf("candidate divergence in %s: current=%s/%d candidate=%s/%d %s",
+		c.Method, c.Current, c.CurrentStatus, c.Candidate, c.CandidateStatus, detail)
+}
@@ -0,0 +1,378 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// candidatePlugin is a candidate handler short-circuiting with status, failing with err, or
+// panicking. It waits for release, when set, before answering.
+type candidatePlugin struct {
+	configuredPlugin
+
+	status  int32
+	err     error
+	panics  bool
+	seen    chan *HTTPRequest
+	release chan struct{}
+}
+
+func (p *candidatePlugin) verdict() (*HTTPResponse, error) {
+	if p.release != nil {
+		<-p.release
+	}
+	switch {
+	case p.panics:
+		panic("policy bug")
+	case p.err != nil:
+		return nil, p.err
+	case p.status != 0:
+		return &HTTPResponse{StatusCode: p.status}, nil
+	default:
+		return &HTTPResponse{Continue: true}, nil
+	}
+}
+
+func (p *candidatePlugin) HandleRequest(_ context.Context, req *HTTPRequest) (*HTTPResponse, error) {
+	if p.seen != nil {
+		p.seen <- req
+	}
+	return p.verdict()
+}
+
+func (p *candidatePlugin) HandleResponse(context.Context, *HTTPResponse) (*HTTPResponse, error) {
+	return p.verdict()
+}
+
+// newCandidateOptions returns serve options evaluating candidate, with a comparison handler sending
+// to the returned channel.
+func newCandidateOptions(
+	t *testing.T,
+	candidate PluginServer,
+	extra ...CandidateOption,
+) (*serveOptions, *recorderLog, *syncBuffer, chan Comparison) {
+	t.Helper()
+
+	r := &recorderLog{}
+	buf := &syncBuffer{}
+	compared := make(chan Comparison, 1)
+	opts := append([]CandidateOption{WithComparisonHandler(func(_ context.Context, c Comparison) {
+		compared <- c
+	})}, extra...)
+	o, err := newServeOptions(WithCandidate(candidate, opts...), WithMetrics(r), WithLogger(log.New(buf, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return o, r, buf, compared
+}
+
+func awaitComparison(t *testing.T, compared <-chan Comparison) Comparison {
+	t.Helper()
+
+	select {
+	case c := <-compared:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("no comparison was made")
+		return Comparison{}
+	}
+}
+
+func TestCandidateComparison(t *testing.T) {
+	errCurrent := errors.New("current failed")
+	tests := []struct {
+		name          string
+		method        string
+		in            any
+		current       *HTTPResponse
+		currentErr    error
+		candidate     *candidatePlugin
+		want          Comparison // Request, Response and CandidateErr are checked separately.
+		wantErr       bool       // Whether the candidate error is set.
+		wantDiverged  bool
+		wantLogSubstr string
+	}{
+		{
+			name:      "both continue",
+			method:    Plugin_HandleRequest_FullMethodName,
+			in:        &HTTPRequest{Method: "POST", Path: "/mcp"},
+			current:   &HTTPResponse{Continue: true},
+			candidate: &candidatePlugin{},
+			want:      Comparison{Method: "HandleRequest", Current: "continue", Candidate: "continue"},
+		},
+		{
+			name:      "same short-circuit",
+			method:    Plugin_HandleRequest_FullMethodName,
+			in:        &HTTPRequest{},
+			current:   &HTTPResponse{StatusCode: 403},
+			candidate: &candidatePlugin{status: 403},
+			want: Comparison{
+				Method: "HandleRequest", Current: "short_circuit", Candidate: "short_circuit",
+				CurrentStatus: 403, CandidateStatus: 403,
+			},
+		},
+		{
+			name:      "candidate blocks",
+			method:    Plugin_HandleRequest_FullMethodName,
+			in:        &HTTPRequest{Method: "POST", Path: "/mcp", Headers: map[string]string{"X-Request-Id": "r1"}},
+			current:   &HTTPResponse{Continue: true},
+			candidate: &candidatePlugin{status: 403},
+			want: Comparison{
+				Method: "HandleRequest", Current: "continue", Candidate: "short_circuit", CandidateStatus: 403,
+			},
+			wantDiverged: true,
+			wantLogSubstr: `candidate divergence in HandleRequest: current=continue/0 candidate=short_circuit/403 ` +
+				`http_method=POST path="/mcp" tool="" correlation_id="r1"`,
+		},
+		{
+			name:      "different status",
+			method:    Plugin_HandleResponse_FullMethodName,
+			in:        &HTTPResponse{StatusCode: 200},
+			current:   &HTTPResponse{StatusCode: 403},
+			candidate: &candidatePlugin{status: 451},
+			want: Comparison{
+				Method: "HandleResponse", Current: "short_circuit", Candidate: "short_circuit",
+				CurrentStatus: 403, CandidateStatus: 451,
+			},
+			wantDiverged:  true,
+			wantLogSubstr: "current=short_circuit/403 candidate=short_circuit/451 correlation_id=",
+		},
+		{
+			name:          "candidate error",
+			method:        Plugin_HandleRequest_FullMethodName,
+			in:            &HTTPRequest{},
+			current:       &HTTPResponse{Continue: true},
+			candidate:     &candidatePlugin{err: errors.New("cel: no such key")},
+			want:          Comparison{Method: "HandleRequest", Current: "continue", Candidate: "error"},
+			wantErr:       true,
+			wantDiverged:  true,
+			wantLogSubstr: `candidate_error="cel: no such key"`,
+		},
+		{
+			name:          "candidate panic",
+			method:        Plugin_HandleRequest_FullMethodName,
+			in:            &HTTPRequest{},
+			current:       &HTTPResponse{Continue: true},
+			candidate:     &candidatePlugin{panics: true},
+			want:          Comparison{Method: "HandleRequest", Current: "continue", Candidate: "error"},
+			wantErr:       true,
+			wantDiverged:  true,
+			wantLogSubstr: `candidate_error="candidate panicked: policy bug"`,
+		},
+		{
+			name:       "current error",
+			method:     Plugin_HandleRequest_FullMethodName,
+			in:         &HTTPRequest{},
+			currentErr: errCurrent,
+			candidate:  &candidatePlugin{err: errors.New("also failed")},
+			want:       Comparison{Method: "HandleRequest", Current: "error", Candidate: "error"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, r, buf, compared := newCandidateOptions(t, tt.candidate)
+			handler := func(context.Context, any) (any, error) {
+				if tt.currentErr != nil {
+					return nil, tt.currentErr
+				}
+				return tt.current, nil
+			}
+
+			resp, err := o.candidate.interceptor()(context.Background(), tt.in,
+				&grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if !errors.Is(err, tt.currentErr) {
+				t.Fatalf("error = %v, want the current handler's %v", err, tt.currentErr)
+			}
+			if tt.currentErr == nil && resp != tt.current {
+				t.Errorf("returned %v, want the current handler's %v", resp, tt.current)
+			}
+
+			c := awaitComparison(t, compared)
+			if (c.CandidateErr != nil) != tt.wantErr {
+				t.Errorf("CandidateErr = %v, want set %v", c.CandidateErr, tt.wantErr)
+			}
+			if (c.Request != nil) != (tt.method == Plugin_HandleRequest_FullMethodName) ||
+				(c.Response != nil) != (tt.method == Plugin_HandleResponse_FullMethodName) {
+				t.Errorf("comparison input = %v/%v, want the %s input", c.Request, c.Response, tt.method)
+			}
+			c.Request, c.Response, c.CandidateErr = nil, nil, nil
+			if c != tt.want {
+				t.Errorf("comparison = %+v, want %+v", c, tt.want)
+			}
+			if c.Diverged() != tt.wantDiverged {
+				t.Errorf("Diverged = %v, want %v", c.Diverged(), tt.wantDiverged)
+			}
+
+			labels := " method=" + tt.want.Method + " verdict=" + tt.want.Current +
+				" candidate_verdict=" + tt.want.Candidate
+			if got := r.named(metrics.CandidateComparisons); !slices.Equal(got,
+				[]string{"count candidate.comparisons 1" + labels}) {
+				t.Errorf("comparisons recorded %q", got)
+			}
+			wantDivergences := []string(nil)
+			if tt.wantDiverged {
+				wantDivergences = []string{"count candidate.divergences 1" + labels}
+			}
+			if got := r.named(metrics.CandidateDivergences); !slices.Equal(got, wantDivergences) {
+				t.Errorf("divergences recorded %q, want %q", got, wantDivergences)
+			}
+			if logged := buf.String(); tt.wantLogSubstr == "" && logged != "" ||
+				!strings.Contains(logged, tt.wantLogSubstr) {
+				t.Errorf("logged %q, want %q", logged, tt.wantLogSubstr)
+			}
+		})
+	}
+}
+
+func TestCandidateInputIsolated(t *testing.T) {
+	candidate := &candidatePlugin{seen: make(chan *HTTPRequest, 1)}
+	o, _, _, compared := newCandidateOptions(t, candidate)
+	req := &HTTPRequest{Path: "/mcp", Headers: map[string]string{"Authorization": "Bearer secret"}}
+
+	// The current handler strips a header; the candidate must still see the original request.
+	handler := func(_ context.Context, in any) (any, error) {
+		delete(in.(*HTTPRequest).Headers, "Authorization")
+		return &HTTPResponse{Continue: true}, nil
+	}
+	if _, err := o.candidate.interceptor()(context.Background(), req,
+		&grpc.UnaryServerInfo{FullMethod: Plugin_HandleRequest_FullMethodName}, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := <-candidate.seen
+	awaitComparison(t, compared)
+	if seen.GetHeaders()["Authorization"] != "Bearer secret" {
+		t.Errorf("candidate saw headers %v, want the request before the current handler ran", seen.GetHeaders())
+	}
+	if seen == req {
+		t.Error("candidate was given the served request itself")
+	}
+}
+
+func TestCandidateConfigure(t *testing.T) {
+	tests := []struct {
+		name         string
+		currentErr   error
+		candidateErr error
+		wantForward  bool
+		wantLog      string
+	}{
+		{name: "forwarded on success", wantForward: true},
+		{name: "not forwarded when current fails", currentErr: errors.New("bad config")},
+		{
+			name:         "candidate failure is logged",
+			candidateErr: errors.New("unknown key"),
+			wantLog:      "candidate: Configure failed: unknown key\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidate := &candidatePlugin{configuredPlugin: configuredPlugin{configureErr: tt.candidateErr}}
+			o, _, buf, _ := newCandidateOptions(t, candidate)
+			cfg := &PluginConfig{CustomConfig: map[string]string{"mode": "strict"}}
+
+			_, err := o.candidate.interceptor()(context.Background(), cfg,
+				&grpc.UnaryServerInfo{FullMethod: Plugin_Configure_FullMethodName},
+				func(context.Context, any) (any, error) { return nil, tt.currentErr })
+			if !errors.Is(err, tt.currentErr) {
+				t.Fatalf("error = %v, want %v", err, tt.currentErr)
+			}
+			if got := candidate.received(); (len(got) == 1) != tt.wantForward {
+				t.Errorf("candidate received %v, want forwarded %v", got, tt.wantForward)
+			}
+			if got := buf.String(); got != tt.wantLog {
+				t.Errorf("logged %q, want %q", got, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestCandidateMaxInFlight(t *testing.T) {
+	candidate := &candidatePlugin{release: make(chan struct{})}
+	o, r, _, compared := newCandidateOptions(t, candidate, WithCandidateMaxInFlight(1))
+	intercept := o.candidate.interceptor()
+	call := func() {
+		t.Helper()
+		if _, err := intercept(context.Background(), &HTTPRequest{},
+			&grpc.UnaryServerInfo{FullMethod: Plugin_HandleRequest_FullMethodName},
+			func(context.Context, any) (any, error) { return &HTTPResponse{Continue: true}, nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	call()
+	call() // Skipped: the first evaluation is still running.
+	close(candidate.release)
+	awaitComparison(t, compared)
+
+	if got := r.named(metrics.CandidateSkipped); !slices.Equal(got,
+		[]string{"count candidate.skipped 1 method=HandleRequest"}) {
+		t.Errorf("skipped recorded %q", got)
+	}
+	select {
+	case c := <-compared:
+		t.Errorf("skipped call was compared: %+v", c)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestCandidateOtherMethods(t *testing.T) {
+	o, _, _, compared := newCandidateOptions(t, &candidatePlugin{})
+
+	want := &Metadata{Name: "p"}
+	got, err := o.candidate.interceptor()(context.Background(), nil,
+		&grpc.UnaryServerInfo{FullMethod: Plugin_GetMetadata_FullMethodName},
+		func(context.Context, any) (any, error) { return want, nil })
+	if err != nil || got != want {
+		t.Errorf("GetMetadata = %v, %v; want the handler's result", got, err)
+	}
+	select {
+	case c := <-compared:
+		t.Errorf("GetMetadata was compared: %+v", c)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestWithCandidateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []ServeOption
+		want string
+	}{
+		{"nil candidate", []ServeOption{WithCandidate(nil)}, "candidate cannot be nil"},
+		{
+			"zero max in flight",
+			[]ServeOption{WithCandidate(&BasePlugin{}, WithCandidateMaxInFlight(0))},
+			"candidate max in flight must be at least 1",
+		},
+		{
+			"nil comparison handler",
+			[]ServeOption{WithCandidate(&BasePlugin{}, WithComparisonHandler(nil))},
+			"comparison handler cannot be nil",
+		},
+		{
+			"with message pooling",
+			[]ServeOption{WithCandidate(&BasePlugin{}), WithMessagePooling()},
+			"message pooling cannot be combined with a candidate",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newServeOptions(tt.opts...)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("newServeOptions error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package mcpdpluginsv1
+
+import (
+	"maps"
+	"slices"
+)
+
+// KnownFlows returns every flow defined by the generated plugin API, in enum order. Interception
+// points added to the proto appear here after regeneration without SDK changes.
+func KnownFlows() []Flow {
+	flows := make([]Flow, 0, len(Flow_name))
+	for _, v := range slices.Sorted(maps.Keys(Flow_name)) {
+		flows = append(flows, Flow(v))
+	}
+
+	return flows
+}
+
+// NewCapabilities returns Capabilities declaring flows, dropping duplicates while keeping order.
+//
+// Usage:
+//
+//	func (p *MyPlugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+//	    return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest, mcpdpluginsv1.FlowResponse), nil
+//	}
+func NewCapabilities(flows ...Flow) *Capabilities {
+	caps := &Capabilities{}
+	for _, f := range flows {
+		if !slices.Contains(caps.Flows, f) {
+			caps.Flows = append(caps.Flows, f)
+		}
+	}
+
+	return caps
+}
+
+// HasFlow reports whether the capabilities declare flow f.
+func (x *Capabilities) HasFlow(f Flow) bool {
+	return slices.Contains(x.GetFlows(), f)
+}
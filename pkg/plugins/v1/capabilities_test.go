@@ -0,0 +1,62 @@
+package mcpdpluginsv1
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestKnownFlows(t *testing.T) {
+	flows := KnownFlows()
+	if len(flows) != len(Flow_name) {
+		t.Fatalf("KnownFlows returned %d flows, want the %d defined", len(flows), len(Flow_name))
+	}
+	if !slices.IsSorted(flows) {
+		t.Errorf("KnownFlows = %v, want enum order", flows)
+	}
+	for _, f := range []Flow{FlowRequest, FlowResponse} {
+		if !slices.Contains(flows, f) {
+			t.Errorf("KnownFlows = %v, missing %s", flows, f)
+		}
+	}
+}
+
+func TestNewCapabilities(t *testing.T) {
+	tests := []struct {
+		name  string
+		flows []Flow
+		want  []Flow
+	}{
+		{name: "none"},
+		{name: "request", flows: []Flow{FlowRequest}, want: []Flow{FlowRequest}},
+		{
+			name:  "order kept",
+			flows: []Flow{FlowResponse, FlowRequest},
+			want:  []Flow{FlowResponse, FlowRequest},
+		},
+		{
+			name:  "duplicates dropped",
+			flows: []Flow{FlowRequest, FlowResponse, FlowRequest},
+			want:  []Flow{FlowRequest, FlowResponse},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caps := NewCapabilities(tt.flows...)
+			if !slices.Equal(caps.GetFlows(), tt.want) {
+				t.Errorf("flows = %v, want %v", caps.GetFlows(), tt.want)
+			}
+			for _, f := range KnownFlows() {
+				if got := caps.HasFlow(f); got != slices.Contains(tt.want, f) {
+					t.Errorf("HasFlow(%s) = %v", f, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHasFlowNil(t *testing.T) {
+	var caps *Capabilities
+	if caps.HasFlow(FlowRequest) {
+		t.Error("nil Capabilities declare the request flow")
+	}
+}
@@ -0,0 +1,83 @@
+// Package clock abstracts the passage of time for the SDK components that depend on it, such as
+// cache and DNS TTLs, rate limit windows, token expiry, circuit breaker cooldowns and retry
+// backoff, so their time-dependent behavior can be tested deterministically.
+//
+// Components default to Real and accept another Clock through a WithClock option. Tests pass a
+// plugintest.FakeClock and advance it explicitly:
+//
+//	clk := plugintest.NewFakeClock(time.Time{})
+//	c, err := cache.New(cfg, nil, cache.WithClock(clk))
+//	...
+//	clk.Advance(cfg.TTL) // The entry has now expired.
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+// Clock tells the time and creates timers.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer returns a Timer firing once, after d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single-shot timer created by a Clock, with the semantics of *time.Timer.
+type Timer interface {
+	// C returns the channel the time is delivered on when the timer fires.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing, reporting whether it was pending.
+	Stop() bool
+
+	// Reset makes the timer fire after d, reporting whether it was pending.
+	Reset(d time.Duration) bool
+}
+
+// Real returns the Clock of the system's wall clock.
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.t.C }
+
+func (t realTimer) Stop() bool { return t.t.Stop() }
+
+func (t realTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
+
+// Since returns the time elapsed on c since t.
+func Since(c Clock, t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Until returns the duration on c until t.
+func Until(c Clock, t time.Time) time.Duration {
+	return t.Sub(c.Now())
+}
+
+// Sleep waits for d to elapse on c, returning ctx's error early if ctx ends first.
+func Sleep(ctx context.Context, c Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := c.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
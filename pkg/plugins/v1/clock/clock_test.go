@@ -0,0 +1,110 @@
+package clock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/plugintest"
+)
+
+func TestSinceUntil(t *testing.T) {
+	tests := []struct {
+		name      string
+		advance   time.Duration
+		at        time.Duration // Offset of the time measured from the clock's start.
+		wantSince time.Duration
+		wantUntil time.Duration
+	}{
+		{name: "now"},
+		{name: "past", advance: time.Minute, wantSince: time.Minute, wantUntil: -time.Minute},
+		{name: "future", advance: time.Hour, at: 3 * time.Hour, wantSince: -2 * time.Hour, wantUntil: 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := plugintest.NewFakeClock(time.Time{})
+			at := clk.Now().Add(tt.at)
+			clk.Advance(tt.advance)
+			if got := clock.Since(clk, at); got != tt.wantSince {
+				t.Errorf("Since = %s, want %s", got, tt.wantSince)
+			}
+			if got := clock.Until(clk, at); got != tt.wantUntil {
+				t.Errorf("Until = %s, want %s", got, tt.wantUntil)
+			}
+		})
+	}
+}
+
+func TestSleep(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		d       time.Duration
+		wantErr error
+	}{
+		{name: "zero", ctx: context.Background(), d: 0},
+		{name: "negative", ctx: context.Background(), d: -time.Second},
+		{name: "zero with a canceled context", ctx: canceled, wantErr: context.Canceled},
+		{name: "canceled", ctx: canceled, d: time.Hour, wantErr: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := plugintest.NewFakeClock(time.Time{})
+			if err := clock.Sleep(tt.ctx, clk, tt.d); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Sleep error = %v, want %v", err, tt.wantErr)
+			}
+			if n := clk.Pending(); n != 0 {
+				t.Errorf("%d timers pending after Sleep, want none", n)
+			}
+		})
+	}
+}
+
+func TestSleepAdvance(t *testing.T) {
+	clk := plugintest.NewFakeClock(time.Time{})
+	done := make(chan error, 1)
+	go func() { done <- clock.Sleep(context.Background(), clk, time.Minute) }()
+
+	clk.WaitForTimers(1)
+	clk.Advance(time.Minute - time.Nanosecond)
+	select {
+	case err := <-done:
+		t.Fatalf("Sleep returned %v before the duration elapsed", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	clk.Advance(time.Nanosecond)
+	if err := <-done; err != nil {
+		t.Errorf("Sleep error = %v, want none", err)
+	}
+}
+
+func TestReal(t *testing.T) {
+	c := clock.Real()
+	before := time.Now()
+	if now := c.Now(); now.Before(before) || now.Sub(before) > time.Second {
+		t.Errorf("Now = %s, want about %s", now, before)
+	}
+
+	timer := c.NewTimer(time.Millisecond)
+	select {
+	case <-timer.C():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timer did not fire")
+	}
+	if timer.Stop() {
+		t.Error("Stop reported a fired timer as pending")
+	}
+	if timer.Reset(time.Hour) {
+		t.Error("Reset reported a fired timer as pending")
+	}
+	if !timer.Stop() {
+		t.Error("Stop reported a reset timer as not pending")
+	}
+	if err := clock.Sleep(context.Background(), c, time.Millisecond); err != nil {
+		t.Errorf("Sleep error = %v, want none", err)
+	}
+}
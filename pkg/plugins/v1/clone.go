@@ -0,0 +1,132 @@
+package mcpdpluginsv1
+
+// CloneRequest returns a deep copy of req, including its headers map and body, so the copy can be
+// mutated or handed to another goroutine (for example an async auditor) without sharing state
+// with the original. It returns nil for a nil req.
+func CloneRequest(req *HTTPRequest) *HTTPRequest {
+	return req.CloneVT()
+}
+
+// CloneResponse returns a deep copy of resp, including any ModifiedRequest, like CloneRequest.
+func CloneResponse(resp *HTTPResponse) *HTTPResponse {
+	return resp.CloneVT()
+}
+
+// COWRequest wraps a request received by a handler and copies it on first mutation, so handlers
+// that change a request only on some paths neither clone needlessly nor mutate the original:
+//
+//	cow := mcpdpluginsv1.NewCOWRequest(req)
+//	if strip {
+//	    delete(cow.Mutate().Headers, "Cookie")
+//	}
+//	audit(req, cow.Get()) // compare original and final request
+//	return cow.Result(), nil
+//
+// A COWRequest is not safe for concurrent use.
+type COWRequest struct {
+	original *HTTPRequest
+	modified *HTTPRequest
+}
+
+// NewCOWRequest returns a COWRequest over req, which it never modifies.
+func NewCOWRequest(req *HTTPRequest) *COWRequest {
+	return &COWRequest{original: req}
+}
+
+// Original returns the wrapped request. It must not be mutated.
+func (c *COWRequest) Original() *HTTPRequest {
+	return c.original
+}
+
+// Get returns the current request: the copy once Mutate has been called, the original before.
+// It must not be mutated; use Mutate.
+func (c *COWRequest) Get() *HTTPRequest {
+	if c.modified != nil {
+		return c.modified
+	}
+
+	return c.original
+}
+
+// Mutate returns the request to modify, copying the original on first call.
+func (c *COWRequest) Mutate() *HTTPRequest {
+	if c.modified == nil {
+		c.modified = CloneRequest(c.original)
+		if c.modified == nil {
+			c.modified = &HTTPRequest{}
+		}
+	}
+
+	return c.modified
+}
+
+// Modified reports whether Mutate has been called.
+func (c *COWRequest) Modified() bool {
+	return c.modified != nil
+}
+
+// Result returns a response continuing the chain, carrying the copy as ModifiedRequest when the
+// request was mutated.
+func (c *COWRequest) Result() *HTTPResponse {
+	return &HTTPResponse{Continue: true, ModifiedRequest: c.modified}
+}
+
+// COWResponse wraps a response received by HandleResponse and copies it on first mutation, like
+// COWRequest. It is not safe for concurrent use.
+type COWResponse struct {
+	original *HTTPResponse
+	modified *HTTPResponse
+}
+
+// NewCOWResponse returns a COWResponse over resp, which it never modifies.
+func NewCOWResponse(resp *HTTPResponse) *COWResponse {
+	return &COWResponse{original: resp}
+}
+
+// Original returns the wrapped response. It must not be mutated.
+func (c *COWResponse) Original() *HTTPResponse {
+	return c.original
+}
+
+// Get returns the current response: the copy once Mutate has been called, the original before.
+// It must not be mutated; use Mutate.
+func (c *COWResponse) Get() *HTTPResponse {
+	if c.modified != nil {
+		return c.modified
+	}
+
+	return c.original
+}
+
+// Mutate returns the response to modify, copying the original on first call.
+func (c *COWResponse) Mutate() *HTTPResponse {
+	if c.modified == nil {
+		c.modified = CloneResponse(c.original)
+		if c.modified == nil {
+			c.modified = &HTTPResponse{}
+		}
+	}
+
+	return c.modified
+}
+
+// Modified reports whether Mutate has been called.
+func (c *COWResponse) Modified() bool {
+	return c.modified != nil
+}
+
+// Result returns the current response with Continue set, for returning from HandleResponse. An
+// unmodified response is returned as a new message sharing the original's headers and body.
+func (c *COWResponse) Result() *HTTPResponse {
+	if c.modified != nil {
+		c.modified.Continue = true
+		return c.modified
+	}
+
+	return &HTTPResponse{
+		Continue:   true,
+		StatusCode: c.original.GetStatusCode(),
+		Headers:    c.original.GetHeaders(),
+		Body:       c.original.GetBody(),
+	}
+}
@@ -0,0 +1,67 @@
+package mcpdpluginsv1
+
+import (
+	"google.golang.org/grpc/encoding"
+	grpcproto "google.golang.org/grpc/encoding/proto"
+	"google.golang.org/grpc/mem"
+)
+
+// vtMessage is implemented by the plugin messages through the vtprotobuf code generated into
+// plugin_vtproto.pb.go.
+type vtMessage interface {
+	SizeVT() int
+	MarshalToSizedBufferVT(dAtA []byte) (int, error)
+	UnmarshalVT(dAtA []byte) error
+}
+
+// vtCodec is the codec Serve installs: the gRPC proto codec, with the plugin messages encoded and
+// decoded by their generated vtprotobuf methods instead of protobuf reflection. Like the methods
+// it calls, Unmarshal merges into the message, which gRPC always hands over freshly allocated.
+type vtCodec struct {
+	base encoding.CodecV2
+}
+
+func newVTCodec() vtCodec {
+	return vtCodec{base: encoding.GetCodecV2(grpcproto.Name)}
+}
+
+func (c vtCodec) Marshal(v any) (mem.BufferSlice, error) {
+	m, ok := v.(vtMessage)
+	if !ok {
+		return c.base.Marshal(v)
+	}
+
+	size := m.SizeVT()
+	if mem.IsBelowBufferPoolingThreshold(size) {
+		buf := make([]byte, size)
+		if _, err := m.MarshalToSizedBufferVT(buf); err != nil {
+			return nil, err
+		}
+		return mem.BufferSlice{mem.SliceBuffer(buf)}, nil
+	}
+
+	pool := mem.DefaultBufferPool()
+	buf := pool.Get(size)
+	if _, err := m.MarshalToSizedBufferVT((*buf)[:size]); err != nil {
+		pool.Put(buf)
+		return nil, err
+	}
+
+	return mem.BufferSlice{mem.NewBuffer(buf, pool)}, nil
+}
+
+func (c vtCodec) Unmarshal(data mem.BufferSlice, v any) error {
+	m, ok := v.(vtMessage)
+	if !ok {
+		return c.base.Unmarshal(data, v)
+	}
+
+	buf := data.MaterializeToBuffer(mem.DefaultBufferPool())
+	defer buf.Free()
+
+	return m.UnmarshalVT(buf.ReadOnlyData())
+}
+
+func (c vtCodec) Name() string {
+	return c.base.Name()
+}
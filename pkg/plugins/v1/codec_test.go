@@ -0,0 +1,129 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/mem"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// codecMessages returns one populated value of each plugin message, plus empty and large ones.
+func codecMessages() []proto.Message {
+	req := &HTTPRequest{
+		Method:     "POST",
+		Url:        "http://upstream/mcp?x=1",
+		Path:       "/mcp",
+		Headers:    map[string]string{"Content-Type": "application/json", "Mcp-Session-Id": "s1", "Empty": ""},
+		Body:       []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`),
+		RemoteAddr: "10.0.0.1:1234",
+		RequestUri: "/mcp?x=1",
+	}
+
+	return []proto.Message{
+		&HTTPRequest{},
+		req,
+		&HTTPRequest{Body: bytes.Repeat([]byte("a"), 1<<20)},
+		&HTTPResponse{
+			StatusCode:      403,
+			Headers:         map[string]string{"X": "y"},
+			Body:            []byte("denied"),
+			ModifiedRequest: req,
+		},
+		&HTTPResponse{Continue: true},
+		&Metadata{Name: "p", Version: "1.0.0", Description: "d", CommitHash: "abc", BuildDate: "2026-01-01"},
+		&Capabilities{Flows: []Flow{Flow_FLOW_REQUEST, Flow_FLOW_RESPONSE}},
+		&PluginConfig{
+			Telemetry:    &TelemetryConfig{OtlpEndpoint: "http://collector:4318", ServiceName: "svc", SampleRatio: 0.5},
+			CustomConfig: map[string]string{"limit": "5"},
+		},
+		&emptypb.Empty{},
+	}
+}
+
+func TestVTCodecRoundTrip(t *testing.T) {
+	c := newVTCodec()
+	if c.Name() != "proto" {
+		t.Errorf("Name = %q, want proto", c.Name())
+	}
+	for _, m := range codecMessages() {
+		name := string(m.ProtoReflect().Descriptor().Name())
+		t.Run(name, func(t *testing.T) {
+			data, err := c.Marshal(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer data.Free()
+
+			// The encoding decodes with protobuf reflection, and back with the codec.
+			want, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data.Len() != len(want) {
+				t.Errorf("encoded %d bytes, protobuf encodes %d", data.Len(), len(want))
+			}
+			viaProto := m.ProtoReflect().New().Interface()
+			if err := proto.Unmarshal(data.Materialize(), viaProto); err != nil || !proto.Equal(viaProto, m) {
+				t.Errorf("proto.Unmarshal = %v, %v; want %v", viaProto, err, m)
+			}
+			got := m.ProtoReflect().New().Interface()
+			if err := c.Unmarshal(mem.BufferSlice{mem.SliceBuffer(want)}, got); err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(got, m) {
+				t.Errorf("Unmarshal = %v, want %v", got, m)
+			}
+		})
+	}
+}
+
+func TestVTCodecUnmarshalErrors(t *testing.T) {
+	c := newVTCodec()
+	truncated, err := proto.Marshal(&HTTPRequest{Method: "POST", Body: []byte("body")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		msg  proto.Message
+	}{
+		{name: "truncated", data: truncated[:len(truncated)-2], msg: &HTTPRequest{}},
+		{name: "bad wire type", data: protowire.AppendTag(nil, 1, protowire.Type(7)), msg: &HTTPResponse{}},
+		{name: "bad reflection message", data: []byte{0xff}, msg: &emptypb.Empty{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.Unmarshal(mem.BufferSlice{mem.SliceBuffer(tt.data)}, tt.msg); err == nil {
+				t.Error("Unmarshal accepted invalid data")
+			}
+		})
+	}
+}
+
+func TestVTCodecUnknownFields(t *testing.T) {
+	c := newVTCodec()
+	data := protowire.AppendTag(nil, 99, protowire.BytesType)
+	data = protowire.AppendString(data, "future")
+	data = protowire.AppendTag(data, 1, protowire.BytesType)
+	data = protowire.AppendString(data, "GET")
+
+	var req HTTPRequest
+	if err := c.Unmarshal(mem.BufferSlice{mem.SliceBuffer(data)}, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.GetMethod() != "GET" || len(req.ProtoReflect().GetUnknown()) == 0 {
+		t.Fatalf("Unmarshal = %v, want the method and an unknown field", &req)
+	}
+	out, err := c.Marshal(&req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out.Materialize()), "future") {
+		t.Error("Marshal dropped the unknown field")
+	}
+}
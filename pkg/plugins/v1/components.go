@@ -0,0 +1,121 @@
+package mcpdpluginsv1
+
+import (
+	"errors"
+	"runtime/debug"
+	"strings"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/inventory"
+)
+
+// cycloneDXSpecVersion is the CycloneDX specification version of the admin GetComponents list.
+const cycloneDXSpecVersion = "1.5"
+
+// errNoBuildInfo is returned when the binary carries no Go build information.
+var errNoBuildInfo = errors.New("binary has no build information")
+
+// componentList returns a CycloneDX-style bill of materials of the running binary, as a structpb
+// compatible map: the plugin's main module as the metadata component, and the Go standard
+// library and every linked module as library components, with package URLs. Module licenses
+// come from the plugin's InventoryProvider, when it implements one.
+func componentList(impl PluginServer) (map[string]any, error) {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil, errNoBuildInfo
+	}
+
+	return buildComponentList(bi, impl), nil
+}
+
+// buildComponentList returns the component list of the binary described by bi.
+func buildComponentList(bi *debug.BuildInfo, impl PluginServer) map[string]any {
+	licenses := map[string][]string{}
+	if p, ok := impl.(InventoryProvider); ok {
+		if inv, err := inventory.Decode(p.Inventory()); err == nil {
+			licenses[inv.Main.Path] = inv.Main.Licenses
+			for _, m := range inv.Modules {
+				licenses[m.Path] = m.Licenses
+			}
+		}
+	}
+
+	main := moduleComponent(&bi.Main, "application", licenses)
+	props, _ := main["properties"].([]any)
+	for _, s := range bi.Settings {
+		if s.Key == "vcs.revision" || s.Key == "vcs.time" || s.Key == "GOOS" || s.Key == "GOARCH" {
+			props = append(props, map[string]any{"name": "go:" + s.Key, "value": s.Value})
+		}
+	}
+	if len(props) > 0 {
+		main["properties"] = props
+	}
+
+	components := []any{map[string]any{
+		"type":    "library",
+		"bom-ref": "pkg:golang/stdlib@" + bi.GoVersion,
+		"name":    "stdlib",
+		"version": bi.GoVersion,
+		"purl":    "pkg:golang/stdlib@" + bi.GoVersion,
+	}}
+	for _, d := range bi.Deps {
+		components = append(components, moduleComponent(d, "library", licenses))
+	}
+
+	return map[string]any{
+		"bomFormat":   "CycloneDX",
+		"specVersion": cycloneDXSpecVersion,
+		"metadata":    map[string]any{"component": main},
+		"components":  components,
+	}
+}
+
+// moduleComponent returns the component of a Go module. A replaced module is reported at the
+// replacement's version, with the replacement recorded as a property.
+func moduleComponent(m *debug.Module, typ string, licenses map[string][]string) map[string]any {
+	version, sum := m.Version, m.Sum
+	var props []any
+	if r := m.Replace; r != nil {
+		version, sum = r.Version, r.Sum
+		replace := strings.TrimSpace(r.Path + " " + r.Version)
+		props = append(props, map[string]any{"name": "go:replace", "value": replace})
+	}
+	if sum != "" {
+		props = append(props, map[string]any{"name": "go:sum", "value": sum})
+	}
+
+	purl := "pkg:golang/" + m.Path
+	if version != "" && version != "(devel)" {
+		purl += "@" + version
+	}
+	c := map[string]any{
+		"type":    typ,
+		"bom-ref": purl,
+		"name":    m.Path,
+		"purl":    purl,
+	}
+	if version != "" {
+		c["version"] = version
+	}
+	if ids := licenses[m.Path]; len(ids) > 0 {
+		var ls []any
+		for _, id := range ids {
+			if id == inventory.NoAssertion {
+				continue
+			}
+			if strings.Contains(id, " ") {
+				// An SPDX expression, such as "Apache-2.0 OR MIT", rather than a single license.
+				ls = append(ls, map[string]any{"expression": id})
+				continue
+			}
+			ls = append(ls, map[string]any{"license": map[string]any{"id": id}})
+		}
+		if len(ls) > 0 {
+			c["licenses"] = ls
+		}
+	}
+	if len(props) > 0 {
+		c["properties"] = props
+	}
+
+	return c
+}
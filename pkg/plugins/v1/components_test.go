@@ -0,0 +1,155 @@
+package mcpdpluginsv1
+
+import (
+	"reflect"
+	"runtime/debug"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// testBuildInfo describes a plugin binary with a replaced, a locally replaced and a plain module.
+func testBuildInfo() *debug.BuildInfo {
+	return &debug.BuildInfo{
+		GoVersion: "go1.25.1",
+		Main:      debug.Module{Path: "example.com/plugin", Version: "(devel)"},
+		Deps: []*debug.Module{
+			{Path: "example.com/a", Version: "v1.0.0", Sum: "h1:a"},
+			{
+				Path:    "example.com/b",
+				Version: "v1.0.0",
+				Sum:     "h1:b",
+				Replace: &debug.Module{Path: "example.com/fork", Version: "v1.0.1", Sum: "h1:fork"},
+			},
+			{Path: "example.com/c", Version: "v0.1.0", Replace: &debug.Module{Path: "../c"}},
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "-trimpath", Value: "true"},
+			{Key: "GOOS", Value: "linux"},
+			{Key: "vcs.revision", Value: "abc123"},
+		},
+	}
+}
+
+func TestBuildComponentList(t *testing.T) {
+	bom := buildComponentList(testBuildInfo(), &BasePlugin{})
+
+	if _, err := structpb.NewStruct(bom); err != nil {
+		t.Fatalf("component list is not structpb compatible: %v", err)
+	}
+	if bom["bomFormat"] != "CycloneDX" || bom["specVersion"] != cycloneDXSpecVersion {
+		t.Errorf("header = %v %v, want CycloneDX %s", bom["bomFormat"], bom["specVersion"], cycloneDXSpecVersion)
+	}
+
+	wantMain := map[string]any{
+		"type":    "application",
+		"bom-ref": "pkg:golang/example.com/plugin",
+		"name":    "example.com/plugin",
+		"purl":    "pkg:golang/example.com/plugin",
+		"version": "(devel)",
+		"properties": []any{
+			map[string]any{"name": "go:GOOS", "value": "linux"},
+			map[string]any{"name": "go:vcs.revision", "value": "abc123"},
+		},
+	}
+	if got := bom["metadata"].(map[string]any)["component"]; !reflect.DeepEqual(got, wantMain) {
+		t.Errorf("metadata component = %v, want %v", got, wantMain)
+	}
+
+	wantComponents := []any{
+		map[string]any{
+			"type":    "library",
+			"bom-ref": "pkg:golang/stdlib@go1.25.1",
+			"name":    "stdlib",
+			"version": "go1.25.1",
+			"purl":    "pkg:golang/stdlib@go1.25.1",
+		},
+		map[string]any{
+			"type":       "library",
+			"bom-ref":    "pkg:golang/example.com/a@v1.0.0",
+			"name":       "example.com/a",
+			"version":    "v1.0.0",
+			"purl":       "pkg:golang/example.com/a@v1.0.0",
+			"properties": []any{map[string]any{"name": "go:sum", "value": "h1:a"}},
+		},
+		map[string]any{
+			"type":    "library",
+			"bom-ref": "pkg:golang/example.com/b@v1.0.1",
+			"name":    "example.com/b",
+			"version": "v1.0.1",
+			"purl":    "pkg:golang/example.com/b@v1.0.1",
+			"properties": []any{
+				map[string]any{"name": "go:replace", "value": "example.com/fork v1.0.1"},
+				map[string]any{"name": "go:sum", "value": "h1:fork"},
+			},
+		},
+		map[string]any{
+			"type":       "library",
+			"bom-ref":    "pkg:golang/example.com/c",
+			"name":       "example.com/c",
+			"purl":       "pkg:golang/example.com/c",
+			"properties": []any{map[string]any{"name": "go:replace", "value": "../c"}},
+		},
+	}
+	if got := bom["components"]; !reflect.DeepEqual(got, wantComponents) {
+		t.Errorf("components =\n%v\nwant\n%v", got, wantComponents)
+	}
+}
+
+func TestBuildComponentListLicenses(t *testing.T) {
+	tests := []struct {
+		name string
+		impl PluginServer
+		want map[string]any // Licenses by component name; absent components have none.
+	}{
+		{name: "no inventory", impl: &BasePlugin{}, want: map[string]any{}},
+		{
+			name: "inventory",
+			impl: &inventoriedPlugin{inv: []byte(`{
+				"main": {"path": "example.com/plugin", "licenses": ["Apache-2.0"]},
+				"modules": [
+					{"path": "example.com/a", "licenses": ["MIT", "NOASSERTION"]},
+					{"path": "example.com/b", "licenses": ["Apache-2.0 OR MIT"]},
+					{"path": "example.com/c", "licenses": ["NOASSERTION"]}
+				]
+			}`)},
+			want: map[string]any{
+				"example.com/plugin": []any{map[string]any{"license": map[string]any{"id": "Apache-2.0"}}},
+				"example.com/a":      []any{map[string]any{"license": map[string]any{"id": "MIT"}}},
+				"example.com/b":      []any{map[string]any{"expression": "Apache-2.0 OR MIT"}},
+			},
+		},
+		{name: "invalid inventory", impl: &inventoriedPlugin{inv: []byte("{")}, want: map[string]any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bom := buildComponentList(testBuildInfo(), tt.impl)
+			components := append([]any{bom["metadata"].(map[string]any)["component"]}, bom["components"].([]any)...)
+			got := map[string]any{}
+			for _, c := range components {
+				c := c.(map[string]any)
+				if ls, ok := c["licenses"]; ok {
+					got[c["name"].(string)] = ls
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("licenses = %v, want %v", got, tt.want)
+			}
+			if _, err := structpb.NewStruct(bom); err != nil {
+				t.Errorf("component list is not structpb compatible: %v", err)
+			}
+		})
+	}
+}
+
+func TestComponentList(t *testing.T) {
+	// Test binaries carry build information too.
+	bom, err := componentList(&BasePlugin{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	components, _ := bom["components"].([]any)
+	if len(components) == 0 || components[0].(map[string]any)["name"] != "stdlib" {
+		t.Errorf("components = %v, want the standard library first", components)
+	}
+}
@@ -0,0 +1,80 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/concurrency"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// WithAdaptiveConcurrency limits concurrent HandleRequest and HandleResponse calls to a limit
+// computed by alg from observed latencies (see concurrency.NewAIMD and concurrency.NewGradient).
+// Calls beyond the limit are rejected with Throttle, suggesting a retry after retryAfter.
+// Calls that time out or fail with codes.ResourceExhausted or codes.Unavailable count as drops
+// and shrink the limit; other errors are ignored. The limit is recorded as
+// metrics.ConcurrencyLimit when WithMetrics is configured.
+func WithAdaptiveConcurrency(alg concurrency.Algorithm, retryAfter time.Duration) ServeOption {
+	return func(o *serveOptions) error {
+		if retryAfter <= 0 {
+			return fmt.Errorf("concurrency retry-after must be positive")
+		}
+		l, err := concurrency.New(alg)
+		if err != nil {
+			return err
+		}
+		o.interceptors = append(o.interceptors, concurrencyInterceptor(o, l, retryAfter))
+		return nil
+	}
+}
+
+func concurrencyInterceptor(
+	o *serveOptions,
+	l *concurrency.Limiter,
+	retryAfter time.Duration,
+) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !isHandlerMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		tok, ok := l.Acquire()
+		if !ok {
+			return nil, o.throttle(ctx, info, retryAfter, ThrottleConcurrency)
+		}
+		resp, err := handler(ctx, req)
+		switch {
+		case err == nil:
+			tok.Success()
+		case isOverload(ctx, err):
+			tok.Dropped()
+		default:
+			tok.Ignore()
+		}
+		if r := o.metricsRecorder(); r != nil {
+			r.Gauge(metrics.ConcurrencyLimit, float64(l.Limit()))
+		}
+
+		return resp, err
+	}
+}
+
+// isOverload reports whether err signals that the plugin or its upstream is overloaded. Calls
+// canceled by mcpd are not overloads.
+func isOverload(ctx context.Context, err error) bool {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.DeadlineExceeded, codes.ResourceExhausted, codes.Unavailable:
+		return true
+	default:
+		return false
+	}
+}
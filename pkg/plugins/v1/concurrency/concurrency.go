@@ -0,0 +1,306 @@
+// Package concurrency limits the number of calls a plugin handles at once with a limit that
+// adapts to observed latency, in the style of Netflix's concurrency-limits. It suits plugins
+// wrapping upstreams whose latency varies, where no static limit is right for long.
+//
+// An Algorithm (NewAIMD or NewGradient) computes the limit; a Limiter enforces it:
+//
+//	alg, err := concurrency.NewGradient(concurrency.GradientConfig{})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	err = mcpdpluginsv1.Serve(&MyPlugin{}, mcpdpluginsv1.WithAdaptiveConcurrency(alg, time.Second))
+package concurrency
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Algorithm computes a concurrency limit from completed calls. Limiter serialises calls to an
+// Algorithm, so implementations need not be safe for concurrent use.
+type Algorithm interface {
+	// Limit returns the current limit.
+	Limit() int
+
+	// Update adjusts the limit after a call that took rtt and started with inFlight calls
+	// (itself included) running. dropped reports an overload signal such as a timeout or an
+	// upstream rejecting the call. It returns the new limit.
+	Update(rtt time.Duration, inFlight int, dropped bool) int
+}
+
+// Limiter admits calls while fewer than its Algorithm's limit are in flight. It is safe for
+// concurrent use.
+type Limiter struct {
+	mu       sync.Mutex
+	alg      Algorithm
+	inFlight int
+}
+
+// New returns a Limiter enforcing the limit computed by alg.
+func New(alg Algorithm) (*Limiter, error) {
+	if alg == nil {
+		return nil, fmt.Errorf("concurrency algorithm is required")
+	}
+
+	return &Limiter{alg: alg}, nil
+}
+
+// Acquire admits a call, returning a Token to complete once the call finishes, or reports false
+// when the limit is reached.
+func (l *Limiter) Acquire() (*Token, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight >= l.alg.Limit() {
+		return nil, false
+	}
+	l.inFlight++
+
+	return &Token{l: l, start: time.Now(), inFlight: l.inFlight}, true
+}
+
+// Limit returns the current limit.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.alg.Limit()
+}
+
+// InFlight returns the number of admitted calls not yet completed.
+func (l *Limiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.inFlight
+}
+
+// Token is an admitted call. Exactly one of Success, Dropped or Ignore should be called; later
+// calls are no-ops.
+type Token struct {
+	l        *Limiter
+	start    time.Time
+	inFlight int
+	once     sync.Once
+}
+
+// Success completes a call whose latency reflects the plugin's load, feeding it to the Algorithm.
+func (t *Token) Success() {
+	t.complete(true, false)
+}
+
+// Dropped completes a call that hit an overload signal, such as a timeout, so the limit backs off.
+func (t *Token) Dropped() {
+	t.complete(true, true)
+}
+
+// Ignore completes a call whose latency says nothing about load, such as one failing validation.
+func (t *Token) Ignore() {
+	t.complete(false, false)
+}
+
+func (t *Token) complete(sample, dropped bool) {
+	t.once.Do(func() {
+		rtt := time.Since(t.start)
+
+		t.l.mu.Lock()
+		defer t.l.mu.Unlock()
+
+		t.l.inFlight--
+		if sample {
+			t.l.alg.Update(rtt, t.inFlight, dropped)
+		}
+	})
+}
+
+// bounds holds the limits shared by the algorithms.
+type bounds struct {
+	min, max int
+}
+
+func (b bounds) clamp(v float64) float64 {
+	return math.Min(math.Max(v, float64(b.min)), float64(b.max))
+}
+
+func newBounds(initial, lo, hi *int) (bounds, error) {
+	if *lo == 0 {
+		*lo = 1
+	}
+	if *hi == 0 {
+		*hi = 1000
+	}
+	if *initial == 0 {
+		*initial = min(20, *hi)
+	}
+	switch {
+	case *lo < 1:
+		return bounds{}, fmt.Errorf("minimum limit must be at least 1")
+	case *hi < *lo:
+		return bounds{}, fmt.Errorf("maximum limit %d is below minimum %d", *hi, *lo)
+	case *initial < *lo || *initial > *hi:
+		return bounds{}, fmt.Errorf("initial limit %d is outside [%d, %d]", *initial, *lo, *hi)
+	}
+
+	return bounds{min: *lo, max: *hi}, nil
+}
+
+// AIMDConfig configures NewAIMD. Zero fields take their defaults.
+type AIMDConfig struct {
+	// Initial is the starting limit (default 20, capped at Max).
+	Initial int
+
+	// Min and Max bound the limit (defaults 1 and 1000).
+	Min int
+	Max int
+
+	// Backoff multiplies the limit after a dropped call (default 0.9).
+	Backoff float64
+
+	// Timeout, when positive, treats calls slower than it as dropped.
+	Timeout time.Duration
+}
+
+// NewAIMD returns an additive-increase/multiplicative-decrease Algorithm: the limit grows by one
+// after each successful call made while at least half the limit was in use, and is multiplied by
+// Backoff after a dropped one.
+func NewAIMD(cfg AIMDConfig) (Algorithm, error) {
+	b, err := newBounds(&cfg.Initial, &cfg.Min, &cfg.Max)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Backoff == 0 {
+		cfg.Backoff = 0.9
+	}
+	if cfg.Backoff <= 0 || cfg.Backoff >= 1 {
+		return nil, fmt.Errorf("backoff must be between 0 and 1, got %v", cfg.Backoff)
+	}
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("timeout cannot be negative")
+	}
+
+	return &aimd{bounds: b, cfg: cfg, limit: cfg.Initial}, nil
+}
+
+type aimd struct {
+	bounds
+	cfg   AIMDConfig
+	limit int
+}
+
+func (a *aimd) Limit() int {
+	return a.limit
+}
+
+func (a *aimd) Update(rtt time.Duration, inFlight int, dropped bool) int {
+	switch {
+	case dropped || (a.cfg.Timeout > 0 && rtt > a.cfg.Timeout):
+		a.limit = int(a.clamp(math.Floor(float64(a.limit) * a.cfg.Backoff)))
+	case inFlight*2 >= a.limit:
+		a.limit = int(a.clamp(float64(a.limit + 1)))
+	}
+
+	return a.limit
+}
+
+// GradientConfig configures NewGradient. Zero fields take their defaults.
+type GradientConfig struct {
+	// Initial is the starting limit (default 20, capped at Max).
+	Initial int
+
+	// Min and Max bound the limit (defaults 1 and 1000).
+	Min int
+	Max int
+
+	// Tolerance is how far the latency may rise above its long-term average before the limit
+	// shrinks, as a ratio (default 1.5).
+	Tolerance float64
+
+	// Smoothing is the weight of each new estimate in the limit, between 0 and 1 (default 0.2).
+	Smoothing float64
+
+	// QueueSize is the headroom added to the estimate so the limit can probe upwards (default 4).
+	QueueSize int
+
+	// LongWindow is the number of calls the long-term latency average spans (default 600).
+	LongWindow int
+}
+
+// NewGradient returns an Algorithm modelled on Netflix's Gradient2: it compares each call's
+// latency with a long-term average and scales the limit by their ratio, shrinking it as queueing
+// inflates latency and growing it by QueueSize while latency stays within Tolerance.
+func NewGradient(cfg GradientConfig) (Algorithm, error) {
+	b, err := newBounds(&cfg.Initial, &cfg.Min, &cfg.Max)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Tolerance == 0 {
+		cfg.Tolerance = 1.5
+	}
+	if cfg.Smoothing == 0 {
+		cfg.Smoothing = 0.2
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = 4
+	}
+	if cfg.LongWindow == 0 {
+		cfg.LongWindow = 600
+	}
+	switch {
+	case cfg.Tolerance < 1:
+		return nil, fmt.Errorf("tolerance must be at least 1, got %v", cfg.Tolerance)
+	case cfg.Smoothing < 0 || cfg.Smoothing > 1:
+		return nil, fmt.Errorf("smoothing must be between 0 and 1, got %v", cfg.Smoothing)
+	case cfg.QueueSize < 0 || cfg.LongWindow < 0:
+		return nil, fmt.Errorf("queue size and long window cannot be negative")
+	}
+
+	return &gradient{bounds: b, cfg: cfg, estimate: float64(cfg.Initial)}, nil
+}
+
+type gradient struct {
+	bounds
+	cfg      GradientConfig
+	estimate float64
+	longRTT  float64
+	samples  int
+}
+
+func (g *gradient) Limit() int {
+	return int(g.estimate)
+}
+
+func (g *gradient) Update(rtt time.Duration, inFlight int, dropped bool) int {
+	short := float64(rtt)
+	if short <= 0 {
+		return g.Limit()
+	}
+
+	// The long-term average is a plain mean until LongWindow samples, then an EWMA over them.
+	g.samples++
+	if g.samples <= g.cfg.LongWindow {
+		g.longRTT += (short - g.longRTT) / float64(g.samples)
+	} else {
+		g.longRTT += (short - g.longRTT) * 2 / float64(g.cfg.LongWindow+1)
+	}
+	// Pull the average down quickly once latency recovers, so a past overload does not keep the
+	// limit high for the whole window.
+	if g.longRTT/short > 2 {
+		g.longRTT *= 0.95
+	}
+
+	// An under-used limit says nothing about capacity: keep it.
+	if !dropped && float64(inFlight) < g.estimate/2 {
+		return g.Limit()
+	}
+
+	ratio := math.Max(0.5, math.Min(1, g.cfg.Tolerance*g.longRTT/short))
+	if dropped {
+		ratio = 0.5
+	}
+	next := g.estimate*ratio + float64(g.cfg.QueueSize)
+	g.estimate = g.clamp(g.estimate*(1-g.cfg.Smoothing) + next*g.cfg.Smoothing)
+
+	return g.Limit()
+}
@@ -0,0 +1,359 @@
+package concurrency_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/concurrency"
+)
+
+// update is one call to fakeAlgorithm.Update.
+type update struct {
+	inFlight int
+	dropped  bool
+}
+
+// fakeAlgorithm has a fixed limit and records its updates.
+type fakeAlgorithm struct {
+	mu      sync.Mutex
+	limit   int
+	updates []update
+}
+
+func (a *fakeAlgorithm) Limit() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.limit
+}
+
+func (a *fakeAlgorithm) Update(_ time.Duration, inFlight int, dropped bool) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.updates = append(a.updates, update{inFlight: inFlight, dropped: dropped})
+	return a.limit
+}
+
+func (a *fakeAlgorithm) recorded() []update {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]update(nil), a.updates...)
+}
+
+func TestNewNil(t *testing.T) {
+	if _, err := concurrency.New(nil); err == nil {
+		t.Error("New accepted a nil algorithm")
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	alg := &fakeAlgorithm{limit: 2}
+	l, err := concurrency.New(alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, ok := l.Acquire()
+	if !ok {
+		t.Fatal("first call rejected")
+	}
+	b, ok := l.Acquire()
+	if !ok {
+		t.Fatal("second call rejected")
+	}
+	if _, ok := l.Acquire(); ok {
+		t.Fatal("call beyond the limit admitted")
+	}
+	if l.InFlight() != 2 || l.Limit() != 2 {
+		t.Errorf("InFlight, Limit = %d, %d; want 2, 2", l.InFlight(), l.Limit())
+	}
+
+	a.Dropped()
+	a.Success() // Later completions are no-ops.
+	c, ok := l.Acquire()
+	if !ok {
+		t.Fatal("call rejected after one completed")
+	}
+	b.Ignore()
+	c.Success()
+	if l.InFlight() != 0 {
+		t.Errorf("InFlight = %d after every call completed, want 0", l.InFlight())
+	}
+
+	// Calls report the in-flight count they started with; ignored calls are not sampled.
+	want := []update{{inFlight: 1, dropped: true}, {inFlight: 2}}
+	if got := alg.recorded(); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("updates = %+v, want %+v", got, want)
+	}
+
+	// A lowered limit applies to new calls only.
+	alg.mu.Lock()
+	alg.limit = 0
+	alg.mu.Unlock()
+	if _, ok := l.Acquire(); ok {
+		t.Error("call admitted at a zero limit")
+	}
+}
+
+func TestLimiterConcurrent(t *testing.T) {
+	alg := &fakeAlgorithm{limit: 5}
+	l, err := concurrency.New(alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		peak     int
+		admitted int
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tok, ok := l.Acquire()
+			if !ok {
+				return
+			}
+			mu.Lock()
+			admitted++
+			peak = max(peak, l.InFlight())
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			tok.Success()
+		}()
+	}
+	wg.Wait()
+
+	if peak > 5 {
+		t.Errorf("peak in flight = %d, want at most 5", peak)
+	}
+	if got := len(alg.recorded()); got != admitted {
+		t.Errorf("%d updates for %d admitted calls", got, admitted)
+	}
+}
+
+func TestNewAIMDErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  concurrency.AIMDConfig
+		want string
+	}{
+		{name: "negative minimum", cfg: concurrency.AIMDConfig{Min: -1}, want: "minimum limit must be at least 1"},
+		{
+			name: "maximum below minimum",
+			cfg:  concurrency.AIMDConfig{Min: 10, Max: 5},
+			want: "maximum limit 5 is below minimum 10",
+		},
+		{
+			name: "initial above maximum",
+			cfg:  concurrency.AIMDConfig{Initial: 50, Max: 30},
+			want: "initial limit 50 is outside [1, 30]",
+		},
+		{
+			name: "initial below minimum",
+			cfg:  concurrency.AIMDConfig{Initial: 2, Min: 5},
+			want: "initial limit 2 is outside [5, 1000]",
+		},
+		{
+			name: "backoff of one",
+			cfg:  concurrency.AIMDConfig{Backoff: 1},
+			want: "backoff must be between 0 and 1, got 1",
+		},
+		{name: "negative backoff", cfg: concurrency.AIMDConfig{Backoff: -0.5}, want: "backoff must be between 0 and 1"},
+		{
+			name: "negative timeout",
+			cfg:  concurrency.AIMDConfig{Timeout: -time.Second},
+			want: "timeout cannot be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := concurrency.NewAIMD(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewAIMD error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAIMD(t *testing.T) {
+	type step struct {
+		rtt      time.Duration
+		inFlight int
+		dropped  bool
+		want     int
+	}
+	tests := []struct {
+		name  string
+		cfg   concurrency.AIMDConfig
+		start int
+		steps []step
+	}{
+		{
+			name:  "defaults",
+			start: 20,
+			steps: []step{
+				{time.Millisecond, 10, false, 21}, {time.Millisecond, 5, false, 21}, {time.Millisecond, 21, true, 18},
+			},
+		},
+		{
+			name:  "initial capped at max",
+			cfg:   concurrency.AIMDConfig{Max: 8},
+			start: 8,
+			steps: []step{{time.Millisecond, 8, false, 8}},
+		},
+		{
+			name:  "timeout counts as a drop",
+			cfg:   concurrency.AIMDConfig{Initial: 10, Backoff: 0.5, Timeout: 100 * time.Millisecond},
+			start: 10,
+			steps: []step{{100 * time.Millisecond, 10, false, 11}, {101 * time.Millisecond, 10, false, 5}},
+		},
+		{
+			name:  "clamped at the minimum",
+			cfg:   concurrency.AIMDConfig{Initial: 3, Min: 2, Backoff: 0.5},
+			start: 3,
+			steps: []step{{0, 3, true, 2}, {0, 2, true, 2}, {0, 1, false, 3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alg, err := concurrency.NewAIMD(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := alg.Limit(); got != tt.start {
+				t.Fatalf("initial limit = %d, want %d", got, tt.start)
+			}
+			for i, s := range tt.steps {
+				if got := alg.Update(s.rtt, s.inFlight, s.dropped); got != s.want || alg.Limit() != s.want {
+					t.Errorf("step %d: limit = %d, want %d", i, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewGradientErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  concurrency.GradientConfig
+		want string
+	}{
+		{name: "bounds", cfg: concurrency.GradientConfig{Min: 10, Max: 5}, want: "maximum limit 5 is below minimum 10"},
+		{
+			name: "tolerance below one",
+			cfg:  concurrency.GradientConfig{Tolerance: 0.5},
+			want: "tolerance must be at least 1, got 0.5",
+		},
+		{
+			name: "smoothing above one",
+			cfg:  concurrency.GradientConfig{Smoothing: 2},
+			want: "smoothing must be between 0 and 1, got 2",
+		},
+		{
+			name: "negative smoothing",
+			cfg:  concurrency.GradientConfig{Smoothing: -0.1},
+			want: "smoothing must be between 0 and 1",
+		},
+		{name: "negative queue", cfg: concurrency.GradientConfig{QueueSize: -1}, want: "cannot be negative"},
+		{name: "negative window", cfg: concurrency.GradientConfig{LongWindow: -1}, want: "cannot be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := concurrency.NewGradient(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewGradient error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// feed updates alg n times with calls taking rtt while inFlight calls run, returning the final
+// limit.
+func feed(alg concurrency.Algorithm, n int, rtt time.Duration, inFlight int, dropped bool) int {
+	limit := alg.Limit()
+	for range n {
+		limit = alg.Update(rtt, inFlight, dropped)
+	}
+
+	return limit
+}
+
+func TestGradient(t *testing.T) {
+	newGradient := func(t *testing.T, cfg concurrency.GradientConfig) concurrency.Algorithm {
+		t.Helper()
+
+		alg, err := concurrency.NewGradient(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return alg
+	}
+
+	t.Run("grows while latency is steady", func(t *testing.T) {
+		alg := newGradient(t, concurrency.GradientConfig{Max: 100})
+		if got := feed(alg, 200, 10*time.Millisecond, 100, false); got != 100 {
+			t.Errorf("limit = %d, want the maximum 100", got)
+		}
+	})
+
+	t.Run("under-used limit is kept", func(t *testing.T) {
+		alg := newGradient(t, concurrency.GradientConfig{})
+		if got := feed(alg, 50, 10*time.Millisecond, 5, false); got != 20 {
+			t.Errorf("limit = %d, want the initial 20", got)
+		}
+	})
+
+	t.Run("shrinks as latency rises", func(t *testing.T) {
+		alg := newGradient(t, concurrency.GradientConfig{Initial: 50, QueueSize: 1})
+		feed(alg, 100, 10*time.Millisecond, 50, false)
+		before := alg.Limit()
+		after := feed(alg, 10, 100*time.Millisecond, before, false)
+		if after >= before {
+			t.Errorf("limit = %d after latency rose tenfold, want below %d", after, before)
+		}
+	})
+
+	t.Run("drops halve the estimate", func(t *testing.T) {
+		alg := newGradient(t, concurrency.GradientConfig{Initial: 40, Smoothing: 1, QueueSize: 1})
+		// Dropped calls shrink the limit even when it is under-used.
+		if got := alg.Update(10*time.Millisecond, 1, true); got != 21 {
+			t.Errorf("limit = %d, want 40*0.5+1", got)
+		}
+	})
+
+	t.Run("clamped at the minimum", func(t *testing.T) {
+		alg := newGradient(t, concurrency.GradientConfig{Initial: 10, Min: 5, Smoothing: 1})
+		if got := feed(alg, 20, 10*time.Millisecond, 10, true); got != 8 {
+			t.Errorf("limit = %d, want the fixed point 8 of x/2+4", got)
+		}
+		alg = newGradient(t, concurrency.GradientConfig{Initial: 10, Min: 5, Smoothing: 1, QueueSize: 1})
+		feed(alg, 20, 10*time.Millisecond, 10, true)
+		if got := alg.Limit(); got != 5 {
+			t.Errorf("limit = %d, want the minimum 5", got)
+		}
+	})
+
+	t.Run("zero latency is ignored", func(t *testing.T) {
+		alg := newGradient(t, concurrency.GradientConfig{})
+		if got := alg.Update(0, 100, true); got != 20 {
+			t.Errorf("limit = %d, want the initial 20", got)
+		}
+	})
+
+	t.Run("recovers after an overload", func(t *testing.T) {
+		alg := newGradient(t, concurrency.GradientConfig{Initial: 50, Max: 50, LongWindow: 20})
+		feed(alg, 100, 100*time.Millisecond, 50, false)
+		feed(alg, 20, time.Second, 50, false)
+		// Latency back to normal: the limit returns to the maximum.
+		if got := feed(alg, 200, 100*time.Millisecond, 50, false); got != 50 {
+			t.Errorf("limit = %d after recovery, want 50", got)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/concurrency"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+func TestIsOverload(t *testing.T) {
+	expired, cancel := context.WithDeadline(context.Background(), time.Unix(0, 0))
+	defer cancel()
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{
+			name: "deadline exceeded",
+			ctx:  context.Background(),
+			err:  fmt.Errorf("upstream: %w", context.DeadlineExceeded),
+			want: true,
+		},
+		{name: "call deadline passed", ctx: expired, err: errors.New("boom"), want: true},
+		{name: "canceled by mcpd", ctx: canceled, err: context.Canceled},
+		{name: "deadline status", ctx: context.Background(), err: status.Error(codes.DeadlineExceeded, ""), want: true},
+		{
+			name: "resource exhausted",
+			ctx:  context.Background(),
+			err:  status.Error(codes.ResourceExhausted, ""),
+			want: true,
+		},
+		{name: "unavailable", ctx: context.Background(), err: status.Error(codes.Unavailable, ""), want: true},
+		{name: "invalid argument", ctx: context.Background(), err: status.Error(codes.InvalidArgument, "")},
+		{name: "plain error", ctx: context.Background(), err: errors.New("boom")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isOverload(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("isOverload = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithAdaptiveConcurrencyErrors(t *testing.T) {
+	alg, err := concurrency.NewAIMD(concurrency.AIMDConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newServeOptions(WithAdaptiveConcurrency(alg, 0)); err == nil {
+		t.Error("WithAdaptiveConcurrency accepted a zero retry-after")
+	}
+	if _, err := newServeOptions(WithAdaptiveConcurrency(nil, time.Second)); err == nil {
+		t.Error("WithAdaptiveConcurrency accepted a nil algorithm")
+	}
+}
+
+func TestConcurrencyInterceptor(t *testing.T) {
+	r := &recorderLog{}
+	o, err := newServeOptions(WithMetrics(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alg, err := concurrency.NewAIMD(concurrency.AIMDConfig{Initial: 2, Min: 1, Max: 2, Backoff: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := concurrency.New(alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	intercept := concurrencyInterceptor(o, l, 2*time.Second)
+	info := &grpc.UnaryServerInfo{FullMethod: Plugin_HandleRequest_FullMethodName}
+	failing := func(err error) grpc.UnaryHandler {
+		return func(context.Context, any) (any, error) { return nil, err }
+	}
+
+	// Errors unrelated to load leave the limit alone.
+	_, err = intercept(context.Background(), &HTTPRequest{}, info, failing(status.Error(codes.InvalidArgument, "")))
+	if err == nil {
+		t.Fatal("handler error not returned")
+	}
+	if l.Limit() != 2 {
+		t.Errorf("limit = %d after an ignored error, want 2", l.Limit())
+	}
+
+	// An overload halves it.
+	_, _ = intercept(context.Background(), &HTTPRequest{}, info, failing(status.Error(codes.Unavailable, "")))
+	if l.Limit() != 1 {
+		t.Errorf("limit = %d after an overload, want 1", l.Limit())
+	}
+	want := []string{"gauge concurrency.limit 2", "gauge concurrency.limit 1"}
+	if got := r.named(metrics.ConcurrencyLimit); !slices.Equal(got, want) {
+		t.Errorf("recorded %q, want %q", got, want)
+	}
+
+	// Calls beyond the limit are throttled while one is in flight.
+	var inner error
+	_, err = intercept(context.Background(), &HTTPRequest{}, info, func(ctx context.Context, _ any) (any, error) {
+		_, inner = intercept(ctx, &HTTPRequest{}, info, failing(nil))
+		return &HTTPResponse{Continue: true}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := RetryAfter(inner); !ok || d != 2*time.Second {
+		t.Errorf("call beyond the limit = %v, want a 2s throttle", inner)
+	}
+	if got := r.named(metrics.Throttled); len(got) != 1 {
+		t.Errorf("recorded %q, want one throttle", got)
+	}
+
+	// Other RPCs bypass the limiter.
+	configure := &grpc.UnaryServerInfo{FullMethod: Plugin_Configure_FullMethodName}
+	_, err = intercept(context.Background(), &PluginConfig{}, configure, func(ctx context.Context, _ any) (any, error) {
+		return intercept(ctx, &PluginConfig{}, configure, failing(nil))
+	})
+	if err != nil || l.InFlight() != 0 {
+		t.Errorf("Configure = %v with %d in flight, want it passed through", err, l.InFlight())
+	}
+}
@@ -0,0 +1,100 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"slices"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+)
+
+// ConfigWarningsMetadataKey is the gRPC response trailer on Configure that carries the warnings
+// recorded by DecodeConfig, as a JSON array of config.Warning objects.
+const ConfigWarningsMetadataKey = "mcpd-config-warnings-bin"
+
+// DecodeConfig decodes cfg's custom configuration into dst (see the config package for the
+// supported struct tags), applying defaults and recording deprecation warnings.
+//
+// When called from Configure under Serve, warnings are logged with the SDK logger and returned to
+// mcpd in the ConfigWarningsMetadataKey trailer.
+//
+// Usage:
+//
+//	func (p *MyPlugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+//	    var s Settings
+//	    if err := mcpdpluginsv1.DecodeConfig(ctx, cfg, &s); err != nil {
+//	        return nil, status.Error(codes.InvalidArgument, err.Error())
+//	    }
+//	    p.settings.Store(&s)
+//	    return &emptypb.Empty{}, nil
+//	}
+func DecodeConfig(ctx context.Context, cfg *PluginConfig, dst any) error {
+	warnings, err := config.Decode(cfg.GetCustomConfig(), dst)
+	if len(warnings) == 0 {
+		return err
+	}
+
+	if c, ok := ctx.Value(configWarningsKey{}).(*configWarnings); ok {
+		c.add(warnings)
+	} else {
+		for _, w := range warnings {
+			log.Printf("config warning: %s", w)
+		}
+	}
+
+	return err
+}
+
+type configWarningsKey struct{}
+
+// configWarnings collects the warnings recorded during a single Configure call.
+type configWarnings struct {
+	mu       sync.Mutex
+	warnings []config.Warning
+}
+
+// add records w, skipping warnings already recorded (e.g. by decoding several config sections).
+func (c *configWarnings) add(w []config.Warning) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, nw := range w {
+		if !slices.Contains(c.warnings, nw) {
+			c.warnings = append(c.warnings, nw)
+		}
+	}
+}
+
+// configWarningsInterceptor collects warnings recorded by DecodeConfig during Configure, logs
+// them and returns them to mcpd in a trailer.
+func configWarningsInterceptor(o *serveOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if info.FullMethod != Plugin_Configure_FullMethodName {
+			return handler(ctx, req)
+		}
+
+		c := &configWarnings{}
+		resp, err := handler(context.WithValue(ctx, configWarningsKey{}, c), req)
+
+		c.mu.Lock()
+		warnings := c.warnings
+		c.mu.Unlock()
+		if len(warnings) == 0 {
+			return resp, err
+		}
+
+		for _, w := range warnings {
+			o.logger.Printf("config warning: %s", w)
+		}
+		if b, merr := json.Marshal(warnings); merr == nil {
+			_ = grpc.SetTrailer(ctx, metadata.Pairs(ConfigWarningsMetadataKey, string(b)))
+		}
+
+		return resp, err
+	}
+}
@@ -0,0 +1,231 @@
+// Package config decodes a plugin's custom configuration into a Go struct.
+//
+// mcpd delivers plugin-specific configuration as a flat map of strings (PluginConfig.custom_config).
+// Decode maps keys to struct fields using struct tags, so Configure does not have to parse each
+// value by hand:
+//
+//	type Settings struct {
+//	    TimeoutMS int           `config:"timeout_ms" default:"30000"`
+//	    Timeout   time.Duration `config:"timeout" deprecated:"use timeout_ms"`
+//	    Methods   []string      `config:"methods" default:"GET,POST"`
+//	}
+//
+// Supported tags:
+//   - config:"key" sets the configuration key (defaults to the field name in snake_case);
+//     config:"-" skips the field.
+//   - default:"value" is decoded into the field when the key is absent.
+//   - deprecated:"message" records a Warning when the key is present.
+//
+// Fields may be strings, booleans, integers, floats, time.Duration, slices of those
+// (comma-separated), or any type implementing encoding.TextUnmarshaler. Embedded structs are
+// flattened. Duration, ByteSize, URL and Regexp parse common human-friendly values such as
+// "10s", "5MiB", "https://example.com" and "^/api/".
+//
+// Most plugins call mcpdpluginsv1.DecodeConfig instead, which also reports warnings to mcpd.
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Struct tags read by Decode.
+const (
+	TagKey        = "config"
+	TagDefault    = "default"
+	TagDeprecated = "deprecated"
+)
+
+// Warning is a non-fatal problem found while decoding configuration.
+type Warning struct {
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+// String returns the warning as "key: message".
+func (w Warning) String() string {
+	return w.Key + ": " + w.Message
+}
+
+// FieldError reports a configuration value that could not be decoded.
+type FieldError struct {
+	Key   string
+	Value string
+	Err   error
+}
+
+// Error implements error.
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("config %s: invalid value %q: %v", e.Key, e.Value, e.Err)
+}
+
+// Unwrap returns the underlying parse error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// Decode decodes m into the struct pointed to by dst, applying defaults for absent keys.
+// It returns the warnings for deprecated keys that are set, and an error joining a *FieldError
+// for every value that could not be decoded.
+func Decode(m map[string]string, dst any) ([]Warning, error) {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config destination must be a non-nil pointer to a struct, got %T", dst)
+	}
+
+	d := &decoder{values: m}
+	d.decodeStruct(rv.Elem())
+
+	return d.warnings, errors.Join(d.errs...)
+}
+
+type decoder struct {
+	values   map[string]string
+	warnings []Warning
+	errs     []error
+}
+
+func (d *decoder) decodeStruct(v reflect.Value) {
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		fv := v.Field(i)
+
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && sf.Tag.Get(TagKey) == "" {
+			d.decodeStruct(fv)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		key := sf.Tag.Get(TagKey)
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = snakeCase(sf.Name)
+		}
+
+		raw, ok := d.values[key]
+		if ok {
+			if msg, deprecated := sf.Tag.Lookup(TagDeprecated); deprecated {
+				d.warnings = append(d.warnings, Warning{Key: key, Message: deprecationMessage(msg)})
+			}
+		} else {
+			raw, ok = sf.Tag.Lookup(TagDefault)
+		}
+		if !ok {
+			continue
+		}
+
+		if err := setValue(fv, raw); err != nil {
+			d.errs = append(d.errs, &FieldError{Key: key, Value: raw, Err: err})
+		}
+	}
+}
+
+func deprecationMessage(msg string) string {
+	if msg == "" {
+		return "is deprecated"
+	}
+
+	return "is deprecated: " + msg
+}
+
+// setValue parses raw into v according to v's type.
+func setValue(v reflect.Value, raw string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	if v.Type() == durationType {
+		dur, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("expected a duration such as \"30s\"")
+		}
+		v.SetInt(int64(dur))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("expected a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected an integer that fits in %s", v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(raw), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a non-negative integer that fits in %s", v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(raw), v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a number")
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var parts []string
+		if strings.TrimSpace(raw) != "" {
+			parts = strings.Split(raw, ",")
+		}
+		s := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setValue(s.Index(i), strings.TrimSpace(p)); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		v.Set(s)
+	case reflect.Pointer:
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(p)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+
+	return nil
+}
+
+// snakeCase converts a Go field name such as "TimeoutMS" to "timeout_ms".
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && !unicode.IsUpper(runes[i-1])
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
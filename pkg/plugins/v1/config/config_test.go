@@ -0,0 +1,241 @@
+package config_test
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+)
+
+// level is a TextUnmarshaler accepting "low" and "high".
+type level int
+
+func (l *level) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("expected low or high")
+	}
+	return nil
+}
+
+type Common struct {
+	Region string `default:"eu"`
+}
+
+type settings struct {
+	Common
+
+	Name      string
+	TimeoutMS int           `config:"timeout_ms" default:"30000"`
+	Timeout   time.Duration `config:"timeout" deprecated:"use timeout_ms"`
+	Old       string        `deprecated:""`
+	Enabled   bool
+	Small     int8
+	Count     uint16
+	Ratio     float64
+	Methods   []string `default:"GET,POST"`
+	Ports     []int
+	Level     level
+	Limit     *int
+	HTTPProxy string
+	Skipped   string `config:"-" default:"never"`
+	hidden    string `default:"never"`
+}
+
+func TestDecode(t *testing.T) {
+	limit := 7
+	tests := []struct {
+		name         string
+		values       map[string]string
+		want         settings
+		wantWarnings []config.Warning
+	}{
+		{
+			name: "defaults",
+			want: settings{Common: Common{Region: "eu"}, TimeoutMS: 30000, Methods: []string{"GET", "POST"}},
+		},
+		{
+			name: "every type",
+			values: map[string]string{
+				"region":     "us",
+				"name":       " spaced ",
+				"timeout_ms": " 100 ",
+				"enabled":    "true",
+				"small":      "-8",
+				"count":      "65535",
+				"ratio":      "0.5",
+				"methods":    "PUT, DELETE ",
+				"ports":      "80,443",
+				"level":      "high",
+				"limit":      "7",
+				"http_proxy": "proxy:3128",
+				"skipped":    "ignored",
+				"hidden":     "ignored",
+			},
+			want: settings{
+				Common:    Common{Region: "us"},
+				Name:      " spaced ",
+				TimeoutMS: 100,
+				Enabled:   true,
+				Small:     -8,
+				Count:     65535,
+				Ratio:     0.5,
+				Methods:   []string{"PUT", "DELETE"},
+				Ports:     []int{80, 443},
+				Level:     2,
+				Limit:     &limit,
+				HTTPProxy: "proxy:3128",
+			},
+		},
+		{
+			name:   "empty slice",
+			values: map[string]string{"methods": " "},
+			want:   settings{Common: Common{Region: "eu"}, TimeoutMS: 30000, Methods: []string{}},
+		},
+		{
+			name:   "deprecated keys",
+			values: map[string]string{"timeout": "5s", "old": "x"},
+			want: settings{
+				Common:    Common{Region: "eu"},
+				TimeoutMS: 30000,
+				Timeout:   5 * time.Second,
+				Old:       "x",
+				Methods:   []string{"GET", "POST"},
+			},
+			wantWarnings: []config.Warning{
+				{Key: "timeout", Message: "is deprecated: use timeout_ms"},
+				{Key: "old", Message: "is deprecated"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got settings
+			warnings, err := config.Decode(tt.values, &got)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !slices.Equal(warnings, tt.wantWarnings) {
+				t.Errorf("warnings = %v, want %v", warnings, tt.wantWarnings)
+			}
+			if got.hidden != "" {
+				t.Errorf("decoded unexported field %q", got.hidden)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decoded\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   []string // FieldError messages.
+	}{
+		{
+			name:   "integer overflow",
+			values: map[string]string{"small": "200"},
+			want:   []string{`config small: invalid value "200": expected an integer that fits in int8`},
+		},
+		{
+			name:   "negative unsigned",
+			values: map[string]string{"count": "-1"},
+			want:   []string{`config count: invalid value "-1": expected a non-negative integer that fits in uint16`},
+		},
+		{
+			name:   "every failure is reported",
+			values: map[string]string{"enabled": "maybe", "ratio": "half", "timeout": "5"},
+			want: []string{
+				`config timeout: invalid value "5": expected a duration such as "30s"`,
+				`config enabled: invalid value "maybe": expected a boolean`,
+				`config ratio: invalid value "half": expected a number`,
+			},
+		},
+		{
+			name:   "slice element",
+			values: map[string]string{"ports": "80,http"},
+			want:   []string{`config ports: invalid value "80,http": element 1: expected an integer that fits in int`},
+		},
+		{
+			name:   "text unmarshaler",
+			values: map[string]string{"level": "medium"},
+			want:   []string{`config level: invalid value "medium": expected low or high`},
+		},
+		{
+			name:   "pointer",
+			values: map[string]string{"limit": "many"},
+			want:   []string{`config limit: invalid value "many": expected an integer that fits in int`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s settings
+			_, err := config.Decode(tt.values, &s)
+			if err == nil {
+				t.Fatal("Decode succeeded")
+			}
+			var got []string
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var ferr *config.FieldError
+				if !errors.As(e, &ferr) {
+					t.Fatalf("error %v is not a *FieldError", e)
+				}
+				got = append(got, ferr.Error())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("errors =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestDecodeKeepsWarningsOnError(t *testing.T) {
+	var s settings
+	warnings, err := config.Decode(map[string]string{"timeout": "soon"}, &s)
+	if err == nil || len(warnings) != 1 {
+		t.Errorf("Decode = %v, %v; want the deprecation warning and the decode error", warnings, err)
+	}
+}
+
+func TestDecodeUnsupportedType(t *testing.T) {
+	var s struct{ Values map[string]string }
+	_, err := config.Decode(map[string]string{"values": "a=b"}, &s)
+	if err == nil || !strings.Contains(err.Error(), "unsupported field type map[string]string") {
+		t.Errorf("Decode error = %v, want an unsupported type error", err)
+	}
+}
+
+func TestDecodeDestination(t *testing.T) {
+	var s settings
+	var n int
+	for _, dst := range []any{nil, s, (*settings)(nil), &n} {
+		if _, err := config.Decode(nil, dst); err == nil {
+			t.Errorf("Decode accepted %T as a destination", dst)
+		}
+	}
+}
+
+func TestFieldErrorUnwrap(t *testing.T) {
+	errParse := errors.New("bad")
+	err := &config.FieldError{Key: "k", Value: "v", Err: errParse}
+	if !errors.Is(err, errParse) {
+		t.Error("FieldError does not unwrap to the parse error")
+	}
+}
+
+func TestWarningString(t *testing.T) {
+	w := config.Warning{Key: "timeout", Message: "is deprecated"}
+	if got := w.String(); got != "timeout: is deprecated" {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"maps"
+	"strings"
+)
+
+// Sections splits m into base keys and named sections. Keys of the form "<prefix><name>.<key>"
+// belong to section name; all other keys are base keys. Each returned section holds the base keys
+// overlaid with the section's own keys, so it can be decoded on its own.
+//
+// For example, with prefix "tenants.":
+//
+//	timeout: 10s
+//	tenants.acme.timeout: 30s
+//
+// yields base {timeout: 10s} and section "acme" {timeout: 30s}.
+func Sections(m map[string]string, prefix string) (map[string]string, map[string]map[string]string) {
+	base := make(map[string]string, len(m))
+	overrides := map[string]map[string]string{}
+
+	for k, v := range m {
+		rest, ok := strings.CutPrefix(k, prefix)
+		if !ok {
+			base[k] = v
+			continue
+		}
+		name, key, ok := strings.Cut(rest, ".")
+		if !ok || name == "" || key == "" {
+			base[k] = v
+			continue
+		}
+		if overrides[name] == nil {
+			overrides[name] = map[string]string{}
+		}
+		overrides[name][key] = v
+	}
+
+	sections := make(map[string]map[string]string, len(overrides))
+	for name, o := range overrides {
+		s := maps.Clone(base)
+		maps.Copy(s, o)
+		sections[name] = s
+	}
+
+	return base, sections
+}
@@ -0,0 +1,94 @@
+package config_test
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+)
+
+func TestSections(t *testing.T) {
+	tests := []struct {
+		name         string
+		m            map[string]string
+		wantBase     map[string]string
+		wantSections map[string]map[string]string
+	}{
+		{
+			name:         "no sections",
+			m:            map[string]string{"timeout": "10s"},
+			wantBase:     map[string]string{"timeout": "10s"},
+			wantSections: map[string]map[string]string{},
+		},
+		{
+			name: "sections overlay the base",
+			m: map[string]string{
+				"timeout":              "10s",
+				"limit":                "5",
+				"tenants.acme.timeout": "30s",
+				"tenants.beta.limit":   "1",
+				"tenants.beta.mode":    "strict",
+			},
+			wantBase: map[string]string{"timeout": "10s", "limit": "5"},
+			wantSections: map[string]map[string]string{
+				"acme": {"timeout": "30s", "limit": "5"},
+				"beta": {"timeout": "10s", "limit": "1", "mode": "strict"},
+			},
+		},
+		{
+			name: "malformed section keys stay in the base",
+			m: map[string]string{
+				"tenants.acme":   "no key",
+				"tenants..x":     "no name",
+				"tenants.acme.":  "empty key",
+				"other.acme.key": "other prefix",
+			},
+			wantBase: map[string]string{
+				"tenants.acme":   "no key",
+				"tenants..x":     "no name",
+				"tenants.acme.":  "empty key",
+				"other.acme.key": "other prefix",
+			},
+			wantSections: map[string]map[string]string{},
+		},
+		{
+			name:     "nested keys keep their dots",
+			m:        map[string]string{"tenants.acme.limits.max": "3"},
+			wantBase: map[string]string{},
+			wantSections: map[string]map[string]string{
+				"acme": {"limits.max": "3"},
+			},
+		},
+		{
+			name:         "nil map",
+			wantBase:     map[string]string{},
+			wantSections: map[string]map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, sections := config.Sections(tt.m, "tenants.")
+			if !maps.Equal(base, tt.wantBase) {
+				t.Errorf("base = %v, want %v", base, tt.wantBase)
+			}
+			if !maps.EqualFunc(sections, tt.wantSections, maps.Equal) {
+				t.Errorf("sections = %v, want %v", sections, tt.wantSections)
+			}
+		})
+	}
+}
+
+func TestSectionsAreIndependent(t *testing.T) {
+	base, sections := config.Sections(map[string]string{
+		"timeout":              "10s",
+		"tenants.acme.timeout": "30s",
+		"tenants.beta.limit":   "1",
+	}, "tenants.")
+	sections["acme"]["extra"] = "1"
+	if _, ok := base["extra"]; ok {
+		t.Error("modifying a section modified the base")
+	}
+	if _, ok := sections["beta"]["extra"]; ok {
+		t.Error("modifying a section modified another section")
+	}
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration decoded from strings such as "10s" or "1h30m".
+// A bare number is rejected so that units are always explicit.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		if _, nerr := strconv.ParseFloat(s, 64); nerr == nil {
+			return fmt.Errorf("duration %q is missing a unit (e.g. \"%ss\" or \"%sms\")", s, s, s)
+		}
+		return fmt.Errorf("expected a duration such as \"10s\" or \"1h30m\"")
+	}
+	d.Duration = v
+
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// ByteSize is a number of bytes decoded from strings such as "512", "64KB" or "5MiB".
+// Decimal units (KB, MB, GB, TB) are powers of 1000 and binary units (KiB, MiB, GiB, TiB)
+// are powers of 1024; units are case-insensitive and may be separated from the number by a space.
+type ByteSize int64
+
+// Byte size units.
+const (
+	Byte ByteSize = 1
+
+	KB ByteSize = 1000 * Byte
+	MB ByteSize = 1000 * KB
+	GB ByteSize = 1000 * MB
+	TB ByteSize = 1000 * GB
+
+	KiB ByteSize = 1024 * Byte
+	MiB ByteSize = 1024 * KiB
+	GiB ByteSize = 1024 * MiB
+	TiB ByteSize = 1024 * GiB
+)
+
+var byteSizeUnits = map[string]ByteSize{
+	"":    Byte,
+	"b":   Byte,
+	"kb":  KB,
+	"mb":  MB,
+	"gb":  GB,
+	"tb":  TB,
+	"kib": KiB,
+	"mib": MiB,
+	"gib": GiB,
+	"tib": TiB,
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i == -1 {
+		i = len(s)
+	}
+	num, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+
+	mult, ok := byteSizeUnits[unit]
+	if !ok {
+		return fmt.Errorf("unknown size unit %q (use B, KB, MB, GB, TB, KiB, MiB, GiB or TiB)", s[i:])
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || num == "" {
+		return fmt.Errorf("expected a size such as \"512KB\" or \"5MiB\"")
+	}
+
+	v := n * float64(mult)
+	if v > math.MaxInt64 {
+		return fmt.Errorf("size %q is too large", s)
+	}
+	*b = ByteSize(v)
+
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// String formats b using the largest binary unit that divides it exactly.
+func (b ByteSize) String() string {
+	for _, u := range []struct {
+		size ByteSize
+		name string
+	}{{TiB, "TiB"}, {GiB, "GiB"}, {MiB, "MiB"}, {KiB, "KiB"}} {
+		if b != 0 && b%u.size == 0 {
+			return strconv.FormatInt(int64(b/u.size), 10) + u.name
+		}
+	}
+
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+// URL is an absolute URL decoded from a string such as "https://example.com/hook".
+type URL struct {
+	*url.URL
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (u *URL) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	v, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("expected a URL: %w", err)
+	}
+	if v.Scheme == "" || v.Host == "" {
+		return fmt.Errorf("expected an absolute URL with scheme and host, such as \"https://example.com\"")
+	}
+	u.URL = v
+
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (u URL) MarshalText() ([]byte, error) {
+	if u.URL == nil {
+		return nil, nil
+	}
+
+	return []byte(u.String()), nil
+}
+
+// Regexp is a regular expression compiled from its RE2 source.
+type Regexp struct {
+	*regexp.Regexp
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (r *Regexp) UnmarshalText(text []byte) error {
+	v, err := regexp.Compile(string(text))
+	if err != nil {
+		return fmt.Errorf("expected a regular expression: %w", err)
+	}
+	r.Regexp = v
+
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (r Regexp) MarshalText() ([]byte, error) {
+	if r.Regexp == nil {
+		return nil, nil
+	}
+
+	return []byte(r.String()), nil
+}
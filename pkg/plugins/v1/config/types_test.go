@@ -0,0 +1,192 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+)
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr string
+	}{
+		{in: "10s", want: 10 * time.Second},
+		{in: " 1h30m ", want: 90 * time.Minute},
+		{in: "30", wantErr: `duration "30" is missing a unit (e.g. "30s" or "30ms")`},
+		{in: "1.5", wantErr: `duration "1.5" is missing a unit`},
+		{in: "soon", wantErr: `expected a duration such as "10s" or "1h30m"`},
+		{in: "", wantErr: "expected a duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var d config.Duration
+			err := d.UnmarshalText([]byte(tt.in))
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr == "" && d.Duration != tt.want {
+				t.Errorf("UnmarshalText(%q) = %s, want %s", tt.in, d.Duration, tt.want)
+			}
+		})
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    config.ByteSize
+		wantErr string
+	}{
+		{in: "512", want: 512},
+		{in: "512B", want: 512},
+		{in: "64KB", want: 64000},
+		{in: "64kb", want: 64000},
+		{in: "5MiB", want: 5 << 20},
+		{in: "5 mib", want: 5 << 20},
+		{in: "1.5KiB", want: 1536},
+		{in: "2GB", want: 2e9},
+		{in: "1TiB", want: 1 << 40},
+		{in: " 3 GiB ", want: 3 << 30},
+		{in: "5XB", wantErr: `unknown size unit "XB"`},
+		{in: "-5", wantErr: "unknown size unit"},
+		{in: "MiB", wantErr: `expected a size such as "512KB" or "5MiB"`},
+		{in: "1.2.3", wantErr: "expected a size"},
+		{in: "", wantErr: "expected a size"},
+		{in: "9000000TiB", wantErr: `size "9000000TiB" is too large`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var b config.ByteSize
+			err := b.UnmarshalText([]byte(tt.in))
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr == "" && b != tt.want {
+				t.Errorf("UnmarshalText(%q) = %d, want %d", tt.in, b, tt.want)
+			}
+		})
+	}
+}
+
+func TestByteSizeString(t *testing.T) {
+	tests := []struct {
+		b    config.ByteSize
+		want string
+	}{
+		{0, "0B"},
+		{512, "512B"},
+		{1000, "1000B"},
+		{config.KiB, "1KiB"},
+		{1536, "1536B"},
+		{3 * config.MiB, "3MiB"},
+		{2 * config.TiB, "2TiB"},
+	}
+	for _, tt := range tests {
+		if got := tt.b.String(); got != tt.want {
+			t.Errorf("ByteSize(%d).String() = %q, want %q", int64(tt.b), got, tt.want)
+		}
+		text, err := tt.b.MarshalText()
+		if err != nil || string(text) != tt.want {
+			t.Errorf("ByteSize(%d).MarshalText() = %q, %v; want %q", int64(tt.b), text, err, tt.want)
+		}
+
+		var back config.ByteSize
+		if err := back.UnmarshalText(text); err != nil || back != tt.b {
+			t.Errorf("ByteSize round trip of %d = %d, %v", int64(tt.b), back, err)
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr string
+	}{
+		{in: " https://example.com/hook?x=1 ", want: "https://example.com/hook?x=1"},
+		{in: "/relative", wantErr: "expected an absolute URL with scheme and host"},
+		{in: "mailto:ops@example.com", wantErr: "expected an absolute URL with scheme and host"},
+		{in: "http://[::1", wantErr: "expected a URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var u config.URL
+			err := u.UnmarshalText([]byte(tt.in))
+			checkErr(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+			if text, err := u.MarshalText(); err != nil || string(text) != tt.want {
+				t.Errorf("MarshalText() = %q, %v; want %q", text, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	var r config.Regexp
+	if err := r.UnmarshalText([]byte("^/api/")); err != nil {
+		t.Fatal(err)
+	}
+	if !r.MatchString("/api/v1") || r.MatchString("/web") {
+		t.Errorf("Regexp %s does not match as compiled", r)
+	}
+	if text, err := r.MarshalText(); err != nil || string(text) != "^/api/" {
+		t.Errorf("MarshalText() = %q, %v", text, err)
+	}
+
+	checkErr(t, r.UnmarshalText([]byte("(")), "expected a regular expression")
+}
+
+func TestZeroValuesMarshal(t *testing.T) {
+	if text, err := (config.URL{}).MarshalText(); err != nil || text != nil {
+		t.Errorf("URL{}.MarshalText() = %q, %v; want nothing", text, err)
+	}
+	if text, err := (config.Regexp{}).MarshalText(); err != nil || text != nil {
+		t.Errorf("Regexp{}.MarshalText() = %q, %v; want nothing", text, err)
+	}
+	if text, err := (config.Duration{}).MarshalText(); err != nil || string(text) != "0s" {
+		t.Errorf("Duration{}.MarshalText() = %q, %v; want 0s", text, err)
+	}
+}
+
+func TestDecodeTypes(t *testing.T) {
+	var s struct {
+		Timeout config.Duration
+		MaxBody config.ByteSize `default:"1MiB"`
+		Hook    config.URL
+		Paths   []config.Regexp
+	}
+	_, err := config.Decode(map[string]string{
+		"timeout": "5s",
+		"hook":    "https://example.com",
+		"paths":   "^/a,^/b",
+	}, &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Timeout.Duration != 5*time.Second || s.MaxBody != config.MiB || s.Hook.Host != "example.com" {
+		t.Errorf("decoded %+v", s)
+	}
+	if len(s.Paths) != 2 || !s.Paths[1].MatchString("/b") {
+		t.Errorf("decoded paths %v", s.Paths)
+	}
+
+	_, err = config.Decode(map[string]string{"timeout": "5"}, &s)
+	want := `config timeout: invalid value "5": duration "5" is missing a unit`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Decode error = %v, want the missing unit", err)
+	}
+}
+
+// checkErr reports whether err matches want, a substring of the expected error or empty for none.
+func checkErr(t *testing.T, err error, want string) {
+	t.Helper()
+
+	switch {
+	case want == "" && err != nil:
+		t.Errorf("unexpected error: %v", err)
+	case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+		t.Errorf("error = %v, want it to contain %q", err, want)
+	}
+}
@@ -0,0 +1,143 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+)
+
+type deprecatedSettings struct {
+	Timeout string `deprecated:"use timeout_ms"`
+	Limit   int
+}
+
+func TestConfigWarningsInterceptor(t *testing.T) {
+	errConfigure := errors.New("bad config")
+	tests := []struct {
+		name    string
+		decodes int // Number of DecodeConfig calls made by the plugin.
+		cfg     map[string]string
+		err     error
+		want    []config.Warning
+	}{
+		{name: "no warnings", decodes: 1, cfg: map[string]string{"limit": "1"}},
+		{
+			name:    "deprecated key",
+			decodes: 1,
+			cfg:     map[string]string{"timeout": "1s"},
+			want:    []config.Warning{{Key: "timeout", Message: "is deprecated: use timeout_ms"}},
+		},
+		{
+			name:    "repeated decodes are deduplicated",
+			decodes: 3,
+			cfg:     map[string]string{"timeout": "1s"},
+			want:    []config.Warning{{Key: "timeout", Message: "is deprecated: use timeout_ms"}},
+		},
+		{
+			name:    "warnings are returned with errors",
+			decodes: 1,
+			cfg:     map[string]string{"timeout": "1s", "limit": "many"},
+			err:     errConfigure,
+			want:    []config.Warning{{Key: "timeout", Message: "is deprecated: use timeout_ms"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			o, err := newServeOptions(WithLogger(log.New(&buf, "", 0)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			stream := &headerStream{}
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+			handler := func(ctx context.Context, req any) (any, error) {
+				for range tt.decodes {
+					var s deprecatedSettings
+					_ = DecodeConfig(ctx, req.(*PluginConfig), &s)
+				}
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return &emptypb.Empty{}, nil
+			}
+
+			_, err = configWarningsInterceptor(o)(ctx, &PluginConfig{CustomConfig: tt.cfg},
+				&grpc.UnaryServerInfo{FullMethod: Plugin_Configure_FullMethodName}, handler)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+
+			trailer := stream.trailer.Get(ConfigWarningsMetadataKey)
+			if tt.want == nil {
+				if len(trailer) != 0 || buf.Len() != 0 {
+					t.Errorf("trailer = %q, logged %q; want no warnings", trailer, buf.String())
+				}
+				return
+			}
+			if len(trailer) != 1 {
+				t.Fatalf("trailer = %q, want one value", trailer)
+			}
+			var got []config.Warning
+			if err := json.Unmarshal([]byte(trailer[0]), &got); err != nil {
+				t.Fatalf("trailer is not JSON: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("trailer warnings = %v, want %v", got, tt.want)
+			}
+			var logged []string
+			for _, w := range tt.want {
+				logged = append(logged, "config warning: "+w.String())
+			}
+			if got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"); !slices.Equal(got, logged) {
+				t.Errorf("logged %q, want %q", got, logged)
+			}
+		})
+	}
+}
+
+func TestConfigWarningsInterceptorOtherMethods(t *testing.T) {
+	o, err := newServeOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := func(ctx context.Context, _ any) (any, error) {
+		if ctx.Value(configWarningsKey{}) != nil {
+			t.Error("warnings are collected outside Configure")
+		}
+		return &emptypb.Empty{}, nil
+	}
+	_, err = configWarningsInterceptor(o)(context.Background(), &HTTPRequest{},
+		&grpc.UnaryServerInfo{FullMethod: Plugin_HandleRequest_FullMethodName}, handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	var s deprecatedSettings
+	err := DecodeConfig(context.Background(), &PluginConfig{CustomConfig: map[string]string{"limit": "3"}}, &s)
+	if err != nil || s.Limit != 3 {
+		t.Errorf("DecodeConfig = %v with %+v, want limit 3", err, s)
+	}
+
+	err = DecodeConfig(context.Background(), nil, &s)
+	if err != nil {
+		t.Errorf("DecodeConfig with a nil config: %v", err)
+	}
+
+	var ferr *config.FieldError
+	err = DecodeConfig(context.Background(), &PluginConfig{CustomConfig: map[string]string{"limit": "x"}}, &s)
+	if !errors.As(err, &ferr) || ferr.Key != "limit" {
+		t.Errorf("DecodeConfig error = %v, want a *config.FieldError for limit", err)
+	}
+}
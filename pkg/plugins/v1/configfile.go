@@ -0,0 +1,177 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.yaml.in/yaml/v3"
+	"google.golang.org/grpc"
+)
+
+// configFileDebounce coalesces the bursts of file system events editors produce on save.
+const configFileDebounce = 100 * time.Millisecond
+
+// configFile is the YAML representation of a PluginConfig read by --config:
+//
+//	telemetry:
+//	  otlp_endpoint: http://localhost:4318
+//	  service_name: my-plugin
+//	custom_config:
+//	  timeout: 10s
+//	  methods: [GET, POST]
+//
+// Scalar custom_config values are converted to strings and lists are joined with commas, matching
+// how DecodeConfig reads them.
+type configFile struct {
+	Telemetry *struct {
+		OTLPEndpoint string  `yaml:"otlp_endpoint"`
+		ServiceName  string  `yaml:"service_name"`
+		Environment  string  `yaml:"environment"`
+		SampleRatio  float64 `yaml:"sample_ratio"`
+	} `yaml:"telemetry"`
+	CustomConfig map[string]any `yaml:"custom_config"`
+}
+
+// loadConfigFile reads a PluginConfig from the YAML file at path.
+func loadConfigFile(path string) (*PluginConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var f configFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	cfg := &PluginConfig{CustomConfig: make(map[string]string, len(f.CustomConfig))}
+	if t := f.Telemetry; t != nil {
+		cfg.Telemetry = &TelemetryConfig{
+			OtlpEndpoint: t.OTLPEndpoint,
+			ServiceName:  t.ServiceName,
+			Environment:  t.Environment,
+			SampleRatio:  t.SampleRatio,
+		}
+	}
+	for k, v := range f.CustomConfig {
+		s, err := configFileValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: custom_config.%s: %w", path, k, err)
+		}
+		cfg.CustomConfig[k] = s
+	}
+
+	return cfg, nil
+}
+
+func configFileValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []any:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			s, err := configFileValue(e)
+			if err != nil {
+				return "", err
+			}
+			if strings.Contains(s, ",") {
+				return "", fmt.Errorf("list element %q cannot contain a comma", s)
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	case map[string]any:
+		return "", fmt.Errorf("nested maps are not supported")
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// configFileLoader applies a config file to the plugin through the server's interceptor chain,
+// so validation, decoding warnings and events behave as they do for Configure calls from mcpd.
+type configFileLoader struct {
+	path      string
+	impl      PluginServer
+	intercept grpc.UnaryServerInterceptor
+	logger    *log.Logger
+}
+
+// apply loads the file and calls Configure with its contents.
+func (l *configFileLoader) apply(ctx context.Context) error {
+	cfg, err := loadConfigFile(l.path)
+	if err != nil {
+		return err
+	}
+
+	info := &grpc.UnaryServerInfo{Server: l.impl, FullMethod: Plugin_Configure_FullMethodName}
+	_, err = l.intercept(ctx, cfg, info, func(ctx context.Context, req any) (any, error) {
+		return l.impl.Configure(ctx, req.(*PluginConfig))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply config file %s: %w", l.path, err)
+	}
+
+	return nil
+}
+
+// watch re-applies the config file whenever it changes, until ctx is done. Reload failures are
+// logged and leave the previous configuration in place.
+func (l *configFileLoader) watch(ctx context.Context) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config file watcher: %w", err)
+	}
+
+	// Watch the directory rather than the file so that editors replacing the file on save
+	// (rename over the original) keep triggering reloads.
+	dir, name := filepath.Split(filepath.Clean(l.path))
+	if dir == "" {
+		dir = "."
+	}
+	if err := w.Add(dir); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("failed to watch config file %s: %w", l.path, err)
+	}
+
+	go func() {
+		defer func() { _ = w.Close() }()
+
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(ev.Name) == name && ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					debounce = time.After(configFileDebounce)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				l.logger.Printf("config file watcher: %v", err)
+			case <-debounce:
+				debounce = nil
+				if err := l.apply(ctx); err != nil {
+					l.logger.Printf("config reload: %v", err)
+					continue
+				}
+				l.logger.Printf("Reloaded config from %s", l.path)
+			}
+		}
+	}()
+
+	return nil
+}
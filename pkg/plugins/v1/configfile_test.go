@@ -0,0 +1,287 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// writeConfigFile writes content to name in dir and returns its path.
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *PluginConfig
+		wantErr string
+	}{
+		{
+			name: "telemetry and custom config",
+			content: `
+telemetry:
+  otlp_endpoint: http://localhost:4318
+  service_name: my-plugin
+  environment: dev
+  sample_ratio: 0.5
+custom_config:
+  timeout: 10s
+  limit: 5
+  ratio: 0.25
+  enabled: true
+  empty:
+  methods: [GET, POST]
+`,
+			want: &PluginConfig{
+				Telemetry: &TelemetryConfig{
+					OtlpEndpoint: "http://localhost:4318",
+					ServiceName:  "my-plugin",
+					Environment:  "dev",
+					SampleRatio:  0.5,
+				},
+				CustomConfig: map[string]string{
+					"timeout": "10s",
+					"limit":   "5",
+					"ratio":   "0.25",
+					"enabled": "true",
+					"empty":   "",
+					"methods": "GET,POST",
+				},
+			},
+		},
+		{
+			name:    "empty file",
+			content: "",
+			want:    &PluginConfig{CustomConfig: map[string]string{}},
+		},
+		{
+			name:    "nested map",
+			content: "custom_config:\n  limits:\n    a: 1\n",
+			wantErr: "custom_config.limits: nested maps are not supported",
+		},
+		{
+			name:    "list element with a comma",
+			content: "custom_config:\n  methods: [\"GET,POST\"]\n",
+			wantErr: `custom_config.methods: list element "GET,POST" cannot contain a comma`,
+		},
+		{
+			name:    "map in a list",
+			content: "custom_config:\n  methods: [{a: 1}]\n",
+			wantErr: "nested maps are not supported",
+		},
+		{
+			name:    "invalid YAML",
+			content: "custom_config: [",
+			wantErr: "failed to parse config file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, t.TempDir(), "config.yaml", tt.content)
+			got, err := loadConfigFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadConfigFile error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("loadConfigFile =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileMissing(t *testing.T) {
+	_, err := loadConfigFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("loadConfigFile error = %v, want a not-exist error", err)
+	}
+}
+
+// configuredPlugin records the configurations it receives, failing when configureErr is set.
+type configuredPlugin struct {
+	BasePlugin
+
+	mu           sync.Mutex
+	configs      []map[string]string
+	configureErr error
+}
+
+func (p *configuredPlugin) Configure(_ context.Context, cfg *PluginConfig) (*emptypb.Empty, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.configureErr != nil {
+		return nil, p.configureErr
+	}
+	p.configs = append(p.configs, cfg.GetCustomConfig())
+
+	return &emptypb.Empty{}, nil
+}
+
+func (p *configuredPlugin) received() []map[string]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]map[string]string(nil), p.configs...)
+}
+
+func TestConfigFileLoaderApply(t *testing.T) {
+	dir := t.TempDir()
+	var methods []string
+	intercept := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+		methods = append(methods, info.FullMethod)
+		return h(ctx, req)
+	}
+
+	p := &configuredPlugin{}
+	l := &configFileLoader{
+		path:      writeConfigFile(t, dir, "config.yaml", "custom_config:\n  mode: strict\n"),
+		impl:      p,
+		intercept: intercept,
+		logger:    discardLogger(),
+	}
+	if err := l.apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.received(); len(got) != 1 || got[0]["mode"] != "strict" {
+		t.Errorf("plugin received %v, want mode=strict", got)
+	}
+	if len(methods) != 1 || methods[0] != Plugin_Configure_FullMethodName {
+		t.Errorf("interceptor saw %v, want one Configure call", methods)
+	}
+
+	p.configureErr = errors.New("bad config")
+	if err := l.apply(context.Background()); !errors.Is(err, p.configureErr) ||
+		!strings.Contains(err.Error(), "failed to apply config file") {
+		t.Errorf("apply error = %v, want the Configure error", err)
+	}
+
+	l.path = filepath.Join(dir, "missing.yaml")
+	if err := l.apply(context.Background()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("apply error = %v, want a not-exist error", err)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use, for loggers written by goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// waitFor polls cond until it holds, failing the test after five seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestConfigFileLoaderWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", "custom_config:\n  mode: strict\n")
+	var logs syncBuffer
+	p := &configuredPlugin{}
+	l := &configFileLoader{
+		path: path,
+		impl: p,
+		intercept: func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+			return h(ctx, req)
+		},
+		logger: log.New(&logs, "", 0),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := l.watch(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes to other files in the directory are ignored.
+	writeConfigFile(t, dir, "other.yaml", "custom_config:\n  mode: other\n")
+
+	// A write reloads the file.
+	writeConfigFile(t, dir, "config.yaml", "custom_config:\n  mode: lax\n")
+	waitFor(t, "the reload", func() bool { return len(p.received()) == 1 })
+	if got := p.received()[0]; !maps.Equal(got, map[string]string{"mode": "lax"}) {
+		t.Errorf("reloaded %v, want mode=lax", got)
+	}
+	waitFor(t, "the reload log", func() bool { return strings.Contains(logs.String(), "Reloaded config from "+path) })
+
+	// An invalid file is logged and leaves the configuration in place.
+	writeConfigFile(t, dir, "config.yaml", "custom_config: [")
+	waitFor(t, "the reload failure", func() bool { return strings.Contains(logs.String(), "config reload: ") })
+	if got := len(p.received()); got != 1 {
+		t.Errorf("plugin configured %d times, want the invalid file skipped", got)
+	}
+
+	// Replacing the file by renaming over it reloads it too.
+	tmp := writeConfigFile(t, t.TempDir(), "config.yaml", "custom_config:\n  mode: renamed\n")
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the rename reload", func() bool { return len(p.received()) == 2 })
+	if got := p.received()[1]["mode"]; got != "renamed" {
+		t.Errorf("reloaded mode=%s, want renamed", got)
+	}
+
+	// Once ctx is done changes are no longer applied.
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	writeConfigFile(t, dir, "config.yaml", "custom_config:\n  mode: late\n")
+	time.Sleep(configFileDebounce + 100*time.Millisecond)
+	if got := len(p.received()); got != 2 {
+		t.Errorf("plugin configured %d times after cancel, want 2", got)
+	}
+}
+
+func TestConfigFileLoaderWatchMissingDir(t *testing.T) {
+	l := &configFileLoader{path: filepath.Join(t.TempDir(), "missing", "config.yaml"), logger: discardLogger()}
+	err := l.watch(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to watch config file") {
+		t.Errorf("watch error = %v, want a watch failure", err)
+	}
+}
@@ -0,0 +1,39 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// correlationKeys are the gRPC metadata keys and HTTP headers checked, in order, for a
+// correlation identifier.
+var correlationKeys = []string{"x-request-id", "x-correlation-id"}
+
+// CorrelationID returns an identifier tying a plugin call to the originating client request.
+// It checks gRPC metadata sent by mcpd, then the X-Request-Id and X-Correlation-Id headers of
+// req (which may be nil), and finally falls back to the propagated trace ID. It returns an empty
+// string when none is available.
+func CorrelationID(ctx context.Context, req *HTTPRequest) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, k := range correlationKeys {
+			if v := md.Get(k); len(v) > 0 && v[0] != "" {
+				return v[0]
+			}
+		}
+	}
+
+	if req != nil {
+		for _, k := range correlationKeys {
+			if v := GetHeader(req.GetHeaders(), k); v != "" {
+				return v
+			}
+		}
+	}
+
+	if tc, ok := TraceContextFromContext(ctx, req); ok {
+		return tc.TraceID
+	}
+
+	return ""
+}
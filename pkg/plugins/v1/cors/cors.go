@@ -0,0 +1,349 @@
+// Package cors implements Cross-Origin Resource Sharing for deployments where browser-based MCP
+// clients call mcpd directly: preflight requests are answered from the request flow without
+// reaching the upstream, and CORS headers are added to responses in the response flow.
+//
+// The policy is decoded from custom_config, so a CORS plugin needs no code beyond serving Plugin:
+//
+//	func main() {
+//	    if err := mcpdpluginsv1.Serve(cors.NewPlugin()); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+//
+// HandleResponse does not see the originating request, so the request flow remembers each
+// request's Origin under its correlation ID (see mcpdpluginsv1.CorrelationID). When mcpd sends
+// no correlation ID, responses still get CORS headers if the policy allows a single origin or
+// any origin.
+package cors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/pending"
+)
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// originTTL bounds how long a request's Origin is remembered while waiting for its response.
+const originTTL = time.Minute
+
+// Config is the CORS policy, decodable from custom_config with mcpdpluginsv1.DecodeConfig.
+type Config struct {
+	// AllowedOrigins lists the origins allowed to call mcpd. "*" allows any origin, and
+	// "https://*.example.com" allows any subdomain of example.com over https.
+	AllowedOrigins []string `config:"allowed_origins" default:"*"`
+
+	// AllowedMethods is returned to preflight requests.
+	AllowedMethods []string `config:"allowed_methods" default:"GET,POST,DELETE,OPTIONS"`
+
+	// AllowedHeaders is returned to preflight requests. When empty (the default), the headers the
+	// browser asks for are allowed, which covers Mcp-Session-Id, Mcp-Protocol-Version and
+	// Authorization without listing them.
+	AllowedHeaders []string `config:"allowed_headers"`
+
+	// ExposedHeaders lists response headers readable by browser scripts.
+	ExposedHeaders []string `config:"exposed_headers" default:"Mcp-Session-Id"`
+
+	// AllowCredentials lets browsers send cookies and HTTP authentication. It cannot be combined
+	// with allowing any origin.
+	AllowCredentials bool `config:"allow_credentials" default:"false"`
+
+	// MaxAge is how long browsers may cache a preflight result.
+	MaxAge time.Duration `config:"max_age" default:"10m"`
+
+	// RejectDisallowed answers preflights from disallowed origins with 403 instead of passing them
+	// to the upstream.
+	RejectDisallowed bool `config:"reject_disallowed" default:"true"`
+}
+
+// DefaultConfig returns the Config with every default applied.
+func DefaultConfig() Config {
+	var cfg Config
+	if _, err := config.Decode(nil, &cfg); err != nil {
+		panic(fmt.Sprintf("cors: invalid defaults: %v", err))
+	}
+
+	return cfg
+}
+
+// Policy applies a Config to requests and responses. It is safe for concurrent use.
+type Policy struct {
+	anyOrigin        bool
+	origins          map[string]struct{}
+	wildcards        [][2]string
+	methods          string
+	headers          string
+	exposed          string
+	allowCredentials bool
+	maxAge           string
+	rejectDisallowed bool
+	now              func() time.Time
+	pending          *pending.Map[string]
+}
+
+// New returns a Policy enforcing cfg.
+func New(cfg Config) (*Policy, error) {
+	p := &Policy{
+		origins:          map[string]struct{}{},
+		methods:          strings.Join(upper(cfg.AllowedMethods), ", "),
+		headers:          strings.Join(cfg.AllowedHeaders, ", "),
+		exposed:          strings.Join(cfg.ExposedHeaders, ", "),
+		allowCredentials: cfg.AllowCredentials,
+		rejectDisallowed: cfg.RejectDisallowed,
+		now:              time.Now,
+		pending:          pending.New[string](originTTL),
+	}
+	if cfg.MaxAge < 0 {
+		return nil, fmt.Errorf("max age cannot be negative")
+	}
+	if cfg.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+
+	for _, o := range cfg.AllowedOrigins {
+		o = strings.ToLower(strings.TrimSuffix(o, "/"))
+		switch {
+		case o == "":
+			continue
+		case o == "*":
+			p.anyOrigin = true
+		case strings.Contains(o, "*"):
+			prefix, suffix, ok := strings.Cut(o, "*")
+			if !ok || strings.Contains(suffix, "*") || !strings.HasSuffix(prefix, "://") {
+				return nil, fmt.Errorf("invalid origin pattern %q: only a leading subdomain wildcard is supported", o)
+			}
+			p.wildcards = append(p.wildcards, [2]string{prefix, suffix})
+		default:
+			p.origins[o] = struct{}{}
+		}
+	}
+	if p.anyOrigin && p.allowCredentials {
+		return nil, fmt.Errorf("credentials cannot be allowed for any origin")
+	}
+	if !p.anyOrigin && len(p.origins) == 0 && len(p.wildcards) == 0 {
+		return nil, fmt.Errorf("at least one allowed origin is required")
+	}
+
+	return p, nil
+}
+
+// Allowed reports whether origin may call mcpd.
+func (p *Policy) Allowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.anyOrigin {
+		return true
+	}
+	o := strings.ToLower(origin)
+	if _, ok := p.origins[o]; ok {
+		return true
+	}
+	for _, w := range p.wildcards {
+		if strings.HasPrefix(o, w[0]) && strings.HasSuffix(o, w[1]) && len(o) > len(w[0])+len(w[1]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HandleRequest answers preflight requests, short-circuiting the chain with 204 for allowed
+// origins (or 403 for disallowed ones when RejectDisallowed is set). Other requests continue
+// unchanged, and their Origin is remembered for HandleResponse.
+func (p *Policy) HandleRequest(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) *mcpdpluginsv1.HTTPResponse {
+	headers := req.GetHeaders()
+	origin := mcpdpluginsv1.GetHeader(headers, "Origin")
+	if origin == "" {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+
+	preflight := strings.EqualFold(req.GetMethod(), http.MethodOptions) &&
+		mcpdpluginsv1.GetHeader(headers, "Access-Control-Request-Method") != ""
+	if !preflight {
+		if id := mcpdpluginsv1.CorrelationID(ctx, req); id != "" && p.Allowed(origin) {
+			p.pending.Put(id, origin, p.now())
+		}
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+
+	if !p.Allowed(origin) {
+		if !p.rejectDisallowed {
+			return &mcpdpluginsv1.HTTPResponse{Continue: true}
+		}
+		return &mcpdpluginsv1.HTTPResponse{
+			StatusCode: http.StatusForbidden,
+			Headers:    map[string]string{"Vary": "Origin"},
+		}
+	}
+
+	out := p.originHeaders(origin)
+	out["Access-Control-Allow-Methods"] = p.methods
+	allowHeaders := p.headers
+	if allowHeaders == "" {
+		// With no configured list, allow whatever the browser asks for.
+		allowHeaders = mcpdpluginsv1.GetHeader(headers, "Access-Control-Request-Headers")
+	}
+	if allowHeaders != "" {
+		out["Access-Control-Allow-Headers"] = allowHeaders
+	}
+	if p.maxAge != "" {
+		out["Access-Control-Max-Age"] = p.maxAge
+	}
+	out["Vary"] = "Origin, Access-Control-Request-Method, Access-Control-Request-Headers"
+
+	return &mcpdpluginsv1.HTTPResponse{StatusCode: http.StatusNoContent, Headers: out}
+}
+
+// HandleResponse returns resp, continuing the chain, with CORS headers added for the origin of
+// the originating request.
+func (p *Policy) HandleResponse(ctx context.Context, resp *mcpdpluginsv1.HTTPResponse) *mcpdpluginsv1.HTTPResponse {
+	out := &mcpdpluginsv1.HTTPResponse{
+		Continue:   true,
+		StatusCode: resp.GetStatusCode(),
+		Headers:    resp.GetHeaders(),
+		Body:       resp.GetBody(),
+	}
+
+	origin, _ := p.pending.Take(mcpdpluginsv1.CorrelationID(ctx, nil), p.now())
+	if origin == "" {
+		origin = p.staticOrigin()
+	}
+	if origin == "" {
+		return out
+	}
+
+	headers := make(map[string]string, len(resp.GetHeaders())+4)
+	for k, v := range resp.GetHeaders() {
+		headers[k] = v
+	}
+	for k, v := range p.originHeaders(origin) {
+		headers[k] = v
+	}
+	if p.exposed != "" {
+		headers["Access-Control-Expose-Headers"] = p.exposed
+	}
+	if vary := mcpdpluginsv1.GetHeader(resp.GetHeaders(), "Vary"); vary != "" && origin != "*" {
+		headers["Vary"] = vary + ", Origin"
+	} else if origin != "*" {
+		headers["Vary"] = "Origin"
+	}
+	out.Headers = headers
+
+	return out
+}
+
+// originHeaders returns the headers allowing origin.
+func (p *Policy) originHeaders(origin string) map[string]string {
+	h := map[string]string{"Access-Control-Allow-Origin": origin}
+	if p.anyOrigin {
+		h["Access-Control-Allow-Origin"] = "*"
+	}
+	if p.allowCredentials {
+		h["Access-Control-Allow-Credentials"] = "true"
+	}
+
+	return h
+}
+
+// staticOrigin returns the Access-Control-Allow-Origin value that does not depend on the request,
+// or an empty string when it does.
+func (p *Policy) staticOrigin() string {
+	switch {
+	case p.anyOrigin:
+		return "*"
+	case len(p.origins) == 1 && len(p.wildcards) == 0:
+		for o := range p.origins {
+			return o
+		}
+	}
+
+	return ""
+}
+
+func upper(s []string) []string {
+	out := make([]string, len(s))
+	for i, v := range s {
+		out[i] = strings.ToUpper(v)
+	}
+
+	return out
+}
+
+// Plugin is a request- and response-flow plugin enforcing the Policy decoded from its
+// custom_config.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	policy atomic.Pointer[Policy]
+}
+
+// NewPlugin returns a Plugin enforcing the default Config until Configure is called.
+func NewPlugin() *Plugin {
+	p := &Plugin{}
+	policy, err := New(DefaultConfig())
+	if err != nil {
+		panic(fmt.Sprintf("cors: invalid defaults: %v", err))
+	}
+	p.policy.Store(policy)
+
+	return p
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "cors",
+		Version:     pluginVersion,
+		Description: "Handles CORS preflights and headers for browser-based MCP clients.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest, mcpdpluginsv1.FlowResponse), nil
+}
+
+// Configure decodes the policy from cfg's custom_config.
+func (p *Plugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	var c Config
+	if err := mcpdpluginsv1.DecodeConfig(ctx, cfg, &c); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	policy, err := New(c)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	p.policy.Store(policy)
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleRequest answers preflight requests with the configured policy.
+func (p *Plugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.policy.Load().HandleRequest(ctx, req), nil
+}
+
+// HandleResponse adds CORS headers with the configured policy.
+func (p *Plugin) HandleResponse(
+	ctx context.Context,
+	resp *mcpdpluginsv1.HTTPResponse,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.policy.Load().HandleResponse(ctx, resp), nil
+}
@@ -0,0 +1,351 @@
+package cors
+
+import (
+	"context"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// newPolicy returns a Policy from the default Config changed by fn.
+func newPolicy(t *testing.T, fn func(c *Config)) *Policy {
+	t.Helper()
+
+	cfg := DefaultConfig()
+	if fn != nil {
+		fn(&cfg)
+	}
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func origins(o ...string) func(c *Config) {
+	return func(c *Config) { c.AllowedOrigins = o }
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  func(c *Config)
+		want string
+	}{
+		{"negative max age", func(c *Config) { c.MaxAge = -time.Second }, "max age cannot be negative"},
+		{"inner wildcard", origins("https://api.*.example.com"), "only a leading subdomain wildcard is supported"},
+		{"wildcard without scheme", origins("*.example.com"), `invalid origin pattern "*.example.com"`},
+		{"two wildcards", origins("https://*.*.example.com"), "invalid origin pattern"},
+		{
+			"credentials for any origin",
+			func(c *Config) { c.AllowCredentials = true },
+			"credentials cannot be allowed for any origin",
+		},
+		{"no origins", origins(), "at least one allowed origin is required"},
+		{"empty origins", origins("", ""), "at least one allowed origin is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.cfg(&cfg)
+			if _, err := New(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	p := newPolicy(t, origins("https://app.example.com/", "HTTPS://*.Example.org"))
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"http://app.example.com", false},
+		{"https://evil.example.com", false},
+		{"https://a.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://.example.org", false},
+		{"https://example.org", false},
+		{"http://a.example.org", false},
+		{"https://a.example.org.evil.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			if got := p.Allowed(tt.origin); got != tt.want {
+				t.Errorf("Allowed(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+
+	if !newPolicy(t, nil).Allowed("null") {
+		t.Error(`the default policy does not allow any origin`)
+	}
+}
+
+// preflight returns an OPTIONS preflight request from origin.
+func preflight(origin string, extra ...string) *mcpdpluginsv1.HTTPRequest {
+	h := map[string]string{"Origin": origin, "Access-Control-Request-Method": "POST"}
+	for i := 0; i+1 < len(extra); i += 2 {
+		h[extra[i]] = extra[i+1]
+	}
+	return &mcpdpluginsv1.HTTPRequest{Method: "OPTIONS", Path: "/mcp", Headers: h}
+}
+
+func TestHandleRequest(t *testing.T) {
+	const (
+		site          = "https://app.example.com"
+		preflightVary = "Origin, Access-Control-Request-Method, Access-Control-Request-Headers"
+	)
+	tests := []struct {
+		name       string
+		cfg        func(c *Config)
+		req        *mcpdpluginsv1.HTTPRequest
+		wantStatus int32 // 0 continues the chain.
+		want       map[string]string
+	}{
+		{
+			name: "no origin",
+			req: &mcpdpluginsv1.HTTPRequest{
+				Method:  "OPTIONS",
+				Headers: map[string]string{"Access-Control-Request-Method": "POST"},
+			},
+		},
+		{
+			name: "not a preflight",
+			req:  &mcpdpluginsv1.HTTPRequest{Method: "OPTIONS", Headers: map[string]string{"Origin": site}},
+		},
+		{
+			name:       "any origin",
+			req:        preflight(site, "Access-Control-Request-Headers", "Mcp-Session-Id, Authorization"),
+			wantStatus: 204,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Methods": "GET, POST, DELETE, OPTIONS",
+				"Access-Control-Allow-Headers": "Mcp-Session-Id, Authorization",
+				"Access-Control-Max-Age":       "600",
+				"Vary":                         preflightVary,
+			},
+		},
+		{
+			name: "listed origin with credentials",
+			cfg: func(c *Config) {
+				c.AllowedOrigins = []string{site}
+				c.AllowCredentials = true
+				c.AllowedMethods = []string{"post"}
+				c.AllowedHeaders = []string{"Authorization", "Content-Type"}
+				c.MaxAge = 0
+			},
+			req: &mcpdpluginsv1.HTTPRequest{Method: "options", Headers: map[string]string{
+				"origin":                         site,
+				"access-control-request-method":  "POST",
+				"Access-Control-Request-Headers": "X-Other",
+			}},
+			wantStatus: 204,
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      site,
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "POST",
+				"Access-Control-Allow-Headers":     "Authorization, Content-Type",
+				"Vary":                             preflightVary,
+			},
+		},
+		{
+			name:       "disallowed origin rejected",
+			cfg:        origins(site),
+			req:        preflight("https://evil.com"),
+			wantStatus: 403,
+			want:       map[string]string{"Vary": "Origin"},
+		},
+		{
+			name: "disallowed origin passed on",
+			cfg:  func(c *Config) { c.AllowedOrigins, c.RejectDisallowed = []string{site}, false },
+			req:  preflight("https://evil.com"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newPolicy(t, tt.cfg).HandleRequest(context.Background(), tt.req)
+			if tt.wantStatus == 0 {
+				if !got.GetContinue() || got.GetModifiedRequest() != nil {
+					t.Errorf("HandleRequest = %v, want the request to continue unchanged", got)
+				}
+				return
+			}
+			if got.GetContinue() || got.GetStatusCode() != tt.wantStatus {
+				t.Errorf("HandleRequest = %v, want a %d short-circuit", got, tt.wantStatus)
+			}
+			if !maps.Equal(got.GetHeaders(), tt.want) {
+				t.Errorf("headers = %v, want %v", got.GetHeaders(), tt.want)
+			}
+		})
+	}
+}
+
+// withRequestID returns a context carrying the correlation ID id, as mcpd sends it.
+func withRequestID(id string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", id))
+}
+
+func TestHandleResponse(t *testing.T) {
+	const site = "https://app.example.com"
+	tests := []struct {
+		name     string
+		cfg      func(c *Config)
+		origin   string // Origin of the request sent under correlation ID r1; empty sends none.
+		respID   string
+		headers  map[string]string
+		advance  time.Duration
+		want     map[string]string
+		wantSame bool // The response headers are returned unchanged.
+	}{
+		{
+			name:    "remembered origin",
+			cfg:     origins(site, "https://other.example.com"),
+			origin:  site,
+			respID:  "r1",
+			headers: map[string]string{"Content-Type": "application/json", "Vary": "Accept"},
+			want: map[string]string{
+				"Content-Type":                  "application/json",
+				"Access-Control-Allow-Origin":   site,
+				"Access-Control-Expose-Headers": "Mcp-Session-Id",
+				"Vary":                          "Accept, Origin",
+			},
+		},
+		{
+			name:     "other response",
+			cfg:      origins(site, "https://other.example.com"),
+			origin:   site,
+			respID:   "r2",
+			wantSame: true,
+		},
+		{
+			name:     "disallowed origin not remembered",
+			cfg:      origins(site, "https://other.example.com"),
+			origin:   "https://evil.com",
+			respID:   "r1",
+			wantSame: true,
+		},
+		{
+			name:     "expired origin",
+			cfg:      origins(site, "https://other.example.com"),
+			origin:   site,
+			respID:   "r1",
+			advance:  originTTL + time.Second,
+			wantSame: true,
+		},
+		{
+			name: "single origin without correlation",
+			cfg: func(c *Config) {
+				c.AllowedOrigins, c.AllowCredentials, c.ExposedHeaders = []string{site}, true, nil
+			},
+			respID: "",
+			want: map[string]string{
+				"Access-Control-Allow-Origin":      site,
+				"Access-Control-Allow-Credentials": "true",
+				"Vary":                             "Origin",
+			},
+		},
+		{
+			name:   "any origin",
+			respID: "r9",
+			want: map[string]string{
+				"Access-Control-Allow-Origin":   "*",
+				"Access-Control-Expose-Headers": "Mcp-Session-Id",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPolicy(t, tt.cfg)
+			now := time.Unix(1_000_000, 0)
+			p.now = func() time.Time { return now }
+			if tt.origin != "" {
+				req := &mcpdpluginsv1.HTTPRequest{Method: "POST", Headers: map[string]string{"Origin": tt.origin}}
+				if got := p.HandleRequest(withRequestID("r1"), req); !got.GetContinue() {
+					t.Fatalf("HandleRequest = %v, want continue", got)
+				}
+			}
+			now = now.Add(tt.advance)
+
+			resp := &mcpdpluginsv1.HTTPResponse{StatusCode: 200, Headers: tt.headers, Body: []byte("ok")}
+			ctx := context.Background()
+			if tt.respID != "" {
+				ctx = withRequestID(tt.respID)
+			}
+			got := p.HandleResponse(ctx, resp)
+			if !got.GetContinue() || got.GetStatusCode() != 200 || string(got.GetBody()) != "ok" {
+				t.Errorf("HandleResponse = %v, want the response continuing", got)
+			}
+			want := tt.want
+			if tt.wantSame {
+				want = tt.headers
+			}
+			if !maps.Equal(got.GetHeaders(), want) {
+				t.Errorf("headers = %v, want %v", got.GetHeaders(), want)
+			}
+		})
+	}
+}
+
+func TestResponseTakesOrigin(t *testing.T) {
+	p := newPolicy(t, origins("https://a.example.com", "https://b.example.com"))
+	req := &mcpdpluginsv1.HTTPRequest{Headers: map[string]string{"Origin": "https://a.example.com"}}
+	p.HandleRequest(withRequestID("r1"), req)
+
+	first := p.HandleResponse(withRequestID("r1"), &mcpdpluginsv1.HTTPResponse{})
+	second := p.HandleResponse(withRequestID("r1"), &mcpdpluginsv1.HTTPResponse{})
+	if first.GetHeaders()["Access-Control-Allow-Origin"] != "https://a.example.com" || len(second.GetHeaders()) != 0 {
+		t.Errorf("responses got %v then %v, want the origin used once", first.GetHeaders(), second.GetHeaders())
+	}
+}
+
+func TestPluginConfigure(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]string
+		wantCode codes.Code
+	}{
+		{name: "valid", config: map[string]string{"allowed_origins": "https://app.example.com", "max_age": "1h"}},
+		{name: "undecodable", config: map[string]string{"max_age": "forever"}, wantCode: codes.InvalidArgument},
+		{
+			name:     "invalid policy",
+			config:   map[string]string{"allow_credentials": "true"},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPlugin()
+			_, err := p.Configure(context.Background(), &mcpdpluginsv1.PluginConfig{CustomConfig: tt.config})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("Configure error = %v, want %s", err, tt.wantCode)
+			}
+
+			got, err := p.HandleRequest(context.Background(), preflight("https://app.example.com"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantOrigin, wantAge := "https://app.example.com", "3600"
+			if tt.wantCode != codes.OK {
+				// The previous policy stays in force.
+				wantOrigin, wantAge = "*", "600"
+			}
+			h := got.GetHeaders()
+			if h["Access-Control-Allow-Origin"] != wantOrigin || h["Access-Control-Max-Age"] != wantAge {
+				t.Errorf("preflight headers = %v, want origin %s and max age %s", h, wantOrigin, wantAge)
+			}
+		})
+	}
+}
@@ -0,0 +1,367 @@
+// Package cost estimates what each MCP request costs and attributes it to the calling identity,
+// for chargeback in mcpd deployments shared between teams.
+//
+// An Attributor weighs the tokens and bytes of a request and its response, plus a per-tool weight,
+// into cost units. It tells the upstream who is calling through attribution headers, tells the
+// caller what the call cost through response headers, and records metrics labelled by identity
+// and tool:
+//
+//	// custom_config:
+//	//   identity:     header:X-Team
+//	//   token_weight: 0.001
+//	//   tool_weights: search=1,summarize=5
+//	if err := mcpdpluginsv1.Serve(cost.NewPlugin(recorder)); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+// Requests and responses are paired through their correlation ID (see
+// mcpdpluginsv1.CorrelationID); requests without one are costed on their own content only.
+// Identity labels can have high cardinality: prefer identities such as teams or tenants over
+// sessions when metrics are exported to a time series database.
+package cost
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/pending"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/tokens"
+)
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// Attribution headers. IdentityHeader and UnitsHeader are set on requests sent upstream, with the
+// request's own cost; UnitsHeader and TokensHeader are set on responses with the whole call's.
+// Values sent by clients are always replaced.
+const (
+	IdentityHeader = "X-Cost-Identity"
+	UnitsHeader    = "X-Cost-Units"
+	TokensHeader   = "X-Cost-Tokens"
+)
+
+// Names of the metrics recorded by an Attributor, labelled by LabelIdentity and LabelTool.
+const (
+	// MetricUnits records the cost units of each call; its sum is the cost to charge back.
+	MetricUnits = "cost.units"
+
+	// MetricTokens and MetricBytes count the tokens and bytes of requests and responses.
+	MetricTokens = "cost.tokens"
+	MetricBytes  = "cost.bytes"
+)
+
+// Label keys of the Attributor metrics. Calls other than tools/call have an empty tool.
+const (
+	LabelIdentity = "identity"
+	LabelTool     = "tool"
+)
+
+// pendingTTL bounds how long a request's estimate waits for its response.
+const pendingTTL = 5 * time.Minute
+
+// Weights maps tool names to their cost, decodable from a comma-separated list of name=weight
+// pairs such as "search=1,summarize=5".
+type Weights map[string]float64
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (w *Weights) UnmarshalText(text []byte) error {
+	out := Weights{}
+	for pair := range strings.SplitSeq(string(text), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("expected name=weight, got %q", pair)
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || f < 0 {
+			return fmt.Errorf("weight of %s must be a non-negative number", name)
+		}
+		out[strings.TrimSpace(name)] = f
+	}
+	*w = out
+
+	return nil
+}
+
+// Config configures an Attributor, decodable from custom_config with mcpdpluginsv1.DecodeConfig.
+type Config struct {
+	// Identity selects who calls are attributed to: session (the MCP session, falling back to the
+	// client), client (the client address), header:<name> (the value of a request header) or
+	// principal (the ID of the principal identified with mcpdpluginsv1.WithIdentity, falling back
+	// to session).
+	Identity string `config:"identity" default:"session"`
+
+	// Encoding is the tokens encoding counting the user-visible content of calls.
+	Encoding string `config:"encoding" default:"heuristic"`
+
+	// TokenWeight and ByteWeight are the cost units of a token and of a body byte.
+	TokenWeight float64 `config:"token_weight" default:"1"`
+	ByteWeight  float64 `config:"byte_weight" default:"0"`
+
+	// ToolWeights adds a per-call cost to tools/call requests of the listed tools, and
+	// DefaultToolWeight to those of other tools.
+	ToolWeights       Weights `config:"tool_weights"`
+	DefaultToolWeight float64 `config:"default_tool_weight" default:"0"`
+
+	// Headers sets the attribution headers on requests and responses.
+	Headers bool `config:"headers" default:"true"`
+}
+
+// DefaultConfig returns the Config with every default applied.
+func DefaultConfig() Config {
+	var cfg Config
+	if _, err := config.Decode(nil, &cfg); err != nil {
+		panic(fmt.Sprintf("cost: invalid defaults: %v", err))
+	}
+
+	return cfg
+}
+
+// IdentityKey returns the key function of an identity: session, client or header:<name>. The
+// principal identity has no request-only key function and returns that of its session fallback.
+func IdentityKey(identity string) (tokens.KeyFunc, error) {
+	switch strings.ToLower(identity) {
+	case "", "session", "principal":
+		return tokens.SessionKey, nil
+	case "client":
+		return tokens.ClientKey, nil
+	}
+	name, ok := strings.CutPrefix(identity, "header:")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("identity must be session, client, principal or header:<name>, got %q", identity)
+	}
+
+	return tokens.HeaderKey(name), nil
+}
+
+// Estimate is the cost of a call, or of its request alone until the response is seen.
+type Estimate struct {
+	Identity string
+	Tool     string
+	Tokens   int
+	Bytes    int
+	Units    float64
+}
+
+// Attributor estimates and attributes the cost of calls. It is safe for concurrent use.
+type Attributor struct {
+	cfg      Config
+	identity tokens.KeyFunc
+	enc      tokens.Encoding
+	recorder metrics.Recorder
+	now      func() time.Time
+	pending  *pending.Map[Estimate]
+}
+
+// New returns an Attributor for cfg recording metrics through recorder (nil disables metrics).
+func New(cfg Config, recorder metrics.Recorder) (*Attributor, error) {
+	identity, err := IdentityKey(cfg.Identity)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := tokens.Lookup(cfg.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.TokenWeight < 0 || cfg.ByteWeight < 0 || cfg.DefaultToolWeight < 0 {
+		return nil, fmt.Errorf("weights cannot be negative")
+	}
+	if recorder == nil {
+		recorder = metrics.Nop()
+	}
+
+	return &Attributor{
+		cfg:      cfg,
+		identity: identity,
+		enc:      enc,
+		recorder: recorder,
+		now:      time.Now,
+		pending:  pending.New[Estimate](pendingTTL),
+	}, nil
+}
+
+// content returns the tokens and bytes of body.
+func (a *Attributor) content(body []byte) (int, int) {
+	n, err := tokens.CountMCP(a.enc, body)
+	if err != nil {
+		n = 0
+	}
+
+	return n, len(body)
+}
+
+// units returns the cost of n tokens and size bytes.
+func (a *Attributor) units(n, size int) float64 {
+	return float64(n)*a.cfg.TokenWeight + float64(size)*a.cfg.ByteWeight
+}
+
+// EstimateRequest returns the cost of req on its own: its content and, for tools/call, the tool's
+// weight.
+func (a *Attributor) EstimateRequest(req *mcpdpluginsv1.HTTPRequest) Estimate {
+	e := Estimate{Identity: a.identity(req)}
+	e.Tokens, e.Bytes = a.content(req.GetBody())
+	e.Units = a.units(e.Tokens, e.Bytes)
+
+	if m, err := mcp.ParseOne(req.GetBody()); err == nil && m.Method == "tools/call" {
+		e.Tool = m.ToolName()
+		if w, ok := a.cfg.ToolWeights[e.Tool]; ok {
+			e.Units += w
+		} else {
+			e.Units += a.cfg.DefaultToolWeight
+		}
+	}
+
+	return e
+}
+
+// record records the metrics of e.
+func (a *Attributor) record(e Estimate) {
+	labels := []metrics.Label{metrics.L(LabelIdentity, e.Identity), metrics.L(LabelTool, e.Tool)}
+	a.recorder.Observe(MetricUnits, e.Units, labels...)
+	a.recorder.Count(MetricTokens, int64(e.Tokens), labels...)
+	a.recorder.Count(MetricBytes, int64(e.Bytes), labels...)
+}
+
+// HandleRequest estimates req's cost and continues the chain with the attribution headers set.
+// The metrics of requests with a correlation ID are recorded with their response.
+func (a *Attributor) HandleRequest(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) *mcpdpluginsv1.HTTPResponse {
+	e := a.EstimateRequest(req)
+	if strings.EqualFold(a.cfg.Identity, "principal") {
+		if id := mcpdpluginsv1.PrincipalID(ctx); id != "" {
+			e.Identity = id
+		}
+	}
+	if id := mcpdpluginsv1.CorrelationID(ctx, req); id != "" {
+		a.pending.Put(id, e, a.now())
+	} else {
+		a.record(e)
+	}
+
+	if !a.cfg.Headers {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+	modified := mcpdpluginsv1.CloneRequest(req)
+	mcpdpluginsv1.SetRequestHeader(modified, IdentityHeader, e.Identity)
+	mcpdpluginsv1.SetRequestHeader(modified, UnitsHeader, formatUnits(e.Units))
+
+	return &mcpdpluginsv1.HTTPResponse{Continue: true, ModifiedRequest: modified}
+}
+
+// HandleResponse adds the cost of resp to its request's, records the call's metrics and continues
+// the chain with the call's cost in the response headers. Responses whose request is unknown
+// continue unchanged.
+func (a *Attributor) HandleResponse(ctx context.Context, resp *mcpdpluginsv1.HTTPResponse) *mcpdpluginsv1.HTTPResponse {
+	out := &mcpdpluginsv1.HTTPResponse{
+		Continue:   true,
+		StatusCode: resp.GetStatusCode(),
+		Headers:    resp.GetHeaders(),
+		Body:       resp.GetBody(),
+	}
+
+	e, ok := a.pending.Take(mcpdpluginsv1.CorrelationID(ctx, nil), a.now())
+	if !ok {
+		return out
+	}
+	n, size := a.content(resp.GetBody())
+	e.Tokens += n
+	e.Bytes += size
+	e.Units += a.units(n, size)
+	a.record(e)
+
+	if a.cfg.Headers {
+		headers := make(map[string]string, len(resp.GetHeaders())+2)
+		for k, v := range resp.GetHeaders() {
+			headers[k] = v
+		}
+		mcpdpluginsv1.SetHeader(headers, UnitsHeader, formatUnits(e.Units))
+		mcpdpluginsv1.SetHeader(headers, TokensHeader, strconv.Itoa(e.Tokens))
+		out.Headers = headers
+	}
+
+	return out
+}
+
+func formatUnits(units float64) string {
+	return strconv.FormatFloat(units, 'f', -1, 64)
+}
+
+// Plugin is a request- and response-flow plugin running the Attributor decoded from its
+// custom_config.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	recorder   metrics.Recorder
+	attributor atomic.Pointer[Attributor]
+}
+
+// NewPlugin returns a Plugin attributing with the default Config until Configure is called,
+// recording metrics through recorder (nil disables metrics).
+func NewPlugin(recorder metrics.Recorder) *Plugin {
+	p := &Plugin{recorder: recorder}
+	a, err := New(DefaultConfig(), recorder)
+	if err != nil {
+		panic(fmt.Sprintf("cost: invalid defaults: %v", err))
+	}
+	p.attributor.Store(a)
+
+	return p
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "cost-attribution",
+		Version:     pluginVersion,
+		Description: "Estimates per-request cost and attributes it to the calling identity.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest, mcpdpluginsv1.FlowResponse), nil
+}
+
+// Configure decodes the attributor from cfg's custom_config.
+func (p *Plugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	var c Config
+	if err := mcpdpluginsv1.DecodeConfig(ctx, cfg, &c); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	a, err := New(c, p.recorder)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	p.attributor.Store(a)
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleRequest attributes requests.
+func (p *Plugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.attributor.Load().HandleRequest(ctx, req), nil
+}
+
+// HandleResponse completes the cost of calls.
+func (p *Plugin) HandleResponse(
+	ctx context.Context,
+	resp *mcpdpluginsv1.HTTPResponse,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.attributor.Load().HandleResponse(ctx, resp), nil
+}
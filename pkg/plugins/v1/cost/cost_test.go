@@ -0,0 +1,506 @@
+package cost_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/cost"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/tokens"
+)
+
+// fakeRecorder records every sample as a line such as "count cost.bytes 10 [identity=a tool=b]".
+type fakeRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *fakeRecorder) add(kind, name string, value any, labels []metrics.Label) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Key + "=" + l.Value
+	}
+	r.lines = append(r.lines, fmt.Sprintf("%s %s %v [%s]", kind, name, value, strings.Join(parts, " ")))
+}
+
+func (r *fakeRecorder) Count(name string, delta int64, labels ...metrics.Label) {
+	r.add("count", name, delta, labels)
+}
+
+func (r *fakeRecorder) Gauge(name string, value float64, labels ...metrics.Label) {
+	r.add("gauge", name, value, labels)
+}
+
+func (r *fakeRecorder) Observe(name string, value float64, labels ...metrics.Label) {
+	r.add("observe", name, value, labels)
+}
+
+func (r *fakeRecorder) Timing(name string, d time.Duration, labels ...metrics.Label) {
+	r.add("timing", name, d, labels)
+}
+
+func (r *fakeRecorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.lines)
+}
+
+const (
+	toolCall = `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search","arguments":{"q":"go"}}}`
+	result   = `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"one two three four"}]}}`
+)
+
+// withID returns a context carrying the correlation ID id, as mcpd sends it.
+func withID(id string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", id))
+}
+
+// countTokens returns the heuristic token count of body.
+func countTokens(t *testing.T, body string) int {
+	t.Helper()
+
+	enc, err := tokens.Lookup("heuristic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := tokens.CountMCP(enc, []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func TestWeightsUnmarshalText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    cost.Weights
+		wantErr string
+	}{
+		{name: "pairs", text: "search=1, summarize = 2.5", want: cost.Weights{"search": 1, "summarize": 2.5}},
+		{name: "empty", text: "", want: cost.Weights{}},
+		{name: "empty entries", text: ",search=1,,", want: cost.Weights{"search": 1}},
+		{name: "zero weight", text: "free=0", want: cost.Weights{"free": 0}},
+		{name: "missing weight", text: "search", wantErr: `expected name=weight, got "search"`},
+		{name: "missing name", text: "=1", wantErr: `expected name=weight, got "=1"`},
+		{name: "not a number", text: "search=lots", wantErr: "weight of search must be a non-negative number"},
+		{name: "negative", text: "search=-1", wantErr: "weight of search must be a non-negative number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := cost.Weights{"stale": 1}
+			err := w.UnmarshalText([]byte(tt.text))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("UnmarshalText error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(w) != len(tt.want) {
+				t.Fatalf("Weights = %v, want %v", w, tt.want)
+			}
+			for k, v := range tt.want {
+				if got, ok := w[k]; !ok || got != v {
+					t.Errorf("Weights = %v, want %v", w, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestIdentityKey(t *testing.T) {
+	req := &mcpdpluginsv1.HTTPRequest{
+		RemoteAddr: "10.0.0.1:5000",
+		Headers:    map[string]string{"Mcp-Session-Id": "s1", "X-Team": "search"},
+	}
+	tests := []struct {
+		identity string
+		want     string
+		wantErr  bool
+	}{
+		{identity: "", want: "session:s1"},
+		{identity: "session", want: "session:s1"},
+		{identity: "Session", want: "session:s1"},
+		{identity: "principal", want: "session:s1"},
+		{identity: "client", want: "client:10.0.0.1"},
+		{identity: "header:X-Team", want: "header:search"},
+		{identity: "header:x-team", want: "header:search"},
+		{identity: "header:", wantErr: true},
+		{identity: "team", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.identity, func(t *testing.T) {
+			key, err := cost.IdentityKey(tt.identity)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "identity must be session, client") {
+					t.Errorf("IdentityKey error = %v, want an invalid identity", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := key(req); got != tt.want {
+				t.Errorf("key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		mod     func(*cost.Config)
+		wantErr string
+	}{
+		{name: "defaults", mod: func(*cost.Config) {}},
+		{name: "unknown identity", mod: func(c *cost.Config) { c.Identity = "team" }, wantErr: "identity must be"},
+		{
+			name:    "unknown encoding",
+			mod:     func(c *cost.Config) { c.Encoding = "nope" },
+			wantErr: `unknown token encoding "nope"`,
+		},
+		{
+			name:    "negative token weight",
+			mod:     func(c *cost.Config) { c.TokenWeight = -1 },
+			wantErr: "weights cannot be negative",
+		},
+		{
+			name:    "negative byte weight",
+			mod:     func(c *cost.Config) { c.ByteWeight = -1 },
+			wantErr: "weights cannot be negative",
+		},
+		{
+			name:    "negative default tool weight",
+			mod:     func(c *cost.Config) { c.DefaultToolWeight = -1 },
+			wantErr: "weights cannot be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cost.DefaultConfig()
+			tt.mod(&cfg)
+			_, err := cost.New(cfg, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("New: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEstimateRequest(t *testing.T) {
+	cfg := cost.DefaultConfig()
+	cfg.TokenWeight, cfg.ByteWeight = 0, 1
+	cfg.ToolWeights = cost.Weights{"search": 10}
+	cfg.DefaultToolWeight = 3
+	a, err := cost.New(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := strings.Replace(toolCall, "search", "fetch", 1)
+	list := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`
+	tests := []struct {
+		name      string
+		body      string
+		wantTool  string
+		wantUnits float64
+	}{
+		{name: "weighted tool", body: toolCall, wantTool: "search", wantUnits: float64(len(toolCall)) + 10},
+		{name: "other tool", body: other, wantTool: "fetch", wantUnits: float64(len(other)) + 3},
+		{name: "not a tool call", body: list, wantUnits: float64(len(list))},
+		{name: "not JSON", body: "hello", wantUnits: 5},
+		{name: "empty body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := a.EstimateRequest(&mcpdpluginsv1.HTTPRequest{
+				Headers: map[string]string{"Mcp-Session-Id": "s1"},
+				Body:    []byte(tt.body),
+			})
+			if e.Identity != "session:s1" || e.Tool != tt.wantTool || e.Bytes != len(tt.body) ||
+				e.Units != tt.wantUnits {
+				t.Errorf("EstimateRequest = %+v, want tool %q, %d bytes and %v units",
+					e, tt.wantTool, len(tt.body), tt.wantUnits)
+			}
+		})
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	cfg := cost.DefaultConfig()
+	cfg.TokenWeight = 0.5
+	a, err := cost.New(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := a.EstimateRequest(&mcpdpluginsv1.HTTPRequest{Body: []byte(result)})
+	n := countTokens(t, result)
+	if n == 0 || e.Tokens != n || e.Units != float64(n)/2 {
+		t.Errorf("EstimateRequest = %+v, want %d tokens at half a unit each", e, n)
+	}
+}
+
+func TestAttributor(t *testing.T) {
+	reqTokens, respTokens := countTokens(t, toolCall), countTokens(t, result)
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		headers     bool
+		wantReqHdrs map[string]string
+		wantUnits   string
+		wantTokens  string
+		wantMetrics []string
+	}{
+		{
+			name:    "paired call",
+			ctx:     withID("1"),
+			headers: true,
+			wantReqHdrs: map[string]string{
+				cost.IdentityHeader: "header:search",
+				cost.UnitsHeader:    strconv.Itoa(len(toolCall) + 2),
+			},
+			wantUnits:  strconv.Itoa(len(toolCall) + len(result) + 2),
+			wantTokens: strconv.Itoa(reqTokens + respTokens),
+			wantMetrics: []string{
+				fmt.Sprintf("observe cost.units %d [identity=header:search tool=search]", len(toolCall)+len(result)+2),
+				fmt.Sprintf("count cost.tokens %d [identity=header:search tool=search]", reqTokens+respTokens),
+				fmt.Sprintf("count cost.bytes %d [identity=header:search tool=search]", len(toolCall)+len(result)),
+			},
+		},
+		{
+			name: "headers disabled",
+			ctx:  withID("1"),
+			wantMetrics: []string{
+				fmt.Sprintf("observe cost.units %d [identity=header:search tool=search]", len(toolCall)+len(result)+2),
+				fmt.Sprintf("count cost.tokens %d [identity=header:search tool=search]", reqTokens+respTokens),
+				fmt.Sprintf("count cost.bytes %d [identity=header:search tool=search]", len(toolCall)+len(result)),
+			},
+		},
+		{
+			name:    "no correlation ID",
+			ctx:     context.Background(),
+			headers: true,
+			wantReqHdrs: map[string]string{
+				cost.IdentityHeader: "header:search",
+				cost.UnitsHeader:    strconv.Itoa(len(toolCall) + 2),
+			},
+			wantMetrics: []string{
+				fmt.Sprintf("observe cost.units %d [identity=header:search tool=search]", len(toolCall)+2),
+				fmt.Sprintf("count cost.tokens %d [identity=header:search tool=search]", reqTokens),
+				fmt.Sprintf("count cost.bytes %d [identity=header:search tool=search]", len(toolCall)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cost.DefaultConfig()
+			cfg.Identity = "header:X-Team"
+			cfg.TokenWeight, cfg.ByteWeight = 0, 1
+			cfg.DefaultToolWeight = 2
+			cfg.Headers = tt.headers
+			r := &fakeRecorder{}
+			a, err := cost.New(cfg, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := &mcpdpluginsv1.HTTPRequest{
+				Headers: map[string]string{"X-Team": "search", cost.UnitsHeader: "0"},
+				Body:    []byte(toolCall),
+			}
+			out := a.HandleRequest(tt.ctx, req)
+			if !out.GetContinue() {
+				t.Fatalf("HandleRequest = %v, want it continued", out)
+			}
+			if req.GetHeaders()[cost.UnitsHeader] != "0" {
+				t.Error("HandleRequest modified the request in place")
+			}
+			if tt.wantReqHdrs == nil {
+				if out.GetModifiedRequest() != nil {
+					t.Errorf("modified request %v, want none", out.GetModifiedRequest())
+				}
+			}
+			for k, v := range tt.wantReqHdrs {
+				if got := mcpdpluginsv1.GetHeader(out.GetModifiedRequest().GetHeaders(), k); got != v {
+					t.Errorf("request header %s = %q, want %q", k, got, v)
+				}
+			}
+
+			resp := &mcpdpluginsv1.HTTPResponse{
+				StatusCode: 200,
+				Headers:    map[string]string{"Content-Type": "application/json", cost.TokensHeader: "0"},
+				Body:       []byte(result),
+			}
+			got := a.HandleResponse(tt.ctx, resp)
+			if !got.GetContinue() || got.GetStatusCode() != 200 || string(got.GetBody()) != result {
+				t.Errorf("HandleResponse = %v, want resp continued", got)
+			}
+			if resp.GetHeaders()[cost.TokensHeader] != "0" {
+				t.Error("HandleResponse modified the response headers in place")
+			}
+			if got.GetHeaders()["Content-Type"] != "application/json" {
+				t.Errorf("response headers = %v, want Content-Type kept", got.GetHeaders())
+			}
+			wantUnits, wantTokens := tt.wantUnits, tt.wantTokens
+			if wantTokens == "" {
+				wantTokens = "0" // Left as the upstream sent it.
+			}
+			if u := mcpdpluginsv1.GetHeader(got.GetHeaders(), cost.UnitsHeader); u != wantUnits {
+				t.Errorf("response %s = %q, want %q", cost.UnitsHeader, u, wantUnits)
+			}
+			if n := mcpdpluginsv1.GetHeader(got.GetHeaders(), cost.TokensHeader); n != wantTokens {
+				t.Errorf("response %s = %q, want %q", cost.TokensHeader, n, wantTokens)
+			}
+
+			if m := r.recorded(); !slices.Equal(m, tt.wantMetrics) {
+				t.Errorf("metrics\n%s\nwant\n%s", strings.Join(m, "\n"), strings.Join(tt.wantMetrics, "\n"))
+			}
+		})
+	}
+}
+
+func TestAttributorPrincipalIdentity(t *testing.T) {
+	alice := mcpdpluginsv1.ContextWithPrincipal(context.Background(), mcpdpluginsv1.NewPrincipal("jwt", "", "alice"))
+	tests := []struct {
+		name     string
+		identity string
+		ctx      context.Context
+		want     string
+	}{
+		{
+			name:     "principal",
+			identity: "principal",
+			ctx:      alice,
+			want:     "jwt:alice",
+		},
+		{
+			name:     "anonymous falls back to the session",
+			identity: "Principal",
+			ctx:      context.Background(),
+			want:     "session:s1",
+		},
+		{
+			name:     "principal ignored for other identities",
+			identity: "session",
+			ctx:      alice,
+			want:     "session:s1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cost.DefaultConfig()
+			cfg.Identity = tt.identity
+			a, err := cost.New(cfg, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := a.HandleRequest(tt.ctx, &mcpdpluginsv1.HTTPRequest{
+				Headers: map[string]string{"Mcp-Session-Id": "s1"},
+				Body:    []byte(toolCall),
+			})
+			if got := out.GetModifiedRequest().GetHeaders()[cost.IdentityHeader]; got != tt.want {
+				t.Errorf("identity = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttributorUnknownResponse(t *testing.T) {
+	r := &fakeRecorder{}
+	a, err := cost.New(cost.DefaultConfig(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := &mcpdpluginsv1.HTTPResponse{StatusCode: 200, Body: []byte(result)}
+	got := a.HandleResponse(withID("unknown"), resp)
+	if !got.GetContinue() || len(got.GetHeaders()) != 0 {
+		t.Errorf("HandleResponse = %v, want resp continued unchanged", got)
+	}
+	// A response is only costed once.
+	a.HandleRequest(withID("1"), &mcpdpluginsv1.HTTPRequest{Body: []byte(toolCall)})
+	a.HandleResponse(withID("1"), resp)
+	a.HandleResponse(withID("1"), resp)
+	if m := r.recorded(); len(m) != 3 {
+		t.Errorf("metrics = %q, want one call recorded", m)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	p := cost.NewPlugin(nil)
+	ctx := context.Background()
+
+	md, err := p.GetMetadata(ctx, &emptypb.Empty{})
+	if err != nil || md.GetName() != "cost-attribution" {
+		t.Errorf("GetMetadata = %v, %v", md, err)
+	}
+	caps, err := p.GetCapabilities(ctx, &emptypb.Empty{})
+	if err != nil || len(caps.GetFlows()) != 2 {
+		t.Errorf("GetCapabilities = %v, %v, want both flows", caps, err)
+	}
+
+	tests := []struct {
+		name     string
+		custom   map[string]string
+		wantCode codes.Code
+	}{
+		{name: "invalid identity", custom: map[string]string{"identity": "team"}, wantCode: codes.InvalidArgument},
+		{name: "invalid weights", custom: map[string]string{"tool_weights": "search"}, wantCode: codes.InvalidArgument},
+		{name: "negative weight", custom: map[string]string{"byte_weight": "-1"}, wantCode: codes.InvalidArgument},
+		{
+			name:   "valid",
+			custom: map[string]string{"identity": "header:X-Team", "token_weight": "0", "tool_weights": "search=4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.Configure(ctx, &mcpdpluginsv1.PluginConfig{CustomConfig: tt.custom})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("Configure error = %v, want %s", err, tt.wantCode)
+			}
+		})
+	}
+
+	resp, err := p.HandleRequest(ctx, &mcpdpluginsv1.HTTPRequest{
+		Headers: map[string]string{"X-Team": "search"},
+		Body:    []byte(toolCall),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdrs := resp.GetModifiedRequest().GetHeaders()
+	if hdrs[cost.IdentityHeader] != "header:search" || hdrs[cost.UnitsHeader] != "4" {
+		t.Errorf("request headers = %v, want the configured attributor's", hdrs)
+	}
+	if _, err := p.HandleResponse(ctx, &mcpdpluginsv1.HTTPResponse{StatusCode: 200}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,107 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ClientTimeoutMetadataKey is the gRPC request metadata key with which mcpd reports, in whole
+// milliseconds, how long the client will still wait for the call's response.
+const ClientTimeoutMetadataKey = "mcpd-client-timeout-ms"
+
+// Client timeout headers read from HTTP requests when mcpd sends no ClientTimeoutMetadataKey.
+const (
+	// RequestTimeoutHeader holds a number of seconds or a Go duration such as "1500ms".
+	RequestTimeoutHeader = "X-Request-Timeout"
+
+	// EnvoyTimeoutHeader is set by Envoy to the upstream timeout in milliseconds.
+	EnvoyTimeoutHeader = "X-Envoy-Expected-Rq-Timeout-Ms"
+)
+
+// WithoutClientDeadline stops Serve from deriving handler deadlines from the client timeout, for
+// plugins whose work must finish even when the client gives up.
+func WithoutClientDeadline() ServeOption {
+	return func(o *serveOptions) error {
+		o.noDeadline = true
+		return nil
+	}
+}
+
+// ClientTimeout returns the remaining client timeout reported for a call: the
+// ClientTimeoutMetadataKey metadata sent by mcpd, or else the RequestTimeoutHeader or
+// EnvoyTimeoutHeader of req (which may be nil). The boolean reports whether one was found.
+func ClientTimeout(ctx context.Context, req *HTTPRequest) (time.Duration, bool) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(ClientTimeoutMetadataKey); len(v) > 0 {
+			if d, ok := parseMillis(v[0]); ok {
+				return d, true
+			}
+		}
+	}
+
+	if req != nil {
+		if v := GetHeader(req.GetHeaders(), RequestTimeoutHeader); v != "" {
+			if d, ok := parseTimeout(v); ok {
+				return d, true
+			}
+		}
+		if v := GetHeader(req.GetHeaders(), EnvoyTimeoutHeader); v != "" {
+			if d, ok := parseMillis(v); ok {
+				return d, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// deadlineInterceptor bounds the handler context by the client timeout so work is abandoned once
+// the client would have given up. Deadlines already set on the call are only ever shortened.
+func deadlineInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		in, _ := req.(*HTTPRequest)
+		if d, ok := ClientTimeout(ctx, in); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d)
+			defer cancel()
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// parseMillis parses a positive whole number of milliseconds.
+func parseMillis(s string) (time.Duration, bool) {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n <= 0 || n > int64(maxClientTimeout/time.Millisecond) {
+		return 0, false
+	}
+
+	return time.Duration(n) * time.Millisecond, true
+}
+
+// parseTimeout parses a positive number of seconds or a Go duration.
+func parseTimeout(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		// The negated comparison also rejects NaN.
+		if !(secs > 0 && secs <= maxClientTimeout.Seconds()) {
+			return 0, false
+		}
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 || d > maxClientTimeout {
+		return 0, false
+	}
+
+	return d, true
+}
+
+// maxClientTimeout bounds accepted client timeouts; larger values are treated as absent.
+const maxClientTimeout = 24 * time.Hour
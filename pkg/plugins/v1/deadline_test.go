@@ -0,0 +1,187 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestClientTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		md      metadata.MD
+		headers map[string]string
+		want    time.Duration
+		wantOK  bool
+	}{
+		{name: "nothing"},
+		{
+			name:   "metadata",
+			md:     metadata.Pairs(ClientTimeoutMetadataKey, "1500"),
+			want:   1500 * time.Millisecond,
+			wantOK: true,
+		},
+		{
+			name:    "metadata wins over headers",
+			md:      metadata.Pairs(ClientTimeoutMetadataKey, "100"),
+			headers: map[string]string{RequestTimeoutHeader: "5"},
+			want:    100 * time.Millisecond,
+			wantOK:  true,
+		},
+		{
+			name:    "invalid metadata falls back to headers",
+			md:      metadata.Pairs(ClientTimeoutMetadataKey, "soon"),
+			headers: map[string]string{RequestTimeoutHeader: "5"},
+			want:    5 * time.Second,
+			wantOK:  true,
+		},
+		{name: "seconds", headers: map[string]string{"x-request-timeout": "2"}, want: 2 * time.Second, wantOK: true},
+		{
+			name:    "fractional seconds",
+			headers: map[string]string{RequestTimeoutHeader: " 0.25 "},
+			want:    250 * time.Millisecond,
+			wantOK:  true,
+		},
+		{
+			name:    "duration",
+			headers: map[string]string{RequestTimeoutHeader: "1m30s"},
+			want:    90 * time.Second,
+			wantOK:  true,
+		},
+		{
+			name:    "envoy",
+			headers: map[string]string{EnvoyTimeoutHeader: "750"},
+			want:    750 * time.Millisecond,
+			wantOK:  true,
+		},
+		{
+			name:    "request timeout wins over envoy",
+			headers: map[string]string{RequestTimeoutHeader: "3s", EnvoyTimeoutHeader: "750"},
+			want:    3 * time.Second,
+			wantOK:  true,
+		},
+		{
+			name:    "invalid request timeout falls back to envoy",
+			headers: map[string]string{RequestTimeoutHeader: "-1", EnvoyTimeoutHeader: "750"},
+			want:    750 * time.Millisecond,
+			wantOK:  true,
+		},
+		{name: "zero seconds", headers: map[string]string{RequestTimeoutHeader: "0"}},
+		{name: "negative duration", headers: map[string]string{RequestTimeoutHeader: "-5s"}},
+		{name: "NaN", headers: map[string]string{RequestTimeoutHeader: "NaN"}},
+		{name: "infinite", headers: map[string]string{RequestTimeoutHeader: "Inf"}},
+		{name: "too long", headers: map[string]string{RequestTimeoutHeader: "25h"}},
+		{name: "too many seconds", headers: map[string]string{RequestTimeoutHeader: "86401"}},
+		{name: "garbage", headers: map[string]string{RequestTimeoutHeader: "whenever"}},
+		{name: "fractional millis", headers: map[string]string{EnvoyTimeoutHeader: "1.5"}},
+		{name: "millis too long", md: metadata.Pairs(ClientTimeoutMetadataKey, "86400001")},
+		{name: "zero millis", md: metadata.Pairs(ClientTimeoutMetadataKey, "0")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			var req *HTTPRequest
+			if tt.headers != nil {
+				req = &HTTPRequest{Headers: tt.headers}
+			}
+			got, ok := ClientTimeout(ctx, req)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ClientTimeout = %s, %t; want %s, %t", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDeadlineInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: Plugin_HandleRequest_FullMethodName}
+	tests := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+		req  any
+		want time.Duration // Expected remaining time, or zero for no deadline.
+	}{
+		{
+			name: "no timeout",
+			ctx:  func() (context.Context, context.CancelFunc) { return context.Background(), func() {} },
+			req:  &HTTPRequest{},
+		},
+		{
+			name: "header timeout",
+			ctx:  func() (context.Context, context.CancelFunc) { return context.Background(), func() {} },
+			req:  &HTTPRequest{Headers: map[string]string{RequestTimeoutHeader: "2s"}},
+			want: 2 * time.Second,
+		},
+		{
+			name: "metadata timeout on a response",
+			ctx: func() (context.Context, context.CancelFunc) {
+				md := metadata.Pairs(ClientTimeoutMetadataKey, "3000")
+				return metadata.NewIncomingContext(context.Background(), md), func() {}
+			},
+			req:  &HTTPResponse{},
+			want: 3 * time.Second,
+		},
+		{
+			name: "shorter call deadline kept",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), time.Second)
+			},
+			req:  &HTTPRequest{Headers: map[string]string{RequestTimeoutHeader: "10"}},
+			want: time.Second,
+		},
+		{
+			name: "longer call deadline shortened",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), time.Minute)
+			},
+			req:  &HTTPRequest{Headers: map[string]string{RequestTimeoutHeader: "10"}},
+			want: 10 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			var (
+				deadline time.Time
+				has      bool
+			)
+			start := time.Now()
+			_, err := deadlineInterceptor()(ctx, tt.req, info, func(ctx context.Context, _ any) (any, error) {
+				deadline, has = ctx.Deadline()
+				return nil, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == 0 {
+				if has {
+					t.Errorf("handler deadline in %s, want none", deadline.Sub(start))
+				}
+				return
+			}
+			if !has {
+				t.Fatal("handler has no deadline")
+			}
+			if got := deadline.Sub(start); got < tt.want-time.Second/2 || got > tt.want+time.Second/2 {
+				t.Errorf("handler deadline in %s, want about %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithoutClientDeadline(t *testing.T) {
+	o, err := newServeOptions(WithoutClientDeadline())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !o.noDeadline {
+		t.Error("WithoutClientDeadline did not disable client deadlines")
+	}
+}
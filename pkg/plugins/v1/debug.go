@@ -0,0 +1,58 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/sampling"
+)
+
+// DebugToggler is implemented by plugins that adjust their own logging when an operator toggles
+// debug mode at runtime, for example through the admin service.
+type DebugToggler interface {
+	SetDebug(enabled bool)
+}
+
+// setDebug switches debug mode, in which the SDK logs every handler call, and notifies impl when
+// it implements DebugToggler. It returns the previous mode.
+func (o *serveOptions) setDebug(impl PluginServer, enabled bool) bool {
+	prev := o.debug.Swap(enabled)
+	if prev == enabled {
+		return prev
+	}
+	if enabled {
+		o.logger.Println("debug mode enabled")
+	} else {
+		o.logger.Println("debug mode disabled")
+	}
+	if t, ok := impl.(DebugToggler); ok {
+		t.SetDebug(enabled)
+	}
+
+	return prev
+}
+
+// subscribeDebugLog logs every handler call sampled as sampling.FeatureDebug while debug mode is on.
+func subscribeDebugLog(o *serveOptions) {
+	o.bus.Subscribe(func(ctx context.Context, ev Event) {
+		if !o.debug.Load() || !o.sample(sampling.FeatureDebug, ev) {
+			return
+		}
+		switch {
+		case ev.Request != nil:
+			req := ev.Request
+			o.logger.Printf(
+				"debug %s: duration=%s verdict=%s http_method=%s path=%q tool=%q correlation_id=%q err=%v",
+				ev.Method, ev.Duration, ev.Verdict(), req.GetMethod(), req.GetPath(),
+				mcp.ToolName(req.GetBody()), CorrelationID(ctx, req), ev.Err,
+			)
+		case ev.Response != nil:
+			o.logger.Printf(
+				"debug %s: duration=%s verdict=%s status=%d correlation_id=%q err=%v",
+				ev.Method, ev.Duration, ev.Verdict(), ev.Response.GetStatusCode(), CorrelationID(ctx, nil), ev.Err,
+			)
+		default:
+			o.logger.Printf("debug %s: duration=%s err=%v", ev.Method, ev.Duration, ev.Err)
+		}
+	}, EventRequest)
+}
@@ -0,0 +1,198 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+// Deny returns a response short-circuiting req with HTTP status statusCode and a well-formed
+// JSON-RPC error body with code and message, echoing the id of each request in req's body so MCP
+// clients can match the failure to their call. Use mcp.CodeServerError for policy denials.
+//
+// Usage:
+//
+//	if !allowed {
+//	    return mcpdpluginsv1.Deny(req, http.StatusForbidden, mcp.CodeServerError, "tool not allowed"), nil
+//	}
+func Deny(req *HTTPRequest, statusCode, code int, message string) *HTTPResponse {
+	return DenyError(req, statusCode, &mcp.Error{Code: code, Message: message})
+}
+
+// DenyError is Deny with a complete JSON-RPC error, for denials carrying error data.
+func DenyError(req *HTTPRequest, statusCode int, e *mcp.Error) *HTTPResponse {
+	return &HTTPResponse{
+		StatusCode: int32(statusCode),
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       mcp.ErrorResponse(req.GetBody(), e),
+	}
+}
+
+// Deny template formats.
+const (
+	// DenyFormatText templates render the JSON-RPC error message.
+	DenyFormatText = "text"
+
+	// DenyFormatJSON templates render the JSON-RPC error object: a JSON object with an optional
+	// code, a message and optional data.
+	DenyFormatJSON = "json"
+)
+
+// Denial describes a denied request to a DenyTemplate. Its fields are the template's variables.
+type Denial struct {
+	// Reason is the plugin's own message for the denial.
+	Reason string
+
+	// RuleID names the policy rule that denied the request, when the plugin has rules.
+	RuleID string
+
+	// CorrelationID ties the denial to the client request (see CorrelationID). DenyTemplate.Deny
+	// fills it in when empty.
+	CorrelationID string
+
+	// Status is the HTTP status and Code the JSON-RPC error code of the response. A zero Code is
+	// mcp.CodeServerError.
+	Status int
+	Code   int
+
+	// Method and Path are the denied request's HTTP method and path.
+	Method string
+	Path   string
+}
+
+// DenyTemplate renders deny responses from an operator-supplied text/template, so security teams
+// can change what callers see without code changes. The template's data is a Denial, and the json
+// function quotes a value for JSON templates:
+//
+//	deny_template_format: json
+//	deny_template: {"message": "Access denied", "data": {"rule": {{json .RuleID}}, "ticket": {{json .CorrelationID}}}}
+//
+// The body is always a JSON-RPC error response as built by DenyError. A nil *DenyTemplate, or one
+// failing to render a denial, renders the plugin's defaults.
+type DenyTemplate struct {
+	format string
+	tmpl   *template.Template
+}
+
+// sampleDenial checks templates at parse time; its quotes catch JSON templates missing json.
+var sampleDenial = Denial{
+	Reason:        `request "denied"`,
+	RuleID:        "rule",
+	CorrelationID: "correlation",
+	Status:        403,
+	Code:          mcp.CodeServerError,
+	Method:        "POST",
+	Path:          "/mcp",
+}
+
+// NewDenyTemplate parses text as a DenyTemplate of the given format, DenyFormatText or
+// DenyFormatJSON. It returns an error when text does not parse or, for JSON templates, does not
+// render a JSON object.
+func NewDenyTemplate(format, text string) (*DenyTemplate, error) {
+	if format != DenyFormatText && format != DenyFormatJSON {
+		return nil, fmt.Errorf("deny template format must be %s or %s, got %q", DenyFormatText, DenyFormatJSON, format)
+	}
+
+	tmpl, err := template.New("deny").
+		Option("missingkey=error").
+		Funcs(template.FuncMap{"json": jsonValue}).
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deny template: %w", err)
+	}
+
+	t := &DenyTemplate{format: format, tmpl: tmpl}
+	if _, err := t.render(sampleDenial); err != nil {
+		return nil, fmt.Errorf("invalid deny template: %w", err)
+	}
+
+	return t, nil
+}
+
+// jsonValue is the json template function.
+func jsonValue(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// Deny returns the response short-circuiting req for d, with HTTP status d.Status.
+func (t *DenyTemplate) Deny(ctx context.Context, req *HTTPRequest, d Denial) *HTTPResponse {
+	if d.Code == 0 {
+		d.Code = mcp.CodeServerError
+	}
+	if d.CorrelationID == "" {
+		d.CorrelationID = CorrelationID(ctx, req)
+	}
+	if d.Method == "" {
+		d.Method = req.GetMethod()
+	}
+	if d.Path == "" {
+		d.Path = req.GetPath()
+	}
+
+	e := &mcp.Error{Code: d.Code, Message: d.Reason}
+	if t != nil {
+		if rendered, err := t.render(d); err == nil {
+			e = rendered
+		}
+	}
+
+	return DenyError(req, d.Status, e)
+}
+
+// render executes the template for d.
+func (t *DenyTemplate) render(d Denial) (*mcp.Error, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, d); err != nil {
+		return nil, err
+	}
+
+	if t.format == DenyFormatText {
+		return &mcp.Error{Code: d.Code, Message: strings.TrimSpace(buf.String())}, nil
+	}
+
+	var out struct {
+		Code    *int            `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		return nil, fmt.Errorf("rendered invalid JSON (quote strings with json, as in {{json .Reason}}): %w", err)
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(buf.Bytes()), []byte("{")) {
+		return nil, fmt.Errorf("rendered %s, want a JSON object", bytes.TrimSpace(buf.Bytes()))
+	}
+	e := &mcp.Error{Code: d.Code, Message: out.Message, Data: out.Data}
+	if out.Code != nil {
+		e.Code = *out.Code
+	}
+	if e.Message == "" {
+		e.Message = d.Reason
+	}
+
+	return e, nil
+}
+
+// DenyTemplateConfig holds the custom_config keys customizing a plugin's deny responses. Embed it
+// in a plugin's config struct decoded with DecodeConfig and build the template with Template.
+type DenyTemplateConfig struct {
+	// DenyTemplate is the template's text; empty keeps the plugin's default denials.
+	DenyTemplate string `config:"deny_template"`
+
+	// DenyTemplateFormat is DenyFormatText or DenyFormatJSON.
+	DenyTemplateFormat string `config:"deny_template_format" default:"text"`
+}
+
+// Template returns the configured DenyTemplate, or nil when none is configured.
+func (c DenyTemplateConfig) Template() (*DenyTemplate, error) {
+	if strings.TrimSpace(c.DenyTemplate) == "" {
+		return nil, nil
+	}
+
+	return NewDenyTemplate(c.DenyTemplateFormat, c.DenyTemplate)
+}
@@ -0,0 +1,262 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+// denyBody decodes the single JSON-RPC error response in resp.
+func denyBody(t *testing.T, resp *HTTPResponse) mcp.Message {
+	t.Helper()
+
+	var m mcp.Message
+	if err := json.Unmarshal(resp.GetBody(), &m); err != nil {
+		t.Fatalf("deny body %q: %v", resp.GetBody(), err)
+	}
+	if m.Error == nil {
+		t.Fatalf("deny body %q has no error", resp.GetBody())
+	}
+
+	return m
+}
+
+func TestDeny(t *testing.T) {
+	req := &HTTPRequest{Body: []byte(`{"jsonrpc":"2.0","id":3,"method":"tools/call"}`)}
+	resp := Deny(req, http.StatusForbidden, mcp.CodeServerError, "tool not allowed")
+
+	if resp.GetContinue() {
+		t.Error("Deny continued the request")
+	}
+	if resp.GetStatusCode() != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", resp.GetStatusCode())
+	}
+	if ct := resp.GetHeaders()["Content-Type"]; ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	m := denyBody(t, resp)
+	if string(m.ID) != "3" || m.Error.Code != mcp.CodeServerError || m.Error.Message != "tool not allowed" {
+		t.Errorf("deny body = %s", resp.GetBody())
+	}
+
+	// A nil request is answered with a null id.
+	if m := denyBody(t, Deny(nil, http.StatusForbidden, mcp.CodeServerError, "no")); string(m.ID) != "null" {
+		t.Errorf("id = %s, want null", m.ID)
+	}
+}
+
+func TestNewDenyTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		text    string
+		wantErr string
+	}{
+		{name: "text", format: DenyFormatText, text: "Denied by {{.RuleID}}: {{.Reason}}"},
+		{
+			name:   "json",
+			format: DenyFormatJSON,
+			text:   `{"message": {{json .Reason}}, "data": {"rule": {{json .RuleID}}}}`,
+		},
+		{name: "json with code", format: DenyFormatJSON, text: `{"code": -32001, "message": "no"}`},
+		{
+			name:    "unknown format",
+			format:  "xml",
+			text:    "x",
+			wantErr: `deny template format must be text or json, got "xml"`,
+		},
+		{name: "parse error", format: DenyFormatText, text: "{{.Reason", wantErr: "invalid deny template"},
+		{name: "unknown field", format: DenyFormatText, text: "{{.Nope}}", wantErr: "invalid deny template"},
+		{
+			name:    "unquoted JSON string",
+			format:  DenyFormatJSON,
+			text:    `{"message": "{{.Reason}}"}`,
+			wantErr: "quote strings with json",
+		},
+		{name: "JSON array", format: DenyFormatJSON, text: `[]`, wantErr: "invalid deny template"},
+		{name: "JSON null", format: DenyFormatJSON, text: `null`, wantErr: "want a JSON object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewDenyTemplate(tt.format, tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NewDenyTemplate error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got == nil {
+				t.Fatalf("NewDenyTemplate = %v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestDenyTemplateDeny(t *testing.T) {
+	req := &HTTPRequest{
+		Method:  "POST",
+		Path:    "/mcp",
+		Headers: map[string]string{"X-Request-Id": "req-1"},
+		Body:    []byte(`{"jsonrpc":"2.0","id":9,"method":"tools/call"}`),
+	}
+	tests := []struct {
+		name        string
+		format      string
+		text        string // Empty for a nil template.
+		denial      Denial
+		wantCode    int
+		wantMessage string
+		wantData    string
+	}{
+		{
+			name:        "nil template",
+			denial:      Denial{Reason: "blocked", Status: 403},
+			wantCode:    mcp.CodeServerError,
+			wantMessage: "blocked",
+		},
+		{
+			name:        "text variables",
+			format:      DenyFormatText,
+			text:        " {{.Method}} {{.Path}} denied by {{.RuleID}} ({{.CorrelationID}}, {{.Status}}, {{.Code}}) ",
+			denial:      Denial{Reason: "blocked", RuleID: "r1", Status: 403},
+			wantCode:    mcp.CodeServerError,
+			wantMessage: "POST /mcp denied by r1 (req-1, 403, -32000)",
+		},
+		{
+			name:        "explicit denial fields win",
+			format:      DenyFormatText,
+			text:        "{{.Method}} {{.Path}} {{.CorrelationID}}",
+			denial:      Denial{Status: 403, Code: -32001, Method: "GET", Path: "/other", CorrelationID: "c-2"},
+			wantCode:    -32001,
+			wantMessage: "GET /other c-2",
+		},
+		{
+			name:        "json with data",
+			format:      DenyFormatJSON,
+			text:        `{"message": "Access denied", "data": {"rule": {{json .RuleID}}}}`,
+			denial:      Denial{Reason: "blocked", RuleID: "r1", Status: 403},
+			wantCode:    mcp.CodeServerError,
+			wantMessage: "Access denied",
+			wantData:    `{"rule": "r1"}`,
+		},
+		{
+			name:        "json code and default message",
+			format:      DenyFormatJSON,
+			text:        `{"code": -32042}`,
+			denial:      Denial{Reason: "blocked", Status: 429},
+			wantCode:    -32042,
+			wantMessage: "blocked",
+		},
+		{
+			name:        "render failure falls back to the reason",
+			format:      DenyFormatJSON,
+			text:        `{"message": {{json .Reason}}}{{if eq .RuleID "broken"}}x{{end}}`,
+			denial:      Denial{Reason: "blocked", RuleID: "broken", Status: 403},
+			wantCode:    mcp.CodeServerError,
+			wantMessage: "blocked",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tmpl *DenyTemplate
+			if tt.text != "" {
+				var err error
+				if tmpl, err = NewDenyTemplate(tt.format, tt.text); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			resp := tmpl.Deny(context.Background(), req, tt.denial)
+			if resp.GetStatusCode() != int32(tt.denial.Status) {
+				t.Errorf("status = %d, want %d", resp.GetStatusCode(), tt.denial.Status)
+			}
+			m := denyBody(t, resp)
+			if string(m.ID) != "9" {
+				t.Errorf("id = %s, want 9", m.ID)
+			}
+			if m.Error.Code != tt.wantCode || m.Error.Message != tt.wantMessage {
+				t.Errorf("error = %d %q, want %d %q", m.Error.Code, m.Error.Message, tt.wantCode, tt.wantMessage)
+			}
+			if tt.wantData != "" && !jsonEqual(t, m.Error.Data, tt.wantData) {
+				t.Errorf("data = %s, want %s", m.Error.Data, tt.wantData)
+			}
+		})
+	}
+}
+
+// jsonEqual reports whether a and b encode the same JSON value.
+func jsonEqual(t *testing.T, a []byte, b string) bool {
+	t.Helper()
+
+	var av, bv any
+	if err := json.Unmarshal(a, &av); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(b), &bv); err != nil {
+		t.Fatal(err)
+	}
+	ab, _ := json.Marshal(av)
+	bb, _ := json.Marshal(bv)
+
+	return string(ab) == string(bb)
+}
+
+func TestDenyTemplateCorrelationFromMetadata(t *testing.T) {
+	tmpl, err := NewDenyTemplate(DenyFormatText, "{{.CorrelationID}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "from-mcpd"))
+
+	m := denyBody(t, tmpl.Deny(ctx, &HTTPRequest{}, Denial{Status: 403}))
+	if m.Error.Message != "from-mcpd" {
+		t.Errorf("message = %q, want the metadata correlation id", m.Error.Message)
+	}
+}
+
+func TestDenyTemplateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     DenyTemplateConfig
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "unset", wantNil: true},
+		{name: "blank", cfg: DenyTemplateConfig{DenyTemplate: "  \n"}, wantNil: true},
+		{name: "text", cfg: DenyTemplateConfig{DenyTemplate: "denied", DenyTemplateFormat: DenyFormatText}},
+		{name: "json", cfg: DenyTemplateConfig{DenyTemplate: `{"message":"no"}`, DenyTemplateFormat: DenyFormatJSON}},
+		{
+			name:    "bad format",
+			cfg:     DenyTemplateConfig{DenyTemplate: "denied", DenyTemplateFormat: "yaml"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.Template()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Template error = %v, want error %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && (got == nil) != tt.wantNil {
+				t.Errorf("Template = %v, want nil %t", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestDenyTemplateConfigDecode(t *testing.T) {
+	var cfg DenyTemplateConfig
+	in := &PluginConfig{CustomConfig: map[string]string{"deny_template": "denied"}}
+	if err := DecodeConfig(context.Background(), in, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DenyTemplateFormat != DenyFormatText {
+		t.Errorf("DenyTemplateFormat = %q, want the text default", cfg.DenyTemplateFormat)
+	}
+}
@@ -0,0 +1,75 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
+)
+
+// maxStackDump bounds the goroutine stacks written by a diagnostics dump.
+const maxStackDump = 64 << 20
+
+// WithoutDiagnosticSignals stops Serve from handling the diagnostics signals. By default, on Unix,
+// SIGUSR1 logs runtime statistics and every goroutine's stack, and SIGUSR2 toggles debug mode
+// (see WithAdmin), so a plugin can be inspected without the admin service.
+func WithoutDiagnosticSignals() ServeOption {
+	return func(o *serveOptions) error {
+		o.noDiagnostics = true
+		return nil
+	}
+}
+
+// handleDiagnosticSignals serves the diagnostics signals until ctx is done.
+func (o *serveOptions) handleDiagnosticSignals(ctx context.Context, impl PluginServer) {
+	dump, debug, ok := diagnosticSignals()
+	if !ok {
+		return
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, dump, debug)
+	defer signal.Stop(sigCh)
+
+	start := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-sigCh:
+			if sig == dump {
+				o.dumpDiagnostics(start)
+			} else {
+				o.setDebug(impl, !o.debug.Load())
+			}
+		}
+	}
+}
+
+// dumpDiagnostics logs runtime statistics followed by the stacks of all goroutines.
+func (o *serveOptions) dumpDiagnostics(start time.Time) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	var lastPause time.Duration
+	if ms.NumGC > 0 {
+		lastPause = time.Duration(ms.PauseNs[(ms.NumGC+255)%256])
+	}
+	o.logger.Printf(
+		"diagnostics: uptime=%s goroutines=%d gomaxprocs=%d rss=%d heap_alloc=%d heap_objects=%d sys=%d "+
+			"num_gc=%d last_gc_pause=%s debug=%t",
+		time.Since(start).Round(time.Second), runtime.NumGoroutine(), runtime.GOMAXPROCS(0), residentMemory(),
+		ms.HeapAlloc, ms.HeapObjects, ms.Sys, ms.NumGC, lastPause, o.debug.Load(),
+	)
+
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxStackDump {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	o.logger.Printf("diagnostics: goroutine stacks:\n%s", buf)
+}
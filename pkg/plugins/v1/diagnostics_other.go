@@ -0,0 +1,10 @@
+//go:build !unix
+
+package mcpdpluginsv1
+
+import "os"
+
+// diagnosticSignals reports that the platform has no diagnostics signals.
+func diagnosticSignals() (dump, debug os.Signal, ok bool) {
+	return nil, nil, false
+}
@@ -0,0 +1,53 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithoutDiagnosticSignals(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []ServeOption
+		want bool
+	}{
+		{name: "handled by default"},
+		{name: "disabled", opts: []ServeOption{WithoutDiagnosticSignals()}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.noDiagnostics != tt.want {
+				t.Errorf("noDiagnostics = %t, want %t", o.noDiagnostics, tt.want)
+			}
+		})
+	}
+}
+
+func TestDumpDiagnostics(t *testing.T) {
+	var logs bytes.Buffer
+	o, err := newServeOptions(WithLogger(log.New(&logs, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.debug.Store(true)
+
+	o.dumpDiagnostics(time.Now().Add(-time.Minute))
+
+	stats := regexp.MustCompile(`diagnostics: uptime=1m0s goroutines=\d+ gomaxprocs=\d+ rss=\d+ heap_alloc=\d+ ` +
+		`heap_objects=\d+ sys=\d+ num_gc=\d+ last_gc_pause=\S+ debug=true\n`)
+	if !stats.MatchString(logs.String()) {
+		t.Errorf("logged %q, want the runtime statistics", logs.String())
+	}
+	stacks := logs.String()[strings.Index(logs.String(), "diagnostics: goroutine stacks:\n"):]
+	if !strings.Contains(stacks, "TestDumpDiagnostics") {
+		t.Errorf("stacks %q do not include the test goroutine", stacks)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build unix
+
+package mcpdpluginsv1
+
+import (
+	"os"
+	"syscall"
+)
+
+// diagnosticSignals returns the signals dumping diagnostics and toggling debug mode.
+func diagnosticSignals() (dump, debug os.Signal, ok bool) {
+	return syscall.SIGUSR1, syscall.SIGUSR2, true
+}
@@ -0,0 +1,72 @@
+//go:build unix
+
+package mcpdpluginsv1
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleDiagnosticSignals(t *testing.T) {
+	// Keep the signals from stopping the test binary if they arrive before the handler is set up.
+	guard := make(chan os.Signal, 16)
+	signal.Notify(guard, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(guard)
+
+	var logs syncBuffer
+	o, err := newServeOptions(WithLogger(log.New(&logs, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &adminPlugin{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		o.handleDiagnosticSignals(ctx, p)
+	}()
+
+	// SIGUSR1 dumps diagnostics; resend it until the handler is listening.
+	waitFor(t, "the diagnostics dump", func() bool {
+		if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		return strings.Contains(logs.String(), "diagnostics: goroutine stacks:")
+	})
+
+	// SIGUSR2 toggles debug mode.
+	for _, want := range []string{"debug mode enabled", "debug mode disabled"} {
+		if err := syscall.Kill(os.Getpid(), syscall.SIGUSR2); err != nil {
+			t.Fatal(err)
+		}
+		waitFor(t, want, func() bool { return strings.Contains(logs.String(), want) })
+	}
+	p.mu.Lock()
+	toggles := slices.Clone(p.toggles)
+	p.mu.Unlock()
+	if want := []bool{true, false}; !slices.Equal(toggles, want) {
+		t.Errorf("plugin notified %v, want %v", toggles, want)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler kept running after ctx was done")
+	}
+}
+
+func TestDiagnosticSignals(t *testing.T) {
+	dump, debug, ok := diagnosticSignals()
+	if !ok || dump != syscall.SIGUSR1 || debug != syscall.SIGUSR2 {
+		t.Errorf("diagnosticSignals = %v, %v, %t, want SIGUSR1, SIGUSR2, true", dump, debug, ok)
+	}
+}
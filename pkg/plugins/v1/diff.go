@@ -0,0 +1,300 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Kinds of Change.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// maxDiffValue bounds the length of values in Diff.String.
+const maxDiffValue = 120
+
+// Change is one difference between two messages.
+type Change struct {
+	// Field names what differs: a message field such as "path" or "status_code", a header as
+	// "headers[<Canonical-Name>]", the body as "body", or, when both bodies are JSON, a value
+	// within it as "body" followed by its JSON Pointer ("body/params/arguments/query"). Fields of
+	// a ModifiedRequest are prefixed with "modified_request.".
+	Field string `json:"field"`
+
+	// Kind is ChangeAdded, ChangeRemoved or ChangeChanged.
+	Kind string `json:"kind"`
+
+	// Old and New are the values before and after; JSON body values are JSON-encoded.
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+}
+
+// Diff lists the differences between two messages in a stable order. It marshals to JSON as an
+// array of changes, for audit logs.
+type Diff []Change
+
+// Empty reports whether the messages are equal.
+func (d Diff) Empty() bool {
+	return len(d) == 0
+}
+
+// String describes the changes one per line, prefixed with + (added), - (removed) or ~ (changed).
+// Long values are truncated.
+func (d Diff) String() string {
+	var b strings.Builder
+	for i, c := range d {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		// JSON body values are printed as JSON, other values quoted.
+		format := strconv.Quote
+		if strings.Contains(c.Field, "body/") {
+			format = func(s string) string { return s }
+		}
+		switch c.Kind {
+		case ChangeAdded:
+			fmt.Fprintf(&b, "+ %s: %s", c.Field, truncate(format(c.New)))
+		case ChangeRemoved:
+			fmt.Fprintf(&b, "- %s: %s", c.Field, truncate(format(c.Old)))
+		default:
+			fmt.Fprintf(&b, "~ %s: %s -> %s", c.Field, truncate(format(c.Old)), truncate(format(c.New)))
+		}
+	}
+
+	return b.String()
+}
+
+// DiffOption configures DiffRequests and DiffResponses.
+type DiffOption func(*diffOptions)
+
+type diffOptions struct {
+	ignoreHeaders map[string]struct{}
+	opaqueBody    bool
+}
+
+// IgnoreDiffHeaders excludes the named headers (case-insensitive) from comparison, e.g. headers
+// carrying timestamps or request IDs.
+func IgnoreDiffHeaders(names ...string) DiffOption {
+	return func(o *diffOptions) {
+		for _, n := range names {
+			o.ignoreHeaders[http.CanonicalHeaderKey(n)] = struct{}{}
+		}
+	}
+}
+
+// OpaqueBodyDiff compares bodies as bytes, reporting a single "body" change even when both
+// are JSON.
+func OpaqueBodyDiff() DiffOption {
+	return func(o *diffOptions) {
+		o.opaqueBody = true
+	}
+}
+
+// DiffRequests returns the differences from a to b. Either may be nil.
+func DiffRequests(a, b *HTTPRequest, opts ...DiffOption) Diff {
+	d := newDiffer(opts)
+	d.requests("", a, b)
+
+	return d.out
+}
+
+// DiffResponses returns the differences from a to b, including their ModifiedRequests.
+// Either may be nil.
+func DiffResponses(a, b *HTTPResponse, opts ...DiffOption) Diff {
+	d := newDiffer(opts)
+	d.scalar("continue", strconv.FormatBool(a.GetContinue()), strconv.FormatBool(b.GetContinue()))
+	d.scalar("status_code", strconv.Itoa(int(a.GetStatusCode())), strconv.Itoa(int(b.GetStatusCode())))
+	d.headers("", a.GetHeaders(), b.GetHeaders())
+	d.body("", a.GetBody(), b.GetBody())
+	if a.GetModifiedRequest() != nil || b.GetModifiedRequest() != nil {
+		d.requests("modified_request.", a.GetModifiedRequest(), b.GetModifiedRequest())
+	}
+
+	return d.out
+}
+
+type differ struct {
+	o   diffOptions
+	out Diff
+}
+
+func newDiffer(opts []DiffOption) *differ {
+	d := &differ{o: diffOptions{ignoreHeaders: map[string]struct{}{}}}
+	for _, opt := range opts {
+		opt(&d.o)
+	}
+
+	return d
+}
+
+func (d *differ) requests(prefix string, a, b *HTTPRequest) {
+	d.text(prefix+"method", a.GetMethod(), b.GetMethod())
+	d.text(prefix+"url", a.GetUrl(), b.GetUrl())
+	d.text(prefix+"path", a.GetPath(), b.GetPath())
+	d.text(prefix+"request_uri", a.GetRequestUri(), b.GetRequestUri())
+	d.text(prefix+"remote_addr", a.GetRemoteAddr(), b.GetRemoteAddr())
+	d.headers(prefix, a.GetHeaders(), b.GetHeaders())
+	d.body(prefix, a.GetBody(), b.GetBody())
+}
+
+func (d *differ) add(field, kind, old, new string) {
+	d.out = append(d.out, Change{Field: field, Kind: kind, Old: old, New: new})
+}
+
+// scalar records a change between two always-present values.
+func (d *differ) scalar(field, a, b string) {
+	if a != b {
+		d.add(field, ChangeChanged, a, b)
+	}
+}
+
+// text records a change between two strings, treating empty as absent.
+func (d *differ) text(field, a, b string) {
+	switch {
+	case a == b:
+	case a == "":
+		d.add(field, ChangeAdded, "", b)
+	case b == "":
+		d.add(field, ChangeRemoved, a, "")
+	default:
+		d.add(field, ChangeChanged, a, b)
+	}
+}
+
+func (d *differ) headers(prefix string, a, b map[string]string) {
+	ca, cb := d.canonical(a), d.canonical(b)
+	names := slices.Sorted(maps.Keys(ca))
+	for n := range cb {
+		if _, ok := ca[n]; !ok {
+			names = append(names, n)
+		}
+	}
+	slices.Sort(names)
+
+	for _, n := range names {
+		av, aok := ca[n]
+		bv, bok := cb[n]
+		field := prefix + "headers[" + n + "]"
+		switch {
+		case !aok:
+			d.add(field, ChangeAdded, "", bv)
+		case !bok:
+			d.add(field, ChangeRemoved, av, "")
+		case av != bv:
+			d.add(field, ChangeChanged, av, bv)
+		}
+	}
+}
+
+func (d *differ) canonical(h map[string]string) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		k = http.CanonicalHeaderKey(k)
+		if _, ignored := d.o.ignoreHeaders[k]; !ignored {
+			out[k] = v
+		}
+	}
+
+	return out
+}
+
+func (d *differ) body(prefix string, a, b []byte) {
+	if bytes.Equal(a, b) {
+		return
+	}
+	field := prefix + "body"
+	if !d.o.opaqueBody {
+		av, aerr := decodeJSON(a)
+		bv, berr := decodeJSON(b)
+		if aerr == nil && berr == nil {
+			d.json(field, av, bv)
+			return
+		}
+	}
+	d.text(field, string(a), string(b))
+}
+
+// json records the differences between two decoded JSON values, descending into objects and
+// same-length arrays and reporting other differences at the deepest common path.
+func (d *differ) json(field string, a, b any) {
+	switch av := a.(type) {
+	case map[string]any:
+		if bv, ok := b.(map[string]any); ok {
+			keys := slices.Sorted(maps.Keys(av))
+			for k := range bv {
+				if _, ok := av[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			slices.Sort(keys)
+			for _, k := range keys {
+				child := field + "/" + escapePointer(k)
+				x, xok := av[k]
+				y, yok := bv[k]
+				switch {
+				case !xok:
+					d.add(child, ChangeAdded, "", encodeJSON(y))
+				case !yok:
+					d.add(child, ChangeRemoved, encodeJSON(x), "")
+				default:
+					d.json(child, x, y)
+				}
+			}
+			return
+		}
+	case []any:
+		if bv, ok := b.([]any); ok && len(av) == len(bv) {
+			for i := range av {
+				d.json(field+"/"+strconv.Itoa(i), av[i], bv[i])
+			}
+			return
+		}
+	}
+
+	if ae, be := encodeJSON(a), encodeJSON(b); ae != be {
+		d.add(field, ChangeChanged, ae, be)
+	}
+}
+
+func decodeJSON(data []byte) (any, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+
+	return v, nil
+}
+
+func encodeJSON(v any) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func truncate(s string) string {
+	if len(s) <= maxDiffValue {
+		return s
+	}
+
+	return s[:maxDiffValue-3] + "..."
+}
@@ -0,0 +1,238 @@
+package mcpdpluginsv1
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffRequests(t *testing.T) {
+	base := func() *HTTPRequest {
+		return &HTTPRequest{
+			Method:     "POST",
+			Url:        "http://mcpd/mcp",
+			Path:       "/mcp",
+			RemoteAddr: "10.0.0.1:5000",
+			Headers:    map[string]string{"content-type": "application/json", "X-Request-Id": "a"},
+			Body: []byte(`{"method":"tools/call",` +
+				`"params":{"name":"search","arguments":{"q":"x","tags":["a","b"]}}}`),
+		}
+	}
+	tests := []struct {
+		name   string
+		a, b   func() *HTTPRequest
+		change func(*HTTPRequest)
+		opts   []DiffOption
+		want   Diff
+	}{
+		{name: "equal", change: func(*HTTPRequest) {}},
+		{
+			name: "header name casing is ignored",
+			change: func(r *HTTPRequest) {
+				r.Headers = map[string]string{"Content-Type": "application/json", "x-request-id": "a"}
+			},
+		},
+		{
+			name: "fields",
+			change: func(r *HTTPRequest) {
+				r.Method = "GET"
+				r.Path = ""
+				r.RequestUri = "/mcp?x=1"
+			},
+			want: Diff{
+				{Field: "method", Kind: ChangeChanged, Old: "POST", New: "GET"},
+				{Field: "path", Kind: ChangeRemoved, Old: "/mcp"},
+				{Field: "request_uri", Kind: ChangeAdded, New: "/mcp?x=1"},
+			},
+		},
+		{
+			name: "headers",
+			change: func(r *HTTPRequest) {
+				r.Headers = map[string]string{"Content-Type": "text/plain", "authorization": "Bearer t"}
+			},
+			want: Diff{
+				{Field: "headers[Authorization]", Kind: ChangeAdded, New: "Bearer t"},
+				{Field: "headers[Content-Type]", Kind: ChangeChanged, Old: "application/json", New: "text/plain"},
+				{Field: "headers[X-Request-Id]", Kind: ChangeRemoved, Old: "a"},
+			},
+		},
+		{
+			name:   "ignored headers",
+			change: func(r *HTTPRequest) { r.Headers["X-Request-Id"] = "b" },
+			opts:   []DiffOption{IgnoreDiffHeaders("x-request-id")},
+		},
+		{
+			name: "JSON body",
+			change: func(r *HTTPRequest) {
+				r.Body = []byte(`{"method":"tools/call","params":{"name":"search",` +
+					`"arguments":{"query":"x","tags":["a","c"],"a/b~":1}}}`)
+			},
+			want: Diff{
+				{Field: "body/params/arguments/a~1b~0", Kind: ChangeAdded, New: "1"},
+				{Field: "body/params/arguments/q", Kind: ChangeRemoved, Old: `"x"`},
+				{Field: "body/params/arguments/query", Kind: ChangeAdded, New: `"x"`},
+				{Field: "body/params/arguments/tags/1", Kind: ChangeChanged, Old: `"b"`, New: `"c"`},
+			},
+		},
+		{
+			name: "JSON body with a resized array",
+			change: func(r *HTTPRequest) {
+				r.Body = []byte(`{"method":"tools/call","params":{"name":"search","arguments":{"q":"x","tags":["a"]}}}`)
+			},
+			want: Diff{{Field: "body/params/arguments/tags", Kind: ChangeChanged, Old: `["a","b"]`, New: `["a"]`}},
+		},
+		{
+			name: "JSON body type change",
+			change: func(r *HTTPRequest) {
+				r.Body = []byte(`{"method":"tools/call","params":["<b>"]}`)
+			},
+			want: Diff{{
+				Field: "body/params",
+				Kind:  ChangeChanged,
+				Old:   `{"arguments":{"q":"x","tags":["a","b"]},"name":"search"}`,
+				New:   `["<b>"]`,
+			}},
+		},
+		{
+			name:   "JSON numbers compared exactly",
+			a:      func() *HTTPRequest { return &HTTPRequest{Body: []byte(`{"id":12345678901234567890}`)} },
+			change: func(r *HTTPRequest) { r.Body = []byte(`{"id":12345678901234567891}`) },
+			want: Diff{
+				{Field: "body/id", Kind: ChangeChanged, Old: "12345678901234567890", New: "12345678901234567891"},
+			},
+		},
+		{
+			name:   "JSON reformatted",
+			a:      func() *HTTPRequest { return &HTTPRequest{Body: []byte(`{"a": 1, "b": [true]}`)} },
+			change: func(r *HTTPRequest) { r.Body = []byte(`{"b":[true],"a":1}`) },
+		},
+		{
+			name:   "opaque body",
+			a:      func() *HTTPRequest { return &HTTPRequest{Body: []byte(`{"a": 1}`)} },
+			change: func(r *HTTPRequest) { r.Body = []byte(`{"a":1}`) },
+			opts:   []DiffOption{OpaqueBodyDiff()},
+			want:   Diff{{Field: "body", Kind: ChangeChanged, Old: `{"a": 1}`, New: `{"a":1}`}},
+		},
+		{
+			name:   "body no longer JSON",
+			a:      func() *HTTPRequest { return &HTTPRequest{Body: []byte(`{"a":1}`)} },
+			change: func(r *HTTPRequest) { r.Body = []byte(`{"a":1} trailing`) },
+			want:   Diff{{Field: "body", Kind: ChangeChanged, Old: `{"a":1}`, New: `{"a":1} trailing`}},
+		},
+		{
+			name:   "body removed",
+			a:      func() *HTTPRequest { return &HTTPRequest{Body: []byte(`{}`)} },
+			change: func(r *HTTPRequest) { r.Body = nil },
+			want:   Diff{{Field: "body", Kind: ChangeRemoved, Old: `{}`}},
+		},
+		{
+			name: "from nil",
+			a:    func() *HTTPRequest { return nil },
+			b:    func() *HTTPRequest { return &HTTPRequest{Method: "GET", Headers: map[string]string{"a": "1"}} },
+			want: Diff{
+				{Field: "method", Kind: ChangeAdded, New: "GET"},
+				{Field: "headers[A]", Kind: ChangeAdded, New: "1"},
+			},
+		},
+		{name: "both nil", a: func() *HTTPRequest { return nil }, b: func() *HTTPRequest { return nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.a == nil {
+				tt.a = base
+			}
+			a := tt.a()
+			b := tt.a()
+			if tt.b != nil {
+				b = tt.b()
+			} else {
+				tt.change(b)
+			}
+
+			got := DiffRequests(a, b, tt.opts...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffRequests =\n%v\nwant\n%v", got, tt.want)
+			}
+			if got.Empty() != (len(tt.want) == 0) {
+				t.Errorf("Empty = %t with %d changes", got.Empty(), len(got))
+			}
+		})
+	}
+}
+
+func TestDiffResponses(t *testing.T) {
+	a := &HTTPResponse{
+		Continue:   true,
+		StatusCode: 200,
+		Headers:    map[string]string{"X-A": "1"},
+		Body:       []byte(`{"ok":true}`),
+	}
+	b := &HTTPResponse{
+		StatusCode:      403,
+		Headers:         map[string]string{"X-A": "1"},
+		Body:            []byte(`{"ok":false}`),
+		ModifiedRequest: &HTTPRequest{Path: "/mcp", Headers: map[string]string{"x-user": "u"}},
+	}
+	want := Diff{
+		{Field: "continue", Kind: ChangeChanged, Old: "true", New: "false"},
+		{Field: "status_code", Kind: ChangeChanged, Old: "200", New: "403"},
+		{Field: "body/ok", Kind: ChangeChanged, Old: "true", New: "false"},
+		{Field: "modified_request.path", Kind: ChangeAdded, New: "/mcp"},
+		{Field: "modified_request.headers[X-User]", Kind: ChangeAdded, New: "u"},
+	}
+	if got := DiffResponses(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffResponses =\n%v\nwant\n%v", got, want)
+	}
+
+	if got := DiffResponses(a, CloneResponse(a)); !got.Empty() {
+		t.Errorf("DiffResponses of equal responses = %v", got)
+	}
+	want = Diff{
+		{Field: "continue", Kind: ChangeChanged, Old: "false", New: "true"},
+		{Field: "status_code", Kind: ChangeChanged, Old: "0", New: "200"},
+		{Field: "headers[X-A]", Kind: ChangeAdded, New: "1"},
+		{Field: "body", Kind: ChangeAdded, New: `{"ok":true}`},
+	}
+	if got := DiffResponses(nil, a); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffResponses from nil =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestDiffString(t *testing.T) {
+	long := strings.Repeat("x", 200)
+	d := Diff{
+		{Field: "headers[X-User]", Kind: ChangeAdded, New: "alice"},
+		{Field: "path", Kind: ChangeRemoved, Old: "/mcp\n"},
+		{Field: "body/params/q", Kind: ChangeChanged, Old: `"x"`, New: `{"y":1}`},
+		{Field: "body", Kind: ChangeChanged, Old: "a", New: long},
+	}
+	want := strings.Join([]string{
+		`+ headers[X-User]: "alice"`,
+		`- path: "/mcp\n"`,
+		`~ body/params/q: "x" -> {"y":1}`,
+		`~ body: "a" -> "` + strings.Repeat("x", maxDiffValue-4) + "...",
+	}, "\n")
+	if got := d.String(); got != want {
+		t.Errorf("String =\n%s\nwant\n%s", got, want)
+	}
+	if got := Diff(nil).String(); got != "" {
+		t.Errorf("empty String = %q", got)
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	d := Diff{
+		{Field: "method", Kind: ChangeChanged, Old: "POST", New: "GET"},
+		{Field: "path", Kind: ChangeAdded, New: "/mcp"},
+	}
+	got, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"field":"method","kind":"changed","old":"POST","new":"GET"},` +
+		`{"field":"path","kind":"added","new":"/mcp"}]`
+	if string(got) != want {
+		t.Errorf("Marshal = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"maps"
+	"math"
+	"slices"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// ConfigDigestMetadataKey is the gRPC response header carrying the ConfigDigest of the
+// configuration the plugin last applied successfully. Serve sets it on Configure, GetMetadata and
+// CheckHealth once a configuration has been applied, so operators can confirm every replica runs
+// the same policy version.
+const ConfigDigestMetadataKey = "mcpd-config-digest"
+
+// configDigestVersion prefixes the encoding hashed by ConfigDigest, so a future change to it
+// cannot collide with digests of the current one.
+const configDigestVersion = "mcpd-config-digest/v1"
+
+// ConfigDigest returns a stable "sha256:<hex>" hash of cfg: equal configurations hash equally
+// regardless of map ordering, protobuf library version or unknown fields. It hashes a canonical
+// encoding of the telemetry fields followed by the custom_config entries sorted by key, every
+// string length-prefixed; a missing telemetry section hashes like an empty one.
+func ConfigDigest(cfg *PluginConfig) string {
+	t := cfg.GetTelemetry()
+	b := appendDigestString(nil, configDigestVersion)
+	b = appendDigestString(b, t.GetOtlpEndpoint())
+	b = appendDigestString(b, t.GetServiceName())
+	b = appendDigestString(b, t.GetEnvironment())
+	b = binary.BigEndian.AppendUint64(b, math.Float64bits(t.GetSampleRatio()))
+
+	custom := cfg.GetCustomConfig()
+	keys := slices.Sorted(maps.Keys(custom))
+	b = binary.AppendUvarint(b, uint64(len(keys)))
+	for _, k := range keys {
+		b = appendDigestString(b, k)
+		b = appendDigestString(b, custom[k])
+	}
+	sum := sha256.Sum256(b)
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// appendDigestString appends s to b prefixed with its length, so adjacent strings cannot run
+// together.
+func appendDigestString(b []byte, s string) []byte {
+	return append(binary.AppendUvarint(b, uint64(len(s))), s...)
+}
+
+// configDigestTracker holds the configuration last applied and its digest.
+type configDigestTracker struct {
+	digest atomic.Pointer[string]
+	config atomic.Pointer[PluginConfig]
+}
+
+// current returns the digest of the configuration last applied, or "" before the first.
+func (t *configDigestTracker) current() string {
+	if d := t.digest.Load(); d != nil {
+		return *d
+	}
+
+	return ""
+}
+
+// configDigestInterceptor records the digest of each configuration applied by Configure, logging
+// when it changes, and reports it in the ConfigDigestMetadataKey header.
+func configDigestInterceptor(o *serveOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		switch info.FullMethod {
+		case Plugin_Configure_FullMethodName:
+			resp, err := handler(ctx, req)
+			cfg, ok := req.(*PluginConfig)
+			if err != nil || !ok {
+				return resp, err
+			}
+			digest := ConfigDigest(cfg)
+			o.configDigest.config.Store(proto.Clone(cfg).(*PluginConfig))
+			if prev := o.configDigest.digest.Swap(&digest); prev == nil {
+				o.logger.Printf("config applied: digest=%s", digest)
+			} else if *prev != digest {
+				o.logger.Printf("config changed: digest=%s previous=%s", digest, *prev)
+			}
+			_ = grpc.SetHeader(ctx, metadata.Pairs(ConfigDigestMetadataKey, digest))
+			return resp, err
+		case Plugin_GetMetadata_FullMethodName, Plugin_CheckHealth_FullMethodName:
+			if digest := o.configDigest.current(); digest != "" {
+				_ = grpc.SetHeader(ctx, metadata.Pairs(ConfigDigestMetadataKey, digest))
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
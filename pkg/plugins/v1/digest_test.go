@@ -0,0 +1,201 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestConfigDigestEqual(t *testing.T) {
+	withUnknown := &PluginConfig{CustomConfig: map[string]string{"a": "1"}}
+	withUnknown.ProtoReflect().SetUnknown(protowire.AppendVarint(protowire.AppendTag(nil, 99, protowire.VarintType), 1))
+
+	tests := []struct {
+		name string
+		a, b *PluginConfig
+	}{
+		{name: "nil and empty", a: nil, b: &PluginConfig{}},
+		{name: "no telemetry and empty telemetry", a: &PluginConfig{}, b: &PluginConfig{Telemetry: &TelemetryConfig{}}},
+		{
+			name: "nil and empty custom config",
+			a:    &PluginConfig{},
+			b:    &PluginConfig{CustomConfig: map[string]string{}},
+		},
+		{
+			name: "map insertion order",
+			a:    &PluginConfig{CustomConfig: map[string]string{"a": "1", "b": "2", "c": "3"}},
+			b:    &PluginConfig{CustomConfig: map[string]string{"c": "3", "b": "2", "a": "1"}},
+		},
+		{
+			name: "unknown fields",
+			a:    &PluginConfig{CustomConfig: map[string]string{"a": "1"}},
+			b:    withUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := ConfigDigest(tt.a), ConfigDigest(tt.b)
+			if a != b {
+				t.Errorf("ConfigDigest = %s and %s, want them equal", a, b)
+			}
+			if !strings.HasPrefix(a, "sha256:") || len(a) != len("sha256:")+64 {
+				t.Errorf("ConfigDigest = %q, want sha256:<64 hex digits>", a)
+			}
+		})
+	}
+}
+
+func TestConfigDigestDistinct(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b *PluginConfig
+	}{
+		{
+			name: "key and value boundary",
+			a:    &PluginConfig{CustomConfig: map[string]string{"a": "bc"}},
+			b:    &PluginConfig{CustomConfig: map[string]string{"ab": "c"}},
+		},
+		{
+			name: "empty value and missing key",
+			a:    &PluginConfig{CustomConfig: map[string]string{"a": ""}},
+			b:    &PluginConfig{},
+		},
+		{
+			name: "entries run together",
+			a:    &PluginConfig{CustomConfig: map[string]string{"a": "1", "b": "2"}},
+			b:    &PluginConfig{CustomConfig: map[string]string{"a": "1b2"}},
+		},
+		{
+			name: "changed value",
+			a:    &PluginConfig{CustomConfig: map[string]string{"mode": "strict"}},
+			b:    &PluginConfig{CustomConfig: map[string]string{"mode": "lax"}},
+		},
+		{
+			name: "telemetry fields swapped",
+			a:    &PluginConfig{Telemetry: &TelemetryConfig{ServiceName: "x"}},
+			b:    &PluginConfig{Telemetry: &TelemetryConfig{Environment: "x"}},
+		},
+		{
+			name: "sample ratio",
+			a:    &PluginConfig{Telemetry: &TelemetryConfig{SampleRatio: 0.5}},
+			b:    &PluginConfig{Telemetry: &TelemetryConfig{SampleRatio: 0.25}},
+		},
+		{
+			name: "endpoint",
+			a:    &PluginConfig{Telemetry: &TelemetryConfig{OtlpEndpoint: "http://a:4318"}},
+			b:    &PluginConfig{Telemetry: &TelemetryConfig{OtlpEndpoint: "http://b:4318"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if a, b := ConfigDigest(tt.a), ConfigDigest(tt.b); a == b {
+				t.Errorf("ConfigDigest = %s for both configurations", a)
+			}
+		})
+	}
+}
+
+func TestConfigDigestInterceptor(t *testing.T) {
+	var logs syncBuffer
+	o := &serveOptions{logger: log.New(&logs, "", 0)}
+	intercept := configDigestInterceptor(o)
+
+	configureErr := errors.New("bad config")
+	call := func(method string, req any, fail bool) *headerStream {
+		t.Helper()
+
+		stream := &headerStream{}
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+		handler := func(context.Context, any) (any, error) {
+			if fail {
+				return nil, configureErr
+			}
+			return &emptypb.Empty{}, nil
+		}
+		_, err := intercept(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		if fail != errors.Is(err, configureErr) {
+			t.Fatalf("%s error = %v", method, err)
+		}
+
+		return stream
+	}
+	strict := &PluginConfig{CustomConfig: map[string]string{"mode": "strict"}}
+	lax := &PluginConfig{CustomConfig: map[string]string{"mode": "lax"}}
+
+	// Before any configuration no digest is reported.
+	for _, method := range []string{Plugin_GetMetadata_FullMethodName, Plugin_CheckHealth_FullMethodName} {
+		if s := call(method, &emptypb.Empty{}, false); s.header.Get(ConfigDigestMetadataKey) != nil {
+			t.Errorf("%s reported a digest before Configure: %v", method, s.header)
+		}
+	}
+	if got := o.configDigest.current(); got != "" {
+		t.Errorf("current = %q before Configure, want empty", got)
+	}
+
+	// A failed Configure is not recorded.
+	if s := call(Plugin_Configure_FullMethodName, strict, true); len(s.header) != 0 {
+		t.Errorf("failed Configure set headers %v", s.header)
+	}
+	if o.configDigest.current() != "" || o.configDigest.config.Load() != nil {
+		t.Error("failed Configure was recorded")
+	}
+
+	steps := []struct {
+		name    string
+		cfg     *PluginConfig
+		wantLog string // Empty when nothing should be logged.
+	}{
+		{name: "first", cfg: strict, wantLog: "config applied: digest=" + ConfigDigest(strict)},
+		{name: "same again", cfg: strict},
+		{
+			name:    "changed",
+			cfg:     lax,
+			wantLog: "config changed: digest=" + ConfigDigest(lax) + " previous=" + ConfigDigest(strict),
+		},
+	}
+	for _, s := range steps {
+		before := len(logs.String())
+		stream := call(Plugin_Configure_FullMethodName, s.cfg, false)
+		want := ConfigDigest(s.cfg)
+		if got := stream.header.Get(ConfigDigestMetadataKey); len(got) != 1 || got[0] != want {
+			t.Errorf("%s: Configure header = %v, want %s", s.name, got, want)
+		}
+		if got := o.configDigest.current(); got != want {
+			t.Errorf("%s: current = %s, want %s", s.name, got, want)
+		}
+		logged := strings.TrimSpace(logs.String()[before:])
+		if logged != s.wantLog {
+			t.Errorf("%s: logged %q, want %q", s.name, logged, s.wantLog)
+		}
+	}
+
+	// The applied configuration is a copy the caller cannot change afterwards.
+	lax.CustomConfig["mode"] = "mutated"
+	if got := o.configDigest.config.Load(); got.GetCustomConfig()["mode"] != "lax" || proto.Equal(got, lax) {
+		t.Errorf("stored config = %v, want an unchanged copy", got)
+	}
+
+	// A failed Configure keeps the previous digest.
+	call(Plugin_Configure_FullMethodName, strict, true)
+	wantDigest := ConfigDigest(&PluginConfig{CustomConfig: map[string]string{"mode": "lax"}})
+	if got := o.configDigest.current(); got != wantDigest {
+		t.Errorf("current after a failed Configure = %s, want %s", got, wantDigest)
+	}
+
+	for _, method := range []string{Plugin_GetMetadata_FullMethodName, Plugin_CheckHealth_FullMethodName} {
+		if got := call(method, &emptypb.Empty{}, false).header.Get(ConfigDigestMetadataKey); len(got) != 1 ||
+			got[0] != wantDigest {
+			t.Errorf("%s header = %v, want %s", method, got, wantDigest)
+		}
+	}
+	if s := call(Plugin_HandleRequest_FullMethodName, &HTTPRequest{}, false); len(s.header) != 0 {
+		t.Errorf("HandleRequest set headers %v", s.header)
+	}
+}
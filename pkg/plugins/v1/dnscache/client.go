@@ -0,0 +1,253 @@
+package dnscache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// udpSize is the EDNS(0) UDP payload size advertised, the value recommended to avoid IP
+// fragmentation.
+const udpSize = 1232
+
+// errServerFailure reports a SERVFAIL or REFUSED answer, which is neither cached nor final: the
+// next server is tried.
+var errServerFailure = errors.New("server failure")
+
+// answer is the outcome of one query.
+type answer struct {
+	addrs []netip.Addr
+	ttl   time.Duration // Of the answer, or of the negative answer when addrs is empty.
+}
+
+// query sends one question for name and typ to the configured servers, trying each in turn for
+// every attempt, and returns the first usable answer.
+func (r *Resolver) query(ctx context.Context, name string, typ dnsmessage.Type) (answer, error) {
+	q, err := dnsmessage.NewName(name)
+	if err != nil {
+		return answer{}, err
+	}
+
+	var lastErr error
+	for range r.conf.attempts {
+		for _, server := range r.conf.servers {
+			ans, err := r.exchange(ctx, server, q, typ)
+			if err == nil {
+				return ans, nil
+			}
+			if ctx.Err() != nil {
+				return answer{}, ctx.Err()
+			}
+			lastErr = fmt.Errorf("%s: %w", server, err)
+		}
+	}
+
+	return answer{}, lastErr
+}
+
+// exchange asks server, over UDP and then TCP if the answer was truncated.
+func (r *Resolver) exchange(
+	ctx context.Context,
+	server string,
+	q dnsmessage.Name,
+	typ dnsmessage.Type,
+) (answer, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.conf.timeout)
+	defer cancel()
+
+	var idb [2]byte
+	_, _ = rand.Read(idb[:])
+	id := binary.BigEndian.Uint16(idb[:])
+
+	b := dnsmessage.NewBuilder(make([]byte, 2, 514), dnsmessage.Header{ID: id, RecursionDesired: true})
+	b.EnableCompression()
+	_ = b.StartQuestions()
+	_ = b.Question(dnsmessage.Question{Name: q, Type: typ, Class: dnsmessage.ClassINET})
+	_ = b.StartAdditionals()
+	var opt dnsmessage.ResourceHeader
+	_ = opt.SetEDNS0(udpSize, dnsmessage.RCodeSuccess, false)
+	_ = b.OPTResource(opt, dnsmessage.OPTResource{})
+	msg, err := b.Finish()
+	if err != nil {
+		return answer{}, err
+	}
+	binary.BigEndian.PutUint16(msg, uint16(len(msg)-2))
+
+	resp, err := r.roundTrip(ctx, "udp", server, msg[2:])
+	if err != nil {
+		return answer{}, err
+	}
+	var h dnsmessage.Header
+	if err := parseHeader(resp, &h); err == nil && h.Truncated {
+		if resp, err = r.roundTrip(ctx, "tcp", server, msg); err != nil {
+			return answer{}, err
+		}
+	}
+
+	return parseAnswer(resp, id, q, typ)
+}
+
+// roundTrip writes msg to server and reads one reply. TCP messages carry their 2-byte length
+// prefix.
+func (r *Resolver) roundTrip(ctx context.Context, network, server string, msg []byte) ([]byte, error) {
+	conn, err := r.dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	if network == "udp" {
+		buf := make([]byte, udpSize)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+func parseHeader(msg []byte, h *dnsmessage.Header) error {
+	var p dnsmessage.Parser
+	hdr, err := p.Start(msg)
+	*h = hdr
+	return err
+}
+
+// parseAnswer extracts the addresses of typ for q from msg and their TTL, following CNAME
+// chains within the answer, or the negative caching TTL of an empty answer (RFC 2308).
+func parseAnswer(msg []byte, id uint16, q dnsmessage.Name, typ dnsmessage.Type) (answer, error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(msg)
+	if err != nil {
+		return answer{}, fmt.Errorf("malformed response: %w", err)
+	}
+	if h.ID != id || !h.Response {
+		return answer{}, fmt.Errorf("mismatched response")
+	}
+	questions, err := p.AllQuestions()
+	if err != nil || len(questions) != 1 || questions[0].Type != typ ||
+		!strings.EqualFold(questions[0].Name.String(), q.String()) {
+		return answer{}, fmt.Errorf("response to a different question")
+	}
+	switch h.RCode {
+	case dnsmessage.RCodeSuccess, dnsmessage.RCodeNameError:
+	default:
+		return answer{}, fmt.Errorf("%w: %s", errServerFailure, h.RCode)
+	}
+
+	var ans answer
+	target := q.String()
+	ttl := uint32(0)
+	first := true
+	for {
+		rh, err := p.AnswerHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			break
+		}
+		if err != nil {
+			return answer{}, fmt.Errorf("malformed answer: %w", err)
+		}
+		if !strings.EqualFold(rh.Name.String(), target) || rh.Class != dnsmessage.ClassINET {
+			_ = p.SkipAnswer()
+			continue
+		}
+		if first || rh.TTL < ttl {
+			ttl, first = rh.TTL, false
+		}
+		switch rh.Type {
+		case dnsmessage.TypeCNAME:
+			rr, err := p.CNAMEResource()
+			if err != nil {
+				return answer{}, fmt.Errorf("malformed CNAME: %w", err)
+			}
+			target = rr.CNAME.String()
+		case dnsmessage.TypeA:
+			rr, err := p.AResource()
+			if err != nil {
+				return answer{}, fmt.Errorf("malformed A record: %w", err)
+			}
+			if typ == dnsmessage.TypeA {
+				ans.addrs = append(ans.addrs, netip.AddrFrom4(rr.A))
+			}
+		case dnsmessage.TypeAAAA:
+			rr, err := p.AAAAResource()
+			if err != nil {
+				return answer{}, fmt.Errorf("malformed AAAA record: %w", err)
+			}
+			if typ == dnsmessage.TypeAAAA {
+				ans.addrs = append(ans.addrs, netip.AddrFrom16(rr.AAAA).Unmap())
+			}
+		default:
+			_ = p.SkipAnswer()
+		}
+	}
+	if len(ans.addrs) > 0 {
+		ans.ttl = time.Duration(ttl) * time.Second
+		return ans, nil
+	}
+
+	// Negative answer (NXDOMAIN or NODATA): cached for the SOA's minimum TTL, bounded by the SOA
+	// record's own TTL.
+	ans.ttl = -1
+	for {
+		rh, err := p.AuthorityHeader()
+		if err != nil {
+			break
+		}
+		if rh.Type != dnsmessage.TypeSOA {
+			_ = p.SkipAuthority()
+			continue
+		}
+		soa, err := p.SOAResource()
+		if err != nil {
+			break
+		}
+		ans.ttl = time.Duration(min(rh.TTL, soa.MinTTL)) * time.Second
+		break
+	}
+
+	return ans, nil
+}
+
+// dnsError returns the *net.DNSError reporting a failed lookup of host, so callers can use
+// IsNotFound and IsTimeout as with the standard resolver.
+func dnsError(host string, err error, notFound bool) error {
+	e := &net.DNSError{Name: host, IsNotFound: notFound}
+	switch {
+	case notFound:
+		e.Err = "no such host"
+	case err != nil:
+		e.Err = err.Error()
+		var ne net.Error
+		e.IsTimeout = errors.Is(err, context.DeadlineExceeded) || errors.As(err, &ne) && ne.Timeout()
+		e.IsTemporary = true
+	}
+
+	return e
+}
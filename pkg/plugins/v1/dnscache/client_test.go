@@ -0,0 +1,455 @@
+package dnscache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// reply is a fake name server's response to a question.
+type reply struct {
+	rcode       dnsmessage.RCode
+	answers     []dnsmessage.Resource
+	authorities []dnsmessage.Resource
+	truncate    bool                 // Sets the TC bit over UDP, so the client retries over TCP.
+	id          int                  // Overrides the message ID when non-zero.
+	question    *dnsmessage.Question // Replaces the question echoed back.
+	drop        bool                 // Sends nothing, so the client times out.
+	raw         []byte               // Sent as is instead of a built message.
+}
+
+// dnsServer is a fake name server on UDP and TCP at the same loopback address.
+type dnsServer struct {
+	addr string
+
+	mu      sync.Mutex
+	handler func(q dnsmessage.Question) reply
+	queries []string // "network name type" of each question received.
+}
+
+func newDNSServer(t *testing.T, handler func(q dnsmessage.Question) reply) *dnsServer {
+	t.Helper()
+
+	s := &dnsServer{handler: handler}
+	var (
+		pc net.PacketConn
+		ln net.Listener
+	)
+	for range 10 {
+		var err error
+		if pc, err = net.ListenPacket("udp", "127.0.0.1:0"); err != nil {
+			t.Fatal(err)
+		}
+		if ln, err = net.Listen("tcp", pc.LocalAddr().String()); err == nil {
+			break
+		}
+		_ = pc.Close()
+		pc = nil
+	}
+	if pc == nil {
+		t.Fatal("no loopback port free for both UDP and TCP")
+	}
+	t.Cleanup(func() {
+		_ = pc.Close()
+		_ = ln.Close()
+	})
+	s.addr = pc.LocalAddr().String()
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, from, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := s.respond("udp", buf[:n]); resp != nil {
+				_, _ = pc.WriteTo(resp, from)
+			}
+		}
+	}()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, msg); err != nil {
+					return
+				}
+				if resp := s.respond("tcp", msg); resp != nil {
+					binary.BigEndian.PutUint16(length[:], uint16(len(resp)))
+					_, _ = conn.Write(append(length[:], resp...))
+				}
+			}()
+		}
+	}()
+
+	return s
+}
+
+func (s *dnsServer) respond(network string, msg []byte) []byte {
+	var p dnsmessage.Parser
+	h, err := p.Start(msg)
+	if err != nil {
+		return nil
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.queries = append(s.queries, network+" "+q.Name.String()+" "+q.Type.String())
+	handler := s.handler
+	s.mu.Unlock()
+
+	r := handler(q)
+	if r.drop {
+		return nil
+	}
+	if r.raw != nil {
+		return r.raw
+	}
+	if r.id != 0 {
+		h.ID = uint16(r.id)
+	}
+	if r.question != nil {
+		q = *r.question
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 h.ID,
+		Response:           true,
+		RecursionAvailable: true,
+		RCode:              r.rcode,
+		Truncated:          r.truncate && network == "udp",
+	})
+	_ = b.StartQuestions()
+	_ = b.Question(q)
+	if !r.truncate || network == "tcp" {
+		_ = b.StartAnswers()
+		for _, rr := range r.answers {
+			addResource(&b, rr)
+		}
+		_ = b.StartAuthorities()
+		for _, rr := range r.authorities {
+			addResource(&b, rr)
+		}
+	}
+	resp, _ := b.Finish()
+
+	return resp
+}
+
+func addResource(b *dnsmessage.Builder, rr dnsmessage.Resource) {
+	switch body := rr.Body.(type) {
+	case *dnsmessage.AResource:
+		_ = b.AResource(rr.Header, *body)
+	case *dnsmessage.AAAAResource:
+		_ = b.AAAAResource(rr.Header, *body)
+	case *dnsmessage.CNAMEResource:
+		_ = b.CNAMEResource(rr.Header, *body)
+	case *dnsmessage.SOAResource:
+		_ = b.SOAResource(rr.Header, *body)
+	case *dnsmessage.TXTResource:
+		_ = b.TXTResource(rr.Header, *body)
+	}
+}
+
+func (s *dnsServer) setHandler(handler func(q dnsmessage.Question) reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handler = handler
+}
+
+func (s *dnsServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.queries...)
+}
+
+func mustName(name string) dnsmessage.Name {
+	return dnsmessage.MustNewName(name)
+}
+
+func rrHeader(name string, typ dnsmessage.Type, ttl uint32) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{Name: mustName(name), Type: typ, Class: dnsmessage.ClassINET, TTL: ttl}
+}
+
+// rrA returns an A or AAAA record of name for ip.
+func rrA(name, ip string, ttl uint32) dnsmessage.Resource {
+	addr := netip.MustParseAddr(ip)
+	if addr.Is4() {
+		return dnsmessage.Resource{
+			Header: rrHeader(name, dnsmessage.TypeA, ttl),
+			Body:   &dnsmessage.AResource{A: addr.As4()},
+		}
+	}
+
+	return dnsmessage.Resource{
+		Header: rrHeader(name, dnsmessage.TypeAAAA, ttl),
+		Body:   &dnsmessage.AAAAResource{AAAA: addr.As16()},
+	}
+}
+
+func rrCNAME(name, target string, ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: rrHeader(name, dnsmessage.TypeCNAME, ttl),
+		Body:   &dnsmessage.CNAMEResource{CNAME: mustName(target)},
+	}
+}
+
+func rrSOA(zone string, ttl, minTTL uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: rrHeader(zone, dnsmessage.TypeSOA, ttl),
+		Body: &dnsmessage.SOAResource{
+			NS:     mustName("ns." + zone),
+			MBox:   mustName("hostmaster." + zone),
+			Serial: 1, Refresh: 3600, Retry: 600, Expire: 86400,
+			MinTTL: minTTL,
+		},
+	}
+}
+
+// testResolver returns a Resolver querying srv, without a search list or hosts, with opts.
+func testResolver(t *testing.T, srv *dnsServer, opts ...Option) *Resolver {
+	t.Helper()
+
+	r, err := New(append([]Option{WithServers(srv.addr)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.conf.search, r.conf.ndots, r.conf.attempts = nil, 1, 1
+	r.conf.timeout = 2 * time.Second
+	r.hosts = map[string][]netip.Addr{}
+
+	return r
+}
+
+func TestQuery(t *testing.T) {
+	question := dnsmessage.Question{Name: mustName("other.test."), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}
+	tests := []struct {
+		name      string
+		typ       dnsmessage.Type
+		reply     reply
+		wantAddrs []string
+		wantTTL   time.Duration
+		wantErr   string
+		wantTCP   bool
+	}{
+		{
+			name: "addresses with the lowest TTL",
+			typ:  dnsmessage.TypeA,
+			reply: reply{answers: []dnsmessage.Resource{
+				rrA("api.test.", "192.0.2.1", 300), rrA("api.test.", "192.0.2.2", 60),
+			}},
+			wantAddrs: []string{"192.0.2.1", "192.0.2.2"},
+			wantTTL:   time.Minute,
+		},
+		{
+			name:      "IPv6",
+			typ:       dnsmessage.TypeAAAA,
+			reply:     reply{answers: []dnsmessage.Resource{rrA("api.test.", "2001:db8::1", 30)}},
+			wantAddrs: []string{"2001:db8::1"},
+			wantTTL:   30 * time.Second,
+		},
+		{
+			name: "CNAME chain",
+			typ:  dnsmessage.TypeA,
+			reply: reply{answers: []dnsmessage.Resource{
+				rrCNAME("api.test.", "edge.cdn.test.", 20),
+				rrA("unrelated.test.", "198.51.100.1", 5),
+				rrCNAME("edge.cdn.test.", "node.cdn.test.", 600),
+				rrA("node.cdn.test.", "192.0.2.9", 300),
+			}},
+			wantAddrs: []string{"192.0.2.9"},
+			wantTTL:   20 * time.Second,
+		},
+		{
+			name:      "case-insensitive owner names",
+			typ:       dnsmessage.TypeA,
+			reply:     reply{answers: []dnsmessage.Resource{rrA("API.Test.", "192.0.2.1", 60)}},
+			wantAddrs: []string{"192.0.2.1"},
+			wantTTL:   time.Minute,
+		},
+		{
+			name: "NXDOMAIN with an SOA",
+			typ:  dnsmessage.TypeA,
+			reply: reply{
+				rcode:       dnsmessage.RCodeNameError,
+				authorities: []dnsmessage.Resource{rrSOA("test.", 900, 60)},
+			},
+			wantTTL: time.Minute,
+		},
+		{
+			name:    "NODATA bounded by the SOA TTL",
+			typ:     dnsmessage.TypeAAAA,
+			reply:   reply{authorities: []dnsmessage.Resource{rrSOA("test.", 10, 3600)}},
+			wantTTL: 10 * time.Second,
+		},
+		{
+			name:    "negative answer without an SOA",
+			typ:     dnsmessage.TypeA,
+			reply:   reply{rcode: dnsmessage.RCodeNameError},
+			wantTTL: -1,
+		},
+		{
+			name:      "truncated answer retried over TCP",
+			typ:       dnsmessage.TypeA,
+			reply:     reply{truncate: true, answers: []dnsmessage.Resource{rrA("api.test.", "192.0.2.1", 60)}},
+			wantAddrs: []string{"192.0.2.1"},
+			wantTTL:   time.Minute,
+			wantTCP:   true,
+		},
+		{
+			name:    "server failure",
+			typ:     dnsmessage.TypeA,
+			reply:   reply{rcode: dnsmessage.RCodeServerFailure},
+			wantErr: "server failure",
+		},
+		{
+			name:    "refused",
+			typ:     dnsmessage.TypeA,
+			reply:   reply{rcode: dnsmessage.RCodeRefused},
+			wantErr: "server failure",
+		},
+		{name: "mismatched ID", typ: dnsmessage.TypeA, reply: reply{id: 1}, wantErr: "mismatched response"},
+		{
+			name:    "other question",
+			typ:     dnsmessage.TypeA,
+			reply:   reply{question: &question},
+			wantErr: "different question",
+		},
+		{name: "malformed", typ: dnsmessage.TypeA, reply: reply{raw: []byte{1, 2, 3}}, wantErr: "malformed response"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newDNSServer(t, func(dnsmessage.Question) reply { return tt.reply })
+			r := testResolver(t, srv)
+
+			ans, err := r.query(context.Background(), "api.test.", tt.typ)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("query error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, a := range ans.addrs {
+				got = append(got, a.String())
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantAddrs, ",") {
+				t.Errorf("addrs = %v, want %v", got, tt.wantAddrs)
+			}
+			if ans.ttl != tt.wantTTL {
+				t.Errorf("ttl = %s, want %s", ans.ttl, tt.wantTTL)
+			}
+			queries := srv.received()
+			if tcp := len(queries) == 2 && strings.HasPrefix(queries[1], "tcp "); tcp != tt.wantTCP {
+				t.Errorf("queries = %v, want TCP retry %t", queries, tt.wantTCP)
+			}
+		})
+	}
+}
+
+func TestQueryServers(t *testing.T) {
+	failing := newDNSServer(t, func(dnsmessage.Question) reply { return reply{rcode: dnsmessage.RCodeServerFailure} })
+	working := newDNSServer(t, func(dnsmessage.Question) reply {
+		return reply{answers: []dnsmessage.Resource{rrA("api.test.", "192.0.2.1", 60)}}
+	})
+
+	// The next server answers when one fails, and every server is tried on each attempt.
+	r := testResolver(t, failing, WithServers(failing.addr, working.addr))
+	if ans, err := r.query(context.Background(), "api.test.", dnsmessage.TypeA); err != nil || len(ans.addrs) != 1 {
+		t.Errorf("query = %+v, %v, want the working server's answer", ans, err)
+	}
+
+	r = testResolver(t, failing, WithServers(failing.addr))
+	r.conf.attempts = 3
+	_, err := r.query(context.Background(), "api.test.", dnsmessage.TypeA)
+	if !errors.Is(err, errServerFailure) || !strings.Contains(err.Error(), failing.addr) {
+		t.Errorf("query error = %v, want the server failure of %s", err, failing.addr)
+	}
+	if got := len(failing.received()); got != 4 {
+		t.Errorf("failing server got %d queries, want one, then one per attempt", got)
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	srv := newDNSServer(t, func(dnsmessage.Question) reply { return reply{drop: true} })
+	r := testResolver(t, srv)
+	r.conf.timeout = 50 * time.Millisecond
+
+	_, err := r.query(context.Background(), "api.test.", dnsmessage.TypeA)
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() {
+		t.Errorf("query error = %v, want a timeout", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.query(ctx, "api.test.", dnsmessage.TypeA); !errors.Is(err, context.Canceled) {
+		t.Errorf("query error = %v, want context.Canceled", err)
+	}
+}
+
+func TestDNSError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		notFound      bool
+		wantErr       string
+		wantTimeout   bool
+		wantTemporary bool
+	}{
+		{name: "not found", notFound: true, wantErr: "no such host"},
+		{name: "failure", err: errServerFailure, wantErr: "server failure", wantTemporary: true},
+		{name: "deadline", err: context.DeadlineExceeded, wantErr: "deadline", wantTimeout: true, wantTemporary: true},
+		{
+			name:          "network timeout",
+			err:           &net.OpError{Op: "read", Err: timeoutError{}},
+			wantErr:       "i/o timeout",
+			wantTimeout:   true,
+			wantTemporary: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e *net.DNSError
+			if !errors.As(dnsError("api.test", tt.err, tt.notFound), &e) {
+				t.Fatal("dnsError did not return a *net.DNSError")
+			}
+			if e.Name != "api.test" || !strings.Contains(e.Err, tt.wantErr) || e.IsNotFound != tt.notFound ||
+				e.IsTimeout != tt.wantTimeout || e.IsTemporary != tt.wantTemporary {
+				t.Errorf("dnsError = %+v", e)
+			}
+		})
+	}
+}
+
+// timeoutError is a net.Error timing out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
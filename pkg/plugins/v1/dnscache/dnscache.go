@@ -0,0 +1,415 @@
+// Package dnscache provides a caching DNS resolver for plugins making many outbound calls. It
+// answers repeated lookups from memory for as long as the records' TTLs allow, and remembers
+// names that do not exist (negative caching, RFC 2308), sparing the cluster resolver the query
+// bursts that search lists with a high ndots cause in containers.
+//
+// The resolver reads the system configuration (/etc/resolv.conf and /etc/hosts), coalesces
+// concurrent lookups of the same name and is safe for concurrent use. Plugins usually share one:
+//
+//	client, err := httpclientx.New(httpclientx.DefaultConfig(), httpclientx.WithResolver(dnscache.Shared()))
+//
+// The Go standard resolver does not expose TTLs, so the package queries the name servers itself,
+// over UDP with a TCP fallback for truncated answers. DNSSEC validation and DNS over TLS or HTTPS
+// are left to the configured name servers.
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// MetricLookups counts the questions the resolver answers, labelled by LabelResult: "hit" (from
+// a cached address), "negative_hit" (from a cached non-existence), "miss" (queried) or "error".
+const MetricLookups = "dns.lookups"
+
+// LabelResult is the label key of MetricLookups.
+const LabelResult = "result"
+
+// Defaults of the TTL bounds and cache size.
+const (
+	DefaultMaxTTL      = 5 * time.Minute
+	DefaultNegativeTTL = 30 * time.Second
+	DefaultMaxEntries  = 10000
+)
+
+// Resolver is a caching DNS resolver.
+type Resolver struct {
+	conf     resolvConf
+	hosts    map[string][]netip.Addr
+	dialer   *net.Dialer
+	minTTL   time.Duration
+	maxTTL   time.Duration
+	negTTL   time.Duration
+	maxSize  int
+	recorder metrics.Recorder
+	clock    clock.Clock
+
+	mu       sync.Mutex
+	entries  map[question]*entry
+	inflight map[question]*call
+}
+
+// question is a cache key: a fully qualified lower-case name and a record type.
+type question struct {
+	name string
+	typ  dnsmessage.Type
+}
+
+type entry struct {
+	ans     answer
+	expires time.Time
+}
+
+// call is a query in flight, shared by concurrent lookups of the same question.
+type call struct {
+	done chan struct{}
+	ans  answer
+	err  error
+}
+
+// Option configures a Resolver.
+type Option func(*Resolver) error
+
+// WithServers sets the name servers queried, as addresses with an optional port (53 by default),
+// instead of those of /etc/resolv.conf.
+func WithServers(servers ...string) Option {
+	return func(r *Resolver) error {
+		if len(servers) == 0 {
+			return fmt.Errorf("at least one name server is required")
+		}
+		r.conf.servers = r.conf.servers[:0]
+		for _, s := range servers {
+			if _, _, err := net.SplitHostPort(s); err != nil {
+				s = net.JoinHostPort(strings.Trim(s, "[]"), "53")
+			}
+			r.conf.servers = append(r.conf.servers, s)
+		}
+		return nil
+	}
+}
+
+// WithMinTTL caches answers for at least d, even when their records' TTL is shorter (zero by
+// default, which respects every TTL).
+func WithMinTTL(d time.Duration) Option {
+	return func(r *Resolver) error {
+		if d < 0 {
+			return fmt.Errorf("minimum TTL cannot be negative")
+		}
+		r.minTTL = d
+		return nil
+	}
+}
+
+// WithMaxTTL caches answers for at most d, even when their records' TTL is longer, so address
+// changes are noticed within d (defaults to DefaultMaxTTL).
+func WithMaxTTL(d time.Duration) Option {
+	return func(r *Resolver) error {
+		if d <= 0 {
+			return fmt.Errorf("maximum TTL must be positive")
+		}
+		r.maxTTL = d
+		return nil
+	}
+}
+
+// WithNegativeTTL caps how long the absence of a name or record is cached, which also applies
+// to negative answers without an SOA record (defaults to DefaultNegativeTTL). Zero disables
+// negative caching.
+func WithNegativeTTL(d time.Duration) Option {
+	return func(r *Resolver) error {
+		if d < 0 {
+			return fmt.Errorf("negative TTL cannot be negative")
+		}
+		r.negTTL = d
+		return nil
+	}
+}
+
+// WithMaxEntries bounds the number of cached answers (defaults to DefaultMaxEntries).
+func WithMaxEntries(n int) Option {
+	return func(r *Resolver) error {
+		if n <= 0 {
+			return fmt.Errorf("maximum entries must be positive")
+		}
+		r.maxSize = n
+		return nil
+	}
+}
+
+// WithMetrics records MetricLookups through rec.
+func WithMetrics(rec metrics.Recorder) Option {
+	return func(r *Resolver) error {
+		if rec == nil {
+			return fmt.Errorf("metrics recorder cannot be nil")
+		}
+		r.recorder = rec
+		return nil
+	}
+}
+
+// WithClock sets the clock cached answers expire by (defaults to clock.Real()). Query timeouts
+// keep the wall clock.
+func WithClock(c clock.Clock) Option {
+	return func(r *Resolver) error {
+		if c == nil {
+			return fmt.Errorf("clock cannot be nil")
+		}
+		r.clock = c
+		return nil
+	}
+}
+
+// New returns a Resolver configured from /etc/resolv.conf and /etc/hosts and opts.
+func New(opts ...Option) (*Resolver, error) {
+	r := &Resolver{
+		conf:     readResolvConf(resolvConfPath),
+		hosts:    readHosts(hostsPath),
+		dialer:   &net.Dialer{},
+		maxTTL:   DefaultMaxTTL,
+		negTTL:   DefaultNegativeTTL,
+		maxSize:  DefaultMaxEntries,
+		recorder: metrics.Nop(),
+		clock:    clock.Real(),
+		entries:  map[question]*entry{},
+		inflight: map[question]*call{},
+	}
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+	if r.minTTL > r.maxTTL {
+		return nil, fmt.Errorf("minimum TTL %s exceeds maximum TTL %s", r.minTTL, r.maxTTL)
+	}
+
+	return r, nil
+}
+
+var shared = sync.OnceValue(func() *Resolver {
+	r, err := New()
+	if err != nil {
+		panic(fmt.Sprintf("dnscache: invalid defaults: %v", err))
+	}
+	return r
+})
+
+// Shared returns the process-wide Resolver with the default configuration.
+func Shared() *Resolver {
+	return shared()
+}
+
+// LookupNetIP looks up host, returning its IPv4 addresses first (network "ip"), or only its IPv4
+// ("ip4") or IPv6 ("ip6") addresses, with the signature of net.Resolver.LookupNetIP. Errors are
+// *net.DNSError values.
+func (r *Resolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	var types []dnsmessage.Type
+	switch network {
+	case "ip":
+		types = []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA}
+	case "ip4":
+		types = []dnsmessage.Type{dnsmessage.TypeA}
+	case "ip6":
+		types = []dnsmessage.Type{dnsmessage.TypeAAAA}
+	default:
+		return nil, &net.DNSError{Name: host, Err: "unsupported network " + network}
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return filterFamily([]netip.Addr{addr}, network), nil
+	}
+	name := strings.ToLower(host)
+	if addrs := filterFamily(r.hosts[strings.TrimSuffix(name, ".")], network); len(addrs) > 0 {
+		return addrs, nil
+	}
+	if name == "" || len(name) > 254 {
+		return nil, dnsError(host, nil, true)
+	}
+
+	for _, fqdn := range r.conf.candidates(name) {
+		answers := make([]answer, len(types))
+		errs := make([]error, len(types))
+		var wg sync.WaitGroup
+		for i, typ := range types {
+			wg.Go(func() {
+				answers[i], errs[i] = r.lookup(ctx, question{name: fqdn, typ: typ})
+			})
+		}
+		wg.Wait()
+
+		var addrs []netip.Addr
+		for _, a := range answers {
+			addrs = append(addrs, a.addrs...)
+		}
+		if len(addrs) > 0 {
+			return addrs, nil
+		}
+		if err := errors.Join(errs...); err != nil {
+			return nil, dnsError(host, err, false)
+		}
+	}
+
+	return nil, dnsError(host, nil, true)
+}
+
+// LookupHost looks up host, returning its addresses as strings, with the signature of
+// net.Resolver.LookupHost.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, err := r.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(addrs))
+	for i, a := range addrs {
+		out[i] = a.String()
+	}
+
+	return out, nil
+}
+
+// DialContext returns a dial function for http.Transport.DialContext and similar hooks, resolving
+// hosts with r and dialing their addresses with dialer (nil uses a zero net.Dialer) in order
+// until one connects.
+func (r *Resolver) DialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		family := "ip"
+		switch network {
+		case "tcp4", "udp4":
+			family = "ip4"
+		case "tcp6", "udp6":
+			family = "ip6"
+		}
+		addrs, err := r.LookupNetIP(ctx, family, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var errs []error
+		for _, ip := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+		}
+		return nil, errors.Join(errs...)
+	}
+}
+
+// Flush discards every cached answer.
+func (r *Resolver) Flush() {
+	r.mu.Lock()
+	clear(r.entries)
+	r.mu.Unlock()
+}
+
+// lookup answers q from the cache, from a query in flight or by querying the name servers.
+func (r *Resolver) lookup(ctx context.Context, q question) (answer, error) {
+	r.mu.Lock()
+	if e, ok := r.entries[q]; ok && r.clock.Now().Before(e.expires) {
+		r.mu.Unlock()
+		result := "hit"
+		if len(e.ans.addrs) == 0 {
+			result = "negative_hit"
+		}
+		r.recorder.Count(MetricLookups, 1, metrics.L(LabelResult, result))
+		return e.ans, nil
+	}
+	c, ok := r.inflight[q]
+	if !ok {
+		c = &call{done: make(chan struct{})}
+		r.inflight[q] = c
+		go r.resolve(context.WithoutCancel(ctx), q, c)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.ans, c.err
+	case <-ctx.Done():
+		return answer{}, ctx.Err()
+	}
+}
+
+// resolve queries the name servers for q, caches the answer and completes c. It runs detached
+// from the lookups waiting for it, so one caller giving up does not fail the others.
+func (r *Resolver) resolve(ctx context.Context, q question, c *call) {
+	ans, err := r.query(ctx, q.name, q.typ)
+	result := "miss"
+	if err != nil {
+		result = "error"
+	}
+	r.recorder.Count(MetricLookups, 1, metrics.L(LabelResult, result))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.inflight, q)
+	c.ans, c.err = ans, err
+	close(c.done)
+	if err != nil {
+		return
+	}
+
+	ttl := ans.ttl
+	if len(ans.addrs) == 0 {
+		if ttl < 0 || ttl > r.negTTL {
+			ttl = r.negTTL
+		}
+	} else {
+		ttl = min(max(ttl, r.minTTL), r.maxTTL)
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	now := r.clock.Now()
+	if len(r.entries) >= r.maxSize {
+		for k, e := range r.entries {
+			if !now.Before(e.expires) {
+				delete(r.entries, k)
+			}
+		}
+		// Still full: evict arbitrary entries, which map iteration picks at random.
+		for k := range r.entries {
+			if len(r.entries) < r.maxSize {
+				break
+			}
+			delete(r.entries, k)
+		}
+	}
+	r.entries[q] = &entry{ans: ans, expires: now.Add(ttl)}
+}
+
+// filterFamily returns the addresses of addrs in network's address family.
+func filterFamily(addrs []netip.Addr, network string) []netip.Addr {
+	if network == "ip" {
+		return addrs
+	}
+	var out []netip.Addr
+	for _, a := range addrs {
+		if a.Is4() == (network == "ip4") {
+			out = append(out, a)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,584 @@
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/plugintest"
+)
+
+// fakeRecorder records counts as "name delta [k=v ...]" lines.
+type fakeRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *fakeRecorder) Count(name string, delta int64, labels ...metrics.Label) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Key + "=" + l.Value
+	}
+	r.lines = append(r.lines, fmt.Sprintf("%s %d [%s]", name, delta, strings.Join(parts, " ")))
+}
+
+func (r *fakeRecorder) Gauge(string, float64, ...metrics.Label) {}
+
+func (r *fakeRecorder) Observe(string, float64, ...metrics.Label) {}
+
+func (r *fakeRecorder) Timing(string, time.Duration, ...metrics.Label) {}
+
+func (r *fakeRecorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.lines)
+}
+
+// zone answers A and AAAA questions from records, keyed by fully qualified name, and NXDOMAIN
+// with a 60s SOA otherwise.
+func zone(ttl uint32, records map[string][]string) func(q dnsmessage.Question) reply {
+	return func(q dnsmessage.Question) reply {
+		ips, ok := records[strings.ToLower(q.Name.String())]
+		if !ok {
+			return nxdomain(60, 60)
+		}
+		var r reply
+		for _, ip := range ips {
+			rr := rrA(q.Name.String(), ip, ttl)
+			if rr.Header.Type == q.Type {
+				r.answers = append(r.answers, rr)
+			}
+		}
+		return r
+	}
+}
+
+// nxdomain is an NXDOMAIN reply with an SOA record of the given TTL and minimum TTL.
+func nxdomain(ttl, minTTL uint32) reply {
+	return reply{rcode: dnsmessage.RCodeNameError, authorities: []dnsmessage.Resource{rrSOA("test.", ttl, minTTL)}}
+}
+
+func strs(addrs []netip.Addr) []string {
+	out := make([]string, len(addrs))
+	for i, a := range addrs {
+		out[i] = a.String()
+	}
+
+	return out
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "no servers", opts: []Option{WithServers()}, want: "at least one name server"},
+		{name: "negative minimum TTL", opts: []Option{WithMinTTL(-time.Second)}, want: "cannot be negative"},
+		{name: "zero maximum TTL", opts: []Option{WithMaxTTL(0)}, want: "must be positive"},
+		{name: "negative negative TTL", opts: []Option{WithNegativeTTL(-time.Second)}, want: "cannot be negative"},
+		{name: "zero entries", opts: []Option{WithMaxEntries(0)}, want: "must be positive"},
+		{name: "nil recorder", opts: []Option{WithMetrics(nil)}, want: "recorder cannot be nil"},
+		{name: "nil clock", opts: []Option{WithClock(nil)}, want: "clock cannot be nil"},
+		{
+			name: "minimum above maximum",
+			opts: []Option{WithMinTTL(time.Hour), WithMaxTTL(time.Minute)},
+			want: "minimum TTL 1h0m0s exceeds maximum TTL 1m0s",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.opts...); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithServers(t *testing.T) {
+	r, err := New(WithServers("192.0.2.53", "192.0.2.54:5353", "2001:db8::53", "[2001:db8::54]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"192.0.2.53:53", "192.0.2.54:5353", "[2001:db8::53]:53", "[2001:db8::54]:53"}
+	if !slices.Equal(r.conf.servers, want) {
+		t.Errorf("servers = %v, want %v", r.conf.servers, want)
+	}
+}
+
+func TestLookupNetIP(t *testing.T) {
+	records := map[string][]string{
+		"api.test.":           {"192.0.2.1", "2001:db8::1", "192.0.2.2"},
+		"v6.test.":            {"2001:db8::6"},
+		"svc.cluster.local.":  {"10.0.0.7"},
+		"api.test.corp.test.": {"192.0.2.99"},
+	}
+	tests := []struct {
+		name         string
+		network      string
+		host         string
+		search       []string
+		ndots        int
+		wantAddrs    []string
+		wantNotFound bool
+		wantErr      string
+		wantQueries  []string
+	}{
+		{
+			name:        "IPv4 first",
+			network:     "ip",
+			host:        "API.test",
+			wantAddrs:   []string{"192.0.2.1", "192.0.2.2", "2001:db8::1"},
+			wantQueries: []string{"udp api.test. TypeA", "udp api.test. TypeAAAA"},
+		},
+		{
+			name:        "IPv4 only",
+			network:     "ip4",
+			host:        "api.test",
+			wantAddrs:   []string{"192.0.2.1", "192.0.2.2"},
+			wantQueries: []string{"udp api.test. TypeA"},
+		},
+		{
+			name:        "IPv6 only",
+			network:     "ip6",
+			host:        "api.test.",
+			wantAddrs:   []string{"2001:db8::1"},
+			wantQueries: []string{"udp api.test. TypeAAAA"},
+		},
+		{name: "IP literal", network: "ip", host: "192.0.2.7", wantAddrs: []string{"192.0.2.7"}},
+		{name: "IP literal of the other family", network: "ip6", host: "192.0.2.7", wantAddrs: []string{}},
+		{name: "hosts file", network: "ip", host: "Printer.lan.", wantAddrs: []string{"192.168.1.5", "fd00::5"}},
+		{name: "hosts file filtered", network: "ip6", host: "printer.lan", wantAddrs: []string{"fd00::5"}},
+		{
+			name:         "hosts file without the family",
+			network:      "ip6",
+			host:         "legacy.lan",
+			wantQueries:  []string{"udp legacy.lan. TypeAAAA"},
+			wantErr:      "no such host",
+			wantNotFound: true,
+		},
+		{name: "unsupported network", network: "tcp", host: "api.test", wantErr: "unsupported network tcp"},
+		{name: "empty name", network: "ip", host: "", wantNotFound: true, wantErr: "no such host"},
+		{name: "name too long", network: "ip", host: strings.Repeat("a.", 128), wantNotFound: true},
+		{
+			name:         "NXDOMAIN",
+			network:      "ip4",
+			host:         "missing.test",
+			wantNotFound: true,
+			wantErr:      "no such host",
+			wantQueries:  []string{"udp missing.test. TypeA"},
+		},
+		{
+			name:        "only IPv6 records",
+			network:     "ip",
+			host:        "v6.test",
+			wantAddrs:   []string{"2001:db8::6"},
+			wantQueries: []string{"udp v6.test. TypeA", "udp v6.test. TypeAAAA"},
+		},
+		{
+			name:        "search list",
+			network:     "ip4",
+			host:        "svc",
+			search:      []string{"default.svc.cluster.local", "cluster.local"},
+			ndots:       5,
+			wantAddrs:   []string{"10.0.0.7"},
+			wantQueries: []string{"udp svc.default.svc.cluster.local. TypeA", "udp svc.cluster.local. TypeA"},
+		},
+		{
+			name:        "enough dots tries the name first",
+			network:     "ip4",
+			host:        "api.test",
+			search:      []string{"corp.test"},
+			ndots:       1,
+			wantAddrs:   []string{"192.0.2.1", "192.0.2.2"},
+			wantQueries: []string{"udp api.test. TypeA"},
+		},
+		{
+			name:        "too few dots tries the search list first",
+			network:     "ip4",
+			host:        "api.test",
+			search:      []string{"corp.test"},
+			ndots:       2,
+			wantAddrs:   []string{"192.0.2.99"},
+			wantQueries: []string{"udp api.test.corp.test. TypeA"},
+		},
+		{
+			name:         "fully qualified names skip the search list",
+			network:      "ip4",
+			host:         "svc.",
+			search:       []string{"cluster.local"},
+			ndots:        5,
+			wantNotFound: true,
+			wantQueries:  []string{"udp svc. TypeA"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newDNSServer(t, zone(60, records))
+			r := testResolver(t, srv)
+			r.hosts = map[string][]netip.Addr{
+				"printer.lan": {netip.MustParseAddr("192.168.1.5"), netip.MustParseAddr("fd00::5")},
+				"legacy.lan":  {netip.MustParseAddr("192.168.1.6")},
+			}
+			r.conf.search = tt.search
+			if tt.ndots != 0 {
+				r.conf.ndots = tt.ndots
+			}
+
+			addrs, err := r.LookupNetIP(context.Background(), tt.network, tt.host)
+			if tt.wantErr != "" || tt.wantNotFound {
+				var dnsErr *net.DNSError
+				if !errors.As(err, &dnsErr) || !strings.Contains(dnsErr.Err, tt.wantErr) ||
+					dnsErr.IsNotFound != tt.wantNotFound || dnsErr.Name != tt.host {
+					t.Errorf("LookupNetIP error = %#v, want a *net.DNSError with %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if got := strs(addrs); !slices.Equal(got, tt.wantAddrs) {
+				t.Errorf("LookupNetIP = %v, want %v", got, tt.wantAddrs)
+			}
+			got := srv.received()
+			slices.Sort(got)
+			want := slices.Clone(tt.wantQueries)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("queries = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestLookupNetIPServerFailure(t *testing.T) {
+	srv := newDNSServer(t, func(q dnsmessage.Question) reply {
+		if q.Type == dnsmessage.TypeAAAA {
+			return reply{rcode: dnsmessage.RCodeServerFailure}
+		}
+		return reply{}
+	})
+	r := testResolver(t, srv)
+
+	// A failure of one family without addresses in the other is reported, not taken for absence.
+	_, err := r.LookupNetIP(context.Background(), "ip", "api.test")
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || dnsErr.IsNotFound || !dnsErr.IsTemporary ||
+		!strings.Contains(dnsErr.Err, "server failure") {
+		t.Errorf("LookupNetIP error = %#v, want a temporary server failure", err)
+	}
+
+	// With addresses in the other family the failure is ignored.
+	srv.setHandler(func(q dnsmessage.Question) reply {
+		if q.Type == dnsmessage.TypeAAAA {
+			return reply{rcode: dnsmessage.RCodeServerFailure}
+		}
+		return reply{answers: []dnsmessage.Resource{rrA("api.test.", "192.0.2.1", 60)}}
+	})
+	r.Flush()
+	addrs, err := r.LookupNetIP(context.Background(), "ip", "api.test")
+	if err != nil || !slices.Equal(strs(addrs), []string{"192.0.2.1"}) {
+		t.Errorf("LookupNetIP = %v, %v, want the IPv4 address", addrs, err)
+	}
+}
+
+func TestLookupCaching(t *testing.T) {
+	type step struct {
+		advance     time.Duration
+		wantQueries int
+		wantMetric  string
+	}
+	tests := []struct {
+		name  string
+		reply reply
+		opts  []Option
+		steps []step
+	}{
+		{
+			name:  "answer cached for its TTL",
+			reply: reply{answers: []dnsmessage.Resource{rrA("api.test.", "192.0.2.1", 30)}},
+			steps: []step{{0, 1, "miss"}, {29 * time.Second, 1, "hit"}, {time.Second, 2, "miss"}},
+		},
+		{
+			name:  "TTL raised to the minimum",
+			reply: reply{answers: []dnsmessage.Resource{rrA("api.test.", "192.0.2.1", 1)}},
+			opts:  []Option{WithMinTTL(10 * time.Second)},
+			steps: []step{{0, 1, "miss"}, {9 * time.Second, 1, "hit"}, {time.Second, 2, "miss"}},
+		},
+		{
+			name:  "TTL capped to the maximum",
+			reply: reply{answers: []dnsmessage.Resource{rrA("api.test.", "192.0.2.1", 86400)}},
+			opts:  []Option{WithMaxTTL(time.Minute)},
+			steps: []step{{0, 1, "miss"}, {59 * time.Second, 1, "hit"}, {time.Second, 2, "miss"}},
+		},
+		{
+			name:  "zero TTL not cached",
+			reply: reply{answers: []dnsmessage.Resource{rrA("api.test.", "192.0.2.1", 0)}},
+			steps: []step{{0, 1, "miss"}, {0, 2, "miss"}},
+		},
+		{
+			name:  "negative answer cached for the SOA minimum",
+			reply: nxdomain(600, 5),
+			steps: []step{{0, 1, "miss"}, {4 * time.Second, 1, "negative_hit"}, {time.Second, 2, "miss"}},
+		},
+		{
+			name:  "negative answer capped to the negative TTL",
+			reply: nxdomain(600, 600),
+			opts:  []Option{WithNegativeTTL(10 * time.Second)},
+			steps: []step{{0, 1, "miss"}, {9 * time.Second, 1, "negative_hit"}, {time.Second, 2, "miss"}},
+		},
+		{
+			name:  "negative answer without an SOA",
+			reply: reply{rcode: dnsmessage.RCodeNameError},
+			steps: []step{
+				{0, 1, "miss"}, {DefaultNegativeTTL - time.Second, 1, "negative_hit"}, {time.Second, 2, "miss"},
+			},
+		},
+		{
+			name:  "negative caching disabled",
+			reply: nxdomain(60, 60),
+			opts:  []Option{WithNegativeTTL(0)},
+			steps: []step{{0, 1, "miss"}, {0, 2, "miss"}},
+		},
+		{
+			name:  "failures not cached",
+			reply: reply{rcode: dnsmessage.RCodeServerFailure},
+			steps: []step{{0, 1, "error"}, {0, 2, "error"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newDNSServer(t, func(dnsmessage.Question) reply { return tt.reply })
+			clk := plugintest.NewFakeClock(time.Time{})
+			rec := &fakeRecorder{}
+			r := testResolver(t, srv, append([]Option{WithClock(clk), WithMetrics(rec)}, tt.opts...)...)
+
+			for i, s := range tt.steps {
+				clk.Advance(s.advance)
+				_, _ = r.LookupNetIP(context.Background(), "ip4", "api.test")
+				if got := len(srv.received()); got != s.wantQueries {
+					t.Errorf("step %d: %d queries, want %d", i, got, s.wantQueries)
+				}
+				recorded := rec.recorded()
+				if want := "dns.lookups 1 [result=" + s.wantMetric + "]"; recorded[len(recorded)-1] != want {
+					t.Errorf("step %d: metric %s, want %s", i, recorded[len(recorded)-1], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLookupCoalesces(t *testing.T) {
+	release := make(chan struct{})
+	srv := newDNSServer(t, func(dnsmessage.Question) reply {
+		<-release
+		// A zero TTL is not cached, so only coalescing spares later lookups a query.
+		return reply{answers: []dnsmessage.Resource{rrA("api.test.", "192.0.2.1", 0)}}
+	})
+	r := testResolver(t, srv)
+
+	const callers = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for range callers {
+		wg.Go(func() {
+			addrs, err := r.LookupNetIP(context.Background(), "ip4", "api.test")
+			if err == nil && len(addrs) != 1 {
+				err = fmt.Errorf("got %v", addrs)
+			}
+			errs <- err
+		})
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(srv.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if got := len(srv.received()); got != 1 {
+		t.Errorf("%d queries for concurrent lookups, want 1", got)
+	}
+}
+
+func TestLookupCanceledCaller(t *testing.T) {
+	release := make(chan struct{})
+	srv := newDNSServer(t, func(dnsmessage.Question) reply {
+		<-release
+		return reply{answers: []dnsmessage.Resource{rrA("api.test.", "192.0.2.1", 60)}}
+	})
+	r := testResolver(t, srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.LookupNetIP(ctx, "ip4", "api.test")
+		done <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(srv.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) && !strings.Contains(err.Error(), "canceled") {
+		t.Errorf("canceled LookupNetIP error = %v", err)
+	}
+
+	// The query goes on for the callers still waiting, and its answer is cached.
+	go close(release)
+	addrs, err := r.LookupNetIP(context.Background(), "ip4", "api.test")
+	if err != nil || len(addrs) != 1 {
+		t.Fatalf("LookupNetIP = %v, %v", addrs, err)
+	}
+	if got := len(srv.received()); got != 1 {
+		t.Errorf("%d queries, want the canceled caller's query reused", got)
+	}
+}
+
+func TestEviction(t *testing.T) {
+	srv := newDNSServer(t, zone(60, map[string][]string{
+		"a.test.": {"192.0.2.1"}, "b.test.": {"192.0.2.2"}, "c.test.": {"192.0.2.3"},
+	}))
+	clk := plugintest.NewFakeClock(time.Time{})
+	r := testResolver(t, srv, WithClock(clk), WithMaxEntries(2), WithMaxTTL(time.Hour))
+	lookup := func(host string) {
+		t.Helper()
+		if _, err := r.LookupNetIP(context.Background(), "ip4", host); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lookup("a.test")
+	clk.Advance(time.Minute) // a.test expires.
+	lookup("b.test")
+	lookup("c.test")
+	r.mu.Lock()
+	_, hasA := r.entries[question{name: "a.test.", typ: dnsmessage.TypeA}]
+	size := len(r.entries)
+	r.mu.Unlock()
+	if hasA || size != 2 {
+		t.Errorf("cache has %d entries, a.test cached %t, want the expired entry evicted first", size, hasA)
+	}
+
+	// Without expired entries an arbitrary one makes room.
+	lookup("a.test")
+	r.mu.Lock()
+	size = len(r.entries)
+	r.mu.Unlock()
+	if size != 2 {
+		t.Errorf("cache has %d entries, want at most 2", size)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	srv := newDNSServer(t, zone(60, map[string][]string{"api.test.": {"192.0.2.1"}}))
+	r := testResolver(t, srv)
+
+	for range 2 {
+		if _, err := r.LookupNetIP(context.Background(), "ip4", "api.test"); err != nil {
+			t.Fatal(err)
+		}
+		r.Flush()
+	}
+	if got := len(srv.received()); got != 2 {
+		t.Errorf("%d queries, want one after each Flush", got)
+	}
+}
+
+func TestLookupHost(t *testing.T) {
+	srv := newDNSServer(t, zone(60, map[string][]string{"api.test.": {"192.0.2.1", "2001:db8::1"}}))
+	r := testResolver(t, srv)
+
+	got, err := r.LookupHost(context.Background(), "api.test")
+	if err != nil || !slices.Equal(got, []string{"192.0.2.1", "2001:db8::1"}) {
+		t.Errorf("LookupHost = %v, %v", got, err)
+	}
+	if _, err := r.LookupHost(context.Background(), "missing.test"); err == nil {
+		t.Error("LookupHost succeeded for a missing name")
+	}
+}
+
+func TestDialContext(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	// A closed port makes the first address fail, so the second is dialed.
+	closed, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skip("127.0.0.2 is not available:", err)
+	}
+	_ = closed.Close()
+
+	r := testResolver(t, newDNSServer(t, zone(60, nil)))
+	r.hosts = map[string][]netip.Addr{
+		"svc.test":  {netip.MustParseAddr("127.0.0.2"), netip.MustParseAddr("127.0.0.1")},
+		"down.test": {netip.MustParseAddr("127.0.0.2")},
+	}
+	dial := r.DialContext(nil)
+
+	conn, err := dial(context.Background(), "tcp", net.JoinHostPort("svc.test", port))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	if got := conn.RemoteAddr().String(); got != ln.Addr().String() {
+		t.Errorf("dialed %s, want %s", got, ln.Addr())
+	}
+	_ = conn.Close()
+
+	tests := []struct {
+		name    string
+		network string
+		addr    string
+		want    string
+	}{
+		{name: "missing port", network: "tcp", addr: "svc.test", want: "missing port"},
+		{name: "unknown host", network: "tcp", addr: "missing.test:80", want: "no such host"},
+		{name: "no address of the family", network: "tcp6", addr: "down.test:80", want: "no such host"},
+		{name: "every address refused", network: "tcp4", addr: "down.test:" + port, want: "refused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := dial(context.Background(), tt.network, tt.addr)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("dial error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestShared(t *testing.T) {
+	if Shared() == nil || Shared() != Shared() {
+		t.Error("Shared does not return one Resolver")
+	}
+}
@@ -0,0 +1,129 @@
+package dnscache
+
+import (
+	"bufio"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Files read by New for the system's resolver configuration.
+const (
+	resolvConfPath = "/etc/resolv.conf"
+	hostsPath      = "/etc/hosts"
+)
+
+// resolvConf is the subset of resolv.conf(5) the resolver honours.
+type resolvConf struct {
+	servers  []string // host:port
+	search   []string // Without trailing dots.
+	ndots    int
+	timeout  time.Duration
+	attempts int
+}
+
+// readResolvConf parses path, falling back to a local server and the glibc defaults for what it
+// does not set.
+func readResolvConf(path string) resolvConf {
+	conf := resolvConf{ndots: 1, timeout: 5 * time.Second, attempts: 2}
+
+	f, err := os.Open(path)
+	if err == nil {
+		defer f.Close()
+
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			fields := strings.Fields(sc.Text())
+			if len(fields) < 2 || fields[0][0] == '#' || fields[0][0] == ';' {
+				continue
+			}
+			switch fields[0] {
+			case "nameserver":
+				if _, err := netip.ParseAddr(fields[1]); err == nil {
+					conf.servers = append(conf.servers, net.JoinHostPort(fields[1], "53"))
+				}
+			case "domain", "search":
+				conf.search = conf.search[:0]
+				for _, d := range fields[1:] {
+					if d = strings.TrimSuffix(d, "."); d != "" {
+						conf.search = append(conf.search, d)
+					}
+				}
+			case "options":
+				for _, opt := range fields[1:] {
+					name, value, _ := strings.Cut(opt, ":")
+					n, err := strconv.Atoi(value)
+					if err != nil || n < 1 {
+						continue
+					}
+					switch name {
+					case "ndots":
+						conf.ndots = min(n, 15)
+					case "timeout":
+						conf.timeout = time.Duration(min(n, 30)) * time.Second
+					case "attempts":
+						conf.attempts = min(n, 5)
+					}
+				}
+			}
+		}
+	}
+	if len(conf.servers) == 0 {
+		conf.servers = []string{"127.0.0.1:53", "[::1]:53"}
+	}
+
+	return conf
+}
+
+// readHosts parses a hosts(5) file into addresses by lower-case name.
+func readHosts(path string) map[string][]netip.Addr {
+	hosts := map[string][]netip.Addr{}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return hosts
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		addr, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			continue
+		}
+		for _, name := range fields[1:] {
+			name = strings.ToLower(strings.TrimSuffix(name, "."))
+			hosts[name] = append(hosts[name], addr.Unmap())
+		}
+	}
+
+	return hosts
+}
+
+// candidates returns the fully qualified names to try for name, in order, applying the search
+// list as the system resolver does.
+func (c *resolvConf) candidates(name string) []string {
+	if strings.HasSuffix(name, ".") {
+		return []string{name}
+	}
+	var out []string
+	if strings.Count(name, ".") >= c.ndots {
+		out = append(out, name+".")
+	}
+	for _, d := range c.search {
+		out = append(out, name+"."+d+".")
+	}
+	if strings.Count(name, ".") < c.ndots {
+		out = append(out, name+".")
+	}
+
+	return out
+}
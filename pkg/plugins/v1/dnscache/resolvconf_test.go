@@ -0,0 +1,165 @@
+package dnscache
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "conf")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestReadResolvConf(t *testing.T) {
+	defaults := resolvConf{
+		servers:  []string{"127.0.0.1:53", "[::1]:53"},
+		ndots:    1,
+		timeout:  5 * time.Second,
+		attempts: 2,
+	}
+	tests := []struct {
+		name    string
+		content string // Empty reads a missing file.
+		want    resolvConf
+	}{
+		{name: "missing file", want: defaults},
+		{
+			name: "Kubernetes pod",
+			content: `# Generated
+nameserver 10.96.0.10
+search default.svc.cluster.local svc.cluster.local cluster.local.
+options ndots:5 timeout:2 attempts:3
+`,
+			want: resolvConf{
+				servers:  []string{"10.96.0.10:53"},
+				search:   []string{"default.svc.cluster.local", "svc.cluster.local", "cluster.local"},
+				ndots:    5,
+				timeout:  2 * time.Second,
+				attempts: 3,
+			},
+		},
+		{
+			name: "IPv6 and invalid servers",
+			content: `nameserver 2001:db8::53
+nameserver dns.example.com
+; nameserver 192.0.2.1
+nameserver 192.0.2.53
+`,
+			want: resolvConf{
+				servers:  []string{"[2001:db8::53]:53", "192.0.2.53:53"},
+				ndots:    1,
+				timeout:  5 * time.Second,
+				attempts: 2,
+			},
+		},
+		{
+			name:    "last search line wins",
+			content: "domain corp.example.com\nsearch a.example b.example\n",
+			want: resolvConf{
+				servers:  defaults.servers,
+				search:   []string{"a.example", "b.example"},
+				ndots:    1,
+				timeout:  5 * time.Second,
+				attempts: 2,
+			},
+		},
+		{
+			name:    "options clamped and invalid ones ignored",
+			content: "options ndots:99 timeout:600 attempts:10 rotate edns0\noptions ndots:0 timeout:x\n",
+			want: resolvConf{
+				servers:  defaults.servers,
+				ndots:    15,
+				timeout:  30 * time.Second,
+				attempts: 5,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "missing")
+			if tt.content != "" {
+				path = writeFile(t, tt.content)
+			}
+			got := readResolvConf(path)
+			if !slices.Equal(got.servers, tt.want.servers) || !slices.Equal(got.search, tt.want.search) ||
+				got.ndots != tt.want.ndots || got.timeout != tt.want.timeout || got.attempts != tt.want.attempts {
+				t.Errorf("readResolvConf = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadHosts(t *testing.T) {
+	path := writeFile(t, `127.0.0.1 localhost
+::1       localhost ip6-localhost # loopback
+192.0.2.5 Printer.LAN. printer
+::ffff:192.0.2.6 mapped
+not-an-ip broken
+192.0.2.7
+# 192.0.2.8 commented
+`)
+	got := readHosts(path)
+	want := map[string][]string{
+		"localhost":     {"127.0.0.1", "::1"},
+		"ip6-localhost": {"::1"},
+		"printer.lan":   {"192.0.2.5"},
+		"printer":       {"192.0.2.5"},
+		"mapped":        {"192.0.2.6"},
+	}
+	if len(got) != len(want) {
+		t.Errorf("readHosts = %v, want %v", got, want)
+	}
+	for name, addrs := range want {
+		if !slices.Equal(strs(got[name]), addrs) {
+			t.Errorf("hosts[%s] = %v, want %v", name, got[name], addrs)
+		}
+	}
+
+	if got := readHosts(filepath.Join(t.TempDir(), "missing")); got == nil || len(got) != 0 {
+		t.Errorf("readHosts of a missing file = %v, want an empty map", got)
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		name   string
+		host   string
+		search []string
+		ndots  int
+		want   []string
+	}{
+		{name: "fully qualified", host: "api.test.", search: []string{"corp"}, ndots: 5, want: []string{"api.test."}},
+		{name: "no search list", host: "api", ndots: 1, want: []string{"api."}},
+		{
+			name:   "few dots",
+			host:   "api",
+			search: []string{"ns.svc.cluster.local", "cluster.local"},
+			ndots:  5,
+			want:   []string{"api.ns.svc.cluster.local.", "api.cluster.local.", "api."},
+		},
+		{
+			name:   "enough dots",
+			host:   "api.example.com",
+			search: []string{"corp"},
+			ndots:  2,
+			want:   []string{"api.example.com.", "api.example.com.corp."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &resolvConf{search: tt.search, ndots: tt.ndots}
+			if got := c.candidates(tt.host); !slices.Equal(got, tt.want) {
+				t.Errorf("candidates(%s) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
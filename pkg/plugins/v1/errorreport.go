@@ -0,0 +1,103 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"runtime/debug"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorReport describes a handler failure passed to an ErrorReporter.
+type ErrorReport struct {
+	// Err is the error returned by the handler, or the error Serve returned for a panic.
+	Err error
+
+	// Panic is the value recovered from a panicking handler, or nil for a returned error.
+	Panic any
+
+	// Stack is the goroutine stack captured when a panic was recovered.
+	Stack []byte
+
+	// Method is the short RPC name (e.g. "HandleRequest").
+	Method string
+
+	// Request is the input of a failed HandleRequest call.
+	Request *HTTPRequest
+
+	// Response is the input of a failed HandleResponse call.
+	Response *HTTPResponse
+
+	// CorrelationID ties the failure to the client request (see CorrelationID).
+	CorrelationID string
+}
+
+// ErrorReporter receives handler errors and recovered panics, for forwarding to error tracking
+// services such as Sentry or Rollbar. Report is called on the RPC goroutine and must not block.
+type ErrorReporter interface {
+	Report(ctx context.Context, r ErrorReport)
+}
+
+// ErrorReporterFunc adapts a function to the ErrorReporter interface.
+type ErrorReporterFunc func(ctx context.Context, r ErrorReport)
+
+// Report calls f(ctx, r).
+func (f ErrorReporterFunc) Report(ctx context.Context, r ErrorReport) {
+	f(ctx, r)
+}
+
+// NopErrorReporter returns an ErrorReporter that discards every report.
+func NopErrorReporter() ErrorReporter {
+	return ErrorReporterFunc(func(context.Context, ErrorReport) {})
+}
+
+// WithErrorReporter makes Serve pass every handler error and recovered panic to r.
+// Panics in handlers are always recovered and returned to mcpd as codes.Internal;
+// without this option they are only logged.
+func WithErrorReporter(r ErrorReporter) ServeOption {
+	return func(o *serveOptions) error {
+		if r == nil {
+			return fmt.Errorf("error reporter cannot be nil")
+		}
+		o.reporter = r
+		return nil
+	}
+}
+
+// recoveryInterceptor converts handler panics into codes.Internal errors and reports both
+// panics and returned errors to reporter.
+func recoveryInterceptor(o *serveOptions) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (resp any, err error) {
+		report := ErrorReport{Method: path.Base(info.FullMethod)}
+		switch in := req.(type) {
+		case *HTTPRequest:
+			report.Request = in
+		case *HTTPResponse:
+			report.Response = in
+		}
+
+		defer func() {
+			if p := recover(); p != nil {
+				report.Panic = p
+				report.Stack = debug.Stack()
+				resp, err = nil, status.Errorf(codes.Internal, "plugin panicked handling %s", report.Method)
+				o.logger.Printf("recovered panic in %s: %v\n%s", report.Method, p, report.Stack)
+			}
+			if err != nil {
+				report.Err = err
+				report.CorrelationID = CorrelationID(ctx, report.Request)
+				o.reporter.Report(ctx, report)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,134 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecoveryInterceptor(t *testing.T) {
+	errBoom := errors.New("boom")
+	errInvalid := status.Error(codes.InvalidArgument, "bad")
+	req := &HTTPRequest{Headers: map[string]string{"X-Request-Id": "req-1"}}
+	resp := &HTTPResponse{StatusCode: 200}
+	tests := []struct {
+		name       string
+		method     string
+		in         any
+		handler    grpc.UnaryHandler
+		wantCode   codes.Code // codes.OK for no error.
+		wantReport bool
+		check      func(t *testing.T, r ErrorReport)
+	}{
+		{
+			name:    "success is not reported",
+			method:  Plugin_HandleRequest_FullMethodName,
+			in:      req,
+			handler: func(context.Context, any) (any, error) { return &HTTPResponse{Continue: true}, nil },
+		},
+		{
+			name:       "returned error",
+			method:     Plugin_HandleRequest_FullMethodName,
+			in:         req,
+			handler:    func(context.Context, any) (any, error) { return nil, errBoom },
+			wantCode:   codes.Unknown,
+			wantReport: true,
+			check: func(t *testing.T, r ErrorReport) {
+				if !errors.Is(r.Err, errBoom) || r.Panic != nil || r.Stack != nil {
+					t.Errorf("report = %+v, want the returned error without a panic", r)
+				}
+				if r.Method != "HandleRequest" || r.Request != req || r.CorrelationID != "req-1" {
+					t.Errorf("report = %+v, want the HandleRequest input and correlation ID", r)
+				}
+			},
+		},
+		{
+			name:       "panic",
+			method:     Plugin_HandleResponse_FullMethodName,
+			in:         resp,
+			handler:    func(context.Context, any) (any, error) { panic("kaboom") },
+			wantCode:   codes.Internal,
+			wantReport: true,
+			check: func(t *testing.T, r ErrorReport) {
+				if r.Panic != "kaboom" || status.Code(r.Err) != codes.Internal {
+					t.Errorf("report = %+v, want the panic value and an Internal error", r)
+				}
+				if !bytes.Contains(r.Stack, []byte("errorreport_test.go")) {
+					t.Errorf("report stack does not include the panicking handler:\n%s", r.Stack)
+				}
+				if r.Method != "HandleResponse" || r.Response != resp || r.Request != nil {
+					t.Errorf("report = %+v, want the HandleResponse input", r)
+				}
+			},
+		},
+		{
+			name:       "other RPCs",
+			method:     Plugin_Configure_FullMethodName,
+			in:         &PluginConfig{},
+			handler:    func(context.Context, any) (any, error) { return nil, errInvalid },
+			wantCode:   codes.InvalidArgument,
+			wantReport: true,
+			check: func(t *testing.T, r ErrorReport) {
+				if r.Method != "Configure" || r.Request != nil || r.Response != nil {
+					t.Errorf("report = %+v, want only the method", r)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reports []ErrorReport
+			reporter := ErrorReporterFunc(func(_ context.Context, r ErrorReport) { reports = append(reports, r) })
+			o, err := newServeOptions(WithErrorReporter(reporter), WithLogger(discardLogger()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = recoveryInterceptor(o)(context.Background(), tt.in, &grpc.UnaryServerInfo{FullMethod: tt.method},
+				tt.handler)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("error = %v, want code %s", err, tt.wantCode)
+			}
+			if got := len(reports) == 1; got != tt.wantReport || len(reports) > 1 {
+				t.Fatalf("%d reports, want reported %v", len(reports), tt.wantReport)
+			}
+			if tt.check != nil {
+				tt.check(t, reports[0])
+			}
+		})
+	}
+}
+
+func TestRecoveryInterceptorLogsPanics(t *testing.T) {
+	var buf bytes.Buffer
+	o, err := newServeOptions(WithLogger(log.New(&buf, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = recoveryInterceptor(o)(context.Background(), &HTTPRequest{},
+		&grpc.UnaryServerInfo{FullMethod: Plugin_HandleRequest_FullMethodName},
+		func(context.Context, any) (any, error) { panic("kaboom") })
+	if status.Code(err) != codes.Internal || !strings.Contains(err.Error(), "plugin panicked handling HandleRequest") {
+		t.Errorf("error = %v, want an Internal panic error", err)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, "recovered panic in HandleRequest: kaboom\n") {
+		t.Errorf("logged %q, want the recovered panic", got)
+	}
+}
+
+func TestWithErrorReporterNil(t *testing.T) {
+	if _, err := newServeOptions(WithErrorReporter(nil)); err == nil {
+		t.Error("WithErrorReporter accepted a nil reporter")
+	}
+}
+
+func TestNopErrorReporter(t *testing.T) {
+	NopErrorReporter().Report(context.Background(), ErrorReport{Err: errors.New("ignored")})
+}
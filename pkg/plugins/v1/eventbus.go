@@ -0,0 +1,180 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// EventKind classifies events published on an EventBus.
+type EventKind string
+
+const (
+	// EventLifecycle events report server lifecycle transitions (see Phase).
+	EventLifecycle EventKind = "lifecycle"
+
+	// EventRequest events are published after every plugin RPC completes, successfully or not.
+	EventRequest EventKind = "request"
+
+	// EventError events are published when a plugin RPC returns an error.
+	EventError EventKind = "error"
+)
+
+// Phase is a server lifecycle stage reported by EventLifecycle events.
+type Phase string
+
+const (
+	// PhaseStarting is published before the listener is created.
+	PhaseStarting Phase = "starting"
+
+	// PhaseServing is published once the server is listening.
+	PhaseServing Phase = "serving"
+
+	// PhaseStopping is published when shutdown begins.
+	PhaseStopping Phase = "stopping"
+
+	// PhaseStopped is published after the server has stopped serving.
+	PhaseStopped Phase = "stopped"
+)
+
+// Event is a notification published by the SDK on an EventBus.
+// Fields that do not apply to the event's kind are left at their zero value.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+
+	// Phase is set for EventLifecycle events.
+	Phase Phase
+
+	// Network and Address describe the listener for EventLifecycle events.
+	Network string
+	Address string
+
+	// Method is the short RPC name (e.g. "HandleRequest") for EventRequest and EventError events.
+	Method string
+
+	// Duration is how long the RPC handler took.
+	Duration time.Duration
+
+	// Err is the error returned by the RPC handler, if any.
+	Err error
+
+	// Request is the input of a HandleRequest call.
+	Request *HTTPRequest
+
+	// Response is the input of a HandleResponse call.
+	Response *HTTPResponse
+
+	// Result is the output of a HandleRequest or HandleResponse call.
+	Result *HTTPResponse
+}
+
+// Verdict summarises the outcome of a HandleRequest or HandleResponse event as one of
+// metrics.VerdictContinue, metrics.VerdictShortCircuit, or metrics.VerdictError.
+// It returns an empty string for other events.
+func (ev Event) Verdict() string {
+	switch {
+	case ev.Request == nil && ev.Response == nil:
+		return ""
+	case ev.Err != nil:
+		return metrics.VerdictError
+	case ev.Result != nil && !ev.Result.GetContinue():
+		return metrics.VerdictShortCircuit
+	default:
+		return metrics.VerdictContinue
+	}
+}
+
+// EventHandler receives events from an EventBus. Handlers run synchronously on the publishing
+// goroutine, which for request events is the RPC goroutine, so they must return quickly and
+// hand off any slow work.
+type EventHandler func(ctx context.Context, ev Event)
+
+// EventBus fans SDK events out to subscribers. It is safe for concurrent use.
+//
+// Observability add-ons (metrics, audit, webhooks) attach to the bus rather than to Serve itself:
+//
+//	bus := mcpdpluginsv1.NewEventBus()
+//	bus.Subscribe(func(ctx context.Context, ev mcpdpluginsv1.Event) {
+//	    log.Printf("%s took %s", ev.Method, ev.Duration)
+//	}, mcpdpluginsv1.EventRequest)
+//
+//	err := mcpdpluginsv1.Serve(&MyPlugin{}, mcpdpluginsv1.WithEventBus(bus))
+type EventBus struct {
+	mu   sync.RWMutex
+	next uint64
+	subs map[uint64]subscription
+}
+
+type subscription struct {
+	handler EventHandler
+	kinds   map[EventKind]struct{}
+}
+
+// NewEventBus returns an empty EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{subs: map[uint64]subscription{}}
+}
+
+// Subscribe registers h for events of the given kinds, or for all events when no kinds are given.
+// The returned function removes the subscription.
+func (b *EventBus) Subscribe(h EventHandler, kinds ...EventKind) func() {
+	s := subscription{handler: h}
+	if len(kinds) > 0 {
+		s.kinds = make(map[EventKind]struct{}, len(kinds))
+		for _, k := range kinds {
+			s.kinds[k] = struct{}{}
+		}
+	}
+
+	b.mu.Lock()
+	id := b.next
+	b.next++
+	b.subs[id] = s
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// Publish delivers ev to every matching subscriber. A zero Time is set to the current time.
+// A panicking subscriber is logged and does not prevent delivery to the others.
+func (b *EventBus) Publish(ctx context.Context, ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := make([]EventHandler, 0, len(b.subs))
+	for _, s := range b.subs {
+		if s.kinds != nil {
+			if _, ok := s.kinds[ev.Kind]; !ok {
+				continue
+			}
+		}
+		handlers = append(handlers, s.handler)
+	}
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		deliverEvent(ctx, h, ev)
+	}
+}
+
+func deliverEvent(ctx context.Context, h EventHandler, ev Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("event subscriber panicked handling %s event: %v", ev.Kind, r)
+		}
+	}()
+	h(ctx, ev)
+}
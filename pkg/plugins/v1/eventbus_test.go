@@ -0,0 +1,325 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// eventLog is an EventHandler recording the events it receives.
+type eventLog struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (l *eventLog) handle(_ context.Context, ev Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.events = append(l.events, ev)
+}
+
+func (l *eventLog) kinds() []EventKind {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	out := make([]EventKind, len(l.events))
+	for i, ev := range l.events {
+		out[i] = ev.Kind
+	}
+
+	return out
+}
+
+func TestEventBusSubscribe(t *testing.T) {
+	tests := []struct {
+		name  string
+		kinds []EventKind
+		want  []EventKind
+	}{
+		{name: "all kinds", want: []EventKind{EventLifecycle, EventRequest, EventError}},
+		{name: "one kind", kinds: []EventKind{EventError}, want: []EventKind{EventError}},
+		{
+			name:  "several kinds",
+			kinds: []EventKind{EventLifecycle, EventError},
+			want:  []EventKind{EventLifecycle, EventError},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := NewEventBus()
+			rec := &eventLog{}
+			bus.Subscribe(rec.handle, tt.kinds...)
+			for _, k := range []EventKind{EventLifecycle, EventRequest, EventError} {
+				bus.Publish(context.Background(), Event{Kind: k})
+			}
+			if got := rec.kinds(); !slices.Equal(got, tt.want) {
+				t.Errorf("received %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	bus := NewEventBus()
+	kept, removed := &eventLog{}, &eventLog{}
+	bus.Subscribe(kept.handle)
+	unsubscribe := bus.Subscribe(removed.handle)
+
+	bus.Publish(context.Background(), Event{Kind: EventRequest})
+	unsubscribe()
+	unsubscribe()
+	bus.Publish(context.Background(), Event{Kind: EventRequest})
+
+	if len(kept.kinds()) != 2 || len(removed.kinds()) != 1 {
+		t.Errorf("kept received %d events and removed %d, want 2 and 1", len(kept.kinds()), len(removed.kinds()))
+	}
+}
+
+func TestEventBusPublish(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	bus := NewEventBus()
+	rec := &eventLog{}
+	bus.Subscribe(func(context.Context, Event) { panic("subscriber bug") })
+	bus.Subscribe(rec.handle)
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	bus.Publish(context.Background(), Event{Kind: EventRequest})
+	bus.Publish(context.Background(), Event{Kind: EventRequest, Time: at})
+
+	if len(rec.events) != 2 {
+		t.Fatalf("received %d events after a panicking subscriber, want 2", len(rec.events))
+	}
+	if rec.events[0].Time.IsZero() {
+		t.Error("Publish left the time unset")
+	}
+	if !rec.events[1].Time.Equal(at) {
+		t.Errorf("Time = %s, want the published %s", rec.events[1].Time, at)
+	}
+}
+
+func TestEventVerdict(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   Event
+		want string
+	}{
+		{name: "lifecycle", ev: Event{Kind: EventLifecycle}},
+		{
+			name: "request continued",
+			ev:   Event{Request: &HTTPRequest{}, Result: &HTTPResponse{Continue: true}},
+			want: metrics.VerdictContinue,
+		},
+		{
+			name: "request short-circuited",
+			ev:   Event{Request: &HTTPRequest{}, Result: &HTTPResponse{}},
+			want: metrics.VerdictShortCircuit,
+		},
+		{name: "response without a result", ev: Event{Response: &HTTPResponse{}}, want: metrics.VerdictContinue},
+		{name: "error", ev: Event{Response: &HTTPResponse{}, Err: errors.New("boom")}, want: metrics.VerdictError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ev.Verdict(); got != tt.want {
+				t.Errorf("Verdict = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventInterceptor(t *testing.T) {
+	errHandler := errors.New("handler failed")
+	tests := []struct {
+		name       string
+		method     string
+		req        any
+		resp       any
+		err        error
+		wantMethod string
+		wantKinds  []EventKind
+		wantResult bool
+	}{
+		{
+			name:       "request",
+			method:     Plugin_HandleRequest_FullMethodName,
+			wantMethod: "HandleRequest",
+			req:        &HTTPRequest{Path: "/mcp"},
+			resp:       &HTTPResponse{Continue: true},
+			wantKinds:  []EventKind{EventRequest},
+			wantResult: true,
+		},
+		{
+			name:       "response",
+			method:     Plugin_HandleResponse_FullMethodName,
+			wantMethod: "HandleResponse",
+			req:        &HTTPResponse{StatusCode: 200},
+			resp:       &HTTPResponse{Continue: true},
+			wantKinds:  []EventKind{EventRequest},
+			wantResult: true,
+		},
+		{
+			name:       "failure",
+			method:     Plugin_HandleRequest_FullMethodName,
+			wantMethod: "HandleRequest",
+			req:        &HTTPRequest{},
+			resp:       &HTTPResponse{Continue: true},
+			err:        errHandler,
+			wantKinds:  []EventKind{EventRequest, EventError},
+		},
+		{
+			name:       "other RPC",
+			method:     Plugin_CheckHealth_FullMethodName,
+			wantMethod: "CheckHealth",
+			wantKinds:  []EventKind{EventRequest},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := NewEventBus()
+			rec := &eventLog{}
+			bus.Subscribe(rec.handle)
+
+			handler := func(context.Context, any) (any, error) {
+				time.Sleep(time.Millisecond)
+				return tt.resp, tt.err
+			}
+			resp, err := eventInterceptor(bus)(context.Background(), tt.req,
+				&grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if resp != tt.resp || !errors.Is(err, tt.err) {
+				t.Errorf("interceptor returned %v, %v; want the handler's %v, %v", resp, err, tt.resp, tt.err)
+			}
+
+			if got := rec.kinds(); !slices.Equal(got, tt.wantKinds) {
+				t.Fatalf("published %v, want %v", got, tt.wantKinds)
+			}
+			for _, ev := range rec.events {
+				if ev.Method != tt.wantMethod || ev.Duration < time.Millisecond ||
+					!errors.Is(ev.Err, tt.err) {
+					t.Errorf("event = %+v, want method, duration and the handler's error", ev)
+				}
+				if req, ok := tt.req.(*HTTPRequest); ok && ev.Request != req {
+					t.Errorf("Request = %v, want the RPC input", ev.Request)
+				}
+				if rsp, ok := tt.req.(*HTTPResponse); ok && ev.Response != rsp {
+					t.Errorf("Response = %v, want the RPC input", ev.Response)
+				}
+				if (ev.Result != nil) != tt.wantResult {
+					t.Errorf("Result = %v, want it set %v", ev.Result, tt.wantResult)
+				}
+			}
+		})
+	}
+}
+
+func TestChainInterceptors(t *testing.T) {
+	var order []string
+	trace := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			order = append(order, name+" in")
+			resp, err := handler(ctx, req)
+			order = append(order, name+" out")
+			return resp, err
+		}
+	}
+
+	chain := chainInterceptors([]grpc.UnaryServerInterceptor{trace("a"), trace("b")})
+	handler := func(_ context.Context, req any) (any, error) {
+		order = append(order, "handler")
+		return req, nil
+	}
+	resp, err := chain(context.Background(), "req", &grpc.UnaryServerInfo{}, handler)
+	if resp != "req" || err != nil {
+		t.Errorf("chain returned %v, %v; want the handler's result", resp, err)
+	}
+	if want := []string{"a in", "b in", "handler", "b out", "a out"}; !slices.Equal(order, want) {
+		t.Errorf("call order = %v, want %v", order, want)
+	}
+}
+
+func TestEventServeOptions(t *testing.T) {
+	bus := NewEventBus()
+	interceptor := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+		return h(ctx, req)
+	}
+
+	tests := []struct {
+		name    string
+		opts    []ServeOption
+		wantErr bool
+	}{
+		{name: "nil bus", opts: []ServeOption{WithEventBus(nil)}, wantErr: true},
+		{name: "two buses", opts: []ServeOption{WithEventBus(bus), WithEventBus(NewEventBus())}, wantErr: true},
+		{name: "same bus twice", opts: []ServeOption{WithEventBus(bus), WithEventBus(bus)}},
+		{name: "nil subscriber", opts: []ServeOption{WithEventSubscriber(nil)}, wantErr: true},
+		{name: "nil interceptor", opts: []ServeOption{WithUnaryInterceptor(nil)}, wantErr: true},
+		{name: "interceptor", opts: []ServeOption{WithUnaryInterceptor(interceptor)}},
+		{name: "nil option ignored", opts: []ServeOption{nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newServeOptions(tt.opts...); (err != nil) != tt.wantErr {
+				t.Errorf("newServeOptions error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEventSubscriberOrder(t *testing.T) {
+	// Subscribers reach the configured bus whether they come before or after WithEventBus.
+	for _, subscriberFirst := range []bool{true, false} {
+		bus := NewEventBus()
+		rec := &eventLog{}
+		opts := []ServeOption{WithEventBus(bus), WithEventSubscriber(rec.handle, EventLifecycle)}
+		if subscriberFirst {
+			opts[0], opts[1] = opts[1], opts[0]
+		}
+		if _, err := newServeOptions(opts...); err != nil {
+			t.Fatal(err)
+		}
+		bus.Publish(context.Background(), Event{Kind: EventLifecycle, Phase: PhaseServing})
+		if got := rec.kinds(); len(got) != 1 {
+			t.Errorf("subscriber first %v: received %v, want one lifecycle event", subscriberFirst, got)
+		}
+	}
+}
+
+func TestServeChainCustomInterceptorsRunInside(t *testing.T) {
+	bus := NewEventBus()
+	var published bool
+	bus.Subscribe(func(context.Context, Event) { published = true }, EventRequest)
+	var publishedBefore bool
+	custom := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+		publishedBefore = published
+		return h(ctx, req)
+	}
+	o, err := newServeOptions(WithEventBus(bus), WithUnaryInterceptor(custom))
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptors, err := o.unaryInterceptors(&BasePlugin{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = chainInterceptors(interceptors)(context.Background(), &HTTPRequest{},
+		&grpc.UnaryServerInfo{FullMethod: Plugin_HandleRequest_FullMethodName},
+		func(context.Context, any) (any, error) { return &HTTPResponse{Continue: true}, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publishedBefore || !published {
+		t.Errorf("request event published before the custom interceptor ran: %v, after: %v", publishedBefore, published)
+	}
+}
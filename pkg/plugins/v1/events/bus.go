@@ -0,0 +1,26 @@
+package events
+
+import (
+	"context"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// Attach forwards SDK bus events to d: requests short-circuited by the plugin become
+// TypeRequestDenied events. The returned function detaches the dispatcher.
+//
+// Usage:
+//
+//	bus := mcpdpluginsv1.NewEventBus()
+//	detach := events.Attach(bus, dispatcher)
+//	defer detach()
+//
+//	err := mcpdpluginsv1.Serve(&MyPlugin{}, mcpdpluginsv1.WithEventBus(bus))
+func Attach(bus *mcpdpluginsv1.EventBus, d *Dispatcher) func() {
+	return bus.Subscribe(func(_ context.Context, ev mcpdpluginsv1.Event) {
+		if ev.Request == nil || ev.Result == nil || ev.Result.GetContinue() {
+			return
+		}
+		d.Emit(RequestDenied(ev.Request, int(ev.Result.GetStatusCode()), "request short-circuited by plugin"))
+	}, mcpdpluginsv1.EventRequest)
+}
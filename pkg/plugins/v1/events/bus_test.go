@@ -0,0 +1,100 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/events"
+)
+
+func TestAttach(t *testing.T) {
+	req := &mcpdpluginsv1.HTTPRequest{Method: "POST", Path: "/mcp"}
+	tests := []struct {
+		name string
+		ev   mcpdpluginsv1.Event
+		want int
+	}{
+		{
+			name: "short-circuited request",
+			ev: mcpdpluginsv1.Event{
+				Kind:    mcpdpluginsv1.EventRequest,
+				Request: req,
+				Result:  &mcpdpluginsv1.HTTPResponse{StatusCode: 403},
+			},
+			want: 1,
+		},
+		{
+			name: "continued request",
+			ev: mcpdpluginsv1.Event{
+				Kind:    mcpdpluginsv1.EventRequest,
+				Request: req,
+				Result:  &mcpdpluginsv1.HTTPResponse{Continue: true},
+			},
+		},
+		{
+			name: "failed request",
+			ev:   mcpdpluginsv1.Event{Kind: mcpdpluginsv1.EventRequest, Request: req},
+		},
+		{
+			name: "short-circuited response",
+			ev: mcpdpluginsv1.Event{
+				Kind:     mcpdpluginsv1.EventRequest,
+				Response: &mcpdpluginsv1.HTTPResponse{},
+				Result:   &mcpdpluginsv1.HTTPResponse{StatusCode: 500},
+			},
+		},
+		{
+			name: "other kinds",
+			ev: mcpdpluginsv1.Event{
+				Kind:    mcpdpluginsv1.EventError,
+				Request: req,
+				Result:  &mcpdpluginsv1.HTTPResponse{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &recordingSink{}
+			d := newDispatcher(t, []events.Sink{sink})
+			bus := mcpdpluginsv1.NewEventBus()
+			events.Attach(bus, d)
+
+			bus.Publish(context.Background(), tt.ev)
+			if err := d.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := sink.delivered(); got != tt.want {
+				t.Fatalf("%d events delivered, want %d", got, tt.want)
+			}
+			if tt.want == 0 {
+				return
+			}
+			ev := sink.batches[0][0]
+			if ev.Type != events.TypeRequestDenied || ev.Attributes["status_code"] != "403" ||
+				ev.Attributes["path"] != "/mcp" {
+				t.Errorf("event = %+v, want a request.denied event for the request", ev)
+			}
+		})
+	}
+}
+
+func TestAttachDetach(t *testing.T) {
+	sink := &recordingSink{}
+	d := newDispatcher(t, []events.Sink{sink})
+	bus := mcpdpluginsv1.NewEventBus()
+	detach := events.Attach(bus, d)
+	detach()
+
+	bus.Publish(context.Background(), mcpdpluginsv1.Event{
+		Kind:    mcpdpluginsv1.EventRequest,
+		Request: &mcpdpluginsv1.HTTPRequest{},
+		Result:  &mcpdpluginsv1.HTTPResponse{StatusCode: 403},
+	})
+	if err := d.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := sink.delivered(); got != 0 {
+		t.Errorf("%d events delivered after detaching, want 0", got)
+	}
+}
@@ -0,0 +1,295 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults used by NewDispatcher.
+const (
+	DefaultBatchSize     = 50
+	DefaultFlushInterval = 5 * time.Second
+	DefaultQueueSize     = 1024
+	DefaultMaxAttempts   = 5
+	DefaultRetryBackoff  = 500 * time.Millisecond
+)
+
+// maxRetryBackoff caps the exponential delay between delivery attempts.
+const maxRetryBackoff = 30 * time.Second
+
+// Dispatcher buffers emitted events and delivers them in batches to every configured sink,
+// retrying failed deliveries with exponential backoff. It is safe for concurrent use.
+type Dispatcher struct {
+	sinks         []Sink
+	source        string
+	batchSize     int
+	flushInterval time.Duration
+	maxAttempts   int
+	backoff       time.Duration
+	onError       func(error)
+
+	queue   chan Event
+	flushCh chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+
+	// mu makes closing done and queueing an event mutually exclusive, so that no event can be
+	// queued after run has drained the queue for the last time.
+	mu        sync.RWMutex
+	closeOnce sync.Once
+	dropped   atomic.Int64
+}
+
+// DispatcherOption configures a Dispatcher.
+type DispatcherOption func(*Dispatcher) error
+
+// WithSource sets the Source stamped on events that do not carry one (typically the plugin name).
+func WithSource(source string) DispatcherOption {
+	return func(d *Dispatcher) error {
+		d.source = source
+		return nil
+	}
+}
+
+// WithBatchSize sets the maximum number of events delivered per batch.
+func WithBatchSize(n int) DispatcherOption {
+	return func(d *Dispatcher) error {
+		if n <= 0 {
+			return fmt.Errorf("batch size must be positive")
+		}
+		d.batchSize = n
+		return nil
+	}
+}
+
+// WithFlushInterval sets how long events may wait before a partial batch is delivered.
+func WithFlushInterval(interval time.Duration) DispatcherOption {
+	return func(d *Dispatcher) error {
+		if interval <= 0 {
+			return fmt.Errorf("flush interval must be positive")
+		}
+		d.flushInterval = interval
+		return nil
+	}
+}
+
+// WithQueueSize sets how many events may be buffered before Emit starts dropping them.
+func WithQueueSize(n int) DispatcherOption {
+	return func(d *Dispatcher) error {
+		if n <= 0 {
+			return fmt.Errorf("queue size must be positive")
+		}
+		d.queue = make(chan Event, n)
+		return nil
+	}
+}
+
+// WithRetry sets the number of delivery attempts per batch and sink, and the initial backoff
+// between attempts (doubled after each failure).
+func WithRetry(maxAttempts int, backoff time.Duration) DispatcherOption {
+	return func(d *Dispatcher) error {
+		if maxAttempts <= 0 {
+			return fmt.Errorf("max attempts must be positive")
+		}
+		if backoff < 0 {
+			return fmt.Errorf("backoff cannot be negative")
+		}
+		d.maxAttempts = maxAttempts
+		d.backoff = backoff
+		return nil
+	}
+}
+
+// WithErrorHandler sets a function called when a batch could not be delivered to a sink.
+func WithErrorHandler(fn func(error)) DispatcherOption {
+	return func(d *Dispatcher) error {
+		d.onError = fn
+		return nil
+	}
+}
+
+// NewDispatcher starts a Dispatcher delivering to sinks. Call Close to flush and stop it.
+func NewDispatcher(sinks []Sink, opts ...DispatcherOption) (*Dispatcher, error) {
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("at least one sink is required")
+	}
+
+	d := &Dispatcher{
+		sinks:         sinks,
+		batchSize:     DefaultBatchSize,
+		flushInterval: DefaultFlushInterval,
+		maxAttempts:   DefaultMaxAttempts,
+		backoff:       DefaultRetryBackoff,
+		onError:       func(error) {},
+		queue:         make(chan Event, DefaultQueueSize),
+		flushCh:       make(chan chan struct{}),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if err := opt(d); err != nil {
+			return nil, err
+		}
+	}
+
+	go d.run()
+
+	return d, nil
+}
+
+// Emit queues ev for delivery without blocking. If the queue is full, or the dispatcher is closed,
+// the event is dropped and counted (see Dropped).
+func (d *Dispatcher) Emit(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	if ev.Source == "" {
+		ev.Source = d.source
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	select {
+	case <-d.done:
+		d.dropped.Add(1)
+		return
+	default:
+	}
+
+	select {
+	case d.queue <- ev:
+	default:
+		d.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of events discarded because the queue was full or closed.
+func (d *Dispatcher) Dropped() int64 {
+	return d.dropped.Load()
+}
+
+// Flush delivers all queued events and waits for delivery to finish or ctx to end.
+func (d *Dispatcher) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case d.flushCh <- ack:
+	case <-d.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting events, delivers what is queued and waits for that to finish or ctx to end.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.closeOnce.Do(func() {
+		d.mu.Lock()
+		close(d.done)
+		d.mu.Unlock()
+	})
+
+	select {
+	case <-d.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.stopped)
+
+	ticker := time.NewTicker(d.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, d.batchSize)
+	deliver := func() {
+		if len(batch) == 0 {
+			return
+		}
+		d.deliver(batch)
+		batch = make([]Event, 0, d.batchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case ev := <-d.queue:
+				batch = append(batch, ev)
+				if len(batch) >= d.batchSize {
+					deliver()
+				}
+			default:
+				deliver()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case ev := <-d.queue:
+			batch = append(batch, ev)
+			if len(batch) >= d.batchSize {
+				deliver()
+			}
+		case <-ticker.C:
+			deliver()
+		case ack := <-d.flushCh:
+			drain()
+			close(ack)
+		case <-d.done:
+			drain()
+			return
+		}
+	}
+}
+
+// deliver sends batch to every sink, retrying each independently.
+func (d *Dispatcher) deliver(batch []Event) {
+	var wg sync.WaitGroup
+	for _, s := range d.sinks {
+		wg.Add(1)
+		go func(s Sink) {
+			defer wg.Done()
+			if err := d.sendWithRetry(s, batch); err != nil {
+				d.onError(err)
+			}
+		}(s)
+	}
+	wg.Wait()
+}
+
+func (d *Dispatcher) sendWithRetry(s Sink, batch []Event) error {
+	delay := d.backoff
+	var err error
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultSinkTimeout)
+		err = s.Send(ctx, batch)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		var perm *errPermanent
+		if errors.As(err, &perm) || attempt == d.maxAttempts {
+			break
+		}
+
+		time.Sleep(delay)
+		delay = min(delay*2, maxRetryBackoff)
+	}
+
+	return fmt.Errorf("failed to deliver %d event(s): %w", len(batch), err)
+}
@@ -0,0 +1,297 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/events"
+)
+
+// recordingSink records the batches it receives, failing the first failures calls with err.
+type recordingSink struct {
+	mu       sync.Mutex
+	batches  [][]events.Event
+	failures int
+	err      error
+	calls    int
+}
+
+func (s *recordingSink) Send(_ context.Context, batch []events.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.calls <= s.failures {
+		return s.err
+	}
+	s.batches = append(s.batches, append([]events.Event(nil), batch...))
+
+	return nil
+}
+
+// sizes returns the sizes of the batches received.
+func (s *recordingSink) sizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]int, len(s.batches))
+	for i, b := range s.batches {
+		out[i] = len(b)
+	}
+
+	return out
+}
+
+func (s *recordingSink) delivered() int {
+	n := 0
+	for _, size := range s.sizes() {
+		n += size
+	}
+
+	return n
+}
+
+func TestNewDispatcherErrors(t *testing.T) {
+	sinks := []events.Sink{&recordingSink{}}
+	tests := []struct {
+		name  string
+		sinks []events.Sink
+		opt   events.DispatcherOption
+	}{
+		{name: "no sinks", opt: events.WithSource("x")},
+		{name: "zero batch size", sinks: sinks, opt: events.WithBatchSize(0)},
+		{name: "zero flush interval", sinks: sinks, opt: events.WithFlushInterval(0)},
+		{name: "zero queue size", sinks: sinks, opt: events.WithQueueSize(0)},
+		{name: "zero attempts", sinks: sinks, opt: events.WithRetry(0, time.Second)},
+		{name: "negative backoff", sinks: sinks, opt: events.WithRetry(1, -time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := events.NewDispatcher(tt.sinks, tt.opt); err == nil {
+				t.Error("NewDispatcher succeeded")
+			}
+		})
+	}
+}
+
+// newDispatcher returns a Dispatcher closed when t ends, which never flushes on its own.
+func newDispatcher(t *testing.T, sinks []events.Sink, opts ...events.DispatcherOption) *events.Dispatcher {
+	t.Helper()
+
+	opts = append([]events.DispatcherOption{events.WithFlushInterval(time.Hour)}, opts...)
+	d, err := events.NewDispatcher(sinks, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = d.Close(context.Background()) })
+
+	return d
+}
+
+func TestDispatcherBatching(t *testing.T) {
+	tests := []struct {
+		name      string
+		emit      int
+		batchSize int
+		want      []int
+	}{
+		{name: "partial batch delivered on flush", emit: 3, batchSize: 10, want: []int{3}},
+		{name: "full batches delivered as they fill", emit: 5, batchSize: 2, want: []int{2, 2, 1}},
+		{name: "nothing to flush", emit: 0, batchSize: 2, want: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &recordingSink{}
+			d := newDispatcher(t, []events.Sink{sink}, events.WithBatchSize(tt.batchSize))
+			for i := range tt.emit {
+				d.Emit(events.New(events.TypeConfigReloaded, fmt.Sprint(i), nil))
+			}
+			if err := d.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := sink.sizes(); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("batch sizes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDispatcherFlushInterval(t *testing.T) {
+	sink := &recordingSink{}
+	d := newDispatcher(t, []events.Sink{sink}, events.WithFlushInterval(10*time.Millisecond))
+	d.Emit(events.ConfigReloaded("reloaded"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sink.delivered() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("event not delivered after the flush interval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDispatcherStampsEvents(t *testing.T) {
+	sink := &recordingSink{}
+	d := newDispatcher(t, []events.Sink{sink}, events.WithSource("my-plugin"))
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	d.Emit(events.Event{Type: events.TypeHealthChanged})
+	d.Emit(events.Event{Type: events.TypeHealthChanged, Time: at, Source: "other"})
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := sink.batches[0]
+	if got[0].Source != "my-plugin" || got[0].Time.IsZero() {
+		t.Errorf("event = %+v, want the dispatcher source and a time", got[0])
+	}
+	if got[1].Source != "other" || !got[1].Time.Equal(at) {
+		t.Errorf("event = %+v, want its own source and time kept", got[1])
+	}
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	errTransient := errors.New("unavailable")
+	tests := []struct {
+		name      string
+		sink      *recordingSink
+		attempts  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{
+			name:      "succeeds after retries",
+			sink:      &recordingSink{failures: 2, err: errTransient},
+			attempts:  3,
+			wantCalls: 3,
+		},
+		{
+			name:      "gives up",
+			sink:      &recordingSink{failures: 5, err: errTransient},
+			attempts:  3,
+			wantCalls: 3,
+			wantErr:   true,
+		},
+		{
+			name:      "permanent failures are not retried",
+			sink:      &recordingSink{failures: 5, err: permanentError(t)},
+			attempts:  3,
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs atomic.Int64
+			d := newDispatcher(t, []events.Sink{tt.sink},
+				events.WithRetry(tt.attempts, time.Millisecond),
+				events.WithErrorHandler(func(error) { errs.Add(1) }))
+			d.Emit(events.ConfigReloaded("reloaded"))
+			if err := d.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if tt.sink.calls != tt.wantCalls {
+				t.Errorf("Send called %d times, want %d", tt.sink.calls, tt.wantCalls)
+			}
+			if got := errs.Load() == 1; got != tt.wantErr {
+				t.Errorf("error handler called %d times, wantErr %v", errs.Load(), tt.wantErr)
+			}
+		})
+	}
+}
+
+// permanentError returns the error a WebhookSink reports for a rejected delivery.
+func permanentError(t *testing.T) error {
+	t.Helper()
+
+	err := events.NewWebhookSink("http://[::1", nil).Send(context.Background(), nil)
+	if err == nil {
+		t.Fatal("Send to an invalid URL succeeded")
+	}
+
+	return err
+}
+
+func TestDispatcherDrops(t *testing.T) {
+	blocked := make(chan struct{})
+	sink := events.SinkFunc(func(context.Context, []events.Event) error {
+		<-blocked
+		return nil
+	})
+	d := newDispatcher(t, []events.Sink{sink}, events.WithQueueSize(1), events.WithBatchSize(1))
+
+	// The first event is taken by the blocked delivery, the second fills the queue.
+	d.Emit(events.ConfigReloaded("1"))
+	deadline := time.Now().Add(5 * time.Second)
+	for d.Emit(events.ConfigReloaded("2")); d.Dropped() == 0; d.Emit(events.ConfigReloaded("3")) {
+		if time.Now().After(deadline) {
+			t.Fatal("no event dropped with a full queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(blocked)
+
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	before := d.Dropped()
+	d.Emit(events.ConfigReloaded("after close"))
+	if d.Dropped() != before+1 {
+		t.Errorf("Dropped = %d after emitting to a closed dispatcher, want %d", d.Dropped(), before+1)
+	}
+	if err := d.Flush(context.Background()); err != nil {
+		t.Errorf("Flush after Close: %v", err)
+	}
+}
+
+func TestDispatcherEmitRacingClose(t *testing.T) {
+	for range 100 {
+		sink := &recordingSink{}
+		d := newDispatcher(t, []events.Sink{sink}, events.WithQueueSize(4096))
+
+		const emitters, perEmitter = 8, 50
+		var wg sync.WaitGroup
+		for range emitters {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range perEmitter {
+					d.Emit(events.ConfigReloaded("x"))
+				}
+			}()
+		}
+		if err := d.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		wg.Wait()
+
+		if got := int64(sink.delivered()) + d.Dropped(); got != emitters*perEmitter {
+			t.Fatalf("%d events delivered and %d dropped, want %d accounted for",
+				sink.delivered(), d.Dropped(), emitters*perEmitter)
+		}
+	}
+}
+
+func TestDispatcherCloseTimeout(t *testing.T) {
+	blocked := make(chan struct{})
+	defer close(blocked)
+	sink := events.SinkFunc(func(context.Context, []events.Event) error {
+		<-blocked
+		return nil
+	})
+	d, err := events.NewDispatcher([]events.Sink{sink})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Emit(events.ConfigReloaded("x"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := d.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
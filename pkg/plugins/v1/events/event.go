@@ -0,0 +1,97 @@
+// Package events lets plugins emit typed operational events (denied requests, config reloads,
+// health changes) and delivers them to webhook-style sinks with batching and retry.
+//
+// Usage:
+//
+//	d, err := events.NewDispatcher([]events.Sink{events.NewWebhookSink(url, nil)})
+//	if err != nil {
+//	    return err
+//	}
+//	defer func() { _ = d.Close(context.Background()) }()
+//
+//	d.Emit(events.RequestDenied(req, http.StatusForbidden, "missing API key"))
+package events
+
+import (
+	"strconv"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/recordenc"
+)
+
+// Type identifies the kind of an Event.
+type Type string
+
+// Built-in event types.
+const (
+	// TypeRequestDenied is emitted when a plugin short-circuits a request.
+	TypeRequestDenied Type = "request.denied"
+
+	// TypeConfigReloaded is emitted when a plugin applies a new configuration.
+	TypeConfigReloaded Type = "config.reloaded"
+
+	// TypeHealthChanged is emitted when a plugin's health or readiness changes.
+	TypeHealthChanged Type = "health.changed"
+)
+
+// Event is a single occurrence reported by a plugin.
+type Event struct {
+	Type       Type              `json:"type"`
+	Time       time.Time         `json:"time"`
+	Source     string            `json:"source,omitempty"`
+	Message    string            `json:"message,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Record returns e as a recordenc.Record, for encoders. Denied requests and health changes are
+// warnings; other events are informational.
+func (e Event) Record() recordenc.Record {
+	sev := recordenc.SeverityInfo
+	if e.Type == TypeRequestDenied || e.Type == TypeHealthChanged {
+		sev = recordenc.SeverityWarning
+	}
+
+	return recordenc.Record{
+		Type:       string(e.Type),
+		Time:       e.Time,
+		Severity:   sev,
+		Source:     e.Source,
+		Message:    e.Message,
+		Attributes: e.Attributes,
+	}
+}
+
+// New returns an event of the given type stamped with the current time.
+func New(typ Type, message string, attrs map[string]string) Event {
+	return Event{
+		Type:       typ,
+		Time:       time.Now().UTC(),
+		Message:    message,
+		Attributes: attrs,
+	}
+}
+
+// RequestDenied returns a TypeRequestDenied event describing req and the verdict.
+func RequestDenied(req *mcpdpluginsv1.HTTPRequest, statusCode int, reason string) Event {
+	return New(TypeRequestDenied, reason, map[string]string{
+		"method":      req.GetMethod(),
+		"path":        req.GetPath(),
+		"remote_addr": req.GetRemoteAddr(),
+		"status_code": strconv.Itoa(statusCode),
+	})
+}
+
+// ConfigReloaded returns a TypeConfigReloaded event.
+func ConfigReloaded(message string) Event {
+	return New(TypeConfigReloaded, message, nil)
+}
+
+// HealthChanged returns a TypeHealthChanged event for a transition between two states
+// (e.g. "healthy" to "unhealthy").
+func HealthChanged(from string, to string, reason string) Event {
+	return New(TypeHealthChanged, reason, map[string]string{
+		"from": from,
+		"to":   to,
+	})
+}
@@ -0,0 +1,102 @@
+package events_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/events"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/recordenc"
+)
+
+func TestConstructors(t *testing.T) {
+	tests := []struct {
+		name        string
+		event       events.Event
+		wantType    events.Type
+		wantMessage string
+		wantAttrs   map[string]string
+	}{
+		{
+			name: "request denied",
+			event: events.RequestDenied(&mcpdpluginsv1.HTTPRequest{
+				Method:     "POST",
+				Path:       "/mcp",
+				RemoteAddr: "10.0.0.1:5",
+			}, 403, "missing API key"),
+			wantType:    events.TypeRequestDenied,
+			wantMessage: "missing API key",
+			wantAttrs: map[string]string{
+				"method":      "POST",
+				"path":        "/mcp",
+				"remote_addr": "10.0.0.1:5",
+				"status_code": "403",
+			},
+		},
+		{
+			name:        "request denied without a request",
+			event:       events.RequestDenied(nil, 401, "no"),
+			wantType:    events.TypeRequestDenied,
+			wantMessage: "no",
+			wantAttrs:   map[string]string{"method": "", "path": "", "remote_addr": "", "status_code": "401"},
+		},
+		{
+			name:        "config reloaded",
+			event:       events.ConfigReloaded("v2"),
+			wantType:    events.TypeConfigReloaded,
+			wantMessage: "v2",
+		},
+		{
+			name:        "health changed",
+			event:       events.HealthChanged("healthy", "unhealthy", "db down"),
+			wantType:    events.TypeHealthChanged,
+			wantMessage: "db down",
+			wantAttrs:   map[string]string{"from": "healthy", "to": "unhealthy"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := tt.event
+			if ev.Type != tt.wantType || ev.Message != tt.wantMessage ||
+				!reflect.DeepEqual(ev.Attributes, tt.wantAttrs) {
+				t.Errorf("event = %+v, want type %s, message %q and attributes %v",
+					ev, tt.wantType, tt.wantMessage, tt.wantAttrs)
+			}
+			if ev.Time.Location() != time.UTC || time.Since(ev.Time) > time.Minute {
+				t.Errorf("Time = %s, want the current UTC time", ev.Time)
+			}
+		})
+	}
+}
+
+func TestEventRecord(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	attrs := map[string]string{"k": "v"}
+	tests := []struct {
+		name         string
+		typ          events.Type
+		wantSeverity recordenc.Severity
+	}{
+		{name: "request denied", typ: events.TypeRequestDenied, wantSeverity: recordenc.SeverityWarning},
+		{name: "health changed", typ: events.TypeHealthChanged, wantSeverity: recordenc.SeverityWarning},
+		{name: "config reloaded", typ: events.TypeConfigReloaded, wantSeverity: recordenc.SeverityInfo},
+		{name: "custom type", typ: "plugin.started", wantSeverity: recordenc.SeverityInfo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := events.Event{Type: tt.typ, Time: at, Source: "p", Message: "m", Attributes: attrs}
+			want := recordenc.Record{
+				Type:       string(tt.typ),
+				Time:       at,
+				Severity:   tt.wantSeverity,
+				Source:     "p",
+				Message:    "m",
+				Attributes: attrs,
+			}
+			if got := ev.Record(); !reflect.DeepEqual(got, want) {
+				t.Errorf("Record = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
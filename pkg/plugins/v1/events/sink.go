@@ -0,0 +1,242 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/recordenc"
+)
+
+// Sink delivers a batch of events to a destination.
+// Send must be safe to call again with the same batch after a failure.
+type Sink interface {
+	Send(ctx context.Context, batch []Event) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(ctx context.Context, batch []Event) error
+
+// Send calls f(ctx, batch).
+func (f SinkFunc) Send(ctx context.Context, batch []Event) error {
+	return f(ctx, batch)
+}
+
+// defaultSinkTimeout bounds a single HTTP delivery attempt.
+const defaultSinkTimeout = 10 * time.Second
+
+// WebhookSink posts batches as JSON ({"events": [...]}) to a URL, or in the encoding of the
+// encoder set with WithEncoder.
+type WebhookSink struct {
+	url     string
+	client  *http.Client
+	headers map[string]string
+	encoder recordenc.Encoder
+}
+
+// NewWebhookSink returns a sink posting to url. Extra headers (e.g. an Authorization header)
+// are added to every request.
+func NewWebhookSink(url string, headers map[string]string) *WebhookSink {
+	return &WebhookSink{
+		url:     url,
+		client:  &http.Client{Timeout: defaultSinkTimeout},
+		headers: headers,
+	}
+}
+
+// WithClient replaces the HTTP client used for delivery.
+func (s *WebhookSink) WithClient(c *http.Client) *WebhookSink {
+	s.client = c
+	return s
+}
+
+// WithEncoder posts batches in enc's encoding, with its content type, such as
+// recordenc.OTLPLogs for an OpenTelemetry collector's /v1/logs endpoint.
+func (s *WebhookSink) WithEncoder(enc recordenc.Encoder) *WebhookSink {
+	s.encoder = enc
+	return s
+}
+
+// Send implements Sink.
+func (s *WebhookSink) Send(ctx context.Context, batch []Event) error {
+	if s.encoder != nil {
+		payload, err := s.encoder.Encode(records(batch))
+		if err != nil {
+			return &errPermanent{fmt.Errorf("failed to encode events: %w", err)}
+		}
+		return post(ctx, s.client, s.url, s.encoder.ContentType(), s.headers, payload)
+	}
+
+	payload, err := json.Marshal(struct {
+		Events []Event `json:"events"`
+	}{Events: batch})
+	if err != nil {
+		return fmt.Errorf("failed to encode events: %w", err)
+	}
+
+	return post(ctx, s.client, s.url, "application/json", s.headers, payload)
+}
+
+// WriterSink writes batches to an io.Writer, such as a file shipped to a SIEM, in the encoding of
+// an Encoder. Text encodings are written followed by a newline; binary ones, such as
+// recordenc.Protobuf, prefixed with their varint-encoded length, the usual protobuf stream framing.
+type WriterSink struct {
+	encoder recordenc.Encoder
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a sink writing batches encoded by enc to w.
+func NewWriterSink(w io.Writer, enc recordenc.Encoder) *WriterSink {
+	return &WriterSink{w: w, encoder: enc}
+}
+
+// Send implements Sink.
+func (s *WriterSink) Send(_ context.Context, batch []Event) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	payload, err := s.encoder.Encode(records(batch))
+	if err != nil {
+		return &errPermanent{fmt.Errorf("failed to encode events: %w", err)}
+	}
+	if recordenc.Text(s.encoder) {
+		payload = append(payload, '\n')
+	} else {
+		payload = append(binary.AppendUvarint(nil, uint64(len(payload))), payload...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.w.Write(payload); err != nil {
+		return fmt.Errorf("failed to write events: %w", err)
+	}
+
+	return nil
+}
+
+// records converts a batch to records for encoders.
+func records(batch []Event) []recordenc.Record {
+	out := make([]recordenc.Record, len(batch))
+	for i, ev := range batch {
+		out[i] = ev.Record()
+	}
+
+	return out
+}
+
+// SlackSink posts batches as a single message to a Slack-compatible incoming webhook.
+type SlackSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewSlackSink returns a sink posting to a Slack-compatible incoming webhook URL.
+func NewSlackSink(url string) *SlackSink {
+	return &SlackSink{
+		url:    url,
+		client: &http.Client{Timeout: defaultSinkTimeout},
+	}
+}
+
+// WithClient replaces the HTTP client used for delivery.
+func (s *SlackSink) WithClient(c *http.Client) *SlackSink {
+	s.client = c
+	return s
+}
+
+// Send implements Sink.
+func (s *SlackSink) Send(ctx context.Context, batch []Event) error {
+	var b strings.Builder
+	for i, ev := range batch {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(formatSlackLine(ev))
+	}
+
+	payload, err := json.Marshal(map[string]string{"text": b.String()})
+	if err != nil {
+		return fmt.Errorf("failed to encode Slack message: %w", err)
+	}
+
+	return post(ctx, s.client, s.url, "application/json", nil, payload)
+}
+
+func formatSlackLine(ev Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s*", ev.Type)
+	if ev.Source != "" {
+		fmt.Fprintf(&b, " [%s]", ev.Source)
+	}
+	if ev.Message != "" {
+		fmt.Fprintf(&b, " %s", ev.Message)
+	}
+
+	keys := make([]string, 0, len(ev.Attributes))
+	for k := range ev.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " `%s=%s`", k, ev.Attributes[k])
+	}
+
+	return b.String()
+}
+
+// errPermanent marks delivery failures that retrying cannot fix.
+type errPermanent struct {
+	err error
+}
+
+func (e *errPermanent) Error() string {
+	return e.err.Error()
+}
+
+func (e *errPermanent) Unwrap() error {
+	return e.err
+}
+
+func post(
+	ctx context.Context,
+	client *http.Client,
+	url, contentType string,
+	headers map[string]string,
+	payload []byte,
+) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return &errPermanent{fmt.Errorf("failed to create request: %w", err)}
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post events to %s: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("event sink %s returned %s", url, resp.Status)
+	default:
+		return &errPermanent{fmt.Errorf("event sink %s returned %s", url, resp.Status)}
+	}
+}
@@ -0,0 +1,379 @@
+package events_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/events"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/recordenc"
+)
+
+// webhook is an HTTP endpoint answering every request with status, recording the last one.
+type webhook struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	status int
+	header http.Header
+	body   []byte
+	calls  int
+}
+
+func newWebhook(t *testing.T, status int) *webhook {
+	t.Helper()
+
+	w := &webhook{status: status}
+	w.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.mu.Lock()
+		w.header, w.body = r.Header, body
+		w.calls++
+		status := w.status
+		w.mu.Unlock()
+		rw.WriteHeader(status)
+	}))
+	t.Cleanup(w.Close)
+
+	return w
+}
+
+func TestWebhookSink(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	batch := []events.Event{{Type: events.TypeConfigReloaded, Time: at, Source: "p", Message: "reloaded"}}
+
+	tests := []struct {
+		name          string
+		status        int
+		wantErr       bool
+		wantPermanent bool
+	}{
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "rate limited is retried", status: http.StatusTooManyRequests, wantErr: true},
+		{name: "server error is retried", status: http.StatusBadGateway, wantErr: true},
+		{name: "client error is permanent", status: http.StatusUnauthorized, wantErr: true, wantPermanent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := newWebhook(t, tt.status)
+			sink := events.NewWebhookSink(hook.URL, map[string]string{"Authorization": "Bearer t"})
+
+			err := sink.Send(context.Background(), batch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				checkRetried(t, sink, batch, hook, tt.wantPermanent)
+				return
+			}
+
+			if got := hook.header.Get("Authorization"); got != "Bearer t" {
+				t.Errorf("Authorization = %q, want the configured header", got)
+			}
+			if got := hook.header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+			var payload struct{ Events []events.Event }
+			if err := json.Unmarshal(hook.body, &payload); err != nil {
+				t.Fatalf("payload %s: %v", hook.body, err)
+			}
+			if len(payload.Events) != 1 || payload.Events[0].Message != "reloaded" ||
+				!payload.Events[0].Time.Equal(at) {
+				t.Errorf("payload = %s, want the batch", hook.body)
+			}
+		})
+	}
+}
+
+// checkRetried checks how often a Dispatcher retries delivering batch to sink, which fails
+// permanently or not.
+func checkRetried(t *testing.T, sink events.Sink, batch []events.Event, hook *webhook, permanent bool) {
+	t.Helper()
+
+	var last error
+	d, err := events.NewDispatcher([]events.Sink{sink},
+		events.WithRetry(3, 0), events.WithErrorHandler(func(err error) { last = err }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook.mu.Lock()
+	hook.calls = 0
+	hook.mu.Unlock()
+
+	d.Emit(batch[0])
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := 3
+	if permanent {
+		want = 1
+	}
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	if hook.calls != want {
+		t.Errorf("endpoint called %d times, want %d", hook.calls, want)
+	}
+	if last == nil {
+		t.Error("delivery failure not reported")
+	}
+}
+
+func TestWebhookSinkUnreachable(t *testing.T) {
+	hook := newWebhook(t, http.StatusOK)
+	hook.Close()
+
+	err := events.NewWebhookSink(hook.URL, nil).Send(context.Background(), nil)
+	if err == nil {
+		t.Fatal("Send to a closed endpoint succeeded")
+	}
+}
+
+func TestWebhookSinkWithClient(t *testing.T) {
+	errTransport := errors.New("no network")
+	client := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errTransport
+	})}
+
+	err := events.NewWebhookSink("http://example.com", nil).WithClient(client).Send(context.Background(), nil)
+	if !errors.Is(err, errTransport) {
+		t.Errorf("Send error = %v, want it to wrap the client's error", err)
+	}
+}
+
+// failingEncoder fails every encoding.
+type failingEncoder struct{}
+
+func (failingEncoder) ContentType() string { return recordenc.ContentTypeJSONLines }
+
+func (failingEncoder) Encode([]recordenc.Record) ([]byte, error) {
+	return nil, errors.New("unencodable")
+}
+
+// deliveries returns how often a Dispatcher retrying three times calls sink to deliver batch.
+func deliveries(t *testing.T, sink events.Sink, batch []events.Event) int {
+	t.Helper()
+
+	var calls int
+	counted := events.SinkFunc(func(ctx context.Context, b []events.Event) error {
+		calls++
+		return sink.Send(ctx, b)
+	})
+	d, err := events.NewDispatcher([]events.Sink{counted},
+		events.WithRetry(3, 0), events.WithErrorHandler(func(error) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range batch {
+		d.Emit(ev)
+	}
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return calls
+}
+
+func TestWebhookSinkWithEncoder(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	batch := []events.Event{
+		{Type: events.TypeConfigReloaded, Time: at, Source: "p", Message: "reloaded"},
+		{Type: events.TypeRequestDenied, Time: at, Source: "p", Message: "denied"},
+	}
+	tests := []struct {
+		name     string
+		enc      recordenc.Encoder
+		wantType string
+	}{
+		{name: "JSON lines", enc: recordenc.JSON(), wantType: recordenc.ContentTypeJSONLines},
+		{name: "CEF", enc: recordenc.CEF("", "", ""), wantType: recordenc.ContentTypeCEF},
+		{name: "OTLP", enc: recordenc.OTLPLogs(map[string]string{"service.name": "p"}), wantType: "application/json"},
+		{name: "protobuf", enc: recordenc.Protobuf(), wantType: recordenc.ContentTypeProtobuf},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := newWebhook(t, http.StatusOK)
+			sink := events.NewWebhookSink(hook.URL, map[string]string{"Authorization": "Bearer t"}).WithEncoder(tt.enc)
+			if err := sink.Send(context.Background(), batch); err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := tt.enc.Encode([]recordenc.Record{batch[0].Record(), batch[1].Record()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			hook.mu.Lock()
+			defer hook.mu.Unlock()
+			if got := hook.header.Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			if got := hook.header.Get("Authorization"); got != "Bearer t" {
+				t.Errorf("Authorization = %q, want the configured header", got)
+			}
+			if !bytes.Equal(hook.body, want) {
+				t.Errorf("payload = %q, want %q", hook.body, want)
+			}
+		})
+	}
+}
+
+func TestWebhookSinkEncodeError(t *testing.T) {
+	hook := newWebhook(t, http.StatusOK)
+	sink := events.NewWebhookSink(hook.URL, nil).WithEncoder(failingEncoder{})
+	batch := []events.Event{events.ConfigReloaded("v2")}
+
+	err := sink.Send(context.Background(), batch)
+	if err == nil || !strings.Contains(err.Error(), "failed to encode events: unencodable") {
+		t.Errorf("Send error = %v, want the encoding failure", err)
+	}
+	if got := deliveries(t, sink, batch); got != 1 {
+		t.Errorf("sink called %d times, want the encoding failure not retried", got)
+	}
+	if hook.calls != 0 {
+		t.Errorf("endpoint called %d times, want nothing posted", hook.calls)
+	}
+}
+
+func TestWriterSink(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	batch := []events.Event{
+		{Type: events.TypeConfigReloaded, Time: at, Message: "a"},
+		{Type: events.TypeConfigReloaded, Time: at, Message: "b"},
+	}
+	tests := []struct {
+		name string
+		enc  recordenc.Encoder
+		// frame returns what a batch encoded as payload is written as.
+		frame func(payload []byte) []byte
+	}{
+		{
+			name:  "text encodings end with a newline",
+			enc:   recordenc.JSON(),
+			frame: func(p []byte) []byte { return append(p, '\n') },
+		},
+		{
+			name:  "CEF",
+			enc:   recordenc.CEF("", "", ""),
+			frame: func(p []byte) []byte { return append(p, '\n') },
+		},
+		{
+			name:  "binary encodings are length-prefixed",
+			enc:   recordenc.Protobuf(),
+			frame: func(p []byte) []byte { return append(binary.AppendUvarint(nil, uint64(len(p))), p...) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			sink := events.NewWriterSink(&buf, tt.enc)
+			if err := sink.Send(context.Background(), batch[:1]); err != nil {
+				t.Fatal(err)
+			}
+			if err := sink.Send(context.Background(), nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := sink.Send(context.Background(), batch); err != nil {
+				t.Fatal(err)
+			}
+
+			first, _ := tt.enc.Encode([]recordenc.Record{batch[0].Record()})
+			both, _ := tt.enc.Encode([]recordenc.Record{batch[0].Record(), batch[1].Record()})
+			want := append(tt.frame(first), tt.frame(both)...)
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("written = %q, want %q", buf.Bytes(), want)
+			}
+		})
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestWriterSinkErrors(t *testing.T) {
+	batch := []events.Event{events.ConfigReloaded("v2")}
+	tests := []struct {
+		name          string
+		sink          events.Sink
+		wantErr       string
+		wantDelivered int
+	}{
+		{
+			name:          "write error is retried",
+			sink:          events.NewWriterSink(failingWriter{}, recordenc.JSON()),
+			wantErr:       "failed to write events: disk full",
+			wantDelivered: 3,
+		},
+		{
+			name:          "encode error is permanent",
+			sink:          events.NewWriterSink(io.Discard, failingEncoder{}),
+			wantErr:       "failed to encode events: unencodable",
+			wantDelivered: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.sink.Send(context.Background(), batch)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Send error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if got := deliveries(t, tt.sink, batch); got != tt.wantDelivered {
+				t.Errorf("sink called %d times, want %d", got, tt.wantDelivered)
+			}
+		})
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestSlackSink(t *testing.T) {
+	tests := []struct {
+		name  string
+		batch []events.Event
+		want  string
+	}{
+		{
+			name: "one line per event",
+			batch: []events.Event{
+				{Type: events.TypeRequestDenied, Source: "auth", Message: "missing key", Attributes: map[string]string{
+					"status_code": "401",
+					"path":        "/mcp",
+				}},
+				{Type: events.TypeConfigReloaded},
+			},
+			want: "*request.denied* [auth] missing key `path=/mcp` `status_code=401`\n*config.reloaded*",
+		},
+		{
+			name:  "no source or message",
+			batch: []events.Event{{Type: events.TypeHealthChanged, Attributes: map[string]string{"to": "ready"}}},
+			want:  "*health.changed* `to=ready`",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := newWebhook(t, http.StatusOK)
+			sink := events.NewSlackSink(hook.URL).WithClient(hook.Client())
+			if err := sink.Send(context.Background(), tt.batch); err != nil {
+				t.Fatal(err)
+			}
+			var msg struct{ Text string }
+			if err := json.Unmarshal(hook.body, &msg); err != nil {
+				t.Fatal(err)
+			}
+			if msg.Text != tt.want {
+				t.Errorf("text = %q, want %q", msg.Text, tt.want)
+			}
+		})
+	}
+}
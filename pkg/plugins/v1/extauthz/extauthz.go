@@ -0,0 +1,377 @@
+// Package extauthz delegates authorization decisions to an external service, in the style of
+// Envoy's ext_authz filter: each request is summarized as a CheckRequest, sent to the service
+// over HTTP or gRPC, and the returned Decision either lets the request continue (optionally
+// adding headers for the upstream) or short-circuits it with the service's status.
+//
+// Decisions are cached for a short time, and the Authorizer fails closed (denying with 503) when
+// the service cannot be reached unless configured to fail open:
+//
+//	checker, err := extauthz.NewHTTPChecker("https://authz.internal/check", nil)
+//	if err != nil {
+//	    return err
+//	}
+//	authz, err := extauthz.New(checker, extauthz.DefaultConfig())
+//	...
+//	return authz.HandleRequest(ctx, req), nil
+//
+// Plugin runs an Authorizer configured from custom_config.
+package extauthz
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/cache"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// CheckRequest is the summary of a request sent to the authorization service.
+type CheckRequest struct {
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	RemoteAddr string            `json:"remoteAddr,omitempty"`
+	Upstream   string            `json:"upstream,omitempty"`
+	Tenant     string            `json:"tenant,omitempty"`
+
+	// Principal is the caller identified with mcpdpluginsv1.WithIdentity, if any.
+	Principal *mcpdpluginsv1.Principal `json:"principal,omitempty"`
+
+	// MCPMethod is the JSON-RPC method of the body, such as "tools/call", and Tool the name of
+	// the called tool.
+	MCPMethod string `json:"mcpMethod,omitempty"`
+	Tool      string `json:"tool,omitempty"`
+}
+
+// Decision is the authorization service's verdict.
+type Decision struct {
+	// Allowed lets the request continue.
+	Allowed bool `json:"allowed"`
+
+	// StatusCode is returned to the client for denied requests (default 403).
+	StatusCode int `json:"statusCode,omitempty"`
+
+	// Reason is the error message returned to the client for denied requests.
+	Reason string `json:"reason,omitempty"`
+
+	// Headers are added to allowed requests before they reach the upstream, for example to
+	// pass the authenticated identity along.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Checker asks an authorization service for a decision.
+type Checker interface {
+	Check(ctx context.Context, req *CheckRequest) (Decision, error)
+}
+
+// CheckerFunc adapts a function to the Checker interface.
+type CheckerFunc func(ctx context.Context, req *CheckRequest) (Decision, error)
+
+// Check implements Checker.
+func (f CheckerFunc) Check(ctx context.Context, req *CheckRequest) (Decision, error) {
+	return f(ctx, req)
+}
+
+// Config configures an Authorizer, decodable from custom_config with mcpdpluginsv1.DecodeConfig.
+type Config struct {
+	// HTTPURL is the endpoint of an HTTP authorization service (used by Plugin).
+	HTTPURL string `config:"http_url"`
+
+	// GRPCTarget and GRPCMethod locate a gRPC authorization service (used by Plugin when
+	// HTTPURL is empty). See NewGRPCChecker for the expected contract.
+	GRPCTarget string `config:"grpc_target"`
+	GRPCMethod string `config:"grpc_method" default:"/mcpd.authz.v1.Authorization/Check"`
+
+	// IncludeHeaders lists the request headers forwarded in CheckRequest.Headers.
+	IncludeHeaders []string `config:"include_headers" default:"Authorization,Mcp-Session-Id"`
+
+	// Timeout bounds each call to the service.
+	Timeout time.Duration `config:"timeout" default:"2s"`
+
+	// AllowTTL and DenyTTL are how long decisions are cached (zero disables caching).
+	AllowTTL time.Duration `config:"allow_ttl" default:"30s"`
+	DenyTTL  time.Duration `config:"deny_ttl" default:"5s"`
+
+	// CacheSize bounds the number of cached decisions.
+	CacheSize int `config:"cache_size" default:"10000"`
+
+	// FailOpen lets requests continue when the service cannot be reached or errors. By default
+	// they are rejected with 503.
+	FailOpen bool `config:"fail_open" default:"false"`
+
+	// DenyTemplateConfig customizes the response to denied requests. The Denial's Reason is the
+	// decision's.
+	mcpdpluginsv1.DenyTemplateConfig
+}
+
+// DefaultConfig returns the Config with every default applied.
+func DefaultConfig() Config {
+	var cfg Config
+	if _, err := config.Decode(nil, &cfg); err != nil {
+		panic(fmt.Sprintf("extauthz: invalid defaults: %v", err))
+	}
+
+	return cfg
+}
+
+// Authorizer applies the decisions of a Checker to requests. It is safe for concurrent use.
+type Authorizer struct {
+	checker  Checker
+	headers  []string
+	timeout  time.Duration
+	allowTTL time.Duration
+	denyTTL  time.Duration
+	failOpen bool
+	cache    *cache.MemoryBackend
+	logger   *log.Logger
+	denial   *mcpdpluginsv1.DenyTemplate
+}
+
+// New returns an Authorizer asking checker according to cfg. The service location fields of cfg
+// are ignored.
+func New(checker Checker, cfg Config) (*Authorizer, error) {
+	if checker == nil {
+		return nil, fmt.Errorf("checker is required")
+	}
+	if cfg.Timeout < 0 || cfg.AllowTTL < 0 || cfg.DenyTTL < 0 {
+		return nil, fmt.Errorf("timeout and cache TTLs cannot be negative")
+	}
+	denial, err := cfg.Template()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authorizer{
+		checker:  checker,
+		headers:  cfg.IncludeHeaders,
+		timeout:  cfg.Timeout,
+		allowTTL: cfg.AllowTTL,
+		denyTTL:  cfg.DenyTTL,
+		failOpen: cfg.FailOpen,
+		cache:    cache.NewMemoryBackend(cfg.CacheSize),
+		logger:   log.Default(),
+		denial:   denial,
+	}, nil
+}
+
+// Summarize builds the CheckRequest for req.
+func (a *Authorizer) Summarize(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) *CheckRequest {
+	cr := &CheckRequest{
+		Method:     req.GetMethod(),
+		Path:       req.GetPath(),
+		RemoteAddr: req.GetRemoteAddr(),
+		Upstream:   mcpdpluginsv1.Upstream(ctx),
+		Tenant:     mcpdpluginsv1.Tenant(ctx),
+	}
+	cr.Principal, _ = mcpdpluginsv1.Identity(ctx)
+	for _, name := range a.headers {
+		if v := mcpdpluginsv1.GetHeader(req.GetHeaders(), name); v != "" {
+			if cr.Headers == nil {
+				cr.Headers = map[string]string{}
+			}
+			cr.Headers[strings.ToLower(name)] = v
+		}
+	}
+	if m, err := mcp.ParseOne(req.GetBody()); err == nil {
+		cr.MCPMethod = m.Method
+		cr.Tool = m.ToolName()
+	}
+
+	return cr
+}
+
+// Authorize returns the decision for req, from the cache when possible.
+func (a *Authorizer) Authorize(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) (Decision, error) {
+	cr := a.Summarize(ctx, req)
+	key := cacheKey(cr)
+	if raw, ok, _ := a.cache.Get(ctx, key); ok {
+		var d Decision
+		if json.Unmarshal(raw, &d) == nil {
+			return d, nil
+		}
+	}
+
+	checkCtx := ctx
+	if a.timeout > 0 {
+		var cancel context.CancelFunc
+		checkCtx, cancel = context.WithTimeout(ctx, a.timeout)
+		defer cancel()
+	}
+	d, err := a.checker.Check(checkCtx, cr)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	ttl := a.denyTTL
+	if d.Allowed {
+		ttl = a.allowTTL
+	}
+	if ttl > 0 {
+		if raw, err := json.Marshal(d); err == nil {
+			_ = a.cache.Set(ctx, key, raw, ttl)
+		}
+	}
+
+	return d, nil
+}
+
+// HandleRequest lets allowed requests continue, with the decision's headers added, and
+// short-circuits denied ones with the decision's status and a JSON-RPC error. Service errors are
+// logged and handled according to the fail mode.
+func (a *Authorizer) HandleRequest(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) *mcpdpluginsv1.HTTPResponse {
+	d, err := a.Authorize(ctx, req)
+	if err != nil {
+		a.logger.Printf("extauthz: authorization service error: %v", err)
+		if a.failOpen {
+			return &mcpdpluginsv1.HTTPResponse{Continue: true}
+		}
+		d = Decision{StatusCode: http.StatusServiceUnavailable, Reason: "authorization service unavailable"}
+	}
+
+	if d.Allowed {
+		if len(d.Headers) == 0 {
+			return &mcpdpluginsv1.HTTPResponse{Continue: true}
+		}
+		modified := mcpdpluginsv1.CloneRequest(req)
+		headers := make(map[string]string, len(req.GetHeaders())+len(d.Headers))
+		for k, v := range req.GetHeaders() {
+			headers[k] = v
+		}
+		for k, v := range d.Headers {
+			mcpdpluginsv1.SetHeader(headers, k, v)
+		}
+		modified.Headers = headers
+		return &mcpdpluginsv1.HTTPResponse{Continue: true, ModifiedRequest: modified}
+	}
+
+	code := d.StatusCode
+	if code < 400 || code > 599 {
+		code = http.StatusForbidden
+	}
+	reason := d.Reason
+	if reason == "" {
+		reason = "request not authorized"
+	}
+
+	return a.denial.Deny(ctx, req, mcpdpluginsv1.Denial{Reason: reason, Status: code})
+}
+
+func cacheKey(cr *CheckRequest) string {
+	b, _ := json.Marshal(cr)
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Plugin is a request-flow plugin authorizing requests with the service configured in its
+// custom_config. It fails closed, denying every request with 503, until configured with a service.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	authz  atomic.Pointer[Authorizer]
+	closer atomic.Pointer[func() error]
+}
+
+// NewPlugin returns an unconfigured Plugin.
+func NewPlugin() *Plugin {
+	return &Plugin{}
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "ext-authz",
+		Version:     pluginVersion,
+		Description: "Delegates authorization decisions to an external HTTP or gRPC service.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest), nil
+}
+
+// Configure connects to the service configured in cfg's custom_config.
+func (p *Plugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	var c Config
+	if err := mcpdpluginsv1.DecodeConfig(ctx, cfg, &c); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var (
+		checker Checker
+		closer  func() error
+		err     error
+	)
+	switch {
+	case c.HTTPURL != "":
+		checker, err = NewHTTPChecker(c.HTTPURL, nil)
+	case c.GRPCTarget != "":
+		var gc *GRPCChecker
+		gc, err = DialGRPCChecker(c.GRPCTarget, c.GRPCMethod)
+		if gc != nil {
+			checker, closer = gc, gc.Close
+		}
+	default:
+		err = errors.New("one of http_url or grpc_target is required")
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	authz, err := New(checker, c)
+	if err != nil {
+		if closer != nil {
+			_ = closer()
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	p.authz.Store(authz)
+	if old := p.closer.Swap(&closer); old != nil && *old != nil {
+		_ = (*old)()
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// Stop closes the connection to a gRPC service.
+func (p *Plugin) Stop(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	if old := p.closer.Swap(nil); old != nil && *old != nil {
+		_ = (*old)()
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleRequest authorizes req with the configured service, denying it with 503 before Configure
+// succeeds.
+func (p *Plugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	authz := p.authz.Load()
+	if authz == nil {
+		return mcpdpluginsv1.Deny(req, http.StatusServiceUnavailable, mcp.CodeServerError,
+			"authorization service not configured"), nil
+	}
+
+	return authz.HandleRequest(ctx, req), nil
+}
@@ -0,0 +1,459 @@
+package extauthz_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/extauthz"
+)
+
+const toolCall = `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search","arguments":{}}}`
+
+// fakeChecker answers with decision or err, recording the requests it is asked about.
+type fakeChecker struct {
+	mu       sync.Mutex
+	decision extauthz.Decision
+	err      error
+	wait     bool // Blocks until the context is done.
+	requests []*extauthz.CheckRequest
+}
+
+func (c *fakeChecker) Check(ctx context.Context, cr *extauthz.CheckRequest) (extauthz.Decision, error) {
+	c.mu.Lock()
+	c.requests = append(c.requests, cr)
+	d, err, wait := c.decision, c.err, c.wait
+	c.mu.Unlock()
+
+	if wait {
+		<-ctx.Done()
+		return extauthz.Decision{}, ctx.Err()
+	}
+
+	return d, err
+}
+
+func (c *fakeChecker) calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.requests)
+}
+
+func newRequest() *mcpdpluginsv1.HTTPRequest {
+	return &mcpdpluginsv1.HTTPRequest{
+		Method:     http.MethodPost,
+		Path:       "/mcp",
+		RemoteAddr: "192.0.2.7:51234",
+		Headers: map[string]string{
+			"Authorization":  "Bearer abc",
+			"Mcp-Session-Id": "s1",
+			"Cookie":         "secret",
+		},
+		Body: []byte(toolCall),
+	}
+}
+
+func newAuthorizer(t *testing.T, c extauthz.Checker, modify func(*extauthz.Config)) *extauthz.Authorizer {
+	t.Helper()
+
+	cfg := extauthz.DefaultConfig()
+	if modify != nil {
+		modify(&cfg)
+	}
+	a, err := extauthz.New(c, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a
+}
+
+// quietLogs discards the standard logger's output for the duration of the test.
+func quietLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	return &buf
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		checker extauthz.Checker
+		cfg     func(*extauthz.Config)
+		want    string
+	}{
+		{name: "no checker", want: "checker is required"},
+		{name: "negative timeout", cfg: func(c *extauthz.Config) { c.Timeout = -time.Second }, want: "negative"},
+		{name: "negative allow TTL", cfg: func(c *extauthz.Config) { c.AllowTTL = -time.Second }, want: "negative"},
+		{name: "negative deny TTL", cfg: func(c *extauthz.Config) { c.DenyTTL = -time.Second }, want: "negative"},
+		{
+			name: "invalid deny template",
+			cfg:  func(c *extauthz.Config) { c.DenyTemplate = "{{.Reason" },
+			want: "template",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := tt.checker
+			if checker == nil && tt.want != "checker is required" {
+				checker = &fakeChecker{}
+			}
+			cfg := extauthz.DefaultConfig()
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			if _, err := extauthz.New(checker, cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	principal := &mcpdpluginsv1.Principal{ID: "jwt:alice", Source: "jwt", Subject: "alice"}
+	ctx := mcpdpluginsv1.ContextWithPrincipal(context.Background(), principal)
+	ctx = mcpdpluginsv1.ContextWithUpstream(ctx, "github")
+	ctx = mcpdpluginsv1.ContextWithTenant(ctx, "acme")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		req  func(*mcpdpluginsv1.HTTPRequest)
+		want *extauthz.CheckRequest
+	}{
+		{
+			name: "full",
+			ctx:  ctx,
+			want: &extauthz.CheckRequest{
+				Method:     http.MethodPost,
+				Path:       "/mcp",
+				Headers:    map[string]string{"authorization": "Bearer abc", "mcp-session-id": "s1"},
+				RemoteAddr: "192.0.2.7:51234",
+				Upstream:   "github",
+				Tenant:     "acme",
+				Principal:  principal,
+				MCPMethod:  "tools/call",
+				Tool:       "search",
+			},
+		},
+		{
+			name: "no headers or body",
+			ctx:  context.Background(),
+			req: func(r *mcpdpluginsv1.HTTPRequest) {
+				r.Headers = map[string]string{"Cookie": "secret"}
+				r.Body = []byte("not json")
+			},
+			want: &extauthz.CheckRequest{Method: http.MethodPost, Path: "/mcp", RemoteAddr: "192.0.2.7:51234"},
+		},
+		{
+			name: "not a tool call",
+			ctx:  context.Background(),
+			req: func(r *mcpdpluginsv1.HTTPRequest) {
+				r.Headers = nil
+				r.Body = []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+			},
+			want: &extauthz.CheckRequest{
+				Method:     http.MethodPost,
+				Path:       "/mcp",
+				RemoteAddr: "192.0.2.7:51234",
+				MCPMethod:  "tools/list",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest()
+			if tt.req != nil {
+				tt.req(req)
+			}
+			got := newAuthorizer(t, &fakeChecker{}, nil).Summarize(tt.ctx, req)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Summarize =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		decision    extauthz.Decision
+		err         error
+		failOpen    bool
+		wantStatus  int32 // Zero for requests that continue.
+		wantReason  string
+		wantHeaders map[string]string // Headers of the modified request, if any.
+	}{
+		{name: "allowed", decision: extauthz.Decision{Allowed: true}},
+		{
+			name: "allowed with headers",
+			decision: extauthz.Decision{
+				Allowed: true,
+				Headers: map[string]string{"x-user": "alice", "authorization": ""},
+			},
+			wantHeaders: map[string]string{
+				"authorization": "", "Mcp-Session-Id": "s1", "Cookie": "secret", "x-user": "alice",
+			},
+		},
+		{
+			name:       "denied with the service's status",
+			decision:   extauthz.Decision{StatusCode: 401, Reason: "token expired"},
+			wantStatus: 401,
+			wantReason: "token expired",
+		},
+		{
+			name:       "denied without a status or reason",
+			decision:   extauthz.Decision{},
+			wantStatus: 403,
+			wantReason: "request not authorized",
+		},
+		{
+			name:       "denied with a success status",
+			decision:   extauthz.Decision{StatusCode: 200, Reason: "no"},
+			wantStatus: 403,
+			wantReason: "no",
+		},
+		{
+			name:       "service error fails closed",
+			err:        errors.New("connection refused"),
+			wantStatus: 503,
+			wantReason: "authorization service unavailable",
+		},
+		{name: "service error fails open", err: errors.New("connection refused"), failOpen: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := quietLogs(t)
+			checker := &fakeChecker{decision: tt.decision, err: tt.err}
+			a := newAuthorizer(t, checker, func(c *extauthz.Config) { c.FailOpen = tt.failOpen })
+
+			req := newRequest()
+			resp := a.HandleRequest(context.Background(), req)
+			if tt.wantStatus == 0 {
+				if !resp.GetContinue() {
+					t.Fatalf("request did not continue: %v", resp)
+				}
+				var got map[string]string
+				if m := resp.GetModifiedRequest(); m != nil {
+					got = m.GetHeaders()
+				}
+				if !reflect.DeepEqual(got, tt.wantHeaders) {
+					t.Errorf("modified headers = %v, want %v", got, tt.wantHeaders)
+				}
+				if req.GetHeaders()["Authorization"] != "Bearer abc" {
+					t.Error("the original request was modified")
+				}
+			} else {
+				if resp.GetContinue() || resp.GetStatusCode() != tt.wantStatus {
+					t.Fatalf("response = %v, want a %d denial", resp, tt.wantStatus)
+				}
+				if !strings.Contains(string(resp.GetBody()), tt.wantReason) {
+					t.Errorf("body = %s, want it to contain %q", resp.GetBody(), tt.wantReason)
+				}
+			}
+			if tt.err != nil && !strings.Contains(logs.String(), "authorization service error: connection refused") {
+				t.Errorf("logged %q, want the service error", logs.String())
+			}
+		})
+	}
+}
+
+func TestHandleRequestDenyTemplate(t *testing.T) {
+	a := newAuthorizer(t, &fakeChecker{decision: extauthz.Decision{StatusCode: 403, Reason: "blocked tool"}},
+		func(c *extauthz.Config) { c.DenyTemplate = "denied by policy: {{.Reason}}" })
+
+	resp := a.HandleRequest(context.Background(), newRequest())
+	if resp.GetStatusCode() != 403 || !strings.Contains(string(resp.GetBody()), "denied by policy: blocked tool") {
+		t.Errorf("response = %v, want the templated denial", resp)
+	}
+}
+
+func TestAuthorizeCache(t *testing.T) {
+	alice := mcpdpluginsv1.ContextWithPrincipal(context.Background(), &mcpdpluginsv1.Principal{ID: "jwt:alice"})
+	bob := mcpdpluginsv1.ContextWithPrincipal(context.Background(), &mcpdpluginsv1.Principal{ID: "jwt:bob"})
+	tests := []struct {
+		name      string
+		decision  extauthz.Decision
+		err       error
+		cfg       func(*extauthz.Config)
+		ctxs      []context.Context
+		wantCalls int
+	}{
+		{
+			name:      "allow cached",
+			decision:  extauthz.Decision{Allowed: true},
+			ctxs:      []context.Context{alice, alice, alice},
+			wantCalls: 1,
+		},
+		{name: "deny cached", ctxs: []context.Context{alice, alice}, wantCalls: 1},
+		{
+			name:      "keyed by principal",
+			decision:  extauthz.Decision{Allowed: true},
+			ctxs:      []context.Context{alice, bob, alice},
+			wantCalls: 2,
+		},
+		{
+			name:      "allow caching disabled",
+			decision:  extauthz.Decision{Allowed: true},
+			cfg:       func(c *extauthz.Config) { c.AllowTTL = 0 },
+			ctxs:      []context.Context{alice, alice},
+			wantCalls: 2,
+		},
+		{
+			name:      "deny caching disabled",
+			cfg:       func(c *extauthz.Config) { c.DenyTTL = 0 },
+			ctxs:      []context.Context{alice, alice},
+			wantCalls: 2,
+		},
+		{
+			name:      "errors not cached",
+			err:       errors.New("unavailable"),
+			ctxs:      []context.Context{alice, alice},
+			wantCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &fakeChecker{decision: tt.decision, err: tt.err}
+			a := newAuthorizer(t, checker, tt.cfg)
+
+			for i, ctx := range tt.ctxs {
+				d, err := a.Authorize(ctx, newRequest())
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("call %d: error = %v, want %v", i, err, tt.err)
+				}
+				if err == nil && !reflect.DeepEqual(d, tt.decision) {
+					t.Errorf("call %d: decision = %+v, want %+v", i, d, tt.decision)
+				}
+			}
+			if got := checker.calls(); got != tt.wantCalls {
+				t.Errorf("service called %d times, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestAuthorizeTimeout(t *testing.T) {
+	a := newAuthorizer(t, &fakeChecker{wait: true}, func(c *extauthz.Config) { c.Timeout = 10 * time.Millisecond })
+
+	if _, err := a.Authorize(context.Background(), newRequest()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Authorize error = %v, want a deadline error", err)
+	}
+}
+
+func TestCheckerFunc(t *testing.T) {
+	allowSearch := func(_ context.Context, cr *extauthz.CheckRequest) (extauthz.Decision, error) {
+		return extauthz.Decision{Allowed: cr.Tool == "search"}, nil
+	}
+	a := newAuthorizer(t, extauthz.CheckerFunc(allowSearch), nil)
+
+	if d, err := a.Authorize(context.Background(), newRequest()); err != nil || !d.Allowed {
+		t.Errorf("Authorize = %+v, %v; want allowed", d, err)
+	}
+}
+
+func configure(t *testing.T, p *extauthz.Plugin, custom map[string]string) error {
+	t.Helper()
+
+	_, err := p.Configure(context.Background(), &mcpdpluginsv1.PluginConfig{CustomConfig: custom})
+	return err
+}
+
+func TestPlugin(t *testing.T) {
+	quietLogs(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"reason":"forbidden by policy"}`))
+	}))
+	defer srv.Close()
+
+	p := extauthz.NewPlugin()
+	resp, err := p.HandleRequest(context.Background(), newRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetStatusCode() != 503 || !strings.Contains(string(resp.GetBody()), "not configured") {
+		t.Errorf("unconfigured response = %v, want a 503 denial", resp)
+	}
+
+	if err := configure(t, p, map[string]string{"http_url": srv.URL}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = p.HandleRequest(context.Background(), newRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetStatusCode() != 403 || !strings.Contains(string(resp.GetBody()), "forbidden by policy") {
+		t.Errorf("response = %v, want the service's denial", resp)
+	}
+
+	if _, err := p.Stop(context.Background(), &emptypb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPluginConfigureErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		custom map[string]string
+		want   string
+	}{
+		{name: "no service", custom: map[string]string{}, want: "one of http_url or grpc_target is required"},
+		{name: "invalid URL", custom: map[string]string{"http_url": "ftp://authz"}, want: "invalid authorization"},
+		{
+			name:   "invalid gRPC method",
+			custom: map[string]string{"grpc_target": "127.0.0.1:1", "grpc_method": "Check"},
+			want:   "invalid gRPC method",
+		},
+		{
+			name:   "negative TTL",
+			custom: map[string]string{"http_url": "http://authz", "allow_ttl": "-1s"},
+			want:   "cannot be negative",
+		},
+		{name: "undecodable", custom: map[string]string{"http_url": "http://a", "timeout": "soon"}, want: "timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := extauthz.NewPlugin()
+			err := configure(t, p, tt.custom)
+			if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Configure error = %v, want InvalidArgument containing %q", err, tt.want)
+			}
+			resp, _ := p.HandleRequest(context.Background(), newRequest())
+			if resp.GetStatusCode() != 503 {
+				t.Errorf("response after failed Configure = %v, want 503", resp)
+			}
+		})
+	}
+}
+
+func TestPluginMetadata(t *testing.T) {
+	p := extauthz.NewPlugin()
+	md, err := p.GetMetadata(context.Background(), &emptypb.Empty{})
+	if err != nil || md.GetName() != "ext-authz" || md.GetVersion() == "" {
+		t.Errorf("GetMetadata = %v, %v", md, err)
+	}
+	caps, err := p.GetCapabilities(context.Background(), &emptypb.Empty{})
+	if err != nil || len(caps.GetFlows()) != 1 || caps.GetFlows()[0] != mcpdpluginsv1.FlowRequest {
+		t.Errorf("GetCapabilities = %v, %v; want the request flow", caps, err)
+	}
+}
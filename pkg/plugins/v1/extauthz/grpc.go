@@ -0,0 +1,93 @@
+package extauthz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// GRPCChecker is a Checker calling a unary gRPC method that takes and returns a
+// google.protobuf.Struct: the request Struct holds the CheckRequest fields and the response
+// Struct the Decision fields, both under their JSON names. This keeps the contract free of
+// generated code on either side; services exposing Envoy's ext_authz API need a small adapter.
+type GRPCChecker struct {
+	conn   grpc.ClientConnInterface
+	method string
+	close  func() error
+}
+
+// NewGRPCChecker returns a GRPCChecker calling method (such as
+// "/mcpd.authz.v1.Authorization/Check") on conn.
+func NewGRPCChecker(conn grpc.ClientConnInterface, method string) (*GRPCChecker, error) {
+	if conn == nil {
+		return nil, fmt.Errorf("connection is required")
+	}
+	if method == "" || method[0] != '/' {
+		return nil, fmt.Errorf("invalid gRPC method %q", method)
+	}
+
+	return &GRPCChecker{conn: conn, method: method, close: func() error { return nil }}, nil
+}
+
+// DialGRPCChecker connects to target without transport security, for services reached over a
+// private network or a local sidecar, and returns a GRPCChecker for method. Use NewGRPCChecker
+// with a connection of your own for TLS.
+func DialGRPCChecker(target, method string) (*GRPCChecker, error) {
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to authorization service: %w", err)
+	}
+	c, err := NewGRPCChecker(conn, method)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	c.close = conn.Close
+
+	return c, nil
+}
+
+// Check implements Checker.
+func (c *GRPCChecker) Check(ctx context.Context, cr *CheckRequest) (Decision, error) {
+	in, err := toStruct(cr)
+	if err != nil {
+		return Decision{}, err
+	}
+	out := &structpb.Struct{}
+	if err := c.conn.Invoke(ctx, c.method, in, out); err != nil {
+		return Decision{}, fmt.Errorf("authorization call failed: %w", err)
+	}
+
+	raw, err := out.MarshalJSON()
+	if err != nil {
+		return Decision{}, err
+	}
+	var d Decision
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return Decision{}, fmt.Errorf("invalid authorization decision: %w", err)
+	}
+
+	return d, nil
+}
+
+// Close closes the connection opened by DialGRPCChecker.
+func (c *GRPCChecker) Close() error {
+	return c.close()
+}
+
+func toStruct(v any) (*structpb.Struct, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := &structpb.Struct{}
+	if err := s.UnmarshalJSON(raw); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
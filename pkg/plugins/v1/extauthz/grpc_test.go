@@ -0,0 +1,175 @@
+package extauthz_test
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/extauthz"
+)
+
+const checkMethod = "/mcpd.authz.v1.Authorization/Check"
+
+// startAuthzServer serves a Struct-to-Struct Check method answering with handle, and returns its
+// address.
+func startAuthzServer(t *testing.T, handle func(in *structpb.Struct) (*structpb.Struct, error)) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "mcpd.authz.v1.Authorization",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Check",
+			Handler: func(_ any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				in := &structpb.Struct{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return handle(in)
+			},
+		}},
+	}, struct{}{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return lis.Addr().String()
+}
+
+func TestNewGRPCCheckerErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		conn   grpc.ClientConnInterface
+		method string
+		want   string
+	}{
+		{name: "no connection", method: checkMethod, want: "connection is required"},
+		{name: "no method", conn: &grpc.ClientConn{}, want: "invalid gRPC method"},
+		{name: "relative method", conn: &grpc.ClientConn{}, method: "Authorization/Check", want: "invalid gRPC method"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := extauthz.NewGRPCChecker(tt.conn, tt.method)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewGRPCChecker error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGRPCChecker(t *testing.T) {
+	cr := &extauthz.CheckRequest{
+		Method:  "POST",
+		Path:    "/mcp",
+		Headers: map[string]string{"authorization": "Bearer abc"},
+	}
+	tests := []struct {
+		name    string
+		reply   map[string]any
+		err     error
+		want    extauthz.Decision
+		wantErr string
+	}{
+		{
+			name:  "allowed",
+			reply: map[string]any{"allowed": true, "headers": map[string]any{"x-user": "alice"}},
+			want:  extauthz.Decision{Allowed: true, Headers: map[string]string{"x-user": "alice"}},
+		},
+		{
+			name:  "denied",
+			reply: map[string]any{"allowed": false, "statusCode": 401, "reason": "expired"},
+			want:  extauthz.Decision{StatusCode: 401, Reason: "expired"},
+		},
+		{name: "empty reply", reply: map[string]any{}, want: extauthz.Decision{}},
+		{name: "invalid decision", reply: map[string]any{"allowed": "yes"}, wantErr: "invalid authorization decision"},
+		{
+			name:    "service error",
+			err:     status.Error(codes.Unavailable, "overloaded"),
+			wantErr: "authorization call failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			addr := startAuthzServer(t, func(in *structpb.Struct) (*structpb.Struct, error) {
+				got = in.AsMap()
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return structpb.NewStruct(tt.reply)
+			})
+			c, err := extauthz.DialGRPCChecker(addr, checkMethod)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = c.Close() }()
+
+			d, err := c.Check(context.Background(), cr)
+			want := map[string]any{
+				"method":  "POST",
+				"path":    "/mcp",
+				"headers": map[string]any{"authorization": "Bearer abc"},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("service received %v, want %v", got, want)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Check error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(d, tt.want) {
+				t.Errorf("Check = %+v, want %+v", d, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialGRPCCheckerErrors(t *testing.T) {
+	if _, err := extauthz.DialGRPCChecker("127.0.0.1:1", "Check"); err == nil ||
+		!strings.Contains(err.Error(), "invalid gRPC method") {
+		t.Errorf("DialGRPCChecker error = %v, want an invalid method", err)
+	}
+}
+
+func TestPluginGRPC(t *testing.T) {
+	addr := startAuthzServer(t, func(in *structpb.Struct) (*structpb.Struct, error) {
+		return structpb.NewStruct(map[string]any{"allowed": in.GetFields()["tool"].GetStringValue() == "search"})
+	})
+
+	p := extauthz.NewPlugin()
+	if err := configure(t, p, map[string]string{"grpc_target": addr}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := p.HandleRequest(context.Background(), newRequest())
+	if err != nil || !resp.GetContinue() {
+		t.Errorf("HandleRequest = %v, %v; want the request allowed", resp, err)
+	}
+
+	// Reconfiguring closes the previous connection; Stop closes the current one.
+	if err := configure(t, p, map[string]string{"grpc_target": addr}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Stop(context.Background(), &emptypb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Stop(context.Background(), &emptypb.Empty{}); err != nil {
+		t.Errorf("second Stop: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package extauthz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/httpclientx"
+)
+
+// maxDecisionSize bounds the response body read from an HTTP authorization service.
+const maxDecisionSize = 64 << 10
+
+// HTTPChecker is a Checker POSTing the CheckRequest as JSON to an HTTP service.
+//
+// A 2xx response allows the request; its body may be a JSON Decision, whose Allowed field then
+// decides and whose Headers are added to the request. Any 4xx response denies the request with
+// that status, taking the reason from a JSON Decision body when present. 5xx responses and
+// network errors are errors, handled by the Authorizer's fail mode.
+type HTTPChecker struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPChecker returns an HTTPChecker for endpoint. A nil client uses httpclientx with its
+// defaults.
+func NewHTTPChecker(endpoint string, client *http.Client) (*HTTPChecker, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid authorization service URL %q", endpoint)
+	}
+	if client == nil {
+		if client, err = httpclientx.New(httpclientx.DefaultConfig()); err != nil {
+			return nil, err
+		}
+	}
+
+	return &HTTPChecker{url: endpoint, client: client}, nil
+}
+
+// Check implements Checker.
+func (c *HTTPChecker) Check(ctx context.Context, cr *CheckRequest) (Decision, error) {
+	body, err := json.Marshal(cr)
+	if err != nil {
+		return Decision{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return Decision{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Decision{}, fmt.Errorf("authorization request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxDecisionSize))
+	if err != nil {
+		return Decision{}, fmt.Errorf("failed to read authorization response: %w", err)
+	}
+
+	var d Decision
+	decoded := json.Unmarshal(raw, &d) == nil
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		if !decoded {
+			return Decision{Allowed: true}, nil
+		}
+		return d, nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		if !decoded {
+			d = Decision{}
+		}
+		d.Allowed = false
+		d.StatusCode = resp.StatusCode
+		return d, nil
+	default:
+		return Decision{}, fmt.Errorf("authorization service returned %s", resp.Status)
+	}
+}
@@ -0,0 +1,105 @@
+package extauthz_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/extauthz"
+)
+
+func TestNewHTTPCheckerErrors(t *testing.T) {
+	for _, endpoint := range []string{"", "authz.internal/check", "ftp://authz.internal", "http://", "http://[::1"} {
+		if _, err := extauthz.NewHTTPChecker(endpoint, nil); err == nil ||
+			!strings.Contains(err.Error(), "invalid authorization service URL") {
+			t.Errorf("NewHTTPChecker(%q) error = %v, want an invalid URL", endpoint, err)
+		}
+	}
+}
+
+func TestHTTPChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    extauthz.Decision
+		wantErr string
+	}{
+		{name: "empty success", status: 200, want: extauthz.Decision{Allowed: true}},
+		{name: "non-JSON success", status: 204, body: "ok", want: extauthz.Decision{Allowed: true}},
+		{
+			name:   "allowed with headers",
+			status: 200,
+			body:   `{"allowed":true,"headers":{"x-user":"alice"}}`,
+			want:   extauthz.Decision{Allowed: true, Headers: map[string]string{"x-user": "alice"}},
+		},
+		{
+			name:   "success denying",
+			status: 200,
+			body:   `{"allowed":false,"statusCode":429,"reason":"quota"}`,
+			want:   extauthz.Decision{StatusCode: 429, Reason: "quota"},
+		},
+		{name: "client error", status: 401, want: extauthz.Decision{StatusCode: 401}},
+		{
+			name:   "client error with a reason",
+			status: 403,
+			body:   `{"allowed":true,"statusCode":200,"reason":"tool blocked"}`,
+			want:   extauthz.Decision{StatusCode: 403, Reason: "tool blocked"},
+		},
+		{name: "server error", status: 500, body: `{"allowed":true}`, wantErr: "authorization service returned 500"},
+		{name: "redirect", status: 304, wantErr: "authorization service returned 304"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got extauthz.CheckRequest
+			var contentType string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contentType = r.Header.Get("Content-Type")
+				_ = json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			c, err := extauthz.NewHTTPChecker(srv.URL+"/check", srv.Client())
+			if err != nil {
+				t.Fatal(err)
+			}
+			cr := &extauthz.CheckRequest{Method: "POST", Path: "/mcp", Tool: "search"}
+			d, err := c.Check(context.Background(), cr)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Check error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(d, tt.want) {
+				t.Errorf("Check = %+v, want %+v", d, tt.want)
+			}
+			if !reflect.DeepEqual(&got, cr) || contentType != "application/json" {
+				t.Errorf("service received %+v as %q, want %+v as JSON", got, contentType, cr)
+			}
+		})
+	}
+}
+
+func TestHTTPCheckerUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	c, err := extauthz.NewHTTPChecker(srv.URL, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Check(context.Background(), &extauthz.CheckRequest{}); err == nil ||
+		!strings.Contains(err.Error(), "authorization request failed") {
+		t.Errorf("Check error = %v, want a request failure", err)
+	}
+}
@@ -0,0 +1,247 @@
+// Package faults injects latency, errors and truncated bodies into plugin handler results, so mcpd
+// operators can validate the proxy's behavior when a plugin misbehaves.
+//
+// Faults are applied by a gRPC interceptor installed with mcpdpluginsv1.WithUnaryInterceptor:
+//
+//	inj, err := faults.New([]faults.Fault{
+//	    faults.Latency(0.1, 200*time.Millisecond, 2*time.Second),
+//	    faults.Error(0.05, codes.Unavailable),
+//	    faults.TruncateBody(0.02, 0.5),
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	err = mcpdpluginsv1.Serve(&MyPlugin{}, mcpdpluginsv1.WithUnaryInterceptor(inj.Interceptor()))
+//
+// Never enable fault injection in production.
+package faults
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// Fault is a misbehavior injected with a given probability on each call.
+type Fault struct {
+	kind        string
+	probability float64
+
+	minDelay time.Duration
+	maxDelay time.Duration
+
+	code codes.Code
+
+	keep float64
+}
+
+// Fault kinds reported by Fault.String and counted by Injector.Injected.
+const (
+	KindLatency  = "latency"
+	KindError    = "error"
+	KindTruncate = "truncate"
+)
+
+// Latency delays the handler result by a uniformly random duration in [lo, hi] with probability p.
+func Latency(p float64, lo, hi time.Duration) Fault {
+	return Fault{kind: KindLatency, probability: p, minDelay: lo, maxDelay: hi}
+}
+
+// Error replaces the handler result with a gRPC error of the given code with probability p.
+func Error(p float64, code codes.Code) Fault {
+	return Fault{kind: KindError, probability: p, code: code}
+}
+
+// TruncateBody cuts the body of HandleRequest and HandleResponse results to the fraction keep
+// (0 to 1) of its length with probability p.
+func TruncateBody(p float64, keep float64) Fault {
+	return Fault{kind: KindTruncate, probability: p, keep: keep}
+}
+
+// String describes the fault.
+func (f Fault) String() string {
+	switch f.kind {
+	case KindLatency:
+		return fmt.Sprintf("%s(p=%v, %s-%s)", f.kind, f.probability, f.minDelay, f.maxDelay)
+	case KindError:
+		return fmt.Sprintf("%s(p=%v, %s)", f.kind, f.probability, f.code)
+	case KindTruncate:
+		return fmt.Sprintf("%s(p=%v, keep=%v)", f.kind, f.probability, f.keep)
+	default:
+		return "invalid fault"
+	}
+}
+
+func (f Fault) validate() error {
+	if f.probability < 0 || f.probability > 1 {
+		return fmt.Errorf("%s fault probability must be between 0 and 1, got %v", f.kind, f.probability)
+	}
+
+	switch f.kind {
+	case KindLatency:
+		if f.minDelay < 0 || f.maxDelay < f.minDelay {
+			return fmt.Errorf("latency fault range %s-%s is invalid", f.minDelay, f.maxDelay)
+		}
+	case KindError:
+		if f.code == codes.OK {
+			return fmt.Errorf("error fault code cannot be OK")
+		}
+	case KindTruncate:
+		if f.keep < 0 || f.keep >= 1 {
+			return fmt.Errorf("truncate fault must keep between 0 and 1 (exclusive) of the body, got %v", f.keep)
+		}
+	default:
+		return fmt.Errorf("fault must be created with Latency, Error or TruncateBody")
+	}
+
+	return nil
+}
+
+// Option configures an Injector.
+type Option func(*Injector) error
+
+// ForMethods limits fault injection to the given RPCs (e.g. "HandleRequest"). By default faults
+// apply to HandleRequest and HandleResponse only.
+func ForMethods(methods ...string) Option {
+	return func(inj *Injector) error {
+		if len(methods) == 0 {
+			return fmt.Errorf("at least one method is required")
+		}
+		inj.methods = methods
+		return nil
+	}
+}
+
+// WithSeed makes fault selection deterministic, for reproducible test runs.
+func WithSeed(seed uint64) Option {
+	return func(inj *Injector) error {
+		inj.rng = rand.New(rand.NewPCG(seed, seed))
+		return nil
+	}
+}
+
+// WithRandSource draws fault selection and latency from src (defaults to a randomly seeded
+// source), for example one rng.New source shared with the other components of a replay run.
+func WithRandSource(src rand.Source) Option {
+	return func(inj *Injector) error {
+		if src == nil {
+			return fmt.Errorf("rand source cannot be nil")
+		}
+		inj.rng = rand.New(src)
+		return nil
+	}
+}
+
+// Injector applies faults to handler results. It is safe for concurrent use.
+type Injector struct {
+	faults  []Fault
+	methods []string
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	injected map[string]int64
+}
+
+// New returns an Injector applying faults in order; several faults may apply to the same call.
+func New(faults []Fault, opts ...Option) (*Injector, error) {
+	for _, f := range faults {
+		if err := f.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	inj := &Injector{
+		faults:   faults,
+		methods:  []string{"HandleRequest", "HandleResponse"},
+		rng:      rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+		injected: map[string]int64{},
+	}
+	for _, opt := range opts {
+		if err := opt(inj); err != nil {
+			return nil, err
+		}
+	}
+
+	return inj, nil
+}
+
+// Injected returns how many times each fault kind has been injected.
+func (inj *Injector) Injected() map[string]int64 {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+
+	return maps.Clone(inj.injected)
+}
+
+// selected returns the faults to inject in the current call.
+func (inj *Injector) selected() []Fault {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+
+	var out []Fault
+	for _, f := range inj.faults {
+		if f.probability > 0 && inj.rng.Float64() < f.probability {
+			out = append(out, f)
+			inj.injected[f.kind]++
+		}
+	}
+
+	return out
+}
+
+func (inj *Injector) delay(f Fault) time.Duration {
+	if f.maxDelay == f.minDelay {
+		return f.minDelay
+	}
+
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+
+	return f.minDelay + time.Duration(inj.rng.Int64N(int64(f.maxDelay-f.minDelay)+1))
+}
+
+// Interceptor returns a gRPC unary server interceptor that applies the injector's faults.
+func (inj *Injector) Interceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		method := path.Base(info.FullMethod)
+		if !slices.Contains(inj.methods, method) {
+			return handler(ctx, req)
+		}
+
+		faults := inj.selected()
+		resp, err := handler(ctx, req)
+
+		for _, f := range faults {
+			switch f.kind {
+			case KindLatency:
+				t := time.NewTimer(inj.delay(f))
+				select {
+				case <-t.C:
+				case <-ctx.Done():
+					t.Stop()
+					return nil, status.FromContextError(ctx.Err()).Err()
+				}
+			case KindError:
+				return nil, status.Errorf(f.code, "fault injected in %s", method)
+			case KindTruncate:
+				if out, ok := resp.(*mcpdpluginsv1.HTTPResponse); ok && err == nil {
+					out.Body = out.Body[:int(float64(len(out.Body))*f.keep)]
+				}
+			}
+		}
+
+		return resp, err
+	}
+}
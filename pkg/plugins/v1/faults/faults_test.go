@@ -0,0 +1,322 @@
+package faults_test
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/faults"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/rng"
+)
+
+var handleRequest = &grpc.UnaryServerInfo{FullMethod: mcpdpluginsv1.Plugin_HandleRequest_FullMethodName}
+
+// passThrough is a handler answering with a copy of the request body.
+func passThrough(_ context.Context, req any) (any, error) {
+	return &mcpdpluginsv1.HTTPResponse{Continue: true, Body: req.(*mcpdpluginsv1.HTTPRequest).GetBody()}, nil
+}
+
+// failures returns which of n calls through inj failed.
+func failures(t *testing.T, inj *faults.Injector, n int) []bool {
+	t.Helper()
+
+	intercept := inj.Interceptor()
+	out := make([]bool, n)
+	for i := range out {
+		_, err := intercept(context.Background(), &mcpdpluginsv1.HTTPRequest{}, handleRequest, passThrough)
+		out[i] = err != nil
+	}
+
+	return out
+}
+
+func TestRandSource(t *testing.T) {
+	newInjector := func(opt faults.Option) *faults.Injector {
+		inj, err := faults.New([]faults.Fault{faults.Error(0.5, codes.Unavailable)}, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return inj
+	}
+
+	tests := []struct {
+		name  string
+		a, b  faults.Option
+		equal bool
+	}{
+		{
+			name:  "same source seed",
+			a:     faults.WithRandSource(rng.New(5)),
+			b:     faults.WithRandSource(rng.New(5)),
+			equal: true,
+		},
+		{
+			name:  "different source seeds",
+			a:     faults.WithRandSource(rng.New(5)),
+			b:     faults.WithRandSource(rng.New(6)),
+			equal: false,
+		},
+		{
+			name:  "same seed",
+			a:     faults.WithSeed(5),
+			b:     faults.WithSeed(5),
+			equal: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := failures(t, newInjector(tt.a), 64)
+			b := failures(t, newInjector(tt.b), 64)
+			if got := slices.Equal(a, b); got != tt.equal {
+				t.Errorf("fault selections equal = %v, want %v", got, tt.equal)
+			}
+		})
+	}
+}
+
+func TestRandSourceNil(t *testing.T) {
+	if _, err := faults.New(nil, faults.WithRandSource(nil)); err == nil {
+		t.Error("New accepted a nil rand source")
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault faults.Fault
+		want  string
+	}{
+		{"negative probability", faults.Error(-0.1, codes.Internal), "error fault probability must be between 0 and 1"},
+		{"probability above one", faults.Latency(1.5, 0, time.Second), "latency fault probability must be between 0"},
+		{"negative delay", faults.Latency(1, -time.Second, time.Second), "latency fault range -1s-1s is invalid"},
+		{"inverted range", faults.Latency(1, time.Second, time.Millisecond), "latency fault range 1s-1ms is invalid"},
+		{"OK code", faults.Error(1, codes.OK), "error fault code cannot be OK"},
+		{"keep everything", faults.TruncateBody(1, 1), "truncate fault must keep between 0 and 1"},
+		{"negative keep", faults.TruncateBody(1, -0.5), "truncate fault must keep between 0 and 1"},
+		{"zero value", faults.Fault{}, "fault must be created with Latency, Error or TruncateBody"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := faults.New([]faults.Fault{tt.fault})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := faults.New(nil, faults.ForMethods()); err == nil {
+		t.Error("New accepted ForMethods without methods")
+	}
+}
+
+func TestFaultString(t *testing.T) {
+	tests := []struct {
+		fault faults.Fault
+		want  string
+	}{
+		{faults.Latency(0.1, 200*time.Millisecond, 2*time.Second), "latency(p=0.1, 200ms-2s)"},
+		{faults.Error(0.05, codes.Unavailable), "error(p=0.05, Unavailable)"},
+		{faults.TruncateBody(0.02, 0.5), "truncate(p=0.02, keep=0.5)"},
+		{faults.Fault{}, "invalid fault"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.fault.String(); got != tt.want {
+				t.Errorf("String = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterceptor(t *testing.T) {
+	errHandler := errors.New("handler failed")
+	tests := []struct {
+		name     string
+		faults   []faults.Fault
+		opts     []faults.Option
+		info     *grpc.UnaryServerInfo
+		handler  grpc.UnaryHandler
+		wantCode codes.Code
+		wantErr  error
+		wantBody string
+		injected map[string]int64
+	}{
+		{
+			name:     "no faults",
+			info:     handleRequest,
+			handler:  passThrough,
+			wantBody: "0123456789",
+			injected: map[string]int64{},
+		},
+		{
+			name:     "never injected",
+			faults:   []faults.Fault{faults.Error(0, codes.Internal), faults.TruncateBody(0, 0)},
+			info:     handleRequest,
+			handler:  passThrough,
+			wantBody: "0123456789",
+			injected: map[string]int64{},
+		},
+		{
+			name:     "error",
+			faults:   []faults.Fault{faults.Error(1, codes.Unavailable)},
+			info:     handleRequest,
+			handler:  passThrough,
+			wantCode: codes.Unavailable,
+			injected: map[string]int64{faults.KindError: 1},
+		},
+		{
+			name:     "truncated body",
+			faults:   []faults.Fault{faults.TruncateBody(1, 0.35)},
+			info:     handleRequest,
+			handler:  passThrough,
+			wantBody: "012",
+			injected: map[string]int64{faults.KindTruncate: 1},
+		},
+		{
+			name:     "emptied body",
+			faults:   []faults.Fault{faults.TruncateBody(1, 0)},
+			info:     handleRequest,
+			handler:  passThrough,
+			injected: map[string]int64{faults.KindTruncate: 1},
+		},
+		{
+			name:     "truncation leaves handler errors alone",
+			faults:   []faults.Fault{faults.TruncateBody(1, 0.5)},
+			info:     handleRequest,
+			handler:  func(context.Context, any) (any, error) { return nil, errHandler },
+			wantErr:  errHandler,
+			injected: map[string]int64{faults.KindTruncate: 1},
+		},
+		{
+			name:     "faults apply in order",
+			faults:   []faults.Fault{faults.TruncateBody(1, 0.5), faults.Error(1, codes.Internal)},
+			info:     handleRequest,
+			handler:  passThrough,
+			wantCode: codes.Internal,
+			injected: map[string]int64{faults.KindTruncate: 1, faults.KindError: 1},
+		},
+		{
+			name:     "other methods by default",
+			faults:   []faults.Fault{faults.Error(1, codes.Internal)},
+			info:     &grpc.UnaryServerInfo{FullMethod: mcpdpluginsv1.Plugin_Configure_FullMethodName},
+			handler:  passThrough,
+			wantBody: "0123456789",
+			injected: map[string]int64{},
+		},
+		{
+			name:     "methods not selected",
+			faults:   []faults.Fault{faults.Error(1, codes.Internal)},
+			opts:     []faults.Option{faults.ForMethods("HandleResponse")},
+			info:     handleRequest,
+			handler:  passThrough,
+			wantBody: "0123456789",
+			injected: map[string]int64{},
+		},
+		{
+			name:     "selected methods",
+			faults:   []faults.Fault{faults.Error(1, codes.Internal)},
+			opts:     []faults.Option{faults.ForMethods("Configure")},
+			info:     &grpc.UnaryServerInfo{FullMethod: mcpdpluginsv1.Plugin_Configure_FullMethodName},
+			handler:  passThrough,
+			wantCode: codes.Internal,
+			injected: map[string]int64{faults.KindError: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inj, err := faults.New(tt.faults, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := inj.Interceptor()(context.Background(),
+				&mcpdpluginsv1.HTTPRequest{Body: []byte("0123456789")}, tt.info, tt.handler)
+			switch {
+			case tt.wantCode != codes.OK:
+				if status.Code(err) != tt.wantCode || resp != nil {
+					t.Fatalf("Interceptor = %v, %v; want a %s error", resp, err, tt.wantCode)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			default:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := string(resp.(*mcpdpluginsv1.HTTPResponse).GetBody()); got != tt.wantBody {
+					t.Errorf("body = %q, want %q", got, tt.wantBody)
+				}
+			}
+			if got := inj.Injected(); !maps.Equal(got, tt.injected) {
+				t.Errorf("Injected = %v, want %v", got, tt.injected)
+			}
+		})
+	}
+}
+
+func TestLatency(t *testing.T) {
+	latency := faults.Latency(1, 20*time.Millisecond, 40*time.Millisecond)
+	inj, err := faults.New([]faults.Fault{latency}, faults.WithSeed(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	intercept := inj.Interceptor()
+
+	for range 5 {
+		start := time.Now()
+		_, err := intercept(context.Background(), &mcpdpluginsv1.HTTPRequest{}, handleRequest, passThrough)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := time.Since(start); d < 20*time.Millisecond {
+			t.Errorf("call took %s, want at least the 20ms minimum delay", d)
+		}
+	}
+	if got := inj.Injected()[faults.KindLatency]; got != 5 {
+		t.Errorf("latency injected %d times, want 5", got)
+	}
+}
+
+func TestLatencyCanceled(t *testing.T) {
+	inj, err := faults.New([]faults.Fault{faults.Latency(1, time.Hour, time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = inj.Interceptor()(ctx, &mcpdpluginsv1.HTTPRequest{}, handleRequest, passThrough)
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("error = %v, want DeadlineExceeded once the caller gives up", err)
+	}
+}
+
+func TestProbability(t *testing.T) {
+	inj, err := faults.New([]faults.Fault{faults.Error(0.25, codes.Internal)}, faults.WithSeed(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := 0
+	for _, f := range failures(t, inj, 2000) {
+		if f {
+			failed++
+		}
+	}
+	if failed < 400 || failed > 600 {
+		t.Errorf("%d of 2000 calls failed, want about 500", failed)
+	}
+	if got := inj.Injected()[faults.KindError]; got != int64(failed) {
+		t.Errorf("Injected counted %d errors, want %d", got, failed)
+	}
+}
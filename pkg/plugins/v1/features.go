@@ -0,0 +1,45 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+)
+
+// SupportedFeatures returns the optional capabilities this SDK build supports: flows defined by the
+// generated plugin API beyond request and response. Streaming bodies and batch RPC join the set
+// once the plugin API defines them.
+func SupportedFeatures() features.Set {
+	var fs []features.Feature
+	for v, name := range Flow_name {
+		if Flow(v) == FlowRequest || Flow(v) == FlowResponse {
+			continue
+		}
+		fs = append(fs, features.Flow(strings.TrimPrefix(name, "FLOW_")))
+	}
+
+	return features.NewSet(fs...)
+}
+
+// featuresInterceptor negotiates features with mcpd: it stores the intersection of the features
+// mcpd advertises and supported, SupportedFeatures plus the plugin's own, in the handler's
+// context, and advertises supported in the GetMetadata response header.
+func featuresInterceptor(supported features.Set) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if info.FullMethod == Plugin_GetMetadata_FullMethodName {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(features.MetadataKey, supported.String()))
+		}
+
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get(features.MetadataKey); len(v) > 0 {
+				ctx = features.NewContext(ctx, features.Parse(strings.Join(v, ",")).Intersect(supported))
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
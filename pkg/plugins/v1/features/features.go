@@ -0,0 +1,125 @@
+// Package features reports which optional plugin API capabilities are available on a call, after
+// negotiation between the SDK and mcpd, so plugin code can branch on a capability instead of
+// probing for zero values in fields an older peer never sets.
+//
+// mcpd advertises the features it supports in the MetadataKey request metadata, and Serve returns
+// the features this SDK supports in the same key on GetMetadata. Serve stores the intersection in
+// each handler's context:
+//
+//	func (p *MyPlugin) HandleRequest(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error) {
+//	    if features.Enabled(ctx, features.StreamingBodies) {
+//	        // Read the body in chunks.
+//	    }
+//	    ...
+//	}
+//
+// A feature is only enabled when both sides support it, so calls from an mcpd that advertises
+// nothing see an empty Set and the baseline request/response behaviour.
+package features
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// MetadataKey is the gRPC metadata key listing supported features, comma-separated.
+const MetadataKey = "mcpd-plugin-features"
+
+// Feature names an optional plugin API capability.
+type Feature string
+
+// Optional capabilities defined by the plugin API.
+const (
+	// StreamingBodies delivers request and response bodies in chunks instead of a single message.
+	StreamingBodies Feature = "streaming_bodies"
+
+	// BatchRPC lets mcpd send several requests or responses in a single call.
+	BatchRPC Feature = "batch_rpc"
+
+	// HeadersOnly lets mcpd omit request and response bodies from calls to a plugin that declared
+	// it does not read them. A body left empty in the plugin's reply means the body is unchanged.
+	HeadersOnly Feature = "headers_only"
+
+	// SelectiveFields lets mcpd send a plugin only the request fields it subscribed to.
+	SelectiveFields Feature = "selective_fields"
+)
+
+// flowPrefix prefixes features announcing flows beyond the baseline request and response flows.
+const flowPrefix = "flow."
+
+// Flow returns the feature announcing support for the named flow (e.g. Flow("stream") is
+// "flow.stream"). The request and response flows are always available and are not features.
+func Flow(name string) Feature {
+	return Feature(flowPrefix + strings.ToLower(name))
+}
+
+// Set is a sorted list of distinct features.
+type Set []Feature
+
+// NewSet returns the set of the given features.
+func NewSet(fs ...Feature) Set {
+	s := slices.Clone(fs)
+	s = slices.DeleteFunc(s, func(f Feature) bool { return f == "" })
+	slices.Sort(s)
+
+	return slices.Compact(s)
+}
+
+// Parse parses a comma-separated feature list as sent in MetadataKey. Whitespace around names is
+// ignored, and names are matched case-insensitively.
+func Parse(s string) Set {
+	var fs []Feature
+	for name := range strings.SplitSeq(s, ",") {
+		fs = append(fs, Feature(strings.ToLower(strings.TrimSpace(name))))
+	}
+
+	return NewSet(fs...)
+}
+
+// Has reports whether f is in the set.
+func (s Set) Has(f Feature) bool {
+	_, ok := slices.BinarySearch(s, f)
+	return ok
+}
+
+// Intersect returns the features present in both sets.
+func (s Set) Intersect(other Set) Set {
+	var out Set
+	for _, f := range s {
+		if other.Has(f) {
+			out = append(out, f)
+		}
+	}
+
+	return out
+}
+
+// String formats the set as a comma-separated list, the MetadataKey wire format.
+func (s Set) String() string {
+	names := make([]string, len(s))
+	for i, f := range s {
+		names[i] = string(f)
+	}
+
+	return strings.Join(names, ",")
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the negotiated features, for use in tests and custom
+// dispatch.
+func NewContext(ctx context.Context, s Set) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext returns the features negotiated for the call, or an empty Set when none were.
+func FromContext(ctx context.Context) Set {
+	s, _ := ctx.Value(contextKey{}).(Set)
+	return s
+}
+
+// Enabled reports whether f was negotiated for the call.
+func Enabled(ctx context.Context, f Feature) bool {
+	return FromContext(ctx).Has(f)
+}
@@ -0,0 +1,99 @@
+package features_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want features.Set
+	}{
+		{name: "empty", in: "", want: features.Set{}},
+		{name: "single", in: "batch_rpc", want: features.Set{features.BatchRPC}},
+		{
+			name: "sorted and deduplicated",
+			in:   "streaming_bodies,batch_rpc,streaming_bodies",
+			want: features.Set{features.BatchRPC, features.StreamingBodies},
+		},
+		{
+			name: "whitespace and case",
+			in:   " Batch_RPC , HEADERS_ONLY ",
+			want: features.Set{features.BatchRPC, features.HeadersOnly},
+		},
+		{name: "empty names", in: ",,batch_rpc,", want: features.Set{features.BatchRPC}},
+		{name: "unknown names kept", in: "flow.Stream", want: features.Set{features.Flow("stream")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := features.Parse(tt.in); !slices.Equal(got, tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	s := features.NewSet(features.StreamingBodies, "", features.BatchRPC, features.BatchRPC)
+	if got := s.String(); got != "batch_rpc,streaming_bodies" {
+		t.Errorf("String = %q, want the sorted, deduplicated wire form", got)
+	}
+	if !s.Has(features.BatchRPC) || s.Has(features.HeadersOnly) || s.Has("") {
+		t.Errorf("Has reports membership wrongly for %v", s)
+	}
+	if got := features.Parse(s.String()); !slices.Equal(got, s) {
+		t.Errorf("Parse(String()) = %v, want %v", got, s)
+	}
+	if got := features.NewSet().String(); got != "" {
+		t.Errorf("empty set String = %q", got)
+	}
+
+	tests := []struct {
+		name string
+		a, b features.Set
+		want features.Set
+	}{
+		{"overlap", s, features.NewSet(features.BatchRPC, features.HeadersOnly), features.Set{features.BatchRPC}},
+		{"disjoint", s, features.NewSet(features.HeadersOnly), nil},
+		{"empty", s, nil, nil},
+		{"same", s, s, s},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Intersect(tt.b); !slices.Equal(got, tt.want) {
+				t.Errorf("Intersect = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSetDoesNotModifyArgs(t *testing.T) {
+	in := []features.Feature{features.StreamingBodies, features.BatchRPC}
+	features.NewSet(in...)
+	if in[0] != features.StreamingBodies {
+		t.Errorf("NewSet reordered its arguments: %v", in)
+	}
+}
+
+func TestFlow(t *testing.T) {
+	if got := features.Flow("PRE_AUTH"); got != "flow.pre_auth" {
+		t.Errorf("Flow = %q, want flow.pre_auth", got)
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if got := features.FromContext(ctx); len(got) != 0 || features.Enabled(ctx, features.BatchRPC) {
+		t.Errorf("FromContext without negotiation = %v, want an empty set", got)
+	}
+
+	ctx = features.NewContext(ctx, features.NewSet(features.BatchRPC))
+	if !features.Enabled(ctx, features.BatchRPC) || features.Enabled(ctx, features.StreamingBodies) {
+		t.Errorf("Enabled does not reflect the negotiated set %v", features.FromContext(ctx))
+	}
+}
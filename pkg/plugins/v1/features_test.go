@@ -0,0 +1,90 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+)
+
+func TestSupportedFeatures(t *testing.T) {
+	got := SupportedFeatures()
+	for _, f := range got {
+		if !strings.HasPrefix(string(f), "flow.") {
+			t.Errorf("SupportedFeatures contains %q, want only flows", f)
+		}
+	}
+	if got.Has(features.Flow("request")) || got.Has(features.Flow("response")) {
+		t.Errorf("SupportedFeatures = %v, want the baseline flows left out", got)
+	}
+	if want := len(Flow_name) - 2; len(got) != want {
+		t.Errorf("SupportedFeatures has %d features, want %d", len(got), want)
+	}
+}
+
+func TestFeaturesInterceptor(t *testing.T) {
+	supported := features.NewSet(features.BatchRPC, features.HeadersOnly)
+	tests := []struct {
+		name       string
+		method     string
+		advertised []string // Values of features.MetadataKey; nil sends no metadata.
+		want       features.Set
+		wantHeader []string
+	}{
+		{
+			name:       "GetMetadata advertises supported features",
+			method:     Plugin_GetMetadata_FullMethodName,
+			wantHeader: []string{"batch_rpc,headers_only"},
+		},
+		{name: "nothing advertised", method: Plugin_HandleRequest_FullMethodName},
+		{
+			name:       "intersection",
+			method:     Plugin_HandleRequest_FullMethodName,
+			advertised: []string{"streaming_bodies, BATCH_RPC"},
+			want:       features.Set{features.BatchRPC},
+		},
+		{
+			name:       "repeated metadata values",
+			method:     Plugin_HandleRequest_FullMethodName,
+			advertised: []string{"batch_rpc", "headers_only"},
+			want:       features.Set{features.BatchRPC, features.HeadersOnly},
+		},
+		{
+			name:       "nothing in common",
+			method:     Plugin_HandleRequest_FullMethodName,
+			advertised: []string{"streaming_bodies"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &headerStream{}
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+			if tt.advertised != nil {
+				md := metadata.MD{}
+				md.Append(features.MetadataKey, tt.advertised...)
+				ctx = metadata.NewIncomingContext(ctx, md)
+			}
+
+			var got features.Set
+			_, err := featuresInterceptor(supported)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method},
+				func(ctx context.Context, _ any) (any, error) {
+					got = features.FromContext(ctx)
+					return nil, nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("handler saw features %v, want %v", got, tt.want)
+			}
+			if h := stream.header.Get(features.MetadataKey); !slices.Equal(h, tt.wantHeader) {
+				t.Errorf("response header = %q, want %q", h, tt.wantHeader)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+)
+
+// FieldsMetadataKey is the gRPC response header on GetCapabilities listing, comma-separated, the
+// HTTPRequest fields a FieldSubscriber needs.
+const FieldsMetadataKey = "mcpd-plugin-fields"
+
+// RequestField names an HTTPRequest attribute a plugin subscribes to.
+type RequestField string
+
+// HTTPRequest attributes a FieldSubscriber can subscribe to. FieldHeaders subscribes to every
+// header; HeaderField subscribes to a single one.
+const (
+	FieldMethod     RequestField = "method"
+	FieldURL        RequestField = "url"
+	FieldPath       RequestField = "path"
+	FieldHeaders    RequestField = "headers"
+	FieldBody       RequestField = "body"
+	FieldRemoteAddr RequestField = "remote_addr"
+	FieldRequestURI RequestField = "request_uri"
+)
+
+// headerFieldPrefix prefixes fields subscribing to a single header.
+const headerFieldPrefix = "header:"
+
+// HeaderField returns the field subscribing to the header name (e.g. HeaderField("Authorization")
+// is "header:authorization"). Header names are matched case-insensitively.
+func HeaderField(name string) RequestField {
+	return RequestField(headerFieldPrefix + strings.ToLower(name))
+}
+
+// FieldSubscriber is implemented by plugins that only need some HTTPRequest attributes. When mcpd
+// supports features.SelectiveFields, it sends HandleRequest only the subscribed fields, reducing
+// what is serialized for every call; unsubscribed fields arrive empty, and leaving them empty in
+// a ModifiedRequest keeps their original values.
+//
+// When the plugin served by Serve implements FieldSubscriber, the SDK advertises
+// features.SelectiveFields and returns the fields in the FieldsMetadataKey header of
+// GetCapabilities. A handler reading the body with Body when FieldBody is not subscribed gets
+// ErrBodyNotRequested, reported to mcpd as with WithHeadersOnly.
+//
+// Usage:
+//
+//	func (p *MyPlugin) RequestFields() []mcpdpluginsv1.RequestField {
+//	    return []mcpdpluginsv1.RequestField{
+//	        mcpdpluginsv1.FieldMethod,
+//	        mcpdpluginsv1.FieldPath,
+//	        mcpdpluginsv1.HeaderField("Authorization"),
+//	    }
+//	}
+type FieldSubscriber interface {
+	RequestFields() []RequestField
+}
+
+// FieldSet is a sorted list of distinct request fields.
+type FieldSet []RequestField
+
+// NewFieldSet returns the set of the given fields, rejecting unknown fields and invalid header
+// names.
+func NewFieldSet(fields ...RequestField) (FieldSet, error) {
+	s := make(FieldSet, 0, len(fields))
+	for _, f := range fields {
+		f = RequestField(strings.ToLower(strings.TrimSpace(string(f))))
+		switch f {
+		case FieldMethod, FieldURL, FieldPath, FieldHeaders, FieldBody, FieldRemoteAddr, FieldRequestURI:
+		default:
+			name, ok := strings.CutPrefix(string(f), headerFieldPrefix)
+			if !ok {
+				return nil, fmt.Errorf("unknown request field %q", f)
+			}
+			if name == "" || strings.ContainsAny(name, " \t,:;\"") {
+				return nil, fmt.Errorf("invalid header name in request field %q", f)
+			}
+		}
+		s = append(s, f)
+	}
+	slices.Sort(s)
+
+	return slices.Compact(s), nil
+}
+
+// ParseFieldSet parses a comma-separated field list as sent in FieldsMetadataKey.
+func ParseFieldSet(s string) (FieldSet, error) {
+	var fields []RequestField
+	for name := range strings.SplitSeq(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			fields = append(fields, RequestField(name))
+		}
+	}
+
+	return NewFieldSet(fields...)
+}
+
+// Has reports whether the set subscribes to f.
+func (s FieldSet) Has(f RequestField) bool {
+	_, ok := slices.BinarySearch(s, f)
+	return ok
+}
+
+// HasHeader reports whether the set subscribes to the header name, directly or through
+// FieldHeaders.
+func (s FieldSet) HasHeader(name string) bool {
+	return s.Has(FieldHeaders) || s.Has(HeaderField(name))
+}
+
+// Select returns a copy of req keeping only the fields in the set, as mcpd sends it to a
+// FieldSubscriber. It is meant for tests and for hosts implementing FieldsMetadataKey.
+func (s FieldSet) Select(req *HTTPRequest) *HTTPRequest {
+	out := &HTTPRequest{}
+	if s.Has(FieldMethod) {
+		out.Method = req.GetMethod()
+	}
+	if s.Has(FieldURL) {
+		out.Url = req.GetUrl()
+	}
+	if s.Has(FieldPath) {
+		out.Path = req.GetPath()
+	}
+	if s.Has(FieldBody) {
+		out.Body = req.GetBody()
+	}
+	if s.Has(FieldRemoteAddr) {
+		out.RemoteAddr = req.GetRemoteAddr()
+	}
+	if s.Has(FieldRequestURI) {
+		out.RequestUri = req.GetRequestUri()
+	}
+	for k, v := range req.GetHeaders() {
+		if s.HasHeader(k) {
+			if out.Headers == nil {
+				out.Headers = make(map[string]string)
+			}
+			out.Headers[k] = v
+		}
+	}
+
+	return out
+}
+
+// String formats the set as a comma-separated list, the FieldsMetadataKey wire format.
+func (s FieldSet) String() string {
+	names := make([]string, len(s))
+	for i, f := range s {
+		names[i] = string(f)
+	}
+
+	return strings.Join(names, ",")
+}
+
+type fieldsContextKey struct{}
+
+// SubscribedFields returns the fields mcpd sent in the current HandleRequest call, and false when
+// it sent the whole request.
+func SubscribedFields(ctx context.Context) (FieldSet, bool) {
+	s, ok := ctx.Value(fieldsContextKey{}).(FieldSet)
+	return s, ok
+}
+
+// fieldSet returns the fields impl subscribes to. It returns nil when impl does not implement
+// FieldSubscriber.
+func fieldSet(impl PluginServer) (FieldSet, error) {
+	sub, ok := impl.(FieldSubscriber)
+	if !ok {
+		return nil, nil
+	}
+
+	s, err := NewFieldSet(sub.RequestFields()...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugin request fields: %w", err)
+	}
+
+	return s, nil
+}
+
+// fieldsInterceptor serves the fields in s on GetCapabilities and, on HandleRequest calls where
+// mcpd applied them, stores them in the handler's context.
+func fieldsInterceptor(s FieldSet) grpc.UnaryServerInterceptor {
+	encoded := s.String()
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		switch info.FullMethod {
+		case Plugin_GetCapabilities_FullMethodName:
+			_ = grpc.SetHeader(ctx, metadata.Pairs(FieldsMetadataKey, encoded))
+		case Plugin_HandleRequest_FullMethodName:
+			if features.Enabled(ctx, features.SelectiveFields) {
+				ctx = context.WithValue(ctx, fieldsContextKey{}, s)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
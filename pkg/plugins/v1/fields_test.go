@@ -0,0 +1,219 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+)
+
+func TestNewFieldSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  []RequestField
+		want    FieldSet
+		wantErr string
+	}{
+		{name: "empty", want: FieldSet{}},
+		{
+			name: "sorted and deduplicated",
+			fields: []RequestField{
+				FieldPath, FieldMethod, " Method ", HeaderField("Authorization"), "HEADER:authorization",
+			},
+			want: FieldSet{"header:authorization", FieldMethod, FieldPath},
+		},
+		{
+			name: "every attribute",
+			fields: []RequestField{
+				FieldRequestURI, FieldRemoteAddr, FieldBody, FieldHeaders, FieldPath, FieldURL, FieldMethod,
+			},
+			want: FieldSet{
+				FieldBody, FieldHeaders, FieldMethod, FieldPath, FieldRemoteAddr, FieldRequestURI, FieldURL,
+			},
+		},
+		{name: "unknown field", fields: []RequestField{"cookies"}, wantErr: `unknown request field "cookies"`},
+		{
+			name:    "empty header name",
+			fields:  []RequestField{"header:"},
+			wantErr: `invalid header name in request field "header:"`,
+		},
+		{name: "header with a comma", fields: []RequestField{HeaderField("a,b")}, wantErr: "invalid header name"},
+		{name: "header with a space", fields: []RequestField{"header:x y"}, wantErr: "invalid header name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFieldSet(tt.fields...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NewFieldSet error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("NewFieldSet = %v, want %v", got, tt.want)
+			}
+			// The wire format round-trips.
+			parsed, err := ParseFieldSet(got.String())
+			if err != nil || !slices.Equal(parsed, got) {
+				t.Errorf("ParseFieldSet(%q) = %v, %v; want %v", got.String(), parsed, err, got)
+			}
+		})
+	}
+}
+
+func TestParseFieldSet(t *testing.T) {
+	got, err := ParseFieldSet(" path, ,method,header:X-Api-Key,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (FieldSet{"header:x-api-key", FieldMethod, FieldPath}); !slices.Equal(got, want) {
+		t.Errorf("ParseFieldSet = %v, want %v", got, want)
+	}
+	if _, err := ParseFieldSet("method,bogus"); err == nil {
+		t.Error("ParseFieldSet accepted an unknown field")
+	}
+}
+
+func TestFieldSetSelect(t *testing.T) {
+	req := &HTTPRequest{
+		Method:     "POST",
+		Url:        "http://upstream/mcp",
+		Path:       "/mcp",
+		Headers:    map[string]string{"Authorization": "Bearer t", "content-type": "application/json"},
+		Body:       []byte("{}"),
+		RemoteAddr: "10.0.0.1:1",
+		RequestUri: "/mcp",
+	}
+	tests := []struct {
+		name   string
+		fields []RequestField
+		want   *HTTPRequest
+	}{
+		{name: "nothing", want: &HTTPRequest{}},
+		{
+			name:   "method and one header",
+			fields: []RequestField{FieldMethod, HeaderField("authorization")},
+			want:   &HTTPRequest{Method: "POST", Headers: map[string]string{"Authorization": "Bearer t"}},
+		},
+		{
+			name:   "header matched case-insensitively",
+			fields: []RequestField{HeaderField("Content-Type")},
+			want:   &HTTPRequest{Headers: map[string]string{"content-type": "application/json"}},
+		},
+		{
+			name:   "all headers",
+			fields: []RequestField{FieldHeaders, HeaderField("authorization")},
+			want:   &HTTPRequest{Headers: req.Headers},
+		},
+		{
+			name: "everything",
+			fields: []RequestField{
+				FieldMethod, FieldURL, FieldPath, FieldHeaders, FieldBody, FieldRemoteAddr, FieldRequestURI,
+			},
+			want: req,
+		},
+		{
+			name:   "absent header",
+			fields: []RequestField{FieldPath, HeaderField("X-Missing")},
+			want:   &HTTPRequest{Path: "/mcp"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewFieldSet(tt.fields...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := s.Select(req)
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("Select = %v, want %v", got, tt.want)
+			}
+			if got.Headers != nil {
+				got.Headers["X-Added"] = "1"
+				if _, ok := req.Headers["X-Added"]; ok {
+					t.Error("Select shares the header map with its input")
+				}
+			}
+		})
+	}
+}
+
+// subscriberPlugin is a FieldSubscriber.
+type subscriberPlugin struct {
+	BasePlugin
+	fields []RequestField
+}
+
+func (p *subscriberPlugin) RequestFields() []RequestField { return p.fields }
+
+func TestFieldSetOfPlugin(t *testing.T) {
+	if s, err := fieldSet(&BasePlugin{}); s != nil || err != nil {
+		t.Errorf("fieldSet of a plain plugin = %v, %v; want nil", s, err)
+	}
+	s, err := fieldSet(&subscriberPlugin{fields: []RequestField{FieldPath, FieldMethod}})
+	if err != nil || s.String() != "method,path" {
+		t.Errorf("fieldSet = %v, %v; want method,path", s, err)
+	}
+	_, err = fieldSet(&subscriberPlugin{fields: []RequestField{"bogus"}})
+	if err == nil || !strings.Contains(err.Error(), "invalid plugin request fields") {
+		t.Errorf("fieldSet error = %v, want an invalid fields error", err)
+	}
+}
+
+func TestFieldsInterceptor(t *testing.T) {
+	s, err := NewFieldSet(FieldMethod, HeaderField("Authorization"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	selective := features.NewSet(features.SelectiveFields)
+	tests := []struct {
+		name       string
+		method     string
+		features   features.Set
+		wantHeader []string
+		wantFields bool
+	}{
+		{
+			name:       "capabilities list the fields",
+			method:     Plugin_GetCapabilities_FullMethodName,
+			wantHeader: []string{"header:authorization,method"},
+		},
+		{name: "selected request", method: Plugin_HandleRequest_FullMethodName, features: selective, wantFields: true},
+		{name: "full request", method: Plugin_HandleRequest_FullMethodName},
+		{name: "responses are sent whole", method: Plugin_HandleResponse_FullMethodName, features: selective},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := &headerStream{}
+			ctx := features.NewContext(context.Background(), tt.features)
+			ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+			var (
+				got FieldSet
+				ok  bool
+			)
+			_, err := fieldsInterceptor(s)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method},
+				func(ctx context.Context, _ any) (any, error) {
+					got, ok = SubscribedFields(ctx)
+					return nil, nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantFields || ok && !slices.Equal(got, s) {
+				t.Errorf("SubscribedFields = %v, %t; want %t", got, ok, tt.wantFields)
+			}
+			if header := stream.header.Get(FieldsMetadataKey); !slices.Equal(header, tt.wantHeader) {
+				t.Errorf("%s header = %q, want %q", FieldsMetadataKey, header, tt.wantHeader)
+			}
+		})
+	}
+}
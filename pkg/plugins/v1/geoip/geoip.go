@@ -0,0 +1,183 @@
+// Package geoip enriches calls with the country, region, city and autonomous system of the client
+// IP, so policy components can condition on where a request comes from.
+//
+// An Enricher maps an address to Attributes. OpenMMDB reads MaxMind DB files (GeoLite2 and GeoIP2
+// Country, City and ASN databases, or any database with the same record layout), and Chain
+// combines several, for example a City and an ASN database:
+//
+//	city, err := geoip.OpenMMDB("/var/lib/GeoIP/GeoLite2-City.mmdb")
+//	...
+//	asn, err := geoip.OpenMMDB("/var/lib/GeoIP/GeoLite2-ASN.mmdb")
+//	...
+//	err = mcpdpluginsv1.Serve(plugin, mcpdpluginsv1.WithUnaryInterceptor(
+//	    geoip.Interceptor(geoip.Chain(city, asn), nil),
+//	))
+//
+// Handlers then read the attributes with FromContext. Attributes.Map returns them as a plain map
+// for use as input to policy engines such as CEL or OPA.
+package geoip
+
+import (
+	"context"
+	"net"
+	"net/netip"
+
+	"google.golang.org/grpc"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// Attributes describes the network location of an IP address. Fields are empty when unknown.
+type Attributes struct {
+	// Country is the ISO 3166-1 alpha-2 country code, such as "DE".
+	Country string `json:"country,omitempty"`
+
+	// Continent is the two-letter continent code, such as "EU".
+	Continent string `json:"continent,omitempty"`
+
+	// Region is the ISO 3166-2 subdivision code of the most specific subdivision, such as "BE".
+	Region string `json:"region,omitempty"`
+
+	// City is the English city name.
+	City string `json:"city,omitempty"`
+
+	// ASN is the autonomous system number, and ASOrg the organization it is registered to.
+	ASN   uint32 `json:"asn,omitempty"`
+	ASOrg string `json:"asOrg,omitempty"`
+}
+
+// IsZero reports whether no attribute is known.
+func (a Attributes) IsZero() bool {
+	return a == Attributes{}
+}
+
+// Map returns the attributes keyed by their JSON names, omitting unknown ones, for use as a
+// policy evaluation context (for example the "geo" variable of a CEL expression).
+func (a Attributes) Map() map[string]any {
+	m := map[string]any{}
+	for k, v := range map[string]string{
+		"country":   a.Country,
+		"continent": a.Continent,
+		"region":    a.Region,
+		"city":      a.City,
+		"asOrg":     a.ASOrg,
+	} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	if a.ASN != 0 {
+		m["asn"] = int64(a.ASN)
+	}
+
+	return m
+}
+
+// merge fills the fields of a that are empty from b.
+func (a Attributes) merge(b Attributes) Attributes {
+	if a.Country == "" {
+		a.Country = b.Country
+	}
+	if a.Continent == "" {
+		a.Continent = b.Continent
+	}
+	if a.Region == "" {
+		a.Region = b.Region
+	}
+	if a.City == "" {
+		a.City = b.City
+	}
+	if a.ASN == 0 {
+		a.ASN, a.ASOrg = b.ASN, b.ASOrg
+	}
+
+	return a
+}
+
+// Enricher looks up the attributes of an IP address. It returns zero Attributes and a nil error
+// when the address is not found. Implementations must be safe for concurrent use.
+type Enricher interface {
+	Lookup(ip netip.Addr) (Attributes, error)
+}
+
+// EnricherFunc adapts a function to the Enricher interface.
+type EnricherFunc func(ip netip.Addr) (Attributes, error)
+
+// Lookup implements Enricher.
+func (f EnricherFunc) Lookup(ip netip.Addr) (Attributes, error) {
+	return f(ip)
+}
+
+// Chain returns an Enricher that queries enrichers in order, each filling the attributes left
+// empty by the previous ones. Lookup errors are returned once all enrichers have been queried.
+func Chain(enrichers ...Enricher) Enricher {
+	return EnricherFunc(func(ip netip.Addr) (Attributes, error) {
+		var (
+			out      Attributes
+			firstErr error
+		)
+		for _, e := range enrichers {
+			a, err := e.Lookup(ip)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			out = out.merge(a)
+		}
+
+		return out, firstErr
+	})
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying a, for use in tests and custom dispatch.
+func NewContext(ctx context.Context, a Attributes) context.Context {
+	return context.WithValue(ctx, contextKey{}, a)
+}
+
+// FromContext returns the attributes of the current call's client, and whether any were found.
+func FromContext(ctx context.Context) (Attributes, bool) {
+	a, ok := ctx.Value(contextKey{}).(Attributes)
+	return a, ok && !a.IsZero()
+}
+
+// ClientIPFunc returns the client IP of a request, or the zero Addr when unknown.
+// ipfilter.Filter.ClientIP has this signature and handles trusted proxies.
+type ClientIPFunc func(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) netip.Addr
+
+// Interceptor looks up the client of every HandleRequest call with e and stores the attributes in
+// the handler's context. clientIP resolves the address; nil uses the request's remote address.
+// Lookup errors leave the context without attributes.
+func Interceptor(e Enricher, clientIP ClientIPFunc) grpc.UnaryServerInterceptor {
+	if clientIP == nil {
+		clientIP = remoteAddr
+	}
+
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		in, ok := req.(*mcpdpluginsv1.HTTPRequest)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		if ip := clientIP(ctx, in); ip.IsValid() {
+			if a, err := e.Lookup(ip); err == nil && !a.IsZero() {
+				ctx = NewContext(ctx, a)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func remoteAddr(_ context.Context, req *mcpdpluginsv1.HTTPRequest) netip.Addr {
+	host, _, err := net.SplitHostPort(req.GetRemoteAddr())
+	if err != nil {
+		host = req.GetRemoteAddr()
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	return addr.Unmap()
+}
@@ -0,0 +1,179 @@
+package geoip_test
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/netip"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/geoip"
+)
+
+func TestAttributesMap(t *testing.T) {
+	tests := []struct {
+		name string
+		a    geoip.Attributes
+		want map[string]any
+	}{
+		{name: "zero", want: map[string]any{}},
+		{
+			name: "all fields",
+			a: geoip.Attributes{
+				Country: "GB", Continent: "EU", Region: "ENG", City: "London", ASN: 64500, ASOrg: "Example",
+			},
+			want: map[string]any{
+				"country": "GB", "continent": "EU", "region": "ENG", "city": "London",
+				"asn": int64(64500), "asOrg": "Example",
+			},
+		},
+		{name: "unknown fields omitted", a: geoip.Attributes{Country: "FR"}, want: map[string]any{"country": "FR"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Map(); !maps.Equal(got, tt.want) {
+				t.Errorf("Map() = %v, want %v", got, tt.want)
+			}
+			if got := tt.a.IsZero(); got != (len(tt.want) == 0) {
+				t.Errorf("IsZero() = %t", got)
+			}
+		})
+	}
+}
+
+// fixed returns an Enricher answering a and err for every address.
+func fixed(a geoip.Attributes, err error) geoip.Enricher {
+	return geoip.EnricherFunc(func(netip.Addr) (geoip.Attributes, error) { return a, err })
+}
+
+func TestChain(t *testing.T) {
+	errFirst, errSecond := errors.New("first"), errors.New("second")
+	tests := []struct {
+		name      string
+		enrichers []geoip.Enricher
+		want      geoip.Attributes
+		wantErr   error
+	}{
+		{name: "none"},
+		{
+			name: "earlier enrichers win",
+			enrichers: []geoip.Enricher{
+				fixed(geoip.Attributes{Country: "GB", City: "London"}, nil),
+				fixed(geoip.Attributes{Country: "FR", ASN: 64500, ASOrg: "Example"}, nil),
+			},
+			want: geoip.Attributes{Country: "GB", City: "London", ASN: 64500, ASOrg: "Example"},
+		},
+		{
+			name: "failures do not stop the chain",
+			enrichers: []geoip.Enricher{
+				fixed(geoip.Attributes{}, errFirst),
+				fixed(geoip.Attributes{Country: "FR"}, errSecond),
+				fixed(geoip.Attributes{ASN: 64500}, nil),
+			},
+			want:    geoip.Attributes{Country: "FR", ASN: 64500},
+			wantErr: errFirst,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := geoip.Chain(tt.enrichers...).Lookup(netip.MustParseAddr("192.0.2.1"))
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("Lookup error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Lookup = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := geoip.FromContext(context.Background()); ok {
+		t.Error("FromContext found attributes in an empty context")
+	}
+	if _, ok := geoip.FromContext(geoip.NewContext(context.Background(), geoip.Attributes{})); ok {
+		t.Error("FromContext reported zero attributes as known")
+	}
+	want := geoip.Attributes{Country: "GB"}
+	if got, ok := geoip.FromContext(geoip.NewContext(context.Background(), want)); !ok || got != want {
+		t.Errorf("FromContext = %+v, %t; want %+v", got, ok, want)
+	}
+}
+
+func TestInterceptor(t *testing.T) {
+	london := geoip.Attributes{Country: "GB", City: "London"}
+	byIP := geoip.EnricherFunc(func(ip netip.Addr) (geoip.Attributes, error) {
+		switch ip.String() {
+		case "81.2.69.160", "2001:db8::1":
+			return london, nil
+		case "192.0.2.1":
+			return london, errors.New("lookup failed")
+		}
+		return geoip.Attributes{}, nil
+	})
+	tests := []struct {
+		name     string
+		req      any
+		clientIP geoip.ClientIPFunc
+		want     geoip.Attributes
+		wantOK   bool
+	}{
+		{
+			name:   "remote address with port",
+			req:    &mcpdpluginsv1.HTTPRequest{RemoteAddr: "81.2.69.160:4711"},
+			want:   london,
+			wantOK: true,
+		},
+		{
+			name:   "bare remote address",
+			req:    &mcpdpluginsv1.HTTPRequest{RemoteAddr: "81.2.69.160"},
+			want:   london,
+			wantOK: true,
+		},
+		{
+			name:   "bracketed IPv6",
+			req:    &mcpdpluginsv1.HTTPRequest{RemoteAddr: "[2001:db8::1]:443"},
+			want:   london,
+			wantOK: true,
+		},
+		{name: "unknown address", req: &mcpdpluginsv1.HTTPRequest{RemoteAddr: "198.51.100.1:1"}},
+		{name: "lookup error", req: &mcpdpluginsv1.HTTPRequest{RemoteAddr: "192.0.2.1:1"}},
+		{name: "invalid remote address", req: &mcpdpluginsv1.HTTPRequest{RemoteAddr: "unix"}},
+		{name: "no remote address", req: &mcpdpluginsv1.HTTPRequest{}},
+		{
+			name: "custom client IP",
+			req:  &mcpdpluginsv1.HTTPRequest{RemoteAddr: "10.0.0.1:1"},
+			clientIP: func(context.Context, *mcpdpluginsv1.HTTPRequest) netip.Addr {
+				return netip.MustParseAddr("81.2.69.160")
+			},
+			want:   london,
+			wantOK: true,
+		},
+		{name: "not a request", req: &mcpdpluginsv1.HTTPResponse{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				got    geoip.Attributes
+				gotOK  bool
+				called bool
+			)
+			handler := func(ctx context.Context, req any) (any, error) {
+				called = true
+				got, gotOK = geoip.FromContext(ctx)
+				return req, nil
+			}
+			info := &grpc.UnaryServerInfo{FullMethod: mcpdpluginsv1.Plugin_HandleRequest_FullMethodName}
+			resp, err := geoip.Interceptor(byIP, tt.clientIP)(context.Background(), tt.req, info, handler)
+			if err != nil || resp != tt.req || !called {
+				t.Fatalf("interceptor = %v, %v; want the handler's result", resp, err)
+			}
+			if got != tt.want || gotOK != tt.wantOK {
+				t.Errorf("FromContext = %+v, %t; want %+v, %t", got, gotOK, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
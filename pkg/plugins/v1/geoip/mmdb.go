@@ -0,0 +1,440 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"os"
+)
+
+// metadataMarker precedes the metadata section at the end of a MaxMind DB file.
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// maxMetadataSize bounds the tail of the file searched for the metadata marker.
+const maxMetadataSize = 128 * 1024
+
+// dataSectionSeparator is the number of zero bytes between the search tree and the data section.
+const dataSectionSeparator = 16
+
+// ErrInvalidDatabase is returned (wrapped) when a MaxMind DB file is malformed.
+var ErrInvalidDatabase = errors.New("invalid MaxMind DB")
+
+// MMDBMetadata describes a MaxMind DB file.
+type MMDBMetadata struct {
+	DatabaseType string
+	IPVersion    int
+	NodeCount    int
+	RecordSize   int
+	BuildEpoch   uint64
+}
+
+// MMDB is an Enricher reading a MaxMind DB file held in memory. It is safe for concurrent use.
+type MMDB struct {
+	meta      MMDBMetadata
+	tree      []byte
+	data      []byte
+	ipv4Start int
+}
+
+// OpenMMDB reads the MaxMind DB file at path.
+func OpenMMDB(path string) (*MMDB, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	db, err := NewMMDB(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return db, nil
+}
+
+// NewMMDB parses a MaxMind DB file from its contents. b must not be modified afterwards.
+func NewMMDB(b []byte) (*MMDB, error) {
+	tail := b[max(0, len(b)-maxMetadataSize):]
+	i := bytes.LastIndex(tail, metadataMarker)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: metadata marker not found", ErrInvalidDatabase)
+	}
+	markerStart := len(b) - len(tail) + i
+	metaStart := markerStart + len(metadataMarker)
+
+	raw, _, err := (&decoder{buf: b[metaStart:]}).decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: metadata: %w", ErrInvalidDatabase, err)
+	}
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", ErrInvalidDatabase)
+	}
+
+	meta := MMDBMetadata{
+		DatabaseType: stringField(m, "database_type"),
+		IPVersion:    int(uintField(m, "ip_version")),
+		NodeCount:    int(uintField(m, "node_count")),
+		RecordSize:   int(uintField(m, "record_size")),
+		BuildEpoch:   uintField(m, "build_epoch"),
+	}
+	switch meta.RecordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidDatabase, meta.RecordSize)
+	}
+	if meta.IPVersion != 4 && meta.IPVersion != 6 {
+		return nil, fmt.Errorf("%w: unsupported IP version %d", ErrInvalidDatabase, meta.IPVersion)
+	}
+
+	treeSize := meta.NodeCount * meta.RecordSize / 4
+	dataStart := treeSize + dataSectionSeparator
+	if meta.NodeCount <= 0 || dataStart > markerStart {
+		return nil, fmt.Errorf("%w: search tree exceeds file size", ErrInvalidDatabase)
+	}
+
+	db := &MMDB{
+		meta: meta,
+		tree: b[:treeSize],
+		data: b[dataStart:markerStart],
+	}
+	if meta.IPVersion == 6 {
+		// IPv4 addresses live under ::/96; find that subtree once.
+		node := 0
+		for range 96 {
+			if node >= meta.NodeCount {
+				break
+			}
+			node = db.record(node, 0)
+		}
+		db.ipv4Start = node
+	}
+
+	return db, nil
+}
+
+// Metadata returns the database's metadata.
+func (db *MMDB) Metadata() MMDBMetadata {
+	return db.meta
+}
+
+// Lookup implements Enricher, reading the GeoIP2/GeoLite2 Country, City and ASN record fields.
+func (db *MMDB) Lookup(ip netip.Addr) (Attributes, error) {
+	rec, err := db.LookupRecord(ip)
+	if err != nil || rec == nil {
+		return Attributes{}, err
+	}
+
+	var a Attributes
+	a.Country = stringField(mapField(rec, "country"), "iso_code")
+	if a.Country == "" {
+		a.Country = stringField(mapField(rec, "registered_country"), "iso_code")
+	}
+	a.Continent = stringField(mapField(rec, "continent"), "code")
+	if subs, ok := rec["subdivisions"].([]any); ok && len(subs) > 0 {
+		if s, ok := subs[len(subs)-1].(map[string]any); ok {
+			a.Region = stringField(s, "iso_code")
+		}
+	}
+	a.City = stringField(mapField(mapField(rec, "city"), "names"), "en")
+	if asn := uintField(rec, "autonomous_system_number"); asn <= math.MaxUint32 {
+		a.ASN = uint32(asn)
+	}
+	a.ASOrg = stringField(rec, "autonomous_system_organization")
+
+	return a, nil
+}
+
+// LookupRecord returns the raw record for ip, or nil when the address is not in the database.
+// Maps decode to map[string]any, arrays to []any, unsigned integers to uint64 (or *big.Int for
+// 128-bit values), int32 to int64, and doubles and floats to float64.
+func (db *MMDB) LookupRecord(ip netip.Addr) (map[string]any, error) {
+	ip = ip.Unmap()
+	if !ip.IsValid() {
+		return nil, fmt.Errorf("invalid IP address")
+	}
+
+	node, bits := 0, 128
+	if ip.Is4() {
+		bits = 32
+		if db.meta.IPVersion == 6 {
+			node = db.ipv4Start
+		}
+	} else if db.meta.IPVersion == 4 {
+		return nil, nil
+	}
+
+	addr := ip.AsSlice()
+	for i := 0; i < bits && node < db.meta.NodeCount; i++ {
+		bit := int(addr[i/8]>>(7-uint(i%8))) & 1
+		node = db.record(node, bit)
+	}
+	if node == db.meta.NodeCount {
+		return nil, nil
+	}
+	if node < db.meta.NodeCount {
+		return nil, fmt.Errorf("%w: search tree deeper than address", ErrInvalidDatabase)
+	}
+
+	offset := node - db.meta.NodeCount - dataSectionSeparator
+	if offset < 0 || offset >= len(db.data) {
+		return nil, fmt.Errorf("%w: data pointer out of range", ErrInvalidDatabase)
+	}
+	v, _, err := (&decoder{buf: db.data}).decode(offset, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDatabase, err)
+	}
+	rec, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: record is not a map", ErrInvalidDatabase)
+	}
+
+	return rec, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of node.
+func (db *MMDB) record(node, bit int) int {
+	switch db.meta.RecordSize {
+	case 24:
+		b := db.tree[node*6+bit*3:]
+		return int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+	case 28:
+		b := db.tree[node*7:]
+		if bit == 0 {
+			return int(b[3]&0xf0)<<20 | int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+		}
+		return int(b[3]&0x0f)<<24 | int(b[4])<<16 | int(b[5])<<8 | int(b[6])
+	default:
+		return int(binary.BigEndian.Uint32(db.tree[node*8+bit*4:]))
+	}
+}
+
+// Data field types of the MaxMind DB format.
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// maxDepth bounds nesting to reject malicious files.
+const maxDepth = 64
+
+// decoder decodes the MaxMind DB data section format. Pointers are offsets into buf.
+type decoder struct {
+	buf []byte
+}
+
+// decode decodes the field at offset and returns it with the offset following it.
+func (d *decoder) decode(offset, depth int) (any, int, error) {
+	if depth > maxDepth {
+		return nil, 0, errors.New("data nested too deeply")
+	}
+
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == typePointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := d.decode(target, depth+1)
+		return v, next, err
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]any, min(size, 64))
+		for range size {
+			k, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			v, next, err := d.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[key], offset = v, next
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, min(size, 64))
+		for range size {
+			v, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a, offset = append(a, v), next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > len(d.buf) {
+		return nil, 0, errors.New("field exceeds data section")
+	}
+	b := d.buf[offset : offset+size]
+	next := offset + size
+
+	switch typ {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes:
+		return bytes.Clone(b), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("invalid double size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("invalid float size %d", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		maxSize := 8
+		switch typ {
+		case typeUint16:
+			maxSize = 2
+		case typeUint32:
+			maxSize = 4
+		}
+		if size > maxSize {
+			return nil, 0, fmt.Errorf("invalid integer size %d", size)
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, next, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("invalid int32 size %d", size)
+		}
+		var n uint32
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		return int64(int32(n)), next, nil
+	case typeUint128:
+		if size > 16 {
+			return nil, 0, fmt.Errorf("invalid uint128 size %d", size)
+		}
+		return new(big.Int).SetBytes(b), next, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported data type %d", typ)
+	}
+}
+
+// control reads the control byte(s) at offset, returning the type, the payload size (or pointer
+// size bits), and the offset of the payload.
+func (d *decoder) control(offset int) (int, int, int, error) {
+	if offset >= len(d.buf) {
+		return 0, 0, 0, errors.New("offset exceeds data section")
+	}
+	ctrl := d.buf[offset]
+	offset++
+
+	typ := int(ctrl >> 5)
+	if typ == typeExtended {
+		if offset >= len(d.buf) {
+			return 0, 0, 0, errors.New("truncated extended type")
+		}
+		typ = 7 + int(d.buf[offset])
+		offset++
+		if typ < typeInt32 {
+			return 0, 0, 0, fmt.Errorf("invalid extended type %d", typ)
+		}
+	}
+	if typ == typePointer {
+		// Pointers keep the raw low bits; pointer decodes them.
+		return typ, int(ctrl & 0x1f), offset, nil
+	}
+
+	size := int(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > len(d.buf) {
+			return 0, 0, 0, errors.New("truncated field size")
+		}
+		var ext int
+		for _, c := range d.buf[offset : offset+n] {
+			ext = ext<<8 | int(c)
+		}
+		offset += n
+		switch size {
+		case 29:
+			size = 29 + ext
+		case 30:
+			size = 285 + ext
+		default:
+			size = 65821 + ext
+		}
+	}
+
+	return typ, size, offset, nil
+}
+
+// pointer decodes a pointer whose control bits are bits and payload starts at offset, returning
+// the target offset and the offset following the pointer.
+func (d *decoder) pointer(bits, offset int) (int, int, error) {
+	n := (bits>>3)&0x3 + 1
+	if offset+n > len(d.buf) {
+		return 0, 0, errors.New("truncated pointer")
+	}
+	b := d.buf[offset : offset+n]
+
+	var p int
+	if n < 4 {
+		p = bits & 0x7
+	}
+	for _, c := range b {
+		p = p<<8 | int(c)
+	}
+	switch n {
+	case 2:
+		p += 2048
+	case 3:
+		p += 526336
+	}
+
+	return p, offset + n, nil
+}
+
+func mapField(m map[string]any, key string) map[string]any {
+	v, _ := m[key].(map[string]any)
+	return v
+}
+
+func stringField(m map[string]any, key string) string {
+	v, _ := m[key].(string)
+	return v
+}
+
+func uintField(m map[string]any, key string) uint64 {
+	v, _ := m[key].(uint64)
+	return v
+}
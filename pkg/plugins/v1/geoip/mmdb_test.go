@@ -0,0 +1,491 @@
+package geoip_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/geoip"
+)
+
+// Values with an explicit MaxMind DB encoding, for building test databases.
+type (
+	// raw is emitted verbatim.
+	raw []byte
+	// selfPointer is a pointer to its own offset.
+	selfPointer struct{}
+	// pointer is a pointer to the value at the given offset of the data section.
+	pointer int
+	// dataOffset is a search tree record pointing at the given data section offset, which may lie
+	// beyond the data section.
+	dataOffset int
+)
+
+// mmdbEncoder encodes values in the MaxMind DB data section format.
+type mmdbEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *mmdbEncoder) control(typ, size int) {
+	var ext []byte
+	switch {
+	case size < 29:
+	case size < 285:
+		ext, size = []byte{byte(size - 29)}, 29
+	default:
+		ext, size = []byte{byte((size - 285) >> 8), byte(size - 285)}, 30
+	}
+	if typ <= 7 {
+		e.buf.WriteByte(byte(typ<<5 | size))
+	} else {
+		e.buf.WriteByte(byte(size))
+		e.buf.WriteByte(byte(typ - 7))
+	}
+	e.buf.Write(ext)
+}
+
+func (e *mmdbEncoder) pointer(p int) {
+	switch {
+	case p < 2048:
+		e.buf.Write([]byte{byte(1<<5 | p>>8), byte(p)})
+	case p < 526336:
+		p -= 2048
+		e.buf.Write([]byte{byte(1<<5 | 1<<3 | p>>16), byte(p >> 8), byte(p)})
+	default:
+		e.buf.WriteByte(1<<5 | 3<<3)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(p)))
+	}
+}
+
+func (e *mmdbEncoder) encode(v any) {
+	switch v := v.(type) {
+	case raw:
+		e.buf.Write(v)
+	case selfPointer:
+		e.pointer(e.buf.Len())
+	case pointer:
+		e.pointer(int(v))
+	case string:
+		e.control(2, len(v))
+		e.buf.WriteString(v)
+	case float64:
+		e.control(3, 8)
+		e.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(v)))
+	case []byte:
+		e.control(4, len(v))
+		e.buf.Write(v)
+	case uint16:
+		e.control(5, 2)
+		e.buf.Write(binary.BigEndian.AppendUint16(nil, v))
+	case uint32:
+		e.control(6, 4)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, v))
+	case map[string]any:
+		e.control(7, len(v))
+		for _, k := range slices.Sorted(mapsKeys(v)) {
+			e.encode(k)
+			e.encode(v[k])
+		}
+	case int32:
+		e.control(8, 4)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(v)))
+	case uint64:
+		e.control(9, 8)
+		e.buf.Write(binary.BigEndian.AppendUint64(nil, v))
+	case *big.Int:
+		b := v.Bytes()
+		e.control(10, len(b))
+		e.buf.Write(b)
+	case []any:
+		e.control(11, len(v))
+		for _, x := range v {
+			e.encode(x)
+		}
+	case bool:
+		size := 0
+		if v {
+			size = 1
+		}
+		e.control(14, size)
+	case float32:
+		e.control(15, 4)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(v)))
+	default:
+		panic("unsupported test value")
+	}
+}
+
+func mapsKeys(m map[string]any) func(func(string) bool) {
+	return func(yield func(string) bool) {
+		for k := range m {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// trieNode is a search tree node; each child is a node, a data offset, or empty.
+type trieNode struct {
+	child [2]*trieNode
+	data  [2]int // Data offset + 1 of a leaf record; 0 when none.
+	index int
+}
+
+// buildMMDB returns a MaxMind DB with the given record size mapping each prefix to its record.
+// For IPv6 databases, IPv4 prefixes are placed under ::/96.
+func buildMMDB(t *testing.T, ipVersion, recordSize int, records map[string]any) []byte {
+	t.Helper()
+
+	root := &trieNode{}
+	var data mmdbEncoder
+	for _, p := range slices.Sorted(mapsKeys(records)) {
+		prefix := netip.MustParsePrefix(p)
+		addr, bits := prefix.Addr().AsSlice(), prefix.Bits()
+		if ipVersion == 6 && prefix.Addr().Is4() {
+			addr, bits = append(make([]byte, 12), addr...), bits+96
+		}
+		offset := data.buf.Len()
+		if d, ok := records[p].(dataOffset); ok {
+			offset = int(d)
+		} else {
+			data.encode(records[p])
+		}
+
+		n := root
+		for i := range bits - 1 {
+			bit := int(addr[i/8]>>(7-uint(i%8))) & 1
+			if n.child[bit] == nil {
+				n.child[bit] = &trieNode{}
+			}
+			n = n.child[bit]
+		}
+		last := int(addr[(bits-1)/8]>>(7-uint((bits-1)%8))) & 1
+		n.data[last] = offset + 1
+	}
+
+	var nodes []*trieNode
+	for queue := []*trieNode{root}; len(queue) > 0; queue = queue[1:] {
+		n := queue[0]
+		n.index = len(nodes)
+		nodes = append(nodes, n)
+		for _, c := range n.child {
+			if c != nil {
+				queue = append(queue, c)
+			}
+		}
+	}
+
+	var tree []byte
+	recordValue := func(n *trieNode, bit int) uint32 {
+		switch {
+		case n.child[bit] != nil:
+			return uint32(n.child[bit].index)
+		case n.data[bit] != 0:
+			return uint32(len(nodes) + 16 + n.data[bit] - 1)
+		default:
+			return uint32(len(nodes))
+		}
+	}
+	for _, n := range nodes {
+		l, r := recordValue(n, 0), recordValue(n, 1)
+		switch recordSize {
+		case 24:
+			tree = append(tree, byte(l>>16), byte(l>>8), byte(l), byte(r>>16), byte(r>>8), byte(r))
+		case 28:
+			tree = append(tree, byte(l>>16), byte(l>>8), byte(l), byte(l>>24&0xf)<<4|byte(r>>24&0xf),
+				byte(r>>16), byte(r>>8), byte(r))
+		default:
+			tree = binary.BigEndian.AppendUint32(tree, l)
+			tree = binary.BigEndian.AppendUint32(tree, r)
+		}
+	}
+
+	return assembleMMDB(tree, data.buf.Bytes(), map[string]any{
+		"database_type": "Test-City",
+		"ip_version":    uint16(ipVersion),
+		"node_count":    uint32(len(nodes)),
+		"record_size":   uint16(recordSize),
+		"build_epoch":   uint64(1_700_000_000),
+	})
+}
+
+func assembleMMDB(tree, data []byte, meta any) []byte {
+	var m mmdbEncoder
+	m.encode(meta)
+
+	b := slices.Clone(tree)
+	b = append(b, make([]byte, 16)...)
+	b = append(b, data...)
+	b = append(b, "\xab\xcd\xefMaxMind.com"...)
+
+	return append(b, m.buf.Bytes()...)
+}
+
+func cityRecord(country, city string, subdivisions ...string) map[string]any {
+	rec := map[string]any{
+		"country":   map[string]any{"iso_code": country},
+		"continent": map[string]any{"code": "EU"},
+		"city":      map[string]any{"names": map[string]any{"en": city, "de": city + "-de"}},
+	}
+	var subs []any
+	for _, s := range subdivisions {
+		subs = append(subs, map[string]any{"iso_code": s})
+	}
+	if subs != nil {
+		rec["subdivisions"] = subs
+	}
+
+	return rec
+}
+
+func TestMMDBLookup(t *testing.T) {
+	records := map[string]any{
+		"81.2.69.0/24": cityRecord("GB", "London", "ENG", "LND"),
+		"89.160.20.0/22": map[string]any{
+			"registered_country":             map[string]any{"iso_code": "SE"},
+			"autonomous_system_number":       uint32(29518),
+			"autonomous_system_organization": "Bredband2 AB",
+		},
+		"2001:db8::/32": cityRecord("DE", "Berlin", "BE"),
+		"10.0.0.0/8":    map[string]any{"autonomous_system_number": uint64(math.MaxUint32 + 1)},
+	}
+	london := geoip.Attributes{Country: "GB", Continent: "EU", Region: "LND", City: "London"}
+	lookups := []struct {
+		ip   string
+		want geoip.Attributes
+		v6   bool // Only found in IPv6 databases.
+	}{
+		{ip: "81.2.69.160", want: london},
+		{ip: "::ffff:81.2.69.1", want: london},
+		{ip: "89.160.23.255", want: geoip.Attributes{Country: "SE", ASN: 29518, ASOrg: "Bredband2 AB"}},
+		{ip: "89.160.24.0"},
+		{ip: "10.1.2.3"}, // ASN out of range is dropped.
+		{
+			ip:   "2001:db8::1",
+			want: geoip.Attributes{Country: "DE", Continent: "EU", Region: "BE", City: "Berlin"},
+			v6:   true,
+		},
+		{ip: "2001:db9::1"},
+	}
+	for _, ipVersion := range []int{4, 6} {
+		for _, recordSize := range []int{24, 28, 32} {
+			db4 := records
+			if ipVersion == 4 {
+				db4 = map[string]any{}
+				for p, r := range records {
+					if netip.MustParsePrefix(p).Addr().Is4() {
+						db4[p] = r
+					}
+				}
+			}
+			db, err := geoip.NewMMDB(buildMMDB(t, ipVersion, recordSize, db4))
+			if err != nil {
+				t.Fatalf("IPv%d, %d-bit records: %v", ipVersion, recordSize, err)
+			}
+			if m := db.Metadata(); m.IPVersion != ipVersion || m.RecordSize != recordSize ||
+				m.DatabaseType != "Test-City" || m.BuildEpoch != 1_700_000_000 || m.NodeCount == 0 {
+				t.Errorf("IPv%d, %d-bit records: Metadata = %+v", ipVersion, recordSize, m)
+			}
+
+			for _, l := range lookups {
+				got, err := db.Lookup(netip.MustParseAddr(l.ip))
+				if err != nil {
+					t.Errorf("IPv%d, %d-bit records: Lookup(%s): %v", ipVersion, recordSize, l.ip, err)
+					continue
+				}
+				want := l.want
+				if l.v6 && ipVersion == 4 {
+					want = geoip.Attributes{}
+				}
+				if got != want {
+					t.Errorf("IPv%d, %d-bit records: Lookup(%s) = %+v, want %+v",
+						ipVersion, recordSize, l.ip, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestLookupRecordTypes(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	db, err := geoip.NewMMDB(buildMMDB(t, 6, 28, map[string]any{
+		"198.51.100.0/24": map[string]any{
+			"int32":   int32(-5),
+			"true":    true,
+			"false":   false,
+			"double":  2.5,
+			"float":   float32(0.5),
+			"uint16":  uint16(443),
+			"uint64":  uint64(1 << 40),
+			"uint128": new(big.Int).Lsh(big.NewInt(1), 100),
+			"bytes":   []byte{0, 1, 2},
+			"array":   []any{"a", uint16(1)},
+			"medium":  strings.Repeat("m", 100),
+			"long":    long,
+			"empty":   map[string]any{},
+		},
+		// Records are encoded in prefix order, so the one above starts the data section.
+		"203.0.113.0/24": map[string]any{"alias": pointer(0)},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := db.LookupRecord(netip.MustParseAddr("198.51.100.9"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"int32":   int64(-5),
+		"true":    true,
+		"false":   false,
+		"double":  2.5,
+		"float":   0.5,
+		"uint16":  uint64(443),
+		"uint64":  uint64(1 << 40),
+		"uint128": new(big.Int).Lsh(big.NewInt(1), 100),
+		"bytes":   []byte{0, 1, 2},
+		"array":   []any{"a", uint64(1)},
+		"medium":  strings.Repeat("m", 100),
+		"long":    long,
+		"empty":   map[string]any{},
+	}
+	if !reflect.DeepEqual(rec, want) {
+		t.Errorf("LookupRecord = %#v, want %#v", rec, want)
+	}
+
+	alias, err := db.LookupRecord(netip.MustParseAddr("203.0.113.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(alias["alias"], want) {
+		t.Errorf("pointer decoded to %#v, want the record it points at", alias["alias"])
+	}
+}
+
+func TestLookupRecordNotFound(t *testing.T) {
+	db, err := geoip.NewMMDB(buildMMDB(t, 4, 24, map[string]any{"192.0.2.0/24": cityRecord("FR", "Paris")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ip := range []string{"192.0.3.1", "2001:db8::1"} {
+		if rec, err := db.LookupRecord(netip.MustParseAddr(ip)); rec != nil || err != nil {
+			t.Errorf("LookupRecord(%s) = %v, %v; want not found", ip, rec, err)
+		}
+	}
+	if _, err := db.LookupRecord(netip.Addr{}); err == nil {
+		t.Error("LookupRecord accepted the zero address")
+	}
+}
+
+func TestNewMMDBErrors(t *testing.T) {
+	valid := buildMMDB(t, 4, 24, map[string]any{"192.0.2.0/24": cityRecord("FR", "Paris")})
+	meta := func(ipVersion, nodeCount, recordSize int) map[string]any {
+		return map[string]any{
+			"ip_version":  uint16(ipVersion),
+			"node_count":  uint32(nodeCount),
+			"record_size": uint16(recordSize),
+		}
+	}
+	tests := []struct {
+		name string
+		b    []byte
+		want string
+	}{
+		{"empty", nil, "metadata marker not found"},
+		{"no marker", valid[:100], "metadata marker not found"},
+		{"metadata not a map", assembleMMDB(nil, nil, "hello"), "metadata is not a map"},
+		{"truncated metadata", assembleMMDB(nil, nil, raw{0xe2, 0x42}), "metadata:"},
+		{"record size", assembleMMDB(make([]byte, 10), nil, meta(4, 1, 20)), "unsupported record size 20"},
+		{"IP version", assembleMMDB(make([]byte, 6), nil, meta(5, 1, 24)), "unsupported IP version 5"},
+		{"tree too large", assembleMMDB(make([]byte, 6), nil, meta(4, 50, 24)), "search tree exceeds file size"},
+		{"no nodes", assembleMMDB(nil, nil, meta(4, 0, 24)), "search tree exceeds file size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := geoip.NewMMDB(tt.b)
+			if !errors.Is(err, geoip.ErrInvalidDatabase) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewMMDB error = %v, want ErrInvalidDatabase %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupRecordCorrupt(t *testing.T) {
+	tests := []struct {
+		name   string
+		record any
+		want   string
+	}{
+		{"data pointer out of range", dataOffset(1000), "data pointer out of range"},
+		{"record not a map", "just a string", "record is not a map"},
+		{"non-string map key", raw{0xe1, 0xa1, 0x05, 0x41, 'x'}, "map key is not a string"},
+		{"truncated field", raw{0x4a, 'a', 'b'}, "field exceeds data section"},
+		{"pointer loop", selfPointer{}, "data nested too deeply"},
+		{"truncated pointer", raw{0x38}, "truncated pointer"},
+		{"invalid extended type", raw{0x00, 0x00}, "invalid extended type 7"},
+		{"truncated extended type", raw{0x00}, "truncated extended type"},
+		{"truncated size", raw{0x5e, 0x01}, "truncated field size"},
+		{"double size", raw{0xe1, 0x41, 'd', 0x64, 1, 2, 3, 4}, "invalid double size 4"},
+		{"float size", raw{0xe1, 0x41, 'f', 0x02, 0x08, 1, 2}, "invalid float size 2"},
+		{"uint16 size", raw{0xe1, 0x41, 'u', 0xa3, 1, 2, 3}, "invalid integer size 3"},
+		{"int32 size", raw{0xe1, 0x41, 'i', 0x05, 0x01, 1, 2, 3, 4, 5}, "invalid int32 size 5"},
+		{
+			"uint128 size",
+			raw(append([]byte{0xe1, 0x41, 'b', 0x11, 0x03}, make([]byte, 17)...)),
+			"invalid uint128 size 17",
+		},
+		{"unsupported type", raw{0xe1, 0x41, 'c', 0x00, 0x05}, "unsupported data type 12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := geoip.NewMMDB(buildMMDB(t, 4, 24, map[string]any{"192.0.2.0/24": tt.record}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = db.Lookup(netip.MustParseAddr("192.0.2.1"))
+			if !errors.Is(err, geoip.ErrInvalidDatabase) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Lookup error = %v, want ErrInvalidDatabase %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenMMDB(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "city.mmdb")
+	b := buildMMDB(t, 6, 24, map[string]any{"192.0.2.0/24": cityRecord("FR", "Paris")})
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := geoip.OpenMMDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, err := db.Lookup(netip.MustParseAddr("192.0.2.1")); err != nil || a.City != "Paris" {
+		t.Errorf("Lookup = %+v, %v; want Paris", a, err)
+	}
+
+	if _, err := geoip.OpenMMDB(filepath.Join(dir, "missing.mmdb")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("OpenMMDB error = %v, want a not-exist error", err)
+	}
+	bad := filepath.Join(dir, "bad.mmdb")
+	if err := os.WriteFile(bad, []byte("not a database"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := geoip.OpenMMDB(bad); !errors.Is(err, geoip.ErrInvalidDatabase) || !strings.Contains(err.Error(), bad) {
+		t.Errorf("OpenMMDB error = %v, want ErrInvalidDatabase naming the file", err)
+	}
+}
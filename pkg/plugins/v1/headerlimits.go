@@ -0,0 +1,128 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// ErrHeaderLimit is returned, wrapped, by CheckHeaderLimits for headers exceeding a HeaderLimits.
+var ErrHeaderLimit = errors.New("header limit exceeded")
+
+// HeaderLimits bounds the headers of the messages a plugin returns. A zero limit disables that
+// check.
+type HeaderLimits struct {
+	// MaxCount is the number of headers a message may carry.
+	MaxCount int
+
+	// MaxNameLength is the length, in bytes, of a header name.
+	MaxNameLength int
+
+	// MaxValueLength is the length, in bytes, of a header value.
+	MaxValueLength int
+}
+
+// DefaultHeaderLimits returns limits in line with the defaults of common proxies: 100 headers,
+// names of 256 bytes and values of 8 KiB.
+func DefaultHeaderLimits() HeaderLimits {
+	return HeaderLimits{MaxCount: 100, MaxNameLength: 256, MaxValueLength: 8 << 10}
+}
+
+// CheckHeaderLimits checks headers against limits, ignoring the entries also present, with the
+// same value, in orig (which may be nil): a plugin is only held to the headers it sets, not to
+// those it passes through. The error wraps ErrHeaderLimit and describes one offending header.
+func CheckHeaderLimits(headers, orig map[string]string, limits HeaderLimits) error {
+	_, err := checkHeaderLimits(headers, orig, limits)
+	return err
+}
+
+// checkHeaderLimits implements CheckHeaderLimits, also returning the exceeded limit as a metric
+// reason ("count", "name" or "value").
+func checkHeaderLimits(headers, orig map[string]string, limits HeaderLimits) (string, error) {
+	if limits.MaxCount > 0 && len(headers) > limits.MaxCount && len(headers) > len(orig) {
+		return "count", fmt.Errorf("%w: %d headers, limit %d", ErrHeaderLimit, len(headers), limits.MaxCount)
+	}
+	for k, v := range headers {
+		if ov, ok := orig[k]; ok && ov == v {
+			continue
+		}
+		if limits.MaxNameLength > 0 && len(k) > limits.MaxNameLength {
+			return "name", fmt.Errorf("%w: header name of %d bytes, limit %d",
+				ErrHeaderLimit, len(k), limits.MaxNameLength)
+		}
+		if limits.MaxValueLength > 0 && len(v) > limits.MaxValueLength {
+			return "value", fmt.Errorf("%w: header %q value is %d bytes, limit %d",
+				ErrHeaderLimit, k, len(v), limits.MaxValueLength)
+		}
+	}
+
+	return "", nil
+}
+
+// WithHeaderLimits checks the headers of the HTTPResponse HandleRequest and HandleResponse return,
+// and of its ModifiedRequest, against limits before they reach mcpd, so a buggy plugin cannot emit
+// a pathological message that breaks mcpd or the upstream server. Headers passed through unchanged
+// from the call's input are exempt (see CheckHeaderLimits).
+//
+// A result exceeding a limit fails the call with codes.Internal, reported as a plugin error, and
+// is counted as metrics.HeaderLimitViolations when WithMetrics is configured. Use
+// DefaultHeaderLimits for limits suited to most deployments.
+func WithHeaderLimits(limits HeaderLimits) ServeOption {
+	return func(o *serveOptions) error {
+		if limits.MaxCount < 0 || limits.MaxNameLength < 0 || limits.MaxValueLength < 0 {
+			return fmt.Errorf("header limits cannot be negative")
+		}
+		if limits == (HeaderLimits{}) {
+			return fmt.Errorf("header limits need at least one limit")
+		}
+		o.headerLimits = &limits
+		return nil
+	}
+}
+
+// headerLimitsInterceptor enforces limits on handler results.
+func headerLimitsInterceptor(o *serveOptions, limits HeaderLimits) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		out, ok := resp.(*HTTPResponse)
+		if err != nil || !ok || !isHandlerMethod(info.FullMethod) {
+			return resp, err
+		}
+
+		var orig map[string]string
+		switch in := req.(type) {
+		case *HTTPRequest:
+			orig = in.GetHeaders()
+		case *HTTPResponse:
+			orig = in.GetHeaders()
+		}
+		reason, err := checkHeaderLimits(out.GetHeaders(), orig, limits)
+		if err == nil && out.GetModifiedRequest() != nil {
+			var reqOrig map[string]string
+			if in, ok := req.(*HTTPRequest); ok {
+				reqOrig = in.GetHeaders()
+			}
+			if reason, err = checkHeaderLimits(out.GetModifiedRequest().GetHeaders(), reqOrig, limits); err != nil {
+				err = fmt.Errorf("modified request: %w", err)
+			}
+		}
+		if err == nil {
+			return resp, nil
+		}
+
+		method := path.Base(info.FullMethod)
+		if r := o.metricsRecorder(); r != nil {
+			r.Count(metrics.HeaderLimitViolations, 1,
+				metrics.L(metrics.LabelMethod, method), metrics.L(metrics.LabelReason, reason))
+		}
+
+		return nil, status.Errorf(codes.Internal, "plugin result of %s exceeds header limits: %v", method, err)
+	}
+}
@@ -0,0 +1,298 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// numberedHeaders returns n headers named X-H0 to X-H<n-1>.
+func numberedHeaders(n int) map[string]string {
+	h := make(map[string]string, n)
+	for i := range n {
+		h[fmt.Sprintf("X-H%d", i)] = "v"
+	}
+
+	return h
+}
+
+func TestCheckHeaderLimits(t *testing.T) {
+	limits := HeaderLimits{MaxCount: 3, MaxNameLength: 8, MaxValueLength: 4}
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		orig       map[string]string
+		limits     HeaderLimits
+		wantReason string
+		wantErr    string
+	}{
+		{
+			name:    "within limits",
+			headers: map[string]string{"X-A": "1234", "X-B": "1"},
+			limits:  limits,
+		},
+		{
+			name:       "too many headers",
+			headers:    numberedHeaders(4),
+			limits:     limits,
+			wantReason: "count",
+			wantErr:    "4 headers, limit 3",
+		},
+		{
+			name:    "too many headers passed through",
+			headers: numberedHeaders(4),
+			orig:    numberedHeaders(4),
+			limits:  limits,
+		},
+		{
+			name:       "header added to too many",
+			headers:    numberedHeaders(5),
+			orig:       numberedHeaders(4),
+			limits:     limits,
+			wantReason: "count",
+			wantErr:    "5 headers, limit 3",
+		},
+		{
+			name:       "long name",
+			headers:    map[string]string{"X-Too-Long": "v"},
+			limits:     limits,
+			wantReason: "name",
+			wantErr:    "header name of 10 bytes, limit 8",
+		},
+		{
+			name:       "long value",
+			headers:    map[string]string{"X-A": "12345"},
+			limits:     limits,
+			wantReason: "value",
+			wantErr:    `header "X-A" value is 5 bytes, limit 4`,
+		},
+		{
+			name:    "long header passed through",
+			headers: map[string]string{"X-Too-Long": "12345"},
+			orig:    map[string]string{"X-Too-Long": "12345"},
+			limits:  limits,
+		},
+		{
+			name:       "long value changed",
+			headers:    map[string]string{"X-A": "67890"},
+			orig:       map[string]string{"X-A": "12345"},
+			limits:     limits,
+			wantReason: "value",
+			wantErr:    `header "X-A" value is 5 bytes`,
+		},
+		{
+			name:    "zero limits disable the checks",
+			headers: map[string]string{"X-Too-Long": strings.Repeat("v", 10<<10)},
+			limits:  HeaderLimits{MaxCount: 1},
+		},
+		{
+			name:   "no headers",
+			limits: limits,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, err := checkHeaderLimits(tt.headers, tt.orig, tt.limits)
+			if reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", reason, tt.wantReason)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkHeaderLimits error = %v, want none", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrHeaderLimit) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkHeaderLimits error = %v, want ErrHeaderLimit containing %q", err, tt.wantErr)
+			}
+			if pub := CheckHeaderLimits(tt.headers, tt.orig, tt.limits); pub == nil || pub.Error() != err.Error() {
+				t.Errorf("CheckHeaderLimits error = %v, want %v", pub, err)
+			}
+		})
+	}
+}
+
+func TestDefaultHeaderLimits(t *testing.T) {
+	want := HeaderLimits{MaxCount: 100, MaxNameLength: 256, MaxValueLength: 8 << 10}
+	if got := DefaultHeaderLimits(); got != want {
+		t.Errorf("DefaultHeaderLimits = %+v, want %+v", got, want)
+	}
+}
+
+func TestWithHeaderLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  HeaderLimits
+		wantErr string
+	}{
+		{name: "defaults", limits: DefaultHeaderLimits()},
+		{name: "single limit", limits: HeaderLimits{MaxValueLength: 1}},
+		{name: "negative count", limits: HeaderLimits{MaxCount: -1}, wantErr: "cannot be negative"},
+		{name: "negative name", limits: HeaderLimits{MaxCount: 1, MaxNameLength: -1}, wantErr: "cannot be negative"},
+		{name: "negative value", limits: HeaderLimits{MaxValueLength: -1}, wantErr: "cannot be negative"},
+		{name: "no limits", wantErr: "at least one limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(WithHeaderLimits(tt.limits))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("WithHeaderLimits error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.headerLimits == nil || *o.headerLimits != tt.limits {
+				t.Errorf("headerLimits = %v, want %+v", o.headerLimits, tt.limits)
+			}
+		})
+	}
+}
+
+func TestHeaderLimitsInterceptor(t *testing.T) {
+	long := strings.Repeat("v", 9)
+	errHandler := errors.New("handler failed")
+	tests := []struct {
+		name       string
+		method     string
+		req        any
+		resp       any
+		handlerErr error
+		wantErr    string
+		wantMetric string
+	}{
+		{
+			name:   "request within limits",
+			method: Plugin_HandleRequest_FullMethodName,
+			req:    &HTTPRequest{},
+			resp:   &HTTPResponse{Headers: map[string]string{"X-A": "ok"}},
+		},
+		{
+			name:       "response header value too long",
+			method:     Plugin_HandleRequest_FullMethodName,
+			req:        &HTTPRequest{},
+			resp:       &HTTPResponse{Headers: map[string]string{"X-A": long}},
+			wantErr:    "plugin result of HandleRequest exceeds header limits",
+			wantMetric: "count header_limits.violations 1 method=HandleRequest reason=value",
+		},
+		{
+			name:   "request headers passed through",
+			method: Plugin_HandleRequest_FullMethodName,
+			req:    &HTTPRequest{Headers: map[string]string{"X-A": long}},
+			resp: &HTTPResponse{
+				Headers:         map[string]string{"X-A": long},
+				ModifiedRequest: &HTTPRequest{Headers: map[string]string{"X-A": long}},
+			},
+		},
+		{
+			name:   "modified request header too long",
+			method: Plugin_HandleRequest_FullMethodName,
+			req:    &HTTPRequest{Headers: map[string]string{"X-A": "ok"}},
+			resp: &HTTPResponse{
+				ModifiedRequest: &HTTPRequest{Headers: map[string]string{"X-A": "ok", "X-Many": long}},
+			},
+			wantErr:    "modified request: header limit exceeded",
+			wantMetric: "count header_limits.violations 1 method=HandleRequest reason=value",
+		},
+		{
+			name:   "response headers passed through",
+			method: Plugin_HandleResponse_FullMethodName,
+			req:    &HTTPResponse{Headers: map[string]string{"X-A": long}},
+			resp:   &HTTPResponse{Headers: map[string]string{"X-A": long}},
+		},
+		{
+			name:       "response name too long",
+			method:     Plugin_HandleResponse_FullMethodName,
+			req:        &HTTPResponse{},
+			resp:       &HTTPResponse{Headers: map[string]string{"X-Very-Long-Name": "v"}},
+			wantErr:    "header name of 16 bytes",
+			wantMetric: "count header_limits.violations 1 method=HandleResponse reason=name",
+		},
+		{
+			name:       "handler error passed through",
+			method:     Plugin_HandleRequest_FullMethodName,
+			req:        &HTTPRequest{},
+			resp:       &HTTPResponse{Headers: map[string]string{"X-A": long}},
+			handlerErr: errHandler,
+			wantErr:    errHandler.Error(),
+		},
+		{
+			name:   "other methods are not checked",
+			method: Plugin_GetMetadata_FullMethodName,
+			req:    &HTTPRequest{},
+			resp:   &HTTPResponse{Headers: map[string]string{"X-A": long}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorderLog{}
+			limits := HeaderLimits{MaxCount: 2, MaxNameLength: 8, MaxValueLength: 8}
+			o, err := newServeOptions(WithHeaderLimits(limits), WithMetrics(r))
+			if err != nil {
+				t.Fatal(err)
+			}
+			intercept := headerLimitsInterceptor(o, limits)
+			info := &grpc.UnaryServerInfo{FullMethod: tt.method}
+			resp, err := intercept(context.Background(), tt.req, info, func(context.Context, any) (any, error) {
+				return tt.resp, tt.handlerErr
+			})
+
+			switch {
+			case tt.handlerErr != nil:
+				if !errors.Is(err, tt.handlerErr) {
+					t.Errorf("error = %v, want the handler error", err)
+				}
+			case tt.wantErr != "":
+				if status.Code(err) != codes.Internal || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want an Internal error containing %q", err, tt.wantErr)
+				}
+				if resp != nil {
+					t.Errorf("response = %v, want none", resp)
+				}
+			default:
+				if err != nil || resp != tt.resp {
+					t.Errorf("intercept = %v, %v; want the handler result", resp, err)
+				}
+			}
+
+			var want []string
+			if tt.wantMetric != "" {
+				want = []string{tt.wantMetric}
+			}
+			if got := r.named(metrics.HeaderLimitViolations); !slices.Equal(got, want) {
+				t.Errorf("recorded %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestHeaderLimitsServeChain(t *testing.T) {
+	o, err := newServeOptions(WithHeaderLimits(HeaderLimits{MaxCount: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptors, err := o.unaryInterceptors(&BasePlugin{}, nil, features.NewSet(SupportedFeatures()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	intercept := chainInterceptors(interceptors)
+	info := &grpc.UnaryServerInfo{FullMethod: Plugin_HandleRequest_FullMethodName}
+	_, err = intercept(context.Background(), &HTTPRequest{}, info, func(context.Context, any) (any, error) {
+		return &HTTPResponse{Headers: numberedHeaders(2)}, nil
+	})
+	if status.Code(err) != codes.Internal || !strings.Contains(err.Error(), "2 headers, limit 1") {
+		t.Errorf("error = %v, want the header count rejected", err)
+	}
+}
@@ -0,0 +1,265 @@
+// Package headerpolicy enforces security headers on responses returned to MCP clients: it strips
+// headers that leak implementation details, removes hop-by-hop headers, and sets hardening headers
+// such as Content-Security-Policy and Strict-Transport-Security.
+//
+// The policy is plain configuration, decoded from custom_config, so a hardening plugin needs no
+// code beyond serving Plugin:
+//
+//	func main() {
+//	    if err := mcpdpluginsv1.Serve(headerpolicy.NewPlugin()); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+//
+// Plugins that do more can build a Policy with New and call its HandleResponse from their own handler.
+package headerpolicy
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+)
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// hopByHop lists the headers meaningful only for a single transport-level connection (RFC 9110).
+var hopByHop = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"TE",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Config is the header policy, decodable from custom_config with mcpdpluginsv1.DecodeConfig.
+// Setting a header value to the empty string disables that header.
+type Config struct {
+	// Strip lists response headers to remove.
+	Strip []string `config:"strip" default:"Server,X-Powered-By,X-AspNet-Version,X-AspNetMvc-Version"`
+
+	// StripHopByHop removes hop-by-hop headers, including any named by Connection.
+	StripHopByHop bool `config:"strip_hop_by_hop" default:"true"`
+
+	// Override replaces values already set by the upstream; otherwise headers are only added
+	// when missing.
+	Override bool `config:"override" default:"false"`
+
+	// Hardening headers set on every response.
+	ContentSecurityPolicy   string `config:"content_security_policy" default:"default-src 'none'; frame-ancestors 'none'"`
+	StrictTransportSecurity string `config:"strict_transport_security" default:"max-age=31536000; includeSubDomains"`
+	ContentTypeOptions      string `config:"content_type_options" default:"nosniff"`
+	FrameOptions            string `config:"frame_options" default:"DENY"`
+	ReferrerPolicy          string `config:"referrer_policy" default:"no-referrer"`
+	CacheControl            string `config:"cache_control" default:"no-store"`
+	CrossOriginPolicy       string `config:"cross_origin_resource_policy" default:"same-origin"`
+}
+
+// DefaultConfig returns the Config with every default applied.
+func DefaultConfig() Config {
+	var cfg Config
+	if _, err := config.Decode(nil, &cfg); err != nil {
+		panic(fmt.Sprintf("headerpolicy: invalid defaults: %v", err))
+	}
+
+	return cfg
+}
+
+// Policy applies a Config to responses. It is immutable and safe for concurrent use.
+type Policy struct {
+	strip         map[string]struct{}
+	stripHopByHop bool
+	override      bool
+	set           [][2]string
+}
+
+// New returns a Policy enforcing cfg.
+func New(cfg Config) (*Policy, error) {
+	p := &Policy{
+		strip:         make(map[string]struct{}, len(cfg.Strip)),
+		stripHopByHop: cfg.StripHopByHop,
+		override:      cfg.Override,
+	}
+	for _, name := range cfg.Strip {
+		if name == "" {
+			continue
+		}
+		p.strip[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+	if cfg.StripHopByHop {
+		for _, name := range hopByHop {
+			p.strip[http.CanonicalHeaderKey(name)] = struct{}{}
+		}
+	}
+
+	for _, h := range [][2]string{
+		{"Content-Security-Policy", cfg.ContentSecurityPolicy},
+		{"Strict-Transport-Security", cfg.StrictTransportSecurity},
+		{"X-Content-Type-Options", cfg.ContentTypeOptions},
+		{"X-Frame-Options", cfg.FrameOptions},
+		{"Referrer-Policy", cfg.ReferrerPolicy},
+		{"Cache-Control", cfg.CacheControl},
+		{"Cross-Origin-Resource-Policy", cfg.CrossOriginPolicy},
+	} {
+		if h[1] == "" {
+			continue
+		}
+		if strings.ContainsAny(h[1], "\r\n") {
+			return nil, fmt.Errorf("invalid %s value %q", h[0], h[1])
+		}
+		p.set = append(p.set, h)
+	}
+
+	return p, nil
+}
+
+// Apply returns headers with the policy enforced: headers itself when it already complies, and
+// a copy otherwise. Header names are matched case-insensitively.
+func (p *Policy) Apply(headers map[string]string) map[string]string {
+	strip := p.strip
+	if conn := mcpdpluginsv1.GetHeader(headers, "Connection"); p.stripHopByHop && conn != "" {
+		strip = maps.Clone(p.strip)
+		for name := range strings.SplitSeq(conn, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				strip[http.CanonicalHeaderKey(name)] = struct{}{}
+			}
+		}
+	}
+	if p.complies(headers, strip) {
+		return headers
+	}
+
+	out := make(map[string]string, len(headers)+len(p.set))
+	present := make(map[string]string, len(headers))
+	for k, v := range headers {
+		canonical := http.CanonicalHeaderKey(k)
+		if _, ok := strip[canonical]; ok {
+			continue
+		}
+		out[k] = v
+		present[canonical] = k
+	}
+
+	for _, h := range p.set {
+		if k, ok := present[h[0]]; ok {
+			if !p.override {
+				continue
+			}
+			delete(out, k)
+		}
+		out[h[0]] = h[1]
+	}
+
+	return out
+}
+
+// complies reports whether applying the policy would leave headers unchanged.
+func (p *Policy) complies(headers map[string]string, strip map[string]struct{}) bool {
+	for k := range headers {
+		if _, ok := strip[http.CanonicalHeaderKey(k)]; ok {
+			return false
+		}
+	}
+	for _, h := range p.set {
+		k, v, ok := lookup(headers, h[0])
+		if !ok || (p.override && (k != h[0] || v != h[1])) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lookup returns the key and value of the header matching name case-insensitively.
+func lookup(headers map[string]string, name string) (string, string, bool) {
+	if v, ok := headers[name]; ok {
+		return name, v, true
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return k, v, true
+		}
+	}
+
+	return "", "", false
+}
+
+// HandleResponse returns resp, continuing the chain, with the policy applied to its headers.
+func (p *Policy) HandleResponse(resp *mcpdpluginsv1.HTTPResponse) *mcpdpluginsv1.HTTPResponse {
+	return &mcpdpluginsv1.HTTPResponse{
+		Continue:   true,
+		StatusCode: resp.GetStatusCode(),
+		Headers:    p.Apply(resp.GetHeaders()),
+		Body:       resp.GetBody(),
+	}
+}
+
+// Plugin is a response-flow plugin enforcing the Policy decoded from its custom_config.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	policy atomic.Pointer[Policy]
+}
+
+// NewPlugin returns a Plugin enforcing the default Config until Configure is called.
+func NewPlugin() *Plugin {
+	p := &Plugin{}
+	policy, err := New(DefaultConfig())
+	if err != nil {
+		panic(fmt.Sprintf("headerpolicy: invalid defaults: %v", err))
+	}
+	p.policy.Store(policy)
+
+	return p
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "header-policy",
+		Version:     pluginVersion,
+		Description: "Enforces security headers on responses.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowResponse), nil
+}
+
+// Configure decodes the policy from cfg's custom_config.
+func (p *Plugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	var c Config
+	if err := mcpdpluginsv1.DecodeConfig(ctx, cfg, &c); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	policy, err := New(c)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	p.policy.Store(policy)
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleResponse applies the configured policy.
+func (p *Plugin) HandleResponse(
+	_ context.Context,
+	resp *mcpdpluginsv1.HTTPResponse,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.policy.Load().HandleResponse(resp), nil
+}
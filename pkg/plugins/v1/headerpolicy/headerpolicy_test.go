@@ -0,0 +1,248 @@
+package headerpolicy
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// hardened is what the default policy adds to every response.
+var hardened = map[string]string{
+	"Content-Security-Policy":      "default-src 'none'; frame-ancestors 'none'",
+	"Strict-Transport-Security":    "max-age=31536000; includeSubDomains",
+	"X-Content-Type-Options":       "nosniff",
+	"X-Frame-Options":              "DENY",
+	"Referrer-Policy":              "no-referrer",
+	"Cache-Control":                "no-store",
+	"Cross-Origin-Resource-Policy": "same-origin",
+}
+
+// withHardened returns h plus the default hardening headers.
+func withHardened(h map[string]string) map[string]string {
+	out := maps.Clone(hardened)
+	maps.Copy(out, h)
+	return out
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     func(c *Config)
+		headers map[string]string
+		want    map[string]string
+	}{
+		{
+			name: "defaults",
+			headers: map[string]string{
+				"Content-Type": "application/json",
+				"Server":       "uvicorn",
+				"x-powered-by": "Express",
+			},
+			want: withHardened(map[string]string{"Content-Type": "application/json"}),
+		},
+		{
+			name: "hop-by-hop headers",
+			headers: map[string]string{
+				"Connection":        "keep-alive, X-Internal-Hop",
+				"Keep-Alive":        "timeout=5",
+				"transfer-encoding": "chunked",
+				"Te":                "trailers",
+				"X-Internal-Hop":    "1",
+				"Mcp-Session-Id":    "s1",
+			},
+			want: withHardened(map[string]string{"Mcp-Session-Id": "s1"}),
+		},
+		{
+			name:    "hop-by-hop headers kept",
+			cfg:     func(c *Config) { c.StripHopByHop = false },
+			headers: map[string]string{"Connection": "X-Internal-Hop", "X-Internal-Hop": "1"},
+			want:    withHardened(map[string]string{"Connection": "X-Internal-Hop", "X-Internal-Hop": "1"}),
+		},
+		{
+			name:    "upstream values kept",
+			headers: map[string]string{"cache-control": "max-age=60"},
+			want: func() map[string]string {
+				h := withHardened(map[string]string{"cache-control": "max-age=60"})
+				delete(h, "Cache-Control")
+				return h
+			}(),
+		},
+		{
+			name:    "upstream values overridden",
+			cfg:     func(c *Config) { c.Override = true },
+			headers: map[string]string{"cache-control": "max-age=60", "X-Frame-Options": "SAMEORIGIN"},
+			want:    hardened,
+		},
+		{
+			name: "disabled headers",
+			cfg: func(c *Config) {
+				*c = Config{ContentTypeOptions: "nosniff"}
+			},
+			headers: map[string]string{"Server": "uvicorn", "Connection": "close"},
+			want:    map[string]string{"Server": "uvicorn", "Connection": "close", "X-Content-Type-Options": "nosniff"},
+		},
+		{
+			name: "custom strip list",
+			cfg: func(c *Config) {
+				c.Strip = []string{"x-debug", ""}
+			},
+			headers: map[string]string{"Server": "uvicorn", "X-Debug": "trace"},
+			want:    withHardened(map[string]string{"Server": "uvicorn"}),
+		},
+		{name: "no headers", want: hardened},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			p, err := New(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			in := maps.Clone(tt.headers)
+
+			got := p.Apply(tt.headers)
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("Apply = %v, want %v", got, tt.want)
+			}
+			if !maps.Equal(tt.headers, in) {
+				t.Errorf("Apply modified its input: %v", tt.headers)
+			}
+			// Applying the policy again changes nothing and returns the headers themselves.
+			if again := p.Apply(got); reflect.ValueOf(again).Pointer() != reflect.ValueOf(got).Pointer() {
+				t.Errorf("Apply copied headers that already comply: %v", again)
+			}
+		})
+	}
+}
+
+func TestApplyOverrideCase(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Override = true
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A header with the right value but different case is replaced by the canonical one.
+	headers := withHardened(nil)
+	delete(headers, "X-Frame-Options")
+	headers["x-frame-options"] = "DENY"
+	if got := p.Apply(headers); !maps.Equal(got, hardened) {
+		t.Errorf("Apply = %v, want %v", got, hardened)
+	}
+}
+
+func TestNewInvalidValue(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ReferrerPolicy = "no-referrer\r\nSet-Cookie: a=b"
+	if _, err := New(cfg); err == nil || !strings.Contains(err.Error(), "invalid Referrer-Policy value") {
+		t.Errorf("New error = %v, want the header injection rejected", err)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	p := NewPlugin()
+	ctx := context.Background()
+	resp := &mcpdpluginsv1.HTTPResponse{
+		StatusCode: 201,
+		Headers:    map[string]string{"Server": "uvicorn"},
+		Body:       []byte("ok"),
+	}
+
+	got, err := p.HandleResponse(ctx, resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.GetContinue() || got.GetStatusCode() != 201 || string(got.GetBody()) != "ok" ||
+		!maps.Equal(got.GetHeaders(), hardened) {
+		t.Errorf("HandleResponse = %v, want the default policy applied", got)
+	}
+
+	tests := []struct {
+		name     string
+		config   map[string]string
+		wantCode codes.Code
+		want     map[string]string
+	}{
+		{
+			name:   "configured",
+			config: map[string]string{"strip": "", "content_security_policy": "", "strict_transport_security": ""},
+			want: func() map[string]string {
+				h := withHardened(map[string]string{"Server": "uvicorn"})
+				delete(h, "Content-Security-Policy")
+				delete(h, "Strict-Transport-Security")
+				return h
+			}(),
+		},
+		{name: "undecodable", config: map[string]string{"override": "maybe"}, wantCode: codes.InvalidArgument},
+		{
+			name:     "invalid value",
+			config:   map[string]string{"frame_options": "DENY\nX: y"},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPlugin()
+			_, err := p.Configure(ctx, &mcpdpluginsv1.PluginConfig{CustomConfig: tt.config})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("Configure error = %v, want %s", err, tt.wantCode)
+			}
+			if tt.wantCode != codes.OK {
+				// The previous policy stays in force.
+				tt.want = hardened
+			}
+			got, _ := p.HandleResponse(ctx, resp)
+			if !maps.Equal(got.GetHeaders(), tt.want) {
+				t.Errorf("headers = %v, want %v", got.GetHeaders(), tt.want)
+			}
+		})
+	}
+}
+
+// headersSink keeps the benchmarked results alive.
+var headersSink map[string]string
+
+func BenchmarkApply(b *testing.B) {
+	p, err := New(DefaultConfig())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	upstream := map[string]string{
+		"Content-Type":   "application/json",
+		"Content-Length": "128",
+		"Date":           "Wed, 14 Oct 2026 05:54:23 GMT",
+		"Mcp-Session-Id": "0f8fad5b-d9cb-469f-a165-70867728950e",
+		"Server":         "uvicorn",
+		"X-Powered-By":   "Express",
+		"Connection":     "keep-alive",
+		"Keep-Alive":     "timeout=5",
+	}
+	compliant := p.Apply(upstream)
+
+	for _, bc := range []struct {
+		name    string
+		headers map[string]string
+	}{
+		{"upstream", upstream},
+		{"compliant", compliant},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				headersSink = p.Apply(bc.headers)
+			}
+		})
+	}
+}
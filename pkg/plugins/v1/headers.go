@@ -0,0 +1,214 @@
+package mcpdpluginsv1
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// GetHeader returns the value of the named header from headers, matching the name
+// case-insensitively as HTTP does. It returns an empty string when the header is absent.
+func GetHeader(headers map[string]string, name string) string {
+	if v, ok := headers[name]; ok {
+		return v
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// SetHeader sets the named header in headers, replacing any existing value whose name differs
+// only in case. Like any map write it panics when headers is nil: use SetRequestHeader or
+// SetResponseHeader for the headers of messages, whose maps may not be allocated.
+func SetHeader(headers map[string]string, name, value string) {
+	for k := range headers {
+		if k != name && strings.EqualFold(k, name) {
+			delete(headers, k)
+		}
+	}
+	headers[name] = value
+}
+
+// SetRequestHeader sets the named header of req as SetHeader does, allocating req.Headers when
+// nil. It does nothing when req is nil.
+func SetRequestHeader(req *HTTPRequest, name, value string) {
+	if req == nil {
+		return
+	}
+	if req.Headers == nil {
+		req.Headers = map[string]string{}
+	}
+	SetHeader(req.Headers, name, value)
+}
+
+// SetResponseHeader sets the named header of resp as SetHeader does, allocating resp.Headers when
+// nil. It does nothing when resp is nil.
+func SetResponseHeader(resp *HTTPResponse, name, value string) {
+	if resp == nil {
+		return
+	}
+	if resp.Headers == nil {
+		resp.Headers = map[string]string{}
+	}
+	SetHeader(resp.Headers, name, value)
+}
+
+// SanitizeHeaderValue returns v made safe to set as a header value: CR, LF and the other control
+// characters but horizontal tab are removed, as are the bytes of invalid UTF-8 sequences, and
+// leading and trailing spaces and tabs are trimmed. A CR or LF copied into a header from a body,
+// a token claim or an upstream reply could otherwise end the header early and inject others
+// downstream (response splitting), and header values are proto3 strings, which must be valid
+// UTF-8. It returns v itself when it is already safe.
+func SanitizeHeaderValue(v string) string {
+	if headerValueSafe(v) {
+		return v
+	}
+
+	var b strings.Builder
+	b.Grow(len(v))
+	for i := 0; i < len(v); {
+		r, size := utf8.DecodeRuneInString(v[i:])
+		if r != utf8.RuneError || size > 1 {
+			if r == '\t' || !unicode.IsControl(r) {
+				b.WriteString(v[i : i+size])
+			}
+		}
+		i += size
+	}
+
+	return strings.Trim(b.String(), " \t")
+}
+
+// EscapeHeaderValue returns v made safe to set as a header value like SanitizeHeaderValue, but
+// percent-encoding the bytes it would remove, and the spaces and tabs it would trim, instead of
+// dropping them. "%" is encoded too, so url.PathUnescape recovers v exactly. It returns v itself
+// when it is already safe and contains no "%".
+func EscapeHeaderValue(v string) string {
+	if headerValueSafe(v) && !strings.Contains(v, "%") {
+		return v
+	}
+
+	const hex = "0123456789ABCDEF"
+	escape := func(b *strings.Builder, s string) {
+		for i := range len(s) {
+			b.WriteByte('%')
+			b.WriteByte(hex[s[i]>>4])
+			b.WriteByte(hex[s[i]&0x0f])
+		}
+	}
+
+	start := len(v) - len(strings.TrimLeft(v, " \t"))
+	end := len(strings.TrimRight(v, " \t"))
+	if end < start {
+		end = start
+	}
+
+	var b strings.Builder
+	b.Grow(len(v) + 8)
+	escape(&b, v[:start])
+	for i := start; i < end; {
+		r, size := utf8.DecodeRuneInString(v[i:end])
+		switch {
+		case r == utf8.RuneError && size <= 1, r == '%', r != '\t' && unicode.IsControl(r):
+			escape(&b, v[i:i+size])
+		default:
+			b.WriteString(v[i : i+size])
+		}
+		i += size
+	}
+	escape(&b, v[end:])
+
+	return b.String()
+}
+
+// SanitizeHeaders applies SanitizeHeaderValue to every value of headers, in place, and deletes
+// the headers whose names are not valid HTTP field names, which no value can make safe.
+func SanitizeHeaders(headers map[string]string) {
+	for k, v := range headers {
+		if !httpguts.ValidHeaderFieldName(k) {
+			delete(headers, k)
+			continue
+		}
+		if s := SanitizeHeaderValue(v); s != v {
+			headers[k] = s
+		}
+	}
+}
+
+// headerValueSafe reports whether v is valid UTF-8 without control characters other than
+// horizontal tab, and without leading or trailing spaces and tabs.
+func headerValueSafe(v string) bool {
+	if v == "" {
+		return true
+	}
+	if v[0] == ' ' || v[0] == '\t' || v[len(v)-1] == ' ' || v[len(v)-1] == '\t' {
+		return false
+	}
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c < utf8.RuneSelf {
+			if c < 0x20 && c != '\t' || c == 0x7f {
+				return false
+			}
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(v[i:])
+		if r == utf8.RuneError && size <= 1 || unicode.IsControl(r) {
+			return false
+		}
+		i += size - 1
+	}
+
+	return true
+}
+
+// CloneHeaders returns a copy of headers sized to fit it. Common header names in the copy share
+// one string with every other copy, so long-lived copies do not pin the buffers of the messages
+// they came from. It returns nil for nil headers.
+func CloneHeaders(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+
+	out := make(map[string]string, len(headers))
+	for k, v := range headers {
+		out[internHeader(k)] = v
+	}
+
+	return out
+}
+
+// commonHeaders maps common header names, in canonical and lowercase form, to a shared copy.
+var commonHeaders = func() map[string]string {
+	names := []string{
+		"Accept", "Accept-Encoding", "Accept-Language", "Authorization", "Cache-Control", "Connection",
+		"Content-Encoding", "Content-Length", "Content-Type", "Cookie", "Date", "Etag", "Host",
+		"Last-Event-Id", "Mcp-Protocol-Version", "Mcp-Session-Id", "Origin", "Referer", "Server",
+		"Set-Cookie", "Traceparent", "Tracestate", "Transfer-Encoding", "User-Agent", "Vary",
+		"X-Correlation-Id", "X-Forwarded-For", "X-Forwarded-Proto", "X-Real-Ip", "X-Request-Id",
+	}
+
+	m := make(map[string]string, 2*len(names))
+	for _, n := range names {
+		m[n] = n
+		lower := strings.ToLower(n)
+		m[lower] = lower
+	}
+
+	return m
+}()
+
+// internHeader returns the shared copy of name when it is a common header, and name otherwise.
+func internHeader(name string) string {
+	if s, ok := commonHeaders[name]; ok {
+		return s
+	}
+
+	return name
+}
@@ -0,0 +1,200 @@
+package mcpdpluginsv1
+
+import (
+	"maps"
+	"net/url"
+	"strings"
+	"testing"
+	"unicode/utf8"
+	"unsafe"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+func TestGetHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		header  string
+		want    string
+	}{
+		{name: "exact name", headers: map[string]string{"Mcp-Session-Id": "a"}, header: "Mcp-Session-Id", want: "a"},
+		{
+			name:    "different case",
+			headers: map[string]string{"mcp-session-id": "a"},
+			header:  "Mcp-Session-Id",
+			want:    "a",
+		},
+		{
+			name:    "exact name preferred",
+			headers: map[string]string{"X-Test": "exact", "x-test": "lower"},
+			header:  "X-Test",
+			want:    "exact",
+		},
+		{name: "empty value", headers: map[string]string{"X-Test": ""}, header: "X-Test", want: ""},
+		{name: "absent", headers: map[string]string{"X-Other": "a"}, header: "X-Test", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetHeader(tt.headers, tt.header); got != tt.want {
+				t.Errorf("GetHeader(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloneHeaders(t *testing.T) {
+	if CloneHeaders(nil) != nil {
+		t.Error("CloneHeaders(nil) is not nil")
+	}
+	if got := CloneHeaders(map[string]string{}); got == nil || len(got) != 0 {
+		t.Errorf("CloneHeaders of an empty map = %v, want an empty map", got)
+	}
+
+	// Names built at run time, as when decoding a message, do not share the literal's bytes.
+	contentType := strings.Clone("Content-Type")
+	lower := strings.Clone("x-request-id")
+	in := map[string]string{contentType: "application/json", lower: "r1", "X-Custom": "c"}
+	out := CloneHeaders(in)
+	if !maps.Equal(out, in) {
+		t.Fatalf("CloneHeaders = %v, want %v", out, in)
+	}
+	out["X-Custom"] = "changed"
+	if in["X-Custom"] != "c" {
+		t.Error("CloneHeaders shares the map with its input")
+	}
+
+	tests := []struct {
+		name   string
+		in     string
+		shared bool
+	}{
+		{name: "canonical common name", in: contentType, shared: true},
+		{name: "lowercase common name", in: lower, shared: true},
+		{name: "other name", in: "X-Custom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k := range out {
+				if k != tt.in {
+					continue
+				}
+				fromInput := unsafe.StringData(k) == unsafe.StringData(tt.in)
+				if fromInput == tt.shared {
+					t.Errorf("key %q shared with the interned copy = %t, want %t", k, !fromInput, tt.shared)
+				}
+			}
+		})
+	}
+}
+
+func TestSanitizeHeaderValue(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "safe", in: "Bearer abc.def", want: "Bearer abc.def"},
+		{name: "empty", in: "", want: ""},
+		{name: "inner tab kept", in: "a\tb", want: "a\tb"},
+		{name: "CRLF injection", in: "v\r\nSet-Cookie: x=1", want: "vSet-Cookie: x=1"},
+		{name: "NUL and DEL", in: "a\x00b\x7fc", want: "abc"},
+		{name: "C1 control", in: "a\u0085b", want: "ab"},
+		{name: "invalid UTF-8", in: "a\xffb\xc3", want: "ab"},
+		{name: "non-ASCII kept", in: "café ☕", want: "café ☕"},
+		{name: "surrounding whitespace trimmed", in: " \tv\t ", want: "v"},
+		{name: "whitespace exposed by removal", in: "\n v \r", want: "v"},
+		{name: "only controls", in: "\r\n", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SanitizeHeaderValue(tt.in)
+			if got != tt.want {
+				t.Errorf("SanitizeHeaderValue(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if !httpguts.ValidHeaderFieldValue(got) || !utf8.ValidString(got) {
+				t.Errorf("SanitizeHeaderValue(%q) = %q is not a valid header value", tt.in, got)
+			}
+			if SanitizeHeaderValue(got) != got {
+				t.Errorf("SanitizeHeaderValue is not idempotent on %q", got)
+			}
+		})
+	}
+}
+
+func TestEscapeHeaderValue(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "safe", in: "Bearer abc", want: "Bearer abc"},
+		{name: "empty", in: "", want: ""},
+		{name: "percent", in: "100%", want: "100%25"},
+		{name: "CRLF", in: "a\r\nb", want: "a%0D%0Ab"},
+		{name: "invalid UTF-8", in: "a\xffb", want: "a%FFb"},
+		{name: "truncated UTF-8", in: "a\xe2\x98", want: "a%E2%98"},
+		{name: "C1 control", in: "a\u0085", want: "a%C2%85"},
+		{name: "non-ASCII kept", in: "café", want: "café"},
+		{name: "surrounding whitespace", in: " a b\t", want: "%20a b%09"},
+		{name: "only whitespace", in: "  ", want: "%20%20"},
+		{name: "trailing whitespace after a control", in: "a\n ", want: "a%0A%20"},
+		{name: "inner tab kept", in: "a\tb", want: "a\tb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EscapeHeaderValue(tt.in)
+			if got != tt.want {
+				t.Errorf("EscapeHeaderValue(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if !headerValueSafe(got) {
+				t.Errorf("EscapeHeaderValue(%q) = %q is not a safe header value", tt.in, got)
+			}
+			if back, err := url.PathUnescape(got); err != nil || back != tt.in {
+				t.Errorf("url.PathUnescape(%q) = %q, %v, want %q", got, back, err, tt.in)
+			}
+		})
+	}
+}
+
+func TestSanitizeHeaders(t *testing.T) {
+	headers := map[string]string{
+		"X-Safe":     "ok",
+		"X-Injected": "a\r\nX-Evil: 1",
+		"Bad Name":   "v",
+		"":           "v",
+		"X-Padded":   " v ",
+	}
+	SanitizeHeaders(headers)
+	want := map[string]string{"X-Safe": "ok", "X-Injected": "aX-Evil: 1", "X-Padded": "v"}
+	if !maps.Equal(headers, want) {
+		t.Errorf("SanitizeHeaders = %q, want %q", headers, want)
+	}
+
+	SanitizeHeaders(nil)
+}
+
+func TestHeaderValueSafe(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "", want: true},
+		{in: "a b", want: true},
+		{in: "a\tb", want: true},
+		{in: "日本", want: true},
+		{in: " a"},
+		{in: "a\t"},
+		{in: "a\x01"},
+		{in: "a\x7f"},
+		{in: "a\u009f"},
+		{in: "\xc3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := headerValueSafe(tt.in); got != tt.want {
+				t.Errorf("headerValueSafe(%q) = %t, want %t", tt.in, got, tt.want)
+			}
+		})
+	}
+}
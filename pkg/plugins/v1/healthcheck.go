@@ -0,0 +1,85 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ErrUnhealthy is returned by Serve run with --healthcheck when the plugin at --address fails
+// CheckHealth or cannot be reached.
+var ErrUnhealthy = errors.New("plugin is unhealthy")
+
+// healthcheck calls CheckHealth on the plugin serving at network and address, as the
+// --healthcheck flag does, and returns nil when it is healthy and an error wrapping ErrUnhealthy
+// otherwise. The outcome is written to w.
+//
+// With WithTLS the call is made over TLS, offering the certificate the server would present as the
+// client's, so plugins requiring client certificates accept it, and without verifying the
+// server's: it only checks a plugin the same container runs.
+func (o *serveOptions) healthcheck(w io.Writer, network, address string) error {
+	target := address
+	if network == "unix" {
+		target = "unix:" + address
+	}
+	creds := insecure.NewCredentials()
+	if o.tls != nil {
+		creds = credentials.NewTLS(&tls.Config{
+			GetClientCertificate: o.clientCertificate,
+			InsecureSkipVerify:   true,
+			MinVersion:           tls.VersionTLS12,
+		})
+	}
+
+	err := func() error {
+		conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = conn.Close() }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		defer cancel()
+		_, err = NewPluginClient(conn).CheckHealth(ctx, &emptypb.Empty{})
+		return err
+	}()
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "unhealthy: %v\n", err)
+		return fmt.Errorf("%w: %w", ErrUnhealthy, err)
+	}
+	_, _ = fmt.Fprintln(w, "healthy")
+
+	return nil
+}
+
+// clientCertificate returns the certificate the WithTLS server presents, for the healthcheck to
+// offer as its client certificate. Configurations rotating certificates through GetCertificate or
+// GetConfigForClient, such as the spiffe package's, are asked for their current one.
+func (o *serveOptions) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cfg := o.tls
+	if len(cfg.Certificates) == 0 && cfg.GetCertificate == nil && cfg.GetConfigForClient != nil {
+		c, err := cfg.GetConfigForClient(&tls.ClientHelloInfo{})
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			cfg = c
+		}
+	}
+	switch {
+	case len(cfg.Certificates) > 0:
+		return &cfg.Certificates[0], nil
+	case cfg.GetCertificate != nil:
+		return cfg.GetCertificate(&tls.ClientHelloInfo{})
+	default:
+		// An empty certificate sends none, which servers not requiring one accept.
+		return &tls.Certificate{}, nil
+	}
+}
@@ -0,0 +1,222 @@
+package mcpdpluginsv1
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// selfSignedCert returns a throwaway certificate valid for an hour.
+func selfSignedCert(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "plugin"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// servePlugin serves a BasePlugin on a unix socket, over TLS when cfg is not nil, and returns the
+// socket path.
+func servePlugin(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+
+	address := filepath.Join(t.TempDir(), "plugin.sock")
+	lis, err := net.Listen("unix", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var opts []grpc.ServerOption
+	if cfg != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg)))
+	}
+	srv := grpc.NewServer(opts...)
+	RegisterPluginServer(srv, &BasePlugin{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return address
+}
+
+func TestHealthcheck(t *testing.T) {
+	cert := selfSignedCert(t)
+	getCertificate := func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return &cert, nil }
+
+	tests := []struct {
+		name      string
+		server    *tls.Config // nil serves plaintext
+		client    *tls.Config // the WithTLS config; nil connects in plaintext
+		noServer  bool
+		wantError bool
+	}{
+		{
+			name: "plaintext",
+		},
+		{
+			name:   "tls with certificates",
+			server: &tls.Config{Certificates: []tls.Certificate{cert}},
+			client: &tls.Config{Certificates: []tls.Certificate{cert}},
+		},
+		{
+			name: "mtls with certificates",
+			server: &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				ClientAuth:   tls.RequireAnyClientCert,
+			},
+			client: &tls.Config{Certificates: []tls.Certificate{cert}},
+		},
+		{
+			// As configured by spiffe.ServerTLSConfig.
+			name:   "mtls with GetCertificate",
+			server: &tls.Config{GetCertificate: getCertificate, ClientAuth: tls.RequireAnyClientCert},
+			client: &tls.Config{GetCertificate: getCertificate, ClientAuth: tls.RequireAnyClientCert},
+		},
+		{
+			name:   "mtls with GetConfigForClient",
+			server: &tls.Config{GetCertificate: getCertificate, ClientAuth: tls.RequireAnyClientCert},
+			client: &tls.Config{
+				GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+					return &tls.Config{GetCertificate: getCertificate}, nil
+				},
+			},
+		},
+		{
+			name:      "plaintext client against tls server",
+			server:    &tls.Config{Certificates: []tls.Certificate{cert}},
+			wantError: true,
+		},
+		{
+			name:      "no server",
+			noServer:  true,
+			wantError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := filepath.Join(t.TempDir(), "missing.sock")
+			if !tt.noServer {
+				address = servePlugin(t, tt.server)
+			}
+			o, err := newServeOptions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			o.tls = tt.client
+
+			var out strings.Builder
+			err = o.healthcheck(&out, "unix", address)
+			if tt.wantError {
+				if !errors.Is(err, ErrUnhealthy) {
+					t.Fatalf("healthcheck() = %v, want ErrUnhealthy", err)
+				}
+				if !strings.HasPrefix(out.String(), "unhealthy: ") {
+					t.Errorf("output = %q, want unhealthy", out.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("healthcheck() = %v, want nil", err)
+			}
+			if out.String() != "healthy\n" {
+				t.Errorf("output = %q, want healthy", out.String())
+			}
+		})
+	}
+}
+
+func TestHealthcheckClientCertificateError(t *testing.T) {
+	cert := selfSignedCert(t)
+	address := servePlugin(t, &tls.Config{Certificates: []tls.Certificate{cert}, ClientAuth: tls.RequireAnyClientCert})
+
+	o, err := newServeOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.tls = &tls.Config{GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return nil, errors.New("no SVID yet")
+	}}
+	if err := o.healthcheck(io.Discard, "unix", address); !errors.Is(err, ErrUnhealthy) {
+		t.Fatalf("healthcheck() = %v, want ErrUnhealthy", err)
+	}
+}
+
+func TestHealthcheckTCP(t *testing.T) {
+	tests := []struct {
+		name      string
+		plugin    *probedPlugin
+		wantError string
+	}{
+		{name: "healthy", plugin: &probedPlugin{}},
+		{
+			name:      "failing CheckHealth",
+			plugin:    &probedPlugin{healthErr: status.Error(codes.Unavailable, "database down")},
+			wantError: "database down",
+		},
+		{
+			// --healthcheck only checks liveness.
+			name:   "not ready",
+			plugin: &probedPlugin{readyErr: errors.New("warming up")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := grpc.NewServer()
+			RegisterPluginServer(srv, tt.plugin)
+			go func() { _ = srv.Serve(lis) }()
+			t.Cleanup(srv.Stop)
+
+			o, err := newServeOptions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out strings.Builder
+			err = o.healthcheck(&out, "tcp", lis.Addr().String())
+			if tt.wantError == "" {
+				if err != nil || out.String() != "healthy\n" {
+					t.Errorf("healthcheck() = %v, output %q; want healthy", err, out.String())
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnhealthy) || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("healthcheck() = %v, want ErrUnhealthy with %q", err, tt.wantError)
+			}
+			if got := out.String(); !strings.HasPrefix(got, "unhealthy: ") || !strings.Contains(got, tt.wantError) {
+				t.Errorf("output = %q, want the unhealthy CheckHealth error", out.String())
+			}
+		})
+	}
+}
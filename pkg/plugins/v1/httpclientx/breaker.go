@@ -0,0 +1,107 @@
+package httpclientx
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+)
+
+// ErrCircuitOpen is returned for requests to a host whose circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// breaker fails requests fast once a host has failed threshold times in a row, letting a single
+// probe through after the cooldown.
+type breaker struct {
+	next      http.RoundTripper
+	threshold int
+	cooldown  time.Duration
+	clock     clock.Clock
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+type hostState struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newBreaker(next http.RoundTripper, threshold int, cooldown time.Duration, c clock.Clock) *breaker {
+	return &breaker{
+		next:      next,
+		threshold: threshold,
+		cooldown:  cooldown,
+		clock:     c,
+		hosts:     map[string]*hostState{},
+	}
+}
+
+func (b *breaker) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if !b.allow(host) {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := b.next.RoundTrip(req)
+	if err != nil && (req.Context().Err() != nil || errors.Is(err, ErrEgressDenied)) {
+		// The caller gave up, or the policy refused the host, which says nothing about its health.
+		b.abandon(host)
+		return resp, err
+	}
+	b.record(host, err == nil && resp.StatusCode < http.StatusInternalServerError)
+
+	return resp, err
+}
+
+func (b *breaker) allow(host string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.hosts[host]
+	if !ok || s.failures < b.threshold {
+		return true
+	}
+	if b.clock.Now().Before(s.openUntil) || s.probing {
+		return false
+	}
+	s.probing = true
+
+	return true
+}
+
+func (b *breaker) record(host string, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ok {
+		delete(b.hosts, host)
+		return
+	}
+	s, found := b.hosts[host]
+	if !found {
+		s = &hostState{}
+		b.hosts[host] = s
+	}
+	s.failures++
+	s.probing = false
+	if s.failures >= b.threshold {
+		s.openUntil = b.clock.Now().Add(b.cooldown)
+	}
+}
+
+// abandon lets another probe through after an attempt cancelled by the caller.
+func (b *breaker) abandon(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if s, ok := b.hosts[host]; ok {
+		s.probing = false
+	}
+}
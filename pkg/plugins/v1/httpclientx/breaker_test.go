@@ -0,0 +1,213 @@
+package httpclientx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/plugintest"
+)
+
+// errNetwork stands for a failed connection.
+var errNetwork = errors.New("connection refused")
+
+// scripted answers each request with the next of its outcomes: a status code, or 0 for
+// errNetwork. It records the requests it receives.
+type scripted struct {
+	outcomes []int
+	requests []*http.Request
+}
+
+func (s *scripted) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.requests = append(s.requests, req)
+	if len(s.outcomes) == 0 {
+		return nil, fmt.Errorf("unexpected request %d", len(s.requests))
+	}
+	code := s.outcomes[0]
+	s.outcomes = s.outcomes[1:]
+	if code == 0 {
+		return nil, errNetwork
+	}
+	rec := httptest.NewRecorder()
+	rec.WriteHeader(code)
+
+	return rec.Result(), nil
+}
+
+// trackedBody is a request body recording whether it was closed.
+type trackedBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestBreaker(t *testing.T) {
+	type step struct {
+		host    string
+		advance time.Duration
+		outcome int   // Outcome of the request if it reaches the transport.
+		wantErr error // Error RoundTrip returns; nil for a response.
+	}
+	open := step{wantErr: ErrCircuitOpen}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name:  "opens after consecutive failures",
+			steps: []step{{outcome: 500}, {outcome: 0, wantErr: errNetwork}, {outcome: 503}, open, open},
+		},
+		{
+			name: "successes reset the count",
+			steps: []step{
+				{outcome: 500}, {outcome: 500}, {outcome: 200}, {outcome: 500}, {outcome: 500}, {outcome: 200},
+			},
+		},
+		{
+			name:  "client errors are successes",
+			steps: []step{{outcome: 500}, {outcome: 500}, {outcome: 404}, {outcome: 429}, {outcome: 500}},
+		},
+		{
+			name: "closes after a successful probe",
+			steps: []step{
+				{outcome: 500},
+				{outcome: 500},
+				{outcome: 500},
+				open,
+				{advance: 29 * time.Second, wantErr: ErrCircuitOpen},
+				{advance: time.Second, outcome: 200},
+				{outcome: 500},
+			},
+		},
+		{
+			name: "reopens after a failed probe",
+			steps: []step{
+				{outcome: 500},
+				{outcome: 500},
+				{outcome: 500},
+				{advance: 30 * time.Second, outcome: 502},
+				open,
+				{advance: 30 * time.Second, outcome: 200},
+			},
+		},
+		{
+			name: "hosts are independent",
+			steps: []step{
+				{outcome: 500},
+				{outcome: 500},
+				{outcome: 500},
+				open,
+				{host: "b.test", outcome: 200},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &scripted{}
+			clk := plugintest.NewFakeClock(time.Time{})
+			b := newBreaker(next, 3, 30*time.Second, clk)
+
+			for i, s := range tt.steps {
+				clk.Advance(s.advance)
+				host := s.host
+				if host == "" {
+					host = "a.test"
+				}
+				sent := len(next.requests)
+				next.outcomes = []int{s.outcome}
+				resp, err := b.RoundTrip(httptest.NewRequest(http.MethodGet, "http://"+host+"/", nil))
+				if resp != nil {
+					_ = resp.Body.Close()
+				}
+				if !errors.Is(err, s.wantErr) || (s.wantErr == nil && err != nil) {
+					t.Fatalf("step %d: error = %v, want %v", i, err, s.wantErr)
+				}
+				if reached := len(next.requests) > sent; reached == errors.Is(err, ErrCircuitOpen) {
+					t.Errorf("step %d: request reached the transport = %t with error %v", i, reached, err)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerSingleProbe(t *testing.T) {
+	clk := plugintest.NewFakeClock(time.Time{})
+	b := newBreaker(&scripted{}, 1, time.Second, clk)
+	b.record("a.test", false)
+
+	if b.allow("a.test") {
+		t.Fatal("open breaker allowed a request")
+	}
+	clk.Advance(time.Second)
+	if !b.allow("a.test") {
+		t.Fatal("breaker refused the probe after the cooldown")
+	}
+	if b.allow("a.test") {
+		t.Error("breaker allowed a second request during the probe")
+	}
+	b.abandon("a.test")
+	if !b.allow("a.test") {
+		t.Error("breaker refused a new probe after one was abandoned")
+	}
+}
+
+func TestBreakerIgnoresNonHealthErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		next roundTripFunc
+		ctx  func() context.Context
+	}{
+		{
+			name: "caller cancelled",
+			next: func(*http.Request) (*http.Response, error) { return nil, context.Canceled },
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+		},
+		{
+			name: "egress denied",
+			next: func(*http.Request) (*http.Response, error) {
+				return nil, fmt.Errorf("%w: a.test is not allowed", ErrEgressDenied)
+			},
+			ctx: context.Background,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker(tt.next, 1, time.Minute, plugintest.NewFakeClock(time.Time{}))
+			for range 3 {
+				req := httptest.NewRequest(http.MethodGet, "http://a.test/", nil).WithContext(tt.ctx())
+				if _, err := b.RoundTrip(req); err == nil || errors.Is(err, ErrCircuitOpen) {
+					t.Fatalf("RoundTrip error = %v, want the transport error", err)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerClosesBody(t *testing.T) {
+	b := newBreaker(&scripted{}, 1, time.Minute, plugintest.NewFakeClock(time.Time{}))
+	b.record("a.test", false)
+
+	body := &trackedBody{Reader: strings.NewReader("payload")}
+	req := httptest.NewRequest(http.MethodPost, "http://a.test/", nil)
+	req.Body = body
+	if _, err := b.RoundTrip(req); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("RoundTrip error = %v, want ErrCircuitOpen", err)
+	}
+	if !body.closed {
+		t.Error("request body was not closed")
+	}
+}
@@ -0,0 +1,265 @@
+package httpclientx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// ErrEgressDenied is returned, wrapped, for requests to destinations the egress policy of the
+// client (see Config.EgressAllow and Config.EgressDenyPrivate) does not permit.
+var ErrEgressDenied = errors.New("egress denied")
+
+// cgnat is the shared address space of carrier-grade NAT (RFC 6598), not publicly routable.
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// egressPolicy constrains the destinations a client may connect to.
+type egressPolicy struct {
+	names       []hostPattern
+	nets        []netip.Prefix
+	denyPrivate bool
+	pins        map[string][]netip.Addr
+}
+
+// hostPattern is an EgressAllow entry naming hosts: an exact host or, with wildcard, its
+// subdomains, optionally restricted to a port.
+type hostPattern struct {
+	host     string
+	wildcard bool
+	port     string
+}
+
+// newEgressPolicy parses the egress settings of cfg, returning nil when they leave egress
+// unconstrained.
+func newEgressPolicy(cfg Config) (*egressPolicy, error) {
+	if len(cfg.EgressAllow) == 0 && !cfg.EgressDenyPrivate && len(cfg.DNSPins) == 0 {
+		return nil, nil
+	}
+
+	p := &egressPolicy{
+		denyPrivate: cfg.EgressDenyPrivate,
+		pins:        map[string][]netip.Addr{},
+	}
+	for _, entry := range cfg.EgressAllow {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			p.nets = append(p.nets, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(strings.Trim(entry, "[]")); err == nil {
+			p.nets = append(p.nets, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		var pat hostPattern
+		pat.host = entry
+		if h, port, err := net.SplitHostPort(entry); err == nil {
+			pat.host, pat.port = h, port
+		}
+		pat.host, pat.wildcard = strings.CutPrefix(pat.host, "*.")
+		if pat.host == "" || strings.ContainsAny(pat.host, "*/ ") {
+			return nil, fmt.Errorf("invalid http_egress_allow entry %q", entry)
+		}
+		p.names = append(p.names, pat)
+	}
+	for _, entry := range cfg.DNSPins {
+		host, ip, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+		if !ok || host == "" || err != nil {
+			return nil, fmt.Errorf("invalid http_dns_pins entry %q: want host=ip", entry)
+		}
+		host = strings.ToLower(strings.TrimSpace(host))
+		p.pins[host] = append(p.pins[host], addr.Unmap())
+	}
+
+	return p, nil
+}
+
+// proxyAddr returns the host:port dialed for the proxy URL u.
+func proxyAddr(u *url.URL) string {
+	return net.JoinHostPort(u.Hostname(), portOf(u))
+}
+
+// portOf returns the port of u, defaulting to that of its scheme.
+func portOf(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	switch u.Scheme {
+	case "https":
+		return "443"
+	case "socks5", "socks5h":
+		return "1080"
+	default:
+		return "80"
+	}
+}
+
+// allowName reports whether host and port match a name entry of the allowlist.
+func (p *egressPolicy) allowName(host, port string) bool {
+	for _, pat := range p.names {
+		if pat.port != "" && pat.port != port {
+			continue
+		}
+		if host == pat.host && !pat.wildcard || pat.wildcard && strings.HasSuffix(host, "."+pat.host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inNets reports whether addr is in an IP range of the allowlist.
+func (p *egressPolicy) inNets(addr netip.Addr) bool {
+	for _, prefix := range p.nets {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// permitted reports whether the policy lets connections reach addr, given whether its host must
+// resolve into the allowlist's IP ranges.
+func (p *egressPolicy) permitted(addr netip.Addr, requireNets bool) bool {
+	addr = addr.Unmap()
+	if p.denyPrivate && !isPublic(addr) {
+		return false
+	}
+
+	return !requireNets || p.inNets(addr)
+}
+
+// isPublic reports whether addr is a globally routable unicast address.
+func isPublic(addr netip.Addr) bool {
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !cgnat.Contains(addr)
+}
+
+type requireNetsKey struct{}
+
+// proxyKey marks the context of a request with the host:port of the proxy the transport sends it
+// through. The dialer connects to that address without checks: proxies are chosen by the
+// operator, not by request URLs.
+type proxyKey struct{}
+
+// egressTransport enforces the name part of the policy on each request, before any connection:
+// requests to hosts outside the allowlist are refused, unless the allowlist has IP ranges the
+// host may resolve into, which the dialer then checks.
+type egressTransport struct {
+	next   http.RoundTripper
+	policy *egressPolicy
+	proxy  func(*http.Request) (*url.URL, error)
+
+	// unchecked is set when next does not dial through the policy, as with WithTransport, so
+	// resolved addresses cannot be checked.
+	unchecked bool
+}
+
+func (t *egressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var proxy string
+	if t.proxy != nil {
+		if u, err := t.proxy(req); err == nil && u != nil {
+			proxy = proxyAddr(u)
+		}
+	}
+	requireNets, err := t.check(req, proxy != "" || t.unchecked)
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+
+	ctx := req.Context()
+	if requireNets {
+		ctx = context.WithValue(ctx, requireNetsKey{}, true)
+	}
+	if proxy != "" {
+		ctx = context.WithValue(ctx, proxyKey{}, proxy)
+	}
+	if ctx != req.Context() {
+		// RoundTrippers must not modify the caller's request.
+		req = req.WithContext(ctx)
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// check reports whether req may proceed and, if so, whether its host must resolve into the
+// allowlist's IP ranges. unresolved is set when the addresses it connects to cannot be checked.
+func (t *egressTransport) check(req *http.Request, unresolved bool) (bool, error) {
+	p := t.policy
+	if len(p.names) == 0 && len(p.nets) == 0 {
+		return false, nil
+	}
+	host, port := strings.ToLower(req.URL.Hostname()), portOf(req.URL)
+	if p.allowName(host, port) {
+		return false, nil
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if p.inNets(addr.Unmap()) {
+			return false, nil
+		}
+		return false, fmt.Errorf("%w: %s is not in http_egress_allow", ErrEgressDenied, req.URL.Host)
+	}
+
+	if len(p.nets) == 0 || unresolved {
+		// Through a proxy or another transport the addresses the host resolves to cannot be checked.
+		return false, fmt.Errorf("%w: %s is not in http_egress_allow", ErrEgressDenied, req.URL.Host)
+	}
+
+	return true, nil
+}
+
+// dialContext returns a DialContext function resolving hosts itself, through the DNS pins or
+// resolver, and connecting only to the addresses the policy permits. The address checked is the
+// address dialed, so a host cannot pass the check and then be rebound to a forbidden address.
+// Only the proxy the transport chose for the request is dialed unchecked.
+func (p *egressPolicy) dialContext(
+	dialer *net.Dialer,
+	resolver Resolver,
+) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if proxy, _ := ctx.Value(proxyKey{}).(string); proxy != "" && proxy == addr {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		host = strings.ToLower(host)
+
+		var addrs []netip.Addr
+		if ip, err := netip.ParseAddr(host); err == nil {
+			addrs = []netip.Addr{ip}
+		} else if pinned, ok := p.pins[host]; ok {
+			addrs = pinned
+		} else if addrs, err = resolver.LookupNetIP(ctx, "ip", host); err != nil {
+			return nil, err
+		}
+
+		requireNets, _ := ctx.Value(requireNetsKey{}).(bool)
+		var errs []error
+		for _, ip := range addrs {
+			if !p.permitted(ip, requireNets) {
+				continue
+			}
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.Unmap().String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+		}
+		if len(errs) == 0 {
+			return nil, fmt.Errorf("%w: %s resolves to no permitted address", ErrEgressDenied, host)
+		}
+
+		return nil, errors.Join(errs...)
+	}
+}
@@ -0,0 +1,461 @@
+package httpclientx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeResolver resolves the hosts of its map, recording the lookups.
+type fakeResolver struct {
+	hosts   map[string][]netip.Addr
+	lookups []string
+}
+
+func (r *fakeResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	r.lookups = append(r.lookups, host)
+	addrs, ok := r.hosts[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return addrs, nil
+}
+
+func addrs(ips ...string) []netip.Addr {
+	out := make([]netip.Addr, len(ips))
+	for i, ip := range ips {
+		out[i] = netip.MustParseAddr(ip)
+	}
+
+	return out
+}
+
+func TestNewEgressPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantNil   bool
+		wantNames []hostPattern
+		wantNets  []string
+		wantPins  map[string][]netip.Addr
+		wantErr   string
+	}{
+		{name: "unconstrained", wantNil: true},
+		{
+			name: "hosts",
+			cfg:  Config{EgressAllow: []string{" API.example.com ", "*.example.org", "db.internal:5432", "*.svc:8443"}},
+			wantNames: []hostPattern{
+				{host: "api.example.com"},
+				{host: "example.org", wildcard: true},
+				{host: "db.internal", port: "5432"},
+				{host: "svc", wildcard: true, port: "8443"},
+			},
+		},
+		{
+			name: "addresses and ranges",
+			cfg: Config{
+				EgressAllow: []string{"203.0.113.7", "198.51.100.9/24", "[2001:db8::1]", "::ffff:192.0.2.1"},
+			},
+			wantNets: []string{"203.0.113.7/32", "198.51.100.0/24", "2001:db8::1/128", "192.0.2.1/32"},
+		},
+		{
+			name:      "IPv6 address with a port",
+			cfg:       Config{EgressAllow: []string{"[2001:db8::1]:443"}},
+			wantNames: []hostPattern{{host: "2001:db8::1", port: "443"}},
+		},
+		{
+			name:     "pins",
+			cfg:      Config{DNSPins: []string{"API.example.com = 203.0.113.7", "api.example.com=::ffff:203.0.113.8"}},
+			wantPins: map[string][]netip.Addr{"api.example.com": addrs("203.0.113.7", "203.0.113.8")},
+		},
+		{name: "deny private only", cfg: Config{EgressDenyPrivate: true}},
+		{
+			name:    "bare wildcard",
+			cfg:     Config{EgressAllow: []string{"*"}},
+			wantErr: `invalid http_egress_allow entry "*"`,
+		},
+		{name: "empty wildcard", cfg: Config{EgressAllow: []string{"*."}}, wantErr: "invalid http_egress_allow entry"},
+		{name: "inner wildcard", cfg: Config{EgressAllow: []string{"api.*.com"}}, wantErr: "invalid http_egress_allow"},
+		{name: "path", cfg: Config{EgressAllow: []string{"example.com/api"}}, wantErr: "invalid http_egress_allow"},
+		{name: "empty entry", cfg: Config{EgressAllow: []string{" "}}, wantErr: "invalid http_egress_allow"},
+		{name: "pin without an address", cfg: Config{DNSPins: []string{"api.example.com"}}, wantErr: "want host=ip"},
+		{name: "pin without a host", cfg: Config{DNSPins: []string{"=203.0.113.7"}}, wantErr: "want host=ip"},
+		{name: "pin to a name", cfg: Config{DNSPins: []string{"a=b.example.com"}}, wantErr: "invalid http_dns_pins"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newEgressPolicy(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("newEgressPolicy error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantNil {
+				if p != nil {
+					t.Errorf("newEgressPolicy = %+v, want nil", p)
+				}
+				return
+			}
+			if !slices.Equal(p.names, tt.wantNames) {
+				t.Errorf("names = %+v, want %+v", p.names, tt.wantNames)
+			}
+			var nets []string
+			for _, n := range p.nets {
+				nets = append(nets, n.String())
+			}
+			if !slices.Equal(nets, tt.wantNets) {
+				t.Errorf("nets = %v, want %v", nets, tt.wantNets)
+			}
+			if len(p.pins) != len(tt.wantPins) {
+				t.Errorf("pins = %v, want %v", p.pins, tt.wantPins)
+			}
+			for host, want := range tt.wantPins {
+				if !slices.Equal(p.pins[host], want) {
+					t.Errorf("pins[%s] = %v, want %v", host, p.pins[host], want)
+				}
+			}
+			if p.denyPrivate != tt.cfg.EgressDenyPrivate {
+				t.Errorf("denyPrivate = %t", p.denyPrivate)
+			}
+		})
+	}
+}
+
+func TestEgressCheck(t *testing.T) {
+	tests := []struct {
+		name            string
+		allow           []string
+		url             string
+		unresolved      bool
+		wantRequireNets bool
+		wantDenied      bool
+	}{
+		{name: "no allowlist", url: "https://anything.example.com"},
+		{name: "exact host", allow: []string{"api.example.com"}, url: "https://API.example.com/mcp"},
+		{name: "other host", allow: []string{"api.example.com"}, url: "https://evil.example.com", wantDenied: true},
+		{
+			name:       "suffix is not a subdomain",
+			allow:      []string{"example.com"},
+			url:        "https://api.example.com",
+			wantDenied: true,
+		},
+		{name: "lookalike", allow: []string{"*.example.com"}, url: "https://evilexample.com", wantDenied: true},
+		{name: "subdomain", allow: []string{"*.example.com"}, url: "https://a.b.example.com"},
+		{
+			name:       "wildcard excludes its apex",
+			allow:      []string{"*.example.com"},
+			url:        "https://example.com",
+			wantDenied: true,
+		},
+		{name: "default port", allow: []string{"api.example.com:443"}, url: "https://api.example.com/"},
+		{name: "explicit port", allow: []string{"api.example.com:8443"}, url: "https://api.example.com:8443/"},
+		{name: "other port", allow: []string{"api.example.com:443"}, url: "http://api.example.com/", wantDenied: true},
+		{name: "IP in range", allow: []string{"203.0.113.0/24"}, url: "http://203.0.113.9:8080/"},
+		{name: "IP outside range", allow: []string{"203.0.113.0/24"}, url: "http://198.51.100.1/", wantDenied: true},
+		{name: "IPv6 literal", allow: []string{"2001:db8::/32"}, url: "http://[2001:db8::5]/"},
+		{
+			name:            "host checked when dialed",
+			allow:           []string{"api.example.com", "203.0.113.0/24"},
+			url:             "https://other.example.com",
+			wantRequireNets: true,
+		},
+		{
+			name:       "host not checkable through a proxy",
+			allow:      []string{"203.0.113.0/24"},
+			url:        "https://other.example.com",
+			unresolved: true,
+			wantDenied: true,
+		},
+		{name: "IP through a proxy", allow: []string{"203.0.113.0/24"}, url: "http://203.0.113.9/", unresolved: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newEgressPolicy(Config{EgressAllow: tt.allow, EgressDenyPrivate: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			et := &egressTransport{policy: p}
+			requireNets, err := et.check(httptest.NewRequest(http.MethodGet, tt.url, nil), tt.unresolved)
+			if got := errors.Is(err, ErrEgressDenied); got != tt.wantDenied {
+				t.Fatalf("check error = %v, want denied %t", err, tt.wantDenied)
+			}
+			if requireNets != tt.wantRequireNets {
+				t.Errorf("requireNets = %t, want %t", requireNets, tt.wantRequireNets)
+			}
+		})
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "203.0.113.7", want: true},
+		{addr: "2001:db8::1", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "10.1.2.3"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "169.254.169.254"},
+		{addr: "fe80::1"},
+		{addr: "fd00:ec2::254"},
+		{addr: "100.64.0.1"},
+		{addr: "100.127.255.255"},
+		{addr: "100.128.0.1", want: true},
+		{addr: "0.0.0.0"},
+		{addr: "224.0.0.1"},
+		{addr: "255.255.255.255"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := isPublic(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("isPublic(%s) = %t, want %t", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEgressDial(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		hosts       map[string][]netip.Addr
+		host        string
+		requireNets bool
+		proxy       string
+		wantLookups []string
+		wantDenied  bool
+		wantErr     bool
+	}{
+		{name: "IP literal", cfg: Config{DNSPins: []string{"x=127.0.0.1"}}, host: "127.0.0.1"},
+		{
+			name:        "resolved",
+			cfg:         Config{DNSPins: []string{"x=127.0.0.1"}},
+			hosts:       map[string][]netip.Addr{"api.test": addrs("127.0.0.1")},
+			host:        "API.test",
+			wantLookups: []string{"api.test"},
+		},
+		{name: "pinned", cfg: Config{DNSPins: []string{"api.test=127.0.0.1"}}, host: "api.test"},
+		{
+			name:        "lookup failure",
+			cfg:         Config{DNSPins: []string{"x=127.0.0.1"}},
+			host:        "missing.test",
+			wantLookups: []string{"missing.test"},
+			wantErr:     true,
+		},
+		{name: "private literal", cfg: Config{EgressDenyPrivate: true}, host: "127.0.0.1", wantDenied: true},
+		{
+			name:        "rebound to a private address",
+			cfg:         Config{EgressDenyPrivate: true},
+			hosts:       map[string][]netip.Addr{"api.test": addrs("127.0.0.1", "::ffff:10.0.0.1")},
+			host:        "api.test",
+			wantLookups: []string{"api.test"},
+			wantDenied:  true,
+		},
+		{
+			name:        "resolved outside the allowed ranges",
+			cfg:         Config{EgressAllow: []string{"10.0.0.0/8"}},
+			hosts:       map[string][]netip.Addr{"api.test": addrs("127.0.0.1")},
+			host:        "api.test",
+			requireNets: true,
+			wantLookups: []string{"api.test"},
+			wantDenied:  true,
+		},
+		{
+			name:        "first permitted address dialed",
+			cfg:         Config{EgressAllow: []string{"127.0.0.0/8"}},
+			hosts:       map[string][]netip.Addr{"api.test": addrs("10.0.0.1", "127.0.0.1")},
+			host:        "api.test",
+			requireNets: true,
+			wantLookups: []string{"api.test"},
+		},
+		{
+			name:  "proxy dialed unchecked",
+			cfg:   Config{EgressDenyPrivate: true},
+			host:  "127.0.0.1",
+			proxy: "127.0.0.1:" + port,
+		},
+		{
+			name:       "only the chosen proxy is unchecked",
+			cfg:        Config{EgressDenyPrivate: true},
+			host:       "127.0.0.1",
+			proxy:      "127.0.0.1:1",
+			wantDenied: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newEgressPolicy(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := &fakeResolver{hosts: tt.hosts}
+			ctx := context.Background()
+			if tt.requireNets {
+				ctx = context.WithValue(ctx, requireNetsKey{}, true)
+			}
+			if tt.proxy != "" {
+				ctx = context.WithValue(ctx, proxyKey{}, tt.proxy)
+			}
+
+			conn, err := p.dialContext(&net.Dialer{}, r)(ctx, "tcp", net.JoinHostPort(tt.host, port))
+			if conn != nil {
+				_ = conn.Close()
+			}
+			if got := errors.Is(err, ErrEgressDenied); got != tt.wantDenied {
+				t.Errorf("dial error = %v, want denied %t", err, tt.wantDenied)
+			}
+			if (err != nil) != (tt.wantDenied || tt.wantErr) {
+				t.Errorf("dial error = %v", err)
+			}
+			if !slices.Equal(r.lookups, tt.wantLookups) {
+				t.Errorf("lookups = %v, want %v", r.lookups, tt.wantLookups)
+			}
+		})
+	}
+}
+
+func TestNewEgress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := u.Port()
+
+	tests := []struct {
+		name       string
+		cfg        Config
+		url        string
+		transport  http.RoundTripper
+		wantDenied bool
+	}{
+		{name: "allowed address", cfg: Config{EgressAllow: []string{"127.0.0.1"}}, url: srv.URL},
+		{name: "host not allowed", cfg: Config{EgressAllow: []string{"api.test"}}, url: srv.URL, wantDenied: true},
+		{name: "private address", cfg: Config{EgressDenyPrivate: true}, url: srv.URL, wantDenied: true},
+		{
+			name: "pinned host",
+			cfg:  Config{EgressAllow: []string{"api.test"}, DNSPins: []string{"api.test=127.0.0.1"}},
+			url:  "http://api.test:" + port + "/",
+		},
+		{
+			name: "pinned host in an allowed range",
+			cfg:  Config{EgressAllow: []string{"127.0.0.0/8"}, DNSPins: []string{"api.test=127.0.0.1"}},
+			url:  "http://api.test:" + port + "/",
+		},
+		{
+			name:       "pinned host outside the allowed ranges",
+			cfg:        Config{EgressAllow: []string{"10.0.0.0/8"}, DNSPins: []string{"api.test=127.0.0.1"}},
+			url:        "http://api.test:" + port + "/",
+			wantDenied: true,
+		},
+		{
+			name:       "host not checkable by a custom transport",
+			cfg:        Config{EgressAllow: []string{"127.0.0.0/8"}},
+			url:        "http://localhost:" + port + "/",
+			transport:  http.DefaultTransport,
+			wantDenied: true,
+		},
+		{
+			name:      "allowed host with a custom transport",
+			cfg:       Config{EgressAllow: []string{"localhost"}},
+			url:       "http://localhost:" + port + "/",
+			transport: http.DefaultTransport,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Proxy = "none"
+			cfg.EgressAllow, cfg.DNSPins = tt.cfg.EgressAllow, tt.cfg.DNSPins
+			cfg.EgressDenyPrivate = tt.cfg.EgressDenyPrivate
+			var opts []Option
+			if tt.transport != nil {
+				opts = append(opts, WithTransport(tt.transport))
+			}
+			rec := &countRecorder{}
+			opts = append(opts, WithMetrics(rec))
+			hc, err := New(cfg, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := hc.Get(tt.url)
+			if resp != nil {
+				_ = resp.Body.Close()
+			}
+			if got := errors.Is(err, ErrEgressDenied); got != tt.wantDenied {
+				t.Errorf("Get error = %v, want denied %t", err, tt.wantDenied)
+			}
+			if !tt.wantDenied && err != nil {
+				t.Fatal(err)
+			}
+			target, _ := url.Parse(tt.url)
+			if got := rec.count(ClientRetries + " " + LabelHost + "=" + target.Host); got != 0 {
+				t.Errorf("request retried %d times", got)
+			}
+		})
+	}
+}
+
+// closeTracker is a request body recording whether it was closed.
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestEgressTransportClosesDeniedBody(t *testing.T) {
+	p, err := newEgressPolicy(Config{EgressAllow: []string{"api.example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	et := &egressTransport{next: http.DefaultTransport, policy: p}
+	body := &closeTracker{Reader: strings.NewReader("{}")}
+	req := httptest.NewRequest(http.MethodPost, "https://evil.example.com/", body)
+
+	if _, err := et.RoundTrip(req); !errors.Is(err, ErrEgressDenied) {
+		t.Fatalf("RoundTrip error = %v, want ErrEgressDenied", err)
+	}
+	if !body.closed {
+		t.Error("request body of a denied request was not closed")
+	}
+}
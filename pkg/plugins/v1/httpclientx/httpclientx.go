@@ -0,0 +1,363 @@
+// Package httpclientx builds the *http.Client plugins use to call external services, with
+// timeouts, connection pooling, proxy support, retries, a per-host circuit breaker, trace
+// propagation and metrics configured in one place:
+//
+//	client, err := httpclientx.New(httpclientx.DefaultConfig(), httpclientx.WithMetrics(recorder))
+//	if err != nil {
+//	    return err
+//	}
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://policy.internal/v1/rules", nil)
+//	resp, err := client.Do(req)
+//
+// Requests built with a plugin handler's context carry the handler's trace: the client sends a
+// traceparent header continuing the trace propagated by mcpd, so outbound calls appear in the
+// same trace as the plugin call.
+//
+// Security teams can constrain what a plugin may contact with the egress settings: an allowlist
+// of hosts and IP ranges, refusal of private and metadata addresses, and fixed DNS pins. The
+// client resolves hosts itself and connects to the very addresses it checked, so DNS rebinding
+// cannot route an allowed host to a forbidden address.
+//
+// Config is decodable from custom_config with mcpdpluginsv1.DecodeConfig and can be embedded in a
+// plugin's own configuration struct.
+package httpclientx
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	mrand "math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/rng"
+)
+
+// Names of the metrics recorded by clients created with WithMetrics.
+const (
+	// ClientRequests counts outbound requests, labelled by host, method and status code.
+	ClientRequests = "http.client.requests"
+
+	// ClientDuration records the latency of outbound requests, including retries.
+	ClientDuration = "http.client.duration"
+
+	// ClientRetries counts retried attempts, labelled by host.
+	ClientRetries = "http.client.retries"
+)
+
+// Label keys used by the client metrics.
+const (
+	LabelHost   = "host"
+	LabelStatus = "status"
+)
+
+// Config configures a client, decodable from custom_config with mcpdpluginsv1.DecodeConfig.
+type Config struct {
+	// Timeout bounds a whole request, retries included.
+	Timeout time.Duration `config:"http_timeout" default:"30s"`
+
+	// DialTimeout bounds connection setup.
+	DialTimeout time.Duration `config:"http_dial_timeout" default:"5s"`
+
+	// TLSHandshakeTimeout bounds the TLS handshake.
+	TLSHandshakeTimeout time.Duration `config:"http_tls_handshake_timeout" default:"10s"`
+
+	// ResponseHeaderTimeout bounds the wait for response headers after the request is written
+	// (zero waits up to Timeout).
+	ResponseHeaderTimeout time.Duration `config:"http_response_header_timeout"`
+
+	// IdleConnTimeout closes pooled connections idle for longer.
+	IdleConnTimeout time.Duration `config:"http_idle_conn_timeout" default:"90s"`
+
+	// MaxIdleConns and MaxIdleConnsPerHost size the connection pool.
+	MaxIdleConns        int `config:"http_max_idle_conns" default:"100"`
+	MaxIdleConnsPerHost int `config:"http_max_idle_conns_per_host" default:"10"`
+
+	// MaxConnsPerHost limits concurrent connections per host (zero is unlimited).
+	MaxConnsPerHost int `config:"http_max_conns_per_host"`
+
+	// Proxy is the proxy URL. When empty, HTTP_PROXY, HTTPS_PROXY and NO_PROXY are honoured;
+	// "none" disables proxying.
+	Proxy string `config:"http_proxy"`
+
+	// Retries is the number of retries after a failed attempt (zero disables retries). Only
+	// idempotent requests, or requests with an Idempotency-Key header, are retried after network
+	// errors and 429, 502, 503 and 504 responses.
+	Retries int `config:"http_retries" default:"2"`
+
+	// RetryBackoff is the initial delay between attempts, doubled on each retry with full jitter
+	// and capped at RetryMaxBackoff. A Retry-After header takes precedence when shorter than the cap.
+	RetryBackoff    time.Duration `config:"http_retry_backoff" default:"200ms"`
+	RetryMaxBackoff time.Duration `config:"http_retry_max_backoff" default:"5s"`
+
+	// BreakerThreshold is the number of consecutive failures (network errors and 5xx responses)
+	// after which requests to a host fail fast with ErrCircuitOpen (zero disables the breaker).
+	BreakerThreshold int `config:"http_breaker_threshold" default:"5"`
+
+	// BreakerCooldown is how long the breaker stays open before letting a probe request through.
+	BreakerCooldown time.Duration `config:"http_breaker_cooldown" default:"30s"`
+
+	// UserAgent is sent when requests do not set one.
+	UserAgent string `config:"http_user_agent"`
+
+	// EgressAllow restricts requests to destinations matching one of its entries: a host such as
+	// "api.example.com", its subdomains as "*.example.com", either with an optional ":port", or an
+	// IP address or CIDR range such as "203.0.113.0/24", which also admits hosts resolving into it.
+	// Empty allows every destination. Through a proxy, only host entries and IP literals can match.
+	EgressAllow []string `config:"http_egress_allow"`
+
+	// EgressDenyPrivate refuses connections to loopback, private, link-local (cloud metadata
+	// endpoints included) and other non-public addresses. Connections to the proxy a request is
+	// sent through are exempt, so destinations reached through a proxy are not checked.
+	EgressDenyPrivate bool `config:"http_egress_deny_private"`
+
+	// DNSPins resolves hosts to fixed addresses instead of querying DNS, as "host=ip" entries;
+	// repeat a host to pin it to several addresses.
+	DNSPins []string `config:"http_dns_pins"`
+}
+
+// DefaultConfig returns the Config with every default applied.
+func DefaultConfig() Config {
+	var cfg Config
+	if _, err := config.Decode(nil, &cfg); err != nil {
+		panic(fmt.Sprintf("httpclientx: invalid defaults: %v", err))
+	}
+
+	return cfg
+}
+
+type options struct {
+	recorder  metrics.Recorder
+	tlsConfig *tls.Config
+	transport http.RoundTripper
+	resolver  Resolver
+	clock     clock.Clock
+	rand      *mrand.Rand
+}
+
+// Resolver resolves host names for the client's connections. *net.Resolver and
+// *dnscache.Resolver implement it.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// Option configures New.
+type Option func(*options) error
+
+// WithMetrics records ClientRequests, ClientDuration and ClientRetries through r.
+func WithMetrics(r metrics.Recorder) Option {
+	return func(o *options) error {
+		if r == nil {
+			return fmt.Errorf("metrics recorder cannot be nil")
+		}
+		o.recorder = r
+		return nil
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the client's transport, for example to trust a
+// private CA or present a client certificate.
+func WithTLSConfig(c *tls.Config) Option {
+	return func(o *options) error {
+		o.tlsConfig = c
+		return nil
+	}
+}
+
+// WithResolver resolves the hosts the client connects to with r instead of the system resolver,
+// for example with a shared dnscache.Resolver.
+func WithResolver(r Resolver) Option {
+	return func(o *options) error {
+		if r == nil {
+			return fmt.Errorf("resolver cannot be nil")
+		}
+		o.resolver = r
+		return nil
+	}
+}
+
+// WithClock sets the clock of retry backoff and circuit breaker cooldowns (defaults to
+// clock.Real()). The client's Timeout and the transport's timeouts keep the wall clock.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) error {
+		if c == nil {
+			return fmt.Errorf("clock cannot be nil")
+		}
+		o.clock = c
+		return nil
+	}
+}
+
+// WithRandSource draws retry backoff jitter from src (defaults to rng.Default()), for example a
+// seeded rng.New source for reproducible test runs.
+func WithRandSource(src mrand.Source) Option {
+	return func(o *options) error {
+		if src == nil {
+			return fmt.Errorf("rand source cannot be nil")
+		}
+		o.rand = mrand.New(rng.Locked(src))
+		return nil
+	}
+}
+
+// WithTransport replaces the pooled transport, for example with a test double. Pooling, proxy,
+// TLS and resolver settings are then ignored; retries, the breaker, tracing and metrics still apply.
+// The host entries of EgressAllow apply too, but since the transport resolves hosts itself, hosts
+// are not matched against its IP ranges, and EgressDenyPrivate and DNSPins have no effect.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(o *options) error {
+		if rt == nil {
+			return fmt.Errorf("transport cannot be nil")
+		}
+		o.transport = rt
+		return nil
+	}
+}
+
+// New returns an *http.Client configured by cfg.
+func New(cfg Config, opts ...Option) (*http.Client, error) {
+	o := options{recorder: metrics.Nop(), clock: clock.Real(), rand: mrand.New(rng.Default())}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Retries < 0 || cfg.BreakerThreshold < 0 {
+		return nil, fmt.Errorf("retries and breaker threshold cannot be negative")
+	}
+
+	egress, err := newEgressPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	base := o.transport
+	var proxy func(*http.Request) (*url.URL, error)
+	if base == nil {
+		t, err := newTransport(cfg, o.tlsConfig, egress, o.resolver)
+		if err != nil {
+			return nil, err
+		}
+		base, proxy = t, t.Proxy
+	}
+
+	var rt http.RoundTripper = base
+	if cfg.BreakerThreshold > 0 {
+		rt = newBreaker(rt, cfg.BreakerThreshold, cfg.BreakerCooldown, o.clock)
+	}
+	if cfg.Retries > 0 {
+		rt = &retrier{
+			next:     rt,
+			retries:  cfg.Retries,
+			backoff:  cfg.RetryBackoff,
+			max:      cfg.RetryMaxBackoff,
+			recorder: o.recorder,
+			clock:    o.clock,
+			rand:     o.rand,
+		}
+	}
+	if egress != nil {
+		rt = &egressTransport{next: rt, policy: egress, proxy: proxy, unchecked: o.transport != nil}
+	}
+	rt = &instrumented{next: rt, recorder: o.recorder, userAgent: cfg.UserAgent}
+
+	return &http.Client{Transport: rt, Timeout: cfg.Timeout}, nil
+}
+
+func newTransport(cfg Config, tlsConfig *tls.Config, egress *egressPolicy, resolver Resolver) (*http.Transport, error) {
+	proxy := http.ProxyFromEnvironment
+	switch p := strings.TrimSpace(cfg.Proxy); {
+	case p == "":
+	case strings.EqualFold(p, "none"):
+		proxy = nil
+	default:
+		u, err := url.Parse(p)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", cfg.Proxy)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: 30 * time.Second}
+	dial := dialer.DialContext
+	if egress != nil || resolver != nil {
+		if egress == nil {
+			// Permits every address: the dialer only resolves through resolver.
+			egress = &egressPolicy{}
+		}
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		dial = egress.dialContext(dialer, resolver)
+	}
+
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dial,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		ExpectContinueTimeout: time.Second,
+		ForceAttemptHTTP2:     true,
+	}, nil
+}
+
+// instrumented propagates the trace context and records metrics around the whole request.
+type instrumented struct {
+	next      http.RoundTripper
+	recorder  metrics.Recorder
+	userAgent string
+}
+
+func (t *instrumented) RoundTrip(req *http.Request) (*http.Response, error) {
+	needsTrace := req.Header.Get(mcpdpluginsv1.TraceparentHeader) == ""
+	needsAgent := t.userAgent != "" && req.Header.Get("User-Agent") == ""
+	if needsTrace || needsAgent {
+		// RoundTrippers must not modify the caller's request.
+		req = req.Clone(req.Context())
+		if needsTrace {
+			if tc, ok := mcpdpluginsv1.TraceContextFromContext(req.Context(), nil); ok {
+				tc.SpanID = newSpanID()
+				req.Header.Set(mcpdpluginsv1.TraceparentHeader, tc.Traceparent())
+			}
+		}
+		if needsAgent {
+			req.Header.Set("User-Agent", t.userAgent)
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	host := metrics.L(LabelHost, req.URL.Host)
+	t.recorder.Count(ClientRequests, 1, host, metrics.L(metrics.LabelMethod, req.Method), metrics.L(LabelStatus, code))
+	t.recorder.Timing(ClientDuration, time.Since(start), host, metrics.L(metrics.LabelMethod, req.Method))
+
+	return resp, err
+}
+
+func newSpanID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	b[0] |= 1 // Never all zeroes.
+
+	return hex.EncodeToString(b)
+}
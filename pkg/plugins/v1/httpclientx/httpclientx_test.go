@@ -0,0 +1,381 @@
+package httpclientx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// countRecorder records counters and timings by name and labels.
+type countRecorder struct {
+	mu      sync.Mutex
+	counts  map[string]int64
+	timings map[string]int
+}
+
+func (r *countRecorder) key(name string, labels []metrics.Label) string {
+	parts := []string{name}
+	for _, l := range labels {
+		parts = append(parts, l.Key+"="+l.Value)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (r *countRecorder) Count(name string, delta int64, labels ...metrics.Label) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.counts == nil {
+		r.counts = map[string]int64{}
+	}
+	r.counts[r.key(name, labels)] += delta
+}
+
+func (r *countRecorder) Timing(name string, _ time.Duration, labels ...metrics.Label) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.timings == nil {
+		r.timings = map[string]int{}
+	}
+	r.timings[r.key(name, labels)]++
+}
+
+func (*countRecorder) Gauge(string, float64, ...metrics.Label)   {}
+func (*countRecorder) Observe(string, float64, ...metrics.Label) {}
+
+func (r *countRecorder) count(key string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.counts[key]
+}
+
+func (r *countRecorder) timing(key string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.timings[key]
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Timeout != 30*time.Second || cfg.DialTimeout != 5*time.Second || cfg.Retries != 2 ||
+		cfg.RetryBackoff != 200*time.Millisecond || cfg.BreakerThreshold != 5 ||
+		cfg.BreakerCooldown != 30*time.Second || cfg.MaxIdleConnsPerHost != 10 {
+		t.Errorf("DefaultConfig = %+v", cfg)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  func(*Config)
+		opt  Option
+		want string
+	}{
+		{name: "negative retries", cfg: func(c *Config) { c.Retries = -1 }, want: "cannot be negative"},
+		{name: "negative threshold", cfg: func(c *Config) { c.BreakerThreshold = -1 }, want: "cannot be negative"},
+		{name: "proxy without a host", cfg: func(c *Config) { c.Proxy = "proxy.internal" }, want: "invalid proxy URL"},
+		{name: "malformed proxy", cfg: func(c *Config) { c.Proxy = "http://[::1" }, want: "invalid proxy URL"},
+		{name: "nil recorder", opt: WithMetrics(nil), want: "metrics recorder cannot be nil"},
+		{name: "nil resolver", opt: WithResolver(nil), want: "resolver cannot be nil"},
+		{name: "nil clock", opt: WithClock(nil), want: "clock cannot be nil"},
+		{name: "nil transport", opt: WithTransport(nil), want: "transport cannot be nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			var opts []Option
+			if tt.opt != nil {
+				opts = append(opts, tt.opt)
+			}
+			if _, err := New(cfg, opts...); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	tests := []struct {
+		name      string
+		proxy     string
+		wantProxy string // Empty for no proxy; "env" for the environment's.
+	}{
+		{name: "environment", proxy: "", wantProxy: "env"},
+		{name: "disabled", proxy: "none"},
+		{name: "disabled in upper case", proxy: " NONE "},
+		{name: "explicit", proxy: "http://proxy.internal:3128", wantProxy: "http://proxy.internal:3128"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Proxy = tt.proxy
+			cfg.MaxConnsPerHost = 4
+			tr, err := newTransport(cfg, nil, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tr.MaxConnsPerHost != 4 || tr.MaxIdleConnsPerHost != cfg.MaxIdleConnsPerHost ||
+				tr.IdleConnTimeout != cfg.IdleConnTimeout || tr.TLSHandshakeTimeout != cfg.TLSHandshakeTimeout {
+				t.Errorf("transport pool settings do not match %+v", cfg)
+			}
+
+			var got string
+			if tr.Proxy != nil {
+				u, err := tr.Proxy(httptest.NewRequest(http.MethodGet, "https://api.example.com/", nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if u != nil {
+					got = u.String()
+				}
+			}
+			if tt.wantProxy == "env" {
+				// ProxyFromEnvironment reads the environment once per process, so only its use is
+				// checked.
+				if tr.Proxy == nil {
+					t.Error("proxy from the environment is not used")
+				}
+				return
+			}
+			if got != tt.wantProxy {
+				t.Errorf("proxy = %q, want %q", got, tt.wantProxy)
+			}
+		})
+	}
+}
+
+// traced returns a context carrying traceparent as mcpd propagates it.
+func traced(traceparent string) context.Context {
+	md := metadata.Pairs(mcpdpluginsv1.TraceparentHeader, traceparent)
+
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func TestInstrumentedHeaders(t *testing.T) {
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		header        http.Header
+		userAgent     string
+		wantTraceID   string // Empty expects no traceparent, or header's own when set.
+		wantUserAgent string
+	}{
+		{name: "no trace", ctx: context.Background(), wantUserAgent: "Go-http-client/1.1"},
+		{
+			name:          "continues the trace",
+			ctx:           traced(parent),
+			wantTraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantUserAgent: "Go-http-client/1.1",
+		},
+		{
+			name:          "keeps the caller's traceparent",
+			ctx:           traced(parent),
+			header:        http.Header{"Traceparent": {"00-11111111111111111111111111111111-2222222222222222-00"}},
+			wantTraceID:   "11111111111111111111111111111111",
+			wantUserAgent: "Go-http-client/1.1",
+		},
+		{
+			name:          "default user agent",
+			ctx:           context.Background(),
+			userAgent:     "policy-plugin/1.0",
+			wantUserAgent: "policy-plugin/1.0",
+		},
+		{
+			name:          "keeps the caller's user agent",
+			ctx:           context.Background(),
+			header:        http.Header{"User-Agent": {"caller/2.0"}},
+			userAgent:     "policy-plugin/1.0",
+			wantUserAgent: "caller/2.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got http.Header
+			srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+			}))
+			defer srv.Close()
+
+			cfg := DefaultConfig()
+			cfg.UserAgent = tt.userAgent
+			client, err := New(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequestWithContext(tt.ctx, http.MethodGet, srv.URL, nil)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			before := req.Header.Clone()
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+
+			if len(req.Header) != len(before) {
+				t.Errorf("caller's request headers changed to %v", req.Header)
+			}
+			if ua := got.Get("User-Agent"); ua != tt.wantUserAgent {
+				t.Errorf("User-Agent = %q, want %q", ua, tt.wantUserAgent)
+			}
+			tp := got.Get(mcpdpluginsv1.TraceparentHeader)
+			if tt.wantTraceID == "" {
+				if tp != "" {
+					t.Errorf("traceparent = %q, want none", tp)
+				}
+				return
+			}
+			tc, ok := mcpdpluginsv1.ParseTraceparent(tp)
+			if !ok || tc.TraceID != tt.wantTraceID {
+				t.Fatalf("traceparent = %q, want trace %s", tp, tt.wantTraceID)
+			}
+			if tt.header == nil && (tc.SpanID == "00f067aa0ba902b7" || !tc.Sampled) {
+				t.Errorf("traceparent = %q, want a new sampled span", tp)
+			}
+		})
+	}
+}
+
+func TestNewMetrics(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	var rec countRecorder
+	client, err := New(DefaultConfig(), WithMetrics(&rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || attempts != 2 {
+		t.Errorf("status %d after %d attempts, want 200 after 2", resp.StatusCode, attempts)
+	}
+	if got := rec.count(ClientRequests + " host=" + host + " method=GET status=200"); got != 1 {
+		t.Errorf("%s = %d, want 1 for the whole request", ClientRequests, got)
+	}
+	if got := rec.count(ClientRetries + " host=" + host); got != 1 {
+		t.Errorf("%s = %d, want 1", ClientRetries, got)
+	}
+	if got := rec.timing(ClientDuration + " host=" + host + " method=GET"); got != 1 {
+		t.Errorf("%s recorded %d times, want 1", ClientDuration, got)
+	}
+
+	srv.Close()
+	if _, err := client.Get(srv.URL); err == nil {
+		t.Fatal("request to a closed server succeeded")
+	}
+	if got := rec.count(ClientRequests + " host=" + host + " method=GET status=error"); got != 1 {
+		t.Errorf("%s with status=error = %d, want 1", ClientRequests, got)
+	}
+}
+
+func TestNewLayers(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          func(*Config)
+		wantAttempts int
+		wantErr      error
+	}{
+		{name: "retries then breaker", wantAttempts: 2, wantErr: ErrCircuitOpen},
+		{name: "no retries", cfg: func(c *Config) { c.Retries = 0 }, wantAttempts: 1},
+		{name: "no breaker", cfg: func(c *Config) { c.BreakerThreshold = 0 }, wantAttempts: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			rt := roundTripFunc(func(*http.Request) (*http.Response, error) {
+				attempts++
+				return nil, errNetwork
+			})
+			cfg := DefaultConfig()
+			cfg.BreakerThreshold = 2
+			cfg.RetryBackoff = time.Microsecond
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			client, err := New(cfg, WithTransport(rt), WithClock(&sleepRecorder{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = client.Get("http://a.test/")
+			want := tt.wantErr
+			if want == nil {
+				want = errNetwork
+			}
+			var uerr *url.Error
+			if !errors.As(err, &uerr) || !errors.Is(err, want) {
+				t.Errorf("Get error = %v, want %v", err, want)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("%d attempts, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestWithResolver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &fakeResolver{hosts: map[string][]netip.Addr{"api.test": addrs("127.0.0.1")}}
+	cfg := DefaultConfig()
+	cfg.Proxy = "none"
+	client, err := New(cfg, WithResolver(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Get("http://api.test:" + u.Port() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+	if !slices.Equal(r.lookups, []string{"api.test"}) {
+		t.Errorf("lookups = %v, want api.test through the resolver", r.lookups)
+	}
+}
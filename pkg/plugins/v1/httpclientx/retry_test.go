@@ -0,0 +1,348 @@
+package httpclientx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	mrand "math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/plugintest"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/rng"
+)
+
+// sleepRecorder is a clock whose timers fire at once, recording the durations slept.
+type sleepRecorder struct {
+	mu     sync.Mutex
+	sleeps []time.Duration
+}
+
+func (c *sleepRecorder) Now() time.Time { return time.Unix(0, 0) }
+
+func (c *sleepRecorder) NewTimer(d time.Duration) clock.Timer {
+	c.mu.Lock()
+	c.sleeps = append(c.sleeps, d)
+	c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	ch <- time.Unix(0, 0)
+
+	return firedTimer(ch)
+}
+
+type firedTimer chan time.Time
+
+func (t firedTimer) C() <-chan time.Time    { return t }
+func (firedTimer) Stop() bool               { return false }
+func (firedTimer) Reset(time.Duration) bool { return false }
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// unavailable answers every request with 503.
+func unavailable(*http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	rec.WriteHeader(http.StatusServiceUnavailable)
+
+	return rec.Result(), nil
+}
+
+// retryDelays returns the delays slept by a retrier drawing jitter from src, retrying a GET
+// against an upstream that keeps failing.
+func retryDelays(t *testing.T, src mrand.Source) []time.Duration {
+	t.Helper()
+
+	clk := &sleepRecorder{}
+	r := &retrier{
+		next:     roundTripFunc(unavailable),
+		retries:  5,
+		backoff:  100 * time.Millisecond,
+		max:      time.Second,
+		recorder: metrics.Nop(),
+		clock:    clk,
+		rand:     mrand.New(rng.Locked(src)),
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://upstream.test/", nil)
+	resp, err := r.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	return clk.sleeps
+}
+
+func TestRetryJitterRandSource(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  uint64
+		equal bool
+	}{
+		{name: "same seed", a: 3, b: 3, equal: true},
+		{name: "different seeds", a: 3, b: 4, equal: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := retryDelays(t, rng.New(tt.a)), retryDelays(t, rng.New(tt.b))
+			if got := slices.Equal(a, b); got != tt.equal {
+				t.Errorf("delays %v and %v: equal = %v, want %v", a, b, got, tt.equal)
+			}
+		})
+	}
+}
+
+func TestRetryJitterBounds(t *testing.T) {
+	// Full jitter: each delay is below the doubling backoff, capped at the maximum.
+	bounds := []time.Duration{100, 200, 400, 800, 1000}
+	delays := retryDelays(t, rng.New(1))
+	if len(delays) != len(bounds) {
+		t.Fatalf("%d retries slept, want %d", len(delays), len(bounds))
+	}
+	for i, d := range delays {
+		if limit := bounds[i] * time.Millisecond; d < 0 || d >= limit {
+			t.Errorf("delay %d = %s, want it in [0, %s)", i, d, limit)
+		}
+	}
+}
+
+func TestWithRandSourceNil(t *testing.T) {
+	if _, err := New(DefaultConfig(), WithRandSource(nil)); err == nil {
+		t.Error("New accepted a nil rand source")
+	}
+}
+
+// newRetrier returns a retrier allowing three retries in front of next, sleeping on clk.
+func newRetrier(next http.RoundTripper, clk clock.Clock) *retrier {
+	return &retrier{
+		next:     next,
+		retries:  3,
+		backoff:  100 * time.Millisecond,
+		max:      time.Second,
+		recorder: metrics.Nop(),
+		clock:    clk,
+		rand:     mrand.New(rng.New(1)),
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		body         bool // Sent with a replayable body.
+		oneShotBody  bool // Sent with a body that cannot be replayed.
+		idempotency  bool
+		outcomes     []int
+		wantAttempts int
+		wantStatus   int // Zero for errNetwork.
+	}{
+		{name: "success", method: http.MethodGet, outcomes: []int{200}, wantAttempts: 1, wantStatus: 200},
+		{
+			name:         "retried until success",
+			method:       http.MethodGet,
+			outcomes:     []int{503, 0, 200},
+			wantAttempts: 3,
+			wantStatus:   200,
+		},
+		{
+			name:         "retries exhausted",
+			method:       http.MethodGet,
+			outcomes:     []int{503, 503, 503, 504},
+			wantAttempts: 4,
+			wantStatus:   504,
+		},
+		{name: "network errors exhausted", method: http.MethodHead, outcomes: []int{0, 0, 0, 0}, wantAttempts: 4},
+		{
+			name:         "rate limited",
+			method:       http.MethodDelete,
+			outcomes:     []int{429, 502, 204},
+			wantAttempts: 3,
+			wantStatus:   204,
+		},
+		{name: "internal error", method: http.MethodGet, outcomes: []int{500}, wantAttempts: 1, wantStatus: 500},
+		{name: "client error", method: http.MethodPut, outcomes: []int{409}, wantAttempts: 1, wantStatus: 409},
+		{name: "POST", method: http.MethodPost, outcomes: []int{503}, wantAttempts: 1, wantStatus: 503},
+		{
+			name:         "POST with an idempotency key",
+			method:       http.MethodPost,
+			body:         true,
+			idempotency:  true,
+			outcomes:     []int{503, 201},
+			wantAttempts: 2,
+			wantStatus:   201,
+		},
+		{
+			name:         "replayable body",
+			method:       http.MethodPut,
+			body:         true,
+			outcomes:     []int{0, 200},
+			wantAttempts: 2,
+			wantStatus:   200,
+		},
+		{
+			name:         "body that cannot be replayed",
+			method:       http.MethodPut,
+			oneShotBody:  true,
+			outcomes:     []int{503},
+			wantAttempts: 1,
+			wantStatus:   503,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &scripted{outcomes: tt.outcomes}
+			var req *http.Request
+			switch {
+			case tt.body:
+				req = httptest.NewRequest(tt.method, "http://a.test/", nil)
+				req.Body = io.NopCloser(strings.NewReader("payload"))
+				req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("payload")), nil }
+			case tt.oneShotBody:
+				req = httptest.NewRequest(tt.method, "http://a.test/", strings.NewReader("payload"))
+				req.GetBody = nil
+			default:
+				req = httptest.NewRequest(tt.method, "http://a.test/", nil)
+			}
+			if tt.idempotency {
+				req.Header.Set("Idempotency-Key", "k1")
+			}
+
+			resp, err := newRetrier(next, &sleepRecorder{}).RoundTrip(req)
+			if tt.wantStatus == 0 {
+				if !errors.Is(err, errNetwork) {
+					t.Fatalf("RoundTrip error = %v, want the network error", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				_ = resp.Body.Close()
+				if resp.StatusCode != tt.wantStatus {
+					t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+				}
+			}
+			if got := len(next.requests); got != tt.wantAttempts {
+				t.Errorf("%d attempts, want %d", got, tt.wantAttempts)
+			}
+			if tt.body {
+				for i, r := range next.requests {
+					if b, _ := io.ReadAll(r.Body); string(b) != "payload" {
+						t.Errorf("attempt %d sent body %q, want the full payload", i, b)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRetryNotRetriedErrors(t *testing.T) {
+	for _, err := range []error{ErrCircuitOpen, fmt.Errorf("%w: a.test", ErrEgressDenied)} {
+		attempts := 0
+		next := roundTripFunc(func(*http.Request) (*http.Response, error) {
+			attempts++
+			return nil, err
+		})
+		_, got := newRetrier(next, &sleepRecorder{}).RoundTrip(httptest.NewRequest(http.MethodGet, "/", nil))
+		if !errors.Is(got, err) {
+			t.Errorf("RoundTrip error = %v, want %v", got, err)
+		}
+		if attempts != 1 {
+			t.Errorf("%v: %d attempts, want 1", err, attempts)
+		}
+	}
+}
+
+func TestRetryGetBodyError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "http://a.test/", strings.NewReader("payload"))
+	req.GetBody = func() (io.ReadCloser, error) { return nil, errors.New("body gone") }
+
+	_, err := newRetrier(&scripted{outcomes: []int{503}}, &sleepRecorder{}).RoundTrip(req)
+	if err == nil || err.Error() != "body gone" {
+		t.Errorf("RoundTrip error = %v, want the GetBody error", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		want       time.Duration // Delay slept; -1 for jittered backoff.
+	}{
+		{name: "seconds", retryAfter: "1", want: time.Second},
+		{name: "zero seconds", retryAfter: "0", want: 0},
+		{name: "current date", retryAfter: time.Unix(0, 0).UTC().Format(http.TimeFormat), want: 0},
+		{name: "future date", retryAfter: time.Unix(1, 0).UTC().Format(http.TimeFormat), want: time.Second},
+		{name: "past date", retryAfter: time.Unix(-60, 0).UTC().Format(http.TimeFormat), want: 0},
+		{name: "beyond the maximum", retryAfter: "30", want: -1},
+		{name: "negative", retryAfter: "-1", want: -1},
+		{name: "malformed", retryAfter: "soon", want: -1},
+		{name: "absent", want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := 0
+			next := roundTripFunc(func(*http.Request) (*http.Response, error) {
+				responses++
+				rec := httptest.NewRecorder()
+				if responses == 1 {
+					if tt.retryAfter != "" {
+						rec.Header().Set("Retry-After", tt.retryAfter)
+					}
+					rec.WriteHeader(http.StatusServiceUnavailable)
+				}
+				return rec.Result(), nil
+			})
+			clk := &sleepRecorder{}
+			resp, err := newRetrier(next, clk).RoundTrip(httptest.NewRequest(http.MethodGet, "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+
+			// clock.Sleep does not start a timer for zero delays.
+			var slept time.Duration
+			if len(clk.sleeps) == 1 {
+				slept = clk.sleeps[0]
+			}
+			switch {
+			case tt.want < 0 && (slept < 0 || slept >= 100*time.Millisecond):
+				t.Errorf("slept %s, want jittered backoff below 100ms", slept)
+			case tt.want >= 0 && slept != tt.want:
+				t.Errorf("slept %s, want %s", slept, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryCancelledDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	next := &scripted{outcomes: []int{503}}
+	clk := plugintest.NewFakeClock(time.Time{})
+	r := newRetrier(next, clk)
+	r.backoff = time.Second
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.RoundTrip(httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+		done <- err
+	}()
+	clk.WaitForTimers(1)
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("RoundTrip error = %v, want context.Canceled", err)
+	}
+	if got := len(next.requests); got != 1 {
+		t.Errorf("%d attempts, want 1", got)
+	}
+}
@@ -0,0 +1,246 @@
+// Package idempotency rejects replayed requests, protecting upstream MCP servers from invoking a
+// tool twice when a client retries a call that already went through.
+//
+// A Guard remembers, for a TTL, the Idempotency-Key header of requests and the JSON-RPC ids of
+// the requests sent within an MCP session, and rejects a request reusing one with 409 Conflict.
+// Seen keys are kept in a quota.Store, so RedisStore and MemcachedStore share them between
+// plugin replicas:
+//
+//	guard, err := idempotency.New(quota.NewMemoryStore(), 10*time.Minute)
+//	if err != nil {
+//	    return err
+//	}
+//
+// and in the plugin's request handler:
+//
+//	return guard.HandleRequest(ctx, req), nil
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/quota"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/tokens"
+)
+
+// DefaultHeader is the request header carrying a client-chosen idempotency key.
+const DefaultHeader = "Idempotency-Key"
+
+// Kinds of keys checked by a Guard.
+const (
+	KindIdempotencyKey = "idempotency_key"
+	KindJSONRPCID      = "jsonrpc_id"
+)
+
+// Decision is the outcome of a replay check.
+type Decision struct {
+	// Replay reports whether the request reuses a key seen within the TTL.
+	Replay bool
+
+	// Kind and Key are the reused key's kind and value as sent by the client: the idempotency key,
+	// or the JSON-RPC id.
+	Kind string
+	Key  string
+}
+
+// Guard rejects requests reusing an idempotency key or a session's JSON-RPC id. It is safe for
+// concurrent use.
+type Guard struct {
+	store      quota.Store
+	ttl        time.Duration
+	header     string
+	scope      quota.KeyFunc
+	methods    map[string]struct{}
+	prefix     string
+	failClosed bool
+	logger     *log.Logger
+	denial     *mcpdpluginsv1.DenyTemplate
+}
+
+// Option configures a Guard.
+type Option func(*Guard) error
+
+// WithHeader sets the header carrying idempotency keys (defaults to DefaultHeader). An empty
+// name disables idempotency keys, leaving only JSON-RPC id checks.
+func WithHeader(name string) Option {
+	return func(g *Guard) error {
+		g.header = name
+		return nil
+	}
+}
+
+// WithScope sets how idempotency keys are scoped, so two clients choosing the same key do not
+// collide (defaults to tokens.SessionKey). JSON-RPC ids are always scoped to their MCP session.
+func WithScope(scope quota.KeyFunc) Option {
+	return func(g *Guard) error {
+		if scope == nil {
+			return fmt.Errorf("scope function cannot be nil")
+		}
+		g.scope = scope
+		return nil
+	}
+}
+
+// WithMethods sets the MCP methods whose JSON-RPC ids are checked (defaults to tools/call). With
+// no methods, the ids of every request are checked.
+func WithMethods(methods ...string) Option {
+	return func(g *Guard) error {
+		g.methods = make(map[string]struct{}, len(methods))
+		for _, m := range methods {
+			g.methods[m] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// WithKeyPrefix sets the prefix of the keys written to the store (default "idempotency:"), so
+// a Guard can share a backend with quota limiters.
+func WithKeyPrefix(prefix string) Option {
+	return func(g *Guard) error {
+		g.prefix = prefix
+		return nil
+	}
+}
+
+// WithFailClosed rejects requests when the store is unavailable. By default they are allowed and
+// the error is logged.
+func WithFailClosed() Option {
+	return func(g *Guard) error {
+		g.failClosed = true
+		return nil
+	}
+}
+
+// WithLogger sets the logger used to report store errors (defaults to log.Default()).
+func WithLogger(logger *log.Logger) Option {
+	return func(g *Guard) error {
+		if logger == nil {
+			return fmt.Errorf("logger cannot be nil")
+		}
+		g.logger = logger
+		return nil
+	}
+}
+
+// WithDenyTemplate renders the responses to replayed requests from t, with the reused key's kind
+// as the Denial's RuleID.
+func WithDenyTemplate(t *mcpdpluginsv1.DenyTemplate) Option {
+	return func(g *Guard) error {
+		g.denial = t
+		return nil
+	}
+}
+
+// New returns a Guard remembering keys in store for ttl.
+func New(store quota.Store, ttl time.Duration, opts ...Option) (*Guard, error) {
+	if store == nil {
+		return nil, fmt.Errorf("store is required")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("ttl must be positive")
+	}
+
+	g := &Guard{
+		store:   store,
+		ttl:     ttl,
+		header:  DefaultHeader,
+		scope:   tokens.SessionKey,
+		methods: map[string]struct{}{"tools/call": {}},
+		prefix:  "idempotency:",
+		logger:  log.Default(),
+	}
+	for _, opt := range opts {
+		if err := opt(g); err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+// Check records the keys of req and reports whether one of them was already seen within the TTL.
+// Requests without a session only have their idempotency key checked, since JSON-RPC ids are
+// only unique within a session; notifications have no id and are never replays. With a store
+// error, the Decision is Replay when the Guard fails closed.
+func (g *Guard) Check(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) (Decision, error) {
+	if g.header != "" {
+		if key := mcpdpluginsv1.GetHeader(req.GetHeaders(), g.header); key != "" {
+			if d, err := g.seen(ctx, KindIdempotencyKey, g.scope(req), key); err != nil || d.Replay {
+				return d, err
+			}
+		}
+	}
+
+	session := mcpdpluginsv1.GetHeader(req.GetHeaders(), tokens.SessionHeader)
+	if session == "" {
+		return Decision{}, nil
+	}
+	msgs, err := mcp.Parse(req.GetBody())
+	if err != nil {
+		return Decision{}, nil
+	}
+	for _, m := range msgs {
+		if !m.IsRequest() || !g.checked(m.Method) {
+			continue
+		}
+		if d, err := g.seen(ctx, KindJSONRPCID, session, string(bytes.TrimSpace(m.ID))); err != nil || d.Replay {
+			return d, err
+		}
+	}
+
+	return Decision{}, nil
+}
+
+// checked reports whether the ids of method's requests are checked.
+func (g *Guard) checked(method string) bool {
+	if len(g.methods) == 0 {
+		return true
+	}
+	_, ok := g.methods[method]
+
+	return ok
+}
+
+// seen records key in scope and reports whether it had been recorded already.
+func (g *Guard) seen(ctx context.Context, kind, scope, key string) (Decision, error) {
+	sum := sha256.Sum256([]byte(kind + "\x00" + scope + "\x00" + key))
+	u, err := g.store.Add(ctx, g.prefix+hex.EncodeToString(sum[:]), 1, g.ttl)
+	if err != nil {
+		return Decision{Replay: g.failClosed, Kind: kind, Key: key}, err
+	}
+
+	return Decision{Replay: u.Used > 1, Kind: kind, Key: key}, nil
+}
+
+// HandleRequest lets the chain continue for new requests and short-circuits replays with 409
+// Conflict and a JSON-RPC error.
+func (g *Guard) HandleRequest(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) *mcpdpluginsv1.HTTPResponse {
+	d, err := g.Check(ctx, req)
+	if err != nil {
+		g.logger.Printf("idempotency: store error: %v", err)
+	}
+	if !d.Replay {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+
+	denial := mcpdpluginsv1.Denial{
+		Reason: "duplicate request",
+		RuleID: d.Kind,
+		Status: http.StatusConflict,
+		Code:   mcp.CodeInvalidRequest,
+	}
+	if err != nil {
+		denial.Reason, denial.Status, denial.Code = "replay protection unavailable", http.StatusServiceUnavailable, 0
+	}
+
+	return g.denial.Deny(ctx, req, denial)
+}
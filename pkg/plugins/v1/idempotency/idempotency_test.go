@@ -0,0 +1,360 @@
+package idempotency_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/idempotency"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/plugintest"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/quota"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/tokens"
+)
+
+// failingStore is a quota.Store that is always unavailable.
+type failingStore struct{}
+
+func (failingStore) Add(context.Context, string, int64, time.Duration) (quota.Usage, error) {
+	return quota.Usage{}, errors.New("store unavailable")
+}
+
+// request returns a request in session with the given idempotency key and body; empty values
+// are left out.
+func request(session, key, body string) *mcpdpluginsv1.HTTPRequest {
+	headers := map[string]string{}
+	if session != "" {
+		headers[tokens.SessionHeader] = session
+	}
+	if key != "" {
+		headers[idempotency.DefaultHeader] = key
+	}
+
+	return &mcpdpluginsv1.HTTPRequest{Method: "POST", Headers: headers, Body: []byte(body), RemoteAddr: "10.0.0.1:5000"}
+}
+
+func call(id string) string {
+	return `{"jsonrpc":"2.0","id":` + id + `,"method":"tools/call","params":{"name":"search"}}`
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name  string
+		store quota.Store
+		ttl   time.Duration
+		opts  []idempotency.Option
+	}{
+		{name: "nil store", ttl: time.Minute},
+		{name: "zero ttl", store: quota.NewMemoryStore()},
+		{name: "negative ttl", store: quota.NewMemoryStore(), ttl: -time.Second},
+		{
+			name:  "nil scope",
+			store: quota.NewMemoryStore(),
+			ttl:   time.Minute,
+			opts:  []idempotency.Option{idempotency.WithScope(nil)},
+		},
+		{
+			name:  "nil logger",
+			store: quota.NewMemoryStore(),
+			ttl:   time.Minute,
+			opts:  []idempotency.Option{idempotency.WithLogger(nil)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := idempotency.New(tt.store, tt.ttl, tt.opts...); err == nil {
+				t.Error("New succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []idempotency.Option
+		reqs []*mcpdpluginsv1.HTTPRequest
+		want []idempotency.Decision // One per request.
+	}{
+		{
+			name: "idempotency key reused",
+			reqs: []*mcpdpluginsv1.HTTPRequest{request("", "k1", ""), request("", "k1", ""), request("", "k2", "")},
+			want: []idempotency.Decision{{}, {Replay: true, Kind: idempotency.KindIdempotencyKey, Key: "k1"}, {}},
+		},
+		{
+			name: "idempotency keys scoped by session",
+			reqs: []*mcpdpluginsv1.HTTPRequest{request("s1", "k1", ""), request("s2", "k1", "")},
+			want: []idempotency.Decision{{}, {}},
+		},
+		{
+			name: "custom scope",
+			opts: []idempotency.Option{idempotency.WithScope(func(*mcpdpluginsv1.HTTPRequest) string { return "all" })},
+			reqs: []*mcpdpluginsv1.HTTPRequest{request("s1", "k1", ""), request("s2", "k1", "")},
+			want: []idempotency.Decision{{}, {Replay: true, Kind: idempotency.KindIdempotencyKey, Key: "k1"}},
+		},
+		{
+			name: "custom header",
+			opts: []idempotency.Option{idempotency.WithHeader("X-Request-Key")},
+			reqs: []*mcpdpluginsv1.HTTPRequest{
+				{Headers: map[string]string{"x-request-key": "a"}},
+				{Headers: map[string]string{"X-Request-Key": "a"}},
+				request("", "a", ""),
+				request("", "a", ""),
+			},
+			want: []idempotency.Decision{{}, {Replay: true, Kind: idempotency.KindIdempotencyKey, Key: "a"}, {}, {}},
+		},
+		{
+			name: "idempotency keys disabled",
+			opts: []idempotency.Option{idempotency.WithHeader("")},
+			reqs: []*mcpdpluginsv1.HTTPRequest{request("", "k1", ""), request("", "k1", "")},
+			want: []idempotency.Decision{{}, {}},
+		},
+		{
+			name: "JSON-RPC id reused in a session",
+			reqs: []*mcpdpluginsv1.HTTPRequest{request("s1", "", call("7")), request("s1", "", call(" 7"))},
+			want: []idempotency.Decision{{}, {Replay: true, Kind: idempotency.KindJSONRPCID, Key: "7"}},
+		},
+		{
+			name: "JSON-RPC ids scoped by session",
+			reqs: []*mcpdpluginsv1.HTTPRequest{request("s1", "", call("7")), request("s2", "", call("7"))},
+			want: []idempotency.Decision{{}, {}},
+		},
+		{
+			name: "number and string ids differ",
+			reqs: []*mcpdpluginsv1.HTTPRequest{request("s1", "", call("7")), request("s1", "", call(`"7"`))},
+			want: []idempotency.Decision{{}, {}},
+		},
+		{
+			name: "no session",
+			reqs: []*mcpdpluginsv1.HTTPRequest{request("", "", call("7")), request("", "", call("7"))},
+			want: []idempotency.Decision{{}, {}},
+		},
+		{
+			name: "other methods not checked",
+			reqs: []*mcpdpluginsv1.HTTPRequest{
+				request("s1", "", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`),
+				request("s1", "", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`),
+			},
+			want: []idempotency.Decision{{}, {}},
+		},
+		{
+			name: "every method checked",
+			opts: []idempotency.Option{idempotency.WithMethods()},
+			reqs: []*mcpdpluginsv1.HTTPRequest{
+				request("s1", "", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`),
+				request("s1", "", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`),
+			},
+			want: []idempotency.Decision{{}, {Replay: true, Kind: idempotency.KindJSONRPCID, Key: "1"}},
+		},
+		{
+			name: "notifications never replays",
+			reqs: []*mcpdpluginsv1.HTTPRequest{
+				request("s1", "", `{"jsonrpc":"2.0","method":"tools/call"}`),
+				request("s1", "", `{"jsonrpc":"2.0","method":"tools/call"}`),
+			},
+			want: []idempotency.Decision{{}, {}},
+		},
+		{
+			name: "replay within a batch",
+			reqs: []*mcpdpluginsv1.HTTPRequest{
+				request("s1", "", "["+call("1")+","+call("2")+"]"),
+				request("s1", "", "["+call("3")+","+call("2")+"]"),
+			},
+			want: []idempotency.Decision{{}, {Replay: true, Kind: idempotency.KindJSONRPCID, Key: "2"}},
+		},
+		{
+			name: "non-JSON-RPC body",
+			reqs: []*mcpdpluginsv1.HTTPRequest{request("s1", "", "hello"), request("s1", "", "hello")},
+			want: []idempotency.Decision{{}, {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := idempotency.New(quota.NewMemoryStore(), time.Minute, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, req := range tt.reqs {
+				got, err := g.Check(context.Background(), req)
+				if err != nil {
+					t.Fatalf("request %d: Check: %v", i, err)
+				}
+				if got != tt.want[i] {
+					t.Errorf("request %d: Check = %+v, want %+v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckTTL(t *testing.T) {
+	clk := plugintest.NewFakeClock(time.Time{})
+	g, err := idempotency.New(quota.NewMemoryStore(quota.WithStoreClock(clk)), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		advance time.Duration
+		want    bool
+	}{
+		{0, false},
+		{59 * time.Second, true},
+		{time.Second, false},
+		{30 * time.Second, true},
+	}
+	for i, s := range steps {
+		clk.Advance(s.advance)
+		d, err := g.Check(context.Background(), request("", "k1", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Replay != s.want {
+			t.Errorf("step %d: Replay = %t, want %t", i, d.Replay, s.want)
+		}
+	}
+}
+
+func TestKeyPrefix(t *testing.T) {
+	store := quota.NewMemoryStore()
+	a, err := idempotency.New(store, time.Minute, idempotency.WithKeyPrefix("a:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := idempotency.New(store, time.Minute, idempotency.WithKeyPrefix("b:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d, _ := a.Check(context.Background(), request("", "k1", "")); d.Replay {
+		t.Fatal("first request is a replay")
+	}
+	if d, _ := b.Check(context.Background(), request("", "k1", "")); d.Replay {
+		t.Error("guards with different prefixes share keys")
+	}
+	if d, _ := a.Check(context.Background(), request("", "k1", "")); !d.Replay {
+		t.Error("replay not detected by the same guard")
+	}
+}
+
+// denyMessage decodes the JSON-RPC error of a deny response.
+func denyMessage(t *testing.T, resp *mcpdpluginsv1.HTTPResponse) mcp.Message {
+	t.Helper()
+
+	var m mcp.Message
+	if err := json.Unmarshal(resp.GetBody(), &m); err != nil || m.Error == nil {
+		t.Fatalf("deny body %q is not a JSON-RPC error: %v", resp.GetBody(), err)
+	}
+
+	return m
+}
+
+func TestHandleRequest(t *testing.T) {
+	tmpl, err := mcpdpluginsv1.NewDenyTemplate(mcpdpluginsv1.DenyFormatText, "replay of {{.RuleID}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		store       quota.Store
+		opts        []idempotency.Option
+		wantStatus  int32 // 0 when the request continues.
+		wantCode    int
+		wantMessage string
+		wantLog     bool
+	}{
+		{
+			name:        "replay",
+			store:       quota.NewMemoryStore(),
+			wantStatus:  http.StatusConflict,
+			wantCode:    mcp.CodeInvalidRequest,
+			wantMessage: "duplicate request",
+		},
+		{
+			name:        "replay with a template",
+			store:       quota.NewMemoryStore(),
+			opts:        []idempotency.Option{idempotency.WithDenyTemplate(tmpl)},
+			wantStatus:  http.StatusConflict,
+			wantCode:    mcp.CodeInvalidRequest,
+			wantMessage: "replay of idempotency_key",
+		},
+		{name: "store down fails open", store: failingStore{}, wantLog: true},
+		{
+			name:        "store down fails closed",
+			store:       failingStore{},
+			opts:        []idempotency.Option{idempotency.WithFailClosed()},
+			wantStatus:  http.StatusServiceUnavailable,
+			wantCode:    mcp.CodeServerError,
+			wantMessage: "replay protection unavailable",
+			wantLog:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			opts := append([]idempotency.Option{idempotency.WithLogger(log.New(&logs, "", 0))}, tt.opts...)
+			g, err := idempotency.New(tt.store, time.Minute, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := request("s1", "k1", call("1"))
+
+			if _, ok := tt.store.(*quota.MemoryStore); ok {
+				if resp := g.HandleRequest(context.Background(), req); !resp.GetContinue() {
+					t.Fatalf("first request was denied: %s", resp.GetBody())
+				}
+			}
+			resp := g.HandleRequest(context.Background(), req)
+			if tt.wantStatus == 0 {
+				if !resp.GetContinue() {
+					t.Errorf("request denied with %d, want it to continue", resp.GetStatusCode())
+				}
+			} else {
+				if resp.GetContinue() || resp.GetStatusCode() != tt.wantStatus {
+					t.Fatalf("response = %d continue=%t, want %d",
+						resp.GetStatusCode(), resp.GetContinue(), tt.wantStatus)
+				}
+				m := denyMessage(t, resp)
+				if string(m.ID) != "1" || m.Error.Code != tt.wantCode || m.Error.Message != tt.wantMessage {
+					t.Errorf("deny body = %s", resp.GetBody())
+				}
+			}
+			logged := strings.Contains(logs.String(), "idempotency: store error: store unavailable")
+			if logged != tt.wantLog {
+				t.Errorf("logged %q, want store error logged %t", logs.String(), tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestCheckStoreError(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []idempotency.Option
+		wantReplay bool
+	}{
+		{name: "fails open"},
+		{name: "fails closed", opts: []idempotency.Option{idempotency.WithFailClosed()}, wantReplay: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := idempotency.New(failingStore{}, time.Minute, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err := g.Check(context.Background(), request("s1", "", call("1")))
+			if err == nil {
+				t.Fatal("Check returned no store error")
+			}
+			if d.Replay != tt.wantReplay || d.Kind != idempotency.KindJSONRPCID {
+				t.Errorf("Check = %+v, want Replay %t for the JSON-RPC id", d, tt.wantReplay)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/pending"
+)
+
+// Identity sources set on Principal.Source by the SDK's providers. Providers in other packages,
+// such as the identity package, define their own.
+const (
+	IdentitySourceHeader  = "header"
+	IdentitySourceSession = "session"
+	IdentitySourceClient  = "client"
+)
+
+// Principal is the normalized identity of the caller behind a request. Policy, quota and audit
+// components should key on ID so they agree no matter which provider identified the caller.
+type Principal struct {
+	// ID is "<source>:<subject>", or "<source>:<issuer>#<subject>" when the issuer is known.
+	ID string `json:"id"`
+
+	// Source names the provider that identified the caller, e.g. "jwt" or "session".
+	Source string `json:"source"`
+
+	// Subject identifies the caller within its source and issuer.
+	Subject string `json:"subject"`
+
+	// Issuer is the authority that vouched for the subject, if any (JWT issuer, certificate issuer).
+	Issuer string `json:"issuer,omitempty"`
+
+	// Groups lists the groups or roles the caller belongs to, if the source provides them.
+	Groups []string `json:"groups,omitempty"`
+
+	// Attributes holds further source-specific details, such as JWT claims or certificate fields.
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// NewPrincipal returns a Principal for subject from source and issuer, with its ID set.
+func NewPrincipal(source, issuer, subject string) *Principal {
+	id := source + ":" + subject
+	if issuer != "" {
+		id = source + ":" + issuer + "#" + subject
+	}
+
+	return &Principal{ID: id, Source: source, Subject: subject, Issuer: issuer}
+}
+
+// InGroup reports whether p is a member of group. It is false for a nil principal.
+func (p *Principal) InGroup(group string) bool {
+	if p == nil {
+		return false
+	}
+	for _, g := range p.Groups {
+		if g == group {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IdentityProvider identifies the caller of a request. Identify returns nil and no error when the
+// request carries no credentials the provider understands, and an error when it carries
+// credentials that are invalid (a bad signature, an expired token...).
+type IdentityProvider interface {
+	Identify(ctx context.Context, req *HTTPRequest) (*Principal, error)
+}
+
+// IdentityProviderFunc adapts a function to the IdentityProvider interface.
+type IdentityProviderFunc func(ctx context.Context, req *HTTPRequest) (*Principal, error)
+
+// Identify calls f(ctx, req).
+func (f IdentityProviderFunc) Identify(ctx context.Context, req *HTTPRequest) (*Principal, error) {
+	return f(ctx, req)
+}
+
+// IdentityFromHeader identifies the caller by the value of the named header, which must be set
+// by a trusted proxy in front of mcpd.
+func IdentityFromHeader(name string) IdentityProvider {
+	return IdentityProviderFunc(func(_ context.Context, req *HTTPRequest) (*Principal, error) {
+		v := GetHeader(req.GetHeaders(), name)
+		if v == "" {
+			return nil, nil
+		}
+		return NewPrincipal(IdentitySourceHeader, "", v), nil
+	})
+}
+
+// IdentityFromSession identifies the caller by its MCP session ID (the Mcp-Session-Id header).
+func IdentityFromSession() IdentityProvider {
+	return IdentityProviderFunc(func(_ context.Context, req *HTTPRequest) (*Principal, error) {
+		id := GetHeader(req.GetHeaders(), "Mcp-Session-Id")
+		if id == "" {
+			return nil, nil
+		}
+		return NewPrincipal(IdentitySourceSession, "", id), nil
+	})
+}
+
+// IdentityFromClient identifies the caller by the host of the request's remote address.
+func IdentityFromClient() IdentityProvider {
+	return IdentityProviderFunc(func(_ context.Context, req *HTTPRequest) (*Principal, error) {
+		host, _, err := net.SplitHostPort(req.GetRemoteAddr())
+		if err != nil {
+			host = req.GetRemoteAddr()
+		}
+		if host == "" {
+			return nil, nil
+		}
+		return NewPrincipal(IdentitySourceClient, "", host), nil
+	})
+}
+
+// FirstIdentity tries providers in order and returns the first principal found. An error stops
+// the search, so invalid credentials never fall back to a weaker identity.
+func FirstIdentity(providers ...IdentityProvider) IdentityProvider {
+	return IdentityProviderFunc(func(ctx context.Context, req *HTTPRequest) (*Principal, error) {
+		for _, p := range providers {
+			if p == nil {
+				continue
+			}
+			if principal, err := p.Identify(ctx, req); err != nil || principal != nil {
+				return principal, err
+			}
+		}
+		return nil, nil
+	})
+}
+
+// WithIdentity makes Serve identify the caller of every HandleRequest call with the providers,
+// tried in order as with FirstIdentity, and expose the principal to handlers through Identity.
+// The principal is carried over to the HandleResponse call with the same CorrelationID.
+func WithIdentity(providers ...IdentityProvider) ServeOption {
+	return func(o *serveOptions) error {
+		if len(providers) == 0 {
+			return fmt.Errorf("at least one identity provider is required")
+		}
+		for _, p := range providers {
+			if p == nil {
+				return fmt.Errorf("identity provider cannot be nil")
+			}
+		}
+		o.interceptors = append(o.interceptors, identityInterceptor(FirstIdentity(providers...)))
+		return nil
+	}
+}
+
+type identityKey struct{}
+
+// identityResult is what the identity interceptor stores in a call's context.
+type identityResult struct {
+	principal *Principal
+	err       error
+}
+
+// Identity returns the principal identified for the current call, or nil when the caller is
+// anonymous, along with the error of the provider that rejected the caller's credentials.
+func Identity(ctx context.Context) (*Principal, error) {
+	r, _ := ctx.Value(identityKey{}).(identityResult)
+	return r.principal, r.err
+}
+
+// PrincipalID returns the ID of the principal identified for the current call, or an empty string.
+func PrincipalID(ctx context.Context) string {
+	if p, _ := Identity(ctx); p != nil {
+		return p.ID
+	}
+
+	return ""
+}
+
+// ContextWithPrincipal returns a copy of ctx carrying p, for use in tests and custom dispatch.
+func ContextWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, identityKey{}, identityResult{principal: p})
+}
+
+// ContextWithIdentityError returns a copy of ctx carrying err as the error of an identity provider
+// that rejected the caller's credentials, as ContextWithPrincipal does for an identified caller.
+func ContextWithIdentityError(ctx context.Context, err error) context.Context {
+	return context.WithValue(ctx, identityKey{}, identityResult{err: err})
+}
+
+// identityTTL bounds how long a request's principal is remembered while waiting for its response.
+const identityTTL = time.Minute
+
+func identityInterceptor(provider IdentityProvider) grpc.UnaryServerInterceptor {
+	principals := pending.New[*Principal](identityTTL)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		switch in := req.(type) {
+		case *HTTPRequest:
+			p, err := provider.Identify(ctx, in)
+			if p != nil {
+				principals.Put(CorrelationID(ctx, in), p, time.Now())
+			}
+			ctx = context.WithValue(ctx, identityKey{}, identityResult{principal: p, err: err})
+		case *HTTPResponse:
+			if p, ok := principals.Take(CorrelationID(ctx, nil), time.Now()); ok {
+				ctx = ContextWithPrincipal(ctx, p)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
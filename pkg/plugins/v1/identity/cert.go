@@ -0,0 +1,157 @@
+package identity
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"strings"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// ForwardedClientCertHeader is the header Envoy and compatible proxies use to forward the client
+// certificate of an mTLS connection they terminated.
+const ForwardedClientCertHeader = "X-Forwarded-Client-Cert"
+
+// ClientCertificate identifies callers by the client certificate a TLS-terminating proxy in front
+// of mcpd forwards in the named header (ForwardedClientCertHeader when empty). The header may hold
+// either the Envoy format (Hash=...;Subject="...";URI=...) or a URL-encoded PEM certificate, as
+// sent by nginx's $ssl_client_escaped_cert.
+//
+// The subject is the certificate's first URI SAN (such as a SPIFFE ID), falling back to its
+// subject DN. The proxy must strip the header from client requests, or anyone can claim any
+// identity.
+func ClientCertificate(header string) mcpdpluginsv1.IdentityProvider {
+	if header == "" {
+		header = ForwardedClientCertHeader
+	}
+
+	return mcpdpluginsv1.IdentityProviderFunc(
+		func(_ context.Context, req *mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.Principal, error) {
+			v := mcpdpluginsv1.GetHeader(req.GetHeaders(), header)
+			if v == "" {
+				return nil, nil
+			}
+
+			if pemText, err := url.QueryUnescape(v); err == nil && strings.HasPrefix(pemText, "-----BEGIN") {
+				return certificatePrincipal(pemText, nil)
+			}
+
+			fields := parseForwardedClientCert(v)
+			if c := fields["cert"]; c != "" {
+				pemText, err := url.QueryUnescape(c)
+				if err != nil {
+					return nil, fmt.Errorf("%w: forwarded certificate: %v", ErrInvalidCredentials, err)
+				}
+				return certificatePrincipal(pemText, fields)
+			}
+
+			sub := fields["uri"]
+			if sub == "" {
+				sub = fields["subject"]
+			}
+			if sub == "" {
+				return nil, fmt.Errorf("%w: forwarded certificate has no URI or Subject", ErrInvalidCredentials)
+			}
+			p := mcpdpluginsv1.NewPrincipal(SourceCertificate, "", sub)
+			p.Attributes = attributes(fields)
+
+			return p, nil
+		},
+	)
+}
+
+// certificatePrincipal builds the principal of a PEM-encoded certificate, adding the forwarded
+// fields, if any, to its attributes.
+func certificatePrincipal(pemText string, fields map[string]string) (*mcpdpluginsv1.Principal, error) {
+	block, _ := pem.Decode([]byte(pemText))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%w: forwarded certificate is not a PEM certificate", ErrInvalidCredentials)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: forwarded certificate: %v", ErrInvalidCredentials, err)
+	}
+
+	sub := cert.Subject.String()
+	if len(cert.URIs) > 0 {
+		sub = cert.URIs[0].String()
+	}
+
+	p := mcpdpluginsv1.NewPrincipal(SourceCertificate, cert.Issuer.String(), sub)
+	p.Groups = cert.Subject.OrganizationalUnit
+	p.Attributes = attributes(fields)
+	p.Attributes["subject"] = cert.Subject.String()
+	p.Attributes["serial"] = cert.SerialNumber.String()
+	p.Attributes["notAfter"] = cert.NotAfter
+	if len(cert.DNSNames) > 0 {
+		p.Attributes["dns"] = cert.DNSNames
+	}
+	if len(cert.EmailAddresses) > 0 {
+		p.Attributes["email"] = cert.EmailAddresses
+	}
+
+	return p, nil
+}
+
+// attributes copies forwarded fields into a principal's attributes, leaving out the certificate.
+func attributes(fields map[string]string) map[string]any {
+	attrs := make(map[string]any, len(fields))
+	for k, v := range fields {
+		if k != "cert" && k != "chain" {
+			attrs[k] = v
+		}
+	}
+
+	return attrs
+}
+
+// parseForwardedClientCert parses the first element of an Envoy x-forwarded-client-cert value,
+// the one describing the original client, into lower-cased keys and unquoted values.
+func parseForwardedClientCert(v string) map[string]string {
+	fields := map[string]string{}
+
+	var key, val strings.Builder
+	inKey, quoted, escaped := true, false, false
+	flush := func() {
+		if k := strings.ToLower(strings.TrimSpace(key.String())); k != "" {
+			// Repeated keys (several DNS SANs) keep the first value.
+			if _, ok := fields[k]; !ok {
+				fields[k] = val.String()
+			}
+		}
+		key.Reset()
+		val.Reset()
+		inKey = true
+	}
+
+	for _, r := range v {
+		switch {
+		case escaped:
+			val.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"' && !inKey:
+			quoted = !quoted
+		case quoted:
+			val.WriteRune(r)
+		case r == ',':
+			flush()
+			return fields
+		case r == ';':
+			flush()
+		case r == '=' && inKey:
+			inKey = false
+		case inKey:
+			key.WriteRune(r)
+		default:
+			val.WriteRune(r)
+		}
+	}
+	flush()
+
+	return fields
+}
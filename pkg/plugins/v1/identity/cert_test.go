@@ -0,0 +1,186 @@
+package identity_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/identity"
+)
+
+// clientCert returns a PEM-encoded self-signed client certificate, with uri as its URI SAN when
+// not empty.
+func clientCert(t *testing.T, uri string) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:   big.NewInt(42),
+		Subject:        pkix.Name{CommonName: "alice", OrganizationalUnit: []string{"dev", "ops"}},
+		NotBefore:      time.Unix(1_700_000_000, 0),
+		NotAfter:       time.Unix(1_800_000_000, 0),
+		DNSNames:       []string{"alice.example.com"},
+		EmailAddresses: []string{"alice@example.com"},
+	}
+	if uri != "" {
+		u, err := url.Parse(uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tmpl.URIs = []*url.URL{u}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestClientCertificate(t *testing.T) {
+	spiffe := "spiffe://example.org/alice"
+	withURI := url.QueryEscape(clientCert(t, spiffe))
+	withoutURI := url.QueryEscape(clientCert(t, ""))
+
+	tests := []struct {
+		name      string
+		header    string
+		value     string
+		want      *mcpdpluginsv1.Principal
+		wantAttrs map[string]any
+		wantErr   string
+	}{
+		{
+			name:  "escaped PEM with a URI SAN",
+			value: withURI,
+			want: &mcpdpluginsv1.Principal{
+				ID: "cert:CN=alice,OU=dev+OU=ops#" + spiffe, Source: "cert", Subject: spiffe,
+				Issuer: "CN=alice,OU=dev+OU=ops", Groups: []string{"dev", "ops"},
+			},
+			wantAttrs: map[string]any{
+				"subject": "CN=alice,OU=dev+OU=ops",
+				"serial":  "42",
+				"dns":     []string{"alice.example.com"},
+				"email":   []string{"alice@example.com"},
+			},
+		},
+		{
+			name:  "escaped PEM without a URI SAN",
+			value: withoutURI,
+			want: &mcpdpluginsv1.Principal{
+				ID: "cert:CN=alice,OU=dev+OU=ops#CN=alice,OU=dev+OU=ops", Source: "cert",
+				Subject: "CN=alice,OU=dev+OU=ops", Issuer: "CN=alice,OU=dev+OU=ops", Groups: []string{"dev", "ops"},
+			},
+		},
+		{
+			name:  "Envoy fields",
+			value: `Hash=abc;Subject="CN=alice,OU=dev";URI=` + spiffe + `;DNS=a.example.com;DNS=b.example.com`,
+			want:  &mcpdpluginsv1.Principal{ID: "cert:" + spiffe, Source: "cert", Subject: spiffe},
+			wantAttrs: map[string]any{
+				"hash":    "abc",
+				"subject": "CN=alice,OU=dev",
+				"uri":     spiffe,
+				"dns":     "a.example.com",
+			},
+		},
+		{
+			name:      "Envoy subject without a URI",
+			value:     `Hash=abc;Subject="CN=bob,O=Example \"Inc\""`,
+			want:      mcpdpluginsv1.NewPrincipal("cert", "", `CN=bob,O=Example "Inc"`),
+			wantAttrs: map[string]any{"hash": "abc", "subject": `CN=bob,O=Example "Inc"`},
+		},
+		{
+			name:  "Envoy proxies chain",
+			value: `Hash=abc;URI=` + spiffe + `,By=spiffe://example.org/proxy;URI=spiffe://example.org/proxy`,
+			want:  &mcpdpluginsv1.Principal{ID: "cert:" + spiffe, Source: "cert", Subject: spiffe},
+		},
+		{
+			name:  "Envoy Cert field",
+			value: `Hash=abc;Cert="` + withURI + `"`,
+			want: &mcpdpluginsv1.Principal{
+				ID: "cert:CN=alice,OU=dev+OU=ops#" + spiffe, Source: "cert", Subject: spiffe,
+				Issuer: "CN=alice,OU=dev+OU=ops", Groups: []string{"dev", "ops"},
+			},
+			wantAttrs: map[string]any{"hash": "abc", "subject": "CN=alice,OU=dev+OU=ops"},
+		},
+		{
+			name:   "custom header",
+			header: "X-Client-Cert",
+			value:  withoutURI,
+			want: &mcpdpluginsv1.Principal{
+				ID: "cert:CN=alice,OU=dev+OU=ops#CN=alice,OU=dev+OU=ops", Source: "cert",
+				Subject: "CN=alice,OU=dev+OU=ops", Issuer: "CN=alice,OU=dev+OU=ops", Groups: []string{"dev", "ops"},
+			},
+		},
+		{name: "no header"},
+		{name: "Envoy without a subject", value: "Hash=abc", wantErr: "forwarded certificate has no URI or Subject"},
+		{
+			name:    "not a certificate",
+			value:   url.QueryEscape("-----BEGIN KEY-----\nAAAA\n-----END KEY-----\n"),
+			wantErr: "not a PEM certificate",
+		},
+		{
+			name:    "corrupt certificate",
+			value:   url.QueryEscape("-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n"),
+			wantErr: "forwarded certificate:",
+		},
+		{name: "badly escaped Cert field", value: `Cert="%zz"`, wantErr: "forwarded certificate:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == "" {
+				header = identity.ForwardedClientCertHeader
+			}
+			req := &mcpdpluginsv1.HTTPRequest{Headers: map[string]string{}}
+			if tt.value != "" {
+				req.Headers[strings.ToLower(header)] = tt.value
+			}
+
+			p, err := identity.ClientCertificate(tt.header).Identify(context.Background(), req)
+			if tt.wantErr != "" {
+				if !errors.Is(err, identity.ErrInvalidCredentials) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Identify error = %v, want invalid credentials with %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if p != nil {
+					t.Errorf("Identify = %+v, want anonymous", p)
+				}
+				return
+			}
+			got := *p
+			got.Attributes = nil
+			if !reflect.DeepEqual(&got, tt.want) {
+				t.Errorf("Identify = %+v, want %+v", &got, tt.want)
+			}
+			for k, v := range tt.wantAttrs {
+				if !reflect.DeepEqual(p.Attributes[k], v) {
+					t.Errorf("attribute %s = %v, want %v", k, p.Attributes[k], v)
+				}
+			}
+			if _, ok := p.Attributes["cert"]; ok {
+				t.Error("attributes hold the forwarded certificate")
+			}
+		})
+	}
+}
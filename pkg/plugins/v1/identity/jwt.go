@@ -0,0 +1,401 @@
+// Package identity provides mcpdpluginsv1.IdentityProvider implementations for credentials that
+// need more than a header lookup: JSON Web Tokens and client certificates forwarded by a
+// TLS-terminating proxy.
+//
+// Providers are combined with the SDK's own and installed with WithIdentity, after which handlers
+// read the normalized principal with mcpdpluginsv1.Identity:
+//
+//	jwt, err := identity.NewJWT(
+//	    identity.WithPublicKey("", key),
+//	    identity.WithIssuer("https://idp.example.com"),
+//	    identity.WithAudience("mcpd"),
+//	)
+//	...
+//	err = mcpdpluginsv1.Serve(plugin, mcpdpluginsv1.WithIdentity(
+//	    jwt,
+//	    identity.ClientCertificate(""),
+//	    mcpdpluginsv1.IdentityFromSession(),
+//	))
+package identity
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // Registers SHA-256 for the *256 algorithms.
+	_ "crypto/sha512" // Registers SHA-384 and SHA-512 for the *384 and *512 algorithms.
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+)
+
+// Identity sources set on mcpdpluginsv1.Principal.Source by this package's providers.
+const (
+	SourceJWT         = "jwt"
+	SourceCertificate = "cert"
+)
+
+// algorithmHashes maps the digest suffix of a JWS algorithm name to its hash.
+var algorithmHashes = map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}
+
+// ErrInvalidCredentials is wrapped by every error returned for credentials that are present but
+// cannot be trusted.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// JWT identifies callers by a JSON Web Token, by default a bearer token in the Authorization
+// header. It is safe for concurrent use.
+type JWT struct {
+	header       string
+	secret       []byte
+	keys         map[string]crypto.PublicKey
+	unverified   bool
+	issuers      []string
+	audience     string
+	leeway       time.Duration
+	subjectClaim string
+	groupsClaim  string
+	clock        clock.Clock
+}
+
+// JWTOption configures a JWT provider.
+type JWTOption func(*JWT) error
+
+// WithHMACSecret accepts tokens signed with HS256, HS384 or HS512 and secret.
+func WithHMACSecret(secret []byte) JWTOption {
+	return func(j *JWT) error {
+		if len(secret) == 0 {
+			return fmt.Errorf("HMAC secret cannot be empty")
+		}
+		j.secret = secret
+		return nil
+	}
+}
+
+// WithPublicKey accepts tokens whose kid header is kid and that are signed with key, an
+// *rsa.PublicKey (RS and PS algorithms), *ecdsa.PublicKey (ES) or ed25519.PublicKey (EdDSA).
+// The key registered with an empty kid verifies tokens whose kid matches no other key.
+func WithPublicKey(kid string, key crypto.PublicKey) JWTOption {
+	return func(j *JWT) error {
+		switch key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		default:
+			return fmt.Errorf("unsupported public key type %T", key)
+		}
+		if j.keys == nil {
+			j.keys = map[string]crypto.PublicKey{}
+		}
+		j.keys[kid] = key
+		return nil
+	}
+}
+
+// WithoutVerification accepts tokens without checking their signature. Only use it when a
+// component in front of the plugin, such as mcpd itself, has already verified them.
+func WithoutVerification() JWTOption {
+	return func(j *JWT) error {
+		j.unverified = true
+		return nil
+	}
+}
+
+// WithHeader reads the token from the named header instead of Authorization. A "Bearer " prefix is
+// stripped wherever the token is read from.
+func WithHeader(name string) JWTOption {
+	return func(j *JWT) error {
+		if name == "" {
+			return fmt.Errorf("header name cannot be empty")
+		}
+		j.header = name
+		return nil
+	}
+}
+
+// WithIssuer only accepts tokens whose iss claim is one of issuers.
+func WithIssuer(issuers ...string) JWTOption {
+	return func(j *JWT) error {
+		j.issuers = append(j.issuers, issuers...)
+		return nil
+	}
+}
+
+// WithAudience only accepts tokens whose aud claim contains audience.
+func WithAudience(audience string) JWTOption {
+	return func(j *JWT) error {
+		j.audience = audience
+		return nil
+	}
+}
+
+// WithLeeway tolerates clock skew of up to d when checking the exp and nbf claims (default 1m).
+func WithLeeway(d time.Duration) JWTOption {
+	return func(j *JWT) error {
+		if d < 0 {
+			return fmt.Errorf("leeway cannot be negative")
+		}
+		j.leeway = d
+		return nil
+	}
+}
+
+// WithClock sets the clock the exp and nbf claims are checked against (defaults to clock.Real()).
+func WithClock(c clock.Clock) JWTOption {
+	return func(j *JWT) error {
+		if c == nil {
+			return fmt.Errorf("clock cannot be nil")
+		}
+		j.clock = c
+		return nil
+	}
+}
+
+// WithSubjectClaim sets the claim holding the principal's subject (default "sub").
+func WithSubjectClaim(claim string) JWTOption {
+	return func(j *JWT) error {
+		if claim == "" {
+			return fmt.Errorf("subject claim cannot be empty")
+		}
+		j.subjectClaim = claim
+		return nil
+	}
+}
+
+// WithGroupsClaim sets the claim holding the principal's groups (default "groups"). The claim may
+// be an array of strings or a space-separated string, as with the scope claim.
+func WithGroupsClaim(claim string) JWTOption {
+	return func(j *JWT) error {
+		j.groupsClaim = claim
+		return nil
+	}
+}
+
+// NewJWT returns a JWT provider. At least one key, or WithoutVerification, is required.
+func NewJWT(opts ...JWTOption) (*JWT, error) {
+	j := &JWT{
+		header:       "Authorization",
+		leeway:       time.Minute,
+		subjectClaim: "sub",
+		groupsClaim:  "groups",
+		clock:        clock.Real(),
+	}
+	for _, opt := range opts {
+		if err := opt(j); err != nil {
+			return nil, err
+		}
+	}
+	if j.secret == nil && len(j.keys) == 0 && !j.unverified {
+		return nil, fmt.Errorf("a verification key or WithoutVerification is required")
+	}
+
+	return j, nil
+}
+
+// Identify implements mcpdpluginsv1.IdentityProvider. Requests without a token, including those
+// authenticating with another scheme than Bearer, are anonymous;
+// tokens that fail verification or claim checks are rejected with ErrInvalidCredentials.
+func (j *JWT) Identify(_ context.Context, req *mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.Principal, error) {
+	// Tokens hold no spaces, so a value with one is a scheme and its credentials: only Bearer
+	// carries a token, and others (Basic...) are left to other providers.
+	token := strings.TrimSpace(mcpdpluginsv1.GetHeader(req.GetHeaders(), j.header))
+	if scheme, rest, ok := strings.Cut(token, " "); ok {
+		token = ""
+		if strings.EqualFold(scheme, "Bearer") {
+			token = strings.TrimSpace(rest)
+		}
+	} else if strings.EqualFold(token, "Bearer") {
+		token = ""
+	}
+	if token == "" {
+		return nil, nil
+	}
+
+	claims, err := j.Parse(token)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, _ := claims[j.subjectClaim].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("%w: token has no %s claim", ErrInvalidCredentials, j.subjectClaim)
+	}
+	iss, _ := claims["iss"].(string)
+
+	p := mcpdpluginsv1.NewPrincipal(SourceJWT, iss, sub)
+	p.Groups = stringList(claims[j.groupsClaim])
+	p.Attributes = claims
+
+	return p, nil
+}
+
+// Parse verifies token and its registered claims and returns its claims.
+func (j *JWT) Parse(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidCredentials)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidCredentials, err)
+	}
+	if !j.unverified {
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("%w: signature: %v", ErrInvalidCredentials, err)
+		}
+		if err := j.verify(header.Alg, header.Kid, parts[0]+"."+parts[1], sig); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+		}
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidCredentials, err)
+	}
+	if err := j.checkClaims(claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	return claims, nil
+}
+
+// verify checks sig over input with the key for alg and kid. Keys are only used with the
+// algorithm family they belong to, so an HMAC token can never be verified with a public key.
+func (j *JWT) verify(alg, kid, input string, sig []byte) error {
+	if alg == "EdDSA" {
+		key, ok := j.key(kid).(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(key, []byte(input), sig) {
+			return fmt.Errorf("signature verification failed")
+		}
+		return nil
+	}
+
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	h, ok := algorithmHashes[alg[2:]]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	digest := h.New()
+	digest.Write([]byte(input))
+	sum := digest.Sum(nil)
+
+	var valid bool
+	switch key := j.key(kid); alg[:2] {
+	case "HS":
+		if j.secret != nil {
+			mac := hmac.New(h.New, j.secret)
+			mac.Write([]byte(input))
+			valid = hmac.Equal(mac.Sum(nil), sig)
+		}
+	case "RS":
+		if key, ok := key.(*rsa.PublicKey); ok {
+			valid = rsa.VerifyPKCS1v15(key, h, sum, sig) == nil
+		}
+	case "PS":
+		if key, ok := key.(*rsa.PublicKey); ok {
+			valid = rsa.VerifyPSS(key, h, sum, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+	case "ES":
+		if key, ok := key.(*ecdsa.PublicKey); ok {
+			size := (key.Curve.Params().BitSize + 7) / 8
+			if len(sig) == 2*size {
+				r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+				valid = ecdsa.Verify(key, sum, r, s)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if !valid {
+		return fmt.Errorf("signature verification failed")
+	}
+
+	return nil
+}
+
+// key returns the public key registered for kid, falling back to the key without a kid.
+func (j *JWT) key(kid string) crypto.PublicKey {
+	if k, ok := j.keys[kid]; ok {
+		return k
+	}
+
+	return j.keys[""]
+}
+
+// checkClaims validates the exp, nbf, iss and aud claims.
+func (j *JWT) checkClaims(claims map[string]any) error {
+	now := j.clock.Now()
+	if exp, ok := claims["exp"].(float64); ok && now.After(unixTime(exp).Add(j.leeway)) {
+		return fmt.Errorf("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(j.leeway).Before(unixTime(nbf)) {
+		return fmt.Errorf("token not valid yet")
+	}
+	if len(j.issuers) > 0 {
+		if iss, _ := claims["iss"].(string); !slices.Contains(j.issuers, iss) {
+			return fmt.Errorf("untrusted issuer %q", iss)
+		}
+	}
+	if j.audience != "" {
+		if !hasAudience(claims["aud"], j.audience) {
+			return fmt.Errorf("token is not intended for %q", j.audience)
+		}
+	}
+
+	return nil
+}
+
+// hasAudience reports whether an aud claim, a single string or an array, contains audience.
+func hasAudience(aud any, audience string) bool {
+	if s, ok := aud.(string); ok {
+		return s == audience
+	}
+
+	return slices.Contains(stringList(aud), audience)
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+func unixTime(sec float64) time.Time {
+	return time.Unix(0, int64(sec*float64(time.Second)))
+}
+
+// stringList reads a claim holding an array of strings or a space-separated string.
+func stringList(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+
+	return nil
+}
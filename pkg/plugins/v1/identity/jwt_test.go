@@ -0,0 +1,404 @@
+package identity_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/identity"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/plugintest"
+)
+
+// now is the time tokens are checked at.
+var now = time.Unix(1_700_000_000, 0)
+
+var (
+	hmacSecret      = []byte("secret")
+	rsaKey          = mustKey(rsa.GenerateKey(rand.Reader, 2048))
+	ecKey           = mustKey(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	edPub, edKey, _ = ed25519.GenerateKey(rand.Reader)
+)
+
+func mustKey[K any](k K, err error) K {
+	if err != nil {
+		panic(err)
+	}
+
+	return k
+}
+
+// sign returns a token with header and claims signed for alg.
+func sign(t *testing.T, header, claims map[string]any) string {
+	t.Helper()
+
+	enc := func(v any) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	input := enc(header) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	var err error
+	switch header["alg"] {
+	case "HS256":
+		mac := hmac.New(sha256.New, hmacSecret)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case "RS256":
+		sig, err = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	case "PS256":
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+		sig, err = rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, digest[:], opts)
+	case "ES256":
+		r, s, serr := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+		err = serr
+		if err == nil {
+			sig = make([]byte, 64)
+			r.FillBytes(sig[:32])
+			s.FillBytes(sig[32:])
+		}
+	case "EdDSA":
+		sig = ed25519.Sign(edKey, []byte(input))
+	default:
+		sig = []byte("unsigned")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// hs256 returns a token with claims signed with hmacSecret.
+func hs256(t *testing.T, claims map[string]any) string {
+	t.Helper()
+
+	return sign(t, map[string]any{"alg": "HS256"}, claims)
+}
+
+// newJWT returns a JWT provider checking tokens at now.
+func newJWT(t *testing.T, opts ...identity.JWTOption) *identity.JWT {
+	t.Helper()
+
+	opts = append([]identity.JWTOption{identity.WithClock(plugintest.NewFakeClock(now))}, opts...)
+	j, err := identity.NewJWT(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return j
+}
+
+func TestNewJWTErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []identity.JWTOption
+		wantErr string
+	}{
+		{name: "no key", wantErr: "a verification key or WithoutVerification is required"},
+		{
+			name:    "empty secret",
+			opts:    []identity.JWTOption{identity.WithHMACSecret(nil)},
+			wantErr: "HMAC secret cannot be empty",
+		},
+		{
+			name:    "unsupported key",
+			opts:    []identity.JWTOption{identity.WithPublicKey("", "not a key")},
+			wantErr: "unsupported public key type string",
+		},
+		{
+			name:    "private key",
+			opts:    []identity.JWTOption{identity.WithPublicKey("", rsaKey)},
+			wantErr: "unsupported public key type *rsa.PrivateKey",
+		},
+		{
+			name:    "empty header",
+			opts:    []identity.JWTOption{identity.WithoutVerification(), identity.WithHeader("")},
+			wantErr: "header name cannot be empty",
+		},
+		{
+			name:    "negative leeway",
+			opts:    []identity.JWTOption{identity.WithoutVerification(), identity.WithLeeway(-time.Second)},
+			wantErr: "leeway cannot be negative",
+		},
+		{
+			name:    "nil clock",
+			opts:    []identity.JWTOption{identity.WithoutVerification(), identity.WithClock(nil)},
+			wantErr: "clock cannot be nil",
+		},
+		{
+			name:    "empty subject claim",
+			opts:    []identity.JWTOption{identity.WithoutVerification(), identity.WithSubjectClaim("")},
+			wantErr: "subject claim cannot be empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := identity.NewJWT(tt.opts...)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("NewJWT error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWTParse(t *testing.T) {
+	keys := []identity.JWTOption{
+		identity.WithHMACSecret(hmacSecret),
+		identity.WithPublicKey("rsa", &rsaKey.PublicKey),
+		identity.WithPublicKey("ec", &ecKey.PublicKey),
+		identity.WithPublicKey("", edPub),
+	}
+	claims := map[string]any{"sub": "alice", "iss": "https://idp", "aud": "mcpd", "exp": float64(now.Unix() + 60)}
+	tamper := func(tok string) string {
+		parts := strings.Split(tok, ".")
+		parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"mallory"}`))
+		return strings.Join(parts, ".")
+	}
+
+	tests := []struct {
+		name    string
+		opts    []identity.JWTOption
+		token   string
+		wantErr string
+	}{
+		{name: "HS256", token: hs256(t, claims)},
+		{name: "RS256", token: sign(t, map[string]any{"alg": "RS256", "kid": "rsa"}, claims)},
+		{name: "PS256", token: sign(t, map[string]any{"alg": "PS256", "kid": "rsa"}, claims)},
+		{name: "ES256", token: sign(t, map[string]any{"alg": "ES256", "kid": "ec"}, claims)},
+		{name: "EdDSA with the default key", token: sign(t, map[string]any{"alg": "EdDSA", "kid": "other"}, claims)},
+		{
+			name:    "tampered claims",
+			token:   tamper(hs256(t, claims)),
+			wantErr: "signature verification failed",
+		},
+		{
+			name:    "key of another family",
+			token:   sign(t, map[string]any{"alg": "RS256", "kid": "ec"}, claims),
+			wantErr: "signature verification failed",
+		},
+		{
+			name:    "HMAC without a secret",
+			opts:    []identity.JWTOption{identity.WithPublicKey("", &rsaKey.PublicKey)},
+			token:   hs256(t, claims),
+			wantErr: "signature verification failed",
+		},
+		{
+			name:    "alg none",
+			token:   sign(t, map[string]any{"alg": "none"}, claims),
+			wantErr: `unsupported algorithm "none"`,
+		},
+		{
+			name:    "unknown hash",
+			token:   sign(t, map[string]any{"alg": "HS999"}, claims),
+			wantErr: `unsupported algorithm "HS999"`,
+		},
+		{
+			name:    "unknown family",
+			token:   sign(t, map[string]any{"alg": "XX256"}, claims),
+			wantErr: `unsupported algorithm "XX256"`,
+		},
+		{
+			name:  "alg none without verification",
+			opts:  []identity.JWTOption{identity.WithoutVerification()},
+			token: sign(t, map[string]any{"alg": "none"}, claims),
+		},
+		{name: "two segments", token: "a.b", wantErr: "malformed token"},
+		{name: "bad header", token: "!.e30.c2ln", wantErr: "header:"},
+		{
+			name:    "bad signature encoding",
+			token:   hs256(t, claims) + "!",
+			wantErr: "signature:",
+		},
+		{
+			name:    "bad claims",
+			opts:    []identity.JWTOption{identity.WithoutVerification()},
+			token:   "eyJhbGciOiJub25lIn0.!.c2ln",
+			wantErr: "claims:",
+		},
+		{
+			name:    "expired",
+			token:   hs256(t, map[string]any{"sub": "a", "exp": float64(now.Unix() - 61)}),
+			wantErr: "token expired",
+		},
+		{
+			name:  "expired within the leeway",
+			token: hs256(t, map[string]any{"sub": "a", "exp": float64(now.Unix() - 59)}),
+		},
+		{
+			name:    "expired without leeway",
+			opts:    []identity.JWTOption{identity.WithHMACSecret(hmacSecret), identity.WithLeeway(0)},
+			token:   hs256(t, map[string]any{"sub": "a", "exp": float64(now.Unix() - 1)}),
+			wantErr: "token expired",
+		},
+		{
+			name:    "not valid yet",
+			token:   hs256(t, map[string]any{"sub": "a", "nbf": float64(now.Unix() + 61)}),
+			wantErr: "token not valid yet",
+		},
+		{
+			name:  "valid within the leeway",
+			token: hs256(t, map[string]any{"sub": "a", "nbf": float64(now.Unix() + 59)}),
+		},
+		{
+			name: "trusted issuer",
+			opts: []identity.JWTOption{
+				identity.WithHMACSecret(hmacSecret),
+				identity.WithIssuer("https://other", "https://idp"),
+			},
+			token: hs256(t, claims),
+		},
+		{
+			name:    "untrusted issuer",
+			opts:    []identity.JWTOption{identity.WithHMACSecret(hmacSecret), identity.WithIssuer("https://other")},
+			token:   hs256(t, claims),
+			wantErr: `untrusted issuer "https://idp"`,
+		},
+		{
+			name:  "audience in a list",
+			opts:  []identity.JWTOption{identity.WithHMACSecret(hmacSecret), identity.WithAudience("mcpd")},
+			token: hs256(t, map[string]any{"sub": "a", "aud": []string{"x", "mcpd"}}),
+		},
+		{
+			name:    "wrong audience",
+			opts:    []identity.JWTOption{identity.WithHMACSecret(hmacSecret), identity.WithAudience("other")},
+			token:   hs256(t, claims),
+			wantErr: `token is not intended for "other"`,
+		},
+		{
+			name:    "missing audience",
+			opts:    []identity.JWTOption{identity.WithHMACSecret(hmacSecret), identity.WithAudience("mcpd")},
+			token:   hs256(t, map[string]any{"sub": "a"}),
+			wantErr: `token is not intended for "mcpd"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			if opts == nil {
+				opts = keys
+			}
+			_, err := newJWT(t, opts...).Parse(tt.token)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Parse: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, identity.ErrInvalidCredentials) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse error = %v, want invalid credentials with %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWTIdentify(t *testing.T) {
+	alice := mcpdpluginsv1.NewPrincipal("jwt", "https://idp", "alice")
+	token := hs256(t, map[string]any{
+		"sub":    "alice",
+		"iss":    "https://idp",
+		"groups": []string{"dev", "ops"},
+		"scope":  "read write",
+		"email":  "alice@example.com",
+	})
+
+	tests := []struct {
+		name       string
+		opts       []identity.JWTOption
+		headers    map[string]string
+		want       *mcpdpluginsv1.Principal
+		wantErr    string
+		wantGroups []string
+	}{
+		{
+			name:       "bearer token",
+			headers:    map[string]string{"authorization": "Bearer " + token},
+			want:       alice,
+			wantGroups: []string{"dev", "ops"},
+		},
+		{
+			name:       "lower-case bearer and spaces",
+			headers:    map[string]string{"Authorization": "bearer   " + token},
+			want:       alice,
+			wantGroups: []string{"dev", "ops"},
+		},
+		{
+			name:       "custom header without a prefix",
+			opts:       []identity.JWTOption{identity.WithHeader("X-Token")},
+			headers:    map[string]string{"X-Token": token},
+			want:       alice,
+			wantGroups: []string{"dev", "ops"},
+		},
+		{
+			name:       "custom claims",
+			opts:       []identity.JWTOption{identity.WithSubjectClaim("email"), identity.WithGroupsClaim("scope")},
+			headers:    map[string]string{"Authorization": "Bearer " + token},
+			want:       mcpdpluginsv1.NewPrincipal("jwt", "https://idp", "alice@example.com"),
+			wantGroups: []string{"read", "write"},
+		},
+		{name: "no token"},
+		{name: "empty bearer", headers: map[string]string{"Authorization": "Bearer "}},
+		{name: "bearer without a token", headers: map[string]string{"Authorization": "Bearer"}},
+		{name: "another scheme", headers: map[string]string{"Authorization": "Basic YWxpY2U6c2VjcmV0"}},
+		{
+			name:    "missing subject",
+			opts:    []identity.JWTOption{identity.WithSubjectClaim("uid")},
+			headers: map[string]string{"Authorization": "Bearer " + token},
+			wantErr: "token has no uid claim",
+		},
+		{
+			name:    "invalid token",
+			headers: map[string]string{"Authorization": "Bearer x.y.z"},
+			wantErr: "invalid credentials",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newJWT(t, append([]identity.JWTOption{identity.WithHMACSecret(hmacSecret)}, tt.opts...)...)
+			p, err := j.Identify(context.Background(), &mcpdpluginsv1.HTTPRequest{Headers: tt.headers})
+			if tt.wantErr != "" {
+				if !errors.Is(err, identity.ErrInvalidCredentials) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Identify error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if p != nil {
+					t.Errorf("Identify = %+v, want anonymous", p)
+				}
+				return
+			}
+			if p.ID != tt.want.ID || p.Source != tt.want.Source || p.Subject != tt.want.Subject ||
+				p.Issuer != tt.want.Issuer {
+				t.Errorf("Identify = %+v, want %+v", p, tt.want)
+			}
+			if !reflect.DeepEqual(p.Groups, tt.wantGroups) {
+				t.Errorf("groups = %q, want %q", p.Groups, tt.wantGroups)
+			}
+			if p.Attributes["email"] != "alice@example.com" {
+				t.Errorf("attributes = %v, want the token's claims", p.Attributes)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestNewPrincipal(t *testing.T) {
+	tests := []struct {
+		name                    string
+		source, issuer, subject string
+		wantID                  string
+	}{
+		{name: "without issuer", source: "session", subject: "s1", wantID: "session:s1"},
+		{name: "with issuer", source: "jwt", issuer: "https://idp", subject: "alice", wantID: "jwt:https://idp#alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPrincipal(tt.source, tt.issuer, tt.subject)
+			want := &Principal{ID: tt.wantID, Source: tt.source, Issuer: tt.issuer, Subject: tt.subject}
+			if !reflect.DeepEqual(p, want) {
+				t.Errorf("NewPrincipal = %+v, want %+v", p, want)
+			}
+		})
+	}
+}
+
+func TestPrincipalInGroup(t *testing.T) {
+	p := &Principal{Groups: []string{"admins", "dev"}}
+	if !p.InGroup("dev") || p.InGroup("ops") || p.InGroup("") {
+		t.Errorf("InGroup misreports the membership of %v", p.Groups)
+	}
+	var anonymous *Principal
+	if anonymous.InGroup("dev") {
+		t.Error("a nil principal is in a group")
+	}
+}
+
+func TestIdentityProviders(t *testing.T) {
+	req := &HTTPRequest{
+		RemoteAddr: "10.0.0.1:5000",
+		Headers:    map[string]string{"x-user": "alice", "mcp-session-id": "s1"},
+	}
+	tests := []struct {
+		name     string
+		provider IdentityProvider
+		req      *HTTPRequest
+		wantID   string
+	}{
+		{name: "header", provider: IdentityFromHeader("X-User"), req: req, wantID: "header:alice"},
+		{name: "missing header", provider: IdentityFromHeader("X-Other"), req: req},
+		{name: "session", provider: IdentityFromSession(), req: req, wantID: "session:s1"},
+		{name: "no session", provider: IdentityFromSession(), req: &HTTPRequest{}},
+		{name: "client", provider: IdentityFromClient(), req: req, wantID: "client:10.0.0.1"},
+		{
+			name:     "client without port",
+			provider: IdentityFromClient(),
+			req:      &HTTPRequest{RemoteAddr: "10.0.0.2"},
+			wantID:   "client:10.0.0.2",
+		},
+		{name: "no client", provider: IdentityFromClient(), req: &HTTPRequest{}},
+		{name: "nil request", provider: IdentityFromSession()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tt.provider.Identify(context.Background(), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := principalID(p); got != tt.wantID {
+				t.Errorf("principal %+v, want ID %q", p, tt.wantID)
+			}
+		})
+	}
+}
+
+// principalID returns p's ID, or an empty string for a nil principal.
+func principalID(p *Principal) string {
+	if p == nil {
+		return ""
+	}
+
+	return p.ID
+}
+
+func TestFirstIdentity(t *testing.T) {
+	errBad := errors.New("bad token")
+	rejecting := IdentityProviderFunc(func(context.Context, *HTTPRequest) (*Principal, error) {
+		return nil, errBad
+	})
+	req := &HTTPRequest{Headers: map[string]string{"mcp-session-id": "s1", "x-user": "alice"}}
+
+	tests := []struct {
+		name      string
+		providers []IdentityProvider
+		wantID    string
+		wantErr   error
+	}{
+		{
+			name:      "first match",
+			providers: []IdentityProvider{IdentityFromHeader("X-User"), IdentityFromSession()},
+			wantID:    "header:alice",
+		},
+		{
+			name:      "falls through anonymous providers",
+			providers: []IdentityProvider{IdentityFromHeader("X-Other"), IdentityFromSession()},
+			wantID:    "session:s1",
+		},
+		{name: "skips nil providers", providers: []IdentityProvider{nil, IdentityFromSession()}, wantID: "session:s1"},
+		{
+			name:      "error stops the search",
+			providers: []IdentityProvider{rejecting, IdentityFromSession()},
+			wantErr:   errBad,
+		},
+		{name: "anonymous", providers: []IdentityProvider{IdentityFromHeader("X-Other")}},
+		{name: "no providers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := FirstIdentity(tt.providers...).Identify(context.Background(), req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Identify error = %v, want %v", err, tt.wantErr)
+			}
+			if got := principalID(p); got != tt.wantID {
+				t.Errorf("principal %+v, want ID %q", p, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestWithIdentityErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		providers []IdentityProvider
+	}{
+		{name: "no providers"},
+		{name: "nil provider", providers: []IdentityProvider{IdentityFromSession(), nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newServeOptions(WithIdentity(tt.providers...)); err == nil {
+				t.Error("WithIdentity accepted invalid providers")
+			}
+		})
+	}
+}
+
+func TestContextWithPrincipal(t *testing.T) {
+	if p, err := Identity(context.Background()); p != nil || err != nil {
+		t.Errorf("Identity of an empty context = %v, %v", p, err)
+	}
+	if id := PrincipalID(context.Background()); id != "" {
+		t.Errorf("PrincipalID of an empty context = %q", id)
+	}
+
+	p := NewPrincipal("jwt", "", "alice")
+	ctx := ContextWithPrincipal(context.Background(), p)
+	if got, err := Identity(ctx); got != p || err != nil {
+		t.Errorf("Identity = %v, %v, want %v", got, err, p)
+	}
+	if id := PrincipalID(ctx); id != "jwt:alice" {
+		t.Errorf("PrincipalID = %q, want jwt:alice", id)
+	}
+}
+
+func TestContextWithIdentityError(t *testing.T) {
+	errBad := errors.New("bad token")
+	ctx := ContextWithIdentityError(context.Background(), errBad)
+	if p, err := Identity(ctx); p != nil || !errors.Is(err, errBad) {
+		t.Errorf("Identity = %v, %v, want the provider's error", p, err)
+	}
+	if id := PrincipalID(ctx); id != "" {
+		t.Errorf("PrincipalID = %q, want none", id)
+	}
+}
+
+func TestIdentityInterceptor(t *testing.T) {
+	errBad := errors.New("bad token")
+	provider := IdentityProviderFunc(func(_ context.Context, req *HTTPRequest) (*Principal, error) {
+		switch GetHeader(req.GetHeaders(), "X-User") {
+		case "":
+			return nil, nil
+		case "mallory":
+			return nil, errBad
+		default:
+			return NewPrincipal("header", "", GetHeader(req.GetHeaders(), "X-User")), nil
+		}
+	})
+	o, err := newServeOptions(WithIdentity(provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	intercept := chainInterceptors(o.interceptors)
+	call := func(ctx context.Context, req any) (*Principal, error) {
+		t.Helper()
+
+		var p *Principal
+		var idErr error
+		_, err := intercept(ctx, req, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+			p, idErr = Identity(ctx)
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return p, idErr
+	}
+	withID := func(id string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", id))
+	}
+	alice := &HTTPRequest{Headers: map[string]string{"X-User": "alice"}}
+
+	// The request's principal is carried over to its response, once.
+	if p, err := call(withID("1"), alice); principalID(p) != "header:alice" || err != nil {
+		t.Errorf("request principal = %v, %v, want alice", p, err)
+	}
+	if p, _ := call(withID("2"), &HTTPResponse{}); p != nil {
+		t.Errorf("response of another request got principal %v", p)
+	}
+	if p, _ := call(withID("1"), &HTTPResponse{}); principalID(p) != "header:alice" {
+		t.Errorf("response principal = %v, want alice", p)
+	}
+	if p, _ := call(withID("1"), &HTTPResponse{}); p != nil {
+		t.Errorf("second response got principal %v", p)
+	}
+
+	// Without a correlation ID, responses cannot be paired with a principal.
+	if p, _ := call(context.Background(), alice); principalID(p) != "header:alice" {
+		t.Errorf("request principal = %v, want alice", p)
+	}
+	if p, _ := call(context.Background(), &HTTPResponse{}); p != nil {
+		t.Errorf("uncorrelated response got principal %v", p)
+	}
+
+	// Rejected credentials reach the handler as an error.
+	if p, err := call(withID("3"), &HTTPRequest{Headers: map[string]string{"X-User": "mallory"}}); p != nil ||
+		!errors.Is(err, errBad) {
+		t.Errorf("rejected request = %v, %v, want the provider's error", p, err)
+	}
+	if p, err := call(withID("4"), &HTTPRequest{}); p != nil || err != nil {
+		t.Errorf("anonymous request = %v, %v", p, err)
+	}
+	if p, err := call(withID("1"), &PluginConfig{}); p != nil || err != nil {
+		t.Errorf("Configure call = %v, %v, want no identity", p, err)
+	}
+}
@@ -0,0 +1,57 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// eventInterceptor publishes an EventRequest (and, on failure, an EventError) for every RPC.
+func eventInterceptor(bus *EventBus) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		ev := Event{
+			Kind:     EventRequest,
+			Method:   path.Base(info.FullMethod),
+			Duration: time.Since(start),
+			Err:      err,
+		}
+		switch in := req.(type) {
+		case *HTTPRequest:
+			ev.Request = in
+		case *HTTPResponse:
+			ev.Response = in
+		}
+		if out, ok := resp.(*HTTPResponse); ok && err == nil {
+			ev.Result = out
+		}
+
+		bus.Publish(ctx, ev)
+		if err != nil {
+			ev.Kind = EventError
+			bus.Publish(ctx, ev)
+		}
+
+		return resp, err
+	}
+}
+
+// chainInterceptors combines interceptors into one, with the first outermost, as
+// grpc.ChainUnaryInterceptor does for the server. It lets the SDK drive calls that do not arrive
+// over gRPC through the same chain.
+func chainInterceptors(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			inner, ic := next, interceptors[i]
+			next = func(ctx context.Context, req any) (any, error) {
+				return ic(ctx, req, info, inner)
+			}
+		}
+		return next(ctx, req)
+	}
+}
@@ -0,0 +1,331 @@
+// Package memcache is a minimal memcached client speaking the text protocol over pooled TCP
+// connections, covering what the SDK's distributed backends need without an external dependency.
+// Keys are spread over several servers by hashing.
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a key does not exist.
+var ErrNotFound = errors.New("memcache: key not found")
+
+// ErrNotStored is returned by Add when the key already exists.
+var ErrNotStored = errors.New("memcache: item not stored")
+
+// maxKeyLength is the longest key memcached accepts.
+const maxKeyLength = 250
+
+// Config configures a Client.
+type Config struct {
+	// Addrs lists the servers' "host:port" addresses.
+	Addrs []string
+
+	// DialTimeout bounds connection setup (default 5s).
+	DialTimeout time.Duration
+
+	// MaxIdle is the number of idle connections kept per server (default 4).
+	MaxIdle int
+}
+
+// Client sends commands to memcached servers. It is safe for concurrent use.
+type Client struct {
+	servers []*server
+}
+
+type server struct {
+	addr        string
+	dialTimeout time.Duration
+	idle        chan *conn
+
+	mu     sync.Mutex
+	closed bool
+}
+
+type conn struct {
+	nc net.Conn
+	rw *bufio.ReadWriter
+}
+
+// New returns a Client for cfg. Connections are opened lazily.
+func New(cfg Config) (*Client, error) {
+	if len(cfg.Addrs) == 0 {
+		return nil, fmt.Errorf("at least one memcached address is required")
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.MaxIdle <= 0 {
+		cfg.MaxIdle = 4
+	}
+
+	c := &Client{}
+	for _, addr := range cfg.Addrs {
+		c.servers = append(c.servers, &server{
+			addr:        addr,
+			dialTimeout: cfg.DialTimeout,
+			idle:        make(chan *conn, cfg.MaxIdle),
+		})
+	}
+
+	return c, nil
+}
+
+// Get returns the value stored under key, or ErrNotFound.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := c.do(ctx, key, "get "+key+"\r\n", nil, func(r *bufio.Reader) error {
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		if line == "END" {
+			return ErrNotFound
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] != "VALUE" {
+			return fmt.Errorf("memcache: unexpected reply %q", line)
+		}
+		n, err := strconv.Atoi(fields[3])
+		if err != nil || n < 0 {
+			return fmt.Errorf("memcache: malformed value length %q", line)
+		}
+		value = make([]byte, n+2)
+		if _, err := io.ReadFull(r, value); err != nil {
+			return err
+		}
+		value = value[:n]
+		if line, err := readLine(r); err != nil || line != "END" {
+			return fmt.Errorf("memcache: missing END after value")
+		}
+		return nil
+	})
+
+	return value, err
+}
+
+// Set stores value under key, expiring after ttl (0 never expires).
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.store(ctx, "set", key, value, ttl)
+}
+
+// Add stores value under key only if the key does not exist, returning ErrNotStored otherwise.
+func (c *Client) Add(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.store(ctx, "add", key, value, ttl)
+}
+
+// Delete removes key. Deleting a missing key is not an error.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	err := c.do(ctx, key, "delete "+key+"\r\n", nil, func(r *bufio.Reader) error {
+		return expect(r, "DELETED", "NOT_FOUND")
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// Incr adds delta to the numeric value of key and returns the new value, or ErrNotFound.
+func (c *Client) Incr(ctx context.Context, key string, delta uint64) (uint64, error) {
+	var n uint64
+	err := c.do(ctx, key, "incr "+key+" "+strconv.FormatUint(delta, 10)+"\r\n", nil, func(r *bufio.Reader) error {
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		if line == "NOT_FOUND" {
+			return ErrNotFound
+		}
+		n, err = strconv.ParseUint(line, 10, 64)
+		if err != nil {
+			return replyError(line)
+		}
+		return nil
+	})
+
+	return n, err
+}
+
+// Close closes idle connections.
+func (c *Client) Close() error {
+	for _, s := range c.servers {
+		s.close()
+	}
+
+	return nil
+}
+
+func (c *Client) store(ctx context.Context, verb, key string, value []byte, ttl time.Duration) error {
+	cmd := fmt.Sprintf("%s %s 0 %d %d\r\n", verb, key, expiration(ttl), len(value))
+	return c.do(ctx, key, cmd, value, func(r *bufio.Reader) error {
+		return expect(r, "STORED", "NOT_STORED")
+	})
+}
+
+// do runs one command against the server owning key. body, when non-nil, follows the command line.
+func (c *Client) do(ctx context.Context, key, cmd string, body []byte, read func(*bufio.Reader) error) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	s := c.servers[0]
+	if len(c.servers) > 1 {
+		s = c.servers[crc32.ChecksumIEEE([]byte(key))%uint32(len(c.servers))]
+	}
+
+	cn, err := s.get(ctx)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	_ = cn.nc.SetDeadline(deadline)
+
+	_, _ = cn.rw.WriteString(cmd)
+	if body != nil {
+		_, _ = cn.rw.Write(body)
+		_, _ = cn.rw.WriteString("\r\n")
+	}
+	if err := cn.rw.Flush(); err != nil {
+		_ = cn.nc.Close()
+		return fmt.Errorf("memcache write failed: %w", err)
+	}
+
+	err = read(cn.rw.Reader)
+	if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrNotStored) && !isReplyError(err) {
+		_ = cn.nc.Close()
+		return fmt.Errorf("memcache read failed: %w", err)
+	}
+	s.put(cn)
+
+	return err
+}
+
+func (s *server) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn, ok := <-s.idle:
+		if ok {
+			return cn, nil
+		}
+		return nil, fmt.Errorf("memcache client is closed")
+	default:
+	}
+
+	d := net.Dialer{Timeout: s.dialTimeout}
+	nc, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to memcached at %s: %w", s.addr, err)
+	}
+
+	return &conn{nc: nc, rw: bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc))}, nil
+}
+
+func (s *server) put(cn *conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		_ = cn.nc.Close()
+		return
+	}
+	select {
+	case s.idle <- cn:
+	default:
+		_ = cn.nc.Close()
+	}
+}
+
+func (s *server) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.idle)
+	for cn := range s.idle {
+		_ = cn.nc.Close()
+	}
+}
+
+// replyError is an error reply (ERROR, CLIENT_ERROR or SERVER_ERROR) from memcached.
+type replyError string
+
+func (e replyError) Error() string {
+	return "memcache: " + string(e)
+}
+
+func isReplyError(err error) bool {
+	var re replyError
+	return errors.As(err, &re)
+}
+
+// expect reads a status line, mapping ok to nil, NOT_STORED to ErrNotStored and NOT_FOUND to
+// ErrNotFound.
+func expect(r *bufio.Reader, ok, alt string) error {
+	line, err := readLine(r)
+	if err != nil {
+		return err
+	}
+	switch line {
+	case ok:
+		return nil
+	case alt:
+		if alt == "NOT_STORED" {
+			return ErrNotStored
+		}
+		return ErrNotFound
+	default:
+		return replyError(line)
+	}
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// maxRelativeExpiration is the longest exptime memcached treats as relative; longer values are
+// read as Unix timestamps.
+const maxRelativeExpiration = 30 * 24 * 60 * 60
+
+// expiration converts ttl to memcached's exptime in seconds, rounding up so short TTLs do not
+// become "never expires".
+func expiration(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	secs := int64((ttl + time.Second - 1) / time.Second)
+	if secs > maxRelativeExpiration {
+		return time.Now().Add(ttl).Unix()
+	}
+
+	return secs
+}
+
+func validKey(key string) error {
+	if key == "" || len(key) > maxKeyLength {
+		return fmt.Errorf("memcache: invalid key length %d", len(key))
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return fmt.Errorf("memcache: key %q contains whitespace or control characters", key)
+		}
+	}
+
+	return nil
+}
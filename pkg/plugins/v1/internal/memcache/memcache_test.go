@@ -0,0 +1,230 @@
+package memcache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/memcache"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/memcache/memcachetest"
+)
+
+func newClient(t *testing.T, addrs ...string) *memcache.Client {
+	t.Helper()
+
+	c, err := memcache.New(memcache.Config{Addrs: addrs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+
+	return c
+}
+
+func TestNew(t *testing.T) {
+	if _, err := memcache.New(memcache.Config{}); err == nil {
+		t.Error("New accepted no addresses")
+	}
+}
+
+func TestCommands(t *testing.T) {
+	srv := memcachetest.NewServer(t)
+	c := newClient(t, srv.Addr)
+	ctx := context.Background()
+
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, memcache.ErrNotFound) {
+		t.Errorf("Get of a missing key error = %v, want ErrNotFound", err)
+	}
+	if _, err := c.Incr(ctx, "n", 1); !errors.Is(err, memcache.ErrNotFound) {
+		t.Errorf("Incr of a missing key error = %v, want ErrNotFound", err)
+	}
+
+	if err := c.Set(ctx, "k", []byte("a b\r\nc"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get(ctx, "k"); err != nil || string(v) != "a b\r\nc" {
+		t.Errorf("Get = %q, %v; want the stored value", v, err)
+	}
+	if err := c.Set(ctx, "empty", []byte{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get(ctx, "empty"); err != nil || len(v) != 0 {
+		t.Errorf("Get of an empty value = %q, %v", v, err)
+	}
+
+	if err := c.Add(ctx, "k", []byte("other"), 0); !errors.Is(err, memcache.ErrNotStored) {
+		t.Errorf("Add of an existing key error = %v, want ErrNotStored", err)
+	}
+	if err := c.Add(ctx, "n", []byte("5"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.Incr(ctx, "n", 3); err != nil || n != 8 {
+		t.Errorf("Incr = %d, %v; want 8", n, err)
+	}
+	if _, err := c.Incr(ctx, "k", 1); err == nil || !strings.Contains(err.Error(), "non-numeric") {
+		t.Errorf("Incr of a string error = %v, want the server's error", err)
+	}
+
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.Item("k"); ok {
+		t.Error("Delete left the item")
+	}
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Errorf("Delete of a missing key = %v, want nil", err)
+	}
+
+	// Not-found, not-stored and error replies keep the connection.
+	if got := srv.Conns(); got != 1 {
+		t.Errorf("%d connections, want 1", got)
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		want func(int64) bool
+	}{
+		{name: "none", ttl: 0, want: func(e int64) bool { return e == 0 }},
+		{name: "rounded up", ttl: 1500 * time.Millisecond, want: func(e int64) bool { return e == 2 }},
+		{name: "sub-second", ttl: time.Millisecond, want: func(e int64) bool { return e == 1 }},
+		{name: "thirty days", ttl: 30 * 24 * time.Hour, want: func(e int64) bool { return e == 30*24*60*60 }},
+		{
+			name: "absolute beyond thirty days",
+			ttl:  31 * 24 * time.Hour,
+			want: func(e int64) bool {
+				want := time.Now().Add(31 * 24 * time.Hour).Unix()
+				return e >= want-5 && e <= want
+			},
+		},
+	}
+	srv := memcachetest.NewServer(t)
+	c := newClient(t, srv.Addr)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.Set(context.Background(), "k", []byte("v"), tt.ttl); err != nil {
+				t.Fatal(err)
+			}
+			if it, _ := srv.Item("k"); !tt.want(it.Exptime) {
+				t.Errorf("exptime = %d", it.Exptime)
+			}
+		})
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+	srv := memcachetest.NewServer(t)
+	c := newClient(t, srv.Addr)
+	for _, key := range []string{"", strings.Repeat("k", 251), "a b", "a\nb", "a\x7fb"} {
+		if err := c.Set(context.Background(), key, []byte("v"), 0); err == nil {
+			t.Errorf("Set accepted key %q", key)
+		}
+	}
+	if err := c.Set(context.Background(), strings.Repeat("k", 250), []byte("v"), 0); err != nil {
+		t.Errorf("Set rejected a 250 byte key: %v", err)
+	}
+	if got := srv.Conns(); got != 1 {
+		t.Errorf("invalid keys opened %d connections, want none besides the valid Set", got)
+	}
+}
+
+func TestMalformedReplies(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		call  func(*memcache.Client) error
+		want  string
+	}{
+		{
+			name:  "unexpected get reply",
+			reply: "STORED\r\n",
+			call:  func(c *memcache.Client) error { _, err := c.Get(context.Background(), "k"); return err },
+			want:  "unexpected reply",
+		},
+		{
+			name:  "malformed value length",
+			reply: "VALUE k 0 x\r\n",
+			call:  func(c *memcache.Client) error { _, err := c.Get(context.Background(), "k"); return err },
+			want:  "malformed value length",
+		},
+		{
+			name:  "missing END",
+			reply: "VALUE k 0 1\r\nv\r\nVALUE\r\n",
+			call:  func(c *memcache.Client) error { _, err := c.Get(context.Background(), "k"); return err },
+			want:  "missing END",
+		},
+		{
+			name: "connection closed",
+			call: func(c *memcache.Client) error { _, err := c.Incr(context.Background(), "k", 1); return err },
+			want: "memcache read failed",
+		},
+		{
+			name:  "server error",
+			reply: "SERVER_ERROR out of memory\r\n",
+			call:  func(c *memcache.Client) error { return c.Set(context.Background(), "k", []byte("v"), 0) },
+			want:  "memcache: SERVER_ERROR out of memory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := memcachetest.NewServer(t)
+			c := newClient(t, srv.Addr)
+			srv.Intercept(func(string) string { return tt.reply })
+
+			if err := tt.call(c); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+
+			// The client recovers once the server behaves.
+			srv.Intercept(nil)
+			if err := c.Set(context.Background(), "k", []byte("v"), 0); err != nil {
+				t.Errorf("Set after the failure: %v", err)
+			}
+		})
+	}
+}
+
+func TestSharding(t *testing.T) {
+	a, b := memcachetest.NewServer(t), memcachetest.NewServer(t)
+	c := newClient(t, a.Addr, b.Addr)
+	ctx := context.Background()
+
+	for i := range 50 {
+		key := fmt.Sprintf("key-%d", i)
+		if err := c.Set(ctx, key, []byte(key), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a.Len() == 0 || b.Len() == 0 || a.Len()+b.Len() != 50 {
+		t.Fatalf("servers hold %d and %d keys, want 50 spread over both", a.Len(), b.Len())
+	}
+	for i := range 50 {
+		key := fmt.Sprintf("key-%d", i)
+		if v, err := c.Get(ctx, key); err != nil || string(v) != key {
+			t.Errorf("Get(%s) = %q, %v", key, v, err)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	srv := memcachetest.NewServer(t)
+	c := newClient(t, srv.Addr)
+	if err := c.Set(context.Background(), "k", []byte("v"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+	if _, err := c.Get(context.Background(), "k"); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("Get after Close error = %v, want a closed client", err)
+	}
+}
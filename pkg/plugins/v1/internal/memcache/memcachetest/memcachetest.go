@@ -0,0 +1,219 @@
+// Package memcachetest provides an in-memory memcached server for testing the SDK's memcached
+// backends. It speaks the subset of the text protocol the memcache client uses and does not
+// expire items.
+package memcachetest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Item is a stored value and the exptime it was stored with.
+type Item struct {
+	Value   []byte
+	Exptime int64
+}
+
+// Server is a memcached server on a loopback port. It is safe for concurrent use.
+type Server struct {
+	// Addr is the server's "host:port".
+	Addr string
+
+	ln net.Listener
+	wg sync.WaitGroup
+
+	mu        sync.Mutex
+	closed    bool
+	conns     []net.Conn
+	items     map[string]Item
+	intercept func(cmd string) string
+}
+
+// NewServer starts an empty Server, closed when the test ends.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("memcachetest: failed to listen: %v", err)
+	}
+	s := &Server{Addr: ln.Addr().String(), ln: ln, items: map[string]Item{}}
+	s.wg.Add(1)
+	go s.serve()
+	tb.Cleanup(s.Close)
+
+	return s
+}
+
+// Item returns the item stored under key.
+func (s *Server) Item(key string) (Item, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	it, ok := s.items[key]
+
+	return it, ok
+}
+
+// Len returns the number of stored items.
+func (s *Server) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.items)
+}
+
+// Conns returns the number of connections accepted so far.
+func (s *Server) Conns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.conns)
+}
+
+// Intercept makes the server answer every command line with f's result, written verbatim, instead
+// of executing it; an empty result closes the connection. A nil f restores normal operation.
+func (s *Server) Intercept(f func(cmd string) string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.intercept = f
+}
+
+// Close stops the server and closes its connections.
+func (s *Server) Close() {
+	_ = s.ln.Close()
+	s.mu.Lock()
+	s.closed = true
+	for _, c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = c.Close()
+			return
+		}
+		s.conns = append(s.conns, c)
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() { _ = c.Close() }()
+			s.serveConn(c)
+		}()
+	}
+}
+
+func (s *Server) serveConn(c net.Conn) {
+	r, w := bufio.NewReader(c), bufio.NewWriter(c)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSuffix(line, "\r\n")
+		fields := strings.Fields(line)
+
+		var body []byte
+		if len(fields) == 5 && (fields[0] == "set" || fields[0] == "add") {
+			n, err := strconv.Atoi(fields[4])
+			if err != nil || n < 0 {
+				return
+			}
+			body = make([]byte, n+2)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return
+			}
+			body = body[:n]
+		}
+
+		s.mu.Lock()
+		var reply string
+		if s.intercept != nil {
+			reply = s.intercept(line)
+		} else {
+			reply = s.execute(fields, body)
+		}
+		s.mu.Unlock()
+		if reply == "" {
+			return
+		}
+		if _, err := w.WriteString(reply); err != nil || w.Flush() != nil {
+			return
+		}
+	}
+}
+
+// execute runs a command with s.mu held and returns its reply.
+func (s *Server) execute(fields []string, body []byte) string {
+	if len(fields) < 2 {
+		return "ERROR\r\n"
+	}
+	key := fields[1]
+
+	switch fields[0] {
+	case "get":
+		it, ok := s.items[key]
+		if !ok {
+			return "END\r\n"
+		}
+		return fmt.Sprintf("VALUE %s 0 %d\r\n%s\r\nEND\r\n", key, len(it.Value), it.Value)
+	case "set", "add":
+		if len(fields) != 5 {
+			return "CLIENT_ERROR bad command line format\r\n"
+		}
+		if _, ok := s.items[key]; ok && fields[0] == "add" {
+			return "NOT_STORED\r\n"
+		}
+		exptime, _ := strconv.ParseInt(fields[3], 10, 64)
+		s.items[key] = Item{Value: body, Exptime: exptime}
+		return "STORED\r\n"
+	case "delete":
+		if _, ok := s.items[key]; !ok {
+			return "NOT_FOUND\r\n"
+		}
+		delete(s.items, key)
+		return "DELETED\r\n"
+	case "incr":
+		if len(fields) != 3 {
+			return "ERROR\r\n"
+		}
+		it, ok := s.items[key]
+		if !ok {
+			return "NOT_FOUND\r\n"
+		}
+		delta, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return "CLIENT_ERROR invalid numeric delta argument\r\n"
+		}
+		v, err := strconv.ParseUint(string(it.Value), 10, 64)
+		if err != nil {
+			return "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n"
+		}
+		it.Value = []byte(strconv.FormatUint(v+delta, 10))
+		s.items[key] = it
+		return string(it.Value) + "\r\n"
+	default:
+		return "ERROR\r\n"
+	}
+}
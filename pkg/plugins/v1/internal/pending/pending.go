@@ -0,0 +1,112 @@
+// Package pending remembers values by correlation ID between a plugin's HandleRequest and
+// HandleResponse calls, for plugins that carry request state over to the response.
+//
+// Entries expire a fixed time after they are put: requests that are short-circuited later in the
+// chain never reach HandleResponse, so their entries are never taken. Expired entries are swept
+// oldest first as new ones are put, in constant amortized time, so a map holding many live
+// entries costs no more per call than an empty one.
+package pending
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Map maps correlation IDs to values of type V until they are taken or expire. It is safe for
+// concurrent use.
+type Map[V any] struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // of *entry[V], in put order, which is expiry order
+}
+
+type entry[V any] struct {
+	id      string
+	value   V
+	expires time.Time
+}
+
+// New returns a Map whose entries expire ttl after they are put.
+func New[V any](ttl time.Duration) *Map[V] {
+	return &Map[V]{ttl: ttl, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+// Put remembers v under id at time now, replacing any value already under id. An empty id is
+// ignored.
+func (m *Map[V]) Put(id string, v V, now time.Time) {
+	if id == "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+	if el, ok := m.entries[id]; ok {
+		m.order.Remove(el)
+	}
+	m.entries[id] = m.order.PushBack(&entry[V]{id: id, value: v, expires: now.Add(m.ttl)})
+}
+
+// Get returns the value under id without removing it, reporting whether it is present and not
+// expired at time now.
+func (m *Map[V]) Get(id string, now time.Time) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.entries[id]; ok {
+		if e := el.Value.(*entry[V]); now.Before(e.expires) {
+			return e.value, true
+		}
+	}
+	var zero V
+
+	return zero, false
+}
+
+// Take removes the value under id and returns it, reporting whether it was present and not
+// expired at time now.
+func (m *Map[V]) Take(id string, now time.Time) (V, bool) {
+	var zero V
+	if id == "" {
+		return zero, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.entries[id]
+	if !ok {
+		return zero, false
+	}
+	delete(m.entries, id)
+	e := m.order.Remove(el).(*entry[V])
+	if !now.Before(e.expires) {
+		return zero, false
+	}
+
+	return e.value, true
+}
+
+// Len returns the number of entries, expired ones not yet swept included.
+func (m *Map[V]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.entries)
+}
+
+// sweep deletes the entries expired at now, oldest first, stopping at the first live one.
+func (m *Map[V]) sweep(now time.Time) {
+	for el := m.order.Front(); el != nil; el = m.order.Front() {
+		e := el.Value.(*entry[V])
+		if now.Before(e.expires) {
+			return
+		}
+		m.order.Remove(el)
+		delete(m.entries, e.id)
+	}
+}
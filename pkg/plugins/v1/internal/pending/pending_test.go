@@ -0,0 +1,168 @@
+package pending_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/pending"
+)
+
+var start = time.Unix(1_700_000_000, 0)
+
+func TestMap(t *testing.T) {
+	type op struct {
+		kind   string // put, get or take
+		id     string
+		value  int
+		at     time.Duration // After start.
+		want   int
+		wantOK bool
+	}
+	tests := []struct {
+		name    string
+		ops     []op
+		wantLen int
+	}{
+		{
+			name: "take once",
+			ops: []op{
+				{kind: "put", id: "a", value: 1},
+				{kind: "take", id: "a", want: 1, wantOK: true},
+				{kind: "take", id: "a"},
+			},
+			wantLen: 0,
+		},
+		{
+			name: "get keeps the entry",
+			ops: []op{
+				{kind: "put", id: "a", value: 1},
+				{kind: "get", id: "a", want: 1, wantOK: true},
+				{kind: "get", id: "a", want: 1, wantOK: true},
+			},
+			wantLen: 1,
+		},
+		{
+			name: "unknown id",
+			ops: []op{
+				{kind: "put", id: "a", value: 1},
+				{kind: "take", id: "b"},
+				{kind: "get", id: "b"},
+			},
+			wantLen: 1,
+		},
+		{
+			name: "empty id is ignored",
+			ops: []op{
+				{kind: "put", id: "", value: 1},
+				{kind: "take", id: ""},
+				{kind: "get", id: ""},
+			},
+			wantLen: 0,
+		},
+		{
+			name: "put replaces",
+			ops: []op{
+				{kind: "put", id: "a", value: 1},
+				{kind: "put", id: "a", value: 2},
+				{kind: "take", id: "a", want: 2, wantOK: true},
+			},
+			wantLen: 0,
+		},
+		{
+			name: "expires after the ttl",
+			ops: []op{
+				{kind: "put", id: "a", value: 1},
+				{kind: "get", id: "a", at: time.Minute - 1, want: 1, wantOK: true},
+				{kind: "get", id: "a", at: time.Minute},
+				{kind: "take", id: "a", at: time.Minute},
+			},
+			wantLen: 0,
+		},
+		{
+			name: "replacing restarts the ttl",
+			ops: []op{
+				{kind: "put", id: "a", value: 1},
+				{kind: "put", id: "a", value: 2, at: 30 * time.Second},
+				{kind: "take", id: "a", at: 80 * time.Second, want: 2, wantOK: true},
+			},
+			wantLen: 0,
+		},
+		{
+			name: "put sweeps expired entries",
+			ops: []op{
+				{kind: "put", id: "a", value: 1},
+				{kind: "put", id: "b", value: 2, at: 30 * time.Second},
+				{kind: "put", id: "c", value: 3, at: time.Minute},
+				{kind: "get", id: "b", at: time.Minute, want: 2, wantOK: true},
+			},
+			wantLen: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := pending.New[int](time.Minute)
+			for i, o := range tt.ops {
+				now := start.Add(o.at)
+				var got int
+				var ok bool
+				switch o.kind {
+				case "put":
+					m.Put(o.id, o.value, now)
+					continue
+				case "get":
+					got, ok = m.Get(o.id, now)
+				case "take":
+					got, ok = m.Take(o.id, now)
+				}
+				if got != o.want || ok != o.wantOK {
+					t.Errorf("op %d: %s(%q) = %d, %t, want %d, %t", i, o.kind, o.id, got, ok, o.want, o.wantOK)
+				}
+			}
+			if got := m.Len(); got != tt.wantLen {
+				t.Errorf("Len = %d, want %d", got, tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestMapSweepStopsAtLiveEntries(t *testing.T) {
+	m := pending.New[int](time.Minute)
+	for i := range 1000 {
+		m.Put(strconv.Itoa(i), i, start.Add(time.Duration(i)*time.Millisecond))
+	}
+	// Half the entries have expired by the time the next one is put.
+	m.Put("late", 0, start.Add(time.Minute+500*time.Millisecond))
+	if got := m.Len(); got != 500 {
+		t.Errorf("Len = %d, want the 499 live entries and the new one", got)
+	}
+	if _, ok := m.Get("500", start.Add(time.Minute+500*time.Millisecond)); ok {
+		t.Error("expired entry 500 still present")
+	}
+	if v, ok := m.Take("501", start.Add(time.Minute+500*time.Millisecond)); !ok || v != 501 {
+		t.Errorf("Take(501) = %d, %t, want the live entry", v, ok)
+	}
+}
+
+func TestMapConcurrent(t *testing.T) {
+	m := pending.New[int](time.Minute)
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 100 {
+				id := strconv.Itoa(g*100 + i)
+				m.Put(id, i, start)
+				if v, ok := m.Take(id, start); !ok || v != i {
+					t.Errorf("Take(%s) = %d, %t, want %d", id, v, ok, i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got := m.Len(); got != 0 {
+		t.Errorf("Len = %d, want every entry taken", got)
+	}
+}
@@ -0,0 +1,257 @@
+// Package resp is a minimal Redis client speaking RESP2 over pooled TCP connections, covering
+// what the SDK's distributed backends need without an external dependency.
+package resp
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNil is returned by Do when Redis replies with a null bulk string or array.
+var ErrNil = errors.New("redis: nil reply")
+
+// Error is an error reply sent by Redis.
+type Error string
+
+// Error implements error.
+func (e Error) Error() string {
+	return "redis: " + string(e)
+}
+
+// Config configures a Client.
+type Config struct {
+	// Addr is the server's "host:port".
+	Addr string
+
+	// Username and Password authenticate with AUTH when Password is set.
+	Username string
+	Password string
+
+	// DB selects the logical database when non-zero.
+	DB int
+
+	// TLS enables TLS when non-nil.
+	TLS *tls.Config
+
+	// DialTimeout bounds connection setup (default 5s).
+	DialTimeout time.Duration
+
+	// MaxIdle is the number of idle connections kept for reuse (default 4).
+	MaxIdle int
+}
+
+// Client sends commands to a Redis server. It is safe for concurrent use.
+type Client struct {
+	cfg  Config
+	idle chan *conn
+
+	mu     sync.Mutex
+	closed bool
+}
+
+type conn struct {
+	nc net.Conn
+	r  *bufio.Reader
+	w  *bufio.Writer
+}
+
+// New returns a Client for cfg. Connections are opened lazily.
+func New(cfg Config) (*Client, error) {
+	if cfg.Addr == "" {
+		return nil, fmt.Errorf("redis address is required")
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.MaxIdle <= 0 {
+		cfg.MaxIdle = 4
+	}
+
+	return &Client{cfg: cfg, idle: make(chan *conn, cfg.MaxIdle)}, nil
+}
+
+// Do sends a command and returns its reply: string for simple and bulk strings, int64 for
+// integers, and []any for arrays. Error replies are returned as Error, null replies as ErrNil.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := cn.do(ctx, args)
+	if err != nil {
+		var re Error
+		if !errors.As(err, &re) && !errors.Is(err, ErrNil) {
+			// The connection state is unknown after an I/O error.
+			_ = cn.nc.Close()
+			return nil, err
+		}
+	}
+	c.put(cn)
+
+	return reply, err
+}
+
+// Close closes idle connections. Connections in use are closed when returned.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	close(c.idle)
+	for cn := range c.idle {
+		_ = cn.nc.Close()
+	}
+
+	return nil
+}
+
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn, ok := <-c.idle:
+		if ok {
+			return cn, nil
+		}
+		return nil, fmt.Errorf("redis client is closed")
+	default:
+	}
+
+	d := net.Dialer{Timeout: c.cfg.DialTimeout}
+	nc, err := d.DialContext(ctx, "tcp", c.cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", c.cfg.Addr, err)
+	}
+	if c.cfg.TLS != nil {
+		tc := tls.Client(nc, c.cfg.TLS)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			_ = nc.Close()
+			return nil, fmt.Errorf("redis TLS handshake failed: %w", err)
+		}
+		nc = tc
+	}
+
+	cn := &conn{nc: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+	if c.cfg.Password != "" {
+		args := []string{"AUTH", c.cfg.Password}
+		if c.cfg.Username != "" {
+			args = []string{"AUTH", c.cfg.Username, c.cfg.Password}
+		}
+		if _, err := cn.do(ctx, args); err != nil {
+			_ = nc.Close()
+			return nil, err
+		}
+	}
+	if c.cfg.DB != 0 {
+		if _, err := cn.do(ctx, []string{"SELECT", strconv.Itoa(c.cfg.DB)}); err != nil {
+			_ = nc.Close()
+			return nil, err
+		}
+	}
+
+	return cn, nil
+}
+
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		_ = cn.nc.Close()
+		return
+	}
+	select {
+	case c.idle <- cn:
+	default:
+		_ = cn.nc.Close()
+	}
+}
+
+func (cn *conn) do(ctx context.Context, args []string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{}
+	}
+	_ = cn.nc.SetDeadline(deadline)
+
+	fmt.Fprintf(cn.w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(cn.w, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if err := cn.w.Flush(); err != nil {
+		return nil, fmt.Errorf("redis write failed: %w", err)
+	}
+
+	return readReply(cn.r)
+}
+
+// readReply reads one RESP2 reply.
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis read failed: %w", err)
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed integer %q", line)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("redis read failed: %w", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", line)
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		out := make([]any, n)
+		for i := range out {
+			v, err := readReply(r)
+			if err != nil && !errors.Is(err, ErrNil) {
+				var re Error
+				if !errors.As(err, &re) {
+					return nil, err
+				}
+				v = re
+			}
+			out[i] = v
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply type %q", line[0])
+	}
+}
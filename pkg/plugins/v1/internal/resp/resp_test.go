@@ -0,0 +1,226 @@
+package resp_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/resp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/resp/resptest"
+)
+
+func newClient(t *testing.T, cfg resp.Config) *resp.Client {
+	t.Helper()
+
+	c, err := resp.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+
+	return c
+}
+
+func TestNew(t *testing.T) {
+	if _, err := resp.New(resp.Config{}); err == nil {
+		t.Error("New accepted an empty address")
+	}
+}
+
+func TestDoReplies(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   any
+		want    any
+		wantErr string
+	}{
+		{name: "simple string", reply: resptest.Status("OK"), want: "OK"},
+		{name: "empty simple string", reply: resptest.Raw("+\r\n"), want: ""},
+		{name: "bulk string", reply: "hello\r\nworld", want: "hello\r\nworld"},
+		{name: "empty bulk string", reply: "", want: ""},
+		{name: "integer", reply: int64(-42), want: int64(-42)},
+		{name: "error", reply: resp.Error("ERR unknown command"), wantErr: "redis: ERR unknown command"},
+		{name: "null bulk string", reply: nil, wantErr: resp.ErrNil.Error()},
+		{name: "null array", reply: resptest.Raw("*-1\r\n"), wantErr: resp.ErrNil.Error()},
+		{
+			name:  "array",
+			reply: []any{"a", int64(1), nil, resp.Error("WRONGTYPE"), []any{resptest.Status("OK")}},
+			want:  []any{"a", int64(1), nil, resp.Error("WRONGTYPE"), []any{"OK"}},
+		},
+		{name: "empty array", reply: []any{}, want: []any{}},
+		{name: "malformed line", reply: resptest.Raw("+OK\n"), wantErr: "malformed reply"},
+		{name: "malformed integer", reply: resptest.Raw(":x\r\n"), wantErr: "malformed integer"},
+		{name: "malformed bulk length", reply: resptest.Raw("$x\r\n"), wantErr: "malformed bulk length"},
+		{name: "malformed array length", reply: resptest.Raw("*x\r\n"), wantErr: "malformed array length"},
+		{name: "malformed array element", reply: resptest.Raw("*1\r\n:x\r\n"), wantErr: "malformed integer"},
+		{name: "unknown type", reply: resptest.Raw("%1\r\n"), wantErr: "unexpected reply type"},
+		{name: "truncated bulk string", reply: resptest.Raw("$10\r\nabc"), wantErr: "redis read failed"},
+		{name: "connection closed", reply: resptest.Hangup, wantErr: "redis read failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := resptest.NewServer(t, func([]string) any { return tt.reply })
+			c := newClient(t, resp.Config{Addr: srv.Addr})
+
+			// Short, since the truncated reply is only detected by the deadline.
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			got, err := c.Do(ctx, "GET", "k")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Do error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Do = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoReusesConnections(t *testing.T) {
+	srv := resptest.NewServer(t, func(args []string) any {
+		switch args[0] {
+		case "GET":
+			return nil
+		case "BAD":
+			return resp.Error("ERR bad")
+		case "DROP":
+			return resptest.Hangup
+		}
+		return resptest.Status("OK")
+	})
+	c := newClient(t, resp.Config{Addr: srv.Addr})
+	ctx := context.Background()
+
+	// Error and null replies leave the connection usable.
+	for _, cmd := range []string{"PING", "GET", "BAD", "PING"} {
+		_, _ = c.Do(ctx, cmd)
+	}
+	if got := srv.Conns(); got != 1 {
+		t.Errorf("%d connections after replies, want 1", got)
+	}
+
+	// An I/O error discards the connection.
+	if _, err := c.Do(ctx, "DROP"); err == nil {
+		t.Fatal("Do succeeded on a dropped connection")
+	}
+	if _, err := c.Do(ctx, "PING"); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.Conns(); got != 2 {
+		t.Errorf("%d connections after an I/O error, want 2", got)
+	}
+}
+
+func TestConnectionSetup(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     resp.Config
+		auth    any
+		want    [][]string
+		wantErr string
+	}{
+		{name: "no setup", want: [][]string{{"PING"}}},
+		{
+			name: "password and database",
+			cfg:  resp.Config{Password: "secret", DB: 2},
+			want: [][]string{{"AUTH", "secret"}, {"SELECT", "2"}, {"PING"}},
+		},
+		{
+			name: "username",
+			cfg:  resp.Config{Username: "app", Password: "secret"},
+			want: [][]string{{"AUTH", "app", "secret"}, {"PING"}},
+		},
+		{name: "username without password", cfg: resp.Config{Username: "app"}, want: [][]string{{"PING"}}},
+		{
+			name:    "authentication failure",
+			cfg:     resp.Config{Password: "wrong"},
+			auth:    resp.Error("WRONGPASS invalid username-password pair"),
+			want:    [][]string{{"AUTH", "wrong"}},
+			wantErr: "WRONGPASS",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := resptest.NewServer(t, func(args []string) any {
+				if args[0] == "AUTH" && tt.auth != nil {
+					return tt.auth
+				}
+				return resptest.Status("OK")
+			})
+			cfg := tt.cfg
+			cfg.Addr = srv.Addr
+			c := newClient(t, cfg)
+
+			_, err := c.Do(context.Background(), "PING")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Do error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if got := srv.Commands(); !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("server received %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	srv := resptest.NewServer(t, func([]string) any {
+		<-release
+		return resptest.Status("OK")
+	})
+	c := newClient(t, resp.Config{Addr: srv.Addr})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Do(ctx, "PING"); err == nil || !strings.Contains(err.Error(), "redis read failed") {
+		t.Errorf("Do error = %v, want a read timeout", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	srv := resptest.NewServer(t, func([]string) any { return resptest.Status("OK") })
+	c := newClient(t, resp.Config{Addr: srv.Addr})
+	if _, err := c.Do(context.Background(), "PING"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+	if _, err := c.Do(context.Background(), "PING"); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("Do after Close error = %v, want a closed client", err)
+	}
+}
+
+func TestDialFailure(t *testing.T) {
+	srv := resptest.NewServer(t, func([]string) any { return nil })
+	addr := srv.Addr
+	srv.Close()
+
+	c := newClient(t, resp.Config{Addr: addr, DialTimeout: time.Second})
+	_, err := c.Do(context.Background(), "PING")
+	if err == nil || !strings.Contains(err.Error(), "failed to connect to redis at "+addr) {
+		t.Errorf("Do error = %v, want a connection failure", err)
+	}
+	if errors.Is(err, resp.ErrNil) {
+		t.Error("connection failure reported as a nil reply")
+	}
+}
@@ -0,0 +1,198 @@
+// Package resptest provides a scriptable RESP2 server for testing the SDK's Redis backends.
+package resptest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/resp"
+)
+
+// Status is a simple string reply, such as "OK".
+type Status string
+
+// Raw is a reply written to the connection verbatim, for sending malformed replies.
+type Raw string
+
+// Hangup closes the connection instead of replying.
+var Hangup = hangup{}
+
+type hangup struct{}
+
+// Handler answers one command. It returns a string for a bulk string, Status, int64, resp.Error,
+// nil for a null bulk string, []any of those for an array, Raw, or Hangup.
+type Handler func(args []string) any
+
+// Server is a RESP2 server on a loopback port answering commands through its Handler. It is safe
+// for concurrent use.
+type Server struct {
+	// Addr is the server's "host:port".
+	Addr string
+
+	ln      net.Listener
+	handler Handler
+	wg      sync.WaitGroup
+
+	mu       sync.Mutex
+	closed   bool
+	conns    []net.Conn
+	commands [][]string
+}
+
+// NewServer starts a Server answering through h, closed when the test ends.
+func NewServer(tb testing.TB, h Handler) *Server {
+	tb.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("resptest: failed to listen: %v", err)
+	}
+	s := &Server{Addr: ln.Addr().String(), ln: ln, handler: h}
+	s.wg.Add(1)
+	go s.serve()
+	tb.Cleanup(s.Close)
+
+	return s
+}
+
+// Conns returns the number of connections accepted so far.
+func (s *Server) Conns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.conns)
+}
+
+// Commands returns the commands received so far, in order.
+func (s *Server) Commands() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([][]string(nil), s.commands...)
+}
+
+// Close stops the server and closes its connections.
+func (s *Server) Close() {
+	_ = s.ln.Close()
+	s.mu.Lock()
+	s.closed = true
+	for _, c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = c.Close()
+			return
+		}
+		s.conns = append(s.conns, c)
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() { _ = c.Close() }()
+			s.serveConn(c)
+		}()
+	}
+}
+
+func (s *Server) serveConn(c net.Conn) {
+	r, w := bufio.NewReader(c), bufio.NewWriter(c)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, args)
+		s.mu.Unlock()
+
+		reply := s.handler(args)
+		if reply == Hangup {
+			return
+		}
+		writeReply(w, reply)
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 4 || line[0] != '*' {
+		return nil, fmt.Errorf("resptest: malformed command %q", line)
+	}
+	n, err := strconv.Atoi(line[1 : len(line)-2])
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if len(line) < 4 || line[0] != '$' {
+			return nil, errors.New("resptest: expected a bulk string")
+		}
+		size, err := strconv.Atoi(line[1 : len(line)-2])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+
+	return args, nil
+}
+
+func writeReply(w *bufio.Writer, reply any) {
+	switch v := reply.(type) {
+	case nil:
+		_, _ = w.WriteString("$-1\r\n")
+	case Raw:
+		_, _ = w.WriteString(string(v))
+	case Status:
+		_, _ = fmt.Fprintf(w, "+%s\r\n", v)
+	case resp.Error:
+		_, _ = fmt.Fprintf(w, "-%s\r\n", string(v))
+	case int64:
+		_, _ = fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		_, _ = fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []any:
+		_, _ = fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, e := range v {
+			writeReply(w, e)
+		}
+	default:
+		panic(fmt.Sprintf("resptest: unsupported reply %T", reply))
+	}
+}
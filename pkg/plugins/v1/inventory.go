@@ -0,0 +1,58 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/inventory"
+)
+
+// InventoryMetadataKey is the gRPC response header on GetMetadata that carries the plugin's
+// dependency and license inventory, for plugins implementing InventoryProvider.
+const InventoryMetadataKey = "mcpd-plugin-inventory-bin"
+
+// InventoryProvider is implemented by plugins exposing the inventory of the Go modules they are
+// built from and their licenses, generated at build time with the inventory package. Serve
+// returns it in the InventoryMetadataKey header of GetMetadata, so security teams can audit the
+// plugin fleet through mcpd.
+//
+// Usage:
+//
+//	//go:embed inventory.json
+//	var inv []byte
+//
+//	func (p *MyPlugin) Inventory() []byte {
+//	    return inv
+//	}
+type InventoryProvider interface {
+	Inventory() []byte
+}
+
+// inventoryInterceptor serves the inventory declared by impl. It returns nil when impl does not
+// implement InventoryProvider.
+func inventoryInterceptor(impl PluginServer) (grpc.UnaryServerInterceptor, error) {
+	provider, ok := impl.(InventoryProvider)
+	if !ok {
+		return nil, nil
+	}
+
+	inv, err := inventory.Decode(provider.Inventory())
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugin inventory: %w", err)
+	}
+	encoded, err := inv.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugin inventory: %w", err)
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if info.FullMethod == Plugin_GetMetadata_FullMethodName {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(InventoryMetadataKey, string(encoded)))
+		}
+
+		return handler(ctx, req)
+	}, nil
+}
@@ -0,0 +1,183 @@
+// Package inventory generates and reads a plugin's dependency and license inventory: the Go
+// modules linked into the binary with the SPDX identifiers of their licenses, so security teams
+// can audit a plugin fleet from the plugins themselves.
+//
+// License files are read from the module cache, which is not available at runtime, so the
+// inventory is generated at build time, typically by a small program run with go generate,
+// and embedded:
+//
+//	// gen/main.go
+//	func main() {
+//	    inv, err := inventory.Generate(context.Background(), "..")
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    if err := inventory.WriteFile("inventory.json", inv); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+//
+//	// plugin.go
+//	//go:generate go run ./gen
+//	//go:embed inventory.json
+//	var inv []byte
+//
+//	func (p *MyPlugin) Inventory() []byte { return inv }
+//
+// A plugin implementing mcpdpluginsv1.InventoryProvider has Serve return the inventory in the
+// InventoryMetadataKey response header of GetMetadata; hosts read it back with Decode.
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// NoAssertion is the SPDX identifier reported for modules whose license could not be determined.
+const NoAssertion = "NOASSERTION"
+
+// Inventory lists the modules a plugin is built from.
+type Inventory struct {
+	// GoVersion is the toolchain the inventory was generated with, such as "go1.25.1".
+	GoVersion string `json:"goVersion,omitempty"`
+
+	// Main is the plugin's own module.
+	Main Module `json:"main"`
+
+	// Modules are the dependencies linked into the plugin, sorted by path.
+	Modules []Module `json:"modules"`
+}
+
+// Module is a Go module and its licenses.
+type Module struct {
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+
+	// Replace is the replacement module, as "path" or "path version", when go.mod replaces it.
+	Replace string `json:"replace,omitempty"`
+
+	// Licenses are the SPDX identifiers of the module's license files, NoAssertion for files
+	// not recognized, or NoAssertion alone when the module has no license file.
+	Licenses []string `json:"licenses"`
+}
+
+// Licenses returns the distinct license identifiers of the inventory's modules, sorted.
+func (inv Inventory) Licenses() []string {
+	var ids []string
+	for _, m := range append([]Module{inv.Main}, inv.Modules...) {
+		ids = append(ids, m.Licenses...)
+	}
+	slices.Sort(ids)
+
+	return slices.Compact(ids)
+}
+
+// listedPackage is the part of the go list -json output Generate reads.
+type listedPackage struct {
+	Standard bool
+	Module   *listedModule
+}
+
+type listedModule struct {
+	Path    string
+	Version string
+	Main    bool
+	Dir     string
+	Replace *listedModule
+}
+
+// Generate returns the inventory of the packages matching patterns (default "./...") in the
+// module at dir, and their dependencies, as go list resolves them. It runs the go command, so
+// it is meant for build time, with the modules downloaded.
+func Generate(ctx context.Context, dir string, patterns ...string) (Inventory, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	cmd := exec.CommandContext(ctx, "go", append([]string{"list", "-deps", "-json"}, patterns...)...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return Inventory{}, fmt.Errorf("inventory: go list failed: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var inv Inventory
+	seen := map[string]bool{}
+	for dec := json.NewDecoder(bytes.NewReader(out)); ; {
+		var p listedPackage
+		if err := dec.Decode(&p); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return Inventory{}, fmt.Errorf("inventory: invalid go list output: %w", err)
+		}
+		if p.Standard || p.Module == nil || seen[p.Module.Path] {
+			continue
+		}
+		seen[p.Module.Path] = true
+
+		if m := module(p.Module); p.Module.Main {
+			inv.Main = m
+		} else {
+			inv.Modules = append(inv.Modules, m)
+		}
+	}
+	slices.SortFunc(inv.Modules, func(a, b Module) int { return strings.Compare(a.Path, b.Path) })
+
+	goVersion, err := exec.CommandContext(ctx, "go", "env", "GOVERSION").Output()
+	if err == nil {
+		inv.GoVersion = string(bytes.TrimSpace(goVersion))
+	}
+
+	return inv, nil
+}
+
+// module converts a listed module, reading its licenses from the directory go list reports.
+func module(lm *listedModule) Module {
+	m := Module{Path: lm.Path, Version: lm.Version}
+	dir := lm.Dir
+	if r := lm.Replace; r != nil {
+		m.Replace = strings.TrimSpace(r.Path + " " + r.Version)
+		dir = r.Dir
+	}
+	m.Licenses = DetectLicenses(dir)
+
+	return m
+}
+
+// Encode returns the JSON encoding of inv.
+func (inv Inventory) Encode() ([]byte, error) {
+	return json.Marshal(inv)
+}
+
+// Decode parses an inventory, as served in the InventoryMetadataKey header.
+func Decode(data []byte) (Inventory, error) {
+	var inv Inventory
+	if err := json.Unmarshal(data, &inv); err != nil {
+		return Inventory{}, fmt.Errorf("inventory: invalid inventory: %w", err)
+	}
+	if inv.Main.Path == "" {
+		return Inventory{}, fmt.Errorf("inventory: invalid inventory: no main module")
+	}
+
+	return inv, nil
+}
+
+// WriteFile writes inv, indented, to path.
+func WriteFile(path string, inv Inventory) error {
+	data, err := json.MarshalIndent(inv, "", "  ")
+	if err != nil {
+		return fmt.Errorf("inventory: failed to encode: %w", err)
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
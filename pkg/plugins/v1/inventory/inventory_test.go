@@ -0,0 +1,209 @@
+package inventory_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/inventory"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    inventory.Inventory
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: `{"goVersion":"go1.25.1","main":{"path":"example.com/p","licenses":["MIT"]},"modules":[]}`,
+			want: inventory.Inventory{
+				GoVersion: "go1.25.1",
+				Main:      inventory.Module{Path: "example.com/p", Licenses: []string{"MIT"}},
+				Modules:   []inventory.Module{},
+			},
+		},
+		{
+			name: "unknown fields ignored",
+			data: `{"main":{"path":"example.com/p","spdx":"x"},"extra":1}`,
+			want: inventory.Inventory{Main: inventory.Module{Path: "example.com/p"}},
+		},
+		{name: "invalid JSON", data: `{"main":`, wantErr: "invalid inventory"},
+		{name: "empty", data: ``, wantErr: "invalid inventory"},
+		{name: "no main module", data: `{"modules":[{"path":"example.com/dep"}]}`, wantErr: "no main module"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := inventory.Decode([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Decode error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testInventory returns an inventory with a main module and two dependencies.
+func testInventory() inventory.Inventory {
+	return inventory.Inventory{
+		GoVersion: "go1.25.1",
+		Main:      inventory.Module{Path: "example.com/plugin", Licenses: []string{"Apache-2.0"}},
+		Modules: []inventory.Module{
+			{Path: "example.com/a", Version: "v1.0.0", Licenses: []string{"MIT", "Apache-2.0"}},
+			{
+				Path:     "example.com/b",
+				Version:  "v0.1.0",
+				Replace:  "../b",
+				Licenses: []string{inventory.NoAssertion},
+			},
+		},
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	inv := testInventory()
+	data, err := inv.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := inventory.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, inv) {
+		t.Errorf("Decode(Encode()) = %+v, want %+v", got, inv)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.json")
+	if err := inventory.WriteFile(path, testInventory()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), "}\n") || !strings.Contains(string(data), "\n  \"main\"") {
+		t.Errorf("file = %q, want indented JSON ending in a newline", data)
+	}
+	if got, err := inventory.Decode(data); err != nil || !reflect.DeepEqual(got, testInventory()) {
+		t.Errorf("Decode(file) = %+v, %v; want the written inventory", got, err)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing", "inventory.json")
+	if err := inventory.WriteFile(missing, testInventory()); err == nil {
+		t.Error("WriteFile into a missing directory succeeded")
+	}
+}
+
+func TestLicenses(t *testing.T) {
+	tests := []struct {
+		name string
+		inv  inventory.Inventory
+		want []string
+	}{
+		{name: "distinct and sorted", inv: testInventory(), want: []string{"Apache-2.0", "MIT", inventory.NoAssertion}},
+		{
+			name: "main only",
+			inv:  inventory.Inventory{Main: inventory.Module{Licenses: []string{"MIT"}}},
+			want: []string{"MIT"},
+		},
+		{name: "none", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.inv.Licenses(); !slices.Equal(got, tt.want) {
+				t.Errorf("Licenses = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeModule writes a module with the given files to a new directory and returns it.
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestGenerate(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod": "module example.com/plugin\n\ngo 1.21\n\n" +
+			"require example.com/dep v0.0.0\n\nreplace example.com/dep => ./dep\n",
+		"LICENSE":     "SPDX-License-Identifier: Apache-2.0",
+		"main.go":     "package main\n\nimport \"example.com/dep\"\n\nvar _ = dep.X\n",
+		"sub/x.go":    "package sub\n",
+		"dep/go.mod":  "module example.com/dep\n\ngo 1.21\n",
+		"dep/LICENSE": "Permission is hereby granted, free of charge",
+		"dep/dep.go":  "package dep\n\nconst X = 1\n",
+	})
+	t.Setenv("GOFLAGS", "-mod=mod")
+
+	dep := inventory.Module{Path: "example.com/dep", Version: "v0.0.0", Replace: "./dep", Licenses: []string{"MIT"}}
+	tests := []struct {
+		name        string
+		patterns    []string
+		wantModules []inventory.Module
+		wantErr     string
+	}{
+		{name: "default patterns", wantModules: []inventory.Module{dep}},
+		{name: "explicit pattern", patterns: []string{"."}, wantModules: []inventory.Module{dep}},
+		{name: "package without dependencies", patterns: []string{"./sub"}},
+		{name: "unknown package", patterns: []string{"./missing"}, wantErr: "go list failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv, err := inventory.Generate(context.Background(), dir, tt.patterns...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Generate error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := inventory.Module{Path: "example.com/plugin", Licenses: []string{"Apache-2.0"}}
+			if !reflect.DeepEqual(inv.Main, want) {
+				t.Errorf("Main = %+v, want %+v", inv.Main, want)
+			}
+			if !reflect.DeepEqual(inv.Modules, tt.wantModules) {
+				t.Errorf("Modules = %+v, want %+v", inv.Modules, tt.wantModules)
+			}
+			if !strings.HasPrefix(inv.GoVersion, "go") {
+				t.Errorf("GoVersion = %q, want the toolchain version", inv.GoVersion)
+			}
+		})
+	}
+}
+
+func TestGenerateNotAModule(t *testing.T) {
+	if _, err := inventory.Generate(context.Background(), filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Generate in a missing directory succeeded")
+	}
+}
@@ -0,0 +1,114 @@
+package inventory
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxLicenseSize bounds the part of a license file read for detection.
+const maxLicenseSize = 256 << 10
+
+// licenseFilePrefixes are the upper-cased prefixes of license file names.
+var licenseFilePrefixes = []string{"LICENSE", "LICENCE", "COPYING", "UNLICENSE"}
+
+// spdxTag matches an SPDX-License-Identifier line.
+var spdxTag = regexp.MustCompile(
+	`SPDX-License-Identifier:\s*([A-Za-z0-9.+\-]+(?:\s+(?:OR|AND|WITH)\s+[A-Za-z0-9.+\-]+)*)`,
+)
+
+// licenseMatcher recognizes a license by phrases that must all appear in its (normalized) text.
+type licenseMatcher struct {
+	id      string
+	phrases []string
+}
+
+// licenseMatchers are tried in order, more specific licenses first.
+var licenseMatchers = []licenseMatcher{
+	{"AGPL-3.0", []string{"gnu affero general public license", "version 3"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"EPL-2.0", []string{"eclipse public license", "2.0"}},
+	{"CC0-1.0", []string{"cc0 1.0 universal"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+}
+
+// DetectLicenses returns the SPDX identifiers of the license files in the module rooted at dir,
+// sorted: from their SPDX-License-Identifier tag when they have one, and recognized from their
+// text otherwise. Unrecognized files, and a dir without license files, yield NoAssertion.
+func DetectLicenses(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if dir == "" || err != nil {
+		return []string{NoAssertion}
+	}
+
+	var ids []string
+	for _, e := range entries {
+		name := strings.ToUpper(e.Name())
+		if !e.Type().IsRegular() || !slices.ContainsFunc(licenseFilePrefixes, func(p string) bool {
+			return strings.HasPrefix(name, p)
+		}) {
+			continue
+		}
+		text, err := readLicense(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		ids = append(ids, Identify(text))
+	}
+	if len(ids) == 0 {
+		return []string{NoAssertion}
+	}
+	slices.Sort(ids)
+
+	return slices.Compact(ids)
+}
+
+// Identify returns the SPDX identifier of a license text, or NoAssertion.
+func Identify(text []byte) string {
+	if m := spdxTag.FindSubmatch(text); m != nil {
+		return string(m[1])
+	}
+
+	normalized := normalize(text)
+	for _, lm := range licenseMatchers {
+		if !slices.ContainsFunc(lm.phrases, func(p string) bool { return !strings.Contains(normalized, p) }) {
+			return lm.id
+		}
+	}
+
+	return NoAssertion
+}
+
+// readLicense reads the start of a license file.
+func readLicense(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	return io.ReadAll(io.LimitReader(f, maxLicenseSize))
+}
+
+// normalize lower-cases text and collapses white space and comment markers, so phrases match
+// however the file is wrapped or commented.
+func normalize(text []byte) string {
+	fields := bytes.FieldsFunc(bytes.ToLower(text), func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '*' || r == '#'
+	})
+
+	return string(bytes.Join(fields, []byte(" ")))
+}
@@ -0,0 +1,134 @@
+package inventory_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/inventory"
+)
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "SPDX tag", text: "// SPDX-License-Identifier: MIT\n", want: "MIT"},
+		{name: "SPDX expression", text: "SPDX-License-Identifier: Apache-2.0 OR MIT", want: "Apache-2.0 OR MIT"},
+		{
+			name: "SPDX tag wins over the text",
+			text: "SPDX-License-Identifier: BSD-3-Clause\nApache License Version 2.0",
+			want: "BSD-3-Clause",
+		},
+		{name: "MIT", text: "Permission is hereby granted, free of charge, to any person", want: "MIT"},
+		{name: "MIT rewrapped", text: "Permission is hereby\n   granted,  free of\r\ncharge", want: "MIT"},
+		{name: "commented", text: "# Permission is hereby granted,\n * free of charge", want: "MIT"},
+		{
+			name: "Apache",
+			text: "                Apache License\n          Version 2.0, January 2004",
+			want: "Apache-2.0",
+		},
+		{
+			name: "BSD-3-Clause",
+			text: "Redistribution and use in source and binary forms ... Neither the name of",
+			want: "BSD-3-Clause",
+		},
+		{
+			name: "BSD-2-Clause",
+			text: "Redistribution and use in source and binary forms, with or without",
+			want: "BSD-2-Clause",
+		},
+		{
+			name: "ISC",
+			text: "Permission to use, copy, modify, and/or distribute this software for any purpose",
+			want: "ISC",
+		},
+		{name: "MPL", text: "Mozilla Public License Version 2.0", want: "MPL-2.0"},
+		{name: "GPL-2.0", text: "GNU GENERAL PUBLIC LICENSE\nVersion 2, June 1991", want: "GPL-2.0"},
+		{name: "GPL-3.0", text: "GNU GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007", want: "GPL-3.0"},
+		{name: "LGPL-2.1", text: "GNU LESSER GENERAL PUBLIC LICENSE\nVersion 2.1, February 1999", want: "LGPL-2.1"},
+		{name: "LGPL-3.0", text: "GNU LESSER GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007", want: "LGPL-3.0"},
+		{name: "AGPL-3.0", text: "GNU AFFERO GENERAL PUBLIC LICENSE\nVersion 3, 19 November 2007", want: "AGPL-3.0"},
+		{name: "EPL-2.0", text: "Eclipse Public License - v 2.0", want: "EPL-2.0"},
+		{name: "CC0", text: "Creative Commons Legal Code\n\nCC0 1.0 Universal", want: "CC0-1.0"},
+		{
+			name: "Unlicense",
+			text: "This is free and unencumbered software released into the public domain.",
+			want: "Unlicense",
+		},
+		{name: "unknown", text: "All rights reserved.", want: inventory.NoAssertion},
+		{name: "empty", want: inventory.NoAssertion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inventory.Identify([]byte(tt.text)); got != tt.want {
+				t.Errorf("Identify = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLicenses(t *testing.T) {
+	const mit = "Permission is hereby granted, free of charge"
+	tests := []struct {
+		name  string
+		files map[string]string // A trailing slash makes a directory.
+		want  []string
+	}{
+		{name: "single license", files: map[string]string{"LICENSE": mit}, want: []string{"MIT"}},
+		{name: "lower-case name", files: map[string]string{"license.md": mit}, want: []string{"MIT"}},
+		{
+			name: "several licenses",
+			files: map[string]string{
+				"LICENSE-MIT":    mit,
+				"LICENSE-APACHE": "Apache License Version 2.0",
+				"COPYING":        mit,
+			},
+			want: []string{"Apache-2.0", "MIT"},
+		},
+		{name: "british spelling", files: map[string]string{"LICENCE.txt": mit}, want: []string{"MIT"}},
+		{
+			name:  "unrecognized",
+			files: map[string]string{"LICENSE": "All rights reserved."},
+			want:  []string{inventory.NoAssertion},
+		},
+		{name: "no license file", files: map[string]string{"README.md": mit}, want: []string{inventory.NoAssertion}},
+		{
+			name:  "license directory ignored",
+			files: map[string]string{"LICENSES/": ""},
+			want:  []string{inventory.NoAssertion},
+		},
+		{name: "empty module", want: []string{inventory.NoAssertion}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				var err error
+				if strings.HasSuffix(name, "/") {
+					err = os.Mkdir(path, 0o755)
+				} else {
+					err = os.WriteFile(path, []byte(content), 0o600)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := inventory.DetectLicenses(dir); !slices.Equal(got, tt.want) {
+				t.Errorf("DetectLicenses = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLicensesNoDir(t *testing.T) {
+	for _, dir := range []string{"", filepath.Join(t.TempDir(), "missing")} {
+		if got := inventory.DetectLicenses(dir); !slices.Equal(got, []string{inventory.NoAssertion}) {
+			t.Errorf("DetectLicenses(%q) = %v, want NoAssertion", dir, got)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/inventory"
+)
+
+// inventoriedPlugin serves inv as its InventoryProvider inventory.
+type inventoriedPlugin struct {
+	BasePlugin
+
+	inv []byte
+}
+
+func (p *inventoriedPlugin) Inventory() []byte {
+	return p.inv
+}
+
+const testInventory = `{
+	"main": {"path": "example.com/plugin", "licenses": ["Apache-2.0"]},
+	"modules": [{"path": "example.com/dep", "version": "v1.0.0", "licenses": ["MIT"]}]
+}`
+
+func TestInventoryInterceptorSetup(t *testing.T) {
+	tests := []struct {
+		name    string
+		impl    PluginServer
+		wantNil bool
+		wantErr string
+	}{
+		{name: "no provider", impl: &BasePlugin{}, wantNil: true},
+		{name: "valid inventory", impl: &inventoriedPlugin{inv: []byte(testInventory)}},
+		{name: "invalid JSON", impl: &inventoriedPlugin{inv: []byte("{")}, wantErr: "invalid plugin inventory"},
+		{name: "empty inventory", impl: &inventoriedPlugin{}, wantErr: "invalid plugin inventory"},
+		{
+			name:    "no main module",
+			impl:    &inventoriedPlugin{inv: []byte(`{"modules": []}`)},
+			wantErr: "no main module",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ii, err := inventoryInterceptor(tt.impl)
+			o, oerr := newServeOptions()
+			if oerr != nil {
+				t.Fatal(oerr)
+			}
+			// The inventory is checked when the chain is built, failing Serve early.
+			_, chainErr := o.unaryInterceptors(tt.impl, nil, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if chainErr == nil {
+					t.Error("unaryInterceptors accepted the invalid inventory")
+				}
+				return
+			}
+			if err != nil || chainErr != nil {
+				t.Fatalf("errors = %v, %v; want none", err, chainErr)
+			}
+			if (ii == nil) != tt.wantNil {
+				t.Errorf("interceptor nil = %t, want %t", ii == nil, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestInventoryHeader(t *testing.T) {
+	impl := &inventoriedPlugin{inv: []byte(testInventory)}
+	ii, err := inventoryInterceptor(impl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address := filepath.Join(t.TempDir(), "plugin.sock")
+	lis, err := net.Listen("unix", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.UnaryInterceptor(ii))
+	RegisterPluginServer(srv, impl)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("unix://"+address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	client := NewPluginClient(conn)
+
+	var md metadata.MD
+	if _, err := client.GetMetadata(context.Background(), &emptypb.Empty{}, grpc.Header(&md)); err != nil {
+		t.Fatal(err)
+	}
+	values := md.Get(InventoryMetadataKey)
+	if len(values) != 1 {
+		t.Fatalf("GetMetadata header %s = %q, want one inventory", InventoryMetadataKey, values)
+	}
+	inv, err := inventory.Decode([]byte(values[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := inv.Licenses(), []string{"Apache-2.0", "MIT"}; inv.Main.Path != "example.com/plugin" ||
+		!slices.Equal(got, want) {
+		t.Errorf("inventory = %+v with licenses %v, want the plugin's with %v", inv, got, want)
+	}
+
+	// Other methods carry no inventory.
+	md = nil
+	if _, err := client.CheckHealth(context.Background(), &emptypb.Empty{}, grpc.Header(&md)); err != nil {
+		t.Fatal(err)
+	}
+	if values := md.Get(InventoryMetadataKey); len(values) != 0 {
+		t.Errorf("CheckHealth header %s = %q, want none", InventoryMetadataKey, values)
+	}
+}
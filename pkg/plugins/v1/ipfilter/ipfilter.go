@@ -0,0 +1,257 @@
+// Package ipfilter allows or denies requests by client IP address, matched against CIDR lists,
+// answering denied requests with 403 before they reach the upstream.
+//
+// The client IP is taken from the ClientIPMetadataKey metadata when mcpd sends it, and otherwise
+// from the request's remote address. When the remote address is a trusted proxy, the client IP
+// header (X-Forwarded-For by default) is walked from the right, skipping trusted proxies, so
+// spoofed entries prepended by clients are ignored.
+//
+// The filter is decoded from custom_config, so a perimeter plugin needs no code beyond serving
+// Plugin:
+//
+//	// custom_config:
+//	//   allow:           10.0.0.0/8,192.168.1.10
+//	//   trusted_proxies: 10.0.0.1/32
+//	if err := mcpdpluginsv1.Serve(ipfilter.NewPlugin()); err != nil {
+//	    log.Fatal(err)
+//	}
+package ipfilter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+)
+
+// ClientIPMetadataKey is the gRPC request metadata key with which mcpd reports the client IP.
+const ClientIPMetadataKey = "mcpd-client-ip"
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// Config is the IP filter, decodable from custom_config with mcpdpluginsv1.DecodeConfig. Entries
+// are CIDR prefixes ("10.0.0.0/8") or single addresses.
+type Config struct {
+	// Allow lists the permitted clients. When empty, every client not denied is allowed.
+	Allow []string `config:"allow"`
+
+	// Deny lists rejected clients. It takes precedence over Allow.
+	Deny []string `config:"deny"`
+
+	// TrustedProxies lists the proxies whose client IP header is believed.
+	TrustedProxies []string `config:"trusted_proxies"`
+
+	// ClientIPHeader is the header carrying the client IP set by trusted proxies, as a
+	// comma-separated list of addresses (X-Forwarded-For) or a single address (X-Real-IP).
+	ClientIPHeader string `config:"client_ip_header" default:"X-Forwarded-For"`
+
+	// DenyTemplateConfig customizes the response to denied clients.
+	mcpdpluginsv1.DenyTemplateConfig
+}
+
+// Filter decides whether clients may call mcpd. It is immutable and safe for concurrent use.
+type Filter struct {
+	allow   []netip.Prefix
+	deny    []netip.Prefix
+	trusted []netip.Prefix
+	header  string
+	denial  *mcpdpluginsv1.DenyTemplate
+}
+
+// New returns a Filter enforcing cfg.
+func New(cfg Config) (*Filter, error) {
+	f := &Filter{header: cfg.ClientIPHeader}
+
+	var err error
+	if f.allow, err = parsePrefixes("allow", cfg.Allow); err != nil {
+		return nil, err
+	}
+	if f.deny, err = parsePrefixes("deny", cfg.Deny); err != nil {
+		return nil, err
+	}
+	if f.denial, err = cfg.Template(); err != nil {
+		return nil, err
+	}
+	if f.trusted, err = parsePrefixes("trusted_proxies", cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// ClientIP returns the client IP of req, or the zero Addr when it cannot be determined.
+func (f *Filter) ClientIP(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) netip.Addr {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(ClientIPMetadataKey); len(v) > 0 {
+			if addr, err := netip.ParseAddr(strings.TrimSpace(v[0])); err == nil {
+				return addr.Unmap()
+			}
+		}
+	}
+
+	addr := parseRemoteAddr(req.GetRemoteAddr())
+	if !addr.IsValid() || !contains(f.trusted, addr) || f.header == "" {
+		return addr
+	}
+
+	hops := strings.Split(mcpdpluginsv1.GetHeader(req.GetHeaders(), f.header), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+		if !contains(f.trusted, addr) {
+			break
+		}
+	}
+
+	return addr
+}
+
+// Allowed reports whether addr may call mcpd. An unknown (zero) address is only allowed when no
+// allow list is configured.
+func (f *Filter) Allowed(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return len(f.allow) == 0
+	}
+	if contains(f.deny, addr) {
+		return false
+	}
+
+	return len(f.allow) == 0 || contains(f.allow, addr)
+}
+
+// HandleRequest lets the chain continue for allowed clients and short-circuits with 403 and a
+// JSON-RPC error otherwise.
+func (f *Filter) HandleRequest(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) *mcpdpluginsv1.HTTPResponse {
+	if f.Allowed(f.ClientIP(ctx, req)) {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+
+	return f.denial.Deny(ctx, req, mcpdpluginsv1.Denial{
+		Reason: "client address not allowed",
+		Status: http.StatusForbidden,
+	})
+}
+
+func parsePrefixes(key string, entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		if e == "" {
+			continue
+		}
+		if strings.Contains(e, "/") {
+			p, err := netip.ParsePrefix(e)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid CIDR %q: %w", key, e, err)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(e)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid address %q: %w", key, e, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseRemoteAddr parses "host:port" or a bare address.
+func parseRemoteAddr(s string) netip.Addr {
+	host, _, err := net.SplitHostPort(s)
+	if err != nil {
+		host = s
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	return addr.Unmap()
+}
+
+// Plugin is a request-flow plugin enforcing the Filter decoded from its custom_config.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	filter atomic.Pointer[Filter]
+}
+
+// NewPlugin returns a Plugin that allows every client until Configure is called.
+func NewPlugin() *Plugin {
+	p := &Plugin{}
+	var cfg Config
+	if _, err := config.Decode(nil, &cfg); err != nil {
+		panic(fmt.Sprintf("ipfilter: invalid defaults: %v", err))
+	}
+	f, err := New(cfg)
+	if err != nil {
+		panic(fmt.Sprintf("ipfilter: invalid defaults: %v", err))
+	}
+	p.filter.Store(f)
+
+	return p
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "ip-filter",
+		Version:     pluginVersion,
+		Description: "Allows or denies requests by client IP address.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest), nil
+}
+
+// Configure decodes the filter from cfg's custom_config.
+func (p *Plugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	var c Config
+	if err := mcpdpluginsv1.DecodeConfig(ctx, cfg, &c); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	f, err := New(c)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	p.filter.Store(f)
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleRequest applies the configured filter.
+func (p *Plugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.filter.Load().HandleRequest(ctx, req), nil
+}
@@ -0,0 +1,221 @@
+package ipfilter_test
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/ipfilter"
+)
+
+func newFilter(t *testing.T, cfg ipfilter.Config) *ipfilter.Filter {
+	t.Helper()
+
+	if cfg.ClientIPHeader == "" {
+		cfg.ClientIPHeader = "X-Forwarded-For"
+	}
+	f, err := ipfilter.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ipfilter.Config
+		want string
+	}{
+		{"invalid allow CIDR", ipfilter.Config{Allow: []string{"10.0.0.0/33"}}, `allow: invalid CIDR "10.0.0.0/33"`},
+		{"invalid deny address", ipfilter.Config{Deny: []string{"10.0.0"}}, `deny: invalid address "10.0.0"`},
+		{
+			"invalid trusted proxy",
+			ipfilter.Config{TrustedProxies: []string{"proxy.internal"}},
+			`trusted_proxies: invalid address "proxy.internal"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ipfilter.New(tt.cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := ipfilter.Config{TrustedProxies: []string{"10.0.0.0/24", "::1"}}
+	tests := []struct {
+		name     string
+		cfg      ipfilter.Config
+		metadata string
+		remote   string
+		headers  map[string]string
+		want     string // Empty for the zero Addr.
+	}{
+		{name: "remote address", remote: "203.0.113.7:51234", want: "203.0.113.7"},
+		{name: "bare remote address", remote: "203.0.113.7", want: "203.0.113.7"},
+		{name: "IPv6 remote address", remote: "[2001:db8::1]:443", want: "2001:db8::1"},
+		{name: "mapped remote address", remote: "[::ffff:203.0.113.7]:443", want: "203.0.113.7"},
+		{name: "unparseable remote address", remote: "unix:@", want: ""},
+		{name: "metadata wins", metadata: " 198.51.100.1 ", remote: "203.0.113.7:1", want: "198.51.100.1"},
+		{name: "invalid metadata ignored", metadata: "nope", remote: "203.0.113.7:1", want: "203.0.113.7"},
+		{
+			name:    "header ignored from untrusted peers",
+			remote:  "203.0.113.7:1",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "header from a trusted proxy",
+			cfg:     trusted,
+			remote:  "10.0.0.1:1",
+			headers: map[string]string{"x-forwarded-for": "198.51.100.1"},
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "spoofed entries skipped",
+			cfg:     trusted,
+			remote:  "10.0.0.1:1",
+			headers: map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1, 10.0.0.2"},
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "every hop trusted",
+			cfg:     trusted,
+			remote:  "[::1]:1",
+			headers: map[string]string{"X-Forwarded-For": "10.0.0.3,10.0.0.2"},
+			want:    "10.0.0.3",
+		},
+		{
+			name:   "trusted proxy without header",
+			cfg:    trusted,
+			remote: "10.0.0.1:1",
+			want:   "10.0.0.1",
+		},
+		{
+			name: "custom header",
+			cfg: ipfilter.Config{
+				TrustedProxies: []string{"10.0.0.1"},
+				ClientIPHeader: "X-Real-IP",
+			},
+			remote: "10.0.0.1:1",
+			headers: map[string]string{
+				"X-Real-IP":       "198.51.100.9",
+				"X-Forwarded-For": "1.2.3.4",
+			},
+			want: "198.51.100.9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.metadata != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(ipfilter.ClientIPMetadataKey, tt.metadata))
+			}
+			req := &mcpdpluginsv1.HTTPRequest{RemoteAddr: tt.remote, Headers: tt.headers}
+
+			got := newFilter(t, tt.cfg).ClientIP(ctx, req)
+			want := netip.Addr{}
+			if tt.want != "" {
+				want = netip.MustParseAddr(tt.want)
+			}
+			if got != want {
+				t.Errorf("ClientIP = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	allowOnly := func(entries ...string) ipfilter.Config { return ipfilter.Config{Allow: entries} }
+	tests := []struct {
+		name string
+		cfg  ipfilter.Config
+		addr string // Empty for the zero Addr.
+		want bool
+	}{
+		{name: "no lists", addr: "203.0.113.7", want: true},
+		{name: "unknown address without allow list", want: true},
+		{name: "unknown address with allow list", cfg: ipfilter.Config{Allow: []string{"10.0.0.0/8"}}},
+		{name: "in allow list", cfg: ipfilter.Config{Allow: []string{"10.0.0.0/8"}}, addr: "10.1.2.3", want: true},
+		{name: "outside allow list", cfg: ipfilter.Config{Allow: []string{"10.0.0.0/8"}}, addr: "11.0.0.1"},
+		{name: "unmasked CIDR", cfg: ipfilter.Config{Allow: []string{"10.1.2.3/8"}}, addr: "10.9.9.9", want: true},
+		{name: "single address", cfg: allowOnly("192.168.1.10"), addr: "192.168.1.10", want: true},
+		{name: "mapped entry", cfg: allowOnly("::ffff:192.168.1.10"), addr: "192.168.1.10", want: true},
+		{name: "IPv6 prefix", cfg: ipfilter.Config{Allow: []string{"2001:db8::/32"}}, addr: "2001:db8::5", want: true},
+		{
+			name: "deny overrides allow",
+			cfg:  ipfilter.Config{Allow: []string{"10.0.0.0/8"}, Deny: []string{"10.0.0.0/16"}},
+			addr: "10.0.5.5",
+		},
+		{name: "denied", cfg: ipfilter.Config{Deny: []string{"203.0.113.0/24"}}, addr: "203.0.113.7"},
+		{name: "empty entries ignored", cfg: ipfilter.Config{Allow: []string{""}}, addr: "203.0.113.7", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var addr netip.Addr
+			if tt.addr != "" {
+				addr = netip.MustParseAddr(tt.addr)
+			}
+			if got := newFilter(t, tt.cfg).Allowed(addr); got != tt.want {
+				t.Errorf("Allowed(%v) = %v, want %v", addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	allowed := &mcpdpluginsv1.HTTPRequest{Method: "POST", RemoteAddr: "10.0.0.5:1"}
+	denied := &mcpdpluginsv1.HTTPRequest{Method: "POST", RemoteAddr: "203.0.113.7:1"}
+	tests := []struct {
+		name        string
+		config      map[string]string
+		wantCode    codes.Code
+		wantDenied  bool // Whether the request from 203.0.113.7 is denied after Configure.
+		wantAllowed bool // Whether the request from 10.0.0.5 continues after Configure.
+	}{
+		{name: "allow list", config: map[string]string{"allow": "10.0.0.0/8"}, wantDenied: true, wantAllowed: true},
+		{name: "deny list", config: map[string]string{"deny": "10.0.0.0/8"}, wantAllowed: false},
+		{name: "invalid CIDR", config: map[string]string{"allow": "10.0.0.0/40"}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := ipfilter.NewPlugin()
+			if resp, _ := p.HandleRequest(context.Background(), denied); !resp.GetContinue() {
+				t.Fatalf("unconfigured plugin denied %v", denied)
+			}
+
+			_, err := p.Configure(context.Background(), &mcpdpluginsv1.PluginConfig{CustomConfig: tt.config})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("Configure error = %v, want %s", err, tt.wantCode)
+			}
+			if tt.wantCode != codes.OK {
+				// The previous filter stays in force.
+				tt.wantAllowed = true
+			}
+
+			resp, err := p.HandleRequest(context.Background(), denied)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := !resp.GetContinue(); got != tt.wantDenied {
+				t.Errorf("203.0.113.7 denied = %v, want %v", got, tt.wantDenied)
+			}
+			if tt.wantDenied && resp.GetStatusCode() != 403 {
+				t.Errorf("denial status = %d, want 403", resp.GetStatusCode())
+			}
+			if resp, _ := p.HandleRequest(context.Background(), allowed); resp.GetContinue() != tt.wantAllowed {
+				t.Errorf("10.0.0.5 continues = %v, want %v", resp.GetContinue(), tt.wantAllowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,569 @@
+// Package jsonpatch applies RFC 6902 JSON Patch and RFC 7386 JSON Merge Patch documents to
+// request and response bodies, for plugins that make surgical payload edits.
+//
+// Patches apply atomically: when any operation fails the original body is kept. Numbers are
+// decoded as json.Number, so values the patch does not touch keep their exact representation
+// (large integer IDs do not round-trip through float64), and HTML characters are not escaped
+// on output. Object keys are re-encoded in sorted order.
+//
+//	body, err := jsonpatch.Apply(req.GetBody(), []byte(`[
+//	    {"op": "test", "path": "/method", "value": "tools/call"},
+//	    {"op": "add", "path": "/params/arguments/limit", "value": 10}
+//	]`))
+//
+// Documents and patches larger than the configured limits are rejected with ErrTooLarge, as are
+// patches growing a document past its limit, which fail as soon as an operation does.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Default limits.
+const (
+	DefaultMaxDocumentSize = 8 << 20
+	DefaultMaxPatchSize    = 1 << 20
+	DefaultMaxOperations   = 1000
+)
+
+// ErrTooLarge is returned when a document or patch exceeds its limit.
+var ErrTooLarge = errors.New("jsonpatch: size limit exceeded")
+
+// ErrTestFailed is returned when a test operation does not match.
+var ErrTestFailed = errors.New("jsonpatch: test operation failed")
+
+// Operation names.
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+	OpMove    = "move"
+	OpCopy    = "copy"
+	OpTest    = "test"
+)
+
+// Operation is one RFC 6902 operation.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Patch is an RFC 6902 JSON Patch document.
+type Patch []Operation
+
+// Option configures limits.
+type Option func(*limits)
+
+type limits struct {
+	maxDocument   int
+	maxPatch      int
+	maxOperations int
+}
+
+// WithMaxDocumentSize limits input and output documents to n bytes (default 8 MiB).
+func WithMaxDocumentSize(n int) Option {
+	return func(l *limits) {
+		l.maxDocument = n
+	}
+}
+
+// WithMaxPatchSize limits patch documents to n bytes (default 1 MiB).
+func WithMaxPatchSize(n int) Option {
+	return func(l *limits) {
+		l.maxPatch = n
+	}
+}
+
+// WithMaxOperations limits JSON Patches to n operations (default 1000).
+func WithMaxOperations(n int) Option {
+	return func(l *limits) {
+		l.maxOperations = n
+	}
+}
+
+func newLimits(opts []Option) limits {
+	l := limits{
+		maxDocument:   DefaultMaxDocumentSize,
+		maxPatch:      DefaultMaxPatchSize,
+		maxOperations: DefaultMaxOperations,
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
+
+	return l
+}
+
+// Decode parses and validates a JSON Patch document.
+func Decode(data []byte, opts ...Option) (Patch, error) {
+	l := newLimits(opts)
+	if len(data) > l.maxPatch {
+		return nil, fmt.Errorf("%w: patch is %d bytes, limit %d", ErrTooLarge, len(data), l.maxPatch)
+	}
+
+	var p Patch
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid JSON patch: %w", err)
+	}
+	if len(p) > l.maxOperations {
+		return nil, fmt.Errorf("%w: patch has %d operations, limit %d", ErrTooLarge, len(p), l.maxOperations)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Validate checks that every operation is well formed, without applying it.
+func (p Patch) Validate() error {
+	for i, op := range p {
+		if _, err := op.compile(); err != nil {
+			return fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Apply applies p to doc and returns the patched document.
+func (p Patch) Apply(doc []byte, opts ...Option) ([]byte, error) {
+	l := newLimits(opts)
+	if len(p) > l.maxOperations {
+		return nil, fmt.Errorf("%w: patch has %d operations, limit %d", ErrTooLarge, len(p), l.maxOperations)
+	}
+	v, err := decodeDocument(doc, l)
+	if err != nil {
+		return nil, err
+	}
+
+	// The size is tracked while the operations run, so that copies and repeated adds cannot grow
+	// the decoded document far past the limit before it is encoded.
+	size := sizer{n: len(doc), max: l.maxDocument}
+	for i, op := range p {
+		c, err := op.compile()
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		if v, err = c.apply(v, &size); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return encodeDocument(v, l)
+}
+
+// Apply applies the JSON Patch document patch to doc.
+func Apply(doc, patch []byte, opts ...Option) ([]byte, error) {
+	p, err := Decode(patch, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Apply(doc, opts...)
+}
+
+// MergePatch applies the RFC 7386 JSON Merge Patch document patch to doc. Object members set to
+// null in patch are removed; any other non-object value replaces the target.
+func MergePatch(doc, patch []byte, opts ...Option) ([]byte, error) {
+	l := newLimits(opts)
+	if len(patch) > l.maxPatch {
+		return nil, fmt.Errorf("%w: patch is %d bytes, limit %d", ErrTooLarge, len(patch), l.maxPatch)
+	}
+	mp, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	v, err := decodeDocument(doc, l)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeDocument(merge(v, mp), l)
+}
+
+func merge(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = merge(t[k], v)
+	}
+
+	return t
+}
+
+// compiled is a validated operation.
+type compiled struct {
+	op    string
+	path  pointer
+	from  pointer
+	value any
+}
+
+func (op Operation) compile() (*compiled, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	c := &compiled{op: op.Op, path: path}
+
+	switch op.Op {
+	case OpAdd, OpReplace, OpTest:
+		if op.Value == nil {
+			return nil, fmt.Errorf("%s requires a value", op.Op)
+		}
+		if c.value, err = decode(op.Value); err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+	case OpRemove:
+		if len(path) == 0 {
+			return nil, fmt.Errorf("remove cannot target the whole document")
+		}
+	case OpMove, OpCopy:
+		if c.from, err = parsePointer(op.From); err != nil {
+			return nil, err
+		}
+		if op.Op == OpMove && len(path) > len(c.from) && path.hasPrefix(c.from) {
+			return nil, fmt.Errorf("cannot move %s into its own child %s", c.from, path)
+		}
+	default:
+		return nil, fmt.Errorf("unknown operation %q", op.Op)
+	}
+
+	return c, nil
+}
+
+func (c *compiled) apply(doc any, size *sizer) (any, error) {
+	switch c.op {
+	case OpAdd:
+		size.remove(replaced(doc, c.path))
+		if err := size.add(c.value); err != nil {
+			return nil, err
+		}
+		return add(doc, c.path, c.value)
+	case OpRemove:
+		v, doc, err := remove(doc, c.path)
+		if err == nil {
+			size.remove(v)
+		}
+		return doc, err
+	case OpReplace:
+		old, err := c.path.get(doc)
+		if err != nil {
+			return nil, err
+		}
+		size.remove(old)
+		if err := size.add(c.value); err != nil {
+			return nil, err
+		}
+		if len(c.path) == 0 {
+			return c.value, nil
+		}
+		_, doc, err := remove(doc, c.path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, c.path, c.value)
+	case OpMove:
+		if c.path.String() == c.from.String() {
+			return doc, nil
+		}
+		v, doc, err := remove(doc, c.from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, c.path, v)
+	case OpCopy:
+		v, err := c.from.get(doc)
+		if err != nil {
+			return nil, err
+		}
+		size.remove(replaced(doc, c.path))
+		if err := size.add(v); err != nil {
+			return nil, err
+		}
+		return add(doc, c.path, deepCopy(v))
+	default:
+		v, err := c.path.get(doc)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(v, c.value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	}
+}
+
+// replaced returns the value an add at p replaces: the existing object member, or the whole
+// document for the root. It returns nil when the add inserts a new value.
+func replaced(doc any, p pointer) any {
+	if len(p) == 0 {
+		return doc
+	}
+	parent, last, err := p.container(doc)
+	if err != nil {
+		return nil
+	}
+	if m, ok := parent.(map[string]any); ok {
+		return m[last]
+	}
+
+	return nil
+}
+
+// sizer tracks an estimate of the encoded size of a document being patched.
+type sizer struct {
+	n   int
+	max int
+}
+
+// add accounts for inserting v, failing with ErrTooLarge once the document exceeds the limit.
+func (s *sizer) add(v any) error {
+	s.n += encodedSize(v, s.max-s.n+1)
+	if s.n > s.max {
+		return fmt.Errorf("%w: patched document exceeds %d bytes", ErrTooLarge, s.max)
+	}
+
+	return nil
+}
+
+// remove accounts for deleting v.
+func (s *sizer) remove(v any) {
+	if v == nil {
+		return
+	}
+	s.n = max(s.n-encodedSize(v, s.n), 0)
+}
+
+// encodedSize estimates the encoded size of v, ignoring string escapes. It stops counting once
+// the size exceeds budget, so oversized values are not walked in full.
+func encodedSize(v any, budget int) int {
+	switch c := v.(type) {
+	case map[string]any:
+		n := 2
+		for k, e := range c {
+			if n > budget {
+				break
+			}
+			n += len(k) + 4 + encodedSize(e, budget-n)
+		}
+		return n
+	case []any:
+		n := 2
+		for _, e := range c {
+			if n > budget {
+				break
+			}
+			n += 1 + encodedSize(e, budget-n)
+		}
+		return n
+	case string:
+		return len(c) + 2
+	case json.Number:
+		return len(c)
+	case bool:
+		return 5
+	default:
+		return 4
+	}
+}
+
+// add inserts v at p and returns the (possibly replaced) document.
+func add(doc any, p pointer, v any) (any, error) {
+	if len(p) == 0 {
+		return v, nil
+	}
+	parent, last, err := p.container(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c := parent.(type) {
+	case map[string]any:
+		c[last] = v
+		return doc, nil
+	case []any:
+		i, err := arrayIndex(last, len(c), true)
+		if err != nil {
+			return nil, err
+		}
+		grown := append(c, nil)
+		copy(grown[i+1:], grown[i:])
+		grown[i] = v
+		return replaceChild(doc, p[:len(p)-1], grown)
+	default:
+		return nil, fmt.Errorf("parent of %s is not a container", p)
+	}
+}
+
+// remove deletes the value at p, returning it and the (possibly replaced) document.
+func remove(doc any, p pointer) (any, any, error) {
+	if len(p) == 0 {
+		return doc, nil, nil
+	}
+	parent, last, err := p.container(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch c := parent.(type) {
+	case map[string]any:
+		v, ok := c[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("path %s does not exist", p)
+		}
+		delete(c, last)
+		return v, doc, nil
+	case []any:
+		i, err := arrayIndex(last, len(c), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		v := c[i]
+		shrunk := append(c[:i:i], c[i+1:]...)
+		doc, err := replaceChild(doc, p[:len(p)-1], shrunk)
+		return v, doc, err
+	default:
+		return nil, nil, fmt.Errorf("path %s does not exist", p)
+	}
+}
+
+// replaceChild stores a resized array at p, since slices cannot grow or shrink in place.
+func replaceChild(doc any, p pointer, v any) (any, error) {
+	if len(p) == 0 {
+		return v, nil
+	}
+	parent, last, err := p.container(doc)
+	if err != nil {
+		return nil, err
+	}
+	switch c := parent.(type) {
+	case map[string]any:
+		c[last] = v
+	case []any:
+		i, err := arrayIndex(last, len(c), false)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = v
+	}
+
+	return doc, nil
+}
+
+// equal compares JSON values, treating numbers by value.
+func equal(a, b any) bool {
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		rx, okx := new(big.Rat).SetString(x.String())
+		ry, oky := new(big.Rat).SetString(y.String())
+		return okx && oky && rx.Cmp(ry) == 0
+	default:
+		return a == b
+	}
+}
+
+func deepCopy(v any) any {
+	switch c := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(c))
+		for k, e := range c {
+			out[k] = deepCopy(e)
+		}
+		return out
+	case []any:
+		out := make([]any, len(c))
+		for i, e := range c {
+			out[i] = deepCopy(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func decodeDocument(doc []byte, l limits) (any, error) {
+	if len(doc) > l.maxDocument {
+		return nil, fmt.Errorf("%w: document is %d bytes, limit %d", ErrTooLarge, len(doc), l.maxDocument)
+	}
+	v, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON document: %w", err)
+	}
+
+	return v, nil
+}
+
+func encodeDocument(v any, l limits) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, fmt.Errorf("failed to encode document: %w", err)
+	}
+	out := bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+	if len(out) > l.maxDocument {
+		return nil, fmt.Errorf("%w: patched document is %d bytes, limit %d", ErrTooLarge, len(out), l.maxDocument)
+	}
+
+	return out, nil
+}
+
+// decode parses a single JSON value keeping numbers exact.
+func decode(data []byte) (any, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+
+	return v, nil
+}
@@ -0,0 +1,501 @@
+package jsonpatch_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/jsonpatch"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		// RFC 6902 appendix A.
+		{
+			name:  "add an object member",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz","value":"qux"}]`,
+			want:  `{"baz":"qux","foo":"bar"}`,
+		},
+		{
+			name:  "add an array element",
+			doc:   `{"foo":["bar","baz"]}`,
+			patch: `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			want:  `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			name:  "remove an object member",
+			doc:   `{"baz":"qux","foo":"bar"}`,
+			patch: `[{"op":"remove","path":"/baz"}]`,
+			want:  `{"foo":"bar"}`,
+		},
+		{
+			name:  "remove an array element",
+			doc:   `{"foo":["bar","qux","baz"]}`,
+			patch: `[{"op":"remove","path":"/foo/1"}]`,
+			want:  `{"foo":["bar","baz"]}`,
+		},
+		{
+			name:  "replace",
+			doc:   `{"baz":"qux","foo":"bar"}`,
+			patch: `[{"op":"replace","path":"/baz","value":"boo"}]`,
+			want:  `{"baz":"boo","foo":"bar"}`,
+		},
+		{
+			name:  "move a value",
+			doc:   `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			patch: `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			want:  `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			name:  "move an array element",
+			doc:   `{"foo":["all","grass","cows","eat"]}`,
+			patch: `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			want:  `{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			name:  "test",
+			doc:   `{"baz":"qux","foo":["a",2,"c"]}`,
+			patch: `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			want:  `{"baz":"qux","foo":["a",2,"c"]}`,
+		},
+		{
+			name:  "add a nested member",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			want:  `{"child":{"grandchild":{}},"foo":"bar"}`,
+		},
+		{
+			name:  "add an array value",
+			doc:   `{"foo":["bar"]}`,
+			patch: `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`,
+			want:  `{"foo":["bar",["abc","def"]]}`,
+		},
+		{
+			name:  "escaped keys",
+			doc:   `{"/":9,"~1":10}`,
+			patch: `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`,
+			want:  `{"~1":10}`,
+		},
+
+		{name: "append at the length", doc: `[1]`, patch: `[{"op":"add","path":"/1","value":2}]`, want: `[1,2]`},
+		{name: "replace the root", doc: `{"a":1}`, patch: `[{"op":"replace","path":"","value":[1]}]`, want: `[1]`},
+		{name: "add the root", doc: `{"a":1}`, patch: `[{"op":"add","path":"","value":"x"}]`, want: `"x"`},
+		{
+			name:  "replace an array element",
+			doc:   `[1,2,3]`,
+			patch: `[{"op":"replace","path":"/1","value":9}]`,
+			want:  `[1,9,3]`,
+		},
+		{
+			name:  "copy",
+			doc:   `{"a":{"b":[1]}}`,
+			patch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"add","path":"/c/b/-","value":2}]`,
+			want:  `{"a":{"b":[1]},"c":{"b":[1,2]}}`,
+		},
+		{name: "move onto itself", doc: `{"a":1}`, patch: `[{"op":"move","from":"/a","path":"/a"}]`, want: `{"a":1}`},
+		{
+			name:  "nested arrays",
+			doc:   `{"a":[[1,2],[3]]}`,
+			patch: `[{"op":"remove","path":"/a/0/0"},{"op":"add","path":"/a/1/0","value":0}]`,
+			want:  `{"a":[[2],[0,3]]}`,
+		},
+		{
+			name:  "numbers compared by value",
+			doc:   `{"n":1.0}`,
+			patch: `[{"op":"test","path":"/n","value":1}]`,
+			want:  `{"n":1.0}`,
+		},
+		{
+			name:  "large integers kept exact",
+			doc:   `{"id":12345678901234567890,"a":1}`,
+			patch: `[{"op":"remove","path":"/a"}]`,
+			want:  `{"id":12345678901234567890}`,
+		},
+		{name: "HTML not escaped", doc: `{"a":"<b>&"}`, patch: `[]`, want: `{"a":"<b>&"}`},
+		{
+			name:  "objects compared regardless of order",
+			doc:   `{"o":{"a":1,"b":[true,null]}}`,
+			patch: `[{"op":"test","path":"/o","value":{"b":[true,null],"a":1}}]`,
+			want:  `{"o":{"a":1,"b":[true,null]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jsonpatch.Apply([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Apply = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		is    error
+	}{
+		{
+			name:  "missing member",
+			doc:   `{"foo":"bar"}`,
+			patch: `[{"op":"add","path":"/baz/bat","value":"qux"}]`,
+			want:  "path /baz does not exist",
+		},
+		{
+			name:  "test mismatch",
+			doc:   `{"baz":"qux"}`,
+			patch: `[{"op":"test","path":"/baz","value":"bar"}]`,
+			is:    jsonpatch.ErrTestFailed,
+		},
+		{
+			name:  "test type mismatch",
+			doc:   `{"n":1}`,
+			patch: `[{"op":"test","path":"/n","value":"1"}]`,
+			is:    jsonpatch.ErrTestFailed,
+		},
+		{
+			name:  "test array length",
+			doc:   `{"a":[1]}`,
+			patch: `[{"op":"test","path":"/a","value":[1,2]}]`,
+			is:    jsonpatch.ErrTestFailed,
+		},
+		{
+			name:  "test object members",
+			doc:   `{"o":{"a":1}}`,
+			patch: `[{"op":"test","path":"/o","value":{"b":1}}]`,
+			is:    jsonpatch.ErrTestFailed,
+		},
+		{name: "remove missing", doc: `{}`, patch: `[{"op":"remove","path":"/a"}]`, want: "path /a does not exist"},
+		{
+			name:  "replace missing",
+			doc:   `{}`,
+			patch: `[{"op":"replace","path":"/a","value":1}]`,
+			want:  "path /a does not exist",
+		},
+		{
+			name:  "array index out of range",
+			doc:   `[1]`,
+			patch: `[{"op":"add","path":"/2","value":2}]`,
+			want:  "array index 2 out of range",
+		},
+		{
+			name:  "remove past the end",
+			doc:   `[1]`,
+			patch: `[{"op":"remove","path":"/1"}]`,
+			want:  "array index 1 out of range",
+		},
+		{name: "remove dash", doc: `[1]`, patch: `[{"op":"remove","path":"/-"}]`, want: `invalid array index "-"`},
+		{
+			name:  "leading zero",
+			doc:   `[1,2]`,
+			patch: `[{"op":"replace","path":"/01","value":2}]`,
+			want:  `invalid array index "01"`,
+		},
+		{
+			name:  "negative index",
+			doc:   `[1,2]`,
+			patch: `[{"op":"add","path":"/-1","value":2}]`,
+			want:  `invalid array index "-1"`,
+		},
+		{
+			name:  "non-numeric index",
+			doc:   `{"a":[1]}`,
+			patch: `[{"op":"test","path":"/a/x","value":1}]`,
+			want:  `path /a/x: invalid array index "x"`,
+		},
+		{
+			name:  "through a scalar",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"add","path":"/a/b","value":1}]`,
+			want:  "parent of /a/b is not a container",
+		},
+		{
+			name:  "get through a scalar",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"test","path":"/a/b","value":1}]`,
+			want:  "path /a/b does not exist",
+		},
+		{
+			name:  "copy missing",
+			doc:   `{}`,
+			patch: `[{"op":"copy","from":"/a","path":"/b"}]`,
+			want:  "path /a does not exist",
+		},
+		{
+			name:  "move missing",
+			doc:   `{}`,
+			patch: `[{"op":"move","from":"/a","path":"/b"}]`,
+			want:  "path /a does not exist",
+		},
+		{
+			name:  "operation in context",
+			doc:   `{}`,
+			patch: `[{"op":"test","path":"","value":{}},{"op":"remove","path":"/x"}]`,
+			want:  "operation 1 (remove /x): ",
+		},
+		{name: "invalid document", doc: `{"a":`, patch: `[]`, want: "invalid JSON document"},
+		{name: "trailing document data", doc: `{} 1`, patch: `[]`, want: "unexpected data after JSON value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jsonpatch.Apply([]byte(tt.doc), []byte(tt.patch))
+			if err == nil {
+				t.Fatalf("Apply = %s, want an error", got)
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("Apply error = %v, want %v", err, tt.is)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Apply error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyIsAtomic(t *testing.T) {
+	p, err := jsonpatch.Decode([]byte(`[{"op":"add","path":"/a/-","value":3},{"op":"test","path":"/a/0","value":0}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := []byte(`{"a":[1,2]}`)
+	if _, err := p.Apply(doc); !errors.Is(err, jsonpatch.ErrTestFailed) {
+		t.Fatalf("Apply error = %v, want ErrTestFailed", err)
+	}
+	if string(doc) != `{"a":[1,2]}` {
+		t.Errorf("document changed to %s after a failed patch", doc)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{name: "not JSON", patch: `[`, want: "invalid JSON patch"},
+		{name: "not an array", patch: `{"op":"add"}`, want: "invalid JSON patch"},
+		{name: "unknown op", patch: `[{"op":"merge","path":"/a"}]`, want: `operation 0: unknown operation "merge"`},
+		{name: "add without a value", patch: `[{"op":"add","path":"/a"}]`, want: "add requires a value"},
+		{name: "replace without a value", patch: `[{"op":"replace","path":"/a"}]`, want: "replace requires a value"},
+		{name: "test without a value", patch: `[{"op":"test","path":"/a"}]`, want: "test requires a value"},
+		{
+			name:  "remove the root",
+			patch: `[{"op":"remove","path":""}]`,
+			want:  "remove cannot target the whole document",
+		},
+		{name: "relative pointer", patch: `[{"op":"remove","path":"a"}]`, want: "must be empty or start with /"},
+		{name: "bad escape", patch: `[{"op":"remove","path":"/a~2"}]`, want: "bad escape"},
+		{name: "trailing tilde", patch: `[{"op":"remove","path":"/a~"}]`, want: "bad escape"},
+		{name: "bad from", patch: `[{"op":"copy","from":"a","path":"/b"}]`, want: "must be empty or start with /"},
+		{
+			name:  "move into a child",
+			patch: `[{"op":"move","from":"/a","path":"/a/b"}]`,
+			want:  "cannot move /a into its own child /a/b",
+		},
+		{
+			name:  "later operation",
+			patch: `[{"op":"remove","path":"/a"},{"op":"copy","from":"b","path":"/b"}]`,
+			want:  "operation 1: ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := jsonpatch.Decode([]byte(tt.patch)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Decode error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoveToSibling(t *testing.T) {
+	// A path sharing the from pointer's prefix without being its child is a valid move target.
+	got, err := jsonpatch.Apply([]byte(`{"a":1}`), []byte(`[{"op":"move","from":"/a","path":"/ab"}]`))
+	if err != nil || string(got) != `{"ab":1}` {
+		t.Errorf("Apply = %s, %v; want {\"ab\":1}", got, err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		opts  []jsonpatch.Option
+		want  string
+	}{
+		{
+			name:  "document",
+			doc:   `{"a":"0123456789"}`,
+			patch: `[]`,
+			opts:  []jsonpatch.Option{jsonpatch.WithMaxDocumentSize(10)},
+			want:  "document is 18 bytes, limit 10",
+		},
+		{
+			name:  "patch",
+			doc:   `{}`,
+			patch: `[{"op":"add","path":"/a","value":1}]`,
+			opts:  []jsonpatch.Option{jsonpatch.WithMaxPatchSize(10)},
+			want:  "patch is 36 bytes, limit 10",
+		},
+		{
+			name:  "operations",
+			doc:   `{}`,
+			patch: `[{"op":"add","path":"/a","value":1},{"op":"add","path":"/b","value":2}]`,
+			opts:  []jsonpatch.Option{jsonpatch.WithMaxOperations(1)},
+			want:  "patch has 2 operations, limit 1",
+		},
+		{
+			name:  "added value",
+			doc:   `{}`,
+			patch: `[{"op":"add","path":"/a","value":"0123456789012345678901234567890123456789"}]`,
+			opts:  []jsonpatch.Option{jsonpatch.WithMaxDocumentSize(32)},
+			want:  "patched document exceeds 32 bytes",
+		},
+		{
+			name: "repeated copies",
+			doc:  `{"a":"0123456789"}`,
+			patch: `[{"op":"copy","from":"/a","path":"/b"},{"op":"copy","from":"/a","path":"/c"},` +
+				`{"op":"copy","from":"/a","path":"/d"}]`,
+			opts: []jsonpatch.Option{jsonpatch.WithMaxDocumentSize(50)},
+			want: "operation 2 (copy /d): jsonpatch: size limit exceeded",
+		},
+		{
+			name:  "replacement",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"replace","path":"/a","value":[1,2,3,4,5,6,7,8,9,10,11,12]}]`,
+			opts:  []jsonpatch.Option{jsonpatch.WithMaxDocumentSize(20)},
+			want:  "patched document exceeds 20 bytes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jsonpatch.Apply([]byte(tt.doc), []byte(tt.patch), tt.opts...)
+			if !errors.Is(err, jsonpatch.ErrTooLarge) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Apply = %s, %v; want ErrTooLarge with %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitsAccountForRemovals(t *testing.T) {
+	doc := `{"a":"0123456789012345678901234567890123456789"}`
+	patch := `[{"op":"remove","path":"/a"},` +
+		`{"op":"add","path":"/b","value":"0123456789012345678901234567890123456789"},` +
+		`{"op":"add","path":"/b","value":"01234567890123456789012345678901234567xx"}]`
+	got, err := jsonpatch.Apply([]byte(doc), []byte(patch), jsonpatch.WithMaxDocumentSize(len(doc)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"b":"01234567890123456789012345678901234567xx"}`; string(got) != want {
+		t.Errorf("Apply = %s, want %s", got, want)
+	}
+}
+
+func TestPatchApplyLimits(t *testing.T) {
+	p := jsonpatch.Patch{{Op: jsonpatch.OpRemove, Path: "/a"}, {Op: jsonpatch.OpRemove, Path: "/b"}}
+	_, err := p.Apply([]byte(`{"a":1,"b":2}`), jsonpatch.WithMaxOperations(1))
+	if !errors.Is(err, jsonpatch.ErrTooLarge) {
+		t.Errorf("Apply error = %v, want ErrTooLarge", err)
+	}
+
+	// Operations built in code are validated when applied.
+	p = jsonpatch.Patch{{Op: jsonpatch.OpAdd, Path: "/a"}}
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "operation 0: add requires a value") {
+		t.Errorf("Validate error = %v, want a missing value", err)
+	}
+	if _, err := p.Apply([]byte(`{}`)); err == nil || !strings.Contains(err.Error(), "add requires a value") {
+		t.Errorf("Apply error = %v, want a missing value", err)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// RFC 7386 appendix A, plus null handling inside arrays.
+	tests := []struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		{doc: `{"a":"b"}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{doc: `{"a":"b"}`, patch: `{"b":"c"}`, want: `{"a":"b","b":"c"}`},
+		{doc: `{"a":"b"}`, patch: `{"a":null}`, want: `{}`},
+		{doc: `{"a":"b","b":"c"}`, patch: `{"a":null}`, want: `{"b":"c"}`},
+		{doc: `{"a":["b"]}`, patch: `{"a":"c"}`, want: `{"a":"c"}`},
+		{doc: `{"a":"c"}`, patch: `{"a":["b"]}`, want: `{"a":["b"]}`},
+		{doc: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, want: `{"a":{"b":"d"}}`},
+		{doc: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, want: `{"a":[1]}`},
+		{doc: `["a","b"]`, patch: `["c","d"]`, want: `["c","d"]`},
+		{doc: `{"a":"b"}`, patch: `["c"]`, want: `["c"]`},
+		{doc: `{"a":"foo"}`, patch: `null`, want: `null`},
+		{doc: `{"a":"foo"}`, patch: `"bar"`, want: `"bar"`},
+		{doc: `{"e":null}`, patch: `{"a":1}`, want: `{"a":1,"e":null}`},
+		{doc: `[1,2]`, patch: `{"a":"b","c":null}`, want: `{"a":"b"}`},
+		{doc: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, want: `{"a":{"bb":{}}}`},
+		{doc: `{"id":12345678901234567890}`, patch: `{"x":[null]}`, want: `{"id":12345678901234567890,"x":[null]}`},
+	}
+	for _, tt := range tests {
+		got, err := jsonpatch.MergePatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("MergePatch(%s, %s): %v", tt.doc, tt.patch, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("MergePatch(%s, %s) = %s, want %s", tt.doc, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestMergePatchErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		opts  []jsonpatch.Option
+		want  string
+		is    error
+	}{
+		{name: "invalid patch", doc: `{}`, patch: `{`, want: "invalid merge patch"},
+		{name: "invalid document", doc: `{`, patch: `{}`, want: "invalid JSON document"},
+		{
+			name:  "patch too large",
+			doc:   `{}`,
+			patch: `{"a":1}`,
+			opts:  []jsonpatch.Option{jsonpatch.WithMaxPatchSize(4)},
+			is:    jsonpatch.ErrTooLarge,
+		},
+		{
+			name:  "document too large",
+			doc:   `{"a":1}`,
+			patch: `{}`,
+			opts:  []jsonpatch.Option{jsonpatch.WithMaxDocumentSize(4)},
+			is:    jsonpatch.ErrTooLarge,
+		},
+		{
+			name:  "result too large",
+			doc:   `{}`,
+			patch: `{"a":"0123456789"}`,
+			opts:  []jsonpatch.Option{jsonpatch.WithMaxDocumentSize(10)},
+			want:  "patched document is 18 bytes, limit 10",
+			is:    jsonpatch.ErrTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jsonpatch.MergePatch([]byte(tt.doc), []byte(tt.patch), tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) || (tt.is != nil && !errors.Is(err, tt.is)) {
+				t.Errorf("MergePatch error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package jsonpatch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pointer is a parsed RFC 6901 JSON Pointer; the empty pointer addresses the whole document.
+type pointer []string
+
+func parsePointer(s string) (pointer, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must be empty or start with /", s)
+	}
+
+	tokens := strings.Split(s[1:], "/")
+	for i, t := range tokens {
+		if strings.Contains(strings.ReplaceAll(strings.ReplaceAll(t, "~0", ""), "~1", ""), "~") {
+			return nil, fmt.Errorf("invalid JSON pointer %q: bad escape", s)
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func (p pointer) String() string {
+	var b strings.Builder
+	for _, t := range p {
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(t, "~", "~0"), "/", "~1"))
+	}
+
+	return b.String()
+}
+
+// hasPrefix reports whether q is p or one of its descendants.
+func (p pointer) hasPrefix(q pointer) bool {
+	if len(p) < len(q) {
+		return false
+	}
+	for i := range q {
+		if p[i] != q[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// get returns the value p addresses within doc.
+func (p pointer) get(doc any) (any, error) {
+	v := doc
+	for i, t := range p {
+		switch c := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = c[t]; !ok {
+				return nil, fmt.Errorf("path %s does not exist", p[:i+1])
+			}
+		case []any:
+			n, err := arrayIndex(t, len(c), false)
+			if err != nil {
+				return nil, fmt.Errorf("path %s: %w", p[:i+1], err)
+			}
+			v = c[n]
+		default:
+			return nil, fmt.Errorf("path %s does not exist", p[:i+1])
+		}
+	}
+
+	return v, nil
+}
+
+// container returns the parent of the value p addresses and p's last token.
+func (p pointer) container(doc any) (any, string, error) {
+	parent, err := p[:len(p)-1].get(doc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return parent, p[len(p)-1], nil
+}
+
+// arrayIndex parses an array index token. With insert, "-" and n (both appending) are allowed.
+func arrayIndex(t string, n int, insert bool) (int, error) {
+	if insert && t == "-" {
+		return n, nil
+	}
+	if t == "" || (len(t) > 1 && t[0] == '0') || strings.TrimLeft(t, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid array index %q", t)
+	}
+	i, err := strconv.Atoi(t)
+	if err != nil {
+		return 0, fmt.Errorf("invalid array index %q", t)
+	}
+	if i > n || (!insert && i == n) {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+
+	return i, nil
+}
@@ -0,0 +1,419 @@
+// Package launcher runs plugin binaries from the host side: it execs the plugin with the flags
+// mcpd passes, waits until it reports ready, restarts it with backoff when it exits unexpectedly,
+// and tears it down cleanly. It is useful for mcpd-like hosts and end-to-end tests in plugin repos.
+//
+//	l, err := launcher.New("./bin/my-plugin", launcher.WithOutput(os.Stderr))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if err := l.Start(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer func() { _ = l.Stop(context.Background()) }()
+//
+//	resp, err := l.Client().HandleRequest(ctx, req)
+package launcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// ErrTooManyRestarts is reported by Err when the plugin exceeded the restart limit.
+var ErrTooManyRestarts = errors.New("plugin exceeded restart limit")
+
+// errStopped is returned by launch when Stop was called while the process was starting.
+var errStopped = errors.New("launcher stopped")
+
+// Option configures a Launcher.
+type Option func(*Launcher) error
+
+// WithArgs appends extra command-line arguments after --address and --network.
+func WithArgs(args ...string) Option {
+	return func(l *Launcher) error {
+		l.args = append(l.args, args...)
+		return nil
+	}
+}
+
+// WithEnv sets extra environment variables ("KEY=value") on top of the host's environment.
+func WithEnv(env ...string) Option {
+	return func(l *Launcher) error {
+		l.env = append(l.env, env...)
+		return nil
+	}
+}
+
+// WithOutput sends the plugin's stdout and stderr to w (discarded by default).
+func WithOutput(w io.Writer) Option {
+	return func(l *Launcher) error {
+		if w == nil {
+			return fmt.Errorf("output writer cannot be nil")
+		}
+		l.output = w
+		return nil
+	}
+}
+
+// WithLogger sets the logger used for restart and shutdown messages (defaults to log.Default()).
+func WithLogger(logger *log.Logger) Option {
+	return func(l *Launcher) error {
+		if logger == nil {
+			return fmt.Errorf("logger cannot be nil")
+		}
+		l.logger = logger
+		return nil
+	}
+}
+
+// WithSocketPath sets the unix socket the plugin listens on (default a file in a new temporary directory).
+func WithSocketPath(path string) Option {
+	return func(l *Launcher) error {
+		if path == "" {
+			return fmt.Errorf("socket path cannot be empty")
+		}
+		l.socket = path
+		return nil
+	}
+}
+
+// WithReadyTimeout bounds how long a started plugin may take to pass CheckReady (default 10s).
+func WithReadyTimeout(d time.Duration) Option {
+	return func(l *Launcher) error {
+		if d <= 0 {
+			return fmt.Errorf("ready timeout must be positive")
+		}
+		l.readyTimeout = d
+		return nil
+	}
+}
+
+// WithBackoff sets the delay before the first restart and its cap; the delay doubles after each
+// consecutive failure and resets once the plugin has stayed up for longer than max
+// (defaults 100ms and 30s).
+func WithBackoff(initial, max time.Duration) Option {
+	return func(l *Launcher) error {
+		if initial <= 0 || max < initial {
+			return fmt.Errorf("invalid backoff %s-%s", initial, max)
+		}
+		l.minBackoff, l.maxBackoff = initial, max
+		return nil
+	}
+}
+
+// WithMaxRestarts limits consecutive restarts; once exceeded the launcher gives up and Err reports
+// ErrTooManyRestarts. A negative value (the default) restarts indefinitely; 0 disables restarts.
+func WithMaxRestarts(n int) Option {
+	return func(l *Launcher) error {
+		l.maxRestarts = n
+		return nil
+	}
+}
+
+// WithShutdownTimeout bounds how long Stop waits for the plugin to exit after SIGTERM before
+// killing it (default 5s).
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(l *Launcher) error {
+		if d <= 0 {
+			return fmt.Errorf("shutdown timeout must be positive")
+		}
+		l.shutdownTimeout = d
+		return nil
+	}
+}
+
+// Launcher supervises a single plugin process.
+type Launcher struct {
+	path            string
+	args            []string
+	env             []string
+	output          io.Writer
+	logger          *log.Logger
+	socket          string
+	tempDir         string
+	readyTimeout    time.Duration
+	minBackoff      time.Duration
+	maxBackoff      time.Duration
+	maxRestarts     int
+	shutdownTimeout time.Duration
+
+	conn   *grpc.ClientConn
+	client mcpdpluginsv1.PluginClient
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	exited   chan struct{}
+	stopping bool
+	err      error
+	done     chan struct{}
+}
+
+// New returns a Launcher for the plugin executable at path. The plugin is not started until Start.
+func New(path string, opts ...Option) (*Launcher, error) {
+	l := &Launcher{
+		path:            path,
+		output:          io.Discard,
+		logger:          log.Default(),
+		readyTimeout:    10 * time.Second,
+		minBackoff:      100 * time.Millisecond,
+		maxBackoff:      30 * time.Second,
+		maxRestarts:     -1,
+		shutdownTimeout: 5 * time.Second,
+		done:            make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.socket == "" {
+		dir, err := os.MkdirTemp("", "mcpd-plugin")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create socket directory: %w", err)
+		}
+		l.tempDir = dir
+		l.socket = filepath.Join(dir, "plugin.sock")
+	}
+
+	conn, err := grpc.NewClient("unix://"+l.socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		if l.tempDir != "" {
+			_ = os.RemoveAll(l.tempDir)
+		}
+		return nil, fmt.Errorf("failed to create plugin client: %w", err)
+	}
+	l.conn = conn
+	l.client = mcpdpluginsv1.NewPluginClient(conn)
+
+	return l, nil
+}
+
+// Client returns a client for the plugin. It stays valid across restarts.
+func (l *Launcher) Client() mcpdpluginsv1.PluginClient {
+	return l.client
+}
+
+// Socket returns the unix socket path the plugin listens on.
+func (l *Launcher) Socket() string {
+	return l.socket
+}
+
+// Done is closed when the launcher stops supervising the plugin, after Stop or when the restart
+// limit is exceeded.
+func (l *Launcher) Done() <-chan struct{} {
+	return l.done
+}
+
+// Err returns the reason supervision ended, or nil while the plugin is supervised or after Stop.
+func (l *Launcher) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
+
+// Start launches the plugin, waits until CheckReady succeeds, and then supervises it in the
+// background. If the plugin does not become ready, it is stopped and the error is returned.
+func (l *Launcher) Start(ctx context.Context) error {
+	if err := l.launch(); err != nil {
+		return err
+	}
+	if err := l.waitReady(ctx); err != nil {
+		_ = l.Stop(context.Background())
+		return err
+	}
+
+	go l.supervise()
+
+	return nil
+}
+
+// launch starts a new plugin process. If Stop was called meanwhile, the process is killed and
+// errStopped returned, so Stop never misses a process it has to stop.
+func (l *Launcher) launch() error {
+	_ = os.Remove(l.socket)
+
+	cmd := exec.Command(l.path, append([]string{"--address", l.socket, "--network", "unix"}, l.args...)...)
+	cmd.Stdout = l.output
+	cmd.Stderr = l.output
+	cmd.Env = append(os.Environ(), l.env...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start plugin %s: %w", l.path, err)
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+
+	l.mu.Lock()
+	if l.stopping {
+		l.mu.Unlock()
+		_ = cmd.Process.Kill()
+		<-exited
+		return errStopped
+	}
+	l.cmd, l.exited = cmd, exited
+	l.mu.Unlock()
+
+	return nil
+}
+
+// waitReady polls CheckReady until it succeeds, the process exits, or the ready timeout passes.
+func (l *Launcher) waitReady(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, l.readyTimeout)
+	defer cancel()
+
+	l.mu.Lock()
+	exited := l.exited
+	l.mu.Unlock()
+
+	for {
+		// The connection may still be backing off from the previous process; retry immediately.
+		l.conn.ResetConnectBackoff()
+
+		callCtx, callCancel := context.WithTimeout(ctx, time.Second)
+		_, err := l.client.CheckReady(callCtx, &emptypb.Empty{})
+		callCancel()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-exited:
+			return fmt.Errorf("plugin %s exited before becoming ready", l.path)
+		case <-ctx.Done():
+			return fmt.Errorf("plugin %s not ready within %s: %w", l.path, l.readyTimeout, err)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// supervise restarts the plugin whenever it exits unexpectedly.
+func (l *Launcher) supervise() {
+	backoff := l.minBackoff
+	restarts := 0
+
+	for {
+		l.mu.Lock()
+		exited := l.exited
+		l.mu.Unlock()
+
+		started := time.Now()
+		<-exited
+
+		l.mu.Lock()
+		stopping := l.stopping
+		state := l.cmd.ProcessState
+		l.mu.Unlock()
+		if stopping {
+			return
+		}
+
+		if time.Since(started) > l.maxBackoff {
+			backoff, restarts = l.minBackoff, 0
+		}
+		if l.maxRestarts >= 0 && restarts >= l.maxRestarts {
+			l.finish(fmt.Errorf("%w: %s exited (%s) after %d restarts", ErrTooManyRestarts, l.path, state, restarts))
+			return
+		}
+		restarts++
+
+		l.logger.Printf("plugin %s exited (%s); restarting in %s", l.path, state, backoff)
+		time.Sleep(backoff)
+		backoff = min(2*backoff, l.maxBackoff)
+
+		l.mu.Lock()
+		stopping = l.stopping
+		l.mu.Unlock()
+		if stopping {
+			return
+		}
+
+		if err := l.launch(); err != nil {
+			if !errors.Is(err, errStopped) {
+				l.finish(err)
+			}
+			return
+		}
+		if err := l.waitReady(context.Background()); err != nil {
+			l.logger.Printf("restarted plugin %s: %v", l.path, err)
+		}
+	}
+}
+
+// finish ends supervision with err.
+func (l *Launcher) finish(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err == nil {
+		l.err = err
+	}
+	if !l.stopping {
+		l.stopping = true
+		close(l.done)
+	}
+}
+
+// Stop asks the plugin to stop (the Stop RPC, then SIGTERM), kills it if it is still running after
+// the shutdown timeout, and releases the launcher's resources. The launcher cannot be restarted.
+func (l *Launcher) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	alreadyStopping := l.stopping
+	l.stopping = true
+	cmd, exited := l.cmd, l.exited
+	l.mu.Unlock()
+	if !alreadyStopping {
+		close(l.done)
+	}
+
+	var err error
+	if cmd != nil {
+		select {
+		case <-exited:
+		default:
+			stopCtx, cancel := context.WithTimeout(ctx, l.shutdownTimeout)
+			_, _ = l.client.Stop(stopCtx, &emptypb.Empty{})
+			cancel()
+			_ = cmd.Process.Signal(syscall.SIGTERM)
+
+			select {
+			case <-exited:
+			case <-time.After(l.shutdownTimeout):
+				_ = cmd.Process.Kill()
+				<-exited
+				err = fmt.Errorf("plugin %s did not exit within %s and was killed", l.path, l.shutdownTimeout)
+			case <-ctx.Done():
+				_ = cmd.Process.Kill()
+				<-exited
+				err = ctx.Err()
+			}
+		}
+	}
+
+	_ = l.conn.Close()
+	_ = os.Remove(l.socket)
+	if l.tempDir != "" {
+		_ = os.RemoveAll(l.tempDir)
+	}
+
+	return err
+}
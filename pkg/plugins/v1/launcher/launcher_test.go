@@ -0,0 +1,346 @@
+package launcher_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/launcher"
+)
+
+// pluginEnv makes the test binary act as a plugin: "serve" serves testPlugin, "exit" exits
+// immediately, and "hang" never listens.
+const pluginEnv = "LAUNCHER_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(pluginEnv) {
+	case "":
+		os.Exit(m.Run())
+	case "serve":
+		if err := mcpdpluginsv1.Serve(&testPlugin{}); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	case "exit":
+		os.Exit(3)
+	case "hang":
+		select {}
+	}
+}
+
+// testPlugin exits on requests to /crash, blocks forever on /hang, and otherwise answers with its
+// command line and LAUNCHER_TEST_VALUE.
+type testPlugin struct {
+	mcpdpluginsv1.BasePlugin
+}
+
+func (p *testPlugin) HandleRequest(
+	_ context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	switch req.GetPath() {
+	case "/crash":
+		os.Exit(2)
+	case "/hang":
+		fmt.Println("hanging")
+		select {}
+	}
+	body := strings.Join(os.Args[1:], " ") + "|" + os.Getenv("LAUNCHER_TEST_VALUE")
+
+	return &mcpdpluginsv1.HTTPResponse{Continue: true, Body: []byte(body)}, nil
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use, for the plugin's output and the logger.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func self(t *testing.T) string {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("runs plugin processes")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return exe
+}
+
+// start starts a launcher running the test binary in plugin mode, stopped when the test ends.
+func start(t *testing.T, mode string, opts ...launcher.Option) *launcher.Launcher {
+	t.Helper()
+
+	opts = append([]launcher.Option{launcher.WithEnv(pluginEnv + "=" + mode)}, opts...)
+	l, err := launcher.New(self(t), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Stop(context.Background()) })
+
+	return l
+}
+
+func call(l *launcher.Launcher, path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := l.Client().HandleRequest(ctx, &mcpdpluginsv1.HTTPRequest{Path: path})
+	return string(resp.GetBody()), err
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  launcher.Option
+		want string
+	}{
+		{"nil output", launcher.WithOutput(nil), "output writer cannot be nil"},
+		{"nil logger", launcher.WithLogger(nil), "logger cannot be nil"},
+		{"empty socket path", launcher.WithSocketPath(""), "socket path cannot be empty"},
+		{"zero ready timeout", launcher.WithReadyTimeout(0), "ready timeout must be positive"},
+		{"zero backoff", launcher.WithBackoff(0, time.Second), "invalid backoff 0s-1s"},
+		{"inverted backoff", launcher.WithBackoff(time.Second, time.Millisecond), "invalid backoff 1s-1ms"},
+		{"zero shutdown timeout", launcher.WithShutdownTimeout(0), "shutdown timeout must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := launcher.New("plugin", tt.opt); err == nil || err.Error() != tt.want {
+				t.Errorf("New error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStartAndStop(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "p.sock")
+	l := start(t, "serve",
+		launcher.WithSocketPath(socket),
+		launcher.WithArgs("--tuning", "latency"),
+		launcher.WithEnv("LAUNCHER_TEST_VALUE=hello"),
+	)
+	if l.Socket() != socket {
+		t.Errorf("Socket = %s, want %s", l.Socket(), socket)
+	}
+
+	got, err := call(l, "/mcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "--address " + socket + " --network unix --tuning latency|hello"; got != want {
+		t.Errorf("plugin saw %q, want %q", got, want)
+	}
+
+	if err := l.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	select {
+	case <-l.Done():
+	default:
+		t.Error("Done is not closed after Stop")
+	}
+	if err := l.Err(); err != nil {
+		t.Errorf("Err after Stop = %v, want nil", err)
+	}
+	if _, err := os.Stat(socket); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("socket still exists after Stop: %v", err)
+	}
+	if err := l.Stop(context.Background()); err != nil {
+		t.Errorf("second Stop: %v", err)
+	}
+}
+
+func TestStartTempSocket(t *testing.T) {
+	l := start(t, "serve")
+	dir := filepath.Dir(l.Socket())
+
+	if err := l.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("temporary socket directory still exists after Stop: %v", err)
+	}
+}
+
+func TestStartFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		path func(t *testing.T) string
+		mode string
+		want string
+	}{
+		{
+			name: "missing binary",
+			path: func(t *testing.T) string { return filepath.Join(t.TempDir(), "missing") },
+			want: "failed to start plugin",
+		},
+		{name: "exits before ready", path: self, mode: "exit", want: "exited before becoming ready"},
+		{name: "never ready", path: self, mode: "hang", want: "not ready within 300ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := launcher.New(tt.path(t),
+				launcher.WithEnv(pluginEnv+"="+tt.mode), launcher.WithReadyTimeout(300*time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = l.Stop(context.Background()) }()
+
+			if err := l.Start(context.Background()); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Start error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestart(t *testing.T) {
+	logs := &syncBuffer{}
+	l := start(t, "serve",
+		launcher.WithBackoff(10*time.Millisecond, time.Second),
+		launcher.WithLogger(log.New(logs, "", 0)),
+	)
+
+	for i := range 2 {
+		if _, err := call(l, "/crash"); status.Code(err) != codes.Unavailable {
+			t.Fatalf("crash %d: error = %v, want Unavailable", i, err)
+		}
+		waitFor(t, "the plugin to restart", func() bool {
+			_, err := call(l, "/mcp")
+			return err == nil
+		})
+	}
+
+	// The delay doubles after consecutive failures.
+	for _, want := range []string{"restarting in 10ms", "restarting in 20ms"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log %q does not mention %q", logs.String(), want)
+		}
+	}
+	if err := l.Err(); err != nil {
+		t.Errorf("Err = %v while supervising", err)
+	}
+}
+
+func TestMaxRestarts(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxRestarts int
+	}{
+		{"restarts disabled", 0},
+		{"one restart", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := start(t, "serve",
+				launcher.WithMaxRestarts(tt.maxRestarts),
+				launcher.WithBackoff(10*time.Millisecond, time.Second),
+				launcher.WithLogger(log.New(&syncBuffer{}, "", 0)),
+			)
+
+			for i := range tt.maxRestarts {
+				_, _ = call(l, "/crash")
+				waitFor(t, fmt.Sprintf("restart %d", i+1), func() bool {
+					_, err := call(l, "/mcp")
+					return err == nil
+				})
+			}
+			_, _ = call(l, "/crash")
+
+			select {
+			case <-l.Done():
+			case <-time.After(5 * time.Second):
+				t.Fatal("launcher kept supervising past the restart limit")
+			}
+			want := fmt.Sprintf("after %d restarts", tt.maxRestarts)
+			if err := l.Err(); !errors.Is(err, launcher.ErrTooManyRestarts) || !strings.Contains(err.Error(), want) {
+				t.Errorf("Err = %v, want ErrTooManyRestarts %s", err, want)
+			}
+		})
+	}
+}
+
+func TestStopKillsHungPlugin(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     func() (context.Context, context.CancelFunc)
+		wantErr string
+	}{
+		{
+			name:    "shutdown timeout",
+			ctx:     func() (context.Context, context.CancelFunc) { return context.Background(), func() {} },
+			wantErr: "did not exit within 200ms and was killed",
+		},
+		{
+			name: "caller gives up",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 100*time.Millisecond)
+			},
+			wantErr: context.DeadlineExceeded.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &syncBuffer{}
+			l := start(t, "serve", launcher.WithOutput(out), launcher.WithShutdownTimeout(200*time.Millisecond))
+
+			// A request that never returns keeps the plugin from stopping gracefully.
+			go func() { _, _ = call(l, "/hang") }()
+			waitFor(t, "the plugin to hang", func() bool { return strings.Contains(out.String(), "hanging") })
+
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			began := time.Now()
+			if err := l.Stop(ctx); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Stop error = %v, want %q", err, tt.wantErr)
+			}
+			if d := time.Since(began); d > 2*time.Second {
+				t.Errorf("Stop took %s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileLock is a Lock held as an exclusive advisory lock on a file, for replicas on the same host.
+// The operating system releases it when the holding process exits, so the TTL is not used.
+// Advisory locks are not supported on every platform or network file system; on platforms without
+// them TryAcquire returns an error.
+type FileLock struct {
+	path string
+
+	mu     sync.Mutex
+	f      *os.File
+	holder string
+}
+
+// NewFileLock returns a FileLock on path, which is created if needed.
+func NewFileLock(path string) *FileLock {
+	return &FileLock{path: path}
+}
+
+// TryAcquire implements Lock.
+func (l *FileLock) TryAcquire(_ context.Context, id string, _ time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f != nil {
+		return l.holder == id, nil
+	}
+
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return false, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	ok, err := tryLockFile(f)
+	if err != nil || !ok {
+		_ = f.Close()
+		return false, err
+	}
+	// Record the holder for operators; the lock itself is the flock.
+	_ = f.Truncate(0)
+	_, _ = f.WriteAt([]byte(id+"\n"), 0)
+	l.f, l.holder = f, id
+
+	return true, nil
+}
+
+// Release implements Lock.
+func (l *FileLock) Release(_ context.Context, id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil || l.holder != id {
+		return nil
+	}
+	_ = l.f.Truncate(0)
+	err := unlockFile(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f, l.holder = nil, ""
+
+	return err
+}
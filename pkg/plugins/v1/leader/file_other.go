@@ -0,0 +1,18 @@
+//go:build !unix
+
+package leader
+
+import (
+	"errors"
+	"os"
+)
+
+var errFileLockUnsupported = errors.New("file locks are not supported on this platform")
+
+func tryLockFile(*os.File) (bool, error) {
+	return false, errFileLockUnsupported
+}
+
+func unlockFile(*os.File) error {
+	return errFileLockUnsupported
+}
@@ -0,0 +1,66 @@
+//go:build unix
+
+package leader_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/leader"
+)
+
+func TestFileLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	ctx := context.Background()
+	a, b := leader.NewFileLock(path), leader.NewFileLock(path)
+
+	steps := []struct {
+		name    string
+		lock    *leader.FileLock
+		release bool
+		id      string
+		want    bool
+		holder  string // Contents of the lock file afterwards.
+	}{
+		{name: "first acquire", lock: a, id: "replica-1", want: true, holder: "replica-1\n"},
+		{name: "renew", lock: a, id: "replica-1", want: true, holder: "replica-1\n"},
+		{name: "other id on the same lock", lock: a, id: "replica-2", holder: "replica-1\n"},
+		{name: "other lock on the same file", lock: b, id: "replica-2", holder: "replica-1\n"},
+		{name: "release by a non-holder", lock: a, release: true, id: "replica-2", holder: "replica-1\n"},
+		{name: "release by a lock not holding it", lock: b, release: true, id: "replica-1", holder: "replica-1\n"},
+		{name: "release", lock: a, release: true, id: "replica-1", holder: ""},
+		{name: "takeover", lock: b, id: "replica-2", want: true, holder: "replica-2\n"},
+		{name: "previous holder locked out", lock: a, id: "replica-1", holder: "replica-2\n"},
+	}
+	for _, st := range steps {
+		if st.release {
+			if err := st.lock.Release(ctx, st.id); err != nil {
+				t.Fatalf("%s: Release: %v", st.name, err)
+			}
+		} else {
+			got, err := st.lock.TryAcquire(ctx, st.id, time.Minute)
+			if err != nil {
+				t.Fatalf("%s: TryAcquire: %v", st.name, err)
+			}
+			if got != st.want {
+				t.Errorf("%s: acquired = %t, want %t", st.name, got, st.want)
+			}
+		}
+		if b, _ := os.ReadFile(path); string(b) != st.holder {
+			t.Errorf("%s: lock file holds %q, want %q", st.name, b, st.holder)
+		}
+	}
+	_ = b.Release(ctx, "replica-2")
+}
+
+func TestFileLockOpenError(t *testing.T) {
+	l := leader.NewFileLock(filepath.Join(t.TempDir(), "missing", "leader.lock"))
+	_, err := l.TryAcquire(context.Background(), "replica-1", time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "failed to open lock file") {
+		t.Errorf("TryAcquire error = %v, want an open failure", err)
+	}
+}
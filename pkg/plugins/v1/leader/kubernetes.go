@@ -0,0 +1,276 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Paths of the credentials Kubernetes mounts into pods.
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	microTimeFormat   = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// KubernetesConfig locates a coordination.k8s.io/v1 Lease. Empty fields are filled from the pod's
+// environment and service account, so plugins running in a cluster only set Name.
+type KubernetesConfig struct {
+	// Name is the Lease's name.
+	Name string
+
+	// Namespace is the Lease's namespace (defaults to the pod's namespace).
+	Namespace string
+
+	// APIServer is the API server URL (defaults to https://$KUBERNETES_SERVICE_HOST:$KUBERNETES_SERVICE_PORT).
+	APIServer string
+
+	// Token is the bearer token (defaults to the service account token, re-read on every request
+	// so rotated tokens are picked up).
+	Token string
+
+	// HTTPClient sends requests (defaults to a client trusting the service account CA).
+	HTTPClient *http.Client
+}
+
+// KubernetesLease is a Lock held through a Lease object, the mechanism Kubernetes controllers use.
+// Expiry is judged against the local clock, so nodes need synchronised clocks. The pod's service
+// account needs get, create and update on leases in the namespace.
+type KubernetesLease struct {
+	cfg      KubernetesConfig
+	endpoint string
+	now      func() time.Time
+}
+
+// lease is the subset of the Lease object used for locking.
+type lease struct {
+	APIVersion string         `json:"apiVersion"`
+	Kind       string         `json:"kind"`
+	Metadata   map[string]any `json:"metadata"`
+	Spec       leaseSpec      `json:"spec"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       *string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds *int32  `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          *string `json:"acquireTime,omitempty"`
+	RenewTime            *string `json:"renewTime,omitempty"`
+	LeaseTransitions     *int32  `json:"leaseTransitions,omitempty"`
+}
+
+// NewKubernetesLease returns a KubernetesLease for cfg.
+func NewKubernetesLease(cfg KubernetesConfig) (*KubernetesLease, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("lease name is required")
+	}
+	if cfg.Namespace == "" {
+		ns, err := os.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("lease namespace is required outside a pod: %w", err)
+		}
+		cfg.Namespace = strings.TrimSpace(string(ns))
+	}
+	if cfg.APIServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("API server is required outside a pod")
+		}
+		cfg.APIServer = "https://" + net.JoinHostPort(host, port)
+	}
+	if cfg.HTTPClient == nil {
+		client, err := inClusterClient()
+		if err != nil {
+			return nil, err
+		}
+		cfg.HTTPClient = client
+	}
+
+	return &KubernetesLease{
+		cfg: cfg,
+		endpoint: fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases",
+			strings.TrimSuffix(cfg.APIServer, "/"), url.PathEscape(cfg.Namespace)),
+		now: time.Now,
+	}, nil
+}
+
+// TryAcquire implements Lock. Conflicting updates by other replicas are reported as not acquired.
+func (l *KubernetesLease) TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	now := l.now()
+	current, found, err := l.get(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	secs := int32(max(1, ttl/time.Second))
+	renew := now.UTC().Format(microTimeFormat)
+	if !found {
+		transitions := int32(0)
+		return l.send(ctx, http.MethodPost, l.endpoint, lease{
+			APIVersion: "coordination.k8s.io/v1",
+			Kind:       "Lease",
+			Metadata:   map[string]any{"name": l.cfg.Name, "namespace": l.cfg.Namespace},
+			Spec: leaseSpec{
+				HolderIdentity:       &id,
+				LeaseDurationSeconds: &secs,
+				AcquireTime:          &renew,
+				RenewTime:            &renew,
+				LeaseTransitions:     &transitions,
+			},
+		})
+	}
+
+	holder := deref(current.Spec.HolderIdentity)
+	if holder != id && holder != "" && !expired(current.Spec, now) {
+		return false, nil
+	}
+
+	spec := current.Spec
+	if holder != id {
+		transitions := deref(spec.LeaseTransitions) + 1
+		spec.LeaseTransitions = &transitions
+		spec.AcquireTime = &renew
+	}
+	spec.HolderIdentity = &id
+	spec.LeaseDurationSeconds = &secs
+	spec.RenewTime = &renew
+	current.Spec = spec
+
+	// metadata.resourceVersion makes the update fail with 409 if another replica got there first.
+	return l.send(ctx, http.MethodPut, l.endpoint+"/"+url.PathEscape(l.cfg.Name), current)
+}
+
+// Release implements Lock, clearing the holder so another replica can take over immediately.
+func (l *KubernetesLease) Release(ctx context.Context, id string) error {
+	current, found, err := l.get(ctx)
+	if err != nil || !found || deref(current.Spec.HolderIdentity) != id {
+		return err
+	}
+
+	empty := ""
+	current.Spec.HolderIdentity = &empty
+	_, err = l.send(ctx, http.MethodPut, l.endpoint+"/"+url.PathEscape(l.cfg.Name), current)
+
+	return err
+}
+
+func (l *KubernetesLease) get(ctx context.Context) (lease, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.endpoint+"/"+url.PathEscape(l.cfg.Name), nil)
+	if err != nil {
+		return lease{}, false, err
+	}
+	resp, err := l.do(req)
+	if err != nil {
+		return lease{}, false, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var out lease
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			return lease{}, false, fmt.Errorf("failed to decode lease: %w", err)
+		}
+		return out, true, nil
+	case http.StatusNotFound:
+		return lease{}, false, nil
+	default:
+		return lease{}, false, apiError(resp)
+	}
+}
+
+// send writes obj and reports whether the write won; 409 conflicts mean another replica did.
+func (l *KubernetesLease) send(ctx context.Context, method, endpoint string, obj lease) (bool, error) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.do(req)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return true, nil
+	case http.StatusConflict:
+		return false, nil
+	default:
+		return false, apiError(resp)
+	}
+}
+
+func (l *KubernetesLease) do(req *http.Request) (*http.Response, error) {
+	token := l.cfg.Token
+	if token == "" {
+		if b, err := os.ReadFile(serviceAccountDir + "/token"); err == nil {
+			token = strings.TrimSpace(string(b))
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := l.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes API request failed: %w", err)
+	}
+
+	return resp, nil
+}
+
+func inClusterClient() (*http.Client, error) {
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("service account CA contains no certificates")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+
+	return &http.Client{Transport: transport, Timeout: 10 * time.Second}, nil
+}
+
+func expired(spec leaseSpec, now time.Time) bool {
+	if spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	renewed, err := time.Parse(time.RFC3339Nano, *spec.RenewTime)
+	if err != nil {
+		return true
+	}
+
+	return now.After(renewed.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second))
+}
+
+func apiError(resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("kubernetes API returned %s: %s", resp.Status, strings.TrimSpace(string(b)))
+}
+
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+
+	return *p
+}
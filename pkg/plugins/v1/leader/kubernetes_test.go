@@ -0,0 +1,341 @@
+package leader_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/leader"
+)
+
+const leasePath = "/apis/coordination.k8s.io/v1/namespaces/plugins/leases"
+
+// leaseSpec is the Lease spec as stored by leaseServer.
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity"`
+	LeaseDurationSeconds int32  `json:"leaseDurationSeconds"`
+	AcquireTime          string `json:"acquireTime"`
+	RenewTime            string `json:"renewTime"`
+	LeaseTransitions     int32  `json:"leaseTransitions"`
+}
+
+type leaseObject struct {
+	APIVersion string         `json:"apiVersion"`
+	Kind       string         `json:"kind"`
+	Metadata   map[string]any `json:"metadata"`
+	Spec       leaseSpec      `json:"spec"`
+}
+
+// leaseServer is a Kubernetes API server holding one Lease, rejecting updates carrying a stale
+// resourceVersion with 409 as the real one does.
+type leaseServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	lease   *leaseObject
+	version int
+	auth    []string
+	status  int                // Non-zero answers every request with it.
+	beforeW func(*leaseServer) // Called before each write, to simulate concurrent writers.
+}
+
+func newLeaseServer(t *testing.T) *leaseServer {
+	t.Helper()
+
+	s := &leaseServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *leaseServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+	if s.status != 0 {
+		http.Error(w, `{"reason":"Forbidden"}`, s.status)
+		return
+	}
+
+	var in leaseObject
+	if r.Method != http.MethodGet {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &in); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if s.beforeW != nil {
+			s.beforeW(s)
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == leasePath+"/refresh":
+		if s.lease == nil {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.lease)
+	case r.Method == http.MethodPost && r.URL.Path == leasePath:
+		if s.lease != nil {
+			http.Error(w, "already exists", http.StatusConflict)
+			return
+		}
+		s.store(in)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && r.URL.Path == leasePath+"/refresh":
+		if s.lease == nil || in.Metadata["resourceVersion"] != strconv.Itoa(s.version) {
+			http.Error(w, "conflict", http.StatusConflict)
+			return
+		}
+		s.store(in)
+	default:
+		http.Error(w, "unexpected request "+r.Method+" "+r.URL.Path, http.StatusBadRequest)
+	}
+}
+
+// store saves in with s.mu held, bumping the resourceVersion.
+func (s *leaseServer) store(in leaseObject) {
+	s.version++
+	if in.Metadata == nil {
+		in.Metadata = map[string]any{}
+	}
+	in.Metadata["resourceVersion"] = strconv.Itoa(s.version)
+	s.lease = &in
+}
+
+func (s *leaseServer) spec() leaseSpec {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lease == nil {
+		return leaseSpec{}
+	}
+
+	return s.lease.Spec
+}
+
+func (s *leaseServer) set(fn func(s *leaseServer)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(s)
+}
+
+func newLease(t *testing.T, srv *leaseServer) *leader.KubernetesLease {
+	t.Helper()
+
+	l, err := leader.NewKubernetesLease(leader.KubernetesConfig{
+		Name:       "refresh",
+		Namespace:  "plugins",
+		APIServer:  srv.URL + "/",
+		Token:      "sa-token",
+		HTTPClient: srv.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return l
+}
+
+func TestKubernetesLease(t *testing.T) {
+	srv := newLeaseServer(t)
+	l := newLease(t, srv)
+	ctx := context.Background()
+
+	acquire := func(id string, want bool) {
+		t.Helper()
+		got, err := l.TryAcquire(ctx, id, 15*time.Second)
+		if err != nil || got != want {
+			t.Fatalf("TryAcquire(%s) = %t, %v; want %t", id, got, err, want)
+		}
+	}
+
+	acquire("replica-1", true)
+	created := srv.spec()
+	if created.HolderIdentity != "replica-1" || created.LeaseDurationSeconds != 15 || created.LeaseTransitions != 0 ||
+		created.AcquireTime == "" || created.AcquireTime != created.RenewTime {
+		t.Errorf("created lease %+v", created)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, created.RenewTime); err != nil {
+		t.Errorf("renewTime %q is not a MicroTime: %v", created.RenewTime, err)
+	}
+
+	time.Sleep(time.Millisecond)
+	acquire("replica-1", true)
+	renewed := srv.spec()
+	if renewed.AcquireTime != created.AcquireTime || renewed.RenewTime == created.RenewTime ||
+		renewed.LeaseTransitions != 0 {
+		t.Errorf("renewed lease %+v, want only renewTime changed", renewed)
+	}
+
+	acquire("replica-2", false)
+	if err := l.Release(ctx, "replica-2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.spec().HolderIdentity; got != "replica-1" {
+		t.Fatalf("release by a non-holder changed the holder to %q", got)
+	}
+	if err := l.Release(ctx, "replica-1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.spec().HolderIdentity; got != "" {
+		t.Fatalf("holder after Release = %q, want none", got)
+	}
+
+	acquire("replica-2", true)
+	if got := srv.spec(); got.HolderIdentity != "replica-2" || got.LeaseTransitions != 1 {
+		t.Errorf("lease after takeover %+v, want one transition", got)
+	}
+
+	srv.set(func(s *leaseServer) {
+		for _, a := range s.auth {
+			if a != "Bearer sa-token" {
+				t.Errorf("Authorization = %q, want the configured token", a)
+			}
+		}
+	})
+}
+
+func TestKubernetesLeaseExpiry(t *testing.T) {
+	tests := []struct {
+		name    string
+		renewed time.Duration // Before now.
+		spec    func(*leaseSpec)
+		want    bool
+	}{
+		{name: "live", renewed: 5 * time.Second, want: false},
+		{name: "expired", renewed: 20 * time.Second, want: true},
+		{name: "no renew time", spec: func(s *leaseSpec) { s.RenewTime = "" }, want: true},
+		{name: "malformed renew time", spec: func(s *leaseSpec) { s.RenewTime = "yesterday" }, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newLeaseServer(t)
+			spec := leaseSpec{
+				HolderIdentity:       "replica-2",
+				LeaseDurationSeconds: 15,
+				RenewTime:            time.Now().Add(-tt.renewed).UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+			}
+			if tt.spec != nil {
+				tt.spec(&spec)
+			}
+			srv.set(func(s *leaseServer) { s.store(leaseObject{Spec: spec}) })
+
+			got, err := newLease(t, srv).TryAcquire(context.Background(), "replica-1", 15*time.Second)
+			if err != nil || got != tt.want {
+				t.Errorf("TryAcquire = %t, %v; want %t", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestKubernetesLeaseConflicts(t *testing.T) {
+	ctx := context.Background()
+
+	// Another replica creates the lease between our read and our create.
+	srv := newLeaseServer(t)
+	srv.set(func(s *leaseServer) {
+		s.beforeW = func(s *leaseServer) {
+			s.beforeW = nil
+			s.store(leaseObject{Spec: leaseSpec{HolderIdentity: "replica-2"}})
+		}
+	})
+	if got, err := newLease(t, srv).TryAcquire(ctx, "replica-1", time.Minute); got || err != nil {
+		t.Errorf("TryAcquire racing a create = %t, %v; want not acquired", got, err)
+	}
+
+	// Another replica updates the lease between our read and our update.
+	srv = newLeaseServer(t)
+	l := newLease(t, srv)
+	if _, err := l.TryAcquire(ctx, "replica-1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	srv.set(func(s *leaseServer) {
+		s.beforeW = func(s *leaseServer) {
+			s.beforeW = nil
+			s.store(*s.lease)
+		}
+	})
+	if got, err := l.TryAcquire(ctx, "replica-1", time.Minute); got || err != nil {
+		t.Errorf("TryAcquire racing an update = %t, %v; want not acquired", got, err)
+	}
+}
+
+func TestKubernetesLeaseErrors(t *testing.T) {
+	srv := newLeaseServer(t)
+	srv.set(func(s *leaseServer) { s.status = http.StatusForbidden })
+	l := newLease(t, srv)
+
+	_, err := l.TryAcquire(context.Background(), "replica-1", time.Minute)
+	if err == nil || !strings.Contains(err.Error(), "kubernetes API returned 403 Forbidden") ||
+		!strings.Contains(err.Error(), `"reason":"Forbidden"`) {
+		t.Errorf("TryAcquire error = %v, want the API error", err)
+	}
+	if err := l.Release(context.Background(), "replica-1"); err == nil {
+		t.Error("Release succeeded against a failing API")
+	}
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("not json"))
+	}))
+	defer bad.Close()
+	l, err = leader.NewKubernetesLease(leader.KubernetesConfig{
+		Name: "refresh", Namespace: "plugins", APIServer: bad.URL, HTTPClient: bad.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.TryAcquire(context.Background(), "replica-1", time.Minute); err == nil ||
+		!strings.Contains(err.Error(), "failed to decode lease") {
+		t.Errorf("TryAcquire error = %v, want a decode failure", err)
+	}
+}
+
+func TestNewKubernetesLeaseErrors(t *testing.T) {
+	if _, err := os.Stat("/var/run/secrets/kubernetes.io/serviceaccount"); err == nil {
+		t.Skip("running in a pod")
+	}
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+
+	tests := []struct {
+		name string
+		cfg  leader.KubernetesConfig
+		want string
+	}{
+		{name: "no name", want: "lease name is required"},
+		{
+			name: "no namespace",
+			cfg:  leader.KubernetesConfig{Name: "l"},
+			want: "lease namespace is required outside a pod",
+		},
+		{
+			name: "no API server",
+			cfg:  leader.KubernetesConfig{Name: "l", Namespace: "ns"},
+			want: "API server is required outside a pod",
+		},
+		{
+			name: "no service account CA",
+			cfg:  leader.KubernetesConfig{Name: "l", Namespace: "ns", APIServer: "https://k8s"},
+			want: "failed to read service account CA",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := leader.NewKubernetesLease(tt.cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewKubernetesLease error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
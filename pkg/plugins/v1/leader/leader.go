@@ -0,0 +1,216 @@
+// Package leader elects a single leader among plugin replicas, so periodic background work such
+// as cache refreshes or policy pulls runs once rather than on every replica.
+//
+// A Lock arbitrates leadership: FileLock for replicas sharing a host, RedisLock for replicas
+// sharing a Redis server, and KubernetesLease for pods using a coordination.k8s.io Lease. An
+// Elector campaigns for the lock, renews it while leading and runs the leader's work with a
+// context cancelled as soon as leadership is lost:
+//
+//	lock, err := leader.NewRedisLock(leader.RedisConfig{Addr: "redis:6379"}, "my-plugin/refresh")
+//	if err != nil {
+//	    return err
+//	}
+//	elector, err := leader.NewElector(lock)
+//	if err != nil {
+//	    return err
+//	}
+//	go func() {
+//	    _ = elector.Run(ctx, func(ctx context.Context) {
+//	        refreshLoop(ctx) // Returns when ctx is cancelled.
+//	    })
+//	}()
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Lock is a leadership lock shared by the replicas. Implementations must be safe for concurrent use.
+type Lock interface {
+	// TryAcquire takes the lock for id, or renews it when id already holds it, for ttl. It reports
+	// false without error when another id holds the lock.
+	TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error)
+
+	// Release gives the lock up if id holds it.
+	Release(ctx context.Context, id string) error
+}
+
+// Elector campaigns for a Lock on behalf of one replica.
+type Elector struct {
+	lock   Lock
+	id     string
+	ttl    time.Duration
+	retry  time.Duration
+	logger *log.Logger
+
+	leading atomic.Bool
+	running sync.Mutex
+}
+
+// Option configures an Elector.
+type Option func(*Elector) error
+
+// WithID sets the identity the replica holds the lock under (defaults to the hostname, process ID
+// and a random suffix).
+func WithID(id string) Option {
+	return func(e *Elector) error {
+		if id == "" {
+			return fmt.Errorf("id cannot be empty")
+		}
+		e.id = id
+		return nil
+	}
+}
+
+// WithTTL sets how long leadership lasts without renewal (default 15s). The leader renews every
+// third of the TTL, so another replica takes over within one TTL of the leader failing.
+func WithTTL(ttl time.Duration) Option {
+	return func(e *Elector) error {
+		if ttl < time.Second {
+			return fmt.Errorf("ttl must be at least 1s")
+		}
+		e.ttl = ttl
+		return nil
+	}
+}
+
+// WithRetryInterval sets how often followers try to take the lock (defaults to the TTL's third).
+func WithRetryInterval(d time.Duration) Option {
+	return func(e *Elector) error {
+		if d <= 0 {
+			return fmt.Errorf("retry interval must be positive")
+		}
+		e.retry = d
+		return nil
+	}
+}
+
+// WithLogger sets the logger used to report lock errors (defaults to log.Default()).
+func WithLogger(logger *log.Logger) Option {
+	return func(e *Elector) error {
+		if logger == nil {
+			return fmt.Errorf("logger cannot be nil")
+		}
+		e.logger = logger
+		return nil
+	}
+}
+
+// NewElector returns an Elector campaigning for lock.
+func NewElector(lock Lock, opts ...Option) (*Elector, error) {
+	if lock == nil {
+		return nil, fmt.Errorf("lock is required")
+	}
+
+	e := &Elector{lock: lock, ttl: 15 * time.Second, logger: log.Default()}
+	for _, opt := range opts {
+		if err := opt(e); err != nil {
+			return nil, err
+		}
+	}
+	if e.id == "" {
+		e.id = defaultID()
+	}
+	if e.retry == 0 {
+		e.retry = e.ttl / 3
+	}
+
+	return e, nil
+}
+
+// ID returns the identity the replica campaigns under.
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// IsLeader reports whether the replica currently holds the lock.
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// Run campaigns until ctx is done. Each time the replica becomes leader, fn is called with a
+// context that is cancelled when leadership is lost or ctx is done; Run waits for fn to return
+// before campaigning again. Leadership is also given up when fn returns on its own. Run returns
+// ctx's error, or an error if it is already running.
+func (e *Elector) Run(ctx context.Context, fn func(ctx context.Context)) error {
+	if !e.running.TryLock() {
+		return errors.New("elector is already running")
+	}
+	defer e.running.Unlock()
+
+	for {
+		acquired, err := e.lock.TryAcquire(ctx, e.id, e.ttl)
+		if err != nil && ctx.Err() == nil {
+			e.logger.Printf("leader: failed to acquire lock: %v", err)
+		}
+		if acquired {
+			e.lead(ctx, fn)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.retry):
+		}
+	}
+}
+
+// lead runs fn while renewing the lock, returning once leadership is lost or ctx is done.
+func (e *Elector) lead(ctx context.Context, fn func(ctx context.Context)) {
+	e.leading.Store(true)
+	defer e.leading.Store(false)
+
+	leadCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(leadCtx)
+	}()
+
+	renew := time.NewTicker(e.ttl / 3)
+	defer renew.Stop()
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-done:
+			break loop
+		case <-renew.C:
+			ok, err := e.lock.TryAcquire(ctx, e.id, e.ttl)
+			if err != nil && ctx.Err() == nil {
+				e.logger.Printf("leader: failed to renew lock: %v", err)
+			}
+			if !ok {
+				break loop
+			}
+		}
+	}
+	cancel()
+	<-done
+
+	// Release with a fresh context: ctx may already be done.
+	releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), e.ttl/3)
+	defer cancelRelease()
+	if err := e.lock.Release(releaseCtx, e.id); err != nil {
+		e.logger.Printf("leader: failed to release lock: %v", err)
+	}
+}
+
+func defaultID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
@@ -0,0 +1,316 @@
+package leader_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/leader"
+)
+
+// fakeLock is an in-memory Lock whose holder tests can change, failing with err when set.
+type fakeLock struct {
+	mu       sync.Mutex
+	holder   string
+	err      error
+	acquires int
+	releases []string
+}
+
+func (l *fakeLock) TryAcquire(_ context.Context, id string, _ time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.acquires++
+	if l.err != nil {
+		return false, l.err
+	}
+	if l.holder != "" && l.holder != id {
+		return false, nil
+	}
+	l.holder = id
+
+	return true, nil
+}
+
+func (l *fakeLock) Release(_ context.Context, id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.releases = append(l.releases, id)
+	if l.holder == id {
+		l.holder = ""
+	}
+
+	return nil
+}
+
+func (l *fakeLock) set(fn func(l *fakeLock)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fn(l)
+}
+
+func (l *fakeLock) get() (holder string, acquires, releases int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.holder, l.acquires, len(l.releases)
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use, for loggers written by Run.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newElector(t *testing.T, lock leader.Lock, opts ...leader.Option) *leader.Elector {
+	t.Helper()
+
+	defaults := []leader.Option{
+		leader.WithID("replica-1"),
+		leader.WithTTL(time.Second),
+		leader.WithRetryInterval(10 * time.Millisecond),
+	}
+	e, err := leader.NewElector(lock, append(defaults, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
+
+func TestNewElectorErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		lock leader.Lock
+		opts []leader.Option
+		want string
+	}{
+		{name: "nil lock", want: "lock is required"},
+		{name: "empty id", lock: &fakeLock{}, opts: []leader.Option{leader.WithID("")}, want: "id cannot be empty"},
+		{
+			name: "short ttl",
+			lock: &fakeLock{},
+			opts: []leader.Option{leader.WithTTL(time.Millisecond)},
+			want: "ttl must be at least 1s",
+		},
+		{
+			name: "zero retry interval",
+			lock: &fakeLock{},
+			opts: []leader.Option{leader.WithRetryInterval(0)},
+			want: "retry interval must be positive",
+		},
+		{
+			name: "nil logger",
+			lock: &fakeLock{},
+			opts: []leader.Option{leader.WithLogger(nil)},
+			want: "logger cannot be nil",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := leader.NewElector(tt.lock, tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewElector error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultID(t *testing.T) {
+	a, err := leader.NewElector(&fakeLock{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := leader.NewElector(&fakeLock{})
+	if a.ID() == "" || a.ID() == b.ID() {
+		t.Errorf("default IDs %q and %q, want distinct identities", a.ID(), b.ID())
+	}
+	if e := newElector(t, &fakeLock{}); e.ID() != "replica-1" {
+		t.Errorf("ID = %q, want the configured one", e.ID())
+	}
+}
+
+func TestRunLeads(t *testing.T) {
+	lock := &fakeLock{}
+	e := newElector(t, lock)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	leading := make(chan context.Context)
+	done := make(chan error)
+	go func() {
+		done <- e.Run(ctx, func(ctx context.Context) {
+			leading <- ctx
+			<-ctx.Done()
+		})
+	}()
+
+	leadCtx := <-leading
+	if !e.IsLeader() {
+		t.Error("IsLeader is false while leading")
+	}
+	if err := e.Run(ctx, func(context.Context) {}); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("second Run error = %v, want it refused", err)
+	}
+
+	// Leadership is renewed while fn runs.
+	waitFor(t, "a renewal", func() bool { _, acquires, _ := lock.get(); return acquires >= 2 })
+	if leadCtx.Err() != nil {
+		t.Fatal("leader context cancelled while renewing")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run = %v, want context.Canceled", err)
+	}
+	if leadCtx.Err() == nil {
+		t.Error("leader context still live after Run returned")
+	}
+	if e.IsLeader() {
+		t.Error("IsLeader is true after Run returned")
+	}
+	if holder, _, releases := lock.get(); holder != "" || releases != 1 {
+		t.Errorf("lock held by %q after %d releases, want it released", holder, releases)
+	}
+}
+
+func TestRunFollows(t *testing.T) {
+	lock := &fakeLock{holder: "replica-2"}
+	e := newElector(t, lock)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	led := make(chan struct{}, 1)
+	go func() {
+		_ = e.Run(ctx, func(ctx context.Context) {
+			led <- struct{}{}
+			<-ctx.Done()
+		})
+	}()
+
+	waitFor(t, "retries", func() bool { _, acquires, _ := lock.get(); return acquires >= 3 })
+	select {
+	case <-led:
+		t.Fatal("follower ran the leader's work")
+	default:
+	}
+	if e.IsLeader() {
+		t.Error("follower reports leading")
+	}
+
+	// The follower takes over once the lock is free.
+	lock.set(func(l *fakeLock) { l.holder = "" })
+	select {
+	case <-led:
+	case <-time.After(5 * time.Second):
+		t.Fatal("follower did not take over the free lock")
+	}
+}
+
+func TestRunLosesLeadership(t *testing.T) {
+	lock := &fakeLock{}
+	e := newElector(t, lock)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	terms := make(chan context.Context, 2)
+	go func() {
+		_ = e.Run(ctx, func(ctx context.Context) {
+			terms <- ctx
+			<-ctx.Done()
+		})
+	}()
+
+	first := <-terms
+	lock.set(func(l *fakeLock) { l.holder = "replica-2" })
+	select {
+	case <-first.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("leader context not cancelled after the lock was taken")
+	}
+	waitFor(t, "stepping down", func() bool { return !e.IsLeader() })
+
+	// Leadership comes back once the other replica lets go.
+	lock.set(func(l *fakeLock) { l.holder = "" })
+	select {
+	case second := <-terms:
+		if second.Err() != nil {
+			t.Error("new term started with a cancelled context")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("leadership not regained")
+	}
+}
+
+func TestRunWorkReturns(t *testing.T) {
+	lock := &fakeLock{}
+	e := newElector(t, lock)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	runs := 0
+	go func() {
+		_ = e.Run(ctx, func(context.Context) {
+			mu.Lock()
+			runs++
+			mu.Unlock()
+		})
+	}()
+
+	// Returning gives leadership up, and the elector campaigns again.
+	waitFor(t, "repeated terms", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return runs >= 3
+	})
+	if _, _, releases := lock.get(); releases < 2 {
+		t.Errorf("%d releases after repeated terms, want one per term", releases)
+	}
+}
+
+func TestRunLogsLockErrors(t *testing.T) {
+	var logs syncBuffer
+	lock := &fakeLock{err: errors.New("redis unavailable")}
+	e := newElector(t, lock, leader.WithLogger(log.New(&logs, "", 0)))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() { _ = e.Run(ctx, func(context.Context) { t.Error("led without the lock") }) }()
+	waitFor(t, "the error log", func() bool {
+		return strings.Contains(logs.String(), "leader: failed to acquire lock: redis unavailable")
+	})
+}
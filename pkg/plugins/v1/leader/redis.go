@@ -0,0 +1,75 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/resp"
+)
+
+// RedisConfig configures the connection of a RedisLock.
+type RedisConfig = resp.Config
+
+// acquireScript takes the lock when free and renews it when already held by the caller.
+const acquireScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  return 1
+end
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+  return 1
+end
+return 0
+`
+
+// releaseScript deletes the lock only when held by the caller.
+const releaseScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+
+// RedisLock is a Lock held as a Redis key expiring after the TTL, for replicas sharing a Redis
+// server. It relies on a single Redis primary; it is not a Redlock implementation.
+type RedisLock struct {
+	client *resp.Client
+	key    string
+}
+
+// NewRedisLock returns a RedisLock stored under key. Connections are opened on first use.
+func NewRedisLock(cfg RedisConfig, key string) (*RedisLock, error) {
+	if key == "" {
+		return nil, fmt.Errorf("lock key is required")
+	}
+	c, err := resp.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisLock{client: c, key: key}, nil
+}
+
+// TryAcquire implements Lock.
+func (l *RedisLock) TryAcquire(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	ms := strconv.FormatInt(max(1, ttl.Milliseconds()), 10)
+	reply, err := l.client.Do(ctx, "EVAL", acquireScript, "1", l.key, id, ms)
+	if err != nil {
+		return false, err
+	}
+
+	return reply == int64(1), nil
+}
+
+// Release implements Lock.
+func (l *RedisLock) Release(ctx context.Context, id string) error {
+	_, err := l.client.Do(ctx, "EVAL", releaseScript, "1", l.key, id)
+	return err
+}
+
+// Close closes the lock's connections.
+func (l *RedisLock) Close() error {
+	return l.client.Close()
+}
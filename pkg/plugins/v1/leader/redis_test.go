@@ -0,0 +1,111 @@
+package leader_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/resp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/resp/resptest"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/leader"
+)
+
+// redisLocks answers the lock's scripts like Redis would, ignoring expiry. The acquire script is
+// sent with an id and a TTL, the release script with an id only.
+type redisLocks struct {
+	mu      sync.Mutex
+	holders map[string]string
+	ttls    []string
+}
+
+func (r *redisLocks) handle(args []string) any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if args[0] != "EVAL" || args[2] != "1" {
+		return resp.Error("ERR unexpected command")
+	}
+	key, id := args[3], args[4]
+	held := r.holders[key]
+	switch len(args) {
+	case 6:
+		r.ttls = append(r.ttls, args[5])
+		if held != "" && held != id {
+			return int64(0)
+		}
+		r.holders[key] = id
+		return int64(1)
+	case 5:
+		if held != id {
+			return int64(0)
+		}
+		delete(r.holders, key)
+		return int64(1)
+	}
+	return resp.Error("ERR wrong number of arguments")
+}
+
+func TestRedisLock(t *testing.T) {
+	locks := &redisLocks{holders: map[string]string{}}
+	srv := resptest.NewServer(t, locks.handle)
+	newLock := func() *leader.RedisLock {
+		l, err := leader.NewRedisLock(leader.RedisConfig{Addr: srv.Addr}, "my-plugin/refresh")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = l.Close() })
+		return l
+	}
+	a, b := newLock(), newLock()
+	ctx := context.Background()
+
+	acquire := func(l *leader.RedisLock, id string, want bool) {
+		t.Helper()
+		got, err := l.TryAcquire(ctx, id, 1500*time.Millisecond)
+		if err != nil || got != want {
+			t.Errorf("TryAcquire(%s) = %t, %v; want %t", id, got, err, want)
+		}
+	}
+	acquire(a, "replica-1", true)
+	acquire(a, "replica-1", true)
+	acquire(b, "replica-2", false)
+	if err := b.Release(ctx, "replica-2"); err != nil {
+		t.Fatal(err)
+	}
+	acquire(b, "replica-2", false)
+	if err := a.Release(ctx, "replica-1"); err != nil {
+		t.Fatal(err)
+	}
+	acquire(b, "replica-2", true)
+
+	locks.mu.Lock()
+	defer locks.mu.Unlock()
+	if locks.ttls[0] != "1500" {
+		t.Errorf("TTL sent as %s, want milliseconds", locks.ttls[0])
+	}
+}
+
+func TestRedisLockErrors(t *testing.T) {
+	if _, err := leader.NewRedisLock(leader.RedisConfig{Addr: "localhost:6379"}, ""); err == nil {
+		t.Error("NewRedisLock accepted an empty key")
+	}
+	if _, err := leader.NewRedisLock(leader.RedisConfig{}, "k"); err == nil {
+		t.Error("NewRedisLock accepted an empty address")
+	}
+
+	srv := resptest.NewServer(t, func([]string) any { return resp.Error("NOSCRIPT no scripts allowed") })
+	l, err := leader.NewRedisLock(leader.RedisConfig{Addr: srv.Addr}, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+	if ok, err := l.TryAcquire(context.Background(), "id", time.Second); ok || err == nil ||
+		!strings.Contains(err.Error(), "NOSCRIPT") {
+		t.Errorf("TryAcquire = %t, %v; want the error reply", ok, err)
+	}
+	if err := l.Release(context.Background(), "id"); err == nil {
+		t.Error("Release succeeded on an error reply")
+	}
+}
@@ -0,0 +1,44 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/manifest"
+)
+
+// manifestInterceptor returns the manifest linked into the binary, if any, in the response header
+// of GetMetadata, and fills the commit hash and build date the plugin leaves empty from it.
+func manifestInterceptor() grpc.UnaryServerInterceptor {
+	encoded := manifest.Embedded()
+	var m manifest.Manifest
+	if env, err := manifest.Decode(encoded); err == nil {
+		m, _ = env.Manifest()
+	} else {
+		encoded = ""
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if encoded == "" || info.FullMethod != Plugin_GetMetadata_FullMethodName {
+			return handler(ctx, req)
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(manifest.MetadataKey, encoded))
+
+		resp, err := handler(ctx, req)
+		if md, ok := resp.(*Metadata); ok && err == nil && (md.GetCommitHash() == "" || md.GetBuildDate() == "") {
+			md = proto.Clone(md).(*Metadata)
+			if md.CommitHash == "" {
+				md.CommitHash = m.Commit
+			}
+			if md.BuildDate == "" {
+				md.BuildDate = m.BuildDate
+			}
+			return md, nil
+		}
+
+		return resp, err
+	}
+}
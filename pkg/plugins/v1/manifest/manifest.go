@@ -0,0 +1,267 @@
+// Package manifest embeds a signed build manifest in plugin binaries and verifies it on the host,
+// so mcpd deployments can enforce plugin provenance.
+//
+// A Manifest records who built a plugin and a digest of the Go build information of the binary
+// (module versions and checksums, VCS revision, toolchain and build settings). A release tool
+// builds the plugin, describes the binary with New, signs the result with an Ed25519 key and
+// prints Envelope.Encode; the plugin is then rebuilt with the envelope linked in:
+//
+//	go build -o plugin ./cmd/plugin
+//	ENCODED=$(./release-tool sign plugin)
+//	PKG=github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/manifest
+//	go build -o plugin -ldflags "-X $PKG.embedded=$ENCODED" ./cmd/plugin
+//
+// The -ldflags build setting is excluded from the digest, so embedding the manifest does not
+// change it, while any change to sources, dependencies or toolchain does. Serve returns the
+// envelope in the MetadataKey response header of GetMetadata. On the host, VerifyFile checks the
+// signature against trusted keys and the digest against the binary before it is launched.
+package manifest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MetadataKey is the gRPC metadata key carrying the encoded envelope in GetMetadata responses.
+const MetadataKey = "mcpd-plugin-manifest"
+
+// marker prefixes encoded envelopes, so they can be located within binaries.
+const marker = "mcpd-manifest:v1:"
+
+// embedded is set at link time with -ldflags "-X .../manifest.embedded=<Encode output>".
+var embedded string
+
+// ErrNoManifest is returned when a binary carries no manifest.
+var ErrNoManifest = errors.New("manifest: no manifest embedded")
+
+// ErrUntrusted is returned when no signature verifies with a trusted key.
+var ErrUntrusted = errors.New("manifest: no trusted signature")
+
+// ErrDigestMismatch is returned when a binary's build information does not match its manifest.
+var ErrDigestMismatch = errors.New("manifest: build info digest mismatch")
+
+// Manifest describes a plugin build.
+type Manifest struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	// Builder identifies who or what produced the build, such as a CI workflow URI.
+	Builder string `json:"builder"`
+
+	// Commit is the VCS revision, and BuildDate the build time in RFC 3339.
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"buildDate,omitempty"`
+
+	// BuildInfoDigest is "sha256:<hex>" of the binary's build information; see BuildInfoDigest.
+	BuildInfoDigest string `json:"buildInfoDigest"`
+}
+
+// New returns a manifest for the binary at path, taking the commit from its VCS build settings.
+func New(name, version, builder, path string) (Manifest, error) {
+	bi, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("manifest: failed to read build info of %s: %w", path, err)
+	}
+
+	m := Manifest{
+		Name:            name,
+		Version:         version,
+		Builder:         builder,
+		BuildDate:       time.Now().UTC().Format(time.RFC3339),
+		BuildInfoDigest: BuildInfoDigest(bi),
+	}
+	for _, s := range bi.Settings {
+		if s.Key == "vcs.revision" {
+			m.Commit = s.Value
+		}
+	}
+
+	return m, nil
+}
+
+// BuildInfoDigest returns "sha256:<hex>" over the toolchain, main module, dependencies and build
+// settings of bi, excluding -ldflags so embedding a manifest does not change it.
+func BuildInfoDigest(bi *debug.BuildInfo) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "go %s\npath %s\n", bi.GoVersion, bi.Path)
+	writeModule(&b, "mod", &bi.Main)
+	deps := make([]*debug.Module, 0, len(bi.Deps))
+	deps = append(deps, bi.Deps...)
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Path < deps[j].Path })
+	for _, d := range deps {
+		writeModule(&b, "dep", d)
+	}
+	settings := append([]debug.BuildSetting(nil), bi.Settings...)
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	for _, s := range settings {
+		if s.Key != "-ldflags" {
+			fmt.Fprintf(&b, "build %s=%s\n", s.Key, s.Value)
+		}
+	}
+	sum := sha256.Sum256(b.Bytes())
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func writeModule(b *bytes.Buffer, kind string, m *debug.Module) {
+	fmt.Fprintf(b, "%s %s %s %s\n", kind, m.Path, m.Version, m.Sum)
+	if m.Replace != nil {
+		writeModule(b, "=>", m.Replace)
+	}
+}
+
+// Signature is an Ed25519 signature over an envelope's payload.
+type Signature struct {
+	KeyID     string `json:"keyId"`
+	Signature []byte `json:"sig"`
+}
+
+// Envelope is a signed manifest. Payload is the manifest's JSON encoding, signed as is.
+type Envelope struct {
+	Payload    []byte      `json:"payload"`
+	Signatures []Signature `json:"signatures"`
+}
+
+// Sign returns an envelope for m signed by key, identified to verifiers as keyID.
+func Sign(m Manifest, keyID string, key ed25519.PrivateKey) (Envelope, error) {
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("manifest: failed to encode: %w", err)
+	}
+	e := Envelope{Payload: payload}
+	e.AddSignature(keyID, key)
+
+	return e, nil
+}
+
+// AddSignature adds a signature by key, for manifests co-signed by several parties.
+func (e *Envelope) AddSignature(keyID string, key ed25519.PrivateKey) {
+	e.Signatures = append(e.Signatures, Signature{KeyID: keyID, Signature: ed25519.Sign(key, e.Payload)})
+}
+
+// Encode returns the envelope as a single token suitable for -ldflags -X and gRPC metadata.
+func (e Envelope) Encode() string {
+	raw, _ := json.Marshal(e)
+	return marker + base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decode parses an encoded envelope.
+func Decode(s string) (Envelope, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(s), marker)
+	if !ok {
+		return Envelope{}, fmt.Errorf("manifest: not an encoded manifest")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(rest)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("manifest: invalid encoding: %w", err)
+	}
+	var e Envelope
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return Envelope{}, fmt.Errorf("manifest: invalid envelope: %w", err)
+	}
+
+	return e, nil
+}
+
+// Manifest returns the envelope's manifest without verifying it.
+func (e Envelope) Manifest() (Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(e.Payload, &m); err != nil {
+		return Manifest{}, fmt.Errorf("manifest: invalid payload: %w", err)
+	}
+
+	return m, nil
+}
+
+// Verify returns the manifest when at least one signature verifies with the trusted key of
+// its key ID.
+func (e Envelope) Verify(trusted map[string]ed25519.PublicKey) (Manifest, error) {
+	for _, s := range e.Signatures {
+		key, ok := trusted[s.KeyID]
+		if ok && len(key) == ed25519.PublicKeySize && ed25519.Verify(key, e.Payload, s.Signature) {
+			return e.Manifest()
+		}
+	}
+
+	return Manifest{}, ErrUntrusted
+}
+
+// Embedded returns the encoded envelope linked into the running binary, or "" when there is none.
+func Embedded() string {
+	return embedded
+}
+
+// Current returns the envelope linked into the running binary.
+func Current() (Envelope, error) {
+	if embedded == "" {
+		return Envelope{}, ErrNoManifest
+	}
+
+	return Decode(embedded)
+}
+
+// ReadFile extracts the envelope linked into the binary at path.
+func ReadFile(path string) (Envelope, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("manifest: %w", err)
+	}
+
+	// The marker also appears as a constant in every SDK binary; the linked value is the
+	// occurrence followed by a payload.
+	for rest := data; ; {
+		i := bytes.Index(rest, []byte(marker))
+		if i < 0 {
+			return Envelope{}, ErrNoManifest
+		}
+		rest = rest[i+len(marker):]
+		n := 0
+		for n < len(rest) && isBase64URL(rest[n]) {
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		if e, err := Decode(marker + string(rest[:n])); err == nil {
+			return e, nil
+		}
+	}
+}
+
+func isBase64URL(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_'
+}
+
+// VerifyFile verifies the manifest linked into the binary at path: a signature must verify with a
+// trusted key and the manifest's digest must match the binary's build information.
+func VerifyFile(path string, trusted map[string]ed25519.PublicKey) (Manifest, error) {
+	e, err := ReadFile(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+	m, err := e.Verify(trusted)
+	if err != nil {
+		return Manifest{}, err
+	}
+	bi, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("manifest: failed to read build info of %s: %w", path, err)
+	}
+	if got := BuildInfoDigest(bi); got != m.BuildInfoDigest {
+		return Manifest{}, fmt.Errorf("%w: binary %s, manifest %s", ErrDigestMismatch, got, m.BuildInfoDigest)
+	}
+
+	return m, nil
+}
@@ -0,0 +1,322 @@
+package manifest_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/manifest"
+)
+
+func newKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pub, priv
+}
+
+func testManifest() manifest.Manifest {
+	return manifest.Manifest{
+		Name:            "rate-limit",
+		Version:         "1.2.3",
+		Builder:         "https://github.com/example/plugin/.github/workflows/release.yml@refs/tags/v1.2.3",
+		Commit:          "0123456789abcdef",
+		BuildDate:       "2026-01-02T03:04:05Z",
+		BuildInfoDigest: "sha256:00",
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	releasePub, releaseKey := newKey(t)
+	auditPub, auditKey := newKey(t)
+	otherPub, _ := newKey(t)
+
+	env, err := manifest.Sign(testManifest(), "release", releaseKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cosigned, err := manifest.Sign(testManifest(), "release", releaseKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cosigned.AddSignature("audit", auditKey)
+
+	tampered := env
+	tampered.Payload = []byte(strings.Replace(string(env.Payload), "1.2.3", "9.9.9", 1))
+
+	tests := []struct {
+		name    string
+		env     manifest.Envelope
+		trusted map[string]ed25519.PublicKey
+		ok      bool
+	}{
+		{name: "trusted", env: env, trusted: map[string]ed25519.PublicKey{"release": releasePub}, ok: true},
+		{name: "co-signer trusted", env: cosigned, trusted: map[string]ed25519.PublicKey{"audit": auditPub}, ok: true},
+		{name: "no trusted keys", env: env},
+		{name: "unknown key ID", env: env, trusted: map[string]ed25519.PublicKey{"audit": releasePub}},
+		{name: "wrong key", env: env, trusted: map[string]ed25519.PublicKey{"release": otherPub}},
+		{name: "tampered payload", env: tampered, trusted: map[string]ed25519.PublicKey{"release": releasePub}},
+		{name: "truncated key", env: env, trusted: map[string]ed25519.PublicKey{"release": releasePub[:16]}},
+		{
+			name:    "unsigned",
+			env:     manifest.Envelope{Payload: env.Payload},
+			trusted: map[string]ed25519.PublicKey{"release": releasePub},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tt.env.Verify(tt.trusted)
+			if !tt.ok {
+				if !errors.Is(err, manifest.ErrUntrusted) {
+					t.Errorf("Verify = %+v, %v; want ErrUntrusted", m, err)
+				}
+				return
+			}
+			if err != nil || m != testManifest() {
+				t.Errorf("Verify = %+v, %v; want %+v", m, err, testManifest())
+			}
+		})
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	_, key := newKey(t)
+	env, err := manifest.Sign(testManifest(), "release", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded := env.Encode()
+	if !strings.HasPrefix(encoded, "mcpd-manifest:v1:") || strings.ContainsAny(encoded, " =+/\n") {
+		t.Errorf("Encode = %q, want a single URL-safe token", encoded)
+	}
+	decoded, err := manifest.Decode("  " + encoded + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err := decoded.Manifest(); err != nil || m != testManifest() {
+		t.Errorf("decoded Manifest = %+v, %v", m, err)
+	}
+	if len(decoded.Signatures) != 1 || decoded.Signatures[0].KeyID != "release" {
+		t.Errorf("decoded signatures = %+v", decoded.Signatures)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    string
+	}{
+		{encoded: "", want: "not an encoded manifest"},
+		{encoded: "mcpd-manifest:v2:abc", want: "not an encoded manifest"},
+		{encoded: "mcpd-manifest:v1:***", want: "invalid encoding"},
+		{encoded: "mcpd-manifest:v1:bm90IGpzb24", want: "invalid envelope"},
+	}
+	for _, tt := range tests {
+		if _, err := manifest.Decode(tt.encoded); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Decode(%q) error = %v, want %q", tt.encoded, err, tt.want)
+		}
+	}
+
+	env := manifest.Envelope{Payload: []byte("not json")}
+	if _, err := env.Manifest(); err == nil || !strings.Contains(err.Error(), "invalid payload") {
+		t.Errorf("Manifest error = %v, want an invalid payload", err)
+	}
+}
+
+func TestBuildInfoDigest(t *testing.T) {
+	base := func() *debug.BuildInfo {
+		return &debug.BuildInfo{
+			GoVersion: "go1.24.0",
+			Path:      "example.com/plugin/cmd/plugin",
+			Main:      debug.Module{Path: "example.com/plugin", Version: "(devel)"},
+			Deps: []*debug.Module{
+				{Path: "google.golang.org/grpc", Version: "v1.70.0", Sum: "h1:a"},
+				{Path: "example.com/fork", Version: "v1.0.0", Sum: "h1:b"},
+			},
+			Settings: []debug.BuildSetting{
+				{Key: "vcs.revision", Value: "abc"},
+				{Key: "-ldflags", Value: "-X main.version=1"},
+				{Key: "CGO_ENABLED", Value: "0"},
+			},
+		}
+	}
+	digest := manifest.BuildInfoDigest(base())
+	if !strings.HasPrefix(digest, "sha256:") || len(digest) != len("sha256:")+64 {
+		t.Fatalf("BuildInfoDigest = %q, want sha256:<hex>", digest)
+	}
+
+	tests := []struct {
+		name   string
+		change func(bi *debug.BuildInfo)
+		same   bool
+	}{
+		{
+			name:   "ldflags",
+			change: func(bi *debug.BuildInfo) { bi.Settings[1].Value = "-X .../manifest.embedded=x" },
+			same:   true,
+		},
+		{
+			name: "dependency order",
+			change: func(bi *debug.BuildInfo) {
+				bi.Deps[0], bi.Deps[1] = bi.Deps[1], bi.Deps[0]
+			},
+			same: true,
+		},
+		{
+			name: "setting order",
+			change: func(bi *debug.BuildInfo) {
+				bi.Settings[0], bi.Settings[2] = bi.Settings[2], bi.Settings[0]
+			},
+			same: true,
+		},
+		{name: "toolchain", change: func(bi *debug.BuildInfo) { bi.GoVersion = "go1.24.1" }},
+		{name: "dependency version", change: func(bi *debug.BuildInfo) { bi.Deps[0].Version = "v1.70.1" }},
+		{name: "dependency checksum", change: func(bi *debug.BuildInfo) { bi.Deps[1].Sum = "h1:c" }},
+		{
+			name: "replacement",
+			change: func(bi *debug.BuildInfo) {
+				bi.Deps[1].Replace = &debug.Module{Path: "../fork", Version: "(devel)"}
+			},
+		},
+		{name: "revision", change: func(bi *debug.BuildInfo) { bi.Settings[0].Value = "def" }},
+		{name: "main module", change: func(bi *debug.BuildInfo) { bi.Main.Version = "v1.0.0" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bi := base()
+			tt.change(bi)
+			if got := manifest.BuildInfoDigest(bi); (got == digest) != tt.same {
+				t.Errorf("digest changed = %t, want %t", got != digest, !tt.same)
+			}
+		})
+	}
+
+	// The slices of the build info are not reordered in place.
+	bi := base()
+	manifest.BuildInfoDigest(bi)
+	if bi.Deps[0].Path != "google.golang.org/grpc" || bi.Settings[0].Key != "vcs.revision" {
+		t.Error("BuildInfoDigest reordered the build info")
+	}
+}
+
+// signedBinary copies the running test binary, which carries Go build information, and appends
+// the encoding of m signed by key, as linking it in with -ldflags would embed it.
+func signedBinary(t *testing.T, m manifest.Manifest, key ed25519.PrivateKey) string {
+	t.Helper()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := manifest.Sign(m, "release", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Like the SDK's own marker constant, a marker without a payload comes first.
+	data = append(data, "mcpd-manifest:v1:\x00 mcpd-manifest:v1:not-an-envelope\x00"+env.Encode()+"\x00"...)
+
+	path := filepath.Join(t.TempDir(), "plugin")
+	if err := os.WriteFile(path, data, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestVerifyFile(t *testing.T) {
+	pub, key := newKey(t)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := manifest.New("rate-limit", "1.2.3", "ci", exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "rate-limit" || m.Version != "1.2.3" || m.Builder != "ci" || m.BuildDate == "" ||
+		!strings.HasPrefix(m.BuildInfoDigest, "sha256:") {
+		t.Errorf("New = %+v", m)
+	}
+	trusted := map[string]ed25519.PublicKey{"release": pub}
+
+	path := signedBinary(t, m, key)
+	if env, err := manifest.ReadFile(path); err != nil || len(env.Signatures) != 1 {
+		t.Errorf("ReadFile = %+v, %v", env, err)
+	}
+	got, err := manifest.VerifyFile(path, trusted)
+	if err != nil || got != m {
+		t.Errorf("VerifyFile = %+v, %v; want %+v", got, err, m)
+	}
+
+	wrong := m
+	wrong.BuildInfoDigest = "sha256:00"
+	_, err = manifest.VerifyFile(signedBinary(t, wrong, key), trusted)
+	if !errors.Is(err, manifest.ErrDigestMismatch) {
+		t.Errorf("VerifyFile error = %v, want ErrDigestMismatch", err)
+	}
+
+	_, other := newKey(t)
+	if _, err := manifest.VerifyFile(signedBinary(t, m, other), trusted); !errors.Is(err, manifest.ErrUntrusted) {
+		t.Errorf("VerifyFile error = %v, want ErrUntrusted", err)
+	}
+}
+
+func TestReadFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain")
+	if err := os.WriteFile(plain, []byte("no manifest here mcpd-manifest:v1:"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manifest.ReadFile(plain); !errors.Is(err, manifest.ErrNoManifest) {
+		t.Errorf("ReadFile error = %v, want ErrNoManifest", err)
+	}
+	if _, err := manifest.VerifyFile(plain, nil); !errors.Is(err, manifest.ErrNoManifest) {
+		t.Errorf("VerifyFile error = %v, want ErrNoManifest", err)
+	}
+	if _, err := manifest.ReadFile(filepath.Join(dir, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadFile error = %v, want a not-exist error", err)
+	}
+	_, err := manifest.New("p", "1", "ci", plain)
+	if err == nil || !strings.Contains(err.Error(), "failed to read build info") {
+		t.Errorf("New error = %v, want a build info failure", err)
+	}
+
+	// A manifest appended to a file without build information cannot be checked.
+	pub, key := newKey(t)
+	env, err := manifest.Sign(testManifest(), "release", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "script")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n# "+env.Encode()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = manifest.VerifyFile(script, map[string]ed25519.PublicKey{"release": pub})
+	if err == nil || !strings.Contains(err.Error(), "failed to read build info") {
+		t.Errorf("VerifyFile error = %v, want a build info failure", err)
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	// Test binaries are not linked with a manifest.
+	if manifest.Embedded() != "" {
+		t.Skip("test binary linked with a manifest")
+	}
+	if _, err := manifest.Current(); !errors.Is(err, manifest.ErrNoManifest) {
+		t.Errorf("Current error = %v, want ErrNoManifest", err)
+	}
+}
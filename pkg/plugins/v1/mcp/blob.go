@@ -0,0 +1,232 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrBlobTooLarge is returned when a decoded blob would exceed the allowed size.
+var ErrBlobTooLarge = errors.New("blob exceeds size limit")
+
+// Blob is a base64-encoded binary payload carried by an MCP message: the data of an image or
+// audio content block, or the blob of resource contents (resources/read and embedded resources).
+type Blob struct {
+	// URI is the resource URI, empty for image and audio blocks.
+	URI string
+
+	// MIMEType is the declared mimeType, possibly empty.
+	MIMEType string
+
+	// Data is the decoded payload.
+	Data []byte
+}
+
+// SniffedMIMEType returns the MIME type detected from the content of the blob, which may differ
+// from the declared one (see net/http.DetectContentType).
+func (b *Blob) SniffedMIMEType() string {
+	return SniffMIMEType(b.Data)
+}
+
+// BlobFunc receives a blob and reports whether it modified Data or MIMEType, in which case the
+// blob is re-encoded into the message.
+type BlobFunc func(b *Blob) bool
+
+// Blobs returns the blobs carried by the MCP message(s) in body, decoding each one. Blobs whose
+// decoded size would exceed maxSize bytes (0 means no limit) are rejected with ErrBlobTooLarge
+// before being decoded.
+func Blobs(body []byte, maxSize int) ([]Blob, error) {
+	var blobs []Blob
+	_, _, err := RewriteBlobs(body, maxSize, func(b *Blob) bool {
+		blobs = append(blobs, *b)
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return blobs, nil
+}
+
+// RewriteBlobs applies fn to each blob carried by the MCP message(s) in body and returns the
+// re-encoded body. The second return value reports whether anything changed; when it is false
+// the original body is returned untouched. Blobs that are not valid base64 are an error, as are
+// blobs larger than maxSize (0 means no limit).
+func RewriteBlobs(body []byte, maxSize int, fn BlobFunc) ([]byte, bool, error) {
+	var walkErr error
+	out, changed, err := rewriteMessages(body, func(msg map[string]any) bool {
+		if walkErr != nil {
+			return false
+		}
+		c, err := rewriteMessageBlobs(msg, maxSize, fn)
+		if err != nil {
+			walkErr = err
+		}
+		return c
+	})
+	if err != nil {
+		return body, false, err
+	}
+	if walkErr != nil {
+		return body, false, walkErr
+	}
+
+	return out, changed, nil
+}
+
+func rewriteMessageBlobs(msg map[string]any, maxSize int, fn BlobFunc) (bool, error) {
+	result, ok := msg["result"].(map[string]any)
+	if !ok {
+		return false, nil
+	}
+
+	var blocks []any
+	for _, key := range []string{"content", "contents"} {
+		if list, ok := result[key].([]any); ok {
+			blocks = append(blocks, list...)
+		}
+	}
+	if messages, ok := result["messages"].([]any); ok {
+		for _, m := range messages {
+			if pm, ok := m.(map[string]any); ok {
+				blocks = append(blocks, pm["content"])
+			}
+		}
+	}
+
+	changed := false
+	for _, b := range blocks {
+		c, err := rewriteBlockBlob(b, maxSize, fn)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || c
+	}
+
+	return changed, nil
+}
+
+// rewriteBlockBlob rewrites the blob of a content block or resource contents object.
+func rewriteBlockBlob(v any, maxSize int, fn BlobFunc) (bool, error) {
+	block, ok := v.(map[string]any)
+	if !ok {
+		return false, nil
+	}
+	if res, ok := block["resource"]; ok {
+		return rewriteBlockBlob(res, maxSize, fn)
+	}
+
+	key := "blob"
+	if typ, _ := block["type"].(string); typ == "image" || typ == "audio" {
+		key = "data"
+	}
+	encoded, ok := block[key].(string)
+	if !ok {
+		return false, nil
+	}
+
+	uri, _ := block["uri"].(string)
+	// Line breaks are not counted, so wrapped blobs within the limit are not rejected early.
+	encodedLen := len(encoded) - strings.Count(encoded, "\n") - strings.Count(encoded, "\r")
+	if maxSize > 0 && base64.StdEncoding.DecodedLen(encodedLen) > maxSize+2 {
+		return false, fmt.Errorf("%w: %s", ErrBlobTooLarge, describe(uri))
+	}
+	data, err := DecodeBase64(encoded)
+	if err != nil {
+		return false, fmt.Errorf("invalid base64 in %s: %w", describe(uri), err)
+	}
+	if maxSize > 0 && len(data) > maxSize {
+		return false, fmt.Errorf("%w: %s", ErrBlobTooLarge, describe(uri))
+	}
+
+	mimeType, _ := block["mimeType"].(string)
+	b := &Blob{URI: uri, MIMEType: mimeType, Data: data}
+	if !fn(b) {
+		return false, nil
+	}
+	block[key] = base64.StdEncoding.EncodeToString(b.Data)
+	if b.MIMEType != "" {
+		block["mimeType"] = b.MIMEType
+	} else {
+		delete(block, "mimeType")
+	}
+
+	return true, nil
+}
+
+func describe(uri string) string {
+	if uri == "" {
+		return "content block"
+	}
+
+	return fmt.Sprintf("resource %q", uri)
+}
+
+// DecodeBase64 decodes standard base64 as used by MCP, also accepting unpadded and URL-safe
+// encodings and ignoring embedded line breaks.
+func DecodeBase64(s string) ([]byte, error) {
+	if strings.ContainsAny(s, "\r\n") {
+		s = strings.NewReplacer("\r", "", "\n", "").Replace(s)
+	}
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	}
+
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// SniffMIMEType returns the MIME type detected from the first bytes of data, without parameters.
+func SniffMIMEType(data []byte) string {
+	mediaType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	return mediaType
+}
+
+// ParseDataURI decodes an RFC 2397 data URI ("data:image/png;base64,..."), returning its media
+// type (text/plain when omitted) and payload.
+func ParseDataURI(uri string) (string, []byte, error) {
+	rest, ok := strings.CutPrefix(uri, "data:")
+	if !ok {
+		return "", nil, fmt.Errorf("not a data URI")
+	}
+	meta, payload, ok := strings.Cut(rest, ",")
+	if !ok {
+		return "", nil, fmt.Errorf("invalid data URI: missing comma")
+	}
+
+	isBase64 := false
+	if m, ok := strings.CutSuffix(meta, ";base64"); ok {
+		meta, isBase64 = m, true
+	}
+	// An omitted type defaults to text/plain, keeping any parameters ("data:;charset=utf-8,...").
+	if meta == "" || meta[0] == ';' {
+		meta = "text/plain" + meta
+	}
+	mt, params, err := mime.ParseMediaType(meta)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid data URI media type: %w", err)
+	}
+	mediaType := mime.FormatMediaType(mt, params)
+
+	if isBase64 {
+		data, err := DecodeBase64(payload)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid data URI payload: %w", err)
+		}
+		return mediaType, data, nil
+	}
+	data, err := url.PathUnescape(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid data URI payload: %w", err)
+	}
+
+	return mediaType, []byte(data), nil
+}
+
+// DataURI encodes data as a base64 data URI of the given media type.
+func DataURI(mediaType string, data []byte) string {
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
@@ -0,0 +1,310 @@
+package mcp_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+// pngHeader is enough of a PNG for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func b64(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func TestBlobs(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []mcp.Blob
+	}{
+		{
+			name: "image and audio blocks",
+			body: `{"jsonrpc":"2.0","id":1,"result":{"content":[` +
+				`{"type":"image","data":"` + b64(pngHeader) + `","mimeType":"image/png"},` +
+				`{"type":"audio","data":"` + b64([]byte("RIFF")) + `"},` +
+				`{"type":"text","text":"not a blob"}]}}`,
+			want: []mcp.Blob{{MIMEType: "image/png", Data: pngHeader}, {Data: []byte("RIFF")}},
+		},
+		{
+			name: "resource contents",
+			body: `{"jsonrpc":"2.0","id":1,"result":{"contents":[` +
+				`{"uri":"file:///a.bin","blob":"` + b64([]byte("abc")) + `","mimeType":"application/octet-stream"},` +
+				`{"uri":"file:///b.txt","text":"skipped"}]}}`,
+			want: []mcp.Blob{{URI: "file:///a.bin", MIMEType: "application/octet-stream", Data: []byte("abc")}},
+		},
+		{
+			name: "embedded resource",
+			body: `{"jsonrpc":"2.0","id":1,"result":{"content":[` +
+				`{"type":"resource","resource":{"uri":"file:///c","blob":"` + b64([]byte("c")) + `"}}]}}`,
+			want: []mcp.Blob{{URI: "file:///c", Data: []byte("c")}},
+		},
+		{
+			name: "prompt messages",
+			body: `{"jsonrpc":"2.0","id":1,"result":{"messages":[` +
+				`{"role":"user","content":{"type":"image","data":"` + b64([]byte("p")) + `",` +
+				`"mimeType":"image/gif"}}]}}`,
+			want: []mcp.Blob{{MIMEType: "image/gif", Data: []byte("p")}},
+		},
+		{
+			name: "batch",
+			body: `[{"jsonrpc":"2.0","id":1,"result":{"contents":[{"uri":"a","blob":"YQ=="}]}},` +
+				`{"jsonrpc":"2.0","id":2,"result":{"contents":[{"uri":"b","blob":"Yg=="}]}}]`,
+			want: []mcp.Blob{{URI: "a", Data: []byte("a")}, {URI: "b", Data: []byte("b")}},
+		},
+		{name: "request", body: `{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"a"}}`},
+		{name: "no blobs", body: `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"x"}]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mcp.Blobs([]byte(tt.body), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Blobs =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlobsErrors(t *testing.T) {
+	contents := func(blob string) string {
+		return `{"jsonrpc":"2.0","id":1,"result":{"contents":[{"uri":"file:///a","blob":"` + blob + `"}]}}`
+	}
+	tests := []struct {
+		name    string
+		body    string
+		maxSize int
+		want    string
+		is      error
+	}{
+		{name: "invalid base64", body: contents("not*base64"), want: `invalid base64 in resource "file:///a"`},
+		{
+			name:    "too large before decoding",
+			body:    contents(b64(bytes.Repeat([]byte("a"), 100))),
+			maxSize: 10,
+			want:    `resource "file:///a"`,
+			is:      mcp.ErrBlobTooLarge,
+		},
+		{
+			name:    "too large after decoding",
+			body:    contents(b64([]byte("abcdefghijk"))),
+			maxSize: 10,
+			is:      mcp.ErrBlobTooLarge,
+		},
+		{
+			name: "content block",
+			body: `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"image","data":"` +
+				b64(pngHeader) + `"}]}}`,
+			maxSize: 4,
+			want:    "content block",
+			is:      mcp.ErrBlobTooLarge,
+		},
+		{name: "not JSON", body: `{`, want: "failed to decode MCP body"},
+		{name: "not JSON-RPC", body: `{"a":1}`, is: mcp.ErrNotJSONRPC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := mcp.Blobs([]byte(tt.body), tt.maxSize)
+			if err == nil || !strings.Contains(err.Error(), tt.want) || (tt.is != nil && !errors.Is(err, tt.is)) {
+				t.Errorf("Blobs error = %v, want %q (%v)", err, tt.want, tt.is)
+			}
+		})
+	}
+}
+
+func TestBlobsSizeLimit(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 570)
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "exact", encoded: b64(data)},
+		{name: "unpadded", encoded: strings.TrimRight(b64(data[:569]), "=")},
+		// MIME-style wrapping lengthens the encoding without growing the data.
+		{name: "wrapped", encoded: wrap(b64(data), 4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"jsonrpc":"2.0","id":1,"result":{"contents":[{"uri":"a","blob":"` +
+				strings.NewReplacer("\r", `\r`, "\n", `\n`).Replace(tt.encoded) + `"}]}}`
+			blobs, err := mcp.Blobs([]byte(body), len(data))
+			if err != nil || len(blobs) != 1 {
+				t.Fatalf("Blobs = %v, %v; want the blob within the limit", blobs, err)
+			}
+		})
+	}
+}
+
+// wrap inserts CRLF every n characters of s.
+func wrap(s string, n int) string {
+	var b strings.Builder
+	for len(s) > n {
+		b.WriteString(s[:n] + "\r\n")
+		s = s[n:]
+	}
+	b.WriteString(s)
+
+	return b.String()
+}
+
+func TestRewriteBlobs(t *testing.T) {
+	body := `{"jsonrpc":"2.0","id":1,"result":{"content":[` +
+		`{"type":"image","data":"` + b64(pngHeader) + `","mimeType":"image/jpeg"},` +
+		`{"type":"resource","resource":{"uri":"file:///secret","blob":"` + b64([]byte("secret")) + `",` +
+		`"mimeType":"text/plain"}},` +
+		`{"type":"text","text":"kept"}]}}`
+
+	out, changed, err := mcp.RewriteBlobs([]byte(body), 0, func(b *mcp.Blob) bool {
+		switch {
+		case b.URI == "file:///secret":
+			b.Data = []byte("[redacted]")
+			b.MIMEType = ""
+			return true
+		case b.SniffedMIMEType() != b.MIMEType:
+			b.MIMEType = b.SniffedMIMEType()
+			return true
+		}
+		return false
+	})
+	if err != nil || !changed {
+		t.Fatalf("RewriteBlobs = %s, %t, %v", out, changed, err)
+	}
+	want := `{"id":1,"jsonrpc":"2.0","result":{"content":[` +
+		`{"data":"` + b64(pngHeader) + `","mimeType":"image/png","type":"image"},` +
+		`{"resource":{"blob":"` + b64([]byte("[redacted]")) + `","uri":"file:///secret"},"type":"resource"},` +
+		`{"text":"kept","type":"text"}]}}`
+	if string(out) != want {
+		t.Errorf("RewriteBlobs =\n%s\nwant\n%s", out, want)
+	}
+
+	// Unchanged bodies are returned as is, without re-encoding.
+	out, changed, err = mcp.RewriteBlobs([]byte(body), 0, func(*mcp.Blob) bool { return false })
+	if err != nil || changed || string(out) != body {
+		t.Errorf("RewriteBlobs without changes = %s, %t, %v; want the original body", out, changed, err)
+	}
+
+	// An error on any blob leaves the body untouched.
+	bad := `[` + body + `,{"jsonrpc":"2.0","id":2,"result":{"contents":[{"uri":"b","blob":"%%"}]}}]`
+	calls := 0
+	out, changed, err = mcp.RewriteBlobs([]byte(bad), 0, func(b *mcp.Blob) bool {
+		calls++
+		b.Data = nil
+		return true
+	})
+	if err == nil || changed || string(out) != bad {
+		t.Errorf("RewriteBlobs with an invalid blob = %s, %t, %v; want the original body and an error",
+			out, changed, err)
+	}
+	if calls != 2 {
+		t.Errorf("fn called %d times, want 2 before the invalid blob", calls)
+	}
+}
+
+func TestDecodeBase64(t *testing.T) {
+	data := []byte{0xfb, 0xff, 0xbf, 'h', 'i'}
+	tests := []struct {
+		name    string
+		encoded string
+		wantErr bool
+	}{
+		{name: "standard", encoded: base64.StdEncoding.EncodeToString(data)},
+		{name: "unpadded", encoded: base64.RawStdEncoding.EncodeToString(data)},
+		{name: "URL-safe", encoded: base64.URLEncoding.EncodeToString(data)},
+		{name: "URL-safe unpadded", encoded: base64.RawURLEncoding.EncodeToString(data)},
+		{name: "line breaks", encoded: wrap(base64.StdEncoding.EncodeToString(data), 3)},
+		{name: "LF only", encoded: strings.ReplaceAll(wrap(base64.StdEncoding.EncodeToString(data), 2), "\r", "")},
+		{name: "invalid characters", encoded: "a*b=", wantErr: true},
+		{name: "mixed alphabets", encoded: "+_==", wantErr: true},
+		{name: "truncated", encoded: "A", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mcp.DecodeBase64(tt.encoded)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("DecodeBase64(%q) = %v, want an error", tt.encoded, got)
+				}
+				return
+			}
+			if err != nil || !bytes.Equal(got, data) {
+				t.Errorf("DecodeBase64(%q) = %v, %v; want %v", tt.encoded, got, err, data)
+			}
+		})
+	}
+}
+
+func TestSniffMIMEType(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want string
+	}{
+		{data: pngHeader, want: "image/png"},
+		{data: []byte("%PDF-1.7"), want: "application/pdf"},
+		{data: []byte("hello"), want: "text/plain"},
+		{data: []byte{0, 1, 2}, want: "application/octet-stream"},
+		{data: nil, want: "text/plain"},
+	}
+	for _, tt := range tests {
+		if got := mcp.SniffMIMEType(tt.data); got != tt.want {
+			t.Errorf("SniffMIMEType(%q) = %s, want %s", tt.data, got, tt.want)
+		}
+		b := mcp.Blob{Data: tt.data}
+		if got := b.SniffedMIMEType(); got != tt.want {
+			t.Errorf("SniffedMIMEType(%q) = %s, want %s", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestParseDataURI(t *testing.T) {
+	tests := []struct {
+		uri      string
+		wantType string
+		wantData string
+		wantErr  string
+	}{
+		{uri: "data:image/png;base64," + b64(pngHeader), wantType: "image/png", wantData: string(pngHeader)},
+		{uri: "data:,Hello%2C%20World", wantType: "text/plain", wantData: "Hello, World"},
+		{uri: "data:;base64,SGk=", wantType: "text/plain", wantData: "Hi"},
+		{uri: "data:;charset=utf-8,caf%C3%A9", wantType: "text/plain; charset=utf-8", wantData: "café"},
+		{uri: "data:Text/HTML;charset=UTF-8,<b>", wantType: "text/html; charset=UTF-8", wantData: "<b>"},
+		{uri: "data:application/json;base64,eyJhIjoxfQ", wantType: "application/json", wantData: `{"a":1}`},
+		{uri: "data:text/plain,a,b", wantType: "text/plain", wantData: "a,b"},
+		{uri: "https://example.com/a.png", wantErr: "not a data URI"},
+		{uri: "data:image/png;base64", wantErr: "missing comma"},
+		{uri: "data:image/;base64,AA==", wantErr: "invalid data URI media type"},
+		{uri: "data:image/png;base64,***", wantErr: "invalid data URI payload"},
+		{uri: "data:,%zz", wantErr: "invalid data URI payload"},
+	}
+	for _, tt := range tests {
+		mediaType, data, err := mcp.ParseDataURI(tt.uri)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseDataURI(%q) error = %v, want %q", tt.uri, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || mediaType != tt.wantType || string(data) != tt.wantData {
+			t.Errorf("ParseDataURI(%q) = %q, %q, %v; want %q, %q",
+				tt.uri, mediaType, data, err, tt.wantType, tt.wantData)
+		}
+	}
+}
+
+func TestDataURIRoundTrip(t *testing.T) {
+	uri := mcp.DataURI("image/png", pngHeader)
+	if want := "data:image/png;base64," + b64(pngHeader); uri != want {
+		t.Errorf("DataURI = %s, want %s", uri, want)
+	}
+	mediaType, data, err := mcp.ParseDataURI(uri)
+	if err != nil || mediaType != "image/png" || !bytes.Equal(data, pngHeader) {
+		t.Errorf("ParseDataURI(DataURI) = %q, %q, %v", mediaType, data, err)
+	}
+}
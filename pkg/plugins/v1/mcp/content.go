@@ -0,0 +1,179 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// TextFunc receives a piece of user-visible MCP text and returns its (possibly modified) replacement.
+type TextFunc func(text string) string
+
+// RewriteText applies fn to the user-visible text carried by the MCP message(s) in body and
+// returns the re-encoded body. The second return value reports whether anything changed;
+// when it is false the original body is returned untouched.
+//
+// The following locations are considered user-visible text:
+//   - every string value inside params.arguments (tools/call, prompts/get)
+//   - the text of "text" content blocks in result.content and result.messages
+//   - the text of embedded resources and of result.contents (resources/read)
+//   - every string value inside result.structuredContent
+//
+// Identifiers such as method names, tool names, URIs and MIME types are never passed to fn.
+func RewriteText(body []byte, fn TextFunc) ([]byte, bool, error) {
+	return rewriteMessages(body, func(msg map[string]any) bool {
+		return rewriteMessage(msg, fn)
+	})
+}
+
+// rewriteMessages applies fn to each JSON-RPC message in body (a single message or a batch) and
+// re-encodes the body when fn reports a change.
+func rewriteMessages(body []byte, fn func(msg map[string]any) bool) ([]byte, bool, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return body, false, nil
+	}
+
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return body, false, fmt.Errorf("failed to decode MCP body: %w", err)
+	}
+
+	changed := false
+	switch v := doc.(type) {
+	case []any:
+		for _, item := range v {
+			if obj, ok := item.(map[string]any); ok && isJSONRPC(obj) {
+				changed = fn(obj) || changed
+			}
+		}
+	case map[string]any:
+		if !isJSONRPC(v) {
+			return body, false, ErrNotJSONRPC
+		}
+		changed = fn(v)
+	default:
+		return body, false, ErrNotJSONRPC
+	}
+
+	if !changed {
+		return body, false, nil
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return body, false, fmt.Errorf("failed to encode MCP body: %w", err)
+	}
+
+	return out, true, nil
+}
+
+// Texts returns the user-visible text carried by the MCP message(s) in body,
+// using the same rules as RewriteText.
+func Texts(body []byte) ([]string, error) {
+	var texts []string
+	_, _, err := RewriteText(body, func(s string) string {
+		texts = append(texts, s)
+		return s
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return texts, nil
+}
+
+func isJSONRPC(obj map[string]any) bool {
+	v, _ := obj["jsonrpc"].(string)
+	return v == JSONRPCVersion
+}
+
+func rewriteMessage(msg map[string]any, fn TextFunc) bool {
+	changed := false
+
+	if params, ok := msg["params"].(map[string]any); ok {
+		if args, ok := params["arguments"]; ok {
+			var c bool
+			params["arguments"], c = rewriteAll(args, fn)
+			changed = changed || c
+		}
+	}
+
+	if result, ok := msg["result"].(map[string]any); ok {
+		for _, key := range []string{"content", "contents"} {
+			if blocks, ok := result[key].([]any); ok {
+				for _, b := range blocks {
+					changed = rewriteBlock(b, fn) || changed
+				}
+			}
+		}
+		if messages, ok := result["messages"].([]any); ok {
+			for _, m := range messages {
+				if pm, ok := m.(map[string]any); ok {
+					changed = rewriteBlock(pm["content"], fn) || changed
+				}
+			}
+		}
+		if sc, ok := result["structuredContent"]; ok {
+			var c bool
+			result["structuredContent"], c = rewriteAll(sc, fn)
+			changed = changed || c
+		}
+	}
+
+	return changed
+}
+
+// rewriteBlock rewrites a content block or resource contents object.
+func rewriteBlock(v any, fn TextFunc) bool {
+	block, ok := v.(map[string]any)
+	if !ok {
+		return false
+	}
+
+	changed := false
+	if text, ok := block["text"].(string); ok {
+		typ, _ := block["type"].(string)
+		_, isResource := block["uri"]
+		if typ == "text" || isResource {
+			if out := fn(text); out != text {
+				block["text"] = out
+				changed = true
+			}
+		}
+	}
+	if res, ok := block["resource"]; ok {
+		changed = rewriteBlock(res, fn) || changed
+	}
+
+	return changed
+}
+
+// rewriteAll applies fn to every string found in v, recursively.
+func rewriteAll(v any, fn TextFunc) (any, bool) {
+	switch t := v.(type) {
+	case string:
+		out := fn(t)
+		return out, out != t
+	case map[string]any:
+		changed := false
+		for k, child := range t {
+			var c bool
+			t[k], c = rewriteAll(child, fn)
+			changed = changed || c
+		}
+		return t, changed
+	case []any:
+		changed := false
+		for i, child := range t {
+			var c bool
+			t[i], c = rewriteAll(child, fn)
+			changed = changed || c
+		}
+		return t, changed
+	default:
+		return v, false
+	}
+}
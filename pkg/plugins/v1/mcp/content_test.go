@@ -0,0 +1,114 @@
+package mcp_test
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+func TestTexts(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string // Sorted, as objects are walked in no particular order.
+		wantErr error
+	}{
+		{
+			name: "tool arguments",
+			body: `{"jsonrpc":"2.0","id":1,"method":"tools/call",` +
+				`"params":{"name":"search","arguments":{"q":"one","opts":{"tags":["two"],"n":3}}}}`,
+			want: []string{"one", "two"},
+		},
+		{
+			name: "content blocks",
+			body: `{"jsonrpc":"2.0","id":1,"result":{"content":[` +
+				`{"type":"text","text":"one"},{"type":"image","text":"skipped","mimeType":"image/png"},` +
+				`{"type":"resource","resource":{"uri":"file:///a","text":"two","mimeType":"text/plain"}}]}}`,
+			want: []string{"one", "two"},
+		},
+		{
+			name: "resource contents",
+			body: `{"jsonrpc":"2.0","id":1,"result":{"contents":[{"uri":"file:///a","text":"one"}]}}`,
+			want: []string{"one"},
+		},
+		{
+			name: "prompt messages",
+			body: `{"jsonrpc":"2.0","id":1,"result":{"messages":[` +
+				`{"role":"user","content":{"type":"text","text":"one"}}]}}`,
+			want: []string{"one"},
+		},
+		{
+			name: "structured content",
+			body: `{"jsonrpc":"2.0","id":1,"result":{"structuredContent":{"a":["one",{"b":"two"}],"n":1}}}`,
+			want: []string{"one", "two"},
+		},
+		{
+			name: "batch skips entries that are not JSON-RPC",
+			body: `[{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"arguments":{"q":"one"}}},` +
+				`{"params":{"arguments":{"q":"skipped"}}}]`,
+			want: []string{"one"},
+		},
+		{
+			name: "protocol fields",
+			body: `{"jsonrpc":"2.0","id":"x","method":"resources/read","params":{"uri":"file:///a"}}`,
+		},
+		{name: "empty body"},
+		{name: "not JSON-RPC", body: `{"params":{"arguments":{"q":"one"}}}`, wantErr: mcp.ErrNotJSONRPC},
+		{name: "scalar", body: `"one"`, wantErr: mcp.ErrNotJSONRPC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mcp.Texts([]byte(tt.body))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Texts error = %v, want %v", err, tt.wantErr)
+			}
+			slices.Sort(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Texts = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteText(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		want        string
+		wantChanged bool
+		wantErr     bool
+	}{
+		{
+			name:        "rewrites text and re-encodes",
+			body:        `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"secret"}],"n":1.50}}`,
+			want:        `{"id":1,"jsonrpc":"2.0","result":{"content":[{"text":"SECRET","type":"text"}],"n":1.50}}`,
+			wantChanged: true,
+		},
+		{
+			name: "unchanged body returned as is",
+			body: `{ "jsonrpc": "2.0", "id": 1, "result": {"content": [{"type": "text", "text": "OK"}]} }`,
+			want: `{ "jsonrpc": "2.0", "id": 1, "result": {"content": [{"type": "text", "text": "OK"}]} }`,
+		},
+		{
+			name:    "invalid JSON",
+			body:    `{"jsonrpc":`,
+			want:    `{"jsonrpc":`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := mcp.RewriteText([]byte(tt.body), strings.ToUpper)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RewriteText error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want || changed != tt.wantChanged {
+				t.Errorf("RewriteText = %s, %v; want %s, %v", got, changed, tt.want, tt.wantChanged)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Standard JSON-RPC 2.0 error codes. Codes from CodeServerError down to -32099 are reserved for
+// implementation-defined server errors, such as a plugin denying a request.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeServerError    = -32000
+)
+
+// nullID is the id of errors answering a message whose id is unknown.
+var nullID = json.RawMessage("null")
+
+// ErrorResponse returns a JSON-RPC error response body answering the message(s) in original with
+// e, echoing their ids. A batch is answered with a batch holding one error per request in it;
+// bodies that are not JSON-RPC, or carry only notifications, are answered with a single error
+// with a null id, as JSON-RPC prescribes for requests whose id cannot be determined.
+func ErrorResponse(original []byte, e *Error) []byte {
+	var ids []json.RawMessage
+	msgs, err := Parse(original)
+	if err == nil {
+		for _, m := range msgs {
+			if len(m.ID) > 0 {
+				ids = append(ids, m.ID)
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		body, _ := json.Marshal(Message{JSONRPC: JSONRPCVersion, ID: nullID, Error: e})
+		return body
+	}
+	if len(msgs) == 1 && bytes.TrimSpace(original)[0] != '[' {
+		body, _ := json.Marshal(Message{JSONRPC: JSONRPCVersion, ID: ids[0], Error: e})
+		return body
+	}
+
+	batch := make([]Message, len(ids))
+	for i, id := range ids {
+		batch[i] = Message{JSONRPC: JSONRPCVersion, ID: id, Error: e}
+	}
+	body, _ := json.Marshal(batch)
+
+	return body
+}
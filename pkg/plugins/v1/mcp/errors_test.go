@@ -0,0 +1,80 @@
+package mcp_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+func TestErrorResponse(t *testing.T) {
+	denied := &mcp.Error{Code: mcp.CodeServerError, Message: "denied"}
+	tests := []struct {
+		name     string
+		original string
+		e        *mcp.Error
+		want     string
+	}{
+		{
+			name:     "request",
+			original: `{"jsonrpc":"2.0","id":7,"method":"tools/call"}`,
+			e:        denied,
+			want:     `{"jsonrpc":"2.0","id":7,"error":{"code":-32000,"message":"denied"}}`,
+		},
+		{
+			name:     "string id",
+			original: ` {"jsonrpc":"2.0","id":"abc","method":"ping"}`,
+			e:        denied,
+			want:     `{"jsonrpc":"2.0","id":"abc","error":{"code":-32000,"message":"denied"}}`,
+		},
+		{
+			name: "batch",
+			original: `[{"jsonrpc":"2.0","id":1,"method":"a"},{"jsonrpc":"2.0","method":"n"},` +
+				`{"jsonrpc":"2.0","id":2,"method":"b"}]`,
+			e: denied,
+			want: `[{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"denied"}},` +
+				`{"jsonrpc":"2.0","id":2,"error":{"code":-32000,"message":"denied"}}]`,
+		},
+		{
+			name:     "batch of one",
+			original: `[{"jsonrpc":"2.0","id":1,"method":"a"}]`,
+			e:        denied,
+			want:     `[{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"denied"}}]`,
+		},
+		{
+			name:     "notification only",
+			original: `{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+			e:        denied,
+			want:     `{"jsonrpc":"2.0","id":null,"error":{"code":-32000,"message":"denied"}}`,
+		},
+		{
+			name:     "not JSON-RPC",
+			original: `hello`,
+			e:        &mcp.Error{Code: mcp.CodeParseError, Message: "parse error"},
+			want:     `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"}}`,
+		},
+		{
+			name:     "empty body",
+			original: ``,
+			e:        denied,
+			want:     `{"jsonrpc":"2.0","id":null,"error":{"code":-32000,"message":"denied"}}`,
+		},
+		{
+			name:     "error data",
+			original: `{"jsonrpc":"2.0","id":1,"method":"a"}`,
+			e:        &mcp.Error{Code: mcp.CodeInvalidParams, Message: "bad", Data: json.RawMessage(`{"field":"x"}`)},
+			want:     `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"bad","data":{"field":"x"}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mcp.ErrorResponse([]byte(tt.original), tt.e)
+			if string(got) != tt.want {
+				t.Errorf("ErrorResponse =\n%s\nwant\n%s", got, tt.want)
+			}
+			if _, err := mcp.Parse(got); err != nil {
+				t.Errorf("ErrorResponse is not JSON-RPC: %v", err)
+			}
+		})
+	}
+}
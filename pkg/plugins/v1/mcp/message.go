@@ -0,0 +1,177 @@
+// Package mcp provides helpers for inspecting and rewriting Model Context Protocol (MCP)
+// JSON-RPC messages carried in HTTP request and response bodies.
+//
+// The helpers operate on raw body bytes so they can be used from HandleRequest and
+// HandleResponse without committing to a full MCP schema.
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// JSONRPCVersion is the only JSON-RPC version used by MCP.
+const JSONRPCVersion = "2.0"
+
+// Well-known MCP method names.
+const (
+	MethodInitialize    = "initialize"
+	MethodToolsList     = "tools/list"
+	MethodToolsCall     = "tools/call"
+	MethodResourcesList = "resources/list"
+	MethodResourcesRead = "resources/read"
+	MethodPromptsList   = "prompts/list"
+	MethodPromptsGet    = "prompts/get"
+)
+
+// ErrNotJSONRPC is returned when a body is valid JSON but is not a JSON-RPC message.
+var ErrNotJSONRPC = errors.New("body is not a JSON-RPC message")
+
+// Message is a single JSON-RPC 2.0 message: a request, a notification, or a response.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC 2.0 error object.
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// IsRequest reports whether the message is a request (has a method and an id).
+func (m *Message) IsRequest() bool {
+	return m.Method != "" && len(m.ID) > 0
+}
+
+// IsNotification reports whether the message is a notification (has a method but no id).
+func (m *Message) IsNotification() bool {
+	return m.Method != "" && len(m.ID) == 0
+}
+
+// IsResponse reports whether the message is a response (has a result or an error).
+func (m *Message) IsResponse() bool {
+	return m.Method == "" && (len(m.Result) > 0 || m.Error != nil)
+}
+
+// ToolName returns the tool name of a tools/call request, or an empty string for any other message.
+func (m *Message) ToolName() string {
+	if m.Method != MethodToolsCall || len(m.Params) == 0 {
+		return ""
+	}
+
+	var params struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(m.Params, &params); err != nil {
+		return ""
+	}
+
+	return params.Name
+}
+
+// ResourceURI returns the URI of a resources/read request, or an empty string for any other message.
+func (m *Message) ResourceURI() string {
+	if m.Method != MethodResourcesRead || len(m.Params) == 0 {
+		return ""
+	}
+
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(m.Params, &params); err != nil {
+		return ""
+	}
+
+	return params.URI
+}
+
+// Parse decodes body as one or more JSON-RPC messages.
+// A JSON-RPC batch (a JSON array) yields one entry per element.
+func Parse(body []byte) ([]*Message, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, ErrNotJSONRPC
+	}
+
+	if trimmed[0] == '[' {
+		var batch []*Message
+		if err := json.Unmarshal(trimmed, &batch); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON-RPC batch: %w", err)
+		}
+		for _, m := range batch {
+			if m == nil || m.JSONRPC != JSONRPCVersion {
+				return nil, ErrNotJSONRPC
+			}
+		}
+
+		return batch, nil
+	}
+
+	m, err := ParseOne(trimmed)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Message{m}, nil
+}
+
+// ParseOne decodes body as a single, non-batched JSON-RPC message.
+func ParseOne(body []byte) (*Message, error) {
+	var m Message
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON-RPC message: %w", err)
+	}
+	if m.JSONRPC != JSONRPCVersion {
+		return nil, ErrNotJSONRPC
+	}
+
+	return &m, nil
+}
+
+// ToolName is a convenience that parses body and returns the tool name of the first tools/call request found.
+func ToolName(body []byte) string {
+	msgs, err := Parse(body)
+	if err != nil {
+		return ""
+	}
+	for _, m := range msgs {
+		if name := m.ToolName(); name != "" {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// RenameTool rewrites the tool name of tools/call requests in body from "from" to "to", or of every
+// tools/call request when from is empty. It returns the body, re-encoded if anything changed, and
+// whether it changed.
+func RenameTool(body []byte, from, to string) ([]byte, bool, error) {
+	if to == "" {
+		return body, false, errors.New("tool name cannot be empty")
+	}
+
+	return rewriteMessages(body, func(msg map[string]any) bool {
+		if method, _ := msg["method"].(string); method != MethodToolsCall {
+			return false
+		}
+		params, ok := msg["params"].(map[string]any)
+		if !ok {
+			return false
+		}
+		name, _ := params["name"].(string)
+		if name == to || (from != "" && name != from) {
+			return false
+		}
+		params["name"] = to
+		return true
+	})
+}
@@ -0,0 +1,143 @@
+package mcp_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    int
+		wantErr error // Matched with errors.Is, or errAny for any error.
+	}{
+		{name: "single message", body: ` {"jsonrpc":"2.0","id":1,"method":"ping"} `, want: 1},
+		{
+			name: "batch",
+			body: `[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"x"}]`,
+			want: 2,
+		},
+		{name: "empty body", body: "  ", wantErr: mcp.ErrNotJSONRPC},
+		{name: "wrong version", body: `{"jsonrpc":"1.0","id":1}`, wantErr: mcp.ErrNotJSONRPC},
+		{
+			name:    "batch with a non JSON-RPC entry",
+			body:    `[{"jsonrpc":"2.0","id":1},{"id":2}]`,
+			wantErr: mcp.ErrNotJSONRPC,
+		},
+		{name: "batch with a null entry", body: `[null]`, wantErr: mcp.ErrNotJSONRPC},
+		{name: "invalid JSON", body: `{"jsonrpc":`, wantErr: errAny},
+		{name: "invalid batch", body: `[1]`, wantErr: errAny},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, err := mcp.Parse([]byte(tt.body))
+			if tt.wantErr == errAny && err != nil {
+				err = errAny
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse error = %v, want %v", err, tt.wantErr)
+			}
+			if len(msgs) != tt.want {
+				t.Errorf("Parse returned %d messages, want %d", len(msgs), tt.want)
+			}
+		})
+	}
+}
+
+// errAny stands for any error in test tables.
+var errAny = errors.New("any error")
+
+func TestMessage(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		request      bool
+		notification bool
+		response     bool
+		tool         string
+		uri          string
+	}{
+		{
+			name:    "tools/call request",
+			body:    `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search","arguments":{}}}`,
+			request: true,
+			tool:    "search",
+		},
+		{
+			name:    "resources/read request",
+			body:    `{"jsonrpc":"2.0","id":"a","method":"resources/read","params":{"uri":"file:///etc/hosts"}}`,
+			request: true,
+			uri:     "file:///etc/hosts",
+		},
+		{
+			name:    "tools/call with malformed params",
+			body:    `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":["search"]}`,
+			request: true,
+		},
+		{
+			name:         "notification",
+			body:         `{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+			notification: true,
+		},
+		{
+			name:     "result",
+			body:     `{"jsonrpc":"2.0","id":1,"result":{}}`,
+			response: true,
+		},
+		{
+			name:     "error",
+			body:     `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"not found"}}`,
+			response: true,
+		},
+		{
+			name: "neither",
+			body: `{"jsonrpc":"2.0","id":1}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := mcp.ParseOne([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.IsRequest() != tt.request || m.IsNotification() != tt.notification || m.IsResponse() != tt.response {
+				t.Errorf("request %v, notification %v, response %v; want %v, %v, %v",
+					m.IsRequest(), m.IsNotification(), m.IsResponse(), tt.request, tt.notification, tt.response)
+			}
+			if got := m.ToolName(); got != tt.tool {
+				t.Errorf("ToolName = %q, want %q", got, tt.tool)
+			}
+			if got := m.ResourceURI(); got != tt.uri {
+				t.Errorf("ResourceURI = %q, want %q", got, tt.uri)
+			}
+		})
+	}
+}
+
+func TestToolName(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "first tools/call in a batch",
+			body: `[{"jsonrpc":"2.0","id":1,"method":"ping"},` +
+				`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"a"}},` +
+				`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"b"}}]`,
+			want: "a",
+		},
+		{name: "no tools/call", body: `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`},
+		{name: "not JSON-RPC", body: `{"name":"a"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mcp.ToolName([]byte(tt.body)); got != tt.want {
+				t.Errorf("ToolName = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,391 @@
+package mdns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// servicesName is the DNS-SD name enumerating the service types advertised on the network.
+const servicesName = "_services._dns-sd._udp." + domain
+
+// legacyTTL caps the TTLs of answers to legacy unicast queries, as RFC 6762 section 6.7 asks.
+const legacyTTL = 10 * time.Second
+
+// Advertiser answers mDNS queries for one plugin instance. It is an mcpdpluginsv1.Announcer:
+// an AnnouncementServing starts or refreshes the advertisement, and an AnnouncementStopping
+// withdraws it. It is safe for concurrent use.
+type Advertiser struct {
+	ifi  *net.Interface
+	ttl  time.Duration
+	name string
+
+	mu      sync.Mutex
+	conn    *net.UDPConn
+	done    chan struct{}
+	records records
+}
+
+// Option configures an Advertiser.
+type Option func(*Advertiser) error
+
+// WithInterface advertises on ifi only (defaults to the system's default multicast interface).
+func WithInterface(ifi *net.Interface) Option {
+	return func(a *Advertiser) error {
+		if ifi == nil {
+			return fmt.Errorf("interface cannot be nil")
+		}
+		a.ifi = ifi
+		return nil
+	}
+}
+
+// WithTTL sets the time-to-live of the advertised records (default DefaultTTL).
+func WithTTL(ttl time.Duration) Option {
+	return func(a *Advertiser) error {
+		if ttl < time.Second {
+			return fmt.Errorf("ttl must be at least 1s")
+		}
+		a.ttl = ttl
+		return nil
+	}
+}
+
+// WithInstanceName sets the DNS-SD instance name (defaults to the plugin name and process ID, so
+// several instances of one plugin can run side by side).
+func WithInstanceName(name string) Option {
+	return func(a *Advertiser) error {
+		if name == "" {
+			return fmt.Errorf("instance name cannot be empty")
+		}
+		a.name = instanceLabel(name)
+		return nil
+	}
+}
+
+// NewAdvertiser returns an Advertiser, not yet advertising anything.
+func NewAdvertiser(opts ...Option) (*Advertiser, error) {
+	a := &Advertiser{ttl: DefaultTTL}
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// Announce implements mcpdpluginsv1.Announcer, joining the mDNS group on the first
+// AnnouncementServing and leaving it on AnnouncementStopping.
+func (a *Advertiser) Announce(_ context.Context, ann mcpdpluginsv1.Announcement) error {
+	if ann.Status == mcpdpluginsv1.AnnouncementStopping {
+		return a.Close()
+	}
+	recs, err := a.recordsFor(ann)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.conn == nil {
+		conn, err := net.ListenMulticastUDP("udp4", a.ifi, group)
+		if err != nil {
+			return fmt.Errorf("failed to join mDNS group: %w", err)
+		}
+		a.conn, a.done = conn, make(chan struct{})
+		go a.serve(conn, a.done)
+	}
+	a.records = recs
+
+	// Unsolicited announcement, so browsers already running see the plugin at once.
+	return send(a.conn, recs.all(recs.ttl), group)
+}
+
+// Close withdraws the advertisement with a goodbye packet and stops answering queries.
+func (a *Advertiser) Close() error {
+	a.mu.Lock()
+	conn, done, recs := a.conn, a.done, a.records
+	a.conn = nil
+	var err error
+	if conn != nil {
+		err = send(conn, recs.all(0), group)
+	}
+	a.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	if cerr := conn.Close(); err == nil {
+		err = cerr
+	}
+	<-done
+
+	return err
+}
+
+// serve answers the queries read from conn until it is closed.
+func (a *Advertiser) serve(conn *net.UDPConn, done chan struct{}) {
+	defer close(done)
+
+	buf := make([]byte, maxPacket)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		var q dnsmessage.Message
+		if err := q.Unpack(buf[:n]); err != nil || q.Response {
+			continue
+		}
+
+		a.mu.Lock()
+		if a.conn == conn {
+			a.answer(conn, q, src)
+		}
+		a.mu.Unlock()
+	}
+}
+
+// answer replies to query q from src, if it asks about the advertised records. Queries from
+// ports other than 5353 are legacy unicast queries (RFC 6762 section 6.7), answered to the
+// sender with the query's ID and questions; others are answered to the group, or to the sender
+// when every question asks for a unicast response.
+func (a *Advertiser) answer(conn *net.UDPConn, q dnsmessage.Message, src *net.UDPAddr) {
+	legacy := src.Port != group.Port
+	ttl := a.records.ttl
+	if legacy {
+		ttl = min(ttl, legacyTTL)
+	}
+
+	var answers, extra []dnsmessage.Resource
+	unicast := true
+	for _, qq := range q.Questions {
+		ans, add := a.records.match(qq, ttl)
+		answers, extra = append(answers, ans...), append(extra, add...)
+		unicast = unicast && qq.Class&classUnicastResponse != 0
+	}
+	if len(answers) == 0 {
+		return
+	}
+
+	dst := group
+	if legacy || unicast {
+		dst = src
+	}
+	resp := dnsmessage.Message{
+		Header:      dnsmessage.Header{Response: true, Authoritative: true},
+		Answers:     answers,
+		Additionals: extra,
+	}
+	if legacy {
+		// Legacy resolvers would misread the cache-flush bit as part of the class.
+		resp.ID, resp.Questions = q.ID, q.Questions
+		for _, rrs := range [][]dnsmessage.Resource{resp.Answers, resp.Additionals} {
+			for i := range rrs {
+				rrs[i].Header.Class &^= classUnicastResponse
+			}
+		}
+	}
+	if b, err := resp.Pack(); err == nil {
+		_, _ = conn.WriteToUDP(b, dst)
+	}
+}
+
+// send writes an unsolicited response carrying answers to dst on conn.
+func send(conn *net.UDPConn, answers []dnsmessage.Resource, dst *net.UDPAddr) error {
+	msg := dnsmessage.Message{
+		Header:  dnsmessage.Header{Response: true, Authoritative: true},
+		Answers: answers,
+	}
+	b, err := msg.Pack()
+	if err != nil {
+		return fmt.Errorf("failed to encode mDNS announcement: %w", err)
+	}
+	if _, err := conn.WriteToUDP(b, dst); err != nil {
+		return fmt.Errorf("failed to send mDNS announcement: %w", err)
+	}
+
+	return nil
+}
+
+// recordsFor returns the records advertising the plugin ann describes.
+func (a *Advertiser) recordsFor(ann mcpdpluginsv1.Announcement) (records, error) {
+	name := a.name
+	if name == "" {
+		plugin := ann.Plugin
+		if plugin == "" {
+			plugin = "plugin"
+		}
+		name = instanceLabel(fmt.Sprintf("%s-%d", plugin, ann.PID))
+	}
+	host, _ := os.Hostname()
+	host, _, _ = strings.Cut(host, ".")
+	if host == "" {
+		host = "localhost"
+	}
+
+	var port uint16
+	if ann.Network != "unix" {
+		if _, p, err := net.SplitHostPort(ann.Address); err == nil {
+			if n, err := strconv.ParseUint(p, 10, 16); err == nil {
+				port = uint16(n)
+			}
+		}
+	}
+
+	var err error
+	r := records{ttl: a.ttl, port: port, addrs: a.addrs()}
+	r.txt = Instance{
+		Plugin:     ann.Plugin,
+		Version:    ann.Version,
+		APIVersion: ann.APIVersion,
+		Flows:      ann.Flows,
+		Network:    ann.Network,
+		Address:    ann.Address,
+	}.txt()
+	if r.service, err = dnsmessage.NewName(serviceName); err != nil {
+		return records{}, err
+	}
+	if r.instance, err = dnsmessage.NewName(name + "." + serviceName); err != nil {
+		return records{}, fmt.Errorf("invalid instance name %q: %w", name, err)
+	}
+	if r.host, err = dnsmessage.NewName(instanceLabel(host) + "." + domain); err != nil {
+		return records{}, fmt.Errorf("invalid host name %q: %w", host, err)
+	}
+
+	return r, nil
+}
+
+// addrs returns the IPv4 addresses the host is reachable at: those of the advertising interface,
+// or of every interface, preferring non-loopback ones.
+func (a *Advertiser) addrs() []netip.Addr {
+	var ifaddrs []net.Addr
+	if a.ifi != nil {
+		ifaddrs, _ = a.ifi.Addrs()
+	} else {
+		ifaddrs, _ = net.InterfaceAddrs()
+	}
+
+	var addrs, loopback []netip.Addr
+	for _, ia := range ifaddrs {
+		ipn, ok := ia.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip, ok := netip.AddrFromSlice(ipn.IP)
+		if !ok || !ip.Unmap().Is4() {
+			continue
+		}
+		if ip = ip.Unmap(); ip.IsLoopback() {
+			loopback = append(loopback, ip)
+		} else {
+			addrs = append(addrs, ip)
+		}
+	}
+	if len(addrs) == 0 {
+		return loopback
+	}
+
+	return addrs
+}
+
+// records are the records advertising one instance.
+type records struct {
+	ttl      time.Duration
+	service  dnsmessage.Name
+	instance dnsmessage.Name
+	host     dnsmessage.Name
+	port     uint16
+	txt      []string
+	addrs    []netip.Addr
+}
+
+// match returns the answers to q and the additional records worth sending with them.
+func (r records) match(q dnsmessage.Question, ttl time.Duration) (answers, extra []dnsmessage.Resource) {
+	if r.instance.Length == 0 {
+		return nil, nil
+	}
+	wildcard := q.Type == dnsmessage.TypeALL
+	switch {
+	case sameName(q.Name, r.service) && (wildcard || q.Type == dnsmessage.TypePTR):
+		return []dnsmessage.Resource{r.ptr(ttl)}, append([]dnsmessage.Resource{r.srv(ttl), r.text(ttl)}, r.a(ttl)...)
+	case sameName(q.Name, r.instance):
+		if wildcard || q.Type == dnsmessage.TypeSRV {
+			answers = append(answers, r.srv(ttl))
+		}
+		if wildcard || q.Type == dnsmessage.TypeTXT {
+			answers = append(answers, r.text(ttl))
+		}
+		if len(answers) > 0 {
+			extra = r.a(ttl)
+		}
+		return answers, extra
+	case sameName(q.Name, r.host) && (wildcard || q.Type == dnsmessage.TypeA):
+		return r.a(ttl), nil
+	case strings.EqualFold(q.Name.String(), servicesName) && (wildcard || q.Type == dnsmessage.TypePTR):
+		return []dnsmessage.Resource{r.resource(q.Name, ttl, false, &dnsmessage.PTRResource{PTR: r.service})}, nil
+	}
+
+	return nil, nil
+}
+
+// all returns every record of the instance, with TTL ttl; a zero TTL makes a goodbye packet.
+func (r records) all(ttl time.Duration) []dnsmessage.Resource {
+	if r.instance.Length == 0 {
+		return nil
+	}
+
+	return append([]dnsmessage.Resource{r.ptr(ttl), r.srv(ttl), r.text(ttl)}, r.a(ttl)...)
+}
+
+func (r records) ptr(ttl time.Duration) dnsmessage.Resource {
+	return r.resource(r.service, ttl, false, &dnsmessage.PTRResource{PTR: r.instance})
+}
+
+func (r records) srv(ttl time.Duration) dnsmessage.Resource {
+	return r.resource(r.instance, ttl, true, &dnsmessage.SRVResource{Port: r.port, Target: r.host})
+}
+
+func (r records) text(ttl time.Duration) dnsmessage.Resource {
+	return r.resource(r.instance, ttl, true, &dnsmessage.TXTResource{TXT: r.txt})
+}
+
+func (r records) a(ttl time.Duration) []dnsmessage.Resource {
+	res := make([]dnsmessage.Resource, 0, len(r.addrs))
+	for _, ip := range r.addrs {
+		res = append(res, r.resource(r.host, ttl, true, &dnsmessage.AResource{A: ip.As4()}))
+	}
+
+	return res
+}
+
+// resource returns a record of name; unique records, owned by this instance alone, carry the
+// cache-flush bit.
+func (r records) resource(
+	name dnsmessage.Name,
+	ttl time.Duration,
+	unique bool,
+	body dnsmessage.ResourceBody,
+) dnsmessage.Resource {
+	class := dnsmessage.ClassINET
+	if unique {
+		class |= classUnicastResponse
+	}
+
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: name, Class: class, TTL: uint32(ttl / time.Second)},
+		Body:   body,
+	}
+}
@@ -0,0 +1,400 @@
+package mdns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// testAnnouncement describes a plugin serving over TCP.
+func testAnnouncement() mcpdpluginsv1.Announcement {
+	return mcpdpluginsv1.Announcement{
+		Status:     mcpdpluginsv1.AnnouncementServing,
+		Plugin:     "my-plugin",
+		Version:    "1.2.3",
+		APIVersion: mcpdpluginsv1.ProtoVersion,
+		Flows:      []string{"FLOW_REQUEST"},
+		Network:    "tcp",
+		Address:    "127.0.0.1:9123",
+		PID:        42,
+	}
+}
+
+// testRecords returns the records of testAnnouncement, reachable at 192.0.2.1.
+func testRecords(t *testing.T) records {
+	t.Helper()
+
+	a, err := NewAdvertiser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := a.recordsFor(testAnnouncement())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.addrs = []netip.Addr{netip.MustParseAddr("192.0.2.1")}
+
+	return r
+}
+
+func TestNewAdvertiser(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr string
+	}{
+		{name: "defaults"},
+		{
+			name: "options",
+			opts: []Option{WithTTL(time.Minute), WithInstanceName("dev"), WithInterface(&net.Interface{})},
+		},
+		{name: "nil interface", opts: []Option{WithInterface(nil)}, wantErr: "interface cannot be nil"},
+		{name: "short ttl", opts: []Option{WithTTL(time.Second - 1)}, wantErr: "ttl must be at least 1s"},
+		{name: "empty instance name", opts: []Option{WithInstanceName("")}, wantErr: "instance name cannot be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAdvertiser(tt.opts...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("NewAdvertiser error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewAdvertiser error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRecordsFor(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []Option
+		ann          func(a *mcpdpluginsv1.Announcement)
+		wantInstance string
+		wantPort     uint16
+	}{
+		{name: "tcp", wantInstance: "my-plugin-42." + serviceName, wantPort: 9123},
+		{
+			name:         "unix socket has no port",
+			ann:          func(a *mcpdpluginsv1.Announcement) { a.Network, a.Address = "unix", "/tmp/p:1.sock" },
+			wantInstance: "my-plugin-42." + serviceName,
+		},
+		{
+			name:         "unnamed plugin",
+			ann:          func(a *mcpdpluginsv1.Announcement) { a.Plugin = "" },
+			wantInstance: "plugin-42." + serviceName,
+			wantPort:     9123,
+		},
+		{
+			name:         "dotted plugin name",
+			ann:          func(a *mcpdpluginsv1.Announcement) { a.Plugin = "acme.auth" },
+			wantInstance: "acme-auth-42." + serviceName,
+			wantPort:     9123,
+		},
+		{
+			name:         "instance name",
+			opts:         []Option{WithInstanceName("dev.box")},
+			wantInstance: "dev-box." + serviceName,
+			wantPort:     9123,
+		},
+		{
+			name:         "port out of range",
+			ann:          func(a *mcpdpluginsv1.Announcement) { a.Address = "127.0.0.1:70000" },
+			wantInstance: "my-plugin-42." + serviceName,
+		},
+		{
+			name:         "long instance name",
+			opts:         []Option{WithInstanceName(strings.Repeat("a", 70))},
+			wantInstance: strings.Repeat("a", 63) + "." + serviceName,
+			wantPort:     9123,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewAdvertiser(tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ann := testAnnouncement()
+			if tt.ann != nil {
+				tt.ann(&ann)
+			}
+			r, err := a.recordsFor(ann)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := r.instance.String(); got != tt.wantInstance {
+				t.Errorf("instance = %s, want %s", got, tt.wantInstance)
+			}
+			if r.port != tt.wantPort {
+				t.Errorf("port = %d, want %d", r.port, tt.wantPort)
+			}
+			var inst Instance
+			inst.parseTXT(r.txt)
+			if inst.Plugin != ann.Plugin || inst.Network != ann.Network || inst.Address != ann.Address {
+				t.Errorf("TXT describes %+v, want the announced plugin", inst)
+			}
+			if r.ttl != DefaultTTL || !strings.HasSuffix(r.host.String(), "."+domain) {
+				t.Errorf("ttl = %s, host = %s; want DefaultTTL on a .local host", r.ttl, r.host)
+			}
+		})
+	}
+}
+
+// rrType returns the type of rr from its body, as Header.Type is only set by packing.
+func rrType(rr dnsmessage.Resource) dnsmessage.Type {
+	switch rr.Body.(type) {
+	case *dnsmessage.PTRResource:
+		return dnsmessage.TypePTR
+	case *dnsmessage.SRVResource:
+		return dnsmessage.TypeSRV
+	case *dnsmessage.TXTResource:
+		return dnsmessage.TypeTXT
+	case *dnsmessage.AResource:
+		return dnsmessage.TypeA
+	}
+
+	return 0
+}
+
+// types returns the record types of rrs.
+func types(rrs []dnsmessage.Resource) []dnsmessage.Type {
+	out := make([]dnsmessage.Type, 0, len(rrs))
+	for _, rr := range rrs {
+		out = append(out, rrType(rr))
+	}
+
+	return out
+}
+
+func TestRecordsMatch(t *testing.T) {
+	r := testRecords(t)
+	instance, host := r.instance.String(), r.host.String()
+	const (
+		ptr = dnsmessage.TypePTR
+		srv = dnsmessage.TypeSRV
+		txt = dnsmessage.TypeTXT
+		a   = dnsmessage.TypeA
+		all = dnsmessage.TypeALL
+	)
+	tests := []struct {
+		name      string
+		qname     string
+		qtype     dnsmessage.Type
+		wantAns   []dnsmessage.Type
+		wantExtra []dnsmessage.Type
+	}{
+		{
+			name:      "service pointer",
+			qname:     serviceName,
+			qtype:     ptr,
+			wantAns:   []dnsmessage.Type{ptr},
+			wantExtra: []dnsmessage.Type{srv, txt, a},
+		},
+		{
+			name:      "service any",
+			qname:     strings.ToUpper(serviceName),
+			qtype:     all,
+			wantAns:   []dnsmessage.Type{ptr},
+			wantExtra: []dnsmessage.Type{srv, txt, a},
+		},
+		{name: "service address", qname: serviceName, qtype: a},
+		{
+			name:      "instance service",
+			qname:     instance,
+			qtype:     srv,
+			wantAns:   []dnsmessage.Type{srv},
+			wantExtra: []dnsmessage.Type{a},
+		},
+		{
+			name:      "instance text",
+			qname:     instance,
+			qtype:     txt,
+			wantAns:   []dnsmessage.Type{txt},
+			wantExtra: []dnsmessage.Type{a},
+		},
+		{
+			name:      "instance any",
+			qname:     instance,
+			qtype:     all,
+			wantAns:   []dnsmessage.Type{srv, txt},
+			wantExtra: []dnsmessage.Type{a},
+		},
+		{name: "instance pointer", qname: instance, qtype: ptr},
+		{name: "host address", qname: host, qtype: a, wantAns: []dnsmessage.Type{a}},
+		{name: "host text", qname: host, qtype: txt},
+		{name: "service enumeration", qname: servicesName, qtype: ptr, wantAns: []dnsmessage.Type{ptr}},
+		{
+			name:    "service enumeration any case",
+			qname:   strings.ToUpper(servicesName),
+			qtype:   ptr,
+			wantAns: []dnsmessage.Type{ptr},
+		},
+		{name: "other name", qname: "_http._tcp.local.", qtype: ptr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := dnsmessage.Question{Name: rrName(tt.qname), Type: tt.qtype, Class: dnsmessage.ClassINET}
+			ans, extra := r.match(q, time.Minute)
+			if got := types(ans); !slices.Equal(got, tt.wantAns) {
+				t.Errorf("answers = %v, want %v", got, tt.wantAns)
+			}
+			if got := types(extra); !slices.Equal(got, tt.wantExtra) {
+				t.Errorf("additionals = %v, want %v", got, tt.wantExtra)
+			}
+			for _, rr := range slices.Concat(ans, extra) {
+				if rr.Header.TTL != 60 {
+					t.Errorf("%v TTL = %d, want 60", rrType(rr), rr.Header.TTL)
+				}
+			}
+		})
+	}
+
+	if ans, _ := (records{}).match(dnsmessage.Question{Name: rrName(serviceName), Type: ptr}, time.Minute); ans != nil {
+		t.Errorf("records without an instance answered %v", ans)
+	}
+}
+
+func TestRecordsAll(t *testing.T) {
+	r := testRecords(t)
+	rrs := r.all(0)
+	want := []dnsmessage.Type{dnsmessage.TypePTR, dnsmessage.TypeSRV, dnsmessage.TypeTXT, dnsmessage.TypeA}
+	if got := types(rrs); !slices.Equal(got, want) {
+		t.Errorf("all = %v, want %v", got, want)
+	}
+	for _, rr := range rrs {
+		flush := rr.Header.Class&classUnicastResponse != 0
+		if rr.Header.TTL != 0 || flush != (rrType(rr) != dnsmessage.TypePTR) {
+			t.Errorf("%v header = %+v, want a goodbye, cache-flushing unless shared", rrType(rr), rr.Header)
+		}
+	}
+	if rrs := (records{}).all(r.ttl); rrs != nil {
+		t.Errorf("records without an instance = %v, want none", rrs)
+	}
+}
+
+// listenLoopback returns a UDP socket on the loopback interface.
+func listenLoopback(t *testing.T) *net.UDPConn {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestAnswerLegacy(t *testing.T) {
+	server, client := listenLoopback(t), listenLoopback(t)
+	a := &Advertiser{records: testRecords(t)}
+
+	tests := []struct {
+		name    string
+		qname   string
+		wantAns int
+	}{
+		{name: "matching question", qname: serviceName, wantAns: 1},
+		{name: "no match", qname: "_http._tcp.local."},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := dnsmessage.Message{
+				Header: dnsmessage.Header{ID: uint16(100 + i)},
+				Questions: []dnsmessage.Question{
+					{Name: rrName(tt.qname), Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET},
+				},
+			}
+			a.answer(server, q, client.LocalAddr().(*net.UDPAddr))
+
+			buf := make([]byte, maxPacket)
+			_ = client.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			n, _, err := client.ReadFromUDP(buf)
+			if tt.wantAns == 0 {
+				var nerr net.Error
+				if !errors.As(err, &nerr) || !nerr.Timeout() {
+					t.Errorf("read = %d bytes, %v; want no answer", n, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var resp dnsmessage.Message
+			if err := resp.Unpack(buf[:n]); err != nil {
+				t.Fatal(err)
+			}
+			if !resp.Response || resp.ID != q.ID || len(resp.Questions) != 1 || len(resp.Answers) != tt.wantAns {
+				t.Errorf("response = %+v, want the legacy answer to query %d", resp.Header, q.ID)
+			}
+			for _, rr := range slices.Concat(resp.Answers, resp.Additionals) {
+				if rr.Header.TTL > uint32(legacyTTL/time.Second) {
+					t.Errorf("%v TTL = %d, want it capped at %s", rr.Header.Type, rr.Header.TTL, legacyTTL)
+				}
+				if rr.Header.Class != dnsmessage.ClassINET {
+					t.Errorf("%v class = %v, want no cache-flush bit in a legacy answer",
+						rr.Header.Type, rr.Header.Class)
+				}
+			}
+		})
+	}
+}
+
+func TestAdvertiseAndBrowse(t *testing.T) {
+	adv, err := NewAdvertiser(WithInstanceName("adv-test-" + time.Now().Format("150405.000000")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ann := testAnnouncement()
+	if err := adv.Announce(context.Background(), ann); err != nil {
+		t.Skipf("Announce error = %v; no multicast network to advertise on", err)
+	}
+	defer func() { _ = adv.Close() }()
+
+	// Announcing again refreshes the records on the same socket.
+	ann.Version = "2.0.0"
+	if err := adv.Announce(context.Background(), ann); err != nil {
+		t.Fatal(err)
+	}
+
+	instances, err := Browse(context.Background(), 500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := slices.IndexFunc(instances, func(i Instance) bool { return i.Name == adv.name })
+	if i < 0 {
+		t.Skipf("browsing found %+v, not the advertised instance; multicast loopback is unavailable", instances)
+	}
+	if got := instances[i]; got.Plugin != ann.Plugin || got.Version != "2.0.0" || got.Target() != ann.Address {
+		t.Errorf("found %+v, want the refreshed announcement", got)
+	}
+
+	stopping := ann
+	stopping.Status = mcpdpluginsv1.AnnouncementStopping
+	if err := adv.Announce(context.Background(), stopping); err != nil {
+		t.Errorf("stopping Announce error = %v", err)
+	}
+	if err := adv.Close(); err != nil {
+		t.Errorf("second Close error = %v, want none", err)
+	}
+	instances, err = Browse(context.Background(), 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.ContainsFunc(instances, func(i Instance) bool { return i.Name == adv.name }) {
+		t.Error("withdrawn instance still answers")
+	}
+}
@@ -0,0 +1,145 @@
+package mdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// defaultWait is how long Browse collects answers when given no positive wait.
+const defaultWait = time.Second
+
+// Browse queries the local network for advertised plugins, collecting the answers that arrive
+// within wait (a non-positive wait means one second) or until ctx ends, and returns the
+// instances found sorted by name. Instances withdrawn while browsing are left out.
+func Browse(ctx context.Context, wait time.Duration) ([]Instance, error) {
+	if wait <= 0 {
+		wait = defaultWait
+	}
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	// An ephemeral port makes this a legacy unicast query (RFC 6762 section 6.7): responders
+	// answer the sender directly, and browsing needs no access to port 5353.
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mDNS socket: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	query, err := browseQuery()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteToUDP(query, group); err != nil {
+		return nil, fmt.Errorf("failed to send mDNS query: %w", err)
+	}
+
+	found := map[string]*Instance{}
+	buf := make([]byte, maxPacket)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				break
+			}
+			return nil, fmt.Errorf("failed to read mDNS answer: %w", err)
+		}
+		var msg dnsmessage.Message
+		if err := msg.Unpack(buf[:n]); err != nil || !msg.Response {
+			continue
+		}
+		source, _ := netip.AddrFromSlice(src.IP)
+		collect(found, msg, source.Unmap())
+	}
+
+	instances := make([]Instance, 0, len(found))
+	for _, name := range slices.Sorted(maps.Keys(found)) {
+		if inst := found[name]; inst.Network != "" && inst.Address != "" {
+			instances = append(instances, *inst)
+		}
+	}
+
+	return instances, nil
+}
+
+// Lookup browses for wait, as Browse does, and returns the first instance of plugin in instance
+// name order, or ErrNotFound.
+func Lookup(ctx context.Context, plugin string, wait time.Duration) (Instance, error) {
+	instances, err := Browse(ctx, wait)
+	if err != nil {
+		return Instance{}, err
+	}
+	for _, inst := range instances {
+		if inst.Plugin == plugin {
+			return inst, nil
+		}
+	}
+
+	return Instance{}, fmt.Errorf("%w: %s", ErrNotFound, plugin)
+}
+
+// browseQuery returns the PTR query for the plugin service type.
+func browseQuery() ([]byte, error) {
+	name, err := dnsmessage.NewName(serviceName)
+	if err != nil {
+		return nil, err
+	}
+	msg := dnsmessage.Message{
+		Questions: []dnsmessage.Question{{Name: name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}},
+	}
+
+	return msg.Pack()
+}
+
+// collect adds the instances described by the records of msg to found, keyed by lower-case
+// instance name, and removes those msg withdraws.
+func collect(found map[string]*Instance, msg dnsmessage.Message, source netip.Addr) {
+	suffix := "." + serviceName
+	lookup := func(name dnsmessage.Name) *Instance {
+		s := name.String()
+		if len(s) <= len(suffix) || !strings.EqualFold(s[len(s)-len(suffix):], suffix) {
+			return nil
+		}
+		key := strings.ToLower(s)
+		inst, ok := found[key]
+		if !ok {
+			inst = &Instance{Name: s[:len(s)-len(suffix)], Source: source}
+			found[key] = inst
+		}
+		return inst
+	}
+
+	for _, rr := range slices.Concat(msg.Answers, msg.Additionals) {
+		var name dnsmessage.Name
+		switch body := rr.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if !strings.EqualFold(rr.Header.Name.String(), serviceName) {
+				continue
+			}
+			name = body.PTR
+		case *dnsmessage.TXTResource:
+			name = rr.Header.Name
+			if inst := lookup(name); inst != nil {
+				inst.parseTXT(body.TXT)
+			}
+		default:
+			continue
+		}
+		if rr.Header.TTL == 0 {
+			delete(found, strings.ToLower(name.String()))
+			continue
+		}
+		lookup(name)
+	}
+}
@@ -0,0 +1,149 @@
+package mdns
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/netip"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// rrName returns s as a dnsmessage.Name, panicking when invalid.
+func rrName(s string) dnsmessage.Name {
+	return dnsmessage.MustNewName(s)
+}
+
+// ptrRR returns a PTR record from the service type named owner to instance, with ttl seconds.
+func ptrRR(owner, instance string, ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: rrName(owner), Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.PTRResource{PTR: rrName(instance)},
+	}
+}
+
+// txtRR returns a TXT record of instance, with ttl seconds.
+func txtRR(instance string, ttl uint32, txt ...string) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: rrName(instance), Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.TXTResource{TXT: txt},
+	}
+}
+
+func TestCollect(t *testing.T) {
+	const a, b = "a." + serviceName, "B." + serviceName
+	source := netip.MustParseAddr("192.0.2.1")
+	tests := []struct {
+		name    string
+		found   []string // Instances already found.
+		answers []dnsmessage.Resource
+		extra   []dnsmessage.Resource
+		want    map[string]Instance
+	}{
+		{
+			name:    "pointer and text",
+			answers: []dnsmessage.Resource{ptrRR(serviceName, a, 120)},
+			extra:   []dnsmessage.Resource{txtRR(a, 120, "plugin=p", "network=tcp", "address=:1")},
+			want: map[string]Instance{
+				"a." + serviceName: {Name: "a", Plugin: "p", Network: "tcp", Address: ":1", Source: source},
+			},
+		},
+		{
+			name: "names compared without case",
+			answers: []dnsmessage.Resource{
+				ptrRR("_MCPD-PLUGIN._tcp.local.", b, 120),
+				txtRR("b."+serviceName, 120, "plugin=q"),
+			},
+			want: map[string]Instance{"b." + serviceName: {Name: "B", Plugin: "q", Source: source}},
+		},
+		{
+			name:    "other service types ignored",
+			answers: []dnsmessage.Resource{ptrRR("_http._tcp.local.", "web._http._tcp.local.", 120)},
+			extra:   []dnsmessage.Resource{txtRR("web._http._tcp.local.", 120, "plugin=p")},
+			want:    map[string]Instance{},
+		},
+		{
+			name:    "bare service name is no instance",
+			answers: []dnsmessage.Resource{txtRR(serviceName, 120, "plugin=p")},
+			want:    map[string]Instance{},
+		},
+		{
+			name:    "goodbye withdraws",
+			found:   []string{a, b},
+			answers: []dnsmessage.Resource{ptrRR(serviceName, a, 0), txtRR(a, 0, "plugin=p")},
+			want:    map[string]Instance{"b." + serviceName: {Name: "B", Source: source}},
+		},
+		{
+			name: "other records ignored",
+			answers: []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: rrName(a), Class: dnsmessage.ClassINET, TTL: 120},
+				Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+			}},
+			want: map[string]Instance{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found := map[string]*Instance{}
+			for _, name := range tt.found {
+				msg := dnsmessage.Message{Answers: []dnsmessage.Resource{ptrRR(serviceName, name, 120)}}
+				collect(found, msg, source)
+			}
+			collect(found, dnsmessage.Message{Answers: tt.answers, Additionals: tt.extra}, source)
+
+			got := map[string]Instance{}
+			for k, inst := range found {
+				got[k] = *inst
+			}
+			if !maps.EqualFunc(got, tt.want, func(x, y Instance) bool {
+				return x.Name == y.Name && x.Plugin == y.Plugin && x.Network == y.Network &&
+					x.Address == y.Address && x.Source == y.Source
+			}) {
+				t.Errorf("found %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBrowseQuery(t *testing.T) {
+	b, err := browseQuery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg dnsmessage.Message
+	if err := msg.Unpack(b); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Response || len(msg.Questions) != 1 {
+		t.Fatalf("query = %+v, want one question", msg)
+	}
+	if q := msg.Questions[0]; q.Name.String() != serviceName || q.Type != dnsmessage.TypePTR {
+		t.Errorf("question = %v, want a PTR query for %s", q, serviceName)
+	}
+}
+
+func TestLookupNotFound(t *testing.T) {
+	_, err := Lookup(context.Background(), "no-such-plugin-"+time.Now().Format("150405.000"), 50*time.Millisecond)
+	if !errors.Is(err, ErrNotFound) {
+		t.Skipf("Lookup error = %v; no multicast network to browse", err)
+	}
+}
+
+func TestBrowseCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	instances, err := Browse(ctx, time.Minute)
+	if err != nil {
+		t.Skipf("Browse error = %v; no multicast network to browse", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Browse took %s with a canceled context", d)
+	}
+	if slices.ContainsFunc(instances, func(i Instance) bool { return i.Network == "" }) {
+		t.Errorf("Browse returned incomplete instances %+v", instances)
+	}
+}
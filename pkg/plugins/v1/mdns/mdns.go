@@ -0,0 +1,158 @@
+// Package mdns advertises plugins on the local network with multicast DNS service discovery
+// (RFC 6762 and RFC 6763), and finds them, so local development setups (a dev harness, a local
+// mcpd) can locate running plugins without hand-typed socket paths.
+//
+// A plugin advertises itself by passing an Advertiser to WithAnnouncer. It is advertised once it
+// serves and withdrawn, with a goodbye packet, when it stops:
+//
+//	adv, err := mdns.NewAdvertiser()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	err = mcpdpluginsv1.Serve(&MyPlugin{}, mcpdpluginsv1.WithAnnouncer(adv, 0))
+//
+// and the host side finds it by plugin name:
+//
+//	inst, err := mdns.Lookup(ctx, "my-plugin", time.Second)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	conn, err := grpc.NewClient(inst.Target(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+//
+// Plugins are advertised as ServiceType instances whose TXT record carries the plugin name and
+// version and the network and address it serves on, which for a unix socket is a path only
+// meaningful on the same host. The package speaks IPv4 only and is meant for development: the
+// advertisement is unauthenticated, so production deployments should use a configured discovery
+// endpoint (WithRegistration) or static configuration instead.
+package mdns
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ServiceType is the DNS-SD service type plugins are advertised under.
+const ServiceType = "_mcpd-plugin._tcp"
+
+// DefaultTTL is the time-to-live of advertised records, the RFC 6762 recommendation for records
+// naming hosts.
+const DefaultTTL = 120 * time.Second
+
+// ErrNotFound is returned by Lookup when no instance of the plugin answered.
+var ErrNotFound = errors.New("plugin not found via mDNS")
+
+// group is the IPv4 mDNS multicast group and port.
+var group = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// domain is the mDNS domain, and serviceName the fully qualified service type.
+const (
+	domain      = "local."
+	serviceName = ServiceType + "." + domain
+)
+
+// maxPacket is the largest mDNS packet read or written, the RFC 6762 bound for multicast.
+const maxPacket = 9000
+
+// classUnicastResponse is the RFC 6762 "QU" bit of a question's class, asking for a unicast
+// response, and the cache-flush bit of a record's class.
+const classUnicastResponse = 0x8000
+
+// Instance is an advertised plugin.
+type Instance struct {
+	// Name is the DNS-SD instance name.
+	Name string
+
+	// Plugin, Version and APIVersion are the plugin's name, version and plugin API version.
+	Plugin     string
+	Version    string
+	APIVersion string
+
+	// Flows are the flows the plugin handles.
+	Flows []string
+
+	// Network and Address are where the plugin serves gRPC, as passed to --network and --address.
+	Network string
+	Address string
+
+	// Source is the address the advertisement came from.
+	Source netip.Addr
+}
+
+// Target returns the gRPC target to dial the instance at: a unix: target for a unix socket, and
+// for TCP the advertised address, with Source as the host when the plugin listens on all
+// interfaces.
+func (i Instance) Target() string {
+	if i.Network == "unix" {
+		return "unix://" + i.Address
+	}
+	host, port, err := net.SplitHostPort(i.Address)
+	if err != nil {
+		return i.Address
+	}
+	if ip, err := netip.ParseAddr(host); (host == "" || err == nil && ip.IsUnspecified()) && i.Source.IsValid() {
+		host = i.Source.String()
+	}
+
+	return net.JoinHostPort(host, port)
+}
+
+// txt returns the TXT strings describing i.
+func (i Instance) txt() []string {
+	txt := []string{
+		"txtvers=1",
+		"plugin=" + i.Plugin,
+		"version=" + i.Version,
+		"api=" + i.APIVersion,
+		"network=" + i.Network,
+		"address=" + i.Address,
+	}
+	if len(i.Flows) > 0 {
+		txt = append(txt, "flows="+strings.Join(i.Flows, ","))
+	}
+
+	return txt
+}
+
+// parseTXT sets the fields of i from TXT strings, ignoring unknown keys.
+func (i *Instance) parseTXT(txt []string) {
+	for _, kv := range txt {
+		k, v, _ := strings.Cut(kv, "=")
+		switch strings.ToLower(k) {
+		case "plugin":
+			i.Plugin = v
+		case "version":
+			i.Version = v
+		case "api":
+			i.APIVersion = v
+		case "network":
+			i.Network = v
+		case "address":
+			i.Address = v
+		case "flows":
+			i.Flows = nil
+			if v != "" {
+				i.Flows = strings.Split(v, ",")
+			}
+		}
+	}
+}
+
+// instanceLabel returns s as a single DNS label: dots, which separate labels, become dashes.
+func instanceLabel(s string) string {
+	s = strings.ReplaceAll(s, ".", "-")
+	if len(s) > 63 {
+		s = s[:63]
+	}
+
+	return s
+}
+
+// sameName reports whether two DNS names are equal, ignoring ASCII case.
+func sameName(a, b dnsmessage.Name) bool {
+	return strings.EqualFold(a.String(), b.String())
+}
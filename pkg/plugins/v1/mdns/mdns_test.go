@@ -0,0 +1,137 @@
+package mdns
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInstanceTarget(t *testing.T) {
+	source := netip.MustParseAddr("192.0.2.7")
+	tests := []struct {
+		name string
+		inst Instance
+		want string
+	}{
+		{
+			name: "unix socket",
+			inst: Instance{Network: "unix", Address: "/tmp/plugin.sock"},
+			want: "unix:///tmp/plugin.sock",
+		},
+		{
+			name: "tcp host",
+			inst: Instance{Network: "tcp", Address: "10.0.0.1:9000", Source: source},
+			want: "10.0.0.1:9000",
+		},
+		{
+			name: "tcp empty host",
+			inst: Instance{Network: "tcp", Address: ":9000", Source: source},
+			want: "192.0.2.7:9000",
+		},
+		{
+			name: "tcp unspecified IPv4",
+			inst: Instance{Network: "tcp", Address: "0.0.0.0:9000", Source: source},
+			want: "192.0.2.7:9000",
+		},
+		{
+			name: "tcp unspecified IPv6",
+			inst: Instance{Network: "tcp", Address: "[::]:9000", Source: source},
+			want: "192.0.2.7:9000",
+		},
+		{name: "unspecified without a source", inst: Instance{Network: "tcp", Address: ":9000"}, want: ":9000"},
+		{
+			name: "host name",
+			inst: Instance{Network: "tcp", Address: "plugin.local:9000", Source: source},
+			want: "plugin.local:9000",
+		},
+		{name: "no port", inst: Instance{Network: "tcp", Address: "10.0.0.1", Source: source}, want: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.inst.Target(); got != tt.want {
+				t.Errorf("Target = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstanceTXT(t *testing.T) {
+	tests := []struct {
+		name string
+		inst Instance
+	}{
+		{
+			name: "all fields",
+			inst: Instance{
+				Plugin:     "my-plugin",
+				Version:    "1.2.3",
+				APIVersion: "v1",
+				Flows:      []string{"FLOW_REQUEST", "FLOW_RESPONSE"},
+				Network:    "tcp",
+				Address:    "127.0.0.1:9000",
+			},
+		},
+		{name: "no flows", inst: Instance{Plugin: "p", Network: "unix", Address: "/tmp/p.sock"}},
+		{name: "value with an equals sign", inst: Instance{Plugin: "p", Version: "a=b", Network: "tcp", Address: ":1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Instance
+			got.parseTXT(tt.inst.txt())
+			if !reflect.DeepEqual(got, tt.inst) {
+				t.Errorf("parseTXT(txt()) = %+v, want %+v", got, tt.inst)
+			}
+		})
+	}
+}
+
+func TestParseTXT(t *testing.T) {
+	tests := []struct {
+		name string
+		txt  []string
+		want Instance
+	}{
+		{
+			name: "keys are case insensitive",
+			txt:  []string{"Plugin=p", "NETWORK=tcp"},
+			want: Instance{Plugin: "p", Network: "tcp"},
+		},
+		{
+			name: "unknown keys ignored",
+			txt:  []string{"txtvers=1", "color=blue", "plugin=p"},
+			want: Instance{Plugin: "p"},
+		},
+		{name: "key without a value", txt: []string{"plugin"}, want: Instance{}},
+		{name: "empty flows", txt: []string{"flows=a", "flows="}, want: Instance{}},
+		{name: "later keys win", txt: []string{"version=1", "version=2"}, want: Instance{Version: "2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Instance
+			got.parseTXT(tt.txt)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTXT = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstanceLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "my-plugin-42", want: "my-plugin-42"},
+		{name: "dots", in: "plugin.v1.2", want: "plugin-v1-2"},
+		{name: "truncated", in: strings.Repeat("a", 70), want: strings.Repeat("a", 63)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := instanceLabel(tt.in); got != tt.want {
+				t.Errorf("instanceLabel = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/sampling"
+)
+
+// WithMetrics makes Serve record RPC counts (metrics.RPCRequests) and handler latencies
+// (metrics.RPCDuration), labelled by method and gRPC status code, through r. HandleRequest and
+// HandleResponse latencies are also recorded as metrics.HandlerDuration, labelled by verdict
+// (continue, short-circuit, or error) with the propagated trace attached as an exemplar; exemplars
+// are subject to the sampler configured with WithSampler (feature sampling.FeatureTrace).
+// It may be given more than once to record to several backends.
+// Recorders implementing metrics.Flusher are flushed when the server stops.
+//
+// Usage:
+//
+//	statsd, err := metrics.NewStatsd("127.0.0.1:8125", metrics.WithDogStatsD())
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer func() { _ = statsd.Close() }()
+//
+//	err = mcpdpluginsv1.Serve(&MyPlugin{}, mcpdpluginsv1.WithMetrics(statsd))
+func WithMetrics(r metrics.Recorder) ServeOption {
+	return func(o *serveOptions) error {
+		if r == nil {
+			return fmt.Errorf("metrics recorder cannot be nil")
+		}
+		o.recorders = append(o.recorders, r)
+		return nil
+	}
+}
+
+// metricsRecorder returns the combined recorder configured through WithMetrics, or nil.
+func (o *serveOptions) metricsRecorder() metrics.Recorder {
+	switch len(o.recorders) {
+	case 0:
+		return nil
+	case 1:
+		return o.recorders[0]
+	default:
+		return metrics.Multi(o.recorders...)
+	}
+}
+
+// subscribeMetrics records SDK metrics from o's bus events through r.
+func subscribeMetrics(o *serveOptions, r metrics.Recorder) {
+	o.bus.Subscribe(func(ctx context.Context, ev Event) {
+		switch ev.Kind {
+		case EventRequest:
+			labels := []metrics.Label{
+				metrics.L(metrics.LabelMethod, ev.Method),
+				metrics.L(metrics.LabelCode, status.Code(ev.Err).String()),
+			}
+			r.Count(metrics.RPCRequests, 1, labels...)
+			r.Timing(metrics.RPCDuration, ev.Duration, labels...)
+			recordHandlerDuration(ctx, r, ev, o.sample(sampling.FeatureTrace, ev))
+		case EventLifecycle:
+			if ev.Phase != PhaseStopped {
+				return
+			}
+			if f, ok := r.(metrics.Flusher); ok {
+				if err := f.Flush(); err != nil {
+					o.logger.Printf("failed to flush metrics: %v", err)
+				}
+			}
+		}
+	}, EventRequest, EventLifecycle)
+}
+
+// recordHandlerDuration records metrics.HandlerDuration for HandleRequest and HandleResponse,
+// labelled by verdict and, when exemplar is set, carrying the propagated trace as an exemplar.
+func recordHandlerDuration(ctx context.Context, r metrics.Recorder, ev Event, exemplar bool) {
+	verdict := ev.Verdict()
+	if verdict == "" {
+		return
+	}
+
+	var ex metrics.Exemplar
+	if tc, ok := TraceContextFromContext(ctx, ev.Request); ok && exemplar {
+		ex = metrics.Exemplar{TraceID: tc.TraceID, SpanID: tc.SpanID}
+	}
+
+	metrics.TimingWithExemplar(r, metrics.HandlerDuration, ev.Duration, ex,
+		metrics.L(metrics.LabelMethod, ev.Method),
+		metrics.L(metrics.LabelVerdict, verdict),
+	)
+}
+
+// WithOTelMetrics makes Serve push SDK metrics to an OpenTelemetry collector over OTLP/HTTP,
+// in addition to any recorders configured with WithMetrics.
+//
+// When no endpoint is given through metrics.WithOTLPEndpoint, the exporter uses the OTLP
+// endpoint, service name, and environment that mcpd sends in Configure's TelemetryConfig, so
+// metrics flow to the same collector as traces. The exporter is only created once every option
+// is valid, and is shut down when the server stops or Serve returns early.
+func WithOTelMetrics(opts ...metrics.OTLPOption) ServeOption {
+	return func(o *serveOptions) error {
+		o.otlp = append(o.otlp, opts)
+		return nil
+	}
+}
+
+// startOTLP creates the exporters requested with WithOTelMetrics. newServeOptions calls it once
+// every option has been applied, so that a failing option cannot leave an export loop running.
+func (o *serveOptions) startOTLP() error {
+	for _, opts := range o.otlp {
+		exp, err := metrics.NewOTLP(opts...)
+		if err != nil {
+			o.shutdownOTLP(context.Background())
+			return fmt.Errorf("failed to create OTLP metrics exporter: %w", err)
+		}
+		o.exporters = append(o.exporters, exp)
+		o.recorders = append(o.recorders, exp)
+		o.interceptors = append(o.interceptors, otlpTelemetryInterceptor(exp))
+	}
+	if len(o.exporters) == 0 {
+		return nil
+	}
+
+	o.subscribers = append(o.subscribers, pendingSubscription{
+		handler: func(ctx context.Context, ev Event) {
+			if ev.Phase == PhaseStopped {
+				o.shutdownOTLP(ctx)
+			}
+		},
+		kinds: []EventKind{EventLifecycle},
+	})
+
+	return nil
+}
+
+// shutdownOTLP stops the exporters created by startOTLP after a final export. Calls after the
+// first do nothing.
+func (o *serveOptions) shutdownOTLP(ctx context.Context) {
+	o.otlpShutdown.Do(func() {
+		for _, exp := range o.exporters {
+			if err := exp.Shutdown(ctx); err != nil {
+				o.logger.Printf("failed to shut down OTLP metrics exporter: %v", err)
+			}
+		}
+	})
+}
+
+// otlpTelemetryInterceptor applies the telemetry settings received in Configure to exp.
+func otlpTelemetryInterceptor(exp *metrics.OTLP) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if cfg, ok := req.(*PluginConfig); ok && info.FullMethod == Plugin_Configure_FullMethodName {
+			t := cfg.GetTelemetry()
+			exp.SetEndpoint(t.GetOtlpEndpoint())
+			exp.SetResourceAttribute("service.name", t.GetServiceName())
+			exp.SetResourceAttribute("deployment.environment", t.GetEnvironment())
+		}
+
+		return handler(ctx, req)
+	}
+}
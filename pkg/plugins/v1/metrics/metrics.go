@@ -0,0 +1,202 @@
+// Package metrics defines the metrics abstraction used by the SDK and by plugins, together with
+// exporters for common backends.
+//
+// A Recorder is backend-agnostic: the SDK records RPC counts and latencies through it when
+// enabled with mcpdpluginsv1.WithMetrics, and plugins may record their own metrics through the
+// same Recorder.
+package metrics
+
+import (
+	"time"
+)
+
+// Names of the metrics recorded by the SDK.
+const (
+	// RPCRequests counts completed plugin RPCs.
+	RPCRequests = "rpc.requests"
+
+	// RPCDuration records the handler latency of plugin RPCs.
+	RPCDuration = "rpc.duration"
+
+	// HandlerDuration records the latency of HandleRequest and HandleResponse, labelled by verdict.
+	HandlerDuration = "handler.duration"
+
+	// ShadowShortCircuits counts short-circuit verdicts converted to pass-through in shadow mode.
+	ShadowShortCircuits = "shadow.short_circuits"
+
+	// CandidateComparisons counts calls evaluated by a candidate handler, labelled by both verdicts.
+	CandidateComparisons = "candidate.comparisons"
+
+	// CandidateDivergences counts candidate evaluations whose verdict differed from the current handler's.
+	CandidateDivergences = "candidate.divergences"
+
+	// CandidateSkipped counts calls not evaluated by the candidate because too many evaluations were in flight.
+	CandidateSkipped = "candidate.skipped"
+
+	// ResourceMemory reports the plugin's resident memory in bytes, sampled by the resource guard.
+	ResourceMemory = "resource.memory"
+
+	// ResourceGoroutines reports the plugin's goroutine count, sampled by the resource guard.
+	ResourceGoroutines = "resource.goroutines"
+
+	// Throttled counts calls rejected with a retry-after signal because the plugin was overloaded,
+	// labelled by reason.
+	Throttled = "throttled"
+
+	// ConcurrencyLimit reports the current adaptive concurrency limit.
+	ConcurrencyLimit = "concurrency.limit"
+
+	// HeaderLimitViolations counts handler results rejected for exceeding the header limits,
+	// labelled by method and reason ("count", "name" or "value").
+	HeaderLimitViolations = "header_limits.violations"
+
+	// QueueWait records how long calls waited for a slot under priority scheduling, labelled by priority.
+	QueueWait = "queue.wait"
+)
+
+// Label keys used by the SDK.
+const (
+	LabelMethod  = "method"
+	LabelCode    = "code"
+	LabelVerdict = "verdict"
+	LabelReason  = "reason"
+
+	// LabelPriority is the priority class of a scheduled call.
+	LabelPriority = "priority"
+
+	// LabelCandidateVerdict is the verdict of the candidate handler in A/B comparisons.
+	LabelCandidateVerdict = "candidate_verdict"
+)
+
+// Values of the LabelVerdict label.
+const (
+	// VerdictContinue marks a handler that let the request or response continue down the chain.
+	VerdictContinue = "continue"
+
+	// VerdictShortCircuit marks a handler that stopped the chain (Continue=false).
+	VerdictShortCircuit = "short_circuit"
+
+	// VerdictError marks a handler that returned an error.
+	VerdictError = "error"
+)
+
+// Label is a metric dimension.
+type Label struct {
+	Key   string
+	Value string
+}
+
+// L is shorthand for constructing a Label.
+func L(key string, value string) Label {
+	return Label{Key: key, Value: value}
+}
+
+// Recorder records metrics. Implementations must be safe for concurrent use.
+type Recorder interface {
+	// Count adds delta to a monotonically increasing counter.
+	Count(name string, delta int64, labels ...Label)
+
+	// Gauge sets the current value of a gauge.
+	Gauge(name string, value float64, labels ...Label)
+
+	// Observe records a sample in a distribution (histogram).
+	Observe(name string, value float64, labels ...Label)
+
+	// Timing records a duration sample in a distribution.
+	Timing(name string, d time.Duration, labels ...Label)
+}
+
+// Exemplar links a metric sample to the trace that produced it.
+type Exemplar struct {
+	// TraceID and SpanID are lowercase hex W3C trace context identifiers.
+	TraceID string
+	SpanID  string
+}
+
+// ExemplarRecorder is implemented by recorders that can attach exemplars to timing samples.
+// Use TimingWithExemplar to record through any Recorder.
+type ExemplarRecorder interface {
+	TimingExemplar(name string, d time.Duration, ex Exemplar, labels ...Label)
+}
+
+// TimingWithExemplar records d through r, attaching ex when r supports exemplars and ex is set.
+func TimingWithExemplar(r Recorder, name string, d time.Duration, ex Exemplar, labels ...Label) {
+	if er, ok := r.(ExemplarRecorder); ok && ex.TraceID != "" {
+		er.TimingExemplar(name, d, ex, labels...)
+		return
+	}
+
+	r.Timing(name, d, labels...)
+}
+
+// Flusher is implemented by recorders that buffer data and can push it on demand.
+type Flusher interface {
+	Flush() error
+}
+
+// Nop returns a Recorder that discards everything.
+func Nop() Recorder {
+	return nopRecorder{}
+}
+
+type nopRecorder struct{}
+
+func (nopRecorder) Count(string, int64, ...Label) {}
+
+func (nopRecorder) Gauge(string, float64, ...Label) {}
+
+func (nopRecorder) Observe(string, float64, ...Label) {}
+
+func (nopRecorder) Timing(string, time.Duration, ...Label) {}
+
+// Multi returns a Recorder that forwards to every recorder in rs.
+func Multi(rs ...Recorder) Recorder {
+	return multiRecorder(rs)
+}
+
+type multiRecorder []Recorder
+
+func (m multiRecorder) Count(name string, delta int64, labels ...Label) {
+	for _, r := range m {
+		r.Count(name, delta, labels...)
+	}
+}
+
+func (m multiRecorder) Gauge(name string, value float64, labels ...Label) {
+	for _, r := range m {
+		r.Gauge(name, value, labels...)
+	}
+}
+
+func (m multiRecorder) Observe(name string, value float64, labels ...Label) {
+	for _, r := range m {
+		r.Observe(name, value, labels...)
+	}
+}
+
+func (m multiRecorder) Timing(name string, d time.Duration, labels ...Label) {
+	for _, r := range m {
+		r.Timing(name, d, labels...)
+	}
+}
+
+// TimingExemplar implements ExemplarRecorder, forwarding the exemplar where supported.
+func (m multiRecorder) TimingExemplar(name string, d time.Duration, ex Exemplar, labels ...Label) {
+	for _, r := range m {
+		TimingWithExemplar(r, name, d, ex, labels...)
+	}
+}
+
+// Flush flushes every recorder that implements Flusher and returns the first error.
+func (m multiRecorder) Flush() error {
+	var first error
+	for _, r := range m {
+		if f, ok := r.(Flusher); ok {
+			if err := f.Flush(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+
+	return first
+}
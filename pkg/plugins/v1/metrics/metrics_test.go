@@ -0,0 +1,148 @@
+package metrics_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// fakeRecorder records every sample as a line such as "count rpc.requests 1 [method=X]".
+type fakeRecorder struct {
+	mu       sync.Mutex
+	lines    []string
+	flushErr error
+	flushes  int
+}
+
+func (r *fakeRecorder) add(kind, name string, value any, labels []metrics.Label) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lines = append(r.lines, fmt.Sprintf("%s %s %v %v", kind, name, value, labels))
+}
+
+func (r *fakeRecorder) Count(name string, delta int64, labels ...metrics.Label) {
+	r.add("count", name, delta, labels)
+}
+
+func (r *fakeRecorder) Gauge(name string, value float64, labels ...metrics.Label) {
+	r.add("gauge", name, value, labels)
+}
+
+func (r *fakeRecorder) Observe(name string, value float64, labels ...metrics.Label) {
+	r.add("observe", name, value, labels)
+}
+
+func (r *fakeRecorder) Timing(name string, d time.Duration, labels ...metrics.Label) {
+	r.add("timing", name, d, labels)
+}
+
+// flushingRecorder is a fakeRecorder implementing metrics.Flusher.
+type flushingRecorder struct{ fakeRecorder }
+
+func (r *flushingRecorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.flushes++
+	return r.flushErr
+}
+
+// exemplarRecorder is a fakeRecorder implementing metrics.ExemplarRecorder.
+type exemplarRecorder struct{ fakeRecorder }
+
+func (r *exemplarRecorder) TimingExemplar(name string, d time.Duration, ex metrics.Exemplar, labels ...metrics.Label) {
+	r.add("exemplar", name, fmt.Sprintf("%v %s/%s", d, ex.TraceID, ex.SpanID), labels)
+}
+
+func TestTimingWithExemplar(t *testing.T) {
+	traced := metrics.Exemplar{TraceID: "t1", SpanID: "s1"}
+	tests := []struct {
+		name     string
+		exemplar bool // Whether the recorder implements ExemplarRecorder.
+		ex       metrics.Exemplar
+		want     string
+	}{
+		{"exemplar recorder", true, traced, "exemplar d 1s t1/s1 [{k v}]"},
+		{"no trace", true, metrics.Exemplar{SpanID: "s1"}, "timing d 1s [{k v}]"},
+		{"plain recorder", false, traced, "timing d 1s [{k v}]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r metrics.Recorder
+			var rec *fakeRecorder
+			if tt.exemplar {
+				er := &exemplarRecorder{}
+				r, rec = er, &er.fakeRecorder
+			} else {
+				rec = &fakeRecorder{}
+				r = rec
+			}
+			metrics.TimingWithExemplar(r, "d", time.Second, tt.ex, metrics.L("k", "v"))
+
+			if want := []string{tt.want}; !slices.Equal(rec.lines, want) {
+				t.Errorf("recorded %q, want %q", rec.lines, want)
+			}
+		})
+	}
+}
+
+func TestMultiTimingExemplar(t *testing.T) {
+	plain, ex := &fakeRecorder{}, &exemplarRecorder{}
+	traced := metrics.Exemplar{TraceID: "t1", SpanID: "s1"}
+	metrics.TimingWithExemplar(metrics.Multi(plain, ex), "d", time.Second, traced)
+
+	if want := []string{"timing d 1s []"}; !slices.Equal(plain.lines, want) {
+		t.Errorf("plain recorder recorded %q, want %q", plain.lines, want)
+	}
+	if want := []string{"exemplar d 1s t1/s1 []"}; !slices.Equal(ex.lines, want) {
+		t.Errorf("exemplar recorder recorded %q, want %q", ex.lines, want)
+	}
+}
+
+func TestMulti(t *testing.T) {
+	a, b := &fakeRecorder{}, &fakeRecorder{}
+	m := metrics.Multi(a, b)
+	m.Count("c", 1, metrics.L("k", "v"))
+	m.Gauge("g", 2)
+	m.Observe("o", 3)
+	m.Timing("t", time.Second)
+
+	want := []string{"count c 1 [{k v}]", "gauge g 2 []", "observe o 3 []", "timing t 1s []"}
+	for _, r := range []*fakeRecorder{a, b} {
+		if !slices.Equal(r.lines, want) {
+			t.Errorf("recorded %q, want %q", r.lines, want)
+		}
+	}
+}
+
+func TestMultiFlush(t *testing.T) {
+	errFirst, errSecond := errors.New("first"), errors.New("second")
+	a := &flushingRecorder{fakeRecorder{flushErr: errFirst}}
+	b := &flushingRecorder{fakeRecorder{flushErr: errSecond}}
+	m := metrics.Multi(a, &fakeRecorder{}, b)
+
+	f, ok := m.(metrics.Flusher)
+	if !ok {
+		t.Fatal("Multi does not implement Flusher")
+	}
+	if err := f.Flush(); !errors.Is(err, errFirst) {
+		t.Errorf("Flush = %v, want the first error", err)
+	}
+	if a.flushes != 1 || b.flushes != 1 {
+		t.Errorf("flushed %d and %d times, want every flusher once", a.flushes, b.flushes)
+	}
+}
+
+func TestNop(t *testing.T) {
+	r := metrics.Nop()
+	r.Count("c", 1)
+	r.Gauge("g", 1)
+	r.Observe("o", 1)
+	r.Timing("t", time.Second)
+}
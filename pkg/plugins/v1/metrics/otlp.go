@@ -0,0 +1,546 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults used by NewOTLP.
+const (
+	DefaultOTLPInterval = 15 * time.Second
+	DefaultOTLPTimeout  = 10 * time.Second
+	otlpScopeName       = "github.com/mozilla-ai/mcpd-plugins-sdk-go"
+	otlpMetricsPath     = "/v1/metrics"
+)
+
+// DefaultHistogramBounds are the explicit bucket boundaries used for Observe and Timing.
+// Timing samples are recorded in seconds.
+var DefaultHistogramBounds = []float64{
+	0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// OTLP is a Recorder that aggregates metrics in memory and periodically pushes them to an
+// OpenTelemetry collector using OTLP over HTTP with JSON encoding. Counters and histograms
+// are exported with cumulative temporality.
+//
+// The endpoint may be left empty at construction and supplied later with SetEndpoint, which
+// is how the SDK applies the telemetry endpoint sent by mcpd in Configure.
+type OTLP struct {
+	client   *http.Client
+	headers  map[string]string
+	bounds   []float64
+	start    time.Time
+	interval time.Duration
+
+	mu       sync.Mutex
+	endpoint string
+	resource map[string]string
+	series   map[string]*otlpSeries
+
+	done     chan struct{}
+	wg       sync.WaitGroup
+	stopOnce sync.Once
+}
+
+type otlpSeries struct {
+	name   string
+	kind   string // "sum", "gauge" or "histogram".
+	unit   string
+	labels []Label
+
+	intValue   int64
+	floatValue float64
+	count      uint64
+	sum        float64
+	buckets    []uint64
+	exemplars  []*otlpExemplar // Most recent exemplar per bucket, if any.
+}
+
+// OTLPOption configures an OTLP recorder.
+type OTLPOption func(*OTLP) error
+
+// WithOTLPEndpoint sets the collector endpoint, e.g. "http://otel-collector:4318".
+// An endpoint without a scheme is assumed to be plain HTTP; "/v1/metrics" is appended unless
+// the endpoint already has a path.
+func WithOTLPEndpoint(endpoint string) OTLPOption {
+	return func(o *OTLP) error {
+		o.endpoint = normalizeOTLPEndpoint(endpoint)
+		return nil
+	}
+}
+
+// WithOTLPHeaders adds headers (e.g. authentication) to every export request.
+func WithOTLPHeaders(headers map[string]string) OTLPOption {
+	return func(o *OTLP) error {
+		for k, v := range headers {
+			o.headers[k] = v
+		}
+		return nil
+	}
+}
+
+// WithOTLPInterval sets how often metrics are exported (defaults to DefaultOTLPInterval).
+func WithOTLPInterval(d time.Duration) OTLPOption {
+	return func(o *OTLP) error {
+		if d <= 0 {
+			return fmt.Errorf("export interval must be positive")
+		}
+		o.interval = d
+		return nil
+	}
+}
+
+// WithResourceAttributes sets OpenTelemetry resource attributes such as "service.name".
+func WithResourceAttributes(attrs map[string]string) OTLPOption {
+	return func(o *OTLP) error {
+		for k, v := range attrs {
+			o.resource[k] = v
+		}
+		return nil
+	}
+}
+
+// WithHistogramBounds replaces DefaultHistogramBounds. Bounds must be strictly increasing.
+func WithHistogramBounds(bounds []float64) OTLPOption {
+	return func(o *OTLP) error {
+		for i := 1; i < len(bounds); i++ {
+			if bounds[i] <= bounds[i-1] {
+				return fmt.Errorf("histogram bounds must be strictly increasing")
+			}
+		}
+		o.bounds = append([]float64(nil), bounds...)
+		return nil
+	}
+}
+
+// WithOTLPClient replaces the HTTP client used for export.
+func WithOTLPClient(c *http.Client) OTLPOption {
+	return func(o *OTLP) error {
+		if c == nil {
+			return fmt.Errorf("HTTP client cannot be nil")
+		}
+		o.client = c
+		return nil
+	}
+}
+
+// NewOTLP returns an OTLP recorder and starts its export loop. Call Shutdown to stop it.
+func NewOTLP(opts ...OTLPOption) (*OTLP, error) {
+	o := &OTLP{
+		client:   &http.Client{Timeout: DefaultOTLPTimeout},
+		headers:  map[string]string{},
+		bounds:   DefaultHistogramBounds,
+		start:    time.Now(),
+		interval: DefaultOTLPInterval,
+		resource: map[string]string{},
+		series:   map[string]*otlpSeries{},
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+
+	o.wg.Add(1)
+	go o.loop()
+
+	return o, nil
+}
+
+// SetEndpoint sets the collector endpoint if none was configured. It reports whether the
+// endpoint was applied.
+func (o *OTLP) SetEndpoint(endpoint string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.endpoint != "" || endpoint == "" {
+		return false
+	}
+	o.endpoint = normalizeOTLPEndpoint(endpoint)
+
+	return true
+}
+
+// SetResourceAttribute sets a resource attribute if it is not already set.
+func (o *OTLP) SetResourceAttribute(key string, value string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.resource[key]; !ok && value != "" {
+		o.resource[key] = value
+	}
+}
+
+// Count implements Recorder.
+func (o *OTLP) Count(name string, delta int64, labels ...Label) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	s := o.seriesFor(name, "sum", "", labels)
+	s.intValue += delta
+}
+
+// Gauge implements Recorder.
+func (o *OTLP) Gauge(name string, value float64, labels ...Label) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	s := o.seriesFor(name, "gauge", "", labels)
+	s.floatValue = value
+}
+
+// Observe implements Recorder.
+func (o *OTLP) Observe(name string, value float64, labels ...Label) {
+	o.observe(name, "", value, nil, labels)
+}
+
+// Timing implements Recorder. Durations are recorded in seconds.
+func (o *OTLP) Timing(name string, d time.Duration, labels ...Label) {
+	o.observe(name, "s", d.Seconds(), nil, labels)
+}
+
+// TimingExemplar implements ExemplarRecorder. Durations are recorded in seconds and the most
+// recent exemplar of each histogram bucket is exported with the data point.
+func (o *OTLP) TimingExemplar(name string, d time.Duration, ex Exemplar, labels ...Label) {
+	o.observe(name, "s", d.Seconds(), &ex, labels)
+}
+
+// Flush exports the current state of all metrics. It is a no-op until an endpoint is known.
+func (o *OTLP) Flush() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultOTLPTimeout)
+	defer cancel()
+
+	return o.export(ctx)
+}
+
+// Shutdown stops the export loop and performs a final export.
+func (o *OTLP) Shutdown(ctx context.Context) error {
+	o.stopOnce.Do(func() { close(o.done) })
+	o.wg.Wait()
+
+	return o.export(ctx)
+}
+
+func (o *OTLP) observe(name string, unit string, value float64, ex *Exemplar, labels []Label) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	s := o.seriesFor(name, "histogram", unit, labels)
+	s.count++
+	s.sum += value
+	idx := bucketIndex(o.bounds, value)
+	s.buckets[idx]++
+
+	if ex != nil && ex.TraceID != "" {
+		s.exemplars[idx] = &otlpExemplar{
+			TimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+			AsDouble:     value,
+			TraceID:      ex.TraceID,
+			SpanID:       ex.SpanID,
+		}
+	}
+}
+
+// seriesFor returns (creating if needed) the series for name and labels. Callers must hold o.mu.
+func (o *OTLP) seriesFor(name string, kind string, unit string, labels []Label) *otlpSeries {
+	key := seriesKey(name, labels)
+	s, ok := o.series[key]
+	if !ok {
+		s = &otlpSeries{
+			name:   name,
+			kind:   kind,
+			unit:   unit,
+			labels: append([]Label(nil), labels...),
+		}
+		if kind == "histogram" {
+			s.buckets = make([]uint64, len(o.bounds)+1)
+			s.exemplars = make([]*otlpExemplar, len(o.bounds)+1)
+		}
+		o.series[key] = s
+	}
+
+	return s
+}
+
+func (o *OTLP) loop() {
+	defer o.wg.Done()
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = o.Flush()
+		case <-o.done:
+			return
+		}
+	}
+}
+
+func (o *OTLP) export(ctx context.Context) error {
+	o.mu.Lock()
+	endpoint := o.endpoint
+	if endpoint == "" || len(o.series) == 0 {
+		o.mu.Unlock()
+		return nil
+	}
+	payload, err := json.Marshal(o.buildRequestLocked())
+	o.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode OTLP metrics: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range o.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export OTLP metrics to %s: %w", endpoint, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("OTLP collector at %s returned %s", endpoint, resp.Status)
+	}
+
+	return nil
+}
+
+// OTLP/JSON wire types. Field names follow the protobuf JSON mapping; 64-bit integers are strings.
+type (
+	otlpRequest struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+	otlpResourceMetrics struct {
+		Resource     otlpResource       `json:"resource"`
+		ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes,omitempty"`
+	}
+	otlpScopeMetrics struct {
+		Scope   otlpScope    `json:"scope"`
+		Metrics []otlpMetric `json:"metrics"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpMetric struct {
+		Name      string         `json:"name"`
+		Unit      string         `json:"unit,omitempty"`
+		Sum       *otlpSum       `json:"sum,omitempty"`
+		Gauge     *otlpGauge     `json:"gauge,omitempty"`
+		Histogram *otlpHistogram `json:"histogram,omitempty"`
+	}
+	otlpSum struct {
+		AggregationTemporality int             `json:"aggregationTemporality"`
+		IsMonotonic            bool            `json:"isMonotonic"`
+		DataPoints             []otlpNumberDPt `json:"dataPoints"`
+	}
+	otlpGauge struct {
+		DataPoints []otlpNumberDPt `json:"dataPoints"`
+	}
+	otlpHistogram struct {
+		AggregationTemporality int                `json:"aggregationTemporality"`
+		DataPoints             []otlpHistogramDPt `json:"dataPoints"`
+	}
+	otlpNumberDPt struct {
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		TimeUnixNano      string         `json:"timeUnixNano"`
+		AsInt             *string        `json:"asInt,omitempty"`
+		AsDouble          *float64       `json:"asDouble,omitempty"`
+	}
+	otlpHistogramDPt struct {
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		TimeUnixNano      string         `json:"timeUnixNano"`
+		Count             string         `json:"count"`
+		Sum               float64        `json:"sum"`
+		BucketCounts      []string       `json:"bucketCounts"`
+		ExplicitBounds    []float64      `json:"explicitBounds"`
+		Exemplars         []otlpExemplar `json:"exemplars,omitempty"`
+	}
+	otlpExemplar struct {
+		TimeUnixNano string  `json:"timeUnixNano"`
+		AsDouble     float64 `json:"asDouble"`
+		TraceID      string  `json:"traceId,omitempty"`
+		SpanID       string  `json:"spanId,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpAnyValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+// otlpCumulative is AGGREGATION_TEMPORALITY_CUMULATIVE.
+const otlpCumulative = 2
+
+// buildRequestLocked converts the aggregated series into an export request. Callers must hold o.mu.
+func (o *OTLP) buildRequestLocked() otlpRequest {
+	start := strconv.FormatInt(o.start.UnixNano(), 10)
+	now := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	keys := make([]string, 0, len(o.series))
+	for k := range o.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	byName := map[string]*otlpMetric{}
+	var ordered []*otlpMetric
+	for _, k := range keys {
+		s := o.series[k]
+		m, ok := byName[s.name]
+		if !ok {
+			m = &otlpMetric{Name: s.name, Unit: s.unit}
+			byName[s.name] = m
+			ordered = append(ordered, m)
+		}
+
+		attrs := toKeyValues(s.labels)
+		switch s.kind {
+		case "sum":
+			if m.Sum == nil {
+				m.Sum = &otlpSum{AggregationTemporality: otlpCumulative, IsMonotonic: true}
+			}
+			v := strconv.FormatInt(s.intValue, 10)
+			m.Sum.DataPoints = append(m.Sum.DataPoints, otlpNumberDPt{
+				Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: now, AsInt: &v,
+			})
+		case "gauge":
+			if m.Gauge == nil {
+				m.Gauge = &otlpGauge{}
+			}
+			v := s.floatValue
+			m.Gauge.DataPoints = append(m.Gauge.DataPoints, otlpNumberDPt{
+				Attributes: attrs, StartTimeUnixNano: start, TimeUnixNano: now, AsDouble: &v,
+			})
+		case "histogram":
+			if m.Histogram == nil {
+				m.Histogram = &otlpHistogram{AggregationTemporality: otlpCumulative}
+			}
+			counts := make([]string, len(s.buckets))
+			for i, c := range s.buckets {
+				counts[i] = strconv.FormatUint(c, 10)
+			}
+			var exemplars []otlpExemplar
+			for _, ex := range s.exemplars {
+				if ex != nil {
+					exemplars = append(exemplars, *ex)
+				}
+			}
+			m.Histogram.DataPoints = append(m.Histogram.DataPoints, otlpHistogramDPt{
+				Attributes:        attrs,
+				StartTimeUnixNano: start,
+				TimeUnixNano:      now,
+				Count:             strconv.FormatUint(s.count, 10),
+				Sum:               s.sum,
+				BucketCounts:      counts,
+				ExplicitBounds:    o.bounds,
+				Exemplars:         exemplars,
+			})
+		}
+	}
+
+	metricsOut := make([]otlpMetric, 0, len(ordered))
+	for _, m := range ordered {
+		metricsOut = append(metricsOut, *m)
+	}
+
+	resource := make([]Label, 0, len(o.resource))
+	for k, v := range o.resource {
+		resource = append(resource, L(k, v))
+	}
+	sort.Slice(resource, func(i, j int) bool { return resource[i].Key < resource[j].Key })
+
+	return otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: otlpResource{Attributes: toKeyValues(resource)},
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope:   otlpScope{Name: otlpScopeName},
+			Metrics: metricsOut,
+		}},
+	}}}
+}
+
+func toKeyValues(labels []Label) []otlpKeyValue {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	out := make([]otlpKeyValue, len(labels))
+	for i, l := range labels {
+		out[i] = otlpKeyValue{Key: l.Key, Value: otlpAnyValue{StringValue: l.Value}}
+	}
+
+	return out
+}
+
+// bucketIndex returns the index of the bucket holding v: bucket i covers (bounds[i-1], bounds[i]].
+func bucketIndex(bounds []float64, v float64) int {
+	if math.IsNaN(v) {
+		return len(bounds)
+	}
+
+	return sort.SearchFloat64s(bounds, v)
+}
+
+// seriesKey identifies a series by name and labels, independent of label order.
+func seriesKey(name string, labels []Label) string {
+	if len(labels) == 0 {
+		return name
+	}
+
+	sorted := append([]Label(nil), labels...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, l := range sorted {
+		b.WriteByte(0)
+		b.WriteString(l.Key)
+		b.WriteByte('=')
+		b.WriteString(l.Value)
+	}
+
+	return b.String()
+}
+
+func normalizeOTLPEndpoint(endpoint string) string {
+	endpoint = strings.TrimSpace(endpoint)
+	if endpoint == "" {
+		return ""
+	}
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+
+	rest := strings.TrimRight(endpoint[strings.Index(endpoint, "://")+3:], "/")
+	if !strings.Contains(rest, "/") {
+		endpoint = strings.TrimRight(endpoint, "/") + otlpMetricsPath
+	}
+
+	return endpoint
+}
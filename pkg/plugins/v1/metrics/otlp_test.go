@@ -0,0 +1,404 @@
+package metrics_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// otlpPayload is the part of an OTLP/JSON export request the tests inspect.
+type otlpPayload struct {
+	ResourceMetrics []struct {
+		Resource struct {
+			Attributes []otlpAttr `json:"attributes"`
+		} `json:"resource"`
+		ScopeMetrics []struct {
+			Metrics []otlpMetric `json:"metrics"`
+		} `json:"scopeMetrics"`
+	} `json:"resourceMetrics"`
+}
+
+type otlpAttr struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpPoint struct {
+	Attributes   []otlpAttr `json:"attributes"`
+	AsInt        string     `json:"asInt"`
+	AsDouble     float64    `json:"asDouble"`
+	Count        string     `json:"count"`
+	Sum          float64    `json:"sum"`
+	BucketCounts []string   `json:"bucketCounts"`
+	Exemplars    []struct {
+		AsDouble float64 `json:"asDouble"`
+		TraceID  string  `json:"traceId"`
+		SpanID   string  `json:"spanId"`
+	} `json:"exemplars"`
+}
+
+type otlpMetric struct {
+	Name string `json:"name"`
+	Unit string `json:"unit"`
+	Sum  *struct {
+		DataPoints []otlpPoint `json:"dataPoints"`
+	} `json:"sum"`
+	Gauge *struct {
+		DataPoints []otlpPoint `json:"dataPoints"`
+	} `json:"gauge"`
+	Histogram *struct {
+		DataPoints []otlpPoint `json:"dataPoints"`
+	} `json:"histogram"`
+}
+
+// export is one request received by a collector.
+type export struct {
+	path    string
+	headers http.Header
+	payload otlpPayload
+}
+
+// resource returns the resource attributes of the export.
+func (e export) resource() map[string]string {
+	attrs := map[string]string{}
+	for _, rm := range e.payload.ResourceMetrics {
+		for _, a := range rm.Resource.Attributes {
+			attrs[a.Key] = a.Value.StringValue
+		}
+	}
+
+	return attrs
+}
+
+// metric returns the exported metric called name.
+func (e export) metric(t *testing.T, name string) otlpMetric {
+	t.Helper()
+
+	for _, rm := range e.payload.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				if m.Name == name {
+					return m
+				}
+			}
+		}
+	}
+	t.Fatalf("metric %s was not exported", name)
+
+	return otlpMetric{}
+}
+
+// collector is an OTLP/HTTP endpoint recording the exports it receives.
+type collector struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	status  int // Status of the responses; 0 answers 200.
+	exports []export
+}
+
+func newCollector(t *testing.T) *collector {
+	t.Helper()
+
+	c := &collector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p otlpPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("collector received an invalid payload: %v", err)
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.exports = append(c.exports, export{path: r.URL.Path, headers: r.Header, payload: p})
+		if c.status != 0 {
+			w.WriteHeader(c.status)
+		}
+	}))
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+func (c *collector) received() []export {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.exports)
+}
+
+// newOTLP returns an OTLP recorder that is shut down when the test ends. The export interval is
+// long enough that only Flush and Shutdown export.
+func newOTLP(t *testing.T, opts ...metrics.OTLPOption) *metrics.OTLP {
+	t.Helper()
+
+	o, err := metrics.NewOTLP(append([]metrics.OTLPOption{metrics.WithOTLPInterval(time.Hour)}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewOTLP: %v", err)
+	}
+	t.Cleanup(func() { _ = o.Shutdown(context.Background()) })
+
+	return o
+}
+
+func TestNewOTLPErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  metrics.OTLPOption
+		want string
+	}{
+		{"zero interval", metrics.WithOTLPInterval(0), "export interval must be positive"},
+		{"negative interval", metrics.WithOTLPInterval(-time.Second), "export interval must be positive"},
+		{"unsorted bounds", metrics.WithHistogramBounds([]float64{1, 0.5}), "strictly increasing"},
+		{"repeated bound", metrics.WithHistogramBounds([]float64{1, 1}), "strictly increasing"},
+		{"nil client", metrics.WithOTLPClient(nil), "HTTP client cannot be nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := metrics.NewOTLP(tt.opt)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewOTLP error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOTLPEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint func(base string) string
+		wantPath string
+	}{
+		{"metrics path appended", func(base string) string { return base }, "/v1/metrics"},
+		{"trailing slash", func(base string) string { return base + "/" }, "/v1/metrics"},
+		{"explicit path kept", func(base string) string { return base + "/custom" }, "/custom"},
+		{"scheme assumed", func(base string) string { return strings.TrimPrefix(base, "http://") }, "/v1/metrics"},
+		{"surrounding space", func(base string) string { return " " + base + " " }, "/v1/metrics"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCollector(t)
+			o := newOTLP(t, metrics.WithOTLPEndpoint(tt.endpoint(c.URL)))
+			o.Count("requests", 1)
+			if err := o.Flush(); err != nil {
+				t.Fatalf("Flush: %v", err)
+			}
+
+			got := c.received()
+			if len(got) != 1 {
+				t.Fatalf("collector received %d exports, want 1", len(got))
+			}
+			if got[0].path != tt.wantPath {
+				t.Errorf("export path = %s, want %s", got[0].path, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestOTLPSetEndpoint(t *testing.T) {
+	c := newCollector(t)
+
+	t.Run("unset", func(t *testing.T) {
+		o := newOTLP(t)
+		o.Count("requests", 1)
+		if err := o.Flush(); err != nil {
+			t.Errorf("Flush without an endpoint: %v", err)
+		}
+		if o.SetEndpoint("") {
+			t.Error("SetEndpoint applied an empty endpoint")
+		}
+		if !o.SetEndpoint(c.URL) {
+			t.Fatal("SetEndpoint did not apply the first endpoint")
+		}
+		if o.SetEndpoint("http://other.example.com") {
+			t.Error("SetEndpoint replaced an applied endpoint")
+		}
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		o := newOTLP(t, metrics.WithOTLPEndpoint(c.URL))
+		if o.SetEndpoint("http://other.example.com") {
+			t.Error("SetEndpoint replaced the configured endpoint")
+		}
+	})
+}
+
+func TestOTLPFlushNothingToExport(t *testing.T) {
+	c := newCollector(t)
+	o := newOTLP(t, metrics.WithOTLPEndpoint(c.URL))
+
+	if err := o.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got := len(c.received()); got != 0 {
+		t.Errorf("collector received %d exports without metrics, want 0", got)
+	}
+}
+
+func TestOTLPPayload(t *testing.T) {
+	c := newCollector(t)
+	o := newOTLP(t,
+		metrics.WithOTLPEndpoint(c.URL),
+		metrics.WithOTLPHeaders(map[string]string{"Authorization": "Bearer secret"}),
+		metrics.WithResourceAttributes(map[string]string{"service.name": "configured"}),
+		metrics.WithHistogramBounds([]float64{1, 2}),
+	)
+	o.SetResourceAttribute("service.name", "from-mcpd")
+	o.SetResourceAttribute("deployment.environment", "prod")
+	o.SetResourceAttribute("empty", "")
+
+	o.Count("requests", 2, metrics.L("method", "a"), metrics.L("code", "OK"))
+	o.Count("requests", 3, metrics.L("code", "OK"), metrics.L("method", "a"))
+	o.Count("requests", 1, metrics.L("method", "b"))
+	o.Gauge("inflight", 1)
+	o.Gauge("inflight", 4)
+	for _, v := range []float64{0.5, 1, 1.5, 3} {
+		o.Observe("size", v)
+	}
+	o.Timing("latency", 1500*time.Millisecond)
+	if err := o.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	got := c.received()
+	if len(got) != 1 {
+		t.Fatalf("collector received %d exports, want 1", len(got))
+	}
+	exp := got[0]
+	if h := exp.headers.Get("Authorization"); h != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the configured header", h)
+	}
+	if h := exp.headers.Get("Content-Type"); h != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", h)
+	}
+	wantResource := map[string]string{"service.name": "configured", "deployment.environment": "prod"}
+	if res := exp.resource(); len(res) != len(wantResource) ||
+		res["service.name"] != wantResource["service.name"] ||
+		res["deployment.environment"] != wantResource["deployment.environment"] {
+		t.Errorf("resource = %v, want %v", res, wantResource)
+	}
+
+	requests := exp.metric(t, "requests")
+	if requests.Sum == nil || len(requests.Sum.DataPoints) != 2 {
+		t.Fatalf("requests = %+v, want a sum with two series", requests)
+	}
+	var ints []string
+	for _, p := range requests.Sum.DataPoints {
+		ints = append(ints, p.AsInt)
+	}
+	slices.Sort(ints)
+	if want := []string{"1", "5"}; !slices.Equal(ints, want) {
+		t.Errorf("requests values = %v, want %v", ints, want)
+	}
+
+	inflight := exp.metric(t, "inflight")
+	if inflight.Gauge == nil || len(inflight.Gauge.DataPoints) != 1 || inflight.Gauge.DataPoints[0].AsDouble != 4 {
+		t.Errorf("inflight = %+v, want a gauge of 4", inflight)
+	}
+
+	size := exp.metric(t, "size")
+	if size.Histogram == nil || len(size.Histogram.DataPoints) != 1 {
+		t.Fatalf("size = %+v, want a histogram", size)
+	}
+	p := size.Histogram.DataPoints[0]
+	if p.Count != "4" || p.Sum != 6 {
+		t.Errorf("size count, sum = %s, %v; want 4, 6", p.Count, p.Sum)
+	}
+	// Buckets are (-inf, 1], (1, 2] and (2, +inf).
+	if want := []string{"2", "1", "1"}; !slices.Equal(p.BucketCounts, want) {
+		t.Errorf("size buckets = %v, want %v", p.BucketCounts, want)
+	}
+
+	latency := exp.metric(t, "latency")
+	if latency.Unit != "s" || latency.Histogram == nil || latency.Histogram.DataPoints[0].Sum != 1.5 {
+		t.Errorf("latency = %+v, want a histogram in seconds summing 1.5", latency)
+	}
+}
+
+func TestOTLPExemplars(t *testing.T) {
+	c := newCollector(t)
+	o := newOTLP(t, metrics.WithOTLPEndpoint(c.URL), metrics.WithHistogramBounds([]float64{1}))
+	o.TimingExemplar("latency", 500*time.Millisecond, metrics.Exemplar{TraceID: "t1", SpanID: "s1"})
+	o.TimingExemplar("latency", 200*time.Millisecond, metrics.Exemplar{TraceID: "t2", SpanID: "s2"})
+	o.TimingExemplar("latency", 3*time.Second, metrics.Exemplar{TraceID: "t3"})
+	o.TimingExemplar("latency", 4*time.Second, metrics.Exemplar{SpanID: "untraced"})
+	o.Timing("latency", 5*time.Second)
+	if err := o.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	got := c.received()
+	if len(got) != 1 {
+		t.Fatalf("collector received %d exports, want 1", len(got))
+	}
+	latency := got[0].metric(t, "latency")
+	if latency.Histogram == nil || len(latency.Histogram.DataPoints) != 1 {
+		t.Fatalf("latency = %+v, want a histogram", latency)
+	}
+	p := latency.Histogram.DataPoints[0]
+	if p.Count != "5" {
+		t.Errorf("count = %s, want every sample", p.Count)
+	}
+
+	// The most recent traced sample of each bucket is kept.
+	var exemplars []string
+	for _, ex := range p.Exemplars {
+		exemplars = append(exemplars, fmt.Sprintf("%v %s/%s", ex.AsDouble, ex.TraceID, ex.SpanID))
+	}
+	if want := []string{"0.2 t2/s2", "3 t3/"}; !slices.Equal(exemplars, want) {
+		t.Errorf("exemplars = %q, want %q", exemplars, want)
+	}
+}
+
+func TestOTLPCollectorError(t *testing.T) {
+	c := newCollector(t)
+	c.status = http.StatusServiceUnavailable
+	o := newOTLP(t, metrics.WithOTLPEndpoint(c.URL))
+	o.Count("requests", 1)
+
+	err := o.Flush()
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Flush error = %v, want the collector's 503", err)
+	}
+}
+
+func TestOTLPInterval(t *testing.T) {
+	c := newCollector(t)
+	o := newOTLP(t, metrics.WithOTLPEndpoint(c.URL), metrics.WithOTLPInterval(10*time.Millisecond))
+	o.Count("requests", 1)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(c.received()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no export within five seconds of a 10ms interval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOTLPShutdown(t *testing.T) {
+	c := newCollector(t)
+	o, err := metrics.NewOTLP(metrics.WithOTLPEndpoint(c.URL), metrics.WithOTLPInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.Count("requests", 1)
+
+	for i := range 2 {
+		if err := o.Shutdown(context.Background()); err != nil {
+			t.Fatalf("Shutdown %d: %v", i+1, err)
+		}
+	}
+	if got := len(c.received()); got != 2 {
+		t.Errorf("collector received %d exports, want one per Shutdown", got)
+	}
+}
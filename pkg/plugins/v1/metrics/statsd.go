@@ -0,0 +1,243 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults used by NewStatsd.
+const (
+	DefaultStatsdPrefix        = "mcpd_plugin."
+	DefaultStatsdFlushInterval = time.Second
+
+	// statsdMaxPacket keeps datagrams below the typical Ethernet MTU.
+	statsdMaxPacket = 1432
+)
+
+// Statsd is a Recorder that sends metrics over UDP using the statsd line protocol, optionally
+// with DogStatsD tag extensions. Lines are buffered and sent when a datagram fills up, on a
+// fixed interval, and on Flush or Close.
+type Statsd struct {
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+	tags      []Label
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// StatsdOption configures a Statsd recorder.
+type StatsdOption func(*statsdConfig) error
+
+type statsdConfig struct {
+	prefix        string
+	dogstatsd     bool
+	tags          []Label
+	flushInterval time.Duration
+}
+
+// WithPrefix sets the prefix prepended to every metric name (defaults to DefaultStatsdPrefix).
+func WithPrefix(prefix string) StatsdOption {
+	return func(c *statsdConfig) error {
+		c.prefix = prefix
+		return nil
+	}
+}
+
+// WithDogStatsD enables DogStatsD extensions: labels are sent as tags and distributions use the
+// histogram type. Without it, labels are not sent because plain statsd has no notion of them.
+func WithDogStatsD() StatsdOption {
+	return func(c *statsdConfig) error {
+		c.dogstatsd = true
+		return nil
+	}
+}
+
+// WithGlobalTags adds tags sent with every metric (DogStatsD only), e.g. env or service.
+func WithGlobalTags(tags ...Label) StatsdOption {
+	return func(c *statsdConfig) error {
+		c.tags = append(c.tags, tags...)
+		return nil
+	}
+}
+
+// WithFlushInterval sets how often buffered metrics are sent (defaults to DefaultStatsdFlushInterval).
+func WithFlushInterval(d time.Duration) StatsdOption {
+	return func(c *statsdConfig) error {
+		if d <= 0 {
+			return fmt.Errorf("flush interval must be positive")
+		}
+		c.flushInterval = d
+		return nil
+	}
+}
+
+// NewStatsd returns a Statsd recorder sending to addr (host:port, typically "127.0.0.1:8125").
+func NewStatsd(addr string, opts ...StatsdOption) (*Statsd, error) {
+	cfg := &statsdConfig{
+		prefix:        DefaultStatsdPrefix,
+		flushInterval: DefaultStatsdFlushInterval,
+	}
+	for _, opt := range opts {
+		if err := opt(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial statsd at %s: %w", addr, err)
+	}
+
+	s := &Statsd{
+		conn:      conn,
+		prefix:    cfg.prefix,
+		dogstatsd: cfg.dogstatsd,
+		tags:      cfg.tags,
+		done:      make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.loop(cfg.flushInterval)
+
+	return s, nil
+}
+
+// Count implements Recorder.
+func (s *Statsd) Count(name string, delta int64, labels ...Label) {
+	s.write(name, strconv.FormatInt(delta, 10), "c", labels)
+}
+
+// Gauge implements Recorder.
+func (s *Statsd) Gauge(name string, value float64, labels ...Label) {
+	s.write(name, formatFloat(value), "g", labels)
+}
+
+// Observe implements Recorder.
+func (s *Statsd) Observe(name string, value float64, labels ...Label) {
+	typ := "ms"
+	if s.dogstatsd {
+		typ = "h"
+	}
+	s.write(name, formatFloat(value), typ, labels)
+}
+
+// Timing implements Recorder. Durations are sent in milliseconds.
+func (s *Statsd) Timing(name string, d time.Duration, labels ...Label) {
+	s.write(name, formatFloat(float64(d)/float64(time.Millisecond)), "ms", labels)
+}
+
+// Flush sends any buffered metrics.
+func (s *Statsd) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.flushLocked()
+}
+
+// Close flushes buffered metrics and closes the connection. Calls after the first return nil.
+func (s *Statsd) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+
+		err = s.Flush()
+		if cerr := s.conn.Close(); cerr != nil {
+			err = cerr
+		}
+	})
+
+	return err
+}
+
+func (s *Statsd) loop(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = s.Flush()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Statsd) write(name string, value string, typ string, labels []Label) {
+	var line strings.Builder
+	line.WriteString(sanitizeStatsd(s.prefix + name))
+	line.WriteByte(':')
+	line.WriteString(value)
+	line.WriteByte('|')
+	line.WriteString(typ)
+
+	if s.dogstatsd && len(s.tags)+len(labels) > 0 {
+		line.WriteString("|#")
+		first := true
+		for _, group := range [][]Label{s.tags, labels} {
+			for _, l := range group {
+				if !first {
+					line.WriteByte(',')
+				}
+				first = false
+				line.WriteString(sanitizeStatsd(l.Key))
+				line.WriteByte(':')
+				line.WriteString(sanitizeStatsd(l.Value))
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.buf.Len() > 0 && s.buf.Len()+1+line.Len() > statsdMaxPacket {
+		_ = s.flushLocked()
+	}
+	if s.buf.Len() > 0 {
+		s.buf.WriteByte('\n')
+	}
+	s.buf.WriteString(line.String())
+}
+
+// flushLocked sends the buffer. Callers must hold s.mu.
+func (s *Statsd) flushLocked() error {
+	if s.buf.Len() == 0 {
+		return nil
+	}
+	defer s.buf.Reset()
+
+	if _, err := s.conn.Write(s.buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to send statsd metrics: %w", err)
+	}
+
+	return nil
+}
+
+// sanitizeStatsd replaces characters that have meaning in the statsd line protocol.
+func sanitizeStatsd(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', ',', '#', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
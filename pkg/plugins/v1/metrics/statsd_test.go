@@ -0,0 +1,227 @@
+package metrics_test
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// statsdServer is a UDP endpoint collecting the datagrams it receives.
+type statsdServer struct {
+	conn    net.PacketConn
+	packets chan string
+}
+
+func newStatsdServer(t *testing.T) *statsdServer {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &statsdServer{conn: conn, packets: make(chan string, 100)}
+	go func() {
+		buf := make([]byte, 64<<10)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			s.packets <- string(buf[:n])
+		}
+	}()
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return s
+}
+
+func (s *statsdServer) addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+// next returns the next datagram received.
+func (s *statsdServer) next(t *testing.T) string {
+	t.Helper()
+
+	select {
+	case p := <-s.packets:
+		return p
+	case <-time.After(5 * time.Second):
+		t.Fatal("no statsd datagram received")
+		return ""
+	}
+}
+
+func TestStatsdLines(t *testing.T) {
+	record := func(r metrics.Recorder) {
+		r.Count("rpc.requests", 2, metrics.L("method", "HandleRequest"))
+		r.Gauge("queue.depth", 1.5)
+		r.Observe("body.size", 512, metrics.L("flow", "request"))
+		r.Timing("rpc.duration", 1500*time.Microsecond, metrics.L("code", "OK"))
+	}
+
+	tests := []struct {
+		name string
+		opts []metrics.StatsdOption
+		want []string
+	}{
+		{
+			name: "plain statsd drops labels",
+			want: []string{
+				"mcpd_plugin.rpc.requests:2|c",
+				"mcpd_plugin.queue.depth:1.5|g",
+				"mcpd_plugin.body.size:512|ms",
+				"mcpd_plugin.rpc.duration:1.5|ms",
+			},
+		},
+		{
+			name: "dogstatsd tags and histograms",
+			opts: []metrics.StatsdOption{
+				metrics.WithDogStatsD(),
+				metrics.WithPrefix("p."),
+				metrics.WithGlobalTags(metrics.L("env", "prod")),
+			},
+			want: []string{
+				"p.rpc.requests:2|c|#env:prod,method:HandleRequest",
+				"p.queue.depth:1.5|g|#env:prod",
+				"p.body.size:512|h|#env:prod,flow:request",
+				"p.rpc.duration:1.5|ms|#env:prod,code:OK",
+			},
+		},
+		{
+			name: "dogstatsd without tags",
+			opts: []metrics.StatsdOption{metrics.WithDogStatsD(), metrics.WithPrefix("")},
+			want: []string{
+				"rpc.requests:2|c|#method:HandleRequest", "queue.depth:1.5|g", "body.size:512|h|#flow:request",
+				"rpc.duration:1.5|ms|#code:OK",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newStatsdServer(t)
+			s, err := metrics.NewStatsd(srv.addr(), append(tt.opts, metrics.WithFlushInterval(time.Hour))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = s.Close() }()
+
+			record(s)
+			if err := s.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Split(srv.next(t), "\n"); !slices.Equal(got, tt.want) {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsdSanitizes(t *testing.T) {
+	srv := newStatsdServer(t)
+	s, err := metrics.NewStatsd(srv.addr(), metrics.WithDogStatsD(), metrics.WithPrefix(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+
+	s.Count("a:b|c", 1, metrics.L("k,#", "v@\n"))
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := srv.next(t), "a_b_c:1|c|#k__:v__"; got != want {
+		t.Errorf("line = %q, want %q", got, want)
+	}
+}
+
+func TestStatsdFlushing(t *testing.T) {
+	t.Run("on the flush interval", func(t *testing.T) {
+		srv := newStatsdServer(t)
+		s, err := metrics.NewStatsd(srv.addr(), metrics.WithFlushInterval(10*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = s.Close() }()
+
+		s.Count("c", 1)
+		if got := srv.next(t); got != "mcpd_plugin.c:1|c" {
+			t.Errorf("datagram = %q", got)
+		}
+	})
+
+	t.Run("when a datagram fills up", func(t *testing.T) {
+		srv := newStatsdServer(t)
+		s, err := metrics.NewStatsd(srv.addr(), metrics.WithFlushInterval(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = s.Close() }()
+
+		name := strings.Repeat("n", 100)
+		for range 20 {
+			s.Count(name, 1)
+		}
+		if got := srv.next(t); len(got) > 1432 || strings.Count(got, "\n") == 0 {
+			t.Errorf("first datagram is %d bytes with %d lines, want several lines within 1432 bytes",
+				len(got), strings.Count(got, "\n")+1)
+		}
+	})
+
+	t.Run("on close", func(t *testing.T) {
+		srv := newStatsdServer(t)
+		s, err := metrics.NewStatsd(srv.addr(), metrics.WithFlushInterval(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s.Count("c", 1)
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := srv.next(t); got != "mcpd_plugin.c:1|c" {
+			t.Errorf("datagram = %q", got)
+		}
+	})
+}
+
+func TestStatsdCloseTwice(t *testing.T) {
+	srv := newStatsdServer(t)
+	s, err := metrics.NewStatsd(srv.addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("first Close: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestNewStatsdErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		addr string
+		opts []metrics.StatsdOption
+	}{
+		{
+			name: "zero flush interval",
+			addr: "127.0.0.1:8125",
+			opts: []metrics.StatsdOption{metrics.WithFlushInterval(0)},
+		},
+		{name: "invalid address", addr: "not an address"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if s, err := metrics.NewStatsd(tt.addr, tt.opts...); err == nil {
+				_ = s.Close()
+				t.Error("NewStatsd succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// recorderLog is a metrics.Recorder and metrics.Flusher recording samples as lines such as
+// "count rpc.requests 1 method=HandleRequest code=OK".
+type recorderLog struct {
+	mu       sync.Mutex
+	lines    []string
+	flushes  int
+	flushErr error
+}
+
+func (r *recorderLog) add(kind, name string, value any, labels []metrics.Label) {
+	line := fmt.Sprintf("%s %s %v", kind, name, value)
+	for _, l := range labels {
+		line += " " + l.Key + "=" + l.Value
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, line)
+}
+
+func (r *recorderLog) Count(name string, delta int64, labels ...metrics.Label) {
+	r.add("count", name, delta, labels)
+}
+
+func (r *recorderLog) Gauge(name string, value float64, labels ...metrics.Label) {
+	r.add("gauge", name, value, labels)
+}
+
+func (r *recorderLog) Observe(name string, value float64, labels ...metrics.Label) {
+	r.add("observe", name, value, labels)
+}
+
+func (r *recorderLog) Timing(name string, d time.Duration, labels ...metrics.Label) {
+	r.add("timing", name, d, labels)
+}
+
+func (r *recorderLog) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.flushes++
+	return r.flushErr
+}
+
+// named returns the recorded lines for the metric name.
+func (r *recorderLog) named(name string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []string
+	for _, line := range r.lines {
+		var kind, n string
+		if _, err := fmt.Sscan(line, &kind, &n); err == nil && n == name {
+			out = append(out, line)
+		}
+	}
+
+	return out
+}
+
+func TestWithMetrics(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   Event
+		want []string
+	}{
+		{
+			name: "successful RPC",
+			ev:   Event{Kind: EventRequest, Method: "Configure", Duration: time.Millisecond},
+			want: []string{
+				"count rpc.requests 1 method=Configure code=OK",
+				"timing rpc.duration 1ms method=Configure code=OK",
+			},
+		},
+		{
+			name: "failed RPC",
+			ev: Event{
+				Kind:     EventRequest,
+				Method:   "HandleRequest",
+				Duration: 2 * time.Millisecond,
+				Err:      status.Error(codes.InvalidArgument, "bad"),
+			},
+			want: []string{
+				"count rpc.requests 1 method=HandleRequest code=InvalidArgument",
+				"timing rpc.duration 2ms method=HandleRequest code=InvalidArgument",
+			},
+		},
+		{
+			name: "error events are not counted twice",
+			ev:   Event{Kind: EventError, Method: "HandleRequest", Err: errors.New("boom")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := &recorderLog{}, &recorderLog{}
+			o, err := newServeOptions(WithMetrics(a), WithMetrics(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			o.bus.Publish(context.Background(), tt.ev)
+
+			for _, r := range []*recorderLog{a, b} {
+				got := append(r.named(metrics.RPCRequests), r.named(metrics.RPCDuration)...)
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("recorded %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWithMetricsFlushesOnStop(t *testing.T) {
+	r := &recorderLog{flushErr: errors.New("collector down")}
+	o, err := newServeOptions(WithMetrics(r), WithLogger(discardLogger()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, phase := range []Phase{PhaseStarting, PhaseServing, PhaseStopping} {
+		o.bus.Publish(context.Background(), Event{Kind: EventLifecycle, Phase: phase})
+	}
+	if r.flushes != 0 {
+		t.Fatalf("flushed %d times before stopping", r.flushes)
+	}
+	o.bus.Publish(context.Background(), Event{Kind: EventLifecycle, Phase: PhaseStopped})
+	if r.flushes != 1 {
+		t.Errorf("flushed %d times after stopping, want 1", r.flushes)
+	}
+}
+
+func TestWithMetricsNil(t *testing.T) {
+	if _, err := newServeOptions(WithMetrics(nil)); err == nil {
+		t.Error("WithMetrics accepted a nil recorder")
+	}
+}
+
+// discardLogger returns a logger for options whose failures are logged, keeping test output clean.
+func discardLogger() *log.Logger {
+	return log.New(io.Discard, "", 0)
+}
+
+// exemplarLog is a recorderLog implementing metrics.ExemplarRecorder, recording exemplar samples
+// as lines such as "exemplar handler.duration 1ms <trace>/<span> method=HandleRequest".
+type exemplarLog struct{ recorderLog }
+
+func (r *exemplarLog) TimingExemplar(name string, d time.Duration, ex metrics.Exemplar, labels ...metrics.Label) {
+	r.add("exemplar", name, fmt.Sprintf("%v %s/%s", d, ex.TraceID, ex.SpanID), labels)
+}
+
+func TestRecordHandlerDuration(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	traced := &HTTPRequest{Headers: map[string]string{"Traceparent": "00-" + traceID + "-" + spanID + "-01"}}
+	tests := []struct {
+		name string
+		ev   Event
+		want []string
+	}{
+		{
+			name: "continue",
+			ev: Event{
+				Method:  "HandleRequest",
+				Request: &HTTPRequest{},
+				Result:  &HTTPResponse{Continue: true},
+			},
+			want: []string{"timing handler.duration 1ms method=HandleRequest verdict=continue"},
+		},
+		{
+			name: "short circuit",
+			ev: Event{
+				Method:   "HandleResponse",
+				Response: &HTTPResponse{},
+				Result:   &HTTPResponse{StatusCode: 403},
+			},
+			want: []string{"timing handler.duration 1ms method=HandleResponse verdict=short_circuit"},
+		},
+		{
+			name: "error wins over the result",
+			ev: Event{
+				Method:  "HandleRequest",
+				Request: &HTTPRequest{},
+				Result:  &HTTPResponse{StatusCode: 403},
+				Err:     errors.New("boom"),
+			},
+			want: []string{"timing handler.duration 1ms method=HandleRequest verdict=error"},
+		},
+		{
+			name: "no result",
+			ev:   Event{Method: "HandleRequest", Request: &HTTPRequest{}},
+			want: []string{"timing handler.duration 1ms method=HandleRequest verdict=continue"},
+		},
+		{
+			name: "trace exemplar",
+			ev:   Event{Method: "HandleRequest", Request: traced, Result: &HTTPResponse{Continue: true}},
+			want: []string{
+				"exemplar handler.duration 1ms " + traceID + "/" + spanID + " method=HandleRequest verdict=continue",
+			},
+		},
+		{
+			name: "other RPCs",
+			ev:   Event{Method: "Configure"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &exemplarLog{}
+			tt.ev.Kind = EventRequest
+			tt.ev.Duration = time.Millisecond
+			o, err := newServeOptions(WithMetrics(r))
+			if err != nil {
+				t.Fatal(err)
+			}
+			o.bus.Publish(context.Background(), tt.ev)
+
+			if got := r.named(metrics.HandlerDuration); !slices.Equal(got, tt.want) {
+				t.Errorf("recorded %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordHandlerDurationWithoutExemplars(t *testing.T) {
+	r := &recorderLog{}
+	o, err := newServeOptions(WithMetrics(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &HTTPRequest{Headers: map[string]string{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}}
+	o.bus.Publish(context.Background(), Event{
+		Kind:     EventRequest,
+		Method:   "HandleRequest",
+		Duration: time.Millisecond,
+		Request:  req,
+	})
+
+	want := []string{"timing handler.duration 1ms method=HandleRequest verdict=continue"}
+	if got := r.named(metrics.HandlerDuration); !slices.Equal(got, want) {
+		t.Errorf("recorded %q, want %q", got, want)
+	}
+}
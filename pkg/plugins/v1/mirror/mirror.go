@@ -0,0 +1,378 @@
+// Package mirror asynchronously copies selected plugin traffic to a secondary sink, such as a
+// file or an HTTP collector, for offline analysis and for building datasets of MCP traffic.
+//
+// A Mirror captures the HandleRequest and HandleResponse calls it selects, redacts them off the
+// hot path and delivers them in batches; a full queue drops records rather than slowing the plugin
+// down:
+//
+//	sink, err := mirror.NewFileSink("traffic.jsonl")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	ratio, _ := sampling.Probabilistic(0.05)
+//	m, err := mirror.New(sink, mirror.WithSampler(ratio), mirror.WithRedactor(mirror.MaskPII(redactor)))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
+//	err = mcpdpluginsv1.Serve(&MyPlugin{}, mcpdpluginsv1.WithUnaryInterceptor(m.Interceptor()), m.CloseOnShutdown())
+//
+// Records are replay.Record values written as JSON lines, so a mirrored file can also be replayed
+// against a new plugin build with replay.Load and replay.Run. Credential headers are redacted by
+// default (see DefaultRedactedHeaders); bodies are mirrored as they are unless a redactor masks
+// them.
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/replay"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/sampling"
+)
+
+// Defaults used by New.
+const (
+	DefaultQueueSize     = 1024
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = time.Second
+)
+
+// sinkTimeout bounds a single delivery to the sink.
+const sinkTimeout = 10 * time.Second
+
+// closeTimeout bounds how long CloseOnShutdown waits for queued records to be delivered.
+const closeTimeout = 10 * time.Second
+
+// Filter selects the calls to mirror. It sees the record before the handler runs, with Request or
+// Response set according to Method, and must not modify it.
+type Filter func(ctx context.Context, rec *replay.Record) bool
+
+// Mirror copies selected plugin calls to a Sink in the background. It is safe for concurrent use.
+type Mirror struct {
+	sink          Sink
+	sampler       sampling.Sampler
+	filters       []Filter
+	redactors     []Redactor
+	batchSize     int
+	flushInterval time.Duration
+	onError       func(error)
+
+	queue   chan replay.Record
+	flushCh chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+
+	// closeMu orders Mirror's enqueues before Close, so that no record is queued once run has
+	// started its final drain.
+	closeMu sync.RWMutex
+	closed  bool
+	dropped atomic.Int64
+}
+
+// Option configures a Mirror.
+type Option func(*Mirror) error
+
+// WithSampler sets the sampler deciding which selected calls are mirrored, consulted with
+// sampling.FeatureMirror and the RPC name (defaults to sampling.Always()). Calls are sampled
+// before the handler runs, so Params.Err and Params.ShortCircuit are always false.
+func WithSampler(s sampling.Sampler) Option {
+	return func(m *Mirror) error {
+		if s == nil {
+			return fmt.Errorf("sampler cannot be nil")
+		}
+		m.sampler = s
+		return nil
+	}
+}
+
+// WithFilter restricts mirroring to the calls f selects. Filters are combined: a call is mirrored
+// when every filter selects it.
+func WithFilter(f Filter) Option {
+	return func(m *Mirror) error {
+		if f == nil {
+			return fmt.Errorf("filter cannot be nil")
+		}
+		m.filters = append(m.filters, f)
+		return nil
+	}
+}
+
+// WithRedactor adds r to the redactors applied to every record before delivery, after the
+// default RedactHeaders(DefaultRedactedHeaders...).
+func WithRedactor(r Redactor) Option {
+	return func(m *Mirror) error {
+		if r == nil {
+			return fmt.Errorf("redactor cannot be nil")
+		}
+		m.redactors = append(m.redactors, r)
+		return nil
+	}
+}
+
+// WithQueueSize sets how many records may wait for delivery before new ones are dropped.
+func WithQueueSize(n int) Option {
+	return func(m *Mirror) error {
+		if n <= 0 {
+			return fmt.Errorf("queue size must be positive")
+		}
+		m.queue = make(chan replay.Record, n)
+		return nil
+	}
+}
+
+// WithBatchSize sets the maximum number of records delivered to the sink at once.
+func WithBatchSize(n int) Option {
+	return func(m *Mirror) error {
+		if n <= 0 {
+			return fmt.Errorf("batch size must be positive")
+		}
+		m.batchSize = n
+		return nil
+	}
+}
+
+// WithFlushInterval sets how long records may wait before a partial batch is delivered.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(m *Mirror) error {
+		if interval <= 0 {
+			return fmt.Errorf("flush interval must be positive")
+		}
+		m.flushInterval = interval
+		return nil
+	}
+}
+
+// WithErrorHandler sets a function called when a batch could not be delivered (defaults to
+// logging with log.Default()). Undelivered batches are not retried.
+func WithErrorHandler(fn func(error)) Option {
+	return func(m *Mirror) error {
+		if fn == nil {
+			return fmt.Errorf("error handler cannot be nil")
+		}
+		m.onError = fn
+		return nil
+	}
+}
+
+// New starts a Mirror delivering to sink. Call Close, or serve with CloseOnShutdown, to deliver
+// the queued records and stop it.
+func New(sink Sink, opts ...Option) (*Mirror, error) {
+	if sink == nil {
+		return nil, fmt.Errorf("sink is required")
+	}
+
+	m := &Mirror{
+		sink:          sink,
+		sampler:       sampling.Always(),
+		redactors:     []Redactor{RedactHeaders(DefaultRedactedHeaders...)},
+		batchSize:     DefaultBatchSize,
+		flushInterval: DefaultFlushInterval,
+		onError:       func(err error) { log.Printf("mirror: %v", err) },
+		queue:         make(chan replay.Record, DefaultQueueSize),
+		flushCh:       make(chan chan struct{}),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if err := opt(m); err != nil {
+			return nil, err
+		}
+	}
+
+	go m.run()
+
+	return m, nil
+}
+
+// Interceptor returns a gRPC unary server interceptor mirroring the HandleRequest and
+// HandleResponse calls selected by the Mirror's filters and sampler, with the handler's result.
+// Inputs are copied before the handler runs, so handlers that modify their input do not affect
+// the mirrored copy.
+func (m *Mirror) Interceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		rec := replay.Record{Time: time.Now(), Method: path.Base(info.FullMethod)}
+		switch in := req.(type) {
+		case *mcpdpluginsv1.HTTPRequest:
+			rec.Request = in
+		case *mcpdpluginsv1.HTTPResponse:
+			rec.Response = in
+		default:
+			return handler(ctx, req)
+		}
+		if !m.selected(ctx, &rec) {
+			return handler(ctx, req)
+		}
+		if rec.Request != nil {
+			rec.Request = mcpdpluginsv1.CloneRequest(rec.Request)
+		} else {
+			rec.Response = mcpdpluginsv1.CloneResponse(rec.Response)
+		}
+
+		resp, err := handler(ctx, req)
+		if out, ok := resp.(*mcpdpluginsv1.HTTPResponse); ok && err == nil {
+			rec.Result = mcpdpluginsv1.CloneResponse(out)
+		}
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		m.Mirror(rec)
+
+		return resp, err
+	}
+}
+
+// selected reports whether the call described by rec passes the filters and the sampler.
+func (m *Mirror) selected(ctx context.Context, rec *replay.Record) bool {
+	for _, f := range m.filters {
+		if !f(ctx, rec) {
+			return false
+		}
+	}
+
+	return m.sampler.Sample(sampling.Params{Feature: sampling.FeatureMirror, Method: rec.Method})
+}
+
+// Mirror queues rec for redaction and delivery without blocking, bypassing filters and sampling.
+// The Mirror owns rec's messages from then on. If the queue is full, or the Mirror is closed, rec
+// is dropped and counted (see Dropped).
+func (m *Mirror) Mirror(rec replay.Record) {
+	m.closeMu.RLock()
+	defer m.closeMu.RUnlock()
+
+	if m.closed {
+		m.dropped.Add(1)
+		return
+	}
+
+	select {
+	case m.queue <- rec:
+	default:
+		m.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of records discarded because the queue was full or closed.
+func (m *Mirror) Dropped() int64 {
+	return m.dropped.Load()
+}
+
+// Flush delivers all queued records and waits for delivery to finish or ctx to end.
+func (m *Mirror) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case m.flushCh <- ack:
+	case <-m.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting records, delivers what is queued and waits for that to finish or ctx to
+// end. It does not close the sink.
+func (m *Mirror) Close(ctx context.Context) error {
+	m.closeMu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.done)
+	}
+	m.closeMu.Unlock()
+
+	select {
+	case <-m.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CloseOnShutdown returns a ServeOption closing the Mirror when Serve begins shutting down.
+func (m *Mirror) CloseOnShutdown() mcpdpluginsv1.ServeOption {
+	return mcpdpluginsv1.WithEventSubscriber(func(ctx context.Context, ev mcpdpluginsv1.Event) {
+		if ev.Phase != mcpdpluginsv1.PhaseStopping {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), closeTimeout)
+		defer cancel()
+		if err := m.Close(ctx); err != nil {
+			log.Printf("mirror: records still queued at shutdown: %v", err)
+		}
+	}, mcpdpluginsv1.EventLifecycle)
+}
+
+func (m *Mirror) run() {
+	defer close(m.stopped)
+
+	ticker := time.NewTicker(m.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]replay.Record, 0, m.batchSize)
+	add := func(rec replay.Record) {
+		for _, r := range m.redactors {
+			r(&rec)
+		}
+		batch = append(batch, rec)
+		if len(batch) >= m.batchSize {
+			m.deliver(batch)
+			batch = batch[:0]
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case rec := <-m.queue:
+				add(rec)
+			default:
+				if len(batch) > 0 {
+					m.deliver(batch)
+					batch = batch[:0]
+				}
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case rec := <-m.queue:
+			add(rec)
+		case <-ticker.C:
+			if len(batch) > 0 {
+				m.deliver(batch)
+				batch = batch[:0]
+			}
+		case ack := <-m.flushCh:
+			drain()
+			close(ack)
+		case <-m.done:
+			drain()
+			return
+		}
+	}
+}
+
+// deliver writes batch to the sink, reporting failures to the error handler.
+func (m *Mirror) deliver(batch []replay.Record) {
+	ctx, cancel := context.WithTimeout(context.Background(), sinkTimeout)
+	defer cancel()
+
+	if err := m.sink.Write(ctx, batch); err != nil {
+		m.onError(fmt.Errorf("failed to mirror %d record(s): %w", len(batch), err))
+	}
+}
@@ -0,0 +1,529 @@
+package mirror_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mirror"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/replay"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/sampling"
+)
+
+// memSink collects the batches delivered to it. While block is set, writes wait for it to be
+// closed; entered receives a value as each write starts.
+type memSink struct {
+	mu      sync.Mutex
+	batches [][]replay.Record
+	err     error
+	block   chan struct{}
+	entered chan struct{}
+}
+
+func (s *memSink) Write(ctx context.Context, batch []replay.Record) error {
+	s.mu.Lock()
+	block, entered := s.block, s.entered
+	s.mu.Unlock()
+	if entered != nil {
+		entered <- struct{}{}
+	}
+	if block != nil {
+		select {
+		case <-block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, append([]replay.Record(nil), batch...))
+
+	return s.err
+}
+
+func (s *memSink) sizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sizes []int
+	for _, b := range s.batches {
+		sizes = append(sizes, len(b))
+	}
+
+	return sizes
+}
+
+func (s *memSink) records() []replay.Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var all []replay.Record
+	for _, b := range s.batches {
+		all = append(all, b...)
+	}
+
+	return all
+}
+
+// newMirror returns a Mirror delivering to sink, closed when the test ends.
+func newMirror(t *testing.T, sink mirror.Sink, opts ...mirror.Option) *mirror.Mirror {
+	t.Helper()
+
+	m, err := mirror.New(sink, opts...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = m.Close(context.Background()) })
+
+	return m
+}
+
+func flush(t *testing.T, m *mirror.Mirror) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		sink mirror.Sink
+		opt  mirror.Option
+	}{
+		{name: "nil sink"},
+		{name: "nil sampler", sink: &memSink{}, opt: mirror.WithSampler(nil)},
+		{name: "nil filter", sink: &memSink{}, opt: mirror.WithFilter(nil)},
+		{name: "nil redactor", sink: &memSink{}, opt: mirror.WithRedactor(nil)},
+		{name: "zero queue size", sink: &memSink{}, opt: mirror.WithQueueSize(0)},
+		{name: "negative batch size", sink: &memSink{}, opt: mirror.WithBatchSize(-1)},
+		{name: "zero flush interval", sink: &memSink{}, opt: mirror.WithFlushInterval(0)},
+		{name: "nil error handler", sink: &memSink{}, opt: mirror.WithErrorHandler(nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []mirror.Option
+			if tt.opt != nil {
+				opts = append(opts, tt.opt)
+			}
+			if _, err := mirror.New(tt.sink, opts...); err == nil {
+				t.Error("New succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestInterceptor(t *testing.T) {
+	req := &mcpdpluginsv1.HTTPRequest{
+		Method:  "POST",
+		Path:    "/mcp",
+		Headers: map[string]string{"Authorization": "Bearer secret", "X-Trace": "t1"},
+	}
+	tests := []struct {
+		name       string
+		opts       []mirror.Option
+		method     string
+		in         any
+		handlerErr error
+		want       bool
+		wantMethod string
+	}{
+		{name: "request", method: mcpdpluginsv1.Plugin_HandleRequest_FullMethodName, in: req, want: true},
+		{
+			name:   "response",
+			method: mcpdpluginsv1.Plugin_HandleResponse_FullMethodName,
+			in:     &mcpdpluginsv1.HTTPResponse{StatusCode: 200},
+			want:   true,
+		},
+		{name: "other messages", method: mcpdpluginsv1.Plugin_CheckHealth_FullMethodName, in: &emptypb.Empty{}},
+		{
+			name:   "filtered out",
+			opts:   []mirror.Option{mirror.WithFilter(func(context.Context, *replay.Record) bool { return false })},
+			method: mcpdpluginsv1.Plugin_HandleRequest_FullMethodName,
+			in:     req,
+		},
+		{
+			name: "every filter must select",
+			opts: []mirror.Option{
+				mirror.WithFilter(func(_ context.Context, r *replay.Record) bool { return r.Request != nil }),
+				mirror.WithFilter(func(_ context.Context, r *replay.Record) bool {
+					return r.Method == "HandleResponse"
+				}),
+			},
+			method: mcpdpluginsv1.Plugin_HandleRequest_FullMethodName,
+			in:     req,
+		},
+		{
+			name:   "sampled out",
+			opts:   []mirror.Option{mirror.WithSampler(sampling.Never())},
+			method: mcpdpluginsv1.Plugin_HandleRequest_FullMethodName,
+			in:     req,
+		},
+		{
+			name:       "handler error",
+			method:     mcpdpluginsv1.Plugin_HandleRequest_FullMethodName,
+			in:         req,
+			handlerErr: errors.New("boom"),
+			want:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &memSink{}
+			m := newMirror(t, sink, tt.opts...)
+
+			result := &mcpdpluginsv1.HTTPResponse{Continue: true, Headers: map[string]string{"Set-Cookie": "s=1"}}
+			handler := func(_ context.Context, in any) (any, error) {
+				// Handlers modifying their input do not change the mirrored copy.
+				if r, ok := in.(*mcpdpluginsv1.HTTPRequest); ok {
+					r.Path = "/changed"
+				}
+				if tt.handlerErr != nil {
+					return nil, tt.handlerErr
+				}
+				return result, nil
+			}
+			in := tt.in
+			if r, ok := in.(*mcpdpluginsv1.HTTPRequest); ok {
+				in = mcpdpluginsv1.CloneRequest(r)
+			}
+			info := &grpc.UnaryServerInfo{FullMethod: tt.method}
+			resp, err := m.Interceptor()(context.Background(), in, info, handler)
+			if !errors.Is(err, tt.handlerErr) {
+				t.Fatalf("interceptor error = %v, want %v", err, tt.handlerErr)
+			}
+			if tt.handlerErr == nil && resp != result {
+				t.Errorf("interceptor returned %v, want the handler's response", resp)
+			}
+			flush(t, m)
+
+			got := sink.records()
+			if !tt.want {
+				if len(got) != 0 {
+					t.Errorf("mirrored %v, want nothing", got)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("mirrored %d records, want 1", len(got))
+			}
+			rec := got[0]
+			if want := tt.method[strings.LastIndex(tt.method, "/")+1:]; rec.Method != want {
+				t.Errorf("Method = %q, want %q", rec.Method, want)
+			}
+			if rec.Time.IsZero() {
+				t.Error("Time is not set")
+			}
+			if rec.Request != nil {
+				if rec.Request.GetPath() != "/mcp" {
+					t.Errorf("mirrored path = %q, want the input before the handler ran", rec.Request.GetPath())
+				}
+				if h := rec.Request.GetHeaders(); h["Authorization"] != mcpdpluginsv1.Redacted || h["X-Trace"] != "t1" {
+					t.Errorf("mirrored headers = %v, want Authorization redacted", h)
+				}
+			}
+			if tt.handlerErr != nil {
+				if rec.Error != "boom" || rec.Result != nil {
+					t.Errorf("Error = %q, Result = %v, want the handler error only", rec.Error, rec.Result)
+				}
+				return
+			}
+			if rec.Result == nil || rec.Result == result ||
+				rec.Result.GetHeaders()["Set-Cookie"] != mcpdpluginsv1.Redacted {
+				t.Errorf("Result = %v, want a redacted copy of the handler's response", rec.Result)
+			}
+			if result.GetHeaders()["Set-Cookie"] != "s=1" {
+				t.Error("redaction changed the handler's response")
+			}
+		})
+	}
+}
+
+func TestMirrorBatching(t *testing.T) {
+	tests := []struct {
+		name      string
+		batchSize int
+		records   int
+		want      []int
+	}{
+		{name: "full batches and a partial one", batchSize: 2, records: 5, want: []int{2, 2, 1}},
+		{name: "exact batches", batchSize: 3, records: 6, want: []int{3, 3}},
+		{name: "one batch", batchSize: 100, records: 4, want: []int{4}},
+		{name: "nothing queued", batchSize: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &memSink{}
+			m := newMirror(t, sink, mirror.WithBatchSize(tt.batchSize), mirror.WithFlushInterval(time.Hour))
+			for i := range tt.records {
+				m.Mirror(replay.Record{Method: fmt.Sprint(i)})
+			}
+			flush(t, m)
+
+			got := sink.sizes()
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("batch sizes = %v, want %v", got, tt.want)
+			}
+			for i, rec := range sink.records() {
+				if rec.Method != fmt.Sprint(i) {
+					t.Errorf("record %d = %q, want records in order", i, rec.Method)
+				}
+			}
+		})
+	}
+}
+
+func TestMirrorFlushInterval(t *testing.T) {
+	sink := &memSink{}
+	m := newMirror(t, sink, mirror.WithFlushInterval(10*time.Millisecond))
+	m.Mirror(replay.Record{Method: "HandleRequest"})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(sink.records()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("partial batch was not delivered after the flush interval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMirrorQueueFull(t *testing.T) {
+	sink := &memSink{block: make(chan struct{}), entered: make(chan struct{}, 1)}
+	m := newMirror(t, sink, mirror.WithQueueSize(1), mirror.WithBatchSize(1))
+
+	// The first record is taken off the queue and its delivery blocks, the second fills the
+	// queue, and the third is dropped.
+	m.Mirror(replay.Record{Method: "1"})
+	<-sink.entered
+	m.Mirror(replay.Record{Method: "2"})
+	m.Mirror(replay.Record{Method: "3"})
+	if got := m.Dropped(); got != 1 {
+		t.Errorf("Dropped = %d, want 1", got)
+	}
+
+	sink.mu.Lock()
+	sink.entered = nil
+	sink.mu.Unlock()
+	close(sink.block)
+	flush(t, m)
+	if got := len(sink.records()); got != 2 {
+		t.Errorf("delivered %d records, want 2", got)
+	}
+}
+
+func TestMirrorErrorHandler(t *testing.T) {
+	errs := make(chan error, 1)
+	sink := &memSink{err: errors.New("collector down")}
+	m := newMirror(t, sink, mirror.WithErrorHandler(func(err error) { errs <- err }))
+
+	m.Mirror(replay.Record{})
+	m.Mirror(replay.Record{})
+	flush(t, m)
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, sink.err) || !strings.Contains(err.Error(), "failed to mirror 2 record(s)") {
+			t.Errorf("error handler got %v, want the sink error", err)
+		}
+	default:
+		t.Fatal("error handler was not called")
+	}
+}
+
+func TestMirrorClose(t *testing.T) {
+	sink := &memSink{}
+	m := newMirror(t, sink, mirror.WithFlushInterval(time.Hour))
+	m.Mirror(replay.Record{Method: "queued"})
+
+	if err := m.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(sink.records()); got != 1 {
+		t.Errorf("delivered %d records on Close, want the queued one", got)
+	}
+
+	// Once closed, records are dropped and Close and Flush return at once.
+	m.Mirror(replay.Record{Method: "late"})
+	if got := m.Dropped(); got != 1 {
+		t.Errorf("Dropped = %d, want 1", got)
+	}
+	if err := m.Close(context.Background()); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if err := m.Flush(context.Background()); err != nil {
+		t.Errorf("Flush after Close: %v", err)
+	}
+	if got := len(sink.records()); got != 1 {
+		t.Errorf("delivered %d records, want the late one dropped", got)
+	}
+}
+
+func TestMirrorCloseTimeout(t *testing.T) {
+	sink := &memSink{block: make(chan struct{})}
+	m := newMirror(t, sink, mirror.WithBatchSize(1))
+	defer close(sink.block)
+	m.Mirror(replay.Record{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close error = %v, want the context deadline", err)
+	}
+	if err := m.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Flush error = %v, want the context deadline", err)
+	}
+}
+
+func TestMirrorConcurrentClose(t *testing.T) {
+	// Every record is either delivered or counted as dropped, even when Close races with Mirror.
+	for range 5 {
+		sink := &memSink{}
+		m := newMirror(t, sink, mirror.WithQueueSize(10000), mirror.WithBatchSize(1))
+
+		const writers, perWriter = 8, 1000
+		var wg sync.WaitGroup
+		for range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range perWriter {
+					m.Mirror(replay.Record{})
+				}
+			}()
+		}
+		for len(sink.records()) == 0 {
+			time.Sleep(10 * time.Microsecond)
+		}
+		if err := m.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		wg.Wait()
+
+		if got := int64(len(sink.records())) + m.Dropped(); got != writers*perWriter {
+			t.Fatalf("delivered and dropped %d records, want %d", got, writers*perWriter)
+		}
+	}
+}
+
+// serveEnv makes the test binary serve a plugin mirroring one record to the file it names.
+const serveEnv = "MIRROR_TEST_SERVE"
+
+func TestMain(m *testing.M) {
+	path := os.Getenv(serveEnv)
+	if path == "" {
+		os.Exit(m.Run())
+	}
+
+	sink, err := mirror.NewFileSink(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mi, err := mirror.New(sink, mirror.WithFlushInterval(time.Hour))
+	if err != nil {
+		log.Fatal(err)
+	}
+	mi.Mirror(replay.Record{Method: "HandleRequest", Request: &mcpdpluginsv1.HTTPRequest{Path: "/mcp"}})
+	if err := mcpdpluginsv1.Serve(&mcpdpluginsv1.BasePlugin{}, mi.CloseOnShutdown()); err != nil {
+		log.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		log.Fatal(err)
+	}
+	os.Exit(0)
+}
+
+func TestCloseOnShutdown(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unix socket paths are short, so the socket is not placed under t.TempDir.
+	dir, err := os.MkdirTemp("", "mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "traffic.jsonl")
+	var out syncBuffer
+	cmd := exec.Command(exe, "--address", filepath.Join(dir, "plugin.sock"), "--network", "unix")
+	cmd.Env = append(os.Environ(), serveEnv+"="+path)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	defer func() { _ = cmd.Process.Kill() }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "Plugin server listening") {
+		if time.Now().After(deadline) {
+			t.Fatalf("plugin did not start:\n%s", out.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-exited:
+		if err != nil {
+			t.Fatalf("plugin exited with %v:\n%s", err, out.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("plugin did not exit after SIGTERM:\n%s", out.String())
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	recs, err := replay.Load(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].Request.GetPath() != "/mcp" {
+		t.Errorf("mirrored file holds %v, want the queued record delivered at shutdown", recs)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
@@ -0,0 +1,80 @@
+package mirror
+
+import (
+	"net/http"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/pii"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/replay"
+)
+
+// DefaultRedactedHeaders are the credential headers every Mirror redacts.
+var DefaultRedactedHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "Mcp-Session-Id",
+}
+
+// Redactor removes sensitive data from a record before it is delivered. Redactors run on the
+// Mirror's delivery goroutine, off the request path, and may modify the record's messages.
+type Redactor func(rec *replay.Record)
+
+// RedactHeaders replaces the values of the named headers (case-insensitive) with
+// mcpdpluginsv1.Redacted in the record's request, response and result.
+func RedactHeaders(names ...string) Redactor {
+	canonical := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		canonical[http.CanonicalHeaderKey(n)] = struct{}{}
+	}
+
+	redact := func(headers map[string]string) {
+		for k := range headers {
+			if _, ok := canonical[http.CanonicalHeaderKey(k)]; ok {
+				headers[k] = mcpdpluginsv1.Redacted
+			}
+		}
+	}
+
+	return func(rec *replay.Record) {
+		if rec.Request != nil {
+			redact(rec.Request.GetHeaders())
+		}
+		if rec.Response != nil {
+			redact(rec.Response.GetHeaders())
+		}
+		if rec.Result != nil {
+			redact(rec.Result.GetHeaders())
+			if mr := rec.Result.GetModifiedRequest(); mr != nil {
+				redact(mr.GetHeaders())
+			}
+		}
+	}
+}
+
+// MaskPII masks the PII r finds in the record's bodies: JSON bodies value by value, other bodies
+// as text.
+func MaskPII(r *pii.Redactor) Redactor {
+	mask := func(body []byte) []byte {
+		if len(body) == 0 {
+			return body
+		}
+		if masked, _, err := r.MaskJSON(body); err == nil {
+			return masked
+		}
+		masked, _ := r.MaskString(string(body))
+		return []byte(masked)
+	}
+
+	return func(rec *replay.Record) {
+		if rec.Request != nil {
+			rec.Request.Body = mask(rec.Request.GetBody())
+		}
+		if rec.Response != nil {
+			rec.Response.Body = mask(rec.Response.GetBody())
+		}
+		if rec.Result != nil {
+			rec.Result.Body = mask(rec.Result.GetBody())
+			if mr := rec.Result.GetModifiedRequest(); mr != nil {
+				mr.Body = mask(mr.GetBody())
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package mirror_test
+
+import (
+	"testing"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mirror"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/pii"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/replay"
+)
+
+func TestRedactHeaders(t *testing.T) {
+	headers := func() map[string]string {
+		return map[string]string{"authorization": "Bearer x", "X-API-KEY": "k", "Accept": "application/json"}
+	}
+	want := map[string]string{
+		"authorization": mcpdpluginsv1.Redacted,
+		"X-API-KEY":     mcpdpluginsv1.Redacted,
+		"Accept":        "application/json",
+	}
+	tests := []struct {
+		name string
+		rec  replay.Record
+		get  func(rec *replay.Record) map[string]string
+	}{
+		{
+			name: "request",
+			rec:  replay.Record{Request: &mcpdpluginsv1.HTTPRequest{Headers: headers()}},
+			get:  func(rec *replay.Record) map[string]string { return rec.Request.GetHeaders() },
+		},
+		{
+			name: "response",
+			rec:  replay.Record{Response: &mcpdpluginsv1.HTTPResponse{Headers: headers()}},
+			get:  func(rec *replay.Record) map[string]string { return rec.Response.GetHeaders() },
+		},
+		{
+			name: "result",
+			rec:  replay.Record{Result: &mcpdpluginsv1.HTTPResponse{Headers: headers()}},
+			get:  func(rec *replay.Record) map[string]string { return rec.Result.GetHeaders() },
+		},
+		{
+			name: "modified request",
+			rec: replay.Record{Result: &mcpdpluginsv1.HTTPResponse{
+				ModifiedRequest: &mcpdpluginsv1.HTTPRequest{Headers: headers()},
+			}},
+			get: func(rec *replay.Record) map[string]string { return rec.Result.GetModifiedRequest().GetHeaders() },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mirror.RedactHeaders("Authorization", "x-api-key")(&tt.rec)
+			got := tt.get(&tt.rec)
+			for k, v := range want {
+				if got[k] != v {
+					t.Errorf("header %s = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+
+	// A record without messages is left alone.
+	mirror.RedactHeaders(mirror.DefaultRedactedHeaders...)(&replay.Record{})
+}
+
+func TestMaskPII(t *testing.T) {
+	r, err := pii.NewRedactor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "JSON", body: `{"email":"jane@example.com","n":1}`, want: `{"email":"[EMAIL]","n":1}`},
+		{name: "text", body: "mail jane@example.com", want: "mail [EMAIL]"},
+		{name: "nothing to mask", body: "hello", want: "hello"},
+		{name: "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := replay.Record{
+				Request:  &mcpdpluginsv1.HTTPRequest{Body: []byte(tt.body)},
+				Response: &mcpdpluginsv1.HTTPResponse{Body: []byte(tt.body)},
+				Result: &mcpdpluginsv1.HTTPResponse{
+					Body:            []byte(tt.body),
+					ModifiedRequest: &mcpdpluginsv1.HTTPRequest{Body: []byte(tt.body)},
+				},
+			}
+			mirror.MaskPII(r)(&rec)
+
+			bodies := map[string][]byte{
+				"request":          rec.Request.GetBody(),
+				"response":         rec.Response.GetBody(),
+				"result":           rec.Result.GetBody(),
+				"modified request": rec.Result.GetModifiedRequest().GetBody(),
+			}
+			for name, got := range bodies {
+				if string(got) != tt.want {
+					t.Errorf("%s body = %q, want %q", name, got, tt.want)
+				}
+			}
+		})
+	}
+}
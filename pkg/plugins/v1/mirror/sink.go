@@ -0,0 +1,135 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/replay"
+)
+
+// Sink delivers a batch of mirrored records. Write must not retain batch after it returns.
+type Sink interface {
+	Write(ctx context.Context, batch []replay.Record) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(ctx context.Context, batch []replay.Record) error
+
+// Write calls f(ctx, batch).
+func (f SinkFunc) Write(ctx context.Context, batch []replay.Record) error {
+	return f(ctx, batch)
+}
+
+// encodeLines encodes batch as JSON lines.
+func encodeLines(batch []replay.Record) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, rec := range batch {
+		if err := enc.Encode(rec); err != nil {
+			return nil, fmt.Errorf("failed to encode mirrored record: %w", err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// WriterSink writes batches to an io.Writer as JSON lines. It is safe for concurrent use.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a sink writing to w.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write implements Sink.
+func (s *WriterSink) Write(_ context.Context, batch []replay.Record) error {
+	lines, err := encodeLines(batch)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(lines)
+	return err
+}
+
+// FileSink appends batches to a file as JSON lines, readable with replay.Load.
+type FileSink struct {
+	*WriterSink
+	f *os.File
+}
+
+// NewFileSink opens, creating it if needed, the file at path for appending. The file is created
+// with mode 0600 since mirrored traffic may be sensitive.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mirror file: %w", err)
+	}
+
+	return &FileSink{WriterSink: NewWriterSink(f), f: f}, nil
+}
+
+// Close closes the file. Close the Mirror first so queued records are written.
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
+
+// HTTPSink posts batches to an HTTP endpoint as JSON lines (Content-Type application/x-ndjson).
+type HTTPSink struct {
+	url     string
+	client  *http.Client
+	headers map[string]string
+}
+
+// NewHTTPSink returns a sink posting to url with client (http.DefaultClient when nil; the
+// delivery itself is bounded by a 10s timeout). Extra headers, such as an Authorization header,
+// are added to every request.
+func NewHTTPSink(url string, client *http.Client, headers map[string]string) *HTTPSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &HTTPSink{url: url, client: client, headers: headers}
+}
+
+// Write implements Sink.
+func (s *HTTPSink) Write(ctx context.Context, batch []replay.Record) error {
+	lines, err := encodeLines(batch)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(lines))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post mirrored records to %s: %w", s.url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("mirror sink %s returned %s", s.url, resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package mirror_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mirror"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/replay"
+)
+
+// testBatch returns a batch of n request records with paths /0, /1, ...
+func testBatch(n int) []replay.Record {
+	batch := make([]replay.Record, n)
+	for i := range batch {
+		batch[i] = replay.Record{
+			Method:  "HandleRequest",
+			Request: &mcpdpluginsv1.HTTPRequest{Path: "/" + string(rune('0'+i))},
+		}
+	}
+
+	return batch
+}
+
+// loadPaths decodes the JSON lines in data and returns their request paths.
+func loadPaths(t *testing.T, data []byte) []string {
+	t.Helper()
+
+	recs, err := replay.Load(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("replay.Load: %v", err)
+	}
+	var paths []string
+	for _, rec := range recs {
+		paths = append(paths, rec.Request.GetPath())
+	}
+
+	return paths
+}
+
+func TestSinkFunc(t *testing.T) {
+	var got int
+	s := mirror.SinkFunc(func(_ context.Context, batch []replay.Record) error {
+		got = len(batch)
+		return nil
+	})
+	if err := s.Write(context.Background(), testBatch(3)); err != nil || got != 3 {
+		t.Errorf("Write = %v with %d records, want 3", err, got)
+	}
+}
+
+// errWriter fails every write.
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	s := mirror.NewWriterSink(&buf)
+	if err := s.Write(context.Background(), testBatch(2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Write(context.Background(), testBatch(1)); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(loadPaths(t, buf.Bytes()), ","); got != "/0,/1,/0" {
+		t.Errorf("paths = %s, want /0,/1,/0", got)
+	}
+
+	if err := mirror.NewWriterSink(errWriter{}).Write(context.Background(), testBatch(1)); err == nil {
+		t.Error("Write succeeded on a failing writer")
+	}
+}
+
+func TestWriterSinkConcurrent(t *testing.T) {
+	var buf bytes.Buffer
+	s := mirror.NewWriterSink(&buf)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = s.Write(context.Background(), testBatch(3))
+		}()
+	}
+	wg.Wait()
+	if got := len(loadPaths(t, buf.Bytes())); got != 24 {
+		t.Errorf("decoded %d records, want 24 intact lines", got)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traffic.jsonl")
+	for _, n := range []int{2, 1} {
+		s, err := mirror.NewFileSink(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Write(context.Background(), testBatch(n)); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(loadPaths(t, data), ","); got != "/0,/1,/0" {
+		t.Errorf("paths = %s, want the second sink to append", got)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("file mode = %o, want 0600", mode)
+	}
+
+	if _, err := mirror.NewFileSink(filepath.Join(t.TempDir(), "missing", "traffic.jsonl")); err == nil {
+		t.Error("NewFileSink succeeded in a missing directory")
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "server error", status: http.StatusBadGateway, wantErr: "returned 502 Bad Gateway"},
+		{name: "redirect not followed", status: http.StatusNotModified, wantErr: "returned 304"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu   sync.Mutex
+				got  *http.Request
+				body []byte
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				got = r
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			s := mirror.NewHTTPSink(srv.URL, nil, map[string]string{"Authorization": "Bearer t"})
+			err := s.Write(context.Background(), testBatch(2))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Write error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if got.Method != http.MethodPost || got.Header.Get("Content-Type") != "application/x-ndjson" {
+				t.Errorf("request = %s %s, want a POST of JSON lines", got.Method, got.Header.Get("Content-Type"))
+			}
+			if got.Header.Get("Authorization") != "Bearer t" {
+				t.Errorf("Authorization = %q, want the extra header", got.Header.Get("Authorization"))
+			}
+			if paths := strings.Join(loadPaths(t, body), ","); paths != "/0,/1" {
+				t.Errorf("posted paths = %s, want /0,/1", paths)
+			}
+		})
+	}
+}
+
+func TestHTTPSinkErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr string
+	}{
+		{name: "unreachable", url: srv.URL, wantErr: "failed to post mirrored records to " + srv.URL},
+		{name: "invalid URL", url: "http://[::1", wantErr: "failed to create request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := mirror.NewHTTPSink(tt.url, srv.Client(), nil).Write(context.Background(), testBatch(1))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Write error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Batcher is a Moderator merging concurrent calls into batched calls to another Moderator, for
+// services that price or rate-limit per request. It is safe for concurrent use.
+type Batcher struct {
+	next    Moderator
+	maxSize int
+	maxWait time.Duration
+
+	in        chan *batchCall
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type batchCall struct {
+	ctx     context.Context
+	inputs  []string
+	results []Result
+	err     error
+	ready   chan struct{}
+}
+
+// NewBatcher returns a Batcher sending up to maxSize texts per call to m, waiting at most maxWait
+// after the first pending call for others to join. Call Close to stop it.
+func NewBatcher(m Moderator, maxSize int, maxWait time.Duration) (*Batcher, error) {
+	if m == nil {
+		return nil, fmt.Errorf("moderator is required")
+	}
+	if maxSize <= 0 || maxWait <= 0 {
+		return nil, fmt.Errorf("batch size and wait must be positive")
+	}
+
+	b := &Batcher{
+		next:    m,
+		maxSize: maxSize,
+		maxWait: maxWait,
+		in:      make(chan *batchCall),
+		done:    make(chan struct{}),
+	}
+	go b.loop()
+
+	return b, nil
+}
+
+// Moderate implements Moderator.
+func (b *Batcher) Moderate(ctx context.Context, inputs []string) ([]Result, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	call := &batchCall{ctx: ctx, inputs: inputs, ready: make(chan struct{})}
+	select {
+	case b.in <- call:
+	case <-b.done:
+		return nil, errors.New("moderation batcher is closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case <-call.ready:
+		return call.results, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close stops the Batcher. Calls already batched complete; later calls fail.
+func (b *Batcher) Close() error {
+	b.closeOnce.Do(func() { close(b.done) })
+	return nil
+}
+
+func (b *Batcher) loop() {
+	for {
+		var first *batchCall
+		select {
+		case first = <-b.in:
+		case <-b.done:
+			return
+		}
+
+		calls := []*batchCall{first}
+		size := len(first.inputs)
+		timer := time.NewTimer(b.maxWait)
+	collect:
+		for size < b.maxSize {
+			select {
+			case c := <-b.in:
+				calls = append(calls, c)
+				size += len(c.inputs)
+			case <-timer.C:
+				break collect
+			case <-b.done:
+				break collect
+			}
+		}
+		timer.Stop()
+
+		go b.flush(calls)
+	}
+}
+
+// flush sends the inputs of calls as one batch, splitting the results back.
+func (b *Batcher) flush(calls []*batchCall) {
+	var inputs []string
+	for _, c := range calls {
+		inputs = append(inputs, c.inputs...)
+	}
+
+	// The batch outlives any single caller, so it runs detached from their cancellation.
+	results, err := b.next.Moderate(context.WithoutCancel(calls[0].ctx), inputs)
+	if err == nil && len(results) != len(inputs) {
+		err = fmt.Errorf("moderator returned %d results for %d inputs", len(results), len(inputs))
+	}
+
+	offset := 0
+	for _, c := range calls {
+		if err != nil {
+			c.err = err
+		} else {
+			c.results = results[offset : offset+len(c.inputs)]
+		}
+		offset += len(c.inputs)
+		close(c.ready)
+	}
+}
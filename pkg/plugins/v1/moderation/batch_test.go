@@ -0,0 +1,186 @@
+package moderation_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/moderation"
+)
+
+func newBatcher(t *testing.T, m moderation.Moderator, size int, wait time.Duration) *moderation.Batcher {
+	t.Helper()
+
+	b, err := moderation.NewBatcher(m, size, wait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = b.Close() })
+
+	return b
+}
+
+func TestNewBatcherErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		m    moderation.Moderator
+		size int
+		wait time.Duration
+		want string
+	}{
+		{name: "no moderator", size: 1, wait: time.Millisecond, want: "moderator is required"},
+		{name: "zero size", m: &fakeModerator{}, wait: time.Millisecond, want: "must be positive"},
+		{name: "zero wait", m: &fakeModerator{}, size: 1, want: "must be positive"},
+		{name: "negative wait", m: &fakeModerator{}, size: 1, wait: -time.Second, want: "must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := moderation.NewBatcher(tt.m, tt.size, tt.wait)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewBatcher error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// moderateAll calls m from n goroutines at once, each with the texts "<i>-a" and "<i>-b" (or
+// "bad" for odd i) and returns each caller's results and error.
+func moderateAll(m moderation.Moderator, n int) ([][]moderation.Result, []error) {
+	results := make([][]moderation.Result, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			second := fmt.Sprintf("%d-b", i)
+			if i%2 == 1 {
+				second = fmt.Sprintf("%d-bad", i)
+			}
+			results[i], errs[i] = m.Moderate(context.Background(), []string{fmt.Sprintf("%d-a", i), second})
+		}()
+	}
+	wg.Wait()
+
+	return results, errs
+}
+
+func TestBatcher(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		callers    int
+		maxBatches int
+		wantMax    int // Largest batch allowed.
+	}{
+		{name: "merged", size: 100, callers: 8, maxBatches: 2, wantMax: 16},
+		{name: "split at the size", size: 4, callers: 8, maxBatches: 8, wantMax: 4},
+		{name: "single caller", size: 100, callers: 1, maxBatches: 1, wantMax: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &fakeModerator{}
+			b := newBatcher(t, next, tt.size, 50*time.Millisecond)
+
+			results, errs := moderateAll(b, tt.callers)
+			for i := range tt.callers {
+				if errs[i] != nil {
+					t.Fatalf("caller %d: %v", i, errs[i])
+				}
+				if len(results[i]) != 2 || results[i][0].Flagged || results[i][1].Flagged != (i%2 == 1) {
+					t.Errorf("caller %d got %+v, want its own results", i, results[i])
+				}
+			}
+
+			batches := next.calls()
+			total := 0
+			for _, batch := range batches {
+				total += len(batch)
+				if len(batch) > tt.wantMax {
+					t.Errorf("batch of %d texts, want at most %d", len(batch), tt.wantMax)
+				}
+			}
+			if total != 2*tt.callers || len(batches) > tt.maxBatches {
+				t.Errorf("%d texts in %d batches, want %d in at most %d",
+					total, len(batches), 2*tt.callers, tt.maxBatches)
+			}
+		})
+	}
+}
+
+func TestBatcherErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		next *fakeModerator
+		want string
+	}{
+		{name: "moderator error", next: &fakeModerator{err: errors.New("rate limited")}, want: "rate limited"},
+		{name: "wrong result count", next: &fakeModerator{short: true}, want: "moderator returned"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := moderateAll(newBatcher(t, tt.next, 100, 20*time.Millisecond), 4)
+			for i, err := range errs {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("caller %d: error = %v, want %q", i, err, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBatcherCancellation(t *testing.T) {
+	release := make(chan struct{})
+	var seen sync.WaitGroup
+	seen.Add(1)
+	var once sync.Once
+	slow := moderation.ModeratorFunc(func(ctx context.Context, inputs []string) ([]moderation.Result, error) {
+		once.Do(seen.Done)
+		<-release
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return make([]moderation.Result, len(inputs)), nil
+	})
+	b := newBatcher(t, slow, 100, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := b.Moderate(ctx, []string{"a"})
+		errc <- err
+	}()
+	seen.Wait()
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("Moderate error = %v, want context.Canceled", err)
+	}
+
+	// The batch itself runs detached from the caller's cancellation.
+	close(release)
+	if got, err := b.Moderate(context.Background(), []string{"b"}); err != nil || len(got) != 1 {
+		t.Errorf("Moderate after a cancelled caller = %v, %v", got, err)
+	}
+}
+
+func TestBatcherClose(t *testing.T) {
+	b := newBatcher(t, &fakeModerator{}, 100, time.Millisecond)
+	if got, err := b.Moderate(context.Background(), nil); got != nil || err != nil {
+		t.Errorf("Moderate(nil) = %v, %v; want nothing", got, err)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	_, err := b.Moderate(context.Background(), []string{"a"})
+	if err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("Moderate after Close error = %v, want the batcher closed", err)
+	}
+}
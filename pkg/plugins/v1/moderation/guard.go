@@ -0,0 +1,465 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// Actions taken on flagged content.
+const (
+	// ActionBlock rejects flagged requests with 403 and replaces flagged responses with a
+	// JSON-RPC error.
+	ActionBlock = "block"
+
+	// ActionRedact replaces each flagged text with Config.Replacement.
+	ActionRedact = "redact"
+
+	// ActionLog lets flagged content through, only reporting it.
+	ActionLog = "log"
+)
+
+// Config configures a Guard, decodable from custom_config with mcpdpluginsv1.DecodeConfig.
+type Config struct {
+	// Action is what a Guard does with flagged content: block, redact or log.
+	Action string `config:"action" default:"block"`
+
+	// Requests and Responses select which flows are moderated.
+	Requests  bool `config:"requests" default:"true"`
+	Responses bool `config:"responses" default:"true"`
+
+	// Categories restricts flagging to these categories. When empty, the service's overall
+	// verdict is used.
+	Categories []string `config:"categories"`
+
+	// Threshold, when positive, flags texts with a score at or above it in any category (or any
+	// of Categories), instead of relying on the service's verdict.
+	Threshold float64 `config:"threshold"`
+
+	// MaxInputChars truncates each text before it is sent to the service.
+	MaxInputChars int `config:"max_input_chars" default:"10000"`
+
+	// Timeout bounds each moderation call.
+	Timeout time.Duration `config:"timeout" default:"5s"`
+
+	// FailOpen lets content through when the service cannot be reached. By default requests are
+	// rejected with 503 and responses replaced with an error.
+	FailOpen bool `config:"fail_open" default:"false"`
+
+	// Replacement is the text substituted for flagged content by the redact action.
+	Replacement string `config:"replacement" default:"[content removed by moderation]"`
+
+	// BaseURL, APIKey and Model configure the OpenAI-compatible endpoint used by Plugin.
+	BaseURL string `config:"base_url" default:"https://api.openai.com/v1"`
+	APIKey  string `config:"api_key"`
+	Model   string `config:"model" default:"omni-moderation-latest"`
+
+	// CacheTTL and CacheSize configure Plugin's result cache (a zero TTL disables it).
+	CacheTTL  time.Duration `config:"cache_ttl" default:"10m"`
+	CacheSize int           `config:"cache_size" default:"10000"`
+
+	// BatchSize and BatchWait configure Plugin's batching of concurrent calls (a zero size
+	// disables it).
+	BatchSize int           `config:"batch_size"`
+	BatchWait time.Duration `config:"batch_wait" default:"20ms"`
+
+	// DenyTemplateConfig customizes the response to blocked requests.
+	mcpdpluginsv1.DenyTemplateConfig
+}
+
+// DefaultConfig returns the Config with every default applied.
+func DefaultConfig() Config {
+	var cfg Config
+	if _, err := config.Decode(nil, &cfg); err != nil {
+		panic(fmt.Sprintf("moderation: invalid defaults: %v", err))
+	}
+
+	return cfg
+}
+
+// Finding describes flagged content seen by a Guard.
+type Finding struct {
+	// Flow is "request" or "response".
+	Flow string
+
+	// Categories lists the categories of the flagged texts, deduplicated.
+	Categories []string
+
+	// Texts is the number of flagged texts.
+	Texts int
+}
+
+// Guard moderates MCP content and acts on flagged texts. It is safe for concurrent use.
+type Guard struct {
+	moderator   Moderator
+	cfg         Config
+	categories  map[string]struct{}
+	onFlagged   func(ctx context.Context, f Finding)
+	replacement string
+	denial      *mcpdpluginsv1.DenyTemplate
+}
+
+// GuardOption configures a Guard.
+type GuardOption func(*Guard) error
+
+// WithFlaggedHandler sets the function told about flagged content, whatever the action (by
+// default findings are logged).
+func WithFlaggedHandler(h func(ctx context.Context, f Finding)) GuardOption {
+	return func(g *Guard) error {
+		if h == nil {
+			return fmt.Errorf("flagged handler cannot be nil")
+		}
+		g.onFlagged = h
+		return nil
+	}
+}
+
+// NewGuard returns a Guard applying cfg with m. The endpoint, cache and batch fields of cfg are
+// ignored; wrap m with Cached or NewBatcher for those.
+func NewGuard(m Moderator, cfg Config, opts ...GuardOption) (*Guard, error) {
+	if m == nil {
+		return nil, fmt.Errorf("moderator is required")
+	}
+	cfg.Action = strings.ToLower(cfg.Action)
+	switch cfg.Action {
+	case ActionBlock, ActionRedact, ActionLog:
+	default:
+		return nil, fmt.Errorf("unknown moderation action %q", cfg.Action)
+	}
+
+	denial, err := cfg.Template()
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Guard{
+		moderator:  m,
+		denial:     denial,
+		cfg:        cfg,
+		categories: map[string]struct{}{},
+		onFlagged: func(_ context.Context, f Finding) {
+			log.Printf("moderation: %d flagged text(s) in %s: %s", f.Texts, f.Flow, strings.Join(f.Categories, ", "))
+		},
+	}
+	for _, c := range cfg.Categories {
+		if c != "" {
+			g.categories[c] = struct{}{}
+		}
+	}
+	for _, opt := range opts {
+		if err := opt(g); err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+// Flagged reports whether r counts as flagged under the Guard's categories and threshold, and
+// the categories responsible.
+func (g *Guard) Flagged(r Result) (bool, []string) {
+	if g.cfg.Threshold > 0 {
+		var hit []string
+		for c, score := range r.Scores {
+			if score >= g.cfg.Threshold && g.selected(c) {
+				hit = append(hit, c)
+			}
+		}
+		return len(hit) > 0, hit
+	}
+	if !r.Flagged {
+		return false, nil
+	}
+	if len(g.categories) == 0 {
+		return true, r.Categories
+	}
+	var hit []string
+	for _, c := range r.Categories {
+		if g.selected(c) {
+			hit = append(hit, c)
+		}
+	}
+
+	return len(hit) > 0, hit
+}
+
+// HandleRequest moderates the MCP content of req. Bodies that are not MCP messages, and requests
+// when request moderation is disabled, continue unchanged.
+func (g *Guard) HandleRequest(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) *mcpdpluginsv1.HTTPResponse {
+	if !g.cfg.Requests {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+
+	flagged, err := g.check(ctx, "request", req.GetBody())
+	switch {
+	case err != nil && !g.cfg.FailOpen:
+		return g.denial.Deny(ctx, req, mcpdpluginsv1.Denial{
+			Reason: "content moderation unavailable",
+			Status: http.StatusServiceUnavailable,
+		})
+	case len(flagged) == 0 || g.cfg.Action == ActionLog:
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	case g.cfg.Action == ActionBlock:
+		return g.denial.Deny(ctx, req, mcpdpluginsv1.Denial{
+			Reason: "request blocked by content moderation",
+			Status: http.StatusForbidden,
+		})
+	}
+
+	body, changed, err := g.redact(req.GetBody(), flagged)
+	if err != nil || !changed {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+	modified := mcpdpluginsv1.CloneRequest(req)
+	modified.Body = body
+
+	return &mcpdpluginsv1.HTTPResponse{Continue: true, ModifiedRequest: modified}
+}
+
+// HandleResponse moderates the MCP content of resp, returning it (possibly modified) and
+// continuing the chain.
+func (g *Guard) HandleResponse(ctx context.Context, resp *mcpdpluginsv1.HTTPResponse) *mcpdpluginsv1.HTTPResponse {
+	out := &mcpdpluginsv1.HTTPResponse{
+		Continue:   true,
+		StatusCode: resp.GetStatusCode(),
+		Headers:    resp.GetHeaders(),
+		Body:       resp.GetBody(),
+	}
+	if !g.cfg.Responses {
+		return out
+	}
+
+	flagged, err := g.check(ctx, "response", resp.GetBody())
+	switch {
+	case err != nil && !g.cfg.FailOpen:
+		out.Body = errorBody(resp.GetBody(), "content moderation unavailable")
+	case len(flagged) == 0 || g.cfg.Action == ActionLog:
+	case g.cfg.Action == ActionBlock:
+		out.Body = errorBody(resp.GetBody(), "response blocked by content moderation")
+	default:
+		if body, changed, err := g.redact(resp.GetBody(), flagged); err == nil && changed {
+			out.Body = body
+		}
+	}
+
+	return out
+}
+
+// check moderates the texts of body and returns those flagged. Non-MCP bodies have no texts.
+func (g *Guard) check(ctx context.Context, flow string, body []byte) (map[string]struct{}, error) {
+	texts, err := mcp.Texts(body)
+	if err != nil {
+		return nil, nil
+	}
+	var inputs []string
+	seen := map[string]struct{}{}
+	for _, t := range texts {
+		if _, dup := seen[t]; dup || strings.TrimSpace(t) == "" {
+			continue
+		}
+		seen[t] = struct{}{}
+		inputs = append(inputs, t)
+	}
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	sent := make([]string, len(inputs))
+	for i, t := range inputs {
+		sent[i] = truncate(t, g.cfg.MaxInputChars)
+	}
+	if g.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.cfg.Timeout)
+		defer cancel()
+	}
+	results, err := g.moderator.Moderate(ctx, sent)
+	if err == nil && len(results) != len(sent) {
+		err = fmt.Errorf("moderator returned %d results for %d inputs", len(results), len(sent))
+	}
+	if err != nil {
+		log.Printf("moderation: %s check failed: %v", flow, err)
+		return nil, err
+	}
+
+	flagged := map[string]struct{}{}
+	categories := map[string]struct{}{}
+	for i, r := range results {
+		ok, cats := g.Flagged(r)
+		if !ok {
+			continue
+		}
+		flagged[inputs[i]] = struct{}{}
+		for _, c := range cats {
+			categories[c] = struct{}{}
+		}
+	}
+	if len(flagged) > 0 {
+		f := Finding{Flow: flow, Texts: len(flagged)}
+		for c := range categories {
+			f.Categories = append(f.Categories, c)
+		}
+		sort.Strings(f.Categories)
+		g.onFlagged(ctx, f)
+	}
+
+	return flagged, nil
+}
+
+func (g *Guard) redact(body []byte, flagged map[string]struct{}) ([]byte, bool, error) {
+	return mcp.RewriteText(body, func(s string) string {
+		if _, ok := flagged[s]; ok {
+			return g.cfg.Replacement
+		}
+		return s
+	})
+}
+
+func (g *Guard) selected(category string) bool {
+	if len(g.categories) == 0 {
+		return true
+	}
+	_, ok := g.categories[category]
+
+	return ok
+}
+
+// truncate cuts s to at most n runes (n <= 0 keeps it whole).
+func truncate(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+
+	return string(r[:n])
+}
+
+// errorBody builds a JSON-RPC error echoing the id of the original message, if any.
+func errorBody(original []byte, msg string) []byte {
+	return mcp.ErrorResponse(original, &mcp.Error{Code: mcp.CodeServerError, Message: msg})
+}
+
+// Plugin is a request- and response-flow plugin moderating content through the
+// OpenAI-compatible endpoint configured in its custom_config. It lets everything through until
+// configured.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	guard   atomic.Pointer[Guard]
+	batcher atomic.Pointer[Batcher]
+}
+
+// NewPlugin returns an unconfigured Plugin.
+func NewPlugin() *Plugin {
+	return &Plugin{}
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "content-moderation",
+		Version:     pluginVersion,
+		Description: "Blocks or redacts MCP content flagged by a moderation endpoint.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest, mcpdpluginsv1.FlowResponse), nil
+}
+
+// Configure builds the moderation pipeline from cfg's custom_config.
+func (p *Plugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	var c Config
+	if err := mcpdpluginsv1.DecodeConfig(ctx, cfg, &c); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	m, err := NewOpenAI(OpenAIConfig{BaseURL: c.BaseURL, APIKey: c.APIKey, Model: c.Model})
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	var batcher *Batcher
+	if c.BatchSize > 0 {
+		if batcher, err = NewBatcher(m, c.BatchSize, c.BatchWait); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		m = batcher
+	}
+	if c.CacheTTL > 0 {
+		m = Cached(m, c.CacheTTL, c.CacheSize)
+	}
+	guard, err := NewGuard(m, c)
+	if err != nil {
+		if batcher != nil {
+			_ = batcher.Close()
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	p.guard.Store(guard)
+	if old := p.batcher.Swap(batcher); old != nil {
+		_ = old.Close()
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// Stop stops batching.
+func (p *Plugin) Stop(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	if old := p.batcher.Swap(nil); old != nil {
+		_ = old.Close()
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleRequest moderates requests.
+func (p *Plugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	g := p.guard.Load()
+	if g == nil {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}, nil
+	}
+
+	return g.HandleRequest(ctx, req), nil
+}
+
+// HandleResponse moderates responses.
+func (p *Plugin) HandleResponse(
+	ctx context.Context,
+	resp *mcpdpluginsv1.HTTPResponse,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	g := p.guard.Load()
+	if g == nil {
+		return &mcpdpluginsv1.HTTPResponse{
+			Continue:   true,
+			StatusCode: resp.GetStatusCode(),
+			Headers:    resp.GetHeaders(),
+			Body:       resp.GetBody(),
+		}, nil
+	}
+
+	return g.HandleResponse(ctx, resp), nil
+}
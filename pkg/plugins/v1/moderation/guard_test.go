@@ -0,0 +1,523 @@
+package moderation_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/moderation"
+)
+
+const (
+	okCall  = `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"post","arguments":{"text":"hello"}}}`
+	badCall = `{"jsonrpc":"2.0","id":1,"method":"tools/call",` +
+		`"params":{"name":"post","arguments":{"text":"bad words","title":"hello"}}}`
+	okResult  = `{"jsonrpc":"2.0","id":7,"result":{"content":[{"type":"text","text":"fine"}]}}`
+	badResult = `{"jsonrpc":"2.0","id":7,"result":{"content":[{"type":"text","text":"bad answer"},` +
+		`{"type":"text","text":"fine"}]}}`
+)
+
+// quietLogs captures the standard logger's output for the duration of the test.
+func quietLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	return &buf
+}
+
+func newGuard(t *testing.T, m moderation.Moderator, modify func(*moderation.Config)) *moderation.Guard {
+	t.Helper()
+
+	cfg := moderation.DefaultConfig()
+	if modify != nil {
+		modify(&cfg)
+	}
+	g, err := moderation.NewGuard(m, cfg, moderation.WithFlaggedHandler(func(context.Context, moderation.Finding) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g
+}
+
+func TestNewGuardErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		m    moderation.Moderator
+		cfg  func(*moderation.Config)
+		opt  moderation.GuardOption
+		want string
+	}{
+		{name: "no moderator", want: "moderator is required"},
+		{
+			name: "unknown action",
+			m:    &fakeModerator{},
+			cfg:  func(c *moderation.Config) { c.Action = "quarantine" },
+			want: "unknown",
+		},
+		{
+			name: "invalid template",
+			m:    &fakeModerator{},
+			cfg:  func(c *moderation.Config) { c.DenyTemplate = "{{" },
+			want: "template",
+		},
+		{name: "nil handler", m: &fakeModerator{}, opt: moderation.WithFlaggedHandler(nil), want: "cannot be nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := moderation.DefaultConfig()
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			var opts []moderation.GuardOption
+			if tt.opt != nil {
+				opts = append(opts, tt.opt)
+			}
+			_, err := moderation.NewGuard(tt.m, cfg, opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewGuard error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlagged(t *testing.T) {
+	flagged := moderation.Result{
+		Flagged:    true,
+		Categories: []string{"hate", "violence"},
+		Scores:     map[string]float64{"hate": 0.4, "violence": 0.8},
+	}
+	clean := moderation.Result{Scores: map[string]float64{"hate": 0.7}}
+	tests := []struct {
+		name       string
+		categories []string
+		threshold  float64
+		result     moderation.Result
+		want       bool
+		wantCats   []string
+	}{
+		{name: "service verdict", result: flagged, want: true, wantCats: []string{"hate", "violence"}},
+		{name: "not flagged", result: clean},
+		{
+			name:       "selected category",
+			categories: []string{"violence"},
+			result:     flagged,
+			want:       true,
+			wantCats:   []string{"violence"},
+		},
+		{name: "other categories", categories: []string{"self-harm", ""}, result: flagged},
+		{name: "threshold", threshold: 0.5, result: flagged, want: true, wantCats: []string{"violence"}},
+		{name: "threshold on a clean verdict", threshold: 0.5, result: clean, want: true, wantCats: []string{"hate"}},
+		{name: "threshold not reached", threshold: 0.9, result: flagged},
+		{
+			name:       "threshold and categories",
+			threshold:  0.3,
+			categories: []string{"hate"},
+			result:     flagged,
+			want:       true,
+			wantCats:   []string{"hate"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newGuard(t, &fakeModerator{}, func(c *moderation.Config) {
+				c.Categories = tt.categories
+				c.Threshold = tt.threshold
+			})
+			got, cats := g.Flagged(tt.result)
+			if got != tt.want || !reflect.DeepEqual(cats, tt.wantCats) {
+				t.Errorf("Flagged = %t, %v; want %t, %v", got, cats, tt.want, tt.wantCats)
+			}
+		})
+	}
+}
+
+func TestGuardHandleRequest(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+	tests := []struct {
+		name       string
+		cfg        func(*moderation.Config)
+		err        error
+		body       string
+		wantStatus int32  // Zero for requests that continue.
+		wantBody   string // Modified body for redacted requests.
+	}{
+		{name: "clean", body: okCall},
+		{name: "blocked", body: badCall, wantStatus: 403},
+		{
+			name:       "block in upper case",
+			cfg:        func(c *moderation.Config) { c.Action = "BLOCK" },
+			body:       badCall,
+			wantStatus: 403,
+		},
+		{name: "logged", cfg: func(c *moderation.Config) { c.Action = moderation.ActionLog }, body: badCall},
+		{
+			name:     "redacted",
+			cfg:      func(c *moderation.Config) { c.Action = moderation.ActionRedact; c.Replacement = "[removed]" },
+			body:     badCall,
+			wantBody: `"text":"[removed]","title":"hello"`,
+		},
+		{name: "not MCP", body: "bad plain text"},
+		{name: "requests not moderated", cfg: func(c *moderation.Config) { c.Requests = false }, body: badCall},
+		{name: "service error fails closed", err: errUnavailable, body: okCall, wantStatus: 503},
+		{
+			name: "service error fails open",
+			cfg:  func(c *moderation.Config) { c.FailOpen = true },
+			err:  errUnavailable,
+			body: okCall,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quietLogs(t)
+			g := newGuard(t, &fakeModerator{err: tt.err}, tt.cfg)
+
+			req := &mcpdpluginsv1.HTTPRequest{Method: "POST", Path: "/mcp", Body: []byte(tt.body)}
+			resp := g.HandleRequest(context.Background(), req)
+			if tt.wantStatus != 0 {
+				if resp.GetContinue() || resp.GetStatusCode() != tt.wantStatus {
+					t.Errorf("response = %v, want a %d denial", resp, tt.wantStatus)
+				}
+				return
+			}
+			if !resp.GetContinue() {
+				t.Fatalf("request did not continue: %v", resp)
+			}
+			got := resp.GetModifiedRequest().GetBody()
+			switch {
+			case tt.wantBody == "" && resp.GetModifiedRequest() != nil:
+				t.Errorf("request modified to %s", got)
+			case tt.wantBody != "" && !strings.Contains(string(got), tt.wantBody):
+				t.Errorf("modified body = %s, want it to contain %s", got, tt.wantBody)
+			}
+			if string(req.GetBody()) != tt.body {
+				t.Error("the original request was modified")
+			}
+		})
+	}
+}
+
+func TestGuardHandleResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      func(*moderation.Config)
+		err      error
+		body     string
+		wantBody string // Substring of the returned body.
+	}{
+		{name: "clean", body: okResult, wantBody: okResult},
+		{
+			name:     "blocked",
+			body:     badResult,
+			wantBody: `"id":7,"error":{"code":-32000,"message":"response blocked by content moderation"}`,
+		},
+		{
+			name:     "logged",
+			cfg:      func(c *moderation.Config) { c.Action = moderation.ActionLog },
+			body:     badResult,
+			wantBody: badResult,
+		},
+		{
+			name:     "redacted",
+			cfg:      func(c *moderation.Config) { c.Action = moderation.ActionRedact },
+			body:     badResult,
+			wantBody: `"text":"[content removed by moderation]","type":"text"},{"text":"fine"`,
+		},
+		{
+			name:     "responses not moderated",
+			cfg:      func(c *moderation.Config) { c.Responses = false },
+			body:     badResult,
+			wantBody: badResult,
+		},
+		{
+			name:     "service error fails closed",
+			err:      errors.New("down"),
+			body:     okResult,
+			wantBody: "content moderation unavailable",
+		},
+		{
+			name:     "service error fails open",
+			cfg:      func(c *moderation.Config) { c.FailOpen = true },
+			err:      errors.New("down"),
+			body:     okResult,
+			wantBody: okResult,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quietLogs(t)
+			g := newGuard(t, &fakeModerator{err: tt.err}, tt.cfg)
+
+			in := &mcpdpluginsv1.HTTPResponse{
+				StatusCode: 200,
+				Headers:    map[string]string{"X-A": "1"},
+				Body:       []byte(tt.body),
+			}
+			resp := g.HandleResponse(context.Background(), in)
+			if !resp.GetContinue() || resp.GetStatusCode() != 200 || resp.GetHeaders()["X-A"] != "1" {
+				t.Errorf("response = %v, want the original status and headers, continuing", resp)
+			}
+			if !strings.Contains(string(resp.GetBody()), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", resp.GetBody(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestGuardInputs(t *testing.T) {
+	next := &fakeModerator{}
+	g := newGuard(t, next, func(c *moderation.Config) { c.MaxInputChars = 4 })
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"t",` +
+		`"arguments":{"a":"héllo world","b":"héllo world","c":"  ","d":"ok"}}}`
+	g.HandleRequest(context.Background(), &mcpdpluginsv1.HTTPRequest{Body: []byte(body)})
+
+	calls := next.calls()
+	if len(calls) != 1 {
+		t.Fatalf("moderator called %d times, want 1", len(calls))
+	}
+	got := map[string]bool{}
+	for _, in := range calls[0] {
+		got[in] = true
+	}
+	if want := map[string]bool{"héll": true, "ok": true}; !reflect.DeepEqual(got, want) || len(calls[0]) != 2 {
+		t.Errorf("moderated %q, want duplicates and blanks dropped and texts cut to 4 runes", calls[0])
+	}
+
+	// Messages without text are not sent at all.
+	ping := &mcpdpluginsv1.HTTPRequest{Body: []byte(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)}
+	g.HandleRequest(context.Background(), ping)
+	if got := len(next.calls()); got != 1 {
+		t.Errorf("moderator called %d times, want no call for a message without text", got)
+	}
+}
+
+func TestGuardTimeout(t *testing.T) {
+	quietLogs(t)
+	var deadline time.Duration
+	slow := moderation.ModeratorFunc(func(ctx context.Context, _ []string) ([]moderation.Result, error) {
+		d, _ := ctx.Deadline()
+		deadline = time.Until(d)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	g := newGuard(t, slow, func(c *moderation.Config) { c.Timeout = 20 * time.Millisecond })
+
+	resp := g.HandleRequest(context.Background(), &mcpdpluginsv1.HTTPRequest{Body: []byte(okCall)})
+	if resp.GetStatusCode() != 503 || deadline <= 0 || deadline > 20*time.Millisecond {
+		t.Errorf("response %v with a %s deadline, want a 503 after the timeout", resp, deadline)
+	}
+}
+
+func TestGuardResultCount(t *testing.T) {
+	logs := quietLogs(t)
+	g := newGuard(t, &fakeModerator{short: true}, nil)
+
+	resp := g.HandleRequest(context.Background(), &mcpdpluginsv1.HTTPRequest{Body: []byte(badCall)})
+	if resp.GetStatusCode() != 503 || !strings.Contains(logs.String(), "returned 1 results for 2 inputs") {
+		t.Errorf("response %v, logs %q; want a 503 for the mismatched results", resp, logs)
+	}
+}
+
+func TestFlaggedHandler(t *testing.T) {
+	var mu sync.Mutex
+	var findings []moderation.Finding
+	cfg := moderation.DefaultConfig()
+	cfg.Action = moderation.ActionLog
+	record := func(_ context.Context, f moderation.Finding) {
+		mu.Lock()
+		defer mu.Unlock()
+		findings = append(findings, f)
+	}
+	g, err := moderation.NewGuard(&fakeModerator{}, cfg, moderation.WithFlaggedHandler(record))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call",` +
+		`"params":{"name":"t","arguments":{"a":"bad","b":"also bad","c":"ok"}}}`
+	g.HandleRequest(context.Background(), &mcpdpluginsv1.HTTPRequest{Body: []byte(body)})
+	g.HandleResponse(context.Background(), &mcpdpluginsv1.HTTPResponse{Body: []byte(okResult)})
+	g.HandleResponse(context.Background(), &mcpdpluginsv1.HTTPResponse{Body: []byte(badResult)})
+
+	want := []moderation.Finding{
+		{Flow: "request", Categories: []string{"harassment"}, Texts: 2},
+		{Flow: "response", Categories: []string{"harassment"}, Texts: 1},
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(findings, want) {
+		t.Errorf("findings = %+v, want %+v", findings, want)
+	}
+}
+
+func TestDefaultFlaggedHandler(t *testing.T) {
+	logs := quietLogs(t)
+	g, err := moderation.NewGuard(&fakeModerator{}, moderation.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.HandleRequest(context.Background(), &mcpdpluginsv1.HTTPRequest{Body: []byte(badCall)})
+	if got := logs.String(); !strings.Contains(got, "moderation: 1 flagged text(s) in request: harassment") {
+		t.Errorf("logged %q, want the finding", got)
+	}
+}
+
+// moderationServer is an OpenAI-compatible endpoint flagging texts containing "bad" and counting
+// its requests.
+type moderationServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests int
+}
+
+func newModerationServer(t *testing.T) *moderationServer {
+	t.Helper()
+
+	s := &moderationServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		s.mu.Unlock()
+
+		results, err := (&fakeModerator{}).Moderate(r.Context(), decodeInputs(t, r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(encodeResults(results))
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *moderationServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+func configure(p *moderation.Plugin, custom map[string]string) error {
+	_, err := p.Configure(context.Background(), &mcpdpluginsv1.PluginConfig{CustomConfig: custom})
+	return err
+}
+
+func TestPlugin(t *testing.T) {
+	quietLogs(t)
+	srv := newModerationServer(t)
+	p := moderation.NewPlugin()
+
+	// Unconfigured, everything passes.
+	resp, err := p.HandleRequest(context.Background(), &mcpdpluginsv1.HTTPRequest{Body: []byte(badCall)})
+	if err != nil || !resp.GetContinue() {
+		t.Fatalf("unconfigured HandleRequest = %v, %v; want it to continue", resp, err)
+	}
+	out, err := p.HandleResponse(context.Background(), &mcpdpluginsv1.HTTPResponse{Body: []byte(badResult)})
+	if err != nil || string(out.GetBody()) != badResult || !out.GetContinue() {
+		t.Fatalf("unconfigured HandleResponse = %v, %v; want it unchanged", out, err)
+	}
+
+	err = configure(p, map[string]string{
+		"base_url":   srv.URL,
+		"batch_size": "8",
+		"batch_wait": "1ms",
+		"cache_ttl":  "1m",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		resp, err := p.HandleRequest(context.Background(), &mcpdpluginsv1.HTTPRequest{Body: []byte(badCall)})
+		if err != nil || resp.GetStatusCode() != 403 {
+			t.Fatalf("HandleRequest = %v, %v; want a 403", resp, err)
+		}
+	}
+	if got := srv.count(); got != 1 {
+		t.Errorf("endpoint called %d times, want the second check cached", got)
+	}
+	out, err = p.HandleResponse(context.Background(), &mcpdpluginsv1.HTTPResponse{Body: []byte(okResult)})
+	if err != nil || string(out.GetBody()) != okResult {
+		t.Errorf("HandleResponse = %v, %v; want it unchanged", out, err)
+	}
+
+	// Reconfiguring without batching or caching closes the previous batcher.
+	if err := configure(p, map[string]string{"base_url": srv.URL, "cache_ttl": "0s", "action": "redact"}); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		resp, _ := p.HandleRequest(context.Background(), &mcpdpluginsv1.HTTPRequest{Body: []byte(badCall)})
+		if !strings.Contains(string(resp.GetModifiedRequest().GetBody()), "[content removed by moderation]") {
+			t.Fatalf("HandleRequest = %v, want the text redacted", resp)
+		}
+	}
+	if got := srv.count(); got != 4 {
+		t.Errorf("endpoint called %d times, want every check sent without the cache", got)
+	}
+
+	if _, err := p.Stop(context.Background(), &emptypb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPluginConfigureErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		custom map[string]string
+		want   string
+	}{
+		{
+			name:   "invalid base URL",
+			custom: map[string]string{"base_url": "localhost:8080"},
+			want:   "invalid moderation base URL",
+		},
+		{name: "unknown action", custom: map[string]string{"action": "hide"}, want: "unknown moderation action"},
+		{
+			name:   "invalid batching",
+			custom: map[string]string{"batch_size": "4", "batch_wait": "0s"},
+			want:   "must be positive",
+		},
+		{name: "undecodable", custom: map[string]string{"threshold": "high"}, want: "threshold"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := configure(moderation.NewPlugin(), tt.custom)
+			if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Configure error = %v, want InvalidArgument containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPluginMetadata(t *testing.T) {
+	p := moderation.NewPlugin()
+	md, err := p.GetMetadata(context.Background(), &emptypb.Empty{})
+	if err != nil || md.GetName() != "content-moderation" {
+		t.Errorf("GetMetadata = %v, %v", md, err)
+	}
+	caps, err := p.GetCapabilities(context.Background(), &emptypb.Empty{})
+	want := []mcpdpluginsv1.Flow{mcpdpluginsv1.FlowRequest, mcpdpluginsv1.FlowResponse}
+	if err != nil || !reflect.DeepEqual(caps.GetFlows(), want) {
+		t.Errorf("GetCapabilities = %v, %v; want both flows", caps, err)
+	}
+}
@@ -0,0 +1,107 @@
+// Package moderation checks MCP content with a content moderation service, for guardrail plugins
+// that block or redact harmful tool arguments and results.
+//
+// A Moderator classifies a batch of texts. NewOpenAI calls an OpenAI-compatible /moderations
+// endpoint; Cached and NewBatcher wrap any Moderator to avoid re-checking identical content and
+// to merge concurrent calls into fewer requests. A Guard applies a Moderator to requests and
+// responses and acts on flagged content:
+//
+//	m, err := moderation.NewOpenAI(moderation.OpenAIConfig{APIKey: os.Getenv("OPENAI_API_KEY")})
+//	if err != nil {
+//	    return err
+//	}
+//	guard, err := moderation.NewGuard(moderation.Cached(m, 10*time.Minute, 10000), moderation.DefaultConfig())
+//	...
+//	return guard.HandleRequest(ctx, req), nil
+//
+// Plugin runs a Guard over the OpenAI-compatible adapter configured from custom_config.
+package moderation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/cache"
+)
+
+// Result is the moderation verdict for one text.
+type Result struct {
+	// Flagged reports whether the service considers the text harmful.
+	Flagged bool `json:"flagged"`
+
+	// Categories lists the categories the text was flagged for.
+	Categories []string `json:"categories,omitempty"`
+
+	// Scores holds the service's confidence per category, when it reports them.
+	Scores map[string]float64 `json:"scores,omitempty"`
+}
+
+// Moderator classifies texts, returning one Result per input in order. Implementations must be
+// safe for concurrent use.
+type Moderator interface {
+	Moderate(ctx context.Context, inputs []string) ([]Result, error)
+}
+
+// ModeratorFunc adapts a function to the Moderator interface.
+type ModeratorFunc func(ctx context.Context, inputs []string) ([]Result, error)
+
+// Moderate implements Moderator.
+func (f ModeratorFunc) Moderate(ctx context.Context, inputs []string) ([]Result, error) {
+	return f(ctx, inputs)
+}
+
+// cached serves results for previously moderated texts from memory.
+type cached struct {
+	next  Moderator
+	ttl   time.Duration
+	cache *cache.MemoryBackend
+}
+
+// Cached returns a Moderator remembering the results of m for ttl, keyed by a hash of each text,
+// so only texts not seen recently are sent to m. At most maxEntries results are kept.
+func Cached(m Moderator, ttl time.Duration, maxEntries int) Moderator {
+	return &cached{next: m, ttl: ttl, cache: cache.NewMemoryBackend(maxEntries)}
+}
+
+func (c *cached) Moderate(ctx context.Context, inputs []string) ([]Result, error) {
+	results := make([]Result, len(inputs))
+	var (
+		missing []string
+		indices []int
+	)
+	for i, in := range inputs {
+		if raw, ok, _ := c.cache.Get(ctx, textKey(in)); ok && json.Unmarshal(raw, &results[i]) == nil {
+			continue
+		}
+		missing = append(missing, in)
+		indices = append(indices, i)
+	}
+	if len(missing) == 0 {
+		return results, nil
+	}
+
+	fresh, err := c.next.Moderate(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(fresh) != len(missing) {
+		return nil, fmt.Errorf("moderator returned %d results for %d inputs", len(fresh), len(missing))
+	}
+	for j, r := range fresh {
+		results[indices[j]] = r
+		if raw, err := json.Marshal(r); err == nil {
+			_ = c.cache.Set(ctx, textKey(missing[j]), raw, c.ttl)
+		}
+	}
+
+	return results, nil
+}
+
+func textKey(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
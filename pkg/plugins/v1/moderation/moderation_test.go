@@ -0,0 +1,139 @@
+package moderation_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/moderation"
+)
+
+// fakeModerator flags texts containing "bad" in category "harassment", recording every batch it
+// is asked to moderate.
+type fakeModerator struct {
+	mu      sync.Mutex
+	batches [][]string
+	err     error
+	short   bool // Returns one result too few.
+}
+
+func (m *fakeModerator) Moderate(_ context.Context, inputs []string) ([]moderation.Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.batches = append(m.batches, append([]string(nil), inputs...))
+	if m.err != nil {
+		return nil, m.err
+	}
+	results := make([]moderation.Result, len(inputs))
+	for i, in := range inputs {
+		if strings.Contains(in, "bad") {
+			results[i] = moderation.Result{
+				Flagged:    true,
+				Categories: []string{"harassment"},
+				Scores:     map[string]float64{"harassment": 0.9, "violence": 0.2},
+			}
+		} else {
+			results[i] = moderation.Result{Scores: map[string]float64{"harassment": 0.1, "violence": 0.6}}
+		}
+	}
+	if m.short {
+		results = results[1:]
+	}
+
+	return results, nil
+}
+
+func (m *fakeModerator) calls() [][]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([][]string(nil), m.batches...)
+}
+
+func flags(results []moderation.Result) []bool {
+	out := make([]bool, len(results))
+	for i, r := range results {
+		out[i] = r.Flagged
+	}
+
+	return out
+}
+
+func TestCached(t *testing.T) {
+	next := &fakeModerator{}
+	m := moderation.Cached(next, time.Minute, 100)
+	ctx := context.Background()
+
+	tests := []struct {
+		inputs    []string
+		want      []bool
+		wantBatch []string // Texts sent to next; nil when served from the cache.
+	}{
+		{inputs: []string{"hello", "bad words"}, want: []bool{false, true}, wantBatch: []string{"hello", "bad words"}},
+		{inputs: []string{"bad words", "hello"}, want: []bool{true, false}},
+		{inputs: []string{"hello", "new", "bad words"}, want: []bool{false, false, true}, wantBatch: []string{"new"}},
+		{inputs: nil, want: []bool{}},
+	}
+	for i, tt := range tests {
+		before := len(next.calls())
+		got, err := m.Moderate(ctx, tt.inputs)
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(flags(got), tt.want) {
+			t.Errorf("call %d: flagged = %v, want %v", i, flags(got), tt.want)
+		}
+		calls := next.calls()[before:]
+		switch {
+		case tt.wantBatch == nil && len(calls) != 0:
+			t.Errorf("call %d: sent %v, want everything from the cache", i, calls)
+		case tt.wantBatch != nil && (len(calls) != 1 || !reflect.DeepEqual(calls[0], tt.wantBatch)):
+			t.Errorf("call %d: sent %v, want %v", i, calls, tt.wantBatch)
+		}
+	}
+
+	// Cached results keep their categories and scores.
+	got, _ := m.Moderate(ctx, []string{"bad words"})
+	if !reflect.DeepEqual(got[0].Categories, []string{"harassment"}) || got[0].Scores["harassment"] != 0.9 {
+		t.Errorf("cached result = %+v, want the moderator's", got[0])
+	}
+}
+
+func TestCachedErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		next *fakeModerator
+		want string
+	}{
+		{name: "moderator error", next: &fakeModerator{err: errors.New("unavailable")}, want: "unavailable"},
+		{name: "wrong result count", next: &fakeModerator{short: true}, want: "returned 1 results for 2 inputs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := moderation.Cached(tt.next, time.Minute, 100)
+			for range 2 {
+				if _, err := m.Moderate(context.Background(), []string{"a", "b"}); err == nil ||
+					!strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("Moderate error = %v, want %q", err, tt.want)
+				}
+			}
+			if got := len(tt.next.calls()); got != 2 {
+				t.Errorf("moderator called %d times, want failures left uncached", got)
+			}
+		})
+	}
+}
+
+func TestModeratorFunc(t *testing.T) {
+	m := moderation.ModeratorFunc(func(_ context.Context, inputs []string) ([]moderation.Result, error) {
+		return []moderation.Result{{Flagged: inputs[0] == "x"}}, nil
+	})
+	if got, err := m.Moderate(context.Background(), []string{"x"}); err != nil || !got[0].Flagged {
+		t.Errorf("Moderate = %v, %v", got, err)
+	}
+}
@@ -0,0 +1,140 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/httpclientx"
+)
+
+// maxModerationResponse bounds the response body read from a moderation endpoint.
+const maxModerationResponse = 4 << 20
+
+// OpenAIConfig configures a Moderator for an OpenAI-compatible moderation endpoint.
+type OpenAIConfig struct {
+	// BaseURL is the API base URL; "/moderations" is appended (default https://api.openai.com/v1).
+	BaseURL string
+
+	// APIKey is sent as a bearer token when set.
+	APIKey string
+
+	// Model is the moderation model (default omni-moderation-latest).
+	Model string
+
+	// HTTPClient sends requests (default httpclientx with its defaults).
+	HTTPClient *http.Client
+}
+
+type openAI struct {
+	endpoint string
+	apiKey   string
+	model    string
+	client   *http.Client
+}
+
+type openAIRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+//nolint:tagliatelle // external API wire format
+type openAIResponse struct {
+	Results []struct {
+		Flagged        bool               `json:"flagged"`
+		Categories     map[string]bool    `json:"categories"`
+		CategoryScores map[string]float64 `json:"category_scores"`
+	} `json:"results"`
+}
+
+// NewOpenAI returns a Moderator calling the /moderations endpoint of an OpenAI-compatible API.
+func NewOpenAI(cfg OpenAIConfig) (Moderator, error) {
+	base := cfg.BaseURL
+	if base == "" {
+		base = "https://api.openai.com/v1"
+	}
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid moderation base URL %q", base)
+	}
+	model := cfg.Model
+	if model == "" {
+		model = "omni-moderation-latest"
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		if client, err = httpclientx.New(httpclientx.DefaultConfig()); err != nil {
+			return nil, err
+		}
+	}
+
+	return &openAI{
+		endpoint: strings.TrimSuffix(base, "/") + "/moderations",
+		apiKey:   cfg.APIKey,
+		model:    model,
+		client:   client,
+	}, nil
+}
+
+func (o *openAI) Moderate(ctx context.Context, inputs []string) ([]Result, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	body, err := json.Marshal(openAIRequest{Model: o.model, Input: inputs})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	// Moderation has no side effects, so it is safe to retry.
+	req.Header.Set("Idempotency-Key", textKey(string(body)))
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("moderation request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxModerationResponse))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read moderation response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		snippet := bytes.TrimSpace(raw[:min(len(raw), 512)])
+		return nil, fmt.Errorf("moderation endpoint returned %s: %s", resp.Status, snippet)
+	}
+
+	var out openAIResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode moderation response: %w", err)
+	}
+	if len(out.Results) != len(inputs) {
+		return nil, fmt.Errorf("moderation endpoint returned %d results for %d inputs", len(out.Results), len(inputs))
+	}
+
+	results := make([]Result, len(out.Results))
+	for i, r := range out.Results {
+		results[i] = Result{Flagged: r.Flagged, Scores: r.CategoryScores}
+		for c, flagged := range r.Categories {
+			if flagged {
+				results[i].Categories = append(results[i].Categories, c)
+			}
+		}
+		sort.Strings(results[i].Categories)
+	}
+
+	return results, nil
+}
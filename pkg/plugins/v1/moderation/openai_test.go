@@ -0,0 +1,202 @@
+package moderation_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/moderation"
+)
+
+func TestNewOpenAIErrors(t *testing.T) {
+	for _, base := range []string{"api.openai.com/v1", "ftp://api.openai.com", "https://", "http://[::1"} {
+		_, err := moderation.NewOpenAI(moderation.OpenAIConfig{BaseURL: base})
+		if err == nil || !strings.Contains(err.Error(), "invalid moderation base URL") {
+			t.Errorf("NewOpenAI(%q) error = %v, want an invalid URL", base, err)
+		}
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	const twoResults = `{"id":"modr-1","results":[
+		{"flagged":false,"categories":{"hate":false},"category_scores":{"hate":0.01}},
+		{"flagged":true,"categories":{"violence":true,"hate":true,"sexual":false},
+		 "category_scores":{"violence":0.97,"hate":0.6,"sexual":0.02}}]}`
+	tests := []struct {
+		name    string
+		cfg     moderation.OpenAIConfig
+		status  int
+		body    string
+		want    []moderation.Result
+		wantErr string
+	}{
+		{
+			name:   "results",
+			cfg:    moderation.OpenAIConfig{APIKey: "sk-test", Model: "text-moderation-stable"},
+			status: 200,
+			body:   twoResults,
+			want: []moderation.Result{
+				{Scores: map[string]float64{"hate": 0.01}},
+				{
+					Flagged:    true,
+					Categories: []string{"hate", "violence"},
+					Scores:     map[string]float64{"violence": 0.97, "hate": 0.6, "sexual": 0.02},
+				},
+			},
+		},
+		{name: "wrong result count", status: 200, body: `{"results":[]}`, wantErr: "returned 0 results for 2 inputs"},
+		{name: "malformed", status: 200, body: `{"results":`, wantErr: "failed to decode moderation response"},
+		{
+			name:    "error status",
+			status:  401,
+			body:    `{"error":{"message":"Incorrect API key"}}` + "\n",
+			wantErr: `moderation endpoint returned 401 Unauthorized: {"error":{"message":"Incorrect API key"}}`,
+		},
+		{
+			name:    "long error body",
+			status:  400,
+			body:    strings.Repeat("x", 2000),
+			wantErr: "400 Bad Request: " + strings.Repeat("x", 512),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got struct {
+				Model string   `json:"model"`
+				Input []string `json:"input"`
+			}
+			var header http.Header
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Clone()
+				if r.URL.Path != "/v1/moderations" || r.Method != http.MethodPost {
+					http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusNotFound)
+					return
+				}
+				_ = json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			cfg := tt.cfg
+			cfg.BaseURL = srv.URL + "/v1/"
+			cfg.HTTPClient = srv.Client()
+			m, err := moderation.NewOpenAI(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			results, err := m.Moderate(context.Background(), []string{"hello", "threat"})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Moderate error = %v, want %q", err, tt.wantErr)
+				}
+				if strings.Contains(tt.name, "long") && strings.Contains(err.Error(), strings.Repeat("x", 513)) {
+					t.Error("error carries more than 512 bytes of the body")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(results, tt.want) {
+				t.Errorf("Moderate =\n%+v\nwant\n%+v", results, tt.want)
+			}
+
+			wantModel := tt.cfg.Model
+			if wantModel == "" {
+				wantModel = "omni-moderation-latest"
+			}
+			if got.Model != wantModel || !reflect.DeepEqual(got.Input, []string{"hello", "threat"}) {
+				t.Errorf("endpoint received %+v", got)
+			}
+			if auth := header.Get("Authorization"); auth != "Bearer "+tt.cfg.APIKey {
+				t.Errorf("Authorization = %q, want the API key", auth)
+			}
+			if header.Get("Idempotency-Key") == "" || header.Get("Content-Type") != "application/json" {
+				t.Errorf("headers = %v, want JSON with an idempotency key", header)
+			}
+		})
+	}
+}
+
+func TestOpenAIDefaults(t *testing.T) {
+	var got struct {
+		Model string `json:"model"`
+	}
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Values("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"results":[{"flagged":false}]}`))
+	}))
+	defer srv.Close()
+
+	m, err := moderation.NewOpenAI(moderation.OpenAIConfig{BaseURL: srv.URL, HTTPClient: srv.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Moderate(context.Background(), []string{"hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Model != "omni-moderation-latest" || len(auth) != 0 {
+		t.Errorf("model %q with Authorization %v, want the default model and no key", got.Model, auth)
+	}
+
+	if results, err := m.Moderate(context.Background(), nil); err != nil || results != nil {
+		t.Errorf("Moderate(nil) = %v, %v; want no call", results, err)
+	}
+}
+
+func TestOpenAIUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	m, err := moderation.NewOpenAI(moderation.OpenAIConfig{BaseURL: srv.URL, HTTPClient: srv.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Moderate(context.Background(), []string{"hello"}); err == nil ||
+		!strings.Contains(err.Error(), "moderation request failed") {
+		t.Errorf("Moderate error = %v, want a request failure", err)
+	}
+}
+
+// decodeInputs returns the inputs of a moderation request.
+func decodeInputs(t *testing.T, r *http.Request) []string {
+	t.Helper()
+
+	var req struct {
+		Input []string `json:"input"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		t.Errorf("invalid moderation request: %v", err)
+	}
+
+	return req.Input
+}
+
+// encodeResults encodes results as an OpenAI moderation response.
+func encodeResults(results []moderation.Result) []byte {
+	type result struct {
+		Flagged        bool               `json:"flagged"`
+		Categories     map[string]bool    `json:"categories"`
+		CategoryScores map[string]float64 `json:"category_scores"` //nolint:tagliatelle // external API wire format
+	}
+	out := struct {
+		Results []result `json:"results"`
+	}{}
+	for _, r := range results {
+		cats := map[string]bool{}
+		for _, c := range r.Categories {
+			cats[c] = true
+		}
+		out.Results = append(out.Results, result{Flagged: r.Flagged, Categories: cats, CategoryScores: r.Scores})
+	}
+	b, _ := json.Marshal(out)
+
+	return b
+}
@@ -0,0 +1,42 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"path"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// nilMessageInterceptor gives handlers an empty message in place of a nil one, which calls the
+// SDK drives itself (through WithConfigFile, shadowing or candidates) or custom interceptors may
+// pass, and fails calls whose handler returns neither a result nor an error with codes.Internal.
+// A nil HTTPResponse would otherwise reach mcpd as an empty one, whose continue=false rejects
+// the request with no status code.
+func nilMessageInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if m, ok := req.(proto.Message); ok && !m.ProtoReflect().IsValid() {
+			req = m.ProtoReflect().Type().New().Interface()
+		}
+
+		resp, err := handler(ctx, req)
+		if err == nil && isNilMessage(resp) {
+			return nil, status.Errorf(codes.Internal, "plugin returned a nil result from %s",
+				path.Base(info.FullMethod))
+		}
+
+		return resp, err
+	}
+}
+
+// isNilMessage reports whether v is nil or a nil message pointer.
+func isNilMessage(v any) bool {
+	if v == nil {
+		return true
+	}
+	m, ok := v.(proto.Message)
+
+	return ok && !m.ProtoReflect().IsValid()
+}
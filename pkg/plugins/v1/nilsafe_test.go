@@ -0,0 +1,264 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+)
+
+// serveChain returns the interceptor chain Serve runs impl's RPCs through with default options.
+func serveChain(t *testing.T, impl PluginServer) grpc.UnaryServerInterceptor {
+	t.Helper()
+
+	o, err := newServeOptions()
+	if err != nil {
+		t.Fatalf("newServeOptions: %v", err)
+	}
+	interceptors, err := o.unaryInterceptors(impl, nil, features.NewSet(SupportedFeatures()...))
+	if err != nil {
+		t.Fatalf("unaryInterceptors: %v", err)
+	}
+
+	return chainInterceptors(interceptors)
+}
+
+func TestServeChainNilMessages(t *testing.T) {
+	impl := &BasePlugin{}
+	intercept := serveChain(t, impl)
+
+	tests := []struct {
+		name    string
+		method  string
+		req     any
+		handler grpc.UnaryHandler
+		code    codes.Code
+	}{
+		{
+			name:   "nil request to HandleRequest",
+			method: Plugin_HandleRequest_FullMethodName,
+			req:    (*HTTPRequest)(nil),
+			handler: func(ctx context.Context, req any) (any, error) {
+				r := req.(*HTTPRequest)
+				if r == nil {
+					t.Error("handler received a nil *HTTPRequest")
+				}
+				return impl.HandleRequest(ctx, r)
+			},
+			code: codes.OK,
+		},
+		{
+			name:   "nil response to HandleResponse",
+			method: Plugin_HandleResponse_FullMethodName,
+			req:    (*HTTPResponse)(nil),
+			handler: func(ctx context.Context, req any) (any, error) {
+				r := req.(*HTTPResponse)
+				if r == nil {
+					t.Error("handler received a nil *HTTPResponse")
+				}
+				return impl.HandleResponse(ctx, r)
+			},
+			code: codes.OK,
+		},
+		{
+			name:   "nil config to Configure",
+			method: Plugin_Configure_FullMethodName,
+			req:    (*PluginConfig)(nil),
+			handler: func(ctx context.Context, req any) (any, error) {
+				if req.(*PluginConfig) == nil {
+					t.Error("handler received a nil *PluginConfig")
+				}
+				return &HTTPResponse{Continue: true}, nil
+			},
+			code: codes.OK,
+		},
+		{
+			name:    "untyped nil result",
+			method:  Plugin_HandleRequest_FullMethodName,
+			req:     &HTTPRequest{},
+			handler: func(context.Context, any) (any, error) { return nil, nil },
+			code:    codes.Internal,
+		},
+		{
+			name:    "nil HTTPResponse result",
+			method:  Plugin_HandleRequest_FullMethodName,
+			req:     &HTTPRequest{},
+			handler: func(context.Context, any) (any, error) { return (*HTTPResponse)(nil), nil },
+			code:    codes.Internal,
+		},
+		{
+			name:    "nil result from HandleResponse",
+			method:  Plugin_HandleResponse_FullMethodName,
+			req:     &HTTPResponse{},
+			handler: func(context.Context, any) (any, error) { return (*HTTPResponse)(nil), nil },
+			code:    codes.Internal,
+		},
+		{
+			name:   "nil result with an error keeps the error",
+			method: Plugin_HandleRequest_FullMethodName,
+			req:    &HTTPRequest{},
+			handler: func(context.Context, any) (any, error) {
+				return nil, status.Error(codes.InvalidArgument, "bad request")
+			},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &grpc.UnaryServerInfo{FullMethod: tt.method}
+			resp, err := intercept(context.Background(), tt.req, info, tt.handler)
+			if got := status.Code(err); got != tt.code {
+				t.Fatalf("code = %s, want %s (err %v)", got, tt.code, err)
+			}
+			if tt.code != codes.OK {
+				return
+			}
+			r, ok := resp.(*HTTPResponse)
+			if !ok || r == nil {
+				t.Fatalf("result = %#v, want a non-nil *HTTPResponse", resp)
+			}
+			if !r.GetContinue() {
+				t.Errorf("continue = false, want true")
+			}
+		})
+	}
+}
+
+func TestBasePluginNilMessages(t *testing.T) {
+	ctx := context.Background()
+	b := &BasePlugin{}
+
+	tests := []struct {
+		name string
+		call func() (*HTTPResponse, error)
+		want *HTTPResponse
+	}{
+		{
+			name: "HandleRequest with nil request",
+			call: func() (*HTTPResponse, error) { return b.HandleRequest(ctx, nil) },
+			want: &HTTPResponse{Continue: true},
+		},
+		{
+			name: "HandleRequest with nil headers",
+			call: func() (*HTTPResponse, error) { return b.HandleRequest(ctx, &HTTPRequest{Body: []byte("{}")}) },
+			want: &HTTPResponse{Continue: true, Body: []byte("{}")},
+		},
+		{
+			name: "HandleResponse with nil response",
+			call: func() (*HTTPResponse, error) { return b.HandleResponse(ctx, nil) },
+			want: &HTTPResponse{Continue: true},
+		},
+		{
+			name: "HandleResponse with nil headers",
+			call: func() (*HTTPResponse, error) { return b.HandleResponse(ctx, &HTTPResponse{StatusCode: 204}) },
+			want: &HTTPResponse{Continue: true, StatusCode: 204},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.call()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Configure with nil config", func(t *testing.T) {
+		if _, err := b.Configure(ctx, nil); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	t.Run("Stop with nil message", func(t *testing.T) {
+		if _, err := b.Stop(ctx, (*emptypb.Empty)(nil)); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestHeaderHelpersNil(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func() map[string]string
+		want map[string]string
+	}{
+		{
+			name: "SetRequestHeader with nil request",
+			set: func() map[string]string {
+				SetRequestHeader(nil, "X-Test", "1")
+				return nil
+			},
+		},
+		{
+			name: "SetRequestHeader with nil headers",
+			set: func() map[string]string {
+				req := &HTTPRequest{}
+				SetRequestHeader(req, "X-Test", "1")
+				return req.GetHeaders()
+			},
+			want: map[string]string{"X-Test": "1"},
+		},
+		{
+			name: "SetRequestHeader replaces a differently cased header",
+			set: func() map[string]string {
+				req := &HTTPRequest{Headers: map[string]string{"x-test": "0"}}
+				SetRequestHeader(req, "X-Test", "1")
+				return req.GetHeaders()
+			},
+			want: map[string]string{"X-Test": "1"},
+		},
+		{
+			name: "SetResponseHeader with nil response",
+			set: func() map[string]string {
+				SetResponseHeader(nil, "X-Test", "1")
+				return nil
+			},
+		},
+		{
+			name: "SetResponseHeader with nil headers",
+			set: func() map[string]string {
+				resp := &HTTPResponse{}
+				SetResponseHeader(resp, "X-Test", "1")
+				return resp.GetHeaders()
+			},
+			want: map[string]string{"X-Test": "1"},
+		},
+		{
+			name: "GetHeader with nil headers",
+			set: func() map[string]string {
+				if v := GetHeader(nil, "X-Test"); v != "" {
+					return map[string]string{"X-Test": v}
+				}
+				return nil
+			},
+		},
+		{
+			name: "CloneHeaders with nil headers",
+			set:  func() map[string]string { return CloneHeaders(nil) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.set()
+			if len(got) != len(tt.want) {
+				t.Fatalf("headers = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("headers = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
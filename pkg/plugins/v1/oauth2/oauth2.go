@@ -0,0 +1,300 @@
+// Package oauth2 authenticates requests to upstream MCP servers with OAuth2 access tokens obtained
+// through the client credentials grant (RFC 6749 section 4.4), injecting them as
+// "Authorization: Bearer" headers, configured per upstream.
+//
+// Tokens are cached per client and refreshed in the background shortly before they expire, so
+// requests only wait for the token endpoint when no valid token is cached:
+//
+//	err := mcpdpluginsv1.Serve(oauth2.NewPlugin(nil, recorder),
+//	    mcpdpluginsv1.WithUpstreams(mcpdpluginsv1.UpstreamFromPath()),
+//	    mcpdpluginsv1.WithSecrets(nil))
+//
+// with upstreams selected by their custom_config section:
+//
+//	token_url:                         https://auth.example.com/oauth2/token
+//	upstreams.crm.client_id:           mcpd-crm
+//	upstreams.crm.client_secret:       secret:env:CRM_CLIENT_SECRET
+//	upstreams.crm.scopes:              crm.read,crm.write
+//	upstreams.billing.client_id:       mcpd-billing
+//	upstreams.billing.client_secret:   secret:file:/run/secrets/billing
+//	upstreams.billing.audience:        https://billing.example.com
+//
+// Base keys, such as token_url above, apply to every upstream unless its section overrides them.
+// Requests to upstreams without a client_id are left unchanged.
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/httpclientx"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// MetricTokenRequests counts requests that needed a token, labelled by LabelClient and
+// LabelOutcome ("ok" or "error").
+const MetricTokenRequests = "oauth2.token_requests"
+
+// Label keys of MetricTokenRequests.
+const (
+	LabelClient  = "client_id"
+	LabelOutcome = "outcome"
+)
+
+// Config configures the client credentials used for one upstream, decodable from custom_config
+// with mcpdpluginsv1.DecodeConfig, typically per upstream with mcpdpluginsv1.UpstreamConfig.
+// Combine it with mcpdpluginsv1.WithSecrets to keep client secrets out of the configuration.
+type Config struct {
+	// TokenURL is the authorization server's token endpoint.
+	TokenURL string `config:"token_url"`
+
+	// ClientID and ClientSecret are the client's credentials. Requests to upstreams without a
+	// ClientID are left unchanged.
+	ClientID     string `config:"client_id"`
+	ClientSecret string `config:"client_secret"`
+
+	// Scopes are the scopes requested for the token.
+	Scopes []string `config:"scopes"`
+
+	// Audience and Resource identify the upstream to authorization servers that issue tokens per
+	// API, with the audience parameter (Auth0, Okta) or the resource parameter (RFC 8707).
+	Audience string `config:"audience"`
+	Resource string `config:"resource"`
+
+	// AuthStyle is how the client authenticates to the token endpoint: AuthStyleHeader or
+	// AuthStyleParams.
+	AuthStyle string `config:"auth_style" default:"header"`
+
+	// RefreshBefore is how long before expiry a token is refreshed in the background.
+	RefreshBefore time.Duration `config:"refresh_before" default:"1m"`
+
+	// Timeout bounds a token request.
+	Timeout time.Duration `config:"token_timeout" default:"10s"`
+
+	// Header is the request header the token is injected into.
+	Header string `config:"header" default:"Authorization"`
+
+	// Overwrite replaces the header when the client already sent one; otherwise the client's
+	// credentials are forwarded as they are.
+	Overwrite bool `config:"overwrite" default:"true"`
+
+	// FailOpen forwards requests without credentials when no token can be obtained, instead of
+	// short-circuiting them with 502.
+	FailOpen bool `config:"fail_open" default:"false"`
+}
+
+// validate reports whether cfg can be used to request tokens.
+func (cfg *Config) validate() error {
+	if cfg.ClientID == "" {
+		return fmt.Errorf("client_id is required")
+	}
+	u, err := url.Parse(cfg.TokenURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("token_url must be an absolute http(s) URL, got %q", cfg.TokenURL)
+	}
+	if cfg.AuthStyle != AuthStyleHeader && cfg.AuthStyle != AuthStyleParams {
+		return fmt.Errorf("auth_style must be %s or %s, got %q", AuthStyleHeader, AuthStyleParams, cfg.AuthStyle)
+	}
+	if cfg.RefreshBefore < 0 {
+		return fmt.Errorf("refresh_before cannot be negative")
+	}
+	if cfg.Timeout <= 0 {
+		return fmt.Errorf("token_timeout must be positive")
+	}
+
+	return nil
+}
+
+// sourceKey identifies the settings a cached token depends on, so upstreams sharing a client share
+// its tokens.
+type sourceKey struct {
+	tokenURL, clientID, clientSecret string
+	scopes, audience, resource       string
+	authStyle                        string
+	refreshBefore, timeout           time.Duration
+}
+
+// Injector adds Bearer tokens to requests, keeping one ClientCredentials per distinct client
+// configuration. It is safe for concurrent use.
+type Injector struct {
+	http     *http.Client
+	recorder metrics.Recorder
+	logger   *log.Logger
+	opts     []Option
+
+	mu      sync.Mutex
+	sources map[sourceKey]*ClientCredentials
+}
+
+// NewInjector returns an Injector requesting tokens with hc (nil uses an httpclientx client with
+// the default configuration) and recording token requests through recorder (nil disables
+// metrics). Its ClientCredentials are configured with opts.
+func NewInjector(hc *http.Client, recorder metrics.Recorder, opts ...Option) (*Injector, error) {
+	// Report invalid options now rather than at the first request.
+	var probe ClientCredentials
+	for _, opt := range opts {
+		if err := opt(&probe); err != nil {
+			return nil, err
+		}
+	}
+	if hc == nil {
+		var err error
+		if hc, err = httpclientx.New(httpclientx.DefaultConfig()); err != nil {
+			return nil, err
+		}
+	}
+	if recorder == nil {
+		recorder = metrics.Nop()
+	}
+
+	return &Injector{
+		http:     hc,
+		recorder: recorder,
+		logger:   log.Default(),
+		opts:     opts,
+		sources:  map[sourceKey]*ClientCredentials{},
+	}, nil
+}
+
+// Source returns the ClientCredentials for cfg, shared by every configuration with the same client
+// settings.
+func (i *Injector) Source(cfg *Config) (*ClientCredentials, error) {
+	key := sourceKey{
+		tokenURL:      cfg.TokenURL,
+		clientID:      cfg.ClientID,
+		clientSecret:  cfg.ClientSecret,
+		scopes:        strings.Join(cfg.Scopes, " "),
+		audience:      cfg.Audience,
+		resource:      cfg.Resource,
+		authStyle:     cfg.AuthStyle,
+		refreshBefore: cfg.RefreshBefore,
+		timeout:       cfg.Timeout,
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if src, ok := i.sources[key]; ok {
+		return src, nil
+	}
+	src, err := NewClientCredentials(*cfg, i.http, i.opts...)
+	if err != nil {
+		return nil, err
+	}
+	i.sources[key] = src
+
+	return src, nil
+}
+
+// HandleRequest continues the chain with a Bearer token for cfg injected into req, or with req
+// unchanged when cfg is nil or has no ClientID.
+func (i *Injector) HandleRequest(
+	ctx context.Context,
+	cfg *Config,
+	req *mcpdpluginsv1.HTTPRequest,
+) *mcpdpluginsv1.HTTPResponse {
+	if cfg == nil || cfg.ClientID == "" {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+	if !cfg.Overwrite && mcpdpluginsv1.GetHeader(req.GetHeaders(), cfg.Header) != "" {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+
+	tok, err := i.token(ctx, cfg)
+	if err != nil {
+		i.recorder.Count(MetricTokenRequests, 1, metrics.L(LabelClient, cfg.ClientID), metrics.L(LabelOutcome, "error"))
+		i.logger.Printf("oauth2: obtaining token for client %s: %v", cfg.ClientID, err)
+		if cfg.FailOpen {
+			return &mcpdpluginsv1.HTTPResponse{Continue: true}
+		}
+		return mcpdpluginsv1.DenyError(req, http.StatusBadGateway, &mcp.Error{
+			Code:    mcp.CodeServerError,
+			Message: "upstream authentication unavailable",
+		})
+	}
+	i.recorder.Count(MetricTokenRequests, 1, metrics.L(LabelClient, cfg.ClientID), metrics.L(LabelOutcome, "ok"))
+
+	modified := mcpdpluginsv1.CloneRequest(req)
+	mcpdpluginsv1.SetRequestHeader(modified, cfg.Header, tok.TokenType+" "+tok.AccessToken)
+
+	return &mcpdpluginsv1.HTTPResponse{Continue: true, ModifiedRequest: modified}
+}
+
+// token returns a token for cfg from its shared source.
+func (i *Injector) token(ctx context.Context, cfg *Config) (*Token, error) {
+	src, err := i.Source(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return src.Token(ctx)
+}
+
+// Plugin is a request-flow plugin injecting Bearer tokens for the upstreams configured in the
+// "upstreams.<name>." sections of its custom_config. Serve it with mcpdpluginsv1.WithUpstreams so
+// requests are matched to their upstream.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	injector *Injector
+	cfg      mcpdpluginsv1.UpstreamConfig[Config]
+}
+
+// NewPlugin returns a Plugin requesting tokens with hc (nil uses an httpclientx client with the
+// default configuration) and recording token requests through recorder (nil disables metrics),
+// with its ClientCredentials configured by opts. It panics when an option is invalid.
+func NewPlugin(hc *http.Client, recorder metrics.Recorder, opts ...Option) *Plugin {
+	i, err := NewInjector(hc, recorder, opts...)
+	if err != nil {
+		panic(fmt.Sprintf("oauth2: %v", err))
+	}
+
+	return &Plugin{injector: i}
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "oauth2",
+		Version:     pluginVersion,
+		Description: "Injects OAuth2 client credentials access tokens into requests to configured upstreams.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest), nil
+}
+
+// Configure decodes the per-upstream configuration of cfg's custom_config, keeping the previous
+// configuration on error.
+func (p *Plugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	if err := p.cfg.Configure(ctx, cfg); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleRequest injects a token for the request's upstream.
+func (p *Plugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.injector.HandleRequest(ctx, p.cfg.Get(ctx), req), nil
+}
@@ -0,0 +1,578 @@
+package oauth2_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/oauth2"
+)
+
+// fakeRecorder records every count as a line such as "oauth2.token_requests 1 [client_id=a outcome=ok]".
+type fakeRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *fakeRecorder) Count(name string, delta int64, labels ...metrics.Label) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Key + "=" + l.Value
+	}
+	r.lines = append(r.lines, fmt.Sprintf("%s %d [%s]", name, delta, strings.Join(parts, " ")))
+}
+
+func (r *fakeRecorder) Gauge(string, float64, ...metrics.Label) {}
+
+func (r *fakeRecorder) Observe(string, float64, ...metrics.Label) {}
+
+func (r *fakeRecorder) Timing(string, time.Duration, ...metrics.Label) {}
+
+func (r *fakeRecorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.lines)
+}
+
+// formServer is a token endpoint answering body with status, recording each request's form and
+// Basic credentials.
+type formServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	body     string
+	forms    []url.Values
+	basic    []string
+	requests int
+}
+
+func newFormServer(t *testing.T, status int, body string) *formServer {
+	t.Helper()
+
+	s := &formServer{status: status, body: body}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		user, pass, _ := r.BasicAuth()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.requests++
+		s.forms = append(s.forms, r.PostForm)
+		s.basic = append(s.basic, user+":"+pass)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(s.status)
+		_, _ = w.Write([]byte(s.body))
+	}))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func TestNewClientCredentialsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(cfg *oauth2.Config)
+		nilHTTP bool
+		wantErr string
+	}{
+		{
+			name:    "no client ID",
+			mutate:  func(cfg *oauth2.Config) { cfg.ClientID = "" },
+			wantErr: "client_id is required",
+		},
+		{
+			name:    "no token URL",
+			mutate:  func(cfg *oauth2.Config) { cfg.TokenURL = "" },
+			wantErr: "token_url must be an absolute",
+		},
+		{
+			name:    "relative token URL",
+			mutate:  func(cfg *oauth2.Config) { cfg.TokenURL = "/oauth2/token" },
+			wantErr: "token_url must be an absolute",
+		},
+		{
+			name:    "other scheme",
+			mutate:  func(cfg *oauth2.Config) { cfg.TokenURL = "ftp://auth.example.com/token" },
+			wantErr: "token_url must be an absolute",
+		},
+		{
+			name:    "unknown auth style",
+			mutate:  func(cfg *oauth2.Config) { cfg.AuthStyle = "jwt" },
+			wantErr: `auth_style must be header or params, got "jwt"`,
+		},
+		{
+			name:    "negative refresh window",
+			mutate:  func(cfg *oauth2.Config) { cfg.RefreshBefore = -time.Second },
+			wantErr: "refresh_before cannot be negative",
+		},
+		{
+			name:    "no timeout",
+			mutate:  func(cfg *oauth2.Config) { cfg.Timeout = 0 },
+			wantErr: "token_timeout must be positive",
+		},
+		{name: "nil HTTP client", nilHTTP: true, wantErr: "HTTP client cannot be nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig("https://auth.example.com/token")
+			if tt.mutate != nil {
+				tt.mutate(&cfg)
+			}
+			hc := http.DefaultClient
+			if tt.nilHTTP {
+				hc = nil
+			}
+			_, err := oauth2.NewClientCredentials(cfg, hc)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewClientCredentials error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTokenRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		mutate    func(cfg *oauth2.Config)
+		wantForm  url.Values
+		wantBasic string
+	}{
+		{
+			name:      "header auth",
+			wantForm:  url.Values{"grant_type": {"client_credentials"}},
+			wantBasic: "client:secret",
+		},
+		{
+			name: "escaped header credentials",
+			mutate: func(cfg *oauth2.Config) {
+				cfg.ClientID, cfg.ClientSecret = "c:1", "s&cret/+"
+			},
+			wantForm:  url.Values{"grant_type": {"client_credentials"}},
+			wantBasic: url.QueryEscape("c:1") + ":" + url.QueryEscape("s&cret/+"),
+		},
+		{
+			name: "params auth with scopes, audience and resource",
+			mutate: func(cfg *oauth2.Config) {
+				cfg.AuthStyle = oauth2.AuthStyleParams
+				cfg.Scopes = []string{"crm.read", "crm.write"}
+				cfg.Audience = "https://crm.example.com"
+				cfg.Resource = "https://crm.example.com/mcp"
+			},
+			wantForm: url.Values{
+				"grant_type":    {"client_credentials"},
+				"client_id":     {"client"},
+				"client_secret": {"secret"},
+				"scope":         {"crm.read crm.write"},
+				"audience":      {"https://crm.example.com"},
+				"resource":      {"https://crm.example.com/mcp"},
+			},
+			wantBasic: ":",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFormServer(t, http.StatusOK, `{"access_token":"tok","token_type":"Bearer","expires_in":60}`)
+			cfg := testConfig(srv.URL)
+			if tt.mutate != nil {
+				tt.mutate(&cfg)
+			}
+			src, err := oauth2.NewClientCredentials(cfg, srv.Client())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := src.Token(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := srv.forms[0]; got.Encode() != tt.wantForm.Encode() {
+				t.Errorf("form = %s, want %s", got.Encode(), tt.wantForm.Encode())
+			}
+			if srv.basic[0] != tt.wantBasic {
+				t.Errorf("Basic credentials = %q, want %q", srv.basic[0], tt.wantBasic)
+			}
+		})
+	}
+}
+
+func TestTokenResponseErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantErr    string
+		wantStatus int // Status of the *oauth2.Error, when one is wanted.
+	}{
+		{
+			name:       "error response",
+			status:     http.StatusUnauthorized,
+			body:       `{"error":"invalid_client","error_description":"unknown client"}`,
+			wantErr:    "oauth2: token endpoint returned HTTP 401: invalid_client: unknown client",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "error without a JSON body",
+			status:     http.StatusBadGateway,
+			body:       "<html>bad gateway</html>",
+			wantErr:    "oauth2: token endpoint returned HTTP 502",
+			wantStatus: http.StatusBadGateway,
+		},
+		{name: "invalid JSON", status: http.StatusOK, body: "{", wantErr: "invalid token response"},
+		{
+			name:    "no access token",
+			status:  http.StatusOK,
+			body:    `{"token_type":"bearer"}`,
+			wantErr: "has no access_token",
+		},
+		{
+			name:    "unsupported token type",
+			status:  http.StatusOK,
+			body:    `{"access_token":"t","token_type":"mac"}`,
+			wantErr: `unsupported token type "mac"`,
+		},
+		{
+			name:    "invalid expires_in",
+			status:  http.StatusOK,
+			body:    `{"access_token":"t","expires_in":"soon"}`,
+			wantErr: `invalid expires_in "soon"`,
+		},
+		{
+			name:    "zero expires_in",
+			status:  http.StatusOK,
+			body:    `{"access_token":"t","expires_in":0}`,
+			wantErr: "non-positive expires_in 0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFormServer(t, tt.status, tt.body)
+			src, err := oauth2.NewClientCredentials(testConfig(srv.URL), srv.Client())
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = src.Token(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Token error = %v, want %q", err, tt.wantErr)
+			}
+			var oerr *oauth2.Error
+			if errors.As(err, &oerr) != (tt.wantStatus != 0) || tt.wantStatus != 0 && oerr.StatusCode != tt.wantStatus {
+				t.Errorf("Token error = %#v, want an *oauth2.Error with status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestTokenNullExpiresIn(t *testing.T) {
+	srv := newFormServer(t, http.StatusOK, `{"access_token":"t","expires_in":null}`)
+	src, err := oauth2.NewClientCredentials(testConfig(srv.URL), srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := src.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lifetime := time.Until(tok.Expiry); lifetime < 4*time.Minute || lifetime > 5*time.Minute {
+		t.Errorf("token expires in %s, want the 5m default", lifetime)
+	}
+}
+
+func TestTokenContextCanceled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		<-release
+		_, _ = w.Write([]byte(`{"access_token":"late"}`))
+	}))
+	defer srv.Close()
+	defer close(release)
+	src, err := oauth2.NewClientCredentials(testConfig(srv.URL), srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := src.Token(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Token error = %v, want the caller's deadline", err)
+	}
+}
+
+func TestValid(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		tok  *oauth2.Token
+		want bool
+	}{
+		{name: "nil", tok: nil},
+		{name: "no access token", tok: &oauth2.Token{Expiry: now.Add(time.Minute)}},
+		{name: "expired", tok: &oauth2.Token{AccessToken: "t", Expiry: now}},
+		{name: "valid", tok: &oauth2.Token{AccessToken: "t", Expiry: now.Add(time.Second)}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tok.Valid(now); got != tt.want {
+				t.Errorf("Valid = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInjectorHandleRequest(t *testing.T) {
+	srv := newTokenServer(t)
+	broken := newFormServer(t, http.StatusUnauthorized, `{"error":"invalid_client"}`)
+	cfg := func(mutate func(cfg *oauth2.Config)) func() *oauth2.Config {
+		return func() *oauth2.Config {
+			c := testConfig(srv.URL)
+			c.Header, c.Overwrite = "Authorization", true
+			if mutate != nil {
+				mutate(&c)
+			}
+			return &c
+		}
+	}
+	brokenCfg := func(failOpen bool) func(cfg *oauth2.Config) {
+		return func(c *oauth2.Config) {
+			c.TokenURL, c.FailOpen = broken.URL, failOpen
+		}
+	}
+
+	tests := []struct {
+		name        string
+		cfg         func() *oauth2.Config
+		headers     map[string]string
+		wantStatus  int32
+		wantHeaders map[string]string // Headers of the modified request; nil when unmodified.
+		wantMetric  string
+	}{
+		{name: "no config", cfg: func() *oauth2.Config { return nil }},
+		{name: "no client ID", cfg: cfg(func(c *oauth2.Config) { c.ClientID = "" })},
+		{
+			name:        "token injected",
+			cfg:         cfg(nil),
+			headers:     map[string]string{"authorization": "Bearer client"},
+			wantHeaders: map[string]string{"Authorization": "Bearer tok-1"},
+			wantMetric:  "oauth2.token_requests 1 [client_id=client outcome=ok]",
+		},
+		{
+			name:    "client credentials kept",
+			cfg:     cfg(func(c *oauth2.Config) { c.Overwrite = false }),
+			headers: map[string]string{"authorization": "Bearer client"},
+		},
+		{
+			name:        "custom header",
+			cfg:         cfg(func(c *oauth2.Config) { c.Header = "X-Upstream-Token" }),
+			headers:     map[string]string{"Authorization": "Bearer client"},
+			wantHeaders: map[string]string{"Authorization": "Bearer client", "X-Upstream-Token": "Bearer tok-1"},
+			wantMetric:  "oauth2.token_requests 1 [client_id=client outcome=ok]",
+		},
+		{
+			name:        "invalid configuration",
+			cfg:         cfg(func(c *oauth2.Config) { c.TokenURL = "not a url" }),
+			wantStatus:  http.StatusBadGateway,
+			wantMetric:  "oauth2.token_requests 1 [client_id=client outcome=error]",
+			wantHeaders: nil,
+		},
+		{
+			name:       "token endpoint failure",
+			cfg:        cfg(brokenCfg(false)),
+			wantStatus: http.StatusBadGateway,
+			wantMetric: "oauth2.token_requests 1 [client_id=client outcome=error]",
+		},
+		{
+			name:       "token endpoint failure failing open",
+			cfg:        cfg(brokenCfg(true)),
+			wantMetric: "oauth2.token_requests 1 [client_id=client outcome=error]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &fakeRecorder{}
+			i, err := oauth2.NewInjector(srv.Client(), rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := &mcpdpluginsv1.HTTPRequest{
+				Headers: tt.headers,
+				Body:    []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`),
+			}
+			resp := i.HandleRequest(context.Background(), tt.cfg(), req)
+
+			if tt.wantStatus != 0 {
+				if resp.GetContinue() || resp.GetStatusCode() != tt.wantStatus {
+					t.Errorf("response = %v, want a %d short-circuit", resp, tt.wantStatus)
+				}
+				if body := string(resp.GetBody()); !strings.Contains(body, "upstream authentication unavailable") ||
+					!strings.Contains(body, `"id":1`) {
+					t.Errorf("deny body = %s, want a JSON-RPC error for the request", body)
+				}
+			} else if !resp.GetContinue() {
+				t.Errorf("response = %v, want the chain continued", resp)
+			}
+			got := resp.GetModifiedRequest()
+			switch {
+			case tt.wantHeaders == nil && got != nil:
+				t.Errorf("modified request = %v, want none", got)
+			case tt.wantHeaders != nil && (got == nil || !maps.Equal(got.GetHeaders(), tt.wantHeaders)):
+				t.Errorf("modified headers = %v, want %v", got.GetHeaders(), tt.wantHeaders)
+			}
+			var wantMetrics []string
+			if tt.wantMetric != "" {
+				wantMetrics = []string{tt.wantMetric}
+			}
+			if got := rec.recorded(); !slices.Equal(got, wantMetrics) {
+				t.Errorf("metrics = %q, want %q", got, wantMetrics)
+			}
+			srv.set(func(s *tokenServer) { s.requests = 0 })
+		})
+	}
+}
+
+func TestInjectorSource(t *testing.T) {
+	i, err := oauth2.NewInjector(http.DefaultClient, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := testConfig("https://auth.example.com/token")
+	src, err := i.Source(&base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(cfg *oauth2.Config)
+		shared bool
+	}{
+		{name: "same client", shared: true},
+		{name: "different header", mutate: func(cfg *oauth2.Config) { cfg.Header = "X-Token" }, shared: true},
+		{name: "different fail_open", mutate: func(cfg *oauth2.Config) { cfg.FailOpen = true }, shared: true},
+		{name: "different client", mutate: func(cfg *oauth2.Config) { cfg.ClientID = "other" }},
+		{name: "rotated secret", mutate: func(cfg *oauth2.Config) { cfg.ClientSecret = "rotated" }},
+		{name: "different scopes", mutate: func(cfg *oauth2.Config) { cfg.Scopes = []string{"read"} }},
+		{name: "different audience", mutate: func(cfg *oauth2.Config) { cfg.Audience = "https://api" }},
+		{name: "different resource", mutate: func(cfg *oauth2.Config) { cfg.Resource = "https://api" }},
+		{
+			name:   "different token URL",
+			mutate: func(cfg *oauth2.Config) { cfg.TokenURL = "https://auth2.example.com/token" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			if tt.mutate != nil {
+				tt.mutate(&cfg)
+			}
+			got, err := i.Source(&cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == src) != tt.shared {
+				t.Errorf("source shared = %t, want %t", got == src, tt.shared)
+			}
+		})
+	}
+
+	invalid := base
+	invalid.AuthStyle = "jwt"
+	if _, err := i.Source(&invalid); err == nil {
+		t.Error("Source accepted an invalid configuration")
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	srv := newTokenServer(t)
+	rec := &fakeRecorder{}
+	p := oauth2.NewPlugin(srv.Client(), rec)
+	ctx := context.Background()
+
+	md, err := p.GetMetadata(ctx, &emptypb.Empty{})
+	if err != nil || md.GetName() != "oauth2" {
+		t.Errorf("GetMetadata = %v, %v", md, err)
+	}
+	caps, err := p.GetCapabilities(ctx, &emptypb.Empty{})
+	if err != nil || !slices.Equal(caps.GetFlows(), []mcpdpluginsv1.Flow{mcpdpluginsv1.FlowRequest}) {
+		t.Errorf("GetCapabilities = %v, %v, want the request flow", caps, err)
+	}
+
+	if _, err := p.Configure(ctx, &mcpdpluginsv1.PluginConfig{CustomConfig: map[string]string{
+		"token_url":                       srv.URL,
+		"upstreams.crm.client_id":         "crm",
+		"upstreams.crm.client_secret":     "s1",
+		"upstreams.crm.scopes":            "crm.read,crm.write",
+		"upstreams.billing.client_id":     "billing",
+		"upstreams.billing.header":        "X-Billing-Token",
+		"upstreams.billing.auth_style":    "params",
+		"upstreams.billing.fail_open":     "true",
+		"upstreams.billing.token_url":     srv.URL + "/billing",
+		"upstreams.billing.audience":      "https://billing.example.com",
+		"upstreams.billing.overwrite":     "false",
+		"upstreams.billing.token_timeout": "5s",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		upstream   string
+		header     string
+		wantHeader string
+	}{
+		{upstream: "crm", header: "Authorization", wantHeader: "Bearer tok-1"},
+		{upstream: "crm", header: "Authorization", wantHeader: "Bearer tok-1"},
+		{upstream: "billing", header: "X-Billing-Token", wantHeader: "Bearer tok-2"},
+		{upstream: "unconfigured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.upstream, func(t *testing.T) {
+			upstreamCtx := mcpdpluginsv1.ContextWithUpstream(ctx, tt.upstream)
+			resp, err := p.HandleRequest(upstreamCtx, &mcpdpluginsv1.HTTPRequest{})
+			if err != nil || !resp.GetContinue() {
+				t.Fatalf("HandleRequest = %v, %v", resp, err)
+			}
+			if tt.header == "" {
+				if resp.GetModifiedRequest() != nil {
+					t.Errorf("modified request %v for an upstream without a client", resp.GetModifiedRequest())
+				}
+				return
+			}
+			if got := mcpdpluginsv1.GetHeader(resp.GetModifiedRequest().GetHeaders(), tt.header); got != tt.wantHeader {
+				t.Errorf("%s = %q, want %q", tt.header, got, tt.wantHeader)
+			}
+		})
+	}
+	if got := srv.fetches(); got != 2 {
+		t.Errorf("%d token requests, want one per client", got)
+	}
+
+	_, err = p.Configure(ctx, &mcpdpluginsv1.PluginConfig{CustomConfig: map[string]string{"token_timeout": "soon"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Configure error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestNewPluginInvalidOption(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewPlugin with a nil clock did not panic")
+		}
+	}()
+	oauth2.NewPlugin(http.DefaultClient, nil, oauth2.WithClock(nil))
+}
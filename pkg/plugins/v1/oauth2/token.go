@@ -0,0 +1,285 @@
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+)
+
+// Client authentication methods at the token endpoint (RFC 6749 section 2.3.1).
+const (
+	// AuthStyleHeader sends the client credentials with HTTP Basic authentication.
+	AuthStyleHeader = "header"
+
+	// AuthStyleParams sends the client credentials as client_id and client_secret form parameters.
+	AuthStyleParams = "params"
+)
+
+// defaultLifetime is how long tokens issued without expires_in are cached.
+const defaultLifetime = 5 * time.Minute
+
+// refreshRetry is how long a failed background refresh waits before the next attempt.
+const refreshRetry = 5 * time.Second
+
+// maxErrorBody bounds the token endpoint error bodies read into an Error.
+const maxErrorBody = 4 << 10
+
+// Token is an access token issued by a token endpoint.
+type Token struct {
+	AccessToken string
+	TokenType   string
+	Expiry      time.Time
+
+	refreshAt time.Time
+}
+
+// Valid reports whether t holds an access token that has not expired at now.
+func (t *Token) Valid(now time.Time) bool {
+	return t != nil && t.AccessToken != "" && now.Before(t.Expiry)
+}
+
+// Error is an error response from a token endpoint.
+type Error struct {
+	StatusCode  int
+	Code        string // The "error" member, such as "invalid_client".
+	Description string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("oauth2: token endpoint returned HTTP %d", e.StatusCode)
+	if e.Code != "" {
+		msg += ": " + e.Code
+	}
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+
+	return msg
+}
+
+// ClientCredentials obtains access tokens with the client credentials grant and caches them. A
+// token is refreshed in the background once it enters its refresh window, so callers keep getting
+// the cached token while the next one is fetched; only a missing or expired token makes Token
+// wait for the endpoint. It is safe for concurrent use.
+type ClientCredentials struct {
+	cfg   Config
+	http  *http.Client
+	clock clock.Clock
+
+	mu         sync.Mutex
+	token      *Token
+	refreshing chan struct{} // Closed when the fetch in flight, if any, completes.
+	lastErr    error
+	failedAt   time.Time
+}
+
+// Option configures a ClientCredentials.
+type Option func(*ClientCredentials) error
+
+// WithClock sets the clock token expiry and refresh windows are measured by (defaults to
+// clock.Real()).
+func WithClock(clk clock.Clock) Option {
+	return func(c *ClientCredentials) error {
+		if clk == nil {
+			return fmt.Errorf("clock cannot be nil")
+		}
+		c.clock = clk
+		return nil
+	}
+}
+
+// NewClientCredentials returns a ClientCredentials fetching tokens as configured by cfg with hc.
+func NewClientCredentials(cfg Config, hc *http.Client, opts ...Option) (*ClientCredentials, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if hc == nil {
+		return nil, fmt.Errorf("HTTP client cannot be nil")
+	}
+
+	c := &ClientCredentials{cfg: cfg, http: hc, clock: clock.Real()}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Token returns a valid access token, fetching one when none is cached or the cached one has
+// expired, and starting a background refresh when it is about to. After a failed fetch, Token
+// returns the same error for a few seconds before trying again.
+func (c *ClientCredentials) Token(ctx context.Context) (*Token, error) {
+	for {
+		now := c.clock.Now()
+
+		c.mu.Lock()
+		tok, wait := c.token, c.refreshing
+		switch {
+		case tok.Valid(now) && now.Before(tok.refreshAt):
+			c.mu.Unlock()
+			return tok, nil
+		case tok.Valid(now):
+			if wait == nil && now.Sub(c.failedAt) >= refreshRetry {
+				c.startFetch(context.WithoutCancel(ctx))
+			}
+			c.mu.Unlock()
+			return tok, nil
+		case wait == nil && c.lastErr != nil && now.Sub(c.failedAt) < refreshRetry:
+			// Spare the token endpoint from a request per call while it keeps failing.
+			err := c.lastErr
+			c.mu.Unlock()
+			return nil, err
+		case wait == nil:
+			wait = c.startFetch(context.WithoutCancel(ctx))
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		c.mu.Lock()
+		tok, err := c.token, c.lastErr
+		c.mu.Unlock()
+		if tok.Valid(c.clock.Now()) {
+			return tok, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Invalidate discards the cached token, for example after the upstream rejected it, so the next
+// Token call fetches a new one.
+func (c *ClientCredentials) Invalidate() {
+	c.mu.Lock()
+	c.token = nil
+	c.mu.Unlock()
+}
+
+// startFetch fetches a token in the background and returns the channel closed when it completes.
+// c.mu must be held.
+func (c *ClientCredentials) startFetch(ctx context.Context) chan struct{} {
+	done := make(chan struct{})
+	c.refreshing = done
+
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+		defer cancel()
+		tok, err := c.fetch(ctx)
+
+		c.mu.Lock()
+		if err == nil {
+			c.token = tok
+		} else {
+			c.failedAt = c.clock.Now()
+		}
+		c.lastErr = err
+		c.refreshing = nil
+		c.mu.Unlock()
+		close(done)
+	}()
+
+	return done
+}
+
+// tokenResponse is a successful or error token endpoint response (RFC 6749 sections 5.1 and 5.2).
+//
+//nolint:tagliatelle // external API wire format
+type tokenResponse struct {
+	AccessToken string          `json:"access_token"`
+	TokenType   string          `json:"token_type"`
+	ExpiresIn   json.RawMessage `json:"expires_in"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// fetch requests a token from the token endpoint.
+func (c *ClientCredentials) fetch(ctx context.Context) (*Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	}
+	if c.cfg.Audience != "" {
+		form.Set("audience", c.cfg.Audience)
+	}
+	if c.cfg.Resource != "" {
+		form.Set("resource", c.cfg.Resource)
+	}
+	if c.cfg.AuthStyle == AuthStyleParams {
+		form.Set("client_id", c.cfg.ClientID)
+		form.Set("client_secret", c.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.AuthStyle == AuthStyleHeader {
+		// RFC 6749 section 2.3.1 form-encodes the credentials before Basic encoding.
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+
+	issued := c.clock.Now()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: requesting token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body tokenResponse
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		_ = json.Unmarshal(b, &body)
+		return nil, &Error{StatusCode: resp.StatusCode, Code: body.Error, Description: body.ErrorDescription}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("oauth2: invalid token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return nil, fmt.Errorf("oauth2: token response has no access_token")
+	}
+	if body.TokenType != "" && !strings.EqualFold(body.TokenType, "bearer") {
+		return nil, fmt.Errorf("oauth2: unsupported token type %q", body.TokenType)
+	}
+
+	lifetime := defaultLifetime
+	if len(body.ExpiresIn) > 0 && string(body.ExpiresIn) != "null" {
+		// Some providers send expires_in as a string.
+		secs, err := strconv.ParseInt(strings.Trim(string(body.ExpiresIn), `"`), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("oauth2: invalid expires_in %s", body.ExpiresIn)
+		}
+		lifetime = time.Duration(secs) * time.Second
+	}
+	if lifetime <= 0 {
+		return nil, fmt.Errorf("oauth2: token response has non-positive expires_in %s", body.ExpiresIn)
+	}
+
+	// Refresh the configured margin before expiry, but no earlier than halfway through the token's
+	// lifetime so short-lived tokens are not refreshed on every use.
+	expiry := issued.Add(lifetime)
+	return &Token{
+		AccessToken: body.AccessToken,
+		TokenType:   "Bearer",
+		Expiry:      expiry,
+		refreshAt:   expiry.Add(-min(c.cfg.RefreshBefore, lifetime/2)),
+	}, nil
+}
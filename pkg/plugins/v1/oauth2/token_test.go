@@ -0,0 +1,266 @@
+package oauth2_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/oauth2"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/plugintest"
+)
+
+// tokenServer is a token endpoint issuing "tok-<n>" for its nth request.
+type tokenServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	expiresIn string // Raw JSON expires_in member; empty leaves it out.
+	status    int    // Status of the responses; 0 answers 200 with a token.
+	requests  int
+}
+
+func newTokenServer(t *testing.T) *tokenServer {
+	t.Helper()
+
+	s := &tokenServer{expiresIn: "60"}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *tokenServer) serve(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	w.Header().Set("Content-Type", "application/json")
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "temporarily_unavailable"})
+		return
+	}
+	body := fmt.Sprintf(`{"access_token":"tok-%d","token_type":"bearer"`, s.requests)
+	if s.expiresIn != "" {
+		body += `,"expires_in":` + s.expiresIn
+	}
+	_, _ = w.Write([]byte(body + "}"))
+}
+
+func (s *tokenServer) set(fn func(s *tokenServer)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(s)
+}
+
+func (s *tokenServer) fetches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+// waitFetches waits for the server to have answered n requests, as background refreshes do
+// asynchronously.
+func (s *tokenServer) waitFetches(t *testing.T, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.fetches() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("token endpoint got %d requests, want %d", s.fetches(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// testConfig returns a valid Config for the endpoint at tokenURL.
+func testConfig(tokenURL string) oauth2.Config {
+	return oauth2.Config{
+		TokenURL:      tokenURL,
+		ClientID:      "client",
+		ClientSecret:  "secret",
+		AuthStyle:     oauth2.AuthStyleHeader,
+		RefreshBefore: time.Minute,
+		Timeout:       5 * time.Second,
+	}
+}
+
+// newSource returns a ClientCredentials for srv on a fake clock.
+func newSource(t *testing.T, srv *tokenServer, cfg oauth2.Config) (*oauth2.ClientCredentials, *plugintest.FakeClock) {
+	t.Helper()
+
+	clk := plugintest.NewFakeClock(time.Time{})
+	src, err := oauth2.NewClientCredentials(cfg, srv.Client(), oauth2.WithClock(clk))
+	if err != nil {
+		t.Fatalf("NewClientCredentials: %v", err)
+	}
+
+	return src, clk
+}
+
+func TestClientCredentialsExpiry(t *testing.T) {
+	type step struct {
+		advance     time.Duration
+		wantToken   string
+		wantFetches int // Awaited after Token returns, since refreshes run in the background.
+	}
+	tests := []struct {
+		name          string
+		expiresIn     string
+		refreshBefore time.Duration
+		steps         []step
+	}{
+		{
+			name:          "cached until the refresh window",
+			expiresIn:     "60",
+			refreshBefore: 10 * time.Second,
+			steps:         []step{{0, "tok-1", 1}, {49 * time.Second, "tok-1", 1}},
+		},
+		{
+			name:          "refreshed in the background within the window",
+			expiresIn:     "60",
+			refreshBefore: 10 * time.Second,
+			steps:         []step{{0, "tok-1", 1}, {51 * time.Second, "tok-1", 2}, {0, "tok-2", 2}},
+		},
+		{
+			name:          "expired token waits for a new one",
+			expiresIn:     "60",
+			refreshBefore: 10 * time.Second,
+			steps:         []step{{0, "tok-1", 1}, {61 * time.Second, "tok-2", 2}},
+		},
+		{
+			name:          "short-lived token refreshed no earlier than halfway",
+			expiresIn:     "10",
+			refreshBefore: time.Minute,
+			steps: []step{
+				{0, "tok-1", 1}, {4 * time.Second, "tok-1", 1}, {2 * time.Second, "tok-1", 2}, {0, "tok-2", 2},
+			},
+		},
+		{
+			name:      "no refresh window",
+			expiresIn: "60",
+			steps:     []step{{0, "tok-1", 1}, {59 * time.Second, "tok-1", 1}, {time.Second, "tok-2", 2}},
+		},
+		{
+			name:  "default lifetime without expires_in",
+			steps: []step{{0, "tok-1", 1}, {5*time.Minute - time.Second, "tok-1", 1}, {time.Second, "tok-2", 2}},
+		},
+		{
+			name:      "expires_in as a string",
+			expiresIn: `"30"`,
+			steps:     []step{{0, "tok-1", 1}, {29 * time.Second, "tok-1", 1}, {time.Second, "tok-2", 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTokenServer(t)
+			srv.set(func(s *tokenServer) { s.expiresIn = tt.expiresIn })
+			cfg := testConfig(srv.URL)
+			cfg.RefreshBefore = tt.refreshBefore
+			src, clk := newSource(t, srv, cfg)
+
+			for i, s := range tt.steps {
+				clk.Advance(s.advance)
+				tok, err := src.Token(context.Background())
+				if err != nil {
+					t.Fatalf("step %d: Token: %v", i, err)
+				}
+				if tok.AccessToken != s.wantToken {
+					t.Errorf("step %d: token = %s, want %s", i, tok.AccessToken, s.wantToken)
+				}
+				if !tok.Valid(clk.Now()) {
+					t.Errorf("step %d: Token returned a token expired at %s", i, tok.Expiry)
+				}
+				srv.waitFetches(t, s.wantFetches)
+				if got := srv.fetches(); got != s.wantFetches {
+					t.Errorf("step %d: %d token requests, want %d", i, got, s.wantFetches)
+				}
+			}
+		})
+	}
+}
+
+func TestClientCredentialsExpiryTime(t *testing.T) {
+	srv := newTokenServer(t)
+	src, clk := newSource(t, srv, testConfig(srv.URL))
+	start := clk.Now()
+
+	tok, err := src.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := start.Add(time.Minute); !tok.Expiry.Equal(want) {
+		t.Errorf("Expiry = %s, want %s", tok.Expiry, want)
+	}
+	if tok.TokenType != "Bearer" {
+		t.Errorf("TokenType = %q, want Bearer", tok.TokenType)
+	}
+}
+
+func TestClientCredentialsFailureBackoff(t *testing.T) {
+	srv := newTokenServer(t)
+	srv.set(func(s *tokenServer) { s.status = http.StatusServiceUnavailable })
+	src, clk := newSource(t, srv, testConfig(srv.URL))
+
+	var oerr *oauth2.Error
+	if _, err := src.Token(context.Background()); !errors.As(err, &oerr) || oerr.StatusCode != 503 {
+		t.Fatalf("Token error = %v, want a 503 *oauth2.Error", err)
+	}
+	if oerr.Code != "temporarily_unavailable" {
+		t.Errorf("Error.Code = %q, want temporarily_unavailable", oerr.Code)
+	}
+
+	// The failure is returned again without a request until the retry delay passes.
+	clk.Advance(4 * time.Second)
+	if _, err := src.Token(context.Background()); err == nil {
+		t.Fatal("Token succeeded during the retry delay")
+	}
+	if got := srv.fetches(); got != 1 {
+		t.Errorf("%d token requests during the retry delay, want 1", got)
+	}
+
+	srv.set(func(s *tokenServer) { s.status = 0 })
+	clk.Advance(time.Second)
+	tok, err := src.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token after the retry delay: %v", err)
+	}
+	if tok.AccessToken != "tok-2" {
+		t.Errorf("token = %s, want tok-2", tok.AccessToken)
+	}
+}
+
+func TestClientCredentialsInvalidate(t *testing.T) {
+	srv := newTokenServer(t)
+	src, _ := newSource(t, srv, testConfig(srv.URL))
+
+	if _, err := src.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	src.Invalidate()
+	tok, err := src.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "tok-2" {
+		t.Errorf("token after Invalidate = %s, want tok-2", tok.AccessToken)
+	}
+}
+
+func TestWithClockNil(t *testing.T) {
+	if _, err := oauth2.NewClientCredentials(testConfig("https://auth.example.com/token"), http.DefaultClient,
+		oauth2.WithClock(nil)); err == nil {
+		t.Error("NewClientCredentials accepted a nil clock")
+	}
+	if _, err := oauth2.NewInjector(http.DefaultClient, nil, oauth2.WithClock(nil)); err == nil {
+		t.Error("NewInjector accepted a nil clock")
+	}
+}
@@ -0,0 +1,175 @@
+package mcpdpluginsv1
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/sampling"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/sandbox"
+)
+
+// ServeOption configures optional behavior of Serve.
+type ServeOption func(*serveOptions) error
+
+// serveOptions holds the configuration assembled from ServeOption values.
+type serveOptions struct {
+	bus          *EventBus
+	logger       *log.Logger
+	sampler      sampling.Sampler
+	reporter     ErrorReporter
+	interceptors []grpc.UnaryServerInterceptor
+	recorders    []metrics.Recorder
+	otlp         [][]metrics.OTLPOption
+	exporters    []*metrics.OTLP
+	otlpShutdown sync.Once
+	subscribers  []pendingSubscription
+	shadow       bool
+	candidate    *candidateEvaluator
+	sandbox      *sandbox.Config
+	resources    *resourceGuard
+	backpressure *backpressure
+	noDeadline   bool
+	pooling      bool
+	headersOnly  bool
+	strict       bool
+	headerLimits *HeaderLimits
+	tuning       *ServerTuning
+	tls          *tls.Config
+
+	statsHandlers []stats.Handler
+	startup       *startupReporter
+	registration  *registrar
+	configDigest  configDigestTracker
+	admin         *adminListener
+	probes        *probeServer
+	debug         atomic.Bool
+	noDiagnostics bool
+
+	terminationGrace time.Duration
+	terminating      atomic.Bool
+}
+
+// pendingSubscription is a WithEventSubscriber registration applied once the bus is known.
+type pendingSubscription struct {
+	handler EventHandler
+	kinds   []EventKind
+}
+
+// newServeOptions applies opts over the defaults.
+func newServeOptions(opts ...ServeOption) (*serveOptions, error) {
+	o := &serveOptions{
+		logger:   log.Default(),
+		sampler:  sampling.Always(),
+		reporter: NopErrorReporter(),
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.pooling && o.candidate != nil {
+		return nil, fmt.Errorf("message pooling cannot be combined with a candidate")
+	}
+	if err := o.startOTLP(); err != nil {
+		return nil, err
+	}
+
+	if o.bus == nil {
+		o.bus = NewEventBus()
+	}
+	for _, sub := range o.subscribers {
+		o.bus.Subscribe(sub.handler, sub.kinds...)
+	}
+	if r := o.metricsRecorder(); r != nil {
+		subscribeMetrics(o, r)
+	}
+	subscribeDebugLog(o)
+
+	return o, nil
+}
+
+// WithLogger sets the logger used by Serve and SDK features (defaults to log.Default()).
+func WithLogger(l *log.Logger) ServeOption {
+	return func(o *serveOptions) error {
+		if l == nil {
+			return fmt.Errorf("logger cannot be nil")
+		}
+		o.logger = l
+		return nil
+	}
+}
+
+// WithSampler sets the sampler consulted by SDK features that record individual calls: the access
+// log, the slow request log, the debug log and the trace exemplars of WithMetrics (defaults to
+// sampling.Always()). Use sampling.PerFeature to tune features
+// individually.
+func WithSampler(s sampling.Sampler) ServeOption {
+	return func(o *serveOptions) error {
+		if s == nil {
+			return fmt.Errorf("sampler cannot be nil")
+		}
+		o.sampler = s
+		return nil
+	}
+}
+
+// sample reports whether the call described by ev should be recorded by feature.
+func (o *serveOptions) sample(feature string, ev Event) bool {
+	return o.sampler.Sample(sampling.Params{
+		Feature:      feature,
+		Method:       ev.Method,
+		Err:          ev.Err != nil,
+		ShortCircuit: ev.Result != nil && !ev.Result.GetContinue(),
+	})
+}
+
+// WithEventBus makes Serve publish lifecycle, request, and error events on bus.
+// Without this option Serve uses a private bus.
+func WithEventBus(bus *EventBus) ServeOption {
+	return func(o *serveOptions) error {
+		if bus == nil {
+			return fmt.Errorf("event bus cannot be nil")
+		}
+		if o.bus != nil && o.bus != bus {
+			return fmt.Errorf("only one event bus can be configured")
+		}
+		o.bus = bus
+		return nil
+	}
+}
+
+// WithEventSubscriber subscribes h to events of the given kinds (all kinds when none are given)
+// on the bus used by Serve.
+func WithEventSubscriber(h EventHandler, kinds ...EventKind) ServeOption {
+	return func(o *serveOptions) error {
+		if h == nil {
+			return fmt.Errorf("event handler cannot be nil")
+		}
+		o.subscribers = append(o.subscribers, pendingSubscription{handler: h, kinds: kinds})
+		return nil
+	}
+}
+
+// WithUnaryInterceptor adds a gRPC unary server interceptor around the plugin's RPC handlers.
+// Interceptors run in the order they are added, inside the SDK's own instrumentation.
+func WithUnaryInterceptor(i grpc.UnaryServerInterceptor) ServeOption {
+	return func(o *serveOptions) error {
+		if i == nil {
+			return fmt.Errorf("interceptor cannot be nil")
+		}
+		o.interceptors = append(o.interceptors, i)
+		return nil
+	}
+}
@@ -0,0 +1,76 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/accesslog"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/sampling"
+)
+
+func TestWithSampler(t *testing.T) {
+	var asked []sampling.Params
+	keep := map[string]bool{}
+	sampler := sampling.SamplerFunc(func(p sampling.Params) bool {
+		asked = append(asked, p)
+		return keep[p.Feature]
+	})
+
+	var slow bytes.Buffer
+	entries := &entryLog{}
+	l, err := accesslog.New(&bytes.Buffer{}, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := newServeOptions(
+		WithSampler(sampler),
+		WithLogger(log.New(&slow, "", 0)),
+		WithAccessLog(l),
+		WithSlowRequestLog(time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev := Event{
+		Kind:     EventRequest,
+		Method:   "HandleRequest",
+		Duration: time.Second,
+		Request:  &HTTPRequest{},
+		Result:   &HTTPResponse{StatusCode: 403},
+		Err:      errors.New("boom"),
+	}
+	o.bus.Publish(context.Background(), ev)
+	// Subscribers are called in no particular order.
+	slices.SortFunc(asked, func(a, b sampling.Params) int { return strings.Compare(a.Feature, b.Feature) })
+	want := []sampling.Params{
+		{Feature: sampling.FeatureAccessLog, Method: "HandleRequest", Err: true, ShortCircuit: true},
+		{Feature: sampling.FeatureSlowLog, Method: "HandleRequest", Err: true, ShortCircuit: true},
+	}
+	if !slices.Equal(asked, want) {
+		t.Errorf("sampler asked %+v, want %+v", asked, want)
+	}
+	if len(entries.entries) != 0 || slow.Len() != 0 {
+		t.Errorf("recorded %d access log entries and slow log %q for an unsampled call",
+			len(entries.entries), slow.String())
+	}
+
+	keep[sampling.FeatureAccessLog] = true
+	o.bus.Publish(context.Background(), ev)
+	if len(entries.entries) != 1 || slow.Len() != 0 {
+		t.Errorf("recorded %d access log entries and slow log %q, want only the access log",
+			len(entries.entries), slow.String())
+	}
+}
+
+func TestWithSamplerNil(t *testing.T) {
+	if _, err := newServeOptions(WithSampler(nil)); err == nil {
+		t.Error("WithSampler accepted a nil sampler")
+	}
+}
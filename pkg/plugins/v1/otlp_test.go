@@ -0,0 +1,134 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// otlpLoops counts the running OTLP export loops.
+func otlpLoops() int {
+	buf := make([]byte, 1<<20)
+	return strings.Count(string(buf[:runtime.Stack(buf, true)]), "metrics.(*OTLP).loop")
+}
+
+// waitOTLPLoops waits for want OTLP export loops to be running. A goroutine only shows up in
+// stack dumps once it has been scheduled.
+func waitOTLPLoops(t *testing.T, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for otlpLoops() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d OTLP export loops running, want %d", otlpLoops(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// otlpCollector is an OTLP/HTTP endpoint counting the exports it receives.
+func otlpCollector(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+
+	var exports atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		exports.Add(1)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &exports
+}
+
+func TestWithOTelMetricsStartsAfterOptions(t *testing.T) {
+	failing := func(*serveOptions) error { return io.ErrUnexpectedEOF }
+	tests := []struct {
+		name string
+		opts []ServeOption
+	}{
+		{"later option fails", []ServeOption{WithOTelMetrics(), ServeOption(failing)}},
+		{"invalid exporter option", []ServeOption{WithOTelMetrics(metrics.WithOTLPInterval(0))}},
+		{"second exporter invalid", []ServeOption{WithOTelMetrics(), WithOTelMetrics(metrics.WithOTLPClient(nil))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := otlpLoops()
+			if _, err := newServeOptions(tt.opts...); err == nil {
+				t.Fatal("newServeOptions succeeded, want an error")
+			}
+			// Give a leaked loop time to be scheduled.
+			time.Sleep(10 * time.Millisecond)
+			if got := otlpLoops(); got != before {
+				t.Errorf("%d OTLP export loops running after the error, want %d", got, before)
+			}
+		})
+	}
+}
+
+func TestWithOTelMetricsShutdownOnStop(t *testing.T) {
+	srv, exports := otlpCollector(t)
+	before := otlpLoops()
+	o, err := newServeOptions(WithOTelMetrics(metrics.WithOTLPEndpoint(srv.URL), metrics.WithOTLPInterval(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitOTLPLoops(t, before+1)
+
+	o.metricsRecorder().Count("requests", 1)
+	o.bus.Publish(context.Background(), Event{Kind: EventLifecycle, Phase: PhaseStopped})
+	stopped := exports.Load()
+	if stopped == 0 {
+		t.Error("collector received no export on stop")
+	}
+	waitOTLPLoops(t, before)
+
+	// Serve shuts the exporters down again when it returns; only the first shutdown exports.
+	o.shutdownOTLP(context.Background())
+	if got := exports.Load(); got != stopped {
+		t.Errorf("collector received %d exports after a second shutdown, want %d", got, stopped)
+	}
+}
+
+func TestOTLPTelemetryInterceptor(t *testing.T) {
+	srv, exports := otlpCollector(t)
+	o, err := newServeOptions(WithOTelMetrics(metrics.WithOTLPInterval(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { o.shutdownOTLP(context.Background()) })
+	if len(o.exporters) != 1 {
+		t.Fatalf("%d exporters, want 1", len(o.exporters))
+	}
+	exp := o.exporters[0]
+	interceptor := otlpTelemetryInterceptor(exp)
+	handler := func(context.Context, any) (any, error) { return nil, nil }
+
+	// Other methods do not configure the exporter.
+	cfg := &PluginConfig{Telemetry: &TelemetryConfig{OtlpEndpoint: srv.URL, ServiceName: "svc"}}
+	info := &grpc.UnaryServerInfo{FullMethod: Plugin_HandleRequest_FullMethodName}
+	if _, err := interceptor(context.Background(), cfg, info, handler); err != nil {
+		t.Fatal(err)
+	}
+	exp.Count("requests", 1)
+	if err := exp.Flush(); err != nil || exports.Load() != 0 {
+		t.Fatalf("Flush = %v with %d exports, want no export before Configure", err, exports.Load())
+	}
+
+	info = &grpc.UnaryServerInfo{FullMethod: Plugin_Configure_FullMethodName}
+	if _, err := interceptor(context.Background(), cfg, info, handler); err != nil {
+		t.Fatal(err)
+	}
+	if err := exp.Flush(); err != nil || exports.Load() != 1 {
+		t.Errorf("Flush = %v with %d exports, want an export to the Configure endpoint", err, exports.Load())
+	}
+}
@@ -0,0 +1,220 @@
+// Package payload classifies request and response bodies and parses multipart payloads, so plugins
+// can skip or specially handle non-JSON bodies rather than corrupting them by rewriting them as
+// JSON.
+//
+//	contentType := mcpdpluginsv1.GetHeader(req.GetHeaders(), "Content-Type")
+//	switch payload.Classify(contentType, req.GetBody()) {
+//	case payload.JSON:
+//	    // inspect or rewrite MCP messages
+//	case payload.Multipart:
+//	    parts, err := payload.ParseMultipart(contentType, req.GetBody())
+//	    ...
+//	default:
+//	    return &mcpdpluginsv1.HTTPResponse{Continue: true}, nil
+//	}
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kind is the broad class of a body.
+type Kind string
+
+// Body kinds.
+const (
+	Empty     Kind = "empty"
+	JSON      Kind = "json"
+	Text      Kind = "text"
+	Form      Kind = "form"
+	Multipart Kind = "multipart"
+	Binary    Kind = "binary"
+)
+
+// Classify returns the kind of data given its Content-Type header value, which may be empty.
+// A declared type is trusted unless the data contradicts it: a JSON type with invalid JSON is
+// reported as Text or Binary. Undeclared data is sniffed.
+func Classify(contentType string, data []byte) Kind {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return Empty
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "" {
+		return sniff(data)
+	}
+
+	switch {
+	case IsJSONType(mediaType):
+		if json.Valid(data) {
+			return JSON
+		}
+		return textOrBinary(data)
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return Multipart
+	case mediaType == "application/x-www-form-urlencoded":
+		return Form
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/xml", strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/javascript", mediaType == "application/yaml":
+		return textOrBinary(data)
+	case mediaType == "application/octet-stream":
+		return sniff(data)
+	default:
+		return Binary
+	}
+}
+
+// IsJSONType reports whether mediaType is application/json or a +json structured syntax type.
+func IsJSONType(mediaType string) bool {
+	mediaType = strings.ToLower(mediaType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func sniff(data []byte) Kind {
+	if trimmed := bytes.TrimSpace(data); (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return JSON
+	}
+
+	return textOrBinary(data)
+}
+
+// textOrBinary reports Text for valid UTF-8 without NUL bytes, using the same sniffing window as
+// net/http.DetectContentType.
+func textOrBinary(data []byte) Kind {
+	window := data
+	if len(window) > 512 {
+		window = window[:512]
+		// Do not split a multi-byte rune at the window edge.
+		for i := 0; i < utf8.UTFMax && len(window) > 0 && !utf8.Valid(window); i++ {
+			window = window[:len(window)-1]
+		}
+	}
+	if bytes.IndexByte(window, 0) >= 0 || !utf8.Valid(window) {
+		return Binary
+	}
+	if strings.HasPrefix(http.DetectContentType(data), "text/") {
+		return Text
+	}
+
+	return Binary
+}
+
+// ErrTooLarge is returned when a multipart body exceeds a parsing limit.
+var ErrTooLarge = errors.New("payload: multipart limit exceeded")
+
+// Default multipart limits.
+const (
+	DefaultMaxParts    = 100
+	DefaultMaxPartSize = 32 << 20
+)
+
+// Part is one part of a multipart body.
+type Part struct {
+	// Name is the form field name from Content-Disposition, if any.
+	Name string
+
+	// Filename is the file name from Content-Disposition, if any.
+	Filename string
+
+	// ContentType is the part's Content-Type header value.
+	ContentType string
+
+	Header textproto.MIMEHeader
+	Data   []byte
+}
+
+// MultipartOption configures ParseMultipart.
+type MultipartOption func(*multipartOptions)
+
+type multipartOptions struct {
+	maxParts    int
+	maxPartSize int64
+}
+
+// WithMaxParts limits the number of parts (default 100).
+func WithMaxParts(n int) MultipartOption {
+	return func(o *multipartOptions) {
+		o.maxParts = n
+	}
+}
+
+// WithMaxPartSize limits the size of each part in bytes (default 32 MiB).
+func WithMaxPartSize(n int64) MultipartOption {
+	return func(o *multipartOptions) {
+		o.maxPartSize = n
+	}
+}
+
+// Boundary returns the boundary parameter of a multipart Content-Type header value.
+func Boundary(contentType string) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("invalid content type %q: %w", contentType, err)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return "", fmt.Errorf("content type %q is not multipart", mediaType)
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		return "", fmt.Errorf("content type %q has no boundary", contentType)
+	}
+
+	return boundary, nil
+}
+
+// ParseMultipart splits a multipart body (multipart/form-data, multipart/mixed, ...) into its
+// parts. Part data is read raw, without decoding any Content-Transfer-Encoding.
+func ParseMultipart(contentType string, data []byte, opts ...MultipartOption) ([]Part, error) {
+	o := multipartOptions{maxParts: DefaultMaxParts, maxPartSize: DefaultMaxPartSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	boundary, err := Boundary(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []Part
+	r := multipart.NewReader(bytes.NewReader(data), boundary)
+	for {
+		p, err := r.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			// The reader reports a body lacking the boundary as empty; only a closing delimiter is.
+			if len(parts) == 0 && !bytes.Contains(data, []byte("--"+boundary)) {
+				return nil, fmt.Errorf("invalid multipart body: boundary %q not found", boundary)
+			}
+			return parts, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid multipart body: %w", err)
+		}
+		if len(parts) == o.maxParts {
+			return nil, fmt.Errorf("%w: more than %d parts", ErrTooLarge, o.maxParts)
+		}
+
+		content, err := io.ReadAll(io.LimitReader(p, o.maxPartSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid multipart body: %w", err)
+		}
+		if int64(len(content)) > o.maxPartSize {
+			return nil, fmt.Errorf("%w: part %d larger than %d bytes", ErrTooLarge, len(parts), o.maxPartSize)
+		}
+		parts = append(parts, Part{
+			Name:        p.FormName(),
+			Filename:    p.FileName(),
+			ContentType: p.Header.Get("Content-Type"),
+			Header:      p.Header,
+			Data:        content,
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package payload_test
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/payload"
+)
+
+func TestClassify(t *testing.T) {
+	// 511 ASCII bytes followed by a two-byte rune straddling the 512-byte sniffing window.
+	straddling := strings.Repeat("a", 511) + "é and more text"
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+	tests := []struct {
+		name        string
+		contentType string
+		data        string
+		want        payload.Kind
+	}{
+		{name: "empty", contentType: "application/json", data: "", want: payload.Empty},
+		{name: "whitespace", contentType: "", data: " \n\t", want: payload.Empty},
+		{name: "JSON", contentType: "application/json", data: `{"jsonrpc":"2.0"}`, want: payload.JSON},
+		{name: "JSON with parameters", contentType: "Application/JSON; charset=utf-8", data: `[1]`, want: payload.JSON},
+		{name: "JSON scalar", contentType: "application/json", data: `"hello"`, want: payload.JSON},
+		{name: "structured JSON", contentType: "application/problem+json", data: `{"title":"x"}`, want: payload.JSON},
+		{name: "invalid JSON text", contentType: "application/json", data: `{"a":`, want: payload.Text},
+		{name: "invalid JSON binary", contentType: "application/json", data: "\x00\x01\x02", want: payload.Binary},
+		{name: "multipart", contentType: "multipart/form-data; boundary=x", data: "--x--", want: payload.Multipart},
+		{name: "multipart mixed", contentType: "multipart/mixed; boundary=x", data: "--x--", want: payload.Multipart},
+		{name: "form", contentType: "application/x-www-form-urlencoded", data: "a=1&b=2", want: payload.Form},
+		{name: "text", contentType: "text/plain", data: "hello", want: payload.Text},
+		{name: "text that is JSON", contentType: "text/plain", data: `{"a":1}`, want: payload.Text},
+		{name: "text with a NUL", contentType: "text/plain", data: "hel\x00lo", want: payload.Binary},
+		{name: "invalid UTF-8 text", contentType: "text/csv", data: "a,\xff\xfe", want: payload.Binary},
+		{name: "XML", contentType: "application/xml", data: "<a/>", want: payload.Text},
+		{name: "XML suffix", contentType: "application/atom+xml", data: "<feed/>", want: payload.Text},
+		{name: "JavaScript", contentType: "application/javascript", data: "let a = 1", want: payload.Text},
+		{name: "YAML", contentType: "application/yaml", data: "a: 1", want: payload.Text},
+		{name: "declared binary", contentType: "image/png", data: "looks like text", want: payload.Binary},
+		{
+			name:        "octet stream sniffed as JSON",
+			contentType: "application/octet-stream",
+			data:        ` {"a":1} `,
+			want:        payload.JSON,
+		},
+		{
+			name:        "octet stream sniffed as binary",
+			contentType: "application/octet-stream",
+			data:        string(png),
+			want:        payload.Binary,
+		},
+		{name: "undeclared JSON", data: "\n[1,2]\n", want: payload.JSON},
+		{name: "undeclared JSON scalar", data: "42", want: payload.Text},
+		{name: "undeclared text", data: "hello world", want: payload.Text},
+		{name: "undeclared broken JSON", data: `{"a":`, want: payload.Text},
+		{name: "undeclared binary", data: string(png), want: payload.Binary},
+		{name: "undeclared PDF", data: "%PDF-1.7\n", want: payload.Binary},
+		{name: "unparseable content type", contentType: "text/plain; charset", data: `{"a":1}`, want: payload.JSON},
+		{name: "rune at the sniffing window", contentType: "text/plain", data: straddling, want: payload.Text},
+		{
+			name:        "invalid UTF-8 past the window",
+			contentType: "text/plain",
+			data:        strings.Repeat("a", 600) + "\xff",
+			want:        payload.Text,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := payload.Classify(tt.contentType, []byte(tt.data)); got != tt.want {
+				t.Errorf("Classify(%q, %.20q) = %s, want %s", tt.contentType, tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsJSONType(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		want      bool
+	}{
+		{mediaType: "application/json", want: true},
+		{mediaType: "APPLICATION/JSON", want: true},
+		{mediaType: "application/vnd.api+json", want: true},
+		{mediaType: "application/json-seq", want: false},
+		{mediaType: "text/json5", want: false},
+		{mediaType: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := payload.IsJSONType(tt.mediaType); got != tt.want {
+			t.Errorf("IsJSONType(%q) = %t, want %t", tt.mediaType, got, tt.want)
+		}
+	}
+}
+
+func TestBoundary(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+		wantErr     string
+	}{
+		{contentType: "multipart/form-data; boundary=abc123", want: "abc123"},
+		{contentType: `multipart/mixed; boundary="with space"`, want: "with space"},
+		{contentType: "application/json", wantErr: `content type "application/json" is not multipart`},
+		{contentType: "multipart/form-data", wantErr: "has no boundary"},
+		{contentType: "multipart/form-data; boundary=", wantErr: "invalid content type"},
+		{contentType: "", wantErr: "invalid content type"},
+	}
+	for _, tt := range tests {
+		got, err := payload.Boundary(tt.contentType)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Boundary(%q) error = %v, want %q", tt.contentType, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Boundary(%q) = %q, %v; want %q", tt.contentType, got, err, tt.want)
+		}
+	}
+}
+
+// form builds a multipart/form-data body with a text field and a file.
+func form(t *testing.T, fileData []byte) (string, []byte) {
+	t.Helper()
+
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	if err := w.WriteField("comment", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", `form-data; name="upload"; filename="report.pdf"`)
+	h.Set("Content-Type", "application/pdf")
+	h.Set("Content-Transfer-Encoding", "base64")
+	fw, err := w.CreatePart(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write(fileData); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return w.FormDataContentType(), b.Bytes()
+}
+
+func TestParseMultipart(t *testing.T) {
+	contentType, body := form(t, []byte("JVBERi0xLjcK"))
+
+	parts, err := payload.ParseMultipart(contentType, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("ParseMultipart returned %d parts, want 2", len(parts))
+	}
+	if p := parts[0]; p.Name != "comment" || p.Filename != "" || p.ContentType != "" || string(p.Data) != "hello" {
+		t.Errorf("part 0 = %+v, want the comment field", p)
+	}
+	p := parts[1]
+	if p.Name != "upload" || p.Filename != "report.pdf" || p.ContentType != "application/pdf" {
+		t.Errorf("part 1 = %+v, want the uploaded file", p)
+	}
+	// Data is raw: the transfer encoding is left to the caller.
+	if string(p.Data) != "JVBERi0xLjcK" || p.Header.Get("Content-Transfer-Encoding") != "base64" {
+		t.Errorf("part 1 data = %q with headers %v, want the raw base64", p.Data, p.Header)
+	}
+
+	parts, err = payload.ParseMultipart("multipart/mixed; boundary=x", []byte("--x--\r\n"))
+	if err != nil || len(parts) != 0 {
+		t.Errorf("ParseMultipart of an empty body = %v, %v", parts, err)
+	}
+}
+
+func TestParseMultipartErrors(t *testing.T) {
+	contentType, body := form(t, bytes.Repeat([]byte("a"), 100))
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		opts        []payload.MultipartOption
+		want        string
+		tooLarge    bool
+	}{
+		{name: "not multipart", contentType: "application/json", body: body, want: "is not multipart"},
+		{
+			name:        "wrong boundary",
+			contentType: "multipart/form-data; boundary=other",
+			body:        body,
+			want:        "invalid multipart body",
+		},
+		{
+			name:        "truncated",
+			contentType: contentType,
+			body:        body[:len(body)-20],
+			want:        "invalid multipart body",
+		},
+		{
+			name:        "too many parts",
+			contentType: contentType,
+			body:        body,
+			opts:        []payload.MultipartOption{payload.WithMaxParts(1)},
+			want:        "more than 1 parts",
+			tooLarge:    true,
+		},
+		{
+			name:        "part too large",
+			contentType: contentType,
+			body:        body,
+			opts:        []payload.MultipartOption{payload.WithMaxPartSize(99)},
+			want:        "part 1 larger than 99 bytes",
+			tooLarge:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := payload.ParseMultipart(tt.contentType, tt.body, tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseMultipart error = %v, want %q", err, tt.want)
+			}
+			if errors.Is(err, payload.ErrTooLarge) != tt.tooLarge {
+				t.Errorf("ParseMultipart error = %v, want ErrTooLarge %t", err, tt.tooLarge)
+			}
+		})
+	}
+
+	// Parts exactly at the limit are accepted.
+	_, err := payload.ParseMultipart(contentType, body, payload.WithMaxPartSize(100), payload.WithMaxParts(2))
+	if err != nil {
+		t.Errorf("ParseMultipart at the limits: %v", err)
+	}
+}
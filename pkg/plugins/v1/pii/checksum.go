@@ -0,0 +1,90 @@
+package pii
+
+import (
+	"strconv"
+	"strings"
+)
+
+// digitsOnly strips every non-digit character from s.
+func digitsOnly(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// luhnValid reports whether the digit string passes the Luhn (mod 10) checksum.
+func luhnValid(digits string) bool {
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+// ssnValid rejects SSNs that are never issued: area 000, 666 or 900-999, group 00, serial 0000.
+func ssnValid(v string) bool {
+	parts := strings.Split(v, "-")
+	if len(parts) != 3 {
+		return false
+	}
+
+	area, err := strconv.Atoi(parts[0])
+	if err != nil || area == 0 || area == 666 || area >= 900 {
+		return false
+	}
+
+	return parts[1] != "00" && parts[2] != "0000"
+}
+
+// bsnValid applies the Dutch "11-proef" to a nine digit BSN.
+func bsnValid(v string) bool {
+	if len(v) != 9 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < 8; i++ {
+		sum += int(v[i]-'0') * (9 - i)
+	}
+	sum -= int(v[8] - '0')
+
+	return sum%11 == 0 && sum != 0
+}
+
+// dniLetters maps the DNI number modulo 23 to its control letter.
+const dniLetters = "TRWAGMYFPDXBNJZSQVHLCKE"
+
+// dniValid checks the control letter of a Spanish DNI.
+func dniValid(v string) bool {
+	v = strings.ReplaceAll(v, "-", "")
+	if len(v) != 9 {
+		return false
+	}
+
+	n, err := strconv.Atoi(v[:8])
+	if err != nil {
+		return false
+	}
+
+	return strings.ToUpper(v[8:]) == string(dniLetters[n%23])
+}
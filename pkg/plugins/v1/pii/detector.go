@@ -0,0 +1,155 @@
+// Package pii provides building blocks for data-protection plugins: detectors that locate
+// personally identifiable information in text, masking strategies that replace it, and a
+// Redactor that applies both to plain text, arbitrary JSON bodies and MCP message content.
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Match is a single occurrence of PII within a string.
+type Match struct {
+	// Detector is the name of the detector that produced the match.
+	Detector string
+
+	// Start and End are byte offsets of the match within the inspected string.
+	Start int
+	End   int
+
+	// Value is the matched text.
+	Value string
+}
+
+// Detector locates PII of a single kind within text.
+type Detector interface {
+	// Name identifies the kind of PII found by the detector (e.g. "email").
+	Name() string
+
+	// Find returns all non-overlapping matches in s, ordered by position.
+	Find(s string) []Match
+}
+
+// regexDetector matches a pattern and, optionally, validates each candidate.
+type regexDetector struct {
+	name     string
+	re       *regexp.Regexp
+	validate func(string) bool
+}
+
+func (d *regexDetector) Name() string {
+	return d.name
+}
+
+func (d *regexDetector) Find(s string) []Match {
+	locs := d.re.FindAllStringIndex(s, -1)
+	if len(locs) == 0 {
+		return nil
+	}
+
+	matches := make([]Match, 0, len(locs))
+	for _, loc := range locs {
+		v := s[loc[0]:loc[1]]
+		if d.validate != nil && !d.validate(v) {
+			continue
+		}
+		matches = append(matches, Match{Detector: d.name, Start: loc[0], End: loc[1], Value: v})
+	}
+
+	return matches
+}
+
+var (
+	emailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	creditCardPattern = regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`)
+	usSSNPattern      = regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)
+	nlBSNPattern      = regexp.MustCompile(`\b\d{9}\b`)
+	esDNIPattern      = regexp.MustCompile(`\b\d{8}-?[A-Za-z]\b`)
+)
+
+// Email returns a detector for email addresses.
+func Email() Detector {
+	return &regexDetector{name: "email", re: emailPattern}
+}
+
+// CreditCard returns a detector for payment card numbers (13 to 19 digits, optionally separated
+// by spaces or dashes). Candidates are only reported when they pass the Luhn checksum.
+func CreditCard() Detector {
+	return &regexDetector{name: "credit_card", re: creditCardPattern, validate: func(v string) bool {
+		return luhnValid(digitsOnly(v))
+	}}
+}
+
+// USSocialSecurityNumber returns a detector for US Social Security Numbers in AAA-GG-SSSS form.
+// Numbers with area, group or serial values that are never issued are not reported.
+func USSocialSecurityNumber() Detector {
+	return &regexDetector{name: "us_ssn", re: usSSNPattern, validate: ssnValid}
+}
+
+// DutchBSN returns a detector for Dutch citizen service numbers (BSN), validated with the 11-check.
+func DutchBSN() Detector {
+	return &regexDetector{name: "nl_bsn", re: nlBSNPattern, validate: bsnValid}
+}
+
+// SpanishDNI returns a detector for Spanish national identity numbers (DNI), validated with the
+// control letter.
+func SpanishDNI() Detector {
+	return &regexDetector{name: "es_dni", re: esDNIPattern, validate: dniValid}
+}
+
+// Regex returns a detector that reports every match of pattern under the given name.
+// The pattern uses RE2 syntax.
+func Regex(name string, pattern string) (Detector, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("detector name is required")
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern for detector %q: %w", name, err)
+	}
+
+	return &regexDetector{name: name, re: re}, nil
+}
+
+// Defaults returns the built-in detectors.
+func Defaults() []Detector {
+	return []Detector{
+		Email(),
+		CreditCard(),
+		USSocialSecurityNumber(),
+		DutchBSN(),
+		SpanishDNI(),
+	}
+}
+
+// findAll runs every detector over s and returns the matches ordered by position.
+// Where matches overlap, the earliest (and then longest) wins.
+func findAll(detectors []Detector, s string) []Match {
+	var all []Match
+	for _, d := range detectors {
+		all = append(all, d.Find(s)...)
+	}
+	if len(all) < 2 {
+		return all
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].Start != all[j].Start {
+			return all[i].Start < all[j].Start
+		}
+		return all[i].End > all[j].End
+	})
+
+	out := all[:1]
+	for _, m := range all[1:] {
+		if m.Start < out[len(out)-1].End {
+			continue
+		}
+		out = append(out, m)
+	}
+
+	return out
+}
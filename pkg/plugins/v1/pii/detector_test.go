@@ -0,0 +1,116 @@
+package pii_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/pii"
+)
+
+func TestDetectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		detector pii.Detector
+		text     string
+		want     []string
+	}{
+		{
+			name:     "email",
+			detector: pii.Email(),
+			text:     "mail jane.doe+x@mail.example.com or bob@example.org, not bob@localhost",
+			want:     []string{"jane.doe+x@mail.example.com", "bob@example.org"},
+		},
+		{
+			name:     "credit card passing luhn",
+			detector: pii.CreditCard(),
+			text:     "cards 4111 1111 1111 1111, 5500-0000-0000-0004 and 4111111111111112",
+			want:     []string{"4111 1111 1111 1111", "5500-0000-0000-0004"},
+		},
+		{
+			name:     "credit card too short",
+			detector: pii.CreditCard(),
+			text:     "order 123456789012",
+		},
+		{
+			name:     "us ssn",
+			detector: pii.USSocialSecurityNumber(),
+			text:     "ssn 123-45-6789, 000-12-3456, 666-12-3456, 900-12-3456, 123-00-4567, 123-45-0000",
+			want:     []string{"123-45-6789"},
+		},
+		{
+			name:     "dutch bsn",
+			detector: pii.DutchBSN(),
+			text:     "bsn 111222333 and 111222334 and 000000000",
+			want:     []string{"111222333"},
+		},
+		{
+			name:     "spanish dni",
+			detector: pii.SpanishDNI(),
+			text:     "dni 12345678Z, 12345678-z and 12345678A",
+			want:     []string{"12345678Z", "12345678-z"},
+		},
+		{
+			name:     "no matches",
+			detector: pii.Email(),
+			text:     "nothing to see",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, m := range tt.detector.Find(tt.text) {
+				if m.Detector != tt.detector.Name() {
+					t.Errorf("match detector = %q, want %q", m.Detector, tt.detector.Name())
+				}
+				if tt.text[m.Start:m.End] != m.Value {
+					t.Errorf("match offsets [%d:%d] do not cover %q", m.Start, m.End, m.Value)
+				}
+				got = append(got, m.Value)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegex(t *testing.T) {
+	tests := []struct {
+		name    string
+		detName string
+		pattern string
+		wantErr bool
+	}{
+		{name: "valid", detName: "employee_id", pattern: `EMP-\d{6}`},
+		{name: "blank name", detName: " ", pattern: `x`, wantErr: true},
+		{name: "invalid pattern", detName: "broken", pattern: `(`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := pii.Regex(tt.detName, tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Regex error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if d.Name() != tt.detName {
+				t.Errorf("Name = %q, want %q", d.Name(), tt.detName)
+			}
+			if got := d.Find("id EMP-123456 and EMP-12"); len(got) != 1 || got[0].Value != "EMP-123456" {
+				t.Errorf("Find = %v, want EMP-123456", got)
+			}
+		})
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	var names []string
+	for _, d := range pii.Defaults() {
+		names = append(names, d.Name())
+	}
+	want := []string{"email", "credit_card", "us_ssn", "nl_bsn", "es_dni"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Defaults = %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,60 @@
+package pii
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode/utf8"
+)
+
+// Masker produces the replacement text for a match.
+type Masker interface {
+	Mask(m Match) string
+}
+
+// MaskerFunc adapts a function to the Masker interface.
+type MaskerFunc func(m Match) string
+
+// Mask calls f(m).
+func (f MaskerFunc) Mask(m Match) string {
+	return f(m)
+}
+
+// Redact replaces every match with the fixed replacement text.
+func Redact(replacement string) Masker {
+	return MaskerFunc(func(Match) string {
+		return replacement
+	})
+}
+
+// Label replaces every match with the upper-cased detector name in brackets, e.g. "[EMAIL]".
+func Label() Masker {
+	return MaskerFunc(func(m Match) string {
+		return "[" + strings.ToUpper(m.Detector) + "]"
+	})
+}
+
+// Partial replaces every character of a match with maskChar except for the last keep characters,
+// e.g. "************1111" for a card number with keep=4. A negative keep is treated as 0, and
+// matches no longer than keep are masked entirely.
+func Partial(maskChar rune, keep int) Masker {
+	keep = max(keep, 0)
+	return MaskerFunc(func(m Match) string {
+		n := utf8.RuneCountInString(m.Value)
+		if keep >= n {
+			return strings.Repeat(string(maskChar), n)
+		}
+
+		runes := []rune(m.Value)
+		return strings.Repeat(string(maskChar), n-keep) + string(runes[n-keep:])
+	})
+}
+
+// Hash replaces every match with a truncated, salted SHA-256 digest so equal values remain
+// correlatable without being recoverable. The output has the form "<detector>:<hex>".
+func Hash(salt string) Masker {
+	return MaskerFunc(func(m Match) string {
+		sum := sha256.Sum256([]byte(salt + m.Value))
+		return m.Detector + ":" + hex.EncodeToString(sum[:8])
+	})
+}
@@ -0,0 +1,96 @@
+package pii_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/pii"
+)
+
+func TestPartial(t *testing.T) {
+	tests := []struct {
+		name  string
+		keep  int
+		value string
+		want  string
+	}{
+		{name: "keeps the last characters", keep: 4, value: "4111111111111111", want: "************1111"},
+		{name: "negative keep masks everything", keep: -3, value: "secret", want: "******"},
+		{name: "zero keep masks everything", keep: 0, value: "secret", want: "******"},
+		{name: "keep equal to length masks everything", keep: 6, value: "secret", want: "******"},
+		{name: "keep beyond length masks everything", keep: 10, value: "secret", want: "******"},
+		{name: "multibyte characters", keep: 2, value: "日本語テキスト", want: "*****スト"},
+		{name: "multibyte fully masked", keep: 5, value: "ñoño", want: "****"},
+		{name: "empty match", keep: 2, value: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pii.Partial('*', tt.keep).Mask(pii.Match{Detector: "test", Value: tt.value})
+			if got != tt.want {
+				t.Errorf("Mask(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskers(t *testing.T) {
+	m := pii.Match{Detector: "email", Value: "jane@example.com"}
+	hashed := pii.Hash("salt").Mask(m)
+
+	tests := []struct {
+		name   string
+		masker pii.Masker
+		check  func(t *testing.T, got string)
+	}{
+		{
+			name:   "redact",
+			masker: pii.Redact("<removed>"),
+			check:  wantMask("<removed>"),
+		},
+		{
+			name:   "label",
+			masker: pii.Label(),
+			check:  wantMask("[EMAIL]"),
+		},
+		{
+			name:   "masker func",
+			masker: pii.MaskerFunc(func(m pii.Match) string { return strings.ToUpper(m.Value) }),
+			check:  wantMask("JANE@EXAMPLE.COM"),
+		},
+		{
+			name:   "hash is prefixed and stable",
+			masker: pii.Hash("salt"),
+			check: func(t *testing.T, got string) {
+				if !strings.HasPrefix(got, "email:") || len(got) != len("email:")+16 {
+					t.Errorf("Mask = %q, want email: followed by 16 hex digits", got)
+				}
+				if got != hashed {
+					t.Errorf("Mask = %q, want the earlier digest %q", got, hashed)
+				}
+			},
+		},
+		{
+			name:   "hash depends on the salt",
+			masker: pii.Hash("pepper"),
+			check: func(t *testing.T, got string) {
+				if got == hashed {
+					t.Errorf("Mask = %q for two different salts", got)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, tt.masker.Mask(m))
+		})
+	}
+}
+
+func wantMask(want string) func(t *testing.T, got string) {
+	return func(t *testing.T, got string) {
+		t.Helper()
+		if got != want {
+			t.Errorf("Mask = %q, want %q", got, want)
+		}
+	}
+}
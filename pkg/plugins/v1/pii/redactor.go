@@ -0,0 +1,228 @@
+package pii
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+// Finding summarises the PII masked by a Redactor, without the sensitive values themselves.
+type Finding struct {
+	Detector string
+	Count    int
+}
+
+// Redactor finds PII with a set of detectors and masks it.
+type Redactor struct {
+	detectors []Detector
+	defMasker Masker
+	maskers   map[string]Masker
+}
+
+// Option configures a Redactor.
+type Option func(*Redactor) error
+
+// WithDetectors replaces the detectors used by the Redactor (defaults to Defaults()).
+func WithDetectors(detectors ...Detector) Option {
+	return func(r *Redactor) error {
+		if len(detectors) == 0 {
+			return fmt.Errorf("at least one detector is required")
+		}
+		r.detectors = detectors
+		return nil
+	}
+}
+
+// WithMasker sets the masker used for detectors without a specific masker (defaults to Label()).
+func WithMasker(m Masker) Option {
+	return func(r *Redactor) error {
+		if m == nil {
+			return fmt.Errorf("masker cannot be nil")
+		}
+		r.defMasker = m
+		return nil
+	}
+}
+
+// WithDetectorMasker sets the masker used for matches produced by the named detector.
+func WithDetectorMasker(detector string, m Masker) Option {
+	return func(r *Redactor) error {
+		if m == nil {
+			return fmt.Errorf("masker for detector %q cannot be nil", detector)
+		}
+		r.maskers[detector] = m
+		return nil
+	}
+}
+
+// NewRedactor returns a Redactor configured by opts.
+func NewRedactor(opts ...Option) (*Redactor, error) {
+	r := &Redactor{
+		detectors: Defaults(),
+		defMasker: Label(),
+		maskers:   map[string]Masker{},
+	}
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Find returns every PII match in s without modifying it.
+func (r *Redactor) Find(s string) []Match {
+	return findAll(r.detectors, s)
+}
+
+// MaskString masks every PII match in s and returns the result with a summary of what was masked.
+func (r *Redactor) MaskString(s string) (string, []Finding) {
+	counts := map[string]int{}
+	out := r.mask(s, counts)
+
+	return out, findings(counts)
+}
+
+// MaskJSON masks PII in every string value of a JSON document (object keys are left untouched).
+// If nothing is masked the original body is returned.
+func (r *Redactor) MaskJSON(body []byte) ([]byte, []Finding, error) {
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return body, nil, fmt.Errorf("failed to decode JSON body: %w", err)
+	}
+
+	counts := map[string]int{}
+	doc = r.maskValue(doc, counts)
+	if len(counts) == 0 {
+		return body, nil, nil
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return body, nil, fmt.Errorf("failed to encode JSON body: %w", err)
+	}
+
+	return out, findings(counts), nil
+}
+
+// MaskMCP masks PII in the user-visible content of MCP messages (tool arguments, content blocks,
+// resource text and structured content), leaving protocol fields untouched. See mcp.RewriteText.
+// If nothing is masked the original body is returned.
+func (r *Redactor) MaskMCP(body []byte) ([]byte, []Finding, error) {
+	counts := map[string]int{}
+	out, _, err := mcp.RewriteText(body, func(s string) string {
+		return r.mask(s, counts)
+	})
+	if err != nil {
+		return body, nil, err
+	}
+
+	return out, findings(counts), nil
+}
+
+// HandleRequest masks PII in the MCP content of req and returns a continuing response carrying
+// the modified request. It is suitable for direct use from a plugin's HandleRequest.
+// Bodies that are not MCP messages are passed through unchanged.
+func (r *Redactor) HandleRequest(req *mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, []Finding) {
+	body, found, err := r.MaskMCP(req.GetBody())
+	if err != nil || len(found) == 0 {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}, nil
+	}
+
+	modified := &mcpdpluginsv1.HTTPRequest{
+		Method:     req.GetMethod(),
+		Url:        req.GetUrl(),
+		Path:       req.GetPath(),
+		Headers:    req.GetHeaders(),
+		Body:       body,
+		RemoteAddr: req.GetRemoteAddr(),
+		RequestUri: req.GetRequestUri(),
+	}
+
+	return &mcpdpluginsv1.HTTPResponse{Continue: true, ModifiedRequest: modified}, found
+}
+
+// HandleResponse masks PII in the MCP content of resp and returns the modified response.
+// Bodies that are not MCP messages are passed through unchanged.
+func (r *Redactor) HandleResponse(resp *mcpdpluginsv1.HTTPResponse) (*mcpdpluginsv1.HTTPResponse, []Finding) {
+	body, found, err := r.MaskMCP(resp.GetBody())
+	if err != nil {
+		found = nil
+	}
+
+	return &mcpdpluginsv1.HTTPResponse{
+		Continue:   true,
+		StatusCode: resp.GetStatusCode(),
+		Headers:    resp.GetHeaders(),
+		Body:       body,
+	}, found
+}
+
+func (r *Redactor) mask(s string, counts map[string]int) string {
+	matches := findAll(r.detectors, s)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	last := 0
+	for _, m := range matches {
+		b.WriteString(s[last:m.Start])
+		b.WriteString(r.maskerFor(m.Detector).Mask(m))
+		last = m.End
+		counts[m.Detector]++
+	}
+	b.WriteString(s[last:])
+
+	return b.String()
+}
+
+func (r *Redactor) maskValue(v any, counts map[string]int) any {
+	switch t := v.(type) {
+	case string:
+		return r.mask(t, counts)
+	case map[string]any:
+		for k, child := range t {
+			t[k] = r.maskValue(child, counts)
+		}
+		return t
+	case []any:
+		for i, child := range t {
+			t[i] = r.maskValue(child, counts)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+func (r *Redactor) maskerFor(detector string) Masker {
+	if m, ok := r.maskers[detector]; ok {
+		return m
+	}
+
+	return r.defMasker
+}
+
+func findings(counts map[string]int) []Finding {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	out := make([]Finding, 0, len(counts))
+	for d, n := range counts {
+		out = append(out, Finding{Detector: d, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Detector < out[j].Detector })
+
+	return out
+}
@@ -0,0 +1,206 @@
+package pii_test
+
+import (
+	"reflect"
+	"testing"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/pii"
+)
+
+func TestNewRedactorOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  pii.Option
+	}{
+		{name: "no detectors", opt: pii.WithDetectors()},
+		{name: "nil masker", opt: pii.WithMasker(nil)},
+		{name: "nil detector masker", opt: pii.WithDetectorMasker("email", nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := pii.NewRedactor(tt.opt); err == nil {
+				t.Error("NewRedactor accepted the option")
+			}
+		})
+	}
+}
+
+func TestMaskString(t *testing.T) {
+	word, err := pii.Regex("word", `jane\S*`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		opts         []pii.Option
+		text         string
+		want         string
+		wantFindings []pii.Finding
+	}{
+		{
+			name: "labels by default",
+			text: "mail jane@example.com, ssn 123-45-6789",
+			want: "mail [EMAIL], ssn [US_SSN]",
+			wantFindings: []pii.Finding{
+				{Detector: "email", Count: 1},
+				{Detector: "us_ssn", Count: 1},
+			},
+		},
+		{
+			name: "detector masker overrides the default",
+			opts: []pii.Option{
+				pii.WithMasker(pii.Redact("x")),
+				pii.WithDetectorMasker("credit_card", pii.Partial('*', 4)),
+			},
+			text: "card 4111111111111111 for a@example.com and b@example.com",
+			want: "card ************1111 for x and x",
+			wantFindings: []pii.Finding{
+				{Detector: "credit_card", Count: 1},
+				{Detector: "email", Count: 2},
+			},
+		},
+		{
+			name:         "overlapping matches keep the earliest and longest",
+			opts:         []pii.Option{pii.WithDetectors(pii.Email(), word)},
+			text:         "to jane@example.com!",
+			want:         "to [WORD]",
+			wantFindings: []pii.Finding{{Detector: "word", Count: 1}},
+		},
+		{
+			name: "nothing found",
+			text: "nothing to see",
+			want: "nothing to see",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := pii.NewRedactor(tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, findings := r.MaskString(tt.text)
+			if got != tt.want {
+				t.Errorf("MaskString = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(findings, tt.wantFindings) {
+				t.Errorf("findings = %v, want %v", findings, tt.wantFindings)
+			}
+		})
+	}
+}
+
+func TestMaskJSON(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		want         string
+		wantFindings int
+		wantErr      bool
+	}{
+		{
+			name:         "nested string values",
+			body:         `{"jane@example.com":{"to":["bob@example.com",1.50,true,null]}}`,
+			want:         `{"jane@example.com":{"to":["[EMAIL]",1.50,true,null]}}`,
+			wantFindings: 1,
+		},
+		{
+			name: "unchanged body returned as is",
+			body: `{ "n": 1 }`,
+			want: `{ "n": 1 }`,
+		},
+		{
+			name:    "invalid JSON",
+			body:    `{"to":`,
+			want:    `{"to":`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := pii.NewRedactor()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, findings, err := r.MaskJSON([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MaskJSON error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MaskJSON = %s, want %s", got, tt.want)
+			}
+			if len(findings) != tt.wantFindings {
+				t.Errorf("findings = %v, want %d", findings, tt.wantFindings)
+			}
+		})
+	}
+}
+
+func TestRedactorHandlers(t *testing.T) {
+	const (
+		call = `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"jane@example.com",` +
+			`"arguments":{"to":"jane@example.com"}}}`
+		masked = `{"id":1,"jsonrpc":"2.0","method":"tools/call","params":{"arguments":{"to":"[EMAIL]"},` +
+			`"name":"jane@example.com"}}`
+		result = `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"ssn 123-45-6789"}]}}`
+	)
+
+	r, err := pii.NewRedactor()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("request", func(t *testing.T) {
+		req := &mcpdpluginsv1.HTTPRequest{
+			Method:  "POST",
+			Path:    "/mcp",
+			Headers: map[string]string{"X-Id": "1"},
+			Body:    []byte(call),
+		}
+		resp, findings := r.HandleRequest(req)
+		if !resp.GetContinue() {
+			t.Fatal("HandleRequest stopped the chain")
+		}
+		mod := resp.GetModifiedRequest()
+		if got := string(mod.GetBody()); got != masked {
+			t.Errorf("modified body = %s, want %s", got, masked)
+		}
+		if mod.GetPath() != "/mcp" || mod.GetHeaders()["X-Id"] != "1" {
+			t.Errorf("modified request lost its fields: %v", mod)
+		}
+		if want := []pii.Finding{{Detector: "email", Count: 1}}; !reflect.DeepEqual(findings, want) {
+			t.Errorf("findings = %v, want %v", findings, want)
+		}
+	})
+
+	t.Run("request without PII or MCP", func(t *testing.T) {
+		for _, body := range []string{`{"jsonrpc":"2.0","id":1,"method":"ping"}`, `not json`, `{"a":1}`} {
+			resp, findings := r.HandleRequest(&mcpdpluginsv1.HTTPRequest{Body: []byte(body)})
+			if !resp.GetContinue() || resp.GetModifiedRequest() != nil || findings != nil {
+				t.Errorf("HandleRequest(%s) = %v, %v; want a plain continue", body, resp, findings)
+			}
+		}
+	})
+
+	t.Run("response", func(t *testing.T) {
+		resp, findings := r.HandleResponse(&mcpdpluginsv1.HTTPResponse{StatusCode: 200, Body: []byte(result)})
+		want := `{"id":1,"jsonrpc":"2.0","result":{"content":[{"text":"ssn [US_SSN]","type":"text"}]}}`
+		if got := string(resp.GetBody()); got != want {
+			t.Errorf("body = %s, want %s", got, want)
+		}
+		if !resp.GetContinue() || resp.GetStatusCode() != 200 {
+			t.Errorf("HandleResponse = continue %v, status %d", resp.GetContinue(), resp.GetStatusCode())
+		}
+		if len(findings) != 1 {
+			t.Errorf("findings = %v, want one", findings)
+		}
+	})
+
+	t.Run("response that is not MCP", func(t *testing.T) {
+		resp, findings := r.HandleResponse(&mcpdpluginsv1.HTTPResponse{StatusCode: 502, Body: []byte("bad gateway")})
+		if string(resp.GetBody()) != "bad gateway" || resp.GetStatusCode() != 502 || findings != nil {
+			t.Errorf("HandleResponse = %v, %v; want the response unchanged", resp, findings)
+		}
+	})
+}
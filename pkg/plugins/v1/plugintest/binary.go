@@ -0,0 +1,108 @@
+package plugintest
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startTimeout bounds how long a plugin binary may take to create its socket.
+const startTimeout = 10 * time.Second
+
+// RunConformanceBinary starts the plugin executable at path as mcpd does (with --address and
+// --network unix, followed by args) and runs RunConformance against it over gRPC. The plugin may
+// be written in any language. After the suite, the process is sent SIGTERM and must exit within
+// a few seconds.
+//
+//	func TestReleaseBinary(t *testing.T) {
+//	    plugintest.RunConformanceBinary(t, "./dist/my-plugin", nil)
+//	}
+func RunConformanceBinary(t *testing.T, path string, args []string, opts ...ConformanceOption) {
+	t.Helper()
+
+	target := StartBinary(t, path, args...)
+	RunConformance(t, dial(t, target), opts...)
+}
+
+// StartBinary starts the plugin executable at path listening on a temporary unix socket, waits for
+// the socket to appear, and returns the gRPC target to dial. The plugin's output is logged through
+// t when the test fails, and the process is stopped with SIGTERM at cleanup.
+func StartBinary(t *testing.T, path string, args ...string) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "plugintest")
+	if err != nil {
+		t.Fatalf("failed to create socket directory: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "plugin.sock")
+
+	var out lockedBuffer
+	cmd := exec.Command(path, append([]string{"--address", sock, "--network", "unix"}, args...)...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start plugin %s: %v", path, err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	t.Cleanup(func() {
+		_ = cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case err := <-exited:
+			if err != nil {
+				t.Errorf("plugin exited with error after SIGTERM: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			_ = cmd.Process.Kill()
+			<-exited
+			t.Error("plugin did not exit within 5s of SIGTERM")
+		}
+		if t.Failed() {
+			t.Logf("plugin output:\n%s", out.String())
+		}
+	})
+
+	deadline := time.Now().Add(startTimeout)
+	for {
+		if _, err := os.Stat(sock); err == nil {
+			return "unix://" + sock
+		}
+		select {
+		case err := <-exited:
+			exited <- err
+			t.Fatalf("plugin exited before listening: %v\n%s", err, out.String())
+		case <-time.After(20 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("plugin did not create %s within %s", sock, startTimeout)
+		}
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writes from the process's stdout and stderr.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
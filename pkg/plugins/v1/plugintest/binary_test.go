@@ -0,0 +1,122 @@
+package plugintest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+var (
+	buildOnce   sync.Once
+	builtPlugin string
+	buildErr    error
+	buildOutput []byte
+)
+
+// pluginBinary builds testdata/plugin once per test process and returns the binary's path.
+func pluginBinary(t *testing.T) string {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("builds a plugin binary")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	buildOnce.Do(func() {
+		var dir string
+		if dir, buildErr = os.MkdirTemp("", "plugintest-bin"); buildErr != nil {
+			return
+		}
+		builtPlugin = filepath.Join(dir, "plugin")
+		buildOutput, buildErr = exec.Command(goTool, "build", "-o", builtPlugin, "./testdata/plugin").CombinedOutput()
+	})
+	if buildErr != nil {
+		t.Fatalf("building testdata/plugin: %v\n%s", buildErr, buildOutput)
+	}
+
+	return builtPlugin
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if builtPlugin != "" {
+		_ = os.RemoveAll(filepath.Dir(builtPlugin))
+	}
+	os.Exit(code)
+}
+
+func TestRunConformanceBinary(t *testing.T) {
+	bin := pluginBinary(t)
+
+	t.Run("defaults", func(t *testing.T) {
+		RunConformanceBinary(t, bin, nil)
+	})
+
+	t.Run("args", func(t *testing.T) {
+		// --deny stops the chain with 403, which conforms.
+		RunConformanceBinary(t, bin, []string{"--deny"})
+	})
+}
+
+func TestStartBinaryArgs(t *testing.T) {
+	client := dial(t, StartBinary(t, pluginBinary(t), "--deny"))
+
+	resp, err := client.HandleRequest(callContext(t), &mcpdpluginsv1.HTTPRequest{Method: "POST", Path: "/mcp"})
+	if err != nil {
+		t.Fatalf("HandleRequest failed: %v", err)
+	}
+	if resp.GetContinue() || resp.GetStatusCode() != 403 {
+		t.Errorf("HandleRequest = continue %v, status %d; want the --deny verdict",
+			resp.GetContinue(), resp.GetStatusCode())
+	}
+}
+
+// binaryFailures are plugin binaries StartBinary must reject, with the failure it reports for each.
+var binaryFailures = []struct {
+	name string
+	args func(bin string) (string, []string)
+	want string
+}{
+	{
+		name: "missing binary",
+		args: func(bin string) (string, []string) { return filepath.Join(filepath.Dir(bin), "missing"), nil },
+		want: "failed to start plugin",
+	},
+	{
+		name: "exits before listening",
+		args: func(bin string) (string, []string) { return bin, []string{"--tuning", "bogus"} },
+		want: "plugin exited before listening",
+	},
+}
+
+func TestStartBinaryFailures(t *testing.T) {
+	bin := pluginBinary(t)
+	if name := os.Getenv(childEnv); name != "" {
+		for _, f := range binaryFailures {
+			if f.name == name {
+				path, args := f.args(bin)
+				StartBinary(t, path, args...)
+			}
+		}
+		return
+	}
+
+	for _, f := range binaryFailures {
+		t.Run(f.name, func(t *testing.T) {
+			out, passed := runChild(t, "TestStartBinaryFailures", f.name)
+			if passed {
+				t.Fatalf("StartBinary succeeded, want it to report %q:\n%s", f.want, out)
+			}
+			if !strings.Contains(out, f.want) {
+				t.Errorf("StartBinary output does not report %q:\n%s", f.want, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package plugintest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// Upstream stands in for the MCP server behind mcpd in a Chain.
+type Upstream func(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error)
+
+// ChainOption configures a Chain.
+type ChainOption func(*Chain)
+
+// WithUpstream sets the upstream called when every request-flow plugin lets the request continue.
+// The default upstream answers 200 echoing the request body.
+func WithUpstream(u Upstream) ChainOption {
+	return func(c *Chain) {
+		c.upstream = u
+	}
+}
+
+// ChainStep records one plugin call made by a Chain.
+type ChainStep struct {
+	// Plugin is the plugin's metadata name, or its position when the name is empty.
+	Plugin string
+	Flow   mcpdpluginsv1.Flow
+
+	// Request and Response are the inputs of HandleRequest and HandleResponse respectively.
+	Request  *mcpdpluginsv1.HTTPRequest
+	Response *mcpdpluginsv1.HTTPResponse
+
+	Result *mcpdpluginsv1.HTTPResponse
+	Err    error
+}
+
+// ChainResult is the outcome of sending a request through a Chain.
+type ChainResult struct {
+	// Response is what mcpd would return to the client.
+	Response *mcpdpluginsv1.HTTPResponse
+
+	// ShortCircuitedBy names the plugin that stopped the chain, if any.
+	ShortCircuitedBy string
+
+	// UpstreamRequest is the request as it reached the upstream, or nil if it never did.
+	UpstreamRequest *mcpdpluginsv1.HTTPRequest
+
+	// Steps lists every plugin call in order.
+	Steps []ChainStep
+}
+
+// Chain wires several plugins together in-process with mcpd's continue/short-circuit semantics,
+// for testing how plugins interact before deploying them together:
+//
+//   - request-flow plugins run in order; a plugin returning Continue=false short-circuits the
+//     chain and its response is returned to the client, otherwise its ModifiedRequest (if set)
+//     replaces the request seen by the next plugin;
+//   - the upstream is called with the final request;
+//   - response-flow plugins run in order on the upstream response, each result replacing the
+//     response; a plugin returning Continue=false stops the remaining response plugins.
+//
+// Plugins only take part in the flows declared by GetCapabilities. Configure plugins before
+// building the chain.
+type Chain struct {
+	links    []chainLink
+	upstream Upstream
+}
+
+type chainLink struct {
+	name  string
+	impl  mcpdpluginsv1.PluginServer
+	flows []mcpdpluginsv1.Flow
+}
+
+// NewChain builds a chain of plugins in mcpd order, reading each plugin's name and flows.
+func NewChain(ctx context.Context, plugins []mcpdpluginsv1.PluginServer, opts ...ChainOption) (*Chain, error) {
+	c := &Chain{upstream: echoUpstream}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	for i, p := range plugins {
+		link := chainLink{name: fmt.Sprintf("plugin[%d]", i), impl: p}
+		if md, err := p.GetMetadata(ctx, &emptypb.Empty{}); err == nil && md.GetName() != "" {
+			link.name = md.GetName()
+		}
+		caps, err := p.GetCapabilities(ctx, &emptypb.Empty{})
+		if err != nil {
+			return nil, fmt.Errorf("%s: GetCapabilities failed: %w", link.name, err)
+		}
+		link.flows = caps.GetFlows()
+		c.links = append(c.links, link)
+	}
+
+	return c, nil
+}
+
+// Do sends req through the chain. A plugin or upstream error aborts the chain and is returned
+// together with the steps made so far.
+func (c *Chain) Do(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) (*ChainResult, error) {
+	res := &ChainResult{}
+	req = mcpdpluginsv1.CloneRequest(req)
+
+	for _, l := range c.links {
+		if !slices.Contains(l.flows, mcpdpluginsv1.FlowRequest) {
+			continue
+		}
+		out, err := l.impl.HandleRequest(ctx, mcpdpluginsv1.CloneRequest(req))
+		res.Steps = append(res.Steps, ChainStep{
+			Plugin:  l.name,
+			Flow:    mcpdpluginsv1.FlowRequest,
+			Request: req,
+			Result:  out,
+			Err:     err,
+		})
+		if err != nil {
+			return res, fmt.Errorf("%s: HandleRequest failed: %w", l.name, err)
+		}
+		if out == nil {
+			return res, fmt.Errorf("%s: HandleRequest returned a nil response", l.name)
+		}
+		if !out.GetContinue() {
+			res.Response, res.ShortCircuitedBy = out, l.name
+			return res, nil
+		}
+		if m := out.GetModifiedRequest(); m != nil {
+			req = m
+		}
+	}
+
+	res.UpstreamRequest = req
+	resp, err := c.upstream(ctx, mcpdpluginsv1.CloneRequest(req))
+	if err != nil {
+		return res, fmt.Errorf("upstream failed: %w", err)
+	}
+
+	for _, l := range c.links {
+		if !slices.Contains(l.flows, mcpdpluginsv1.FlowResponse) {
+			continue
+		}
+		out, err := l.impl.HandleResponse(ctx, mcpdpluginsv1.CloneResponse(resp))
+		res.Steps = append(res.Steps, ChainStep{
+			Plugin:   l.name,
+			Flow:     mcpdpluginsv1.FlowResponse,
+			Response: resp,
+			Result:   out,
+			Err:      err,
+		})
+		if err != nil {
+			return res, fmt.Errorf("%s: HandleResponse failed: %w", l.name, err)
+		}
+		if out == nil {
+			return res, fmt.Errorf("%s: HandleResponse returned a nil response", l.name)
+		}
+		resp = out
+		if !out.GetContinue() {
+			res.ShortCircuitedBy = l.name
+			break
+		}
+	}
+
+	res.Response = resp
+
+	return res, nil
+}
+
+func echoUpstream(_ context.Context, req *mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error) {
+	return &mcpdpluginsv1.HTTPResponse{
+		StatusCode: 200,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       req.GetBody(),
+		Continue:   true,
+	}, nil
+}
@@ -0,0 +1,320 @@
+package plugintest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// chainPlugin is a plugin taking part in the given flows, answering with onRequest and onResponse
+// (continuing unchanged when nil).
+type chainPlugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	name       string
+	flows      []mcpdpluginsv1.Flow
+	capsErr    error
+	onRequest  func(req *mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error)
+	onResponse func(resp *mcpdpluginsv1.HTTPResponse) (*mcpdpluginsv1.HTTPResponse, error)
+}
+
+func (p *chainPlugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	if p.name == "" {
+		return nil, errors.New("no metadata")
+	}
+	return &mcpdpluginsv1.Metadata{Name: p.name}, nil
+}
+
+func (p *chainPlugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	if p.capsErr != nil {
+		return nil, p.capsErr
+	}
+	return mcpdpluginsv1.NewCapabilities(p.flows...), nil
+}
+
+func (p *chainPlugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	if p.onRequest != nil {
+		return p.onRequest(req)
+	}
+	return p.BasePlugin.HandleRequest(ctx, req)
+}
+
+func (p *chainPlugin) HandleResponse(
+	ctx context.Context,
+	resp *mcpdpluginsv1.HTTPResponse,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	if p.onResponse != nil {
+		return p.onResponse(resp)
+	}
+	return p.BasePlugin.HandleResponse(ctx, resp)
+}
+
+var (
+	requestFlow  = []mcpdpluginsv1.Flow{mcpdpluginsv1.FlowRequest}
+	responseFlow = []mcpdpluginsv1.Flow{mcpdpluginsv1.FlowResponse}
+	bothFlows    = []mcpdpluginsv1.Flow{mcpdpluginsv1.FlowRequest, mcpdpluginsv1.FlowResponse}
+)
+
+// auth rejects requests without an Authorization header and strips it otherwise.
+func auth() *chainPlugin {
+	return &chainPlugin{
+		name:  "auth",
+		flows: requestFlow,
+		onRequest: func(req *mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error) {
+			if req.GetHeaders()["Authorization"] == "" {
+				return &mcpdpluginsv1.HTTPResponse{StatusCode: 401}, nil
+			}
+			m := mcpdpluginsv1.CloneRequest(req)
+			delete(m.Headers, "Authorization")
+			return &mcpdpluginsv1.HTTPResponse{Continue: true, ModifiedRequest: m}, nil
+		},
+	}
+}
+
+// redact replaces response bodies containing "secret".
+func redact() *chainPlugin {
+	return &chainPlugin{
+		name:  "redact",
+		flows: responseFlow,
+		onResponse: func(resp *mcpdpluginsv1.HTTPResponse) (*mcpdpluginsv1.HTTPResponse, error) {
+			out := mcpdpluginsv1.CloneResponse(resp)
+			out.Body = []byte(strings.ReplaceAll(string(resp.GetBody()), "secret", "[redacted]"))
+			return out, nil
+		},
+	}
+}
+
+// stepNames lists the steps of res as "plugin/flow".
+func stepNames(res *ChainResult) []string {
+	var out []string
+	for _, s := range res.Steps {
+		out = append(out, s.Plugin+"/"+s.Flow.String())
+	}
+	return out
+}
+
+func TestChain(t *testing.T) {
+	errPolicy := errors.New("policy failed")
+	tests := []struct {
+		name          string
+		plugins       []mcpdpluginsv1.PluginServer
+		upstream      Upstream
+		headers       map[string]string
+		wantStatus    int32
+		wantBody      string
+		wantBy        string
+		wantUpstream  bool
+		wantSteps     []string
+		wantErrSubstr string
+	}{
+		{
+			name:         "every plugin continues",
+			plugins:      []mcpdpluginsv1.PluginServer{auth(), redact(), &chainPlugin{name: "log", flows: bothFlows}},
+			headers:      map[string]string{"Authorization": "Bearer x"},
+			wantStatus:   200,
+			wantBody:     `{"token":"[redacted]"}`,
+			wantUpstream: true,
+			wantSteps: []string{
+				"auth/FLOW_REQUEST", "log/FLOW_REQUEST", "redact/FLOW_RESPONSE", "log/FLOW_RESPONSE",
+			},
+		},
+		{
+			name:       "request short-circuit",
+			plugins:    []mcpdpluginsv1.PluginServer{auth(), redact(), &chainPlugin{name: "log", flows: bothFlows}},
+			wantStatus: 401,
+			wantBy:     "auth",
+			wantSteps:  []string{"auth/FLOW_REQUEST"},
+		},
+		{
+			name: "response short-circuit",
+			plugins: []mcpdpluginsv1.PluginServer{
+				&chainPlugin{
+					name:  "block",
+					flows: responseFlow,
+					onResponse: func(*mcpdpluginsv1.HTTPResponse) (*mcpdpluginsv1.HTTPResponse, error) {
+						return &mcpdpluginsv1.HTTPResponse{StatusCode: 502, Body: []byte("blocked")}, nil
+					},
+				},
+				redact(),
+			},
+			wantStatus:   502,
+			wantBody:     "blocked",
+			wantBy:       "block",
+			wantUpstream: true,
+			wantSteps:    []string{"block/FLOW_RESPONSE"},
+		},
+		{
+			name: "custom upstream",
+			plugins: []mcpdpluginsv1.PluginServer{
+				&chainPlugin{name: "log", flows: bothFlows},
+			},
+			upstream: func(context.Context, *mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error) {
+				return &mcpdpluginsv1.HTTPResponse{Continue: true, StatusCode: 204}, nil
+			},
+			wantStatus:   204,
+			wantUpstream: true,
+			wantSteps:    []string{"log/FLOW_REQUEST", "log/FLOW_RESPONSE"},
+		},
+		{
+			name:       "unnamed plugins",
+			plugins:    []mcpdpluginsv1.PluginServer{&chainPlugin{flows: requestFlow}, auth()},
+			wantStatus: 401,
+			wantBy:     "auth",
+			wantSteps:  []string{"plugin[0]/FLOW_REQUEST", "auth/FLOW_REQUEST"},
+		},
+		{
+			name: "request error",
+			plugins: []mcpdpluginsv1.PluginServer{&chainPlugin{
+				name:  "policy",
+				flows: requestFlow,
+				onRequest: func(*mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error) {
+					return nil, errPolicy
+				},
+			}, redact()},
+			wantSteps:     []string{"policy/FLOW_REQUEST"},
+			wantErrSubstr: "policy: HandleRequest failed: policy failed",
+		},
+		{
+			name: "nil request result",
+			plugins: []mcpdpluginsv1.PluginServer{&chainPlugin{
+				name:  "policy",
+				flows: requestFlow,
+				onRequest: func(*mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error) {
+					return nil, nil
+				},
+			}},
+			wantSteps:     []string{"policy/FLOW_REQUEST"},
+			wantErrSubstr: "policy: HandleRequest returned a nil response",
+		},
+		{
+			name:    "upstream error",
+			plugins: []mcpdpluginsv1.PluginServer{redact()},
+			upstream: func(context.Context, *mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error) {
+				return nil, errors.New("connection refused")
+			},
+			wantUpstream:  true,
+			wantErrSubstr: "upstream failed: connection refused",
+		},
+		{
+			name: "response error",
+			plugins: []mcpdpluginsv1.PluginServer{&chainPlugin{
+				name:  "scan",
+				flows: responseFlow,
+				onResponse: func(*mcpdpluginsv1.HTTPResponse) (*mcpdpluginsv1.HTTPResponse, error) {
+					return nil, errPolicy
+				},
+			}},
+			wantUpstream:  true,
+			wantSteps:     []string{"scan/FLOW_RESPONSE"},
+			wantErrSubstr: "scan: HandleResponse failed: policy failed",
+		},
+		{
+			name: "nil response result",
+			plugins: []mcpdpluginsv1.PluginServer{&chainPlugin{
+				name:  "scan",
+				flows: responseFlow,
+				onResponse: func(*mcpdpluginsv1.HTTPResponse) (*mcpdpluginsv1.HTTPResponse, error) {
+					return nil, nil
+				},
+			}},
+			wantUpstream:  true,
+			wantSteps:     []string{"scan/FLOW_RESPONSE"},
+			wantErrSubstr: "scan: HandleResponse returned a nil response",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []ChainOption
+			if tt.upstream != nil {
+				opts = append(opts, WithUpstream(tt.upstream))
+			}
+			chain, err := NewChain(context.Background(), tt.plugins, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := &mcpdpluginsv1.HTTPRequest{
+				Method: "POST", Path: "/mcp", Headers: tt.headers,
+				Body: []byte(`{"token":"secret"}`),
+			}
+			res, err := chain.Do(context.Background(), req)
+			if got := stepNames(res); !slices.Equal(got, tt.wantSteps) {
+				t.Errorf("steps = %q, want %q", got, tt.wantSteps)
+			}
+			if (res.UpstreamRequest != nil) != tt.wantUpstream {
+				t.Errorf("UpstreamRequest = %v, want reached %v", res.UpstreamRequest, tt.wantUpstream)
+			}
+			if tt.wantErrSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrSubstr) {
+					t.Fatalf("Do error = %v, want %q", err, tt.wantErrSubstr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.Response.GetStatusCode(); got != tt.wantStatus {
+				t.Errorf("response status = %d, want %d", got, tt.wantStatus)
+			}
+			if got := string(res.Response.GetBody()); tt.wantBody != "" && got != tt.wantBody {
+				t.Errorf("response body = %q, want %q", got, tt.wantBody)
+			}
+			if res.ShortCircuitedBy != tt.wantBy {
+				t.Errorf("ShortCircuitedBy = %q, want %q", res.ShortCircuitedBy, tt.wantBy)
+			}
+		})
+	}
+}
+
+func TestChainModifiedRequest(t *testing.T) {
+	var seen []map[string]string
+	record := &chainPlugin{
+		name:  "record",
+		flows: requestFlow,
+		onRequest: func(req *mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error) {
+			seen = append(seen, req.GetHeaders())
+			req.Headers["Mutated"] = "yes" // Plugins get copies, so this must not leak.
+			return &mcpdpluginsv1.HTTPResponse{Continue: true}, nil
+		},
+	}
+	chain, err := NewChain(context.Background(), []mcpdpluginsv1.PluginServer{record, auth(), record})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &mcpdpluginsv1.HTTPRequest{Headers: map[string]string{"Authorization": "Bearer x"}}
+	res, err := chain.Do(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != 2 || seen[0]["Authorization"] == "" || seen[1]["Authorization"] != "" {
+		t.Errorf("plugins saw headers %v, want auth's ModifiedRequest after it", seen)
+	}
+	if _, ok := res.UpstreamRequest.GetHeaders()["Authorization"]; ok {
+		t.Errorf("upstream request headers = %v, want auth's ModifiedRequest", res.UpstreamRequest.GetHeaders())
+	}
+	if _, ok := req.GetHeaders()["Mutated"]; ok || req.GetHeaders()["Authorization"] == "" {
+		t.Errorf("caller's request was modified: %v", req.GetHeaders())
+	}
+	if got := res.Steps[0].Request.GetHeaders(); got["Mutated"] != "" {
+		t.Errorf("recorded step input = %v, want the plugin's input before it ran", got)
+	}
+}
+
+func TestNewChainCapabilitiesError(t *testing.T) {
+	p := &chainPlugin{name: "broken", capsErr: errors.New("unavailable")}
+	_, err := NewChain(context.Background(), []mcpdpluginsv1.PluginServer{auth(), p})
+	if err == nil || err.Error() != "broken: GetCapabilities failed: unavailable" {
+		t.Errorf("NewChain error = %v, want the GetCapabilities failure", err)
+	}
+}
@@ -0,0 +1,170 @@
+package plugintest
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+)
+
+// FakeClock is a clock.Clock whose time only moves when the test advances it, firing the timers
+// that come due. It is safe for concurrent use.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	timers  []*fakeTimer
+	created int
+}
+
+// NewFakeClock returns a FakeClock set to start, or to 2024-01-01T00:00:00Z when start is zero.
+func NewFakeClock(start time.Time) *FakeClock {
+	if start.IsZero() {
+		start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	c := &FakeClock{now: start}
+	c.cond = sync.NewCond(&c.mu)
+
+	return c
+}
+
+// Now implements clock.Clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// NewTimer implements clock.Clock. A timer created with a non-positive duration fires at once.
+func (c *FakeClock) NewTimer(d time.Duration) clock.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, ch: make(chan time.Time, 1)}
+	c.schedule(t, d)
+	c.created++
+	c.cond.Broadcast()
+
+	return t
+}
+
+// Advance moves the clock forward by d, firing in order the timers due by then.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setLocked(c.now.Add(d))
+}
+
+// Set moves the clock to t, firing in order the timers due by then. Setting an earlier time
+// fires nothing.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setLocked(t)
+}
+
+// Pending returns the number of timers not yet fired or stopped.
+func (c *FakeClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
+
+// WaitForTimers blocks until n timers have been created on the clock since it was made, so a
+// test can advance the clock only once the goroutine under test is waiting on it.
+func (c *FakeClock) WaitForTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.created < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *FakeClock) setLocked(t time.Time) {
+	if t.After(c.now) {
+		c.now = t
+	}
+	for len(c.timers) > 0 && !c.timers[0].when.After(c.now) {
+		ft := c.timers[0]
+		c.timers = c.timers[1:]
+		select {
+		case ft.ch <- c.now:
+		default:
+		}
+	}
+}
+
+// schedule arms t to fire after d, keeping the timers sorted by due time.
+func (c *FakeClock) schedule(t *fakeTimer, d time.Duration) {
+	t.when = c.now.Add(d)
+	if d <= 0 {
+		select {
+		case t.ch <- c.now:
+		default:
+		}
+		return
+	}
+	i, _ := slices.BinarySearchFunc(c.timers, t.when, func(ft *fakeTimer, when time.Time) int {
+		if ft.when.After(when) {
+			return 1
+		}
+		return -1
+	})
+	c.timers = slices.Insert(c.timers, i, t)
+}
+
+// remove disarms t, reporting whether it was pending.
+func (c *FakeClock) remove(t *fakeTimer) bool {
+	i := slices.Index(c.timers, t)
+	if i < 0 {
+		return false
+	}
+	c.timers = slices.Delete(c.timers, i, i+1)
+
+	return true
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	ch    chan time.Time
+	when  time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	return t.disarm()
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	pending := t.disarm()
+	t.clock.schedule(t, d)
+
+	return pending
+}
+
+// disarm removes t from its clock and drains a fired but unreceived time, as *time.Timer does
+// since Go 1.23, so no stale time is received after Stop or Reset. A time drained still counts
+// as pending. The clock's lock must be held.
+func (t *fakeTimer) disarm() bool {
+	pending := t.clock.remove(t)
+	select {
+	case <-t.ch:
+		pending = true
+	default:
+	}
+
+	return pending
+}
@@ -0,0 +1,232 @@
+package plugintest
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// fired returns the times delivered by the timers that have fired, in timers' order, with the
+// zero time for those that have not.
+func fired(timers ...*fakeTimer) []time.Time {
+	out := make([]time.Time, len(timers))
+	for i, t := range timers {
+		select {
+		case out[i] = <-t.ch:
+		default:
+		}
+	}
+
+	return out
+}
+
+func TestNewFakeClock(t *testing.T) {
+	if got, want := NewFakeClock(time.Time{}).Now(), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Now = %s, want %s", got, want)
+	}
+	start := time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+	if got := NewFakeClock(start).Now(); !got.Equal(start) {
+		t.Errorf("Now = %s, want %s", got, start)
+	}
+}
+
+func TestFakeClockAdvance(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		advance   []time.Duration
+		want      []time.Duration // Offsets from the start each timer fires at; -1 for unfired.
+		pending   int
+	}{
+		{
+			name:      "fires due timers",
+			durations: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second},
+			advance:   []time.Duration{2 * time.Second},
+			want:      []time.Duration{2 * time.Second, 2 * time.Second, -1},
+			pending:   1,
+		},
+		{
+			name:      "fires at the exact due time",
+			durations: []time.Duration{time.Second},
+			advance:   []time.Duration{time.Second - time.Nanosecond, time.Nanosecond},
+			want:      []time.Duration{time.Second},
+		},
+		{
+			name:      "fires in steps",
+			durations: []time.Duration{3 * time.Second, time.Second},
+			advance:   []time.Duration{time.Second, 2 * time.Second},
+			want:      []time.Duration{3 * time.Second, time.Second},
+		},
+		{
+			name:      "non-positive durations fire at once",
+			durations: []time.Duration{0, -time.Second},
+			want:      []time.Duration{0, 0},
+		},
+		{
+			name:      "nothing due",
+			durations: []time.Duration{time.Hour},
+			advance:   []time.Duration{time.Minute},
+			want:      []time.Duration{-1},
+			pending:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewFakeClock(time.Time{})
+			start := c.Now()
+			var timers []*fakeTimer
+			for _, d := range tt.durations {
+				timers = append(timers, c.NewTimer(d).(*fakeTimer))
+			}
+			// Read each timer as soon as it fires, since a channel holds a single time.
+			got := make([]time.Duration, len(timers))
+			for i := range got {
+				got[i] = -1
+			}
+			collect := func() {
+				for i, at := range fired(timers...) {
+					if !at.IsZero() {
+						got[i] = at.Sub(start)
+					}
+				}
+			}
+			collect()
+			for _, d := range tt.advance {
+				c.Advance(d)
+				collect()
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("timers fired at %v, want %v", got, tt.want)
+			}
+			if n := c.Pending(); n != tt.pending {
+				t.Errorf("Pending = %d, want %d", n, tt.pending)
+			}
+		})
+	}
+}
+
+func TestFakeClockSet(t *testing.T) {
+	c := NewFakeClock(time.Time{})
+	start := c.Now()
+	timer := c.NewTimer(time.Minute).(*fakeTimer)
+
+	// Setting an earlier time neither moves the clock back nor fires anything.
+	c.Set(start.Add(-time.Hour))
+	if got := c.Now(); !got.Equal(start) {
+		t.Errorf("Now after an earlier Set = %s, want %s", got, start)
+	}
+	if got := fired(timer)[0]; !got.IsZero() {
+		t.Errorf("timer fired at %s on an earlier Set", got)
+	}
+
+	c.Set(start.Add(time.Hour))
+	if got := fired(timer)[0]; !got.Equal(start.Add(time.Hour)) {
+		t.Errorf("timer fired at %s, want %s", got, start.Add(time.Hour))
+	}
+}
+
+func TestFakeTimerStopReset(t *testing.T) {
+	tests := []struct {
+		name        string
+		run         func(c *FakeClock, timer *fakeTimer) bool
+		wantResult  bool
+		wantPending int
+		wantFire    bool // Whether the timer fires once the clock is advanced by an hour.
+	}{
+		{
+			name:       "stop pending",
+			run:        func(_ *FakeClock, timer *fakeTimer) bool { return timer.Stop() },
+			wantResult: true,
+		},
+		{
+			name: "stop stopped",
+			run: func(_ *FakeClock, timer *fakeTimer) bool {
+				timer.Stop()
+				return timer.Stop()
+			},
+		},
+		{
+			name: "stop fired and received",
+			run: func(c *FakeClock, timer *fakeTimer) bool {
+				c.Advance(time.Minute)
+				<-timer.ch
+				return timer.Stop()
+			},
+		},
+		{
+			name: "stop fired but unreceived drains the channel",
+			run: func(c *FakeClock, timer *fakeTimer) bool {
+				c.Advance(time.Minute)
+				return timer.Stop()
+			},
+			wantResult: true,
+		},
+		{
+			name:        "reset pending",
+			run:         func(_ *FakeClock, timer *fakeTimer) bool { return timer.Reset(2 * time.Hour) },
+			wantResult:  true,
+			wantPending: 1,
+		},
+		{
+			name: "reset stopped",
+			run: func(_ *FakeClock, timer *fakeTimer) bool {
+				timer.Stop()
+				return timer.Reset(30 * time.Minute)
+			},
+			wantFire: true,
+		},
+		{
+			name: "reset fired but unreceived drops the stale time",
+			run: func(c *FakeClock, timer *fakeTimer) bool {
+				c.Advance(time.Minute)
+				return timer.Reset(2 * time.Hour)
+			},
+			wantResult:  true,
+			wantPending: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewFakeClock(time.Time{})
+			timer := c.NewTimer(time.Minute).(*fakeTimer)
+			if got := tt.run(c, timer); got != tt.wantResult {
+				t.Errorf("result = %t, want %t", got, tt.wantResult)
+			}
+			if got := fired(timer)[0]; !got.IsZero() {
+				t.Errorf("received a stale time %s", got)
+			}
+			c.Advance(time.Hour)
+			if got := !fired(timer)[0].IsZero(); got != tt.wantFire {
+				t.Errorf("fired = %t, want %t", got, tt.wantFire)
+			}
+			if n := c.Pending(); n != tt.wantPending {
+				t.Errorf("Pending = %d, want %d", n, tt.wantPending)
+			}
+		})
+	}
+}
+
+func TestFakeClockWaitForTimers(t *testing.T) {
+	c := NewFakeClock(time.Time{})
+	done := make(chan struct{})
+	go func() {
+		c.WaitForTimers(2)
+		close(done)
+	}()
+
+	c.NewTimer(time.Second)
+	select {
+	case <-done:
+		t.Fatal("WaitForTimers returned after one timer")
+	case <-time.After(20 * time.Millisecond):
+	}
+	c.NewTimer(0)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("WaitForTimers did not return after two timers")
+	}
+
+	// Timers already created count.
+	c.WaitForTimers(1)
+}
@@ -0,0 +1,227 @@
+package plugintest
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// callTimeout bounds each RPC made by the conformance suite.
+const callTimeout = 5 * time.Second
+
+// ConformanceOption configures the conformance suite.
+type ConformanceOption func(*conformanceOptions)
+
+type conformanceOptions struct {
+	config   *mcpdpluginsv1.PluginConfig
+	request  *mcpdpluginsv1.HTTPRequest
+	response *mcpdpluginsv1.HTTPResponse
+}
+
+// WithConformanceConfig sets the configuration sent with Configure (default an empty PluginConfig).
+func WithConformanceConfig(cfg *mcpdpluginsv1.PluginConfig) ConformanceOption {
+	return func(o *conformanceOptions) {
+		o.config = cfg
+	}
+}
+
+// WithConformanceRequest sets the request sent to HandleRequest.
+func WithConformanceRequest(req *mcpdpluginsv1.HTTPRequest) ConformanceOption {
+	return func(o *conformanceOptions) {
+		o.request = req
+	}
+}
+
+// WithConformanceResponse sets the response sent to HandleResponse.
+func WithConformanceResponse(resp *mcpdpluginsv1.HTTPResponse) ConformanceOption {
+	return func(o *conformanceOptions) {
+		o.response = resp
+	}
+}
+
+// RunConformance checks that the plugin reachable through client honours the contract mcpd
+// relies on, as a set of subtests:
+//   - GetMetadata returns a name and version;
+//   - GetCapabilities declares at least one known flow, without duplicates;
+//   - Configure accepts the configuration, after which CheckHealth and CheckReady succeed;
+//   - HandleRequest and HandleResponse (for declared flows) return a response, with a status
+//     code when the chain is stopped;
+//   - Stop succeeds.
+//
+// Use RunConformanceServer for Go implementations and RunConformanceBinary for built plugins.
+func RunConformance(t *testing.T, client mcpdpluginsv1.PluginClient, opts ...ConformanceOption) {
+	t.Helper()
+
+	o := &conformanceOptions{
+		config:   &mcpdpluginsv1.PluginConfig{},
+		request:  defaultRequest(),
+		response: defaultResponse(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var flows []mcpdpluginsv1.Flow
+
+	t.Run("GetMetadata", func(t *testing.T) {
+		md, err := client.GetMetadata(callContext(t), &emptypb.Empty{})
+		if err != nil {
+			t.Fatalf("GetMetadata failed: %v", err)
+		}
+		if md.GetName() == "" {
+			t.Error("metadata name is empty")
+		}
+		if md.GetVersion() == "" {
+			t.Error("metadata version is empty")
+		}
+	})
+
+	t.Run("GetCapabilities", func(t *testing.T) {
+		caps, err := client.GetCapabilities(callContext(t), &emptypb.Empty{})
+		if err != nil {
+			t.Fatalf("GetCapabilities failed: %v", err)
+		}
+		flows = caps.GetFlows()
+		if len(flows) == 0 {
+			t.Error("no flows declared")
+		}
+		for i, f := range flows {
+			if _, ok := mcpdpluginsv1.Flow_name[int32(f)]; !ok {
+				t.Errorf("unknown flow %d", f)
+			}
+			if slices.Contains(flows[:i], f) {
+				t.Errorf("flow %s declared more than once", f)
+			}
+		}
+	})
+
+	t.Run("Configure", func(t *testing.T) {
+		if _, err := client.Configure(callContext(t), o.config); err != nil {
+			t.Fatalf("Configure failed: %v", err)
+		}
+	})
+
+	t.Run("CheckHealth", func(t *testing.T) {
+		if _, err := client.CheckHealth(callContext(t), &emptypb.Empty{}); err != nil {
+			t.Errorf("CheckHealth failed: %v", err)
+		}
+	})
+
+	t.Run("CheckReady", func(t *testing.T) {
+		if _, err := client.CheckReady(callContext(t), &emptypb.Empty{}); err != nil {
+			t.Errorf("CheckReady failed: %v", err)
+		}
+	})
+
+	t.Run("HandleRequest", func(t *testing.T) {
+		if !slices.Contains(flows, mcpdpluginsv1.FlowRequest) {
+			t.Skip("request flow not declared")
+		}
+		resp, err := client.HandleRequest(callContext(t), o.request)
+		if err != nil {
+			t.Fatalf("HandleRequest failed: %v", err)
+		}
+		checkVerdict(t, resp)
+	})
+
+	t.Run("HandleResponse", func(t *testing.T) {
+		if !slices.Contains(flows, mcpdpluginsv1.FlowResponse) {
+			t.Skip("response flow not declared")
+		}
+		resp, err := client.HandleResponse(callContext(t), o.response)
+		if err != nil {
+			t.Fatalf("HandleResponse failed: %v", err)
+		}
+		checkVerdict(t, resp)
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		if _, err := client.Stop(callContext(t), &emptypb.Empty{}); err != nil {
+			t.Errorf("Stop failed: %v", err)
+		}
+	})
+}
+
+// RunConformanceServer serves impl over gRPC on a temporary unix socket and runs RunConformance
+// against it, so Go implementations are checked through the same transport mcpd uses.
+func RunConformanceServer(t *testing.T, impl mcpdpluginsv1.PluginServer, opts ...ConformanceOption) {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "plugintest")
+	if err != nil {
+		t.Fatalf("failed to create socket directory: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	lis, err := net.Listen("unix", filepath.Join(dir, "plugin.sock"))
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	srv := grpc.NewServer()
+	mcpdpluginsv1.RegisterPluginServer(srv, impl)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	RunConformance(t, dial(t, "unix://"+lis.Addr().String()), opts...)
+}
+
+// dial connects to a plugin server at target, closing the connection at cleanup.
+func dial(t *testing.T, target string) mcpdpluginsv1.PluginClient {
+	t.Helper()
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to connect to plugin: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return mcpdpluginsv1.NewPluginClient(conn)
+}
+
+func checkVerdict(t *testing.T, resp *mcpdpluginsv1.HTTPResponse) {
+	t.Helper()
+
+	if resp == nil {
+		t.Fatal("nil response")
+	}
+	if !resp.GetContinue() && resp.GetStatusCode() < 100 {
+		t.Errorf("chain stopped without a valid status code (got %d)", resp.GetStatusCode())
+	}
+}
+
+func callContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	t.Cleanup(cancel)
+
+	return ctx
+}
+
+func defaultRequest() *mcpdpluginsv1.HTTPRequest {
+	return &mcpdpluginsv1.HTTPRequest{
+		Method:     "POST",
+		Url:        "http://localhost/mcp",
+		Path:       "/mcp",
+		RequestUri: "/mcp",
+		RemoteAddr: "127.0.0.1:50000",
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{}}}`),
+	}
+}
+
+func defaultResponse() *mcpdpluginsv1.HTTPResponse {
+	return &mcpdpluginsv1.HTTPResponse{
+		StatusCode: 200,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       []byte(`{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"ok"}]}}`),
+	}
+}
@@ -0,0 +1,269 @@
+package plugintest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// conformancePlugin is a plugin whose contract can be bent per test. It records the inputs it
+// receives.
+type conformancePlugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	md           *mcpdpluginsv1.Metadata
+	flows        []mcpdpluginsv1.Flow
+	configureErr error
+	healthErr    error
+	verdict      *mcpdpluginsv1.HTTPResponse // Returned by HandleRequest when set.
+	responseErr  error
+
+	mu       sync.Mutex
+	config   *mcpdpluginsv1.PluginConfig
+	request  *mcpdpluginsv1.HTTPRequest
+	response *mcpdpluginsv1.HTTPResponse
+}
+
+// newConformancePlugin returns a conformancePlugin honouring the contract, handling both flows.
+func newConformancePlugin() *conformancePlugin {
+	return &conformancePlugin{
+		md:    &mcpdpluginsv1.Metadata{Name: "conformance", Version: "1.0.0"},
+		flows: []mcpdpluginsv1.Flow{mcpdpluginsv1.FlowRequest, mcpdpluginsv1.FlowResponse},
+	}
+}
+
+func (p *conformancePlugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return p.md, nil
+}
+
+func (p *conformancePlugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return &mcpdpluginsv1.Capabilities{Flows: p.flows}, nil
+}
+
+func (p *conformancePlugin) Configure(_ context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	p.mu.Lock()
+	p.config = cfg
+	p.mu.Unlock()
+	if p.configureErr != nil {
+		return nil, p.configureErr
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (p *conformancePlugin) CheckHealth(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	if p.healthErr != nil {
+		return nil, p.healthErr
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (p *conformancePlugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	p.mu.Lock()
+	p.request = req
+	p.mu.Unlock()
+	if p.verdict != nil {
+		return p.verdict, nil
+	}
+
+	return p.BasePlugin.HandleRequest(ctx, req)
+}
+
+func (p *conformancePlugin) HandleResponse(
+	ctx context.Context,
+	resp *mcpdpluginsv1.HTTPResponse,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	p.mu.Lock()
+	p.response = resp
+	p.mu.Unlock()
+	if p.responseErr != nil {
+		return nil, p.responseErr
+	}
+
+	return p.BasePlugin.HandleResponse(ctx, resp)
+}
+
+func TestRunConformanceServer(t *testing.T) {
+	t.Run("conforming plugin", func(t *testing.T) {
+		RunConformanceServer(t, newConformancePlugin())
+	})
+
+	t.Run("stopped chain with a status code", func(t *testing.T) {
+		p := newConformancePlugin()
+		p.verdict = &mcpdpluginsv1.HTTPResponse{StatusCode: 403}
+		RunConformanceServer(t, p)
+	})
+
+	t.Run("undeclared flows are not called", func(t *testing.T) {
+		p := newConformancePlugin()
+		p.flows = []mcpdpluginsv1.Flow{mcpdpluginsv1.FlowRequest}
+		p.responseErr = errors.New("HandleResponse must not be called")
+		RunConformanceServer(t, p)
+	})
+
+	t.Run("options reach the plugin", func(t *testing.T) {
+		cfg := &mcpdpluginsv1.PluginConfig{CustomConfig: map[string]string{"mode": "strict"}}
+		req := &mcpdpluginsv1.HTTPRequest{Method: "GET", Path: "/health"}
+		resp := &mcpdpluginsv1.HTTPResponse{StatusCode: 204}
+		p := newConformancePlugin()
+		RunConformanceServer(t, p,
+			WithConformanceConfig(cfg), WithConformanceRequest(req), WithConformanceResponse(resp))
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if !proto.Equal(p.config, cfg) {
+			t.Errorf("Configure received %v, want %v", p.config, cfg)
+		}
+		if !proto.Equal(p.request, req) {
+			t.Errorf("HandleRequest received %v, want %v", p.request, req)
+		}
+		if !proto.Equal(p.response, resp) {
+			t.Errorf("HandleResponse received %v, want %v", p.response, resp)
+		}
+	})
+}
+
+// violations are plugins breaking the contract, with the failure the suite must report for each.
+var violations = []struct {
+	name   string
+	plugin func() *conformancePlugin
+	want   string
+}{
+	{
+		name: "no name",
+		plugin: func() *conformancePlugin {
+			p := newConformancePlugin()
+			p.md = &mcpdpluginsv1.Metadata{Version: "1.0.0"}
+			return p
+		},
+		want: "metadata name is empty",
+	},
+	{
+		name: "no version",
+		plugin: func() *conformancePlugin {
+			p := newConformancePlugin()
+			p.md = &mcpdpluginsv1.Metadata{Name: "conformance"}
+			return p
+		},
+		want: "metadata version is empty",
+	},
+	{
+		name: "no flows",
+		plugin: func() *conformancePlugin {
+			p := newConformancePlugin()
+			p.flows = nil
+			return p
+		},
+		want: "no flows declared",
+	},
+	{
+		name: "duplicate flow",
+		plugin: func() *conformancePlugin {
+			p := newConformancePlugin()
+			p.flows = []mcpdpluginsv1.Flow{mcpdpluginsv1.FlowRequest, mcpdpluginsv1.FlowRequest}
+			return p
+		},
+		want: "declared more than once",
+	},
+	{
+		name: "unknown flow",
+		plugin: func() *conformancePlugin {
+			p := newConformancePlugin()
+			p.flows = []mcpdpluginsv1.Flow{99}
+			return p
+		},
+		want: "unknown flow 99",
+	},
+	{
+		name: "configure error",
+		plugin: func() *conformancePlugin {
+			p := newConformancePlugin()
+			p.configureErr = errors.New("bad config")
+			return p
+		},
+		want: "Configure failed",
+	},
+	{
+		name: "unhealthy",
+		plugin: func() *conformancePlugin {
+			p := newConformancePlugin()
+			p.healthErr = errors.New("down")
+			return p
+		},
+		want: "CheckHealth failed",
+	},
+	{
+		name: "stopped chain without a status code",
+		plugin: func() *conformancePlugin {
+			p := newConformancePlugin()
+			p.verdict = &mcpdpluginsv1.HTTPResponse{}
+			return p
+		},
+		want: "chain stopped without a valid status code",
+	},
+	{
+		name: "response error",
+		plugin: func() *conformancePlugin {
+			p := newConformancePlugin()
+			p.responseErr = errors.New("boom")
+			return p
+		},
+		want: "HandleResponse failed",
+	},
+}
+
+// childEnv names the case a child test process runs, set by runChild.
+const childEnv = "PLUGINTEST_CHILD_CASE"
+
+// runChild runs the test named test again in a child process with childEnv set to name, and
+// returns its output and whether it passed. The suite reports through the *testing.T it is given,
+// so failures are only observable from outside the test process.
+func runChild(t *testing.T, test, name string) (string, bool) {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^"+test+"$", "-test.count=1", "-test.v")
+	cmd.Env = append(os.Environ(), childEnv+"="+name)
+	out, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		t.Fatalf("running child test: %v", err)
+	}
+
+	return string(out), err == nil
+}
+
+func TestRunConformanceViolations(t *testing.T) {
+	if name := os.Getenv(childEnv); name != "" {
+		for _, v := range violations {
+			if v.name == name {
+				RunConformanceServer(t, v.plugin())
+			}
+		}
+		return
+	}
+
+	for _, v := range violations {
+		t.Run(v.name, func(t *testing.T) {
+			out, passed := runChild(t, "TestRunConformanceViolations", v.name)
+			if passed {
+				t.Fatalf("suite passed, want it to report %q:\n%s", v.want, out)
+			}
+			if !strings.Contains(out, v.want) {
+				t.Errorf("suite output does not report %q:\n%s", v.want, out)
+			}
+		})
+	}
+}
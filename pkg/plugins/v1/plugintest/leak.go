@@ -0,0 +1,167 @@
+// Package plugintest provides helpers for testing plugin implementations.
+package plugintest
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// LeakOption configures CheckLeaks.
+type LeakOption func(*leakOptions)
+
+type leakOptions struct {
+	settle        time.Duration
+	maxHeapGrowth uint64
+	ignore        []string
+}
+
+// WithSettleTimeout sets how long CheckLeaks waits for goroutines to exit after the test before
+// reporting them (default 2s).
+func WithSettleTimeout(d time.Duration) LeakOption {
+	return func(o *leakOptions) {
+		o.settle = d
+	}
+}
+
+// WithMaxHeapGrowth makes CheckLeaks also fail when the live heap grows by more than n bytes over
+// the test, measured after forcing garbage collection. Heap checks are off by default because
+// caches and lazily initialised globals make them noisy.
+func WithMaxHeapGrowth(n uint64) LeakOption {
+	return func(o *leakOptions) {
+		o.maxHeapGrowth = n
+	}
+}
+
+// IgnoreGoroutines ignores leaked goroutines whose stack contains any of the given substrings,
+// such as the name of a function that intentionally runs for the life of the process.
+func IgnoreGoroutines(substrings ...string) LeakOption {
+	return func(o *leakOptions) {
+		o.ignore = append(o.ignore, substrings...)
+	}
+}
+
+// CheckLeaks snapshots the running goroutines (and, with WithMaxHeapGrowth, the live heap) and
+// fails t at cleanup if goroutines started during the test are still running. Call it first in the
+// test so that its check runs after every other cleanup:
+//
+//	func TestPlugin(t *testing.T) {
+//	    plugintest.CheckLeaks(t)
+//
+//	    p := &MyPlugin{}
+//	    // Configure, HandleRequest, Stop...
+//	}
+//
+// Leaked per-request goroutines are reported with their stacks.
+func CheckLeaks(t testing.TB, opts ...LeakOption) {
+	t.Helper()
+
+	o := &leakOptions{settle: 2 * time.Second}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	before := goroutineIDs()
+	var heapBefore uint64
+	if o.maxHeapGrowth > 0 {
+		heapBefore = liveHeap()
+	}
+
+	t.Cleanup(func() {
+		leaked := waitForGoroutines(before, o)
+		if len(leaked) > 0 {
+			t.Errorf("plugintest: %d goroutine(s) leaked:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+		}
+
+		if o.maxHeapGrowth > 0 {
+			if heapAfter := liveHeap(); heapAfter > heapBefore && heapAfter-heapBefore > o.maxHeapGrowth {
+				t.Errorf("plugintest: live heap grew by %d bytes (limit %d)", heapAfter-heapBefore, o.maxHeapGrowth)
+			}
+		}
+	})
+}
+
+// waitForGoroutines polls until no goroutine outside before is running or the settle timeout
+// passes, and returns the stacks of the remaining ones.
+func waitForGoroutines(before map[string]struct{}, o *leakOptions) []string {
+	deadline := time.Now().Add(o.settle)
+	for {
+		var leaked []string
+		for id, stack := range goroutineStacks() {
+			if _, ok := before[id]; ok || ignoredGoroutine(stack, o.ignore) {
+				continue
+			}
+			leaked = append(leaked, stack)
+		}
+		if len(leaked) == 0 || time.Now().After(deadline) {
+			return leaked
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func ignoredGoroutine(stack string, ignore []string) bool {
+	// Goroutines owned by the testing package come and go independently of the test.
+	for _, s := range append([]string{"testing.(*T).Run", "testing.tRunner"}, ignore...) {
+		if strings.Contains(firstFrames(stack), s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// firstFrames returns the goroutine header and its top frames, which identify what it is running.
+func firstFrames(stack string) string {
+	lines := strings.SplitN(stack, "\n", 6)
+
+	return strings.Join(lines[:min(len(lines), 5)], "\n")
+}
+
+func goroutineIDs() map[string]struct{} {
+	ids := map[string]struct{}{}
+	for id := range goroutineStacks() {
+		ids[id] = struct{}{}
+	}
+
+	return ids
+}
+
+// goroutineStacks returns the stack of every goroutine except the caller's, keyed by goroutine ID.
+func goroutineStacks() map[string]string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := map[string]string{}
+	for i, g := range bytes.Split(buf, []byte("\n\n")) {
+		if i == 0 {
+			continue // The calling goroutine.
+		}
+		var id string
+		if _, err := fmt.Sscanf(string(g), "goroutine %s", &id); err == nil {
+			stacks[id] = string(g)
+		}
+	}
+
+	return stacks
+}
+
+func liveHeap() uint64 {
+	runtime.GC()
+	runtime.GC()
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	return m.HeapAlloc
+}
@@ -0,0 +1,159 @@
+package plugintest
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeT is a testing.TB recording the failures reported through it instead of failing the test,
+// for checking that the helpers report what they should.
+type fakeT struct {
+	testing.TB
+
+	mu       sync.Mutex
+	errors   []string
+	cleanups []func()
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+// Fatalf records the failure and ends the calling goroutine, as testing.T does; run code calling
+// it through run.
+func (f *fakeT) Fatalf(format string, args ...any) {
+	f.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func (f *fakeT) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+// run calls fn on its own goroutine, so a Fatalf ends only fn, and then runs the cleanups in
+// reverse order.
+func (f *fakeT) run(fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	<-done
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+// failures returns the failures reported so far, joined by newlines.
+func (f *fakeT) failures() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return strings.Join(f.errors, "\n")
+}
+
+// leakyWorker blocks until stop is closed, standing in for a goroutine a plugin forgot to stop.
+func leakyWorker(stop <-chan struct{}) {
+	<-stop
+}
+
+// heapSink keeps allocations made during a test reachable.
+var heapSink [][]byte
+
+func TestCheckLeaks(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []LeakOption
+		// during runs between the snapshot and the check, returning a function releasing what it
+		// started.
+		during func() (release func())
+		want   string // Substring of the reported failures; empty for none.
+	}{
+		{
+			name:   "no goroutines",
+			during: func() func() { return func() {} },
+		},
+		{
+			name: "leaked goroutine",
+			opts: []LeakOption{WithSettleTimeout(50 * time.Millisecond)},
+			during: func() func() {
+				stop := make(chan struct{})
+				go leakyWorker(stop)
+				return func() { close(stop) }
+			},
+			want: "1 goroutine(s) leaked",
+		},
+		{
+			name: "leaked goroutine stack",
+			opts: []LeakOption{WithSettleTimeout(50 * time.Millisecond)},
+			during: func() func() {
+				stop := make(chan struct{})
+				go leakyWorker(stop)
+				return func() { close(stop) }
+			},
+			want: "plugintest.leakyWorker",
+		},
+		{
+			name: "goroutine exiting within the settle timeout",
+			opts: []LeakOption{WithSettleTimeout(5 * time.Second)},
+			during: func() func() {
+				go time.Sleep(20 * time.Millisecond)
+				return func() {}
+			},
+		},
+		{
+			name: "ignored goroutine",
+			opts: []LeakOption{WithSettleTimeout(50 * time.Millisecond), IgnoreGoroutines("leakyWorker")},
+			during: func() func() {
+				stop := make(chan struct{})
+				go leakyWorker(stop)
+				return func() { close(stop) }
+			},
+		},
+		{
+			name: "heap growth within the limit",
+			opts: []LeakOption{WithMaxHeapGrowth(64 << 20)},
+			during: func() func() {
+				heapSink = append(heapSink, make([]byte, 1<<20))
+				return func() { heapSink = nil }
+			},
+		},
+		{
+			name: "heap growth over the limit",
+			opts: []LeakOption{WithMaxHeapGrowth(1 << 20)},
+			during: func() func() {
+				heapSink = append(heapSink, make([]byte, 16<<20))
+				return func() { heapSink = nil }
+			},
+			want: "live heap grew",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var release func()
+			ft := &fakeT{TB: t}
+			ft.run(func() {
+				CheckLeaks(ft, tt.opts...)
+				release = tt.during()
+			})
+			release()
+
+			got := ft.failures()
+			switch {
+			case tt.want == "" && got != "":
+				t.Errorf("CheckLeaks reported:\n%s", got)
+			case tt.want != "" && !strings.Contains(got, tt.want):
+				t.Errorf("CheckLeaks reported %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package plugintest
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// StressOption configures Stress.
+type StressOption func(*stressOptions)
+
+type stressOptions struct {
+	workers   int
+	duration  time.Duration
+	configs   []*mcpdpluginsv1.PluginConfig
+	requests  []*mcpdpluginsv1.HTTPRequest
+	responses []*mcpdpluginsv1.HTTPResponse
+	noStop    bool
+}
+
+// WithWorkers sets the number of concurrent callers (default 4 × GOMAXPROCS).
+func WithWorkers(n int) StressOption {
+	return func(o *stressOptions) {
+		o.workers = n
+	}
+}
+
+// WithDuration sets how long Stress runs (default 1s).
+func WithDuration(d time.Duration) StressOption {
+	return func(o *stressOptions) {
+		o.duration = d
+	}
+}
+
+// WithConfigs sets the configurations passed to Configure, chosen at random on each call
+// (default a single empty PluginConfig).
+func WithConfigs(cfgs ...*mcpdpluginsv1.PluginConfig) StressOption {
+	return func(o *stressOptions) {
+		o.configs = cfgs
+	}
+}
+
+// WithRequests sets the requests passed to HandleRequest, chosen at random on each call.
+func WithRequests(reqs ...*mcpdpluginsv1.HTTPRequest) StressOption {
+	return func(o *stressOptions) {
+		o.requests = reqs
+	}
+}
+
+// WithResponses sets the responses passed to HandleResponse, chosen at random on each call.
+func WithResponses(resps ...*mcpdpluginsv1.HTTPResponse) StressOption {
+	return func(o *stressOptions) {
+		o.responses = resps
+	}
+}
+
+// WithoutStop leaves Stop out of the mix, for plugins that cannot serve calls once stopped.
+func WithoutStop() StressOption {
+	return func(o *stressOptions) {
+		o.noStop = true
+	}
+}
+
+// StressResult counts the calls made by Stress.
+type StressResult struct {
+	Calls  map[string]int64
+	Errors map[string]int64
+}
+
+// Stress calls Configure, HandleRequest, HandleResponse, CheckHealth, CheckReady and Stop on impl
+// concurrently from many goroutines, so that lifecycle races (such as Configure swapping state
+// while requests read it, or Stop racing in-flight calls) are detected when tests run with -race:
+//
+//	func TestPluginRaces(t *testing.T) {
+//	    plugintest.Stress(t, &MyPlugin{},
+//	        plugintest.WithConfigs(cfgA, cfgB),
+//	        plugintest.WithRequests(&mcpdpluginsv1.HTTPRequest{Method: "POST", Path: "/mcp"}),
+//	    )
+//	}
+//
+// Errors returned by handlers are counted but not treated as failures. Stress fails t when a
+// handler panics, or when HandleRequest or HandleResponse returns neither a response nor an error.
+func Stress(t testing.TB, impl mcpdpluginsv1.PluginServer, opts ...StressOption) StressResult {
+	t.Helper()
+
+	o := &stressOptions{
+		workers:  4 * runtime.GOMAXPROCS(0),
+		duration: time.Second,
+		configs:  []*mcpdpluginsv1.PluginConfig{{}},
+		requests: []*mcpdpluginsv1.HTTPRequest{{
+			Method:  "POST",
+			Path:    "/mcp",
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{}}}`),
+		}},
+		responses: []*mcpdpluginsv1.HTTPResponse{{
+			StatusCode: 200,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Body:       []byte(`{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"ok"}]}}`),
+		}},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.workers < 1 || len(o.configs) == 0 || len(o.requests) == 0 || len(o.responses) == 0 {
+		t.Fatalf("plugintest: Stress needs at least one worker, config, request and response")
+	}
+
+	ops := []string{"Configure", "HandleRequest", "HandleResponse", "CheckHealth", "CheckReady"}
+	if !o.noStop {
+		ops = append(ops, "Stop")
+	}
+
+	var (
+		calls, errs [6]atomic.Int64
+		failures    atomic.Int64
+		wg          sync.WaitGroup
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), o.duration)
+	defer cancel()
+
+	for range o.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := rand.IntN(len(ops))
+				calls[i].Add(1)
+				err := stressCall(ctx, impl, ops[i], o)
+				if err == nil {
+					continue
+				}
+				if _, isFailure := err.(stressFailure); isFailure {
+					if failures.Add(1) <= 10 {
+						t.Errorf("plugintest: %v", err)
+					}
+					continue
+				}
+				errs[i].Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	res := StressResult{Calls: map[string]int64{}, Errors: map[string]int64{}}
+	for i, op := range ops {
+		res.Calls[op] = calls[i].Load()
+		res.Errors[op] = errs[i].Load()
+	}
+	if n := failures.Load(); n > 10 {
+		t.Errorf("plugintest: %d further failures not shown", n-10)
+	}
+
+	return res
+}
+
+// stressFailure is a contract violation, as opposed to an error returned by the plugin.
+type stressFailure string
+
+func (f stressFailure) Error() string {
+	return string(f)
+}
+
+func stressCall(ctx context.Context, impl mcpdpluginsv1.PluginServer, op string, o *stressOptions) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = stressFailure(fmt.Sprintf("%s panicked: %v\n%s", op, p, debug.Stack()))
+		}
+	}()
+
+	switch op {
+	case "Configure":
+		_, err = impl.Configure(ctx, pick(o.configs))
+	case "HandleRequest":
+		var resp *mcpdpluginsv1.HTTPResponse
+		if resp, err = impl.HandleRequest(ctx, pick(o.requests)); resp == nil && err == nil {
+			return stressFailure("HandleRequest returned a nil response and a nil error")
+		}
+	case "HandleResponse":
+		var resp *mcpdpluginsv1.HTTPResponse
+		if resp, err = impl.HandleResponse(ctx, pick(o.responses)); resp == nil && err == nil {
+			return stressFailure("HandleResponse returned a nil response and a nil error")
+		}
+	case "CheckHealth":
+		_, err = impl.CheckHealth(ctx, &emptypb.Empty{})
+	case "CheckReady":
+		_, err = impl.CheckReady(ctx, &emptypb.Empty{})
+	case "Stop":
+		_, err = impl.Stop(ctx, &emptypb.Empty{})
+	}
+
+	return err
+}
+
+// pick returns a copy of a random element of msgs, so handlers may modify their input.
+func pick[M proto.Message](msgs []M) M {
+	return proto.Clone(msgs[rand.IntN(len(msgs))]).(M)
+}
@@ -0,0 +1,207 @@
+package plugintest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// stressPlugin is a BasePlugin whose handlers can be replaced per test, counting Stop calls.
+type stressPlugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	configure      func(*mcpdpluginsv1.PluginConfig) error
+	handleRequest  func(*mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error)
+	handleResponse func(*mcpdpluginsv1.HTTPResponse) (*mcpdpluginsv1.HTTPResponse, error)
+	stops          atomic.Int64
+}
+
+func (p *stressPlugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	if p.configure != nil {
+		if err := p.configure(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (p *stressPlugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	if p.handleRequest != nil {
+		return p.handleRequest(req)
+	}
+
+	return p.BasePlugin.HandleRequest(ctx, req)
+}
+
+func (p *stressPlugin) HandleResponse(
+	ctx context.Context,
+	resp *mcpdpluginsv1.HTTPResponse,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	if p.handleResponse != nil {
+		return p.handleResponse(resp)
+	}
+
+	return p.BasePlugin.HandleResponse(ctx, resp)
+}
+
+func (p *stressPlugin) Stop(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	p.stops.Add(1)
+
+	return &emptypb.Empty{}, nil
+}
+
+func TestStress(t *testing.T) {
+	errConfig := errors.New("bad config")
+	shared := &mcpdpluginsv1.HTTPRequest{Headers: map[string]string{"X-Test": "1"}}
+
+	tests := []struct {
+		name   string
+		plugin *stressPlugin
+		opts   []StressOption
+		want   string // Substring of the reported failures; empty for none.
+		check  func(t *testing.T, p *stressPlugin, res StressResult)
+	}{
+		{
+			name:   "well-behaved plugin",
+			plugin: &stressPlugin{},
+			check: func(t *testing.T, p *stressPlugin, res StressResult) {
+				ops := []string{"Configure", "HandleRequest", "HandleResponse", "CheckHealth", "CheckReady", "Stop"}
+				for _, op := range ops {
+					if res.Calls[op] == 0 {
+						t.Errorf("%s was never called", op)
+					}
+					if res.Errors[op] != 0 {
+						t.Errorf("%s returned %d errors", op, res.Errors[op])
+					}
+				}
+				if got := p.stops.Load(); got != res.Calls["Stop"] {
+					t.Errorf("plugin saw %d Stop calls, result counts %d", got, res.Calls["Stop"])
+				}
+			},
+		},
+		{
+			name:   "handler errors are counted, not failures",
+			plugin: &stressPlugin{configure: func(*mcpdpluginsv1.PluginConfig) error { return errConfig }},
+			check: func(t *testing.T, _ *stressPlugin, res StressResult) {
+				if calls, errs := res.Calls["Configure"], res.Errors["Configure"]; calls == 0 || errs != calls {
+					t.Errorf("Configure: %d errors in %d calls, want every call", errs, calls)
+				}
+			},
+		},
+		{
+			name:   "without stop",
+			plugin: &stressPlugin{},
+			opts:   []StressOption{WithoutStop()},
+			check: func(t *testing.T, p *stressPlugin, res StressResult) {
+				if _, ok := res.Calls["Stop"]; ok {
+					t.Errorf("result counts Stop calls: %v", res.Calls)
+				}
+				if got := p.stops.Load(); got != 0 {
+					t.Errorf("Stop was called %d times", got)
+				}
+			},
+		},
+		{
+			name: "inputs are copies",
+			plugin: &stressPlugin{
+				handleRequest: func(req *mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error) {
+					req.Headers["X-Seen"] = "1"
+					return &mcpdpluginsv1.HTTPResponse{Continue: true}, nil
+				},
+			},
+			opts: []StressOption{WithRequests(shared)},
+			check: func(t *testing.T, _ *stressPlugin, _ StressResult) {
+				if _, ok := shared.Headers["X-Seen"]; ok {
+					t.Error("handler modified the request passed to WithRequests")
+				}
+			},
+		},
+		{
+			name: "nil request result",
+			plugin: &stressPlugin{
+				handleRequest: func(*mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error) {
+					return nil, nil
+				},
+			},
+			want: "HandleRequest returned a nil response and a nil error",
+		},
+		{
+			name: "nil response result",
+			plugin: &stressPlugin{
+				handleResponse: func(*mcpdpluginsv1.HTTPResponse) (*mcpdpluginsv1.HTTPResponse, error) {
+					return nil, nil
+				},
+			},
+			want: "HandleResponse returned a nil response and a nil error",
+		},
+		{
+			name: "panic",
+			plugin: &stressPlugin{
+				handleResponse: func(*mcpdpluginsv1.HTTPResponse) (*mcpdpluginsv1.HTTPResponse, error) {
+					panic("boom")
+				},
+			},
+			want: "HandleResponse panicked: boom",
+		},
+		{
+			name:   "no workers",
+			plugin: &stressPlugin{},
+			opts:   []StressOption{WithWorkers(0)},
+			want:   "Stress needs at least one worker",
+		},
+		{
+			name:   "no requests",
+			plugin: &stressPlugin{},
+			opts:   []StressOption{WithRequests()},
+			want:   "Stress needs at least one worker, config, request and response",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]StressOption{WithWorkers(4), WithDuration(50 * time.Millisecond)}, tt.opts...)
+			var res StressResult
+			ft := &fakeT{TB: t}
+			ft.run(func() { res = Stress(ft, tt.plugin, opts...) })
+
+			got := ft.failures()
+			switch {
+			case tt.want == "" && got != "":
+				t.Fatalf("Stress reported:\n%s", got)
+			case tt.want != "" && !strings.Contains(got, tt.want):
+				t.Fatalf("Stress reported %q, want it to contain %q", got, tt.want)
+			}
+			if tt.check != nil {
+				tt.check(t, tt.plugin, res)
+			}
+		})
+	}
+}
+
+func TestStressFailureCap(t *testing.T) {
+	p := &stressPlugin{handleRequest: func(*mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error) {
+		return nil, nil
+	}}
+	ft := &fakeT{TB: t}
+	ft.run(func() { Stress(ft, p, WithWorkers(4), WithDuration(50*time.Millisecond)) })
+
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	// Ten failures are shown, then a summary of the rest.
+	if len(ft.errors) != 11 {
+		t.Fatalf("Stress reported %d failures, want 11", len(ft.errors))
+	}
+	if !strings.Contains(ft.errors[10], "further failures not shown") {
+		t.Errorf("last failure = %q, want a summary", ft.errors[10])
+	}
+}
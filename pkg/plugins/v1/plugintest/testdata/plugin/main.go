@@ -0,0 +1,46 @@
+// Command plugin is a minimal plugin served with mcpdpluginsv1.Serve, built by the plugintest
+// tests to run the conformance suite against a real binary.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+var deny = flag.Bool("deny", false, "Deny every request with 403")
+
+type plugin struct {
+	mcpdpluginsv1.BasePlugin
+}
+
+func (p *plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{Name: "testdata-plugin", Version: "1.0.0"}, nil
+}
+
+func (p *plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest, mcpdpluginsv1.FlowResponse), nil
+}
+
+func (p *plugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	if *deny {
+		return mcpdpluginsv1.Deny(req, http.StatusForbidden, mcp.CodeServerError, "denied"), nil
+	}
+
+	return p.BasePlugin.HandleRequest(ctx, req)
+}
+
+func main() {
+	if err := mcpdpluginsv1.Serve(&plugin{}); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,186 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/mem"
+	"google.golang.org/grpc/status"
+)
+
+// WithMessagePooling makes Serve decode HandleRequest and HandleResponse inputs into messages
+// drawn from a pool, reusing their header maps and body buffers, and return them to the pool as
+// soon as the handler's reply has been encoded. It reduces GC pressure in plugins handling tens
+// of thousands of calls per second.
+//
+// A pooled message, including its Headers map and Body, is only valid until the call completes:
+// handlers and event subscribers that keep any of it, for example to process it on another
+// goroutine, must copy it first with CloneRequest or CloneResponse. For that reason pooling
+// cannot be combined with WithCandidate, whose comparisons outlive the call.
+func WithMessagePooling() ServeOption {
+	return func(o *serveOptions) error {
+		o.pooling = true
+		return nil
+	}
+}
+
+var (
+	requestPool  = sync.Pool{New: func() any { return new(HTTPRequest) }}
+	responsePool = sync.Pool{New: func() any { return new(HTTPResponse) }}
+)
+
+// Limits on what pooled messages keep, so one unusually large message does not pin its map or
+// body for the life of the process.
+const (
+	maxPooledHeaders = 64
+	maxPooledBody    = 256 << 10
+)
+
+// poolServerOptions returns the gRPC server options and the service description that serve the
+// plugin with pooled messages.
+func poolServerOptions() ([]grpc.ServerOption, *grpc.ServiceDesc) {
+	codec := poolCodec{vtCodec: newVTCodec()}
+
+	desc := Plugin_ServiceDesc
+	desc.Methods = make([]grpc.MethodDesc, len(Plugin_ServiceDesc.Methods))
+	copy(desc.Methods, Plugin_ServiceDesc.Methods)
+	for i, m := range desc.Methods {
+		switch m.MethodName {
+		case "HandleRequest":
+			desc.Methods[i].Handler = codec.handleRequest
+		case "HandleResponse":
+			desc.Methods[i].Handler = codec.handleResponse
+		}
+	}
+
+	return []grpc.ServerOption{grpc.ForceServerCodecV2(codec)}, &desc
+}
+
+// encodedMessage is a reply marshalled by a pooled handler before it released its input, which
+// the reply may share headers or body with.
+type encodedMessage struct {
+	data mem.BufferSlice
+}
+
+func (c poolCodec) handleRequest(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	in := requestPool.Get().(*HTTPRequest)
+	defer func() {
+		if resetRequest(in) {
+			requestPool.Put(in)
+		}
+	}()
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+
+	var resp any
+	var err error
+	if interceptor == nil {
+		resp, err = srv.(PluginServer).HandleRequest(ctx, in)
+	} else {
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: Plugin_HandleRequest_FullMethodName}
+		resp, err = interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+			return srv.(PluginServer).HandleRequest(ctx, req.(*HTTPRequest))
+		})
+	}
+
+	return c.encode(resp, err)
+}
+
+func (c poolCodec) handleResponse(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	in := responsePool.Get().(*HTTPResponse)
+	defer func() {
+		if resetResponse(in) {
+			responsePool.Put(in)
+		}
+	}()
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+
+	var resp any
+	var err error
+	if interceptor == nil {
+		resp, err = srv.(PluginServer).HandleResponse(ctx, in)
+	} else {
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: Plugin_HandleResponse_FullMethodName}
+		resp, err = interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+			return srv.(PluginServer).HandleResponse(ctx, req.(*HTTPResponse))
+		})
+	}
+
+	return c.encode(resp, err)
+}
+
+// encode marshals a handler's reply while its pooled input is still valid.
+func (c poolCodec) encode(resp any, err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.vtCodec.Marshal(resp)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to marshal response: %v", err)
+	}
+
+	return &encodedMessage{data: data}, nil
+}
+
+// resetRequest clears m for reuse, keeping its header map and body buffer, and reports whether it
+// is worth pooling.
+func resetRequest(m *HTTPRequest) bool {
+	h, body := m.Headers, m.Body
+	m.Reset()
+	if len(h) > maxPooledHeaders || cap(body) > maxPooledBody {
+		return false
+	}
+	if h != nil {
+		clear(h)
+		m.Headers = h
+	}
+	m.Body = body[:0]
+
+	return true
+}
+
+// resetResponse clears m for reuse like resetRequest. A ModifiedRequest is not kept.
+func resetResponse(m *HTTPResponse) bool {
+	h, body := m.Headers, m.Body
+	m.Reset()
+	if len(h) > maxPooledHeaders || cap(body) > maxPooledBody {
+		return false
+	}
+	if h != nil {
+		clear(h)
+		m.Headers = h
+	}
+	m.Body = body[:0]
+
+	return true
+}
+
+// poolCodec is the vtCodec, decoding pooled messages by merging into them once resetRequest or
+// resetResponse has cleared them, so their header maps and body buffers are reused, and passing
+// replies encoded by its handlers through.
+type poolCodec struct {
+	vtCodec
+}
+
+func (c poolCodec) Marshal(v any) (mem.BufferSlice, error) {
+	if em, ok := v.(*encodedMessage); ok {
+		return em.data, nil
+	}
+
+	return c.vtCodec.Marshal(v)
+}
@@ -0,0 +1,223 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"net"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestResetRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      *HTTPRequest
+		wantPool bool
+	}{
+		{name: "empty", msg: &HTTPRequest{}, wantPool: true},
+		{
+			name: "full",
+			msg: &HTTPRequest{
+				Method:  "POST",
+				Url:     "http://upstream/mcp",
+				Path:    "/mcp",
+				Headers: map[string]string{"Content-Type": "application/json"},
+				Body:    make([]byte, 10, 64),
+			},
+			wantPool: true,
+		},
+		{name: "too many headers", msg: &HTTPRequest{Headers: manyHeaders(maxPooledHeaders + 1)}},
+		{name: "body too large", msg: &HTTPRequest{Body: make([]byte, 0, maxPooledBody+1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, body := tt.msg.Headers, tt.msg.Body
+			if got := resetRequest(tt.msg); got != tt.wantPool {
+				t.Fatalf("resetRequest = %t, want %t", got, tt.wantPool)
+			}
+			if !tt.wantPool {
+				return
+			}
+			if tt.msg.Method != "" || tt.msg.Url != "" || tt.msg.Path != "" || len(tt.msg.Headers) != 0 ||
+				len(tt.msg.Body) != 0 {
+				t.Errorf("reset message = %v, want it empty", tt.msg)
+			}
+			if h != nil && reflect.ValueOf(tt.msg.Headers).Pointer() != reflect.ValueOf(h).Pointer() {
+				t.Error("header map not kept")
+			}
+			if cap(body) > 0 && cap(tt.msg.Body) != cap(body) {
+				t.Error("body buffer not kept")
+			}
+		})
+	}
+}
+
+func TestResetResponse(t *testing.T) {
+	m := &HTTPResponse{
+		StatusCode:      200,
+		Headers:         map[string]string{"Content-Type": "application/json"},
+		Body:            []byte("{}"),
+		Continue:        true,
+		ModifiedRequest: &HTTPRequest{Method: "GET"},
+	}
+	h := m.Headers
+	if !resetResponse(m) {
+		t.Fatal("resetResponse did not pool a small response")
+	}
+	if m.StatusCode != 0 || m.Continue || m.ModifiedRequest != nil || len(m.Headers) != 0 || len(m.Body) != 0 {
+		t.Errorf("reset response = %v, want it empty", m)
+	}
+	if reflect.ValueOf(m.Headers).Pointer() != reflect.ValueOf(h).Pointer() {
+		t.Error("header map not kept")
+	}
+
+	if resetResponse(&HTTPResponse{Headers: manyHeaders(maxPooledHeaders + 1)}) {
+		t.Error("resetResponse pooled a response with too many headers")
+	}
+	if resetResponse(&HTTPResponse{Body: make([]byte, 0, maxPooledBody+1)}) {
+		t.Error("resetResponse pooled a response with a large body")
+	}
+}
+
+func manyHeaders(n int) map[string]string {
+	h := make(map[string]string, n)
+	for i := range n {
+		h["X-Header-"+strings.Repeat("a", i)] = "v"
+	}
+
+	return h
+}
+
+// echoPlugin replies with its input's headers and body, sharing them as pooled handlers may.
+type echoPlugin struct {
+	BasePlugin
+}
+
+func (p *echoPlugin) HandleRequest(_ context.Context, req *HTTPRequest) (*HTTPResponse, error) {
+	if req.GetMethod() == "FAIL" {
+		return nil, status.Error(codes.InvalidArgument, "bad request")
+	}
+
+	return &HTTPResponse{Continue: true, Headers: req.Headers, Body: req.Body}, nil
+}
+
+func (p *echoPlugin) HandleResponse(_ context.Context, resp *HTTPResponse) (*HTTPResponse, error) {
+	return &HTTPResponse{StatusCode: resp.GetStatusCode(), Headers: resp.Headers, Body: resp.Body}, nil
+}
+
+// servePooled serves p with pooled messages on a unix socket and returns a client for it.
+func servePooled(t *testing.T, opts ...grpc.ServerOption) PluginClient {
+	t.Helper()
+
+	address := filepath.Join(t.TempDir(), "plugin.sock")
+	lis, err := net.Listen("unix", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poolOpts, desc := poolServerOptions()
+	srv := grpc.NewServer(append(opts, poolOpts...)...)
+	srv.RegisterService(desc, &echoPlugin{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("unix://"+address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return NewPluginClient(conn)
+}
+
+func TestPooledServer(t *testing.T) {
+	var (
+		mu          sync.Mutex
+		intercepted []string
+	)
+	interceptor := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+		mu.Lock()
+		intercepted = append(intercepted, info.FullMethod)
+		mu.Unlock()
+		return h(ctx, req)
+	}
+	tests := []struct {
+		name string
+		opts []grpc.ServerOption
+		want []string
+	}{
+		{name: "without interceptors"},
+		{
+			name: "with an interceptor",
+			opts: []grpc.ServerOption{grpc.UnaryInterceptor(interceptor)},
+			want: []string{
+				Plugin_HandleRequest_FullMethodName,
+				Plugin_HandleRequest_FullMethodName,
+				Plugin_HandleResponse_FullMethodName,
+				Plugin_HandleResponse_FullMethodName,
+				Plugin_HandleRequest_FullMethodName,
+				Plugin_CheckHealth_FullMethodName,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			intercepted = nil
+			mu.Unlock()
+			client := servePooled(t, tt.opts...)
+			ctx := context.Background()
+
+			// Replies are encoded before the pooled input is reused, and nothing leaks between calls.
+			big := &HTTPRequest{Headers: map[string]string{"A": "1", "B": "2"}, Body: []byte(`{"long":"body"}`)}
+			small := &HTTPRequest{Headers: map[string]string{"C": "3"}, Body: []byte("x")}
+			for _, req := range []*HTTPRequest{big, small} {
+				got, err := client.HandleRequest(ctx, req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !maps.Equal(got.GetHeaders(), req.GetHeaders()) || !bytes.Equal(got.GetBody(), req.GetBody()) {
+					t.Errorf("HandleRequest = %v, want the input echoed", got)
+				}
+			}
+			for _, resp := range []*HTTPResponse{
+				{StatusCode: 201, Headers: map[string]string{"A": "1"}, Body: []byte("created")},
+				{StatusCode: 204},
+			} {
+				got, err := client.HandleResponse(ctx, resp)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := proto.Clone(resp).(*HTTPResponse)
+				if !proto.Equal(got, want) {
+					t.Errorf("HandleResponse = %v, want %v", got, want)
+				}
+			}
+
+			_, err := client.HandleRequest(ctx, &HTTPRequest{Method: "FAIL"})
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("failing HandleRequest = %v, want InvalidArgument", err)
+			}
+			// Other RPCs use the default handlers and codec.
+			if _, err := client.CheckHealth(ctx, &emptypb.Empty{}); err != nil {
+				t.Errorf("CheckHealth = %v", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if !slices.Equal(intercepted, tt.want) {
+				t.Errorf("intercepted %v, want %v", intercepted, tt.want)
+			}
+		})
+	}
+}
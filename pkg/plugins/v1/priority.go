@@ -0,0 +1,259 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// PriorityMetadataKey is the gRPC request metadata key with which mcpd classifies a call's
+// priority (such as PriorityInteractive or PriorityBulk).
+const PriorityMetadataKey = "mcpd-priority"
+
+// Priority classes with default scheduling weights.
+const (
+	PriorityInteractive = "interactive"
+	PriorityNormal      = "normal"
+	PriorityBulk        = "bulk"
+)
+
+// Priority returns the priority mcpd attached to the current call, lowercased, or an empty
+// string when none was sent.
+func Priority(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(PriorityMetadataKey); len(v) > 0 {
+			return strings.ToLower(strings.TrimSpace(v[0]))
+		}
+	}
+
+	return ""
+}
+
+// SchedulerConfig configures WithPriorityScheduling.
+type SchedulerConfig struct {
+	// MaxConcurrent is the number of HandleRequest and HandleResponse calls run at once.
+	// Further calls wait in a queue per priority.
+	MaxConcurrent int
+
+	// Weights maps priority classes to their share of freed slots. When several classes are
+	// waiting, a class with weight 8 is admitted eight times as often as one with weight 1.
+	// Defaults to interactive=8, normal=4, bulk=1.
+	Weights map[string]int
+
+	// Default is the class of calls without a priority or with one missing from Weights
+	// (default PriorityNormal).
+	Default string
+
+	// MaxQueue is the number of calls each class may have waiting; further calls are rejected
+	// with Throttle (default 100).
+	MaxQueue int
+
+	// RetryAfter is the delay suggested in calls rejected because their queue is full (default 1s).
+	RetryAfter time.Duration
+}
+
+// WithPriorityScheduling runs at most cfg.MaxConcurrent HandleRequest and HandleResponse calls at
+// once and, when the plugin is the bottleneck, admits waiting calls by weighted round robin over
+// the priority mcpd sends in PriorityMetadataKey, so interactive traffic is not starved behind
+// bulk tool calls. A waiting call ends early when its context is done. Queue waits are recorded
+// as metrics.QueueWait, labelled by priority, when WithMetrics is configured.
+func WithPriorityScheduling(cfg SchedulerConfig) ServeOption {
+	return func(o *serveOptions) error {
+		s, err := newScheduler(cfg)
+		if err != nil {
+			return err
+		}
+		o.interceptors = append(o.interceptors, s.interceptor(o))
+		return nil
+	}
+}
+
+// scheduler hands out run slots to queued calls by smooth weighted round robin.
+type scheduler struct {
+	cfg SchedulerConfig
+
+	mu      sync.Mutex
+	free    int
+	classes map[string]*priorityClass
+
+	// order lists classes by name so ties are broken deterministically.
+	order []*priorityClass
+}
+
+type priorityClass struct {
+	name    string
+	weight  int
+	current int
+	waiting []chan struct{}
+}
+
+func newScheduler(cfg SchedulerConfig) (*scheduler, error) {
+	if cfg.MaxConcurrent <= 0 {
+		return nil, fmt.Errorf("scheduler MaxConcurrent must be positive")
+	}
+	if cfg.MaxQueue < 0 || cfg.RetryAfter < 0 {
+		return nil, fmt.Errorf("scheduler settings cannot be negative")
+	}
+	if len(cfg.Weights) == 0 {
+		cfg.Weights = map[string]int{PriorityInteractive: 8, PriorityNormal: 4, PriorityBulk: 1}
+	}
+	if cfg.Default == "" {
+		cfg.Default = PriorityNormal
+	}
+	if cfg.MaxQueue == 0 {
+		cfg.MaxQueue = 100
+	}
+	if cfg.RetryAfter == 0 {
+		cfg.RetryAfter = time.Second
+	}
+	if _, ok := cfg.Weights[cfg.Default]; !ok {
+		return nil, fmt.Errorf("default priority %q has no weight", cfg.Default)
+	}
+
+	s := &scheduler{cfg: cfg, free: cfg.MaxConcurrent, classes: make(map[string]*priorityClass)}
+	for name, w := range cfg.Weights {
+		if w <= 0 {
+			return nil, fmt.Errorf("weight of priority %q must be positive", name)
+		}
+		c := &priorityClass{name: name, weight: w}
+		s.classes[name] = c
+		s.order = append(s.order, c)
+	}
+	sort.Slice(s.order, func(i, j int) bool { return s.order[i].name < s.order[j].name })
+
+	return s, nil
+}
+
+// class returns the class a call with priority p is queued in.
+func (s *scheduler) class(p string) *priorityClass {
+	if c, ok := s.classes[p]; ok {
+		return c
+	}
+
+	return s.classes[s.cfg.Default]
+}
+
+func (s *scheduler) interceptor(o *serveOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !isHandlerMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		c := s.class(Priority(ctx))
+		start := time.Now()
+		queued, err := s.acquire(ctx, c)
+		if queued {
+			if r := o.metricsRecorder(); r != nil {
+				r.Timing(metrics.QueueWait, time.Since(start),
+					metrics.L(metrics.LabelMethod, path.Base(info.FullMethod)),
+					metrics.L(metrics.LabelPriority, c.name),
+				)
+			}
+		}
+		if err != nil {
+			if errors.Is(err, errQueueFull) {
+				return nil, o.throttle(ctx, info, s.cfg.RetryAfter, ThrottleQueue)
+			}
+			return nil, status.FromContextError(err).Err()
+		}
+		defer s.release()
+
+		return handler(ctx, req)
+	}
+}
+
+// errQueueFull is returned by acquire when the class has cfg.MaxQueue calls waiting.
+var errQueueFull = errors.New("priority queue full")
+
+// acquire takes a run slot for a call of class c, waiting for one when none is free. It reports
+// whether the call had to queue.
+func (s *scheduler) acquire(ctx context.Context, c *priorityClass) (bool, error) {
+	s.mu.Lock()
+	if s.free > 0 && s.idle() {
+		s.free--
+		s.mu.Unlock()
+		return false, nil
+	}
+	if len(c.waiting) >= s.cfg.MaxQueue {
+		s.mu.Unlock()
+		return false, errQueueFull
+	}
+	ready := make(chan struct{})
+	c.waiting = append(c.waiting, ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return true, nil
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, w := range c.waiting {
+		if w == ready {
+			c.waiting = append(c.waiting[:i], c.waiting[i+1:]...)
+			return true, ctx.Err()
+		}
+	}
+	// The slot was handed over as the context ended: pass it on.
+	s.handOff()
+
+	return true, ctx.Err()
+}
+
+// release returns a run slot, handing it to the next waiting call.
+func (s *scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handOff()
+}
+
+// handOff gives a slot to the waiting call picked by smooth weighted round robin, or frees it
+// when nothing waits. s.mu must be held.
+func (s *scheduler) handOff() {
+	var next *priorityClass
+	total := 0
+	for _, c := range s.order {
+		if len(c.waiting) == 0 {
+			continue
+		}
+		c.current += c.weight
+		total += c.weight
+		if next == nil || c.current > next.current {
+			next = c
+		}
+	}
+	if next == nil {
+		s.free++
+		return
+	}
+	next.current -= total
+
+	ready := next.waiting[0]
+	next.waiting = next.waiting[1:]
+	close(ready)
+}
+
+// idle reports whether no call is waiting. s.mu must be held.
+func (s *scheduler) idle() bool {
+	for _, c := range s.order {
+		if len(c.waiting) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,333 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+func TestPriority(t *testing.T) {
+	tests := []struct {
+		name string
+		md   metadata.MD
+		want string
+	}{
+		{name: "no metadata"},
+		{name: "no priority", md: metadata.Pairs("other", "x")},
+		{name: "interactive", md: metadata.Pairs(PriorityMetadataKey, "interactive"), want: PriorityInteractive},
+		{name: "normalized", md: metadata.Pairs(PriorityMetadataKey, " Bulk "), want: PriorityBulk},
+		{
+			name: "first value",
+			md:   metadata.Pairs(PriorityMetadataKey, "bulk", PriorityMetadataKey, "interactive"),
+			want: PriorityBulk,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			if got := Priority(ctx); got != tt.want {
+				t.Errorf("Priority = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewScheduler(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     SchedulerConfig
+		wantErr string
+	}{
+		{name: "defaults", cfg: SchedulerConfig{MaxConcurrent: 1}},
+		{name: "custom classes", cfg: SchedulerConfig{MaxConcurrent: 1, Weights: map[string]int{"a": 1}, Default: "a"}},
+		{name: "no concurrency", cfg: SchedulerConfig{}, wantErr: "MaxConcurrent must be positive"},
+		{name: "negative queue", cfg: SchedulerConfig{MaxConcurrent: 1, MaxQueue: -1}, wantErr: "cannot be negative"},
+		{
+			name:    "negative retry",
+			cfg:     SchedulerConfig{MaxConcurrent: 1, RetryAfter: -time.Second},
+			wantErr: "cannot be negative",
+		},
+		{
+			name:    "default without weight",
+			cfg:     SchedulerConfig{MaxConcurrent: 1, Weights: map[string]int{"a": 1}},
+			wantErr: `default priority "normal" has no weight`,
+		},
+		{
+			name:    "zero weight",
+			cfg:     SchedulerConfig{MaxConcurrent: 1, Weights: map[string]int{"a": 1, "b": 0}, Default: "a"},
+			wantErr: `weight of priority "b" must be positive`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newServeOptions(WithPriorityScheduling(tt.cfg))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("newServeOptions: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("newServeOptions error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	s, err := newScheduler(SchedulerConfig{MaxConcurrent: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.cfg.MaxQueue != 100 || s.cfg.RetryAfter != time.Second || s.free != 3 {
+		t.Errorf("scheduler = %+v, want the defaults", s.cfg)
+	}
+	classes := map[string]string{"interactive": "interactive", "bulk": "bulk", "": "normal", "urgent": "normal"}
+	for p, want := range classes {
+		if got := s.class(p).name; got != want {
+			t.Errorf("class(%q) = %s, want %s", p, got, want)
+		}
+	}
+}
+
+// queueCall starts acquiring a slot for class in the background and waits until it is queued.
+// The class name is sent on admitted once the call gets a slot.
+func queueCall(t *testing.T, s *scheduler, class string, admitted chan<- string) {
+	t.Helper()
+
+	c := s.class(class)
+	s.mu.Lock()
+	n := len(c.waiting)
+	s.mu.Unlock()
+
+	go func() {
+		if _, err := s.acquire(context.Background(), c); err != nil {
+			t.Errorf("acquire: %v", err)
+			return
+		}
+		admitted <- class
+	}()
+	waitFor(t, "the call to queue", func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(c.waiting) > n
+	})
+}
+
+func TestSchedulerWeightedOrder(t *testing.T) {
+	s, err := newScheduler(SchedulerConfig{MaxConcurrent: 1, Weights: map[string]int{"a": 2, "b": 1}, Default: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queued, err := s.acquire(context.Background(), s.class("a")); queued || err != nil {
+		t.Fatalf("first acquire = %t, %v; want a free slot", queued, err)
+	}
+
+	admitted := make(chan string)
+	for range 3 {
+		queueCall(t, s, "b", admitted)
+	}
+	for range 3 {
+		queueCall(t, s, "a", admitted)
+	}
+
+	var order []string
+	for range 6 {
+		s.release()
+		order = append(order, <-admitted)
+	}
+	if want := []string{"a", "b", "a", "a", "b", "b"}; !slices.Equal(order, want) {
+		t.Errorf("admission order = %v, want %v", order, want)
+	}
+
+	// With nothing waiting the slot is freed, and a new call takes it without queueing.
+	s.release()
+	if queued, err := s.acquire(context.Background(), s.class("b")); queued || err != nil {
+		t.Errorf("acquire after release = %t, %v; want a free slot", queued, err)
+	}
+}
+
+func TestSchedulerFIFOWithinClass(t *testing.T) {
+	s, err := newScheduler(SchedulerConfig{MaxConcurrent: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.acquire(context.Background(), s.class("")); err != nil {
+		t.Fatal(err)
+	}
+
+	admitted := make(chan string)
+	for _, name := range []string{"first", "second", "third"} {
+		// Unknown priorities share the default class.
+		queueCall(t, s, name, admitted)
+	}
+	for _, want := range []string{"first", "second", "third"} {
+		s.release()
+		if got := <-admitted; got != want {
+			t.Errorf("admitted %s, want %s", got, want)
+		}
+	}
+}
+
+func TestSchedulerCanceledWait(t *testing.T) {
+	s, err := newScheduler(SchedulerConfig{MaxConcurrent: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.acquire(context.Background(), s.class("")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := s.class("")
+	done := make(chan error)
+	go func() {
+		_, err := s.acquire(ctx, c)
+		done <- err
+	}()
+	waitFor(t, "the call to queue", func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(c.waiting) == 1
+	})
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("acquire error = %v, want context.Canceled", err)
+	}
+	s.mu.Lock()
+	if len(c.waiting) != 0 {
+		t.Error("canceled call left in the queue")
+	}
+	s.mu.Unlock()
+
+	// The slot is not lost: releasing frees it for the next call.
+	s.release()
+	if queued, err := s.acquire(context.Background(), c); queued || err != nil {
+		t.Errorf("acquire = %t, %v; want a free slot", queued, err)
+	}
+}
+
+func TestSchedulerHandOffOnCancel(t *testing.T) {
+	s, err := newScheduler(SchedulerConfig{MaxConcurrent: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := s.class("")
+	// A call whose context is already done may still be handed the slot; it passes it on.
+	for range 100 {
+		if _, err := s.acquire(context.Background(), c); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			_, err := s.acquire(ctx, c)
+			done <- err
+		}()
+		waitFor(t, "the call to queue", func() bool {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return len(c.waiting) == 1
+		})
+		cancel()
+		s.release()
+		if err := <-done; err == nil {
+			s.release()
+		}
+		s.mu.Lock()
+		free := s.free
+		s.mu.Unlock()
+		if free != 1 {
+			t.Fatalf("%d free slots after the calls ended, want 1", free)
+		}
+	}
+}
+
+func TestSchedulerInterceptor(t *testing.T) {
+	r := &recorderLog{}
+	o, err := newServeOptions(WithMetrics(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := newScheduler(SchedulerConfig{MaxConcurrent: 1, MaxQueue: 1, RetryAfter: 2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	intercept := s.interceptor(o)
+	info := &grpc.UnaryServerInfo{FullMethod: Plugin_HandleRequest_FullMethodName}
+	bulk := metadata.NewIncomingContext(context.Background(), metadata.Pairs(PriorityMetadataKey, PriorityBulk))
+	ok := func(context.Context, any) (any, error) { return &HTTPResponse{Continue: true}, nil }
+
+	// The first call runs at once and records no queue wait.
+	release := make(chan struct{})
+	running := make(chan struct{})
+	go func() {
+		_, _ = intercept(bulk, &HTTPRequest{}, info, func(context.Context, any) (any, error) {
+			close(running)
+			<-release
+			return &HTTPResponse{Continue: true}, nil
+		})
+	}()
+	<-running
+
+	// The second waits in the bulk queue, filling it.
+	queued := make(chan error)
+	go func() {
+		_, err := intercept(bulk, &HTTPRequest{}, info, ok)
+		queued <- err
+	}()
+	waitFor(t, "the call to queue", func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.class(PriorityBulk).waiting) == 1
+	})
+
+	// The third is throttled.
+	_, err = intercept(bulk, &HTTPRequest{}, info, ok)
+	if d, ok := RetryAfter(err); !ok || d != 2*time.Second {
+		t.Errorf("call to a full queue = %v, want a 2s throttle", err)
+	}
+
+	// A call whose deadline passes while queued fails with its context error.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := intercept(ctx, &HTTPRequest{}, info, ok); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expired queued call = %v, want DeadlineExceeded", err)
+	}
+
+	// Other RPCs bypass the scheduler.
+	configure := &grpc.UnaryServerInfo{FullMethod: Plugin_Configure_FullMethodName}
+	if _, err := intercept(context.Background(), &PluginConfig{}, configure, ok); err != nil {
+		t.Errorf("Configure = %v, want it passed through", err)
+	}
+
+	close(release)
+	if err := <-queued; err != nil {
+		t.Errorf("queued call = %v", err)
+	}
+	waits := r.named(metrics.QueueWait)
+	if len(waits) != 2 {
+		t.Fatalf("recorded %q, want two queue waits", waits)
+	}
+	var priorities []string
+	for _, w := range waits {
+		priorities = append(priorities, w[strings.LastIndex(w, "priority="):])
+	}
+	slices.Sort(priorities)
+	if want := []string{"priority=bulk", "priority=normal"}; !slices.Equal(priorities, want) {
+		t.Errorf("queue waits labelled %v, want %v", priorities, want)
+	}
+	if got := r.named(metrics.Throttled); len(got) != 1 || !strings.Contains(got[0], "reason="+ThrottleQueue) {
+		t.Errorf("recorded %q, want one queue throttle", got)
+	}
+}
@@ -0,0 +1,105 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Paths of the Kubernetes probe endpoints served by WithKubernetesProbes.
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+// probeTimeout bounds a probe's check, for kubelets configured with long probe timeouts.
+const probeTimeout = 5 * time.Second
+
+// WithKubernetesProbes serves HTTP liveness and readiness endpoints on address (host:port) for
+// kubelet HTTP probes: LivenessPath answers from the plugin's CheckHealth and ReadinessPath from
+// its CheckReady, both called through the SDK's interceptor chain, so WithResourceGuard limits and
+// other SDK health reporting apply as they do for mcpd. A passing check answers 200, a failing
+// one 503 with the check's error message as the body.
+//
+// The endpoints are unauthenticated and reveal health check errors, so bind them to the pod's
+// network only:
+//
+//	livenessProbe:
+//	  httpGet: {path: /healthz, port: 8081}
+//	readinessProbe:
+//	  httpGet: {path: /readyz, port: 8081}
+func WithKubernetesProbes(address string) ServeOption {
+	return func(o *serveOptions) error {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return fmt.Errorf("invalid probe address %q: %w", address, err)
+		}
+		o.probes = &probeServer{address: address}
+		return nil
+	}
+}
+
+// probeServer serves the Kubernetes probe endpoints.
+type probeServer struct {
+	address string
+}
+
+// serve starts answering probes with impl's checks run through intercept, returning the function
+// that stops the server.
+func (p *probeServer) serve(o *serveOptions, impl PluginServer, intercept grpc.UnaryServerInterceptor) (func(), error) {
+	lis, err := net.Listen("tcp", p.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for probes on %s: %w", p.address, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(LivenessPath, probeHandler(impl, intercept, Plugin_CheckHealth_FullMethodName, impl.CheckHealth))
+	mux.Handle(ReadinessPath, probeHandler(impl, intercept, Plugin_CheckReady_FullMethodName, impl.CheckReady))
+	s := &http.Server{Handler: mux, ReadHeaderTimeout: probeTimeout, ErrorLog: o.logger}
+	go func() {
+		if err := s.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			o.logger.Printf("probe server stopped: %v", err)
+		}
+	}()
+	o.logger.Printf("Probe server listening on %s", lis.Addr())
+
+	return func() { _ = s.Close() }, nil
+}
+
+// probeHandler answers a probe with check, called as method through intercept.
+func probeHandler(
+	impl PluginServer,
+	intercept grpc.UnaryServerInterceptor,
+	method string,
+	check func(context.Context, *emptypb.Empty) (*emptypb.Empty, error),
+) http.Handler {
+	info := &grpc.UnaryServerInfo{Server: impl, FullMethod: method}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), probeTimeout)
+		defer cancel()
+		_, err := intercept(ctx, &emptypb.Empty{}, info, func(ctx context.Context, req any) (any, error) {
+			return check(ctx, req.(*emptypb.Empty))
+		})
+
+		w.Header().Set("Cache-Control", "no-store")
+		if err != nil {
+			http.Error(w, status.Convert(err).Message(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+}
@@ -0,0 +1,229 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// probedPlugin fails CheckHealth and CheckReady with their errors, when set.
+type probedPlugin struct {
+	BasePlugin
+
+	healthErr error
+	readyErr  error
+}
+
+func (p *probedPlugin) CheckHealth(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	if p.healthErr != nil {
+		return nil, p.healthErr
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (p *probedPlugin) CheckReady(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	if p.readyErr != nil {
+		return nil, p.readyErr
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func TestWithKubernetesProbes(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		wantErr bool
+	}{
+		{name: "host and port", address: "127.0.0.1:8081"},
+		{name: "all interfaces", address: ":8081"},
+		{name: "no port", address: "127.0.0.1", wantErr: true},
+		{name: "empty", address: "", wantErr: true},
+		{name: "path", address: "/run/probes.sock", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(WithKubernetesProbes(tt.address))
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "invalid probe address") {
+					t.Errorf("error = %v, want an invalid probe address", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.probes == nil || o.probes.address != tt.address {
+				t.Errorf("probes = %+v, want address %s", o.probes, tt.address)
+			}
+		})
+	}
+}
+
+func TestProbeHandler(t *testing.T) {
+	passthrough := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+		return h(ctx, req)
+	}
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		plugin     *probedPlugin
+		intercept  grpc.UnaryServerInterceptor
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "healthy", path: LivenessPath, plugin: &probedPlugin{}, wantStatus: http.StatusOK, wantBody: "ok\n"},
+		{name: "ready", path: ReadinessPath, plugin: &probedPlugin{}, wantStatus: http.StatusOK, wantBody: "ok\n"},
+		{
+			name:       "head",
+			method:     http.MethodHead,
+			path:       LivenessPath,
+			plugin:     &probedPlugin{},
+			wantStatus: http.StatusOK,
+			wantBody:   "ok\n", // Discarded by net/http, not the recorder.
+		},
+		{
+			name:       "unhealthy",
+			path:       LivenessPath,
+			plugin:     &probedPlugin{healthErr: errors.New("database down")},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "database down\n",
+		},
+		{
+			name:       "not ready",
+			path:       ReadinessPath,
+			plugin:     &probedPlugin{readyErr: status.Error(codes.Unavailable, "warming up")},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "warming up\n",
+		},
+		{
+			name:       "liveness ignores readiness",
+			path:       LivenessPath,
+			plugin:     &probedPlugin{readyErr: errors.New("warming up")},
+			wantStatus: http.StatusOK,
+			wantBody:   "ok\n",
+		},
+		{
+			name:   "interceptor failure",
+			path:   ReadinessPath,
+			plugin: &probedPlugin{},
+			intercept: func(context.Context, any, *grpc.UnaryServerInfo, grpc.UnaryHandler) (any, error) {
+				return nil, status.Error(codes.ResourceExhausted, "memory limit exceeded")
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   "memory limit exceeded\n",
+		},
+		{
+			name:       "method not allowed",
+			method:     http.MethodPost,
+			path:       LivenessPath,
+			plugin:     &probedPlugin{},
+			wantStatus: http.StatusMethodNotAllowed,
+			wantBody:   "Method Not Allowed\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intercept := tt.intercept
+			if intercept == nil {
+				intercept = passthrough
+			}
+			var methods []string
+			record := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (any, error) {
+				methods = append(methods, info.FullMethod)
+				return intercept(ctx, req, info, h)
+			}
+			check, method := tt.plugin.CheckHealth, Plugin_CheckHealth_FullMethodName
+			if tt.path == ReadinessPath {
+				check, method = tt.plugin.CheckReady, Plugin_CheckReady_FullMethodName
+			}
+			h := probeHandler(tt.plugin, record, method, check)
+
+			reqMethod := tt.method
+			if reqMethod == "" {
+				reqMethod = http.MethodGet
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(reqMethod, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+			if tt.wantStatus == http.StatusMethodNotAllowed {
+				if got := w.Header().Get("Allow"); got != "GET, HEAD" {
+					t.Errorf("Allow = %q, want GET, HEAD", got)
+				}
+				if len(methods) != 0 {
+					t.Errorf("check called for a %s request", reqMethod)
+				}
+				return
+			}
+			if got := w.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control = %q, want no-store", got)
+			}
+			if len(methods) != 1 || methods[0] != method {
+				t.Errorf("interceptor saw %v, want one %s call", methods, method)
+			}
+		})
+	}
+}
+
+func TestProbeServerServe(t *testing.T) {
+	o, err := newServeOptions(WithLogger(discardLogger()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := lis.Addr().String()
+	_ = lis.Close()
+
+	plugin := &probedPlugin{readyErr: errors.New("warming up")}
+	p := &probeServer{address: address}
+	stop, err := p.serve(o, plugin, serveChain(t, plugin))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]int{
+		LivenessPath:  http.StatusOK,
+		ReadinessPath: http.StatusServiceUnavailable,
+		"/other":      http.StatusNotFound,
+	} {
+		resp, err := http.Get("http://" + address + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+
+	// The address is taken while serving.
+	if _, err := (&probeServer{address: address}).serve(o, plugin, serveChain(t, plugin)); err == nil ||
+		!strings.Contains(err.Error(), "failed to listen for probes") {
+		t.Errorf("second serve error = %v, want a listen failure", err)
+	}
+
+	stop()
+	if _, err := http.Get("http://" + address + LivenessPath); err == nil {
+		t.Error("probe server still answering after stop")
+	}
+}
@@ -0,0 +1,85 @@
+package quota
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/memcache"
+)
+
+// MemcachedConfig configures the connections of a MemcachedStore.
+type MemcachedConfig = memcache.Config
+
+// MemcachedStore is a Store backed by memcached, sharing usage between every plugin replica using
+// the same servers. Windows are aligned to multiples of their length since the Unix epoch and
+// stored under one counter each, so replicas must have synchronised clocks. Windows shorter than
+// a second are rounded up to a second, memcached's expiry resolution.
+type MemcachedStore struct {
+	client *memcache.Client
+	now    func() time.Time
+}
+
+// NewMemcachedStore returns a MemcachedStore for cfg. Connections are opened on first use.
+func NewMemcachedStore(cfg MemcachedConfig) (*MemcachedStore, error) {
+	c, err := memcache.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemcachedStore{client: c, now: time.Now}, nil
+}
+
+// Add implements Store. Negative n is not supported.
+func (s *MemcachedStore) Add(ctx context.Context, key string, n int64, window time.Duration) (Usage, error) {
+	if n < 0 {
+		return Usage{}, fmt.Errorf("memcached store cannot subtract usage")
+	}
+	window = max(window, time.Second)
+	start := s.now().Truncate(window)
+	reset := start.Add(window)
+	k := memcachedKey(key) + ":" + strconv.FormatInt(start.Unix(), 10)
+
+	for range 2 {
+		used, err := s.client.Incr(ctx, k, uint64(n))
+		if err == nil {
+			return Usage{Used: int64(used), Reset: reset}, nil
+		}
+		if !errors.Is(err, memcache.ErrNotFound) {
+			return Usage{}, err
+		}
+
+		// First use of the window; keep the counter a little past its end to tolerate clock skew.
+		err = s.client.Add(ctx, k, []byte(strconv.FormatInt(n, 10)), time.Until(reset)+window)
+		if err == nil {
+			return Usage{Used: n, Reset: reset}, nil
+		}
+		if !errors.Is(err, memcache.ErrNotStored) {
+			return Usage{}, err
+		}
+		// Another replica created the counter first; increment it.
+	}
+
+	return Usage{}, fmt.Errorf("memcached: failed to update quota counter %s", k)
+}
+
+// Close closes the store's connections.
+func (s *MemcachedStore) Close() error {
+	return s.client.Close()
+}
+
+// memcachedKey hashes keys memcached would reject, such as header values with spaces or keys
+// longer than its 250 byte limit.
+func memcachedKey(key string) string {
+	if len(key) <= 200 && !strings.ContainsFunc(key, func(r rune) bool { return r <= ' ' || r == 0x7f }) {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
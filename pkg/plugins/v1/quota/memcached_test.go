@@ -0,0 +1,171 @@
+package quota_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/memcache/memcachetest"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/quota"
+)
+
+func newMemcachedStore(t *testing.T) (*quota.MemcachedStore, *memcachetest.Server) {
+	t.Helper()
+
+	srv := memcachetest.NewServer(t)
+	s, err := quota.NewMemcachedStore(quota.MemcachedConfig{Addrs: []string{srv.Addr}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	return s, srv
+}
+
+func TestMemcachedStore(t *testing.T) {
+	s, srv := newMemcachedStore(t)
+	ctx := context.Background()
+
+	var reset time.Time
+	for i, want := range []int64{2, 3, 8} {
+		u, err := s.Add(ctx, "quota:a", []int64{2, 1, 5}[i], time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Used != want {
+			t.Errorf("add %d: used %d, want %d", i, u.Used, want)
+		}
+		reset = u.Reset
+	}
+
+	// Windows are aligned to their length and stored under their start.
+	if reset.Unix()%3600 != 0 || time.Until(reset) > time.Hour || time.Until(reset) <= 0 {
+		t.Fatalf("reset %s, want the end of the current hour", reset)
+	}
+	key := "quota:a:" + strconv.FormatInt(reset.Add(-time.Hour).Unix(), 10)
+	it, ok := srv.Item(key)
+	if !ok || string(it.Value) != "8" {
+		t.Fatalf("item %s = %+v, %t; want the counter", key, it, ok)
+	}
+	// The counter outlives its window by one window.
+	if ttl := time.Duration(it.Exptime) * time.Second; ttl <= time.Hour || ttl > 2*time.Hour+time.Second {
+		t.Errorf("counter exptime %s, want between one and two windows", ttl)
+	}
+}
+
+func TestMemcachedStoreKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		hashed bool
+	}{
+		{name: "plain", key: "quota:client:10.0.0.1"},
+		{name: "whitespace", key: "quota:header:a b", hashed: true},
+		{name: "control character", key: "quota:header:a\x01", hashed: true},
+		{name: "long", key: "quota:" + strings.Repeat("k", 300), hashed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, srv := newMemcachedStore(t)
+			u, err := s.Add(context.Background(), tt.key, 1, 500*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Sub-second windows are rounded up to a second.
+			if got := time.Until(u.Reset); got > time.Second || u.Reset.Nanosecond() != 0 {
+				t.Errorf("reset %s, want the end of the current second", u.Reset)
+			}
+			suffix := ":" + strconv.FormatInt(u.Reset.Add(-time.Second).Unix(), 10)
+			if _, ok := srv.Item(tt.key + suffix); ok == tt.hashed {
+				t.Errorf("key stored as-is = %t, want %t", ok, !tt.hashed)
+			}
+			if srv.Len() != 1 {
+				t.Errorf("%d items, want 1", srv.Len())
+			}
+		})
+	}
+}
+
+func TestMemcachedStoreRaces(t *testing.T) {
+	tests := []struct {
+		name    string
+		replies func(cmd string, incrs int) string
+		want    int64
+		wantErr string
+	}{
+		{
+			name: "another replica creates the counter",
+			replies: func(cmd string, incrs int) string {
+				switch {
+				case strings.HasPrefix(cmd, "add "):
+					return "NOT_STORED\r\n"
+				case incrs == 1:
+					return "NOT_FOUND\r\n"
+				default:
+					return "7\r\n"
+				}
+			},
+			want: 7,
+		},
+		{
+			name: "counter keeps disappearing",
+			replies: func(cmd string, _ int) string {
+				if strings.HasPrefix(cmd, "add ") {
+					return "NOT_STORED\r\n"
+				}
+				return "NOT_FOUND\r\n"
+			},
+			wantErr: "failed to update quota counter",
+		},
+		{
+			name:    "server error",
+			replies: func(string, int) string { return "SERVER_ERROR out of memory\r\n" },
+			wantErr: "SERVER_ERROR out of memory",
+		},
+		{
+			name: "add fails",
+			replies: func(cmd string, _ int) string {
+				if strings.HasPrefix(cmd, "add ") {
+					return "SERVER_ERROR out of memory\r\n"
+				}
+				return "NOT_FOUND\r\n"
+			},
+			wantErr: "SERVER_ERROR out of memory",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, srv := newMemcachedStore(t)
+			incrs := 0
+			srv.Intercept(func(cmd string) string {
+				if strings.HasPrefix(cmd, "incr ") {
+					incrs++
+				}
+				return tt.replies(cmd, incrs)
+			})
+
+			u, err := s.Add(context.Background(), "k", 1, time.Minute)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Add error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || u.Used != tt.want {
+				t.Errorf("Add = %+v, %v; want %d used", u, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemcachedStoreErrors(t *testing.T) {
+	if _, err := quota.NewMemcachedStore(quota.MemcachedConfig{}); err == nil {
+		t.Error("NewMemcachedStore accepted no addresses")
+	}
+	s, _ := newMemcachedStore(t)
+	if _, err := s.Add(context.Background(), "k", -1, time.Minute); err == nil {
+		t.Error("Add accepted a negative increment")
+	}
+}
@@ -0,0 +1,66 @@
+package quota
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+)
+
+// pruneThreshold is the number of tracked keys above which expired windows are swept.
+const pruneThreshold = 1024
+
+// MemoryStore is a Store kept in process memory, for single-instance deployments and tests.
+type MemoryStore struct {
+	clock clock.Clock
+
+	mu      sync.Mutex
+	windows map[string]*Usage
+}
+
+// MemoryOption configures a MemoryStore.
+type MemoryOption func(*MemoryStore)
+
+// WithStoreClock sets the clock windows are measured by (defaults to clock.Real()). A nil c keeps
+// the default.
+func WithStoreClock(c clock.Clock) MemoryOption {
+	return func(s *MemoryStore) {
+		if c != nil {
+			s.clock = c
+		}
+	}
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore(opts ...MemoryOption) *MemoryStore {
+	s := &MemoryStore{clock: clock.Real(), windows: map[string]*Usage{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Add implements Store.
+func (s *MemoryStore) Add(_ context.Context, key string, n int64, window time.Duration) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	u, ok := s.windows[key]
+	if !ok || !now.Before(u.Reset) {
+		if !ok && len(s.windows) >= pruneThreshold {
+			for k, w := range s.windows {
+				if !now.Before(w.Reset) {
+					delete(s.windows, k)
+				}
+			}
+		}
+		u = &Usage{Reset: now.Add(window)}
+		s.windows[key] = u
+	}
+	u.Used += n
+
+	return *u, nil
+}
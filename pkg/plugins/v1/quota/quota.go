@@ -0,0 +1,220 @@
+// Package quota enforces per-client request limits over fixed time windows, with usage kept in a
+// pluggable Store so limits hold across plugin replicas.
+//
+// MemoryStore suits a single plugin instance. RedisStore and MemcachedStore share usage between
+// replicas:
+//
+//	store, err := quota.NewRedisStore(quota.RedisConfig{Addr: "redis:6379"})
+//	if err != nil {
+//	    return err
+//	}
+//	limiter, err := quota.NewLimiter(store, 100, time.Minute)
+//	if err != nil {
+//	    return err
+//	}
+//
+// and in the plugin's request handler:
+//
+//	return limiter.HandleRequest(ctx, req), nil
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/tokens"
+)
+
+// Usage is the usage recorded for a key in its current window.
+type Usage struct {
+	// Used is the total recorded in the window, including the latest addition.
+	Used int64
+
+	// Reset is when the window ends and usage starts again from zero.
+	Reset time.Time
+}
+
+// Store records usage per key in fixed windows. Implementations must be safe for concurrent use,
+// and Add must be atomic across every client of the same backend.
+type Store interface {
+	// Add adds n to key's usage in its current window, starting a new window of the given length
+	// when none is active, and returns the usage after adding.
+	Add(ctx context.Context, key string, n int64, window time.Duration) (Usage, error)
+}
+
+// KeyFunc derives the quota key (client, session, tenant...) for a request. The key functions of
+// the tokens package, such as tokens.ClientKey and tokens.SessionKey, can be used directly.
+type KeyFunc func(req *mcpdpluginsv1.HTTPRequest) string
+
+// Decision is the outcome of a quota check.
+type Decision struct {
+	Allowed   bool
+	Limit     int64
+	Remaining int64
+	Reset     time.Time
+}
+
+// Limiter allows up to a fixed number of requests per key and window. It is safe for concurrent use.
+type Limiter struct {
+	store      Store
+	limit      int64
+	window     time.Duration
+	key        KeyFunc
+	principal  bool
+	prefix     string
+	failClosed bool
+	logger     *log.Logger
+	clock      clock.Clock
+}
+
+// LimiterOption configures a Limiter.
+type LimiterOption func(*Limiter) error
+
+// WithKey sets how requests are grouped (defaults to tokens.ClientKey).
+func WithKey(key KeyFunc) LimiterOption {
+	return func(l *Limiter) error {
+		if key == nil {
+			return fmt.Errorf("key function cannot be nil")
+		}
+		l.key = key
+		return nil
+	}
+}
+
+// WithPrincipalKey keys requests on the ID of the caller's principal (see
+// mcpdpluginsv1.WithIdentity), so a caller shares one quota across sessions and clients. Anonymous
+// requests fall back to the key function.
+func WithPrincipalKey() LimiterOption {
+	return func(l *Limiter) error {
+		l.principal = true
+		return nil
+	}
+}
+
+// WithKeyPrefix sets the prefix of the keys written to the store (default "quota:"), so several
+// limiters can share one backend.
+func WithKeyPrefix(prefix string) LimiterOption {
+	return func(l *Limiter) error {
+		l.prefix = prefix
+		return nil
+	}
+}
+
+// WithFailClosed rejects requests when the store is unavailable. By default they are allowed and
+// the error is logged, so a backend outage does not take the plugin chain down.
+func WithFailClosed() LimiterOption {
+	return func(l *Limiter) error {
+		l.failClosed = true
+		return nil
+	}
+}
+
+// WithLogger sets the logger used to report store errors (defaults to log.Default()).
+func WithLogger(logger *log.Logger) LimiterOption {
+	return func(l *Limiter) error {
+		if logger == nil {
+			return fmt.Errorf("logger cannot be nil")
+		}
+		l.logger = logger
+		return nil
+	}
+}
+
+// WithClock sets the clock Retry-After headers are computed by (defaults to clock.Real()). It
+// should be the clock of the store, such as a MemoryStore created WithStoreClock.
+func WithClock(c clock.Clock) LimiterOption {
+	return func(l *Limiter) error {
+		if c == nil {
+			return fmt.Errorf("clock cannot be nil")
+		}
+		l.clock = c
+		return nil
+	}
+}
+
+// NewLimiter returns a Limiter allowing limit requests per key in each window, recorded in store.
+func NewLimiter(store Store, limit int64, window time.Duration, opts ...LimiterOption) (*Limiter, error) {
+	if store == nil {
+		return nil, fmt.Errorf("store is required")
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("window must be positive")
+	}
+
+	l := &Limiter{
+		store:  store,
+		limit:  limit,
+		window: window,
+		key:    tokens.ClientKey,
+		prefix: "quota:",
+		logger: log.Default(),
+		clock:  clock.Real(),
+	}
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+// Allow records one request for key and reports whether it is within the limit. Rejected
+// requests count towards the window too, so clients that keep retrying stay limited.
+func (l *Limiter) Allow(ctx context.Context, key string) (Decision, error) {
+	u, err := l.store.Add(ctx, l.prefix+key, 1, l.window)
+	if err != nil {
+		return Decision{Allowed: !l.failClosed, Limit: l.limit}, err
+	}
+
+	return Decision{
+		Allowed:   u.Used <= l.limit,
+		Limit:     l.limit,
+		Remaining: max(0, l.limit-u.Used),
+		Reset:     u.Reset,
+	}, nil
+}
+
+// HandleRequest lets the chain continue while the request's key is within the limit and
+// short-circuits with 429, a Retry-After header and a JSON-RPC error otherwise.
+func (l *Limiter) HandleRequest(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) *mcpdpluginsv1.HTTPResponse {
+	key := l.key(req)
+	if l.principal {
+		if id := mcpdpluginsv1.PrincipalID(ctx); id != "" {
+			key = id
+		}
+	}
+
+	d, err := l.Allow(ctx, key)
+	if err != nil {
+		l.logger.Printf("quota: store error: %v", err)
+	}
+	if d.Allowed {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+
+	status, msg := http.StatusTooManyRequests, "request quota exceeded"
+	if err != nil {
+		status, msg = http.StatusServiceUnavailable, "request quota unavailable"
+	}
+
+	data, _ := json.Marshal(map[string]int64{"limit": d.Limit, "remaining": d.Remaining})
+	resp := mcpdpluginsv1.DenyError(req, status, &mcp.Error{Code: mcp.CodeServerError, Message: msg, Data: data})
+	if !d.Reset.IsZero() {
+		retry := max(1, int(clock.Until(l.clock, d.Reset).Round(time.Second).Seconds()))
+		resp.Headers["Retry-After"] = strconv.Itoa(retry)
+	}
+
+	return resp
+}
@@ -0,0 +1,287 @@
+package quota_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/plugintest"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/quota"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/tokens"
+)
+
+// keyStore records the keys it is given and fails with err when set.
+type keyStore struct {
+	mu   sync.Mutex
+	keys []string
+	err  error
+}
+
+func (s *keyStore) Add(_ context.Context, key string, _ int64, _ time.Duration) (quota.Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = append(s.keys, key)
+	if s.err != nil {
+		return quota.Usage{}, s.err
+	}
+
+	return quota.Usage{Used: 1}, nil
+}
+
+func TestNewLimiterErrors(t *testing.T) {
+	store := quota.NewMemoryStore()
+	tests := []struct {
+		name   string
+		store  quota.Store
+		limit  int64
+		window time.Duration
+		opts   []quota.LimiterOption
+		want   string
+	}{
+		{name: "nil store", limit: 1, window: time.Second, want: "store is required"},
+		{name: "zero limit", store: store, window: time.Second, want: "limit must be positive"},
+		{name: "zero window", store: store, limit: 1, want: "window must be positive"},
+		{
+			name: "nil key", store: store, limit: 1, window: time.Second,
+			opts: []quota.LimiterOption{quota.WithKey(nil)}, want: "key function cannot be nil",
+		},
+		{
+			name: "nil logger", store: store, limit: 1, window: time.Second,
+			opts: []quota.LimiterOption{quota.WithLogger(nil)}, want: "logger cannot be nil",
+		},
+		{
+			name: "nil clock", store: store, limit: 1, window: time.Second,
+			opts: []quota.LimiterOption{quota.WithClock(nil)}, want: "clock cannot be nil",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := quota.NewLimiter(tt.store, tt.limit, tt.window, tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewLimiter error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// quotaError is the JSON-RPC error of a denied request.
+type quotaError struct {
+	Error struct {
+		Code    int
+		Message string
+		Data    struct{ Limit, Remaining int64 }
+	}
+}
+
+func TestHandleRequest(t *testing.T) {
+	clk := plugintest.NewFakeClock(time.Time{})
+	store := quota.NewMemoryStore(quota.WithStoreClock(clk))
+	l, err := quota.NewLimiter(store, 2, time.Minute, quota.WithClock(clk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := func(addr string) *mcpdpluginsv1.HTTPRequest {
+		return &mcpdpluginsv1.HTTPRequest{RemoteAddr: addr, Body: []byte(`{"jsonrpc":"2.0","id":7,"method":"ping"}`)}
+	}
+
+	steps := []struct {
+		advance    time.Duration
+		addr       string
+		wantStatus int32 // 0 when the request continues.
+		wantRetry  string
+	}{
+		{addr: "10.0.0.1:1000"},
+		{addr: "10.0.0.1:2000"},
+		{addr: "10.0.0.1:3000", wantStatus: 429, wantRetry: "60"},
+		{addr: "10.0.0.2:1000"}, // Other clients have their own quota.
+		{advance: 30 * time.Second, addr: "10.0.0.1:1000", wantStatus: 429, wantRetry: "30"},
+		{advance: 29*time.Second + 800*time.Millisecond, addr: "10.0.0.1:1000", wantStatus: 429, wantRetry: "1"},
+		{advance: 200 * time.Millisecond, addr: "10.0.0.1:1000"}, // The window has reset.
+	}
+	for i, s := range steps {
+		clk.Advance(s.advance)
+		resp := l.HandleRequest(context.Background(), req(s.addr))
+		if s.wantStatus == 0 {
+			if !resp.GetContinue() {
+				t.Errorf("step %d: request denied with %d", i, resp.GetStatusCode())
+			}
+			continue
+		}
+		if resp.GetContinue() || resp.GetStatusCode() != s.wantStatus {
+			t.Fatalf("step %d: response %v, want status %d", i, resp, s.wantStatus)
+		}
+		if got := resp.GetHeaders()["Retry-After"]; got != s.wantRetry {
+			t.Errorf("step %d: Retry-After = %q, want %q", i, got, s.wantRetry)
+		}
+		var body quotaError
+		if err := json.Unmarshal(resp.GetBody(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if e := body.Error; e.Message != "request quota exceeded" || e.Data.Limit != 2 || e.Data.Remaining != 0 {
+			t.Errorf("step %d: error %+v", i, body.Error)
+		}
+	}
+}
+
+func TestAllow(t *testing.T) {
+	l, err := quota.NewLimiter(quota.NewMemoryStore(), 3, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []int64{2, 1, 0, 0} {
+		d, err := l.Allow(context.Background(), "k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Remaining != want || d.Allowed != (i < 3) || d.Limit != 3 || d.Reset.IsZero() {
+			t.Errorf("request %d: decision %+v, want %d remaining", i, d, want)
+		}
+	}
+}
+
+func TestStoreFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		failClosed bool
+		wantStatus int32
+	}{
+		{name: "fail open"},
+		{name: "fail closed", failClosed: true, wantStatus: 503},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			opts := []quota.LimiterOption{quota.WithLogger(log.New(&logs, "", 0))}
+			if tt.failClosed {
+				opts = append(opts, quota.WithFailClosed())
+			}
+			l, err := quota.NewLimiter(&keyStore{err: errors.New("connection refused")}, 1, time.Minute, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp := l.HandleRequest(context.Background(), &mcpdpluginsv1.HTTPRequest{})
+			if resp.GetContinue() != (tt.wantStatus == 0) || resp.GetStatusCode() != tt.wantStatus {
+				t.Errorf("response %v, want status %d", resp, tt.wantStatus)
+			}
+			if _, ok := resp.GetHeaders()["Retry-After"]; ok {
+				t.Error("Retry-After set without a known window")
+			}
+			if got := logs.String(); !strings.Contains(got, "quota: store error: connection refused") {
+				t.Errorf("log = %q, want the store error", got)
+			}
+		})
+	}
+}
+
+func TestLimiterKeys(t *testing.T) {
+	principal := mcpdpluginsv1.ContextWithPrincipal(context.Background(), &mcpdpluginsv1.Principal{ID: "jwt:alice"})
+	req := &mcpdpluginsv1.HTTPRequest{
+		RemoteAddr: "10.0.0.1:1000",
+		Headers:    map[string]string{tokens.SessionHeader: "s1"},
+	}
+	tests := []struct {
+		name string
+		ctx  context.Context
+		opts []quota.LimiterOption
+		want string
+	}{
+		{name: "client by default", ctx: context.Background(), want: "quota:client:10.0.0.1"},
+		{
+			name: "key function and prefix",
+			ctx:  context.Background(),
+			opts: []quota.LimiterOption{quota.WithKey(tokens.SessionKey), quota.WithKeyPrefix("tools:")},
+			want: "tools:session:s1",
+		},
+		{
+			name: "principal",
+			ctx:  principal,
+			opts: []quota.LimiterOption{quota.WithPrincipalKey()},
+			want: "quota:jwt:alice",
+		},
+		{
+			name: "anonymous principal falls back",
+			ctx:  context.Background(),
+			opts: []quota.LimiterOption{quota.WithPrincipalKey()},
+			want: "quota:client:10.0.0.1",
+		},
+		{name: "principal ignored without the option", ctx: principal, want: "quota:client:10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &keyStore{}
+			l, err := quota.NewLimiter(store, 1, time.Minute, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l.HandleRequest(tt.ctx, req)
+			if len(store.keys) != 1 || store.keys[0] != tt.want {
+				t.Errorf("store keys %q, want %q", store.keys, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	clk := plugintest.NewFakeClock(time.Time{})
+	s := quota.NewMemoryStore(quota.WithStoreClock(clk), quota.WithStoreClock(nil))
+	ctx := context.Background()
+	start := clk.Now()
+
+	steps := []struct {
+		advance  time.Duration
+		key      string
+		n        int64
+		window   time.Duration
+		wantUsed int64
+		wantEnd  time.Duration // Window end relative to start.
+	}{
+		{key: "a", n: 2, window: time.Minute, wantUsed: 2, wantEnd: time.Minute},
+		{key: "a", n: 3, window: time.Minute, wantUsed: 5, wantEnd: time.Minute},
+		{key: "a", n: -1, window: time.Hour, wantUsed: 4, wantEnd: time.Minute}, // The active window is kept.
+		{key: "b", n: 1, window: time.Second, wantUsed: 1, wantEnd: time.Second},
+		{advance: time.Minute, key: "a", n: 1, window: time.Minute, wantUsed: 1, wantEnd: 2 * time.Minute},
+	}
+	for i, st := range steps {
+		clk.Advance(st.advance)
+		u, err := s.Add(ctx, st.key, st.n, st.window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Used != st.wantUsed || !u.Reset.Equal(start.Add(st.wantEnd)) {
+			t.Errorf("step %d: usage %+v, want %d until %s", i, u, st.wantUsed, start.Add(st.wantEnd))
+		}
+	}
+}
+
+func TestMemoryStoreManyKeys(t *testing.T) {
+	clk := plugintest.NewFakeClock(time.Time{})
+	s := quota.NewMemoryStore(quota.WithStoreClock(clk))
+	ctx := context.Background()
+
+	for i := range 1100 {
+		if _, err := s.Add(ctx, fmt.Sprintf("old-%d", i), 1, time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Going over the prune threshold sweeps expired windows; either way they restart from zero.
+	clk.Advance(time.Second)
+	for i := range 1100 {
+		u, err := s.Add(ctx, fmt.Sprintf("old-%d", i), 1, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Used != 1 {
+			t.Fatalf("old-%d: used %d after its window, want 1", i, u.Used)
+		}
+	}
+}
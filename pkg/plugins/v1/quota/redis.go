@@ -0,0 +1,66 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/resp"
+)
+
+// RedisConfig configures the connection of a RedisStore.
+type RedisConfig = resp.Config
+
+// addScript increments a counter and starts its window on first use, atomically.
+const addScript = `
+local v = redis.call('INCRBY', KEYS[1], ARGV[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  ttl = tonumber(ARGV[2])
+end
+return {v, ttl}
+`
+
+// RedisStore is a Store backed by Redis, sharing usage between every plugin replica using the
+// same server. Each window is a counter expiring when the window ends.
+type RedisStore struct {
+	client *resp.Client
+}
+
+// NewRedisStore returns a RedisStore for cfg. Connections are opened on first use.
+func NewRedisStore(cfg RedisConfig) (*RedisStore, error) {
+	c, err := resp.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisStore{client: c}, nil
+}
+
+// Add implements Store.
+func (s *RedisStore) Add(ctx context.Context, key string, n int64, window time.Duration) (Usage, error) {
+	ms := max(1, window.Milliseconds())
+	reply, err := s.client.Do(ctx, "EVAL", addScript, "1", key, strconv.FormatInt(n, 10), strconv.FormatInt(ms, 10))
+	if err != nil {
+		return Usage{}, err
+	}
+
+	vals, ok := reply.([]any)
+	if !ok || len(vals) != 2 {
+		return Usage{}, fmt.Errorf("redis: unexpected quota reply %v", reply)
+	}
+	used, ok1 := vals[0].(int64)
+	ttl, ok2 := vals[1].(int64)
+	if !ok1 || !ok2 {
+		return Usage{}, fmt.Errorf("redis: unexpected quota reply %v", reply)
+	}
+
+	return Usage{Used: used, Reset: time.Now().Add(time.Duration(ttl) * time.Millisecond)}, nil
+}
+
+// Close closes the store's connections.
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
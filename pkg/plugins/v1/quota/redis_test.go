@@ -0,0 +1,110 @@
+package quota_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/resp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/resp/resptest"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/quota"
+)
+
+// redisCounters answers the store's EVAL like its script would, ignoring expiry: each counter
+// keeps the TTL it was created with.
+func redisCounters() resptest.Handler {
+	var mu sync.Mutex
+	counters := map[string][2]int64{}
+
+	return func(args []string) any {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if len(args) != 6 || args[0] != "EVAL" || args[2] != "1" {
+			return resp.Error("ERR unexpected command")
+		}
+		n, _ := strconv.ParseInt(args[4], 10, 64)
+		ms, _ := strconv.ParseInt(args[5], 10, 64)
+		c, ok := counters[args[3]]
+		if !ok {
+			c[1] = ms
+		}
+		c[0] += n
+		counters[args[3]] = c
+
+		return []any{c[0], c[1]}
+	}
+}
+
+func newRedisStore(t *testing.T, h resptest.Handler) (*quota.RedisStore, *resptest.Server) {
+	t.Helper()
+
+	srv := resptest.NewServer(t, h)
+	s, err := quota.NewRedisStore(quota.RedisConfig{Addr: srv.Addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	return s, srv
+}
+
+func TestRedisStore(t *testing.T) {
+	s, srv := newRedisStore(t, redisCounters())
+	ctx := context.Background()
+
+	before := time.Now()
+	for i, want := range []int64{1, 3, 6} {
+		u, err := s.Add(ctx, "quota:a", int64(i+1), time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Used != want {
+			t.Errorf("add %d: used %d, want %d", i, u.Used, want)
+		}
+		if u.Reset.Before(before.Add(time.Minute)) || u.Reset.After(time.Now().Add(time.Minute)) {
+			t.Errorf("add %d: reset %s, want a minute from now", i, u.Reset)
+		}
+	}
+
+	// Windows are sent in milliseconds, at least one.
+	if _, err := s.Add(ctx, "quota:b", 1, time.Microsecond); err != nil {
+		t.Fatal(err)
+	}
+	cmds := srv.Commands()
+	if got := cmds[0][3:]; strings.Join(got, " ") != "quota:a 1 60000" {
+		t.Errorf("EVAL arguments %q, want key, increment and window", got)
+	}
+	if got := cmds[len(cmds)-1][5]; got != "1" {
+		t.Errorf("sub-millisecond window sent as %s ms, want 1", got)
+	}
+}
+
+func TestRedisStoreErrors(t *testing.T) {
+	if _, err := quota.NewRedisStore(quota.RedisConfig{}); err == nil {
+		t.Error("NewRedisStore accepted an empty address")
+	}
+
+	tests := []struct {
+		name  string
+		reply any
+		want  string
+	}{
+		{name: "error reply", reply: resp.Error("NOSCRIPT"), want: "redis: NOSCRIPT"},
+		{name: "not an array", reply: int64(1), want: "unexpected quota reply"},
+		{name: "wrong length", reply: []any{int64(1)}, want: "unexpected quota reply"},
+		{name: "wrong types", reply: []any{"1", int64(1000)}, want: "unexpected quota reply"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newRedisStore(t, func([]string) any { return tt.reply })
+			if _, err := s.Add(context.Background(), "k", 1, time.Minute); err == nil ||
+				!strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Add error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package rbac
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/rules"
+)
+
+// RoleSectionPrefix prefixes the custom_config keys of roles: "roles.<role>.<key>".
+const RoleSectionPrefix = "roles."
+
+// Kinds of MCP objects a role grants access to.
+const (
+	KindTool     = "tool"
+	KindResource = "resource"
+	KindPrompt   = "prompt"
+)
+
+// Attributes maps principal attribute names to required values, decodable from a comma-separated
+// list of name=value pairs such as "department=eng,level=senior".
+type Attributes map[string]string
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Attributes) UnmarshalText(text []byte) error {
+	out := Attributes{}
+	for pair := range strings.SplitSeq(string(text), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("expected name=value, got %q", pair)
+		}
+		out[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	*a = out
+
+	return nil
+}
+
+// RoleConfig configures a role: who is granted it and what it allows. Patterns are globs with the
+// syntax of rules.CompileGlob, so "read_*" matches tool names and "file:///docs/**" resource URIs.
+type RoleConfig struct {
+	// Groups grants the role to principals in any of the groups.
+	Groups []string `config:"groups"`
+
+	// Principals grants the role to principals whose ID matches any of the patterns.
+	Principals []string `config:"principals"`
+
+	// Attributes grants the role to principals having all the attribute values. Array attributes,
+	// such as JWT claims listing several values, match when they contain the value.
+	Attributes Attributes `config:"attributes"`
+
+	// Inherits grants everything the listed roles allow.
+	Inherits []string `config:"inherits"`
+
+	// Tools, Resources and Prompts list the tool names, resource URIs and prompt names the role
+	// allows.
+	Tools     []string `config:"tools"`
+	Resources []string `config:"resources"`
+	Prompts   []string `config:"prompts"`
+}
+
+// RolesFromConfig decodes the roles defined under RoleSectionPrefix in custom.
+func RolesFromConfig(custom map[string]string) (map[string]RoleConfig, error) {
+	// Only role keys are split, so top-level plugin keys never leak into role sections.
+	scoped := map[string]string{}
+	for k, v := range custom {
+		if strings.HasPrefix(k, RoleSectionPrefix) {
+			scoped[k] = v
+		}
+	}
+	_, sections := config.Sections(scoped, RoleSectionPrefix)
+
+	roles := make(map[string]RoleConfig, len(sections))
+	for name, values := range sections {
+		var rc RoleConfig
+		if _, err := config.Decode(values, &rc); err != nil {
+			return nil, fmt.Errorf("role %s: %w", name, err)
+		}
+		roles[name] = rc
+	}
+
+	return roles, nil
+}
+
+// role is a compiled role.
+type role struct {
+	name       string
+	groups     []string
+	principals []*regexp.Regexp
+	attributes Attributes
+	grants     map[string][]*regexp.Regexp
+	inherits   []string
+}
+
+// Policy maps principals to roles and roles to the tools, resources and prompts they allow. It is
+// immutable and safe for concurrent use.
+type Policy struct {
+	roles     map[string]*role
+	anonymous []string
+	defaults  []string
+}
+
+// NewPolicy compiles roles. Anonymous callers get the anonymous roles, and every identified
+// principal the default roles on top of the roles granted to it.
+func NewPolicy(roles map[string]RoleConfig, anonymous, defaults []string) (*Policy, error) {
+	p := &Policy{roles: make(map[string]*role, len(roles)), anonymous: anonymous, defaults: defaults}
+	for name, rc := range roles {
+		r := &role{
+			name:       name,
+			groups:     rc.Groups,
+			attributes: rc.Attributes,
+			inherits:   rc.Inherits,
+			grants:     map[string][]*regexp.Regexp{},
+		}
+		var err error
+		if r.principals, err = compileGlobs(rc.Principals); err != nil {
+			return nil, fmt.Errorf("role %s: principals: %w", name, err)
+		}
+		for kind, patterns := range map[string][]string{
+			KindTool:     rc.Tools,
+			KindResource: rc.Resources,
+			KindPrompt:   rc.Prompts,
+		} {
+			if r.grants[kind], err = compileGlobs(patterns); err != nil {
+				return nil, fmt.Errorf("role %s: %ss: %w", name, kind, err)
+			}
+		}
+		p.roles[name] = r
+	}
+
+	for _, name := range slices.Concat(anonymous, defaults) {
+		if _, ok := p.roles[name]; !ok {
+			return nil, fmt.Errorf("unknown role %q", name)
+		}
+	}
+	for name := range p.roles {
+		if err := p.checkInherits(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// checkInherits reports unknown and cyclic inherited roles of name, reached through path.
+func (p *Policy) checkInherits(name string, path []string) error {
+	if slices.Contains(path, name) {
+		return fmt.Errorf("role %s inherits itself through %s", name, strings.Join(path, " -> "))
+	}
+	r, ok := p.roles[name]
+	if !ok {
+		return fmt.Errorf("role %s inherits unknown role %q", path[len(path)-1], name)
+	}
+	for _, parent := range r.inherits {
+		if err := p.checkInherits(parent, append(path, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Roles returns the sorted roles of principal, which is anonymous when nil, including inherited
+// roles.
+func (p *Policy) Roles(principal *mcpdpluginsv1.Principal) []string {
+	granted := map[string]bool{}
+	var grant func(name string)
+	grant = func(name string) {
+		if granted[name] {
+			return
+		}
+		granted[name] = true
+		for _, parent := range p.roles[name].inherits {
+			grant(parent)
+		}
+	}
+
+	if principal == nil {
+		for _, name := range p.anonymous {
+			grant(name)
+		}
+	} else {
+		for _, name := range p.defaults {
+			grant(name)
+		}
+		for name, r := range p.roles {
+			if r.matches(principal) {
+				grant(name)
+			}
+		}
+	}
+
+	roles := make([]string, 0, len(granted))
+	for name := range granted {
+		roles = append(roles, name)
+	}
+	sort.Strings(roles)
+
+	return roles
+}
+
+// Allow returns the first of roles, in order, allowing the object of kind named name, and
+// whether there is one.
+func (p *Policy) Allow(roles []string, kind, name string) (string, bool) {
+	for _, rn := range roles {
+		r, ok := p.roles[rn]
+		if !ok {
+			continue
+		}
+		for _, re := range r.grants[kind] {
+			if re.MatchString(name) {
+				return rn, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// matches reports whether r is granted to principal directly.
+func (r *role) matches(principal *mcpdpluginsv1.Principal) bool {
+	if len(r.groups) == 0 && len(r.principals) == 0 && len(r.attributes) == 0 {
+		return false
+	}
+	if len(r.groups) > 0 && !slices.ContainsFunc(r.groups, principal.InGroup) {
+		return false
+	}
+	if len(r.principals) > 0 && !slices.ContainsFunc(r.principals, func(re *regexp.Regexp) bool {
+		return re.MatchString(principal.ID)
+	}) {
+		return false
+	}
+	for k, want := range r.attributes {
+		if !attributeHas(principal.Attributes[k], want) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// attributeHas reports whether an attribute value is want or, for arrays, contains it.
+func attributeHas(v any, want string) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case string:
+		return v == want
+	case []string:
+		return slices.Contains(v, want)
+	case []any:
+		return slices.ContainsFunc(v, func(e any) bool { return fmt.Sprint(e) == want })
+	default:
+		return fmt.Sprint(v) == want
+	}
+}
+
+func compileGlobs(patterns []string) ([]*regexp.Regexp, error) {
+	out := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := rules.CompileGlob(p)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", p, err)
+		}
+		out = append(out, re)
+	}
+
+	return out, nil
+}
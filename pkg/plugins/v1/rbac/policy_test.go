@@ -0,0 +1,283 @@
+package rbac
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/rules"
+)
+
+func TestAttributesUnmarshalText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    Attributes
+		wantErr string
+	}{
+		{
+			name: "pairs",
+			text: "department=eng, level = senior",
+			want: Attributes{"department": "eng", "level": "senior"},
+		},
+		{name: "empty value", text: "team=", want: Attributes{"team": ""}},
+		{name: "empty entries", text: ",team=a,", want: Attributes{"team": "a"}},
+		{name: "empty", text: "", want: Attributes{}},
+		{name: "missing value", text: "team", wantErr: `expected name=value, got "team"`},
+		{name: "missing name", text: " =a", wantErr: `expected name=value, got "=a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a Attributes
+			err := a.UnmarshalText([]byte(tt.text))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("UnmarshalText error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(a, tt.want) {
+				t.Errorf("Attributes = %v, want %v", a, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolesFromConfig(t *testing.T) {
+	roles, err := RolesFromConfig(map[string]string{
+		"roles.viewer.tools":      "search,read_*",
+		"roles.viewer.resources":  "file:///docs/**",
+		"roles.editor.groups":     "editors",
+		"roles.editor.inherits":   "viewer",
+		"roles.admin.attributes":  "department=platform",
+		"roles.admin.principals":  "jwt:*",
+		"default_roles":           "viewer",
+		"roles":                   "ignored",
+		"roles.incomplete":        "ignored",
+		"other.roles.viewer.tool": "ignored",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]RoleConfig{
+		"viewer": {Tools: []string{"search", "read_*"}, Resources: []string{"file:///docs/**"}},
+		"editor": {Groups: []string{"editors"}, Inherits: []string{"viewer"}},
+		"admin":  {Attributes: Attributes{"department": "platform"}, Principals: []string{"jwt:*"}},
+	}
+	if !reflect.DeepEqual(roles, want) {
+		t.Errorf("RolesFromConfig =\n%+v\nwant\n%+v", roles, want)
+	}
+
+	_, err = RolesFromConfig(map[string]string{"roles.admin.attributes": "department"})
+	if err == nil || !strings.Contains(err.Error(), "role admin:") {
+		t.Errorf("RolesFromConfig error = %v, want the role's decode error", err)
+	}
+}
+
+func TestNewPolicyErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		roles     map[string]RoleConfig
+		anonymous []string
+		defaults  []string
+		wantErr   string
+	}{
+		{name: "unknown anonymous role", anonymous: []string{"guest"}, wantErr: `unknown role "guest"`},
+		{name: "unknown default role", defaults: []string{"viewer"}, wantErr: `unknown role "viewer"`},
+		{
+			name:    "unknown inherited role",
+			roles:   map[string]RoleConfig{"editor": {Inherits: []string{"viewer"}}},
+			wantErr: `role editor inherits unknown role "viewer"`,
+		},
+		{
+			name:    "inheriting itself",
+			roles:   map[string]RoleConfig{"a": {Inherits: []string{"a"}}},
+			wantErr: "role a inherits itself through a",
+		},
+		{
+			name:    "inheritance cycle",
+			roles:   map[string]RoleConfig{"a": {Inherits: []string{"b"}}, "b": {Inherits: []string{"a"}}},
+			wantErr: "inherits itself through",
+		},
+		{
+			name:    "invalid principal pattern",
+			roles:   map[string]RoleConfig{"a": {Principals: []string{strings.Repeat("x", rules.MaxPatternLength+1)}}},
+			wantErr: "role a: principals:",
+		},
+		{
+			name:    "invalid tool pattern",
+			roles:   map[string]RoleConfig{"a": {Tools: []string{strings.Repeat("x", rules.MaxPatternLength+1)}}},
+			wantErr: "role a: tools:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPolicy(tt.roles, tt.anonymous, tt.defaults)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewPolicy error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// testPolicy returns the policy of the package example.
+func testPolicy(t *testing.T) *Policy {
+	t.Helper()
+
+	p, err := NewPolicy(map[string]RoleConfig{
+		"guest": {Tools: []string{"ping"}},
+		"viewer": {
+			Tools:     []string{"search", "read_*"},
+			Resources: []string{"file:///docs/**"},
+			Prompts:   []string{"summary"},
+		},
+		"editor":  {Groups: []string{"editors"}, Inherits: []string{"viewer"}, Tools: []string{"write_*"}},
+		"admin":   {Attributes: Attributes{"department": "platform"}, Tools: []string{"*"}},
+		"bots":    {Principals: []string{"jwt:**#bot-*"}, Tools: []string{"deploy"}},
+		"leads":   {Groups: []string{"leads"}, Attributes: Attributes{"level": "senior"}, Tools: []string{"approve"}},
+		"nothing": {Tools: []string{"never"}},
+	}, []string{"guest"}, []string{"viewer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestPolicyRoles(t *testing.T) {
+	p := testPolicy(t)
+	principal := func(id string, groups []string, attrs map[string]any) *mcpdpluginsv1.Principal {
+		return &mcpdpluginsv1.Principal{ID: id, Groups: groups, Attributes: attrs}
+	}
+	tests := []struct {
+		name      string
+		principal *mcpdpluginsv1.Principal
+		want      []string
+	}{
+		{name: "anonymous", want: []string{"guest"}},
+		{name: "default roles", principal: principal("session:s1", nil, nil), want: []string{"viewer"}},
+		{
+			name:      "group",
+			principal: principal("jwt:alice", []string{"editors"}, nil),
+			want:      []string{"editor", "viewer"},
+		},
+		{
+			name:      "string attribute",
+			principal: principal("jwt:alice", nil, map[string]any{"department": "platform"}),
+			want:      []string{"admin", "viewer"},
+		},
+		{
+			name:      "array attribute",
+			principal: principal("jwt:alice", nil, map[string]any{"department": []any{"eng", "platform"}}),
+			want:      []string{"admin", "viewer"},
+		},
+		{
+			name:      "string array attribute",
+			principal: principal("cert:alice", nil, map[string]any{"department": []string{"platform"}}),
+			want:      []string{"admin", "viewer"},
+		},
+		{
+			name:      "other attribute value",
+			principal: principal("jwt:alice", nil, map[string]any{"department": "eng"}),
+			want:      []string{"viewer"},
+		},
+		{
+			name:      "principal pattern",
+			principal: principal("jwt:https://idp#bot-ci", nil, nil),
+			want:      []string{"bots", "viewer"},
+		},
+		{
+			name:      "principal pattern mismatch",
+			principal: principal("jwt:https://idp#alice", nil, nil),
+			want:      []string{"viewer"},
+		},
+		{
+			name:      "every condition of a role",
+			principal: principal("jwt:bob", []string{"leads"}, map[string]any{"level": "senior"}),
+			want:      []string{"leads", "viewer"},
+		},
+		{
+			name:      "some conditions of a role",
+			principal: principal("jwt:bob", []string{"leads"}, map[string]any{"level": "junior"}),
+			want:      []string{"viewer"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Roles(tt.principal); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Roles = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyRolesInheritedTransitively(t *testing.T) {
+	p, err := NewPolicy(map[string]RoleConfig{
+		"a": {Groups: []string{"g"}, Inherits: []string{"b"}},
+		"b": {Inherits: []string{"c", "d"}},
+		"c": {Inherits: []string{"d"}},
+		"d": {},
+	}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := p.Roles(&mcpdpluginsv1.Principal{ID: "x", Groups: []string{"g"}})
+	if want := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Roles = %q, want %q", got, want)
+	}
+	if got := p.Roles(nil); len(got) != 0 {
+		t.Errorf("anonymous roles = %q, want none", got)
+	}
+}
+
+func TestPolicyAllow(t *testing.T) {
+	p := testPolicy(t)
+	tests := []struct {
+		name     string
+		roles    []string
+		kind     string
+		object   string
+		wantRole string
+	}{
+		{name: "exact tool", roles: []string{"viewer"}, kind: KindTool, object: "search", wantRole: "viewer"},
+		{name: "tool glob", roles: []string{"viewer"}, kind: KindTool, object: "read_file", wantRole: "viewer"},
+		{name: "tool not granted", roles: []string{"viewer"}, kind: KindTool, object: "write_file"},
+		{
+			name:     "resource glob",
+			roles:    []string{"viewer"},
+			kind:     KindResource,
+			object:   "file:///docs/a/b.md",
+			wantRole: "viewer",
+		},
+		{
+			name:   "resource outside the glob",
+			roles:  []string{"viewer"},
+			kind:   KindResource,
+			object: "file:///etc/passwd",
+		},
+		{name: "prompt", roles: []string{"viewer"}, kind: KindPrompt, object: "summary", wantRole: "viewer"},
+		{name: "kinds are separate", roles: []string{"viewer"}, kind: KindPrompt, object: "search"},
+		{
+			name:     "first allowing role",
+			roles:    []string{"admin", "viewer"},
+			kind:     KindTool,
+			object:   "search",
+			wantRole: "admin",
+		},
+		{name: "unknown role", roles: []string{"root"}, kind: KindTool, object: "search"},
+		{name: "inherited grants come from their role", roles: []string{"editor"}, kind: KindTool, object: "search"},
+		{name: "no roles", kind: KindTool, object: "search"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role, ok := p.Allow(tt.roles, tt.kind, tt.object)
+			if role != tt.wantRole || ok != (tt.wantRole != "") {
+				t.Errorf("Allow = %q, %t, want %q", role, ok, tt.wantRole)
+			}
+		})
+	}
+}
@@ -0,0 +1,426 @@
+// Package rbac authorizes MCP calls by role: principals identified with
+// mcpdpluginsv1.WithIdentity are mapped to roles by group, ID or attribute, and roles to the tools,
+// resources and prompts they may use.
+//
+// Roles are sections of custom_config under "roles.<role>.":
+//
+//	roles.viewer.tools:          search,read_*
+//	roles.viewer.resources:      file:///docs/**
+//	roles.editor.groups:         editors
+//	roles.editor.inherits:       viewer
+//	roles.editor.tools:          write_*
+//	roles.admin.attributes:      department=platform
+//	roles.admin.tools:           *
+//	default_roles:               viewer
+//
+// An Enforcer denies tools/call, resources/read and prompts/get requests no role of the caller
+// allows, removes what the caller may not use from tools/list, resources/list and prompts/list
+// responses, and logs and counts its decisions. Other requests are left alone, so clients can
+// still initialize and list.
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// MetricDecisions counts authorization decisions, labelled by LabelKind, LabelDecision and
+// LabelRole (the role that allowed the call, empty for denials).
+const MetricDecisions = "rbac.decisions"
+
+// Label keys of MetricDecisions.
+const (
+	LabelKind     = "kind"
+	LabelDecision = "decision"
+	LabelRole     = "role"
+)
+
+// Decision log levels.
+const (
+	LogOff  = "off"
+	LogDeny = "deny"
+	LogAll  = "all"
+)
+
+// Config configures an Enforcer, decodable from custom_config with mcpdpluginsv1.DecodeConfig
+// (roles with RolesFromConfig).
+type Config struct {
+	// Roles are the roles of the policy, keyed by name.
+	Roles map[string]RoleConfig `config:"-"`
+
+	// AnonymousRoles are granted to callers without a principal.
+	AnonymousRoles []string `config:"anonymous_roles"`
+
+	// DefaultRoles are granted to every identified principal.
+	DefaultRoles []string `config:"default_roles"`
+
+	// FilterLists removes the tools, resources and prompts the caller may not use from list
+	// responses.
+	FilterLists bool `config:"filter_lists" default:"true"`
+
+	// LogDecisions selects which decisions are logged: off, deny or all.
+	LogDecisions string `config:"log_decisions" default:"deny"`
+
+	mcpdpluginsv1.DenyTemplateConfig
+}
+
+// DefaultConfig returns the Config with every default applied. It has no roles, so everything
+// governed by the policy is denied.
+func DefaultConfig() Config {
+	var cfg Config
+	if _, err := config.Decode(nil, &cfg); err != nil {
+		panic(fmt.Sprintf("rbac: invalid defaults: %v", err))
+	}
+
+	return cfg
+}
+
+// Decision is the outcome of authorizing a principal's use of a tool, resource or prompt.
+type Decision struct {
+	Allowed bool `json:"allowed"`
+
+	// Principal is the caller's principal ID, empty for anonymous callers.
+	Principal string   `json:"principal,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+
+	// Kind and Name identify the object, such as KindTool and a tool name.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+
+	// Role is the role that allowed the call.
+	Role string `json:"role,omitempty"`
+}
+
+// Enforcer applies a Policy to MCP calls. It is safe for concurrent use.
+type Enforcer struct {
+	policy      *Policy
+	filterLists bool
+	logLevel    string
+	logger      *log.Logger
+	recorder    metrics.Recorder
+	denial      *mcpdpluginsv1.DenyTemplate
+}
+
+// New returns an Enforcer for cfg recording decisions through recorder (nil disables metrics).
+func New(cfg Config, recorder metrics.Recorder) (*Enforcer, error) {
+	policy, err := NewPolicy(cfg.Roles, cfg.AnonymousRoles, cfg.DefaultRoles)
+	if err != nil {
+		return nil, err
+	}
+	level := strings.ToLower(cfg.LogDecisions)
+	switch level {
+	case LogOff, LogDeny, LogAll:
+	default:
+		return nil, fmt.Errorf("log_decisions must be off, deny or all, got %q", cfg.LogDecisions)
+	}
+	denial, err := cfg.Template()
+	if err != nil {
+		return nil, err
+	}
+	if recorder == nil {
+		recorder = metrics.Nop()
+	}
+
+	return &Enforcer{
+		policy:      policy,
+		filterLists: cfg.FilterLists,
+		logLevel:    level,
+		logger:      log.Default(),
+		recorder:    recorder,
+		denial:      denial,
+	}, nil
+}
+
+// Policy returns the enforcer's policy.
+func (e *Enforcer) Policy() *Policy {
+	return e.policy
+}
+
+// Authorize decides whether principal, which is anonymous when nil, may use the object of kind
+// named name, and logs and counts the decision.
+func (e *Enforcer) Authorize(principal *mcpdpluginsv1.Principal, kind, name string) Decision {
+	d := e.decide(principal, e.policy.Roles(principal), kind, name)
+	e.report(d)
+
+	return d
+}
+
+func (e *Enforcer) decide(principal *mcpdpluginsv1.Principal, roles []string, kind, name string) Decision {
+	d := Decision{Roles: roles, Kind: kind, Name: name}
+	if principal != nil {
+		d.Principal = principal.ID
+	}
+	d.Role, d.Allowed = e.policy.Allow(roles, kind, name)
+
+	return d
+}
+
+// report logs and counts d.
+func (e *Enforcer) report(d Decision) {
+	verdict := "deny"
+	if d.Allowed {
+		verdict = "allow"
+	}
+	e.recorder.Count(MetricDecisions, 1,
+		metrics.L(LabelKind, d.Kind), metrics.L(LabelDecision, verdict), metrics.L(LabelRole, d.Role))
+
+	if e.logLevel == LogAll || e.logLevel == LogDeny && !d.Allowed {
+		principal := d.Principal
+		if principal == "" {
+			principal = "anonymous"
+		}
+		e.logger.Printf("rbac: %s %s %q for %s (roles: %s)",
+			verdict, d.Kind, d.Name, principal, strings.Join(d.Roles, ","))
+	}
+}
+
+// HandleRequest lets the chain continue when every tools/call, resources/read and prompts/get
+// message of req is allowed, and short-circuits with 403 and a JSON-RPC error otherwise. Callers
+// whose credentials were rejected by their identity provider get 401.
+func (e *Enforcer) HandleRequest(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) *mcpdpluginsv1.HTTPResponse {
+	msgs, err := mcp.Parse(req.GetBody())
+	if err != nil {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+
+	principal, idErr := mcpdpluginsv1.Identity(ctx)
+	var roles []string
+	for _, m := range msgs {
+		kind, name := object(m)
+		if kind == "" {
+			continue
+		}
+		if idErr != nil {
+			return e.denial.Deny(ctx, req, mcpdpluginsv1.Denial{
+				Reason: "invalid credentials",
+				Status: http.StatusUnauthorized,
+			})
+		}
+		if roles == nil {
+			roles = e.policy.Roles(principal)
+		}
+
+		d := e.decide(principal, roles, kind, name)
+		e.report(d)
+		if !d.Allowed {
+			return e.denial.Deny(ctx, req, mcpdpluginsv1.Denial{
+				Reason: fmt.Sprintf("%s %s is not allowed", kind, name),
+				Status: http.StatusForbidden,
+			})
+		}
+	}
+
+	return &mcpdpluginsv1.HTTPResponse{Continue: true}
+}
+
+// object returns the kind and name of the object m uses, or empty strings when m is not governed
+// by the policy.
+func object(m *mcp.Message) (string, string) {
+	switch m.Method {
+	case mcp.MethodToolsCall:
+		return KindTool, m.ToolName()
+	case mcp.MethodResourcesRead:
+		return KindResource, m.ResourceURI()
+	case mcp.MethodPromptsGet:
+		var params struct {
+			Name string `json:"name"`
+		}
+		_ = json.Unmarshal(m.Params, &params)
+		return KindPrompt, params.Name
+	}
+
+	return "", ""
+}
+
+// listFields maps the result fields of list responses to the kind of their entries and the
+// entry field naming them.
+var listFields = map[string]struct{ kind, key string }{
+	"tools":     {KindTool, "name"},
+	"resources": {KindResource, "uri"},
+	"prompts":   {KindPrompt, "name"},
+}
+
+// HandleResponse removes the entries the caller may not use from tools/list, resources/list and
+// prompts/list responses, batched or not, and lets every response continue. Filtering relies on
+// mcpdpluginsv1.WithIdentity carrying the request's principal over to its response.
+func (e *Enforcer) HandleResponse(ctx context.Context, resp *mcpdpluginsv1.HTTPResponse) *mcpdpluginsv1.HTTPResponse {
+	out := &mcpdpluginsv1.HTTPResponse{
+		Continue:   true,
+		StatusCode: resp.GetStatusCode(),
+		Headers:    resp.GetHeaders(),
+		Body:       resp.GetBody(),
+	}
+	if !e.filterLists {
+		return out
+	}
+
+	principal, _ := mcpdpluginsv1.Identity(ctx)
+	roles := e.policy.Roles(principal)
+
+	body := bytes.TrimSpace(resp.GetBody())
+	if !bytes.HasPrefix(body, []byte("[")) {
+		if b, ok := e.filterMessage(roles, body); ok {
+			out.Body = b
+		}
+		return out
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return out
+	}
+	changed := false
+	for i, raw := range batch {
+		if b, ok := e.filterMessage(roles, raw); ok {
+			batch[i], changed = b, true
+		}
+	}
+	if !changed {
+		return out
+	}
+	if b, err := json.Marshal(batch); err == nil {
+		out.Body = b
+	}
+
+	return out
+}
+
+// filterMessage removes the entries roles do not allow from the list result of the JSON-RPC
+// response raw, returning the filtered message and whether anything was removed.
+func (e *Enforcer) filterMessage(roles []string, raw []byte) ([]byte, bool) {
+	var msg map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &msg); err != nil || len(msg["result"]) == 0 {
+		return nil, false
+	}
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(msg["result"], &result); err != nil {
+		return nil, false
+	}
+
+	changed := false
+	for field, lf := range listFields {
+		var entries []map[string]any
+		if len(result[field]) == 0 || json.Unmarshal(result[field], &entries) != nil {
+			continue
+		}
+		kept := entries[:0]
+		for _, entry := range entries {
+			name, _ := entry[lf.key].(string)
+			if _, ok := e.policy.Allow(roles, lf.kind, name); ok {
+				kept = append(kept, entry)
+			}
+		}
+		if len(kept) == len(entries) {
+			continue
+		}
+		b, err := json.Marshal(kept)
+		if err != nil {
+			return nil, false
+		}
+		result[field], changed = b, true
+	}
+	if !changed {
+		return nil, false
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return nil, false
+	}
+	msg["result"] = b
+	if b, err = json.Marshal(msg); err != nil {
+		return nil, false
+	}
+
+	return b, true
+}
+
+// Plugin is a request- and response-flow plugin enforcing the roles of its custom_config. It
+// denies every governed call until configured with roles.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	recorder metrics.Recorder
+	enforcer atomic.Pointer[Enforcer]
+}
+
+// NewPlugin returns a Plugin recording decisions through recorder (nil disables metrics).
+func NewPlugin(recorder metrics.Recorder) *Plugin {
+	p := &Plugin{recorder: recorder}
+	e, err := New(DefaultConfig(), recorder)
+	if err != nil {
+		panic(fmt.Sprintf("rbac: invalid defaults: %v", err))
+	}
+	p.enforcer.Store(e)
+
+	return p
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "rbac",
+		Version:     pluginVersion,
+		Description: "Authorizes tool, resource and prompt use by the caller's roles.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest, mcpdpluginsv1.FlowResponse), nil
+}
+
+// Configure decodes the enforcer and its roles from cfg's custom_config, keeping the previous
+// policy on error.
+func (p *Plugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	var c Config
+	if err := mcpdpluginsv1.DecodeConfig(ctx, cfg, &c); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	roles, err := RolesFromConfig(cfg.GetCustomConfig())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	c.Roles = roles
+	e, err := New(c, p.recorder)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	p.enforcer.Store(e)
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleRequest denies calls the caller's roles do not allow.
+func (p *Plugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.enforcer.Load().HandleRequest(ctx, req), nil
+}
+
+// HandleResponse filters list responses by the caller's roles.
+func (p *Plugin) HandleResponse(
+	ctx context.Context,
+	resp *mcpdpluginsv1.HTTPResponse,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.enforcer.Load().HandleResponse(ctx, resp), nil
+}
@@ -0,0 +1,541 @@
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// fakeRecorder records every count as a line such as "rbac.decisions 1 [kind=tool ...]".
+type fakeRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *fakeRecorder) Count(name string, delta int64, labels ...metrics.Label) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Key + "=" + l.Value
+	}
+	r.lines = append(r.lines, fmt.Sprintf("%s %d [%s]", name, delta, strings.Join(parts, " ")))
+}
+
+func (r *fakeRecorder) Gauge(string, float64, ...metrics.Label) {}
+
+func (r *fakeRecorder) Observe(string, float64, ...metrics.Label) {}
+
+func (r *fakeRecorder) Timing(string, time.Duration, ...metrics.Label) {}
+
+func (r *fakeRecorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.lines)
+}
+
+// testConfig returns a Config with a viewer role for every identified caller and an editor role
+// for the editors group.
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Roles = map[string]RoleConfig{
+		"viewer": {Tools: []string{"search"}, Resources: []string{"file:///docs/**"}, Prompts: []string{"summary"}},
+		"editor": {Groups: []string{"editors"}, Inherits: []string{"viewer"}, Tools: []string{"write_*"}},
+	}
+	cfg.DefaultRoles = []string{"viewer"}
+
+	return cfg
+}
+
+// newEnforcer returns an Enforcer for cfg logging to logs.
+func newEnforcer(t *testing.T, cfg Config, r metrics.Recorder, logs *bytes.Buffer) *Enforcer {
+	t.Helper()
+
+	e, err := New(cfg, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.logger = log.New(logs, "", 0)
+
+	return e
+}
+
+var (
+	alice = &mcpdpluginsv1.Principal{ID: "jwt:alice"}
+	bob   = &mcpdpluginsv1.Principal{ID: "jwt:bob", Groups: []string{"editors"}}
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		mod     func(*Config)
+		wantErr string
+	}{
+		{name: "defaults", mod: func(*Config) {}},
+		{name: "log level case", mod: func(c *Config) { c.LogDecisions = "ALL" }},
+		{name: "unknown log level", mod: func(c *Config) { c.LogDecisions = "some" }, wantErr: `got "some"`},
+		{
+			name:    "unknown role",
+			mod:     func(c *Config) { c.DefaultRoles = []string{"viewer"} },
+			wantErr: `unknown role "viewer"`,
+		},
+		{
+			name:    "invalid deny template",
+			mod:     func(c *Config) { c.DenyTemplate, c.DenyTemplateFormat = "{{", "text" },
+			wantErr: "invalid deny template",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.mod(&cfg)
+			_, err := New(cfg, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("New: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     string
+		principal *mcpdpluginsv1.Principal
+		object    string
+		want      Decision
+		wantLog   string
+		wantCount string
+	}{
+		{
+			name:      "allowed",
+			level:     LogDeny,
+			principal: alice,
+			object:    "search",
+			want: Decision{
+				Allowed: true, Principal: "jwt:alice", Roles: []string{"viewer"}, Kind: KindTool, Name: "search",
+				Role: "viewer",
+			},
+			wantCount: "rbac.decisions 1 [kind=tool decision=allow role=viewer]",
+		},
+		{
+			name:      "denied",
+			level:     LogDeny,
+			principal: alice,
+			object:    "write_file",
+			want:      Decision{Principal: "jwt:alice", Roles: []string{"viewer"}, Kind: KindTool, Name: "write_file"},
+			wantLog:   `rbac: deny tool "write_file" for jwt:alice (roles: viewer)` + "\n",
+			wantCount: "rbac.decisions 1 [kind=tool decision=deny role=]",
+		},
+		{
+			name:      "anonymous",
+			level:     LogAll,
+			object:    "search",
+			want:      Decision{Roles: []string{}, Kind: KindTool, Name: "search"},
+			wantLog:   `rbac: deny tool "search" for anonymous (roles: )` + "\n",
+			wantCount: "rbac.decisions 1 [kind=tool decision=deny role=]",
+		},
+		{
+			name:      "allowed and logged",
+			level:     LogAll,
+			principal: bob,
+			object:    "write_file",
+			want: Decision{
+				Allowed: true, Principal: "jwt:bob", Roles: []string{"editor", "viewer"}, Kind: KindTool,
+				Name: "write_file", Role: "editor",
+			},
+			wantLog:   `rbac: allow tool "write_file" for jwt:bob (roles: editor,viewer)` + "\n",
+			wantCount: "rbac.decisions 1 [kind=tool decision=allow role=editor]",
+		},
+		{
+			name:      "logging off",
+			level:     LogOff,
+			principal: alice,
+			object:    "write_file",
+			want:      Decision{Principal: "jwt:alice", Roles: []string{"viewer"}, Kind: KindTool, Name: "write_file"},
+			wantCount: "rbac.decisions 1 [kind=tool decision=deny role=]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.LogDecisions = tt.level
+			r := &fakeRecorder{}
+			var logs bytes.Buffer
+			e := newEnforcer(t, cfg, r, &logs)
+
+			if got := e.Authorize(tt.principal, KindTool, tt.object); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Authorize =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			if logs.String() != tt.wantLog {
+				t.Errorf("logged %q, want %q", logs.String(), tt.wantLog)
+			}
+			if got := r.recorded(); len(got) != 1 || got[0] != tt.wantCount {
+				t.Errorf("metrics = %q, want %q", got, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestDecisionJSON(t *testing.T) {
+	b, err := json.Marshal(Decision{Kind: KindTool, Name: "search"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"allowed":false,"kind":"tool","name":"search"}`; string(b) != want {
+		t.Errorf("Decision JSON = %s, want %s", b, want)
+	}
+}
+
+// call returns a JSON-RPC request of method with params.
+func call(method, params string) string {
+	return `{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":` + params + `}`
+}
+
+// batch returns a JSON-RPC batch of msgs.
+func batch(msgs ...string) string {
+	return "[" + strings.Join(msgs, ",") + "]"
+}
+
+// denyReason returns the JSON-RPC error message of a deny body, the first one for a batch.
+func denyReason(t *testing.T, body []byte) string {
+	t.Helper()
+
+	type message struct {
+		Error struct{ Message string } `json:"error"`
+	}
+	var msgs []message
+	if err := json.Unmarshal(body, &msgs); err != nil {
+		var m message
+		if err := json.Unmarshal(body, &m); err != nil {
+			t.Fatalf("deny body %s: %v", body, err)
+		}
+		msgs = []message{m}
+	}
+	if len(msgs) == 0 {
+		t.Fatalf("empty deny body %s", body)
+	}
+
+	return msgs[0].Error.Message
+}
+
+func TestHandleRequest(t *testing.T) {
+	aliceCtx := mcpdpluginsv1.ContextWithPrincipal(context.Background(), alice)
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		body       string
+		wantStatus int32 // 0 continues the chain.
+		wantReason string
+		wantCounts int
+	}{
+		{name: "allowed tool", ctx: aliceCtx, body: call("tools/call", `{"name":"search"}`), wantCounts: 1},
+		{
+			name:       "denied tool",
+			ctx:        aliceCtx,
+			body:       call("tools/call", `{"name":"delete"}`),
+			wantStatus: http.StatusForbidden,
+			wantReason: "tool delete is not allowed",
+			wantCounts: 1,
+		},
+		{
+			name:       "allowed resource",
+			ctx:        aliceCtx,
+			body:       call("resources/read", `{"uri":"file:///docs/a.md"}`),
+			wantCounts: 1,
+		},
+		{
+			name:       "denied resource",
+			ctx:        aliceCtx,
+			body:       call("resources/read", `{"uri":"file:///etc/passwd"}`),
+			wantStatus: http.StatusForbidden,
+			wantReason: "resource file:///etc/passwd is not allowed",
+			wantCounts: 1,
+		},
+		{name: "allowed prompt", ctx: aliceCtx, body: call("prompts/get", `{"name":"summary"}`), wantCounts: 1},
+		{
+			name:       "denied prompt",
+			ctx:        aliceCtx,
+			body:       call("prompts/get", `{"name":"secrets"}`),
+			wantStatus: http.StatusForbidden,
+			wantReason: "prompt secrets is not allowed",
+			wantCounts: 1,
+		},
+		{
+			name:       "anonymous",
+			ctx:        context.Background(),
+			body:       call("tools/call", `{"name":"search"}`),
+			wantStatus: http.StatusForbidden,
+			wantReason: "tool search is not allowed",
+			wantCounts: 1,
+		},
+		{name: "ungoverned method", ctx: context.Background(), body: call("tools/list", `{}`)},
+		{name: "not JSON", ctx: context.Background(), body: "hello"},
+		{
+			name:       "batch with one denied call",
+			ctx:        aliceCtx,
+			body:       batch(call("tools/call", `{"name":"search"}`), call("tools/call", `{"name":"delete"}`)),
+			wantStatus: http.StatusForbidden,
+			wantReason: "tool delete is not allowed",
+			wantCounts: 2,
+		},
+		{
+			name:       "batch allowed",
+			ctx:        aliceCtx,
+			body:       batch(call("tools/call", `{"name":"search"}`), call("prompts/get", `{"name":"summary"}`)),
+			wantCounts: 2,
+		},
+		{
+			name:       "rejected credentials",
+			ctx:        mcpdpluginsv1.ContextWithIdentityError(context.Background(), errors.New("expired")),
+			body:       call("tools/call", `{"name":"search"}`),
+			wantStatus: http.StatusUnauthorized,
+			wantReason: "invalid credentials",
+		},
+		{
+			name: "rejected credentials on an ungoverned method",
+			ctx:  mcpdpluginsv1.ContextWithIdentityError(context.Background(), errors.New("expired")),
+			body: call("initialize", `{}`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &fakeRecorder{}
+			e := newEnforcer(t, testConfig(), r, &bytes.Buffer{})
+			resp := e.HandleRequest(tt.ctx, &mcpdpluginsv1.HTTPRequest{Method: http.MethodPost, Body: []byte(tt.body)})
+			if tt.wantStatus == 0 {
+				if !resp.GetContinue() {
+					t.Errorf("HandleRequest = %v, want it continued", resp)
+				}
+			} else {
+				if resp.GetContinue() || resp.GetStatusCode() != tt.wantStatus {
+					t.Fatalf("HandleRequest = %v, want status %d", resp, tt.wantStatus)
+				}
+				if got := denyReason(t, resp.GetBody()); got != tt.wantReason {
+					t.Errorf("deny reason = %q, want %q", got, tt.wantReason)
+				}
+			}
+			if got := len(r.recorded()); got != tt.wantCounts {
+				t.Errorf("%d decisions counted, want %d", got, tt.wantCounts)
+			}
+		})
+	}
+}
+
+// result returns a JSON-RPC response with result.
+func result(id int, result string) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":%s}`, id, result)
+}
+
+// jsonEqual reports whether a and b hold the same JSON value.
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+
+	var av, bv any
+	if err := json.Unmarshal(a, &av); err != nil {
+		t.Fatalf("%s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &bv); err != nil {
+		t.Fatalf("%s: %v", b, err)
+	}
+
+	return reflect.DeepEqual(av, bv)
+}
+
+func TestHandleResponse(t *testing.T) {
+	aliceCtx := mcpdpluginsv1.ContextWithPrincipal(context.Background(), alice)
+	bobCtx := mcpdpluginsv1.ContextWithPrincipal(context.Background(), bob)
+	tools := `{"tools":[{"name":"search"},{"name":"write_file"},{"name":"delete"}],"nextCursor":"c"}`
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		filter bool
+		body   string
+		want   string // Empty when the body is unchanged.
+	}{
+		{
+			name:   "tools",
+			ctx:    aliceCtx,
+			filter: true,
+			body:   result(1, tools),
+			want:   result(1, `{"tools":[{"name":"search"}],"nextCursor":"c"}`),
+		},
+		{
+			name:   "inherited roles",
+			ctx:    bobCtx,
+			filter: true,
+			body:   result(1, tools),
+			want:   result(1, `{"tools":[{"name":"search"},{"name":"write_file"}],"nextCursor":"c"}`),
+		},
+		{
+			name:   "resources",
+			ctx:    aliceCtx,
+			filter: true,
+			body:   result(1, `{"resources":[{"uri":"file:///docs/a.md","name":"a"},{"uri":"file:///etc/passwd"}]}`),
+			want:   result(1, `{"resources":[{"uri":"file:///docs/a.md","name":"a"}]}`),
+		},
+		{
+			name:   "prompts",
+			ctx:    aliceCtx,
+			filter: true,
+			body:   result(1, `{"prompts":[{"name":"secrets"},{"name":"summary"}]}`),
+			want:   result(1, `{"prompts":[{"name":"summary"}]}`),
+		},
+		{
+			name:   "anonymous sees nothing",
+			ctx:    context.Background(),
+			filter: true,
+			body:   result(1, tools),
+			want:   result(1, `{"tools":[],"nextCursor":"c"}`),
+		},
+		{
+			name:   "batch",
+			ctx:    aliceCtx,
+			filter: true,
+			body:   batch(result(1, tools), result(2, `{"content":[]}`)),
+			want:   batch(result(1, `{"tools":[{"name":"search"}],"nextCursor":"c"}`), result(2, `{"content":[]}`)),
+		},
+		{
+			name:   "batch with nothing to remove",
+			ctx:    bobCtx,
+			filter: true,
+			body:   batch(result(1, `{"tools":[{"name":"search"}]}`)),
+		},
+		{name: "nothing to remove", ctx: aliceCtx, filter: true, body: result(1, `{"tools":[{"name":"search"}]}`)},
+		{name: "filtering off", ctx: aliceCtx, body: result(1, tools)},
+		{
+			name:   "error response",
+			ctx:    aliceCtx,
+			filter: true,
+			body:   `{"jsonrpc":"2.0","id":1,"error":{"code":-1,"message":"x"}}`,
+		},
+		{name: "not a list", ctx: aliceCtx, filter: true, body: result(1, `{"tools":"search"}`)},
+		{name: "not JSON", ctx: aliceCtx, filter: true, body: "hello"},
+		{name: "empty", ctx: aliceCtx, filter: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.FilterLists = tt.filter
+			e := newEnforcer(t, cfg, nil, &bytes.Buffer{})
+
+			resp := &mcpdpluginsv1.HTTPResponse{
+				StatusCode: 200,
+				Headers:    map[string]string{"Content-Type": "application/json"},
+				Body:       []byte(tt.body),
+			}
+			got := e.HandleResponse(tt.ctx, resp)
+			if !got.GetContinue() || got.GetStatusCode() != 200 ||
+				got.GetHeaders()["Content-Type"] != "application/json" {
+				t.Errorf("HandleResponse = %v, want resp continued", got)
+			}
+			want := tt.want
+			if want == "" {
+				if string(got.GetBody()) != tt.body {
+					t.Errorf("body = %s, want it unchanged", got.GetBody())
+				}
+				return
+			}
+			if !jsonEqual(t, got.GetBody(), []byte(want)) {
+				t.Errorf("body = %s, want %s", got.GetBody(), want)
+			}
+		})
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	p := NewPlugin(nil)
+	ctx := context.Background()
+	aliceCtx := mcpdpluginsv1.ContextWithPrincipal(ctx, alice)
+	search := &mcpdpluginsv1.HTTPRequest{Body: []byte(call("tools/call", `{"name":"search"}`))}
+
+	md, err := p.GetMetadata(ctx, &emptypb.Empty{})
+	if err != nil || md.GetName() != "rbac" {
+		t.Errorf("GetMetadata = %v, %v", md, err)
+	}
+	caps, err := p.GetCapabilities(ctx, &emptypb.Empty{})
+	if err != nil || len(caps.GetFlows()) != 2 {
+		t.Errorf("GetCapabilities = %v, %v, want both flows", caps, err)
+	}
+
+	// Until configured, every governed call is denied.
+	p.enforcer.Load().logger = log.New(&bytes.Buffer{}, "", 0)
+	if resp, err := p.HandleRequest(aliceCtx, search); err != nil || resp.GetContinue() {
+		t.Errorf("unconfigured HandleRequest = %v, %v, want a denial", resp, err)
+	}
+
+	tests := []struct {
+		name     string
+		custom   map[string]string
+		wantCode codes.Code
+	}{
+		{name: "valid", custom: map[string]string{"roles.viewer.tools": "search", "default_roles": "viewer"}},
+		{
+			name:     "invalid role section",
+			custom:   map[string]string{"roles.viewer.attributes": "x"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "unknown default role",
+			custom:   map[string]string{"default_roles": "admin"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "invalid log level",
+			custom:   map[string]string{"log_decisions": "some"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "undecodable option",
+			custom:   map[string]string{"filter_lists": "maybe"},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.Configure(ctx, &mcpdpluginsv1.PluginConfig{CustomConfig: tt.custom})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("Configure error = %v, want %s", err, tt.wantCode)
+			}
+		})
+	}
+
+	// Failed configurations keep the valid policy.
+	p.enforcer.Load().logger = log.New(&bytes.Buffer{}, "", 0)
+	if resp, err := p.HandleRequest(aliceCtx, search); err != nil || !resp.GetContinue() {
+		t.Errorf("HandleRequest = %v, %v, want search allowed", resp, err)
+	}
+	resp, err := p.HandleResponse(aliceCtx, &mcpdpluginsv1.HTTPResponse{
+		StatusCode: 200,
+		Body:       []byte(result(1, `{"tools":[{"name":"search"},{"name":"delete"}]}`)),
+	})
+	if err != nil || !jsonEqual(t, resp.GetBody(), []byte(result(1, `{"tools":[{"name":"search"}]}`))) {
+		t.Errorf("HandleResponse = %s, %v, want delete filtered out", resp.GetBody(), err)
+	}
+}
@@ -0,0 +1,116 @@
+package recordenc_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/recordenc"
+)
+
+func TestCEF(t *testing.T) {
+	const rt = "rt=1760104536500"
+	tests := []struct {
+		name    string
+		enc     recordenc.Encoder
+		records []recordenc.Record
+		want    string
+	}{
+		{
+			name:    "defaults",
+			enc:     recordenc.CEF("", "", ""),
+			records: []recordenc.Record{{Type: "config.reloaded", Time: recordTime}},
+			want:    "CEF:0|Mozilla.ai|mcpd-plugin|0|config.reloaded|config.reloaded|3|" + rt,
+		},
+		{
+			name: "record",
+			enc:  recordenc.CEF("Acme", "guard", "1.2.3"),
+			records: []recordenc.Record{{
+				Type:     "request.denied",
+				Time:     recordTime,
+				Severity: recordenc.SeverityWarning,
+				Source:   "my-plugin",
+				Message:  "blocked by policy",
+				Attributes: map[string]string{
+					"path":        "/mcp",
+					"method":      "POST",
+					"duration_ms": "1.5",
+					"rule":        "deny-all",
+				},
+			}},
+			want: "CEF:0|Acme|guard|1.2.3|request.denied|blocked by policy|6|" + rt + " msg=blocked by policy" +
+				" cs1=my-plugin cs1Label=source cn1=1.5 cn1Label=duration_ms requestMethod=POST" +
+				" request=/mcp cs2=deny-all cs2Label=rule",
+		},
+		{
+			name: "header escaping",
+			enc:  recordenc.CEF(`Ac|me`, `gu\ard`, "1\n2"),
+			records: []recordenc.Record{{
+				Type:     "a|b",
+				Severity: recordenc.SeverityError,
+				Message:  "line1\r\nline2",
+			}},
+			want: `CEF:0|Ac\|me|gu\\ard|1 2|a\|b|line1  line2|8|msg=line1\r\nline2`,
+		},
+		{
+			name: "extension escaping",
+			enc:  recordenc.CEF("", "", ""),
+			records: []recordenc.Record{{
+				Type:       "t",
+				Attributes: map[string]string{"a=b": `x=y\z`, "path": "/a|b\n"},
+			}},
+			want: `CEF:0|Mozilla.ai|mcpd-plugin|0|t|t|3|cs1=x\=y\\z cs1Label=a\=b request=/a|b\n`,
+		},
+		{
+			name:    "unknown severity",
+			enc:     recordenc.CEF("", "", ""),
+			records: []recordenc.Record{{Type: "t", Severity: recordenc.Severity(9)}},
+			want:    "CEF:0|Mozilla.ai|mcpd-plugin|0|t|t|3|",
+		},
+		{
+			name: "several records",
+			enc:  recordenc.CEF("", "", ""),
+			records: []recordenc.Record{
+				{Type: "a", Time: recordTime},
+				{Type: "b", Time: recordTime, Severity: recordenc.SeverityError},
+			},
+			want: "CEF:0|Mozilla.ai|mcpd-plugin|0|a|a|3|" + rt + "\nCEF:0|Mozilla.ai|mcpd-plugin|0|b|b|8|" + rt,
+		},
+		{name: "no records", enc: recordenc.CEF("", "", "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.enc.Encode(tt.records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Encode =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCEFCustomSlots(t *testing.T) {
+	attrs := map[string]string{}
+	for i := range 8 {
+		attrs[fmt.Sprintf("k%d", i)] = fmt.Sprintf("v%d", i)
+	}
+	got, err := recordenc.CEF("", "", "").Encode([]recordenc.Record{{Type: "t", Source: "src", Attributes: attrs}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The source takes the first slot, then attributes in name order until the slots run out.
+	line := string(got)
+	for i, want := range []string{"cs1=src", "cs2=v0", "cs3=v1", "cs4=v2", "cs5=v3", "cs6=v4"} {
+		if !strings.Contains(line, " "+want+" ") && !strings.Contains(line, "|"+want+" ") {
+			t.Errorf("slot %d: %q missing from %s", i+1, want, line)
+		}
+	}
+	for _, dropped := range []string{"v5", "v6", "v7", "cs7"} {
+		if strings.Contains(line, dropped) {
+			t.Errorf("%q written beyond the six custom slots: %s", dropped, line)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package recordenc
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// otlpScopeName is the instrumentation scope of the exported log records.
+const otlpScopeName = "github.com/mozilla-ai/mcpd-plugins-sdk-go"
+
+// otlpSeverities are the OTLP severity numbers and texts of each Severity.
+var otlpSeverities = map[Severity]struct {
+	number int
+	text   string
+}{
+	SeverityInfo:    {9, "INFO"},
+	SeverityWarning: {13, "WARN"},
+	SeverityError:   {17, "ERROR"},
+}
+
+type otlpEncoder struct {
+	resource []otlpKeyValue
+}
+
+// OTLPLogs returns an Encoder writing a batch as an OTLP ExportLogsServiceRequest in the OTLP/JSON
+// encoding, the body of a POST to a collector's /v1/logs endpoint and the line format of the
+// collector's file exporter. Each record becomes a log record whose body is its message, with
+// its type as the event.name attribute and its source as source; resource attributes, such
+// as service.name, describe the emitting plugin. Records without a time have the OTLP unknown
+// timestamp, 0.
+func OTLPLogs(resource map[string]string) Encoder {
+	return otlpEncoder{resource: otlpAttributes(resource)}
+}
+
+func (otlpEncoder) ContentType() string { return ContentTypeOTLPJSON }
+
+func (e otlpEncoder) Encode(records []Record) ([]byte, error) {
+	logs := make([]otlpLogRecord, 0, len(records))
+	for _, r := range records {
+		attrs := map[string]string{"event.name": r.Type}
+		if r.Source != "" {
+			attrs["source"] = r.Source
+		}
+		maps.Copy(attrs, r.Attributes)
+		sev := otlpSeverities[r.Severity.known()]
+		ts := "0" // Unknown, in OTLP.
+		if !r.Time.IsZero() {
+			ts = strconv.FormatInt(r.Time.UnixNano(), 10)
+		}
+		logs = append(logs, otlpLogRecord{
+			TimeUnixNano:         ts,
+			ObservedTimeUnixNano: ts,
+			SeverityNumber:       sev.number,
+			SeverityText:         sev.text,
+			Body:                 otlpAnyValue{StringValue: r.Message},
+			Attributes:           otlpAttributes(attrs),
+		})
+	}
+
+	return json.Marshal(otlpLogsRequest{ResourceLogs: []otlpResourceLogs{{
+		Resource:  otlpResource{Attributes: e.resource},
+		ScopeLogs: []otlpScopeLogs{{Scope: otlpScope{Name: otlpScopeName}, LogRecords: logs}},
+	}}})
+}
+
+// otlpAttributes returns attrs as OTLP key-values, sorted by key.
+func otlpAttributes(attrs map[string]string) []otlpKeyValue {
+	kvs := make([]otlpKeyValue, 0, len(attrs))
+	for _, k := range slices.Sorted(maps.Keys(attrs)) {
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: otlpAnyValue{StringValue: attrs[k]}})
+	}
+
+	return kvs
+}
+
+// OTLP/JSON wire types. Field names follow the protobuf JSON mapping; 64-bit integers are strings.
+type (
+	otlpLogsRequest struct {
+		ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+	}
+	otlpResourceLogs struct {
+		Resource  otlpResource    `json:"resource"`
+		ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes,omitempty"`
+	}
+	otlpScopeLogs struct {
+		Scope      otlpScope       `json:"scope"`
+		LogRecords []otlpLogRecord `json:"logRecords"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpLogRecord struct {
+		TimeUnixNano         string         `json:"timeUnixNano"`
+		ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+		SeverityNumber       int            `json:"severityNumber"`
+		SeverityText         string         `json:"severityText"`
+		Body                 otlpAnyValue   `json:"body"`
+		Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpAnyValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
@@ -0,0 +1,100 @@
+package recordenc_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/recordenc"
+)
+
+// jsonValue decodes s, failing the test when it is not valid JSON.
+func jsonValue(t *testing.T, s string) any {
+	t.Helper()
+
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", s, err)
+	}
+
+	return v
+}
+
+func TestOTLPLogs(t *testing.T) {
+	const scope = `"scope":{"name":"github.com/mozilla-ai/mcpd-plugins-sdk-go"}`
+	tests := []struct {
+		name     string
+		resource map[string]string
+		records  []recordenc.Record
+		want     string
+	}{
+		{
+			name:     "record",
+			resource: map[string]string{"service.name": "guard", "deployment.environment": "dev"},
+			records: []recordenc.Record{{
+				Type:       "request.denied",
+				Time:       recordTime,
+				Severity:   recordenc.SeverityWarning,
+				Source:     "my-plugin",
+				Message:    "blocked",
+				Attributes: map[string]string{"rule": "deny-all", "method": "POST"},
+			}},
+			want: `{"resourceLogs":[{"resource":{"attributes":[
+				{"key":"deployment.environment","value":{"stringValue":"dev"}},
+				{"key":"service.name","value":{"stringValue":"guard"}}]},
+				"scopeLogs":[{` + scope + `,"logRecords":[{
+					"timeUnixNano":"1760104536500000000","observedTimeUnixNano":"1760104536500000000",
+					"severityNumber":13,"severityText":"WARN","body":{"stringValue":"blocked"},
+					"attributes":[
+						{"key":"event.name","value":{"stringValue":"request.denied"}},
+						{"key":"method","value":{"stringValue":"POST"}},
+						{"key":"rule","value":{"stringValue":"deny-all"}},
+						{"key":"source","value":{"stringValue":"my-plugin"}}]}]}]}]}`,
+		},
+		{
+			name: "severities",
+			records: []recordenc.Record{
+				{Type: "i", Time: recordTime, Severity: recordenc.SeverityInfo},
+				{Type: "e", Time: recordTime, Severity: recordenc.SeverityError},
+				{Type: "u", Time: recordTime, Severity: recordenc.Severity(9)},
+			},
+			want: `{"resourceLogs":[{"resource":{},"scopeLogs":[{` + scope + `,"logRecords":[
+				{"timeUnixNano":"1760104536500000000","observedTimeUnixNano":"1760104536500000000",
+				"severityNumber":9,"severityText":"INFO","body":{"stringValue":""},
+				"attributes":[{"key":"event.name","value":{"stringValue":"i"}}]},
+				{"timeUnixNano":"1760104536500000000","observedTimeUnixNano":"1760104536500000000",
+				"severityNumber":17,"severityText":"ERROR","body":{"stringValue":""},
+				"attributes":[{"key":"event.name","value":{"stringValue":"e"}}]},
+				{"timeUnixNano":"1760104536500000000","observedTimeUnixNano":"1760104536500000000",
+				"severityNumber":9,"severityText":"INFO","body":{"stringValue":""},
+				"attributes":[{"key":"event.name","value":{"stringValue":"u"}}]}]}]}]}`,
+		},
+		{
+			name:    "no time",
+			records: []recordenc.Record{{Type: "t"}},
+			want: `{"resourceLogs":[{"resource":{},"scopeLogs":[{` + scope + `,"logRecords":[
+				{"timeUnixNano":"0","observedTimeUnixNano":"0",
+				"severityNumber":9,"severityText":"INFO","body":{"stringValue":""},
+				"attributes":[{"key":"event.name","value":{"stringValue":"t"}}]}]}]}]}`,
+		},
+		{
+			name: "no records",
+			want: `{"resourceLogs":[{"resource":{},"scopeLogs":[{` + scope + `,"logRecords":[]}]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc := recordenc.OTLPLogs(tt.resource)
+			if got := enc.ContentType(); got != recordenc.ContentTypeOTLPJSON {
+				t.Errorf("ContentType = %s, want %s", got, recordenc.ContentTypeOTLPJSON)
+			}
+			got, err := enc.Encode(tt.records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(jsonValue(t, string(got)), jsonValue(t, tt.want)) {
+				t.Errorf("Encode =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
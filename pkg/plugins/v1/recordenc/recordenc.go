@@ -0,0 +1,167 @@
+// Package recordenc encodes audit and event records for log pipelines and SIEMs, so sinks can
+// emit the format an ingestion endpoint expects without a custom sink per format. An Encoder
+// turns a batch of Records into one payload; the package provides JSON Lines, protobuf, ArcSight
+// CEF and OTLP logs encoders.
+//
+// The events sinks and the access log accept an Encoder:
+//
+//	sink := events.NewWebhookSink(url, nil).WithEncoder(recordenc.OTLPLogs(map[string]string{
+//	    "service.name": "my-plugin",
+//	}))
+//
+//	l, err := accesslog.New(os.Stdout, accesslog.Encoded(recordenc.CEF("", "my-plugin", "1.0.0")))
+package recordenc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Content types of the encoders' payloads.
+const (
+	ContentTypeJSONLines = "application/x-ndjson"
+	ContentTypeProtobuf  = "application/x-protobuf"
+	ContentTypeCEF       = "text/plain; charset=utf-8"
+	ContentTypeOTLPJSON  = "application/json"
+)
+
+// Severity grades a Record.
+type Severity int
+
+// Severities, from least to most severe. The zero Severity is SeverityInfo.
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+// known returns s, or SeverityInfo when s is not one of the defined severities, as String
+// reports it.
+func (s Severity) known() Severity {
+	if s == SeverityWarning || s == SeverityError {
+		return s
+	}
+
+	return SeverityInfo
+}
+
+// String returns "info", "warning" or "error".
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// Record is one audit or event record.
+type Record struct {
+	// Type identifies the kind of record, such as "request.denied".
+	Type string `json:"type"`
+
+	Time     time.Time `json:"time"`
+	Severity Severity  `json:"-"`
+
+	// Source identifies what emitted the record, such as the plugin name.
+	Source string `json:"source,omitempty"`
+
+	Message    string            `json:"message,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Encoder encodes batches of records. Implementations must be safe for concurrent use.
+type Encoder interface {
+	// ContentType returns the media type of the encoded payloads.
+	ContentType() string
+
+	// Encode encodes records as one payload.
+	Encode(records []Record) ([]byte, error)
+}
+
+// Text reports whether payloads of enc are text made of whole lines, without a trailing newline,
+// rather than binary: JSON and CEF write a line per record, OTLPLogs a line per batch.
+func Text(enc Encoder) bool {
+	return enc.ContentType() != ContentTypeProtobuf
+}
+
+// jsonRecord is the JSON form of a Record.
+type jsonRecord struct {
+	Record
+	Severity string `json:"severity"`
+}
+
+type jsonLines struct{}
+
+// JSON returns an Encoder writing each record as a JSON object on its own line (JSON Lines), with
+// the severity as "info", "warning" or "error".
+func JSON() Encoder {
+	return jsonLines{}
+}
+
+func (jsonLines) ContentType() string { return ContentTypeJSONLines }
+
+func (jsonLines) Encode(records []Record) ([]byte, error) {
+	var b bytes.Buffer
+	for i, r := range records {
+		line, err := json.Marshal(jsonRecord{Record: r, Severity: r.Severity.String()})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode record: %w", err)
+		}
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.Write(line)
+	}
+
+	return b.Bytes(), nil
+}
+
+type protobufEncoder struct{}
+
+// Protobuf returns an Encoder writing a batch as a google.protobuf.ListValue holding one
+// google.protobuf.Struct per record, with the fields of the JSON encoding, so consumers can
+// decode it with the protobuf well-known types alone.
+func Protobuf() Encoder {
+	return protobufEncoder{}
+}
+
+func (protobufEncoder) ContentType() string { return ContentTypeProtobuf }
+
+func (protobufEncoder) Encode(records []Record) ([]byte, error) {
+	list := &structpb.ListValue{Values: make([]*structpb.Value, 0, len(records))}
+	for _, r := range records {
+		fields := map[string]any{
+			"type":     r.Type,
+			"time":     r.Time.UTC().Format(time.RFC3339Nano),
+			"severity": r.Severity.String(),
+		}
+		if r.Source != "" {
+			fields["source"] = r.Source
+		}
+		if r.Message != "" {
+			fields["message"] = r.Message
+		}
+		if len(r.Attributes) > 0 {
+			attrs := make(map[string]any, len(r.Attributes))
+			for k, v := range r.Attributes {
+				attrs[k] = v
+			}
+			fields["attributes"] = attrs
+		}
+		s, err := structpb.NewStruct(fields)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode record: %w", err)
+		}
+		list.Values = append(list.Values, structpb.NewStructValue(s))
+	}
+
+	return proto.MarshalOptions{Deterministic: true}.Marshal(list)
+}
@@ -0,0 +1,182 @@
+package recordenc_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/recordenc"
+)
+
+var recordTime = time.Date(2025, 10, 10, 13, 55, 36, 500_000_000, time.UTC)
+
+// testRecords returns a denied request warning and a bare informational record.
+func testRecords() []recordenc.Record {
+	return []recordenc.Record{
+		{
+			Type:       "request.denied",
+			Time:       recordTime,
+			Severity:   recordenc.SeverityWarning,
+			Source:     "my-plugin",
+			Message:    "blocked by policy",
+			Attributes: map[string]string{"path": "/mcp", "rule": "deny-all"},
+		},
+		{Type: "config.reloaded", Time: recordTime},
+	}
+}
+
+func TestSeverityString(t *testing.T) {
+	tests := []struct {
+		sev  recordenc.Severity
+		want string
+	}{
+		{recordenc.SeverityInfo, "info"},
+		{recordenc.SeverityWarning, "warning"},
+		{recordenc.SeverityError, "error"},
+		{recordenc.Severity(42), "info"},
+		{recordenc.Severity(-1), "info"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.sev.String(); got != tt.want {
+				t.Errorf("Severity(%d).String() = %q, want %q", tt.sev, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name        string
+		enc         recordenc.Encoder
+		contentType string
+		text        bool
+	}{
+		{name: "json", enc: recordenc.JSON(), contentType: recordenc.ContentTypeJSONLines, text: true},
+		{name: "protobuf", enc: recordenc.Protobuf(), contentType: recordenc.ContentTypeProtobuf},
+		{name: "cef", enc: recordenc.CEF("", "", ""), contentType: recordenc.ContentTypeCEF, text: true},
+		{name: "otlp", enc: recordenc.OTLPLogs(nil), contentType: recordenc.ContentTypeOTLPJSON, text: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.enc.ContentType(); got != tt.contentType {
+				t.Errorf("ContentType = %q, want %q", got, tt.contentType)
+			}
+			if got := recordenc.Text(tt.enc); got != tt.text {
+				t.Errorf("Text = %t, want %t", got, tt.text)
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []recordenc.Record
+		want    []map[string]any
+	}{
+		{
+			name:    "records",
+			records: testRecords(),
+			want: []map[string]any{
+				{
+					"type":       "request.denied",
+					"time":       "2025-10-10T13:55:36.5Z",
+					"severity":   "warning",
+					"source":     "my-plugin",
+					"message":    "blocked by policy",
+					"attributes": map[string]any{"path": "/mcp", "rule": "deny-all"},
+				},
+				{"type": "config.reloaded", "time": "2025-10-10T13:55:36.5Z", "severity": "info"},
+			},
+		},
+		{
+			name:    "message with a newline stays on its line",
+			records: []recordenc.Record{{Type: "t", Time: recordTime, Message: "a\nb"}},
+			want: []map[string]any{
+				{"type": "t", "time": "2025-10-10T13:55:36.5Z", "severity": "info", "message": "a\nb"},
+			},
+		},
+		{name: "no records"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := recordenc.JSON().Encode(tt.records)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.records) == 0 {
+				if len(payload) != 0 {
+					t.Errorf("payload = %q, want none", payload)
+				}
+				return
+			}
+			if strings.HasSuffix(string(payload), "\n") {
+				t.Errorf("payload %q ends with a newline", payload)
+			}
+			lines := strings.Split(string(payload), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("payload has %d lines, want %d: %q", len(lines), len(tt.want), payload)
+			}
+			for i, line := range lines {
+				var got map[string]any
+				if err := json.Unmarshal([]byte(line), &got); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, tt.want[i]) {
+					t.Errorf("line %d = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	payload, err := recordenc.Protobuf().Encode(testRecords())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list structpb.ListValue
+	if err := proto.Unmarshal(payload, &list); err != nil {
+		t.Fatal(err)
+	}
+	want := []any{
+		map[string]any{
+			"type":       "request.denied",
+			"time":       "2025-10-10T13:55:36.5Z",
+			"severity":   "warning",
+			"source":     "my-plugin",
+			"message":    "blocked by policy",
+			"attributes": map[string]any{"path": "/mcp", "rule": "deny-all"},
+		},
+		map[string]any{"type": "config.reloaded", "time": "2025-10-10T13:55:36.5Z", "severity": "info"},
+	}
+	if got := list.AsSlice(); !reflect.DeepEqual(got, want) {
+		t.Errorf("records = %v, want %v", got, want)
+	}
+
+	// The encoding is deterministic, for deduplicating sinks.
+	again, err := recordenc.Protobuf().Encode(testRecords())
+	if err != nil || string(again) != string(payload) {
+		t.Errorf("second encoding differs: %v", err)
+	}
+
+	empty, err := recordenc.Protobuf().Encode(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := proto.Unmarshal(empty, &list); err != nil || len(list.GetValues()) != 0 {
+		t.Errorf("empty batch decodes to %v, %v; want no records", list.GetValues(), err)
+	}
+}
+
+func TestProtobufInvalidUTF8(t *testing.T) {
+	if _, err := recordenc.Protobuf().Encode([]recordenc.Record{{Type: "t", Message: "\xff"}}); err == nil {
+		t.Error("Encode accepted a message that is not UTF-8")
+	}
+}
@@ -0,0 +1,268 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/manifest"
+)
+
+// Announcement statuses.
+const (
+	// AnnouncementServing marks a plugin that is serving at its address.
+	AnnouncementServing = "serving"
+
+	// AnnouncementStopping marks a plugin that is shutting down and should no longer be used.
+	AnnouncementStopping = "stopping"
+)
+
+// Retry bounds of failed announcements and the timeout of the final one.
+const (
+	announceMinBackoff   = time.Second
+	announceMaxBackoff   = time.Minute
+	announceFinalTimeout = 5 * time.Second
+)
+
+// Announcement describes a plugin instance to a discovery service or fleet inventory.
+type Announcement struct {
+	// Status is AnnouncementServing or AnnouncementStopping.
+	Status string    `json:"status"`
+	Time   time.Time `json:"time"`
+
+	// Plugin and Version are the plugin's GetMetadata name and version, and Commit the commit of
+	// its build manifest when one is embedded.
+	Plugin  string `json:"plugin,omitempty"`
+	Version string `json:"version,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+
+	// Network and Address are where the plugin serves gRPC, as passed to --network and --address.
+	Network string `json:"network"`
+	Address string `json:"address"`
+
+	// Host and PID identify the process.
+	Host string `json:"host,omitempty"`
+	PID  int    `json:"pid"`
+
+	// APIVersion is the plugin API version the SDK speaks (ProtoVersion), Flows the flows the
+	// plugin returns from GetCapabilities, and Features the optional features offered to mcpd.
+	APIVersion string   `json:"apiVersion"`
+	Flows      []string `json:"flows,omitempty"`
+	Features   []string `json:"features,omitempty"`
+}
+
+// Announcer publishes Announcements, such as to a discovery endpoint. It must be safe for
+// concurrent use.
+type Announcer interface {
+	Announce(ctx context.Context, a Announcement) error
+}
+
+// AnnouncerFunc adapts a function to the Announcer interface.
+type AnnouncerFunc func(ctx context.Context, a Announcement) error
+
+// Announce calls f(ctx, a).
+func (f AnnouncerFunc) Announce(ctx context.Context, a Announcement) error {
+	return f(ctx, a)
+}
+
+// HTTPAnnouncer returns an Announcer POSTing each Announcement as JSON to endpoint with client
+// (nil uses a client with a 10s timeout). Responses other than 2xx are errors.
+func HTTPAnnouncer(endpoint string, client *http.Client) (Announcer, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("registration endpoint must be an http or https URL, got %q", endpoint)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return AnnouncerFunc(func(ctx context.Context, a Announcement) error {
+		body, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("registration endpoint returned %s", resp.Status)
+		}
+		return nil
+	}), nil
+}
+
+// WithRegistration announces the plugin to the discovery endpoint at endpoint with an
+// HTTPAnnouncer, as WithAnnouncer does.
+func WithRegistration(endpoint string, every time.Duration) ServeOption {
+	return func(o *serveOptions) error {
+		a, err := HTTPAnnouncer(endpoint, nil)
+		if err != nil {
+			return err
+		}
+		return WithAnnouncer(a, every)(o)
+	}
+}
+
+// WithAnnouncer makes Serve announce the plugin through a once it is serving: its name, version,
+// capabilities and address, so mcpd or fleet inventory tools can discover plugins dynamically.
+// Failed announcements are logged and retried with backoff, and with a positive every the
+// announcement is repeated at that interval, for registries that expire stale entries. On
+// shutdown a final AnnouncementStopping is sent, best effort.
+//
+// Registration never delays or stops serving.
+//
+// Usage:
+//
+//	err := mcpdpluginsv1.Serve(&MyPlugin{},
+//	    mcpdpluginsv1.WithRegistration("https://inventory.internal/v1/plugins", time.Minute))
+func WithAnnouncer(a Announcer, every time.Duration) ServeOption {
+	return func(o *serveOptions) error {
+		if a == nil {
+			return fmt.Errorf("announcer cannot be nil")
+		}
+		if every < 0 {
+			return fmt.Errorf("announcement interval cannot be negative")
+		}
+		o.registration = &registrar{announcer: a, every: every}
+		return nil
+	}
+}
+
+// registrar announces the plugin while it serves.
+type registrar struct {
+	announcer Announcer
+	every     time.Duration
+}
+
+// start announces the plugin described by info in the background, returning the function that
+// stops announcing and sends the final announcement. It also runs, once, when shutdown begins, so
+// the registry stops routing to the plugin before it drains.
+func (r *registrar) start(ctx context.Context, o *serveOptions, info startupInfo) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		once      sync.Once
+		last      Announcement
+		announced bool
+	)
+
+	wg.Go(func() {
+		a := announcement(ctx, info)
+		mu.Lock()
+		last = a
+		mu.Unlock()
+
+		backoff := announceMinBackoff
+		for {
+			a.Time = time.Now().UTC()
+			wait := r.every
+			if err := r.announcer.Announce(ctx, a); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				o.logger.Printf("plugin announcement failed, retrying in %s: %v", backoff, err)
+				wait = backoff
+				backoff = min(2*backoff, announceMaxBackoff)
+			} else {
+				mu.Lock()
+				announced = true
+				mu.Unlock()
+				backoff = announceMinBackoff
+				if wait == 0 {
+					return
+				}
+			}
+
+			t := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return
+			case <-t.C:
+			}
+		}
+	})
+
+	stop := func() {
+		once.Do(func() {
+			cancel()
+			wg.Wait()
+
+			mu.Lock()
+			a, ok := last, announced
+			mu.Unlock()
+			if !ok {
+				return
+			}
+			a.Status, a.Time = AnnouncementStopping, time.Now().UTC()
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), announceFinalTimeout)
+			defer cancel()
+			if err := r.announcer.Announce(ctx, a); err != nil {
+				o.logger.Printf("plugin stop announcement failed: %v", err)
+			}
+		})
+	}
+	unsubscribe := o.bus.Subscribe(func(_ context.Context, ev Event) {
+		if ev.Phase == PhaseStopping {
+			stop()
+		}
+	}, EventLifecycle)
+
+	return func() {
+		unsubscribe()
+		stop()
+	}
+}
+
+// announcement builds the AnnouncementServing of the plugin described by info. What cannot be
+// read from the plugin is left out.
+func announcement(ctx context.Context, info startupInfo) Announcement {
+	a := Announcement{
+		Status:     AnnouncementServing,
+		Network:    info.network,
+		Address:    info.address,
+		PID:        os.Getpid(),
+		APIVersion: ProtoVersion,
+	}
+	a.Host, _ = os.Hostname()
+	for _, f := range info.features {
+		a.Features = append(a.Features, string(f))
+	}
+	if env, err := manifest.Decode(manifest.Embedded()); err == nil {
+		if m, err := env.Manifest(); err == nil {
+			a.Commit = m.Commit
+		}
+	}
+	if md, err := info.impl.GetMetadata(ctx, &emptypb.Empty{}); err == nil {
+		a.Plugin, a.Version = md.GetName(), md.GetVersion()
+		if a.Commit == "" {
+			a.Commit = md.GetCommitHash()
+		}
+	}
+	if caps, err := info.impl.GetCapabilities(ctx, &emptypb.Empty{}); err == nil {
+		for _, f := range caps.GetFlows() {
+			a.Flows = append(a.Flows, f.String())
+		}
+	}
+
+	return a
+}
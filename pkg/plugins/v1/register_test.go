@@ -0,0 +1,322 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+)
+
+// announcementLog is an Announcer recording the announcements it receives, failing the first
+// failures calls.
+type announcementLog struct {
+	mu       sync.Mutex
+	got      []Announcement
+	failures int
+	calls    int
+}
+
+func (l *announcementLog) Announce(_ context.Context, a Announcement) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.calls++
+	if l.calls <= l.failures {
+		return errors.New("registry unavailable")
+	}
+	l.got = append(l.got, a)
+
+	return nil
+}
+
+// attempts returns the number of Announce calls, failed or not.
+func (l *announcementLog) attempts() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.calls
+}
+
+// statuses returns the statuses of the announcements received.
+func (l *announcementLog) statuses() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	out := make([]string, 0, len(l.got))
+	for _, a := range l.got {
+		out = append(out, a.Status)
+	}
+
+	return out
+}
+
+func TestHTTPAnnouncer(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string // Empty uses the test server.
+		status   int
+		wantErr  string
+	}{
+		{name: "accepted", status: http.StatusOK},
+		{name: "no content", status: http.StatusNoContent},
+		{name: "rejected", status: http.StatusServiceUnavailable, wantErr: "returned 503 Service Unavailable"},
+		{name: "not modified", status: http.StatusNotModified, wantErr: "returned 304"},
+		{name: "not a URL", endpoint: "://bad", wantErr: "must be an http or https URL"},
+		{name: "unsupported scheme", endpoint: "ftp://registry.example.com", wantErr: "must be an http or https URL"},
+		{name: "no host", endpoint: "http:///v1/plugins", wantErr: "must be an http or https URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Announcement
+			var contentType string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contentType = r.Header.Get("Content-Type")
+				if r.Method != http.MethodPost {
+					t.Errorf("method = %s, want POST", r.Method)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decoding the announcement: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			endpoint := tt.endpoint
+			if endpoint == "" {
+				endpoint = srv.URL + "/v1/plugins"
+			}
+			a, err := HTTPAnnouncer(endpoint, srv.Client())
+			if err == nil {
+				want := Announcement{Status: AnnouncementServing, Plugin: "p", Address: "127.0.0.1:1", PID: 7}
+				err = a.Announce(context.Background(), want)
+				if err == nil && (got.Status != want.Status || got.Plugin != want.Plugin ||
+					got.Address != want.Address || got.PID != want.PID) {
+					t.Errorf("endpoint received %+v, want %+v", got, want)
+				}
+				if err == nil && contentType != "application/json" {
+					t.Errorf("Content-Type = %q, want application/json", contentType)
+				}
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Announce error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPAnnouncerUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	endpoint := srv.URL
+	srv.Close()
+
+	a, err := HTTPAnnouncer(endpoint, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Announce(context.Background(), Announcement{}); err == nil {
+		t.Error("Announce to a closed server succeeded")
+	}
+}
+
+func TestWithAnnouncer(t *testing.T) {
+	tests := []struct {
+		name    string
+		opt     ServeOption
+		wantErr string
+	}{
+		{name: "once", opt: WithAnnouncer(&announcementLog{}, 0)},
+		{name: "repeated", opt: WithAnnouncer(&announcementLog{}, time.Minute)},
+		{name: "nil announcer", opt: WithAnnouncer(nil, 0), wantErr: "announcer cannot be nil"},
+		{
+			name:    "negative interval",
+			opt:     WithAnnouncer(&announcementLog{}, -time.Second),
+			wantErr: "cannot be negative",
+		},
+		{name: "registration", opt: WithRegistration("https://registry.example.com/v1/plugins", time.Minute)},
+		{
+			name:    "registration with a bad endpoint",
+			opt:     WithRegistration("registry.example.com", 0),
+			wantErr: "must be an http or https URL",
+		},
+		{
+			name:    "registration with a negative interval",
+			opt:     WithRegistration("https://registry.example.com", -time.Second),
+			wantErr: "cannot be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(tt.opt)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.registration == nil {
+				t.Error("registration not configured")
+			}
+		})
+	}
+}
+
+func TestAnnouncement(t *testing.T) {
+	tests := []struct {
+		name        string
+		plugin      *reportedPlugin
+		wantPlugin  string
+		wantVersion string
+		wantCommit  string
+		wantFlows   []string
+	}{
+		{
+			name:        "metadata and capabilities",
+			plugin:      &reportedPlugin{},
+			wantPlugin:  "reported",
+			wantVersion: "1.2.3",
+			wantCommit:  "abc123",
+			wantFlows:   []string{"FLOW_REQUEST", "FLOW_RESPONSE"},
+		},
+		{
+			name:      "metadata failure",
+			plugin:    &reportedPlugin{metadataErr: errors.New("boom")},
+			wantFlows: []string{"FLOW_REQUEST", "FLOW_RESPONSE"},
+		},
+		{
+			name:        "capabilities failure",
+			plugin:      &reportedPlugin{capabilitiesErr: errors.New("boom")},
+			wantPlugin:  "reported",
+			wantVersion: "1.2.3",
+			wantCommit:  "abc123",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := announcement(context.Background(), startupInfo{
+				impl:     tt.plugin,
+				network:  "tcp",
+				address:  "127.0.0.1:9000",
+				features: features.NewSet(features.HeadersOnly, features.SelectiveFields),
+			})
+			if a.Status != AnnouncementServing || a.Network != "tcp" || a.Address != "127.0.0.1:9000" ||
+				a.APIVersion != ProtoVersion || a.PID == 0 {
+				t.Errorf("announcement = %+v, want the serving tcp address", a)
+			}
+			if a.Plugin != tt.wantPlugin || a.Version != tt.wantVersion || a.Commit != tt.wantCommit {
+				t.Errorf("plugin = %q %q %q, want %q %q %q",
+					a.Plugin, a.Version, a.Commit, tt.wantPlugin, tt.wantVersion, tt.wantCommit)
+			}
+			if !slices.Equal(a.Flows, tt.wantFlows) {
+				t.Errorf("Flows = %v, want %v", a.Flows, tt.wantFlows)
+			}
+			want := []string{string(features.HeadersOnly), string(features.SelectiveFields)}
+			slices.Sort(want)
+			if !slices.Equal(a.Features, want) {
+				t.Errorf("Features = %v, want %v", a.Features, want)
+			}
+		})
+	}
+}
+
+func TestRegistrarStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		every    time.Duration
+		failures int
+		wait     func(l *announcementLog) bool // Awaited before stopping.
+		want     []string                      // Statuses once stopped.
+	}{
+		{
+			name: "once",
+			wait: func(l *announcementLog) bool { return len(l.statuses()) == 1 },
+			want: []string{AnnouncementServing, AnnouncementStopping},
+		},
+		{
+			name:  "repeated",
+			every: time.Millisecond,
+			wait:  func(l *announcementLog) bool { return len(l.statuses()) >= 3 },
+		},
+		{
+			name:     "retried after a failure",
+			failures: 1,
+			wait:     func(l *announcementLog) bool { return len(l.statuses()) == 1 },
+			want:     []string{AnnouncementServing, AnnouncementStopping},
+		},
+		{
+			name:     "never announced sends no stop",
+			failures: 100,
+			wait:     func(l *announcementLog) bool { return l.attempts() >= 1 },
+			want:     []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs syncBuffer
+			o, err := newServeOptions(WithLogger(log.New(&logs, "", 0)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			l := &announcementLog{failures: tt.failures}
+			r := &registrar{announcer: l, every: tt.every}
+			stop := r.start(context.Background(), o, startupInfo{impl: &reportedPlugin{}, network: "tcp"})
+			waitFor(t, "the announcements", func() bool { return tt.wait(l) })
+			stop()
+			stop()
+
+			got := l.statuses()
+			if tt.want != nil && !slices.Equal(got, tt.want) {
+				t.Errorf("statuses = %v, want %v", got, tt.want)
+			}
+			if tt.want == nil {
+				// Repeated announcements end with a single stop.
+				n := len(got)
+				if got[n-1] != AnnouncementStopping || slices.Index(got, AnnouncementStopping) != n-1 {
+					t.Errorf("statuses = %v, want servings then one stopping", got)
+				}
+			}
+			if tt.failures > 0 && !strings.Contains(logs.String(), "plugin announcement failed, retrying in 1s") {
+				t.Errorf("log = %q, want the failure logged", logs.String())
+			}
+		})
+	}
+}
+
+func TestRegistrarStopsOnShutdown(t *testing.T) {
+	o, err := newServeOptions(WithLogger(discardLogger()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &announcementLog{}
+	r := &registrar{announcer: l}
+	stop := r.start(context.Background(), o, startupInfo{impl: &reportedPlugin{}})
+	waitFor(t, "the announcement", func() bool { return len(l.statuses()) == 1 })
+
+	// Shutdown beginning sends the stop announcement, before the server drains.
+	o.bus.Publish(context.Background(), Event{Kind: EventLifecycle, Phase: PhaseStopping})
+	if got, want := l.statuses(), []string{AnnouncementServing, AnnouncementStopping}; !slices.Equal(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+
+	stop()
+	if got := len(l.statuses()); got != 2 {
+		t.Errorf("%d announcements after stop, want the stop sent once", got)
+	}
+}
@@ -0,0 +1,220 @@
+// Package replay captures plugin traffic and replays it through a plugin build offline, diffing
+// the outputs against what was recorded, for regression-testing policy changes against real traffic.
+//
+// Capture traffic from a running plugin with a Recorder:
+//
+//	f, err := os.Create("traffic.jsonl")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	rec := replay.NewRecorder(f)
+//
+//	err = mcpdpluginsv1.Serve(&MyPlugin{}, mcpdpluginsv1.WithUnaryInterceptor(rec.Interceptor()))
+//
+// Then replay it against a new build, e.g. from a test or a small command:
+//
+//	records, err := replay.Load(f)
+//	report, err := replay.Run(ctx, &MyPlugin{}, records)
+//	for _, d := range report.Diffs {
+//	    fmt.Println(d)
+//	}
+//
+// Recordings contain full request and response bodies and headers; consider masking them (see the
+// pii package) or restricting where they are stored.
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// Methods captured by a Recorder.
+const (
+	MethodConfigure      = "Configure"
+	MethodHandleRequest  = "HandleRequest"
+	MethodHandleResponse = "HandleResponse"
+)
+
+// Record is a single captured plugin call. Exactly one of Config, Request and Response is set,
+// according to Method.
+type Record struct {
+	Time   time.Time
+	Method string
+
+	Config   *mcpdpluginsv1.PluginConfig
+	Request  *mcpdpluginsv1.HTTPRequest
+	Response *mcpdpluginsv1.HTTPResponse
+
+	// Result is the plugin's output for HandleRequest and HandleResponse calls.
+	Result *mcpdpluginsv1.HTTPResponse
+
+	// Error is the message of the error returned by the plugin, if any.
+	Error string
+}
+
+// recordJSON is the on-disk form of a Record, one JSON object per line.
+type recordJSON struct {
+	Time     time.Time       `json:"time"`
+	Method   string          `json:"method"`
+	Config   json.RawMessage `json:"config,omitempty"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r Record) MarshalJSON() ([]byte, error) {
+	out := recordJSON{Time: r.Time, Method: r.Method, Error: r.Error}
+
+	var err error
+	if out.Config, err = marshalMessage(r.Config); err != nil {
+		return nil, err
+	}
+	if out.Request, err = marshalMessage(r.Request); err != nil {
+		return nil, err
+	}
+	if out.Response, err = marshalMessage(r.Response); err != nil {
+		return nil, err
+	}
+	if out.Result, err = marshalMessage(r.Result); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *Record) UnmarshalJSON(data []byte) error {
+	var in recordJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	*r = Record{Time: in.Time, Method: in.Method, Error: in.Error}
+	if len(in.Config) > 0 {
+		r.Config = &mcpdpluginsv1.PluginConfig{}
+		if err := protojson.Unmarshal(in.Config, r.Config); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+	}
+	if len(in.Request) > 0 {
+		r.Request = &mcpdpluginsv1.HTTPRequest{}
+		if err := protojson.Unmarshal(in.Request, r.Request); err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+	}
+	if len(in.Response) > 0 {
+		r.Response = &mcpdpluginsv1.HTTPResponse{}
+		if err := protojson.Unmarshal(in.Response, r.Response); err != nil {
+			return fmt.Errorf("invalid response: %w", err)
+		}
+	}
+	if len(in.Result) > 0 {
+		r.Result = &mcpdpluginsv1.HTTPResponse{}
+		if err := protojson.Unmarshal(in.Result, r.Result); err != nil {
+			return fmt.Errorf("invalid result: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func marshalMessage[M interface {
+	proto.Message
+	comparable
+}](m M) (json.RawMessage, error) {
+	var zero M
+	if m == zero {
+		return nil, nil
+	}
+
+	return protojson.Marshal(m)
+}
+
+// Recorder writes captured plugin calls to an io.Writer as JSON lines. It is safe for concurrent use.
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder returns a Recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// Interceptor returns a gRPC unary server interceptor that captures Configure, HandleRequest and
+// HandleResponse calls. Inputs are copied before the handler runs, so handlers that modify their
+// input do not affect the recording.
+func (r *Recorder) Interceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		rec := Record{Time: time.Now(), Method: path.Base(info.FullMethod)}
+		switch in := req.(type) {
+		case *mcpdpluginsv1.PluginConfig:
+			rec.Config = proto.Clone(in).(*mcpdpluginsv1.PluginConfig)
+		case *mcpdpluginsv1.HTTPRequest:
+			rec.Request = mcpdpluginsv1.CloneRequest(in)
+		case *mcpdpluginsv1.HTTPResponse:
+			rec.Response = mcpdpluginsv1.CloneResponse(in)
+		default:
+			return handler(ctx, req)
+		}
+
+		resp, err := handler(ctx, req)
+		if out, ok := resp.(*mcpdpluginsv1.HTTPResponse); ok && err == nil {
+			rec.Result = out
+		}
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		r.Write(rec)
+
+		return resp, err
+	}
+}
+
+// Write appends rec to the recording. Write errors are remembered and reported by Err.
+func (r *Recorder) Write(rec Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.enc.Encode(rec); err != nil && r.err == nil {
+		r.err = fmt.Errorf("failed to write replay record: %w", err)
+	}
+}
+
+// Err returns the first error encountered while writing records.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Load reads records written by a Recorder.
+func Load(rd io.Reader) ([]Record, error) {
+	dec := json.NewDecoder(rd)
+
+	var records []Record
+	for {
+		var rec Record
+		if err := dec.Decode(&rec); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read replay record %d: %w", len(records)+1, err)
+		}
+		records = append(records, rec)
+	}
+}
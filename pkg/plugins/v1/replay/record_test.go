@@ -0,0 +1,118 @@
+package replay_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/replay"
+)
+
+func TestRecorderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	rec := replay.NewRecorder(&buf)
+	intercept := rec.Interceptor()
+	call := func(method string, req any, handler grpc.UnaryHandler) {
+		t.Helper()
+		_, _ = intercept(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	}
+
+	call(mcpdpluginsv1.Plugin_Configure_FullMethodName,
+		&mcpdpluginsv1.PluginConfig{CustomConfig: map[string]string{"mode": "strict"}},
+		func(context.Context, any) (any, error) { return nil, nil })
+	// The handler modifies its input; the recording keeps the request as received.
+	call(mcpdpluginsv1.Plugin_HandleRequest_FullMethodName,
+		&mcpdpluginsv1.HTTPRequest{Method: "POST", Headers: map[string]string{"Authorization": "Bearer x"}},
+		func(_ context.Context, in any) (any, error) {
+			delete(in.(*mcpdpluginsv1.HTTPRequest).Headers, "Authorization")
+			return &mcpdpluginsv1.HTTPResponse{Continue: true, Body: []byte("ok")}, nil
+		})
+	call(mcpdpluginsv1.Plugin_HandleResponse_FullMethodName, &mcpdpluginsv1.HTTPResponse{StatusCode: 200},
+		func(context.Context, any) (any, error) { return nil, errors.New("boom") })
+	call(mcpdpluginsv1.Plugin_GetMetadata_FullMethodName, nil,
+		func(context.Context, any) (any, error) { return &mcpdpluginsv1.Metadata{}, nil })
+	if err := rec.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := replay.Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("loaded %d records, want 3 (GetMetadata is not recorded)", len(records))
+	}
+
+	cfg, req, resp := records[0], records[1], records[2]
+	if cfg.Method != replay.MethodConfigure || cfg.Config.GetCustomConfig()["mode"] != "strict" ||
+		cfg.Request != nil || cfg.Result != nil {
+		t.Errorf("Configure record = %+v", cfg)
+	}
+	if req.Method != replay.MethodHandleRequest || req.Request.GetHeaders()["Authorization"] != "Bearer x" {
+		t.Errorf("HandleRequest record = %+v, want the request before the handler ran", req)
+	}
+	if !proto.Equal(req.Result, &mcpdpluginsv1.HTTPResponse{Continue: true, Body: []byte("ok")}) || req.Error != "" {
+		t.Errorf("HandleRequest result = %v, error %q", req.Result, req.Error)
+	}
+	if resp.Method != replay.MethodHandleResponse || resp.Response.GetStatusCode() != 200 ||
+		resp.Result != nil || resp.Error != "boom" {
+		t.Errorf("HandleResponse record = %+v, want the error and no result", resp)
+	}
+	if req.Time.IsZero() {
+		t.Error("record time was not set")
+	}
+}
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write([]byte) (int, error) { return 0, w.err }
+
+func TestRecorderWriteError(t *testing.T) {
+	errWrite := errors.New("disk full")
+	rec := replay.NewRecorder(failingWriter{errWrite})
+
+	rec.Write(replay.Record{Method: replay.MethodHandleRequest, Request: &mcpdpluginsv1.HTTPRequest{}})
+	rec.Write(replay.Record{Method: replay.MethodHandleRequest, Request: &mcpdpluginsv1.HTTPRequest{}})
+	if err := rec.Err(); !errors.Is(err, errWrite) || !strings.Contains(err.Error(), "failed to write replay record") {
+		t.Errorf("Err = %v, want the write error", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	valid := `{"method":"HandleRequest","request":{"method":"GET"}}` + "\n"
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"malformed JSON", valid + "{not json\n", "failed to read replay record 2"},
+		{"invalid config", `{"method":"Configure","config":{"bogus":1}}`, "invalid config"},
+		{"invalid request", `{"method":"HandleRequest","request":{"method":3}}`, "invalid request"},
+		{"invalid response", `{"method":"HandleResponse","response":{"statusCode":"x"}}`, "invalid response"},
+		{"invalid result", valid[:len(valid)-2] + `,"result":[]}`, "invalid result"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := replay.Load(strings.NewReader(tt.in))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want %q", err, tt.want)
+			}
+			if records != nil {
+				t.Errorf("Load returned %d records alongside the error", len(records))
+			}
+		})
+	}
+}
+
+func TestLoadEmpty(t *testing.T) {
+	records, err := replay.Load(strings.NewReader(""))
+	if err != nil || len(records) != 0 {
+		t.Errorf("Load = %v, %v; want no records", records, err)
+	}
+}
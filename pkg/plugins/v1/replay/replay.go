@@ -0,0 +1,197 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+
+	"google.golang.org/protobuf/proto"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// Option configures Run.
+type Option func(*options)
+
+type options struct {
+	ignoreHeaders map[string]struct{}
+	skipConfigure bool
+}
+
+// IgnoreHeaders excludes the named headers (case-insensitive) from comparison, e.g. headers
+// carrying timestamps or request IDs generated by the plugin.
+func IgnoreHeaders(names ...string) Option {
+	return func(o *options) {
+		for _, n := range names {
+			o.ignoreHeaders[http.CanonicalHeaderKey(n)] = struct{}{}
+		}
+	}
+}
+
+// SkipConfigure does not replay recorded Configure calls, for plugins already configured by the caller.
+func SkipConfigure() Option {
+	return func(o *options) {
+		o.skipConfigure = true
+	}
+}
+
+// Diff is a difference between a recorded result and the replayed one.
+type Diff struct {
+	// Index is the position of the record in the replayed slice.
+	Index  int
+	Method string
+
+	// Field names what differs: "error", "continue", "status_code", "headers[<name>]", "body",
+	// or "modified_request".
+	Field string
+	Want  string
+	Got   string
+}
+
+// String describes the difference on one line.
+func (d Diff) String() string {
+	return fmt.Sprintf("record %d (%s): %s: want %s, got %s", d.Index, d.Method, d.Field, d.Want, d.Got)
+}
+
+// Report summarises a replay.
+type Report struct {
+	// Replayed counts the HandleRequest and HandleResponse records replayed.
+	Replayed int
+
+	// Matched counts those whose replayed result matched the recording.
+	Matched int
+
+	Diffs []Diff
+}
+
+// OK reports whether every replayed result matched the recording.
+func (r *Report) OK() bool {
+	return len(r.Diffs) == 0
+}
+
+// Run feeds records through impl in order and compares each HandleRequest and HandleResponse result
+// with the recorded one. Recorded Configure calls are replayed as well, so configuration changes
+// apply at the same points as in the recording; a failing Configure aborts the replay.
+func Run(ctx context.Context, impl mcpdpluginsv1.PluginServer, records []Record, opts ...Option) (*Report, error) {
+	o := &options{ignoreHeaders: map[string]struct{}{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	report := &Report{}
+	for i, rec := range records {
+		var (
+			got *mcpdpluginsv1.HTTPResponse
+			err error
+		)
+		switch {
+		case rec.Config != nil:
+			if o.skipConfigure {
+				continue
+			}
+			if _, err := impl.Configure(ctx, proto.Clone(rec.Config).(*mcpdpluginsv1.PluginConfig)); err != nil {
+				return report, fmt.Errorf("record %d: Configure failed: %w", i, err)
+			}
+			continue
+		case rec.Request != nil:
+			got, err = impl.HandleRequest(ctx, mcpdpluginsv1.CloneRequest(rec.Request))
+		case rec.Response != nil:
+			got, err = impl.HandleResponse(ctx, mcpdpluginsv1.CloneResponse(rec.Response))
+		default:
+			continue
+		}
+
+		report.Replayed++
+		diffs := compare(i, rec, got, err, o)
+		if len(diffs) == 0 {
+			report.Matched++
+		}
+		report.Diffs = append(report.Diffs, diffs...)
+	}
+
+	return report, nil
+}
+
+// compare returns the differences between rec's recorded outcome and the replayed got and err.
+func compare(i int, rec Record, got *mcpdpluginsv1.HTTPResponse, err error, o *options) []Diff {
+	var diffs []Diff
+	add := func(field, want, got string) {
+		diffs = append(diffs, Diff{Index: i, Method: rec.Method, Field: field, Want: want, Got: got})
+	}
+
+	gotErr := ""
+	if err != nil {
+		gotErr = err.Error()
+	}
+	if rec.Error != "" || gotErr != "" {
+		if rec.Error != gotErr {
+			add("error", quoteOrNone(rec.Error), quoteOrNone(gotErr))
+		}
+		return diffs
+	}
+
+	want := rec.Result
+	if want.GetContinue() != got.GetContinue() {
+		add("continue", fmt.Sprint(want.GetContinue()), fmt.Sprint(got.GetContinue()))
+	}
+	if want.GetStatusCode() != got.GetStatusCode() {
+		add("status_code", fmt.Sprint(want.GetStatusCode()), fmt.Sprint(got.GetStatusCode()))
+	}
+
+	wantHeaders := canonicalHeaders(want.GetHeaders(), o)
+	gotHeaders := canonicalHeaders(got.GetHeaders(), o)
+	names := slices.Sorted(maps.Keys(wantHeaders))
+	for n := range gotHeaders {
+		if _, ok := wantHeaders[n]; !ok {
+			names = append(names, n)
+		}
+	}
+	slices.Sort(names)
+	for _, n := range names {
+		w, wok := wantHeaders[n]
+		g, gok := gotHeaders[n]
+		if w != g || wok != gok {
+			add("headers["+n+"]", headerValue(w, wok), headerValue(g, gok))
+		}
+	}
+
+	if !bytes.Equal(want.GetBody(), got.GetBody()) {
+		add("body", fmt.Sprintf("%q", want.GetBody()), fmt.Sprintf("%q", got.GetBody()))
+	}
+	if !proto.Equal(want.GetModifiedRequest(), got.GetModifiedRequest()) {
+		add("modified_request", want.GetModifiedRequest().String(), got.GetModifiedRequest().String())
+	}
+
+	return diffs
+}
+
+func canonicalHeaders(h map[string]string, o *options) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		k = http.CanonicalHeaderKey(k)
+		if _, ignored := o.ignoreHeaders[k]; !ignored {
+			out[k] = v
+		}
+	}
+
+	return out
+}
+
+func headerValue(v string, ok bool) string {
+	if !ok {
+		return "<absent>"
+	}
+
+	return fmt.Sprintf("%q", v)
+}
+
+func quoteOrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+
+	return fmt.Sprintf("%q", s)
+}
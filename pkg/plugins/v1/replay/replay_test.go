@@ -0,0 +1,242 @@
+package replay_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/replay"
+)
+
+// policyPlugin blocks requests to blockedPath with 403 once configured with mode=strict, and
+// stamps every result with an X-Policy header.
+type policyPlugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	strict       bool
+	blockedPath  string
+	configureErr error
+}
+
+func (p *policyPlugin) Configure(_ context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	if p.configureErr != nil {
+		return nil, p.configureErr
+	}
+	p.strict = cfg.GetCustomConfig()["mode"] == "strict"
+
+	return &emptypb.Empty{}, nil
+}
+
+func (p *policyPlugin) HandleRequest(
+	_ context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	switch {
+	case req.GetPath() == "/error":
+		return nil, errors.New("policy failed")
+	case p.strict && req.GetPath() == p.blockedPath:
+		return &mcpdpluginsv1.HTTPResponse{StatusCode: 403, Headers: map[string]string{"X-Policy": "v2"}}, nil
+	default:
+		return &mcpdpluginsv1.HTTPResponse{Continue: true, Headers: map[string]string{"X-Policy": "v2"}}, nil
+	}
+}
+
+func (p *policyPlugin) HandleResponse(
+	_ context.Context,
+	resp *mcpdpluginsv1.HTTPResponse,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return &mcpdpluginsv1.HTTPResponse{Continue: true, StatusCode: resp.GetStatusCode(), Body: []byte("redacted")}, nil
+}
+
+func strictConfig() replay.Record {
+	return replay.Record{
+		Method: replay.MethodConfigure,
+		Config: &mcpdpluginsv1.PluginConfig{CustomConfig: map[string]string{"mode": "strict"}},
+	}
+}
+
+func requestRecord(path string, result *mcpdpluginsv1.HTTPResponse, errMsg string) replay.Record {
+	return replay.Record{
+		Method:  replay.MethodHandleRequest,
+		Request: &mcpdpluginsv1.HTTPRequest{Method: "POST", Path: path},
+		Result:  result,
+		Error:   errMsg,
+	}
+}
+
+func TestRun(t *testing.T) {
+	pass := func(h map[string]string) *mcpdpluginsv1.HTTPResponse {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true, Headers: h}
+	}
+	policy := map[string]string{"X-Policy": "v2"}
+	tests := []struct {
+		name        string
+		records     []replay.Record
+		opts        []replay.Option
+		wantReplay  int
+		wantMatched int
+		wantDiffs   []string
+	}{
+		{
+			name:        "matching results",
+			records:     []replay.Record{strictConfig(), requestRecord("/mcp", pass(policy), "")},
+			wantReplay:  1,
+			wantMatched: 1,
+		},
+		{
+			name: "configuration applied in order",
+			records: []replay.Record{
+				requestRecord("/admin", pass(policy), ""),
+				strictConfig(),
+				requestRecord("/admin", &mcpdpluginsv1.HTTPResponse{StatusCode: 403, Headers: policy}, ""),
+			},
+			wantReplay:  2,
+			wantMatched: 2,
+		},
+		{
+			name:       "changed verdict",
+			records:    []replay.Record{strictConfig(), requestRecord("/admin", pass(policy), "")},
+			wantReplay: 1,
+			wantDiffs: []string{
+				"record 1 (HandleRequest): continue: want true, got false",
+				"record 1 (HandleRequest): status_code: want 0, got 403",
+			},
+		},
+		{
+			name: "header differences",
+			records: []replay.Record{requestRecord("/mcp", pass(map[string]string{
+				"x-policy": "v1",
+				"X-Trace":  "abc",
+			}), "")},
+			wantReplay: 1,
+			wantDiffs: []string{
+				`record 0 (HandleRequest): headers[X-Policy]: want "v1", got "v2"`,
+				`record 0 (HandleRequest): headers[X-Trace]: want "abc", got <absent>`,
+			},
+		},
+		{
+			name:        "ignored headers",
+			records:     []replay.Record{requestRecord("/mcp", pass(map[string]string{"X-Trace": "abc"}), "")},
+			opts:        []replay.Option{replay.IgnoreHeaders("x-policy", "X-TRACE")},
+			wantReplay:  1,
+			wantMatched: 1,
+		},
+		{
+			name:       "new error",
+			records:    []replay.Record{requestRecord("/error", pass(policy), "")},
+			wantReplay: 1,
+			wantDiffs:  []string{`record 0 (HandleRequest): error: want <none>, got "policy failed"`},
+		},
+		{
+			name:        "same error",
+			records:     []replay.Record{requestRecord("/error", nil, "policy failed")},
+			wantReplay:  1,
+			wantMatched: 1,
+		},
+		{
+			name:       "error gone",
+			records:    []replay.Record{requestRecord("/mcp", nil, "timeout")},
+			wantReplay: 1,
+			wantDiffs:  []string{`record 0 (HandleRequest): error: want "timeout", got <none>`},
+		},
+		{
+			name: "response body and modified request",
+			records: []replay.Record{{
+				Method:   replay.MethodHandleResponse,
+				Response: &mcpdpluginsv1.HTTPResponse{StatusCode: 200},
+				Result: &mcpdpluginsv1.HTTPResponse{
+					Continue:        true,
+					StatusCode:      200,
+					Body:            []byte("secret"),
+					ModifiedRequest: &mcpdpluginsv1.HTTPRequest{Path: "/v2"},
+				},
+			}},
+			wantReplay: 1,
+			wantDiffs: []string{
+				`record 0 (HandleResponse): body: want "secret", got "redacted"`,
+				"record 0 (HandleResponse): modified_request",
+			},
+		},
+		{
+			name:        "skipped configure",
+			records:     []replay.Record{strictConfig(), requestRecord("/admin", pass(policy), "")},
+			opts:        []replay.Option{replay.SkipConfigure()},
+			wantReplay:  1,
+			wantMatched: 1,
+		},
+		{
+			name:    "empty records are skipped",
+			records: []replay.Record{{Method: "GetMetadata"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &policyPlugin{blockedPath: "/admin"}
+			report, err := replay.Run(context.Background(), p, tt.records, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var diffs []string
+			for _, d := range report.Diffs {
+				if d.Field == "modified_request" {
+					// Message text output is deliberately unstable, so only the field is compared.
+					d.Want, d.Got = "", ""
+				}
+				diffs = append(diffs, strings.TrimSuffix(d.String(), ": want , got "))
+			}
+			if !slices.Equal(diffs, tt.wantDiffs) {
+				t.Errorf("diffs = %q, want %q", diffs, tt.wantDiffs)
+			}
+			if report.Replayed != tt.wantReplay || report.Matched != tt.wantMatched {
+				t.Errorf("replayed %d, matched %d; want %d, %d",
+					report.Replayed, report.Matched, tt.wantReplay, tt.wantMatched)
+			}
+			if report.OK() != (len(tt.wantDiffs) == 0) {
+				t.Errorf("OK = %v with %d diffs", report.OK(), len(tt.wantDiffs))
+			}
+		})
+	}
+}
+
+func TestRunConfigureFailure(t *testing.T) {
+	errConfig := errors.New("unknown key")
+	p := &policyPlugin{configureErr: errConfig}
+	records := []replay.Record{requestRecord("/mcp", nil, "timeout"), strictConfig(), requestRecord("/mcp", nil, "")}
+
+	report, err := replay.Run(context.Background(), p, records)
+	if !errors.Is(err, errConfig) || err.Error() != "record 1: Configure failed: unknown key" {
+		t.Fatalf("Run error = %v, want the Configure failure", err)
+	}
+	if report == nil || report.Replayed != 1 || len(report.Diffs) != 1 {
+		t.Errorf("report = %+v, want the records replayed before the failure", report)
+	}
+}
+
+func TestRunDoesNotModifyRecords(t *testing.T) {
+	records := []replay.Record{requestRecord("/mcp", nil, "")}
+	records[0].Request.Headers = map[string]string{"A": "b"}
+	mutating := &mutatingPlugin{}
+
+	if _, err := replay.Run(context.Background(), mutating, records); err != nil {
+		t.Fatal(err)
+	}
+	if records[0].Request.GetHeaders()["A"] != "b" {
+		t.Errorf("record headers = %v after replay, want them unchanged", records[0].Request.GetHeaders())
+	}
+}
+
+type mutatingPlugin struct{ mcpdpluginsv1.BasePlugin }
+
+func (p *mutatingPlugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	delete(req.Headers, "A")
+	return p.BasePlugin.HandleRequest(ctx, req)
+}
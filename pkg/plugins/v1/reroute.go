@@ -0,0 +1,120 @@
+package mcpdpluginsv1
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+// ErrInvalidRewrite is returned (wrapped) when a rewritten request is not well-formed.
+var ErrInvalidRewrite = errors.New("invalid request rewrite")
+
+// serversSegment marks the upstream server name in mcpd request paths.
+const serversSegment = "/servers/"
+
+// RerouteUpstream returns a response that lets the chain continue with req redirected to the
+// upstream MCP server named upstream, by rewriting the server segment of its path
+// ("/servers/<name>/...") in Path, RequestUri and Url. Use it for failover and canary routing:
+//
+//	if rand.Float64() < 0.05 {
+//	    return mcpdpluginsv1.RerouteUpstream(req, "github-canary")
+//	}
+//
+// The request must have a server segment and upstream must be a valid server name.
+func RerouteUpstream(req *HTTPRequest, upstream string) (*HTTPResponse, error) {
+	if upstream == "" || upstream == "." || upstream == ".." || strings.ContainsAny(upstream, "/?#%") {
+		return nil, fmt.Errorf("%w: invalid upstream name %q", ErrInvalidRewrite, upstream)
+	}
+	if !strings.Contains(req.GetPath(), serversSegment) {
+		return nil, fmt.Errorf("%w: path %q has no %s segment", ErrInvalidRewrite, req.GetPath(), serversSegment)
+	}
+
+	out := CloneRequest(req)
+	out.Path = replaceServer(out.GetPath(), upstream)
+	out.RequestUri = replaceServer(out.GetRequestUri(), upstream)
+	if out.GetUrl() != "" {
+		u, err := url.Parse(out.GetUrl())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRewrite, err)
+		}
+		u.Path, u.RawPath = replaceServer(u.Path, upstream), ""
+		out.Url = u.String()
+	}
+
+	return rerouted(out)
+}
+
+// RerouteTool returns a response that lets the chain continue with the tools/call request(s) in
+// req calling tool instead, for example to send calls to a mirrored or versioned tool.
+func RerouteTool(req *HTTPRequest, tool string) (*HTTPResponse, error) {
+	body, changed, err := mcp.RenameTool(req.GetBody(), "", tool)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRewrite, err)
+	}
+	if !changed && mcp.ToolName(req.GetBody()) == "" {
+		return nil, fmt.Errorf("%w: request is not a tools/call request", ErrInvalidRewrite)
+	}
+
+	out := CloneRequest(req)
+	out.Body = body
+
+	return rerouted(out)
+}
+
+// ValidateRewrite checks that a request a plugin returns as ModifiedRequest is well-formed: it has
+// a method and an absolute, clean path, and its RequestUri and Url (when set) agree with the path.
+func ValidateRewrite(req *HTTPRequest) error {
+	if req.GetMethod() == "" {
+		return fmt.Errorf("%w: method is empty", ErrInvalidRewrite)
+	}
+	p := req.GetPath()
+	if clean := path.Clean(p); !strings.HasPrefix(p, "/") || (clean != p && clean+"/" != p) {
+		return fmt.Errorf("%w: path %q is not absolute and clean", ErrInvalidRewrite, p)
+	}
+	if uri := req.GetRequestUri(); uri != "" {
+		u, err := url.ParseRequestURI(uri)
+		if err != nil {
+			return fmt.Errorf("%w: request URI %q: %w", ErrInvalidRewrite, uri, err)
+		}
+		if u.Path != p {
+			return fmt.Errorf("%w: request URI %q does not match path %q", ErrInvalidRewrite, uri, p)
+		}
+	}
+	if raw := req.GetUrl(); raw != "" {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("%w: URL %q: %w", ErrInvalidRewrite, raw, err)
+		}
+		if u.Path != p {
+			return fmt.Errorf("%w: URL %q does not match path %q", ErrInvalidRewrite, raw, p)
+		}
+	}
+
+	return nil
+}
+
+// rerouted validates req and wraps it in a continue response.
+func rerouted(req *HTTPRequest) (*HTTPResponse, error) {
+	if err := ValidateRewrite(req); err != nil {
+		return nil, err
+	}
+
+	return &HTTPResponse{Continue: true, ModifiedRequest: req}, nil
+}
+
+// replaceServer replaces the name following the first "/servers/" in s with name.
+func replaceServer(s, name string) string {
+	before, rest, ok := strings.Cut(s, serversSegment)
+	if !ok {
+		return s
+	}
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		return before + serversSegment + name + rest[i:]
+	}
+
+	return before + serversSegment + name
+}
@@ -0,0 +1,221 @@
+package mcpdpluginsv1
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+func TestRerouteUpstream(t *testing.T) {
+	onA := &HTTPRequest{Method: "POST", Path: "/servers/a"}
+	tests := []struct {
+		name     string
+		req      *HTTPRequest
+		upstream string
+		want     *HTTPRequest // Path, RequestUri and Url of the rerouted request.
+		wantErr  string
+	}{
+		{
+			name: "path, request URI and URL",
+			req: &HTTPRequest{
+				Method:     "POST",
+				Path:       "/servers/github/tools",
+				RequestUri: "/servers/github/tools?x=1",
+				Url:        "http://mcpd:8090/servers/github/tools?x=1",
+			},
+			upstream: "github-canary",
+			want: &HTTPRequest{
+				Path:       "/servers/github-canary/tools",
+				RequestUri: "/servers/github-canary/tools?x=1",
+				Url:        "http://mcpd:8090/servers/github-canary/tools?x=1",
+			},
+		},
+		{
+			name:     "server at the end of the path",
+			req:      &HTTPRequest{Method: "POST", Path: "/api/servers/github"},
+			upstream: "gitlab",
+			want:     &HTTPRequest{Path: "/api/servers/gitlab"},
+		},
+		{
+			name:     "escaped URL path",
+			req:      &HTTPRequest{Method: "POST", Path: "/servers/a b/mcp", Url: "http://mcpd/servers/a%20b/mcp"},
+			upstream: "c",
+			want:     &HTTPRequest{Path: "/servers/c/mcp", Url: "http://mcpd/servers/c/mcp"},
+		},
+		{name: "empty upstream", req: onA, wantErr: `invalid upstream name ""`},
+		{name: "dot upstream", req: onA, upstream: ".", wantErr: "invalid upstream"},
+		{name: "dot-dot upstream", req: onA, upstream: "..", wantErr: "invalid upstream"},
+		{name: "slash in upstream", req: onA, upstream: "a/b", wantErr: "invalid upstream"},
+		{name: "query in upstream", req: onA, upstream: "a?b", wantErr: "invalid upstream"},
+		{
+			name:     "escape in upstream",
+			req:      onA,
+			upstream: "a%2fb",
+			wantErr:  "invalid upstream",
+		},
+		{
+			name:     "no server segment",
+			req:      &HTTPRequest{Method: "POST", Path: "/mcp"},
+			upstream: "b",
+			wantErr:  `path "/mcp" has no /servers/ segment`,
+		},
+		{
+			name:     "malformed URL",
+			req:      &HTTPRequest{Method: "POST", Path: "/servers/a", Url: "http://[::1/servers/a"},
+			upstream: "b",
+			wantErr:  "invalid request rewrite: parse",
+		},
+		{
+			name:     "result validated",
+			req:      &HTTPRequest{Path: "/servers/a"},
+			upstream: "b",
+			wantErr:  "method is empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := CloneRequest(tt.req)
+			resp, err := RerouteUpstream(tt.req, tt.upstream)
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrInvalidRewrite) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RerouteUpstream error = %v, want ErrInvalidRewrite %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := resp.GetModifiedRequest()
+			if !resp.GetContinue() || m == nil {
+				t.Fatalf("RerouteUpstream = %v, want a continue response with a modified request", resp)
+			}
+			if m.GetPath() != tt.want.GetPath() || m.GetRequestUri() != tt.want.GetRequestUri() ||
+				m.GetUrl() != tt.want.GetUrl() {
+				t.Errorf("rerouted to %q %q %q, want %q %q %q", m.GetPath(), m.GetRequestUri(), m.GetUrl(),
+					tt.want.GetPath(), tt.want.GetRequestUri(), tt.want.GetUrl())
+			}
+			if m.GetMethod() != tt.req.GetMethod() {
+				t.Errorf("method = %q, want %q", m.GetMethod(), tt.req.GetMethod())
+			}
+			if tt.req.GetPath() != orig.GetPath() || tt.req.GetUrl() != orig.GetUrl() {
+				t.Errorf("input request was modified: %v", tt.req)
+			}
+		})
+	}
+}
+
+func TestRerouteTool(t *testing.T) {
+	call := func(tool string) []byte {
+		return []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + tool + `"}}`)
+	}
+	tests := []struct {
+		name    string
+		body    []byte
+		tool    string
+		wantErr string
+	}{
+		{name: "renamed", body: call("search"), tool: "search_v2"},
+		{name: "already the tool", body: call("search"), tool: "search"},
+		{
+			name: "batch",
+			body: []byte(`[{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"a"}},` +
+				`{"jsonrpc":"2.0","id":2,"method":"tools/list"}]`),
+			tool: "b",
+		},
+		{name: "empty tool", body: call("search"), wantErr: "tool name cannot be empty"},
+		{
+			name:    "not a tool call",
+			body:    []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`),
+			tool:    "search",
+			wantErr: "request is not a tools/call request",
+		},
+		{name: "empty body", tool: "search", wantErr: "request is not a tools/call request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &HTTPRequest{Method: "POST", Path: "/servers/github/mcp", Body: tt.body}
+			resp, err := RerouteTool(req, tt.tool)
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrInvalidRewrite) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("RerouteTool error = %v, want ErrInvalidRewrite %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := resp.GetModifiedRequest()
+			if got := mcp.ToolName(m.GetBody()); !resp.GetContinue() || got != tt.tool {
+				t.Errorf("rerouted to tool %q (continue %v), want %q", got, resp.GetContinue(), tt.tool)
+			}
+			if string(req.GetBody()) != string(tt.body) {
+				t.Errorf("input body was modified: %s", req.GetBody())
+			}
+		})
+	}
+}
+
+func TestValidateRewrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *HTTPRequest
+		wantErr string
+	}{
+		{name: "path only", req: &HTTPRequest{Method: "POST", Path: "/servers/a/mcp"}},
+		{name: "trailing slash", req: &HTTPRequest{Method: "GET", Path: "/servers/a/"}},
+		{
+			name: "matching URI and URL",
+			req: &HTTPRequest{
+				Method: "POST", Path: "/mcp", RequestUri: "/mcp?session=1", Url: "https://mcpd.example.com/mcp",
+			},
+		},
+		{name: "no method", req: &HTTPRequest{Path: "/mcp"}, wantErr: "method is empty"},
+		{name: "nil request", wantErr: "method is empty"},
+		{name: "relative path", req: &HTTPRequest{Method: "POST", Path: "mcp"}, wantErr: `path "mcp" is not absolute`},
+		{name: "empty path", req: &HTTPRequest{Method: "POST"}, wantErr: `path "" is not absolute`},
+		{
+			name:    "dot segments",
+			req:     &HTTPRequest{Method: "POST", Path: "/servers/a/../b"},
+			wantErr: "is not absolute and clean",
+		},
+		{name: "double slash", req: &HTTPRequest{Method: "POST", Path: "//mcp"}, wantErr: "is not absolute and clean"},
+		{
+			name:    "request URI mismatch",
+			req:     &HTTPRequest{Method: "POST", Path: "/mcp", RequestUri: "/other"},
+			wantErr: `request URI "/other" does not match path "/mcp"`,
+		},
+		{
+			name:    "malformed request URI",
+			req:     &HTTPRequest{Method: "POST", Path: "/mcp", RequestUri: "mcp"},
+			wantErr: `request URI "mcp"`,
+		},
+		{
+			name:    "URL mismatch",
+			req:     &HTTPRequest{Method: "POST", Path: "/mcp", Url: "http://mcpd/other"},
+			wantErr: `URL "http://mcpd/other" does not match path "/mcp"`,
+		},
+		{
+			name:    "malformed URL",
+			req:     &HTTPRequest{Method: "POST", Path: "/mcp", Url: "http://[::1/mcp"},
+			wantErr: `URL "http://[::1/mcp"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRewrite(tt.req)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateRewrite = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidRewrite) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateRewrite error = %v, want ErrInvalidRewrite %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	rtmetrics "runtime/metrics"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// HealthMetadataKey is the gRPC response header on CheckHealth carrying the plugin's resource
+// state ("ok", "degraded" or "exhausted") when WithResourceGuard is enabled.
+const HealthMetadataKey = "mcpd-plugin-health"
+
+// ErrResourceLimit is returned by Serve when the resource guard stopped the server so the host
+// can restart the plugin.
+var ErrResourceLimit = errors.New("plugin exceeded its resource limits")
+
+// ResourceState is the plugin's standing against its ResourceLimits.
+type ResourceState int32
+
+const (
+	// ResourceOK means every sample is within the soft limits.
+	ResourceOK ResourceState = iota
+
+	// ResourceDegraded means a soft limit is exceeded: CheckHealth still succeeds but reports the
+	// state in HealthMetadataKey.
+	ResourceDegraded
+
+	// ResourceExhausted means a hard limit is exceeded: CheckHealth fails and new HandleRequest
+	// and HandleResponse calls are rejected with Throttle.
+	ResourceExhausted
+)
+
+// String returns the state as reported in HealthMetadataKey.
+func (s ResourceState) String() string {
+	switch s {
+	case ResourceDegraded:
+		return "degraded"
+	case ResourceExhausted:
+		return "exhausted"
+	default:
+		return "ok"
+	}
+}
+
+// ResourceLimits configures WithResourceGuard. A zero limit disables that check.
+type ResourceLimits struct {
+	// SoftMemory is the resident memory, in bytes, above which the plugin is degraded. While it is
+	// exceeded the guard forces a garbage collection and returns freed memory to the OS after
+	// each sample.
+	SoftMemory uint64
+
+	// HardMemory is the resident memory, in bytes, above which the plugin is exhausted.
+	HardMemory uint64
+
+	// SoftGoroutines is the goroutine count above which the plugin is degraded.
+	SoftGoroutines int
+
+	// HardGoroutines is the goroutine count above which the plugin is exhausted.
+	HardGoroutines int
+
+	// RestartAfter, when positive, stops the server once the plugin has been exhausted for this
+	// long, so Serve returns ErrResourceLimit and the host restarts the process.
+	RestartAfter time.Duration
+
+	// Interval is how often usage is sampled (default 5s).
+	Interval time.Duration
+}
+
+// ResourceUsage is one sample of the plugin's resource usage.
+type ResourceUsage struct {
+	// Memory is the resident set size in bytes where the OS reports it, and otherwise the memory
+	// mapped by the Go runtime and not returned to the OS.
+	Memory uint64
+
+	// Goroutines is the number of live goroutines.
+	Goroutines int
+}
+
+// CurrentResourceUsage samples the plugin's resource usage.
+func CurrentResourceUsage() ResourceUsage {
+	return ResourceUsage{Memory: residentMemory(), Goroutines: runtime.NumGoroutine()}
+}
+
+// WithResourceGuard makes Serve sample the plugin's memory and goroutine count against limits so
+// one leaking plugin cannot destabilize its host. A degraded plugin is reported through
+// CheckHealth and, for memory, has garbage collection forced; an exhausted plugin fails
+// CheckHealth, rejects new HandleRequest and HandleResponse calls with Throttle, suggesting a
+// retry after limits.Interval, and is stopped after limits.RestartAfter.
+// Usage is recorded as metrics.ResourceMemory and metrics.ResourceGoroutines when WithMetrics
+// is configured.
+func WithResourceGuard(limits ResourceLimits) ServeOption {
+	return func(o *serveOptions) error {
+		if limits.SoftMemory == 0 && limits.HardMemory == 0 &&
+			limits.SoftGoroutines == 0 && limits.HardGoroutines == 0 {
+			return fmt.Errorf("resource guard needs at least one limit")
+		}
+		if limits.SoftGoroutines < 0 || limits.HardGoroutines < 0 {
+			return fmt.Errorf("goroutine limits cannot be negative")
+		}
+		if limits.HardMemory > 0 && limits.SoftMemory > limits.HardMemory {
+			return fmt.Errorf("soft memory limit %d exceeds hard limit %d", limits.SoftMemory, limits.HardMemory)
+		}
+		if limits.HardGoroutines > 0 && limits.SoftGoroutines > limits.HardGoroutines {
+			return fmt.Errorf(
+				"soft goroutine limit %d exceeds hard limit %d", limits.SoftGoroutines, limits.HardGoroutines,
+			)
+		}
+		if limits.RestartAfter < 0 || limits.Interval < 0 {
+			return fmt.Errorf("resource guard durations cannot be negative")
+		}
+		if limits.RestartAfter > 0 && limits.HardMemory == 0 && limits.HardGoroutines == 0 {
+			return fmt.Errorf("RestartAfter requires a hard limit")
+		}
+		if limits.Interval == 0 {
+			limits.Interval = 5 * time.Second
+		}
+		o.resources = &resourceGuard{limits: limits, restart: make(chan struct{})}
+		return nil
+	}
+}
+
+// resourceGuard samples usage on an interval and gates calls on the resulting state.
+type resourceGuard struct {
+	limits  ResourceLimits
+	state   atomic.Int32
+	restart chan struct{}
+	once    sync.Once
+
+	// mu serializes samples, taken on the interval and on demand by the admin service.
+	mu sync.Mutex
+
+	// exhaustedSince is when the plugin last became exhausted; only touched by sample.
+	exhaustedSince time.Time
+}
+
+// current returns the state from the latest sample.
+func (g *resourceGuard) current() ResourceState {
+	return ResourceState(g.state.Load())
+}
+
+// restarting reports whether the guard asked for the server to stop.
+func (g *resourceGuard) restarting() bool {
+	select {
+	case <-g.restart:
+		return true
+	default:
+		return false
+	}
+}
+
+// run samples usage until ctx is done.
+func (g *resourceGuard) run(ctx context.Context, o *serveOptions) {
+	t := time.NewTicker(g.limits.Interval)
+	defer t.Stop()
+
+	g.sample(o)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			g.sample(o)
+		}
+	}
+}
+
+func (g *resourceGuard) sample(o *serveOptions) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	u := CurrentResourceUsage()
+	if r := o.metricsRecorder(); r != nil {
+		r.Gauge(metrics.ResourceMemory, float64(u.Memory))
+		r.Gauge(metrics.ResourceGoroutines, float64(u.Goroutines))
+	}
+
+	state := g.classify(u)
+	if prev := ResourceState(g.state.Swap(int32(state))); prev != state {
+		o.logger.Printf(
+			"resource state changed from %s to %s: memory=%d goroutines=%d", prev, state, u.Memory, u.Goroutines,
+		)
+	}
+
+	if g.limits.SoftMemory > 0 && u.Memory > g.limits.SoftMemory {
+		debug.FreeOSMemory()
+	}
+
+	if state != ResourceExhausted {
+		g.exhaustedSince = time.Time{}
+		return
+	}
+	now := time.Now()
+	if g.exhaustedSince.IsZero() {
+		g.exhaustedSince = now
+	}
+	if g.limits.RestartAfter > 0 && now.Sub(g.exhaustedSince) >= g.limits.RestartAfter {
+		g.once.Do(func() { close(g.restart) })
+	}
+}
+
+func (g *resourceGuard) classify(u ResourceUsage) ResourceState {
+	switch {
+	case g.limits.HardMemory > 0 && u.Memory > g.limits.HardMemory,
+		g.limits.HardGoroutines > 0 && u.Goroutines > g.limits.HardGoroutines:
+		return ResourceExhausted
+	case g.limits.SoftMemory > 0 && u.Memory > g.limits.SoftMemory,
+		g.limits.SoftGoroutines > 0 && u.Goroutines > g.limits.SoftGoroutines:
+		return ResourceDegraded
+	default:
+		return ResourceOK
+	}
+}
+
+// interceptor reports the state on CheckHealth and rejects handler calls while exhausted.
+func (g *resourceGuard) interceptor(o *serveOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		state := g.current()
+		switch info.FullMethod {
+		case Plugin_CheckHealth_FullMethodName:
+			_ = grpc.SetHeader(ctx, metadata.Pairs(HealthMetadataKey, state.String()))
+			if state == ResourceExhausted {
+				return nil, status.Error(codes.Unavailable, "plugin resource limits exceeded")
+			}
+		case Plugin_HandleRequest_FullMethodName, Plugin_HandleResponse_FullMethodName:
+			if state == ResourceExhausted {
+				return nil, o.throttle(ctx, info, g.limits.Interval, ThrottleResources)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// residentMemory returns the process's resident set size from /proc when available, and the
+// memory the Go runtime holds from the OS otherwise.
+func residentMemory() uint64 {
+	if b, err := os.ReadFile("/proc/self/statm"); err == nil {
+		if f := bytes.Fields(b); len(f) > 1 {
+			if pages, err := strconv.ParseUint(string(f[1]), 10, 64); err == nil {
+				return pages * uint64(os.Getpagesize())
+			}
+		}
+	}
+
+	samples := []rtmetrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	rtmetrics.Read(samples)
+	if samples[0].Value.Kind() != rtmetrics.KindUint64 || samples[1].Value.Kind() != rtmetrics.KindUint64 {
+		return 0
+	}
+
+	return samples[0].Value.Uint64() - samples[1].Value.Uint64()
+}
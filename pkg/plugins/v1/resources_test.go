@@ -0,0 +1,378 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+func TestResourceStateString(t *testing.T) {
+	tests := []struct {
+		state ResourceState
+		want  string
+	}{
+		{ResourceOK, "ok"},
+		{ResourceDegraded, "degraded"},
+		{ResourceExhausted, "exhausted"},
+		{ResourceState(7), "ok"},
+	}
+	for _, tt := range tests {
+		if got := tt.state.String(); got != tt.want {
+			t.Errorf("ResourceState(%d).String() = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
+
+func TestWithResourceGuardErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits ResourceLimits
+		want   string
+	}{
+		{name: "no limit", limits: ResourceLimits{Interval: time.Second}, want: "needs at least one limit"},
+		{name: "negative goroutines", limits: ResourceLimits{SoftGoroutines: -1}, want: "cannot be negative"},
+		{
+			name:   "soft memory above hard",
+			limits: ResourceLimits{SoftMemory: 2 << 20, HardMemory: 1 << 20},
+			want:   "soft memory limit 2097152 exceeds hard limit 1048576",
+		},
+		{
+			name:   "soft goroutines above hard",
+			limits: ResourceLimits{SoftGoroutines: 20, HardGoroutines: 10},
+			want:   "soft goroutine limit 20 exceeds hard limit 10",
+		},
+		{
+			name:   "negative interval",
+			limits: ResourceLimits{HardGoroutines: 10, Interval: -time.Second},
+			want:   "durations cannot be negative",
+		},
+		{
+			name:   "negative restart",
+			limits: ResourceLimits{HardGoroutines: 10, RestartAfter: -time.Second},
+			want:   "durations cannot be negative",
+		},
+		{
+			name:   "restart without a hard limit",
+			limits: ResourceLimits{SoftMemory: 1 << 20, RestartAfter: time.Minute},
+			want:   "RestartAfter requires a hard limit",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newServeOptions(WithResourceGuard(tt.limits))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("newServeOptions error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithResourceGuardDefaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits ResourceLimits
+		want   time.Duration
+	}{
+		{name: "default interval", limits: ResourceLimits{SoftMemory: 1 << 30}, want: 5 * time.Second},
+		{name: "soft limit alone", limits: ResourceLimits{SoftGoroutines: 5, Interval: time.Second}, want: time.Second},
+		{
+			name:   "soft above an unset hard limit",
+			limits: ResourceLimits{SoftMemory: 1 << 30, HardGoroutines: 5},
+			want:   5 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(WithResourceGuard(tt.limits))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := o.resources.limits.Interval; got != tt.want {
+				t.Errorf("Interval = %s, want %s", got, tt.want)
+			}
+			if o.resources.current() != ResourceOK || o.resources.restarting() {
+				t.Error("new guard is not ok")
+			}
+		})
+	}
+}
+
+func TestResourceGuardClassify(t *testing.T) {
+	limits := ResourceLimits{SoftMemory: 100, HardMemory: 200, SoftGoroutines: 10, HardGoroutines: 20}
+	tests := []struct {
+		name   string
+		limits ResourceLimits
+		usage  ResourceUsage
+		want   ResourceState
+	}{
+		{name: "within limits", limits: limits, usage: ResourceUsage{Memory: 100, Goroutines: 10}, want: ResourceOK},
+		{name: "soft memory", limits: limits, usage: ResourceUsage{Memory: 101, Goroutines: 1}, want: ResourceDegraded},
+		{
+			name:   "soft goroutines",
+			limits: limits,
+			usage:  ResourceUsage{Memory: 1, Goroutines: 11},
+			want:   ResourceDegraded,
+		},
+		{
+			name:   "hard memory",
+			limits: limits,
+			usage:  ResourceUsage{Memory: 201, Goroutines: 1},
+			want:   ResourceExhausted,
+		},
+		{
+			name:   "hard goroutines over soft memory",
+			limits: limits,
+			usage:  ResourceUsage{Memory: 150, Goroutines: 21},
+			want:   ResourceExhausted,
+		},
+		{
+			name:   "unset limits are not checked",
+			limits: ResourceLimits{HardGoroutines: 20},
+			usage:  ResourceUsage{Memory: 1 << 40, Goroutines: 20},
+			want:   ResourceOK,
+		},
+		{
+			name:   "hard limit without a soft one",
+			limits: ResourceLimits{HardMemory: 200},
+			usage:  ResourceUsage{Memory: 201},
+			want:   ResourceExhausted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &resourceGuard{limits: tt.limits}
+			if got := g.classify(tt.usage); got != tt.want {
+				t.Errorf("classify(%+v) = %s, want %s", tt.usage, got, tt.want)
+			}
+		})
+	}
+}
+
+// newTestGuard returns a guard with limits and options recording metrics to r and logs to buf.
+func newTestGuard(
+	t *testing.T,
+	limits ResourceLimits,
+	r metrics.Recorder,
+	buf *bytes.Buffer,
+) (*resourceGuard, *serveOptions) {
+	t.Helper()
+
+	o, err := newServeOptions(WithResourceGuard(limits), WithMetrics(r), WithLogger(log.New(buf, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return o.resources, o
+}
+
+func TestResourceGuardSample(t *testing.T) {
+	// A test binary always runs more than one goroutine, and far fewer than a million.
+	tests := []struct {
+		name    string
+		limits  ResourceLimits
+		want    ResourceState
+		wantLog string
+	}{
+		{name: "ok", limits: ResourceLimits{SoftGoroutines: 1 << 20}, want: ResourceOK},
+		{
+			name:    "degraded",
+			limits:  ResourceLimits{SoftGoroutines: 1},
+			want:    ResourceDegraded,
+			wantLog: "from ok to degraded",
+		},
+		{
+			name:    "exhausted",
+			limits:  ResourceLimits{HardGoroutines: 1},
+			want:    ResourceExhausted,
+			wantLog: "from ok to exhausted",
+		},
+		{
+			name:    "soft memory frees memory",
+			limits:  ResourceLimits{SoftMemory: 1},
+			want:    ResourceDegraded,
+			wantLog: "from ok to degraded: memory=",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorderLog{}
+			var buf bytes.Buffer
+			g, o := newTestGuard(t, tt.limits, r, &buf)
+
+			g.sample(o)
+			if got := g.current(); got != tt.want {
+				t.Errorf("state = %s, want %s", got, tt.want)
+			}
+			if tt.wantLog == "" && buf.Len() > 0 || !strings.Contains(buf.String(), tt.wantLog) {
+				t.Errorf("logged %q, want %q", buf.String(), tt.wantLog)
+			}
+			if len(r.named(metrics.ResourceMemory)) != 1 || len(r.named(metrics.ResourceGoroutines)) != 1 {
+				t.Errorf("recorded %q, want one memory and one goroutine gauge", r.lines)
+			}
+
+			// An unchanged state is not logged again.
+			buf.Reset()
+			g.sample(o)
+			if buf.Len() > 0 {
+				t.Errorf("logged %q for an unchanged state", buf.String())
+			}
+		})
+	}
+}
+
+func TestResourceGuardRestart(t *testing.T) {
+	var buf bytes.Buffer
+	limits := ResourceLimits{HardGoroutines: 1, RestartAfter: 20 * time.Millisecond}
+	g, o := newTestGuard(t, limits, &recorderLog{}, &buf)
+
+	g.sample(o)
+	if g.restarting() {
+		t.Fatal("restarting as soon as the plugin is exhausted")
+	}
+
+	// Recovering resets the exhaustion period.
+	time.Sleep(30 * time.Millisecond)
+	g.limits.HardGoroutines = 1 << 20
+	g.sample(o)
+	g.limits.HardGoroutines = 1
+	g.sample(o)
+	if g.restarting() {
+		t.Fatal("restarting after the plugin recovered")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	g.sample(o)
+	if !g.restarting() {
+		t.Fatal("not restarting after RestartAfter")
+	}
+	// Further samples do not close the channel again.
+	g.sample(o)
+}
+
+func TestResourceGuardRun(t *testing.T) {
+	r := &recorderLog{}
+	var buf bytes.Buffer
+	g, o := newTestGuard(t, ResourceLimits{HardGoroutines: 1 << 20, Interval: time.Millisecond}, r, &buf)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.run(ctx, o)
+	}()
+	waitFor(t, "samples", func() bool { return len(r.named(metrics.ResourceGoroutines)) >= 3 })
+	cancel()
+	<-done
+}
+
+func TestResourceGuardInterceptor(t *testing.T) {
+	tests := []struct {
+		name       string
+		state      ResourceState
+		method     string
+		wantHeader string
+		wantCode   codes.Code
+		wantCalled bool
+	}{
+		{
+			name:       "health ok",
+			state:      ResourceOK,
+			method:     Plugin_CheckHealth_FullMethodName,
+			wantHeader: "ok",
+			wantCalled: true,
+		},
+		{
+			name:       "health degraded",
+			state:      ResourceDegraded,
+			method:     Plugin_CheckHealth_FullMethodName,
+			wantHeader: "degraded",
+			wantCalled: true,
+		},
+		{
+			name:       "health exhausted",
+			state:      ResourceExhausted,
+			method:     Plugin_CheckHealth_FullMethodName,
+			wantHeader: "exhausted",
+			wantCode:   codes.Unavailable,
+		},
+		{
+			name:       "request degraded",
+			state:      ResourceDegraded,
+			method:     Plugin_HandleRequest_FullMethodName,
+			wantCalled: true,
+		},
+		{
+			name:     "request exhausted",
+			state:    ResourceExhausted,
+			method:   Plugin_HandleRequest_FullMethodName,
+			wantCode: codes.ResourceExhausted,
+		},
+		{
+			name:     "response exhausted",
+			state:    ResourceExhausted,
+			method:   Plugin_HandleResponse_FullMethodName,
+			wantCode: codes.ResourceExhausted,
+		},
+		{
+			name:       "configure exhausted",
+			state:      ResourceExhausted,
+			method:     Plugin_Configure_FullMethodName,
+			wantCalled: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorderLog{}
+			var buf bytes.Buffer
+			g, o := newTestGuard(t, ResourceLimits{HardGoroutines: 100, Interval: 3 * time.Second}, r, &buf)
+			g.state.Store(int32(tt.state))
+
+			stream := &headerStream{}
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+			called := false
+			_, err := g.interceptor(o)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method},
+				func(context.Context, any) (any, error) {
+					called = true
+					return nil, nil
+				})
+
+			if called != tt.wantCalled {
+				t.Errorf("handler called = %t, want %t", called, tt.wantCalled)
+			}
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("code = %s, want %s", got, tt.wantCode)
+			}
+			if got := stream.header.Get(HealthMetadataKey); tt.wantHeader == "" && len(got) > 0 ||
+				tt.wantHeader != "" && !slices.Equal(got, []string{tt.wantHeader}) {
+				t.Errorf("%s header = %q, want %q", HealthMetadataKey, got, tt.wantHeader)
+			}
+			if tt.wantCode != codes.ResourceExhausted {
+				return
+			}
+			if d, ok := RetryAfter(err); !ok || d != 3*time.Second {
+				t.Errorf("RetryAfter = %s, %t; want the sampling interval", d, ok)
+			}
+			got := r.named(metrics.Throttled)
+			if len(got) != 1 || !strings.Contains(got[0], "reason="+ThrottleResources) {
+				t.Errorf("recorded %q, want one resources throttle", got)
+			}
+		})
+	}
+}
+
+func TestCurrentResourceUsage(t *testing.T) {
+	u := CurrentResourceUsage()
+	if u.Memory == 0 || u.Goroutines == 0 {
+		t.Errorf("CurrentResourceUsage = %+v, want memory and goroutines", u)
+	}
+}
@@ -0,0 +1,53 @@
+// Package rng provides the random sources SDK components draw from for sampling decisions, retry
+// and scheduling jitter and fault selection. Components default to the runtime's randomly seeded
+// source and accept another math/rand/v2 Source through a WithRandSource option, so tests and
+// replay runs can fix the seed and get the same decisions every run:
+//
+//	src := rng.New(42)
+//	sampler, err := sampling.Probabilistic(0.1, sampling.WithRandSource(src))
+//
+// Sources returned by the package are safe for concurrent use; with several goroutines drawing
+// from one, the sequence is fixed but which goroutine gets which value is not.
+package rng
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// Default returns the runtime's global source, randomly seeded and safe for concurrent use.
+func Default() rand.Source {
+	return globalSource{}
+}
+
+type globalSource struct{}
+
+func (globalSource) Uint64() uint64 { return rand.Uint64() }
+
+// New returns a PCG source seeded with seed, safe for concurrent use.
+func New(seed uint64) rand.Source {
+	return Locked(rand.NewPCG(seed, seed))
+}
+
+// Locked returns src guarded by a mutex, for sources that are not safe for concurrent use, such
+// as *rand.PCG and *rand.ChaCha8. Sources returned by this package are returned as is.
+func Locked(src rand.Source) rand.Source {
+	switch src.(type) {
+	case globalSource, *lockedSource:
+		return src
+	}
+
+	return &lockedSource{src: src}
+}
+
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.src.Uint64()
+}
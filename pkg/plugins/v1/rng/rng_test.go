@@ -0,0 +1,97 @@
+package rng_test
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/rng"
+)
+
+// draw returns the next n values of src.
+func draw(src rand.Source, n int) []uint64 {
+	out := make([]uint64, n)
+	for i := range out {
+		out[i] = src.Uint64()
+	}
+
+	return out
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  uint64
+		equal bool
+	}{
+		{name: "same seed", a: 42, b: 42, equal: true},
+		{name: "different seeds", a: 42, b: 43, equal: false},
+		{name: "zero seed", a: 0, b: 0, equal: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slices.Equal(draw(rng.New(tt.a), 16), draw(rng.New(tt.b), 16)); got != tt.equal {
+				t.Errorf("sequences equal = %v, want %v", got, tt.equal)
+			}
+		})
+	}
+}
+
+func TestNewMatchesPCG(t *testing.T) {
+	// Locking does not change the sequence of the underlying source.
+	if got, want := draw(rng.New(7), 8), draw(rand.NewPCG(7, 7), 8); !slices.Equal(got, want) {
+		t.Errorf("New(7) = %v, want the PCG sequence %v", got, want)
+	}
+}
+
+func TestLocked(t *testing.T) {
+	locked := rng.Locked(rand.NewPCG(1, 1))
+	tests := []struct {
+		name string
+		src  rand.Source
+		same bool // Whether Locked returns src itself.
+	}{
+		{name: "default", src: rng.Default(), same: true},
+		{name: "already locked", src: locked, same: true},
+		{name: "from New", src: rng.New(1), same: true},
+		{name: "pcg", src: rand.NewPCG(1, 1), same: false},
+		{name: "chacha8", src: rand.NewChaCha8([32]byte{}), same: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rng.Locked(tt.src) == tt.src; got != tt.same {
+				t.Errorf("Locked returned its argument = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
+
+func TestConcurrentDraws(t *testing.T) {
+	// Concurrent draws share one sequence: together they are its first values, in some order.
+	const goroutines, each = 8, 100
+	src := rng.New(9)
+	var (
+		mu  sync.Mutex
+		got []uint64
+		wg  sync.WaitGroup
+	)
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vals := draw(src, each)
+			mu.Lock()
+			got = append(got, vals...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	want := draw(rng.New(9), goroutines*each)
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Error("concurrent draws are not a permutation of the seeded sequence")
+	}
+}
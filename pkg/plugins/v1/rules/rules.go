@@ -0,0 +1,473 @@
+// Package rules compiles pattern rules from custom_config once, at Configure, and evaluates them
+// against requests, the common core of filter plugins.
+//
+// Each rule is a section of custom_config under "rules.<name>.":
+//
+//	rules.no-admin.field:   path
+//	rules.no-admin.match:   /servers/*/admin/**
+//	rules.no-admin.type:    glob
+//	rules.no-shell.field:   tool
+//	rules.no-shell.match:   ^(exec|shell|run_command)$
+//	rules.no-shell.action:  deny
+//
+// Patterns are RE2 regular expressions (Go's regexp package), so matching runs in time linear in
+// the input whatever the pattern; constructs that need backtracking, such as backreferences and
+// lookarounds, are rejected at Configure. Globs match "*" within a path segment, "**" across
+// segments and "?" as one character.
+//
+// A RuleSet evaluates rules in priority order and records a RuleHits count per hit.
+// Plugin denies requests matching a rule whose action is deny.
+package rules
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// SectionPrefix prefixes the custom_config keys of rules: "rules.<name>.<key>".
+const SectionPrefix = "rules."
+
+// MaxPatternLength bounds the length of rule patterns.
+const MaxPatternLength = 4096
+
+// RuleHits counts rule matches, labelled by LabelRule and LabelAction.
+const RuleHits = "rules.hits"
+
+// Label keys of RuleHits.
+const (
+	LabelRule   = "rule"
+	LabelAction = "action"
+)
+
+// Rule actions with a meaning for RuleSet.HandleRequest. Other actions are reported in hits
+// and otherwise ignored, so plugins can define their own.
+const (
+	ActionDeny  = "deny"
+	ActionAllow = "allow"
+)
+
+// Fields a rule can match. FieldHeaderPrefix is followed by a header name, such as
+// "header.User-Agent".
+const (
+	FieldMethod       = "method"
+	FieldPath         = "path"
+	FieldURL          = "url"
+	FieldRemoteAddr   = "remote_addr"
+	FieldMCPMethod    = "mcp_method"
+	FieldTool         = "tool"
+	FieldResource     = "resource"
+	FieldBody         = "body"
+	FieldText         = "text"
+	FieldHeaderPrefix = "header."
+)
+
+// RuleConfig is a rule as written in custom_config.
+type RuleConfig struct {
+	// Field is what the rule matches: method, path, url, remote_addr, mcp_method, tool, resource,
+	// body, text (each user-visible MCP text) or header.<name>. Rules on absent MCP fields and
+	// headers never match.
+	Field string `config:"field" default:"path"`
+
+	// Match is the pattern.
+	Match string `config:"match"`
+
+	// Type is regex or glob.
+	Type string `config:"type" default:"regex"`
+
+	// Action is reported when the rule matches.
+	Action string `config:"action" default:"deny"`
+
+	// Priority orders evaluation, lowest first; rules of equal priority run by name.
+	Priority int `config:"priority"`
+
+	// CaseInsensitive matches regardless of case.
+	CaseInsensitive bool `config:"case_insensitive" default:"false"`
+}
+
+// Rule is a compiled rule.
+type Rule struct {
+	Name     string
+	Field    string
+	Action   string
+	Priority int
+
+	re *regexp.Regexp
+}
+
+// Compile validates and compiles rc as the rule name.
+func Compile(name string, rc RuleConfig) (*Rule, error) {
+	if rc.Match == "" {
+		return nil, fmt.Errorf("rule %s: match is required", name)
+	}
+	if len(rc.Match) > MaxPatternLength {
+		return nil, fmt.Errorf("rule %s: pattern longer than %d bytes", name, MaxPatternLength)
+	}
+	field := strings.ToLower(rc.Field)
+	if !validField(field) {
+		return nil, fmt.Errorf("rule %s: unknown field %q", name, rc.Field)
+	}
+	if strings.HasPrefix(field, FieldHeaderPrefix) {
+		// Keep the header name as written; GetHeader matches it case-insensitively.
+		field = FieldHeaderPrefix + rc.Field[len(FieldHeaderPrefix):]
+	}
+
+	var pattern string
+	switch strings.ToLower(rc.Type) {
+	case "", "regex":
+		pattern = rc.Match
+	case "glob":
+		pattern = globToRegexp(rc.Match)
+	default:
+		return nil, fmt.Errorf("rule %s: unknown type %q", name, rc.Type)
+	}
+	if rc.CaseInsensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: invalid RE2 pattern (backreferences and lookarounds are not supported): %w",
+			name, err)
+	}
+
+	return &Rule{Name: name, Field: field, Action: rc.Action, Priority: rc.Priority, re: re}, nil
+}
+
+// FromConfig compiles the rules defined under SectionPrefix in custom.
+func FromConfig(custom map[string]string) ([]*Rule, error) {
+	// Only rule keys are split, so top-level plugin keys never leak into rule sections.
+	scoped := map[string]string{}
+	for k, v := range custom {
+		if strings.HasPrefix(k, SectionPrefix) {
+			scoped[k] = v
+		}
+	}
+	_, sections := config.Sections(scoped, SectionPrefix)
+
+	rules := make([]*Rule, 0, len(sections))
+	for name, values := range sections {
+		var rc RuleConfig
+		if _, err := config.Decode(values, &rc); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		r, err := Compile(name, rc)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// Hit is a rule that matched a request.
+type Hit struct {
+	Rule   string
+	Action string
+
+	// Value is the field value that matched.
+	Value string
+}
+
+// RuleSet evaluates compiled rules. It is immutable and safe for concurrent use.
+type RuleSet struct {
+	rules    []*Rule
+	recorder metrics.Recorder
+	denial   *mcpdpluginsv1.DenyTemplate
+}
+
+// Option configures a RuleSet.
+type Option func(*RuleSet)
+
+// WithMetrics records RuleHits through r.
+func WithMetrics(r metrics.Recorder) Option {
+	return func(s *RuleSet) {
+		if r != nil {
+			s.recorder = r
+		}
+	}
+}
+
+// WithDenyTemplate renders the responses of HandleRequest to denied requests from t, with the
+// deny rule's name as the Denial's RuleID.
+func WithDenyTemplate(t *mcpdpluginsv1.DenyTemplate) Option {
+	return func(s *RuleSet) {
+		s.denial = t
+	}
+}
+
+// NewRuleSet returns a RuleSet evaluating rules by priority, then name.
+func NewRuleSet(rules []*Rule, opts ...Option) *RuleSet {
+	s := &RuleSet{rules: append([]*Rule(nil), rules...), recorder: metrics.Nop()}
+	sort.SliceStable(s.rules, func(i, j int) bool {
+		if s.rules[i].Priority != s.rules[j].Priority {
+			return s.rules[i].Priority < s.rules[j].Priority
+		}
+		return s.rules[i].Name < s.rules[j].Name
+	})
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Rules returns the rules in evaluation order.
+func (s *RuleSet) Rules() []*Rule {
+	return append([]*Rule(nil), s.rules...)
+}
+
+// Match returns every rule matching req, in evaluation order.
+func (s *RuleSet) Match(req *mcpdpluginsv1.HTTPRequest) []Hit {
+	var hits []Hit
+	s.eval(req, func(h Hit) bool {
+		hits = append(hits, h)
+		return true
+	})
+
+	return hits
+}
+
+// First returns the first rule matching req.
+func (s *RuleSet) First(req *mcpdpluginsv1.HTTPRequest) (Hit, bool) {
+	var (
+		hit   Hit
+		found bool
+	)
+	s.eval(req, func(h Hit) bool {
+		hit, found = h, true
+		return false
+	})
+
+	return hit, found
+}
+
+// HandleRequest short-circuits req with 403 and a JSON-RPC error when its first matching deny
+// or allow rule is a deny rule, and lets it continue otherwise.
+func (s *RuleSet) HandleRequest(req *mcpdpluginsv1.HTTPRequest) *mcpdpluginsv1.HTTPResponse {
+	return s.handle(context.Background(), req)
+}
+
+// handle is HandleRequest, with ctx giving deny templates the call's correlation ID.
+func (s *RuleSet) handle(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) *mcpdpluginsv1.HTTPResponse {
+	var deny *Hit
+	s.eval(req, func(h Hit) bool {
+		switch h.Action {
+		case ActionDeny:
+			deny = &h
+			return false
+		case ActionAllow:
+			return false
+		default:
+			return true
+		}
+	})
+	if deny == nil {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+
+	return s.denial.Deny(ctx, req, mcpdpluginsv1.Denial{
+		Reason: "request denied by rule " + deny.Rule,
+		RuleID: deny.Rule,
+		Status: http.StatusForbidden,
+	})
+}
+
+// eval calls fn for each hit in order until it returns false. Field values are extracted once.
+func (s *RuleSet) eval(req *mcpdpluginsv1.HTTPRequest, fn func(Hit) bool) {
+	f := fields{req: req}
+	for _, r := range s.rules {
+		for _, v := range f.values(r.Field) {
+			if !r.re.MatchString(v) {
+				continue
+			}
+			s.recorder.Count(RuleHits, 1, metrics.L(LabelRule, r.Name), metrics.L(LabelAction, r.Action))
+			if !fn(Hit{Rule: r.Name, Action: r.Action, Value: v}) {
+				return
+			}
+			break
+		}
+	}
+}
+
+// fields extracts and memoizes the values of a request's fields.
+type fields struct {
+	req    *mcpdpluginsv1.HTTPRequest
+	parsed bool
+	msg    *mcp.Message
+	texts  []string
+	tried  bool
+}
+
+func (f *fields) values(field string) []string {
+	switch field {
+	case FieldMethod:
+		return []string{f.req.GetMethod()}
+	case FieldPath:
+		return []string{f.req.GetPath()}
+	case FieldURL:
+		return []string{f.req.GetUrl()}
+	case FieldRemoteAddr:
+		return []string{f.req.GetRemoteAddr()}
+	case FieldBody:
+		return []string{string(f.req.GetBody())}
+	case FieldMCPMethod, FieldTool, FieldResource:
+		m := f.message()
+		if m == nil {
+			return nil
+		}
+		switch field {
+		case FieldMCPMethod:
+			return []string{m.Method}
+		case FieldTool:
+			return nonEmpty(m.ToolName())
+		default:
+			return nonEmpty(m.ResourceURI())
+		}
+	case FieldText:
+		if !f.tried {
+			f.tried = true
+			f.texts, _ = mcp.Texts(f.req.GetBody())
+		}
+		return f.texts
+	default:
+		name := strings.TrimPrefix(field, FieldHeaderPrefix)
+		return nonEmpty(mcpdpluginsv1.GetHeader(f.req.GetHeaders(), name))
+	}
+}
+
+func (f *fields) message() *mcp.Message {
+	if !f.parsed {
+		f.parsed = true
+		f.msg, _ = mcp.ParseOne(f.req.GetBody())
+	}
+
+	return f.msg
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return []string{s}
+}
+
+func validField(field string) bool {
+	switch field {
+	case FieldMethod, FieldPath, FieldURL, FieldRemoteAddr, FieldMCPMethod, FieldTool, FieldResource, FieldBody,
+		FieldText:
+		return true
+	default:
+		return strings.HasPrefix(field, FieldHeaderPrefix) && len(field) > len(FieldHeaderPrefix)
+	}
+}
+
+// CompileGlob compiles a glob with the syntax of glob rules: "*" within a path segment, "**" across
+// segments and "?" as one character.
+func CompileGlob(glob string) (*regexp.Regexp, error) {
+	if len(glob) > MaxPatternLength {
+		return nil, fmt.Errorf("pattern longer than %d bytes", MaxPatternLength)
+	}
+
+	return regexp.Compile(globToRegexp(glob))
+}
+
+// globToRegexp translates a glob into an anchored RE2 pattern.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	return b.String()
+}
+
+// Plugin is a request-flow plugin enforcing the rules of its custom_config. It allows every
+// request until configured.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	recorder metrics.Recorder
+	rules    atomic.Pointer[RuleSet]
+}
+
+// NewPlugin returns a Plugin recording rule hits through recorder (nil disables metrics).
+func NewPlugin(recorder metrics.Recorder) *Plugin {
+	p := &Plugin{recorder: recorder}
+	p.rules.Store(NewRuleSet(nil))
+
+	return p
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "rules",
+		Version:     pluginVersion,
+		Description: "Denies requests matching regex or glob rules.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest), nil
+}
+
+// Configure compiles the rules of cfg's custom_config, keeping the previous rules on error. The
+// top-level deny_template keys of mcpdpluginsv1.DenyTemplateConfig customize denials.
+func (p *Plugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	rules, err := FromConfig(cfg.GetCustomConfig())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	var dc mcpdpluginsv1.DenyTemplateConfig
+	if err := mcpdpluginsv1.DecodeConfig(ctx, cfg, &dc); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	denial, err := dc.Template()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	p.rules.Store(NewRuleSet(rules, WithMetrics(p.recorder), WithDenyTemplate(denial)))
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleRequest applies the configured rules.
+func (p *Plugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.rules.Load().handle(ctx, req), nil
+}
@@ -0,0 +1,470 @@
+package rules_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/rules"
+)
+
+const toolCall = `{"jsonrpc":"2.0","id":1,"method":"tools/call",` +
+	`"params":{"name":"run_command","arguments":{"cmd":"rm -rf /","note":"cleanup"}}}`
+
+// hitRecorder counts RuleHits by rule and action.
+type hitRecorder struct {
+	mu   sync.Mutex
+	hits map[string]int64
+}
+
+func (r *hitRecorder) Count(name string, delta int64, labels ...metrics.Label) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.hits == nil {
+		r.hits = map[string]int64{}
+	}
+	key := name
+	for _, l := range labels {
+		key += " " + l.Key + "=" + l.Value
+	}
+	r.hits[key] += delta
+}
+
+func (*hitRecorder) Gauge(string, float64, ...metrics.Label)        {}
+func (*hitRecorder) Observe(string, float64, ...metrics.Label)      {}
+func (*hitRecorder) Timing(string, time.Duration, ...metrics.Label) {}
+
+func (r *hitRecorder) get() map[string]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := map[string]int64{}
+	for k, v := range r.hits {
+		out[k] = v
+	}
+
+	return out
+}
+
+func newRequest() *mcpdpluginsv1.HTTPRequest {
+	return &mcpdpluginsv1.HTTPRequest{
+		Method:     "POST",
+		Path:       "/servers/github/admin/users",
+		Url:        "http://mcpd/servers/github/admin/users?page=2",
+		RemoteAddr: "10.1.2.3:4567",
+		Headers:    map[string]string{"User-Agent": "curl/8.0"},
+		Body:       []byte(toolCall),
+	}
+}
+
+func compile(t *testing.T, name string, rc rules.RuleConfig) *rules.Rule {
+	t.Helper()
+
+	r, err := rules.Compile(name, rc)
+	if err != nil {
+		t.Fatalf("Compile(%s): %v", name, err)
+	}
+
+	return r
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rc   rules.RuleConfig
+		want string
+	}{
+		{name: "no pattern", rc: rules.RuleConfig{Field: "path"}, want: "match is required"},
+		{
+			name: "pattern too long",
+			rc:   rules.RuleConfig{Field: "path", Match: strings.Repeat("a", rules.MaxPatternLength+1)},
+			want: "pattern longer than 4096 bytes",
+		},
+		{name: "unknown field", rc: rules.RuleConfig{Field: "cookie", Match: "x"}, want: `unknown field "cookie"`},
+		{name: "header without a name", rc: rules.RuleConfig{Field: "header.", Match: "x"}, want: "unknown field"},
+		{
+			name: "unknown type",
+			rc:   rules.RuleConfig{Field: "path", Match: "x", Type: "pcre"},
+			want: `unknown type "pcre"`,
+		},
+		{name: "backreference", rc: rules.RuleConfig{Field: "path", Match: `(a)\1`}, want: "invalid RE2 pattern"},
+		{name: "lookahead", rc: rules.RuleConfig{Field: "path", Match: `a(?=b)`}, want: "invalid RE2 pattern"},
+		{name: "unbalanced", rc: rules.RuleConfig{Field: "path", Match: `(a`}, want: "invalid RE2 pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rules.Compile("r1", tt.rc)
+			if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.HasPrefix(err.Error(), "rule r1: ") {
+				t.Errorf("Compile error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleFields(t *testing.T) {
+	tests := []struct {
+		name string
+		rc   rules.RuleConfig
+		req  func(*mcpdpluginsv1.HTTPRequest)
+		want string // Matched value; empty for no match.
+	}{
+		{name: "method", rc: rules.RuleConfig{Field: "method", Match: "^POST$"}, want: "POST"},
+		{name: "field in upper case", rc: rules.RuleConfig{Field: "METHOD", Match: "^POST$"}, want: "POST"},
+		{name: "path", rc: rules.RuleConfig{Field: "path", Match: "/admin/"}, want: "/servers/github/admin/users"},
+		{
+			name: "url",
+			rc:   rules.RuleConfig{Field: "url", Match: `page=\d`},
+			want: "http://mcpd/servers/github/admin/users?page=2",
+		},
+		{name: "remote address", rc: rules.RuleConfig{Field: "remote_addr", Match: `^10\.`}, want: "10.1.2.3:4567"},
+		{name: "MCP method", rc: rules.RuleConfig{Field: "mcp_method", Match: "^tools/"}, want: "tools/call"},
+		{name: "tool", rc: rules.RuleConfig{Field: "tool", Match: "^(exec|shell|run_command)$"}, want: "run_command"},
+		{name: "body", rc: rules.RuleConfig{Field: "body", Match: "rm -rf"}, want: toolCall},
+		{name: "text", rc: rules.RuleConfig{Field: "text", Match: "^cleanup$"}, want: "cleanup"},
+		{name: "header", rc: rules.RuleConfig{Field: "header.user-agent", Match: "^curl/"}, want: "curl/8.0"},
+		{
+			name: "header name as written",
+			rc:   rules.RuleConfig{Field: "Header.User-Agent", Match: "curl"},
+			want: "curl/8.0",
+		},
+		{name: "absent header", rc: rules.RuleConfig{Field: "header.X-Forwarded-For", Match: ".*"}},
+		{name: "no resource", rc: rules.RuleConfig{Field: "resource", Match: ".*"}},
+		{
+			name: "resource",
+			rc:   rules.RuleConfig{Field: "resource", Match: "^file://"},
+			req: func(r *mcpdpluginsv1.HTTPRequest) {
+				r.Body = []byte(`{"jsonrpc":"2.0","id":1,"method":"resources/read",` +
+					`"params":{"uri":"file:///etc/passwd"}}`)
+			},
+			want: "file:///etc/passwd",
+		},
+		{
+			name: "not MCP",
+			rc:   rules.RuleConfig{Field: "mcp_method", Match: ".*"},
+			req:  func(r *mcpdpluginsv1.HTTPRequest) { r.Body = []byte("plain") },
+		},
+		{name: "case sensitive", rc: rules.RuleConfig{Field: "path", Match: "/ADMIN/"}},
+		{
+			name: "case insensitive",
+			rc:   rules.RuleConfig{Field: "path", Match: "/ADMIN/", CaseInsensitive: true},
+			want: "/servers/github/admin/users",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest()
+			if tt.req != nil {
+				tt.req(req)
+			}
+			set := rules.NewRuleSet([]*rules.Rule{compile(t, "r", tt.rc)})
+			hit, ok := set.First(req)
+			if ok != (tt.want != "") || hit.Value != tt.want {
+				t.Errorf("First = %+v, %t; want value %q", hit, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestGlob(t *testing.T) {
+	tests := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{glob: "/servers/*/admin/**", path: "/servers/github/admin/users/1", match: true},
+		{glob: "/servers/*/admin/**", path: "/servers/github/admin/", match: true},
+		{glob: "/servers/*/admin/**", path: "/servers/a/b/admin/users", match: false},
+		{glob: "/servers/*", path: "/servers/github", match: true},
+		{glob: "/servers/*", path: "/servers/github/tools", match: false},
+		{glob: "/v?/tools", path: "/v1/tools", match: true},
+		{glob: "/v?/tools", path: "/v10/tools", match: false},
+		{glob: "/v?/tools", path: "/v//tools", match: false},
+		{glob: "/a.b+(c)", path: "/a.b+(c)", match: true},
+		{glob: "/a.b+(c)", path: "/aXb+(c)", match: false},
+		{glob: "/tools", path: "/tools/list", match: false},
+		{glob: "**", path: "", match: true},
+	}
+	for _, tt := range tests {
+		re, err := rules.CompileGlob(tt.glob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := re.MatchString(tt.path); got != tt.match {
+			t.Errorf("glob %q on %q = %t, want %t", tt.glob, tt.path, got, tt.match)
+		}
+
+		r := compile(t, "g", rules.RuleConfig{Field: "path", Match: tt.glob, Type: "GLOB"})
+		_, got := rules.NewRuleSet([]*rules.Rule{r}).First(&mcpdpluginsv1.HTTPRequest{Path: tt.path})
+		if got != tt.match {
+			t.Errorf("glob rule %q on %q = %t, want %t", tt.glob, tt.path, got, tt.match)
+		}
+	}
+
+	if _, err := rules.CompileGlob(strings.Repeat("*", rules.MaxPatternLength+1)); err == nil {
+		t.Error("CompileGlob accepted an over-long pattern")
+	}
+}
+
+func TestRuleSetOrder(t *testing.T) {
+	rs := []*rules.Rule{
+		compile(t, "b-log", rules.RuleConfig{Field: "path", Match: "admin", Action: "log"}),
+		compile(t, "a-log", rules.RuleConfig{Field: "path", Match: "admin", Action: "log"}),
+		compile(t, "late-deny", rules.RuleConfig{Field: "tool", Match: "run", Action: "deny", Priority: 10}),
+		compile(t, "early-allow", rules.RuleConfig{Field: "method", Match: "POST", Action: "allow", Priority: -1}),
+		compile(t, "miss", rules.RuleConfig{Field: "method", Match: "GET", Priority: -5}),
+	}
+	var rec hitRecorder
+	set := rules.NewRuleSet(rs, rules.WithMetrics(&rec), rules.WithMetrics(nil))
+
+	var names []string
+	for _, r := range set.Rules() {
+		names = append(names, r.Name)
+	}
+	if want := []string{"miss", "early-allow", "a-log", "b-log", "late-deny"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Rules = %v, want %v", names, want)
+	}
+
+	hits := set.Match(newRequest())
+	want := []rules.Hit{
+		{Rule: "early-allow", Action: "allow", Value: "POST"},
+		{Rule: "a-log", Action: "log", Value: "/servers/github/admin/users"},
+		{Rule: "b-log", Action: "log", Value: "/servers/github/admin/users"},
+		{Rule: "late-deny", Action: "deny", Value: "run_command"},
+	}
+	if !reflect.DeepEqual(hits, want) {
+		t.Errorf("Match =\n%+v\nwant\n%+v", hits, want)
+	}
+	if first, ok := set.First(newRequest()); !ok || first != want[0] {
+		t.Errorf("First = %+v, %t; want %+v", first, ok, want[0])
+	}
+
+	wantHits := map[string]int64{
+		"rules.hits rule=early-allow action=allow": 2,
+		"rules.hits rule=a-log action=log":         1,
+		"rules.hits rule=b-log action=log":         1,
+		"rules.hits rule=late-deny action=deny":    1,
+	}
+	if got := rec.get(); !reflect.DeepEqual(got, wantHits) {
+		t.Errorf("hits = %v, want %v", got, wantHits)
+	}
+
+	// The set keeps its own copy of the rules.
+	rs[0] = nil
+	if len(set.Match(newRequest())) != 4 {
+		t.Error("RuleSet shares the caller's slice")
+	}
+}
+
+func TestRuleSetTextHitOnce(t *testing.T) {
+	var rec hitRecorder
+	r := compile(t, "any-text", rules.RuleConfig{Field: "text", Match: ".", Action: "deny"})
+	hits := rules.NewRuleSet([]*rules.Rule{r}, rules.WithMetrics(&rec)).Match(newRequest())
+
+	if len(hits) != 1 || hits[0].Value != "rm -rf /" && hits[0].Value != "cleanup" {
+		t.Errorf("Match = %+v, want one hit for the first matching text", hits)
+	}
+	if got := rec.get()["rules.hits rule=any-text action=deny"]; got != 1 {
+		t.Errorf("recorded %d hits, want 1", got)
+	}
+}
+
+func TestHandleRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []rules.RuleConfig
+		wantDeny string // Denying rule; empty when the request continues.
+	}{
+		{name: "no rules"},
+		{
+			name:     "deny",
+			rules:    []rules.RuleConfig{{Field: "tool", Match: "run_command", Action: "deny"}},
+			wantDeny: "r0",
+		},
+		{
+			name: "allow before deny",
+			rules: []rules.RuleConfig{
+				{Field: "remote_addr", Match: `^10\.`, Action: "allow"},
+				{Field: "tool", Match: "run_command", Action: "deny", Priority: 1},
+			},
+		},
+		{
+			name: "custom actions are skipped",
+			rules: []rules.RuleConfig{
+				{Field: "path", Match: "admin", Action: "audit"},
+				{Field: "tool", Match: "run_command", Action: "deny", Priority: 1},
+			},
+			wantDeny: "r1",
+		},
+		{name: "no match", rules: []rules.RuleConfig{{Field: "tool", Match: "^exec$", Action: "deny"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rs []*rules.Rule
+			for i, rc := range tt.rules {
+				rs = append(rs, compile(t, "r"+string(rune('0'+i)), rc))
+			}
+			resp := rules.NewRuleSet(rs).HandleRequest(newRequest())
+			if tt.wantDeny == "" {
+				if !resp.GetContinue() {
+					t.Errorf("response = %v, want the request to continue", resp)
+				}
+				return
+			}
+			if resp.GetContinue() || resp.GetStatusCode() != 403 ||
+				!strings.Contains(string(resp.GetBody()), "request denied by rule "+tt.wantDeny) {
+				t.Errorf("response = %v, want a 403 from rule %s", resp, tt.wantDeny)
+			}
+		})
+	}
+}
+
+func TestDenyTemplate(t *testing.T) {
+	tmpl, err := mcpdpluginsv1.NewDenyTemplate(mcpdpluginsv1.DenyFormatText, "blocked by {{.RuleID}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := compile(t, "no-shell", rules.RuleConfig{Field: "tool", Match: "run_command", Action: "deny"})
+
+	resp := rules.NewRuleSet([]*rules.Rule{r}, rules.WithDenyTemplate(tmpl)).HandleRequest(newRequest())
+	if !strings.Contains(string(resp.GetBody()), "blocked by no-shell") {
+		t.Errorf("body = %s, want the templated denial", resp.GetBody())
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	custom := map[string]string{
+		"rules.no-admin.field":            "path",
+		"rules.no-admin.match":            "/servers/*/admin/**",
+		"rules.no-admin.type":             "glob",
+		"rules.no-shell.field":            "tool",
+		"rules.no-shell.match":            "^(exec|shell|run_command)$",
+		"rules.no-shell.priority":         "-1",
+		"rules.no-shell.case_insensitive": "true",
+		"deny_template":                   "ignored here",
+		"match":                           "top-level keys are not rules",
+	}
+	rs, err := rules.FromConfig(custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := rules.NewRuleSet(rs)
+	var got []string
+	for _, r := range set.Rules() {
+		got = append(got, r.Name+":"+r.Field+":"+r.Action)
+	}
+	if want := []string{"no-shell:tool:deny", "no-admin:path:deny"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rules = %v, want %v", got, want)
+	}
+
+	req := newRequest()
+	req.Body = []byte(strings.Replace(toolCall, "run_command", "SHELL", 1))
+	if hit, ok := set.First(req); !ok || hit.Rule != "no-shell" {
+		t.Errorf("First = %+v, %t; want the case-insensitive no-shell rule", hit, ok)
+	}
+}
+
+func TestFromConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		custom map[string]string
+		want   string
+	}{
+		{
+			name:   "invalid pattern",
+			custom: map[string]string{"rules.r.match": "(a"},
+			want:   "rule r: invalid RE2 pattern",
+		},
+		{
+			name:   "missing pattern",
+			custom: map[string]string{"rules.r.field": "tool"},
+			want:   "rule r: match is required",
+		},
+		{
+			name:   "undecodable",
+			custom: map[string]string{"rules.r.match": "a", "rules.r.priority": "high"},
+			want:   "rule r: ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := rules.FromConfig(tt.custom); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("FromConfig error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func configure(p *rules.Plugin, custom map[string]string) error {
+	_, err := p.Configure(context.Background(), &mcpdpluginsv1.PluginConfig{CustomConfig: custom})
+	return err
+}
+
+func TestPlugin(t *testing.T) {
+	var rec hitRecorder
+	p := rules.NewPlugin(&rec)
+	ctx := context.Background()
+
+	resp, err := p.HandleRequest(ctx, newRequest())
+	if err != nil || !resp.GetContinue() {
+		t.Fatalf("unconfigured HandleRequest = %v, %v; want it to continue", resp, err)
+	}
+
+	err = configure(p, map[string]string{
+		"rules.no-shell.field": "tool",
+		"rules.no-shell.match": "run_command",
+		"deny_template":        "denied: {{.RuleID}}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = p.HandleRequest(ctx, newRequest())
+	if err != nil || resp.GetStatusCode() != 403 || !strings.Contains(string(resp.GetBody()), "denied: no-shell") {
+		t.Errorf("HandleRequest = %v, %v; want the templated 403", resp, err)
+	}
+	if got := rec.get()["rules.hits rule=no-shell action=deny"]; got != 1 {
+		t.Errorf("recorded %d hits, want 1", got)
+	}
+
+	// A failed Configure keeps the previous rules.
+	for _, custom := range []map[string]string{
+		{"rules.bad.match": `(a`},
+		{"rules.ok.match": "a", "deny_template": "{{"},
+		{"rules.ok.match": "a", "deny_template_format": "yaml", "deny_template": "x"},
+	} {
+		if err := configure(p, custom); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Configure(%v) error = %v, want InvalidArgument", custom, err)
+		}
+	}
+	if resp, _ := p.HandleRequest(ctx, newRequest()); resp.GetStatusCode() != 403 {
+		t.Errorf("HandleRequest after failed Configure = %v, want the previous rules", resp)
+	}
+
+	if err := configure(p, map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := p.HandleRequest(ctx, newRequest()); !resp.GetContinue() {
+		t.Errorf("HandleRequest without rules = %v, want it to continue", resp)
+	}
+}
+
+func TestPluginMetadata(t *testing.T) {
+	p := rules.NewPlugin(nil)
+	md, err := p.GetMetadata(context.Background(), &emptypb.Empty{})
+	if err != nil || md.GetName() != "rules" {
+		t.Errorf("GetMetadata = %v, %v", md, err)
+	}
+	caps, err := p.GetCapabilities(context.Background(), &emptypb.Empty{})
+	if err != nil || !reflect.DeepEqual(caps.GetFlows(), []mcpdpluginsv1.Flow{mcpdpluginsv1.FlowRequest}) {
+		t.Errorf("GetCapabilities = %v, %v; want the request flow", caps, err)
+	}
+}
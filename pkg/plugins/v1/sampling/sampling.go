@@ -0,0 +1,154 @@
+// Package sampling decides which plugin calls are recorded by optional observability features
+// (the access log, slow request log, debug log and trace exemplars) so high-QPS deployments can
+// keep observability cheap.
+//
+// Samplers are configured centrally with mcpdpluginsv1.WithSampler and consulted by every
+// SDK feature that records per-call data. Use PerFeature to tune features individually.
+package sampling
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/rng"
+)
+
+// Feature names passed to samplers by SDK features.
+const (
+	FeatureAccessLog = "access_log"
+	FeatureDebug     = "debug"
+	FeatureMirror    = "mirror"
+	FeatureSlowLog   = "slow_log"
+	FeatureTrace     = "trace"
+)
+
+// Params describes the call being considered for sampling.
+type Params struct {
+	// Feature is the SDK feature asking (e.g. FeatureAccessLog).
+	Feature string
+
+	// Method is the short RPC name (e.g. "HandleRequest").
+	Method string
+
+	// Err reports whether the handler returned an error.
+	Err bool
+
+	// ShortCircuit reports whether the handler stopped the chain.
+	ShortCircuit bool
+}
+
+// Sampler decides whether a call is recorded. Implementations must be safe for concurrent use.
+type Sampler interface {
+	Sample(p Params) bool
+}
+
+// SamplerFunc adapts a function to the Sampler interface.
+type SamplerFunc func(p Params) bool
+
+// Sample calls f(p).
+func (f SamplerFunc) Sample(p Params) bool {
+	return f(p)
+}
+
+// Always samples every call.
+func Always() Sampler {
+	return SamplerFunc(func(Params) bool { return true })
+}
+
+// Never samples nothing.
+func Never() Sampler {
+	return SamplerFunc(func(Params) bool { return false })
+}
+
+// Option configures a sampler.
+type Option func(*options)
+
+type options struct {
+	rand *rand.Rand
+}
+
+// WithRandSource draws the sampler's decisions from src (defaults to rng.Default()), for example
+// a seeded rng.New source for reproducible test and replay runs. A nil src keeps the default.
+func WithRandSource(src rand.Source) Option {
+	return func(o *options) {
+		if src != nil {
+			o.rand = rand.New(rng.Locked(src))
+		}
+	}
+}
+
+// Probabilistic samples each call independently with probability ratio (0 to 1).
+func Probabilistic(ratio float64, opts ...Option) (Sampler, error) {
+	if ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("sampling ratio must be between 0 and 1, got %v", ratio)
+	}
+	o := options{rand: rand.New(rng.Default())}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return SamplerFunc(func(Params) bool {
+		return ratio == 1 || (ratio > 0 && o.rand.Float64() < ratio)
+	}), nil
+}
+
+// RateLimited samples at most perSecond calls per second, allowing bursts of up to burst calls.
+func RateLimited(perSecond float64, burst int) (Sampler, error) {
+	if perSecond <= 0 {
+		return nil, fmt.Errorf("rate must be positive")
+	}
+	if burst < 1 {
+		return nil, fmt.Errorf("burst must be at least 1")
+	}
+
+	return &rateLimited{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}, nil
+}
+
+type rateLimited struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func (r *rateLimited) Sample(Params) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.tokens = min(r.burst, r.tokens+now.Sub(r.last).Seconds()*r.rate)
+	r.last = now
+
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+
+	return true
+}
+
+// AlwaysOnError samples every failed or short-circuited call and defers to inner for the rest.
+func AlwaysOnError(inner Sampler) Sampler {
+	return SamplerFunc(func(p Params) bool {
+		return p.Err || p.ShortCircuit || inner.Sample(p)
+	})
+}
+
+// PerFeature uses the sampler registered for p.Feature, falling back to def.
+func PerFeature(def Sampler, features map[string]Sampler) Sampler {
+	return SamplerFunc(func(p Params) bool {
+		if s, ok := features[p.Feature]; ok {
+			return s.Sample(p)
+		}
+		return def.Sample(p)
+	})
+}
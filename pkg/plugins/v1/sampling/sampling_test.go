@@ -0,0 +1,184 @@
+package sampling_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/rng"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/sampling"
+)
+
+// decisions returns the next n decisions of s.
+func decisions(t *testing.T, s sampling.Sampler, n int) []bool {
+	t.Helper()
+
+	out := make([]bool, n)
+	for i := range out {
+		out[i] = s.Sample(sampling.Params{Feature: sampling.FeatureAccessLog, Method: "HandleRequest"})
+	}
+
+	return out
+}
+
+func TestProbabilisticRandSource(t *testing.T) {
+	newSampler := func(seed uint64) sampling.Sampler {
+		s, err := sampling.Probabilistic(0.5, sampling.WithRandSource(rng.New(seed)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name  string
+		a, b  uint64
+		equal bool
+	}{
+		{name: "same seed", a: 1, b: 1, equal: true},
+		{name: "different seeds", a: 1, b: 2, equal: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := decisions(t, newSampler(tt.a), 64)
+			b := decisions(t, newSampler(tt.b), 64)
+			if got := slices.Equal(a, b); got != tt.equal {
+				t.Errorf("decisions equal = %v, want %v", got, tt.equal)
+			}
+		})
+	}
+}
+
+func TestProbabilisticNilRandSource(t *testing.T) {
+	s, err := sampling.Probabilistic(1, sampling.WithRandSource(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(decisions(t, s, 16), false) {
+		t.Error("ratio 1 with the default source skipped a call")
+	}
+}
+
+func TestAlwaysNever(t *testing.T) {
+	if slices.Contains(decisions(t, sampling.Always(), 16), false) {
+		t.Error("Always skipped a call")
+	}
+	if slices.Contains(decisions(t, sampling.Never(), 16), true) {
+		t.Error("Never sampled a call")
+	}
+}
+
+func TestProbabilistic(t *testing.T) {
+	tests := []struct {
+		name    string
+		ratio   float64
+		wantErr bool
+		check   func(sampled int) bool // Over 1000 calls.
+	}{
+		{name: "below zero", ratio: -0.1, wantErr: true},
+		{name: "above one", ratio: 1.1, wantErr: true},
+		{name: "zero", ratio: 0, check: func(n int) bool { return n == 0 }},
+		{name: "one", ratio: 1, check: func(n int) bool { return n == 1000 }},
+		{name: "quarter", ratio: 0.25, check: func(n int) bool { return n > 150 && n < 350 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := sampling.Probabilistic(tt.ratio, sampling.WithRandSource(rng.New(1)))
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "between 0 and 1") {
+					t.Errorf("Probabilistic(%v) error = %v, want a range error", tt.ratio, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sampled int
+			for _, ok := range decisions(t, s, 1000) {
+				if ok {
+					sampled++
+				}
+			}
+			if !tt.check(sampled) {
+				t.Errorf("Probabilistic(%v) sampled %d of 1000 calls", tt.ratio, sampled)
+			}
+		})
+	}
+}
+
+func TestRateLimitedErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		perSecond float64
+		burst     int
+		want      string
+	}{
+		{"zero rate", 0, 1, "rate must be positive"},
+		{"negative rate", -1, 1, "rate must be positive"},
+		{"zero burst", 1, 0, "burst must be at least 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := sampling.RateLimited(tt.perSecond, tt.burst)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("RateLimited error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	s, err := sampling.RateLimited(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The burst is available at once, then calls are sampled as tokens refill.
+	if got, want := decisions(t, s, 4), []bool{true, true, true, false}; !slices.Equal(got, want) {
+		t.Errorf("burst decisions = %v, want %v", got, want)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if !s.Sample(sampling.Params{}) {
+		t.Error("no call sampled after a token refilled")
+	}
+}
+
+func TestAlwaysOnError(t *testing.T) {
+	s := sampling.AlwaysOnError(sampling.Never())
+	tests := []struct {
+		name string
+		p    sampling.Params
+		want bool
+	}{
+		{"error", sampling.Params{Err: true}, true},
+		{"short circuit", sampling.Params{ShortCircuit: true}, true},
+		{"success defers to inner", sampling.Params{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Sample(tt.p); got != tt.want {
+				t.Errorf("Sample(%+v) = %v, want %v", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerFeature(t *testing.T) {
+	s := sampling.PerFeature(sampling.Never(), map[string]sampling.Sampler{
+		sampling.FeatureAccessLog: sampling.Always(),
+	})
+	tests := []struct {
+		feature string
+		want    bool
+	}{
+		{sampling.FeatureAccessLog, true},
+		{sampling.FeatureSlowLog, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := s.Sample(sampling.Params{Feature: tt.feature}); got != tt.want {
+			t.Errorf("Sample(%q) = %v, want %v", tt.feature, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package mcpdpluginsv1
+
+import (
+	"fmt"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/sandbox"
+)
+
+// WithSandbox hardens the plugin process with cfg once Serve has opened its listener and loaded
+// any standalone config file, and before it serves calls. Serve fails if a step cannot be
+// applied, so a plugin never runs with less isolation than requested. Sandboxing is Linux-only.
+func WithSandbox(cfg sandbox.Config) ServeOption {
+	return func(o *serveOptions) error {
+		if !cfg.Enabled() {
+			return fmt.Errorf("sandbox config enables no hardening step")
+		}
+		o.sandbox = &cfg
+		return nil
+	}
+}
@@ -0,0 +1,62 @@
+// Package sandbox lets a plugin reduce its own privileges once it is listening, limiting the
+// blast radius of a compromise. It is Linux-only; Apply fails elsewhere when anything is
+// requested.
+//
+// Serve applies a Config given with mcpdpluginsv1.WithSandbox after opening its listener:
+//
+//	err := mcpdpluginsv1.Serve(plugin, mcpdpluginsv1.WithSandbox(sandbox.Config{
+//	    DropGroups: true,
+//	    NoNewPrivs: true,
+//	    Seccomp:    true,
+//	}))
+//
+// Steps run in order: chroot, dropping supplementary groups, setting no-new-privs, and installing
+// a seccomp filter on every thread. The default seccomp allowlist covers the Go runtime,
+// networking and file access but not process execution, privilege changes, mounts, tracing or
+// kernel module and BPF loading; Allow extends it for plugins that need more. Syscalls outside
+// the allowlist fail with EPERM unless another action is configured.
+package sandbox
+
+// Seccomp actions for syscalls outside the allowlist.
+const (
+	// ActionErrno fails the syscall with EPERM.
+	ActionErrno = "errno"
+
+	// ActionKill kills the process.
+	ActionKill = "kill"
+
+	// ActionLog allows the syscall and logs it to the kernel audit log, for building allowlists.
+	ActionLog = "log"
+)
+
+// Config selects the hardening steps Apply performs.
+type Config struct {
+	// Chroot, when set, changes the root directory to this path (requires CAP_SYS_CHROOT).
+	// Paths used afterwards, such as a standalone config file or the unix socket Serve removes on
+	// exit, resolve inside it.
+	Chroot string
+
+	// DropGroups removes supplementary groups (requires CAP_SETGID unless there are none).
+	DropGroups bool
+
+	// NoNewPrivs prevents the process and its children from gaining privileges through setuid
+	// binaries or file capabilities. Seccomp implies it.
+	NoNewPrivs bool
+
+	// Seccomp installs a syscall allowlist filter.
+	Seccomp bool
+
+	// Syscalls replaces the default allowlist when non-empty.
+	Syscalls []string
+
+	// Allow adds syscalls to the allowlist.
+	Allow []string
+
+	// Action is the seccomp action for other syscalls: errno (default), kill or log.
+	Action string
+}
+
+// Enabled reports whether cfg requests any hardening step.
+func (cfg Config) Enabled() bool {
+	return cfg.Chroot != "" || cfg.DropGroups || cfg.NoNewPrivs || cfg.Seccomp
+}
@@ -0,0 +1,163 @@
+package sandbox
+
+import (
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultSyscalls returns the default seccomp allowlist for this architecture.
+func DefaultSyscalls() []string {
+	return slices.Clone(defaultSyscalls)
+}
+
+// Apply performs the hardening steps cfg selects. It stops at the first failing step.
+func Apply(cfg Config) error {
+	var filter []unix.SockFilter
+	if cfg.Seccomp {
+		// Build the filter first, so configuration errors leave the process untouched.
+		var err error
+		if filter, err = buildFilter(cfg); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Chroot != "" {
+		if err := syscall.Chroot(cfg.Chroot); err != nil {
+			return fmt.Errorf("failed to chroot to %s: %w", cfg.Chroot, err)
+		}
+		if err := syscall.Chdir("/"); err != nil {
+			return fmt.Errorf("failed to change directory after chroot: %w", err)
+		}
+	}
+
+	if cfg.DropGroups {
+		groups, err := syscall.Getgroups()
+		if err != nil {
+			return fmt.Errorf("failed to read supplementary groups: %w", err)
+		}
+		// syscall.Setgroups applies to every thread of the process.
+		if len(groups) > 0 {
+			if err := syscall.Setgroups(nil); err != nil {
+				return fmt.Errorf("failed to drop supplementary groups: %w", err)
+			}
+		}
+	}
+
+	switch {
+	case cfg.Seccomp:
+		return installFilter(filter)
+	case cfg.NoNewPrivs:
+		// no_new_privs is per thread.
+		_, _, errno := syscall.AllThreadsSyscall(syscall.SYS_PRCTL, unix.PR_SET_NO_NEW_PRIVS, 1, 0)
+		switch {
+		case errno == syscall.ENOTSUP:
+			// AllThreadsSyscall is unavailable in binaries using cgo. Synchronizing a filter that
+			// allows every syscall propagates no_new_privs instead.
+			return installFilter([]unix.SockFilter{stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ALLOW)})
+		case errno != 0:
+			return fmt.Errorf("failed to set no_new_privs: %w", errno)
+		}
+	}
+
+	return nil
+}
+
+// installFilter sets no_new_privs and installs filter on every thread. no_new_privs is per
+// thread; the TSYNC flag propagates it with the filter, and a locked thread keeps both calls on
+// the same one.
+func installFilter(filter []unix.SockFilter) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	r, _, errno := unix.Syscall(
+		unix.SYS_SECCOMP,
+		unix.SECCOMP_SET_MODE_FILTER,
+		unix.SECCOMP_FILTER_FLAG_TSYNC,
+		uintptr(unsafe.Pointer(&prog)),
+	)
+	switch {
+	case errno != 0:
+		return fmt.Errorf("failed to install seccomp filter: %w", errno)
+	case r != 0:
+		return fmt.Errorf("failed to install seccomp filter: thread %d could not be synchronized", r)
+	}
+
+	return nil
+}
+
+// buildFilter compiles the allowlist into a classic BPF program. Each allowed syscall is a
+// compare followed by an allow, so the program has no long jumps.
+func buildFilter(cfg Config) ([]unix.SockFilter, error) {
+	if len(syscallNumbers) == 0 {
+		return nil, fmt.Errorf("seccomp is not supported on %s", runtime.GOARCH)
+	}
+
+	var deny uint32
+	switch strings.ToLower(cfg.Action) {
+	case "", ActionErrno:
+		deny = unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM)
+	case ActionKill:
+		deny = unix.SECCOMP_RET_KILL_PROCESS
+	case ActionLog:
+		deny = unix.SECCOMP_RET_LOG
+	default:
+		return nil, fmt.Errorf("unknown seccomp action %q", cfg.Action)
+	}
+
+	names := cfg.Syscalls
+	if len(names) == 0 {
+		names = defaultSyscalls
+	}
+	numbers := map[uintptr]struct{}{}
+	for _, name := range slices.Concat(names, cfg.Allow) {
+		nr, ok := syscallNumbers[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("unknown syscall %q", name)
+		}
+		numbers[nr] = struct{}{}
+	}
+
+	const (
+		offsetNR   = 0
+		offsetArch = 4
+	)
+	filter := []unix.SockFilter{
+		// Kill on a foreign architecture, whose syscall numbers differ.
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, offsetArch),
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, auditArch, 1, 0),
+		stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_KILL_PROCESS),
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, offsetNR),
+	}
+	for _, nr := range slices.Sorted(maps.Keys(numbers)) {
+		filter = append(filter,
+			jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, uint32(nr), 0, 1),
+			stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ALLOW),
+		)
+	}
+	filter = append(filter, stmt(unix.BPF_RET|unix.BPF_K, deny))
+	if len(filter) > unix.BPF_MAXINSNS {
+		return nil, fmt.Errorf("seccomp allowlist too long")
+	}
+
+	return filter, nil
+}
+
+func stmt(code uint16, k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: code, K: k}
+}
+
+func jump(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+	return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
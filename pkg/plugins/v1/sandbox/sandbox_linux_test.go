@@ -0,0 +1,186 @@
+package sandbox_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/sandbox"
+)
+
+// helperEnv selects the sandboxing scenario TestHelperProcess runs in a child process, since
+// sandboxing cannot be undone.
+const helperEnv = "SANDBOX_TEST_HELPER"
+
+func TestHelperProcess(t *testing.T) {
+	scenario := os.Getenv(helperEnv)
+	if scenario == "" {
+		t.Skip("sandbox helper process")
+	}
+	if err := runScenario(scenario); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// runScenario applies the sandbox for scenario and checks its effect from inside the process.
+func runScenario(scenario string) error {
+	switch scenario {
+	case "seccomp":
+		if err := sandbox.Apply(sandbox.Config{Seccomp: true}); err != nil {
+			return err
+		}
+		if err := syscall.Exec("/bin/true", []string{"true"}, nil); !errors.Is(err, syscall.EPERM) {
+			return fmt.Errorf("execve error = %v, want EPERM", err)
+		}
+		if _, err := unix.PrctlRetInt(unix.PR_GET_NO_NEW_PRIVS, 0, 0, 0, 0); err != nil {
+			return fmt.Errorf("prctl after seccomp: %w", err)
+		}
+		// Allowed syscalls keep working, on new threads too.
+		done := make(chan error)
+		go func() {
+			runtime.LockOSThread()
+			_, err := os.ReadFile("/proc/self/status")
+			done <- err
+		}()
+		if err := <-done; err != nil {
+			return fmt.Errorf("reading a file: %w", err)
+		}
+	case "allow":
+		err := sandbox.Apply(sandbox.Config{Seccomp: true, Allow: []string{"execve"}})
+		if err != nil {
+			return err
+		}
+		if err := syscall.Exec("/bin/true", []string{"true"}, nil); err != nil {
+			return fmt.Errorf("execve error = %v, want it allowed", err)
+		}
+	case "no-new-privs":
+		if err := sandbox.Apply(sandbox.Config{NoNewPrivs: true}); err != nil {
+			return err
+		}
+		// Every thread has the flag, including ones started before Apply.
+		for range 4 {
+			done := make(chan int)
+			go func() {
+				runtime.LockOSThread()
+				v, _ := unix.PrctlRetInt(unix.PR_GET_NO_NEW_PRIVS, 0, 0, 0, 0)
+				done <- v
+			}()
+			if v := <-done; v != 1 {
+				return fmt.Errorf("no_new_privs = %d on a thread, want 1", v)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown scenario %q", scenario)
+	}
+
+	return nil
+}
+
+// runHelper runs scenario in a child test process and returns its combined output.
+func runHelper(t *testing.T, scenario string) (string, error) {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(), helperEnv+"="+scenario)
+	out, err := cmd.CombinedOutput()
+
+	return string(out), err
+}
+
+func TestApply(t *testing.T) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		t.Skipf("seccomp is not supported on %s", runtime.GOARCH)
+	}
+	// Probe for seccomp support, which container runtimes may withhold.
+	if out, err := runHelper(t, "no-new-privs"); err != nil {
+		t.Skipf("sandboxing unavailable: %v: %s", err, out)
+	}
+	scenarios := []string{"seccomp", "allow", "no-new-privs"}
+	for _, scenario := range scenarios {
+		t.Run(scenario, func(t *testing.T) {
+			if out, err := runHelper(t, scenario); err != nil {
+				t.Errorf("helper failed: %v: %s", err, out)
+			}
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		t.Skipf("seccomp is not supported on %s", runtime.GOARCH)
+	}
+	// These fail while building the filter, before anything is applied to the test process.
+	tests := []struct {
+		name string
+		cfg  sandbox.Config
+		want string
+	}{
+		{
+			name: "unknown action",
+			cfg:  sandbox.Config{Seccomp: true, Action: "trap"},
+			want: `unknown seccomp action "trap"`,
+		},
+		{
+			name: "unknown syscall",
+			cfg:  sandbox.Config{Seccomp: true, Allow: []string{"frobnicate"}},
+			want: `unknown syscall "frobnicate"`,
+		},
+		{
+			name: "unknown syscall in a replacement list",
+			cfg:  sandbox.Config{Seccomp: true, Syscalls: []string{"read", ""}, NoNewPrivs: true},
+			want: `unknown syscall ""`,
+		},
+		{
+			name: "filter checked before chroot",
+			cfg:  sandbox.Config{Chroot: "/nonexistent", Seccomp: true, Action: "bogus"},
+			want: "unknown seccomp action",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sandbox.Apply(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Apply error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyChrootError(t *testing.T) {
+	err := sandbox.Apply(sandbox.Config{Chroot: "/nonexistent/sandbox"})
+	if err == nil || !strings.Contains(err.Error(), "failed to chroot to /nonexistent/sandbox") {
+		t.Errorf("Apply error = %v, want a chroot failure", err)
+	}
+}
+
+func TestDefaultSyscalls(t *testing.T) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		t.Skipf("seccomp is not supported on %s", runtime.GOARCH)
+	}
+	got := sandbox.DefaultSyscalls()
+	for _, name := range []string{"read", "write", "futex", "epoll_pwait", "accept4", "exit_group"} {
+		if !slices.Contains(got, name) {
+			t.Errorf("DefaultSyscalls is missing %s", name)
+		}
+	}
+	for _, name := range []string{"execve", "ptrace", "mount", "setuid", "init_module", "bpf", "chroot"} {
+		if slices.Contains(got, name) {
+			t.Errorf("DefaultSyscalls allows %s", name)
+		}
+	}
+
+	got[0] = "changed"
+	if sandbox.DefaultSyscalls()[0] == "changed" {
+		t.Error("DefaultSyscalls returned the shared slice")
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package sandbox
+
+import "fmt"
+
+// Apply fails when cfg requests any hardening step, which is only supported on Linux.
+func Apply(cfg Config) error {
+	if cfg.Enabled() {
+		return fmt.Errorf("sandboxing is only supported on Linux")
+	}
+
+	return nil
+}
+
+// DefaultSyscalls returns nil, as seccomp is only supported on Linux.
+func DefaultSyscalls() []string {
+	return nil
+}
@@ -0,0 +1,35 @@
+package sandbox_test
+
+import (
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/sandbox"
+)
+
+func TestEnabled(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  sandbox.Config
+		want bool
+	}{
+		{name: "empty"},
+		{name: "allowlist alone", cfg: sandbox.Config{Allow: []string{"ptrace"}, Action: sandbox.ActionKill}},
+		{name: "chroot", cfg: sandbox.Config{Chroot: "/var/empty"}, want: true},
+		{name: "drop groups", cfg: sandbox.Config{DropGroups: true}, want: true},
+		{name: "no new privs", cfg: sandbox.Config{NoNewPrivs: true}, want: true},
+		{name: "seccomp", cfg: sandbox.Config{Seccomp: true}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.Enabled(); got != tt.want {
+				t.Errorf("Enabled = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyNothing(t *testing.T) {
+	if err := sandbox.Apply(sandbox.Config{Allow: []string{"not-a-syscall"}}); err != nil {
+		t.Errorf("Apply of a config without steps = %v, want nil", err)
+	}
+}
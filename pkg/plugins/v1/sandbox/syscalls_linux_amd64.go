@@ -0,0 +1,256 @@
+package sandbox
+
+import "golang.org/x/sys/unix"
+
+// auditArch identifies the architecture in seccomp filters.
+const auditArch = unix.AUDIT_ARCH_X86_64
+
+// syscallNumbers maps the syscall names Config accepts to their numbers.
+var syscallNumbers = map[string]uintptr{
+	"accept":             unix.SYS_ACCEPT,
+	"accept4":            unix.SYS_ACCEPT4,
+	"access":             unix.SYS_ACCESS,
+	"acct":               unix.SYS_ACCT,
+	"add_key":            unix.SYS_ADD_KEY,
+	"arch_prctl":         unix.SYS_ARCH_PRCTL,
+	"bind":               unix.SYS_BIND,
+	"bpf":                unix.SYS_BPF,
+	"brk":                unix.SYS_BRK,
+	"capget":             unix.SYS_CAPGET,
+	"capset":             unix.SYS_CAPSET,
+	"chdir":              unix.SYS_CHDIR,
+	"chmod":              unix.SYS_CHMOD,
+	"chroot":             unix.SYS_CHROOT,
+	"clock_getres":       unix.SYS_CLOCK_GETRES,
+	"clock_gettime":      unix.SYS_CLOCK_GETTIME,
+	"clock_nanosleep":    unix.SYS_CLOCK_NANOSLEEP,
+	"clone":              unix.SYS_CLONE,
+	"clone3":             unix.SYS_CLONE3,
+	"close":              unix.SYS_CLOSE,
+	"close_range":        unix.SYS_CLOSE_RANGE,
+	"connect":            unix.SYS_CONNECT,
+	"copy_file_range":    unix.SYS_COPY_FILE_RANGE,
+	"delete_module":      unix.SYS_DELETE_MODULE,
+	"dup":                unix.SYS_DUP,
+	"dup2":               unix.SYS_DUP2,
+	"dup3":               unix.SYS_DUP3,
+	"epoll_create":       unix.SYS_EPOLL_CREATE,
+	"epoll_create1":      unix.SYS_EPOLL_CREATE1,
+	"epoll_ctl":          unix.SYS_EPOLL_CTL,
+	"epoll_pwait":        unix.SYS_EPOLL_PWAIT,
+	"epoll_pwait2":       unix.SYS_EPOLL_PWAIT2,
+	"epoll_wait":         unix.SYS_EPOLL_WAIT,
+	"eventfd":            unix.SYS_EVENTFD,
+	"eventfd2":           unix.SYS_EVENTFD2,
+	"execve":             unix.SYS_EXECVE,
+	"execveat":           unix.SYS_EXECVEAT,
+	"exit":               unix.SYS_EXIT,
+	"exit_group":         unix.SYS_EXIT_GROUP,
+	"faccessat":          unix.SYS_FACCESSAT,
+	"faccessat2":         unix.SYS_FACCESSAT2,
+	"fallocate":          unix.SYS_FALLOCATE,
+	"fchdir":             unix.SYS_FCHDIR,
+	"fchmod":             unix.SYS_FCHMOD,
+	"fchmodat":           unix.SYS_FCHMODAT,
+	"fchown":             unix.SYS_FCHOWN,
+	"fchownat":           unix.SYS_FCHOWNAT,
+	"fcntl":              unix.SYS_FCNTL,
+	"fdatasync":          unix.SYS_FDATASYNC,
+	"finit_module":       unix.SYS_FINIT_MODULE,
+	"flock":              unix.SYS_FLOCK,
+	"fork":               unix.SYS_FORK,
+	"fstat":              unix.SYS_FSTAT,
+	"fstatfs":            unix.SYS_FSTATFS,
+	"fsync":              unix.SYS_FSYNC,
+	"ftruncate":          unix.SYS_FTRUNCATE,
+	"futex":              unix.SYS_FUTEX,
+	"get_robust_list":    unix.SYS_GET_ROBUST_LIST,
+	"getcwd":             unix.SYS_GETCWD,
+	"getdents":           unix.SYS_GETDENTS,
+	"getdents64":         unix.SYS_GETDENTS64,
+	"getegid":            unix.SYS_GETEGID,
+	"geteuid":            unix.SYS_GETEUID,
+	"getgid":             unix.SYS_GETGID,
+	"getgroups":          unix.SYS_GETGROUPS,
+	"getpeername":        unix.SYS_GETPEERNAME,
+	"getpgrp":            unix.SYS_GETPGRP,
+	"getpid":             unix.SYS_GETPID,
+	"getppid":            unix.SYS_GETPPID,
+	"getpriority":        unix.SYS_GETPRIORITY,
+	"getrandom":          unix.SYS_GETRANDOM,
+	"getrlimit":          unix.SYS_GETRLIMIT,
+	"getrusage":          unix.SYS_GETRUSAGE,
+	"getsockname":        unix.SYS_GETSOCKNAME,
+	"getsockopt":         unix.SYS_GETSOCKOPT,
+	"gettid":             unix.SYS_GETTID,
+	"gettimeofday":       unix.SYS_GETTIMEOFDAY,
+	"getuid":             unix.SYS_GETUID,
+	"init_module":        unix.SYS_INIT_MODULE,
+	"inotify_add_watch":  unix.SYS_INOTIFY_ADD_WATCH,
+	"inotify_init":       unix.SYS_INOTIFY_INIT,
+	"inotify_init1":      unix.SYS_INOTIFY_INIT1,
+	"inotify_rm_watch":   unix.SYS_INOTIFY_RM_WATCH,
+	"io_uring_enter":     unix.SYS_IO_URING_ENTER,
+	"io_uring_register":  unix.SYS_IO_URING_REGISTER,
+	"io_uring_setup":     unix.SYS_IO_URING_SETUP,
+	"ioctl":              unix.SYS_IOCTL,
+	"ioperm":             unix.SYS_IOPERM,
+	"iopl":               unix.SYS_IOPL,
+	"kexec_load":         unix.SYS_KEXEC_LOAD,
+	"keyctl":             unix.SYS_KEYCTL,
+	"kill":               unix.SYS_KILL,
+	"link":               unix.SYS_LINK,
+	"linkat":             unix.SYS_LINKAT,
+	"listen":             unix.SYS_LISTEN,
+	"lseek":              unix.SYS_LSEEK,
+	"lstat":              unix.SYS_LSTAT,
+	"madvise":            unix.SYS_MADVISE,
+	"membarrier":         unix.SYS_MEMBARRIER,
+	"memfd_create":       unix.SYS_MEMFD_CREATE,
+	"mincore":            unix.SYS_MINCORE,
+	"mkdir":              unix.SYS_MKDIR,
+	"mkdirat":            unix.SYS_MKDIRAT,
+	"mknod":              unix.SYS_MKNOD,
+	"mknodat":            unix.SYS_MKNODAT,
+	"mlock":              unix.SYS_MLOCK,
+	"mlockall":           unix.SYS_MLOCKALL,
+	"mmap":               unix.SYS_MMAP,
+	"mount":              unix.SYS_MOUNT,
+	"mprotect":           unix.SYS_MPROTECT,
+	"mremap":             unix.SYS_MREMAP,
+	"msync":              unix.SYS_MSYNC,
+	"munlock":            unix.SYS_MUNLOCK,
+	"munlockall":         unix.SYS_MUNLOCKALL,
+	"munmap":             unix.SYS_MUNMAP,
+	"nanosleep":          unix.SYS_NANOSLEEP,
+	"newfstatat":         unix.SYS_NEWFSTATAT,
+	"open":               unix.SYS_OPEN,
+	"openat":             unix.SYS_OPENAT,
+	"openat2":            unix.SYS_OPENAT2,
+	"perf_event_open":    unix.SYS_PERF_EVENT_OPEN,
+	"pidfd_open":         unix.SYS_PIDFD_OPEN,
+	"pidfd_send_signal":  unix.SYS_PIDFD_SEND_SIGNAL,
+	"pipe":               unix.SYS_PIPE,
+	"pipe2":              unix.SYS_PIPE2,
+	"pivot_root":         unix.SYS_PIVOT_ROOT,
+	"poll":               unix.SYS_POLL,
+	"ppoll":              unix.SYS_PPOLL,
+	"prctl":              unix.SYS_PRCTL,
+	"pread64":            unix.SYS_PREAD64,
+	"preadv":             unix.SYS_PREADV,
+	"prlimit64":          unix.SYS_PRLIMIT64,
+	"process_vm_readv":   unix.SYS_PROCESS_VM_READV,
+	"process_vm_writev":  unix.SYS_PROCESS_VM_WRITEV,
+	"pselect6":           unix.SYS_PSELECT6,
+	"ptrace":             unix.SYS_PTRACE,
+	"pwrite64":           unix.SYS_PWRITE64,
+	"pwritev":            unix.SYS_PWRITEV,
+	"read":               unix.SYS_READ,
+	"readlink":           unix.SYS_READLINK,
+	"readlinkat":         unix.SYS_READLINKAT,
+	"readv":              unix.SYS_READV,
+	"reboot":             unix.SYS_REBOOT,
+	"recvfrom":           unix.SYS_RECVFROM,
+	"recvmmsg":           unix.SYS_RECVMMSG,
+	"recvmsg":            unix.SYS_RECVMSG,
+	"rename":             unix.SYS_RENAME,
+	"renameat":           unix.SYS_RENAMEAT,
+	"renameat2":          unix.SYS_RENAMEAT2,
+	"request_key":        unix.SYS_REQUEST_KEY,
+	"restart_syscall":    unix.SYS_RESTART_SYSCALL,
+	"rmdir":              unix.SYS_RMDIR,
+	"rseq":               unix.SYS_RSEQ,
+	"rt_sigaction":       unix.SYS_RT_SIGACTION,
+	"rt_sigprocmask":     unix.SYS_RT_SIGPROCMASK,
+	"rt_sigqueueinfo":    unix.SYS_RT_SIGQUEUEINFO,
+	"rt_sigreturn":       unix.SYS_RT_SIGRETURN,
+	"rt_sigtimedwait":    unix.SYS_RT_SIGTIMEDWAIT,
+	"sched_getaffinity":  unix.SYS_SCHED_GETAFFINITY,
+	"sched_getparam":     unix.SYS_SCHED_GETPARAM,
+	"sched_getscheduler": unix.SYS_SCHED_GETSCHEDULER,
+	"sched_setaffinity":  unix.SYS_SCHED_SETAFFINITY,
+	"sched_setparam":     unix.SYS_SCHED_SETPARAM,
+	"sched_setscheduler": unix.SYS_SCHED_SETSCHEDULER,
+	"sched_yield":        unix.SYS_SCHED_YIELD,
+	"seccomp":            unix.SYS_SECCOMP,
+	"select":             unix.SYS_SELECT,
+	"sendfile":           unix.SYS_SENDFILE,
+	"sendmmsg":           unix.SYS_SENDMMSG,
+	"sendmsg":            unix.SYS_SENDMSG,
+	"sendto":             unix.SYS_SENDTO,
+	"set_robust_list":    unix.SYS_SET_ROBUST_LIST,
+	"set_tid_address":    unix.SYS_SET_TID_ADDRESS,
+	"setdomainname":      unix.SYS_SETDOMAINNAME,
+	"setgid":             unix.SYS_SETGID,
+	"setgroups":          unix.SYS_SETGROUPS,
+	"sethostname":        unix.SYS_SETHOSTNAME,
+	"setns":              unix.SYS_SETNS,
+	"setpriority":        unix.SYS_SETPRIORITY,
+	"setregid":           unix.SYS_SETREGID,
+	"setresgid":          unix.SYS_SETRESGID,
+	"setresuid":          unix.SYS_SETRESUID,
+	"setreuid":           unix.SYS_SETREUID,
+	"setsockopt":         unix.SYS_SETSOCKOPT,
+	"setuid":             unix.SYS_SETUID,
+	"shmat":              unix.SYS_SHMAT,
+	"shmctl":             unix.SYS_SHMCTL,
+	"shmdt":              unix.SYS_SHMDT,
+	"shmget":             unix.SYS_SHMGET,
+	"shutdown":           unix.SYS_SHUTDOWN,
+	"sigaltstack":        unix.SYS_SIGALTSTACK,
+	"socket":             unix.SYS_SOCKET,
+	"socketpair":         unix.SYS_SOCKETPAIR,
+	"splice":             unix.SYS_SPLICE,
+	"stat":               unix.SYS_STAT,
+	"statfs":             unix.SYS_STATFS,
+	"statx":              unix.SYS_STATX,
+	"swapoff":            unix.SYS_SWAPOFF,
+	"swapon":             unix.SYS_SWAPON,
+	"symlink":            unix.SYS_SYMLINK,
+	"symlinkat":          unix.SYS_SYMLINKAT,
+	"sysinfo":            unix.SYS_SYSINFO,
+	"tgkill":             unix.SYS_TGKILL,
+	"time":               unix.SYS_TIME,
+	"timer_create":       unix.SYS_TIMER_CREATE,
+	"timer_delete":       unix.SYS_TIMER_DELETE,
+	"timer_gettime":      unix.SYS_TIMER_GETTIME,
+	"timer_settime":      unix.SYS_TIMER_SETTIME,
+	"tkill":              unix.SYS_TKILL,
+	"truncate":           unix.SYS_TRUNCATE,
+	"umask":              unix.SYS_UMASK,
+	"umount2":            unix.SYS_UMOUNT2,
+	"uname":              unix.SYS_UNAME,
+	"unlink":             unix.SYS_UNLINK,
+	"unlinkat":           unix.SYS_UNLINKAT,
+	"unshare":            unix.SYS_UNSHARE,
+	"userfaultfd":        unix.SYS_USERFAULTFD,
+	"utimensat":          unix.SYS_UTIMENSAT,
+	"vfork":              unix.SYS_VFORK,
+	"wait4":              unix.SYS_WAIT4,
+	"waitid":             unix.SYS_WAITID,
+	"write":              unix.SYS_WRITE,
+	"writev":             unix.SYS_WRITEV,
+}
+
+// defaultSyscalls is the allowlist covering the Go runtime, networking and file access.
+var defaultSyscalls = []string{
+	"read", "write", "close", "fstat", "lseek", "mmap", "mprotect", "munmap", "brk", "rt_sigaction",
+	"rt_sigprocmask", "rt_sigreturn", "ioctl", "pread64", "pwrite64", "readv", "writev", "preadv", "pwritev",
+	"access", "faccessat", "faccessat2", "pipe", "pipe2", "select", "pselect6", "poll", "ppoll", "sched_yield",
+	"sched_getaffinity", "mremap", "msync", "mincore", "madvise", "dup", "dup2", "dup3", "nanosleep",
+	"clock_nanosleep", "getpid", "getppid", "gettid", "getuid", "getgid", "geteuid", "getegid", "getpgrp",
+	"getgroups", "getrlimit", "prlimit64", "getrusage", "sysinfo", "uname", "socket", "socketpair", "connect",
+	"accept", "accept4", "sendto", "recvfrom", "sendmsg", "recvmsg", "sendmmsg", "recvmmsg", "shutdown", "bind",
+	"listen", "getsockname", "getpeername", "setsockopt", "getsockopt", "clone", "clone3", "exit", "exit_group",
+	"wait4", "waitid", "kill", "tkill", "tgkill", "sigaltstack", "restart_syscall", "fcntl", "flock", "fsync",
+	"fdatasync", "truncate", "ftruncate", "fallocate", "getdents", "getdents64", "getcwd", "chdir", "fchdir",
+	"rename", "renameat", "renameat2", "mkdir", "mkdirat", "rmdir", "unlink", "unlinkat", "readlink",
+	"readlinkat", "symlink", "symlinkat", "link", "linkat", "chmod", "fchmod", "fchmodat", "fchown", "fchownat",
+	"umask", "utimensat", "open", "openat", "openat2", "stat", "lstat", "newfstatat", "statx", "statfs",
+	"fstatfs", "arch_prctl", "prctl", "futex", "set_tid_address", "set_robust_list", "get_robust_list", "rseq",
+	"membarrier", "gettimeofday", "clock_gettime", "clock_getres", "time", "epoll_create", "epoll_create1",
+	"epoll_ctl", "epoll_wait", "epoll_pwait", "epoll_pwait2", "eventfd", "eventfd2", "inotify_init",
+	"inotify_init1", "inotify_add_watch", "inotify_rm_watch", "getrandom", "timer_create", "timer_settime",
+	"timer_gettime", "timer_delete", "rt_sigtimedwait", "rt_sigqueueinfo", "close_range", "pidfd_open",
+	"pidfd_send_signal", "splice", "sendfile", "copy_file_range",
+}
@@ -0,0 +1,12 @@
+//go:build linux && !amd64 && !arm64
+
+package sandbox
+
+// auditArch is unset on architectures without seccomp support in this package.
+const auditArch = 0
+
+// syscallNumbers is empty on architectures without seccomp support in this package.
+var syscallNumbers = map[string]uintptr{}
+
+// defaultSyscalls is empty on architectures without seccomp support in this package.
+var defaultSyscalls []string
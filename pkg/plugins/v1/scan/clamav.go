@@ -0,0 +1,123 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ClamAVConfig configures a ClamAV scanner.
+type ClamAVConfig struct {
+	// Network is "tcp" (default) or "unix".
+	Network string
+
+	// Addr is clamd's "host:port" or socket path.
+	Addr string
+
+	// Timeout bounds each scan, including connection setup (default 30s).
+	Timeout time.Duration
+
+	// ChunkSize is the size of INSTREAM chunks (default 64 KiB). It must not exceed clamd's
+	// StreamMaxLength.
+	ChunkSize int
+}
+
+// ClamAV scans content with clamd.
+type ClamAV struct {
+	cfg ClamAVConfig
+}
+
+// NewClamAV returns a ClamAV scanner for cfg. Connections are opened per scan.
+func NewClamAV(cfg ClamAVConfig) (*ClamAV, error) {
+	if cfg.Addr == "" {
+		return nil, fmt.Errorf("clamd address is required")
+	}
+	if cfg.Network == "" {
+		cfg.Network = "tcp"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = 64 << 10
+	}
+
+	return &ClamAV{cfg: cfg}, nil
+}
+
+// Scan implements Scanner, streaming r to clamd with the INSTREAM command.
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) (Verdict, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.cfg.Network, c.cfg.Addr)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to connect to clamd at %s: %w", c.cfg.Addr, err)
+	}
+	defer func() { _ = conn.Close() }()
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	w := bufio.NewWriterSize(conn, c.cfg.ChunkSize+4)
+	if _, err := w.WriteString("zINSTREAM\x00"); err != nil {
+		return Verdict{}, fmt.Errorf("clamd write failed: %w", err)
+	}
+	buf := make([]byte, c.cfg.ChunkSize)
+	var size [4]byte
+	for {
+		n, rerr := io.ReadFull(r, buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			_, _ = w.Write(size[:])
+			if _, err := w.Write(buf[:n]); err != nil {
+				return Verdict{}, fmt.Errorf("clamd write failed: %w", err)
+			}
+		}
+		if errors.Is(rerr, io.EOF) || errors.Is(rerr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if rerr != nil {
+			return Verdict{}, fmt.Errorf("failed to read content: %w", rerr)
+		}
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	_, _ = w.Write(size[:])
+	if err := w.Flush(); err != nil {
+		return Verdict{}, fmt.Errorf("clamd write failed: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return Verdict{}, fmt.Errorf("clamd read failed: %w", err)
+	}
+
+	return parseClamdReply(reply)
+}
+
+// parseClamdReply interprets "stream: OK", "stream: <threat> FOUND" and "<message> ERROR".
+func parseClamdReply(reply string) (Verdict, error) {
+	reply = strings.TrimSpace(string(bytes.TrimRight([]byte(reply), "\x00")))
+	_, result, ok := strings.Cut(reply, ": ")
+	if !ok {
+		result = reply
+	}
+
+	switch {
+	case result == "OK":
+		return Verdict{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return Verdict{Infected: true, Threat: strings.TrimSuffix(result, " FOUND")}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return Verdict{}, fmt.Errorf("clamd error: %s", strings.TrimSuffix(reply, " ERROR"))
+	default:
+		return Verdict{}, fmt.Errorf("unexpected clamd reply %q", reply)
+	}
+}
@@ -0,0 +1,260 @@
+package scan_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/scan"
+)
+
+// eicar is the EICAR antivirus test file.
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd speaks clamd's INSTREAM protocol, answering each stream with reply(data).
+type fakeClamd struct {
+	ln    net.Listener
+	reply func(data []byte) string
+
+	mu     sync.Mutex
+	chunks []int // Sizes of the chunks received, across streams.
+	data   [][]byte
+}
+
+func newFakeClamd(t *testing.T, network, addr string, reply func(data []byte) string) *fakeClamd {
+	t.Helper()
+
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeClamd{ln: ln, reply: reply}
+	t.Cleanup(func() { _ = ln.Close() })
+	go f.serve()
+
+	return f
+}
+
+func (f *fakeClamd) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeClamd) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	r := bufio.NewReader(conn)
+	cmd, err := r.ReadString(0)
+	if err != nil || cmd != "zINSTREAM\x00" {
+		_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+	var data []byte
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint32(size[:])
+		if n == 0 {
+			break
+		}
+		chunk := make([]byte, n)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.chunks = append(f.chunks, int(n))
+		f.mu.Unlock()
+		data = append(data, chunk...)
+	}
+	f.mu.Lock()
+	f.data = append(f.data, data)
+	f.mu.Unlock()
+	_, _ = conn.Write([]byte(f.reply(data) + "\x00"))
+}
+
+func (f *fakeClamd) received() ([]int, [][]byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]int(nil), f.chunks...), append([][]byte(nil), f.data...)
+}
+
+// clamdReply answers like clamd with the EICAR signature.
+func clamdReply(data []byte) string {
+	if bytes.Contains(data, []byte("EICAR-STANDARD-ANTIVIRUS-TEST-FILE")) {
+		return "stream: Win.Test.EICAR_HDB-1 FOUND"
+	}
+
+	return "stream: OK"
+}
+
+func TestNewClamAVErrors(t *testing.T) {
+	_, err := scan.NewClamAV(scan.ClamAVConfig{})
+	if err == nil || !strings.Contains(err.Error(), "address is required") {
+		t.Errorf("NewClamAV error = %v, want a missing address", err)
+	}
+}
+
+func TestClamAV(t *testing.T) {
+	clamd := newFakeClamd(t, "tcp", "127.0.0.1:0", clamdReply)
+	c, err := scan.NewClamAV(scan.ClamAVConfig{Addr: clamd.ln.Addr().String(), ChunkSize: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want scan.Verdict
+	}{
+		{name: "clean", data: "hello world", want: scan.Verdict{}},
+		{name: "infected", data: eicar, want: scan.Verdict{Infected: true, Threat: "Win.Test.EICAR_HDB-1"}},
+		{name: "empty", data: "", want: scan.Verdict{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := c.Scan(context.Background(), strings.NewReader(tt.data))
+			if err != nil || v != tt.want {
+				t.Errorf("Scan = %+v, %v; want %+v", v, err, tt.want)
+			}
+		})
+	}
+
+	chunks, data := clamd.received()
+	for _, n := range chunks {
+		if n > 16 {
+			t.Errorf("chunk of %d bytes, want at most the configured 16", n)
+		}
+	}
+	if len(data) != 3 || string(data[1]) != eicar {
+		t.Errorf("clamd received %q, want the scanned contents", data)
+	}
+}
+
+func TestClamAVUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "clamd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "clamd.sock")
+	newFakeClamd(t, "unix", sock, clamdReply)
+
+	s, err := scan.NewScanner(scan.Config{Backend: "ClamAV", ClamAVAddr: sock, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := s.Scan(context.Background(), strings.NewReader(eicar))
+	if err != nil || !v.Infected {
+		t.Errorf("Scan over a unix socket = %+v, %v; want infected", v, err)
+	}
+}
+
+func TestClamAVReplies(t *testing.T) {
+	tests := []struct {
+		reply   string
+		want    scan.Verdict
+		wantErr string
+	}{
+		{reply: "stream: OK", want: scan.Verdict{}},
+		{reply: "stream: Eicar-Signature FOUND\n", want: scan.Verdict{Infected: true, Threat: "Eicar-Signature"}},
+		{reply: "INSTREAM size limit exceeded. ERROR", wantErr: "clamd error: INSTREAM size limit exceeded."},
+		{reply: "stream: lstat() failed ERROR", wantErr: "clamd error: stream: lstat() failed"},
+		{reply: "PONG", wantErr: `unexpected clamd reply "PONG"`},
+		{reply: "", wantErr: "unexpected clamd reply"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reply, func(t *testing.T) {
+			clamd := newFakeClamd(t, "tcp", "127.0.0.1:0", func([]byte) string { return tt.reply })
+			c, err := scan.NewClamAV(scan.ClamAVConfig{Addr: clamd.ln.Addr().String()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := c.Scan(context.Background(), strings.NewReader("data"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Scan = %+v, %v; want error %q", v, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || v != tt.want {
+				t.Errorf("Scan = %+v, %v; want %+v", v, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClamAVErrors(t *testing.T) {
+	t.Run("unreachable", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := ln.Addr().String()
+		_ = ln.Close()
+		c, _ := scan.NewClamAV(scan.ClamAVConfig{Addr: addr})
+		if _, err := c.Scan(context.Background(), strings.NewReader("x")); err == nil ||
+			!strings.Contains(err.Error(), "failed to connect to clamd at "+addr) {
+			t.Errorf("Scan error = %v, want a connection failure", err)
+		}
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		clamd := newFakeClamd(t, "tcp", "127.0.0.1:0", clamdReply)
+		c, _ := scan.NewClamAV(scan.ClamAVConfig{Addr: clamd.ln.Addr().String()})
+		boom := errors.New("boom")
+		r := io.MultiReader(strings.NewReader("partial"), iotestErrReader{boom})
+		if _, err := c.Scan(context.Background(), r); !errors.Is(err, boom) ||
+			!strings.Contains(err.Error(), "failed to read content") {
+			t.Errorf("Scan error = %v, want the read error", err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = ln.Close() })
+		// Accept connections but never answer.
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				t.Cleanup(func() { _ = conn.Close() })
+			}
+		}()
+		c, _ := scan.NewClamAV(scan.ClamAVConfig{Addr: ln.Addr().String(), Timeout: 50 * time.Millisecond})
+		start := time.Now()
+		if _, err := c.Scan(context.Background(), strings.NewReader("x")); err == nil {
+			t.Error("Scan succeeded against a silent clamd")
+		}
+		if d := time.Since(start); d > 5*time.Second {
+			t.Errorf("Scan took %s, want the 50ms timeout", d)
+		}
+	})
+}
+
+// iotestErrReader fails every read with err.
+type iotestErrReader struct{ err error }
+
+func (r iotestErrReader) Read([]byte) (int, error) { return 0, r.err }
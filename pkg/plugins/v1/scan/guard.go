@@ -0,0 +1,284 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/payload"
+)
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// Actions taken on infected content.
+const (
+	// ActionBlock replaces responses carrying infected blobs with a JSON-RPC error.
+	ActionBlock = "block"
+
+	// ActionLog only reports findings.
+	ActionLog = "log"
+)
+
+// Scanner backends selectable in Config.
+const (
+	BackendClamAV = "clamav"
+	BackendICAP   = "icap"
+)
+
+// Config configures a Guard and, for Plugin, the scanner.
+type Config struct {
+	// Action is what a Guard does with infected content: block or log.
+	Action string `config:"action" default:"block"`
+
+	// MaxBlobSize is the largest decoded blob scanned, in bytes. Larger blobs cannot be scanned
+	// and are treated like scanner failures.
+	MaxBlobSize int `config:"max_blob_size" default:"26214400"`
+
+	// Timeout bounds the scans of one response.
+	Timeout time.Duration `config:"timeout" default:"30s"`
+
+	// FailOpen lets responses through when their blobs cannot be scanned. By default they are
+	// blocked.
+	FailOpen bool `config:"fail_open" default:"false"`
+
+	// Backend selects the scanner Plugin uses: clamav or icap.
+	Backend string `config:"backend" default:"clamav"`
+
+	// ClamAVAddr is clamd's "host:port", or a socket path when it starts with "/".
+	ClamAVAddr string `config:"clamav_addr" default:"localhost:3310"`
+
+	// ICAPURL is the ICAP service URL.
+	ICAPURL string `config:"icap_url" default:"icap://localhost:1344/avscan"`
+}
+
+// DefaultConfig returns the default configuration.
+func DefaultConfig() Config {
+	var cfg Config
+	if _, err := config.Decode(nil, &cfg); err != nil {
+		panic(fmt.Sprintf("scan: invalid defaults: %v", err))
+	}
+
+	return cfg
+}
+
+// NewScanner returns the scanner selected by cfg.Backend.
+func NewScanner(cfg Config) (Scanner, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case BackendClamAV:
+		network := "tcp"
+		if strings.HasPrefix(cfg.ClamAVAddr, "/") {
+			network = "unix"
+		}
+		return NewClamAV(ClamAVConfig{Network: network, Addr: cfg.ClamAVAddr, Timeout: cfg.Timeout})
+	case BackendICAP:
+		return NewICAP(ICAPConfig{URL: cfg.ICAPURL, Timeout: cfg.Timeout})
+	default:
+		return nil, fmt.Errorf("unknown scanner backend %q", cfg.Backend)
+	}
+}
+
+// Finding describes an infected blob seen by a Guard.
+type Finding struct {
+	// URI is the resource URI, empty for image and audio content blocks.
+	URI string
+
+	// MIMEType is the declared MIME type, and SniffedMIMEType the one detected from content.
+	MIMEType        string
+	SniffedMIMEType string
+
+	Size   int
+	Threat string
+}
+
+// GuardOption configures a Guard.
+type GuardOption func(*Guard)
+
+// WithFindingHandler calls fn for every infected blob. fn must not block.
+func WithFindingHandler(fn func(ctx context.Context, f Finding)) GuardOption {
+	return func(g *Guard) {
+		g.onFinding = fn
+	}
+}
+
+// Guard scans the blobs of MCP responses. It is safe for concurrent use.
+type Guard struct {
+	scanner   Scanner
+	cfg       Config
+	onFinding func(ctx context.Context, f Finding)
+}
+
+// NewGuard returns a Guard scanning with s.
+func NewGuard(s Scanner, cfg Config, opts ...GuardOption) (*Guard, error) {
+	if s == nil {
+		return nil, fmt.Errorf("scanner is required")
+	}
+	cfg.Action = strings.ToLower(cfg.Action)
+	switch cfg.Action {
+	case ActionBlock, ActionLog:
+	default:
+		return nil, fmt.Errorf("unknown scan action %q", cfg.Action)
+	}
+
+	g := &Guard{
+		scanner: s,
+		cfg:     cfg,
+		onFinding: func(_ context.Context, f Finding) {
+			log.Printf("scan: infected blob %q (%s, %d bytes): %s", f.URI, f.MIMEType, f.Size, f.Threat)
+		},
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g, nil
+}
+
+// Scan scans the blobs carried by the MCP message(s) in body and returns the infected ones.
+// Bodies that are not MCP messages carry no blobs. It fails when a blob is too large, is not
+// valid base64, or the scanner fails.
+func (g *Guard) Scan(ctx context.Context, body []byte) ([]Finding, error) {
+	if payload.Classify("", body) != payload.JSON {
+		return nil, nil
+	}
+	blobs, err := mcp.Blobs(body, g.cfg.MaxBlobSize)
+	switch {
+	case errors.Is(err, mcp.ErrNotJSONRPC):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case len(blobs) == 0:
+		return nil, nil
+	}
+
+	if g.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.cfg.Timeout)
+		defer cancel()
+	}
+
+	var findings []Finding
+	for _, b := range blobs {
+		v, err := g.scanner.Scan(ctx, bytes.NewReader(b.Data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan blob: %w", err)
+		}
+		if !v.Infected {
+			continue
+		}
+		f := Finding{
+			URI:             b.URI,
+			MIMEType:        b.MIMEType,
+			SniffedMIMEType: b.SniffedMIMEType(),
+			Size:            len(b.Data),
+			Threat:          v.Threat,
+		}
+		g.onFinding(ctx, f)
+		findings = append(findings, f)
+	}
+
+	return findings, nil
+}
+
+// HandleResponse scans resp and returns it, or a JSON-RPC error in its place when it carries
+// infected blobs and the action is block, continuing the chain either way.
+func (g *Guard) HandleResponse(ctx context.Context, resp *mcpdpluginsv1.HTTPResponse) *mcpdpluginsv1.HTTPResponse {
+	out := &mcpdpluginsv1.HTTPResponse{
+		Continue:   true,
+		StatusCode: resp.GetStatusCode(),
+		Headers:    resp.GetHeaders(),
+		Body:       resp.GetBody(),
+	}
+
+	findings, err := g.Scan(ctx, resp.GetBody())
+	switch {
+	case err != nil && !g.cfg.FailOpen:
+		out.Body = errorBody(resp.GetBody(), "content scan unavailable")
+	case len(findings) == 0 || g.cfg.Action == ActionLog:
+	default:
+		out.Body = errorBody(resp.GetBody(), "response blocked: infected content ("+findings[0].Threat+")")
+	}
+
+	return out
+}
+
+// errorBody builds a JSON-RPC error echoing the id of the original message, if any.
+func errorBody(original []byte, msg string) []byte {
+	return mcp.ErrorResponse(original, &mcp.Error{Code: mcp.CodeServerError, Message: msg})
+}
+
+// Plugin is a response-flow plugin scanning blobs with the scanner configured in its
+// custom_config. It lets everything through until configured.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	guard atomic.Pointer[Guard]
+}
+
+// NewPlugin returns an unconfigured Plugin.
+func NewPlugin() *Plugin {
+	return &Plugin{}
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "content-scan",
+		Version:     pluginVersion,
+		Description: "Blocks MCP responses carrying files flagged by an antivirus scanner.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowResponse), nil
+}
+
+// Configure builds the scanner and guard from cfg's custom_config.
+func (p *Plugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	var c Config
+	if err := mcpdpluginsv1.DecodeConfig(ctx, cfg, &c); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	s, err := NewScanner(c)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	g, err := NewGuard(s, c)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	p.guard.Store(g)
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleResponse scans responses.
+func (p *Plugin) HandleResponse(
+	ctx context.Context,
+	resp *mcpdpluginsv1.HTTPResponse,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	g := p.guard.Load()
+	if g == nil {
+		return &mcpdpluginsv1.HTTPResponse{
+			Continue:   true,
+			StatusCode: resp.GetStatusCode(),
+			Headers:    resp.GetHeaders(),
+			Body:       resp.GetBody(),
+		}, nil
+	}
+
+	return g.HandleResponse(ctx, resp), nil
+}
@@ -0,0 +1,409 @@
+package scan_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/scan"
+)
+
+// fakeScanner flags content containing "EICAR", failing with err when set.
+type fakeScanner struct {
+	err error
+
+	mu        sync.Mutex
+	scanned   []string
+	deadlines []bool
+}
+
+func (s *fakeScanner) Scan(ctx context.Context, r io.Reader) (scan.Verdict, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return scan.Verdict{}, err
+	}
+	_, hasDeadline := ctx.Deadline()
+	s.mu.Lock()
+	s.scanned = append(s.scanned, string(data))
+	s.deadlines = append(s.deadlines, hasDeadline)
+	s.mu.Unlock()
+
+	if s.err != nil {
+		return scan.Verdict{}, s.err
+	}
+	if strings.Contains(string(data), "EICAR") {
+		return scan.Verdict{Infected: true, Threat: "EICAR-Test-File"}, nil
+	}
+
+	return scan.Verdict{}, nil
+}
+
+func (s *fakeScanner) calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.scanned...)
+}
+
+// readResult returns a resources/read result carrying the given blobs, keyed by URI.
+func readResult(blobs ...string) []byte {
+	contents := make([]map[string]any, 0, len(blobs)/2)
+	for i := 0; i+1 < len(blobs); i += 2 {
+		contents = append(contents, map[string]any{
+			"uri":      blobs[i],
+			"mimeType": "text/plain",
+			"blob":     base64.StdEncoding.EncodeToString([]byte(blobs[i+1])),
+		})
+	}
+	body, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      7,
+		"result":  map[string]any{"contents": contents},
+	})
+
+	return body
+}
+
+func quietLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(io.Discard) })
+
+	return &buf
+}
+
+func newGuard(t *testing.T, s scan.Scanner, fn func(*scan.Config), opts ...scan.GuardOption) *scan.Guard {
+	t.Helper()
+
+	cfg := scan.DefaultConfig()
+	if fn != nil {
+		fn(&cfg)
+	}
+	g, err := scan.NewGuard(s, cfg, opts...)
+	if err != nil {
+		t.Fatalf("NewGuard: %v", err)
+	}
+
+	return g
+}
+
+func TestDefaultConfig(t *testing.T) {
+	want := scan.Config{
+		Action:      scan.ActionBlock,
+		MaxBlobSize: 25 << 20,
+		Timeout:     30 * time.Second,
+		Backend:     scan.BackendClamAV,
+		ClamAVAddr:  "localhost:3310",
+		ICAPURL:     "icap://localhost:1344/avscan",
+	}
+	if got := scan.DefaultConfig(); got != want {
+		t.Errorf("DefaultConfig = %+v, want %+v", got, want)
+	}
+}
+
+func TestNewScanner(t *testing.T) {
+	s, err := scan.NewScanner(scan.DefaultConfig())
+	if _, ok := s.(*scan.ClamAV); err != nil || !ok {
+		t.Errorf("NewScanner(defaults) = %T, %v; want a ClamAV scanner", s, err)
+	}
+	cfg := scan.DefaultConfig()
+	cfg.Backend = "ICAP"
+	s, err = scan.NewScanner(cfg)
+	if _, ok := s.(*scan.ICAP); err != nil || !ok {
+		t.Errorf("NewScanner(icap) = %T, %v; want an ICAP scanner", s, err)
+	}
+	cfg.Backend = "sophos"
+	_, err = scan.NewScanner(cfg)
+	if err == nil || !strings.Contains(err.Error(), `unknown scanner backend "sophos"`) {
+		t.Errorf("NewScanner error = %v, want an unknown backend", err)
+	}
+}
+
+func TestNewGuardErrors(t *testing.T) {
+	_, err := scan.NewGuard(nil, scan.DefaultConfig())
+	if err == nil || !strings.Contains(err.Error(), "scanner is required") {
+		t.Errorf("NewGuard(nil) error = %v", err)
+	}
+	cfg := scan.DefaultConfig()
+	cfg.Action = "quarantine"
+	_, err = scan.NewGuard(&fakeScanner{}, cfg)
+	if err == nil || !strings.Contains(err.Error(), `unknown scan action "quarantine"`) {
+		t.Errorf("NewGuard error = %v, want an unknown action", err)
+	}
+	cfg.Action = "LOG"
+	if _, err := scan.NewGuard(&fakeScanner{}, cfg); err != nil {
+		t.Errorf("NewGuard with an upper-case action: %v", err)
+	}
+}
+
+func TestGuardScan(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      []byte
+		want      []scan.Finding
+		wantScans int
+	}{
+		{
+			name: "infected blob",
+			body: readResult("file:///clean.txt", "hello", "file:///eicar.com", eicar),
+			want: []scan.Finding{{
+				URI:             "file:///eicar.com",
+				MIMEType:        "text/plain",
+				SniffedMIMEType: "text/plain",
+				Size:            len(eicar),
+				Threat:          "EICAR-Test-File",
+			}},
+			wantScans: 2,
+		},
+		{name: "clean blobs", body: readResult("file:///a", "a", "file:///b", "b"), wantScans: 2},
+		{
+			name: "no blobs",
+			body: []byte(`{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"EICAR"}]}}`),
+		},
+		{name: "not JSON", body: []byte("EICAR in plain text")},
+		{name: "not JSON-RPC", body: []byte(`{"blob":"RUlDQVI="}`)},
+		{name: "empty", body: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &fakeScanner{}
+			var seen []scan.Finding
+			g := newGuard(t, s, nil, scan.WithFindingHandler(func(_ context.Context, f scan.Finding) {
+				seen = append(seen, f)
+			}))
+
+			got, err := g.Scan(context.Background(), tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(seen, tt.want) {
+				t.Errorf("Scan = %+v (handler saw %+v), want %+v", got, seen, tt.want)
+			}
+			if n := len(s.calls()); n != tt.wantScans {
+				t.Errorf("scanner called %d times, want %d", n, tt.wantScans)
+			}
+		})
+	}
+}
+
+func TestGuardScanErrors(t *testing.T) {
+	boom := errors.New("clamd down")
+	tests := []struct {
+		name    string
+		scanner *fakeScanner
+		cfg     func(*scan.Config)
+		body    []byte
+		want    string
+	}{
+		{
+			name:    "scanner failure",
+			scanner: &fakeScanner{err: boom},
+			body:    readResult("a", "x"),
+			want:    "failed to scan blob: clamd down",
+		},
+		{
+			name:    "blob too large",
+			scanner: &fakeScanner{},
+			cfg:     func(c *scan.Config) { c.MaxBlobSize = 4 },
+			body:    readResult("a", "0123456789"),
+			want:    "blob exceeds size limit",
+		},
+		{
+			name:    "invalid base64",
+			scanner: &fakeScanner{},
+			body:    []byte(`{"jsonrpc":"2.0","id":1,"result":{"contents":[{"uri":"a","blob":"***"}]}}`),
+			want:    "invalid base64",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newGuard(t, tt.scanner, tt.cfg).Scan(context.Background(), tt.body)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Scan error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGuardTimeout(t *testing.T) {
+	s := &fakeScanner{}
+	if _, err := newGuard(t, s, nil).Scan(context.Background(), readResult("a", "x")); err != nil {
+		t.Fatal(err)
+	}
+	noTimeout := &fakeScanner{}
+	g := newGuard(t, noTimeout, func(c *scan.Config) { c.Timeout = 0 })
+	if _, err := g.Scan(context.Background(), readResult("a", "x")); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.deadlines, []bool{true}) || !reflect.DeepEqual(noTimeout.deadlines, []bool{false}) {
+		t.Errorf("scans had deadlines %v and %v, want only the configured timeout", s.deadlines, noTimeout.deadlines)
+	}
+}
+
+func TestGuardHandleResponse(t *testing.T) {
+	infected := readResult("file:///eicar.com", eicar)
+	clean := readResult("file:///a", "hello")
+	tests := []struct {
+		name      string
+		scanner   *fakeScanner
+		cfg       func(*scan.Config)
+		body      []byte
+		wantError string // Expected JSON-RPC error message; empty when the body passes.
+	}{
+		{name: "clean", scanner: &fakeScanner{}, body: clean},
+		{
+			name:      "infected",
+			scanner:   &fakeScanner{},
+			body:      infected,
+			wantError: "response blocked: infected content (EICAR-Test-File)",
+		},
+		{
+			name:    "log only",
+			scanner: &fakeScanner{},
+			cfg:     func(c *scan.Config) { c.Action = scan.ActionLog },
+			body:    infected,
+		},
+		{
+			name:      "fail closed",
+			scanner:   &fakeScanner{err: errors.New("down")},
+			body:      clean,
+			wantError: "content scan unavailable",
+		},
+		{
+			name:    "fail open",
+			scanner: &fakeScanner{err: errors.New("down")},
+			cfg:     func(c *scan.Config) { c.FailOpen = true },
+			body:    clean,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quietLogs(t)
+			g := newGuard(t, tt.scanner, tt.cfg)
+			resp := &mcpdpluginsv1.HTTPResponse{
+				StatusCode: 200,
+				Headers:    map[string]string{"Content-Type": "application/json"},
+				Body:       tt.body,
+			}
+
+			out := g.HandleResponse(context.Background(), resp)
+			if !out.GetContinue() || out.GetStatusCode() != 200 ||
+				out.GetHeaders()["Content-Type"] != "application/json" {
+				t.Errorf("HandleResponse = %v, want a continuing response keeping status and headers", out)
+			}
+			if tt.wantError == "" {
+				if !bytes.Equal(out.GetBody(), tt.body) {
+					t.Errorf("body = %s, want it unchanged", out.GetBody())
+				}
+				return
+			}
+			var msg struct {
+				ID    json.RawMessage `json:"id"`
+				Error struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(out.GetBody(), &msg); err != nil {
+				t.Fatalf("body %s: %v", out.GetBody(), err)
+			}
+			if string(msg.ID) != "7" || msg.Error.Message != tt.wantError {
+				t.Errorf("body = %s, want error %q for id 7", out.GetBody(), tt.wantError)
+			}
+		})
+	}
+}
+
+func TestDefaultFindingHandler(t *testing.T) {
+	logs := quietLogs(t)
+	g := newGuard(t, &fakeScanner{}, func(c *scan.Config) { c.Action = scan.ActionLog })
+	if _, err := g.Scan(context.Background(), readResult("file:///eicar.com", eicar)); err != nil {
+		t.Fatal(err)
+	}
+	want := `scan: infected blob "file:///eicar.com" (text/plain, 68 bytes): EICAR-Test-File`
+	if !strings.Contains(logs.String(), want) {
+		t.Errorf("logs = %q, want %q", logs.String(), want)
+	}
+}
+
+func TestScannerFunc(t *testing.T) {
+	var s scan.Scanner = scan.ScannerFunc(func(_ context.Context, r io.Reader) (scan.Verdict, error) {
+		data, _ := io.ReadAll(r)
+		return scan.Verdict{Infected: len(data) > 0, Threat: string(data)}, nil
+	})
+	if v, err := s.Scan(context.Background(), strings.NewReader("x")); err != nil || v.Threat != "x" {
+		t.Errorf("Scan = %+v, %v", v, err)
+	}
+}
+
+func configure(p *scan.Plugin, custom map[string]string) error {
+	_, err := p.Configure(context.Background(), &mcpdpluginsv1.PluginConfig{CustomConfig: custom})
+	return err
+}
+
+func TestPlugin(t *testing.T) {
+	quietLogs(t)
+	p := scan.NewPlugin()
+	ctx := context.Background()
+	resp := &mcpdpluginsv1.HTTPResponse{StatusCode: 200, Body: readResult("file:///eicar.com", eicar)}
+
+	out, err := p.HandleResponse(ctx, resp)
+	if err != nil || !out.GetContinue() || !bytes.Equal(out.GetBody(), resp.GetBody()) {
+		t.Fatalf("unconfigured HandleResponse = %v, %v; want it unchanged", out, err)
+	}
+
+	clamd := newFakeClamd(t, "tcp", "127.0.0.1:0", clamdReply)
+	if err := configure(p, map[string]string{"clamav_addr": clamd.ln.Addr().String()}); err != nil {
+		t.Fatal(err)
+	}
+	out, err = p.HandleResponse(ctx, resp)
+	if err != nil || !strings.Contains(string(out.GetBody()), "infected content (Win.Test.EICAR_HDB-1)") {
+		t.Errorf("HandleResponse = %s, %v; want the infected blob blocked", out.GetBody(), err)
+	}
+	clean := &mcpdpluginsv1.HTTPResponse{StatusCode: 200, Body: readResult("file:///a", "hello")}
+	if out, _ := p.HandleResponse(ctx, clean); !bytes.Equal(out.GetBody(), clean.GetBody()) {
+		t.Errorf("HandleResponse = %s, want the clean body", out.GetBody())
+	}
+
+	// A failed Configure keeps the previous guard.
+	for _, custom := range []map[string]string{
+		{"backend": "sophos"},
+		{"action": "quarantine"},
+		{"timeout": "soon"},
+		{"backend": "icap", "icap_url": "http://x"},
+	} {
+		if err := configure(p, custom); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Configure(%v) error = %v, want InvalidArgument", custom, err)
+		}
+	}
+	if out, _ := p.HandleResponse(ctx, resp); !strings.Contains(string(out.GetBody()), "infected content") {
+		t.Errorf("HandleResponse after failed Configure = %s, want the previous guard", out.GetBody())
+	}
+}
+
+func TestPluginMetadata(t *testing.T) {
+	p := scan.NewPlugin()
+	md, err := p.GetMetadata(context.Background(), &emptypb.Empty{})
+	if err != nil || md.GetName() != "content-scan" {
+		t.Errorf("GetMetadata = %v, %v", md, err)
+	}
+	caps, err := p.GetCapabilities(context.Background(), &emptypb.Empty{})
+	if err != nil || !reflect.DeepEqual(caps.GetFlows(), []mcpdpluginsv1.Flow{mcpdpluginsv1.FlowResponse}) {
+		t.Errorf("GetCapabilities = %v, %v; want the response flow", caps, err)
+	}
+}
@@ -0,0 +1,155 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultICAPPort is the IANA-registered ICAP port.
+const defaultICAPPort = "1344"
+
+// ICAPConfig configures an ICAP scanner.
+type ICAPConfig struct {
+	// URL is the service URL, such as "icap://icap.internal:1344/avscan".
+	URL string
+
+	// Timeout bounds each scan, including connection setup (default 30s).
+	Timeout time.Duration
+}
+
+// ICAP scans content with an RFC 3507 ICAP service using RESPMOD.
+//
+// A 204 reply means the content is clean. A 200 reply means the service replaced the content;
+// the threat is read from the X-Infection-Found, X-Virus-ID or X-Violations-Found headers, and
+// defaults to "blocked by ICAP service" when none is set.
+type ICAP struct {
+	cfg     ICAPConfig
+	addr    string
+	host    string
+	service string
+}
+
+// NewICAP returns an ICAP scanner for cfg. Connections are opened per scan.
+func NewICAP(cfg ICAPConfig) (*ICAP, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Scheme != "icap" || u.Host == "" {
+		return nil, fmt.Errorf("invalid ICAP URL %q: expected icap://host[:port]/service", cfg.URL)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), defaultICAPPort)
+	}
+
+	return &ICAP{cfg: cfg, addr: addr, host: u.Host, service: u.String()}, nil
+}
+
+// Scan implements Scanner, sending r as the body of an HTTP response for modification.
+func (c *ICAP) Scan(ctx context.Context, r io.Reader) (Verdict, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed to connect to ICAP service at %s: %w", c.addr, err)
+	}
+	defer func() { _ = conn.Close() }()
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	resHeader := "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\n\r\n"
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "RESPMOD %s ICAP/1.0\r\n", c.service)
+	fmt.Fprintf(w, "Host: %s\r\n", c.host)
+	_, _ = w.WriteString("Allow: 204\r\n")
+	fmt.Fprintf(w, "Encapsulated: res-hdr=0, res-body=%d\r\n\r\n", len(resHeader))
+	_, _ = w.WriteString(resHeader)
+
+	buf := make([]byte, 32<<10)
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			fmt.Fprintf(w, "%x\r\n", n)
+			_, _ = w.Write(buf[:n])
+			_, _ = w.WriteString("\r\n")
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return Verdict{}, fmt.Errorf("failed to read content: %w", rerr)
+		}
+	}
+	_, _ = w.WriteString("0\r\n\r\n")
+	if err := w.Flush(); err != nil {
+		return Verdict{}, fmt.Errorf("ICAP write failed: %w", err)
+	}
+
+	tp := textproto.NewReader(bufio.NewReader(conn))
+	line, err := tp.ReadLine()
+	if err != nil {
+		return Verdict{}, fmt.Errorf("ICAP read failed: %w", err)
+	}
+	code, err := icapStatus(line)
+	if err != nil {
+		return Verdict{}, err
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return Verdict{}, fmt.Errorf("ICAP read failed: %w", err)
+	}
+
+	switch code {
+	case 204:
+		return Verdict{}, nil
+	case 200:
+		return Verdict{Infected: true, Threat: icapThreat(header)}, nil
+	default:
+		return Verdict{}, fmt.Errorf("ICAP service returned %q", line)
+	}
+}
+
+func icapStatus(line string) (int, error) {
+	proto, rest, ok := strings.Cut(line, " ")
+	if !ok || !strings.HasPrefix(proto, "ICAP/") {
+		return 0, fmt.Errorf("malformed ICAP status line %q", line)
+	}
+	codeText, _, _ := strings.Cut(rest, " ")
+	code, err := strconv.Atoi(codeText)
+	if err != nil {
+		return 0, fmt.Errorf("malformed ICAP status line %q", line)
+	}
+
+	return code, nil
+}
+
+// icapThreat extracts the threat name from the vendor headers services use to report findings.
+func icapThreat(h textproto.MIMEHeader) string {
+	if v := h.Get("X-Infection-Found"); v != "" {
+		// "Type=0; Resolution=2; Threat=EICAR-Test-File;"
+		for _, field := range strings.Split(v, ";") {
+			if name, ok := strings.CutPrefix(strings.TrimSpace(field), "Threat="); ok && name != "" {
+				return name
+			}
+		}
+		return v
+	}
+	for _, k := range []string{"X-Virus-ID", "X-Violations-Found"} {
+		if v := h.Get(k); v != "" {
+			return v
+		}
+	}
+
+	return "blocked by ICAP service"
+}
@@ -0,0 +1,230 @@
+package scan_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http/httputil"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/scan"
+)
+
+// icapRequest is a RESPMOD request received by fakeICAP.
+type icapRequest struct {
+	line   string
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// fakeICAP answers RESPMOD requests with reply(request).
+type fakeICAP struct {
+	ln    net.Listener
+	reply func(req icapRequest) string
+
+	mu       sync.Mutex
+	requests []icapRequest
+}
+
+func newFakeICAP(t *testing.T, reply func(req icapRequest) string) *fakeICAP {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeICAP{ln: ln, reply: reply}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.handle(conn)
+		}
+	}()
+
+	return f
+}
+
+func (f *fakeICAP) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	tp := textproto.NewReader(bufio.NewReader(conn))
+	line, err := tp.ReadLine()
+	if err != nil {
+		return
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return
+	}
+	// The encapsulated HTTP response header, then its chunked body.
+	if _, err := tp.ReadLine(); err != nil {
+		return
+	}
+	if _, err := tp.ReadMIMEHeader(); err != nil {
+		return
+	}
+	body, err := io.ReadAll(httputil.NewChunkedReader(tp.R))
+	if err != nil {
+		return
+	}
+
+	req := icapRequest{line: line, header: header, body: body}
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	f.mu.Unlock()
+	_, _ = conn.Write([]byte(f.reply(req)))
+}
+
+func (f *fakeICAP) received() []icapRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]icapRequest(nil), f.requests...)
+}
+
+func TestNewICAPErrors(t *testing.T) {
+	for _, u := range []string{"", "http://icap.internal/avscan", "icap:///avscan", "icap://%zz"} {
+		_, err := scan.NewICAP(scan.ICAPConfig{URL: u})
+		if err == nil || !strings.Contains(err.Error(), "invalid ICAP URL") {
+			t.Errorf("NewICAP(%q) error = %v, want an invalid URL", u, err)
+		}
+	}
+	if _, err := scan.NewScanner(scan.Config{Backend: "icap", ICAPURL: "http://x"}); err == nil {
+		t.Error("NewScanner accepted an invalid ICAP URL")
+	}
+}
+
+func TestICAP(t *testing.T) {
+	srv := newFakeICAP(t, func(req icapRequest) string {
+		if strings.Contains(string(req.body), "EICAR") {
+			return "ICAP/1.0 200 OK\r\nX-Infection-Found: Type=0; Resolution=2; Threat=EICAR-Test-File;\r\n" +
+				"Encapsulated: res-hdr=0, null-body=19\r\n\r\nHTTP/1.1 403 Forbidden\r\n\r\n"
+		}
+		return "ICAP/1.0 204 No Content\r\nISTag: \"1\"\r\n\r\n"
+	})
+	addr := srv.ln.Addr().String()
+	c, err := scan.NewICAP(scan.ICAPConfig{URL: "icap://" + addr + "/avscan"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := c.Scan(context.Background(), strings.NewReader("hello world"))
+	if err != nil || v.Infected {
+		t.Errorf("Scan clean = %+v, %v", v, err)
+	}
+	// More than one read buffer, so the content spans several chunks.
+	big := strings.Repeat("a", 100<<10) + eicar
+	v, err = c.Scan(context.Background(), strings.NewReader(big))
+	if err != nil || v != (scan.Verdict{Infected: true, Threat: "EICAR-Test-File"}) {
+		t.Errorf("Scan infected = %+v, %v", v, err)
+	}
+
+	reqs := srv.received()
+	if len(reqs) != 2 {
+		t.Fatalf("ICAP service received %d requests, want 2", len(reqs))
+	}
+	r := reqs[0]
+	if r.line != "RESPMOD icap://"+addr+"/avscan ICAP/1.0" {
+		t.Errorf("request line = %q", r.line)
+	}
+	if r.header.Get("Host") != addr || r.header.Get("Allow") != "204" ||
+		!strings.HasPrefix(r.header.Get("Encapsulated"), "res-hdr=0, res-body=") {
+		t.Errorf("request headers = %v", r.header)
+	}
+	if string(r.body) != "hello world" || string(reqs[1].body) != big {
+		t.Errorf("service received bodies of %d and %d bytes, want the scanned contents",
+			len(r.body), len(reqs[1].body))
+	}
+}
+
+func TestICAPReplies(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    scan.Verdict
+		wantErr string
+	}{
+		{name: "clean", reply: "ICAP/1.0 204 No Content\r\n\r\n"},
+		{
+			name:  "infection header without a threat field",
+			reply: "ICAP/1.0 200 OK\r\nX-Infection-Found: Type=0; Resolution=2\r\n\r\n",
+			want:  scan.Verdict{Infected: true, Threat: "Type=0; Resolution=2"},
+		},
+		{
+			name:  "virus ID",
+			reply: "ICAP/1.0 200 OK\r\nX-Virus-ID: Trojan.Generic\r\n\r\n",
+			want:  scan.Verdict{Infected: true, Threat: "Trojan.Generic"},
+		},
+		{
+			name:  "violations",
+			reply: "ICAP/1.0 200 OK\r\nX-Violations-Found: 1\r\n\r\n",
+			want:  scan.Verdict{Infected: true, Threat: "1"},
+		},
+		{
+			name:  "modified without a threat",
+			reply: "ICAP/1.0 200 OK\r\n\r\n",
+			want:  scan.Verdict{Infected: true, Threat: "blocked by ICAP service"},
+		},
+		{
+			name:    "service error",
+			reply:   "ICAP/1.0 500 Server Error\r\n\r\n",
+			wantErr: `ICAP service returned "ICAP/1.0 500 Server Error"`,
+		},
+		{name: "not ICAP", reply: "HTTP/1.1 200 OK\r\n\r\n", wantErr: "malformed ICAP status line"},
+		{name: "bad code", reply: "ICAP/1.0 abc OK\r\n\r\n", wantErr: "malformed ICAP status line"},
+		{name: "no status", reply: "", wantErr: "ICAP read failed"},
+		{name: "truncated headers", reply: "ICAP/1.0 204 No Content\r\nISTag", wantErr: "ICAP read failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeICAP(t, func(icapRequest) string { return tt.reply })
+			c, err := scan.NewICAP(scan.ICAPConfig{URL: "icap://" + srv.ln.Addr().String() + "/avscan"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := c.Scan(context.Background(), strings.NewReader("data"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Scan = %+v, %v; want error %q", v, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || v != tt.want {
+				t.Errorf("Scan = %+v, %v; want %+v", v, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestICAPErrors(t *testing.T) {
+	t.Run("default port", func(t *testing.T) {
+		c, err := scan.NewICAP(scan.ICAPConfig{URL: "icap://127.0.0.1/avscan", Timeout: time.Second})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Nothing listens on the ICAP port in tests; the error names the address dialled.
+		_, err = c.Scan(context.Background(), strings.NewReader("x"))
+		if err == nil || !strings.Contains(err.Error(), "127.0.0.1:1344") {
+			t.Errorf("Scan error = %v, want a connection failure to port 1344", err)
+		}
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		srv := newFakeICAP(t, func(icapRequest) string { return "ICAP/1.0 204 No Content\r\n\r\n" })
+		c, _ := scan.NewICAP(scan.ICAPConfig{URL: "icap://" + srv.ln.Addr().String() + "/avscan"})
+		boom := errors.New("boom")
+		if _, err := c.Scan(context.Background(), iotestErrReader{boom}); !errors.Is(err, boom) {
+			t.Errorf("Scan error = %v, want the read error", err)
+		}
+	})
+}
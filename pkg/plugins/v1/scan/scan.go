@@ -0,0 +1,42 @@
+// Package scan inspects file contents carried by MCP messages with antivirus and content
+// scanners, such as the blobs returned by resources/read.
+//
+// A Scanner reads content and returns a Verdict. ClamAV talks to clamd with the INSTREAM
+// command and ICAP to any RFC 3507 service (c-icap, Kaspersky, Sophos, Trend Micro, ...). Guard
+// scans the blobs of responses, blocking those with infected content:
+//
+//	scanner, err := scan.NewClamAV(scan.ClamAVConfig{Addr: "clamd:3310"})
+//	...
+//	guard, err := scan.NewGuard(scanner, scan.DefaultConfig())
+//	...
+//	return guard.HandleResponse(ctx, resp), nil
+//
+// Plugin wraps a Guard configured from custom_config.
+package scan
+
+import (
+	"context"
+	"io"
+)
+
+// Verdict is the outcome of a scan.
+type Verdict struct {
+	// Infected reports whether the scanner found a threat.
+	Infected bool
+
+	// Threat names what was found, as reported by the scanner.
+	Threat string
+}
+
+// Scanner scans content. Implementations must be safe for concurrent use.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (Verdict, error)
+}
+
+// ScannerFunc adapts a function to the Scanner interface.
+type ScannerFunc func(ctx context.Context, r io.Reader) (Verdict, error)
+
+// Scan implements Scanner.
+func (f ScannerFunc) Scan(ctx context.Context, r io.Reader) (Verdict, error) {
+	return f(ctx, r)
+}
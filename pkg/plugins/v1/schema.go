@@ -0,0 +1,93 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/schema"
+)
+
+// ConfigSchemaMetadataKey is the gRPC response header on GetMetadata that carries the plugin's
+// config JSON Schema, for plugins implementing SchemaProvider. The "-bin" suffix lets the
+// document travel unmodified as binary metadata.
+const ConfigSchemaMetadataKey = "mcpd-config-schema-bin"
+
+// SchemaProvider is implemented by plugins that declare a JSON Schema for the custom_config
+// section of their PluginConfig (see the schema package for the supported subset).
+//
+// When the plugin served by Serve implements SchemaProvider, the SDK:
+//   - rejects Configure calls whose custom_config does not validate, with codes.InvalidArgument
+//     and a BadRequest detail listing every field violation, without calling the plugin;
+//   - returns the schema document in the ConfigSchemaMetadataKey header of GetMetadata, so mcpd
+//     and UIs can validate and autocomplete configuration.
+//
+// Usage:
+//
+//	//go:embed config.schema.json
+//	var configSchema []byte
+//
+//	func (p *MyPlugin) ConfigSchema() []byte {
+//	    return configSchema
+//	}
+type SchemaProvider interface {
+	ConfigSchema() []byte
+}
+
+// schemaInterceptor enforces and serves the schema declared by impl. It returns nil when impl
+// does not implement SchemaProvider.
+func schemaInterceptor(impl PluginServer) (grpc.UnaryServerInterceptor, error) {
+	provider, ok := impl.(SchemaProvider)
+	if !ok {
+		return nil, nil
+	}
+
+	s, err := schema.Parse(provider.ConfigSchema())
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugin config schema: %w", err)
+	}
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		switch info.FullMethod {
+		case Plugin_Configure_FullMethodName:
+			if cfg, ok := req.(*PluginConfig); ok {
+				if err := s.Validate(cfg.GetCustomConfig()); err != nil {
+					return nil, configValidationStatus(err)
+				}
+			}
+		case Plugin_GetMetadata_FullMethodName:
+			_ = grpc.SetHeader(ctx, metadata.Pairs(ConfigSchemaMetadataKey, string(s.Raw())))
+		}
+
+		return handler(ctx, req)
+	}, nil
+}
+
+// configValidationStatus converts a schema validation failure into an InvalidArgument status.
+func configValidationStatus(err error) error {
+	st := status.New(codes.InvalidArgument, err.Error())
+
+	var verr *schema.ValidationError
+	if !errors.As(err, &verr) {
+		return st.Err()
+	}
+
+	br := &errdetails.BadRequest{}
+	for _, v := range verr.Violations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       "custom_config." + v.Field,
+			Description: v.Message,
+		})
+	}
+	if detailed, derr := st.WithDetails(br); derr == nil {
+		return detailed.Err()
+	}
+
+	return st.Err()
+}
@@ -0,0 +1,234 @@
+// Package schema validates plugin configuration against a JSON Schema document.
+//
+// mcpd delivers plugin-specific configuration as a flat map of strings (PluginConfig.custom_config),
+// so the supported schema is the subset that describes such a map: a top-level object whose
+// properties declare a scalar type, to which each string value must be convertible, plus the
+// usual string and numeric constraints. Unsupported keywords are ignored, so a richer schema can
+// still be served to UIs for autocompletion.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Supported property types.
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+)
+
+// Supported string formats.
+const (
+	FormatDuration = "duration"
+	FormatURI      = "uri"
+)
+
+// Schema is a JSON Schema describing a plugin's custom configuration.
+type Schema struct {
+	Type                 string               `json:"type,omitempty"`
+	Title                string               `json:"title,omitempty"`
+	Description          string               `json:"description,omitempty"`
+	Properties           map[string]*Property `json:"properties,omitempty"`
+	Required             []string             `json:"required,omitempty"`
+	AdditionalProperties *bool                `json:"additionalProperties,omitempty"`
+
+	raw []byte
+}
+
+// Property describes a single configuration key.
+type Property struct {
+	Type        string          `json:"type,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Default     json.RawMessage `json:"default,omitempty"`
+	Enum        []string        `json:"enum,omitempty"`
+	Pattern     string          `json:"pattern,omitempty"`
+	Format      string          `json:"format,omitempty"`
+	MinLength   *int            `json:"minLength,omitempty"`
+	MaxLength   *int            `json:"maxLength,omitempty"`
+	Minimum     *float64        `json:"minimum,omitempty"`
+	Maximum     *float64        `json:"maximum,omitempty"`
+	Deprecated  bool            `json:"deprecated,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// Violation is a single validation failure.
+type Violation struct {
+	Field   string
+	Message string
+}
+
+// ValidationError lists every violation found by Validate.
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Error implements error.
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Field + ": " + v.Message
+	}
+
+	return "invalid configuration: " + strings.Join(parts, "; ")
+}
+
+// Parse decodes and checks a JSON Schema document.
+func Parse(doc []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(doc, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode config schema: %w", err)
+	}
+	if s.Type != "" && s.Type != "object" {
+		return nil, fmt.Errorf("config schema must describe an object, got type %q", s.Type)
+	}
+
+	for name, p := range s.Properties {
+		if p == nil {
+			return nil, fmt.Errorf("property %q has no definition", name)
+		}
+		switch p.Type {
+		case "", TypeString, TypeInteger, TypeNumber, TypeBoolean:
+		default:
+			return nil, fmt.Errorf("property %q has unsupported type %q", name, p.Type)
+		}
+		if p.Pattern != "" {
+			re, err := regexp.Compile(p.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("property %q has an invalid pattern: %w", name, err)
+			}
+			p.pattern = re
+		}
+	}
+	for _, name := range s.Required {
+		if _, ok := s.Properties[name]; !ok {
+			return nil, fmt.Errorf("required property %q is not defined", name)
+		}
+	}
+
+	s.raw = append([]byte(nil), doc...)
+
+	return &s, nil
+}
+
+// MustParse is like Parse but panics on error. It is intended for schemas embedded in plugin code.
+func MustParse(doc []byte) *Schema {
+	s, err := Parse(doc)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// Raw returns the schema document as it was parsed.
+func (s *Schema) Raw() []byte {
+	return s.raw
+}
+
+// Validate checks cfg against the schema and returns a *ValidationError listing every violation.
+func (s *Schema) Validate(cfg map[string]string) error {
+	var violations []Violation
+
+	for _, name := range s.Required {
+		if _, ok := cfg[name]; !ok {
+			violations = append(violations, Violation{Field: name, Message: "is required"})
+		}
+	}
+
+	keys := make([]string, 0, len(cfg))
+	for k := range cfg {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p, ok := s.Properties[k]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				violations = append(violations, Violation{Field: k, Message: "is not a recognised configuration key"})
+			}
+			continue
+		}
+		if msg := p.check(cfg[k]); msg != "" {
+			violations = append(violations, Violation{Field: k, Message: msg})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+
+	return nil
+}
+
+// check returns a description of why v does not satisfy p, or an empty string.
+func (p *Property) check(v string) string {
+	var num float64
+	switch p.Type {
+	case TypeInteger:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return fmt.Sprintf("must be an integer, got %q", v)
+		}
+		num = float64(n)
+	case TypeNumber:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return fmt.Sprintf("must be a number, got %q", v)
+		}
+		num = n
+	case TypeBoolean:
+		if _, err := strconv.ParseBool(strings.TrimSpace(v)); err != nil {
+			return fmt.Sprintf("must be a boolean, got %q", v)
+		}
+	}
+
+	if p.Type == TypeInteger || p.Type == TypeNumber {
+		if p.Minimum != nil && num < *p.Minimum {
+			return fmt.Sprintf("must be at least %v", *p.Minimum)
+		}
+		if p.Maximum != nil && num > *p.Maximum {
+			return fmt.Sprintf("must be at most %v", *p.Maximum)
+		}
+	}
+
+	if len(p.Enum) > 0 && !slices.Contains(p.Enum, v) {
+		return fmt.Sprintf("must be one of %s", strings.Join(p.Enum, ", "))
+	}
+
+	n := utf8.RuneCountInString(v)
+	if p.MinLength != nil && n < *p.MinLength {
+		return fmt.Sprintf("must be at least %d characters long", *p.MinLength)
+	}
+	if p.MaxLength != nil && n > *p.MaxLength {
+		return fmt.Sprintf("must be at most %d characters long", *p.MaxLength)
+	}
+	if p.pattern != nil && !p.pattern.MatchString(v) {
+		return fmt.Sprintf("must match pattern %s", p.Pattern)
+	}
+
+	switch p.Format {
+	case FormatDuration:
+		if _, err := time.ParseDuration(v); err != nil {
+			return fmt.Sprintf("must be a duration such as \"30s\", got %q", v)
+		}
+	case FormatURI:
+		if u, err := url.Parse(v); err != nil || u.Scheme == "" {
+			return fmt.Sprintf("must be an absolute URI, got %q", v)
+		}
+	}
+
+	return ""
+}
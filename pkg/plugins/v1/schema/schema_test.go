@@ -0,0 +1,192 @@
+package schema_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/schema"
+)
+
+const testSchema = `{
+	"type": "object",
+	"properties": {
+		"mode":    {"type": "string", "enum": ["strict", "lax"]},
+		"name":    {"type": "string", "minLength": 2, "maxLength": 4, "pattern": "^[a-zß]+$"},
+		"limit":   {"type": "integer", "minimum": 1, "maximum": 10},
+		"ratio":   {"type": "number", "minimum": 0, "maximum": 1},
+		"enabled": {"type": "boolean"},
+		"timeout": {"type": "string", "format": "duration"},
+		"url":     {"type": "string", "format": "uri"},
+		"any":     {}
+	},
+	"required": ["mode"],
+	"additionalProperties": false
+}`
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"invalid JSON", `{`, "failed to decode config schema"},
+		{"not an object", `{"type": "array"}`, `must describe an object, got type "array"`},
+		{"null property", `{"properties": {"a": null}}`, `property "a" has no definition`},
+		{"unsupported type", `{"properties": {"a": {"type": "array"}}}`, `property "a" has unsupported type "array"`},
+		{"invalid pattern", `{"properties": {"a": {"pattern": "("}}}`, `property "a" has an invalid pattern`},
+		{"undefined required", `{"required": ["a"]}`, `required property "a" is not defined`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := schema.Parse([]byte(tt.doc))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIgnoresUnsupportedKeywords(t *testing.T) {
+	doc := `{"$schema": "https://json-schema.org/draft/2020-12/schema", "properties": {"a": {"examples": ["x"]}}}`
+	s, err := schema.Parse([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(s.Raw()) != doc {
+		t.Errorf("Raw() = %s, want the parsed document", s.Raw())
+	}
+	if err := s.Validate(map[string]string{"a": "anything", "b": "unknown keys are allowed"}); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestMustParse(t *testing.T) {
+	if s := schema.MustParse([]byte(testSchema)); s == nil {
+		t.Fatal("MustParse returned nil")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustParse did not panic on an invalid schema")
+		}
+	}()
+	schema.MustParse([]byte(`{`))
+}
+
+func TestValidate(t *testing.T) {
+	s := schema.MustParse([]byte(testSchema))
+	tests := []struct {
+		name string
+		cfg  map[string]string
+		want []schema.Violation // Nil for a valid configuration.
+	}{
+		{
+			name: "valid",
+			cfg: map[string]string{
+				"mode":    "strict",
+				"name":    "ßab",
+				"limit":   " 10 ",
+				"ratio":   "0.5",
+				"enabled": "true",
+				"timeout": "30s",
+				"url":     "https://example.com/x",
+				"any":     "",
+			},
+		},
+		{
+			name: "missing required",
+			cfg:  map[string]string{},
+			want: []schema.Violation{{Field: "mode", Message: "is required"}},
+		},
+		{
+			name: "unknown key",
+			cfg:  map[string]string{"mode": "lax", "extra": "1"},
+			want: []schema.Violation{{Field: "extra", Message: "is not a recognised configuration key"}},
+		},
+		{
+			name: "enum",
+			cfg:  map[string]string{"mode": "loose"},
+			want: []schema.Violation{{Field: "mode", Message: "must be one of strict, lax"}},
+		},
+		{
+			name: "types",
+			cfg:  map[string]string{"mode": "lax", "limit": "1.5", "ratio": "half", "enabled": "yes"},
+			want: []schema.Violation{
+				{Field: "enabled", Message: `must be a boolean, got "yes"`},
+				{Field: "limit", Message: `must be an integer, got "1.5"`},
+				{Field: "ratio", Message: `must be a number, got "half"`},
+			},
+		},
+		{
+			name: "numeric bounds",
+			cfg:  map[string]string{"mode": "lax", "limit": "0", "ratio": "1.5"},
+			want: []schema.Violation{
+				{Field: "limit", Message: "must be at least 1"},
+				{Field: "ratio", Message: "must be at most 1"},
+			},
+		},
+		{
+			name: "string length counts characters",
+			cfg:  map[string]string{"mode": "lax", "name": "ß"},
+			want: []schema.Violation{{Field: "name", Message: "must be at least 2 characters long"}},
+		},
+		{
+			name: "too long",
+			cfg:  map[string]string{"mode": "lax", "name": "abcde"},
+			want: []schema.Violation{{Field: "name", Message: "must be at most 4 characters long"}},
+		},
+		{
+			name: "pattern",
+			cfg:  map[string]string{"mode": "lax", "name": "AB"},
+			want: []schema.Violation{{Field: "name", Message: "must match pattern ^[a-zß]+$"}},
+		},
+		{
+			name: "formats",
+			cfg:  map[string]string{"mode": "lax", "timeout": "30", "url": "/relative"},
+			want: []schema.Violation{
+				{Field: "timeout", Message: `must be a duration such as "30s", got "30"`},
+				{Field: "url", Message: `must be an absolute URI, got "/relative"`},
+			},
+		},
+		{
+			name: "every violation is listed",
+			cfg:  map[string]string{"limit": "11", "extra": ""},
+			want: []schema.Violation{
+				{Field: "mode", Message: "is required"},
+				{Field: "extra", Message: "is not a recognised configuration key"},
+				{Field: "limit", Message: "must be at most 10"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.Validate(tt.cfg)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate: %v", err)
+				}
+				return
+			}
+
+			var verr *schema.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate error = %v, want a *ValidationError", err)
+			}
+			if !slices.Equal(verr.Violations, tt.want) {
+				t.Errorf("violations = %+v, want %+v", verr.Violations, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	err := &schema.ValidationError{Violations: []schema.Violation{
+		{Field: "a", Message: "is required"},
+		{Field: "b", Message: "must be a number"},
+	}}
+	if want := "invalid configuration: a: is required; b: must be a number"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,146 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// schemaPlugin is a plugin declaring a config schema.
+type schemaPlugin struct {
+	BasePlugin
+
+	schema string
+}
+
+func (p *schemaPlugin) ConfigSchema() []byte { return []byte(p.schema) }
+
+// headerStream is a grpc.ServerTransportStream recording the headers and trailers set on it.
+type headerStream struct {
+	grpc.ServerTransportStream
+
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+func (s *headerStream) Method() string { return "" }
+
+const limitSchema = `{"properties": {"limit": {"type": "integer", "minimum": 1}}, "required": ["limit"]}`
+
+func TestSchemaInterceptorConfigure(t *testing.T) {
+	si, err := schemaInterceptor(&schemaPlugin{schema: limitSchema})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: Plugin_Configure_FullMethodName}
+
+	tests := []struct {
+		name       string
+		cfg        map[string]string
+		wantCalled bool
+		wantFields map[string]string // BadRequest field violations when rejected.
+	}{
+		{name: "valid", cfg: map[string]string{"limit": "5"}, wantCalled: true},
+		{
+			name:       "missing",
+			cfg:        nil,
+			wantFields: map[string]string{"custom_config.limit": "is required"},
+		},
+		{
+			name:       "out of range",
+			cfg:        map[string]string{"limit": "0"},
+			wantFields: map[string]string{"custom_config.limit": "must be at least 1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+			handler := func(context.Context, any) (any, error) {
+				called = true
+				return &emptypb.Empty{}, nil
+			}
+			_, err := si(context.Background(), &PluginConfig{CustomConfig: tt.cfg}, info, handler)
+			if called != tt.wantCalled {
+				t.Errorf("plugin called = %v, want %v", called, tt.wantCalled)
+			}
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Errorf("Configure: %v", err)
+				}
+				return
+			}
+
+			st := status.Convert(err)
+			if st.Code() != codes.InvalidArgument {
+				t.Fatalf("error = %v, want InvalidArgument", err)
+			}
+			got := map[string]string{}
+			for _, d := range st.Details() {
+				if br, ok := d.(*errdetails.BadRequest); ok {
+					for _, v := range br.GetFieldViolations() {
+						got[v.GetField()] = v.GetDescription()
+					}
+				}
+			}
+			if len(got) != len(tt.wantFields) {
+				t.Errorf("field violations = %v, want %v", got, tt.wantFields)
+			}
+			for f, d := range tt.wantFields {
+				if got[f] != d {
+					t.Errorf("violation of %s = %q, want %q", f, got[f], d)
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaInterceptorMetadata(t *testing.T) {
+	si, err := schemaInterceptor(&schemaPlugin{schema: limitSchema})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := &headerStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	info := &grpc.UnaryServerInfo{FullMethod: Plugin_GetMetadata_FullMethodName}
+
+	if _, err := si(ctx, nil, info, func(context.Context, any) (any, error) { return &Metadata{}, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if got := stream.header.Get(ConfigSchemaMetadataKey); len(got) != 1 || got[0] != limitSchema {
+		t.Errorf("%s header = %q, want the schema document", ConfigSchemaMetadataKey, got)
+	}
+}
+
+func TestSchemaInterceptorDeclaration(t *testing.T) {
+	if si, err := schemaInterceptor(&BasePlugin{}); si != nil || err != nil {
+		t.Errorf("schemaInterceptor without a SchemaProvider = %v, %v; want nil, nil", si != nil, err)
+	}
+	_, err := schemaInterceptor(&schemaPlugin{schema: `{"type": "array"}`})
+	if err == nil || !strings.Contains(err.Error(), "invalid plugin config schema") {
+		t.Errorf("schemaInterceptor error = %v, want an invalid schema error", err)
+	}
+}
+
+func TestConfigValidationStatusOtherErrors(t *testing.T) {
+	st := status.Convert(configValidationStatus(context.Canceled))
+	if st.Code() != codes.InvalidArgument || st.Message() != context.Canceled.Error() || len(st.Details()) != 0 {
+		t.Errorf("status = %v with details %v, want a plain InvalidArgument", st, st.Details())
+	}
+}
@@ -0,0 +1,139 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SecretRefPrefix marks a custom_config value as a secret reference, "secret:<scheme>:<ref>",
+// resolved by the SecretResolver registered for scheme with WithSecrets. For example
+// "secret:env:API_TOKEN" or "secret:vault:secret/data/app#token".
+const SecretRefPrefix = "secret:"
+
+// Secret schemes resolved without further configuration once WithSecrets is used.
+const (
+	// SecretSchemeEnv resolves a reference to the value of the environment variable it names.
+	SecretSchemeEnv = "env"
+
+	// SecretSchemeFile resolves a reference to the contents of the file at the path it names,
+	// without trailing newlines, as with Docker and Kubernetes secret mounts.
+	SecretSchemeFile = "file"
+)
+
+// SecretResolver resolves secret references of one scheme, such as a path in a secret store, to
+// their values.
+type SecretResolver interface {
+	ResolveSecret(ctx context.Context, ref string) (string, error)
+}
+
+// SecretResolverFunc adapts a function to the SecretResolver interface.
+type SecretResolverFunc func(ctx context.Context, ref string) (string, error)
+
+// ResolveSecret calls f(ctx, ref).
+func (f SecretResolverFunc) ResolveSecret(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// defaultSecretResolvers returns the resolvers of SecretSchemeEnv and SecretSchemeFile.
+func defaultSecretResolvers() map[string]SecretResolver {
+	return map[string]SecretResolver{
+		SecretSchemeEnv: SecretResolverFunc(func(_ context.Context, name string) (string, error) {
+			v, ok := os.LookupEnv(name)
+			if !ok {
+				return "", fmt.Errorf("environment variable %s is not set", name)
+			}
+			return v, nil
+		}),
+		SecretSchemeFile: SecretResolverFunc(func(_ context.Context, path string) (string, error) {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimRight(string(b), "\r\n"), nil
+		}),
+	}
+}
+
+// WithSecrets makes Serve resolve the secret references (see SecretRefPrefix) of custom_config
+// values before they reach the plugin's Configure, with resolvers keyed by scheme in addition to
+// the env and file schemes. Settings reported by the SDK, such as the admin service's config
+// digest, keep the references rather than the secrets.
+func WithSecrets(resolvers map[string]SecretResolver) ServeOption {
+	return func(o *serveOptions) error {
+		all := defaultSecretResolvers()
+		for scheme, r := range resolvers {
+			if scheme == "" || strings.Contains(scheme, ":") {
+				return fmt.Errorf("invalid secret scheme %q", scheme)
+			}
+			if r == nil {
+				return fmt.Errorf("secret resolver for %s cannot be nil", scheme)
+			}
+			all[scheme] = r
+		}
+		o.interceptors = append(o.interceptors, secretsInterceptor(all))
+		return nil
+	}
+}
+
+// ResolveSecrets returns a copy of custom with the secret references resolved by resolvers, keyed
+// by scheme. Values without SecretRefPrefix are copied as they are, and custom itself is returned
+// when it holds no reference.
+func ResolveSecrets(
+	ctx context.Context,
+	custom map[string]string,
+	resolvers map[string]SecretResolver,
+) (map[string]string, error) {
+	var out map[string]string
+	for k, v := range custom {
+		rest, ok := strings.CutPrefix(v, SecretRefPrefix)
+		if !ok {
+			continue
+		}
+		scheme, ref, ok := strings.Cut(rest, ":")
+		if !ok || ref == "" {
+			return nil, fmt.Errorf("custom_config key %s: secret reference must be %s<scheme>:<ref>",
+				k, SecretRefPrefix)
+		}
+		r, ok := resolvers[scheme]
+		if !ok {
+			return nil, fmt.Errorf("custom_config key %s: unknown secret scheme %q", k, scheme)
+		}
+		secret, err := r.ResolveSecret(ctx, ref)
+		if err != nil {
+			// The reference, not the secret, is safe to report.
+			return nil, fmt.Errorf("custom_config key %s: resolving %s: %w", k, v, err)
+		}
+		if out == nil {
+			out = maps.Clone(custom)
+		}
+		out[k] = secret
+	}
+	if out == nil {
+		return custom, nil
+	}
+
+	return out, nil
+}
+
+func secretsInterceptor(resolvers map[string]SecretResolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		cfg, ok := req.(*PluginConfig)
+		if !ok || info.FullMethod != Plugin_Configure_FullMethodName {
+			return handler(ctx, req)
+		}
+
+		custom, err := ResolveSecrets(ctx, cfg.GetCustomConfig(), resolvers)
+		if err != nil {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+
+		return handler(ctx, &PluginConfig{Telemetry: cfg.GetTelemetry(), CustomConfig: custom})
+	}
+}
@@ -0,0 +1,222 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	t.Setenv("SECRETS_TEST_TOKEN", "env-secret")
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("file-secret\r\n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	resolvers := defaultSecretResolvers()
+	resolvers["stub"] = SecretResolverFunc(func(_ context.Context, ref string) (string, error) {
+		if ref == "fail" {
+			return "", errors.New("store unavailable")
+		}
+		return "stub:" + ref, nil
+	})
+
+	tests := []struct {
+		name    string
+		custom  map[string]string
+		want    map[string]string
+		wantErr string
+	}{
+		{name: "no references", custom: map[string]string{"mode": "strict"}, want: map[string]string{"mode": "strict"}},
+		{name: "nil", custom: nil, want: nil},
+		{
+			name:   "env",
+			custom: map[string]string{"token": "secret:env:SECRETS_TEST_TOKEN", "mode": "strict"},
+			want:   map[string]string{"token": "env-secret", "mode": "strict"},
+		},
+		{
+			name:   "file without trailing newlines",
+			custom: map[string]string{"token": "secret:file:" + path},
+			want:   map[string]string{"token": "file-secret"},
+		},
+		{
+			name:   "custom scheme with colons in the reference",
+			custom: map[string]string{"token": "secret:stub:a:b#c"},
+			want:   map[string]string{"token": "stub:a:b#c"},
+		},
+		{
+			name:    "unset variable",
+			custom:  map[string]string{"token": "secret:env:SECRETS_TEST_UNSET"},
+			wantErr: "custom_config key token: resolving secret:env:SECRETS_TEST_UNSET: environment variable",
+		},
+		{
+			name:    "missing file",
+			custom:  map[string]string{"token": "secret:file:" + path + ".missing"},
+			wantErr: "no such file",
+		},
+		{
+			name:    "no scheme",
+			custom:  map[string]string{"token": "secret:env"},
+			wantErr: "custom_config key token: secret reference must be secret:<scheme>:<ref>",
+		},
+		{
+			name:    "empty reference",
+			custom:  map[string]string{"token": "secret:env:"},
+			wantErr: "must be secret:<scheme>:<ref>",
+		},
+		{
+			name:    "unknown scheme",
+			custom:  map[string]string{"token": "secret:aws:db"},
+			wantErr: `custom_config key token: unknown secret scheme "aws"`,
+		},
+		{
+			name:    "resolver error",
+			custom:  map[string]string{"token": "secret:stub:fail"},
+			wantErr: "resolving secret:stub:fail: store unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := maps.Clone(tt.custom)
+			got, err := ResolveSecrets(context.Background(), tt.custom, resolvers)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ResolveSecrets error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("ResolveSecrets = %v, want %v", got, tt.want)
+			}
+			if !maps.Equal(tt.custom, input) {
+				t.Errorf("ResolveSecrets modified its input to %v", tt.custom)
+			}
+		})
+	}
+}
+
+func TestWithSecretsErrors(t *testing.T) {
+	stub := SecretResolverFunc(func(context.Context, string) (string, error) { return "", nil })
+	tests := []struct {
+		name      string
+		resolvers map[string]SecretResolver
+		wantErr   string
+	}{
+		{name: "empty scheme", resolvers: map[string]SecretResolver{"": stub}, wantErr: `invalid secret scheme ""`},
+		{name: "colon", resolvers: map[string]SecretResolver{"a:b": stub}, wantErr: `invalid secret scheme "a:b"`},
+		{
+			name:      "nil resolver",
+			resolvers: map[string]SecretResolver{"vault": nil},
+			wantErr:   "resolver for vault cannot be nil",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newServeOptions(WithSecrets(tt.resolvers))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("WithSecrets error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretsInterceptor(t *testing.T) {
+	t.Setenv("SECRETS_TEST_TOKEN", "env-secret")
+	o, err := newServeOptions(WithSecrets(map[string]SecretResolver{
+		// Registered resolvers replace the defaults of their scheme.
+		SecretSchemeEnv: SecretResolverFunc(func(_ context.Context, ref string) (string, error) {
+			return "overridden:" + ref, nil
+		}),
+		"vault": SecretResolverFunc(func(_ context.Context, ref string) (string, error) {
+			if ref == "fail" {
+				return "", errors.New("sealed")
+			}
+			return "vault:" + ref, nil
+		}),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	intercept := chainInterceptors(o.interceptors)
+	telemetry := &TelemetryConfig{ServiceName: "plugin"}
+
+	tests := []struct {
+		name     string
+		method   string
+		req      any
+		want     any
+		wantCode codes.Code
+	}{
+		{
+			name:   "configure",
+			method: Plugin_Configure_FullMethodName,
+			req: &PluginConfig{
+				Telemetry:    telemetry,
+				CustomConfig: map[string]string{"a": "secret:vault:db#pw", "b": "secret:env:X", "c": "plain"},
+			},
+			want: &PluginConfig{
+				Telemetry:    telemetry,
+				CustomConfig: map[string]string{"a": "vault:db#pw", "b": "overridden:X", "c": "plain"},
+			},
+		},
+		{
+			name:     "unresolvable reference",
+			method:   Plugin_Configure_FullMethodName,
+			req:      &PluginConfig{CustomConfig: map[string]string{"a": "secret:vault:fail"}},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:   "other methods",
+			method: Plugin_HandleRequest_FullMethodName,
+			req:    &HTTPRequest{Path: "secret:vault:db#pw"},
+			want:   &HTTPRequest{Path: "secret:vault:db#pw"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got any
+			handler := func(_ context.Context, req any) (any, error) {
+				got = req
+				return req, nil
+			}
+			_, err := intercept(context.Background(), tt.req, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if tt.wantCode != codes.OK {
+				if status.Code(err) != tt.wantCode || got != nil {
+					t.Errorf("error = %v, want %s before the handler", err, tt.wantCode)
+				}
+				if !strings.Contains(err.Error(), "resolving secret:vault:fail: sealed") {
+					t.Errorf("error %q does not name the reference", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(got.(proto.Message), tt.want.(proto.Message)) {
+				t.Errorf("handler got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// The request mcpd sent keeps its references.
+	req := &PluginConfig{CustomConfig: map[string]string{"a": "secret:vault:db#pw"}}
+	handler := func(_ context.Context, req any) (any, error) { return req, nil }
+	info := &grpc.UnaryServerInfo{FullMethod: Plugin_Configure_FullMethodName}
+	if _, err := intercept(context.Background(), req, info, handler); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.GetCustomConfig()["a"]; got != "secret:vault:db#pw" {
+		t.Errorf("original request value = %q, want the reference", got)
+	}
+}
@@ -0,0 +1,187 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/manifest"
+)
+
+// dependencyCheckTimeout bounds each DependencyCheck run for the startup report.
+const dependencyCheckTimeout = 5 * time.Second
+
+// DependencyCheck probes something the plugin needs to work, such as an upstream API or a
+// database, for the startup report. Check returns nil when the dependency is healthy.
+type DependencyCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// StartupReport is the self-check Serve emits once the plugin is ready to serve, when
+// WithStartupReport is configured.
+type StartupReport struct {
+	// OK is false when a dependency check failed or the plugin's metadata could not be read.
+	OK bool `json:"ok"`
+
+	Time    time.Time `json:"time"`
+	Network string    `json:"network"`
+	Address string    `json:"address"`
+
+	// Plugin and Version are the plugin's GetMetadata name and version, and Commit the commit of
+	// its build manifest when one is embedded.
+	Plugin  string `json:"plugin,omitempty"`
+	Version string `json:"version,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+
+	// APIVersion is the plugin API version the SDK speaks (ProtoVersion), and Features the optional
+	// features offered to mcpd for negotiation.
+	APIVersion string   `json:"apiVersion"`
+	Features   []string `json:"features,omitempty"`
+
+	// Flows are the flows the plugin returns from GetCapabilities, and Fields the request fields
+	// it subscribes to as a FieldSubscriber.
+	Flows  []string `json:"flows,omitempty"`
+	Fields []string `json:"fields,omitempty"`
+
+	// ConfigDigest is "sha256:<hex>" of the configuration applied from --config, if any.
+	ConfigDigest string `json:"configDigest,omitempty"`
+
+	Dependencies []DependencyStatus `json:"dependencies,omitempty"`
+
+	// Errors lists what went wrong reading the plugin's metadata or capabilities.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// DependencyStatus is the outcome of one DependencyCheck.
+type DependencyStatus struct {
+	Name     string        `json:"name"`
+	OK       bool          `json:"ok"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"durationNs"`
+}
+
+// WithStartupReport makes Serve write a StartupReport as a single JSON line to w once the server
+// is listening, so supervisors and humans can tell at a glance whether the plugin came up
+// correctly. A nil w sends the report to the logger instead. Each check runs with a 5s timeout;
+// failed checks are reported but do not stop the plugin from serving.
+//
+// Usage:
+//
+//	err := mcpdpluginsv1.Serve(&MyPlugin{}, mcpdpluginsv1.WithStartupReport(os.Stdout,
+//	    mcpdpluginsv1.DependencyCheck{Name: "upstream", Check: pingUpstream},
+//	))
+func WithStartupReport(w io.Writer, checks ...DependencyCheck) ServeOption {
+	return func(o *serveOptions) error {
+		for _, c := range checks {
+			if c.Name == "" || c.Check == nil {
+				return fmt.Errorf("dependency checks need a name and a check function")
+			}
+		}
+		o.startup = &startupReporter{w: w, checks: checks}
+		return nil
+	}
+}
+
+// startupReporter builds and writes the StartupReport.
+type startupReporter struct {
+	w      io.Writer
+	checks []DependencyCheck
+}
+
+// startupInfo is what Serve knows about its own setup when the report is built.
+type startupInfo struct {
+	impl         PluginServer
+	network      string
+	address      string
+	features     features.Set
+	fields       FieldSet
+	configDigest string
+}
+
+// report builds the StartupReport for the plugin described by info.
+func (r *startupReporter) report(ctx context.Context, info startupInfo) StartupReport {
+	rep := StartupReport{
+		OK:           true,
+		Time:         time.Now().UTC(),
+		Network:      info.network,
+		Address:      info.address,
+		APIVersion:   ProtoVersion,
+		ConfigDigest: info.configDigest,
+	}
+	for _, f := range info.features {
+		rep.Features = append(rep.Features, string(f))
+	}
+	for _, f := range info.fields {
+		rep.Fields = append(rep.Fields, string(f))
+	}
+	if env, err := manifest.Decode(manifest.Embedded()); err == nil {
+		if m, err := env.Manifest(); err == nil {
+			rep.Commit = m.Commit
+		}
+	}
+
+	if md, err := info.impl.GetMetadata(ctx, &emptypb.Empty{}); err != nil {
+		rep.Errors = append(rep.Errors, fmt.Sprintf("GetMetadata: %v", err))
+	} else {
+		rep.Plugin, rep.Version = md.GetName(), md.GetVersion()
+		if rep.Commit == "" {
+			rep.Commit = md.GetCommitHash()
+		}
+	}
+	if caps, err := info.impl.GetCapabilities(ctx, &emptypb.Empty{}); err != nil {
+		rep.Errors = append(rep.Errors, fmt.Sprintf("GetCapabilities: %v", err))
+	} else {
+		for _, f := range caps.GetFlows() {
+			rep.Flows = append(rep.Flows, f.String())
+		}
+	}
+
+	for _, c := range r.checks {
+		rep.Dependencies = append(rep.Dependencies, runDependencyCheck(ctx, c))
+	}
+	for _, d := range rep.Dependencies {
+		rep.OK = rep.OK && d.OK
+	}
+	rep.OK = rep.OK && len(rep.Errors) == 0
+
+	return rep
+}
+
+func runDependencyCheck(ctx context.Context, c DependencyCheck) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, dependencyCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.Check(ctx)
+	s := DependencyStatus{Name: c.Name, OK: err == nil, Duration: time.Since(start)}
+	if err != nil {
+		s.Error = err.Error()
+	}
+
+	return s
+}
+
+// emit builds the report and writes it.
+func (r *startupReporter) emit(ctx context.Context, o *serveOptions, info startupInfo) {
+	rep := r.report(ctx, info)
+	line, err := json.Marshal(rep)
+	if err != nil {
+		o.logger.Printf("failed to encode startup report: %v", err)
+		return
+	}
+
+	if r.w == nil {
+		o.logger.Printf("startup report: %s", line)
+	} else if _, err := fmt.Fprintf(r.w, "%s\n", line); err != nil {
+		o.logger.Printf("failed to write startup report: %v", err)
+	}
+	if !rep.OK {
+		o.logger.Printf("startup self-check failed, see the startup report")
+	}
+}
@@ -0,0 +1,286 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+)
+
+// reportedPlugin answers GetMetadata and GetCapabilities for the startup report, failing each
+// when its error is set.
+type reportedPlugin struct {
+	BasePlugin
+
+	metadataErr     error
+	capabilitiesErr error
+}
+
+func (p *reportedPlugin) GetMetadata(context.Context, *emptypb.Empty) (*Metadata, error) {
+	if p.metadataErr != nil {
+		return nil, p.metadataErr
+	}
+	return &Metadata{Name: "reported", Version: "1.2.3", CommitHash: "abc123"}, nil
+}
+
+func (p *reportedPlugin) GetCapabilities(context.Context, *emptypb.Empty) (*Capabilities, error) {
+	if p.capabilitiesErr != nil {
+		return nil, p.capabilitiesErr
+	}
+	return NewCapabilities(Flow_FLOW_REQUEST, Flow_FLOW_RESPONSE), nil
+}
+
+func TestWithStartupReport(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	tests := []struct {
+		name    string
+		checks  []DependencyCheck
+		wantErr bool
+	}{
+		{name: "no checks"},
+		{name: "named checks", checks: []DependencyCheck{{Name: "a", Check: ok}, {Name: "b", Check: ok}}},
+		{name: "missing name", checks: []DependencyCheck{{Check: ok}}, wantErr: true},
+		{name: "missing check", checks: []DependencyCheck{{Name: "a"}}, wantErr: true},
+		{
+			name:    "one invalid among valid",
+			checks:  []DependencyCheck{{Name: "a", Check: ok}, {Name: "b"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(WithStartupReport(&bytes.Buffer{}, tt.checks...))
+			if tt.wantErr {
+				if err == nil {
+					t.Error("WithStartupReport accepted an invalid dependency check")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.startup == nil || len(o.startup.checks) != len(tt.checks) {
+				t.Errorf("startup reporter = %+v, want %d checks", o.startup, len(tt.checks))
+			}
+		})
+	}
+}
+
+func TestStartupReport(t *testing.T) {
+	failed := errors.New("connection refused")
+	healthy := DependencyCheck{Name: "db", Check: func(context.Context) error { return nil }}
+	down := DependencyCheck{Name: "upstream", Check: func(context.Context) error { return failed }}
+	tests := []struct {
+		name       string
+		plugin     *reportedPlugin
+		checks     []DependencyCheck
+		wantOK     bool
+		wantDeps   []DependencyStatus
+		wantErrors []string
+	}{
+		{
+			name:   "healthy",
+			plugin: &reportedPlugin{},
+			checks: []DependencyCheck{healthy},
+			wantOK: true,
+			wantDeps: []DependencyStatus{
+				{Name: "db", OK: true},
+			},
+		},
+		{
+			name:   "no checks",
+			plugin: &reportedPlugin{},
+			wantOK: true,
+		},
+		{
+			name:   "failed dependency",
+			plugin: &reportedPlugin{},
+			checks: []DependencyCheck{healthy, down},
+			wantDeps: []DependencyStatus{
+				{Name: "db", OK: true},
+				{Name: "upstream", Error: "connection refused"},
+			},
+		},
+		{
+			name:       "metadata error",
+			plugin:     &reportedPlugin{metadataErr: errors.New("no metadata")},
+			wantErrors: []string{"GetMetadata: no metadata"},
+		},
+		{
+			name: "metadata and capabilities errors",
+			plugin: &reportedPlugin{
+				metadataErr:     errors.New("no metadata"),
+				capabilitiesErr: errors.New("no capabilities"),
+			},
+			checks:     []DependencyCheck{healthy},
+			wantDeps:   []DependencyStatus{{Name: "db", OK: true}},
+			wantErrors: []string{"GetMetadata: no metadata", "GetCapabilities: no capabilities"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &startupReporter{checks: tt.checks}
+			rep := r.report(context.Background(), startupInfo{
+				impl:         tt.plugin,
+				network:      "unix",
+				address:      "/tmp/plugin.sock",
+				features:     features.Set{features.HeadersOnly, features.SelectiveFields},
+				fields:       FieldSet{FieldMethod, FieldHeaders},
+				configDigest: "sha256:00",
+			})
+
+			if rep.OK != tt.wantOK {
+				t.Errorf("OK = %t, want %t", rep.OK, tt.wantOK)
+			}
+			if rep.Network != "unix" || rep.Address != "/tmp/plugin.sock" || rep.ConfigDigest != "sha256:00" {
+				t.Errorf("report = %+v, want the listener and config digest", rep)
+			}
+			if rep.APIVersion != ProtoVersion {
+				t.Errorf("APIVersion = %q, want %q", rep.APIVersion, ProtoVersion)
+			}
+			if want := []string{"headers_only", "selective_fields"}; !slices.Equal(rep.Features, want) {
+				t.Errorf("Features = %v, want %v", rep.Features, want)
+			}
+			if want := []string{"method", "headers"}; !slices.Equal(rep.Fields, want) {
+				t.Errorf("Fields = %v, want %v", rep.Fields, want)
+			}
+			if !slices.Equal(rep.Errors, tt.wantErrors) {
+				t.Errorf("Errors = %q, want %q", rep.Errors, tt.wantErrors)
+			}
+			if tt.plugin.metadataErr == nil &&
+				(rep.Plugin != "reported" || rep.Version != "1.2.3" || rep.Commit != "abc123") {
+				t.Errorf("plugin = %s %s %s, want reported 1.2.3 abc123", rep.Plugin, rep.Version, rep.Commit)
+			}
+			wantFlows := []string{Flow_FLOW_REQUEST.String(), Flow_FLOW_RESPONSE.String()}
+			if tt.plugin.capabilitiesErr != nil {
+				wantFlows = nil
+			}
+			if !slices.Equal(rep.Flows, wantFlows) {
+				t.Errorf("Flows = %v, want %v", rep.Flows, wantFlows)
+			}
+
+			if len(rep.Dependencies) != len(tt.wantDeps) {
+				t.Fatalf("Dependencies = %+v, want %+v", rep.Dependencies, tt.wantDeps)
+			}
+			for i, d := range rep.Dependencies {
+				want := tt.wantDeps[i]
+				if d.Name != want.Name || d.OK != want.OK || d.Error != want.Error {
+					t.Errorf("dependency %d = %+v, want %+v", i, d, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunDependencyCheck(t *testing.T) {
+	s := runDependencyCheck(context.Background(), DependencyCheck{
+		Name: "slow",
+		Check: func(ctx context.Context) error {
+			deadline, ok := ctx.Deadline()
+			if !ok || time.Until(deadline) > dependencyCheckTimeout {
+				t.Errorf("check deadline = %s (set %t), want within %s", deadline, ok, dependencyCheckTimeout)
+			}
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		},
+	})
+	if !s.OK || s.Duration < 10*time.Millisecond {
+		t.Errorf("status = %+v, want OK after at least 10ms", s)
+	}
+
+	// A check outliving its context sees the context error.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s = runDependencyCheck(ctx, DependencyCheck{Name: "canceled", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	if s.OK || s.Error != context.Canceled.Error() {
+		t.Errorf("status = %+v, want the context error", s)
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("closed pipe") }
+
+func TestStartupReporterEmit(t *testing.T) {
+	down := DependencyCheck{Name: "upstream", Check: func(context.Context) error { return errors.New("down") }}
+	tests := []struct {
+		name       string
+		toLogger   bool
+		failWrites bool
+		checks     []DependencyCheck
+		wantLogs   []string
+		wantOut    bool
+	}{
+		{
+			name:    "written as one JSON line",
+			wantOut: true,
+		},
+		{
+			name:     "logged without a writer",
+			toLogger: true,
+			wantLogs: []string{`startup report: {"ok":true`},
+		},
+		{
+			name:     "failed self-check is logged",
+			checks:   []DependencyCheck{down},
+			wantLogs: []string{"startup self-check failed"},
+			wantOut:  true,
+		},
+		{
+			name:       "write error is logged",
+			failWrites: true,
+			wantLogs:   []string{"failed to write startup report: closed pipe"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, logs bytes.Buffer
+			r := &startupReporter{checks: tt.checks}
+			switch {
+			case tt.failWrites:
+				r.w = failingWriter{}
+			case !tt.toLogger:
+				r.w = &out
+			}
+			o := &serveOptions{logger: log.New(&logs, "", 0)}
+			info := startupInfo{impl: &reportedPlugin{}, network: "tcp", address: "127.0.0.1:0"}
+			r.emit(context.Background(), o, info)
+
+			for _, want := range tt.wantLogs {
+				if !strings.Contains(logs.String(), want) {
+					t.Errorf("logs = %q, want them to contain %q", logs.String(), want)
+				}
+			}
+			if !tt.wantOut {
+				if out.Len() != 0 {
+					t.Errorf("report written to the writer: %q", out.String())
+				}
+				return
+			}
+			line, ok := strings.CutSuffix(out.String(), "\n")
+			if !ok || strings.Contains(line, "\n") {
+				t.Fatalf("output = %q, want a single JSON line", out.String())
+			}
+			var rep StartupReport
+			if err := json.Unmarshal([]byte(line), &rep); err != nil {
+				t.Fatal(err)
+			}
+			if rep.Plugin != "reported" || rep.Address != "127.0.0.1:0" || rep.OK != (len(tt.checks) == 0) {
+				t.Errorf("decoded report = %+v", rep)
+			}
+		})
+	}
+}
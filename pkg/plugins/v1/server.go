@@ -1,21 +1,40 @@
 package mcpdpluginsv1
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"log"
 	"net"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"google.golang.org/grpc"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/sandbox"
 )
 
 // Serve is a convenience function that handles all the boilerplate for running a plugin server.
 // It parses command-line flags, sets up the appropriate network listener, creates a gRPC server,
 // and serves the plugin implementation.
 //
+// Optional behavior, such as subscribing to SDK events, is enabled with ServeOption values.
+//
+// Handlers never receive nil messages: a nil one is replaced with an empty message, so a nil
+// HTTPRequest arrives with no method, headers or body. Every SDK helper accepts such messages,
+// treating absent fields as empty, and those that cannot do without a field, such as
+// ValidateRewrite without a method, return an error rather than panic. A handler returning a nil
+// result without an error fails the call with codes.Internal, so the mistake surfaces as a
+// plugin failure rather than as a verdict.
+//
+// Run with --healthcheck, the binary checks the plugin already serving at --address instead of
+// serving: it calls CheckHealth and returns nil if the plugin is healthy and an error wrapping
+// ErrUnhealthy if not, so a main that exits non-zero on error, like the one below, lets container
+// images declare a health check without shipping other tools:
+//
+//	HEALTHCHECK CMD ["/plugin", "--address", "/run/plugin.sock", "--healthcheck"]
+//
 // Usage:
 //
 //	import (
@@ -29,15 +48,43 @@ import (
 //	        log.Fatal(err)
 //	    }
 //	}
-func Serve(impl PluginServer) error {
-	var address, network string
+func Serve(impl PluginServer, opts ...ServeOption) error {
+	o, err := newServeOptions(opts...)
+	if err != nil {
+		return fmt.Errorf("invalid serve options: %w", err)
+	}
+	defer o.shutdownOTLP(context.Background())
+
+	var address, network, configPath, tuningPreset, adminAddress string
+	var healthcheck bool
 	flag.StringVar(&address, "address", "", "gRPC address (socket path for unix, host:port for tcp)")
 	flag.StringVar(&network, "network", "unix", "Network type (unix or tcp)")
+	flag.StringVar(&configPath, "config", "", "YAML plugin config file for standalone runs (reloaded on change)")
+	flag.StringVar(&tuningPreset, "tuning", "", "gRPC server tuning preset (latency or throughput)")
+	flag.StringVar(&adminAddress, "admin-address", "", "Unix socket path for the admin service (disabled if empty)")
+	flag.BoolVar(&healthcheck, "healthcheck", false, "Exit 0 if the plugin at --address passes CheckHealth, 1 if not")
 	flag.Parse()
 
 	if address == "" {
 		return fmt.Errorf("--address flag is required")
 	}
+	if healthcheck {
+		return o.healthcheck(os.Stdout, network, address)
+	}
+	if tuningPreset != "" {
+		t, err := TuningPreset(tuningPreset)
+		if err != nil {
+			return fmt.Errorf("invalid --tuning flag: %w", err)
+		}
+		o.tuning = &t
+	}
+	if adminAddress != "" {
+		o.admin = &adminListener{network: "unix", address: adminAddress}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	o.bus.Publish(ctx, Event{Kind: EventLifecycle, Phase: PhaseStarting, Network: network, Address: address})
 
 	lis, err := net.Listen(network, address)
 	if err != nil {
@@ -49,22 +96,188 @@ func Serve(impl PluginServer) error {
 		defer func() { _ = os.Remove(address) }()
 	}
 
-	grpcServer := grpc.NewServer()
-	RegisterPluginServer(grpcServer, impl)
+	fields, err := fieldSet(impl)
+	if err != nil {
+		return err
+	}
+
+	offered := features.NewSet(append(SupportedFeatures(), o.features(fields)...)...)
+
+	interceptors, err := o.unaryInterceptors(impl, fields, offered)
+	if err != nil {
+		return err
+	}
+	serverOpts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(interceptors...)}
+	if o.tuning != nil {
+		serverOpts = append(serverOpts, o.tuning.serverOptions()...)
+	}
+	serverOpts = append(serverOpts, o.tlsServerOptions()...)
+	for _, h := range o.statsHandlers {
+		serverOpts = append(serverOpts, grpc.StatsHandler(h))
+	}
+	var grpcServer *grpc.Server
+	if o.pooling {
+		poolOpts, desc := poolServerOptions()
+		grpcServer = grpc.NewServer(append(serverOpts, poolOpts...)...)
+		grpcServer.RegisterService(desc, impl)
+	} else {
+		grpcServer = grpc.NewServer(append(serverOpts, grpc.ForceServerCodecV2(newVTCodec()))...)
+		RegisterPluginServer(grpcServer, impl)
+	}
+
+	// Outside mcpd, configuration comes from a file applied through the same interceptor chain.
+	if configPath != "" {
+		loader := &configFileLoader{
+			path:      configPath,
+			impl:      impl,
+			intercept: chainInterceptors(interceptors),
+			logger:    o.logger,
+		}
+		if err := loader.apply(ctx); err != nil {
+			return err
+		}
+		if err := loader.watch(ctx); err != nil {
+			return err
+		}
+	}
+
+	if o.admin != nil {
+		stopAdmin, err := (&adminServer{o: o, impl: impl}).serve(o.admin)
+		if err != nil {
+			return err
+		}
+		defer stopAdmin()
+	}
+	if o.probes != nil {
+		stopProbes, err := o.probes.serve(o, impl, chainInterceptors(interceptors))
+		if err != nil {
+			return err
+		}
+		defer stopProbes()
+	}
 
-	// Handle graceful shutdown.
+	if !o.noDiagnostics {
+		go o.handleDiagnosticSignals(ctx, impl)
+	}
+
+	// Handle graceful shutdown, on a signal or when the resource guard asks for a restart.
+	var restart <-chan struct{}
+	if o.resources != nil {
+		restart = o.resources.restart
+		go o.resources.run(ctx, o)
+	}
 	go func() {
 		sigCh := make(chan os.Signal, 1)
 		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-		<-sigCh
-		log.Println("Shutting down gracefully...")
+		select {
+		case <-sigCh:
+			o.logger.Println("Shutting down gracefully...")
+		case <-restart:
+			o.logger.Println("Resource limits exceeded, shutting down for restart...")
+		}
+		o.bus.Publish(ctx, Event{Kind: EventLifecycle, Phase: PhaseStopping, Network: network, Address: address})
+		o.awaitTermination(sigCh)
 		grpcServer.GracefulStop()
 	}()
 
-	log.Printf("Plugin server listening on %s %s", network, address)
+	if o.sandbox != nil {
+		if err := sandbox.Apply(*o.sandbox); err != nil {
+			return fmt.Errorf("failed to sandbox plugin: %w", err)
+		}
+	}
+
+	o.logger.Printf("Plugin server listening on %s %s", network, address)
+	o.bus.Publish(ctx, Event{Kind: EventLifecycle, Phase: PhaseServing, Network: network, Address: address})
+	defer o.bus.Publish(ctx, Event{Kind: EventLifecycle, Phase: PhaseStopped, Network: network, Address: address})
+	if o.startup != nil {
+		go o.startup.emit(ctx, o, startupInfo{
+			impl:         impl,
+			network:      network,
+			address:      address,
+			features:     offered,
+			fields:       fields,
+			configDigest: o.configDigest.current(),
+		})
+	}
+	if o.registration != nil {
+		stopRegistration := o.registration.start(ctx, o, startupInfo{
+			impl:     impl,
+			network:  network,
+			address:  address,
+			features: offered,
+		})
+		defer stopRegistration()
+	}
+
 	if err := grpcServer.Serve(lis); err != nil {
 		return fmt.Errorf("failed to serve: %w", err)
 	}
+	if o.resources != nil && o.resources.restarting() {
+		return ErrResourceLimit
+	}
 
 	return nil
 }
+
+// unaryInterceptors returns the interceptor chain Serve runs every plugin RPC through, ending with
+// the interceptors added with WithUnaryInterceptor.
+func (o *serveOptions) unaryInterceptors(
+	impl PluginServer,
+	fields FieldSet,
+	offered features.Set,
+) ([]grpc.UnaryServerInterceptor, error) {
+	interceptors := []grpc.UnaryServerInterceptor{
+		eventInterceptor(o.bus),
+		recoveryInterceptor(o),
+		nilMessageInterceptor(),
+		configWarningsInterceptor(o),
+		configDigestInterceptor(o),
+		versionSkewInterceptor(o),
+		featuresInterceptor(offered),
+		manifestInterceptor(),
+		targetInterceptor(),
+	}
+	if fields != nil {
+		interceptors = append(interceptors, fieldsInterceptor(fields))
+	}
+	if o.strict {
+		interceptors = append(interceptors, strictInterceptor())
+	}
+	if o.headerLimits != nil {
+		interceptors = append(interceptors, headerLimitsInterceptor(o, *o.headerLimits))
+	}
+	if o.headersOnly || fields != nil {
+		interceptors = append(interceptors, bodyInterceptor())
+	}
+	if !o.noDeadline {
+		interceptors = append(interceptors, deadlineInterceptor())
+	}
+	if o.resources != nil {
+		interceptors = append(interceptors, o.resources.interceptor(o))
+	}
+	if o.terminationGrace > 0 {
+		interceptors = append(interceptors, terminationInterceptor(o))
+	}
+	if o.backpressure != nil {
+		interceptors = append(interceptors, o.backpressure.interceptor(o))
+	}
+	if o.shadow {
+		interceptors = append(interceptors, shadowInterceptor(o))
+	}
+	if o.candidate != nil {
+		interceptors = append(interceptors, o.candidate.interceptor())
+	}
+	if si, err := schemaInterceptor(impl); err != nil {
+		return nil, err
+	} else if si != nil {
+		interceptors = append(interceptors, si)
+	}
+	if ii, err := inventoryInterceptor(impl); err != nil {
+		return nil, err
+	} else if ii != nil {
+		interceptors = append(interceptors, ii)
+	}
+	interceptors = append(interceptors, o.interceptors...)
+
+	return interceptors, nil
+}
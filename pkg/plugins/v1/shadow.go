@@ -0,0 +1,63 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"path"
+
+	"google.golang.org/grpc"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// WithShadowMode runs the plugin as a dry run: whenever HandleRequest or HandleResponse (or an
+// interceptor added with WithUnaryInterceptor) stops the chain, the verdict is logged and counted
+// as metrics.ShadowShortCircuits, and the original request or response is passed through
+// unchanged instead. Use it to trial a new policy plugin on production traffic before enforcing it.
+//
+// Events, access logs and metrics report the pass-through actually returned to mcpd.
+func WithShadowMode() ServeOption {
+	return func(o *serveOptions) error {
+		o.shadow = true
+		return nil
+	}
+}
+
+// shadowInterceptor converts short-circuit verdicts into pass-through.
+func shadowInterceptor(o *serveOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		out, ok := resp.(*HTTPResponse)
+		if err != nil || !ok || out.GetContinue() {
+			return resp, err
+		}
+
+		method := path.Base(info.FullMethod)
+		if r := o.metricsRecorder(); r != nil {
+			r.Count(metrics.ShadowShortCircuits, 1, metrics.L(metrics.LabelMethod, method))
+		}
+
+		switch in := req.(type) {
+		case *HTTPRequest:
+			o.logger.Printf(
+				"shadow mode: %s would short-circuit with status %d: http_method=%s path=%q tool=%q correlation_id=%q",
+				method, out.GetStatusCode(), in.GetMethod(), in.GetPath(),
+				mcp.ToolName(in.GetBody()), CorrelationID(ctx, in),
+			)
+			return &HTTPResponse{Continue: true, Headers: in.GetHeaders(), Body: in.GetBody()}, nil
+		case *HTTPResponse:
+			o.logger.Printf(
+				"shadow mode: %s would short-circuit with status %d: upstream_status=%d correlation_id=%q",
+				method, out.GetStatusCode(), in.GetStatusCode(), CorrelationID(ctx, nil),
+			)
+			return &HTTPResponse{
+				Continue:   true,
+				StatusCode: in.GetStatusCode(),
+				Headers:    in.GetHeaders(),
+				Body:       in.GetBody(),
+			}, nil
+		default:
+			return resp, err
+		}
+	}
+}
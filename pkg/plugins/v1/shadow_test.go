@@ -0,0 +1,148 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+func TestShadowInterceptor(t *testing.T) {
+	errBoom := errors.New("boom")
+	req := &HTTPRequest{
+		Method:  "POST",
+		Path:    "/mcp",
+		Headers: map[string]string{"X-Request-Id": "req-1"},
+		Body:    []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search"}}`),
+	}
+	resp := &HTTPResponse{StatusCode: 200, Headers: map[string]string{"A": "b"}, Body: []byte("ok")}
+	tests := []struct {
+		name       string
+		method     string
+		in         any
+		result     any
+		err        error
+		want       any
+		wantLog    string // Prefix of the logged line; empty for none.
+		wantMetric []string
+	}{
+		{
+			name:   "short-circuited request passes through",
+			method: Plugin_HandleRequest_FullMethodName,
+			in:     req,
+			result: &HTTPResponse{StatusCode: 403},
+			want:   &HTTPResponse{Continue: true, Headers: req.Headers, Body: req.Body},
+			wantLog: `shadow mode: HandleRequest would short-circuit with status 403: http_method=POST path="/mcp" ` +
+				`tool="search" correlation_id="req-1"`,
+			wantMetric: []string{"count shadow.short_circuits 1 method=HandleRequest"},
+		},
+		{
+			name:   "short-circuited response passes through",
+			method: Plugin_HandleResponse_FullMethodName,
+			in:     resp,
+			result: &HTTPResponse{StatusCode: 502, Body: []byte("blocked")},
+			want: &HTTPResponse{
+				Continue:   true,
+				StatusCode: 200,
+				Headers:    resp.Headers,
+				Body:       resp.Body,
+			},
+			wantLog:    `shadow mode: HandleResponse would short-circuit with status 502: upstream_status=200`,
+			wantMetric: []string{"count shadow.short_circuits 1 method=HandleResponse"},
+		},
+		{
+			name:   "continue is untouched",
+			method: Plugin_HandleRequest_FullMethodName,
+			in:     req,
+			result: &HTTPResponse{Continue: true, Body: []byte("rewritten")},
+			want:   &HTTPResponse{Continue: true, Body: []byte("rewritten")},
+		},
+		{
+			name:   "errors are untouched",
+			method: Plugin_HandleRequest_FullMethodName,
+			in:     req,
+			err:    errBoom,
+		},
+		{
+			name:   "other results are untouched",
+			method: Plugin_GetMetadata_FullMethodName,
+			in:     nil,
+			result: &Metadata{Name: "p"},
+			want:   &Metadata{Name: "p"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			r := &recorderLog{}
+			o, err := newServeOptions(WithShadowMode(), WithMetrics(r), WithLogger(log.New(&buf, "", 0)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler := func(context.Context, any) (any, error) { return tt.result, tt.err }
+
+			got, err := shadowInterceptor(o)(context.Background(), tt.in,
+				&grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if tt.want != nil && !proto.Equal(got.(proto.Message), tt.want.(proto.Message)) {
+				t.Errorf("returned %v, want %v", got, tt.want)
+			}
+			if tt.wantLog == "" && buf.Len() != 0 || !strings.HasPrefix(buf.String(), tt.wantLog) {
+				t.Errorf("logged %q, want %q", buf.String(), tt.wantLog)
+			}
+			if got := r.named(metrics.ShadowShortCircuits); !slices.Equal(got, tt.wantMetric) {
+				t.Errorf("recorded %q, want %q", got, tt.wantMetric)
+			}
+		})
+	}
+}
+
+func TestWithShadowModeChain(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []ServeOption
+		wantStatus int32
+	}{
+		{"enforced", nil, 403},
+		{"shadow", []ServeOption{WithShadowMode()}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The custom interceptor runs inside the shadow interceptor, so its verdicts are
+			// trialled too.
+			deny := func(context.Context, any, *grpc.UnaryServerInfo, grpc.UnaryHandler) (any, error) {
+				return &HTTPResponse{StatusCode: 403}, nil
+			}
+			opts := append([]ServeOption{WithLogger(discardLogger()), WithUnaryInterceptor(deny)}, tt.opts...)
+			o, err := newServeOptions(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			interceptors, err := o.unaryInterceptors(&BasePlugin{}, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := chainInterceptors(interceptors)(context.Background(), &HTTPRequest{},
+				&grpc.UnaryServerInfo{FullMethod: Plugin_HandleRequest_FullMethodName},
+				func(context.Context, any) (any, error) { return &HTTPResponse{Continue: true}, nil })
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := got.(*HTTPResponse)
+			if out.GetStatusCode() != tt.wantStatus || out.GetContinue() != (tt.wantStatus == 0) {
+				t.Errorf("returned %v, want status %d", out, tt.wantStatus)
+			}
+		})
+	}
+}
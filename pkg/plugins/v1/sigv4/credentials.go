@@ -0,0 +1,466 @@
+package sigv4
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables read by the default credential chain, as by the AWS SDKs and CLI.
+const (
+	EnvAccessKeyID          = "AWS_ACCESS_KEY_ID"
+	EnvSecretAccessKey      = "AWS_SECRET_ACCESS_KEY"
+	EnvSessionToken         = "AWS_SESSION_TOKEN"
+	EnvRegion               = "AWS_REGION"
+	EnvDefaultRegion        = "AWS_DEFAULT_REGION"
+	EnvProfile              = "AWS_PROFILE"
+	EnvSharedCredentials    = "AWS_SHARED_CREDENTIALS_FILE"
+	EnvWebIdentityTokenFile = "AWS_WEB_IDENTITY_TOKEN_FILE"
+	EnvRoleARN              = "AWS_ROLE_ARN"
+	EnvRoleSessionName      = "AWS_ROLE_SESSION_NAME"
+	EnvContainerRelativeURI = "AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"
+	EnvContainerFullURI     = "AWS_CONTAINER_CREDENTIALS_FULL_URI"
+	EnvContainerToken       = "AWS_CONTAINER_AUTHORIZATION_TOKEN"
+	EnvContainerTokenFile   = "AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"
+	EnvMetadataDisabled     = "AWS_EC2_METADATA_DISABLED"
+	EnvMetadataEndpoint     = "AWS_EC2_METADATA_SERVICE_ENDPOINT"
+)
+
+// Endpoints of the ECS container credentials and EC2 instance metadata services.
+const (
+	containerEndpoint = "http://169.254.170.2"
+	metadataEndpoint  = "http://169.254.169.254"
+)
+
+// metadataTokenTTL is the lifetime requested for IMDSv2 session tokens.
+const metadataTokenTTL = 6 * time.Hour
+
+// expiryWindow is how long before their expiry cached credentials are refreshed.
+const expiryWindow = 5 * time.Minute
+
+// ErrNoCredentials is returned, wrapped, by a CredentialsProvider that is not configured in the
+// current environment. ChainCredentials moves on to its next provider on such errors.
+var ErrNoCredentials = errors.New("sigv4: no credentials")
+
+// Credentials are AWS security credentials.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Set for temporary credentials.
+
+	// Expires is when temporary credentials expire, or zero for long-term credentials.
+	Expires time.Time
+
+	// Source names the provider the credentials came from, such as "env" or "imds".
+	Source string
+}
+
+// CredentialsProvider retrieves AWS credentials.
+type CredentialsProvider interface {
+	Retrieve(ctx context.Context) (Credentials, error)
+}
+
+// CredentialsProviderFunc adapts a function to the CredentialsProvider interface.
+type CredentialsProviderFunc func(ctx context.Context) (Credentials, error)
+
+// Retrieve calls f(ctx).
+func (f CredentialsProviderFunc) Retrieve(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// StaticCredentials returns a provider of fixed credentials.
+func StaticCredentials(accessKeyID, secretAccessKey, sessionToken string) CredentialsProvider {
+	return CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+		return Credentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+			SessionToken:    sessionToken,
+			Source:          "static",
+		}, nil
+	})
+}
+
+// DefaultCredentials returns the default credential chain of the AWS SDKs, cached until shortly
+// before the credentials expire: environment variables, the shared credentials file, web identity
+// (EKS IRSA), container credentials (ECS, EKS Pod Identity) and the EC2 instance role. Credentials
+// are fetched with hc (nil uses an http.Client with a 5s timeout).
+func DefaultCredentials(hc *http.Client) CredentialsProvider {
+	if hc == nil {
+		hc = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	return CachedCredentials(ChainCredentials(
+		EnvCredentials(),
+		SharedCredentials("", ""),
+		WebIdentityCredentials(hc),
+		ContainerCredentials(hc),
+		InstanceCredentials(hc),
+	))
+}
+
+// ChainCredentials returns a provider trying providers in order until one is configured. An error
+// other than ErrNoCredentials stops the chain.
+func ChainCredentials(providers ...CredentialsProvider) CredentialsProvider {
+	return CredentialsProviderFunc(func(ctx context.Context) (Credentials, error) {
+		var errs []error
+		for _, p := range providers {
+			creds, err := p.Retrieve(ctx)
+			if err == nil {
+				return creds, nil
+			}
+			if !errors.Is(err, ErrNoCredentials) {
+				return Credentials{}, err
+			}
+			errs = append(errs, err)
+		}
+		if len(errs) == 0 {
+			return Credentials{}, fmt.Errorf("%w: no providers in the chain", ErrNoCredentials)
+		}
+		return Credentials{}, errors.Join(errs...)
+	})
+}
+
+// CachedCredentials returns a provider caching the credentials of p until five minutes before
+// they expire. Long-term credentials are cached indefinitely.
+func CachedCredentials(p CredentialsProvider) CredentialsProvider {
+	var (
+		mu     sync.Mutex
+		cached *Credentials
+	)
+
+	return CredentialsProviderFunc(func(ctx context.Context) (Credentials, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if cached != nil && (cached.Expires.IsZero() || time.Now().Before(cached.Expires.Add(-expiryWindow))) {
+			return *cached, nil
+		}
+		creds, err := p.Retrieve(ctx)
+		if err != nil {
+			return Credentials{}, err
+		}
+		cached = &creds
+		return creds, nil
+	})
+}
+
+// EnvCredentials returns a provider reading AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN.
+func EnvCredentials() CredentialsProvider {
+	return CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+		id, secret := os.Getenv(EnvAccessKeyID), os.Getenv(EnvSecretAccessKey)
+		if id == "" || secret == "" {
+			return Credentials{}, fmt.Errorf("%w: %s and %s are not set",
+				ErrNoCredentials, EnvAccessKeyID, EnvSecretAccessKey)
+		}
+		return Credentials{
+			AccessKeyID:     id,
+			SecretAccessKey: secret,
+			SessionToken:    os.Getenv(EnvSessionToken),
+			Source:          "env",
+		}, nil
+	})
+}
+
+// SharedCredentials returns a provider reading the static keys of profile in the shared
+// credentials file at path. An empty path defaults to AWS_SHARED_CREDENTIALS_FILE or
+// ~/.aws/credentials, and an empty profile to AWS_PROFILE or "default". Profiles configuring SSO,
+// role assumption or credential processes in ~/.aws/config are not supported.
+func SharedCredentials(path, profile string) CredentialsProvider {
+	return CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+		path := firstNonEmpty(path, os.Getenv(EnvSharedCredentials))
+		if path == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return Credentials{}, fmt.Errorf("%w: %v", ErrNoCredentials, err)
+			}
+			path = filepath.Join(home, ".aws", "credentials")
+		}
+		profile := firstNonEmpty(profile, os.Getenv(EnvProfile), "default")
+
+		f, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return Credentials{}, fmt.Errorf("%w: %s does not exist", ErrNoCredentials, path)
+		}
+		if err != nil {
+			return Credentials{}, fmt.Errorf("sigv4: reading shared credentials: %w", err)
+		}
+		defer f.Close()
+
+		values, err := iniSection(f, profile)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("sigv4: reading %s: %w", path, err)
+		}
+		if values == nil {
+			return Credentials{}, fmt.Errorf("%w: profile %s not found in %s", ErrNoCredentials, profile, path)
+		}
+		if values["aws_access_key_id"] == "" || values["aws_secret_access_key"] == "" {
+			return Credentials{}, fmt.Errorf("sigv4: profile %s in %s has no static keys", profile, path)
+		}
+		return Credentials{
+			AccessKeyID:     values["aws_access_key_id"],
+			SecretAccessKey: values["aws_secret_access_key"],
+			SessionToken:    values["aws_session_token"],
+			Source:          "shared:" + profile,
+		}, nil
+	})
+}
+
+// iniSection returns the keys of the named section of an INI file, or nil if it is absent.
+func iniSection(r io.Reader, name string) (map[string]string, error) {
+	var (
+		values  map[string]string
+		current string
+	)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+		case line[0] == '[' && strings.HasSuffix(line, "]"):
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			if current == name && values == nil {
+				values = map[string]string{}
+			}
+		case current == name:
+			k, v, ok := strings.Cut(line, "=")
+			if ok {
+				values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			}
+		}
+	}
+
+	return values, sc.Err()
+}
+
+// WebIdentityCredentials returns a provider exchanging the OIDC token in the file named by
+// AWS_WEB_IDENTITY_TOKEN_FILE for temporary credentials of the role AWS_ROLE_ARN, with STS
+// AssumeRoleWithWebIdentity, as set up by EKS IAM roles for service accounts.
+func WebIdentityCredentials(hc *http.Client) CredentialsProvider {
+	return CredentialsProviderFunc(func(ctx context.Context) (Credentials, error) {
+		tokenFile, role := os.Getenv(EnvWebIdentityTokenFile), os.Getenv(EnvRoleARN)
+		if tokenFile == "" || role == "" {
+			return Credentials{}, fmt.Errorf("%w: %s and %s are not set",
+				ErrNoCredentials, EnvWebIdentityTokenFile, EnvRoleARN)
+		}
+		token, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("sigv4: reading web identity token: %w", err)
+		}
+		session := firstNonEmpty(os.Getenv(EnvRoleSessionName), "mcpd-plugin-"+strconv.FormatInt(time.Now().Unix(), 10))
+
+		form := url.Values{
+			"Action":           {"AssumeRoleWithWebIdentity"},
+			"Version":          {"2011-06-15"},
+			"RoleArn":          {role},
+			"RoleSessionName":  {session},
+			"WebIdentityToken": {strings.TrimSpace(string(token))},
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, stsEndpoint(), strings.NewReader(form.Encode()))
+		if err != nil {
+			return Credentials{}, fmt.Errorf("sigv4: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var out struct {
+			Credentials struct {
+				AccessKeyID     string    `xml:"AccessKeyId"`
+				SecretAccessKey string    `xml:"SecretAccessKey"`
+				SessionToken    string    `xml:"SessionToken"`
+				Expiration      time.Time `xml:"Expiration"`
+			} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+		}
+		if err := do(hc, req, func(body io.Reader) error { return xml.NewDecoder(body).Decode(&out) }); err != nil {
+			return Credentials{}, fmt.Errorf("sigv4: AssumeRoleWithWebIdentity: %w", err)
+		}
+		return Credentials{
+			AccessKeyID:     out.Credentials.AccessKeyID,
+			SecretAccessKey: out.Credentials.SecretAccessKey,
+			SessionToken:    out.Credentials.SessionToken,
+			Expires:         out.Credentials.Expiration,
+			Source:          "web_identity",
+		}, nil
+	})
+}
+
+// stsEndpoint returns the regional STS endpoint of AWS_REGION, or the global one.
+func stsEndpoint() string {
+	region := firstNonEmpty(os.Getenv(EnvRegion), os.Getenv(EnvDefaultRegion))
+	switch {
+	case region == "":
+		return "https://sts.amazonaws.com"
+	case strings.HasPrefix(region, "cn-"):
+		return "https://sts." + region + ".amazonaws.com.cn"
+	default:
+		return "https://sts." + region + ".amazonaws.com"
+	}
+}
+
+// metadataCredentials is the credentials document of the container and instance metadata
+// services.
+type metadataCredentials struct {
+	Code            string
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string
+	Token           string
+	Expiration      time.Time
+}
+
+func (m *metadataCredentials) credentials(source string) (Credentials, error) {
+	if m.Code != "" && m.Code != "Success" {
+		return Credentials{}, fmt.Errorf("credentials document has code %s", m.Code)
+	}
+	if m.AccessKeyID == "" || m.SecretAccessKey == "" {
+		return Credentials{}, fmt.Errorf("credentials document has no keys")
+	}
+
+	return Credentials{
+		AccessKeyID:     m.AccessKeyID,
+		SecretAccessKey: m.SecretAccessKey,
+		SessionToken:    m.Token,
+		Expires:         m.Expiration,
+		Source:          source,
+	}, nil
+}
+
+// ContainerCredentials returns a provider fetching credentials from the container credentials
+// endpoint named by AWS_CONTAINER_CREDENTIALS_RELATIVE_URI (ECS task roles) or
+// AWS_CONTAINER_CREDENTIALS_FULL_URI, authorized by AWS_CONTAINER_AUTHORIZATION_TOKEN or
+// AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE (EKS Pod Identity).
+func ContainerCredentials(hc *http.Client) CredentialsProvider {
+	return CredentialsProviderFunc(func(ctx context.Context) (Credentials, error) {
+		endpoint := os.Getenv(EnvContainerFullURI)
+		if rel := os.Getenv(EnvContainerRelativeURI); rel != "" {
+			endpoint = containerEndpoint + rel
+		}
+		if endpoint == "" {
+			return Credentials{}, fmt.Errorf("%w: %s and %s are not set",
+				ErrNoCredentials, EnvContainerRelativeURI, EnvContainerFullURI)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("sigv4: container credentials: %w", err)
+		}
+		token := os.Getenv(EnvContainerToken)
+		if file := os.Getenv(EnvContainerTokenFile); file != "" {
+			b, err := os.ReadFile(file)
+			if err != nil {
+				return Credentials{}, fmt.Errorf("sigv4: reading container authorization token: %w", err)
+			}
+			token = strings.TrimSpace(string(b))
+		}
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+
+		var doc metadataCredentials
+		if err := do(hc, req, func(body io.Reader) error { return json.NewDecoder(body).Decode(&doc) }); err != nil {
+			return Credentials{}, fmt.Errorf("sigv4: container credentials: %w", err)
+		}
+		creds, err := doc.credentials("container")
+		if err != nil {
+			return Credentials{}, fmt.Errorf("sigv4: container credentials: %w", err)
+		}
+		return creds, nil
+	})
+}
+
+// InstanceCredentials returns a provider fetching the credentials of the EC2 instance role from
+// the instance metadata service with IMDSv2 session tokens. It reports ErrNoCredentials when the
+// service is unreachable or AWS_EC2_METADATA_DISABLED is true, as is the case off EC2.
+func InstanceCredentials(hc *http.Client) CredentialsProvider {
+	return CredentialsProviderFunc(func(ctx context.Context) (Credentials, error) {
+		if strings.EqualFold(os.Getenv(EnvMetadataDisabled), "true") {
+			return Credentials{}, fmt.Errorf("%w: %s is true", ErrNoCredentials, EnvMetadataDisabled)
+		}
+		base := strings.TrimSuffix(firstNonEmpty(os.Getenv(EnvMetadataEndpoint), metadataEndpoint), "/")
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, base+"/latest/api/token", nil)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("sigv4: instance metadata: %w", err)
+		}
+		req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", strconv.Itoa(int(metadataTokenTTL.Seconds())))
+		var token string
+		if err := do(hc, req, readString(&token)); err != nil {
+			return Credentials{}, fmt.Errorf("%w: instance metadata service unavailable: %v", ErrNoCredentials, err)
+		}
+
+		get := func(path string, decode func(io.Reader) error) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+path, nil)
+			if err != nil {
+				return err
+			}
+			req.Header.Set("X-Aws-Ec2-Metadata-Token", token)
+			return do(hc, req, decode)
+		}
+		const rolesPath = "/latest/meta-data/iam/security-credentials/"
+		var roles string
+		if err := get(rolesPath, readString(&roles)); err != nil {
+			return Credentials{}, fmt.Errorf("%w: no instance role: %v", ErrNoCredentials, err)
+		}
+		role, _, _ := strings.Cut(strings.TrimSpace(roles), "\n")
+		if role == "" {
+			return Credentials{}, fmt.Errorf("%w: no instance role", ErrNoCredentials)
+		}
+
+		var doc metadataCredentials
+		decode := func(body io.Reader) error { return json.NewDecoder(body).Decode(&doc) }
+		if err := get(rolesPath+role, decode); err != nil {
+			return Credentials{}, fmt.Errorf("sigv4: instance role %s: %w", role, err)
+		}
+		creds, err := doc.credentials("imds")
+		if err != nil {
+			return Credentials{}, fmt.Errorf("sigv4: instance role %s: %w", role, err)
+		}
+		return creds, nil
+	})
+}
+
+// do sends req with hc and decodes a 200 response body with decode.
+func do(hc *http.Client, req *http.Request, decode func(io.Reader) error) error {
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	return decode(resp.Body)
+}
+
+// readString returns a decode function storing the body in dst.
+func readString(dst *string) func(io.Reader) error {
+	return func(body io.Reader) error {
+		b, err := io.ReadAll(io.LimitReader(body, 64<<10))
+		*dst = string(b)
+		return err
+	}
+}
+
+// firstNonEmpty returns the first non-empty value.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
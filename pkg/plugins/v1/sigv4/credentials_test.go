@@ -0,0 +1,694 @@
+package sigv4
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets the AWS environment variables for the duration of the test.
+func clearEnv(t *testing.T) {
+	t.Helper()
+
+	for _, name := range []string{
+		EnvAccessKeyID, EnvSecretAccessKey, EnvSessionToken, EnvRegion, EnvDefaultRegion, EnvProfile,
+		EnvSharedCredentials, EnvWebIdentityTokenFile, EnvRoleARN, EnvRoleSessionName,
+		EnvContainerRelativeURI, EnvContainerFullURI, EnvContainerToken, EnvContainerTokenFile,
+		EnvMetadataDisabled, EnvMetadataEndpoint,
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+// writeFile writes content to name in a temporary directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// rewriteTransport sends every request to the host of target, for endpoints that cannot be
+// configured.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (rt rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = rt.target.Scheme, rt.target.Host
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// rewritingClient returns a client sending every request to srv.
+func rewritingClient(t *testing.T, srv *httptest.Server) *http.Client {
+	t.Helper()
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &http.Client{Transport: rewriteTransport{target: u}}
+}
+
+func TestEnvCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Credentials
+		wantErr bool
+	}{
+		{
+			name: "keys and token",
+			env:  map[string]string{EnvAccessKeyID: "id", EnvSecretAccessKey: "secret", EnvSessionToken: "tok"},
+			want: Credentials{AccessKeyID: "id", SecretAccessKey: "secret", SessionToken: "tok", Source: "env"},
+		},
+		{
+			name: "long-term keys",
+			env:  map[string]string{EnvAccessKeyID: "id", EnvSecretAccessKey: "secret"},
+			want: Credentials{AccessKeyID: "id", SecretAccessKey: "secret", Source: "env"},
+		},
+		{name: "no secret", env: map[string]string{EnvAccessKeyID: "id"}, wantErr: true},
+		{name: "unset", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			got, err := EnvCredentials().Retrieve(context.Background())
+			if tt.wantErr {
+				if !errors.Is(err, ErrNoCredentials) {
+					t.Errorf("Retrieve error = %v, want ErrNoCredentials", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Retrieve = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSharedCredentials(t *testing.T) {
+	const file = `
+# Comment
+[default]
+aws_access_key_id = default-id
+aws_secret_access_key = default-secret
+
+; Another comment
+[ work ]
+aws_access_key_id=work-id
+aws_secret_access_key=work-secret
+aws_session_token = work-token
+
+[empty]
+region = eu-west-1
+`
+	path := writeFile(t, "credentials", file)
+	tests := []struct {
+		name       string
+		path       string
+		profile    string
+		env        map[string]string
+		want       Credentials
+		wantErr    string
+		wantNoCred bool
+	}{
+		{
+			name: "default profile",
+			path: path,
+			want: Credentials{AccessKeyID: "default-id", SecretAccessKey: "default-secret", Source: "shared:default"},
+		},
+		{
+			name:    "named profile",
+			path:    path,
+			profile: "work",
+			want: Credentials{
+				AccessKeyID:     "work-id",
+				SecretAccessKey: "work-secret",
+				SessionToken:    "work-token",
+				Source:          "shared:work",
+			},
+		},
+		{
+			name: "profile and path from the environment",
+			env:  map[string]string{EnvSharedCredentials: path, EnvProfile: "work"},
+			want: Credentials{
+				AccessKeyID:     "work-id",
+				SecretAccessKey: "work-secret",
+				SessionToken:    "work-token",
+				Source:          "shared:work",
+			},
+		},
+		{name: "missing profile", path: path, profile: "other", wantNoCred: true, wantErr: "profile other not found"},
+		{name: "profile without keys", path: path, profile: "empty", wantErr: "has no static keys"},
+		{name: "missing file", path: filepath.Join(t.TempDir(), "credentials"), wantNoCred: true},
+		{name: "directory", path: t.TempDir(), wantErr: "sigv4: reading"},
+		{
+			name:       "home directory",
+			env:        map[string]string{"HOME": t.TempDir()},
+			wantNoCred: true,
+			wantErr:    filepath.Join(".aws", "credentials") + " does not exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			got, err := SharedCredentials(tt.path, tt.profile).Retrieve(context.Background())
+			if tt.wantErr != "" || tt.wantNoCred {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Retrieve error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if got := errors.Is(err, ErrNoCredentials); got != tt.wantNoCred {
+					t.Errorf("errors.Is(err, ErrNoCredentials) = %t, want %t", got, tt.wantNoCred)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Retrieve = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainCredentials(t *testing.T) {
+	unset := CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+		return Credentials{}, fmt.Errorf("%w: unset", ErrNoCredentials)
+	})
+	broken := CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+		return Credentials{}, errors.New("broken")
+	})
+	static := StaticCredentials("id", "secret", "")
+	tests := []struct {
+		name       string
+		providers  []CredentialsProvider
+		wantSource string
+		wantErr    string
+	}{
+		{name: "first configured", providers: []CredentialsProvider{unset, static, broken}, wantSource: "static"},
+		{name: "stops on an error", providers: []CredentialsProvider{unset, broken, static}, wantErr: "broken"},
+		{name: "none configured", providers: []CredentialsProvider{unset, unset}, wantErr: "unset\nsigv4"},
+		{name: "empty", wantErr: "no providers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ChainCredentials(tt.providers...).Retrieve(context.Background())
+			if tt.wantSource != "" {
+				if err != nil || got.Source != tt.wantSource {
+					t.Errorf("Retrieve = %+v, %v, want credentials from %s", got, err, tt.wantSource)
+				}
+				return
+			}
+			if !errors.Is(err, ErrNoCredentials) != (tt.wantErr == "broken") {
+				t.Errorf("errors.Is(%v, ErrNoCredentials) = %t", err, errors.Is(err, ErrNoCredentials))
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Retrieve error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCachedCredentials(t *testing.T) {
+	tests := []struct {
+		name        string
+		expires     time.Time
+		wantFetches int32
+	}{
+		{name: "long-term", wantFetches: 1},
+		{name: "valid", expires: time.Now().Add(time.Hour), wantFetches: 1},
+		{name: "within the expiry window", expires: time.Now().Add(expiryWindow - time.Minute), wantFetches: 3},
+		{name: "expired", expires: time.Now().Add(-time.Minute), wantFetches: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetches atomic.Int32
+			p := CachedCredentials(CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+				fetches.Add(1)
+				return Credentials{AccessKeyID: "id", Expires: tt.expires}, nil
+			}))
+			for range 3 {
+				if _, err := p.Retrieve(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := fetches.Load(); got != tt.wantFetches {
+				t.Errorf("%d fetches, want %d", got, tt.wantFetches)
+			}
+		})
+	}
+}
+
+func TestCachedCredentialsError(t *testing.T) {
+	var fetches atomic.Int32
+	p := CachedCredentials(CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+		if fetches.Add(1) == 1 {
+			return Credentials{}, errors.New("unavailable")
+		}
+		return Credentials{AccessKeyID: "id"}, nil
+	}))
+	if _, err := p.Retrieve(context.Background()); err == nil {
+		t.Fatal("Retrieve succeeded, want the provider error")
+	}
+	// Errors are not cached.
+	if got, err := p.Retrieve(context.Background()); err != nil || got.AccessKeyID != "id" {
+		t.Errorf("Retrieve = %+v, %v, want the credentials", got, err)
+	}
+}
+
+// stsResponse is an AssumeRoleWithWebIdentity response.
+const stsResponse = `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>ASIAID</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>session</SessionToken>
+      <Expiration>2030-01-02T03:04:05Z</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`
+
+func TestWebIdentityCredentials(t *testing.T) {
+	tokenFile := writeFile(t, "token", "oidc-token\n")
+	tests := []struct {
+		name       string
+		env        map[string]string
+		status     int
+		want       Credentials
+		wantForm   url.Values
+		wantErr    string
+		wantNoCred bool
+	}{
+		{
+			name: "assumes the role",
+			env: map[string]string{
+				EnvWebIdentityTokenFile: tokenFile,
+				EnvRoleARN:              "arn:aws:iam::123456789012:role/mcp",
+				EnvRoleSessionName:      "session-name",
+			},
+			want: Credentials{
+				AccessKeyID:     "ASIAID",
+				SecretAccessKey: "secret",
+				SessionToken:    "session",
+				Expires:         time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+				Source:          "web_identity",
+			},
+			wantForm: url.Values{
+				"Action":           {"AssumeRoleWithWebIdentity"},
+				"Version":          {"2011-06-15"},
+				"RoleArn":          {"arn:aws:iam::123456789012:role/mcp"},
+				"RoleSessionName":  {"session-name"},
+				"WebIdentityToken": {"oidc-token"},
+			},
+		},
+		{
+			name:       "no role",
+			env:        map[string]string{EnvWebIdentityTokenFile: tokenFile},
+			wantNoCred: true,
+		},
+		{
+			name: "missing token file",
+			env: map[string]string{
+				EnvWebIdentityTokenFile: filepath.Join(t.TempDir(), "token"),
+				EnvRoleARN:              "arn:aws:iam::123456789012:role/mcp",
+			},
+			wantErr: "reading web identity token",
+		},
+		{
+			name:    "STS error",
+			env:     map[string]string{EnvWebIdentityTokenFile: tokenFile, EnvRoleARN: "arn"},
+			status:  http.StatusForbidden,
+			wantErr: "AssumeRoleWithWebIdentity: HTTP 403: AccessDenied",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			var form url.Values
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Error(err)
+				}
+				form = r.PostForm
+				if tt.status != 0 {
+					http.Error(w, "AccessDenied", tt.status)
+					return
+				}
+				_, _ = io.WriteString(w, stsResponse)
+			}))
+			defer srv.Close()
+
+			got, err := WebIdentityCredentials(rewritingClient(t, srv)).Retrieve(context.Background())
+			if tt.wantErr != "" || tt.wantNoCred {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Retrieve error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if got := errors.Is(err, ErrNoCredentials); got != tt.wantNoCred {
+					t.Errorf("errors.Is(err, ErrNoCredentials) = %t, want %t", got, tt.wantNoCred)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Retrieve = %+v, want %+v", got, tt.want)
+			}
+			if form.Encode() != tt.wantForm.Encode() {
+				t.Errorf("form = %v, want %v", form, tt.wantForm)
+			}
+		})
+	}
+}
+
+func TestWebIdentityDefaultSessionName(t *testing.T) {
+	clearEnv(t)
+	t.Setenv(EnvWebIdentityTokenFile, writeFile(t, "token", "oidc-token"))
+	t.Setenv(EnvRoleARN, "arn")
+	var session string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session = r.FormValue("RoleSessionName")
+		_, _ = io.WriteString(w, stsResponse)
+	}))
+	defer srv.Close()
+
+	if _, err := WebIdentityCredentials(rewritingClient(t, srv)).Retrieve(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(session, "mcpd-plugin-") {
+		t.Errorf("RoleSessionName = %q, want a mcpd-plugin- default", session)
+	}
+}
+
+func TestSTSEndpoint(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{name: "global", want: "https://sts.amazonaws.com"},
+		{name: "region", env: map[string]string{EnvRegion: "eu-west-1"}, want: "https://sts.eu-west-1.amazonaws.com"},
+		{
+			name: "default region",
+			env:  map[string]string{EnvDefaultRegion: "us-west-2"},
+			want: "https://sts.us-west-2.amazonaws.com",
+		},
+		{
+			name: "region before default region",
+			env:  map[string]string{EnvRegion: "eu-west-1", EnvDefaultRegion: "us-west-2"},
+			want: "https://sts.eu-west-1.amazonaws.com",
+		},
+		{
+			name: "China",
+			env:  map[string]string{EnvRegion: "cn-north-1"},
+			want: "https://sts.cn-north-1.amazonaws.com.cn",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if got := stsEndpoint(); got != tt.want {
+				t.Errorf("stsEndpoint = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// metadataDocument is a credentials document of the container and instance metadata services.
+const metadataDocument = `{"Code":"Success","AccessKeyId":"ASIAMETA","SecretAccessKey":"secret",` +
+	`"Token":"session","Expiration":"2030-01-02T03:04:05Z"}`
+
+func TestContainerCredentials(t *testing.T) {
+	tokenFile := writeFile(t, "token", "file-token\n")
+	tests := []struct {
+		name       string
+		env        func(srv string) map[string]string
+		document   string
+		wantAuth   string
+		wantErr    string
+		wantNoCred bool
+	}{
+		{
+			name: "full URI with a token",
+			env: func(srv string) map[string]string {
+				return map[string]string{EnvContainerFullURI: srv, EnvContainerToken: "tok"}
+			},
+			document: metadataDocument,
+			wantAuth: "tok",
+		},
+		{
+			name: "token file before token",
+			env: func(srv string) map[string]string {
+				return map[string]string{
+					EnvContainerFullURI:   srv,
+					EnvContainerToken:     "tok",
+					EnvContainerTokenFile: tokenFile,
+				}
+			},
+			document: metadataDocument,
+			wantAuth: "file-token",
+		},
+		{
+			name: "relative URI",
+			env: func(string) map[string]string {
+				return map[string]string{EnvContainerRelativeURI: "/v2/credentials/id"}
+			},
+			document: metadataDocument,
+		},
+		{
+			name:       "unset",
+			env:        func(string) map[string]string { return nil },
+			wantNoCred: true,
+		},
+		{
+			name: "missing token file",
+			env: func(srv string) map[string]string {
+				return map[string]string{
+					EnvContainerFullURI:   srv,
+					EnvContainerTokenFile: filepath.Join(t.TempDir(), "token"),
+				}
+			},
+			wantErr: "reading container authorization token",
+		},
+		{
+			name:     "error code",
+			env:      func(srv string) map[string]string { return map[string]string{EnvContainerFullURI: srv} },
+			document: `{"Code":"AssumeRoleUnauthorizedAccess","AccessKeyId":"id","SecretAccessKey":"secret"}`,
+			wantErr:  "has code AssumeRoleUnauthorizedAccess",
+		},
+		{
+			name:     "no keys",
+			env:      func(srv string) map[string]string { return map[string]string{EnvContainerFullURI: srv} },
+			document: `{"Code":"Success"}`,
+			wantErr:  "has no keys",
+		},
+		{
+			name:     "invalid document",
+			env:      func(srv string) map[string]string { return map[string]string{EnvContainerFullURI: srv} },
+			document: `{`,
+			wantErr:  "container credentials",
+		},
+		{
+			name:    "endpoint error",
+			env:     func(srv string) map[string]string { return map[string]string{EnvContainerFullURI: srv} },
+			wantErr: "HTTP 500: unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var auth, path string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth, path = r.Header.Get("Authorization"), r.URL.Path
+				if tt.document == "" {
+					http.Error(w, "unavailable", http.StatusInternalServerError)
+					return
+				}
+				_, _ = io.WriteString(w, tt.document)
+			}))
+			defer srv.Close()
+			clearEnv(t)
+			env := tt.env(srv.URL + "/creds")
+			for k, v := range env {
+				t.Setenv(k, v)
+			}
+
+			// The relative URI only resolves against the ECS endpoint, so requests go to srv instead.
+			got, err := ContainerCredentials(rewritingClient(t, srv)).Retrieve(context.Background())
+			if tt.wantErr != "" || tt.wantNoCred {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Retrieve error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if got := errors.Is(err, ErrNoCredentials); got != tt.wantNoCred {
+					t.Errorf("errors.Is(err, ErrNoCredentials) = %t, want %t", got, tt.wantNoCred)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := Credentials{
+				AccessKeyID:     "ASIAMETA",
+				SecretAccessKey: "secret",
+				SessionToken:    "session",
+				Expires:         time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+				Source:          "container",
+			}
+			if got != want {
+				t.Errorf("Retrieve = %+v, want %+v", got, want)
+			}
+			if auth != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", auth, tt.wantAuth)
+			}
+			wantPath := "/creds"
+			if rel := env[EnvContainerRelativeURI]; rel != "" {
+				wantPath = rel
+			}
+			if path != wantPath {
+				t.Errorf("path = %s, want %s", path, wantPath)
+			}
+		})
+	}
+}
+
+// imds is a fake instance metadata service.
+type imds struct {
+	roles    string
+	document string
+	tokenTTL string
+}
+
+func (m *imds) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const token = "imds-token"
+	if r.Method == http.MethodPut && r.URL.Path == "/latest/api/token" {
+		m.tokenTTL = r.Header.Get("X-Aws-Ec2-Metadata-Token-Ttl-Seconds")
+		_, _ = io.WriteString(w, token)
+		return
+	}
+	if r.Header.Get("X-Aws-Ec2-Metadata-Token") != token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	switch r.URL.Path {
+	case "/latest/meta-data/iam/security-credentials/":
+		if m.roles == "" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, m.roles)
+	case "/latest/meta-data/iam/security-credentials/mcp-role":
+		_, _ = io.WriteString(w, m.document)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestInstanceCredentials(t *testing.T) {
+	tests := []struct {
+		name       string
+		imds       *imds
+		disabled   bool
+		want       Credentials
+		wantErr    string
+		wantNoCred bool
+	}{
+		{
+			name: "instance role",
+			imds: &imds{roles: "mcp-role\nother-role\n", document: metadataDocument},
+			want: Credentials{
+				AccessKeyID:     "ASIAMETA",
+				SecretAccessKey: "secret",
+				SessionToken:    "session",
+				Expires:         time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+				Source:          "imds",
+			},
+		},
+		{name: "disabled", imds: &imds{}, disabled: true, wantNoCred: true, wantErr: "is true"},
+		{name: "no role", imds: &imds{}, wantNoCred: true, wantErr: "no instance role"},
+		{name: "blank role", imds: &imds{roles: "\n"}, wantNoCred: true, wantErr: "no instance role"},
+		{
+			name:    "invalid document",
+			imds:    &imds{roles: "mcp-role", document: `{"Code":"Failure"}`},
+			wantErr: "instance role mcp-role: credentials document has code Failure",
+		},
+		{name: "unreachable", wantNoCred: true, wantErr: "instance metadata service unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			if tt.imds != nil {
+				srv := httptest.NewServer(tt.imds)
+				defer srv.Close()
+				t.Setenv(EnvMetadataEndpoint, srv.URL+"/")
+			} else {
+				srv := httptest.NewServer(http.NotFoundHandler())
+				srv.Close()
+				t.Setenv(EnvMetadataEndpoint, srv.URL)
+			}
+			if tt.disabled {
+				t.Setenv(EnvMetadataDisabled, "TRUE")
+			}
+
+			got, err := InstanceCredentials(http.DefaultClient).Retrieve(context.Background())
+			if tt.wantErr != "" || tt.wantNoCred {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Retrieve error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if got := errors.Is(err, ErrNoCredentials); got != tt.wantNoCred {
+					t.Errorf("errors.Is(err, ErrNoCredentials) = %t, want %t", got, tt.wantNoCred)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Retrieve = %+v, want %+v", got, tt.want)
+			}
+			if tt.imds.tokenTTL != "21600" {
+				t.Errorf("token TTL = %q, want 21600", tt.imds.tokenTTL)
+			}
+		})
+	}
+}
+
+func TestStaticCredentials(t *testing.T) {
+	got, err := StaticCredentials("id", "secret", "tok").Retrieve(context.Background())
+	want := Credentials{AccessKeyID: "id", SecretAccessKey: "secret", SessionToken: "tok", Source: "static"}
+	if err != nil || got != want {
+		t.Errorf("Retrieve = %+v, %v, want %+v", got, err, want)
+	}
+}
@@ -0,0 +1,251 @@
+package sigv4
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Algorithm is the signing algorithm named in Authorization headers.
+const Algorithm = "AWS4-HMAC-SHA256"
+
+// UnsignedPayload is the payload hash of requests whose body is not signed, accepted by Amazon S3
+// and some other services.
+const UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// Headers set by Sign.
+const (
+	HeaderAuthorization = "Authorization"
+	HeaderDate          = "X-Amz-Date"
+	HeaderSecurityToken = "X-Amz-Security-Token"
+	HeaderContentSHA256 = "X-Amz-Content-Sha256"
+)
+
+// timeFormat is the format of X-Amz-Date, and dateFormat that of the credential scope date.
+const (
+	timeFormat = "20060102T150405Z"
+	dateFormat = "20060102"
+)
+
+// Params are the signing parameters of a request.
+type Params struct {
+	// Region and Service form the credential scope, such as "us-east-1" and "lambda".
+	Region  string
+	Service string
+
+	// SignedHeaders names headers signed in addition to host, content-type and x-amz-* headers
+	// when the request has them.
+	SignedHeaders []string
+
+	// UnsignedPayload signs UnsignedPayload instead of the body's hash.
+	UnsignedPayload bool
+}
+
+// Signer signs requests with Signature Version 4. It is safe for concurrent use.
+type Signer struct {
+	creds CredentialsProvider
+	now   func() time.Time
+}
+
+// NewSigner returns a Signer signing with the credentials of creds (nil uses
+// DefaultCredentials(nil)).
+func NewSigner(creds CredentialsProvider) *Signer {
+	if creds == nil {
+		creds = DefaultCredentials(nil)
+	}
+
+	return &Signer{creds: creds, now: time.Now}
+}
+
+// Sign signs a request to target with the given method, headers and body, setting the
+// Authorization, X-Amz-Date, X-Amz-Content-Sha256 and, for temporary credentials,
+// X-Amz-Security-Token headers and the Host header in headers. The signature covers target's host,
+// path and query exactly as given, so target must be the URL the service receives.
+func (s *Signer) Sign(
+	ctx context.Context,
+	method string,
+	target *url.URL,
+	headers map[string]string,
+	body []byte,
+	p Params,
+) error {
+	if p.Region == "" || p.Service == "" {
+		return fmt.Errorf("sigv4: region and service are required")
+	}
+	if headers == nil {
+		return fmt.Errorf("sigv4: headers cannot be nil")
+	}
+	creds, err := s.creds.Retrieve(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := s.now().UTC()
+	payloadHash := UnsignedPayload
+	if !p.UnsignedPayload {
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+
+	deleteHeader(headers, HeaderAuthorization)
+	deleteHeader(headers, HeaderSecurityToken)
+	setHeader(headers, "Host", canonicalHost(target))
+	setHeader(headers, HeaderDate, now.Format(timeFormat))
+	setHeader(headers, HeaderContentSHA256, payloadHash)
+	if creds.SessionToken != "" {
+		setHeader(headers, HeaderSecurityToken, creds.SessionToken)
+	}
+
+	names, canonicalHeaders := canonicalHeaders(headers, p.SignedHeaders)
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		strings.ToUpper(method),
+		canonicalPath(target, p.Service),
+		canonicalQuery(target),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format(dateFormat), p.Region, p.Service, "aws4_request"}, "/")
+	crHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		Algorithm,
+		now.Format(timeFormat),
+		scope,
+		hex.EncodeToString(crHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), now.Format(dateFormat))
+	for _, part := range []string{p.Region, p.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	headers[HeaderAuthorization] = fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		Algorithm, creds.AccessKeyID, scope, signedHeaders, signature)
+
+	return nil
+}
+
+// canonicalHost returns the host of u without the default port of its scheme, as HTTP clients
+// send it.
+func canonicalHost(u *url.URL) string {
+	host, port := u.Hostname(), u.Port()
+	if port == "" || (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+		if strings.Contains(host, ":") {
+			return "[" + host + "]"
+		}
+		return host
+	}
+
+	return u.Host
+}
+
+// canonicalPath returns the canonical URI of u: its escaped path, escaped once more for services
+// other than Amazon S3, which signs the path as sent.
+func canonicalPath(u *url.URL, service string) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	if service == "s3" {
+		return path
+	}
+
+	return escape(path, false)
+}
+
+// canonicalQuery returns the query of u with its parameters sorted by name and value and escaped
+// as SigV4 requires.
+func canonicalQuery(u *url.URL) string {
+	if u.RawQuery == "" {
+		return ""
+	}
+	q := u.Query()
+	params := make([]string, 0, len(q))
+	for k, vs := range q {
+		for _, v := range vs {
+			params = append(params, escape(k, true)+"="+escape(v, true))
+		}
+	}
+	slices.Sort(params)
+
+	return strings.Join(params, "&")
+}
+
+// canonicalHeaders returns the sorted lower-case names of the headers to sign and their canonical
+// block: host, content-type, x-amz-* and the extra headers present in headers.
+func canonicalHeaders(headers map[string]string, extra []string) ([]string, string) {
+	values := map[string]string{}
+	for k, v := range headers {
+		name := strings.ToLower(k)
+		if name == "host" || name == "content-type" || strings.HasPrefix(name, "x-amz-") ||
+			slices.ContainsFunc(extra, func(e string) bool { return strings.EqualFold(e, name) }) {
+			values[name] = strings.Join(strings.Fields(v), " ")
+		}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(values[name])
+		b.WriteByte('\n')
+	}
+
+	return names, b.String()
+}
+
+// escape percent-encodes every byte of s except the RFC 3986 unreserved characters and, unless
+// encodeSlash, '/'.
+func escape(s string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&0xF])
+	}
+
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// setHeader sets name in headers, replacing values whose names differ only in case.
+func setHeader(headers map[string]string, name, value string) {
+	deleteHeader(headers, name)
+	headers[name] = value
+}
+
+// deleteHeader removes name from headers, matching case-insensitively.
+func deleteHeader(headers map[string]string, name string) {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			delete(headers, k)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package sigv4 signs requests to AWS-hosted upstream MCP servers, such as Lambda function URLs,
+// API Gateway endpoints with IAM authorization and Bedrock AgentCore runtimes, with AWS Signature
+// Version 4, configured per upstream.
+//
+// Credentials come from the default credential chain of the AWS SDKs (see DefaultCredentials), so
+// the plugin picks up environment variables, the shared credentials file, EKS service account
+// roles, ECS task roles and EC2 instance roles without configuration:
+//
+//	err := mcpdpluginsv1.Serve(sigv4.NewPlugin(nil, recorder),
+//	    mcpdpluginsv1.WithUpstreams(mcpdpluginsv1.UpstreamFromPath()))
+//
+// with upstreams selected by their custom_config section:
+//
+//	region:                       us-east-1
+//	upstreams.search.service:     lambda
+//	upstreams.search.endpoint:    https://abc123.lambda-url.us-east-1.on.aws/mcp
+//	upstreams.agents.service:     bedrock-agentcore
+//
+// The signature covers the body and the URL the upstream receives: the endpoint key when set, and
+// otherwise the base URL mcpd reports for the upstream (see mcpdpluginsv1.Target). Since any later
+// change to the request invalidates it, sigv4 must be the last plugin of the request chain.
+package sigv4
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// MetricSignatures counts signed requests, labelled by LabelService and LabelOutcome ("ok" or
+// "error").
+const MetricSignatures = "sigv4.signatures"
+
+// Label keys of MetricSignatures.
+const (
+	LabelService = "service"
+	LabelOutcome = "outcome"
+)
+
+// Config configures the signing of requests to one upstream, decodable from custom_config with
+// mcpdpluginsv1.DecodeConfig, typically per upstream with mcpdpluginsv1.UpstreamConfig.
+type Config struct {
+	// Service is the signing name of the AWS service hosting the upstream, such as "lambda",
+	// "execute-api" or "bedrock-agentcore". Requests to upstreams without one are left unchanged.
+	Service string `config:"service"`
+
+	// Region is the upstream's AWS region, defaulting to AWS_REGION or AWS_DEFAULT_REGION.
+	Region string `config:"region"`
+
+	// Endpoint is the URL the upstream receives, defaulting to the base URL mcpd reports.
+	Endpoint string `config:"endpoint"`
+
+	// SignedHeaders names request headers signed in addition to host, content-type and x-amz-*.
+	SignedHeaders []string `config:"signed_headers"`
+
+	// UnsignedPayload leaves the body out of the signature, for services that accept it.
+	UnsignedPayload bool `config:"unsigned_payload" default:"false"`
+
+	// FailOpen forwards requests unsigned when signing fails, instead of short-circuiting them
+	// with 502.
+	FailOpen bool `config:"fail_open" default:"false"`
+}
+
+// Injector signs requests. It is safe for concurrent use.
+type Injector struct {
+	signer   *Signer
+	recorder metrics.Recorder
+	logger   *log.Logger
+}
+
+// NewInjector returns an Injector signing with the credentials of creds (nil uses
+// DefaultCredentials(nil)) and recording signatures through recorder (nil disables metrics).
+func NewInjector(creds CredentialsProvider, recorder metrics.Recorder) *Injector {
+	if recorder == nil {
+		recorder = metrics.Nop()
+	}
+
+	return &Injector{signer: NewSigner(creds), recorder: recorder, logger: log.Default()}
+}
+
+// HandleRequest continues the chain with req signed as configured by cfg, or with req unchanged
+// when cfg is nil or has no Service. It returns mcpdpluginsv1.ErrBodyNotRequested when the body is
+// to be signed but mcpd omitted it.
+func (i *Injector) HandleRequest(
+	ctx context.Context,
+	cfg *Config,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	if cfg == nil || cfg.Service == "" {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}, nil
+	}
+	var body []byte
+	if !cfg.UnsignedPayload {
+		var err error
+		if body, err = mcpdpluginsv1.Body(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+
+	modified := mcpdpluginsv1.CloneRequest(req)
+	if modified.Headers == nil {
+		modified.Headers = map[string]string{}
+	}
+	err := i.sign(ctx, cfg, modified, body)
+	if err != nil {
+		i.recorder.Count(MetricSignatures, 1, metrics.L(LabelService, cfg.Service), metrics.L(LabelOutcome, "error"))
+		i.logger.Printf("sigv4: signing request for %s: %v", cfg.Service, err)
+		if cfg.FailOpen {
+			return &mcpdpluginsv1.HTTPResponse{Continue: true}, nil
+		}
+		return mcpdpluginsv1.DenyError(req, http.StatusBadGateway, &mcp.Error{
+			Code:    mcp.CodeServerError,
+			Message: "upstream authentication unavailable",
+		}), nil
+	}
+	i.recorder.Count(MetricSignatures, 1, metrics.L(LabelService, cfg.Service), metrics.L(LabelOutcome, "ok"))
+
+	return &mcpdpluginsv1.HTTPResponse{Continue: true, ModifiedRequest: modified}, nil
+}
+
+// sign signs req's headers for its upstream.
+func (i *Injector) sign(ctx context.Context, cfg *Config, req *mcpdpluginsv1.HTTPRequest, body []byte) error {
+	var target *url.URL
+	if cfg.Endpoint != "" {
+		u, err := url.Parse(cfg.Endpoint)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("endpoint must be an absolute URL, got %q", cfg.Endpoint)
+		}
+		target = u
+	} else if t, ok := mcpdpluginsv1.Target(ctx); ok && t.BaseURL != nil {
+		target = t.BaseURL
+	} else {
+		return errors.New("no endpoint configured and mcpd reported no upstream URL")
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = firstNonEmpty(os.Getenv(EnvRegion), os.Getenv(EnvDefaultRegion))
+	}
+
+	return i.signer.Sign(ctx, req.GetMethod(), target, req.Headers, body, Params{
+		Region:          region,
+		Service:         cfg.Service,
+		SignedHeaders:   cfg.SignedHeaders,
+		UnsignedPayload: cfg.UnsignedPayload,
+	})
+}
+
+// Plugin is a request-flow plugin signing requests to the upstreams configured in the
+// "upstreams.<name>." sections of its custom_config. Serve it with mcpdpluginsv1.WithUpstreams so
+// requests are matched to their upstream.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	injector *Injector
+	cfg      mcpdpluginsv1.UpstreamConfig[Config]
+}
+
+// NewPlugin returns a Plugin signing with the credentials of creds (nil uses
+// DefaultCredentials(nil)) and recording signatures through recorder (nil disables metrics).
+func NewPlugin(creds CredentialsProvider, recorder metrics.Recorder) *Plugin {
+	return &Plugin{injector: NewInjector(creds, recorder)}
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "sigv4",
+		Version:     pluginVersion,
+		Description: "Signs requests to AWS-hosted upstreams with AWS Signature Version 4.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest), nil
+}
+
+// Configure decodes the per-upstream configuration of cfg's custom_config, keeping the previous
+// configuration on error.
+func (p *Plugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	if err := p.cfg.Configure(ctx, cfg); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleRequest signs the request for its upstream.
+func (p *Plugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.injector.HandleRequest(ctx, p.cfg.Get(ctx), req)
+}
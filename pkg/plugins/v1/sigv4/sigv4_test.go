@@ -0,0 +1,314 @@
+package sigv4
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// fakeRecorder records counts as "name delta [k=v ...]" lines.
+type fakeRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *fakeRecorder) Count(name string, delta int64, labels ...metrics.Label) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Key + "=" + l.Value
+	}
+	r.lines = append(r.lines, fmt.Sprintf("%s %d [%s]", name, delta, strings.Join(parts, " ")))
+}
+
+func (r *fakeRecorder) Gauge(string, float64, ...metrics.Label) {}
+
+func (r *fakeRecorder) Observe(string, float64, ...metrics.Label) {}
+
+func (r *fakeRecorder) Timing(string, time.Duration, ...metrics.Label) {}
+
+func (r *fakeRecorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.lines)
+}
+
+// newTestInjector returns an Injector signing with creds at the example time, logging nowhere.
+func newTestInjector(creds CredentialsProvider, rec metrics.Recorder) *Injector {
+	i := NewInjector(creds, rec)
+	i.signer.now = func() time.Time { return exampleTime }
+	i.logger = log.New(io.Discard, "", 0)
+
+	return i
+}
+
+// withTarget returns ctx carrying the upstream base URL u.
+func withTarget(ctx context.Context, u *url.URL) context.Context {
+	return mcpdpluginsv1.ContextWithTarget(ctx, mcpdpluginsv1.TargetInfo{BaseURL: u})
+}
+
+func TestInjectorHandleRequest(t *testing.T) {
+	static := StaticCredentials(exampleAccessKeyID, exampleSecretKey, "")
+	failing := CredentialsProviderFunc(func(context.Context) (Credentials, error) {
+		return Credentials{}, errors.New("no role")
+	})
+	tests := []struct {
+		name       string
+		creds      CredentialsProvider
+		cfg        *Config
+		target     string // Base URL reported by mcpd; empty reports none.
+		env        map[string]string
+		headers    map[string]string
+		wantHost   string // Host of a signed request; empty when the request is not modified.
+		wantScope  string
+		wantStatus int32
+		wantMetric string
+	}{
+		{name: "nil config", creds: static, target: "https://api.example.com"},
+		{name: "no service", creds: static, cfg: &Config{Region: "us-east-1"}, target: "https://api.example.com"},
+		{
+			name:       "upstream URL",
+			creds:      static,
+			cfg:        &Config{Service: "lambda", Region: "us-east-1"},
+			target:     "https://abc.lambda-url.us-east-1.on.aws/",
+			wantHost:   "abc.lambda-url.us-east-1.on.aws",
+			wantScope:  "/20130524/us-east-1/lambda/aws4_request",
+			wantMetric: "sigv4.signatures 1 [service=lambda outcome=ok]",
+		},
+		{
+			name:  "endpoint before the upstream URL",
+			creds: static,
+			cfg: &Config{
+				Service:  "execute-api",
+				Region:   "eu-west-1",
+				Endpoint: "https://gw.example.com:8443/mcp",
+			},
+			target:     "https://abc.lambda-url.us-east-1.on.aws/",
+			headers:    map[string]string{"Content-Type": "application/json"},
+			wantHost:   "gw.example.com:8443",
+			wantScope:  "/20130524/eu-west-1/execute-api/aws4_request",
+			wantMetric: "sigv4.signatures 1 [service=execute-api outcome=ok]",
+		},
+		{
+			name:       "region from the environment",
+			creds:      static,
+			cfg:        &Config{Service: "bedrock-agentcore"},
+			target:     "https://agent.example.com",
+			env:        map[string]string{EnvDefaultRegion: "us-west-2"},
+			wantHost:   "agent.example.com",
+			wantScope:  "/20130524/us-west-2/bedrock-agentcore/aws4_request",
+			wantMetric: "sigv4.signatures 1 [service=bedrock-agentcore outcome=ok]",
+		},
+		{
+			name:       "no upstream URL",
+			creds:      static,
+			cfg:        &Config{Service: "lambda", Region: "us-east-1"},
+			wantStatus: 502,
+			wantMetric: "sigv4.signatures 1 [service=lambda outcome=error]",
+		},
+		{
+			name:       "relative endpoint",
+			creds:      static,
+			cfg:        &Config{Service: "lambda", Region: "us-east-1", Endpoint: "/mcp"},
+			target:     "https://api.example.com",
+			wantStatus: 502,
+			wantMetric: "sigv4.signatures 1 [service=lambda outcome=error]",
+		},
+		{
+			name:       "no region",
+			creds:      static,
+			cfg:        &Config{Service: "lambda"},
+			target:     "https://api.example.com",
+			wantStatus: 502,
+			wantMetric: "sigv4.signatures 1 [service=lambda outcome=error]",
+		},
+		{
+			name:       "credentials unavailable",
+			creds:      failing,
+			cfg:        &Config{Service: "lambda", Region: "us-east-1"},
+			target:     "https://api.example.com",
+			wantStatus: 502,
+			wantMetric: "sigv4.signatures 1 [service=lambda outcome=error]",
+		},
+		{
+			name:       "credentials unavailable failing open",
+			creds:      failing,
+			cfg:        &Config{Service: "lambda", Region: "us-east-1", FailOpen: true},
+			target:     "https://api.example.com",
+			wantMetric: "sigv4.signatures 1 [service=lambda outcome=error]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			rec := &fakeRecorder{}
+			ctx := context.Background()
+			if tt.target != "" {
+				ctx = withTarget(ctx, mustParse(t, tt.target))
+			}
+			req := &mcpdpluginsv1.HTTPRequest{Method: "POST", Headers: tt.headers, Body: []byte(`{"jsonrpc":"2.0"}`)}
+
+			resp, err := newTestInjector(tt.creds, rec).HandleRequest(ctx, tt.cfg, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantStatus != 0 {
+				if resp.GetContinue() || resp.GetStatusCode() != tt.wantStatus {
+					t.Errorf("HandleRequest = %v, want a %d response", resp, tt.wantStatus)
+				}
+				if !strings.Contains(string(resp.GetBody()), "upstream authentication unavailable") {
+					t.Errorf("body = %s, want the authentication error", resp.GetBody())
+				}
+			} else if !resp.GetContinue() {
+				t.Errorf("HandleRequest = %v, want it to continue", resp)
+			}
+			if tt.wantHost == "" {
+				if resp.GetModifiedRequest() != nil {
+					t.Errorf("modified request %v, want none", resp.GetModifiedRequest())
+				}
+			} else {
+				headers := resp.GetModifiedRequest().GetHeaders()
+				if got := headers["Host"]; got != tt.wantHost {
+					t.Errorf("Host = %s, want %s", got, tt.wantHost)
+				}
+				if got := headers[HeaderAuthorization]; !strings.Contains(got, exampleAccessKeyID+tt.wantScope) {
+					t.Errorf("Authorization = %s, want scope %s", got, tt.wantScope)
+				}
+				if len(req.GetHeaders()) != len(tt.headers) {
+					t.Errorf("original request headers modified: %v", req.GetHeaders())
+				}
+			}
+			var want []string
+			if tt.wantMetric != "" {
+				want = []string{tt.wantMetric}
+			}
+			if got := rec.recorded(); !slices.Equal(got, want) {
+				t.Errorf("metrics = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestInjectorSignatureMatchesSigner(t *testing.T) {
+	creds := StaticCredentials(exampleAccessKeyID, exampleSecretKey, "")
+	body := []byte(`{"jsonrpc":"2.0","method":"tools/list"}`)
+	target := mustParse(t, "https://api.example.com/mcp?x=1")
+	ctx := withTarget(context.Background(), target)
+	req := &mcpdpluginsv1.HTTPRequest{Method: "POST", Body: body, Headers: map[string]string{"X-Tenant": "acme"}}
+	cfg := &Config{Service: "execute-api", Region: "us-east-1", SignedHeaders: []string{"X-Tenant"}}
+
+	resp, err := newTestInjector(creds, nil).HandleRequest(ctx, cfg, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"X-Tenant": "acme"}
+	params := Params{Region: "us-east-1", Service: "execute-api", SignedHeaders: []string{"X-Tenant"}}
+	if err := exampleSigner("").Sign(context.Background(), "POST", target, want, body, params); err != nil {
+		t.Fatal(err)
+	}
+	got := resp.GetModifiedRequest().GetHeaders()
+	if got[HeaderAuthorization] != want[HeaderAuthorization] {
+		t.Errorf("Authorization = %s, want %s", got[HeaderAuthorization], want[HeaderAuthorization])
+	}
+}
+
+func TestInjectorBody(t *testing.T) {
+	creds := StaticCredentials(exampleAccessKeyID, exampleSecretKey, "")
+	ctx := withTarget(context.Background(), mustParse(t, "https://bucket.s3.amazonaws.com/key"))
+	ctx = features.NewContext(ctx, features.NewSet(features.HeadersOnly))
+	req := &mcpdpluginsv1.HTTPRequest{Method: "PUT"}
+
+	_, err := newTestInjector(creds, nil).HandleRequest(ctx, &Config{Service: "s3", Region: "us-east-1"}, req)
+	if !errors.Is(err, mcpdpluginsv1.ErrBodyNotRequested) {
+		t.Errorf("HandleRequest error = %v, want ErrBodyNotRequested", err)
+	}
+
+	// An unsigned payload does not need the body.
+	cfg := &Config{Service: "s3", Region: "us-east-1", UnsignedPayload: true}
+	resp, err := newTestInjector(creds, nil).HandleRequest(ctx, cfg, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.GetModifiedRequest().GetHeaders()[HeaderContentSHA256]; got != UnsignedPayload {
+		t.Errorf("%s = %s, want %s", HeaderContentSHA256, got, UnsignedPayload)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	clearEnv(t)
+	p := NewPlugin(StaticCredentials(exampleAccessKeyID, exampleSecretKey, ""), nil)
+	p.injector.logger = log.New(io.Discard, "", 0)
+	ctx := context.Background()
+	agent := mustParse(t, "https://agent.example.com")
+
+	md, err := p.GetMetadata(ctx, &emptypb.Empty{})
+	if err != nil || md.GetName() != "sigv4" {
+		t.Errorf("GetMetadata = %v, %v", md, err)
+	}
+	caps, err := p.GetCapabilities(ctx, &emptypb.Empty{})
+	if err != nil || !slices.Equal(caps.GetFlows(), []mcpdpluginsv1.Flow{mcpdpluginsv1.FlowRequest}) {
+		t.Errorf("GetCapabilities = %v, %v, want the request flow", caps, err)
+	}
+
+	if _, err := p.Configure(ctx, &mcpdpluginsv1.PluginConfig{CustomConfig: map[string]string{
+		"region":                     "us-east-1",
+		"upstreams.search.service":   "lambda",
+		"upstreams.search.endpoint":  "https://abc.lambda-url.us-east-1.on.aws/mcp",
+		"upstreams.agents.service":   "bedrock-agentcore",
+		"upstreams.agents.region":    "eu-central-1",
+		"upstreams.agents.fail_open": "true",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		upstream  string
+		wantScope string
+	}{
+		{upstream: "search", wantScope: "/us-east-1/lambda/"},
+		{upstream: "agents", wantScope: "/eu-central-1/bedrock-agentcore/"},
+		{upstream: "unconfigured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.upstream, func(t *testing.T) {
+			upstreamCtx := withTarget(mcpdpluginsv1.ContextWithUpstream(ctx, tt.upstream), agent)
+			resp, err := p.HandleRequest(upstreamCtx, &mcpdpluginsv1.HTTPRequest{Method: "POST"})
+			if err != nil || !resp.GetContinue() {
+				t.Fatalf("HandleRequest = %v, %v", resp, err)
+			}
+			got := resp.GetModifiedRequest().GetHeaders()[HeaderAuthorization]
+			if tt.wantScope == "" && got != "" || !strings.Contains(got, tt.wantScope) {
+				t.Errorf("Authorization = %q, want scope %q", got, tt.wantScope)
+			}
+		})
+	}
+
+	_, err = p.Configure(ctx, &mcpdpluginsv1.PluginConfig{CustomConfig: map[string]string{
+		"upstreams.search.fail_open": "sometimes",
+	}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Configure error = %v, want InvalidArgument", err)
+	}
+}
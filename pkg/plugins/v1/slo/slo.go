@@ -0,0 +1,546 @@
+// Package slo tracks the success rate and latency of each MCP tool against a service level
+// objective, and can stop calling a tool whose error budget is burned.
+//
+// A Tracker pairs each tools/call request with its response through the correlation ID (see
+// mcpdpluginsv1.CorrelationID) and counts a call as bad when it fails or, with a latency
+// threshold, when it is slow. Over a sliding window, a tool's error budget is the share of calls
+// the objective allows to be bad: with objective 0.99, 1%. Once more than that are bad, the
+// budget is burned and the configured action applies until enough bad calls age out of the
+// window:
+//
+//	// custom_config:
+//	//   objective:         0.99
+//	//   window:            5m
+//	//   latency_threshold: 2s
+//	//   action:            deny
+//	if err := mcpdpluginsv1.Serve(slo.NewPlugin(recorder), mcpdpluginsv1.WithAdmin("unix", sock)); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+// Statistics are exported as metrics and returned by the admin service's GetStats.
+package slo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/internal/pending"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// Names of the metrics recorded by a Tracker.
+const (
+	// MetricCalls counts tool calls, labelled by LabelTool and LabelOutcome.
+	MetricCalls = "slo.calls"
+
+	// MetricLatency records the latency of tool calls, labelled by LabelTool.
+	MetricLatency = "slo.latency"
+
+	// MetricBudgetBurned is 1 while a tool's error budget is burned and 0 otherwise, labelled by
+	// LabelTool.
+	MetricBudgetBurned = "slo.budget_burned"
+)
+
+// Label keys of the Tracker metrics.
+const (
+	LabelTool    = "tool"
+	LabelOutcome = "outcome"
+)
+
+// Outcomes of tool calls.
+const (
+	OutcomeOK    = "ok"
+	OutcomeError = "error"
+	OutcomeSlow  = "slow"
+)
+
+// Actions taken for tools whose error budget is burned.
+const (
+	// ActionOff only tracks tools.
+	ActionOff = "off"
+
+	// ActionWarn logs when a tool's budget is burned and restored, and lets calls through.
+	ActionWarn = "warn"
+
+	// ActionDeny short-circuits calls to the tool with 503 until its budget is restored.
+	ActionDeny = "deny"
+)
+
+// windowBuckets is the number of buckets a window is divided into.
+const windowBuckets = 10
+
+// pendingTTL bounds how long a call waits for its response before it is forgotten.
+const pendingTTL = 5 * time.Minute
+
+// Config is the objective, decodable from custom_config with mcpdpluginsv1.DecodeConfig.
+type Config struct {
+	// Objective is the share of calls that must succeed, between 0 and 1 exclusive.
+	Objective float64 `config:"objective" default:"0.99"`
+
+	// Window is the sliding window over which calls are counted.
+	Window time.Duration `config:"window" default:"5m"`
+
+	// LatencyThreshold counts calls slower than it as bad (zero only counts failures).
+	LatencyThreshold time.Duration `config:"latency_threshold"`
+
+	// MinCalls is the number of calls in the window below which a budget is never burned, so a
+	// few early failures do not trip the action.
+	MinCalls int64 `config:"min_calls" default:"20"`
+
+	// Action is what happens to a tool whose budget is burned: off, warn or deny.
+	Action string `config:"action" default:"warn"`
+
+	// Tools lists the tracked tools. When empty, every tool is tracked.
+	Tools []string `config:"tools"`
+
+	// DenyTemplateConfig customizes the response to calls denied by ActionDeny. The Denial's
+	// RuleID is the tool name.
+	mcpdpluginsv1.DenyTemplateConfig
+}
+
+// DefaultConfig returns the Config with every default applied.
+func DefaultConfig() Config {
+	var cfg Config
+	if _, err := config.Decode(nil, &cfg); err != nil {
+		panic(fmt.Sprintf("slo: invalid defaults: %v", err))
+	}
+
+	return cfg
+}
+
+// ToolStats summarizes a tool's calls over the current window.
+type ToolStats struct {
+	Tool string
+
+	// Calls counts the calls of the window; Errors and Slow those that failed or were slower than
+	// the latency threshold.
+	Calls  int64
+	Errors int64
+	Slow   int64
+
+	// SuccessRate is the share of good calls (1 without calls).
+	SuccessRate float64
+
+	// BudgetRemaining is the share of the error budget left, from 1 (no bad call) down to 0.
+	BudgetRemaining float64
+
+	MeanLatency time.Duration
+	MaxLatency  time.Duration
+
+	// Burned reports whether the error budget is burned.
+	Burned bool
+}
+
+// bucket counts the calls of one slice of a window.
+type bucket struct {
+	slot    int64
+	calls   int64
+	errors  int64
+	slow    int64
+	latency time.Duration
+	max     time.Duration
+}
+
+// toolWindow is the sliding window of one tool.
+type toolWindow struct {
+	buckets [windowBuckets]bucket
+	burned  bool
+}
+
+// pendingCall is a tool call waiting for its response.
+type pendingCall struct {
+	tool  string
+	start time.Time
+}
+
+// Tracker tracks tool calls against the Config's objective. It is safe for concurrent use.
+type Tracker struct {
+	cfg      Config
+	width    time.Duration
+	tools    map[string]struct{}
+	recorder metrics.Recorder
+	logger   *log.Logger
+	denial   *mcpdpluginsv1.DenyTemplate
+	now      func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]*toolWindow
+
+	pending *pending.Map[pendingCall]
+}
+
+// New returns a Tracker for cfg recording metrics through recorder (nil disables metrics).
+func New(cfg Config, recorder metrics.Recorder) (*Tracker, error) {
+	if cfg.Objective <= 0 || cfg.Objective >= 1 {
+		return nil, fmt.Errorf("objective must be between 0 and 1 exclusive, got %v", cfg.Objective)
+	}
+	if cfg.Window < windowBuckets*time.Millisecond {
+		return nil, fmt.Errorf("window must be at least %dms", windowBuckets)
+	}
+	if cfg.LatencyThreshold < 0 {
+		return nil, fmt.Errorf("latency threshold cannot be negative")
+	}
+	cfg.Action = strings.ToLower(cfg.Action)
+	switch cfg.Action {
+	case ActionOff, ActionWarn, ActionDeny:
+	default:
+		return nil, fmt.Errorf("unknown slo action %q", cfg.Action)
+	}
+	denial, err := cfg.Template()
+	if err != nil {
+		return nil, err
+	}
+	if recorder == nil {
+		recorder = metrics.Nop()
+	}
+
+	t := &Tracker{
+		cfg:      cfg,
+		width:    cfg.Window / windowBuckets,
+		recorder: recorder,
+		logger:   log.Default(),
+		denial:   denial,
+		now:      time.Now,
+		windows:  map[string]*toolWindow{},
+		pending:  pending.New[pendingCall](pendingTTL),
+	}
+	if len(cfg.Tools) > 0 {
+		t.tools = make(map[string]struct{}, len(cfg.Tools))
+		for _, name := range cfg.Tools {
+			t.tools[name] = struct{}{}
+		}
+	}
+
+	return t, nil
+}
+
+// tracked reports whether tool is tracked.
+func (t *Tracker) tracked(tool string) bool {
+	if tool == "" {
+		return false
+	}
+	if t.tools == nil {
+		return true
+	}
+	_, ok := t.tools[tool]
+
+	return ok
+}
+
+// Record records a call to tool that took latency and failed or not.
+func (t *Tracker) Record(tool string, latency time.Duration, failed bool) {
+	if !t.tracked(tool) {
+		return
+	}
+
+	slow := !failed && t.cfg.LatencyThreshold > 0 && latency > t.cfg.LatencyThreshold
+	outcome := OutcomeOK
+	switch {
+	case failed:
+		outcome = OutcomeError
+	case slow:
+		outcome = OutcomeSlow
+	}
+	t.recorder.Count(MetricCalls, 1, metrics.L(LabelTool, tool), metrics.L(LabelOutcome, outcome))
+	t.recorder.Timing(MetricLatency, latency, metrics.L(LabelTool, tool))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	w, ok := t.windows[tool]
+	if !ok {
+		w = &toolWindow{}
+		t.windows[tool] = w
+	}
+	b := t.bucket(w)
+	b.calls++
+	if failed {
+		b.errors++
+	}
+	if slow {
+		b.slow++
+	}
+	b.latency += latency
+	b.max = max(b.max, latency)
+	t.update(tool, w)
+}
+
+// bucket returns the current bucket of w, resetting it when it held an older slot.
+func (t *Tracker) bucket(w *toolWindow) *bucket {
+	slot := t.now().UnixNano() / int64(t.width)
+	b := &w.buckets[slot%windowBuckets]
+	if b.slot != slot {
+		*b = bucket{slot: slot}
+	}
+
+	return b
+}
+
+// stats summarizes w. It must be called with t.mu held.
+func (t *Tracker) stats(tool string, w *toolWindow) ToolStats {
+	oldest := t.now().UnixNano()/int64(t.width) - windowBuckets + 1
+	s := ToolStats{Tool: tool}
+	var latency time.Duration
+	for _, b := range w.buckets {
+		if b.slot < oldest {
+			continue
+		}
+		s.Calls += b.calls
+		s.Errors += b.errors
+		s.Slow += b.slow
+		latency += b.latency
+		s.MaxLatency = max(s.MaxLatency, b.max)
+	}
+
+	s.SuccessRate, s.BudgetRemaining = 1, 1
+	if s.Calls > 0 {
+		bad := float64(s.Errors+s.Slow) / float64(s.Calls)
+		s.SuccessRate = 1 - bad
+		s.BudgetRemaining = max(0, 1-bad/(1-t.cfg.Objective))
+		s.MeanLatency = latency / time.Duration(s.Calls)
+	}
+	// 1-Objective is inexact in floating point (1-0.9 < 0.1), so a budget spent exactly would
+	// otherwise count as burned; allow for the rounding.
+	allowed := (1-t.cfg.Objective)*float64(s.Calls) + 1e-9
+	s.Burned = s.Calls > 0 && s.Calls >= t.cfg.MinCalls && float64(s.Errors+s.Slow) > allowed
+
+	return s
+}
+
+// update refreshes w's burned state, reporting transitions. It must be called with t.mu held.
+func (t *Tracker) update(tool string, w *toolWindow) ToolStats {
+	s := t.stats(tool, w)
+	if s.Burned == w.burned {
+		return s
+	}
+
+	w.burned = s.Burned
+	burned := 0.0
+	if s.Burned {
+		burned = 1
+	}
+	t.recorder.Gauge(MetricBudgetBurned, burned, metrics.L(LabelTool, tool))
+	if t.cfg.Action != ActionOff {
+		if s.Burned {
+			t.logger.Printf("slo: error budget of tool %s burned: %.2f%% of %d calls succeeded",
+				tool, 100*s.SuccessRate, s.Calls)
+		} else {
+			t.logger.Printf("slo: error budget of tool %s restored", tool)
+		}
+	}
+
+	return s
+}
+
+// Burned reports whether tool's error budget is burned.
+func (t *Tracker) Burned(tool string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	w, ok := t.windows[tool]
+	if !ok {
+		return false
+	}
+
+	return t.update(tool, w).Burned
+}
+
+// Stats returns the statistics of every tool called, sorted by name.
+func (t *Tracker) Stats() []ToolStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]ToolStats, 0, len(t.windows))
+	for tool, w := range t.windows {
+		out = append(out, t.update(tool, w))
+	}
+	slices.SortFunc(out, func(a, b ToolStats) int { return strings.Compare(a.Tool, b.Tool) })
+
+	return out
+}
+
+// AdminStats implements mcpdpluginsv1.StatsReporter, reporting Stats under "tools" with
+// latencies in milliseconds.
+func (t *Tracker) AdminStats(context.Context) (map[string]any, error) {
+	tools := map[string]any{}
+	for _, s := range t.Stats() {
+		tools[s.Tool] = map[string]any{
+			"calls":            float64(s.Calls),
+			"errors":           float64(s.Errors),
+			"slow":             float64(s.Slow),
+			"success_rate":     s.SuccessRate,
+			"budget_remaining": s.BudgetRemaining,
+			"mean_latency_ms":  float64(s.MeanLatency) / float64(time.Millisecond),
+			"max_latency_ms":   float64(s.MaxLatency) / float64(time.Millisecond),
+			"burned":           s.Burned,
+		}
+	}
+
+	return map[string]any{
+		"objective": t.cfg.Objective,
+		"window":    t.cfg.Window.String(),
+		"action":    t.cfg.Action,
+		"tools":     tools,
+	}, nil
+}
+
+// HandleRequest starts timing tools/call requests of tracked tools and, with ActionDeny,
+// short-circuits calls to tools whose budget is burned. Calls without a correlation ID cannot be
+// paired with their response and are not timed.
+func (t *Tracker) HandleRequest(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) *mcpdpluginsv1.HTTPResponse {
+	m, err := mcp.ParseOne(req.GetBody())
+	if err != nil || !m.IsRequest() || m.Method != "tools/call" {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+	tool := m.ToolName()
+	if !t.tracked(tool) {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+
+	if t.cfg.Action == ActionDeny && t.Burned(tool) {
+		return t.denial.Deny(ctx, req, mcpdpluginsv1.Denial{
+			Reason: fmt.Sprintf("tool %s is unavailable: error budget exhausted", tool),
+			RuleID: tool,
+			Status: http.StatusServiceUnavailable,
+		})
+	}
+	if id := mcpdpluginsv1.CorrelationID(ctx, req); id != "" {
+		now := t.now()
+		t.pending.Put(id, pendingCall{tool: tool, start: now}, now)
+	}
+
+	return &mcpdpluginsv1.HTTPResponse{Continue: true}
+}
+
+// HandleResponse records the outcome of the tool call resp answers and returns resp, continuing
+// the chain. 5xx responses, JSON-RPC errors other than the client's own (invalid request or
+// params, unknown method, parse error) and tool results flagged isError are failures.
+func (t *Tracker) HandleResponse(ctx context.Context, resp *mcpdpluginsv1.HTTPResponse) *mcpdpluginsv1.HTTPResponse {
+	out := &mcpdpluginsv1.HTTPResponse{
+		Continue:   true,
+		StatusCode: resp.GetStatusCode(),
+		Headers:    resp.GetHeaders(),
+		Body:       resp.GetBody(),
+	}
+
+	call, ok := t.pending.Take(mcpdpluginsv1.CorrelationID(ctx, nil), t.now())
+	if ok {
+		t.Record(call.tool, t.now().Sub(call.start), failed(resp))
+	}
+
+	return out
+}
+
+// failed reports whether resp is a failed tool call.
+func failed(resp *mcpdpluginsv1.HTTPResponse) bool {
+	if resp.GetStatusCode() >= 500 {
+		return true
+	}
+	m, err := mcp.ParseOne(resp.GetBody())
+	if err != nil {
+		return false
+	}
+	if m.Error != nil {
+		switch m.Error.Code {
+		case mcp.CodeParseError, mcp.CodeInvalidRequest, mcp.CodeMethodNotFound, mcp.CodeInvalidParams:
+			return false
+		default:
+			return true
+		}
+	}
+	var r struct {
+		IsError bool `json:"isError"`
+	}
+
+	return json.Unmarshal(m.Result, &r) == nil && r.IsError
+}
+
+// Plugin is a request- and response-flow plugin running the Tracker decoded from its
+// custom_config. Its statistics are reported through the admin service.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	recorder metrics.Recorder
+	tracker  atomic.Pointer[Tracker]
+}
+
+// NewPlugin returns a Plugin tracking with the default Config until Configure is called, recording
+// metrics through recorder (nil disables metrics).
+func NewPlugin(recorder metrics.Recorder) *Plugin {
+	p := &Plugin{recorder: recorder}
+	t, err := New(DefaultConfig(), recorder)
+	if err != nil {
+		panic(fmt.Sprintf("slo: invalid defaults: %v", err))
+	}
+	p.tracker.Store(t)
+
+	return p
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "tool-slo",
+		Version:     pluginVersion,
+		Description: "Tracks per-tool success rates and latencies against an error budget.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest, mcpdpluginsv1.FlowResponse), nil
+}
+
+// Configure decodes the tracker from cfg's custom_config. Statistics start over.
+func (p *Plugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	var c Config
+	if err := mcpdpluginsv1.DecodeConfig(ctx, cfg, &c); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	t, err := New(c, p.recorder)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	p.tracker.Store(t)
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleRequest applies the tracker to requests.
+func (p *Plugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.tracker.Load().HandleRequest(ctx, req), nil
+}
+
+// HandleResponse records tool call outcomes.
+func (p *Plugin) HandleResponse(
+	ctx context.Context,
+	resp *mcpdpluginsv1.HTTPResponse,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.tracker.Load().HandleResponse(ctx, resp), nil
+}
+
+// AdminStats implements mcpdpluginsv1.StatsReporter.
+func (p *Plugin) AdminStats(ctx context.Context) (map[string]any, error) {
+	return p.tracker.Load().AdminStats(ctx)
+}
@@ -0,0 +1,565 @@
+package slo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// fakeRecorder records every sample as a line such as "count slo.calls 1 [tool=a outcome=ok]".
+type fakeRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *fakeRecorder) add(kind, name string, value any, labels []metrics.Label) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Key + "=" + l.Value
+	}
+	r.lines = append(r.lines, fmt.Sprintf("%s %s %v [%s]", kind, name, value, strings.Join(parts, " ")))
+}
+
+func (r *fakeRecorder) Count(name string, delta int64, labels ...metrics.Label) {
+	r.add("count", name, delta, labels)
+}
+
+func (r *fakeRecorder) Gauge(name string, value float64, labels ...metrics.Label) {
+	r.add("gauge", name, value, labels)
+}
+
+func (r *fakeRecorder) Observe(name string, value float64, labels ...metrics.Label) {
+	r.add("observe", name, value, labels)
+}
+
+func (r *fakeRecorder) Timing(name string, d time.Duration, labels ...metrics.Label) {
+	r.add("timing", name, d, labels)
+}
+
+func (r *fakeRecorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.lines)
+}
+
+// fakeNow is a settable time source for a Tracker.
+type fakeNow struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeNow) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+func (f *fakeNow) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+}
+
+// newTracker returns a Tracker for cfg on a fake clock, logging to logs.
+func newTracker(t *testing.T, cfg Config, r metrics.Recorder, logs *bytes.Buffer) (*Tracker, *fakeNow) {
+	t.Helper()
+
+	tr, err := New(cfg, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := &fakeNow{now: time.Unix(1_700_000_000, 0)}
+	tr.now = clk.Now
+	if logs != nil {
+		tr.logger = log.New(logs, "", 0)
+	}
+
+	return tr, clk
+}
+
+// testConfig returns a Config with a 10s window, a 99% objective and MinCalls 10.
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Window = 10 * time.Second
+	cfg.MinCalls = 10
+
+	return cfg
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Objective != 0.99 || cfg.Window != 5*time.Minute || cfg.MinCalls != 20 || cfg.Action != ActionWarn {
+		t.Errorf("DefaultConfig = %+v", cfg)
+	}
+	if _, err := New(cfg, nil); err != nil {
+		t.Errorf("New(DefaultConfig()): %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		mod     func(*Config)
+		wantErr string
+	}{
+		{
+			name:    "zero objective",
+			mod:     func(c *Config) { c.Objective = 0 },
+			wantErr: "objective must be between 0 and 1",
+		},
+		{
+			name:    "objective of 1",
+			mod:     func(c *Config) { c.Objective = 1 },
+			wantErr: "objective must be between 0 and 1",
+		},
+		{
+			name:    "short window",
+			mod:     func(c *Config) { c.Window = 9 * time.Millisecond },
+			wantErr: "window must be at least 10ms",
+		},
+		{
+			name:    "negative latency threshold",
+			mod:     func(c *Config) { c.LatencyThreshold = -time.Second },
+			wantErr: "latency threshold cannot be negative",
+		},
+		{name: "unknown action", mod: func(c *Config) { c.Action = "page" }, wantErr: `unknown slo action "page"`},
+		{name: "action case", mod: func(c *Config) { c.Action = "DENY" }},
+		{
+			name:    "invalid deny template",
+			mod:     func(c *Config) { c.DenyTemplate, c.DenyTemplateFormat = "{{", "text" },
+			wantErr: "invalid deny template",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.mod(&cfg)
+			tr, err := New(cfg, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("New error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tr.cfg.Action != strings.ToLower(cfg.Action) {
+				t.Errorf("action = %q, want it lowercased", tr.cfg.Action)
+			}
+		})
+	}
+}
+
+func TestRecordOutcomes(t *testing.T) {
+	cfg := testConfig()
+	cfg.LatencyThreshold = time.Second
+	cfg.Tools = []string{"search"}
+	r := &fakeRecorder{}
+	tr, _ := newTracker(t, cfg, r, nil)
+
+	tr.Record("search", 100*time.Millisecond, false)
+	tr.Record("search", 2*time.Second, false)
+	tr.Record("search", 3*time.Second, true)
+	tr.Record("other", time.Millisecond, true)
+	tr.Record("", time.Millisecond, true)
+
+	want := []string{
+		"count slo.calls 1 [tool=search outcome=ok]",
+		"timing slo.latency 100ms [tool=search]",
+		"count slo.calls 1 [tool=search outcome=slow]",
+		"timing slo.latency 2s [tool=search]",
+		"count slo.calls 1 [tool=search outcome=error]",
+		"timing slo.latency 3s [tool=search]",
+	}
+	if got := r.recorded(); !slices.Equal(got, want) {
+		t.Errorf("recorded\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	stats := tr.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("Stats = %+v, want only the tracked tool", stats)
+	}
+	s := stats[0]
+	if s.Calls != 3 || s.Errors != 1 || s.Slow != 1 {
+		t.Errorf("counts = %d calls, %d errors, %d slow, want 3, 1, 1", s.Calls, s.Errors, s.Slow)
+	}
+	if s.MeanLatency != 1700*time.Millisecond || s.MaxLatency != 3*time.Second {
+		t.Errorf("latency mean %s max %s, want 1.7s and 3s", s.MeanLatency, s.MaxLatency)
+	}
+	if s.SuccessRate < 0.333 || s.SuccessRate > 0.334 || s.BudgetRemaining != 0 {
+		t.Errorf("success rate %v budget %v, want 1/3 and 0", s.SuccessRate, s.BudgetRemaining)
+	}
+	if s.Burned {
+		t.Error("budget burned below MinCalls")
+	}
+}
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		name          string
+		objective     float64
+		minCalls      int64
+		good, bad     int
+		wantBurned    bool
+		wantRemaining float64
+	}{
+		{name: "no calls", objective: 0.99, wantRemaining: 1},
+		{name: "all good", objective: 0.99, good: 100, wantRemaining: 1},
+		{name: "within budget", objective: 0.9, good: 95, bad: 5, wantRemaining: 0.5},
+		{name: "budget exactly spent", objective: 0.9, good: 90, bad: 10, wantRemaining: 0},
+		{name: "burned", objective: 0.9, good: 89, bad: 11, wantBurned: true},
+		{name: "below MinCalls", objective: 0.9, minCalls: 50, good: 10, bad: 10},
+		{name: "at MinCalls", objective: 0.9, minCalls: 20, good: 10, bad: 10, wantBurned: true},
+		{name: "no MinCalls", objective: 0.5, bad: 1, wantBurned: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Objective, cfg.MinCalls = tt.objective, tt.minCalls
+			tr, _ := newTracker(t, cfg, nil, &bytes.Buffer{})
+			for range tt.good {
+				tr.Record("a", time.Millisecond, false)
+			}
+			for range tt.bad {
+				tr.Record("a", time.Millisecond, true)
+			}
+
+			if got := tr.Burned("a"); got != tt.wantBurned {
+				t.Errorf("Burned = %t, want %t", got, tt.wantBurned)
+			}
+			if tt.good+tt.bad == 0 {
+				if len(tr.Stats()) != 0 {
+					t.Errorf("Stats = %+v for a tool never called", tr.Stats())
+				}
+				return
+			}
+			s := tr.Stats()[0]
+			if diff := s.BudgetRemaining - tt.wantRemaining; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("BudgetRemaining = %v, want %v", s.BudgetRemaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	var logs bytes.Buffer
+	r := &fakeRecorder{}
+	tr, clk := newTracker(t, testConfig(), r, &logs)
+
+	// Ten failures burn the budget.
+	for range 10 {
+		tr.Record("a", time.Millisecond, true)
+	}
+	if !tr.Burned("a") {
+		t.Fatal("budget not burned")
+	}
+	if !strings.Contains(logs.String(), "slo: error budget of tool a burned: 0.00% of 10 calls succeeded") {
+		t.Errorf("logged %q, want the burn", logs.String())
+	}
+
+	// Good calls in later buckets do not restore it while the failures are in the window.
+	clk.advance(5 * time.Second)
+	for range 50 {
+		tr.Record("a", time.Millisecond, false)
+	}
+	if !tr.Burned("a") {
+		t.Error("budget restored with the failures still in the window")
+	}
+
+	// Once the failures age out, it is restored.
+	clk.advance(5 * time.Second)
+	if tr.Burned("a") {
+		t.Error("budget still burned after the failures left the window")
+	}
+	if s := tr.Stats()[0]; s.Calls != 50 || s.Errors != 0 {
+		t.Errorf("window holds %d calls and %d errors, want the 50 good calls", s.Calls, s.Errors)
+	}
+	if !strings.Contains(logs.String(), "slo: error budget of tool a restored") {
+		t.Errorf("logged %q, want the restore", logs.String())
+	}
+
+	// A whole window later, nothing is left.
+	clk.advance(time.Hour)
+	if s := tr.Stats()[0]; s.Calls != 0 || s.SuccessRate != 1 {
+		t.Errorf("stats after an hour = %+v, want an empty window", s)
+	}
+
+	var gauges []string
+	for _, l := range r.recorded() {
+		if strings.HasPrefix(l, "gauge ") {
+			gauges = append(gauges, l)
+		}
+	}
+	want := []string{"gauge slo.budget_burned 1 [tool=a]", "gauge slo.budget_burned 0 [tool=a]"}
+	if !slices.Equal(gauges, want) {
+		t.Errorf("gauges = %q, want %q", gauges, want)
+	}
+}
+
+func TestActionOffDoesNotLog(t *testing.T) {
+	var logs bytes.Buffer
+	cfg := testConfig()
+	cfg.Action = ActionOff
+	tr, _ := newTracker(t, cfg, nil, &logs)
+	for range 10 {
+		tr.Record("a", time.Millisecond, true)
+	}
+	if !tr.Burned("a") || logs.Len() != 0 {
+		t.Errorf("burned %t, logged %q, want burned silently", tr.Burned("a"), logs.String())
+	}
+}
+
+// withID returns a context carrying the correlation ID id, as mcpd sends it.
+func withID(id string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", id))
+}
+
+func toolCall(tool string) *mcpdpluginsv1.HTTPRequest {
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + tool + `"}}`
+	return &mcpdpluginsv1.HTTPRequest{Method: http.MethodPost, Body: []byte(body)}
+}
+
+func TestHandleRequestDeny(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     string
+		template   string
+		wantDenied bool
+		wantMsg    string
+	}{
+		{name: "deny", action: ActionDeny, wantDenied: true, wantMsg: "tool a is unavailable: error budget exhausted"},
+		{
+			name:       "deny template",
+			action:     ActionDeny,
+			template:   "{{.RuleID}} is resting",
+			wantDenied: true,
+			wantMsg:    "a is resting",
+		},
+		{name: "warn", action: ActionWarn},
+		{name: "off", action: ActionOff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Action = tt.action
+			cfg.DenyTemplate = tt.template
+			tr, _ := newTracker(t, cfg, nil, &bytes.Buffer{})
+			for range 10 {
+				tr.Record("a", time.Millisecond, true)
+			}
+
+			resp := tr.HandleRequest(withID("1"), toolCall("a"))
+			if resp.GetContinue() == tt.wantDenied {
+				t.Fatalf("response %v, want denied %t", resp, tt.wantDenied)
+			}
+			if !tt.wantDenied {
+				return
+			}
+			if resp.GetStatusCode() != http.StatusServiceUnavailable {
+				t.Errorf("status = %d, want 503", resp.GetStatusCode())
+			}
+			var m mcp.Message
+			err := json.Unmarshal(resp.GetBody(), &m)
+			if err != nil || m.Error == nil || m.Error.Message != tt.wantMsg {
+				t.Errorf("deny body = %s, want message %q", resp.GetBody(), tt.wantMsg)
+			}
+			// Other tools still pass.
+			if r := tr.HandleRequest(withID("2"), toolCall("b")); !r.GetContinue() {
+				t.Errorf("call to a healthy tool denied: %v", r)
+			}
+		})
+	}
+}
+
+func TestHandleResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		resp       *mcpdpluginsv1.HTTPResponse
+		wantErrors int64
+	}{
+		{
+			name: "ok",
+			resp: &mcpdpluginsv1.HTTPResponse{StatusCode: 200, Body: []byte(`{"jsonrpc":"2.0","id":1,"result":{}}`)},
+		},
+		{name: "server error status", resp: &mcpdpluginsv1.HTTPResponse{StatusCode: 502}, wantErrors: 1},
+		{
+			name: "tool error",
+			resp: &mcpdpluginsv1.HTTPResponse{
+				StatusCode: 200,
+				Body:       []byte(`{"jsonrpc":"2.0","id":1,"result":{"isError":true}}`),
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "internal JSON-RPC error",
+			resp: &mcpdpluginsv1.HTTPResponse{
+				StatusCode: 200,
+				Body:       []byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"x"}}`),
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "client JSON-RPC error",
+			resp: &mcpdpluginsv1.HTTPResponse{
+				StatusCode: 200,
+				Body:       []byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"x"}}`),
+			},
+		},
+		{name: "client error status", resp: &mcpdpluginsv1.HTTPResponse{StatusCode: 404, Body: []byte("not found")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			tr, clk := newTracker(t, cfg, nil, nil)
+
+			if r := tr.HandleRequest(withID("1"), toolCall("a")); !r.GetContinue() {
+				t.Fatalf("request not continued: %v", r)
+			}
+			clk.advance(250 * time.Millisecond)
+			out := tr.HandleResponse(withID("1"), tt.resp)
+			if !out.GetContinue() || out.GetStatusCode() != tt.resp.GetStatusCode() ||
+				!bytes.Equal(out.GetBody(), tt.resp.GetBody()) {
+				t.Errorf("HandleResponse = %v, want resp continued unchanged", out)
+			}
+
+			stats := tr.Stats()
+			if len(stats) != 1 || stats[0].Calls != 1 || stats[0].Errors != tt.wantErrors {
+				t.Fatalf("Stats = %+v, want 1 call with %d errors", stats, tt.wantErrors)
+			}
+			if stats[0].MeanLatency != 250*time.Millisecond {
+				t.Errorf("latency = %s, want 250ms", stats[0].MeanLatency)
+			}
+		})
+	}
+}
+
+func TestHandleRequestUntracked(t *testing.T) {
+	cfg := testConfig()
+	cfg.Tools = []string{"search"}
+	tests := []struct {
+		name string
+		ctx  context.Context
+		req  *mcpdpluginsv1.HTTPRequest
+	}{
+		{name: "untracked tool", ctx: withID("1"), req: toolCall("other")},
+		{name: "not a tool call", ctx: withID("1"), req: &mcpdpluginsv1.HTTPRequest{
+			Body: []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`),
+		}},
+		{name: "notification", ctx: withID("1"), req: &mcpdpluginsv1.HTTPRequest{
+			Body: []byte(`{"jsonrpc":"2.0","method":"tools/call","params":{"name":"search"}}`),
+		}},
+		{name: "no correlation ID", ctx: context.Background(), req: toolCall("search")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, _ := newTracker(t, cfg, nil, nil)
+			if r := tr.HandleRequest(tt.ctx, tt.req); !r.GetContinue() {
+				t.Fatalf("request not continued: %v", r)
+			}
+			tr.HandleResponse(tt.ctx, &mcpdpluginsv1.HTTPResponse{StatusCode: 500})
+			if s := tr.Stats(); len(s) != 0 {
+				t.Errorf("Stats = %+v, want nothing recorded", s)
+			}
+		})
+	}
+}
+
+func TestAdminStats(t *testing.T) {
+	tr, _ := newTracker(t, testConfig(), nil, nil)
+	tr.Record("b", 2*time.Millisecond, false)
+	tr.Record("a", 4*time.Millisecond, true)
+
+	got, err := tr.AdminStats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["objective"] != 0.99 || got["window"] != "10s" || got["action"] != ActionWarn {
+		t.Errorf("AdminStats = %v", got)
+	}
+	tools := got["tools"].(map[string]any)
+	a := tools["a"].(map[string]any)
+	if a["calls"] != 1.0 || a["errors"] != 1.0 || a["max_latency_ms"] != 4.0 || a["success_rate"] != 0.0 {
+		t.Errorf("tool a = %v", a)
+	}
+	if len(tools) != 2 {
+		t.Errorf("tools = %v, want a and b", tools)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	p := NewPlugin(nil)
+	ctx := context.Background()
+
+	md, err := p.GetMetadata(ctx, &emptypb.Empty{})
+	if err != nil || md.GetName() != "tool-slo" || md.GetVersion() != pluginVersion {
+		t.Errorf("GetMetadata = %v, %v", md, err)
+	}
+	caps, err := p.GetCapabilities(ctx, &emptypb.Empty{})
+	if err != nil || len(caps.GetFlows()) != 2 {
+		t.Errorf("GetCapabilities = %v, %v, want both flows", caps, err)
+	}
+
+	tests := []struct {
+		name     string
+		custom   map[string]string
+		wantCode codes.Code
+	}{
+		{name: "valid", custom: map[string]string{"objective": "0.9", "window": "1m", "action": "deny"}},
+		{name: "invalid objective", custom: map[string]string{"objective": "2"}, wantCode: codes.InvalidArgument},
+		{name: "undecodable window", custom: map[string]string{"window": "soon"}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := p.tracker.Load()
+			_, err := p.Configure(ctx, &mcpdpluginsv1.PluginConfig{CustomConfig: tt.custom})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("Configure error = %v, want %s", err, tt.wantCode)
+			}
+			if after := p.tracker.Load(); (after != before) != (tt.wantCode == codes.OK) {
+				t.Errorf("tracker replaced %t, want %t", after != before, tt.wantCode == codes.OK)
+			}
+		})
+	}
+	if got := p.tracker.Load().cfg; got.Objective != 0.9 || got.Action != ActionDeny {
+		t.Errorf("configured %+v, want the valid configuration kept", got)
+	}
+
+	resp, err := p.HandleRequest(withID("1"), toolCall("a"))
+	if err != nil || !resp.GetContinue() {
+		t.Fatalf("HandleRequest = %v, %v", resp, err)
+	}
+	if _, err := p.HandleResponse(withID("1"), &mcpdpluginsv1.HTTPResponse{StatusCode: 500}); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := p.AdminStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := stats["tools"].(map[string]any)["a"].(map[string]any); a["errors"] != 1.0 {
+		t.Errorf("AdminStats = %v, want the failed call to a", stats)
+	}
+}
@@ -0,0 +1,50 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/sampling"
+)
+
+// WithSlowRequestLog logs every HandleRequest and HandleResponse call whose handler takes at
+// least threshold, including the HTTP method and path, the MCP tool name (for tools/call
+// requests), and the correlation ID (see CorrelationID). Slow calls are subject to the sampler
+// configured with WithSampler (feature sampling.FeatureSlowLog).
+func WithSlowRequestLog(threshold time.Duration) ServeOption {
+	return func(o *serveOptions) error {
+		if threshold <= 0 {
+			return fmt.Errorf("slow request threshold must be positive")
+		}
+		o.subscribers = append(o.subscribers, pendingSubscription{
+			handler: func(ctx context.Context, ev Event) {
+				if ev.Duration < threshold || !o.sample(sampling.FeatureSlowLog, ev) {
+					return
+				}
+				logSlowRequest(ctx, o.logger, threshold, ev)
+			},
+			kinds: []EventKind{EventRequest},
+		})
+		return nil
+	}
+}
+
+func logSlowRequest(ctx context.Context, logger *log.Logger, threshold time.Duration, ev Event) {
+	switch {
+	case ev.Request != nil:
+		req := ev.Request
+		logger.Printf(
+			"slow %s: duration=%s threshold=%s http_method=%s path=%q tool=%q correlation_id=%q",
+			ev.Method, ev.Duration, threshold, req.GetMethod(), req.GetPath(),
+			mcp.ToolName(req.GetBody()), CorrelationID(ctx, req),
+		)
+	case ev.Response != nil:
+		logger.Printf(
+			"slow %s: duration=%s threshold=%s status=%d correlation_id=%q",
+			ev.Method, ev.Duration, threshold, ev.Response.GetStatusCode(), CorrelationID(ctx, nil),
+		)
+	}
+}
@@ -0,0 +1,148 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+func TestWithSlowRequestLog(t *testing.T) {
+	toolCall := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search"}}`
+	tests := []struct {
+		name string
+		ev   Event
+		want string // Logged line without the timestamp; empty for none.
+	}{
+		{
+			name: "slow request",
+			ev: Event{
+				Method:   "HandleRequest",
+				Duration: 2 * time.Second,
+				Request: &HTTPRequest{
+					Method:  "POST",
+					Path:    "/mcp",
+					Body:    []byte(toolCall),
+					Headers: map[string]string{"X-Request-Id": "req-1"},
+				},
+			},
+			want: `slow HandleRequest: duration=2s threshold=1s http_method=POST path="/mcp" tool="search" ` +
+				`correlation_id="req-1"`,
+		},
+		{
+			name: "threshold is inclusive",
+			ev: Event{
+				Method:   "HandleRequest",
+				Duration: time.Second,
+				Request:  &HTTPRequest{Method: "GET", Path: "/"},
+			},
+			want: `slow HandleRequest: duration=1s threshold=1s http_method=GET path="/" tool="" correlation_id=""`,
+		},
+		{
+			name: "slow response",
+			ev:   Event{Method: "HandleResponse", Duration: 3 * time.Second, Response: &HTTPResponse{StatusCode: 502}},
+			want: `slow HandleResponse: duration=3s threshold=1s status=502 correlation_id=""`,
+		},
+		{
+			name: "fast request",
+			ev:   Event{Method: "HandleRequest", Duration: 999 * time.Millisecond, Request: &HTTPRequest{}},
+		},
+		{
+			name: "other RPCs",
+			ev:   Event{Method: "Configure", Duration: time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			o, err := newServeOptions(WithLogger(log.New(&buf, "", 0)), WithSlowRequestLog(time.Second))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.ev.Kind = EventRequest
+			o.bus.Publish(context.Background(), tt.ev)
+
+			if got := strings.TrimSuffix(buf.String(), "\n"); got != tt.want {
+				t.Errorf("logged %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithSlowRequestLogThreshold(t *testing.T) {
+	for _, threshold := range []time.Duration{0, -time.Second} {
+		if _, err := newServeOptions(WithSlowRequestLog(threshold)); err == nil {
+			t.Errorf("WithSlowRequestLog(%s) was accepted", threshold)
+		}
+	}
+}
+
+func TestWithLoggerNil(t *testing.T) {
+	if _, err := newServeOptions(WithLogger(nil)); err == nil {
+		t.Error("WithLogger accepted a nil logger")
+	}
+}
+
+func TestCorrelationID(t *testing.T) {
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	tests := []struct {
+		name string
+		md   metadata.MD
+		req  *HTTPRequest
+		want string
+	}{
+		{
+			name: "request ID metadata",
+			md:   metadata.Pairs("x-request-id", "md-req", "x-correlation-id", "md-corr"),
+			req:  &HTTPRequest{Headers: map[string]string{"X-Request-Id": "hdr-req"}},
+			want: "md-req",
+		},
+		{
+			name: "correlation ID metadata",
+			md:   metadata.Pairs("x-correlation-id", "md-corr"),
+			want: "md-corr",
+		},
+		{
+			name: "empty metadata value is skipped",
+			md:   metadata.Pairs("x-request-id", "", "x-correlation-id", "md-corr"),
+			want: "md-corr",
+		},
+		{
+			name: "request ID header",
+			req:  &HTTPRequest{Headers: map[string]string{"x-correlation-id": "hdr-corr", "X-Request-ID": "hdr-req"}},
+			want: "hdr-req",
+		},
+		{
+			name: "correlation ID header",
+			req:  &HTTPRequest{Headers: map[string]string{"X-Correlation-Id": "hdr-corr"}},
+			want: "hdr-corr",
+		},
+		{
+			name: "trace ID fallback",
+			md:   metadata.Pairs(TraceparentHeader, traceparent),
+			want: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name: "trace ID header fallback",
+			req:  &HTTPRequest{Headers: map[string]string{"traceparent": traceparent}},
+			want: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{name: "none", req: &HTTPRequest{}},
+		{name: "nil request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			if got := CorrelationID(ctx, tt.req); got != tt.want {
+				t.Errorf("CorrelationID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+// Package spiffe gives plugins a SPIFFE workload identity: X.509-SVIDs fetched from the SPIFFE
+// Workload API (as served by the SPIRE agent) and rotated automatically, for the plugin's TLS
+// listener and for its outbound calls.
+//
+// A Source streams SVID and trust bundle updates from the Workload API, and its TLS configurations
+// always present the current SVID and verify peers against the current bundles:
+//
+//	src, err := spiffe.NewSource(ctx) // SPIFFE_ENDPOINT_SOCKET, e.g. unix:///run/spire/agent.sock
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	mcpdID := spiffe.AuthorizeID("spiffe://example.org/mcpd")
+//	client, err := httpclientx.New(httpclientx.DefaultConfig(),
+//	    httpclientx.WithTLSConfig(src.ClientTLSConfig(spiffe.AuthorizeMemberOf("example.org"))))
+//	...
+//	err = mcpdpluginsv1.Serve(plugin, mcpdpluginsv1.WithTLS(src.ServerTLSConfig(mcpdID)), src.CloseOnShutdown())
+//
+// Only X.509-SVIDs are supported; JWT-SVIDs are not fetched.
+package spiffe
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// EndpointSocketEnv is the environment variable holding the Workload API address.
+const EndpointSocketEnv = "SPIFFE_ENDPOINT_SOCKET"
+
+// Reconnection backoff bounds after the Workload API stream fails.
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// SVID is an X.509 SPIFFE Verifiable Identity Document.
+type SVID struct {
+	// ID is the SPIFFE ID, such as "spiffe://example.org/plugins/authz".
+	ID string
+
+	// Certificates is the certificate chain, leaf first, and PrivateKey the leaf's key.
+	Certificates []*x509.Certificate
+	PrivateKey   crypto.Signer
+
+	// Bundle holds the CA certificates of the SVID's trust domain.
+	Bundle []*x509.Certificate
+
+	// Hint is the operator-assigned hint telling several SVIDs of a workload apart.
+	Hint string
+}
+
+// TrustDomain returns the trust domain name of the SVID, such as "example.org".
+func (s *SVID) TrustDomain() string {
+	return trustDomainName(s.ID)
+}
+
+// Source holds the current SVID and trust bundles of the workload, kept up to date from the
+// Workload API. It is safe for concurrent use.
+type Source struct {
+	addr   string
+	hint   string
+	logger *log.Logger
+
+	conn   *grpc.ClientConn
+	state  atomic.Pointer[x509Update]
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	closeOnce sync.Once
+}
+
+// Option configures a Source.
+type Option func(*Source) error
+
+// WithAddress sets the Workload API address, a unix:// or tcp:// URL (defaults to the
+// SPIFFE_ENDPOINT_SOCKET environment variable).
+func WithAddress(addr string) Option {
+	return func(s *Source) error {
+		if addr == "" {
+			return fmt.Errorf("workload API address cannot be empty")
+		}
+		s.addr = addr
+		return nil
+	}
+}
+
+// WithHint selects the SVID with the given hint when the workload is issued several. By default
+// the first SVID, which the Workload API designates as the default, is used.
+func WithHint(hint string) Option {
+	return func(s *Source) error {
+		s.hint = hint
+		return nil
+	}
+}
+
+// WithLogger sets the logger used to report Workload API errors and rotations (defaults to
+// log.Default()).
+func WithLogger(logger *log.Logger) Option {
+	return func(s *Source) error {
+		if logger == nil {
+			return fmt.Errorf("logger cannot be nil")
+		}
+		s.logger = logger
+		return nil
+	}
+}
+
+// NewSource connects to the Workload API and waits, until ctx is done, for the first SVID. The
+// Source keeps streaming updates, reconnecting with backoff, until Close.
+func NewSource(ctx context.Context, opts ...Option) (*Source, error) {
+	s := &Source{addr: os.Getenv(EndpointSocketEnv), logger: log.Default(), done: make(chan struct{})}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	if s.addr == "" {
+		return nil, fmt.Errorf("workload API address is required (set %s or use WithAddress)", EndpointSocketEnv)
+	}
+
+	conn, err := dialWorkloadAPI(s.addr)
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	ready := make(chan struct{})
+	go s.run(runCtx, ready)
+
+	select {
+	case <-ready:
+		return s, nil
+	case <-ctx.Done():
+		_ = s.Close()
+		return nil, fmt.Errorf("waiting for the first SVID: %w", ctx.Err())
+	}
+}
+
+// run streams updates until ctx is done, closing ready after the first one.
+func (s *Source) run(ctx context.Context, ready chan struct{}) {
+	defer close(s.done)
+
+	var once sync.Once
+	backoff := minBackoff
+	for {
+		err := streamX509(ctx, s.conn, func(u x509Update) {
+			if err := s.apply(u); err != nil {
+				s.logger.Printf("spiffe: ignoring update: %v", err)
+				return
+			}
+			backoff = minBackoff
+			once.Do(func() { close(ready) })
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.Printf("spiffe: workload API stream failed, retrying in %s: %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// apply makes u current, moving its selected SVID first.
+func (s *Source) apply(u x509Update) error {
+	i := 0
+	if s.hint != "" {
+		i = -1
+		for j, svid := range u.svids {
+			if svid.Hint == s.hint {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			return fmt.Errorf("no SVID with hint %q", s.hint)
+		}
+	}
+	u.svids[0], u.svids[i] = u.svids[i], u.svids[0]
+
+	if prev := s.state.Load(); prev != nil && prev.svids[0].ID == u.svids[0].ID &&
+		prev.svids[0].Certificates[0].Equal(u.svids[0].Certificates[0]) {
+		s.state.Store(&u)
+		return nil
+	}
+	s.state.Store(&u)
+	leaf := u.svids[0].Certificates[0]
+	s.logger.Printf("spiffe: using SVID %s (expires %s)", u.svids[0].ID, leaf.NotAfter.Format(time.RFC3339))
+
+	return nil
+}
+
+// SVID returns the current SVID.
+func (s *Source) SVID() *SVID {
+	return s.state.Load().svids[0]
+}
+
+// Bundle returns the CA certificates of trustDomain (such as "example.org"), the workload's own
+// or a federated one, and whether the trust domain is known.
+func (s *Source) Bundle(trustDomain string) ([]*x509.Certificate, bool) {
+	b, ok := s.state.Load().bundles[trustDomainName(trustDomain)]
+	return b, ok
+}
+
+// Close stops streaming updates and closes the Workload API connection. The last SVID and bundles
+// remain available.
+func (s *Source) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.cancel()
+		err = s.conn.Close()
+		<-s.done
+	})
+
+	return err
+}
+
+// CloseOnShutdown returns a ServeOption closing the source when the plugin server starts
+// stopping.
+func (s *Source) CloseOnShutdown() mcpdpluginsv1.ServeOption {
+	return mcpdpluginsv1.WithEventSubscriber(func(_ context.Context, ev mcpdpluginsv1.Event) {
+		if ev.Phase != mcpdpluginsv1.PhaseStopping {
+			return
+		}
+		if err := s.Close(); err != nil {
+			s.logger.Printf("spiffe: closing workload API connection: %v", err)
+		}
+	}, mcpdpluginsv1.EventLifecycle)
+}
+
+// trustDomainName returns the trust domain of a SPIFFE ID or trust domain ID, without the scheme:
+// "example.org" for "spiffe://example.org/workload".
+func trustDomainName(id string) string {
+	if u, err := url.Parse(id); err == nil && u.Scheme == "spiffe" {
+		return strings.ToLower(u.Host)
+	}
+
+	return strings.ToLower(strings.TrimPrefix(id, "spiffe://"))
+}
@@ -0,0 +1,295 @@
+package spiffe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use, for loggers written by the stream
+// goroutine.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// waitFor polls cond until it holds, failing the test after five seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// newTestSource connects a Source to api, logging to logs.
+func newTestSource(t *testing.T, api *workloadAPI, logs *syncBuffer, opts ...Option) *Source {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	opts = append([]Option{WithAddress(api.addr), WithLogger(log.New(logs, "", 0))}, opts...)
+	src, err := NewSource(ctx, opts...)
+	if err != nil {
+		t.Fatalf("NewSource: %v", err)
+	}
+	t.Cleanup(func() { _ = src.Close() })
+
+	return src
+}
+
+func TestNewSourceErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		opts    []Option
+		wantErr string
+	}{
+		{name: "no address", wantErr: "workload API address is required"},
+		{name: "empty address", opts: []Option{WithAddress("")}, wantErr: "address cannot be empty"},
+		{name: "invalid address", env: "/run/spire/agent.sock", wantErr: "must start with unix:// or tcp://"},
+		{
+			name:    "nil logger",
+			opts:    []Option{WithAddress("unix:///run/spire/agent.sock"), WithLogger(nil)},
+			wantErr: "logger cannot be nil",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EndpointSocketEnv, tt.env)
+			_, err := NewSource(context.Background(), tt.opts...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewSource error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewSourceAddressFromEnv(t *testing.T) {
+	api := newWorkloadAPI(t)
+	ca := newCA(t, "example.org")
+	api.updates <- encodeResponse([][]byte{ca.svidMessage(t, "spiffe://example.org/a", "")}, nil)
+	t.Setenv(EndpointSocketEnv, api.addr)
+
+	src := newTestSource(t, api, &syncBuffer{})
+	if got := src.SVID().ID; got != "spiffe://example.org/a" {
+		t.Errorf("SVID = %s, want spiffe://example.org/a", got)
+	}
+	_, headers := api.stats()
+	if len(headers) != 1 || headers[0] != "true" {
+		t.Errorf("%s headers = %q, want true", securityHeader, headers)
+	}
+}
+
+func TestNewSourceWaitsForFirstSVID(t *testing.T) {
+	ca := newCA(t, "example.org")
+
+	tests := []struct {
+		name    string
+		update  []byte
+		opts    []Option
+		wantLog string
+	}{
+		{name: "no update"},
+		{
+			name:    "no SVID with the hint",
+			update:  encodeResponse([][]byte{ca.svidMessage(t, "spiffe://example.org/a", "a")}, nil),
+			opts:    []Option{WithHint("b")},
+			wantLog: `spiffe: ignoring update: no SVID with hint "b"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newWorkloadAPI(t)
+			if tt.update != nil {
+				api.updates <- tt.update
+			}
+			var logs syncBuffer
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			opts := append([]Option{WithAddress(api.addr), WithLogger(log.New(&logs, "", 0))}, tt.opts...)
+			_, err := NewSource(ctx, opts...)
+			if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "first SVID") {
+				t.Errorf("NewSource error = %v, want a deadline waiting for the first SVID", err)
+			}
+			if !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("logs = %q, want %q", logs.String(), tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestSourceHint(t *testing.T) {
+	ca := newCA(t, "example.org")
+	update := encodeResponse([][]byte{
+		ca.svidMessage(t, "spiffe://example.org/a", "internal"),
+		ca.svidMessage(t, "spiffe://example.org/b", "external"),
+	}, nil)
+
+	tests := []struct {
+		hint string
+		want string
+	}{
+		{hint: "", want: "spiffe://example.org/a"},
+		{hint: "internal", want: "spiffe://example.org/a"},
+		{hint: "external", want: "spiffe://example.org/b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hint, func(t *testing.T) {
+			api := newWorkloadAPI(t)
+			api.updates <- update
+			src := newTestSource(t, api, &syncBuffer{}, WithHint(tt.hint))
+			if got := src.SVID(); got.ID != tt.want || got.Hint != tt.hint && tt.hint != "" {
+				t.Errorf("SVID = %s (hint %q), want %s", got.ID, got.Hint, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourceBundle(t *testing.T) {
+	api := newWorkloadAPI(t)
+	ca, other := newCA(t, "example.org"), newCA(t, "other.org")
+	api.updates <- encodeResponse(
+		[][]byte{ca.svidMessage(t, "spiffe://example.org/a", "")},
+		map[string][]byte{"spiffe://other.org": other.cert.Raw},
+	)
+	src := newTestSource(t, api, &syncBuffer{})
+
+	if got := src.SVID().TrustDomain(); got != "example.org" {
+		t.Errorf("TrustDomain = %s, want example.org", got)
+	}
+	tests := []struct {
+		trustDomain string
+		want        *testCA
+	}{
+		{trustDomain: "example.org", want: ca},
+		{trustDomain: "spiffe://example.org", want: ca},
+		{trustDomain: "Other.org", want: other},
+		{trustDomain: "unknown.org"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.trustDomain, func(t *testing.T) {
+			b, ok := src.Bundle(tt.trustDomain)
+			if ok != (tt.want != nil) {
+				t.Fatalf("Bundle found = %t, want %t", ok, tt.want != nil)
+			}
+			if ok && (len(b) != 1 || !b[0].Equal(tt.want.cert)) {
+				t.Errorf("Bundle = %v, want the %s CA", b, tt.want.domain)
+			}
+		})
+	}
+}
+
+func TestSourceRotation(t *testing.T) {
+	api := newWorkloadAPI(t)
+	ca := newCA(t, "example.org")
+	first := ca.svidMessage(t, "spiffe://example.org/a", "")
+	api.updates <- encodeResponse([][]byte{first}, nil)
+	var logs syncBuffer
+	src := newTestSource(t, api, &logs)
+	initial := src.SVID().Certificates[0]
+
+	// A bundle-only change keeps the SVID and is not logged as a rotation.
+	other := newCA(t, "other.org")
+	api.updates <- encodeResponse([][]byte{first}, map[string][]byte{"other.org": other.cert.Raw})
+	waitFor(t, "the bundle update", func() bool {
+		_, ok := src.Bundle("other.org")
+		return ok
+	})
+	if got := strings.Count(logs.String(), "using SVID"); got != 1 {
+		t.Errorf("logged %d rotations after a bundle update, want 1:\n%s", got, logs.String())
+	}
+
+	api.updates <- encodeResponse([][]byte{ca.svidMessage(t, "spiffe://example.org/a", "")}, nil)
+	waitFor(t, "the rotation", func() bool { return !src.SVID().Certificates[0].Equal(initial) })
+	if got := strings.Count(logs.String(), "using SVID spiffe://example.org/a"); got != 2 {
+		t.Errorf("logged %d rotations, want 2:\n%s", got, logs.String())
+	}
+}
+
+func TestSourceReconnects(t *testing.T) {
+	api := newWorkloadAPI(t)
+	ca := newCA(t, "example.org")
+	api.updates <- encodeResponse([][]byte{ca.svidMessage(t, "spiffe://example.org/a", "")}, nil)
+	var logs syncBuffer
+	src := newTestSource(t, api, &logs)
+
+	// An invalid response fails the stream, and the source reconnects after the backoff keeping
+	// its SVID.
+	api.updates <- encodeResponse(nil, nil)
+	waitFor(t, "the reconnection", func() bool {
+		streams, _ := api.stats()
+		return streams == 2
+	})
+	if want := "invalid workload API response: no SVID"; !strings.Contains(logs.String(), want) {
+		t.Errorf("logs = %q, want %q", logs.String(), want)
+	}
+	if got := src.SVID().ID; got != "spiffe://example.org/a" {
+		t.Errorf("SVID after the failure = %s", got)
+	}
+
+	api.updates <- encodeResponse([][]byte{ca.svidMessage(t, "spiffe://example.org/b", "")}, nil)
+	waitFor(t, "the update after reconnecting", func() bool { return src.SVID().ID == "spiffe://example.org/b" })
+}
+
+func TestSourceClose(t *testing.T) {
+	api := newWorkloadAPI(t)
+	ca := newCA(t, "example.org")
+	api.updates <- encodeResponse([][]byte{ca.svidMessage(t, "spiffe://example.org/a", "")}, nil)
+	src := newTestSource(t, api, &syncBuffer{})
+
+	if err := src.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := src.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+	if got := src.SVID().ID; got != "spiffe://example.org/a" {
+		t.Errorf("SVID after Close = %s, want the last one", got)
+	}
+	if _, ok := src.Bundle("example.org"); !ok {
+		t.Error("Bundle after Close lost the last bundle")
+	}
+}
+
+func TestTrustDomainName(t *testing.T) {
+	tests := []struct {
+		id   string
+		want string
+	}{
+		{id: "spiffe://example.org/workload", want: "example.org"},
+		{id: "spiffe://Example.ORG", want: "example.org"},
+		{id: "example.org", want: "example.org"},
+		{id: "Example.org", want: "example.org"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if got := trustDomainName(tt.id); got != tt.want {
+				t.Errorf("trustDomainName(%q) = %q, want %q", tt.id, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package spiffe
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"slices"
+)
+
+// Authorizer decides whether a peer whose SVID verified against its trust bundle may connect. id
+// is the peer's SPIFFE ID and chain its verified certificate chain, leaf first.
+type Authorizer func(id string, chain []*x509.Certificate) error
+
+// AuthorizeAny accepts every peer with a valid SVID from a trust domain the source has a bundle
+// for.
+func AuthorizeAny() Authorizer {
+	return func(string, []*x509.Certificate) error {
+		return nil
+	}
+}
+
+// AuthorizeID accepts peers whose SPIFFE ID is one of ids.
+func AuthorizeID(ids ...string) Authorizer {
+	return func(id string, _ []*x509.Certificate) error {
+		if !slices.Contains(ids, id) {
+			return fmt.Errorf("unexpected SPIFFE ID %s", id)
+		}
+		return nil
+	}
+}
+
+// AuthorizeMemberOf accepts peers in trustDomain, such as "example.org".
+func AuthorizeMemberOf(trustDomain string) Authorizer {
+	td := trustDomainName(trustDomain)
+	return func(id string, _ []*x509.Certificate) error {
+		if trustDomainName(id) != td {
+			return fmt.Errorf("SPIFFE ID %s is not a member of %s", id, td)
+		}
+		return nil
+	}
+}
+
+// ServerTLSConfig returns a TLS configuration presenting the current SVID and requiring clients
+// to present an SVID accepted by authorize (mTLS), for use with mcpdpluginsv1.WithTLS.
+func (s *Source) ServerTLSConfig(authorize Authorizer) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAnyClientCert,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return s.certificate(), nil
+		},
+		// Peers are verified against the SPIFFE bundle of their trust domain, not the system roots.
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: s.verifier(authorize),
+	}
+}
+
+// ClientTLSConfig returns a TLS configuration presenting the current SVID as the client
+// certificate and requiring servers to present an SVID accepted by authorize, for outbound calls
+// such as through httpclientx.WithTLSConfig.
+func (s *Source) ClientTLSConfig(authorize Authorizer) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return s.certificate(), nil
+		},
+		// SVIDs do not name hosts: servers are verified by SPIFFE ID instead of hostname.
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: s.verifier(authorize),
+	}
+}
+
+// certificate returns the current SVID as a TLS certificate.
+func (s *Source) certificate() *tls.Certificate {
+	svid := s.SVID()
+	cert := &tls.Certificate{PrivateKey: svid.PrivateKey, Leaf: svid.Certificates[0]}
+	for _, c := range svid.Certificates {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+
+	return cert
+}
+
+// verifier returns a VerifyPeerCertificate function checking the peer's SVID against the bundle
+// of its trust domain and then authorize.
+func (s *Source) verifier(authorize Authorizer) func([][]byte, [][]*x509.Certificate) error {
+	if authorize == nil {
+		authorize = AuthorizeAny()
+	}
+
+	return func(raw [][]byte, _ [][]*x509.Certificate) error {
+		id, chain, err := s.Verify(raw)
+		if err != nil {
+			return err
+		}
+		return authorize(id, chain)
+	}
+}
+
+// Verify verifies a peer's DER certificate chain, leaf first, as an X.509-SVID against the current
+// bundle of its trust domain and returns its SPIFFE ID and verified chain.
+func (s *Source) Verify(raw [][]byte) (string, []*x509.Certificate, error) {
+	if len(raw) == 0 {
+		return "", nil, fmt.Errorf("peer presented no certificate")
+	}
+	certs := make([]*x509.Certificate, 0, len(raw))
+	for _, der := range raw {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid peer certificate: %w", err)
+		}
+		certs = append(certs, c)
+	}
+
+	leaf := certs[0]
+	if len(leaf.URIs) != 1 || leaf.URIs[0].Scheme != "spiffe" {
+		return "", nil, fmt.Errorf("peer certificate is not an SVID: it must have exactly one spiffe:// URI SAN")
+	}
+	if leaf.IsCA {
+		return "", nil, fmt.Errorf("peer SVID is a CA certificate")
+	}
+	id := leaf.URIs[0].String()
+
+	bundle, ok := s.Bundle(trustDomainName(id))
+	if !ok {
+		return "", nil, fmt.Errorf("no trust bundle for the trust domain of %s", id)
+	}
+	roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+	for _, c := range bundle {
+		roots.AddCert(c)
+	}
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("peer SVID %s: %w", id, err)
+	}
+
+	return id, chains[0], nil
+}
@@ -0,0 +1,256 @@
+package spiffe
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// staticSource returns a Source holding the update parsed from msg, without a Workload API.
+func staticSource(t *testing.T, msg []byte) *Source {
+	t.Helper()
+
+	u, err := parseX509Response(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Source{logger: log.New(io.Discard, "", 0)}
+	if err := s.apply(u); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestAuthorizers(t *testing.T) {
+	tests := []struct {
+		name      string
+		authorize Authorizer
+		id        string
+		wantErr   string
+	}{
+		{name: "any", authorize: AuthorizeAny(), id: "spiffe://other.org/x"},
+		{
+			name:      "id",
+			authorize: AuthorizeID("spiffe://example.org/a", "spiffe://example.org/b"),
+			id:        "spiffe://example.org/b",
+		},
+		{
+			name:      "other id",
+			authorize: AuthorizeID("spiffe://example.org/a"),
+			id:        "spiffe://example.org/ab",
+			wantErr:   "unexpected SPIFFE ID spiffe://example.org/ab",
+		},
+		{name: "no ids", authorize: AuthorizeID(), id: "spiffe://example.org/a", wantErr: "unexpected SPIFFE ID"},
+		{name: "member", authorize: AuthorizeMemberOf("example.org"), id: "spiffe://example.org/a/b"},
+		{
+			name:      "member of trust domain ID",
+			authorize: AuthorizeMemberOf("spiffe://Example.org"),
+			id:        "spiffe://example.org/a",
+		},
+		{
+			name:      "not a member",
+			authorize: AuthorizeMemberOf("example.org"),
+			id:        "spiffe://example.org.evil.com/a",
+			wantErr:   "is not a member of example.org",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.authorize(tt.id, nil)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("authorize(%s) = %v, want nil", tt.id, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("authorize(%s) = %v, want %q", tt.id, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ca, other, unknown := newCA(t, "example.org"), newCA(t, "other.org"), newCA(t, "unknown.org")
+	src := staticSource(t, encodeResponse(
+		[][]byte{ca.svidMessage(t, "spiffe://example.org/self", "")},
+		map[string][]byte{"other.org": other.cert.Raw},
+	))
+
+	leaf, _ := ca.issue(t, false, "spiffe://example.org/peer")
+	federated, _ := other.issue(t, false, "spiffe://other.org/peer")
+	untrusted, _ := unknown.issue(t, false, "spiffe://unknown.org/peer")
+	forged, _ := unknown.issue(t, false, "spiffe://example.org/peer")
+	noURI, _ := ca.issue(t, false)
+	twoURIs, _ := ca.issue(t, false, "spiffe://example.org/a", "spiffe://example.org/b")
+	httpsURI, _ := ca.issue(t, false, "https://example.org/peer")
+	caSVID, _ := ca.issue(t, true, "spiffe://example.org/ca")
+
+	// An intermediate CA of example.org, presented in the chain after the leaf.
+	inter := &testCA{domain: "example.org"}
+	interCert, interKey := ca.issue(t, true, "spiffe://example.org")
+	inter.cert = interCert
+	key, err := x509.ParsePKCS8PrivateKey(interKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inter.key = key.(*ecdsa.PrivateKey)
+	chained, _ := inter.issue(t, false, "spiffe://example.org/chained")
+
+	tests := []struct {
+		name    string
+		raw     [][]byte
+		wantID  string
+		wantLen int
+		wantErr string
+	}{
+		{name: "own trust domain", raw: [][]byte{leaf.Raw}, wantID: "spiffe://example.org/peer", wantLen: 2},
+		{name: "federated", raw: [][]byte{federated.Raw}, wantID: "spiffe://other.org/peer", wantLen: 2},
+		{
+			name:    "intermediate",
+			raw:     [][]byte{chained.Raw, interCert.Raw},
+			wantID:  "spiffe://example.org/chained",
+			wantLen: 3,
+		},
+		{name: "no certificate", wantErr: "peer presented no certificate"},
+		{name: "invalid certificate", raw: [][]byte{[]byte("x")}, wantErr: "invalid peer certificate"},
+		{name: "no URI", raw: [][]byte{noURI.Raw}, wantErr: "not an SVID"},
+		{name: "two URIs", raw: [][]byte{twoURIs.Raw}, wantErr: "not an SVID"},
+		{name: "not spiffe", raw: [][]byte{httpsURI.Raw}, wantErr: "not an SVID"},
+		{name: "CA", raw: [][]byte{caSVID.Raw}, wantErr: "peer SVID is a CA certificate"},
+		{name: "unknown trust domain", raw: [][]byte{untrusted.Raw}, wantErr: "no trust bundle for the trust domain"},
+		{name: "signed by another CA", raw: [][]byte{forged.Raw}, wantErr: "peer SVID spiffe://example.org/peer"},
+		{name: "missing intermediate", raw: [][]byte{chained.Raw}, wantErr: "peer SVID spiffe://example.org/chained"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, chain, err := src.Verify(tt.raw)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Verify error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tt.wantID || len(chain) != tt.wantLen {
+				t.Errorf("Verify = %s with %d certificates, want %s with %d", id, len(chain), tt.wantID, tt.wantLen)
+			}
+		})
+	}
+}
+
+// handshake runs a TLS handshake between server and client over loopback TCP, returning each
+// side's error.
+func handshake(t *testing.T, server, client *tls.Config) (serverErr, clientErr error) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = lis.Close() }()
+
+	done := make(chan error, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+		s := tls.Server(conn, server)
+		err = s.Handshake()
+		// Closing sends the client a close_notify, or an alert after a failed handshake.
+		_ = s.Close()
+		done <- err
+	}()
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	c := tls.Client(conn, client)
+	defer func() { _ = c.Close() }()
+	clientErr = c.Handshake()
+	if clientErr == nil {
+		// TLS 1.3 servers verify the client certificate after the client's handshake returns.
+		if _, err := c.Read(make([]byte, 1)); err != io.EOF {
+			clientErr = err
+		}
+	}
+
+	return <-done, clientErr
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca, other := newCA(t, "example.org"), newCA(t, "other.org")
+	server := staticSource(t, encodeResponse([][]byte{ca.svidMessage(t, "spiffe://example.org/server", "")}, nil))
+	client := staticSource(t, encodeResponse([][]byte{ca.svidMessage(t, "spiffe://example.org/client", "")}, nil))
+	outsider := staticSource(t, encodeResponse(
+		[][]byte{other.svidMessage(t, "spiffe://other.org/client", "")},
+		map[string][]byte{"example.org": ca.cert.Raw},
+	))
+
+	tests := []struct {
+		name          string
+		server        Authorizer
+		client        *tls.Config
+		wantServerErr string
+		wantClientErr string
+	}{
+		{
+			name:   "authorized",
+			server: AuthorizeID("spiffe://example.org/client"),
+			client: client.ClientTLSConfig(AuthorizeID("spiffe://example.org/server")),
+		},
+		{name: "nil authorizer accepts any SVID", client: client.ClientTLSConfig(nil)},
+		{
+			name:          "client not authorized",
+			server:        AuthorizeID("spiffe://example.org/other"),
+			client:        client.ClientTLSConfig(nil),
+			wantServerErr: "unexpected SPIFFE ID spiffe://example.org/client",
+		},
+		{
+			name:          "server not authorized",
+			client:        client.ClientTLSConfig(AuthorizeMemberOf("other.org")),
+			wantClientErr: "is not a member of other.org",
+		},
+		{
+			name:          "client from an unknown trust domain",
+			client:        outsider.ClientTLSConfig(AuthorizeAny()),
+			wantServerErr: "no trust bundle for the trust domain of spiffe://other.org/client",
+		},
+		{
+			name:          "no client certificate",
+			client:        &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // The server rejects the client.
+			wantServerErr: "client didn't provide a certificate",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverErr, clientErr := handshake(t, server.ServerTLSConfig(tt.server), tt.client)
+			for _, c := range []struct {
+				side string
+				err  error
+				want string
+			}{{"server", serverErr, tt.wantServerErr}, {"client", clientErr, tt.wantClientErr}} {
+				if c.want == "" && tt.wantServerErr == "" && tt.wantClientErr == "" && c.err != nil {
+					t.Errorf("%s handshake: %v", c.side, c.err)
+				}
+				if c.want != "" && (c.err == nil || !strings.Contains(c.err.Error(), c.want)) {
+					t.Errorf("%s handshake error = %v, want %q", c.side, c.err, c.want)
+				}
+			}
+		})
+	}
+}
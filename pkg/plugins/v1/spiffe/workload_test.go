@@ -0,0 +1,389 @@
+package spiffe
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// testCA is a trust domain's certificate authority issuing SVIDs.
+type testCA struct {
+	domain string
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+}
+
+var serial int64
+
+func newCA(t *testing.T, domain string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial++
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: domain + " CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		URIs:                  []*url.URL{{Scheme: "spiffe", Host: domain}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCA{domain: domain, cert: cert, key: key}
+}
+
+// issue returns a leaf certificate for the SPIFFE IDs uris (none for a non-SVID) and its PKCS#8
+// key, signed by ca.
+func (ca *testCA) issue(t *testing.T, isCA bool, uris ...string) (*x509.Certificate, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial++
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	for _, u := range uris {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tmpl.URIs = append(tmpl.URIs, parsed)
+	}
+	if isCA {
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, pkcs8
+}
+
+// svidMessage encodes an X509SVID for id issued by ca.
+func (ca *testCA) svidMessage(t *testing.T, id, hint string) []byte {
+	t.Helper()
+
+	cert, key := ca.issue(t, false, id)
+	return encodeSVID(id, cert.Raw, key, ca.cert.Raw, hint)
+}
+
+func encodeSVID(id string, chain, key, bundle []byte, hint string) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, id)
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendBytes(b, chain)
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendBytes(b, key)
+	b = protowire.AppendTag(b, 4, protowire.BytesType)
+	b = protowire.AppendBytes(b, bundle)
+	if hint != "" {
+		b = protowire.AppendTag(b, 5, protowire.BytesType)
+		b = protowire.AppendString(b, hint)
+	}
+
+	return b
+}
+
+// encodeResponse encodes an X509SVIDResponse of svids and federated bundles.
+func encodeResponse(svids [][]byte, federated map[string][]byte) []byte {
+	var b []byte
+	for _, s := range svids {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, s)
+	}
+	for td, der := range federated {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, td)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, der)
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+
+	return b
+}
+
+// workloadAPI is a fake Workload API streaming the responses sent on updates.
+type workloadAPI struct {
+	addr    string
+	updates chan []byte
+
+	mu      sync.Mutex
+	streams int
+	headers []string
+}
+
+func newWorkloadAPI(t *testing.T) *workloadAPI {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "spiffe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "agent.sock")
+	lis, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &workloadAPI{addr: "unix://" + sock, updates: make(chan []byte, 10)}
+	srv := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(w.handle))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return w
+}
+
+func (w *workloadAPI) handle(_ any, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+	md, _ := metadata.FromIncomingContext(stream.Context())
+
+	w.mu.Lock()
+	w.streams++
+	w.headers = append(w.headers, strings.Join(md.Get(securityHeader), ","))
+	w.mu.Unlock()
+
+	if method != fetchX509SVIDMethod {
+		return status.Errorf(codes.Unimplemented, "unexpected method %s", method)
+	}
+	var req []byte
+	if err := stream.RecvMsg(&req); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case u := <-w.updates:
+			if err := stream.SendMsg(&u); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (w *workloadAPI) stats() (int, []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.streams, append([]string(nil), w.headers...)
+}
+
+func TestDialWorkloadAPI(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{addr: "unix:///run/spire/agent.sock"},
+		{addr: "unix:agent.sock"},
+		{addr: "tcp://127.0.0.1:8081"},
+		{addr: "/run/spire/agent.sock", wantErr: true},
+		{addr: "http://127.0.0.1:8081", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			conn, err := dialWorkloadAPI(tt.addr)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "must start with unix:// or tcp://") {
+					t.Errorf("dialWorkloadAPI error = %v, want an invalid address", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = conn.Close()
+		})
+	}
+}
+
+func TestParseX509Response(t *testing.T) {
+	ca := newCA(t, "example.org")
+	other := newCA(t, "other.org")
+	const id = "spiffe://example.org/a"
+	cert, key := ca.issue(t, false, id)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	edPKCS8, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A varint field before the SVIDs is skipped.
+	varint := protowire.AppendVarint(protowire.AppendTag(nil, 9, protowire.VarintType), 1)
+
+	tests := []struct {
+		name        string
+		msg         []byte
+		wantIDs     []string
+		wantBundles []string
+		wantErr     string
+	}{
+		{
+			name:        "svid",
+			msg:         encodeResponse([][]byte{ca.svidMessage(t, id, "")}, nil),
+			wantIDs:     []string{"spiffe://example.org/a"},
+			wantBundles: []string{"example.org"},
+		},
+		{
+			name: "several svids and federated bundles",
+			msg: encodeResponse(
+				[][]byte{ca.svidMessage(t, id, ""), ca.svidMessage(t, "spiffe://example.org/b", "b")},
+				map[string][]byte{"spiffe://Other.org": other.cert.Raw},
+			),
+			wantIDs:     []string{"spiffe://example.org/a", "spiffe://example.org/b"},
+			wantBundles: []string{"example.org", "other.org"},
+		},
+		{
+			name:        "ed25519 key",
+			msg:         encodeResponse([][]byte{encodeSVID(id, cert.Raw, edPKCS8, ca.cert.Raw, "")}, nil),
+			wantIDs:     []string{"spiffe://example.org/a"},
+			wantBundles: []string{"example.org"},
+		},
+		{
+			name:        "unknown fields",
+			msg:         append(varint, encodeResponse([][]byte{ca.svidMessage(t, id, "")}, nil)...),
+			wantIDs:     []string{"spiffe://example.org/a"},
+			wantBundles: []string{"example.org"},
+		},
+		{name: "no svid", msg: encodeResponse(nil, map[string][]byte{"other.org": other.cert.Raw}), wantErr: "no SVID"},
+		{name: "empty", wantErr: "no SVID"},
+		{name: "truncated", msg: []byte{0x0a, 0x10, 0x01}, wantErr: "unexpected EOF"},
+		{
+			name:    "no certificate",
+			msg:     encodeResponse([][]byte{encodeSVID(id, nil, key, ca.cert.Raw, "")}, nil),
+			wantErr: "SVID spiffe://example.org/a: invalid certificate chain",
+		},
+		{
+			name:    "invalid certificate",
+			msg:     encodeResponse([][]byte{encodeSVID(id, []byte("x"), key, ca.cert.Raw, "")}, nil),
+			wantErr: "invalid certificate chain",
+		},
+		{
+			name:    "invalid key",
+			msg:     encodeResponse([][]byte{encodeSVID(id, cert.Raw, []byte("x"), ca.cert.Raw, "")}, nil),
+			wantErr: "invalid private key",
+		},
+		{
+			name:    "invalid bundle",
+			msg:     encodeResponse([][]byte{encodeSVID(id, cert.Raw, key, []byte("x"), "")}, nil),
+			wantErr: "invalid bundle",
+		},
+		{
+			name: "invalid federated bundle",
+			msg: encodeResponse(
+				[][]byte{ca.svidMessage(t, id, "")},
+				map[string][]byte{"other.org": []byte("x")},
+			),
+			wantErr: "federated bundle of other.org",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := parseX509Response(tt.msg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseX509Response error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, s := range u.svids {
+				ids = append(ids, s.ID)
+				if s.PrivateKey == nil || len(s.Certificates) == 0 {
+					t.Errorf("SVID %s has no key or certificate", s.ID)
+				}
+			}
+			if strings.Join(ids, " ") != strings.Join(tt.wantIDs, " ") {
+				t.Errorf("SVIDs %q, want %q", ids, tt.wantIDs)
+			}
+			if len(u.bundles) != len(tt.wantBundles) {
+				t.Errorf("bundles %v, want %q", u.bundles, tt.wantBundles)
+			}
+			for _, td := range tt.wantBundles {
+				if len(u.bundles[td]) != 1 {
+					t.Errorf("bundle of %s = %v, want one CA", td, u.bundles[td])
+				}
+			}
+		})
+	}
+}
+
+func TestRawCodec(t *testing.T) {
+	c := rawCodec{}
+	msg := []byte("abc")
+	b, err := c.Marshal(&msg)
+	if err != nil || !bytes.Equal(b, msg) {
+		t.Errorf("Marshal = %q, %v", b, err)
+	}
+	if _, err := c.Marshal("abc"); err == nil {
+		t.Error("Marshal accepted a string")
+	}
+	var out []byte
+	if err := c.Unmarshal(msg, &out); err != nil || !bytes.Equal(out, msg) {
+		t.Errorf("Unmarshal = %q, %v", out, err)
+	}
+	msg[0] = 'x'
+	if out[0] != 'a' {
+		t.Error("Unmarshal kept a reference to the input")
+	}
+	if err := c.Unmarshal(msg, new(string)); err == nil || errors.Is(err, nil) {
+		t.Error("Unmarshal accepted a *string")
+	}
+}
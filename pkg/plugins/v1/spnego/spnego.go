@@ -0,0 +1,213 @@
+// Package spnego authenticates requests to upstream MCP servers behind Windows (Kerberos)
+// authentication: it obtains an SPNEGO token for the upstream's service principal name and injects
+// it as an "Authorization: Negotiate" header, configured per upstream.
+//
+// Tokens come from a Negotiator, which holds the Kerberos credentials. The SDK does not implement
+// the Kerberos protocol itself; adapt a Kerberos library or the platform's GSS-API, for example
+// github.com/jcmturner/gokrb5:
+//
+//	kt, err := keytab.Load("/etc/plugin.keytab")
+//	...
+//	krb := client.NewWithKeytab("svc-mcp", "CORP.EXAMPLE.COM", kt, krbConf)
+//	negotiator := spnego.NegotiatorFunc(func(_ context.Context, spn string) ([]byte, error) {
+//	    token, err := gokrb5spnego.SPNEGOClient(krb, spn).InitSecContext()
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return token.Marshal()
+//	})
+//	err = mcpdpluginsv1.Serve(spnego.NewPlugin(negotiator),
+//	    mcpdpluginsv1.WithUpstreams(mcpdpluginsv1.UpstreamFromPath()))
+//
+// with upstreams selected by their custom_config section:
+//
+//	upstreams.sharepoint.spn:  HTTP/sharepoint.corp.example.com
+//	upstreams.reports.spn:     HTTP/reports.corp.example.com
+//
+// A base "spn" key applies to every upstream without a section of its own.
+//
+// A fresh token is negotiated for every request, since servers reject replayed Kerberos
+// authenticators. NTLM is not supported: it authenticates a connection rather than a request, so it
+// cannot be carried by a header injected into requests that mcpd forwards over its own connections.
+package spnego
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// MetricNegotiations counts token negotiations, labelled by LabelSPN and LabelOutcome ("ok" or
+// "error").
+const MetricNegotiations = "spnego.negotiations"
+
+// Label keys of MetricNegotiations.
+const (
+	LabelSPN     = "spn"
+	LabelOutcome = "outcome"
+)
+
+// Negotiator initiates a security context with the service principal name spn, such as
+// "HTTP/server.corp.example.com", and returns the SPNEGO token (a GSS-API InitialContextToken) to
+// send to the server.
+type Negotiator interface {
+	Token(ctx context.Context, spn string) ([]byte, error)
+}
+
+// NegotiatorFunc adapts a function to the Negotiator interface.
+type NegotiatorFunc func(ctx context.Context, spn string) ([]byte, error)
+
+// Token calls f(ctx, spn).
+func (f NegotiatorFunc) Token(ctx context.Context, spn string) ([]byte, error) {
+	return f(ctx, spn)
+}
+
+// Config configures the credentials injected for one upstream, decodable from custom_config with
+// mcpdpluginsv1.DecodeConfig, typically per upstream with mcpdpluginsv1.UpstreamConfig.
+type Config struct {
+	// SPN is the upstream's service principal name. Requests to upstreams without one are left
+	// unchanged.
+	SPN string `config:"spn"`
+
+	// Header is the request header the token is injected into.
+	Header string `config:"header" default:"Authorization"`
+
+	// Overwrite replaces the header when the client already sent one; otherwise the client's
+	// credentials are forwarded as they are.
+	Overwrite bool `config:"overwrite" default:"true"`
+
+	// FailOpen forwards requests without credentials when negotiation fails, instead of
+	// short-circuiting them with 502.
+	FailOpen bool `config:"fail_open" default:"false"`
+}
+
+// Injector adds SPNEGO credentials to requests. It is safe for concurrent use.
+type Injector struct {
+	negotiator Negotiator
+	recorder   metrics.Recorder
+	logger     *log.Logger
+}
+
+// NewInjector returns an Injector negotiating tokens with negotiator and recording negotiations
+// through recorder (nil disables metrics).
+func NewInjector(negotiator Negotiator, recorder metrics.Recorder) (*Injector, error) {
+	if negotiator == nil {
+		return nil, fmt.Errorf("negotiator is required")
+	}
+	if recorder == nil {
+		recorder = metrics.Nop()
+	}
+
+	return &Injector{negotiator: negotiator, recorder: recorder, logger: log.Default()}, nil
+}
+
+// HandleRequest continues the chain with a Negotiate token for cfg.SPN injected into req, or with
+// req unchanged when cfg is nil or has no SPN.
+func (i *Injector) HandleRequest(
+	ctx context.Context,
+	cfg *Config,
+	req *mcpdpluginsv1.HTTPRequest,
+) *mcpdpluginsv1.HTTPResponse {
+	if cfg == nil || cfg.SPN == "" {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+	if !cfg.Overwrite && mcpdpluginsv1.GetHeader(req.GetHeaders(), cfg.Header) != "" {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+
+	token, err := i.negotiator.Token(ctx, cfg.SPN)
+	if err == nil && len(token) == 0 {
+		err = fmt.Errorf("negotiator returned an empty token")
+	}
+	if err != nil {
+		i.recorder.Count(MetricNegotiations, 1, metrics.L(LabelSPN, cfg.SPN), metrics.L(LabelOutcome, "error"))
+		i.logger.Printf("spnego: negotiating with %s: %v", cfg.SPN, err)
+		if cfg.FailOpen {
+			return &mcpdpluginsv1.HTTPResponse{Continue: true}
+		}
+		return mcpdpluginsv1.DenyError(req, http.StatusBadGateway, &mcp.Error{
+			Code:    mcp.CodeServerError,
+			Message: "upstream authentication unavailable",
+		})
+	}
+	i.recorder.Count(MetricNegotiations, 1, metrics.L(LabelSPN, cfg.SPN), metrics.L(LabelOutcome, "ok"))
+
+	modified := mcpdpluginsv1.CloneRequest(req)
+	mcpdpluginsv1.SetRequestHeader(modified, cfg.Header, "Negotiate "+base64.StdEncoding.EncodeToString(token))
+
+	return &mcpdpluginsv1.HTTPResponse{Continue: true, ModifiedRequest: modified}
+}
+
+// Plugin is a request-flow plugin injecting SPNEGO credentials for the upstreams configured in
+// the "upstreams.<name>." sections of its custom_config. Serve it with mcpdpluginsv1.WithUpstreams
+// so requests are matched to their upstream.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	injector *Injector
+	cfg      mcpdpluginsv1.UpstreamConfig[Config]
+}
+
+// NewPlugin returns a Plugin negotiating tokens with negotiator. It panics if negotiator is nil.
+func NewPlugin(negotiator Negotiator) *Plugin {
+	i, err := NewInjector(negotiator, nil)
+	if err != nil {
+		panic(fmt.Sprintf("spnego: %v", err))
+	}
+
+	return &Plugin{injector: i}
+}
+
+// WithMetrics records negotiations through r and returns p.
+func (p *Plugin) WithMetrics(r metrics.Recorder) *Plugin {
+	if r != nil {
+		p.injector.recorder = r
+	}
+
+	return p
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "spnego",
+		Version:     pluginVersion,
+		Description: "Injects Kerberos SPNEGO credentials into requests to configured upstreams.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest), nil
+}
+
+// Configure decodes the per-upstream configuration of cfg's custom_config, keeping the previous
+// configuration on error.
+func (p *Plugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	if err := p.cfg.Configure(ctx, cfg); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleRequest injects credentials for the request's upstream.
+func (p *Plugin) HandleRequest(
+	ctx context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.injector.HandleRequest(ctx, p.cfg.Get(ctx), req), nil
+}
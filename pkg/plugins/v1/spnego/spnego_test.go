@@ -0,0 +1,359 @@
+package spnego
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/metrics"
+)
+
+// fakeRecorder records every count as a line such as "spnego.negotiations 1 [spn=... outcome=ok]".
+type fakeRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *fakeRecorder) Count(name string, delta int64, labels ...metrics.Label) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Key + "=" + l.Value
+	}
+	r.lines = append(r.lines, fmt.Sprintf("%s %d [%s]", name, delta, strings.Join(parts, " ")))
+}
+
+func (r *fakeRecorder) Gauge(string, float64, ...metrics.Label) {}
+
+func (r *fakeRecorder) Observe(string, float64, ...metrics.Label) {}
+
+func (r *fakeRecorder) Timing(string, time.Duration, ...metrics.Label) {}
+
+func (r *fakeRecorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.lines)
+}
+
+// fakeNegotiator returns "token-for-<spn>", or err when set, and records the SPNs asked for.
+type fakeNegotiator struct {
+	mu    sync.Mutex
+	spns  []string
+	token []byte
+	err   error
+}
+
+func (n *fakeNegotiator) Token(_ context.Context, spn string) ([]byte, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.spns = append(n.spns, spn)
+	if n.err != nil || n.token != nil {
+		return n.token, n.err
+	}
+
+	return []byte("token-for-" + spn), nil
+}
+
+func (n *fakeNegotiator) asked() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return slices.Clone(n.spns)
+}
+
+// negotiate returns the Authorization value carrying the token the fake negotiator issues for spn.
+func negotiate(spn string) string {
+	return "Negotiate " + base64.StdEncoding.EncodeToString([]byte("token-for-"+spn))
+}
+
+// testConfig returns a Config for spn with the defaults applied.
+func testConfig(spn string) *Config {
+	return &Config{SPN: spn, Header: "Authorization", Overwrite: true}
+}
+
+func TestNewInjector(t *testing.T) {
+	if _, err := NewInjector(nil, nil); err == nil || !strings.Contains(err.Error(), "negotiator is required") {
+		t.Errorf("NewInjector(nil) error = %v, want the missing negotiator", err)
+	}
+	i, err := NewInjector(&fakeNegotiator{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.recorder == nil {
+		t.Error("NewInjector without a recorder left it nil")
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	var cfg Config
+	if err := mcpdpluginsv1.DecodeConfig(context.Background(), &mcpdpluginsv1.PluginConfig{}, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Config{Header: "Authorization", Overwrite: true}); cfg != want {
+		t.Errorf("decoded defaults = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestInjectorHandleRequest(t *testing.T) {
+	const spn = "HTTP/sharepoint.corp.example.com"
+	tests := []struct {
+		name        string
+		cfg         func() *Config
+		headers     map[string]string
+		negotiator  *fakeNegotiator
+		wantStatus  int32
+		wantHeaders map[string]string // Headers of the modified request; nil when unmodified.
+		wantAsked   bool
+		wantMetric  string
+		wantLog     string
+	}{
+		{name: "no config", cfg: func() *Config { return nil }},
+		{name: "no SPN", cfg: func() *Config { return testConfig("") }},
+		{
+			name:        "token injected",
+			cfg:         func() *Config { return testConfig(spn) },
+			headers:     map[string]string{"Accept": "application/json"},
+			wantHeaders: map[string]string{"Accept": "application/json", "Authorization": negotiate(spn)},
+			wantAsked:   true,
+			wantMetric:  "spnego.negotiations 1 [spn=" + spn + " outcome=ok]",
+		},
+		{
+			name:        "client credentials overwritten",
+			cfg:         func() *Config { return testConfig(spn) },
+			headers:     map[string]string{"authorization": "Basic dTpw"},
+			wantHeaders: map[string]string{"Authorization": negotiate(spn)},
+			wantAsked:   true,
+			wantMetric:  "spnego.negotiations 1 [spn=" + spn + " outcome=ok]",
+		},
+		{
+			name: "client credentials kept",
+			cfg: func() *Config {
+				cfg := testConfig(spn)
+				cfg.Overwrite = false
+				return cfg
+			},
+			headers: map[string]string{"authorization": "Basic dTpw"},
+		},
+		{
+			name: "no client credentials to keep",
+			cfg: func() *Config {
+				cfg := testConfig(spn)
+				cfg.Overwrite = false
+				return cfg
+			},
+			wantHeaders: map[string]string{"Authorization": negotiate(spn)},
+			wantAsked:   true,
+			wantMetric:  "spnego.negotiations 1 [spn=" + spn + " outcome=ok]",
+		},
+		{
+			name: "custom header",
+			cfg: func() *Config {
+				cfg := testConfig(spn)
+				cfg.Header = "X-Upstream-Authorization"
+				return cfg
+			},
+			headers: map[string]string{"Authorization": "Bearer client"},
+			wantHeaders: map[string]string{
+				"Authorization":            "Bearer client",
+				"X-Upstream-Authorization": negotiate(spn),
+			},
+			wantAsked:  true,
+			wantMetric: "spnego.negotiations 1 [spn=" + spn + " outcome=ok]",
+		},
+		{
+			name:       "negotiation failure",
+			cfg:        func() *Config { return testConfig(spn) },
+			negotiator: &fakeNegotiator{err: errors.New("KDC unreachable")},
+			wantStatus: http.StatusBadGateway,
+			wantAsked:  true,
+			wantMetric: "spnego.negotiations 1 [spn=" + spn + " outcome=error]",
+			wantLog:    "spnego: negotiating with " + spn + ": KDC unreachable",
+		},
+		{
+			name:       "empty token",
+			cfg:        func() *Config { return testConfig(spn) },
+			negotiator: &fakeNegotiator{token: []byte{}},
+			wantStatus: http.StatusBadGateway,
+			wantAsked:  true,
+			wantMetric: "spnego.negotiations 1 [spn=" + spn + " outcome=error]",
+			wantLog:    "negotiator returned an empty token",
+		},
+		{
+			name: "negotiation failure failing open",
+			cfg: func() *Config {
+				cfg := testConfig(spn)
+				cfg.FailOpen = true
+				return cfg
+			},
+			headers:    map[string]string{"Authorization": "Bearer client"},
+			negotiator: &fakeNegotiator{err: errors.New("KDC unreachable")},
+			wantAsked:  true,
+			wantMetric: "spnego.negotiations 1 [spn=" + spn + " outcome=error]",
+			wantLog:    "KDC unreachable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			negotiator := tt.negotiator
+			if negotiator == nil {
+				negotiator = &fakeNegotiator{}
+			}
+			rec := &fakeRecorder{}
+			i, err := NewInjector(negotiator, rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var logs bytes.Buffer
+			i.logger = log.New(&logs, "", 0)
+
+			req := &mcpdpluginsv1.HTTPRequest{
+				Method:  http.MethodPost,
+				Headers: tt.headers,
+				Body:    []byte(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`),
+			}
+			headers := maps.Clone(tt.headers)
+			resp := i.HandleRequest(context.Background(), tt.cfg(), req)
+
+			if tt.wantStatus != 0 {
+				if resp.GetContinue() || resp.GetStatusCode() != tt.wantStatus {
+					t.Errorf("response = %v, want a %d short-circuit", resp, tt.wantStatus)
+				}
+				if !strings.Contains(string(resp.GetBody()), "upstream authentication unavailable") ||
+					strings.Contains(string(resp.GetBody()), "KDC") {
+					t.Errorf("deny body = %s, want the generic error only", resp.GetBody())
+				}
+			} else if !resp.GetContinue() {
+				t.Errorf("response = %v, want the chain continued", resp)
+			}
+			if got := resp.GetModifiedRequest(); tt.wantHeaders == nil {
+				if got != nil {
+					t.Errorf("modified request = %v, want none", got)
+				}
+			} else if got == nil || !maps.Equal(got.GetHeaders(), tt.wantHeaders) {
+				t.Errorf("modified headers = %v, want %v", got.GetHeaders(), tt.wantHeaders)
+			}
+			if !maps.Equal(req.GetHeaders(), headers) {
+				t.Errorf("original headers changed to %v", req.GetHeaders())
+			}
+			if asked := negotiator.asked(); (len(asked) == 1) != tt.wantAsked {
+				t.Errorf("negotiated for %q, want a negotiation %t", asked, tt.wantAsked)
+			}
+			var wantMetrics []string
+			if tt.wantMetric != "" {
+				wantMetrics = []string{tt.wantMetric}
+			}
+			if got := rec.recorded(); !slices.Equal(got, wantMetrics) {
+				t.Errorf("metrics = %q, want %q", got, wantMetrics)
+			}
+			if tt.wantLog == "" && logs.Len() > 0 || !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("logs = %q, want %q", logs.String(), tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestInjectorFreshTokenPerRequest(t *testing.T) {
+	n := &fakeNegotiator{}
+	i, err := NewInjector(n, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		i.HandleRequest(context.Background(), testConfig("HTTP/a"), &mcpdpluginsv1.HTTPRequest{})
+	}
+	if got := n.asked(); len(got) != 3 {
+		t.Errorf("negotiated %d times for 3 requests, want one each", len(got))
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	rec := &fakeRecorder{}
+	p := NewPlugin(&fakeNegotiator{}).WithMetrics(rec)
+	ctx := context.Background()
+
+	md, err := p.GetMetadata(ctx, &emptypb.Empty{})
+	if err != nil || md.GetName() != "spnego" || md.GetVersion() != pluginVersion {
+		t.Errorf("GetMetadata = %v, %v", md, err)
+	}
+	caps, err := p.GetCapabilities(ctx, &emptypb.Empty{})
+	if err != nil || !slices.Equal(caps.GetFlows(), []mcpdpluginsv1.Flow{mcpdpluginsv1.FlowRequest}) {
+		t.Errorf("GetCapabilities = %v, %v, want the request flow", caps, err)
+	}
+
+	// Before Configure nothing is injected.
+	resp, err := p.HandleRequest(mcpdpluginsv1.ContextWithUpstream(ctx, "sharepoint"), &mcpdpluginsv1.HTTPRequest{})
+	if err != nil || !resp.GetContinue() || resp.GetModifiedRequest() != nil {
+		t.Errorf("HandleRequest before Configure = %v, %v, want the request unchanged", resp, err)
+	}
+
+	if _, err := p.Configure(ctx, &mcpdpluginsv1.PluginConfig{CustomConfig: map[string]string{
+		"spn":                         "HTTP/default.corp.example.com",
+		"upstreams.sharepoint.spn":    "HTTP/sharepoint.corp.example.com",
+		"upstreams.sharepoint.header": "X-Auth",
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		upstream   string
+		wantHeader string
+		wantValue  string
+	}{
+		{upstream: "sharepoint", wantHeader: "X-Auth", wantValue: negotiate("HTTP/sharepoint.corp.example.com")},
+		{upstream: "reports", wantHeader: "Authorization", wantValue: negotiate("HTTP/default.corp.example.com")},
+		{upstream: "", wantHeader: "Authorization", wantValue: negotiate("HTTP/default.corp.example.com")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.upstream, func(t *testing.T) {
+			upstreamCtx := mcpdpluginsv1.ContextWithUpstream(ctx, tt.upstream)
+			resp, err := p.HandleRequest(upstreamCtx, &mcpdpluginsv1.HTTPRequest{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := mcpdpluginsv1.GetHeader(resp.GetModifiedRequest().GetHeaders(), tt.wantHeader)
+			if got != tt.wantValue {
+				t.Errorf("%s = %q, want %q", tt.wantHeader, got, tt.wantValue)
+			}
+		})
+	}
+	if got := len(rec.recorded()); got != 3 {
+		t.Errorf("recorded %d negotiations, want 3 through WithMetrics", got)
+	}
+
+	// An invalid configuration is rejected and the previous one kept.
+	_, err = p.Configure(ctx, &mcpdpluginsv1.PluginConfig{CustomConfig: map[string]string{"overwrite": "maybe"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Configure error = %v, want InvalidArgument", err)
+	}
+	resp, err = p.HandleRequest(mcpdpluginsv1.ContextWithUpstream(ctx, "sharepoint"), &mcpdpluginsv1.HTTPRequest{})
+	if err != nil || resp.GetModifiedRequest() == nil {
+		t.Errorf("HandleRequest after a failed Configure = %v, %v, want the previous config", resp, err)
+	}
+}
+
+func TestNewPluginNilNegotiator(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewPlugin(nil) did not panic")
+		}
+	}()
+	NewPlugin(nil)
+}
@@ -0,0 +1,304 @@
+package state
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrLocked is returned by OpenFile when another FileStore, in this or another process, has the
+// file open.
+var ErrLocked = errors.New("state file is locked by another store")
+
+// compactRatio is how many log records per live key are tolerated before Flush compacts the log.
+const compactRatio = 4
+
+// record is one line of a FileStore log. A nil Value deletes Key.
+type record struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// FileStore is a Store kept in memory and persisted to an append-only log file, one JSON record
+// per write. The log is replayed on open and compacted when it grows well beyond the live data and
+// on Close. Writes are buffered until Flush unless the store syncs every write; a record
+// truncated by a crash is discarded on the next open.
+//
+// Only one store may have a file open at a time: OpenFile takes an exclusive lock on a path+".lock"
+// file beside the log (a flock, on unix platforms) and fails with ErrLocked while another store,
+// such as a second replica sharing the volume, holds it.
+type FileStore struct {
+	path string
+	sync bool
+	lock *os.File
+
+	mu      sync.RWMutex
+	data    table
+	f       *os.File
+	w       *bufio.Writer
+	records int
+	closed  bool
+}
+
+// FileOption configures a FileStore.
+type FileOption func(*FileStore)
+
+// WithSyncWrites flushes and fsyncs the log after every write, trading throughput for not losing
+// the writes since the last Flush on a crash.
+func WithSyncWrites() FileOption {
+	return func(s *FileStore) {
+		s.sync = true
+	}
+}
+
+// OpenFile opens the FileStore logged at path, creating the file and its directory if needed.
+func OpenFile(path string, opts ...FileOption) (*FileStore, error) {
+	s := &FileStore{path: path, data: table{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	// The lock is on a file of its own, as compaction replaces the log file.
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state lock file: %w", err)
+	}
+	if err := lockFile(lock); err != nil {
+		_ = lock.Close()
+		return nil, fmt.Errorf("failed to open state file %s: %w", path, err)
+	}
+	s.lock = lock
+	if err := s.replay(); err != nil {
+		_ = lock.Close()
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		if s.f != nil {
+			_ = s.f.Close()
+		}
+		_ = lock.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Get implements Store.
+func (s *FileStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, false, ErrClosed
+	}
+	v, ok := s.data.get(key)
+
+	return v, ok, nil
+}
+
+// Put implements Store.
+func (s *FileStore) Put(_ context.Context, key string, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+
+	return s.write(key, func([]byte, bool) ([]byte, error) { return value, nil })
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(_ context.Context, key string) error {
+	return s.write(key, func([]byte, bool) ([]byte, error) { return nil, nil })
+}
+
+// Update implements Store.
+func (s *FileStore) Update(_ context.Context, key string, fn UpdateFunc) error {
+	return s.write(key, fn)
+}
+
+// Range implements Store.
+func (s *FileStore) Range(_ context.Context, prefix string, fn func(key string, value []byte) bool) error {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return ErrClosed
+	}
+	keys, values := s.data.snapshot(prefix)
+	s.mu.RUnlock()
+
+	for i, k := range keys {
+		if !fn(k, values[i]) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Flush implements Store, writing buffered records to disk and compacting the log when it has
+// grown well beyond the live data.
+func (s *FileStore) Flush(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if s.records > compactRatio*max(len(s.data), 256) {
+		return s.compact()
+	}
+
+	return s.syncLog()
+}
+
+// Close implements Store, flushing buffered writes and compacting the log.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	err := s.syncLog()
+	if cerr := s.compact(); err == nil {
+		err = cerr
+	}
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	// Closing the lock file releases the lock.
+	if cerr := s.lock.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+func (s *FileStore) write(key string, fn UpdateFunc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	old, found := s.data.get(key)
+	value, err := fn(old, found)
+	if err != nil {
+		return err
+	}
+	if value == nil && !found {
+		return nil
+	}
+
+	line, err := json.Marshal(record{Key: key, Value: value})
+	if err != nil {
+		return err
+	}
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write state log: %w", err)
+	}
+	s.records++
+	s.data.set(key, value)
+
+	if s.sync {
+		return s.syncLog()
+	}
+
+	return nil
+}
+
+// replay loads the log into memory.
+func (s *FileStore) replay() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open state file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A final line without a newline is a record cut short by a crash.
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read state file: %w", err)
+		}
+
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("corrupt state file %s: %w", s.path, err)
+		}
+		s.data.set(rec.Key, rec.Value)
+	}
+}
+
+// compact rewrites the log with one record per live key and reopens it for appending.
+func (s *FileStore) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to compact state file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	keys, values := s.data.snapshot("")
+	for i, k := range keys {
+		if err := enc.Encode(record{Key: k, Value: values[i]}); err != nil {
+			_ = tmp.Close()
+			return fmt.Errorf("failed to compact state file: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to compact state file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to compact state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to compact state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to compact state file: %w", err)
+	}
+
+	if s.f != nil {
+		_ = s.f.Close()
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open state file: %w", err)
+	}
+	s.f, s.w, s.records = f, bufio.NewWriter(f), len(keys)
+
+	return nil
+}
+
+func (s *FileStore) syncLog() error {
+	if err := s.w.Flush(); err != nil {
+		return fmt.Errorf("failed to flush state log: %w", err)
+	}
+	if err := s.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync state log: %w", err)
+	}
+
+	return nil
+}
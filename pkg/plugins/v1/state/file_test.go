@@ -0,0 +1,230 @@
+package state_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/state"
+)
+
+func openFile(t *testing.T, path string, opts ...state.FileOption) *state.FileStore {
+	t.Helper()
+
+	s, err := state.OpenFile(path, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	return s
+}
+
+// contents returns every key and value of s as "k=v" lines.
+func contents(t *testing.T, s state.Store) string {
+	t.Helper()
+
+	var b strings.Builder
+	err := s.Range(context.Background(), "", func(k string, v []byte) bool {
+		fmt.Fprintf(&b, "%s=%s\n", k, v)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b.String()
+}
+
+func lines(t *testing.T, path string) int {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.Count(b, []byte("\n"))
+}
+
+func TestFileStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "dir", "state.log")
+	ctx := context.Background()
+
+	s := openFile(t, path)
+	_ = s.Put(ctx, "a", []byte("1"))
+	_ = s.Put(ctx, "b", []byte("2"))
+	_ = s.Put(ctx, "empty", nil)
+	_ = s.Put(ctx, "bin", []byte{0, '\n', 0xff})
+	_ = s.Delete(ctx, "b")
+	if _, err := state.Incr(ctx, s, "n", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openFile(t, path)
+	if got, want := contents(t, s), "a=1\nbin=\x00\n\xff\nempty=\nn=5\n"; got != want {
+		t.Errorf("reopened store holds\n%q\nwant\n%q", got, want)
+	}
+	if v, ok, _ := s.Get(ctx, "empty"); !ok || len(v) != 0 {
+		t.Errorf("empty value reopened as %q, %t", v, ok)
+	}
+	// Close compacted the log to one record per live key.
+	if n := lines(t, path); n != 4 {
+		t.Errorf("log has %d records after Close, want 4", n)
+	}
+}
+
+func TestFileStoreFlush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.log")
+	ctx := context.Background()
+	s := openFile(t, path)
+
+	_ = s.Put(ctx, "a", []byte("1"))
+	if n := lines(t, path); n != 0 {
+		t.Errorf("%d records on disk before Flush, want the write buffered", n)
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := lines(t, path); n != 1 {
+		t.Errorf("%d records on disk after Flush, want 1", n)
+	}
+
+	// Rewriting one key grows the log until it is well beyond the live data, then Flush compacts.
+	for i := range 1000 {
+		_ = s.Put(ctx, "a", []byte(fmt.Sprint(i)))
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := lines(t, path); n != 1001 {
+		t.Errorf("%d records after 1001 writes, want no compaction yet", n)
+	}
+	for range 24 {
+		_ = s.Put(ctx, "a", []byte("last"))
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := lines(t, path); n != 1 {
+		t.Errorf("%d records after compaction, want 1", n)
+	}
+	if v, _, _ := s.Get(ctx, "a"); string(v) != "last" {
+		t.Errorf("value after compaction %q", v)
+	}
+
+	// Writes after compaction go to the new log.
+	_ = s.Put(ctx, "b", []byte("2"))
+	_ = s.Flush(ctx)
+	if n := lines(t, path); n != 2 {
+		t.Errorf("%d records after a write following compaction, want 2", n)
+	}
+	matches, _ := filepath.Glob(path + ".*.tmp")
+	if len(matches) != 0 {
+		t.Errorf("compaction left temporary files %v", matches)
+	}
+}
+
+func TestFileStoreSyncWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.log")
+	s := openFile(t, path, state.WithSyncWrites())
+
+	_ = s.Put(context.Background(), "a", []byte("1"))
+	_ = s.Delete(context.Background(), "a")
+	_ = s.Delete(context.Background(), "a") // Deleting a missing key writes nothing.
+	if n := lines(t, path); n != 2 {
+		t.Errorf("%d records on disk without Flush, want every write synced", n)
+	}
+}
+
+func TestFileStoreTornWrite(t *testing.T) {
+	tests := []struct {
+		name string
+		tail string // Appended to a log holding a=1 and b=2.
+		want string
+	}{
+		{name: "partial record", tail: `{"key":"c","val`, want: "a=1\nb=2\n"},
+		{name: "complete record without newline", tail: `{"key":"c","value":"Mw=="}`, want: "a=1\nb=2\n"},
+		{name: "partial delete", tail: `{"key":"a","value":nu`, want: "a=1\nb=2\n"},
+		{name: "nothing", want: "a=1\nb=2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state.log")
+			log := `{"key":"a","value":"MQ=="}` + "\n" + `{"key":"b","value":"Mg=="}` + "\n" + tt.tail
+			if err := os.WriteFile(path, []byte(log), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			s := openFile(t, path)
+			if got := contents(t, s); got != tt.want {
+				t.Errorf("recovered %q, want %q", got, tt.want)
+			}
+
+			// The torn record is dropped from the file, so new writes are not appended to it.
+			_ = s.Put(context.Background(), "c", []byte("3"))
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			s = openFile(t, path)
+			if got := contents(t, s); got != "a=1\nb=2\nc=3\n" {
+				t.Errorf("after a write and reopen: %q", got)
+			}
+		})
+	}
+}
+
+func TestFileStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.log")
+	log := `{"key":"a","value":"MQ=="}` + "\n" + "garbage\n" + `{"key":"b","value":"Mg=="}` + "\n"
+	if err := os.WriteFile(path, []byte(log), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := state.OpenFile(path)
+	if err == nil || !strings.Contains(err.Error(), "corrupt state file "+path) {
+		t.Fatalf("OpenFile error = %v, want a corrupt file", err)
+	}
+	// The failed open released the lock and left the file for inspection.
+	if b, _ := os.ReadFile(path); string(b) != log {
+		t.Error("failed open modified the log")
+	}
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	openFile(t, path)
+}
+
+func TestFileStoreOpenErrors(t *testing.T) {
+	dir := t.TempDir()
+	file, logDir := filepath.Join(dir, "file"), filepath.Join(dir, "log")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(logDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "directory is a file", path: filepath.Join(file, "state.log"), want: "failed to create state directory"},
+		{name: "log is a directory", path: logDir, want: "failed to read state file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := state.OpenFile(tt.path); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("OpenFile error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
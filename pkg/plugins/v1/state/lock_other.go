@@ -0,0 +1,11 @@
+//go:build !unix
+
+package state
+
+import "os"
+
+// lockFile is a no-op: file locks are not supported on this platform, so nothing stops a second
+// process from opening the same FileStore.
+func lockFile(*os.File) error {
+	return nil
+}
@@ -0,0 +1,92 @@
+//go:build unix
+
+package state_test
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/state"
+)
+
+// lockHelperEnv makes TestLockHelperProcess hold the store at its value open until stdin closes.
+const lockHelperEnv = "STATE_TEST_LOCK_PATH"
+
+// TestLockHelperProcess is not a test: it is run in a child process by TestFileStoreLockAcrossProcesses.
+func TestLockHelperProcess(t *testing.T) {
+	path := os.Getenv(lockHelperEnv)
+	if path == "" {
+		t.Skip("helper process")
+	}
+
+	s, err := state.OpenFile(path)
+	if err != nil {
+		_, _ = os.Stdout.WriteString("error: " + err.Error() + "\n")
+		os.Exit(1)
+	}
+	_, _ = os.Stdout.WriteString("locked\n")
+	_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+	_ = s.Close()
+	os.Exit(0)
+}
+
+func TestFileStoreLockAcrossProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.log")
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestLockHelperProcess$")
+	cmd.Env = append(os.Environ(), lockHelperEnv+"="+path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cmd.Process.Kill(); _ = cmd.Wait() }()
+
+	if line, _ := bufio.NewReader(stdout).ReadString('\n'); line != "locked\n" {
+		t.Fatalf("helper process said %q, want it to hold the store", line)
+	}
+	if _, err := state.OpenFile(path); !errors.Is(err, state.ErrLocked) {
+		t.Fatalf("OpenFile while another process holds the store error = %v, want ErrLocked", err)
+	}
+
+	// Closing the other process's store releases the lock.
+	_ = stdin.Close()
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("helper process: %v", err)
+	}
+	s, err := state.OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile after the other process exited: %v", err)
+	}
+	_ = s.Close()
+}
+
+func TestFileStoreLockInProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.log")
+	s, err := state.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := state.OpenFile(path); !errors.Is(err, state.ErrLocked) {
+		t.Errorf("second OpenFile error = %v, want ErrLocked", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s, err = state.OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile after Close: %v", err)
+	}
+	_ = s.Close()
+}
@@ -0,0 +1,126 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+)
+
+// Backend names accepted by Config.Backend.
+const (
+	BackendMemory = "memory"
+	BackendFile   = "file"
+)
+
+// Config selects a Store, decodable from custom_config with mcpdpluginsv1.DecodeConfig. Its keys
+// are prefixed with "state_" so it can be embedded in a plugin's own configuration struct.
+type Config struct {
+	// Backend is where state is kept: memory or file.
+	Backend string `config:"state_backend" default:"memory"`
+
+	// Path is the log file of the file backend.
+	Path string `config:"state_path"`
+
+	// SyncWrites fsyncs the file backend after every write instead of on Flush and Stop.
+	SyncWrites bool `config:"state_sync_writes" default:"false"`
+}
+
+// Open returns the Store selected by cfg.
+func Open(cfg Config) (Store, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "", BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendFile:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("state_path is required for the file backend")
+		}
+		var opts []FileOption
+		if cfg.SyncWrites {
+			opts = append(opts, WithSyncWrites())
+		}
+		return OpenFile(cfg.Path, opts...)
+	default:
+		return nil, fmt.Errorf("unknown state backend %q", cfg.Backend)
+	}
+}
+
+// Managed holds a plugin's Store across its lifecycle. Call Configure from the plugin's Configure
+// method and Stop from its Stop method. It is safe for concurrent use.
+type Managed struct {
+	mu    sync.RWMutex
+	cfg   Config
+	store Store
+}
+
+// Configure decodes Config from cfg's custom_config and opens the selected store. When the
+// configuration is unchanged the open store is kept, so reconfiguring does not lose in-memory
+// state; otherwise the previous store is closed once the new one is open, or before it when both
+// use the same file, which only one store may have open.
+func (m *Managed) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) error {
+	var c Config
+	if err := mcpdpluginsv1.DecodeConfig(ctx, cfg, &c); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.store != nil && c == m.cfg {
+		return nil
+	}
+	if m.store != nil && sameFile(c, m.cfg) {
+		err := m.store.Close()
+		m.store = nil
+		if err != nil {
+			return fmt.Errorf("failed to close previous state store: %w", err)
+		}
+	}
+	store, err := Open(c)
+	if err != nil {
+		return err
+	}
+	if m.store != nil {
+		if err := m.store.Close(); err != nil {
+			_ = store.Close()
+			return fmt.Errorf("failed to close previous state store: %w", err)
+		}
+	}
+	m.cfg, m.store = c, store
+
+	return nil
+}
+
+// sameFile reports whether a and b select the file backend at the same path.
+func sameFile(a, b Config) bool {
+	return strings.EqualFold(a.Backend, BackendFile) && strings.EqualFold(b.Backend, BackendFile) &&
+		filepath.Clean(a.Path) == filepath.Clean(b.Path)
+}
+
+// Store returns the open store, or nil before the first successful Configure and after Stop.
+func (m *Managed) Store() Store {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.store
+}
+
+// Stop flushes and closes the open store. It is a no-op when no store is open.
+func (m *Managed) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.store == nil {
+		return nil
+	}
+	err := m.store.Flush(ctx)
+	if cerr := m.store.Close(); err == nil {
+		err = cerr
+	}
+	m.store = nil
+
+	return err
+}
@@ -0,0 +1,142 @@
+package state_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/state"
+)
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		cfg     state.Config
+		file    bool
+		wantErr string
+	}{
+		{name: "default", cfg: state.Config{}},
+		{name: "memory", cfg: state.Config{Backend: "Memory"}},
+		{name: "file", cfg: state.Config{Backend: "file", Path: filepath.Join(dir, "a.log")}, file: true},
+		{
+			name: "file with sync writes",
+			cfg:  state.Config{Backend: "FILE", Path: filepath.Join(dir, "b.log"), SyncWrites: true},
+			file: true,
+		},
+		{name: "file without a path", cfg: state.Config{Backend: "file"}, wantErr: "state_path is required"},
+		{name: "unknown", cfg: state.Config{Backend: "bolt"}, wantErr: `unknown state backend "bolt"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := state.Open(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Open error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = s.Close() }()
+			if _, isFile := s.(*state.FileStore); isFile != tt.file {
+				t.Errorf("Open returned a %T", s)
+			}
+		})
+	}
+}
+
+func pluginConfig(kv ...string) *mcpdpluginsv1.PluginConfig {
+	cfg := map[string]string{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		cfg[kv[i]] = kv[i+1]
+	}
+
+	return &mcpdpluginsv1.PluginConfig{CustomConfig: cfg}
+}
+
+func TestManaged(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.log")
+	var m state.Managed
+
+	if m.Store() != nil {
+		t.Fatal("Store before Configure is not nil")
+	}
+	if err := m.Stop(ctx); err != nil {
+		t.Errorf("Stop before Configure = %v", err)
+	}
+
+	if err := m.Configure(ctx, pluginConfig()); err != nil {
+		t.Fatal(err)
+	}
+	mem := m.Store()
+	_ = mem.Put(ctx, "k", []byte("v"))
+
+	// An unchanged configuration keeps the store and its contents.
+	if err := m.Configure(ctx, pluginConfig("state_backend", "memory")); err != nil {
+		t.Fatal(err)
+	}
+	if m.Store() != mem {
+		t.Fatal("unchanged configuration replaced the store")
+	}
+
+	// An invalid configuration keeps the open store.
+	for _, cfg := range []*mcpdpluginsv1.PluginConfig{
+		pluginConfig("state_backend", "file"),
+		pluginConfig("state_sync_writes", "sometimes"),
+	} {
+		if err := m.Configure(ctx, cfg); err == nil {
+			t.Errorf("Configure(%v) succeeded", cfg.GetCustomConfig())
+		}
+	}
+	if m.Store() != mem {
+		t.Fatal("failed Configure replaced the store")
+	}
+
+	// A new backend replaces the store and closes the previous one.
+	if err := m.Configure(ctx, pluginConfig("state_backend", "file", "state_path", path)); err != nil {
+		t.Fatal(err)
+	}
+	file := m.Store()
+	if _, _, err := mem.Get(ctx, "k"); !errors.Is(err, state.ErrClosed) {
+		t.Errorf("previous store Get error = %v, want ErrClosed", err)
+	}
+	_ = file.Put(ctx, "k", []byte("durable"))
+
+	// Changing options of the same file closes it before reopening, as only one store may hold it.
+	samePath := dir + "/./state.log"
+	err := m.Configure(ctx, pluginConfig("state_backend", "file", "state_path", samePath, "state_sync_writes", "true"))
+	if err != nil {
+		t.Fatalf("reconfiguring the same file: %v", err)
+	}
+	if v, _, _ := m.Store().Get(ctx, "k"); string(v) != "durable" {
+		t.Errorf("reopened store holds %q, want the previous writes", v)
+	}
+
+	store := m.Store()
+	if err := m.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if m.Store() != nil {
+		t.Error("Store after Stop is not nil")
+	}
+	if _, _, err := store.Get(ctx, "k"); !errors.Is(err, state.ErrClosed) {
+		t.Errorf("Get after Stop error = %v, want ErrClosed", err)
+	}
+
+	// Stop persisted the data.
+	s, err := state.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+	if v, _, _ := s.Get(ctx, "k"); string(v) != "durable" {
+		t.Errorf("persisted value %q", v)
+	}
+}
@@ -0,0 +1,105 @@
+package state
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore is a Store kept in process memory. Its contents are lost when the plugin exits.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	data   table
+	closed bool
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: table{}}
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, false, ErrClosed
+	}
+	v, ok := s.data.get(key)
+
+	return v, ok, nil
+}
+
+// Put implements Store.
+func (s *MemoryStore) Put(_ context.Context, key string, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+
+	return s.write(func() error {
+		s.data.set(key, value)
+		return nil
+	})
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	return s.write(func() error {
+		s.data.set(key, nil)
+		return nil
+	})
+}
+
+// Update implements Store.
+func (s *MemoryStore) Update(_ context.Context, key string, fn UpdateFunc) error {
+	return s.write(func() error {
+		_, err := s.data.update(key, fn)
+		return err
+	})
+}
+
+// Range implements Store.
+func (s *MemoryStore) Range(_ context.Context, prefix string, fn func(key string, value []byte) bool) error {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return ErrClosed
+	}
+	keys, values := s.data.snapshot(prefix)
+	s.mu.RUnlock()
+
+	for i, k := range keys {
+		if !fn(k, values[i]) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Flush implements Store; there is nothing to persist.
+func (s *MemoryStore) Flush(context.Context) error {
+	return nil
+}
+
+// Close implements Store.
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.data = table{}
+
+	return nil
+}
+
+func (s *MemoryStore) write(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	return fn()
+}
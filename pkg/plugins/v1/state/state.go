@@ -0,0 +1,151 @@
+// Package state gives plugins a small durable key-value store for counters, session data and other
+// state that should survive restarts.
+//
+// Store is implemented in memory (NewMemoryStore) and in an append-only file (OpenFile). The file
+// backend is a JSON Lines log replayed into memory on open, rather than an embedded database such
+// as bbolt, which keeps a storage engine out of the SDK's dependencies; it suits the small state
+// plugins keep, and is locked so that only one process uses a file at a time.
+//
+// Managed ties a store to the plugin lifecycle, opening it from custom_config on Configure and
+// flushing and closing it on Stop:
+//
+//	type MyPlugin struct {
+//	    mcpdpluginsv1.BasePlugin
+//	    state state.Managed
+//	}
+//
+//	// custom_config:
+//	//   state_backend: file
+//	//   state_path:    /var/lib/my-plugin/state.log
+//	func (p *MyPlugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+//	    if err := p.state.Configure(ctx, cfg); err != nil {
+//	        return nil, status.Error(codes.InvalidArgument, err.Error())
+//	    }
+//	    return &emptypb.Empty{}, nil
+//	}
+//
+//	func (p *MyPlugin) Stop(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+//	    return &emptypb.Empty{}, p.state.Stop(ctx)
+//	}
+//
+// Handlers then use p.state.Store(), for example with Incr for durable counters.
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrClosed is returned by stores that have been closed.
+var ErrClosed = errors.New("state store is closed")
+
+// UpdateFunc computes the new value of a key from its current value. Returning a nil value deletes
+// the key; returning an error leaves it unchanged.
+type UpdateFunc func(value []byte, found bool) ([]byte, error)
+
+// Store is a key-value store. Values passed to and returned from a Store are copied, so callers
+// may modify them. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the value stored under key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Put stores value under key.
+	Put(ctx context.Context, key string, value []byte) error
+
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// Update atomically replaces the value of key with the result of fn.
+	Update(ctx context.Context, key string, fn UpdateFunc) error
+
+	// Range calls fn for every key with the given prefix in ascending order, until fn returns false.
+	Range(ctx context.Context, prefix string, fn func(key string, value []byte) bool) error
+
+	// Flush persists buffered writes.
+	Flush(ctx context.Context) error
+
+	// Close flushes and releases the store.
+	Close() error
+}
+
+// Incr atomically adds delta to the integer stored under key (zero when absent) and returns the
+// result. Values are stored as decimal strings.
+func Incr(ctx context.Context, s Store, key string, delta int64) (int64, error) {
+	var n int64
+	err := s.Update(ctx, key, func(value []byte, found bool) ([]byte, error) {
+		if found {
+			v, err := strconv.ParseInt(string(value), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("value of %q is not an integer: %w", key, err)
+			}
+			n = v
+		}
+		n += delta
+		return []byte(strconv.FormatInt(n, 10)), nil
+	})
+
+	return n, err
+}
+
+// table is the map shared by the store implementations; callers provide locking.
+type table map[string][]byte
+
+func (t table) get(key string) ([]byte, bool) {
+	v, ok := t[key]
+	if !ok {
+		return nil, false
+	}
+
+	return clone(v), true
+}
+
+// set stores value under key, deleting it when value is nil.
+func (t table) set(key string, value []byte) {
+	if value == nil {
+		delete(t, key)
+		return
+	}
+	t[key] = clone(value)
+}
+
+// update applies fn to key and returns the new value (nil when deleted).
+func (t table) update(key string, fn UpdateFunc) ([]byte, error) {
+	old, found := t.get(key)
+	value, err := fn(old, found)
+	if err != nil {
+		return nil, err
+	}
+	t.set(key, value)
+
+	return value, nil
+}
+
+// snapshot returns copies of the entries with the given prefix, sorted by key.
+func (t table) snapshot(prefix string) ([]string, [][]byte) {
+	keys := make([]string, 0, len(t))
+	for k := range t {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	values := make([][]byte, len(keys))
+	for i, k := range keys {
+		values[i] = clone(t[k])
+	}
+
+	return keys, values
+}
+
+func clone(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	return append(make([]byte, 0, len(b)), b...)
+}
@@ -0,0 +1,210 @@
+package state_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/state"
+)
+
+// stores returns a fresh store of each implementation, closed when the test ends.
+func stores(t *testing.T) map[string]state.Store {
+	t.Helper()
+
+	f, err := state.OpenFile(filepath.Join(t.TempDir(), "state.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := map[string]state.Store{"memory": state.NewMemoryStore(), "file": f}
+	for _, s := range all {
+		t.Cleanup(func() { _ = s.Close() })
+	}
+
+	return all
+}
+
+func TestStore(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			if _, ok, err := s.Get(ctx, "missing"); ok || err != nil {
+				t.Errorf("Get of a missing key = %t, %v", ok, err)
+			}
+			if err := s.Put(ctx, "a", []byte("1")); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Put(ctx, "empty", nil); err != nil {
+				t.Fatal(err)
+			}
+			if v, ok, err := s.Get(ctx, "empty"); !ok || err != nil || len(v) != 0 {
+				t.Errorf("Get of an empty value = %q, %t, %v; want it found", v, ok, err)
+			}
+			if err := s.Delete(ctx, "empty"); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok, _ := s.Get(ctx, "empty"); ok {
+				t.Error("Get found a deleted key")
+			}
+			if err := s.Delete(ctx, "never"); err != nil {
+				t.Errorf("Delete of a missing key = %v", err)
+			}
+
+			// Values are copied on the way in and out.
+			in := []byte("mutable")
+			_ = s.Put(ctx, "m", in)
+			in[0] = 'X'
+			out, _, _ := s.Get(ctx, "m")
+			out[1] = 'X'
+			if v, _, _ := s.Get(ctx, "m"); string(v) != "mutable" {
+				t.Errorf("stored value changed to %q by its callers", v)
+			}
+
+			// Update sees the current value; nil deletes and an error leaves the key unchanged.
+			errUpdate := errors.New("refused")
+			appendTwo := func(v []byte, _ bool) ([]byte, error) { return append(v, '2'), nil }
+			refuse := func([]byte, bool) ([]byte, error) { return []byte("x"), errUpdate }
+			steps := []struct {
+				fn      state.UpdateFunc
+				wantErr error
+				want    string
+				found   bool
+			}{
+				{fn: appendTwo, want: "12", found: true},
+				{fn: refuse, wantErr: errUpdate, want: "12", found: true},
+				{fn: func([]byte, bool) ([]byte, error) { return nil, nil }},
+				{fn: func(_ []byte, found bool) ([]byte, error) {
+					if found {
+						return nil, errors.New("found a deleted key")
+					}
+					return nil, nil
+				}},
+			}
+			for i, st := range steps {
+				err := s.Update(ctx, "a", st.fn)
+				if !errors.Is(err, st.wantErr) || (err == nil) != (st.wantErr == nil) {
+					t.Errorf("update %d error = %v, want %v", i, err, st.wantErr)
+				}
+				v, found, _ := s.Get(ctx, "a")
+				if found != st.found || string(v) != st.want {
+					t.Errorf("after update %d: %q, %t; want %q, %t", i, v, found, st.want, st.found)
+				}
+			}
+		})
+	}
+}
+
+func TestRange(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for _, k := range []string{"session:b", "counter:x", "session:a", "session:c", "sessions"} {
+				_ = s.Put(ctx, k, []byte(k))
+			}
+
+			tests := []struct {
+				prefix string
+				limit  int
+				want   []string
+			}{
+				{prefix: "session:", want: []string{"session:a", "session:b", "session:c"}},
+				{prefix: "session:", limit: 2, want: []string{"session:a", "session:b"}},
+				{prefix: "", want: []string{"counter:x", "session:a", "session:b", "session:c", "sessions"}},
+				{prefix: "none:"},
+			}
+			for _, tt := range tests {
+				var got []string
+				err := s.Range(ctx, tt.prefix, func(k string, v []byte) bool {
+					if string(v) != k {
+						t.Errorf("Range value of %s = %q", k, v)
+					}
+					got = append(got, k)
+					return tt.limit == 0 || len(got) < tt.limit
+				})
+				if err != nil || !slices.Equal(got, tt.want) {
+					t.Errorf("Range(%q) visited %q, %v; want %q", tt.prefix, got, err, tt.want)
+				}
+			}
+
+			// fn may write to the store.
+			err := s.Range(ctx, "session:", func(k string, _ []byte) bool {
+				return s.Delete(ctx, k) == nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok, _ := s.Get(ctx, "session:a"); ok {
+				t.Error("Range callback could not delete")
+			}
+		})
+	}
+}
+
+func TestClosed(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if err := s.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Close(); err != nil {
+				t.Errorf("second Close = %v", err)
+			}
+
+			noop := func([]byte, bool) ([]byte, error) { return nil, nil }
+			calls := map[string]func() error{
+				"Get":    func() error { _, _, err := s.Get(ctx, "k"); return err },
+				"Put":    func() error { return s.Put(ctx, "k", nil) },
+				"Delete": func() error { return s.Delete(ctx, "k") },
+				"Update": func() error { return s.Update(ctx, "k", noop) },
+				"Range":  func() error { return s.Range(ctx, "", func(string, []byte) bool { return true }) },
+			}
+			for method, call := range calls {
+				if err := call(); !errors.Is(err, state.ErrClosed) {
+					t.Errorf("%s after Close error = %v, want ErrClosed", method, err)
+				}
+			}
+		})
+	}
+}
+
+func TestIncr(t *testing.T) {
+	ctx := context.Background()
+	s := state.NewMemoryStore()
+	_ = s.Put(ctx, "text", []byte("ten"))
+
+	tests := []struct {
+		key     string
+		delta   int64
+		want    int64
+		wantErr string
+	}{
+		{key: "n", delta: 1, want: 1},
+		{key: "n", delta: 41, want: 42},
+		{key: "n", delta: -50, want: -8},
+		{key: "zero", delta: 0, want: 0},
+		{key: "text", delta: 1, wantErr: `value of "text" is not an integer`},
+	}
+	for _, tt := range tests {
+		got, err := state.Incr(ctx, s, tt.key, tt.delta)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Incr(%s) error = %v, want %q", tt.key, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Incr(%s, %d) = %d, %v; want %d", tt.key, tt.delta, got, err, tt.want)
+		}
+	}
+	if v, _, _ := s.Get(ctx, "n"); string(v) != "-8" {
+		t.Errorf("stored counter %q, want a decimal string", v)
+	}
+	if v, _, _ := s.Get(ctx, "text"); string(v) != "ten" {
+		t.Errorf("failed Incr changed the value to %q", v)
+	}
+}
@@ -0,0 +1,124 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"google.golang.org/grpc/stats"
+)
+
+// WithStatsHandler installs h on the gRPC server started by Serve, so it observes every RPC and
+// connection at the wire level: headers, payloads with their wire sizes, and the timing of each.
+// It may be given several times; handlers are called in order. Use NewWireTimingHandler for
+// per-call timings without implementing stats.Handler.
+func WithStatsHandler(h stats.Handler) ServeOption {
+	return func(o *serveOptions) error {
+		if h == nil {
+			return fmt.Errorf("stats handler cannot be nil")
+		}
+		o.statsHandlers = append(o.statsHandlers, h)
+		return nil
+	}
+}
+
+// WireTiming breaks down where the time of one RPC went, as seen by the gRPC transport.
+type WireTiming struct {
+	// Method is the RPC method name (e.g. "HandleRequest").
+	Method string
+
+	// Receive is the time from the start of the RPC until its request was read and decoded.
+	Receive time.Duration
+
+	// TimeToFirstByte is the time from the start of the RPC until the response headers were sent,
+	// once the handler returned.
+	TimeToFirstByte time.Duration
+
+	// Send is the time spent marshalling and writing the response after the headers. With
+	// WithMessagePooling the response is marshalled by the handler, so Send only covers the write.
+	Send time.Duration
+
+	// Total is the time from the start to the end of the RPC.
+	Total time.Duration
+
+	// RequestBytes and ResponseBytes are the wire sizes of the request and response messages.
+	RequestBytes  int
+	ResponseBytes int
+
+	// Err is the error the RPC ended with, if any.
+	Err error
+}
+
+// NewWireTimingHandler returns a stats.Handler, for WithStatsHandler, that calls fn with the
+// WireTiming of every server RPC once it ends.
+//
+// Usage:
+//
+//	timings := mcpdpluginsv1.NewWireTimingHandler(func(ctx context.Context, t mcpdpluginsv1.WireTiming) {
+//	    if t.Method == "HandleRequest" {
+//	        ttfb.Observe(t.TimeToFirstByte.Seconds())
+//	    }
+//	})
+//	err := mcpdpluginsv1.Serve(&MyPlugin{}, mcpdpluginsv1.WithStatsHandler(timings))
+func NewWireTimingHandler(fn func(context.Context, WireTiming)) stats.Handler {
+	return &wireTimingHandler{fn: fn}
+}
+
+type wireTimingHandler struct {
+	fn func(context.Context, WireTiming)
+}
+
+// wireCall accumulates the stats of one RPC. The events of an RPC are delivered in order, so it
+// needs no locking.
+type wireCall struct {
+	timing   WireTiming
+	begin    time.Time
+	received time.Time
+	headers  time.Time
+}
+
+type wireCallKey struct{}
+
+func (h *wireTimingHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, wireCallKey{}, &wireCall{timing: WireTiming{Method: path.Base(info.FullMethodName)}})
+}
+
+func (h *wireTimingHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	c, ok := ctx.Value(wireCallKey{}).(*wireCall)
+	if !ok || s.IsClient() {
+		return
+	}
+
+	switch s := s.(type) {
+	case *stats.Begin:
+		c.begin = s.BeginTime
+	case *stats.InPayload:
+		c.received = s.RecvTime
+		c.timing.RequestBytes += s.WireLength
+	case *stats.OutHeader:
+		c.headers = time.Now()
+	case *stats.OutPayload:
+		if c.headers.IsZero() {
+			c.headers = s.SentTime
+		}
+		c.timing.Send += s.SentTime.Sub(c.headers)
+		c.timing.ResponseBytes += s.WireLength
+	case *stats.End:
+		if !c.received.IsZero() {
+			c.timing.Receive = c.received.Sub(c.begin)
+		}
+		if !c.headers.IsZero() {
+			c.timing.TimeToFirstByte = c.headers.Sub(c.begin)
+		}
+		c.timing.Total = s.EndTime.Sub(s.BeginTime)
+		c.timing.Err = s.Error
+		h.fn(ctx, c.timing)
+	}
+}
+
+func (h *wireTimingHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *wireTimingHandler) HandleConn(context.Context, stats.ConnStats) {}
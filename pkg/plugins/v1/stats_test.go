@@ -0,0 +1,179 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestWithStatsHandler(t *testing.T) {
+	if _, err := newServeOptions(WithStatsHandler(nil)); err == nil {
+		t.Error("WithStatsHandler accepted a nil handler")
+	}
+	h := NewWireTimingHandler(func(context.Context, WireTiming) {})
+	o, err := newServeOptions(WithStatsHandler(h), WithStatsHandler(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(o.statsHandlers) != 2 {
+		t.Errorf("%d stats handlers, want 2", len(o.statsHandlers))
+	}
+}
+
+func TestWireTimingHandlerEvents(t *testing.T) {
+	t0 := time.Unix(1_700_000_000, 0)
+	at := func(ms int) time.Time { return t0.Add(time.Duration(ms) * time.Millisecond) }
+	failure := errors.New("boom")
+
+	tests := []struct {
+		name   string
+		events []stats.RPCStats
+		want   WireTiming
+	}{
+		{
+			name: "complete call",
+			events: []stats.RPCStats{
+				&stats.Begin{BeginTime: at(0)},
+				&stats.InPayload{RecvTime: at(2), WireLength: 100},
+				&stats.OutPayload{SentTime: at(10), WireLength: 40},
+				&stats.End{BeginTime: at(0), EndTime: at(12)},
+			},
+			want: WireTiming{
+				Method:          "HandleRequest",
+				Receive:         2 * time.Millisecond,
+				TimeToFirstByte: 10 * time.Millisecond,
+				Total:           12 * time.Millisecond,
+				RequestBytes:    100,
+				ResponseBytes:   40,
+			},
+		},
+		{
+			name: "failed before the request was read",
+			events: []stats.RPCStats{
+				&stats.Begin{BeginTime: at(0)},
+				&stats.End{BeginTime: at(0), EndTime: at(1), Error: failure},
+			},
+			want: WireTiming{Method: "HandleRequest", Total: time.Millisecond, Err: failure},
+		},
+		{
+			name: "client events ignored",
+			events: []stats.RPCStats{
+				&stats.Begin{BeginTime: at(0)},
+				&stats.Begin{Client: true, BeginTime: at(5)},
+				&stats.InPayload{Client: true, RecvTime: at(6), WireLength: 1},
+				&stats.InPayload{RecvTime: at(3), WireLength: 7},
+				&stats.End{BeginTime: at(0), EndTime: at(4)},
+			},
+			want: WireTiming{
+				Method:       "HandleRequest",
+				Receive:      3 * time.Millisecond,
+				Total:        4 * time.Millisecond,
+				RequestBytes: 7,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []WireTiming
+			h := NewWireTimingHandler(func(_ context.Context, wt WireTiming) { got = append(got, wt) })
+			info := &stats.RPCTagInfo{FullMethodName: Plugin_HandleRequest_FullMethodName}
+			ctx := h.TagRPC(context.Background(), info)
+			for _, ev := range tt.events {
+				h.HandleRPC(ctx, ev)
+			}
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("timings = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Events on a context the handler did not tag are ignored.
+	h := NewWireTimingHandler(func(context.Context, WireTiming) { t.Error("timing reported for an untagged call") })
+	h.HandleRPC(context.Background(), &stats.End{})
+	if ctx := h.TagConn(context.Background(), &stats.ConnTagInfo{}); ctx != context.Background() {
+		t.Error("TagConn changed the context")
+	}
+}
+
+func TestWireTimingHandlerServer(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		timings []WireTiming
+	)
+	h := NewWireTimingHandler(func(_ context.Context, wt WireTiming) {
+		mu.Lock()
+		defer mu.Unlock()
+		timings = append(timings, wt)
+	})
+
+	address := filepath.Join(t.TempDir(), "plugin.sock")
+	lis, err := net.Listen("unix", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.StatsHandler(h))
+	RegisterPluginServer(srv, &echoPlugin{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("unix://"+address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	client := NewPluginClient(conn)
+
+	ctx := context.Background()
+	if _, err := client.HandleRequest(ctx, &HTTPRequest{Body: []byte("payload")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.HandleRequest(ctx, &HTTPRequest{Method: "FAIL"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("failing call = %v", err)
+	}
+	if _, err := client.CheckHealth(ctx, &emptypb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server reports a call's stats after the client has its response.
+	waitFor(t, "the timings", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(timings) == 3
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	// Calls end in order on the client, but the server may report them in any order.
+	var ok, failed, health WireTiming
+	for _, wt := range timings {
+		switch {
+		case wt.Method == "CheckHealth":
+			health = wt
+		case wt.Err != nil:
+			failed = wt
+		default:
+			ok = wt
+		}
+	}
+	if ok.Method != "HandleRequest" || ok.RequestBytes == 0 || ok.ResponseBytes == 0 || ok.Err != nil {
+		t.Errorf("successful call = %+v", ok)
+	}
+	if ok.Receive > ok.TimeToFirstByte || ok.TimeToFirstByte > ok.Total || ok.Total == 0 {
+		t.Errorf("successful call phases out of order: %+v", ok)
+	}
+	if status.Code(failed.Err) != codes.InvalidArgument || failed.ResponseBytes != 0 {
+		t.Errorf("failed call = %+v", failed)
+	}
+	if health.Method != "CheckHealth" || health.Err != nil {
+		t.Errorf("health check = %+v", health)
+	}
+}
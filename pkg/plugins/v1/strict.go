@@ -0,0 +1,191 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MessageViolation is one invariant of HTTP a message breaks.
+type MessageViolation struct {
+	// Field is the offending field, such as "method" or "headers[X-Api-Key]".
+	Field   string
+	Message string
+}
+
+// MessageError lists every violation found by ValidateRequest or ValidateResponse.
+type MessageError struct {
+	// Message names the validated message, "HTTPRequest" or "HTTPResponse".
+	Message    string
+	Violations []MessageViolation
+}
+
+// Error implements error.
+func (e *MessageError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Field + ": " + v.Message
+	}
+
+	return "invalid " + e.Message + ": " + strings.Join(parts, "; ")
+}
+
+// WithStrictValidation checks the HTTPRequest and HTTPResponse mcpd sends HandleRequest and
+// HandleResponse with ValidateRequest and ValidateResponse before calling the plugin, and rejects
+// malformed ones with codes.InvalidArgument and a BadRequest detail listing every violation. It
+// catches bugs on the mcpd side at the boundary, instead of as odd behavior deep in a handler.
+//
+// Fields mcpd leaves out because the plugin is a FieldSubscriber not subscribed to them are not
+// required.
+func WithStrictValidation() ServeOption {
+	return func(o *serveOptions) error {
+		o.strict = true
+		return nil
+	}
+}
+
+// ValidateRequest checks that req is a well-formed HTTP request: it has a method that is an HTTP
+// token, a path starting with "/", parseable RequestUri and Url when set, and valid header names
+// and values. It returns a *MessageError listing every violation.
+func ValidateRequest(req *HTTPRequest) error {
+	return validateRequest(req, nil)
+}
+
+// ValidateResponse checks that resp is a well-formed HTTP response: it has a status code in the
+// 100-599 range and valid header names and values. It returns a *MessageError listing every
+// violation.
+func ValidateResponse(resp *HTTPResponse) error {
+	v := &messageValidator{}
+	if code := resp.GetStatusCode(); code < 100 || code > 599 {
+		v.add("status_code", fmt.Sprintf("%d is not an HTTP status code", code))
+	}
+	v.headers(resp.GetHeaders())
+
+	return v.err("HTTPResponse")
+}
+
+// validateRequest implements ValidateRequest, requiring only the fields of subscribed, or every
+// field when subscribed is nil.
+func validateRequest(req *HTTPRequest, subscribed FieldSet) error {
+	required := func(f RequestField) bool { return subscribed == nil || subscribed.Has(f) }
+
+	v := &messageValidator{}
+	switch method := req.GetMethod(); {
+	case method == "":
+		if required(FieldMethod) {
+			v.add("method", "is empty")
+		}
+	case !isToken(method):
+		v.add("method", fmt.Sprintf("%q is not an HTTP token", method))
+	}
+	switch p := req.GetPath(); {
+	case p == "":
+		if required(FieldPath) {
+			v.add("path", "is empty")
+		}
+	case !strings.HasPrefix(p, "/"):
+		v.add("path", fmt.Sprintf("%q does not start with /", p))
+	}
+	if uri := req.GetRequestUri(); uri != "" {
+		if _, err := url.ParseRequestURI(uri); err != nil {
+			v.add("request_uri", err.Error())
+		}
+	}
+	if raw := req.GetUrl(); raw != "" {
+		if _, err := url.Parse(raw); err != nil {
+			v.add("url", err.Error())
+		}
+	}
+	v.headers(req.GetHeaders())
+
+	return v.err("HTTPRequest")
+}
+
+// messageValidator collects violations.
+type messageValidator struct {
+	violations []MessageViolation
+}
+
+func (v *messageValidator) add(field, msg string) {
+	v.violations = append(v.violations, MessageViolation{Field: field, Message: msg})
+}
+
+// headers checks every header name and value, in name order.
+func (v *messageValidator) headers(h map[string]string) {
+	for _, name := range slices.Sorted(maps.Keys(h)) {
+		field := "headers[" + name + "]"
+		if !httpguts.ValidHeaderFieldName(name) {
+			field = "headers[" + strconv.Quote(name) + "]"
+			v.add(field, "is not a valid header name")
+		}
+		if !httpguts.ValidHeaderFieldValue(h[name]) {
+			v.add(field, "value contains control characters")
+		}
+	}
+}
+
+func (v *messageValidator) err(message string) error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+
+	return &MessageError{Message: message, Violations: v.violations}
+}
+
+// isToken reports whether s is a non-empty HTTP token (RFC 9110, section 5.6.2).
+func isToken(s string) bool {
+	for _, r := range s {
+		if !httpguts.IsTokenRune(r) {
+			return false
+		}
+	}
+
+	return s != ""
+}
+
+// strictInterceptor rejects malformed handler inputs before the plugin sees them.
+func strictInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var err error
+		switch in := req.(type) {
+		case *HTTPRequest:
+			fields, _ := SubscribedFields(ctx)
+			err = validateRequest(in, fields)
+		case *HTTPResponse:
+			err = ValidateResponse(in)
+		}
+		if err != nil && isHandlerMethod(info.FullMethod) {
+			return nil, messageValidationStatus(err.(*MessageError))
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// messageValidationStatus converts e into an InvalidArgument status.
+func messageValidationStatus(e *MessageError) error {
+	st := status.New(codes.InvalidArgument, e.Error())
+
+	br := &errdetails.BadRequest{}
+	for _, v := range e.Violations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Message,
+		})
+	}
+	if detailed, derr := st.WithDetails(br); derr == nil {
+		return detailed.Err()
+	}
+
+	return st.Err()
+}
@@ -0,0 +1,270 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+)
+
+// violationFields returns the fields of the violations err lists, or nil when err is nil.
+func violationFields(t *testing.T, err error) []string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+	var merr *MessageError
+	if !errors.As(err, &merr) {
+		t.Fatalf("error = %v, want a *MessageError", err)
+	}
+	var fields []string
+	for _, v := range merr.Violations {
+		fields = append(fields, v.Field)
+	}
+
+	return fields
+}
+
+func TestValidateRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *HTTPRequest
+		want []string // Fields of the violations.
+	}{
+		{
+			name: "well-formed",
+			req: &HTTPRequest{
+				Method:     "POST",
+				Path:       "/mcp",
+				RequestUri: "/mcp?session=1",
+				Url:        "https://mcpd.example.com/mcp",
+				Headers:    map[string]string{"Content-Type": "application/json", "X-Empty": ""},
+			},
+		},
+		{name: "extension method", req: &HTTPRequest{Method: "PROPFIND", Path: "/"}},
+		{name: "nil", want: []string{"method", "path"}},
+		{name: "method not a token", req: &HTTPRequest{Method: "GET /", Path: "/"}, want: []string{"method"}},
+		{name: "relative path", req: &HTTPRequest{Method: "GET", Path: "mcp"}, want: []string{"path"}},
+		{
+			name: "malformed request URI",
+			req:  &HTTPRequest{Method: "GET", Path: "/", RequestUri: "mcp"},
+			want: []string{"request_uri"},
+		},
+		{name: "malformed URL", req: &HTTPRequest{Method: "GET", Path: "/", Url: "http://[::1"}, want: []string{"url"}},
+		{
+			name: "invalid header name and value",
+			req: &HTTPRequest{Method: "GET", Path: "/", Headers: map[string]string{
+				"X Bad":   "1",
+				"X-Split": "a\r\nInjected: 1",
+				"X-Ok":    "fine",
+			}},
+			want: []string{`headers["X Bad"]`, "headers[X-Split]"},
+		},
+		{
+			name: "every violation listed",
+			req:  &HTTPRequest{Method: "G{T", Path: "x", Headers: map[string]string{"": "v"}},
+			want: []string{"method", "path", `headers[""]`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := violationFields(t, ValidateRequest(tt.req)); !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRequestSubscribed(t *testing.T) {
+	fields, err := NewFieldSet(FieldHeaders)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fields left out of the subscription are not required, but are still checked when present.
+	if err := validateRequest(&HTTPRequest{Headers: map[string]string{"X-A": "1"}}, fields); err != nil {
+		t.Errorf("validateRequest = %v, want the unsubscribed method and path not required", err)
+	}
+	err = validateRequest(&HTTPRequest{Method: "G T", Path: "mcp"}, fields)
+	if got := violationFields(t, err); !slices.Equal(got, []string{"method", "path"}) {
+		t.Errorf("violations = %v, want the malformed method and path", got)
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *HTTPResponse
+		want []string
+	}{
+		{
+			name: "well-formed",
+			resp: &HTTPResponse{StatusCode: 200, Headers: map[string]string{"Content-Type": "text/plain"}},
+		},
+		{name: "lowest code", resp: &HTTPResponse{StatusCode: 100}},
+		{name: "highest code", resp: &HTTPResponse{StatusCode: 599}},
+		{name: "nil", want: []string{"status_code"}},
+		{name: "code too low", resp: &HTTPResponse{StatusCode: 99}, want: []string{"status_code"}},
+		{name: "code too high", resp: &HTTPResponse{StatusCode: 600}, want: []string{"status_code"}},
+		{
+			name: "invalid header",
+			resp: &HTTPResponse{StatusCode: 200, Headers: map[string]string{"Set-Cookie": "a\x00b"}},
+			want: []string{"headers[Set-Cookie]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := violationFields(t, ValidateResponse(tt.resp)); !slices.Equal(got, tt.want) {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageErrorString(t *testing.T) {
+	err := ValidateRequest(&HTTPRequest{Path: "mcp"})
+	want := `invalid HTTPRequest: method: is empty; path: "mcp" does not start with /`
+	if err == nil || err.Error() != want {
+		t.Errorf("Error() = %v, want %s", err, want)
+	}
+}
+
+func TestStrictValidationInterceptor(t *testing.T) {
+	tests := []struct {
+		name       string
+		fields     []RequestField // Subscription of a FieldSubscriber; nil for none.
+		negotiated bool           // Whether mcpd applies the subscription.
+		method     string
+		req        any
+		wantCode   codes.Code
+		wantFields []string // Fields of the BadRequest detail.
+	}{
+		{
+			name:     "valid request",
+			method:   Plugin_HandleRequest_FullMethodName,
+			req:      &HTTPRequest{Method: "POST", Path: "/mcp"},
+			wantCode: codes.OK,
+		},
+		{
+			name:       "malformed request",
+			method:     Plugin_HandleRequest_FullMethodName,
+			req:        &HTTPRequest{Method: "POST", Path: "/mcp", Headers: map[string]string{"X-A": "\n"}},
+			wantCode:   codes.InvalidArgument,
+			wantFields: []string{"headers[X-A]"},
+		},
+		{
+			name:       "nil request",
+			method:     Plugin_HandleRequest_FullMethodName,
+			req:        (*HTTPRequest)(nil),
+			wantCode:   codes.InvalidArgument,
+			wantFields: []string{"method", "path"},
+		},
+		{
+			name:       "unsubscribed fields not required",
+			fields:     []RequestField{FieldHeaders},
+			negotiated: true,
+			method:     Plugin_HandleRequest_FullMethodName,
+			req:        &HTTPRequest{Headers: map[string]string{"X-A": "1"}},
+			wantCode:   codes.OK,
+		},
+		{
+			name:       "subscription not applied by mcpd",
+			fields:     []RequestField{FieldHeaders},
+			method:     Plugin_HandleRequest_FullMethodName,
+			req:        &HTTPRequest{Headers: map[string]string{"X-A": "1"}},
+			wantCode:   codes.InvalidArgument,
+			wantFields: []string{"method", "path"},
+		},
+		{
+			name:       "malformed response",
+			method:     Plugin_HandleResponse_FullMethodName,
+			req:        &HTTPResponse{StatusCode: 0},
+			wantCode:   codes.InvalidArgument,
+			wantFields: []string{"status_code"},
+		},
+		{
+			name:     "valid response",
+			method:   Plugin_HandleResponse_FullMethodName,
+			req:      &HTTPResponse{StatusCode: 404},
+			wantCode: codes.OK,
+		},
+		{
+			name:     "other RPCs unchecked",
+			method:   Plugin_Configure_FullMethodName,
+			req:      &PluginConfig{},
+			wantCode: codes.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(WithStrictValidation())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fields FieldSet
+			if tt.fields != nil {
+				if fields, err = NewFieldSet(tt.fields...); err != nil {
+					t.Fatal(err)
+				}
+			}
+			offered := features.NewSet(append(SupportedFeatures(), o.features(fields)...)...)
+			interceptors, err := o.unaryInterceptors(&BasePlugin{}, fields, offered)
+			if err != nil {
+				t.Fatal(err)
+			}
+			called := false
+			handler := func(context.Context, any) (any, error) {
+				called = true
+				return &HTTPResponse{Continue: true}, nil
+			}
+
+			ctx := context.Background()
+			if tt.negotiated {
+				offered := features.NewSet(features.SelectiveFields).String()
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(features.MetadataKey, offered))
+			}
+			info := &grpc.UnaryServerInfo{FullMethod: tt.method}
+			_, err = chainInterceptors(interceptors)(ctx, tt.req, info, handler)
+			st := status.Convert(err)
+			if st.Code() != tt.wantCode {
+				t.Fatalf("code = %s, want %s (err %v)", st.Code(), tt.wantCode, err)
+			}
+			if called != (tt.wantCode == codes.OK) {
+				t.Errorf("handler called = %t", called)
+			}
+			if tt.wantCode == codes.OK {
+				return
+			}
+			var got []string
+			for _, d := range st.Details() {
+				if br, ok := d.(*errdetails.BadRequest); ok {
+					for _, v := range br.GetFieldViolations() {
+						got = append(got, v.GetField())
+					}
+				}
+			}
+			if !slices.Equal(got, tt.wantFields) {
+				t.Errorf("BadRequest fields = %v, want %v", got, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestStrictValidationOff(t *testing.T) {
+	intercept := serveChain(t, &BasePlugin{})
+	info := &grpc.UnaryServerInfo{FullMethod: Plugin_HandleRequest_FullMethodName}
+	handler := func(context.Context, any) (any, error) { return &HTTPResponse{Continue: true}, nil }
+
+	if _, err := intercept(context.Background(), &HTTPRequest{Method: "G T"}, info, handler); err != nil {
+		t.Errorf("malformed request rejected without WithStrictValidation: %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+// Package structx reads nested google.protobuf.Struct values by path, returning typed results
+// and descriptive errors instead of requiring chains of type assertions on Value kinds.
+//
+// Paths separate object fields with dots and index lists with brackets:
+//
+//	name, err := structx.GetString(s, "upstream.servers[0].name")
+//	tags, err := structx.GetStrings(s, "labels.tags")
+//
+// A missing field or index yields an error wrapping ErrNotFound; a value of the wrong kind yields
+// a *TypeError. The *Or variants return a fallback for missing values but still report type errors,
+// so misconfigured values are not silently ignored.
+package structx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ErrNotFound is returned when a path does not exist.
+var ErrNotFound = errors.New("structx: path not found")
+
+// TypeError is returned when the value at Path is not of the requested kind.
+type TypeError struct {
+	Path string
+	Want string
+	Got  string
+}
+
+// Error implements error.
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("structx: %s is %s, not %s", e.Path, e.Got, e.Want)
+}
+
+// segment is a field name or, when index is non-negative, a list index.
+type segment struct {
+	field string
+	index int
+}
+
+func parsePath(path string) ([]segment, error) {
+	var segs []segment
+	for _, part := range strings.Split(path, ".") {
+		name, rest, bracket := strings.Cut(part, "[")
+		if name == "" && (len(segs) == 0 || !bracket) {
+			return nil, fmt.Errorf("structx: invalid path %q: empty field", path)
+		}
+		if name != "" {
+			segs = append(segs, segment{field: name, index: -1})
+		}
+		for bracket {
+			idx, tail, ok := strings.Cut(rest, "]")
+			n, err := strconv.Atoi(idx)
+			if !ok || err != nil || n < 0 {
+				return nil, fmt.Errorf("structx: invalid path %q: bad index", path)
+			}
+			segs = append(segs, segment{index: n})
+			if tail == "" {
+				break
+			}
+			if tail[0] != '[' {
+				return nil, fmt.Errorf("structx: invalid path %q: unexpected %q", path, tail)
+			}
+			rest = tail[1:]
+		}
+	}
+
+	return segs, nil
+}
+
+// Get returns the value at path within s. A nil s is treated as an empty struct.
+func Get(s *structpb.Struct, path string) (*structpb.Value, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		s = &structpb.Struct{}
+	}
+
+	v := structpb.NewStructValue(s)
+	walked := ""
+	for _, seg := range segs {
+		at := walked
+		if seg.index < 0 {
+			if walked != "" {
+				walked += "."
+			}
+			walked += seg.field
+			obj := v.GetStructValue()
+			if obj == nil {
+				return nil, &TypeError{Path: display(at), Want: "struct", Got: kind(v)}
+			}
+			next, ok := obj.GetFields()[seg.field]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrNotFound, walked)
+			}
+			v = next
+			continue
+		}
+
+		walked += "[" + strconv.Itoa(seg.index) + "]"
+		list := v.GetListValue()
+		if list == nil {
+			return nil, &TypeError{Path: display(at), Want: "list", Got: kind(v)}
+		}
+		if seg.index >= len(list.GetValues()) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, walked)
+		}
+		v = list.GetValues()[seg.index]
+	}
+
+	return v, nil
+}
+
+func display(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+
+	return path
+}
+
+// Has reports whether path exists within s.
+func Has(s *structpb.Struct, path string) bool {
+	_, err := Get(s, path)
+	return err == nil
+}
+
+// GetString returns the string at path.
+func GetString(s *structpb.Struct, path string) (string, error) {
+	v, err := typed(s, path, "string")
+	if err != nil {
+		return "", err
+	}
+
+	return v.GetStringValue(), nil
+}
+
+// GetNumber returns the number at path.
+func GetNumber(s *structpb.Struct, path string) (float64, error) {
+	v, err := typed(s, path, "number")
+	if err != nil {
+		return 0, err
+	}
+
+	return v.GetNumberValue(), nil
+}
+
+// GetInt returns the number at path, which must be an integer within int64 range.
+func GetInt(s *structpb.Struct, path string) (int64, error) {
+	f, err := GetNumber(s, path)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, &TypeError{Path: path, Want: "integer", Got: strconv.FormatFloat(f, 'g', -1, 64)}
+	}
+
+	return int64(f), nil
+}
+
+// GetBool returns the boolean at path.
+func GetBool(s *structpb.Struct, path string) (bool, error) {
+	v, err := typed(s, path, "bool")
+	if err != nil {
+		return false, err
+	}
+
+	return v.GetBoolValue(), nil
+}
+
+// GetStruct returns the struct at path.
+func GetStruct(s *structpb.Struct, path string) (*structpb.Struct, error) {
+	v, err := typed(s, path, "struct")
+	if err != nil {
+		return nil, err
+	}
+
+	return v.GetStructValue(), nil
+}
+
+// GetList returns the elements of the list at path.
+func GetList(s *structpb.Struct, path string) ([]*structpb.Value, error) {
+	v, err := typed(s, path, "list")
+	if err != nil {
+		return nil, err
+	}
+
+	return v.GetListValue().GetValues(), nil
+}
+
+// GetStrings returns the list of strings at path.
+func GetStrings(s *structpb.Struct, path string) ([]string, error) {
+	values, err := GetList(s, path)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(values))
+	for i, v := range values {
+		if _, ok := v.GetKind().(*structpb.Value_StringValue); !ok {
+			return nil, &TypeError{Path: fmt.Sprintf("%s[%d]", path, i), Want: "string", Got: kind(v)}
+		}
+		out[i] = v.GetStringValue()
+	}
+
+	return out, nil
+}
+
+// GetStringOr returns the string at path, or fallback when the path does not exist.
+func GetStringOr(s *structpb.Struct, path, fallback string) (string, error) {
+	v, err := GetString(s, path)
+	if errors.Is(err, ErrNotFound) {
+		return fallback, nil
+	}
+
+	return v, err
+}
+
+// GetNumberOr returns the number at path, or fallback when the path does not exist.
+func GetNumberOr(s *structpb.Struct, path string, fallback float64) (float64, error) {
+	v, err := GetNumber(s, path)
+	if errors.Is(err, ErrNotFound) {
+		return fallback, nil
+	}
+
+	return v, err
+}
+
+// GetBoolOr returns the boolean at path, or fallback when the path does not exist.
+func GetBoolOr(s *structpb.Struct, path string, fallback bool) (bool, error) {
+	v, err := GetBool(s, path)
+	if errors.Is(err, ErrNotFound) {
+		return fallback, nil
+	}
+
+	return v, err
+}
+
+// Merge returns a deep copy of base with overlay applied: nested structs merge field by field,
+// and any other overlay value, including lists and null, replaces the base value.
+func Merge(base, overlay *structpb.Struct) *structpb.Struct {
+	out := &structpb.Struct{}
+	if base != nil {
+		out = proto.Clone(base).(*structpb.Struct)
+	}
+	if out.Fields == nil {
+		out.Fields = map[string]*structpb.Value{}
+	}
+	for k, v := range overlay.GetFields() {
+		if o := v.GetStructValue(); o != nil {
+			if b := out.Fields[k].GetStructValue(); b != nil {
+				out.Fields[k] = structpb.NewStructValue(Merge(b, o))
+				continue
+			}
+		}
+		out.Fields[k] = proto.Clone(v).(*structpb.Value)
+	}
+
+	return out
+}
+
+// FromAny unpacks a google.protobuf.Struct carried in a, reporting the packed type otherwise.
+func FromAny(a *anypb.Any) (*structpb.Struct, error) {
+	s := &structpb.Struct{}
+	if !a.MessageIs(s) {
+		return nil, fmt.Errorf("structx: Any holds %s, not google.protobuf.Struct", a.GetTypeUrl())
+	}
+	if err := a.UnmarshalTo(s); err != nil {
+		return nil, fmt.Errorf("structx: failed to unpack Struct: %w", err)
+	}
+
+	return s, nil
+}
+
+func typed(s *structpb.Struct, path, want string) (*structpb.Value, error) {
+	v, err := Get(s, path)
+	if err != nil {
+		return nil, err
+	}
+	if got := kind(v); got != want {
+		return nil, &TypeError{Path: path, Want: want, Got: got}
+	}
+
+	return v, nil
+}
+
+// kind names the kind of v as used in errors.
+func kind(v *structpb.Value) string {
+	switch v.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		return "string"
+	case *structpb.Value_NumberValue:
+		return "number"
+	case *structpb.Value_BoolValue:
+		return "bool"
+	case *structpb.Value_StructValue:
+		return "struct"
+	case *structpb.Value_ListValue:
+		return "list"
+	default:
+		return "null"
+	}
+}
@@ -0,0 +1,353 @@
+package structx_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/structx"
+)
+
+func newStruct(t *testing.T, m map[string]any) *structpb.Struct {
+	t.Helper()
+
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func config(t *testing.T) *structpb.Struct {
+	t.Helper()
+
+	return newStruct(t, map[string]any{
+		"name":    "gateway",
+		"enabled": true,
+		"limit":   float64(10),
+		"ratio":   0.25,
+		"nothing": nil,
+		"dotted":  map[string]any{"a": "b"},
+		"upstream": map[string]any{
+			"servers": []any{
+				map[string]any{"name": "github", "ports": []any{float64(80), float64(443)}},
+				map[string]any{"name": "jira"},
+			},
+		},
+		"matrix": []any{[]any{"a", "b"}, []any{"c"}},
+		"labels": map[string]any{"tags": []any{"x", "y"}, "mixed": []any{"x", float64(1)}},
+	})
+}
+
+func TestGet(t *testing.T) {
+	s := config(t)
+	tests := []struct {
+		path     string
+		want     any    // Expected value as returned by Value.AsInterface.
+		wantErr  string // Substring of the error; empty for success.
+		notFound bool
+		typeErr  *structx.TypeError
+	}{
+		{path: "name", want: "gateway"},
+		{path: "upstream.servers[0].name", want: "github"},
+		{path: "upstream.servers[0].ports[1]", want: float64(443)},
+		{path: "matrix[0][1]", want: "b"},
+		{path: "matrix[1]", want: []any{"c"}},
+		{path: "nothing", want: nil},
+		{path: "missing", notFound: true, wantErr: "structx: path not found: missing"},
+		{path: "upstream.clients", notFound: true, wantErr: "path not found: upstream.clients"},
+		{path: "upstream.servers[2]", notFound: true, wantErr: "path not found: upstream.servers[2]"},
+		{path: "upstream.servers[1].ports", notFound: true, wantErr: "upstream.servers[1].ports"},
+		{path: "name.first", typeErr: &structx.TypeError{Path: "name", Want: "struct", Got: "string"}},
+		{path: "name[0]", typeErr: &structx.TypeError{Path: "name", Want: "list", Got: "string"}},
+		{path: "upstream[0]", typeErr: &structx.TypeError{Path: "upstream", Want: "list", Got: "struct"}},
+		{path: "matrix.a", typeErr: &structx.TypeError{Path: "matrix", Want: "struct", Got: "list"}},
+		{path: "matrix[0][0].x", typeErr: &structx.TypeError{Path: "matrix[0][0]", Want: "struct", Got: "string"}},
+		{path: "nothing.x", typeErr: &structx.TypeError{Path: "nothing", Want: "struct", Got: "null"}},
+		{path: "", wantErr: "empty field"},
+		{path: "a..b", wantErr: "empty field"},
+		{path: "a.", wantErr: "empty field"},
+		{path: "[0]", wantErr: "empty field"},
+		{path: "a[", wantErr: "bad index"},
+		{path: "a[]", wantErr: "bad index"},
+		{path: "a[-1]", wantErr: "bad index"},
+		{path: "a[x]", wantErr: "bad index"},
+		{path: "a[0", wantErr: "bad index"},
+		{path: "a[0]b", wantErr: `unexpected "b"`},
+		{path: "a[0][", wantErr: "bad index"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			v, err := structx.Get(s, tt.path)
+			if tt.typeErr != nil {
+				var te *structx.TypeError
+				if !errors.As(err, &te) || *te != *tt.typeErr {
+					t.Errorf("Get error = %v, want %v", err, tt.typeErr)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Get error = %v, want %q", err, tt.wantErr)
+				}
+				if errors.Is(err, structx.ErrNotFound) != tt.notFound {
+					t.Errorf("Get error = %v, want ErrNotFound %t", err, tt.notFound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.AsInterface(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get = %v, want %v", got, tt.want)
+			}
+			if !structx.Has(s, tt.path) {
+				t.Errorf("Has(%q) = false", tt.path)
+			}
+		})
+	}
+}
+
+func TestGetRoot(t *testing.T) {
+	_, err := structx.Get(nil, "a")
+	if !errors.Is(err, structx.ErrNotFound) {
+		t.Errorf("Get on a nil struct = %v, want ErrNotFound", err)
+	}
+	if structx.Has(config(t), "missing") {
+		t.Error("Has reported a missing path")
+	}
+}
+
+func TestTypedGetters(t *testing.T) {
+	s := config(t)
+
+	if v, err := structx.GetString(s, "upstream.servers[1].name"); err != nil || v != "jira" {
+		t.Errorf("GetString = %q, %v", v, err)
+	}
+	if v, err := structx.GetNumber(s, "ratio"); err != nil || v != 0.25 {
+		t.Errorf("GetNumber = %v, %v", v, err)
+	}
+	if v, err := structx.GetInt(s, "limit"); err != nil || v != 10 {
+		t.Errorf("GetInt = %v, %v", v, err)
+	}
+	if v, err := structx.GetBool(s, "enabled"); err != nil || !v {
+		t.Errorf("GetBool = %v, %v", v, err)
+	}
+	if v, err := structx.GetStruct(s, "dotted"); err != nil || v.GetFields()["a"].GetStringValue() != "b" {
+		t.Errorf("GetStruct = %v, %v", v, err)
+	}
+	if v, err := structx.GetList(s, "upstream.servers"); err != nil || len(v) != 2 {
+		t.Errorf("GetList = %v, %v", v, err)
+	}
+	if v, err := structx.GetStrings(s, "labels.tags"); err != nil || !reflect.DeepEqual(v, []string{"x", "y"}) {
+		t.Errorf("GetStrings = %v, %v", v, err)
+	}
+}
+
+func TestTypedGetterErrors(t *testing.T) {
+	s := config(t)
+	tests := []struct {
+		name string
+		get  func() error
+		want structx.TypeError
+	}{
+		{
+			name: "string",
+			get:  func() error { _, err := structx.GetString(s, "limit"); return err },
+			want: structx.TypeError{Path: "limit", Want: "string", Got: "number"},
+		},
+		{
+			name: "number",
+			get:  func() error { _, err := structx.GetNumber(s, "name"); return err },
+			want: structx.TypeError{Path: "name", Want: "number", Got: "string"},
+		},
+		{
+			name: "bool",
+			get:  func() error { _, err := structx.GetBool(s, "nothing"); return err },
+			want: structx.TypeError{Path: "nothing", Want: "bool", Got: "null"},
+		},
+		{
+			name: "struct",
+			get:  func() error { _, err := structx.GetStruct(s, "matrix"); return err },
+			want: structx.TypeError{Path: "matrix", Want: "struct", Got: "list"},
+		},
+		{
+			name: "list",
+			get:  func() error { _, err := structx.GetList(s, "enabled"); return err },
+			want: structx.TypeError{Path: "enabled", Want: "list", Got: "bool"},
+		},
+		{
+			name: "strings element",
+			get:  func() error { _, err := structx.GetStrings(s, "labels.mixed"); return err },
+			want: structx.TypeError{Path: "labels.mixed[1]", Want: "string", Got: "number"},
+		},
+		{
+			name: "fractional int",
+			get:  func() error { _, err := structx.GetInt(s, "ratio"); return err },
+			want: structx.TypeError{Path: "ratio", Want: "integer", Got: "0.25"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.get()
+			var te *structx.TypeError
+			if !errors.As(err, &te) || *te != tt.want {
+				t.Errorf("error = %v, want %v", err, &tt.want)
+			}
+			if errors.Is(err, structx.ErrNotFound) {
+				t.Errorf("type error %v matches ErrNotFound", err)
+			}
+		})
+	}
+
+	err := &structx.TypeError{Path: "a", Want: "string", Got: "bool"}
+	if got := err.Error(); got != "structx: a is bool, not string" {
+		t.Errorf("Error = %q", got)
+	}
+}
+
+func TestGetIntRange(t *testing.T) {
+	tests := []struct {
+		n    float64
+		want int64
+		ok   bool
+	}{
+		{n: 0, want: 0, ok: true},
+		{n: -42, want: -42, ok: true},
+		{n: 1 << 53, want: 1 << 53, ok: true},
+		{n: math.MinInt64, want: math.MinInt64, ok: true},
+		{n: math.MaxInt64}, // Rounds to 2^63, one past the range.
+		{n: math.Inf(1)},
+		{n: math.NaN()},
+		{n: 2.5},
+	}
+	for _, tt := range tests {
+		s := &structpb.Struct{Fields: map[string]*structpb.Value{"n": structpb.NewNumberValue(tt.n)}}
+		got, err := structx.GetInt(s, "n")
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("GetInt(%v) = %d, %v; want %d, ok %t", tt.n, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGetOr(t *testing.T) {
+	s := config(t)
+
+	if v, err := structx.GetStringOr(s, "missing", "fallback"); err != nil || v != "fallback" {
+		t.Errorf("GetStringOr missing = %q, %v", v, err)
+	}
+	if v, err := structx.GetStringOr(s, "name", "fallback"); err != nil || v != "gateway" {
+		t.Errorf("GetStringOr present = %q, %v", v, err)
+	}
+	if v, err := structx.GetNumberOr(s, "upstream.servers[5]", 3); err != nil || v != 3 {
+		t.Errorf("GetNumberOr missing = %v, %v", v, err)
+	}
+	if v, err := structx.GetBoolOr(s, "enabled", false); err != nil || !v {
+		t.Errorf("GetBoolOr present = %v, %v", v, err)
+	}
+	if v, err := structx.GetBoolOr(s, "debug", true); err != nil || !v {
+		t.Errorf("GetBoolOr missing = %v, %v", v, err)
+	}
+
+	// Type errors and invalid paths are still reported.
+	var te *structx.TypeError
+	if _, err := structx.GetStringOr(s, "limit", "x"); !errors.As(err, &te) {
+		t.Errorf("GetStringOr on a number = %v, want a TypeError", err)
+	}
+	if _, err := structx.GetNumberOr(s, "name", 1); !errors.As(err, &te) {
+		t.Errorf("GetNumberOr on a string = %v, want a TypeError", err)
+	}
+	if _, err := structx.GetBoolOr(s, "name.x", false); !errors.As(err, &te) {
+		t.Errorf("GetBoolOr through a string = %v, want a TypeError", err)
+	}
+	if _, err := structx.GetStringOr(s, "a..b", "x"); err == nil {
+		t.Error("GetStringOr accepted an invalid path")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := newStruct(t, map[string]any{
+		"name":   "base",
+		"keep":   true,
+		"nested": map[string]any{"a": float64(1), "b": float64(2), "deep": map[string]any{"x": "y"}},
+		"list":   []any{"a", "b"},
+		"scalar": "s",
+	})
+	before := proto.Clone(base)
+	overlay := newStruct(t, map[string]any{
+		"name":   "overlay",
+		"nested": map[string]any{"b": float64(3), "deep": map[string]any{"z": "w"}},
+		"list":   []any{"c"},
+		"scalar": map[string]any{"now": "struct"},
+		"keep":   nil,
+		"added":  "new",
+	})
+
+	got := structx.Merge(base, overlay).AsMap()
+	want := map[string]any{
+		"name":   "overlay",
+		"keep":   nil,
+		"nested": map[string]any{"a": float64(1), "b": float64(3), "deep": map[string]any{"x": "y", "z": "w"}},
+		"list":   []any{"c"},
+		"scalar": map[string]any{"now": "struct"},
+		"added":  "new",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge =\n%v\nwant\n%v", got, want)
+	}
+	if !proto.Equal(base, before) {
+		t.Error("Merge modified the base struct")
+	}
+
+	merged := structx.Merge(base, overlay)
+	overlay.Fields["added"] = structpb.NewStringValue("changed")
+	if merged.GetFields()["added"].GetStringValue() != "new" {
+		t.Error("Merge shares values with the overlay")
+	}
+
+	if got := structx.Merge(nil, nil); got == nil || len(got.GetFields()) != 0 {
+		t.Errorf("Merge(nil, nil) = %v, want an empty struct", got)
+	}
+	if got := structx.Merge(nil, overlay).AsMap()["name"]; got != "overlay" {
+		t.Errorf("Merge(nil, overlay) name = %v", got)
+	}
+	if got := structx.Merge(&structpb.Struct{}, overlay).AsMap()["name"]; got != "overlay" {
+		t.Errorf("Merge onto a struct without fields: name = %v", got)
+	}
+}
+
+func TestFromAny(t *testing.T) {
+	s := config(t)
+	a, err := anypb.New(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := structx.FromAny(a)
+	if err != nil || !proto.Equal(got, s) {
+		t.Errorf("FromAny = %v, %v; want the packed struct", got, err)
+	}
+
+	other, err := anypb.New(wrapperspb.String("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := structx.FromAny(other); err == nil || !strings.Contains(err.Error(), "google.protobuf.StringValue") {
+		t.Errorf("FromAny error = %v, want the packed type", err)
+	}
+
+	corrupt := &anypb.Any{TypeUrl: a.GetTypeUrl(), Value: []byte{0xff}}
+	if _, err := structx.FromAny(corrupt); err == nil || !strings.Contains(err.Error(), "failed to unpack Struct") {
+		t.Errorf("FromAny error = %v, want an unpack failure", err)
+	}
+	if _, err := structx.FromAny(nil); err == nil {
+		t.Error("FromAny accepted a nil Any")
+	}
+}
@@ -0,0 +1,109 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// gRPC request metadata keys with which mcpd describes the upstream MCP server a call targets.
+const (
+	TargetNameMetadataKey      = "mcpd-target-name"
+	TargetURLMetadataKey       = "mcpd-target-url"
+	TargetTransportMetadataKey = "mcpd-target-transport"
+)
+
+// Transport is the transport mcpd uses to reach an upstream MCP server.
+type Transport string
+
+// Transports reported in TargetInfo.
+const (
+	TransportStdio Transport = "stdio"
+	TransportHTTP  Transport = "http"
+	TransportSSE   Transport = "sse"
+)
+
+// TargetInfo describes the upstream MCP server a call is proxied to.
+type TargetInfo struct {
+	// Name is the upstream server's name in mcpd's configuration.
+	Name string
+
+	// BaseURL is the server's base URL, or nil for stdio servers and when mcpd did not send one.
+	BaseURL *url.URL
+
+	// Transport is how mcpd reaches the server, or empty when unknown.
+	Transport Transport
+}
+
+type targetKey struct{}
+
+// Target returns the upstream target mcpd attached to the current call. When mcpd sent no name,
+// Name falls back to the upstream resolved by WithUpstreams. The boolean reports whether any
+// target information is available.
+//
+// Usage:
+//
+//	if t, ok := mcpdpluginsv1.Target(ctx); ok && t.Transport == mcpdpluginsv1.TransportStdio {
+//	    // Local server: skip network-only checks.
+//	}
+func Target(ctx context.Context) (TargetInfo, bool) {
+	t, ok := ctx.Value(targetKey{}).(TargetInfo)
+	if t.Name == "" {
+		if u := Upstream(ctx); u != "" {
+			t.Name, ok = u, true
+		}
+	}
+
+	return t, ok
+}
+
+// ContextWithTarget returns a copy of ctx carrying t, for use in tests and custom dispatch.
+func ContextWithTarget(ctx context.Context, t TargetInfo) context.Context {
+	return context.WithValue(ctx, targetKey{}, t)
+}
+
+// UpstreamFromTarget resolves the upstream from the target name sent by mcpd, for use with
+// WithUpstreams.
+func UpstreamFromTarget() UpstreamResolver {
+	return func(ctx context.Context, _ string, _ map[string]string) string {
+		t, _ := ctx.Value(targetKey{}).(TargetInfo)
+		return t.Name
+	}
+}
+
+// targetInterceptor stores the target information found in request metadata in the context.
+func targetInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if t, ok := targetFromMetadata(md); ok {
+				ctx = ContextWithTarget(ctx, t)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func targetFromMetadata(md metadata.MD) (TargetInfo, bool) {
+	first := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return strings.TrimSpace(v[0])
+		}
+		return ""
+	}
+
+	t := TargetInfo{
+		Name:      first(TargetNameMetadataKey),
+		Transport: Transport(strings.ToLower(first(TargetTransportMetadataKey))),
+	}
+	if raw := first(TargetURLMetadataKey); raw != "" {
+		if u, err := url.Parse(raw); err == nil && u.IsAbs() {
+			t.BaseURL = u
+		}
+	}
+
+	return t, t.Name != "" || t.BaseURL != nil || t.Transport != ""
+}
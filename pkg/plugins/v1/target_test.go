@@ -0,0 +1,154 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestTargetInterceptor(t *testing.T) {
+	tests := []struct {
+		name    string
+		md      metadata.MD // nil sends no metadata.
+		want    TargetInfo
+		wantURL string
+		wantOK  bool
+	}{
+		{name: "no metadata"},
+		{name: "unrelated metadata", md: metadata.Pairs("x-request-id", "r1")},
+		{
+			name: "full target",
+			md: metadata.Pairs(
+				TargetNameMetadataKey, "github",
+				TargetURLMetadataKey, "https://mcp.example.com/v1",
+				TargetTransportMetadataKey, "SSE",
+			),
+			want:    TargetInfo{Name: "github", Transport: TransportSSE},
+			wantURL: "https://mcp.example.com/v1",
+			wantOK:  true,
+		},
+		{
+			name:   "stdio without URL",
+			md:     metadata.Pairs(TargetNameMetadataKey, " fs ", TargetTransportMetadataKey, "stdio"),
+			want:   TargetInfo{Name: "fs", Transport: TransportStdio},
+			wantOK: true,
+		},
+		{
+			name:   "relative URL ignored",
+			md:     metadata.Pairs(TargetNameMetadataKey, "github", TargetURLMetadataKey, "/v1"),
+			want:   TargetInfo{Name: "github"},
+			wantOK: true,
+		},
+		{
+			name:   "malformed URL ignored",
+			md:     metadata.Pairs(TargetURLMetadataKey, "http://[::1"),
+			wantOK: false,
+		},
+		{
+			name:    "URL only",
+			md:      metadata.Pairs(TargetURLMetadataKey, "http://localhost:8080"),
+			wantURL: "http://localhost:8080",
+			wantOK:  true,
+		},
+		{
+			name:   "first value wins",
+			md:     metadata.Pairs(TargetNameMetadataKey, "a", TargetNameMetadataKey, "b"),
+			want:   TargetInfo{Name: "a"},
+			wantOK: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+
+			var (
+				got TargetInfo
+				ok  bool
+			)
+			handler := func(ctx context.Context, _ any) (any, error) {
+				got, ok = Target(ctx)
+				return nil, nil
+			}
+			if _, err := targetInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, handler); err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK {
+				t.Errorf("Target ok = %v, want %v", ok, tt.wantOK)
+			}
+			gotURL := ""
+			if got.BaseURL != nil {
+				gotURL = got.BaseURL.String()
+			}
+			if gotURL != tt.wantURL {
+				t.Errorf("BaseURL = %q, want %q", gotURL, tt.wantURL)
+			}
+			got.BaseURL = nil
+			if got != tt.want {
+				t.Errorf("Target = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTargetUpstreamFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   *TargetInfo
+		upstream string
+		want     TargetInfo
+		wantOK   bool
+	}{
+		{name: "nothing"},
+		{name: "upstream only", upstream: "github", want: TargetInfo{Name: "github"}, wantOK: true},
+		{
+			name:     "target name preferred",
+			target:   &TargetInfo{Name: "fs", Transport: TransportStdio},
+			upstream: "github",
+			want:     TargetInfo{Name: "fs", Transport: TransportStdio},
+			wantOK:   true,
+		},
+		{
+			name:     "unnamed target takes the upstream",
+			target:   &TargetInfo{Transport: TransportHTTP},
+			upstream: "github",
+			want:     TargetInfo{Name: "github", Transport: TransportHTTP},
+			wantOK:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.target != nil {
+				ctx = ContextWithTarget(ctx, *tt.target)
+			}
+			if tt.upstream != "" {
+				ctx = ContextWithUpstream(ctx, tt.upstream)
+			}
+
+			got, ok := Target(ctx)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Target = %+v, %v; want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestUpstreamFromTarget(t *testing.T) {
+	resolve := UpstreamFromTarget()
+	if got := resolve(context.Background(), "/mcp", nil); got != "" {
+		t.Errorf("resolved %q without a target", got)
+	}
+	ctx := ContextWithTarget(context.Background(), TargetInfo{Name: "github"})
+	if got := resolve(ctx, "/mcp", nil); got != "github" {
+		t.Errorf("resolved %q, want github", got)
+	}
+	// The resolver reads the target itself, not the upstream falling back to it.
+	if got := resolve(ContextWithUpstream(context.Background(), "fs"), "/mcp", nil); got != "" {
+		t.Errorf("resolved %q from the upstream", got)
+	}
+}
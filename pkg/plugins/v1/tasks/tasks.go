@@ -0,0 +1,277 @@
+// Package tasks runs a plugin's background jobs, periodic or delayed, with jitter and panic
+// recovery, and cancels them all when the plugin stops, so no goroutine outlives the server.
+//
+//	sched := tasks.New()
+//	_ = sched.Every("refresh-policy", time.Minute, p.refreshPolicy, tasks.WithJitter(10*time.Second))
+//
+//	err := mcpdpluginsv1.Serve(p, sched.StopOnShutdown())
+//
+// Plugins that are stopped by mcpd through the Stop RPC should also call Scheduler.Stop from
+// their Stop method. Combined with the leader package, WithCondition(elector.IsLeader) runs a job
+// on one replica only.
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/rng"
+)
+
+// ErrStopped is returned when scheduling a job on a stopped Scheduler.
+var ErrStopped = errors.New("scheduler is stopped")
+
+// stopTimeout bounds how long StopOnShutdown waits for running jobs to return.
+const stopTimeout = 10 * time.Second
+
+// Job is a unit of background work. ctx is cancelled when the Scheduler stops, and jobs must
+// return promptly once it is.
+type Job func(ctx context.Context) error
+
+// ErrorHandler is called with the name of a job and the error it returned or the panic it raised.
+type ErrorHandler func(name string, err error)
+
+// Scheduler runs jobs on their own goroutines until stopped. It is safe for concurrent use.
+type Scheduler struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	onError ErrorHandler
+	rand    *rand.Rand
+
+	mu      sync.Mutex
+	stopped bool
+}
+
+// Option configures a Scheduler.
+type Option func(*Scheduler)
+
+// WithErrorHandler sets the handler for job errors and panics (defaults to logging them with
+// log.Default()).
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(s *Scheduler) {
+		if h != nil {
+			s.onError = h
+		}
+	}
+}
+
+// WithRandSource draws job jitter from src (defaults to rng.Default()), for example a seeded
+// rng.New source for reproducible test runs. A nil src keeps the default.
+func WithRandSource(src rand.Source) Option {
+	return func(s *Scheduler) {
+		if src != nil {
+			s.rand = rand.New(rng.Locked(src))
+		}
+	}
+}
+
+// New returns a running Scheduler.
+func New(opts ...Option) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		ctx:    ctx,
+		cancel: cancel,
+		rand:   rand.New(rng.Default()),
+		onError: func(name string, err error) {
+			log.Printf("tasks: job %s failed: %v", name, err)
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+type jobOptions struct {
+	jitter    time.Duration
+	delay     time.Duration
+	timeout   time.Duration
+	condition func() bool
+}
+
+// JobOption configures a scheduled job.
+type JobOption func(*jobOptions)
+
+// WithJitter adds a random delay in [0, d) before every run, so replicas started together do not
+// hit shared services at the same moment.
+func WithJitter(d time.Duration) JobOption {
+	return func(o *jobOptions) {
+		o.jitter = max(0, d)
+	}
+}
+
+// WithInitialDelay delays the first run of a periodic job (by default it runs immediately).
+func WithInitialDelay(d time.Duration) JobOption {
+	return func(o *jobOptions) {
+		o.delay = max(0, d)
+	}
+}
+
+// WithTimeout cancels each run's context after d.
+func WithTimeout(d time.Duration) JobOption {
+	return func(o *jobOptions) {
+		o.timeout = max(0, d)
+	}
+}
+
+// WithCondition skips runs for which cond returns false, such as leader.Elector.IsLeader.
+func WithCondition(cond func() bool) JobOption {
+	return func(o *jobOptions) {
+		o.condition = cond
+	}
+}
+
+// Every runs job immediately and then every interval, measured from the end of the previous run
+// so slow runs never overlap.
+func (s *Scheduler) Every(name string, interval time.Duration, job Job, opts ...JobOption) error {
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	o := newJobOptions(opts)
+
+	return s.start(job, func(ctx context.Context) {
+		wait := o.delay
+		for {
+			if !s.sleep(ctx, wait+s.jitter(o.jitter)) {
+				return
+			}
+			s.run(ctx, name, job, o)
+			wait = interval
+		}
+	})
+}
+
+// After runs job once after delay.
+func (s *Scheduler) After(name string, delay time.Duration, job Job, opts ...JobOption) error {
+	o := newJobOptions(opts)
+
+	return s.start(job, func(ctx context.Context) {
+		if s.sleep(ctx, max(0, delay)+s.jitter(o.jitter)) {
+			s.run(ctx, name, job, o)
+		}
+	})
+}
+
+// Go runs job once, now.
+func (s *Scheduler) Go(name string, job Job, opts ...JobOption) error {
+	return s.After(name, 0, job, opts...)
+}
+
+// Stop cancels every job's context and waits for running jobs to return, or for ctx to be done.
+// Scheduling fails with ErrStopped afterwards. Stop can be called more than once.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background jobs still running: %w", ctx.Err())
+	}
+}
+
+// StopOnShutdown returns a ServeOption stopping the Scheduler when Serve begins shutting down.
+func (s *Scheduler) StopOnShutdown() mcpdpluginsv1.ServeOption {
+	return mcpdpluginsv1.WithEventSubscriber(func(ctx context.Context, ev mcpdpluginsv1.Event) {
+		if ev.Phase != mcpdpluginsv1.PhaseStopping {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), stopTimeout)
+		defer cancel()
+		if err := s.Stop(ctx); err != nil {
+			log.Printf("tasks: %v", err)
+		}
+	}, mcpdpluginsv1.EventLifecycle)
+}
+
+func (s *Scheduler) start(job Job, loop func(ctx context.Context)) error {
+	if job == nil {
+		return fmt.Errorf("job cannot be nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return ErrStopped
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		loop(s.ctx)
+	}()
+
+	return nil
+}
+
+// run runs job once, reporting its error or panic.
+func (s *Scheduler) run(ctx context.Context, name string, job Job, o jobOptions) {
+	if o.condition != nil && !o.condition() {
+		return
+	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.onError(name, fmt.Errorf("panic: %v\n%s", r, debug.Stack()))
+		}
+	}()
+	if err := job(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		s.onError(name, err)
+	}
+}
+
+// sleep waits for d, reporting false if ctx is done first.
+func (s *Scheduler) sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func newJobOptions(opts []JobOption) jobOptions {
+	var o jobOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+func (s *Scheduler) jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+
+	return time.Duration(s.rand.Int64N(int64(d)))
+}
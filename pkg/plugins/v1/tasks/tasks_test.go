@@ -0,0 +1,548 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/rng"
+)
+
+// newScheduler returns a Scheduler stopped when t ends.
+func newScheduler(t *testing.T, opts ...Option) *Scheduler {
+	t.Helper()
+
+	s := New(opts...)
+	t.Cleanup(func() {
+		if err := s.Stop(context.Background()); err != nil {
+			t.Errorf("Stop: %v", err)
+		}
+	})
+
+	return s
+}
+
+// jitters returns the next n jitters of up to d drawn by s.
+func jitters(s *Scheduler, d time.Duration, n int) []time.Duration {
+	out := make([]time.Duration, n)
+	for i := range out {
+		out[i] = s.jitter(d)
+	}
+
+	return out
+}
+
+func TestJitterRandSource(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  uint64
+		equal bool
+	}{
+		{name: "same seed", a: 11, b: 11, equal: true},
+		{name: "different seeds", a: 11, b: 12, equal: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := newScheduler(t, WithRandSource(rng.New(tt.a))), newScheduler(t, WithRandSource(rng.New(tt.b)))
+
+			if got := slices.Equal(jitters(a, time.Second, 32), jitters(b, time.Second, 32)); got != tt.equal {
+				t.Errorf("jitters equal = %v, want %v", got, tt.equal)
+			}
+		})
+	}
+}
+
+func TestJitterBounds(t *testing.T) {
+	s := newScheduler(t, WithRandSource(nil))
+
+	for _, d := range []time.Duration{-time.Second, 0, 1, time.Millisecond, time.Hour} {
+		for _, j := range jitters(s, d, 32) {
+			if j < 0 || (d > 0 && j >= d) || (d <= 0 && j != 0) {
+				t.Errorf("jitter(%s) = %s, want it in [0, %s)", d, j, max(d, 0))
+			}
+		}
+	}
+}
+
+// serveEnv makes the test binary serve a plugin whose scheduler runs a job until shutdown.
+const serveEnv = "TASKS_TEST_SERVE"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(serveEnv) == "" {
+		os.Exit(m.Run())
+	}
+
+	s := New()
+	_ = s.Go("wait", func(ctx context.Context) error {
+		fmt.Println("job started")
+		<-ctx.Done()
+		fmt.Println("job stopped")
+		return ctx.Err()
+	})
+	if err := mcpdpluginsv1.Serve(&mcpdpluginsv1.BasePlugin{}, s.StopOnShutdown()); err != nil {
+		log.Fatal(err)
+	}
+	if err := s.Go("late", func(context.Context) error { return nil }); !errors.Is(err, ErrStopped) {
+		log.Fatalf("Go after shutdown = %v, want ErrStopped", err)
+	}
+	os.Exit(0)
+}
+
+// recorder is an ErrorHandler collecting what it is called with.
+type recorder struct {
+	mu   sync.Mutex
+	errs map[string][]error
+}
+
+func (r *recorder) handle(name string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.errs == nil {
+		r.errs = make(map[string][]error)
+	}
+	r.errs[name] = append(r.errs[name], err)
+}
+
+func (r *recorder) get(name string) []error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]error(nil), r.errs[name]...)
+}
+
+// counter is a Job counting its runs, optionally taking d to return.
+type counter struct {
+	runs    atomic.Int32
+	running atomic.Int32
+	overlap atomic.Bool
+	d       time.Duration
+}
+
+func (c *counter) job(ctx context.Context) error {
+	if c.running.Add(1) > 1 {
+		c.overlap.Store(true)
+	}
+	defer c.running.Add(-1)
+	c.runs.Add(1)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(c.d):
+	}
+
+	return nil
+}
+
+// waitFor polls cond until it holds, failing the test after five seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScheduleErrors(t *testing.T) {
+	job := func(context.Context) error { return nil }
+	stopped := New()
+	if err := stopped.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		schedule func(s *Scheduler) error
+		want     string
+	}{
+		{name: "zero interval", schedule: func(s *Scheduler) error { return s.Every("j", 0, job) }, want: "positive"},
+		{
+			name:     "negative interval",
+			schedule: func(s *Scheduler) error { return s.Every("j", -time.Second, job) },
+			want:     "positive",
+		},
+		{name: "nil periodic job", schedule: func(s *Scheduler) error { return s.Every("j", time.Second, nil) }},
+		{name: "nil delayed job", schedule: func(s *Scheduler) error { return s.After("j", time.Second, nil) }},
+		{name: "nil job", schedule: func(s *Scheduler) error { return s.Go("j", nil) }, want: "cannot be nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schedule(newScheduler(t))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+
+	for name, schedule := range map[string]func() error{
+		"Every": func() error { return stopped.Every("j", time.Second, job) },
+		"After": func() error { return stopped.After("j", time.Second, job) },
+		"Go":    func() error { return stopped.Go("j", job) },
+	} {
+		if err := schedule(); !errors.Is(err, ErrStopped) {
+			t.Errorf("%s on a stopped scheduler = %v, want ErrStopped", name, err)
+		}
+	}
+}
+
+func TestEvery(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []JobOption
+		firstRun time.Duration // Minimum delay before the first run.
+	}{
+		{name: "immediate"},
+		{
+			name:     "initial delay",
+			opts:     []JobOption{WithInitialDelay(50 * time.Millisecond)},
+			firstRun: 50 * time.Millisecond,
+		},
+		{name: "negative initial delay", opts: []JobOption{WithInitialDelay(-time.Hour)}},
+		{name: "jitter", opts: []JobOption{WithJitter(5 * time.Millisecond)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScheduler(t)
+			c := &counter{d: 10 * time.Millisecond}
+			start := time.Now()
+			var first atomic.Int64
+			job := func(ctx context.Context) error {
+				first.CompareAndSwap(0, int64(time.Since(start)))
+				return c.job(ctx)
+			}
+			if err := s.Every("tick", time.Millisecond, job, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+
+			waitFor(t, "three runs", func() bool { return c.runs.Load() >= 3 })
+			if got := time.Duration(first.Load()); got < tt.firstRun {
+				t.Errorf("first run after %s, want at least %s", got, tt.firstRun)
+			}
+			if c.overlap.Load() {
+				t.Error("runs overlapped")
+			}
+		})
+	}
+}
+
+func TestAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		delay time.Duration
+	}{
+		{name: "delayed", delay: 30 * time.Millisecond},
+		{name: "negative delay", delay: -time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScheduler(t)
+			ran := make(chan time.Duration, 2)
+			start := time.Now()
+			err := s.After("once", tt.delay, func(context.Context) error {
+				ran <- time.Since(start)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case got := <-ran:
+				if got < tt.delay {
+					t.Errorf("ran after %s, want at least %s", got, tt.delay)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("job never ran")
+			}
+			time.Sleep(tt.delay + 20*time.Millisecond)
+			if len(ran) != 0 {
+				t.Error("job ran more than once")
+			}
+		})
+	}
+}
+
+func TestJobErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+	tests := []struct {
+		name string
+		job  Job
+		want string // Empty when nothing is reported.
+	}{
+		{name: "success", job: func(context.Context) error { return nil }},
+		{name: "error", job: func(context.Context) error { return errBoom }, want: "boom"},
+		{
+			name: "wrapped error",
+			job:  func(context.Context) error { return fmt.Errorf("refresh: %w", errBoom) },
+			want: "refresh: boom",
+		},
+		{name: "cancellation", job: func(context.Context) error { return fmt.Errorf("stopped: %w", context.Canceled) }},
+		{name: "panic", job: func(context.Context) error { panic("boom") }, want: "panic: boom\ngoroutine "},
+		{name: "panic with error", job: func(context.Context) error { panic(errBoom) }, want: "panic: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r recorder
+			s := newScheduler(t, WithErrorHandler(r.handle))
+			done := make(chan struct{})
+			err := s.Go("job", func(ctx context.Context) error {
+				defer close(done)
+				return tt.job(ctx)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			<-done
+			if err := s.Stop(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			got := r.get("job")
+			if tt.want == "" {
+				if len(got) != 0 {
+					t.Errorf("reported %v, want nothing", got)
+				}
+				return
+			}
+			if len(got) != 1 || !strings.Contains(got[0].Error(), tt.want) {
+				t.Errorf("reported %v, want one error containing %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPanicKeepsSchedule(t *testing.T) {
+	var r recorder
+	s := newScheduler(t, WithErrorHandler(r.handle))
+	var runs atomic.Int32
+	err := s.Every("flaky", time.Millisecond, func(context.Context) error {
+		if runs.Add(1) == 1 {
+			panic("first run")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "a run after the panic", func() bool { return runs.Load() >= 2 })
+	if got := r.get("flaky"); len(got) == 0 || !strings.Contains(got[0].Error(), "panic: first run") {
+		t.Errorf("reported %v, want the panic", got)
+	}
+}
+
+func TestDefaultErrorHandler(t *testing.T) {
+	var logs syncBuffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	s := newScheduler(t, WithErrorHandler(nil))
+	done := make(chan struct{})
+	_ = s.Go("sync", func(context.Context) error {
+		defer close(done)
+		return errors.New("upstream down")
+	})
+	<-done
+	_ = s.Stop(context.Background())
+
+	if got := logs.String(); !strings.Contains(got, "tasks: job sync failed: upstream down") {
+		t.Errorf("logged %q, want the job error", got)
+	}
+}
+
+func TestWithCondition(t *testing.T) {
+	var leader atomic.Bool
+	s := newScheduler(t)
+	c := &counter{}
+	if err := s.Every("leader-only", time.Millisecond, c.job, WithCondition(leader.Load)); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if got := c.runs.Load(); got != 0 {
+		t.Fatalf("job ran %d times while the condition was false", got)
+	}
+	leader.Store(true)
+	waitFor(t, "a run once the condition holds", func() bool { return c.runs.Load() > 0 })
+}
+
+func TestWithTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    error
+	}{
+		{name: "timeout", timeout: 10 * time.Millisecond, want: context.DeadlineExceeded},
+		{name: "no timeout", timeout: 0},
+		{name: "negative timeout", timeout: -time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r recorder
+			s := newScheduler(t, WithErrorHandler(r.handle))
+			done := make(chan struct{})
+			err := s.Go("slow", func(ctx context.Context) error {
+				defer close(done)
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(100 * time.Millisecond):
+					return nil
+				}
+			}, WithTimeout(tt.timeout))
+			if err != nil {
+				t.Fatal(err)
+			}
+			<-done
+
+			got := r.get("slow")
+			switch {
+			case tt.want == nil && len(got) != 0:
+				t.Errorf("reported %v, want nothing", got)
+			case tt.want != nil && (len(got) != 1 || !errors.Is(got[0], tt.want)):
+				t.Errorf("reported %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStop(t *testing.T) {
+	var r recorder
+	s := New(WithErrorHandler(r.handle))
+	started := make(chan struct{})
+	var cancelled atomic.Bool
+	_ = s.Go("running", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		cancelled.Store(true)
+		return ctx.Err()
+	})
+	var delayed atomic.Bool
+	_ = s.After("pending", time.Hour, func(context.Context) error {
+		delayed.Store(true)
+		return nil
+	})
+	<-started
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !cancelled.Load() {
+		t.Error("Stop returned before the running job")
+	}
+	if delayed.Load() {
+		t.Error("pending job ran on Stop")
+	}
+	if got := r.get("running"); len(got) != 0 {
+		t.Errorf("reported %v for a cancelled job, want nothing", got)
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Errorf("second Stop: %v", err)
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	s := New()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	_ = s.Go("stubborn", func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := s.Stop(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "background jobs still running") {
+		t.Errorf("Stop error = %v, want a deadline error", err)
+	}
+
+	close(release)
+	if err := s.Stop(context.Background()); err != nil {
+		t.Errorf("Stop after the job returned: %v", err)
+	}
+}
+
+func TestStopOnShutdown(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unix socket paths are short, so the socket is not placed under t.TempDir.
+	dir, err := os.MkdirTemp("", "tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "plugin.sock")
+	var out syncBuffer
+	cmd := exec.Command(exe, "--address", sock, "--network", "unix")
+	cmd.Env = append(os.Environ(), serveEnv+"=1")
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	defer func() { _ = cmd.Process.Kill() }()
+
+	waitFor(t, "the plugin to serve", func() bool {
+		got := out.String()
+		return strings.Contains(got, "Plugin server listening") && strings.Contains(got, "job started")
+	})
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-exited:
+		if err != nil {
+			t.Fatalf("plugin exited with %v:\n%s", err, out.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("plugin did not exit after SIGTERM:\n%s", out.String())
+	}
+	if got := out.String(); !strings.Contains(got, "job stopped") {
+		t.Errorf("job was not cancelled on shutdown:\n%s", got)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
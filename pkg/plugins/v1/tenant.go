@@ -0,0 +1,163 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+)
+
+// TenantSectionPrefix prefixes custom_config keys that apply to a single tenant:
+// "tenants.<tenant>.<key>" overrides "<key>" for requests from that tenant.
+const TenantSectionPrefix = "tenants."
+
+// TenantResolver identifies the tenant (mcpd client group) a call belongs to from its HTTP headers.
+// It returns an empty string when the tenant is unknown, in which case the base configuration applies.
+type TenantResolver func(ctx context.Context, headers map[string]string) string
+
+// TenantFromHeader resolves the tenant from the value of the named header.
+func TenantFromHeader(name string) TenantResolver {
+	return func(_ context.Context, headers map[string]string) string {
+		return GetHeader(headers, name)
+	}
+}
+
+// TenantFromSession resolves the tenant by passing the MCP session ID (the Mcp-Session-Id header)
+// to lookup, e.g. to consult a session store populated at initialization.
+func TenantFromSession(lookup func(sessionID string) string) TenantResolver {
+	return func(_ context.Context, headers map[string]string) string {
+		id := GetHeader(headers, "Mcp-Session-Id")
+		if id == "" {
+			return ""
+		}
+		return lookup(id)
+	}
+}
+
+// WithTenancy makes Serve resolve the tenant of every HandleRequest and HandleResponse call with
+// resolve and expose it to handlers through Tenant and TenantConfig.
+func WithTenancy(resolve TenantResolver) ServeOption {
+	return func(o *serveOptions) error {
+		if resolve == nil {
+			return fmt.Errorf("tenant resolver cannot be nil")
+		}
+		o.interceptors = append(o.interceptors, tenantInterceptor(resolve))
+		return nil
+	}
+}
+
+type tenantKey struct{}
+
+// Tenant returns the tenant resolved for the current call, or an empty string.
+func Tenant(ctx context.Context) string {
+	t, _ := ctx.Value(tenantKey{}).(string)
+	return t
+}
+
+// ContextWithTenant returns a copy of ctx carrying tenant, for use in tests and custom dispatch.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+func tenantInterceptor(resolve TenantResolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var headers map[string]string
+		switch in := req.(type) {
+		case *HTTPRequest:
+			headers = in.GetHeaders()
+		case *HTTPResponse:
+			headers = in.GetHeaders()
+		default:
+			return handler(ctx, req)
+		}
+
+		if t := resolve(ctx, headers); t != "" {
+			ctx = ContextWithTenant(ctx, t)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// TenantConfig holds a plugin's decoded configuration for each tenant. Call Configure from the
+// plugin's Configure method and Get from handlers. It is safe for concurrent use.
+//
+// Usage:
+//
+//	type MyPlugin struct {
+//	    mcpdpluginsv1.BasePlugin
+//	    cfg mcpdpluginsv1.TenantConfig[Settings]
+//	}
+//
+//	func (p *MyPlugin) Configure(ctx context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+//	    if err := p.cfg.Configure(ctx, cfg); err != nil {
+//	        return nil, status.Error(codes.InvalidArgument, err.Error())
+//	    }
+//	    return &emptypb.Empty{}, nil
+//	}
+//
+//	func (p *MyPlugin) HandleRequest(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error) {
+//	    s := p.cfg.Get(ctx) // Settings for the caller's tenant.
+//	    // ...
+//	}
+type TenantConfig[T any] struct {
+	sections sectionedConfig[T]
+}
+
+// Configure decodes the base configuration and every "tenants.<tenant>." section of cfg with
+// DecodeConfig. The previous configuration is kept if any section fails to decode.
+func (c *TenantConfig[T]) Configure(ctx context.Context, cfg *PluginConfig) error {
+	return c.sections.configure(ctx, cfg, TenantSectionPrefix)
+}
+
+// Get returns the configuration for the tenant of the current call, falling back to the base
+// configuration. It returns nil before the first successful Configure.
+func (c *TenantConfig[T]) Get(ctx context.Context) *T {
+	return c.sections.get(Tenant(ctx))
+}
+
+// sectionedConfig decodes a base configuration and named sections of it.
+type sectionedConfig[T any] struct {
+	mu       sync.RWMutex
+	base     *T
+	sections map[string]*T
+}
+
+func (c *sectionedConfig[T]) configure(ctx context.Context, cfg *PluginConfig, prefix string) error {
+	baseValues, sectionValues := config.Sections(cfg.GetCustomConfig(), prefix)
+
+	base := new(T)
+	baseCfg := &PluginConfig{Telemetry: cfg.GetTelemetry(), CustomConfig: baseValues}
+	if err := DecodeConfig(ctx, baseCfg, base); err != nil {
+		return err
+	}
+	sections := make(map[string]*T, len(sectionValues))
+	for name, values := range sectionValues {
+		v := new(T)
+		sectionCfg := &PluginConfig{Telemetry: cfg.GetTelemetry(), CustomConfig: values}
+		if err := DecodeConfig(ctx, sectionCfg, v); err != nil {
+			return fmt.Errorf("section %s%s: %w", prefix, name, err)
+		}
+		sections[name] = v
+	}
+
+	c.mu.Lock()
+	c.base, c.sections = base, sections
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *sectionedConfig[T]) get(name string) *T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if v, ok := c.sections[name]; ok && name != "" {
+		return v
+	}
+
+	return c.base
+}
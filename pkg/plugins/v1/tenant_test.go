@@ -0,0 +1,164 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+func TestTenantResolvers(t *testing.T) {
+	sessions := map[string]string{"s1": "acme"}
+	fromSession := TenantFromSession(func(id string) string { return sessions[id] })
+	tests := []struct {
+		name    string
+		resolve TenantResolver
+		headers map[string]string
+		want    string
+	}{
+		{"header", TenantFromHeader("X-Tenant"), map[string]string{"x-tenant": "acme"}, "acme"},
+		{"missing header", TenantFromHeader("X-Tenant"), map[string]string{}, ""},
+		{"session", fromSession, map[string]string{"Mcp-Session-Id": "s1"}, "acme"},
+		{"unknown session", fromSession, map[string]string{"Mcp-Session-Id": "s2"}, ""},
+		{"no session", fromSession, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.resolve(context.Background(), tt.headers); got != tt.want {
+				t.Errorf("resolved %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantFromSessionSkipsLookupWithoutSession(t *testing.T) {
+	resolve := TenantFromSession(func(string) string {
+		t.Error("lookup called without a session ID")
+		return ""
+	})
+	resolve(context.Background(), map[string]string{})
+}
+
+func TestTenantInterceptor(t *testing.T) {
+	headers := map[string]string{"X-Tenant": "acme"}
+	tests := []struct {
+		name string
+		req  any
+		want string
+	}{
+		{"request", &HTTPRequest{Headers: headers}, "acme"},
+		{"response", &HTTPResponse{Headers: headers}, "acme"},
+		{"no tenant", &HTTPRequest{}, ""},
+		{"other RPCs", &PluginConfig{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(WithTenancy(TenantFromHeader("X-Tenant")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			_, err = chainInterceptors(o.interceptors)(context.Background(), tt.req, &grpc.UnaryServerInfo{},
+				func(ctx context.Context, _ any) (any, error) {
+					got = Tenant(ctx)
+					return nil, nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Tenant = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithTenancyNil(t *testing.T) {
+	if _, err := newServeOptions(WithTenancy(nil)); err == nil {
+		t.Error("WithTenancy accepted a nil resolver")
+	}
+}
+
+type tenantSettings struct {
+	Timeout string `default:"10s"`
+	Limit   int
+}
+
+func TestTenantConfig(t *testing.T) {
+	var c TenantConfig[tenantSettings]
+	ctx := context.Background()
+	if got := c.Get(ctx); got != nil {
+		t.Errorf("Get before Configure = %+v, want nil", got)
+	}
+
+	err := c.Configure(ctx, &PluginConfig{CustomConfig: map[string]string{
+		"limit":                "5",
+		"tenants.acme.timeout": "30s",
+		"tenants.beta.limit":   "1",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		tenant string
+		want   tenantSettings
+	}{
+		{"", tenantSettings{Timeout: "10s", Limit: 5}},
+		{"acme", tenantSettings{Timeout: "30s", Limit: 5}},
+		{"beta", tenantSettings{Timeout: "10s", Limit: 1}},
+		{"unknown", tenantSettings{Timeout: "10s", Limit: 5}},
+	}
+	for _, tt := range tests {
+		if got := c.Get(ContextWithTenant(ctx, tt.tenant)); got == nil || *got != tt.want {
+			t.Errorf("Get(%q) = %+v, want %+v", tt.tenant, got, tt.want)
+		}
+	}
+}
+
+func TestTenantConfigKeepsPreviousOnError(t *testing.T) {
+	var c TenantConfig[tenantSettings]
+	ctx := context.Background()
+	if err := c.Configure(ctx, &PluginConfig{CustomConfig: map[string]string{"limit": "5"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   string
+	}{
+		{"invalid base", map[string]string{"limit": "many"}, "config limit"},
+		{"invalid section", map[string]string{"tenants.acme.limit": "many"}, "section tenants.acme: config limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.Configure(ctx, &PluginConfig{CustomConfig: tt.values})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Configure error = %v, want it to contain %q", err, tt.want)
+			}
+			if got := c.Get(ContextWithTenant(ctx, "acme")); got == nil || got.Limit != 5 {
+				t.Errorf("Get after a failed Configure = %+v, want the previous configuration", got)
+			}
+		})
+	}
+}
+
+func TestTenantConfigConcurrent(t *testing.T) {
+	var c TenantConfig[tenantSettings]
+	ctx := ContextWithTenant(context.Background(), "acme")
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%2 == 0 {
+				_ = c.Configure(ctx, &PluginConfig{CustomConfig: map[string]string{"tenants.acme.limit": "1"}})
+				return
+			}
+			c.Get(ctx)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,60 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WithTerminationGrace makes shutdown Kubernetes-friendly: when shutdown begins, CheckReady (and
+// so the WithKubernetesProbes readiness endpoint) fails at once while the plugin keeps serving
+// for grace, giving endpoints and mcpd time to stop sending it new calls; only then are in-flight
+// calls drained and the server stopped. Rollouts behind mcpd then complete without errors, with
+// no sleep in a preStop hook.
+//
+// Keep grace, plus the time in-flight calls take to finish, below the pod's
+// terminationGracePeriodSeconds. A second SIGINT or SIGTERM during the grace window starts
+// draining at once.
+func WithTerminationGrace(grace time.Duration) ServeOption {
+	return func(o *serveOptions) error {
+		if grace <= 0 {
+			return fmt.Errorf("termination grace must be positive")
+		}
+		o.terminationGrace = grace
+		return nil
+	}
+}
+
+// awaitTermination fails readiness and waits out the termination grace, or until another signal
+// arrives on sigCh.
+func (o *serveOptions) awaitTermination(sigCh <-chan os.Signal) {
+	if o.terminationGrace <= 0 {
+		return
+	}
+	o.terminating.Store(true)
+	o.logger.Printf("Failing readiness, draining in %s...", o.terminationGrace)
+
+	t := time.NewTimer(o.terminationGrace)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-sigCh:
+		o.logger.Println("Second signal received, draining now...")
+	}
+}
+
+// terminationInterceptor fails CheckReady once shutdown has begun.
+func terminationInterceptor(o *serveOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if info.FullMethod == Plugin_CheckReady_FullMethodName && o.terminating.Load() {
+			return nil, status.Error(codes.Unavailable, "plugin is shutting down")
+		}
+
+		return handler(ctx, req)
+	}
+}
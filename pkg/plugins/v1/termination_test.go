@@ -0,0 +1,165 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/features"
+)
+
+func TestWithTerminationGrace(t *testing.T) {
+	tests := []struct {
+		name    string
+		grace   time.Duration
+		wantErr bool
+	}{
+		{name: "positive", grace: 10 * time.Second},
+		{name: "zero", wantErr: true},
+		{name: "negative", grace: -time.Second, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(WithTerminationGrace(tt.grace))
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "termination grace must be positive") {
+					t.Errorf("error = %v, want a positive grace required", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.terminationGrace != tt.grace {
+				t.Errorf("terminationGrace = %s, want %s", o.terminationGrace, tt.grace)
+			}
+		})
+	}
+}
+
+func TestAwaitTermination(t *testing.T) {
+	tests := []struct {
+		name            string
+		grace           time.Duration
+		signal          bool // Whether a second signal arrives during the grace.
+		wantTerminating bool
+		wantMin         time.Duration
+		wantMax         time.Duration
+		wantLog         string
+	}{
+		{name: "no grace", wantMax: time.Second},
+		{
+			name:            "grace elapses",
+			grace:           50 * time.Millisecond,
+			wantTerminating: true,
+			wantMin:         50 * time.Millisecond,
+			wantMax:         5 * time.Second,
+			wantLog:         "Failing readiness, draining in 50ms...",
+		},
+		{
+			name:            "second signal",
+			grace:           time.Hour,
+			signal:          true,
+			wantTerminating: true,
+			wantMax:         5 * time.Second,
+			wantLog:         "Second signal received, draining now...",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs syncBuffer
+			o, err := newServeOptions(WithLogger(log.New(&logs, "", 0)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			o.terminationGrace = tt.grace
+			sigCh := make(chan os.Signal, 1)
+			if tt.signal {
+				sigCh <- syscall.SIGTERM
+			}
+
+			start := time.Now()
+			o.awaitTermination(sigCh)
+			if d := time.Since(start); d < tt.wantMin || d > tt.wantMax {
+				t.Errorf("awaitTermination took %s, want between %s and %s", d, tt.wantMin, tt.wantMax)
+			}
+			if got := o.terminating.Load(); got != tt.wantTerminating {
+				t.Errorf("terminating = %t, want %t", got, tt.wantTerminating)
+			}
+			if !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("log = %q, want it to contain %q", logs.String(), tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestTerminationInterceptor(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		terminating bool
+		wantCode    codes.Code
+	}{
+		{name: "ready while serving", method: Plugin_CheckReady_FullMethodName},
+		{
+			name:        "ready while terminating",
+			method:      Plugin_CheckReady_FullMethodName,
+			terminating: true,
+			wantCode:    codes.Unavailable,
+		},
+		{name: "health while terminating", method: Plugin_CheckHealth_FullMethodName, terminating: true},
+		{name: "requests while terminating", method: Plugin_HandleRequest_FullMethodName, terminating: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(WithTerminationGrace(time.Second))
+			if err != nil {
+				t.Fatal(err)
+			}
+			o.terminating.Store(tt.terminating)
+			called := false
+			_, err = terminationInterceptor(o)(context.Background(), &HTTPRequest{},
+				&grpc.UnaryServerInfo{FullMethod: tt.method}, func(context.Context, any) (any, error) {
+					called = true
+					return &HTTPResponse{}, nil
+				})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("code = %s, want %s", got, tt.wantCode)
+			}
+			if called != (tt.wantCode == codes.OK) {
+				t.Errorf("handler called = %t, want %t", called, tt.wantCode == codes.OK)
+			}
+		})
+	}
+}
+
+func TestTerminationServeChain(t *testing.T) {
+	o, err := newServeOptions(WithTerminationGrace(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	impl := &probedPlugin{}
+	interceptors, err := o.unaryInterceptors(impl, nil, features.NewSet(SupportedFeatures()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Shutting down fails the readiness probe served by WithKubernetesProbes.
+	o.terminating.Store(true)
+	h := probeHandler(impl, chainInterceptors(interceptors), Plugin_CheckReady_FullMethodName, impl.CheckReady)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, ReadinessPath, nil))
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != "plugin is shutting down\n" {
+		t.Errorf("readiness = %d %q, want 503 plugin is shutting down", w.Code, w.Body.String())
+	}
+}
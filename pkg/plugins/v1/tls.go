@@ -0,0 +1,35 @@
+package mcpdpluginsv1
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// WithTLS makes Serve accept only TLS connections configured by cfg, typically for plugins listening
+// on tcp. Set cfg.ClientAuth to require mcpd to present a client certificate (mTLS), and
+// GetCertificate or GetConfigForClient to rotate certificates without restarting, as the spiffe
+// package does.
+func WithTLS(cfg *tls.Config) ServeOption {
+	return func(o *serveOptions) error {
+		if cfg == nil {
+			return fmt.Errorf("TLS config cannot be nil")
+		}
+		if len(cfg.Certificates) == 0 && cfg.GetCertificate == nil && cfg.GetConfigForClient == nil {
+			return fmt.Errorf("TLS config has no server certificate")
+		}
+		o.tls = cfg
+		return nil
+	}
+}
+
+// tlsServerOptions returns the gRPC server options installing the WithTLS credentials, if any.
+func (o *serveOptions) tlsServerOptions() []grpc.ServerOption {
+	if o.tls == nil {
+		return nil
+	}
+
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(o.tls))}
+}
@@ -0,0 +1,116 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestWithTLS(t *testing.T) {
+	cert := selfSignedCert(t)
+	tests := []struct {
+		name    string
+		cfg     *tls.Config
+		wantErr string
+	}{
+		{name: "nil", wantErr: "TLS config cannot be nil"},
+		{
+			name:    "no certificate",
+			cfg:     &tls.Config{ClientAuth: tls.RequireAnyClientCert},
+			wantErr: "no server certificate",
+		},
+		{name: "certificates", cfg: &tls.Config{Certificates: []tls.Certificate{cert}}},
+		{
+			name: "GetCertificate",
+			cfg: &tls.Config{
+				GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return &cert, nil },
+			},
+		},
+		{
+			name: "GetConfigForClient",
+			cfg: &tls.Config{
+				GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) { return nil, nil },
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(WithTLS(tt.cfg))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("WithTLS error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.tls != tt.cfg || len(o.tlsServerOptions()) != 1 {
+				t.Errorf("WithTLS did not install the config")
+			}
+		})
+	}
+
+	o, err := newServeOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts := o.tlsServerOptions(); opts != nil {
+		t.Errorf("tlsServerOptions without WithTLS = %v, want none", opts)
+	}
+}
+
+func TestTLSServerOptions(t *testing.T) {
+	cert := selfSignedCert(t)
+	o, err := newServeOptions(WithTLS(&tls.Config{Certificates: []tls.Certificate{cert}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := filepath.Join(t.TempDir(), "plugin.sock")
+	lis, err := net.Listen("unix", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(o.tlsServerOptions()...)
+	RegisterPluginServer(srv, &BasePlugin{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	tests := []struct {
+		name    string
+		creds   credentials.TransportCredentials
+		wantErr bool
+	}{
+		{
+			name: "tls",
+			//nolint:gosec // Self-signed test certificate.
+			creds: credentials.NewTLS(&tls.Config{InsecureSkipVerify: true}),
+		},
+		{name: "plaintext", creds: insecure.NewCredentials(), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := grpc.NewClient("unix://"+address, grpc.WithTransportCredentials(tt.creds))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = conn.Close() }()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			_, err = NewPluginClient(conn).CheckHealth(ctx, &emptypb.Empty{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckHealth error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package tokens
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+// SessionHeader is the HTTP header carrying the MCP session identifier.
+const SessionHeader = "Mcp-Session-Id"
+
+// pruneThreshold is the number of tracked keys above which expired entries are swept.
+const pruneThreshold = 1024
+
+// Action is what an Enforcer does with a response that exceeds the remaining budget.
+type Action int
+
+const (
+	// ActionReject short-circuits the response with a JSON-RPC error.
+	ActionReject Action = iota
+
+	// ActionTruncate cuts the response content down to the remaining budget.
+	ActionTruncate
+)
+
+// KeyFunc derives the budget key (session, client, tenant...) for a request.
+type KeyFunc func(req *mcpdpluginsv1.HTTPRequest) string
+
+// SessionKey keys budgets on the MCP session header, falling back to ClientKey.
+func SessionKey(req *mcpdpluginsv1.HTTPRequest) string {
+	if id := mcpdpluginsv1.GetHeader(req.GetHeaders(), SessionHeader); id != "" {
+		return "session:" + id
+	}
+
+	return ClientKey(req)
+}
+
+// ClientKey keys budgets on the client host taken from the request's remote address.
+func ClientKey(req *mcpdpluginsv1.HTTPRequest) string {
+	host, _, err := net.SplitHostPort(req.GetRemoteAddr())
+	if err != nil {
+		host = req.GetRemoteAddr()
+	}
+
+	return "client:" + host
+}
+
+// HeaderKey keys budgets on the value of the named request header.
+func HeaderKey(name string) KeyFunc {
+	return func(req *mcpdpluginsv1.HTTPRequest) string {
+		return "header:" + mcpdpluginsv1.GetHeader(req.GetHeaders(), name)
+	}
+}
+
+// Enforcer tracks token usage per key within a rolling window and enforces a limit.
+// It is safe for concurrent use.
+type Enforcer struct {
+	enc    Encoding
+	limit  int
+	window time.Duration
+	action Action
+	clock  clock.Clock
+
+	mu    sync.Mutex
+	usage map[string]*usage
+}
+
+type usage struct {
+	used  int
+	reset time.Time
+}
+
+// EnforcerOption configures an Enforcer.
+type EnforcerOption func(*Enforcer) error
+
+// WithWindow sets the period after which a key's usage resets. Zero (the default) never resets.
+func WithWindow(d time.Duration) EnforcerOption {
+	return func(e *Enforcer) error {
+		if d < 0 {
+			return fmt.Errorf("window cannot be negative")
+		}
+		e.window = d
+		return nil
+	}
+}
+
+// WithAction sets what happens to responses exceeding the remaining budget (defaults to ActionReject).
+func WithAction(a Action) EnforcerOption {
+	return func(e *Enforcer) error {
+		e.action = a
+		return nil
+	}
+}
+
+// WithClock sets the clock windows are measured by (defaults to clock.Real()).
+func WithClock(c clock.Clock) EnforcerOption {
+	return func(e *Enforcer) error {
+		if c == nil {
+			return fmt.Errorf("clock cannot be nil")
+		}
+		e.clock = c
+		return nil
+	}
+}
+
+// NewEnforcer returns an Enforcer allowing limit tokens per key, counted with enc.
+func NewEnforcer(enc Encoding, limit int, opts ...EnforcerOption) (*Enforcer, error) {
+	if enc == nil {
+		return nil, fmt.Errorf("encoding is required")
+	}
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+
+	e := &Enforcer{
+		enc:   enc,
+		limit: limit,
+		clock: clock.Real(),
+		usage: map[string]*usage{},
+	}
+	for _, opt := range opts {
+		if err := opt(e); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// Remaining returns the tokens still available to key in the current window.
+func (e *Enforcer) Remaining(key string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.limit - e.entry(key).used
+}
+
+// Charge records n tokens against key if they fit in the remaining budget.
+// It reports whether the charge was accepted and the budget left afterwards.
+func (e *Enforcer) Charge(key string, n int) (bool, int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	u := e.entry(key)
+	if u.used+n > e.limit {
+		return false, e.limit - u.used
+	}
+	u.used += n
+
+	return true, e.limit - u.used
+}
+
+// Reset clears the usage recorded for key.
+func (e *Enforcer) Reset(key string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.usage, key)
+}
+
+// HandleResponse charges the tokens carried by resp to key. If they exceed the remaining budget
+// the response is either rejected or truncated depending on the configured Action.
+// Responses that are not MCP messages are passed through unchanged and not charged.
+func (e *Enforcer) HandleResponse(key string, resp *mcpdpluginsv1.HTTPResponse) *mcpdpluginsv1.HTTPResponse {
+	passThrough := &mcpdpluginsv1.HTTPResponse{
+		Continue:   true,
+		StatusCode: resp.GetStatusCode(),
+		Headers:    resp.GetHeaders(),
+		Body:       resp.GetBody(),
+	}
+
+	n, err := CountMCP(e.enc, resp.GetBody())
+	if err != nil {
+		return passThrough
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	u := e.entry(key)
+	remaining := e.limit - u.used
+	if n <= remaining {
+		u.used += n
+		return passThrough
+	}
+
+	if e.action == ActionTruncate && remaining > 0 {
+		body, _, err := Truncate(e.enc, resp.GetBody(), remaining)
+		if err == nil {
+			u.used = e.limit
+			passThrough.Body = body
+			return passThrough
+		}
+	}
+
+	return &mcpdpluginsv1.HTTPResponse{
+		Continue:   false,
+		StatusCode: http.StatusTooManyRequests,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       budgetExceededBody(resp.GetBody(), n, remaining),
+	}
+}
+
+// entry returns the usage for key, resetting it if its window has elapsed. Callers must hold e.mu.
+func (e *Enforcer) entry(key string) *usage {
+	now := e.clock.Now()
+	u, ok := e.usage[key]
+	if !ok || (e.window > 0 && !now.Before(u.reset)) {
+		if !ok && e.window > 0 && len(e.usage) >= pruneThreshold {
+			e.prune(now)
+		}
+		u = &usage{reset: now.Add(e.window)}
+		e.usage[key] = u
+	}
+
+	return u
+}
+
+// prune drops entries whose window has elapsed. Callers must hold e.mu.
+func (e *Enforcer) prune(now time.Time) {
+	for k, u := range e.usage {
+		if !now.Before(u.reset) {
+			delete(e.usage, k)
+		}
+	}
+}
+
+// budgetExceededBody builds a JSON-RPC error echoing the id of the original response, if any.
+func budgetExceededBody(original []byte, requested int, remaining int) []byte {
+	data, _ := json.Marshal(map[string]int{"requested": requested, "remaining": remaining})
+
+	return mcp.ErrorResponse(
+		original,
+		&mcp.Error{Code: mcp.CodeServerError, Message: "token budget exceeded", Data: data},
+	)
+}
@@ -0,0 +1,225 @@
+package tokens_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/plugintest"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/tokens"
+)
+
+func TestKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  tokens.KeyFunc
+		req  *mcpdpluginsv1.HTTPRequest
+		want string
+	}{
+		{
+			name: "session header",
+			key:  tokens.SessionKey,
+			req: &mcpdpluginsv1.HTTPRequest{
+				Headers:    map[string]string{"mcp-session-id": "s1"},
+				RemoteAddr: "10.0.0.1:5",
+			},
+			want: "session:s1",
+		},
+		{
+			name: "session falls back to the client",
+			key:  tokens.SessionKey,
+			req:  &mcpdpluginsv1.HTTPRequest{RemoteAddr: "10.0.0.1:5"},
+			want: "client:10.0.0.1",
+		},
+		{
+			name: "client without a port",
+			key:  tokens.ClientKey,
+			req:  &mcpdpluginsv1.HTTPRequest{RemoteAddr: "10.0.0.1"},
+			want: "client:10.0.0.1",
+		},
+		{
+			name: "client with an IPv6 address",
+			key:  tokens.ClientKey,
+			req:  &mcpdpluginsv1.HTTPRequest{RemoteAddr: "[::1]:5"},
+			want: "client:::1",
+		},
+		{
+			name: "header",
+			key:  tokens.HeaderKey("X-Tenant"),
+			req:  &mcpdpluginsv1.HTTPRequest{Headers: map[string]string{"x-tenant": "acme"}},
+			want: "header:acme",
+		},
+		{
+			name: "missing header",
+			key:  tokens.HeaderKey("X-Tenant"),
+			req:  &mcpdpluginsv1.HTTPRequest{},
+			want: "header:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key(tt.req); got != tt.want {
+				t.Errorf("key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEnforcerErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		enc   tokens.Encoding
+		limit int
+		opts  []tokens.EnforcerOption
+	}{
+		{name: "nil encoding", limit: 1},
+		{name: "zero limit", enc: wordCount},
+		{name: "negative window", enc: wordCount, limit: 1, opts: []tokens.EnforcerOption{tokens.WithWindow(-1)}},
+		{name: "nil clock", enc: wordCount, limit: 1, opts: []tokens.EnforcerOption{tokens.WithClock(nil)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tokens.NewEnforcer(tt.enc, tt.limit, tt.opts...); err == nil {
+				t.Error("NewEnforcer succeeded")
+			}
+		})
+	}
+}
+
+func TestEnforcerCharge(t *testing.T) {
+	clk := plugintest.NewFakeClock(time.Time{})
+	e, err := tokens.NewEnforcer(wordCount, 10, tokens.WithWindow(time.Minute), tokens.WithClock(clk))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		advance       time.Duration
+		key           string
+		charge        int
+		wantOK        bool
+		wantRemaining int
+	}{
+		{key: "a", charge: 6, wantOK: true, wantRemaining: 4},
+		{key: "a", charge: 5, wantOK: false, wantRemaining: 4},
+		{key: "b", charge: 10, wantOK: true, wantRemaining: 0},
+		{key: "a", charge: 4, wantOK: true, wantRemaining: 0},
+		{advance: 59 * time.Second, key: "a", charge: 1, wantOK: false, wantRemaining: 0},
+		{advance: time.Second, key: "a", charge: 1, wantOK: true, wantRemaining: 9},
+	}
+	for i, s := range steps {
+		clk.Advance(s.advance)
+		if ok, remaining := e.Charge(s.key, s.charge); ok != s.wantOK || remaining != s.wantRemaining {
+			t.Errorf("step %d: Charge(%s, %d) = %v, %d; want %v, %d",
+				i, s.key, s.charge, ok, remaining, s.wantOK, s.wantRemaining)
+		}
+	}
+
+	e.Reset("b")
+	if got := e.Remaining("b"); got != 10 {
+		t.Errorf("Remaining after Reset = %d, want 10", got)
+	}
+}
+
+func TestEnforcerHandleResponse(t *testing.T) {
+	tests := []struct {
+		name          string
+		action        tokens.Action
+		used          int
+		body          string
+		wantContinue  bool
+		wantBody      string // Checked when the chain continues.
+		wantRemaining int
+	}{
+		{
+			name:          "within budget",
+			body:          toolResult("one two"),
+			wantContinue:  true,
+			wantBody:      toolResult("one two"),
+			wantRemaining: 3,
+		},
+		{
+			name:          "rejected over budget",
+			used:          3,
+			body:          toolResult("one two three"),
+			wantRemaining: 2,
+		},
+		{
+			name:          "truncated over budget",
+			action:        tokens.ActionTruncate,
+			used:          3,
+			body:          toolResult("one two three"),
+			wantContinue:  true,
+			wantBody:      toolResult("one two …[truncated]"),
+			wantRemaining: 0,
+		},
+		{
+			name:          "truncation rejects once the budget is spent",
+			action:        tokens.ActionTruncate,
+			used:          5,
+			body:          toolResult("one"),
+			wantRemaining: 0,
+		},
+		{
+			name:          "not MCP is passed through uncharged",
+			used:          5,
+			body:          "plain text",
+			wantContinue:  true,
+			wantBody:      "plain text",
+			wantRemaining: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := tokens.NewEnforcer(wordCount, 5, tokens.WithAction(tt.action))
+			if err != nil {
+				t.Fatal(err)
+			}
+			e.Charge("k", tt.used)
+
+			resp := &mcpdpluginsv1.HTTPResponse{
+				StatusCode: 200,
+				Headers:    map[string]string{"X-Upstream": "1"},
+				Body:       []byte(tt.body),
+			}
+			got := e.HandleResponse("k", resp)
+			if got.GetContinue() != tt.wantContinue {
+				t.Fatalf("Continue = %v, want %v", got.GetContinue(), tt.wantContinue)
+			}
+			if rem := e.Remaining("k"); rem != tt.wantRemaining {
+				t.Errorf("Remaining = %d, want %d", rem, tt.wantRemaining)
+			}
+			if tt.wantContinue {
+				if string(got.GetBody()) != tt.wantBody || got.GetStatusCode() != 200 ||
+					got.GetHeaders()["X-Upstream"] != "1" {
+					t.Errorf("HandleResponse = %v, want body %s with the upstream status and headers", got, tt.wantBody)
+				}
+				return
+			}
+			checkBudgetError(t, got, 5-tt.used)
+		})
+	}
+}
+
+// checkBudgetError checks resp is the JSON-RPC error answering toolResult's id when a budget with
+// remaining tokens left is exceeded.
+func checkBudgetError(t *testing.T, resp *mcpdpluginsv1.HTTPResponse, remaining int) {
+	t.Helper()
+
+	if resp.GetStatusCode() != 429 {
+		t.Errorf("StatusCode = %d, want 429", resp.GetStatusCode())
+	}
+	m, err := mcp.ParseOne(resp.GetBody())
+	if err != nil {
+		t.Fatalf("error body %s: %v", resp.GetBody(), err)
+	}
+	if string(m.ID) != "7" || m.Error == nil || m.Error.Code != mcp.CodeServerError {
+		t.Fatalf("error body = %s, want a server error answering id 7", resp.GetBody())
+	}
+	var data struct{ Requested, Remaining int }
+	if err := json.Unmarshal(m.Error.Data, &data); err != nil || data.Remaining != remaining || data.Requested == 0 {
+		t.Errorf("error data = %s, want %d tokens remaining", m.Error.Data, remaining)
+	}
+}
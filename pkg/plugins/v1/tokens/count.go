@@ -0,0 +1,75 @@
+package tokens
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/mcp"
+)
+
+// truncationMarker is appended to text cut short by Truncate.
+const truncationMarker = " …[truncated]"
+
+// CountText returns the total number of tokens across texts.
+func CountText(enc Encoding, texts ...string) int {
+	n := 0
+	for _, t := range texts {
+		n += enc.Count(t)
+	}
+
+	return n
+}
+
+// CountMCP returns the number of tokens in the user-visible content of the MCP message(s) in body.
+// See mcp.RewriteText for what counts as user-visible content.
+func CountMCP(enc Encoding, body []byte) (int, error) {
+	texts, err := mcp.Texts(body)
+	if err != nil {
+		return 0, err
+	}
+
+	return CountText(enc, texts...), nil
+}
+
+// Truncate cuts the user-visible content of the MCP message(s) in body so that it carries at most
+// maxTokens tokens. Content beyond the limit is dropped and the last kept text is marked as
+// truncated. The second return value reports whether anything was cut.
+func Truncate(enc Encoding, body []byte, maxTokens int) ([]byte, bool, error) {
+	if maxTokens < 0 {
+		maxTokens = 0
+	}
+
+	used := 0
+	return mcp.RewriteText(body, func(text string) string {
+		n := enc.Count(text)
+		if used+n <= maxTokens {
+			used += n
+			return text
+		}
+
+		remaining := maxTokens - used
+		used = maxTokens
+		if remaining <= 0 {
+			return ""
+		}
+
+		return strings.TrimRightFunc(truncateText(enc, text, remaining), unicode.IsSpace) + truncationMarker
+	})
+}
+
+// truncateText returns the longest rune prefix of text that fits within maxTokens.
+func truncateText(enc Encoding, text string, maxTokens int) string {
+	lo, hi := 0, utf8.RuneCountInString(text)
+	runes := []rune(text)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if enc.Count(string(runes[:mid])) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return string(runes[:lo])
+}
@@ -0,0 +1,106 @@
+package tokens_test
+
+import (
+	"testing"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/tokens"
+)
+
+// toolResult returns a tools/call result carrying one text content block per text, encoded as
+// re-encoding does.
+func toolResult(texts ...string) string {
+	body := `{"id":7,"jsonrpc":"2.0","result":{"content":[`
+	for i, text := range texts {
+		if i > 0 {
+			body += ","
+		}
+		body += `{"text":"` + text + `","type":"text"}`
+	}
+
+	return body + `]}}`
+}
+
+func TestCountMCP(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    int
+		wantErr bool
+	}{
+		{name: "content blocks", body: toolResult("one two", "three"), want: 3},
+		{name: "no content", body: `{"jsonrpc":"2.0","id":1,"result":{}}`, want: 0},
+		{name: "not JSON-RPC", body: `{"text":"one"}`, wantErr: true},
+		{name: "invalid JSON", body: `{`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tokens.CountMCP(wordCount, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CountMCP error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("CountMCP = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if got := tokens.CountText(wordCount, "a b", "", "c"); got != 3 {
+		t.Errorf("CountText = %d, want 3", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		max       int
+		want      string
+		wantTrunc bool
+		wantErr   bool
+	}{
+		{
+			name: "fits",
+			body: toolResult("one two", "three"),
+			max:  3,
+			want: toolResult("one two", "three"),
+		},
+		{
+			name:      "cuts the text crossing the limit and drops the rest",
+			body:      toolResult("one two", "three four five", "six"),
+			max:       3,
+			want:      toolResult("one two", "three …[truncated]", ""),
+			wantTrunc: true,
+		},
+		{
+			name:      "limit at a text boundary",
+			body:      toolResult("one two", "three"),
+			max:       2,
+			want:      toolResult("one two", ""),
+			wantTrunc: true,
+		},
+		{
+			name:      "negative limit drops everything",
+			body:      toolResult("one", "two"),
+			max:       -1,
+			want:      toolResult("", ""),
+			wantTrunc: true,
+		},
+		{
+			name:    "not JSON-RPC",
+			body:    `{"text":"one"}`,
+			want:    `{"text":"one"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated, err := tokens.Truncate(wordCount, []byte(tt.body), tt.max)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Truncate error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want || truncated != tt.wantTrunc {
+				t.Errorf("Truncate = %s, %v; want %s, %v", got, truncated, tt.want, tt.wantTrunc)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Package tokens estimates the number of model tokens carried by MCP messages and enforces
+// token budgets per session or per client, for cost-control plugins.
+//
+// Token counts are produced by an Encoding. The package ships heuristic encodings that need no
+// vocabulary files; exact tokenizers (e.g. a tiktoken port) can be plugged in with EncodingFunc
+// and Register.
+package tokens
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Encoding counts the tokens in a piece of text.
+type Encoding interface {
+	// Name identifies the encoding (e.g. "cl100k_base").
+	Name() string
+
+	// Count returns the number of tokens in text.
+	Count(text string) int
+}
+
+// EncodingFunc adapts a counting function to the Encoding interface.
+func EncodingFunc(name string, count func(text string) int) Encoding {
+	return &funcEncoding{name: name, count: count}
+}
+
+type funcEncoding struct {
+	name  string
+	count func(string) int
+}
+
+func (e *funcEncoding) Name() string {
+	return e.name
+}
+
+func (e *funcEncoding) Count(text string) int {
+	return e.count(text)
+}
+
+// Names of the built-in encodings.
+const (
+	// EncodingHeuristic approximates BPE tokenizers by splitting text into word pieces,
+	// digit groups and punctuation.
+	EncodingHeuristic = "heuristic"
+
+	// EncodingChars4 assumes one token per four bytes of UTF-8 text.
+	EncodingChars4 = "chars4"
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Encoding{
+		EncodingHeuristic: Heuristic(),
+		EncodingChars4:    CharRatio(EncodingChars4, 4),
+	}
+)
+
+// Register makes enc available through Lookup, replacing any encoding with the same name.
+func Register(enc Encoding) error {
+	if enc == nil || enc.Name() == "" {
+		return fmt.Errorf("encoding must be non-nil and named")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[enc.Name()] = enc
+
+	return nil
+}
+
+// Lookup returns the registered encoding with the given name.
+func Lookup(name string) (Encoding, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	enc, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown token encoding %q", name)
+	}
+
+	return enc, nil
+}
+
+// Encodings returns the names of all registered encodings, sorted.
+func Encodings() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for n := range registry {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// CharRatio returns an encoding that assumes one token per bytesPerToken bytes of text, rounded up.
+func CharRatio(name string, bytesPerToken float64) Encoding {
+	return EncodingFunc(name, func(text string) int {
+		if text == "" || bytesPerToken <= 0 {
+			return 0
+		}
+		return int(math.Ceil(float64(len(text)) / bytesPerToken))
+	})
+}
+
+// Heuristic returns an encoding that approximates common BPE tokenizers: letters are counted in
+// pieces of up to four characters, digits in groups of up to three, every other non-space
+// character as one token, and whitespace is free.
+func Heuristic() Encoding {
+	return EncodingFunc(EncodingHeuristic, heuristicCount)
+}
+
+func heuristicCount(text string) int {
+	const (
+		letterPiece = 4
+		digitPiece  = 3
+	)
+
+	n := 0
+	run, runKind := 0, 0 // runKind: 0 none, 1 letter, 2 digit.
+	flush := func() {
+		switch runKind {
+		case 1:
+			n += (run + letterPiece - 1) / letterPiece
+		case 2:
+			n += (run + digitPiece - 1) / digitPiece
+		}
+		run, runKind = 0, 0
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+
+		switch {
+		case unicode.IsLetter(r) && r < unicode.MaxLatin1:
+			if runKind != 1 {
+				flush()
+				runKind = 1
+			}
+			run++
+		case unicode.IsDigit(r):
+			if runKind != 2 {
+				flush()
+				runKind = 2
+			}
+			run++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			// Punctuation, symbols and non-Latin scripts: roughly one token per rune.
+			flush()
+			n++
+		}
+	}
+	flush()
+
+	return n
+}
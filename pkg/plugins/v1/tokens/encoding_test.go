@@ -0,0 +1,77 @@
+package tokens_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/tokens"
+)
+
+func TestBuiltinEncodings(t *testing.T) {
+	tests := []struct {
+		name string
+		enc  tokens.Encoding
+		text string
+		want int
+	}{
+		{name: "heuristic empty", enc: tokens.Heuristic(), text: "", want: 0},
+		{name: "heuristic words in pieces of four", enc: tokens.Heuristic(), text: "hello world", want: 4},
+		{name: "heuristic digits in groups of three", enc: tokens.Heuristic(), text: "12345", want: 2},
+		{name: "heuristic punctuation", enc: tokens.Heuristic(), text: "a,b!", want: 4},
+		{name: "heuristic whitespace is free", enc: tokens.Heuristic(), text: " \t\n ", want: 0},
+		{name: "heuristic non-Latin runes", enc: tokens.Heuristic(), text: "日本語", want: 3},
+		{name: "heuristic mixed runs", enc: tokens.Heuristic(), text: "abc123def", want: 3},
+		{name: "char ratio rounds up", enc: tokens.CharRatio("c", 4), text: "abcde", want: 2},
+		{name: "char ratio counts bytes", enc: tokens.CharRatio("c", 4), text: "ñññ", want: 2},
+		{name: "char ratio without a ratio", enc: tokens.CharRatio("c", 0), text: "abcde", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.enc.Count(tt.text); got != tt.want {
+				t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	words := tokens.EncodingFunc("test_words", func(s string) int { return len(strings.Fields(s)) })
+
+	tests := []struct {
+		name    string
+		enc     tokens.Encoding
+		wantErr bool
+	}{
+		{name: "nil encoding", enc: nil, wantErr: true},
+		{name: "unnamed encoding", enc: tokens.EncodingFunc("", utf8.RuneCountInString), wantErr: true},
+		{name: "named encoding", enc: words},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tokens.Register(tt.enc); (err != nil) != tt.wantErr {
+				t.Fatalf("Register error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	for _, name := range []string{tokens.EncodingHeuristic, tokens.EncodingChars4, "test_words"} {
+		enc, err := tokens.Lookup(name)
+		if err != nil {
+			t.Fatalf("Lookup(%q): %v", name, err)
+		}
+		if enc.Name() != name {
+			t.Errorf("Lookup(%q) returned %q", name, enc.Name())
+		}
+	}
+	if _, err := tokens.Lookup("missing"); err == nil {
+		t.Error("Lookup found an unregistered encoding")
+	}
+	if names := tokens.Encodings(); !slices.IsSorted(names) || !slices.Contains(names, "test_words") {
+		t.Errorf("Encodings = %q, want sorted names including test_words", names)
+	}
+}
+
+// wordCount is an encoding counting one token per whitespace-separated word.
+var wordCount = tokens.EncodingFunc("words", func(s string) int { return len(strings.Fields(s)) })
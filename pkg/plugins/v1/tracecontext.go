@@ -0,0 +1,101 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// TraceparentHeader is the W3C Trace Context header (and gRPC metadata key) carrying the trace
+// and parent span identifiers.
+const TraceparentHeader = "traceparent"
+
+// TraceContext identifies the trace a plugin call belongs to.
+type TraceContext struct {
+	// TraceID is the 32 character lowercase hex trace identifier.
+	TraceID string
+
+	// SpanID is the 16 character lowercase hex identifier of the parent span.
+	SpanID string
+
+	// Sampled reports whether the upstream tracer sampled the trace.
+	Sampled bool
+}
+
+// TraceContextFromContext extracts the W3C trace context propagated by mcpd through gRPC
+// metadata or, failing that, the traceparent header of req (which may be nil).
+func TraceContextFromContext(ctx context.Context, req *HTTPRequest) (TraceContext, bool) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, v := range md.Get(TraceparentHeader) {
+			if tc, ok := ParseTraceparent(v); ok {
+				return tc, true
+			}
+		}
+	}
+
+	if req != nil {
+		if tc, ok := ParseTraceparent(GetHeader(req.GetHeaders(), TraceparentHeader)); ok {
+			return tc, true
+		}
+	}
+
+	return TraceContext{}, false
+}
+
+// ParseTraceparent parses a W3C traceparent value ("00-<trace-id>-<span-id>-<flags>").
+// All-zero identifiers are rejected as the specification requires.
+func ParseTraceparent(v string) (TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return TraceContext{}, false
+	}
+
+	traceID, spanID, flags := parts[1], parts[2], parts[3]
+	if !isHex(traceID, 32) || !isHex(spanID, 16) || !isHex(flags, 2) {
+		return TraceContext{}, false
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(spanID, "0") == "" {
+		return TraceContext{}, false
+	}
+
+	return TraceContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: (hexNibble(flags[1]) & 1) == 1,
+	}, true
+}
+
+// Traceparent formats tc as a W3C traceparent value.
+func (tc TraceContext) Traceparent() string {
+	flags := "00"
+	if tc.Sampled {
+		flags = "01"
+	}
+
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + flags
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if hexNibble(s[i]) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hexNibble(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,136 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   TraceContext
+		wantOK bool
+	}{
+		{
+			name:   "sampled",
+			value:  "00-" + testTraceID + "-" + testSpanID + "-01",
+			want:   TraceContext{TraceID: testTraceID, SpanID: testSpanID, Sampled: true},
+			wantOK: true,
+		},
+		{
+			name:   "not sampled",
+			value:  "00-" + testTraceID + "-" + testSpanID + "-00",
+			want:   TraceContext{TraceID: testTraceID, SpanID: testSpanID},
+			wantOK: true,
+		},
+		{
+			name:   "other flags",
+			value:  "00-" + testTraceID + "-" + testSpanID + "-03",
+			want:   TraceContext{TraceID: testTraceID, SpanID: testSpanID, Sampled: true},
+			wantOK: true,
+		},
+		{
+			name:   "future version with extra fields",
+			value:  " 01-" + testTraceID + "-" + testSpanID + "-01-extra ",
+			want:   TraceContext{TraceID: testTraceID, SpanID: testSpanID, Sampled: true},
+			wantOK: true,
+		},
+		{name: "empty", value: ""},
+		{name: "missing flags", value: "00-" + testTraceID + "-" + testSpanID},
+		{name: "invalid version", value: "ff-" + testTraceID + "-" + testSpanID + "-01"},
+		{name: "long version", value: "000-" + testTraceID + "-" + testSpanID + "-01"},
+		{name: "short trace ID", value: "00-" + testTraceID[1:] + "-" + testSpanID + "-01"},
+		{name: "short span ID", value: "00-" + testTraceID + "-" + testSpanID[1:] + "-01"},
+		{name: "uppercase hex", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-" + testSpanID + "-01"},
+		{name: "non-hex flags", value: "00-" + testTraceID + "-" + testSpanID + "-0x"},
+		{name: "zero trace ID", value: "00-00000000000000000000000000000000-" + testSpanID + "-01"},
+		{name: "zero span ID", value: "00-" + testTraceID + "-0000000000000000-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseTraceparent(tt.value)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("ParseTraceparent(%q) = %+v, %v; want %+v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	for _, sampled := range []bool{true, false} {
+		tc := TraceContext{TraceID: testTraceID, SpanID: testSpanID, Sampled: sampled}
+		got, ok := ParseTraceparent(tc.Traceparent())
+		if !ok || got != tc {
+			t.Errorf("ParseTraceparent(%q) = %+v, %v; want %+v", tc.Traceparent(), got, ok, tc)
+		}
+	}
+}
+
+func TestTraceContextFromContext(t *testing.T) {
+	fromMetadata := TraceContext{TraceID: testTraceID, SpanID: testSpanID, Sampled: true}
+	fromHeader := TraceContext{TraceID: testTraceID, SpanID: "1111111111111111"}
+	withHeader := &HTTPRequest{Headers: map[string]string{"Traceparent": fromHeader.Traceparent()}}
+
+	tests := []struct {
+		name   string
+		md     metadata.MD
+		req    *HTTPRequest
+		want   TraceContext
+		wantOK bool
+	}{
+		{
+			name:   "metadata",
+			md:     metadata.Pairs(TraceparentHeader, fromMetadata.Traceparent()),
+			want:   fromMetadata,
+			wantOK: true,
+		},
+		{
+			name:   "metadata wins over the header",
+			md:     metadata.Pairs(TraceparentHeader, fromMetadata.Traceparent()),
+			req:    withHeader,
+			want:   fromMetadata,
+			wantOK: true,
+		},
+		{
+			name:   "first valid metadata value",
+			md:     metadata.Pairs(TraceparentHeader, "bogus", TraceparentHeader, fromMetadata.Traceparent()),
+			want:   fromMetadata,
+			wantOK: true,
+		},
+		{
+			name:   "invalid metadata falls back to the header",
+			md:     metadata.Pairs(TraceparentHeader, "bogus"),
+			req:    withHeader,
+			want:   fromHeader,
+			wantOK: true,
+		},
+		{
+			name:   "header only",
+			req:    withHeader,
+			want:   fromHeader,
+			wantOK: true,
+		},
+		{name: "nothing propagated", req: &HTTPRequest{}},
+		{name: "nil request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			got, ok := TraceContextFromContext(ctx, tt.req)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("TraceContextFromContext = %+v, %v; want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// path is a parsed dot path such as "params.arguments.query". Segments address object keys,
+// or array elements when numeric; "\." escapes a dot within a key.
+type path []string
+
+func parsePath(s string) (path, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var (
+		p   path
+		cur strings.Builder
+	)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '.':
+			cur.WriteByte('.')
+			i++
+		case c == '.':
+			if cur.Len() == 0 {
+				return nil, fmt.Errorf("invalid path %q: empty segment", s)
+			}
+			p = append(p, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	if cur.Len() == 0 {
+		return nil, fmt.Errorf("invalid path %q: empty segment", s)
+	}
+
+	return append(p, cur.String()), nil
+}
+
+// get returns the value at p within doc.
+func (p path) get(doc any) (any, bool) {
+	v := doc
+	for _, seg := range p {
+		switch c := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = c[seg]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, ok := index(seg, len(c))
+			if !ok {
+				return nil, false
+			}
+			v = c[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return v, true
+}
+
+// set stores value at p within doc, creating missing objects along the way. It reports false
+// when the path crosses a scalar or an array element that does not exist.
+func (p path) set(doc any, value any) bool {
+	if len(p) == 0 {
+		return false
+	}
+	parent := doc
+	for _, seg := range p[:len(p)-1] {
+		switch c := parent.(type) {
+		case map[string]any:
+			next, ok := c[seg]
+			if !ok || next == nil {
+				next = map[string]any{}
+				c[seg] = next
+			}
+			parent = next
+		case []any:
+			i, ok := index(seg, len(c))
+			if !ok {
+				return false
+			}
+			parent = c[i]
+		default:
+			return false
+		}
+	}
+
+	last := p[len(p)-1]
+	switch c := parent.(type) {
+	case map[string]any:
+		c[last] = value
+		return true
+	case []any:
+		i, ok := index(last, len(c))
+		if !ok {
+			return false
+		}
+		c[i] = value
+		return true
+	default:
+		return false
+	}
+}
+
+// remove deletes the object key at p within doc and reports whether it existed. Array elements
+// are not removed, so sibling indexes stay stable.
+func (p path) remove(doc any) bool {
+	if len(p) == 0 {
+		return false
+	}
+	parent, ok := p[:len(p)-1].get(doc)
+	if !ok {
+		return false
+	}
+	obj, ok := parent.(map[string]any)
+	if !ok {
+		return false
+	}
+	if _, ok := obj[p[len(p)-1]]; !ok {
+		return false
+	}
+	delete(obj, p[len(p)-1])
+
+	return true
+}
+
+func index(seg string, n int) (int, bool) {
+	i, err := strconv.Atoi(seg)
+	if err != nil || i < 0 || i >= n {
+		return 0, false
+	}
+
+	return i, true
+}
@@ -0,0 +1,514 @@
+// Package transform rewrites JSON request and response bodies with declarative steps from
+// custom_config, so simple payload-massaging plugins need no custom code.
+//
+// Each step is a section of custom_config under "transform.<name>.", run in order, then by name:
+//
+//	transform.tag.op:             set
+//	transform.tag.path:           params._meta.source
+//	transform.tag.value:          "gateway"
+//	transform.rename.op:          rename
+//	transform.rename.path:        params.arguments.q
+//	transform.rename.to:          params.arguments.query
+//	transform.region.op:          map
+//	transform.region.path:        params.arguments.region
+//	transform.region.mapping:     eu=eu-west-1,us=us-east-1
+//	transform.greeting.op:        template
+//	transform.greeting.path:      params.arguments.prompt
+//	transform.greeting.template:  Answer in {{ .params.arguments.lang | default "English" }}.
+//
+// Paths are dot separated, with numeric segments indexing arrays and "\." escaping a dot in a
+// key. Templates are Go text/templates over the decoded message, with the functions upper,
+// lower, trim, replace, join, json and default. A template step without a path replaces the
+// whole message with its output, which must then be JSON.
+//
+// Steps apply to each message of a JSON-RPC batch. Bodies that are not JSON pass through unchanged.
+package transform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"text/template"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+)
+
+// pluginVersion is the version Plugin reports in its metadata.
+const pluginVersion = "1.0.0"
+
+// SectionPrefix prefixes the custom_config keys of steps: "transform.<name>.<key>".
+const SectionPrefix = "transform."
+
+// Step operations.
+const (
+	OpSet      = "set"
+	OpRemove   = "remove"
+	OpRename   = "rename"
+	OpMap      = "map"
+	OpTemplate = "template"
+)
+
+// Flows a step applies to.
+const (
+	FlowRequest  = "request"
+	FlowResponse = "response"
+	FlowBoth     = "both"
+)
+
+// StepConfig is a step as written in custom_config.
+type StepConfig struct {
+	// Op is set, remove, rename, map or template.
+	Op string `config:"op"`
+
+	// Path is the value the step operates on.
+	Path string `config:"path"`
+
+	// To is the destination path of rename.
+	To string `config:"to"`
+
+	// Value is the value stored by set, parsed as JSON when valid and used as a string otherwise.
+	Value string `config:"value"`
+
+	// Mapping lists the from=to replacements of map, with values parsed like Value.
+	Mapping []string `config:"mapping"`
+
+	// Default replaces values that match no mapping entry; empty keeps them.
+	Default string `config:"default"`
+
+	// Template is the text/template rendered by template.
+	Template string `config:"template"`
+
+	// JSON parses the template output as JSON instead of storing it as a string.
+	JSON bool `config:"json" default:"false"`
+
+	// Flow is request, response or both.
+	Flow string `config:"flow" default:"request"`
+
+	// Methods restricts the step to messages with one of these JSON-RPC methods. Responses carry
+	// no method, so steps with methods only apply to requests and notifications.
+	Methods []string `config:"methods"`
+
+	// Order sorts steps, lowest first; steps of equal order run by name.
+	Order int `config:"order"`
+}
+
+// Step is a compiled transformation step.
+type Step struct {
+	Name string
+
+	cfg      StepConfig
+	path     path
+	to       path
+	value    any
+	mapping  map[string]any
+	fallback any
+	tmpl     *template.Template
+	methods  map[string]struct{}
+}
+
+// Compile validates and compiles sc as the step name.
+func Compile(name string, sc StepConfig) (*Step, error) {
+	s := &Step{Name: name, cfg: sc}
+
+	var err error
+	if s.path, err = parsePath(sc.Path); err != nil {
+		return nil, fmt.Errorf("step %s: %w", name, err)
+	}
+	switch sc.Flow {
+	case FlowRequest, FlowResponse, FlowBoth:
+	default:
+		return nil, fmt.Errorf("step %s: unknown flow %q", name, sc.Flow)
+	}
+	if len(s.path) == 0 && sc.Op != OpTemplate {
+		return nil, fmt.Errorf("step %s: path is required", name)
+	}
+
+	switch sc.Op {
+	case OpSet:
+		s.value = literal(sc.Value)
+	case OpRemove:
+	case OpRename:
+		if s.to, err = parsePath(sc.To); err != nil {
+			return nil, fmt.Errorf("step %s: %w", name, err)
+		}
+		if len(s.to) == 0 {
+			return nil, fmt.Errorf("step %s: to is required", name)
+		}
+	case OpMap:
+		if len(sc.Mapping) == 0 {
+			return nil, fmt.Errorf("step %s: mapping is required", name)
+		}
+		s.mapping = make(map[string]any, len(sc.Mapping))
+		for _, pair := range sc.Mapping {
+			from, to, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("step %s: invalid mapping %q: expected from=to", name, pair)
+			}
+			s.mapping[strings.TrimSpace(from)] = literal(strings.TrimSpace(to))
+		}
+		if sc.Default != "" {
+			s.fallback = literal(sc.Default)
+		}
+	case OpTemplate:
+		if sc.Template == "" {
+			return nil, fmt.Errorf("step %s: template is required", name)
+		}
+		if s.tmpl, err = template.New(name).Funcs(funcs).Parse(sc.Template); err != nil {
+			return nil, fmt.Errorf("step %s: invalid template: %w", name, err)
+		}
+	default:
+		return nil, fmt.Errorf("step %s: unknown op %q", name, sc.Op)
+	}
+
+	if len(sc.Methods) > 0 {
+		s.methods = make(map[string]struct{}, len(sc.Methods))
+		for _, m := range sc.Methods {
+			s.methods[m] = struct{}{}
+		}
+	}
+
+	return s, nil
+}
+
+// FromConfig compiles the steps defined under SectionPrefix in custom.
+func FromConfig(custom map[string]string) ([]*Step, error) {
+	// Only step keys are split, so top-level plugin keys never leak into step sections.
+	scoped := map[string]string{}
+	for k, v := range custom {
+		if strings.HasPrefix(k, SectionPrefix) {
+			scoped[k] = v
+		}
+	}
+	_, sections := config.Sections(scoped, SectionPrefix)
+
+	steps := make([]*Step, 0, len(sections))
+	for name, values := range sections {
+		var sc StepConfig
+		if _, err := config.Decode(values, &sc); err != nil {
+			return nil, fmt.Errorf("step %s: %w", name, err)
+		}
+		s, err := Compile(name, sc)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+	}
+
+	return steps, nil
+}
+
+// apply runs the step on msg, returning the (possibly replaced) message and whether it changed.
+func (s *Step) apply(msg any) (any, bool, error) {
+	if s.methods != nil {
+		obj, _ := msg.(map[string]any)
+		method, _ := obj["method"].(string)
+		if _, ok := s.methods[method]; !ok {
+			return msg, false, nil
+		}
+	}
+
+	switch s.cfg.Op {
+	case OpSet:
+		return msg, s.path.set(msg, clone(s.value)), nil
+	case OpRemove:
+		return msg, s.path.remove(msg), nil
+	case OpRename:
+		v, ok := s.path.get(msg)
+		if !ok || !s.to.set(msg, v) {
+			return msg, false, nil
+		}
+		s.path.remove(msg)
+		return msg, true, nil
+	case OpMap:
+		v, ok := s.path.get(msg)
+		if !ok {
+			return msg, false, nil
+		}
+		to, ok := s.mapping[scalar(v)]
+		if !ok {
+			if s.fallback == nil {
+				return msg, false, nil
+			}
+			to = s.fallback
+		}
+		return msg, s.path.set(msg, clone(to)), nil
+	default:
+		return s.render(msg)
+	}
+}
+
+func (s *Step) render(msg any) (any, bool, error) {
+	var b bytes.Buffer
+	if err := s.tmpl.Execute(&b, msg); err != nil {
+		return msg, false, fmt.Errorf("step %s: %w", s.Name, err)
+	}
+
+	var v any = b.String()
+	if s.cfg.JSON || len(s.path) == 0 {
+		var err error
+		if v, err = decode(b.Bytes()); err != nil {
+			return msg, false, fmt.Errorf("step %s: template output is not JSON: %w", s.Name, err)
+		}
+	}
+	if len(s.path) == 0 {
+		return v, true, nil
+	}
+
+	return msg, s.path.set(msg, v), nil
+}
+
+// Transformer applies steps to bodies. It is immutable and safe for concurrent use.
+type Transformer struct {
+	request  []*Step
+	response []*Step
+}
+
+// New returns a Transformer running steps by order, then name.
+func New(steps ...*Step) *Transformer {
+	sorted := append([]*Step(nil), steps...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].cfg.Order != sorted[j].cfg.Order {
+			return sorted[i].cfg.Order < sorted[j].cfg.Order
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	t := &Transformer{}
+	for _, s := range sorted {
+		if s.cfg.Flow != FlowResponse {
+			t.request = append(t.request, s)
+		}
+		if s.cfg.Flow != FlowRequest {
+			t.response = append(t.response, s)
+		}
+	}
+
+	return t
+}
+
+// Request applies the request steps to body. The second return value reports whether anything
+// changed; when it is false the original body is returned untouched.
+func (t *Transformer) Request(body []byte) ([]byte, bool, error) {
+	return run(t.request, body)
+}
+
+// Response applies the response steps to body, like Request.
+func (t *Transformer) Response(body []byte) ([]byte, bool, error) {
+	return run(t.response, body)
+}
+
+// HandleRequest transforms the body of req and returns a continuing response carrying the
+// modified request. Bodies that fail to transform are passed through unchanged.
+func (t *Transformer) HandleRequest(req *mcpdpluginsv1.HTTPRequest) *mcpdpluginsv1.HTTPResponse {
+	body, changed, err := t.Request(req.GetBody())
+	if err != nil || !changed {
+		return &mcpdpluginsv1.HTTPResponse{Continue: true}
+	}
+	modified := mcpdpluginsv1.CloneRequest(req)
+	modified.Body = body
+
+	return &mcpdpluginsv1.HTTPResponse{Continue: true, ModifiedRequest: modified}
+}
+
+// HandleResponse transforms the body of resp and returns the modified response.
+// Bodies that fail to transform are passed through unchanged.
+func (t *Transformer) HandleResponse(resp *mcpdpluginsv1.HTTPResponse) *mcpdpluginsv1.HTTPResponse {
+	out := &mcpdpluginsv1.HTTPResponse{
+		Continue:   true,
+		StatusCode: resp.GetStatusCode(),
+		Headers:    resp.GetHeaders(),
+		Body:       resp.GetBody(),
+	}
+	if body, changed, err := t.Response(resp.GetBody()); err == nil && changed {
+		out.Body = body
+	}
+
+	return out
+}
+
+func run(steps []*Step, body []byte) ([]byte, bool, error) {
+	if len(steps) == 0 || len(bytes.TrimSpace(body)) == 0 {
+		return body, false, nil
+	}
+	doc, err := decode(body)
+	if err != nil {
+		return body, false, nil
+	}
+
+	changed := false
+	applyAll := func(msg any) (any, error) {
+		for _, s := range steps {
+			out, ok, err := s.apply(msg)
+			if err != nil {
+				return nil, err
+			}
+			msg, changed = out, changed || ok
+		}
+		return msg, nil
+	}
+	if batch, ok := doc.([]any); ok {
+		for i, msg := range batch {
+			if batch[i], err = applyAll(msg); err != nil {
+				return body, false, err
+			}
+		}
+	} else if doc, err = applyAll(doc); err != nil {
+		return body, false, err
+	}
+	if !changed {
+		return body, false, nil
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return body, false, fmt.Errorf("failed to encode body: %w", err)
+	}
+
+	return out, true, nil
+}
+
+// decode parses JSON keeping numbers exact.
+func decode(data []byte) (any, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+
+	return v, nil
+}
+
+// literal parses s as JSON, falling back to the string itself.
+func literal(s string) any {
+	if v, err := decode([]byte(s)); err == nil {
+		return v
+	}
+
+	return s
+}
+
+// clone deep-copies configured values so messages never share them.
+func clone(v any) any {
+	switch c := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(c))
+		for k, e := range c {
+			out[k] = clone(e)
+		}
+		return out
+	case []any:
+		out := make([]any, len(c))
+		for i, e := range c {
+			out[i] = clone(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// scalar formats v for mapping lookups.
+func scalar(v any) string {
+	switch c := v.(type) {
+	case string:
+		return c
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(c)
+	}
+}
+
+var funcs = template.FuncMap{
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"trim":    strings.TrimSpace,
+	"replace": func(old, replacement, s string) string { return strings.ReplaceAll(s, old, replacement) },
+	"join": func(sep string, v []any) string {
+		parts := make([]string, len(v))
+		for i, e := range v {
+			parts[i] = scalar(e)
+		}
+		return strings.Join(parts, sep)
+	},
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"default": func(fallback, v any) any {
+		if v == nil || v == "" {
+			return fallback
+		}
+		return v
+	},
+}
+
+// Plugin is a request- and response-flow plugin applying the steps of its custom_config. It
+// changes nothing until configured.
+type Plugin struct {
+	mcpdpluginsv1.BasePlugin
+
+	transformer atomic.Pointer[Transformer]
+}
+
+// NewPlugin returns an unconfigured Plugin.
+func NewPlugin() *Plugin {
+	p := &Plugin{}
+	p.transformer.Store(New())
+
+	return p
+}
+
+// GetMetadata implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetMetadata(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Metadata, error) {
+	return &mcpdpluginsv1.Metadata{
+		Name:        "transform",
+		Version:     pluginVersion,
+		Description: "Rewrites JSON bodies with declarative transformation steps.",
+	}, nil
+}
+
+// GetCapabilities implements mcpdpluginsv1.PluginServer.
+func (p *Plugin) GetCapabilities(context.Context, *emptypb.Empty) (*mcpdpluginsv1.Capabilities, error) {
+	return mcpdpluginsv1.NewCapabilities(mcpdpluginsv1.FlowRequest, mcpdpluginsv1.FlowResponse), nil
+}
+
+// Configure compiles the steps of cfg's custom_config, keeping the previous steps on error.
+func (p *Plugin) Configure(_ context.Context, cfg *mcpdpluginsv1.PluginConfig) (*emptypb.Empty, error) {
+	steps, err := FromConfig(cfg.GetCustomConfig())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	p.transformer.Store(New(steps...))
+
+	return &emptypb.Empty{}, nil
+}
+
+// HandleRequest applies the request steps.
+func (p *Plugin) HandleRequest(
+	_ context.Context,
+	req *mcpdpluginsv1.HTTPRequest,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.transformer.Load().HandleRequest(req), nil
+}
+
+// HandleResponse applies the response steps.
+func (p *Plugin) HandleResponse(
+	_ context.Context,
+	resp *mcpdpluginsv1.HTTPResponse,
+) (*mcpdpluginsv1.HTTPResponse, error) {
+	return p.transformer.Load().HandleResponse(resp), nil
+}
@@ -0,0 +1,479 @@
+package transform_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/transform"
+)
+
+const toolCall = `{"jsonrpc":"2.0","id":1,"method":"tools/call",` +
+	`"params":{"name":"search","arguments":{"q":"llamas","region":"eu","tags":["a","b"],"n":12345678901234567890}}}`
+
+func compile(t *testing.T, name string, sc transform.StepConfig) *transform.Step {
+	t.Helper()
+
+	if sc.Flow == "" {
+		sc.Flow = transform.FlowRequest
+	}
+	s, err := transform.Compile(name, sc)
+	if err != nil {
+		t.Fatalf("Compile(%s): %v", name, err)
+	}
+
+	return s
+}
+
+// equalJSON reports whether a and b encode the same JSON value.
+func equalJSON(t *testing.T, a, b []byte) bool {
+	t.Helper()
+
+	var x, y any
+	if err := json.Unmarshal(a, &x); err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &y); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+
+	return reflect.DeepEqual(x, y)
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sc   transform.StepConfig
+		want string
+	}{
+		{name: "unknown op", sc: transform.StepConfig{Op: "merge", Path: "a"}, want: `unknown op "merge"`},
+		{
+			name: "unknown flow",
+			sc:   transform.StepConfig{Op: "remove", Path: "a", Flow: "sideways"},
+			want: `unknown flow "sideways"`,
+		},
+		{name: "no path", sc: transform.StepConfig{Op: "set", Value: "1"}, want: "path is required"},
+		{name: "empty segment", sc: transform.StepConfig{Op: "remove", Path: "a..b"}, want: "empty segment"},
+		{name: "leading dot", sc: transform.StepConfig{Op: "remove", Path: ".a"}, want: "empty segment"},
+		{name: "trailing dot", sc: transform.StepConfig{Op: "remove", Path: "a."}, want: "empty segment"},
+		{name: "rename without to", sc: transform.StepConfig{Op: "rename", Path: "a"}, want: "to is required"},
+		{name: "invalid to", sc: transform.StepConfig{Op: "rename", Path: "a", To: "b."}, want: "empty segment"},
+		{name: "map without mapping", sc: transform.StepConfig{Op: "map", Path: "a"}, want: "mapping is required"},
+		{
+			name: "mapping without =",
+			sc:   transform.StepConfig{Op: "map", Path: "a", Mapping: []string{"eu"}},
+			want: `invalid mapping "eu": expected from=to`,
+		},
+		{name: "template missing", sc: transform.StepConfig{Op: "template", Path: "a"}, want: "template is required"},
+		{
+			name: "template invalid",
+			sc:   transform.StepConfig{Op: "template", Template: "{{ .a"},
+			want: "invalid template",
+		},
+		{
+			name: "template unknown function",
+			sc:   transform.StepConfig{Op: "template", Template: "{{ shout .a }}"},
+			want: "invalid template",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.sc.Flow == "" {
+				tt.sc.Flow = transform.FlowRequest
+			}
+			_, err := transform.Compile("s1", tt.sc)
+			if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.HasPrefix(err.Error(), "step s1: ") {
+				t.Errorf("Compile error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSteps(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []transform.StepConfig
+		body  string
+		want  string // Expected body; empty when nothing changes.
+	}{
+		{
+			name:  "set a string",
+			steps: []transform.StepConfig{{Op: "set", Path: "params._meta.source", Value: "gateway"}},
+			body:  `{"params":{}}`,
+			want:  `{"params":{"_meta":{"source":"gateway"}}}`,
+		},
+		{
+			name:  "set JSON",
+			steps: []transform.StepConfig{{Op: "set", Path: "params.limit", Value: `{"max":5}`}},
+			body:  `{"params":null}`,
+			want:  `{"params":{"limit":{"max":5}}}`,
+		},
+		{
+			name:  "set an array element",
+			steps: []transform.StepConfig{{Op: "set", Path: "tags.1", Value: `"z"`}},
+			body:  `{"tags":["a","b"]}`,
+			want:  `{"tags":["a","z"]}`,
+		},
+		{
+			name:  "set past the end of an array",
+			steps: []transform.StepConfig{{Op: "set", Path: "tags.2", Value: "z"}},
+			body:  `{"tags":["a","b"]}`,
+		},
+		{
+			name:  "set through a scalar",
+			steps: []transform.StepConfig{{Op: "set", Path: "id.x", Value: "z"}},
+			body:  `{"id":1}`,
+		},
+		{
+			name:  "set an escaped key",
+			steps: []transform.StepConfig{{Op: "set", Path: `headers.x\.trace`, Value: "on"}},
+			body:  `{"headers":{}}`,
+			want:  `{"headers":{"x.trace":"on"}}`,
+		},
+		{
+			name:  "remove",
+			steps: []transform.StepConfig{{Op: "remove", Path: "params.arguments.secret"}},
+			body:  `{"params":{"arguments":{"secret":"s","q":"x"}}}`,
+			want:  `{"params":{"arguments":{"q":"x"}}}`,
+		},
+		{
+			name:  "remove a missing key",
+			steps: []transform.StepConfig{{Op: "remove", Path: "params.secret"}},
+			body:  `{"params":{}}`,
+		},
+		{
+			name:  "remove keeps array elements",
+			steps: []transform.StepConfig{{Op: "remove", Path: "tags.0"}},
+			body:  `{"tags":["a"]}`,
+		},
+		{
+			name:  "rename",
+			steps: []transform.StepConfig{{Op: "rename", Path: "params.arguments.q", To: "params.arguments.query"}},
+			body:  `{"params":{"arguments":{"q":"x"}}}`,
+			want:  `{"params":{"arguments":{"query":"x"}}}`,
+		},
+		{
+			name:  "rename a missing key",
+			steps: []transform.StepConfig{{Op: "rename", Path: "params.q", To: "params.query"}},
+			body:  `{"params":{}}`,
+		},
+		{
+			name:  "rename onto a scalar keeps the source",
+			steps: []transform.StepConfig{{Op: "rename", Path: "q", To: "id.q"}},
+			body:  `{"id":1,"q":"x"}`,
+		},
+		{
+			name: "map",
+			steps: []transform.StepConfig{
+				{Op: "map", Path: "region", Mapping: []string{"eu = eu-west-1", "us=us-east-1"}},
+			},
+			body: `{"region":"eu"}`,
+			want: `{"region":"eu-west-1"}`,
+		},
+		{
+			name:  "map numbers and null",
+			steps: []transform.StepConfig{{Op: "map", Path: "level", Mapping: []string{"1=low", "null=0"}}},
+			body:  `[{"level":1},{"level":null}]`,
+			want:  `[{"level":"low"},{"level":0}]`,
+		},
+		{
+			name:  "map without a match",
+			steps: []transform.StepConfig{{Op: "map", Path: "region", Mapping: []string{"eu=eu-west-1"}}},
+			body:  `{"region":"ap"}`,
+		},
+		{
+			name: "map default",
+			steps: []transform.StepConfig{
+				{Op: "map", Path: "region", Mapping: []string{"eu=eu-west-1"}, Default: "us-east-1"},
+			},
+			body: `{"region":"ap"}`,
+			want: `{"region":"us-east-1"}`,
+		},
+		{
+			name: "template",
+			steps: []transform.StepConfig{{
+				Op:   "template",
+				Path: "prompt",
+				Template: `{{ .lang | default "English" | upper }}: ` +
+					`{{ join "," .tags }} {{ replace "a" "o" .q | trim }}`,
+			}},
+			body: `{"tags":["a",1],"q":" llama "}`,
+			want: `{"tags":["a",1],"q":" llama ","prompt":"ENGLISH: a,1 llomo"}`,
+		},
+		{
+			name:  "template as JSON",
+			steps: []transform.StepConfig{{Op: "template", Path: "copy", Template: "{{ json .tags }}", JSON: true}},
+			body:  `{"tags":["a","b"]}`,
+			want:  `{"tags":["a","b"],"copy":["a","b"]}`,
+		},
+		{
+			name:  "template replacing the message",
+			steps: []transform.StepConfig{{Op: "template", Template: `{"wrapped":{{ json .params }}}`}},
+			body:  `{"params":{"a":1}}`,
+			want:  `{"wrapped":{"a":1}}`,
+		},
+		{
+			name: "methods",
+			steps: []transform.StepConfig{
+				{Op: "set", Path: "params.seen", Value: "true", Methods: []string{"tools/call"}},
+			},
+			body: `[{"method":"tools/call","params":{}},{"method":"tools/list","params":{}},{"id":1,"result":{}}]`,
+			want: `[{"method":"tools/call","params":{"seen":true}},` +
+				`{"method":"tools/list","params":{}},{"id":1,"result":{}}]`,
+		},
+		{
+			name: "order then name",
+			steps: []transform.StepConfig{
+				{Op: "set", Path: "log", Value: "b", Order: -1},
+				{Op: "template", Path: "log", Template: "{{ .log }}a"},
+				{Op: "template", Path: "log", Template: "{{ .log }}c"},
+			},
+			body: `{}`,
+			want: `{"log":"bca"}`,
+		},
+		{
+			name:  "numbers stay exact",
+			steps: []transform.StepConfig{{Op: "remove", Path: "params.arguments.q"}},
+			body:  toolCall,
+			want: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search",` +
+				`"arguments":{"region":"eu","tags":["a","b"],"n":12345678901234567890}}}`,
+		},
+		{name: "not JSON", steps: []transform.StepConfig{{Op: "set", Path: "a", Value: "1"}}, body: "plain text"},
+		{name: "trailing data", steps: []transform.StepConfig{{Op: "set", Path: "a", Value: "1"}}, body: `{} {}`},
+		{name: "empty body", steps: []transform.StepConfig{{Op: "set", Path: "a", Value: "1"}}, body: "  "},
+		{name: "no steps", body: `{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := make([]*transform.Step, len(tt.steps))
+			for i, sc := range tt.steps {
+				steps[i] = compile(t, string(rune('c'-i)), sc)
+			}
+			got, changed, err := transform.New(steps...).Request([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if changed || string(got) != tt.body {
+					t.Errorf("Request = %s, %t; want the body unchanged", got, changed)
+				}
+				return
+			}
+			if !changed || !equalJSON(t, got, []byte(tt.want)) {
+				t.Errorf("Request = %s, %t; want %s", got, changed, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetValuesAreNotShared(t *testing.T) {
+	tr := transform.New(compile(t, "meta", transform.StepConfig{Op: "set", Path: "meta", Value: `{"tags":[]}`}))
+
+	body, _, err := tr.Request([]byte(`[{},{}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"meta":{"tags":[]}},{"meta":{"tags":[]}}]`; string(body) != want {
+		t.Errorf("Request = %s, want %s", body, want)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		sc   transform.StepConfig
+		want string
+	}{
+		{
+			name: "execution",
+			sc:   transform.StepConfig{Op: "template", Path: "a", Template: "{{ upper .n }}"},
+			want: "step s: ",
+		},
+		{
+			name: "output is not JSON",
+			sc:   transform.StepConfig{Op: "template", Path: "a", Template: "{not json", JSON: true},
+			want: "template output is not JSON",
+		},
+		{
+			name: "replacement is not JSON",
+			sc:   transform.StepConfig{Op: "template", Template: "hello"},
+			want: "template output is not JSON",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := transform.New(compile(t, "s", tt.sc))
+			body := []byte(`{"n":1}`)
+			got, changed, err := tr.Request(body)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Request error = %v, want %q", err, tt.want)
+			}
+			if changed || string(got) != string(body) {
+				t.Errorf("Request = %s, %t; want the original body", got, changed)
+			}
+
+			resp := tr.HandleRequest(&mcpdpluginsv1.HTTPRequest{Body: body})
+			if !resp.GetContinue() || resp.GetModifiedRequest() != nil {
+				t.Errorf("HandleRequest = %v, want the request passed through", resp)
+			}
+		})
+	}
+}
+
+func TestFlows(t *testing.T) {
+	tr := transform.New(
+		compile(t, "req", transform.StepConfig{Op: "set", Path: "req", Value: "1", Flow: transform.FlowRequest}),
+		compile(t, "resp", transform.StepConfig{Op: "set", Path: "resp", Value: "1", Flow: transform.FlowResponse}),
+		compile(t, "both", transform.StepConfig{Op: "set", Path: "both", Value: "1", Flow: transform.FlowBoth}),
+	)
+
+	req := &mcpdpluginsv1.HTTPRequest{Method: "POST", Path: "/mcp", Body: []byte(`{}`)}
+	out := tr.HandleRequest(req)
+	if !out.GetContinue() || !equalJSON(t, out.GetModifiedRequest().GetBody(), []byte(`{"req":1,"both":1}`)) {
+		t.Errorf("HandleRequest = %v, want the request steps applied", out)
+	}
+	if out.GetModifiedRequest().GetPath() != "/mcp" || string(req.GetBody()) != `{}` {
+		t.Errorf("HandleRequest = %v, want a modified copy of the request", out)
+	}
+
+	resp := &mcpdpluginsv1.HTTPResponse{StatusCode: 201, Headers: map[string]string{"X-A": "1"}, Body: []byte(`{}`)}
+	out = tr.HandleResponse(resp)
+	if !out.GetContinue() || out.GetStatusCode() != 201 || out.GetHeaders()["X-A"] != "1" ||
+		!equalJSON(t, out.GetBody(), []byte(`{"resp":1,"both":1}`)) {
+		t.Errorf("HandleResponse = %v, want the response steps applied", out)
+	}
+
+	out = tr.HandleResponse(&mcpdpluginsv1.HTTPResponse{StatusCode: 200, Body: []byte("plain")})
+	if string(out.GetBody()) != "plain" {
+		t.Errorf("HandleResponse body = %s, want non-JSON passed through", out.GetBody())
+	}
+	if out := transform.New().HandleRequest(req); !out.GetContinue() || out.GetModifiedRequest() != nil {
+		t.Errorf("HandleRequest without steps = %v, want it to continue unchanged", out)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	custom := map[string]string{
+		"transform.tag.op":         "set",
+		"transform.tag.path":       "params._meta.source",
+		"transform.tag.value":      "gateway",
+		"transform.tag.flow":       "both",
+		"transform.region.op":      "map",
+		"transform.region.path":    "params.arguments.region",
+		"transform.region.mapping": "eu=eu-west-1,us=us-east-1",
+		"transform.region.methods": "tools/call",
+		"transform.region.order":   "-1",
+		"op":                       "top-level keys are not steps",
+	}
+	steps, err := transform.FromConfig(custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 {
+		t.Fatalf("FromConfig returned %d steps, want 2", len(steps))
+	}
+
+	body, changed, err := transform.New(steps...).Request([]byte(toolCall))
+	if err != nil || !changed {
+		t.Fatalf("Request = %s, %t, %v", body, changed, err)
+	}
+	want := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"_meta":{"source":"gateway"},"name":"search",` +
+		`"arguments":{"q":"llamas","region":"eu-west-1","tags":["a","b"],"n":12345678901234567890}}}`
+	if !equalJSON(t, body, []byte(want)) {
+		t.Errorf("Request = %s, want %s", body, want)
+	}
+}
+
+func TestFromConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		custom map[string]string
+		want   string
+	}{
+		{name: "missing op", custom: map[string]string{"transform.s.path": "a"}, want: `step s: unknown op ""`},
+		{
+			name: "undecodable",
+			custom: map[string]string{
+				"transform.s.op":    "remove",
+				"transform.s.path":  "a",
+				"transform.s.order": "first",
+			},
+			want: "step s: ",
+		},
+		{
+			name:   "invalid flow",
+			custom: map[string]string{"transform.s.op": "remove", "transform.s.path": "a", "transform.s.flow": "up"},
+			want:   `step s: unknown flow "up"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := transform.FromConfig(tt.custom); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("FromConfig error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func configure(p *transform.Plugin, custom map[string]string) error {
+	_, err := p.Configure(context.Background(), &mcpdpluginsv1.PluginConfig{CustomConfig: custom})
+	return err
+}
+
+func TestPlugin(t *testing.T) {
+	p := transform.NewPlugin()
+	ctx := context.Background()
+	req := &mcpdpluginsv1.HTTPRequest{Body: []byte(`{"q":"x"}`)}
+
+	resp, err := p.HandleRequest(ctx, req)
+	if err != nil || !resp.GetContinue() || resp.GetModifiedRequest() != nil {
+		t.Fatalf("unconfigured HandleRequest = %v, %v; want it unchanged", resp, err)
+	}
+
+	err = configure(p, map[string]string{
+		"transform.q.op":   "rename",
+		"transform.q.path": "q",
+		"transform.q.to":   "query",
+		"transform.q.flow": "both",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = p.HandleRequest(ctx, req)
+	if err != nil || string(resp.GetModifiedRequest().GetBody()) != `{"query":"x"}` {
+		t.Errorf("HandleRequest = %v, %v; want q renamed", resp, err)
+	}
+	resp, err = p.HandleResponse(ctx, &mcpdpluginsv1.HTTPResponse{StatusCode: 200, Body: []byte(`{"q":"y"}`)})
+	if err != nil || string(resp.GetBody()) != `{"query":"y"}` {
+		t.Errorf("HandleResponse = %v, %v; want q renamed", resp, err)
+	}
+
+	// A failed Configure keeps the previous steps.
+	err = configure(p, map[string]string{"transform.bad.op": "explode"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Configure error = %v, want InvalidArgument", err)
+	}
+	if resp, _ := p.HandleRequest(ctx, req); string(resp.GetModifiedRequest().GetBody()) != `{"query":"x"}` {
+		t.Errorf("HandleRequest after failed Configure = %v, want the previous steps", resp)
+	}
+}
+
+func TestPluginMetadata(t *testing.T) {
+	p := transform.NewPlugin()
+	md, err := p.GetMetadata(context.Background(), &emptypb.Empty{})
+	if err != nil || md.GetName() != "transform" {
+		t.Errorf("GetMetadata = %v, %v", md, err)
+	}
+	caps, err := p.GetCapabilities(context.Background(), &emptypb.Empty{})
+	want := []mcpdpluginsv1.Flow{mcpdpluginsv1.FlowRequest, mcpdpluginsv1.FlowResponse}
+	if err != nil || !reflect.DeepEqual(caps.GetFlows(), want) {
+		t.Errorf("GetCapabilities = %v, %v; want request and response flows", caps, err)
+	}
+}
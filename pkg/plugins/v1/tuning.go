@@ -0,0 +1,126 @@
+package mcpdpluginsv1
+
+import (
+	"fmt"
+	"runtime"
+
+	"google.golang.org/grpc"
+)
+
+// Names of the ServerTuning presets returned by TuningPreset.
+const (
+	// TuningLatency favours per-call latency: a fixed pool of stream workers avoids spawning a
+	// goroutine per call, and writes go straight to the connection instead of being batched.
+	TuningLatency = "latency"
+
+	// TuningThroughput favours sustained volume: more stream workers, and large flow-control
+	// windows and buffers so big bodies move in fewer round trips and syscalls.
+	TuningThroughput = "throughput"
+)
+
+// minWindowSize is the smallest flow-control window gRPC accepts; smaller values are ignored.
+const minWindowSize = 64 << 10
+
+// ServerTuning tunes the gRPC server started by Serve. Zero fields keep gRPC's defaults.
+type ServerTuning struct {
+	// NumStreamWorkers is the number of goroutines reused to handle calls. Zero starts a
+	// goroutine per call.
+	NumStreamWorkers uint32
+
+	// InitialWindowSize and InitialConnWindowSize are the flow-control windows, in bytes, of each
+	// call and of each connection. Values must be at least 64KiB; setting either disables gRPC's
+	// dynamic window sizing for that window.
+	InitialWindowSize     int32
+	InitialConnWindowSize int32
+
+	// WriteBufferSize and ReadBufferSize are the connection buffer sizes in bytes (gRPC defaults
+	// to 32KiB). -1 disables the buffer, so each write or read reaches the connection directly.
+	WriteBufferSize int
+	ReadBufferSize  int
+
+	// MaxConcurrentStreams limits the calls in flight on each connection.
+	MaxConcurrentStreams uint32
+}
+
+// TuningPreset returns the ServerTuning named TuningLatency or TuningThroughput, sized for the
+// number of CPUs available.
+func TuningPreset(name string) (ServerTuning, error) {
+	cpus := uint32(runtime.GOMAXPROCS(0))
+
+	switch name {
+	case TuningLatency:
+		return ServerTuning{
+			NumStreamWorkers: cpus,
+			WriteBufferSize:  -1,
+		}, nil
+	case TuningThroughput:
+		return ServerTuning{
+			NumStreamWorkers:      2 * cpus,
+			InitialWindowSize:     1 << 20,
+			InitialConnWindowSize: 8 << 20,
+			WriteBufferSize:       256 << 10,
+			ReadBufferSize:        256 << 10,
+		}, nil
+	default:
+		return ServerTuning{}, fmt.Errorf(
+			"unknown tuning preset %q (want %s or %s)", name, TuningLatency, TuningThroughput,
+		)
+	}
+}
+
+// WithServerTuning applies t to the gRPC server started by Serve. Operators can override it at
+// launch with the --tuning flag, which selects a preset by name.
+//
+// Usage:
+//
+//	tuning, _ := mcpdpluginsv1.TuningPreset(mcpdpluginsv1.TuningThroughput)
+//	tuning.MaxConcurrentStreams = 256
+//	err := mcpdpluginsv1.Serve(&MyPlugin{}, mcpdpluginsv1.WithServerTuning(tuning))
+func WithServerTuning(t ServerTuning) ServeOption {
+	return func(o *serveOptions) error {
+		if err := t.validate(); err != nil {
+			return err
+		}
+		o.tuning = &t
+		return nil
+	}
+}
+
+func (t ServerTuning) validate() error {
+	if t.InitialWindowSize != 0 && t.InitialWindowSize < minWindowSize {
+		return fmt.Errorf("initial window size must be at least %d bytes", minWindowSize)
+	}
+	if t.InitialConnWindowSize != 0 && t.InitialConnWindowSize < minWindowSize {
+		return fmt.Errorf("initial connection window size must be at least %d bytes", minWindowSize)
+	}
+	if t.WriteBufferSize < -1 || t.ReadBufferSize < -1 {
+		return fmt.Errorf("buffer sizes must be positive, zero for the default, or -1 to disable")
+	}
+
+	return nil
+}
+
+// serverOptions returns the gRPC server options applying t.
+func (t ServerTuning) serverOptions() []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if t.NumStreamWorkers > 0 {
+		opts = append(opts, grpc.NumStreamWorkers(t.NumStreamWorkers))
+	}
+	if t.InitialWindowSize > 0 {
+		opts = append(opts, grpc.InitialWindowSize(t.InitialWindowSize))
+	}
+	if t.InitialConnWindowSize > 0 {
+		opts = append(opts, grpc.InitialConnWindowSize(t.InitialConnWindowSize))
+	}
+	if t.WriteBufferSize != 0 {
+		opts = append(opts, grpc.WriteBufferSize(max(t.WriteBufferSize, 0)))
+	}
+	if t.ReadBufferSize != 0 {
+		opts = append(opts, grpc.ReadBufferSize(max(t.ReadBufferSize, 0)))
+	}
+	if t.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(t.MaxConcurrentStreams))
+	}
+
+	return opts
+}
@@ -0,0 +1,151 @@
+package mcpdpluginsv1
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestTuningPreset(t *testing.T) {
+	cpus := uint32(runtime.GOMAXPROCS(0))
+	tests := []struct {
+		name    string
+		want    ServerTuning
+		wantErr string
+	}{
+		{name: TuningLatency, want: ServerTuning{NumStreamWorkers: cpus, WriteBufferSize: -1}},
+		{
+			name: TuningThroughput,
+			want: ServerTuning{
+				NumStreamWorkers:      2 * cpus,
+				InitialWindowSize:     1 << 20,
+				InitialConnWindowSize: 8 << 20,
+				WriteBufferSize:       256 << 10,
+				ReadBufferSize:        256 << 10,
+			},
+		},
+		{name: "Latency", wantErr: `unknown tuning preset "Latency" (want latency or throughput)`},
+		{name: "", wantErr: "unknown tuning preset"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TuningPreset(tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("TuningPreset error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("TuningPreset = %+v, want %+v", got, tt.want)
+			}
+			// Presets are always valid.
+			if _, err := newServeOptions(WithServerTuning(got)); err != nil {
+				t.Errorf("WithServerTuning(%s) = %v", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestWithServerTuning(t *testing.T) {
+	tests := []struct {
+		name     string
+		tuning   ServerTuning
+		wantOpts int
+		wantErr  string
+	}{
+		{name: "zero keeps the defaults"},
+		{
+			name: "every setting",
+			tuning: ServerTuning{
+				NumStreamWorkers:      4,
+				InitialWindowSize:     minWindowSize,
+				InitialConnWindowSize: 1 << 20,
+				WriteBufferSize:       -1,
+				ReadBufferSize:        64 << 10,
+				MaxConcurrentStreams:  100,
+			},
+			wantOpts: 6,
+		},
+		{
+			name:    "small window",
+			tuning:  ServerTuning{InitialWindowSize: 1024},
+			wantErr: "initial window size must be at least 65536",
+		},
+		{
+			name:    "small connection window",
+			tuning:  ServerTuning{InitialConnWindowSize: minWindowSize - 1},
+			wantErr: "initial connection window size must be at least 65536",
+		},
+		{name: "negative window", tuning: ServerTuning{InitialWindowSize: -1}, wantErr: "initial window size"},
+		{name: "write buffer", tuning: ServerTuning{WriteBufferSize: -2}, wantErr: "buffer sizes must be positive"},
+		{name: "read buffer", tuning: ServerTuning{ReadBufferSize: -2}, wantErr: "buffer sizes must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(WithServerTuning(tt.tuning))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("newServeOptions error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *o.tuning != tt.tuning {
+				t.Errorf("tuning = %+v, want %+v", *o.tuning, tt.tuning)
+			}
+			if got := len(o.tuning.serverOptions()); got != tt.wantOpts {
+				t.Errorf("%d server options, want %d", got, tt.wantOpts)
+			}
+		})
+	}
+}
+
+func TestServerTuningServes(t *testing.T) {
+	for _, name := range []string{TuningLatency, TuningThroughput} {
+		t.Run(name, func(t *testing.T) {
+			tuning, err := TuningPreset(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tuning.MaxConcurrentStreams = 8
+
+			address := filepath.Join(t.TempDir(), "plugin.sock")
+			lis, err := net.Listen("unix", address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := grpc.NewServer(tuning.serverOptions()...)
+			RegisterPluginServer(srv, &echoPlugin{})
+			go func() { _ = srv.Serve(lis) }()
+			t.Cleanup(srv.Stop)
+			conn, err := grpc.NewClient("unix://"+address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+
+			// A body larger than the default buffers and windows crosses intact.
+			body := bytes.Repeat([]byte("x"), 2<<20)
+			resp, err := NewPluginClient(conn).HandleRequest(context.Background(), &HTTPRequest{Body: body})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(resp.GetBody(), body) {
+				t.Errorf("echoed %d bytes, want %d", len(resp.GetBody()), len(body))
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// UpstreamSectionPrefix prefixes custom_config keys that apply to a single upstream MCP server:
+// "upstreams.<server>.<key>" overrides "<key>" for requests proxied to that server.
+const UpstreamSectionPrefix = "upstreams."
+
+// UpstreamResolver identifies the upstream MCP server a call targets from the request path (empty
+// for HandleResponse calls) and HTTP headers. It returns an empty string when the upstream is unknown.
+type UpstreamResolver func(ctx context.Context, path string, headers map[string]string) string
+
+// UpstreamFromHeader resolves the upstream from the value of the named header.
+func UpstreamFromHeader(name string) UpstreamResolver {
+	return func(_ context.Context, _ string, headers map[string]string) string {
+		return GetHeader(headers, name)
+	}
+}
+
+// UpstreamFromPath resolves the upstream from the path segment following "/servers/", as in
+// "/api/v1/servers/{name}/tools/{tool}".
+func UpstreamFromPath() UpstreamResolver {
+	return func(_ context.Context, path string, _ map[string]string) string {
+		_, rest, ok := strings.Cut(path, "/servers/")
+		if !ok {
+			return ""
+		}
+		name, _, _ := strings.Cut(rest, "/")
+		return name
+	}
+}
+
+// FirstUpstream combines resolvers, returning the first non-empty result.
+func FirstUpstream(resolvers ...UpstreamResolver) UpstreamResolver {
+	return func(ctx context.Context, path string, headers map[string]string) string {
+		for _, r := range resolvers {
+			if u := r(ctx, path, headers); u != "" {
+				return u
+			}
+		}
+		return ""
+	}
+}
+
+// WithUpstreams makes Serve resolve the upstream MCP server of every HandleRequest and
+// HandleResponse call with resolve and expose it to handlers through Upstream and UpstreamConfig.
+func WithUpstreams(resolve UpstreamResolver) ServeOption {
+	return func(o *serveOptions) error {
+		if resolve == nil {
+			return fmt.Errorf("upstream resolver cannot be nil")
+		}
+		o.interceptors = append(o.interceptors, upstreamInterceptor(resolve))
+		return nil
+	}
+}
+
+type upstreamKey struct{}
+
+// Upstream returns the upstream MCP server resolved for the current call, or an empty string.
+func Upstream(ctx context.Context) string {
+	u, _ := ctx.Value(upstreamKey{}).(string)
+	return u
+}
+
+// ContextWithUpstream returns a copy of ctx carrying upstream, for use in tests and custom dispatch.
+func ContextWithUpstream(ctx context.Context, upstream string) context.Context {
+	return context.WithValue(ctx, upstreamKey{}, upstream)
+}
+
+func upstreamInterceptor(resolve UpstreamResolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var u string
+		switch in := req.(type) {
+		case *HTTPRequest:
+			u = resolve(ctx, in.GetPath(), in.GetHeaders())
+		case *HTTPResponse:
+			u = resolve(ctx, "", in.GetHeaders())
+		default:
+			return handler(ctx, req)
+		}
+
+		if u != "" {
+			ctx = ContextWithUpstream(ctx, u)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// UpstreamConfig holds a plugin's decoded configuration for each upstream MCP server, for example
+// to inject different credentials per server. Call Configure from the plugin's Configure method and
+// Get from handlers. It is safe for concurrent use.
+//
+// Usage:
+//
+//	type Settings struct {
+//	    Token string `config:"token"`
+//	}
+//
+//	// custom_config:
+//	//   upstreams.github.token: ghp_...
+//	//   upstreams.jira.token:   jira_...
+//	var cfg mcpdpluginsv1.UpstreamConfig[Settings]
+//
+//	func (p *MyPlugin) HandleRequest(ctx context.Context, req *mcpdpluginsv1.HTTPRequest) (*mcpdpluginsv1.HTTPResponse, error) {
+//	    token := cfg.Get(ctx).Token // Token for the upstream this request targets.
+//	    // ...
+//	}
+type UpstreamConfig[T any] struct {
+	sections sectionedConfig[T]
+}
+
+// Configure decodes the base configuration and every "upstreams.<server>." section of cfg with
+// DecodeConfig. The previous configuration is kept if any section fails to decode.
+func (c *UpstreamConfig[T]) Configure(ctx context.Context, cfg *PluginConfig) error {
+	return c.sections.configure(ctx, cfg, UpstreamSectionPrefix)
+}
+
+// Get returns the configuration for the upstream of the current call, falling back to the base
+// configuration. It returns nil before the first successful Configure.
+func (c *UpstreamConfig[T]) Get(ctx context.Context) *T {
+	return c.sections.get(Upstream(ctx))
+}
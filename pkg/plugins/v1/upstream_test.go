@@ -0,0 +1,146 @@
+package mcpdpluginsv1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+func TestUpstreamResolvers(t *testing.T) {
+	fromHeader := UpstreamFromHeader("X-Upstream")
+	tests := []struct {
+		name    string
+		resolve UpstreamResolver
+		path    string
+		headers map[string]string
+		want    string
+	}{
+		{"header", fromHeader, "", map[string]string{"x-upstream": "github"}, "github"},
+		{"missing header", fromHeader, "/servers/jira", nil, ""},
+		{"path", UpstreamFromPath(), "/api/v1/servers/github/tools/search", nil, "github"},
+		{"path ending in the name", UpstreamFromPath(), "/servers/jira", nil, "jira"},
+		{"path without servers", UpstreamFromPath(), "/api/v1/tools/search", nil, ""},
+		{"empty path", UpstreamFromPath(), "", nil, ""},
+		{
+			"first non-empty",
+			FirstUpstream(fromHeader, UpstreamFromPath()),
+			"/servers/jira",
+			map[string]string{"X-Upstream": "github"},
+			"github",
+		},
+		{"falls through", FirstUpstream(fromHeader, UpstreamFromPath()), "/servers/jira", nil, "jira"},
+		{"none", FirstUpstream(), "/servers/jira", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.resolve(context.Background(), tt.path, tt.headers); got != tt.want {
+				t.Errorf("resolved %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpstreamInterceptor(t *testing.T) {
+	resolve := FirstUpstream(UpstreamFromHeader("X-Upstream"), UpstreamFromPath())
+	tests := []struct {
+		name string
+		req  any
+		want string
+	}{
+		{"request path", &HTTPRequest{Path: "/servers/github/tools"}, "github"},
+		{"request header", &HTTPRequest{Headers: map[string]string{"X-Upstream": "jira"}}, "jira"},
+		{"response header", &HTTPResponse{Headers: map[string]string{"X-Upstream": "jira"}}, "jira"},
+		{"unresolved", &HTTPRequest{Path: "/mcp"}, ""},
+		{"other RPCs", &PluginConfig{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newServeOptions(WithUpstreams(resolve))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			_, err = chainInterceptors(o.interceptors)(context.Background(), tt.req, &grpc.UnaryServerInfo{},
+				func(ctx context.Context, _ any) (any, error) {
+					got = Upstream(ctx)
+					return nil, nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Upstream = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpstreamInterceptorResponsePath(t *testing.T) {
+	var paths []string
+	resolve := func(_ context.Context, path string, _ map[string]string) string {
+		paths = append(paths, path)
+		return ""
+	}
+	_, err := upstreamInterceptor(resolve)(context.Background(), &HTTPResponse{}, &grpc.UnaryServerInfo{},
+		func(context.Context, any) (any, error) { return nil, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != "" {
+		t.Errorf("resolver got paths %q, want one empty path for a response", paths)
+	}
+}
+
+func TestWithUpstreamsNil(t *testing.T) {
+	if _, err := newServeOptions(WithUpstreams(nil)); err == nil {
+		t.Error("WithUpstreams accepted a nil resolver")
+	}
+}
+
+type upstreamSettings struct {
+	Token string `config:"token"`
+}
+
+func TestUpstreamConfig(t *testing.T) {
+	var c UpstreamConfig[upstreamSettings]
+	ctx := context.Background()
+	if got := c.Get(ctx); got != nil {
+		t.Errorf("Get before Configure = %+v, want nil", got)
+	}
+
+	err := c.Configure(ctx, &PluginConfig{CustomConfig: map[string]string{
+		"token":                  "default",
+		"upstreams.github.token": "ghp",
+		"tenants.acme.token":     "not an upstream section",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		upstream string
+		want     string
+	}{
+		{"", "default"},
+		{"github", "ghp"},
+		{"jira", "default"},
+	}
+	for _, tt := range tests {
+		if got := c.Get(ContextWithUpstream(ctx, tt.upstream)); got == nil || got.Token != tt.want {
+			t.Errorf("Get(%q) = %+v, want token %s", tt.upstream, got, tt.want)
+		}
+	}
+
+	err = c.Configure(ctx, &PluginConfig{CustomConfig: map[string]string{"upstreams.github.unknown": "x"}})
+	if err != nil {
+		t.Fatalf("Configure with an unknown key: %v", err)
+	}
+
+	type limited struct{ Limit int }
+	var lc UpstreamConfig[limited]
+	err = lc.Configure(ctx, &PluginConfig{CustomConfig: map[string]string{"upstreams.jira.limit": "x"}})
+	if err == nil || !strings.Contains(err.Error(), "section upstreams.jira") {
+		t.Errorf("Configure error = %v, want the failing upstream section", err)
+	}
+}
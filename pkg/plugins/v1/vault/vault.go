@@ -0,0 +1,663 @@
+// Package vault is a HashiCorp Vault client for plugins that inject credentials: it logs in with
+// a token or AppRole, keeps the token and any leased secrets renewed, reads KV and dynamic
+// secrets, and resolves "secret:vault:" references for mcpdpluginsv1.WithSecrets.
+//
+//	// custom_config:
+//	//   vault_addr:           https://vault.example.com:8200
+//	//   vault_role_id:        3f1c...
+//	//   vault_secret_id_file: /run/secrets/vault-secret-id
+//	//   upstream_token:       secret:vault:secret/data/mcp/github#token
+//	client, err := vault.New(ctx, cfg)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	err = mcpdpluginsv1.Serve(plugin,
+//	    mcpdpluginsv1.WithSecrets(map[string]mcpdpluginsv1.SecretResolver{"vault": client}),
+//	    client.CloseOnShutdown(),
+//	)
+//
+// References are "<path>#<field>": KV version 2 responses are unwrapped, so the path is the API
+// path including "data/". Close revokes the leases of dynamic secrets read through the client and
+// the token it logged in with.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	mcpdpluginsv1 "github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/clock"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/config"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/httpclientx"
+)
+
+// Environment variables read when the corresponding Config field is empty, as by the Vault CLI.
+const (
+	EnvAddress   = "VAULT_ADDR"
+	EnvToken     = "VAULT_TOKEN"
+	EnvNamespace = "VAULT_NAMESPACE"
+)
+
+// defaultAddress is the address of a local Vault dev server, used when none is configured.
+const defaultAddress = "http://127.0.0.1:8200"
+
+// maxRenewWait bounds how long the renewal loop sleeps, so it notices new leases and clock jumps.
+const maxRenewWait = time.Minute
+
+// closeTimeout bounds the revocations done by CloseOnShutdown.
+const closeTimeout = 10 * time.Second
+
+// ErrNotFound is returned when a secret does not exist.
+var ErrNotFound = errors.New("vault: secret not found")
+
+// Config configures a Client, decodable from custom_config with mcpdpluginsv1.DecodeConfig.
+type Config struct {
+	// Address is the Vault server URL (defaults to VAULT_ADDR, then http://127.0.0.1:8200).
+	Address string `config:"vault_addr"`
+
+	// Namespace is the Vault Enterprise namespace (defaults to VAULT_NAMESPACE).
+	Namespace string `config:"vault_namespace"`
+
+	// Token authenticates with a token (defaults to VAULT_TOKEN when no AppRole is configured).
+	Token string `config:"vault_token"`
+
+	// RoleID and SecretID, or SecretIDFile, authenticate with AppRole at AppRoleMount. AppRole
+	// takes precedence over a token; its token is renewed, or replaced by logging in again.
+	RoleID       string `config:"vault_role_id"`
+	SecretID     string `config:"vault_secret_id"`
+	SecretIDFile string `config:"vault_secret_id_file"`
+	AppRoleMount string `config:"vault_approle_mount" default:"approle"`
+
+	// Timeout bounds each Vault request.
+	Timeout time.Duration `config:"vault_timeout" default:"10s"`
+}
+
+// DefaultConfig returns the Config with every default applied.
+func DefaultConfig() Config {
+	var cfg Config
+	if _, err := config.Decode(nil, &cfg); err != nil {
+		panic(fmt.Sprintf("vault: invalid defaults: %v", err))
+	}
+
+	return cfg
+}
+
+// Secret is a Vault response: the secret's data and, for leased secrets, its lease.
+type Secret struct {
+	Data          map[string]any
+	LeaseID       string
+	LeaseDuration time.Duration
+	Renewable     bool
+}
+
+// Error is a Vault API error response.
+type Error struct {
+	StatusCode int
+	Errors     []string
+}
+
+func (e *Error) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("vault: HTTP %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("vault: HTTP %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+// lease is a token or secret lease kept alive by the renewal loop.
+type lease struct {
+	secret  *Secret
+	renewAt time.Time
+	expires time.Time
+}
+
+// Client is a Vault client. It is safe for concurrent use.
+type Client struct {
+	cfg    Config
+	http   *http.Client
+	logger *log.Logger
+	clock  clock.Clock
+
+	mu       sync.Mutex
+	token    string
+	tokenTTL lease
+	loggedIn bool
+	leases   map[string]*lease
+	resolved map[string]*Secret
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Option configures a Client.
+type Option func(*Client) error
+
+// WithHTTPClient sets the HTTP client used to reach Vault (defaults to an httpclientx client).
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) error {
+		if hc == nil {
+			return fmt.Errorf("HTTP client cannot be nil")
+		}
+		c.http = hc
+		return nil
+	}
+}
+
+// WithLogger sets the logger used to report renewal failures (defaults to log.Default()).
+func WithLogger(logger *log.Logger) Option {
+	return func(c *Client) error {
+		if logger == nil {
+			return fmt.Errorf("logger cannot be nil")
+		}
+		c.logger = logger
+		return nil
+	}
+}
+
+// WithClock sets the clock token and lease renewals are scheduled by (defaults to clock.Real()).
+func WithClock(clk clock.Clock) Option {
+	return func(c *Client) error {
+		if clk == nil {
+			return fmt.Errorf("clock cannot be nil")
+		}
+		c.clock = clk
+		return nil
+	}
+}
+
+// New logs in to Vault as configured by cfg and starts renewing the token in the background.
+func New(ctx context.Context, cfg Config, opts ...Option) (*Client, error) {
+	cfg.Address = firstNonEmpty(cfg.Address, os.Getenv(EnvAddress), defaultAddress)
+	cfg.Namespace = firstNonEmpty(cfg.Namespace, os.Getenv(EnvNamespace))
+	if cfg.RoleID == "" {
+		cfg.Token = firstNonEmpty(cfg.Token, os.Getenv(EnvToken))
+	}
+	if _, err := url.Parse(cfg.Address); err != nil {
+		return nil, fmt.Errorf("invalid vault_addr: %w", err)
+	}
+	if cfg.RoleID == "" && cfg.Token == "" {
+		return nil, fmt.Errorf("vault_token or vault_role_id is required")
+	}
+	if cfg.RoleID != "" && cfg.SecretID == "" && cfg.SecretIDFile == "" {
+		return nil, fmt.Errorf("vault_secret_id or vault_secret_id_file is required with vault_role_id")
+	}
+	if cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("vault_timeout must be positive")
+	}
+
+	c := &Client{
+		cfg:      cfg,
+		logger:   log.Default(),
+		clock:    clock.Real(),
+		leases:   map[string]*lease{},
+		resolved: map[string]*Secret{},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.http == nil {
+		hc, err := httpclientx.New(httpclientx.DefaultConfig())
+		if err != nil {
+			return nil, err
+		}
+		c.http = hc
+	}
+
+	if err := c.login(ctx); err != nil {
+		return nil, err
+	}
+	go c.renewLoop()
+
+	return c, nil
+}
+
+// login authenticates with AppRole, or looks up the configured token's TTL.
+func (c *Client) login(ctx context.Context) error {
+	if c.cfg.RoleID == "" {
+		c.mu.Lock()
+		c.token = c.cfg.Token
+		c.mu.Unlock()
+
+		var resp response
+		if err := c.do(ctx, http.MethodGet, "auth/token/lookup-self", nil, &resp); err != nil {
+			return fmt.Errorf("vault: token lookup failed: %w", err)
+		}
+		ttl, _ := resp.Data["ttl"].(float64)
+		renewable, _ := resp.Data["renewable"].(bool)
+		c.setToken(c.cfg.Token, time.Duration(ttl)*time.Second, renewable, false)
+		return nil
+	}
+
+	secretID := c.cfg.SecretID
+	if c.cfg.SecretIDFile != "" {
+		b, err := os.ReadFile(c.cfg.SecretIDFile)
+		if err != nil {
+			return fmt.Errorf("vault: reading secret ID: %w", err)
+		}
+		secretID = strings.TrimSpace(string(b))
+	}
+
+	var resp response
+	body := map[string]any{"role_id": c.cfg.RoleID, "secret_id": secretID}
+	if err := c.do(ctx, http.MethodPost, "auth/"+c.cfg.AppRoleMount+"/login", body, &resp); err != nil {
+		return fmt.Errorf("vault: AppRole login failed: %w", err)
+	}
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return fmt.Errorf("vault: AppRole login returned no token")
+	}
+	c.setToken(resp.Auth.ClientToken, time.Duration(resp.Auth.LeaseDuration)*time.Second, resp.Auth.Renewable, true)
+
+	return nil
+}
+
+// setToken makes token current, with its renewal two thirds into its TTL (none when ttl is zero).
+func (c *Client) setToken(token string, ttl time.Duration, renewable, loggedIn bool) {
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token, c.loggedIn = token, loggedIn
+	c.tokenTTL = lease{secret: &Secret{LeaseDuration: ttl, Renewable: renewable}}
+	if ttl > 0 {
+		c.tokenTTL.renewAt = now.Add(ttl * 2 / 3)
+		c.tokenTTL.expires = now.Add(ttl)
+	}
+}
+
+// Token returns the current Vault token.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.token
+}
+
+// Read reads the secret at path, such as "database/creds/readonly".
+func (c *Client) Read(ctx context.Context, path string) (*Secret, error) {
+	var resp response
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		var ve *Error
+		if errors.As(err, &ve) && ve.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+		}
+		return nil, err
+	}
+
+	return resp.secret(), nil
+}
+
+// Write writes data to path and returns the response's secret, if any.
+func (c *Client) Write(ctx context.Context, path string, data map[string]any) (*Secret, error) {
+	var resp response
+	if err := c.do(ctx, http.MethodPut, path, data, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.secret(), nil
+}
+
+// KV reads the latest version of the secret at path in the KV version 2 engine mounted at mount.
+func (c *Client) KV(ctx context.Context, mount, path string) (map[string]any, error) {
+	s, err := c.Read(ctx, strings.Trim(mount, "/")+"/data/"+strings.TrimLeft(path, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	return kvData(s.Data), nil
+}
+
+// Lease reads the dynamic secret at path and keeps its lease renewed until Close, which revokes
+// it.
+func (c *Client) Lease(ctx context.Context, path string) (*Secret, error) {
+	s, err := c.Read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	c.track(s)
+
+	return s, nil
+}
+
+// track adds the lease of s, if any, to the renewal loop. Leases that are not renewable are only
+// kept for revocation until they expire.
+func (c *Client) track(s *Secret) {
+	if s.LeaseID == "" {
+		return
+	}
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l := &lease{secret: s, renewAt: now.Add(s.LeaseDuration * 2 / 3), expires: now.Add(s.LeaseDuration)}
+	if !s.Renewable {
+		l.renewAt = l.expires
+	}
+	c.leases[s.LeaseID] = l
+}
+
+// ResolveSecret implements mcpdpluginsv1.SecretResolver for references of the form "<path>#<field>".
+// Leased secrets are renewed until Close and reused while their lease lasts, so reconfiguring does
+// not issue new credentials.
+func (c *Client) ResolveSecret(ctx context.Context, ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("vault reference must be <path>#<field>, got %q", ref)
+	}
+
+	c.mu.Lock()
+	s, cached := c.resolved[path]
+	if cached {
+		if l, ok := c.leases[s.LeaseID]; !ok || !c.clock.Now().Before(l.expires) {
+			cached = false
+		}
+	}
+	c.mu.Unlock()
+
+	if !cached {
+		var err error
+		if s, err = c.Read(ctx, path); err != nil {
+			return "", err
+		}
+		if s.LeaseID != "" {
+			c.track(s)
+			c.mu.Lock()
+			c.resolved[path] = s
+			c.mu.Unlock()
+		}
+	}
+
+	v, ok := kvData(s.Data)[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no field %s", path, field)
+	}
+	if str, ok := v.(string); ok {
+		return str, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// renewLoop renews the token and tracked leases as they come due until Close.
+func (c *Client) renewLoop() {
+	defer close(c.done)
+
+	for {
+		wait := maxRenewWait
+		c.mu.Lock()
+		now := c.clock.Now()
+		if !c.tokenTTL.renewAt.IsZero() {
+			wait = min(wait, c.tokenTTL.renewAt.Sub(now))
+		}
+		for _, l := range c.leases {
+			wait = min(wait, l.renewAt.Sub(now))
+		}
+		c.mu.Unlock()
+
+		timer := c.clock.NewTimer(max(wait, 0))
+		select {
+		case <-c.stop:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		c.renewDue()
+	}
+}
+
+// renewDue renews the token and the leases whose renewal time has come.
+func (c *Client) renewDue() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	tokenDue := !c.tokenTTL.renewAt.IsZero() && !now.Before(c.tokenTTL.renewAt)
+	renewable := c.tokenTTL.secret != nil && c.tokenTTL.secret.Renewable
+	var due []*lease
+	for id, l := range c.leases {
+		switch {
+		case !now.Before(l.expires):
+			delete(c.leases, id)
+		case !now.Before(l.renewAt):
+			due = append(due, l)
+		}
+	}
+	c.mu.Unlock()
+
+	if tokenDue {
+		c.renewToken(ctx, renewable)
+	}
+	for _, l := range due {
+		c.renewLease(ctx, l)
+	}
+}
+
+// renewToken renews the current token, logging in again when it cannot be renewed.
+func (c *Client) renewToken(ctx context.Context, renewable bool) {
+	if renewable {
+		var resp response
+		err := c.do(ctx, http.MethodPost, "auth/token/renew-self", map[string]any{}, &resp)
+		if err == nil && resp.Auth != nil {
+			c.mu.Lock()
+			loggedIn := c.loggedIn
+			c.mu.Unlock()
+			c.setToken(c.Token(), time.Duration(resp.Auth.LeaseDuration)*time.Second, resp.Auth.Renewable, loggedIn)
+			return
+		}
+		c.logger.Printf("vault: token renewal failed: %v", err)
+	}
+
+	if c.cfg.RoleID == "" {
+		// A static token cannot be replaced; retry until it expires.
+		now := c.clock.Now()
+		c.mu.Lock()
+		if now.Before(c.tokenTTL.expires) {
+			c.tokenTTL.renewAt = now.Add(min(maxRenewWait, c.tokenTTL.expires.Sub(now)/2))
+		} else {
+			c.tokenTTL.renewAt = time.Time{}
+			c.logger.Printf("vault: token expired")
+		}
+		c.mu.Unlock()
+		return
+	}
+	if err := c.login(ctx); err != nil {
+		c.logger.Printf("vault: %v", err)
+		c.mu.Lock()
+		c.tokenTTL.renewAt = c.clock.Now().Add(maxRenewWait / 4)
+		c.mu.Unlock()
+	}
+}
+
+// renewLease extends l, or stops renewing it when Vault refuses.
+func (c *Client) renewLease(ctx context.Context, l *lease) {
+	id := l.secret.LeaseID
+	var resp response
+	err := c.do(ctx, http.MethodPut, "sys/leases/renew", map[string]any{"lease_id": id}, &resp)
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		c.logger.Printf("vault: renewing lease %s failed: %v", id, err)
+		// Retry before the lease expires, then let renewDue drop it.
+		l.renewAt = now.Add(min(maxRenewWait, l.expires.Sub(now)/2))
+		return
+	}
+	ttl := time.Duration(resp.LeaseDuration) * time.Second
+	l.renewAt, l.expires = now.Add(ttl*2/3), now.Add(ttl)
+	if !resp.Renewable {
+		// Vault will not extend it again: keep it for revocation until it expires.
+		l.renewAt = l.expires
+	}
+}
+
+// Close stops renewals, revokes the tracked leases and, when the client logged in itself, its
+// token. It returns the revocation errors joined.
+func (c *Client) Close(ctx context.Context) error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		<-c.done
+
+		c.mu.Lock()
+		ids := make([]string, 0, len(c.leases))
+		for id := range c.leases {
+			ids = append(ids, id)
+		}
+		loggedIn := c.loggedIn
+		c.mu.Unlock()
+
+		for _, id := range ids {
+			body := map[string]any{"lease_id": id}
+			if rerr := c.do(ctx, http.MethodPut, "sys/leases/revoke", body, nil); rerr != nil {
+				err = errors.Join(err, fmt.Errorf("revoking lease %s: %w", id, rerr))
+			}
+		}
+		if loggedIn {
+			if rerr := c.do(ctx, http.MethodPost, "auth/token/revoke-self", map[string]any{}, nil); rerr != nil {
+				err = errors.Join(err, fmt.Errorf("revoking token: %w", rerr))
+			}
+		}
+	})
+
+	return err
+}
+
+// CloseOnShutdown returns a ServeOption closing the client when the plugin server starts stopping.
+func (c *Client) CloseOnShutdown() mcpdpluginsv1.ServeOption {
+	return mcpdpluginsv1.WithEventSubscriber(func(ctx context.Context, ev mcpdpluginsv1.Event) {
+		if ev.Phase != mcpdpluginsv1.PhaseStopping {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), closeTimeout)
+		defer cancel()
+		if err := c.Close(ctx); err != nil {
+			c.logger.Printf("vault: %v", err)
+		}
+	}, mcpdpluginsv1.EventLifecycle)
+}
+
+// response is the envelope of Vault API responses.
+//
+//nolint:tagliatelle // external API wire format
+type response struct {
+	LeaseID       string         `json:"lease_id"`
+	LeaseDuration int            `json:"lease_duration"`
+	Renewable     bool           `json:"renewable"`
+	Data          map[string]any `json:"data"`
+	Auth          *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+}
+
+func (r *response) secret() *Secret {
+	return &Secret{
+		Data:          r.Data,
+		LeaseID:       r.LeaseID,
+		LeaseDuration: time.Duration(r.LeaseDuration) * time.Second,
+		Renewable:     r.Renewable,
+	}
+}
+
+// do calls the Vault API at /v1/<path> with body as JSON and decodes the response into out,
+// which may be nil.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method,
+		strings.TrimRight(c.cfg.Address, "/")+"/v1/"+strings.TrimLeft(path, "/"), r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Request", "true")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if t := c.Token(); t != "" {
+		req.Header.Set("X-Vault-Token", t)
+	}
+	if c.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.cfg.Namespace)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		ve := &Error{StatusCode: resp.StatusCode}
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(data, &e) == nil {
+			ve.Errors = e.Errors
+		}
+		return ve
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+// kvData unwraps the data of a KV version 2 read, which nests the secret under "data" next to
+// "metadata", and returns other data as it is.
+func kvData(data map[string]any) map[string]any {
+	inner, ok := data["data"].(map[string]any)
+	if _, hasMeta := data["metadata"]; ok && hasMeta {
+		return inner
+	}
+
+	return data
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
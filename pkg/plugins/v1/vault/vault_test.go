@@ -0,0 +1,672 @@
+package vault_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/plugintest"
+	"github.com/mozilla-ai/mcpd-plugins-sdk-go/pkg/plugins/v1/vault"
+)
+
+// Requests made by the client, as recorded by vaultServer.
+const (
+	approleLogin = "POST auth/approle/login"
+	lookupSelf   = "GET auth/token/lookup-self"
+	renewSelf    = "POST auth/token/renew-self"
+	revokeSelf   = "POST auth/token/revoke-self"
+	readCreds    = "GET database/creds/ro"
+	renewLease   = "PUT sys/leases/renew"
+	revokeLease  = "PUT sys/leases/revoke"
+)
+
+// defaultResponses are the vaultServer responses to each request; others are answered with {}.
+var defaultResponses = map[string]string{
+	approleLogin: `{"auth":{"client_token":"approle-token","lease_duration":30,"renewable":true}}`,
+	lookupSelf:   `{"data":{"ttl":30,"renewable":true}}`,
+	renewSelf:    `{"auth":{"client_token":"ignored","lease_duration":30,"renewable":true}}`,
+	readCreds:    `{"lease_id":"creds/1","lease_duration":90,"renewable":true,"data":{"username":"u","password":"p"}}`,
+	renewLease:   `{"lease_id":"creds/1","lease_duration":90,"renewable":true}`,
+}
+
+// vaultServer is a Vault API recording the requests it gets as "<method> <path>".
+type vaultServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses map[string]string
+	fail      map[string]int // Status answering a request instead of its response.
+	calls     []string
+	tokens    []string // X-Vault-Token of each call.
+}
+
+func newVaultServer(t *testing.T) *vaultServer {
+	t.Helper()
+
+	s := &vaultServer{responses: map[string]string{}, fail: map[string]int{}}
+	for k, v := range defaultResponses {
+		s.responses[k] = v
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *vaultServer) serve(w http.ResponseWriter, r *http.Request) {
+	call := r.Method + " " + strings.TrimPrefix(r.URL.Path, "/v1/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, call)
+	s.tokens = append(s.tokens, r.Header.Get("X-Vault-Token"))
+	w.Header().Set("Content-Type", "application/json")
+	if status := s.fail[call]; status != 0 {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"errors":["denied"]}`))
+		return
+	}
+	body, ok := s.responses[call]
+	if !ok {
+		body = "{}"
+	}
+	_, _ = w.Write([]byte(body))
+}
+
+func (s *vaultServer) set(fn func(s *vaultServer)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(s)
+}
+
+// since returns the calls recorded after the first n.
+func (s *vaultServer) since(n int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.calls[n:])
+}
+
+func (s *vaultServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.calls)
+}
+
+// approleConfig and tokenConfig return Configs for the server at addr.
+func approleConfig(addr string) vault.Config {
+	return vault.Config{
+		Address:      addr,
+		RoleID:       "role",
+		SecretID:     "secret",
+		AppRoleMount: "approle",
+		Timeout:      5 * time.Second,
+	}
+}
+
+func tokenConfig(addr string) vault.Config {
+	return vault.Config{Address: addr, Token: "static-token", AppRoleMount: "approle", Timeout: 5 * time.Second}
+}
+
+// newClient returns a Client for cfg on a fake clock, closed at cleanup.
+func newClient(t *testing.T, srv *vaultServer, cfg vault.Config) (*vault.Client, *plugintest.FakeClock) {
+	t.Helper()
+
+	clk := plugintest.NewFakeClock(time.Time{})
+	c, err := vault.New(context.Background(), cfg,
+		vault.WithHTTPClient(srv.Client()),
+		vault.WithClock(clk),
+		vault.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close(context.Background()) })
+
+	return c, clk
+}
+
+// renewalLoop drives the client's renewal loop on its fake clock.
+type renewalLoop struct {
+	clk    *plugintest.FakeClock
+	timers int
+}
+
+// advance moves the clock by d, which must reach the loop's next wake-up, and waits for the
+// iteration it wakes to finish.
+func (l *renewalLoop) advance(t *testing.T, d time.Duration) {
+	t.Helper()
+
+	waitForTimers(t, l.clk, l.timers+1)
+	l.clk.Advance(d)
+	l.timers++
+	// The loop arms its next timer once the renewals are done.
+	waitForTimers(t, l.clk, l.timers+1)
+}
+
+// waitForTimers waits for n timers to have been created on clk.
+func waitForTimers(t *testing.T, clk *plugintest.FakeClock, n int) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		clk.WaitForTimers(n)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("renewal loop did not arm timer %d", n)
+	}
+}
+
+func TestClientRenewal(t *testing.T) {
+	type step struct {
+		advance time.Duration
+		want    []string // Requests made by the iteration the step wakes.
+	}
+	tests := []struct {
+		name      string
+		approle   bool
+		responses map[string]string // Overrides of defaultResponses.
+		lease     bool              // Lease readCreds before the first step.
+		fail      map[string]int    // Failures injected once the client is running.
+		steps     []step
+		wantClose []string
+	}{
+		{
+			name:    "approle token renewed two thirds into its ttl",
+			approle: true,
+			steps: []step{
+				{20 * time.Second, []string{renewSelf}},
+				{20 * time.Second, []string{renewSelf}},
+			},
+			wantClose: []string{revokeSelf},
+		},
+		{
+			name:      "static token renewed and not revoked",
+			steps:     []step{{20 * time.Second, []string{renewSelf}}},
+			wantClose: nil,
+		},
+		{
+			name:      "static token without ttl never renewed",
+			responses: map[string]string{lookupSelf: `{"data":{"ttl":0}}`},
+			steps:     []step{{time.Minute, nil}, {time.Minute, nil}},
+		},
+		{
+			name:      "non-renewable static token retried until it expires",
+			responses: map[string]string{lookupSelf: `{"data":{"ttl":30,"renewable":false}}`},
+			// Halfway to expiry at 20s, then 25s.
+			steps: []step{{20 * time.Second, nil}, {5 * time.Second, nil}},
+		},
+		{
+			name:    "non-renewable approle token replaced by logging in",
+			approle: true,
+			responses: map[string]string{
+				approleLogin: `{"auth":{"client_token":"approle-token","lease_duration":30,"renewable":false}}`,
+			},
+			steps:     []step{{20 * time.Second, []string{approleLogin}}},
+			wantClose: []string{revokeSelf},
+		},
+		{
+			name:      "failed renewal falls back to logging in",
+			approle:   true,
+			fail:      map[string]int{renewSelf: http.StatusForbidden},
+			steps:     []step{{20 * time.Second, []string{renewSelf, approleLogin}}},
+			wantClose: []string{revokeSelf},
+		},
+		{
+			name:    "failed login retried a quarter of the maximum wait later",
+			approle: true,
+			fail:    map[string]int{renewSelf: http.StatusForbidden, approleLogin: http.StatusInternalServerError},
+			steps: []step{
+				{20 * time.Second, []string{renewSelf, approleLogin}},
+				{15 * time.Second, []string{renewSelf, approleLogin}},
+			},
+			wantClose: []string{revokeSelf},
+		},
+		{
+			name:      "lease renewed two thirds into its duration",
+			responses: map[string]string{lookupSelf: `{"data":{"ttl":0}}`},
+			lease:     true,
+			steps: []step{
+				{time.Minute, []string{renewLease}},
+				{time.Minute, []string{renewLease}},
+			},
+			wantClose: []string{revokeLease},
+		},
+		{
+			name:      "failed lease renewal retried, then dropped once expired",
+			responses: map[string]string{lookupSelf: `{"data":{"ttl":0}}`},
+			lease:     true,
+			fail:      map[string]int{renewLease: http.StatusInternalServerError},
+			steps: []step{
+				{time.Minute, []string{renewLease}},      // Due at 60s; retried halfway to expiry.
+				{15 * time.Second, []string{renewLease}}, // At 75s; retried at 82.5s.
+				{30 * time.Second, nil},                  // Expired at 90s.
+				{time.Minute, nil},
+			},
+			wantClose: nil,
+		},
+		{
+			name: "non-renewable lease kept for revocation",
+			responses: map[string]string{
+				lookupSelf: `{"data":{"ttl":0}}`,
+				readCreds:  `{"lease_id":"creds/1","lease_duration":90,"renewable":false}`,
+			},
+			lease:     true,
+			steps:     []step{{time.Minute, nil}},
+			wantClose: []string{revokeLease},
+		},
+		{
+			name: "non-renewable lease dropped once expired",
+			responses: map[string]string{
+				lookupSelf: `{"data":{"ttl":0}}`,
+				readCreds:  `{"lease_id":"creds/1","lease_duration":90,"renewable":false}`,
+			},
+			lease:     true,
+			steps:     []step{{time.Minute, nil}, {30 * time.Second, nil}, {time.Minute, nil}},
+			wantClose: nil,
+		},
+		{
+			name: "lease renewed as non-renewable not renewed again",
+			responses: map[string]string{
+				lookupSelf: `{"data":{"ttl":0}}`,
+				renewLease: `{"lease_id":"creds/1","lease_duration":90,"renewable":false}`,
+			},
+			lease: true,
+			steps: []step{
+				{time.Minute, []string{renewLease}}, // Expires at 150s.
+				{time.Minute, nil},
+				{30 * time.Second, nil},
+			},
+			wantClose: nil,
+		},
+		{
+			name:      "lease renewed without a duration dropped",
+			responses: map[string]string{lookupSelf: `{"data":{"ttl":0}}`, renewLease: `{}`},
+			lease:     true,
+			steps:     []step{{time.Minute, []string{renewLease}}, {time.Minute, nil}},
+			wantClose: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newVaultServer(t)
+			srv.set(func(s *vaultServer) {
+				for k, v := range tt.responses {
+					s.responses[k] = v
+				}
+			})
+			cfg := tokenConfig(srv.URL)
+			if tt.approle {
+				cfg = approleConfig(srv.URL)
+			}
+			c, clk := newClient(t, srv, cfg)
+			if tt.lease {
+				if _, err := c.Lease(context.Background(), "database/creds/ro"); err != nil {
+					t.Fatalf("Lease: %v", err)
+				}
+			}
+			srv.set(func(s *vaultServer) {
+				for k, v := range tt.fail {
+					s.fail[k] = v
+				}
+			})
+
+			loop := &renewalLoop{clk: clk}
+			for i, s := range tt.steps {
+				n := srv.count()
+				loop.advance(t, s.advance)
+				if got := srv.since(n); !slices.Equal(got, s.want) {
+					t.Errorf("step %d: requests = %q, want %q", i, got, s.want)
+				}
+			}
+
+			n := srv.count()
+			if err := c.Close(context.Background()); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			if got := srv.since(n); !slices.Equal(got, tt.wantClose) {
+				t.Errorf("Close requests = %q, want %q", got, tt.wantClose)
+			}
+		})
+	}
+}
+
+func TestResolveSecretLeaseExpiry(t *testing.T) {
+	srv := newVaultServer(t)
+	srv.set(func(s *vaultServer) { s.responses[lookupSelf] = `{"data":{"ttl":0}}` })
+	c, clk := newClient(t, srv, tokenConfig(srv.URL))
+
+	resolve := func() {
+		t.Helper()
+		v, err := c.ResolveSecret(context.Background(), "database/creds/ro#password")
+		if err != nil {
+			t.Fatalf("ResolveSecret: %v", err)
+		}
+		if v != "p" {
+			t.Errorf("ResolveSecret = %q, want p", v)
+		}
+	}
+	reads := func() int {
+		t.Helper()
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		return len(slices.DeleteFunc(slices.Clone(srv.calls), func(c string) bool { return c != readCreds }))
+	}
+
+	resolve()
+	resolve()
+	if got := reads(); got != 1 {
+		t.Fatalf("%d reads while the lease lasts, want 1", got)
+	}
+
+	// The renewal fails, so the lease expires and the next resolution reads a new secret.
+	srv.set(func(s *vaultServer) { s.fail[renewLease] = http.StatusInternalServerError })
+	loop := &renewalLoop{clk: clk}
+	loop.advance(t, time.Minute)
+	resolve()
+	if got := reads(); got != 1 {
+		t.Fatalf("%d reads before the lease expired, want 1", got)
+	}
+	loop.advance(t, 31*time.Second)
+	resolve()
+	if got := reads(); got != 2 {
+		t.Errorf("%d reads after the lease expired, want 2", got)
+	}
+}
+
+func TestCloseRevocationErrors(t *testing.T) {
+	srv := newVaultServer(t)
+	c, _ := newClient(t, srv, approleConfig(srv.URL))
+	if _, err := c.Lease(context.Background(), "database/creds/ro"); err != nil {
+		t.Fatal(err)
+	}
+	srv.set(func(s *vaultServer) {
+		s.fail[revokeLease] = http.StatusForbidden
+		s.fail[revokeSelf] = http.StatusForbidden
+	})
+
+	err := c.Close(context.Background())
+	if err == nil {
+		t.Fatal("Close succeeded, want the revocation errors")
+	}
+	for _, want := range []string{"revoking lease creds/1", "revoking token"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Close error %q does not contain %q", err, want)
+		}
+	}
+	if err := c.Close(context.Background()); err != nil {
+		t.Errorf("second Close = %v, want nil", err)
+	}
+}
+
+func TestClientToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    func(addr string) vault.Config
+		want   string
+		logins []string // Requests made by New.
+	}{
+		{name: "approle", cfg: approleConfig, want: "approle-token", logins: []string{approleLogin}},
+		{name: "static token", cfg: tokenConfig, want: "static-token", logins: []string{lookupSelf}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newVaultServer(t)
+			c, _ := newClient(t, srv, tt.cfg(srv.URL))
+			if got := srv.since(0); !slices.Equal(got, tt.logins) {
+				t.Errorf("New requests = %q, want %q", got, tt.logins)
+			}
+			if got := c.Token(); got != tt.want {
+				t.Errorf("Token() = %q, want %q", got, tt.want)
+			}
+			if _, err := c.Read(context.Background(), "database/creds/ro"); err != nil {
+				t.Fatal(err)
+			}
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+			if got := srv.tokens[len(srv.tokens)-1]; got != tt.want {
+				t.Errorf("X-Vault-Token = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithClockNil(t *testing.T) {
+	if _, err := vault.New(context.Background(), tokenConfig("http://127.0.0.1:1"), vault.WithClock(nil)); err == nil {
+		t.Error("New accepted a nil clock")
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	srv := newVaultServer(t)
+	srv.set(func(s *vaultServer) { s.fail[approleLogin] = http.StatusBadRequest })
+	tests := []struct {
+		name    string
+		cfg     vault.Config
+		wantErr string
+	}{
+		{
+			name:    "no credentials",
+			cfg:     vault.Config{Address: srv.URL, Timeout: time.Second},
+			wantErr: "vault_token or vault_role_id",
+		},
+		{
+			name:    "role without secret ID",
+			cfg:     vault.Config{Address: srv.URL, RoleID: "role", Timeout: time.Second},
+			wantErr: "vault_secret_id or vault_secret_id_file is required",
+		},
+		{
+			name:    "invalid address",
+			cfg:     vault.Config{Address: "http://[::1", Token: "t", Timeout: time.Second},
+			wantErr: "invalid vault_addr",
+		},
+		{
+			name:    "no timeout",
+			cfg:     vault.Config{Address: srv.URL, Token: "t"},
+			wantErr: "vault_timeout must be positive",
+		},
+		{
+			name: "missing secret ID file",
+			cfg: vault.Config{
+				Address:      srv.URL,
+				RoleID:       "role",
+				SecretIDFile: filepath.Join(t.TempDir(), "missing"),
+				Timeout:      time.Second,
+			},
+			wantErr: "reading secret ID",
+		},
+		{name: "login refused", cfg: approleConfig(srv.URL), wantErr: "AppRole login failed: vault: HTTP 400: denied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(vault.EnvToken, "")
+			_, err := vault.New(context.Background(), tt.cfg, vault.WithHTTPClient(srv.Client()))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewLoginResponses(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses map[string]string
+		fail      map[string]int
+		cfg       func(addr string) vault.Config
+		wantErr   string
+	}{
+		{
+			name:      "approle login without a token",
+			responses: map[string]string{approleLogin: `{"auth":{"lease_duration":30}}`},
+			cfg:       approleConfig,
+			wantErr:   "AppRole login returned no token",
+		},
+		{
+			name:    "token lookup refused",
+			fail:    map[string]int{lookupSelf: http.StatusForbidden},
+			cfg:     tokenConfig,
+			wantErr: "token lookup failed: vault: HTTP 403: denied",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newVaultServer(t)
+			srv.set(func(s *vaultServer) {
+				for k, v := range tt.responses {
+					s.responses[k] = v
+				}
+				for k, v := range tt.fail {
+					s.fail[k] = v
+				}
+			})
+			_, err := vault.New(context.Background(), tt.cfg(srv.URL), vault.WithHTTPClient(srv.Client()))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewSecretIDFileAndEnv(t *testing.T) {
+	srv := newVaultServer(t)
+	path := filepath.Join(t.TempDir(), "secret-id")
+	if err := os.WriteFile(path, []byte("  file-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var bodies []string
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, r.Header.Get("X-Vault-Namespace")+" "+string(b))
+		srv.serve(w, r)
+	})
+	t.Setenv(vault.EnvAddress, srv.URL)
+	t.Setenv(vault.EnvNamespace, "team")
+
+	cfg := vault.Config{RoleID: "role", SecretIDFile: path, AppRoleMount: "approle", Timeout: time.Second}
+	c, err := vault.New(context.Background(), cfg, vault.WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = c.Close(context.Background()) }()
+	if want := `team {"role_id":"role","secret_id":"file-secret"}`; len(bodies) == 0 || bodies[0] != want {
+		t.Errorf("login request = %q, want %q", bodies, want)
+	}
+}
+
+func TestReadAndKV(t *testing.T) {
+	srv := newVaultServer(t)
+	srv.set(func(s *vaultServer) {
+		s.responses["GET secret/data/app"] = `{"data":{"data":{"token":"t"},"metadata":{"version":3}}}`
+		s.responses["GET kv1/app"] = `{"data":{"data":"raw","token":"t1"}}`
+		s.responses["PUT transit/encrypt/k"] = `{"data":{"ciphertext":"vault:v1:x"}}`
+		s.fail["GET secret/data/missing"] = http.StatusNotFound
+		s.fail["GET secret/data/denied"] = http.StatusForbidden
+	})
+	c, _ := newClient(t, srv, tokenConfig(srv.URL))
+	ctx := context.Background()
+
+	kv, err := c.KV(ctx, "/secret/", "/app")
+	if err != nil || kv["token"] != "t" || len(kv) != 1 {
+		t.Errorf("KV = %v, %v, want token=t", kv, err)
+	}
+	s, err := c.Read(ctx, "kv1/app")
+	if err != nil || s.Data["token"] != "t1" || s.Data["data"] != "raw" {
+		t.Errorf("Read = %v, %v", s, err)
+	}
+	w, err := c.Write(ctx, "transit/encrypt/k", map[string]any{"plaintext": "eA=="})
+	if err != nil || w.Data["ciphertext"] != "vault:v1:x" {
+		t.Errorf("Write = %v, %v", w, err)
+	}
+
+	if _, err := c.KV(ctx, "secret", "missing"); !errors.Is(err, vault.ErrNotFound) {
+		t.Errorf("KV of a missing secret = %v, want ErrNotFound", err)
+	}
+	var ve *vault.Error
+	if _, err := c.Read(ctx, "secret/data/denied"); !errors.As(err, &ve) || ve.StatusCode != http.StatusForbidden {
+		t.Errorf("Read of a denied secret = %v, want a 403 *vault.Error", err)
+	}
+	if _, err := c.Lease(ctx, "secret/data/missing"); !errors.Is(err, vault.ErrNotFound) {
+		t.Errorf("Lease of a missing secret = %v, want ErrNotFound", err)
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	srv := newVaultServer(t)
+	srv.set(func(s *vaultServer) {
+		s.responses["GET secret/data/app"] = `{"data":{"data":{"token":"t","port":5432,"tags":["a"]},"metadata":{}}}`
+		s.fail["GET secret/data/missing"] = http.StatusNotFound
+	})
+	c, _ := newClient(t, srv, tokenConfig(srv.URL))
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{ref: "secret/data/app#token", want: "t"},
+		{ref: "secret/data/app#port", want: "5432"},
+		{ref: "secret/data/app#tags", want: `["a"]`},
+		{ref: "database/creds/ro#username", want: "u"},
+		{ref: "secret/data/app#missing", wantErr: "secret secret/data/app has no field missing"},
+		{ref: "secret/data/missing#token", wantErr: "vault: secret not found"},
+		{ref: "secret/data/app", wantErr: "must be <path>#<field>"},
+		{ref: "#token", wantErr: "must be <path>#<field>"},
+		{ref: "secret/data/app#", wantErr: "must be <path>#<field>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := c.ResolveSecret(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ResolveSecret error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ResolveSecret = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		err  *vault.Error
+		want string
+	}{
+		{err: &vault.Error{StatusCode: 500}, want: "vault: HTTP 500"},
+		{
+			err:  &vault.Error{StatusCode: 403, Errors: []string{"denied", "expired"}},
+			want: "vault: HTTP 403: denied; expired",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := vault.DefaultConfig()
+	if cfg.AppRoleMount != "approle" || cfg.Timeout != 10*time.Second {
+		t.Errorf("DefaultConfig = %+v, want the approle mount and a 10s timeout", cfg)
+	}
+}